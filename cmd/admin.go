@@ -0,0 +1,260 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/armchr/codeapi/internal/config"
+	"github.com/armchr/codeapi/internal/controller"
+	"github.com/armchr/codeapi/internal/db"
+	init_services "github.com/armchr/codeapi/internal/init"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+func newConfigCommand(opts *cliOptions) *cobra.Command {
+	configCmd := &cobra.Command{
+		Use:   "config",
+		Short: "Inspect the configuration",
+	}
+	configCmd.AddCommand(&cobra.Command{
+		Use:   "check",
+		Short: "Validate app.yaml and source.yaml without connecting to any service",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			os.Exit(ConfigCheckCommand(opts.appConfigPath, opts.sourceConfigPath))
+		},
+	})
+	return configCmd
+}
+
+func newDBCommand(opts *cliOptions) *cobra.Command {
+	dbCmd := &cobra.Command{
+		Use:   "db",
+		Short: "Manage the relational store schema",
+	}
+
+	var statusOnly bool
+	migrate := &cobra.Command{
+		Use:   "migrate",
+		Short: "Apply pending schema migrations for all configured repositories",
+		Args:  cobra.NoArgs,
+		Run: opts.run(func(cfg *config.Config, logger *zap.Logger, _ []string) {
+			MigrateCommand(cfg, logger, statusOnly)
+		}),
+	}
+	migrate.Flags().BoolVar(&statusOnly, "status", false, "Show the schema version of each table without applying migrations")
+
+	dbCmd.AddCommand(migrate)
+	return dbCmd
+}
+
+func newBackupCommand(opts *cliOptions) *cobra.Command {
+	var repoName, outDir string
+	cmd := &cobra.Command{
+		Use:   "backup",
+		Short: "Write a repository's graph, vectors and relational rows to one archive",
+		Args:  cobra.NoArgs,
+		Run: opts.run(func(cfg *config.Config, logger *zap.Logger, _ []string) {
+			BackupCommand(cfg, logger, repoName, outDir)
+		}),
+	}
+	cmd.Flags().StringVar(&repoName, "repo", "", "Repository to back up")
+	cmd.Flags().StringVar(&outDir, "out", ".", "Directory to write the archive to")
+	cmd.MarkFlagRequired("repo")
+	return cmd
+}
+
+func newRestoreCommand(opts *cliOptions) *cobra.Command {
+	var inPath, repoName string
+	cmd := &cobra.Command{
+		Use:   "restore",
+		Short: "Replace a repository's data with the contents of a backup archive",
+		Args:  cobra.NoArgs,
+		Run: opts.run(func(cfg *config.Config, logger *zap.Logger, _ []string) {
+			RestoreCommand(cfg, logger, inPath, repoName)
+		}),
+	}
+	cmd.Flags().StringVar(&inPath, "in", "", "Archive written by the backup command")
+	cmd.Flags().StringVar(&repoName, "repo", "", "Repository the archive must hold (optional safety check)")
+	cmd.MarkFlagRequired("in")
+	return cmd
+}
+
+// newBackupManager connects to the configured stores for backup and restore
+func newBackupManager(cfg *config.Config, logger *zap.Logger) (*controller.BackupManager, *init_services.ServiceContainer) {
+	opts := init_services.ServiceInitOptions{
+		EnableDB:         cfg.HasRelationalStore(),
+		EnableCodeGraph:  cfg.Neo4j.URI != "",
+		EnableEmbeddings: cfg.Qdrant.Host != "",
+	}
+	container, err := init_services.NewServiceContainer(cfg, opts, logger)
+	if err != nil {
+		logger.Fatal("Failed to initialize services", zap.Error(err))
+	}
+	return controller.NewBackupManager(container.DBConn, container.CodeGraph, container.VectorDB, logger), container
+}
+
+// BackupCommand writes the graph, vectors and relational rows of one
+// repository to <out>/<repo>-<timestamp>.tar.gz
+func BackupCommand(cfg *config.Config, logger *zap.Logger, repoName, outDir string) {
+	ctx := context.Background()
+
+	if _, err := cfg.GetRepository(repoName); err != nil {
+		logger.Warn("Repository is not in the configuration, backing up stored data only",
+			zap.String("repo_name", repoName))
+	}
+
+	if err := os.MkdirAll(outDir, 0o755); err != nil {
+		logger.Fatal("Failed to create output directory", zap.Error(err))
+	}
+	path := filepath.Join(outDir, fmt.Sprintf("%s-%s.tar.gz", repoName, time.Now().UTC().Format("20060102-150405")))
+	f, err := os.Create(path)
+	if err != nil {
+		logger.Fatal("Failed to create archive", zap.Error(err))
+	}
+	defer f.Close()
+
+	manager, container := newBackupManager(cfg, logger)
+	defer container.Close(ctx)
+
+	if _, err := manager.Backup(ctx, repoName, f); err != nil {
+		f.Close()
+		os.Remove(path)
+		logger.Fatal("Backup failed", zap.String("repo_name", repoName), zap.Error(err))
+	}
+	if err := f.Close(); err != nil {
+		logger.Fatal("Failed to write archive", zap.Error(err))
+	}
+
+	fmt.Println(path)
+	logger.Info("Backup command completed", zap.String("archive", path))
+}
+
+// RestoreCommand replaces a repository's data with the contents of an
+// archive written by BackupCommand
+func RestoreCommand(cfg *config.Config, logger *zap.Logger, inPath, repoName string) {
+	ctx := context.Background()
+
+	f, err := os.Open(inPath)
+	if err != nil {
+		logger.Fatal("Failed to open archive", zap.Error(err))
+	}
+	defer f.Close()
+
+	manager, container := newBackupManager(cfg, logger)
+	defer container.Close(ctx)
+
+	manifest, err := manager.Restore(ctx, f, repoName)
+	if err != nil {
+		logger.Fatal("Restore failed", zap.String("archive", inPath), zap.Error(err))
+	}
+
+	if _, err := cfg.GetRepository(manifest.RepoName); err != nil {
+		logger.Warn("Restored repository is not in source.yaml and will not be served until it is added",
+			zap.String("repo_name", manifest.RepoName))
+	}
+	logger.Info("Restore command completed",
+		zap.String("repo_name", manifest.RepoName),
+		zap.Time("backup_created_at", manifest.CreatedAt))
+}
+
+// ConfigCheckCommand prints a validation report for the configuration files
+// and returns the process exit code: 0 when usable, 1 otherwise
+func ConfigCheckCommand(appConfigPath, sourceConfigPath string) int {
+	fmt.Printf("Checking %s and %s\n\n", appConfigPath, sourceConfigPath)
+
+	_, report, err := config.LoadConfigWithReport(appConfigPath, sourceConfigPath)
+	if err != nil {
+		fmt.Printf("Errors (1):\n  - %v\n", err)
+		return 1
+	}
+
+	fmt.Print(report.String())
+	if report.HasErrors() {
+		return 1
+	}
+	return 0
+}
+
+// MigrateCommand applies (or, with statusOnly, reports) schema migrations of
+// the shared relational store tables and moves the legacy per-repository
+// tables of every configured repository into them
+func MigrateCommand(cfg *config.Config, logger *zap.Logger, statusOnly bool) {
+	ctx := context.Background()
+
+	opts := init_services.ServiceInitOptions{
+		EnableDB:  true,
+		RequireDB: true,
+	}
+	container, err := init_services.NewServiceContainer(cfg, opts, logger)
+	if err != nil {
+		logger.Fatal("Failed to initialize database for migrations", zap.Error(err))
+		return
+	}
+	defer container.Close(ctx)
+
+	sqlDB := container.DBConn.GetDB()
+	failed := false
+
+	if !statusOnly {
+		for _, repo := range cfg.Source.Repositories {
+			if err := db.EnsureFileVersionSchema(sqlDB, repo.Name, logger); err != nil {
+				logger.Error("File version migration failed", zap.String("repo_name", repo.Name), zap.Error(err))
+				failed = true
+			}
+			if err := db.EnsureSummarySchema(sqlDB, repo.Name, logger); err != nil {
+				logger.Error("Summary migration failed", zap.String("repo_name", repo.Name), zap.Error(err))
+				failed = true
+			}
+		}
+		if err := db.EnsureCodeNoteSchema(sqlDB, logger); err != nil {
+			logger.Error("Code note migration failed", zap.Error(err))
+			failed = true
+		}
+		if err := db.EnsureFeedbackSchema(sqlDB, logger); err != nil {
+			logger.Error("Feedback migration failed", zap.Error(err))
+			failed = true
+		}
+		if err := db.EnsureAuditLogSchema(sqlDB, logger); err != nil {
+			logger.Error("Audit log migration failed", zap.Error(err))
+			failed = true
+		}
+		if err := db.EnsureGraphOverflowSchema(sqlDB, logger); err != nil {
+			logger.Error("Graph overflow migration failed", zap.Error(err))
+			failed = true
+		}
+	}
+
+	migrator := db.NewMigrator(sqlDB, logger)
+	for _, t := range db.SharedTables {
+		status, err := migrator.Status(t.Name, t.Migrations)
+		if err != nil {
+			logger.Error("Failed to read migration status", zap.String("table", t.Name), zap.Error(err))
+			failed = true
+			continue
+		}
+		fmt.Printf("%-30s version %d/%d pending %v\n", status.Table, status.CurrentVersion, status.LatestVersion, status.Pending)
+	}
+
+	for _, repo := range cfg.Source.Repositories {
+		legacy, err := db.HasLegacyTables(sqlDB, repo.Name)
+		if err != nil {
+			logger.Error("Failed to check for legacy tables", zap.String("repo_name", repo.Name), zap.Error(err))
+			failed = true
+			continue
+		}
+		if legacy {
+			fmt.Printf("%-30s legacy per-repository tables pending import\n", repo.Name)
+		}
+	}
+
+	if failed {
+		logger.Fatal("Migrate command finished with errors")
+	}
+	logger.Info("Migrate command completed")
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"text/tabwriter"
+
+	"github.com/armchr/codeapi/internal/config"
+	init_services "github.com/armchr/codeapi/internal/init"
+	"github.com/armchr/codeapi/internal/service/codegraph"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+func newGraphCommand(opts *cliOptions) *cobra.Command {
+	graph := &cobra.Command{
+		Use:   "graph",
+		Short: "Inspect the code graph",
+	}
+
+	var outPath string
+	dump := &cobra.Command{
+		Use:   "dump REPO...",
+		Short: "Write the code graph of repositories to a text file",
+		Args:  cobra.MinimumNArgs(1),
+		Run: opts.run(func(cfg *config.Config, logger *zap.Logger, args []string) {
+			GraphDumpCommand(cfg, logger, args, outPath)
+		}),
+	}
+	dump.Flags().StringVarP(&outPath, "out", "o", "", "File to write the dump to")
+	dump.MarkFlagRequired("out")
+
+	var apply bool
+	schema := &cobra.Command{
+		Use:   "schema",
+		Short: "Report missing and extra graph indexes and constraints",
+		Args:  cobra.NoArgs,
+		Run: opts.run(func(cfg *config.Config, logger *zap.Logger, _ []string) {
+			if GraphSchemaCommand(cfg, logger, apply) != 0 {
+				os.Exit(1)
+			}
+		}),
+	}
+	schema.Flags().BoolVar(&apply, "apply", false, "Create the missing indexes and constraints")
+
+	graph.AddCommand(dump, schema)
+	return graph
+}
+
+// GraphDumpCommand writes the code graph of the given repositories to
+// outPath, in the format the integration tests compare against
+func GraphDumpCommand(cfg *config.Config, logger *zap.Logger, repoNames []string, outPath string) {
+	ctx := context.Background()
+
+	opts := init_services.ServiceInitOptions{EnableCodeGraph: true}
+	container, err := init_services.NewServiceContainer(cfg, opts, logger)
+	if err != nil {
+		logger.Fatal("Failed to initialize code graph", zap.Error(err))
+		return
+	}
+	defer container.Close(ctx)
+
+	if container.CodeGraph == nil {
+		logger.Fatal("Cannot dump code graph: neo4j is not configured")
+		return
+	}
+
+	logger.Info("Dumping code graph to file",
+		zap.Strings("repositories", repoNames),
+		zap.String("path", outPath))
+	if err := container.CodeGraph.DumpToFile(ctx, outPath, repoNames); err != nil {
+		logger.Fatal("Failed to dump code graph", zap.Error(err))
+	}
+	logger.Info("Code graph dumped successfully", zap.String("path", outPath))
+}
+
+// GraphSchemaCommand prints the indexes and constraints of the code graph
+// schema that are missing, and the node indexes codeapi does not use. With
+// apply the missing ones are created. It returns 1 when any are still
+// missing.
+func GraphSchemaCommand(cfg *config.Config, logger *zap.Logger, apply bool) int {
+	ctx := context.Background()
+
+	opts := init_services.ServiceInitOptions{EnableCodeGraph: true}
+	container, err := init_services.NewServiceContainer(cfg, opts, logger)
+	if err != nil {
+		logger.Fatal("Failed to initialize code graph", zap.Error(err))
+		return 1
+	}
+	defer container.Close(ctx)
+
+	if container.CodeGraph == nil {
+		logger.Fatal("Cannot check graph schema: neo4j is not configured")
+		return 1
+	}
+
+	var report *codegraph.SchemaReport
+	if apply {
+		report, err = container.CodeGraph.EnsureSchema(ctx)
+	} else {
+		report, err = container.CodeGraph.CheckSchema(ctx)
+	}
+	if report == nil {
+		logger.Error("Failed to read graph schema", zap.Error(err))
+		return 1
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "STATUS\tNAME\tINDEX")
+	for _, index := range report.Missing {
+		status := "missing"
+		if slices.ContainsFunc(report.Created, func(c codegraph.GraphIndex) bool { return c.Name == index.Name }) {
+			status = "created"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", status, index.Name, index)
+	}
+	for _, index := range report.Extra {
+		fmt.Fprintf(w, "extra\t%s\t%s\n", index.Name, index)
+	}
+	w.Flush()
+
+	if err != nil {
+		logger.Error("Failed to apply graph schema", zap.Error(err))
+	}
+	if len(report.Created) < len(report.Missing) {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,524 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"os/user"
+	"syscall"
+
+	"github.com/armchr/codeapi/internal/config"
+	"github.com/armchr/codeapi/internal/controller"
+	"github.com/armchr/codeapi/internal/db"
+	init_services "github.com/armchr/codeapi/internal/init"
+	"github.com/armchr/codeapi/internal/service/audit"
+	"github.com/armchr/codeapi/internal/util"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+func newIndexCommand(opts *cliOptions) *cobra.Command {
+	index := &cobra.Command{
+		Use:   "index",
+		Short: "Build, clean and compact repository indexes",
+	}
+
+	var useHead, noProgress, dryRun bool
+	build := &cobra.Command{
+		Use:   "build REPO...",
+		Short: "Build the code graph, embeddings and summaries enabled in app.yaml",
+		Args:  cobra.MinimumNArgs(1),
+		Run: opts.run(func(cfg *config.Config, logger *zap.Logger, args []string) {
+			if dryRun {
+				DryRunBuildCommand(cfg, logger, args, useHead)
+				return
+			}
+			BuildIndexCommand(cfg, logger, args, useHead, !noProgress)
+		}),
+	}
+	build.Flags().BoolVar(&useHead, "head", false, "Index the committed git HEAD version instead of the working directory")
+	build.Flags().BoolVar(&noProgress, "no-progress", false, "Do not print progress while building")
+	build.Flags().BoolVar(&dryRun, "dry-run", false, "Report the files that would be processed and the estimated token cost without writing anything")
+
+	var cleanDryRun bool
+	clean := &cobra.Command{
+		Use:   "clean REPO...",
+		Short: "Delete all stored data (graph, vectors, file versions, summaries) of repositories",
+		Args:  cobra.MinimumNArgs(1),
+		Run: opts.run(func(cfg *config.Config, logger *zap.Logger, args []string) {
+			if cleanDryRun {
+				DryRunCleanCommand(cfg, logger, args)
+				return
+			}
+			CleanCommand(cfg, logger, args)
+		}),
+	}
+	clean.Flags().BoolVar(&cleanDryRun, "dry-run", false, "Report what would be deleted without deleting it")
+
+	var keepVersions int
+	compact := &cobra.Command{
+		Use:   "compact REPO...",
+		Short: "Drop old file versions and their derived data",
+		Args:  cobra.MinimumNArgs(1),
+		Run: opts.run(func(cfg *config.Config, logger *zap.Logger, args []string) {
+			CompactCommand(cfg, logger, args, keepVersions)
+		}),
+	}
+	compact.Flags().IntVar(&keepVersions, "keep-versions", 0, "Versions to keep per file path (overrides retention.keep_versions)")
+
+	index.AddCommand(build, clean, compact)
+	return index
+}
+
+// BuildIndexCommand builds the indexes enabled under index_building for
+// each repository and prints a statistics table when done. With showProgress
+// the progress of each build is printed to stderr.
+func BuildIndexCommand(cfg *config.Config, logger *zap.Logger, repoNames []string, useHead, showProgress bool) {
+	ctx, stop := interruptibleContext()
+	defer stop()
+
+	logger.Info("Build index command started",
+		zap.Strings("repositories", repoNames),
+		zap.Bool("use_head", useHead),
+		zap.Bool("code_graph_enabled", cfg.IndexBuilding.EnableCodeGraph),
+		zap.Bool("embeddings_enabled", cfg.IndexBuilding.EnableEmbeddings),
+		zap.Bool("language_server", cfg.IndexBuilding.UsesLanguageServer()))
+
+	// Initialize all services using the new initialization module
+	opts := init_services.GetIndexBuildingOptions(cfg)
+	container, err := init_services.NewServiceContainer(cfg, opts, logger)
+	if err != nil {
+		logger.Fatal("Failed to initialize services", zap.Error(err))
+		return
+	}
+	// Buffered graph writes are flushed even after an interrupt
+	defer container.Close(context.WithoutCancel(ctx))
+
+	// Initialize processors based on configuration
+	if err := container.InitProcessors(cfg); err != nil {
+		logger.Fatal("Failed to initialize processors", zap.Error(err))
+		return
+	}
+
+	var progress *progressPrinter
+	if showProgress {
+		progress = newProgressPrinter(os.Stderr)
+	}
+	var stats []*controller.BuildStats
+
+	// Process each repository
+	for _, repoName := range repoNames {
+		if ctx.Err() != nil {
+			logger.Warn("Index building interrupted, skipping remaining repositories")
+			break
+		}
+		logger.Info("Processing repository for index building",
+			zap.String("repo_name", repoName))
+
+		// Validate repository exists in config
+		repo, err := cfg.GetRepository(repoName)
+		if err != nil {
+			logger.Error("Repository not found in configuration",
+				zap.String("repo_name", repoName),
+				zap.Error(err))
+			continue
+		}
+
+		logger.Info("Building indexes for repository",
+			zap.String("repo_name", repo.Name),
+			zap.String("path", repo.Path),
+			zap.String("language", repo.Language))
+
+		// Create FileVersionRepository for this repository
+		fileVersionRepo, err := db.NewFileVersionRepository(container.DBConn.GetDB(), repo.Name, logger)
+		if err != nil {
+			logger.Error("Failed to create file version repository",
+				zap.String("repo_name", repo.Name),
+				zap.Error(err))
+			continue
+		}
+
+		// Create index builder with FileVersionRepository for this specific repo
+		indexBuilder := controller.NewIndexBuilder(cfg, container.Processors, fileVersionRepo, logger)
+		if progress != nil {
+			indexBuilder.SetProgressListener(progress)
+		}
+
+		// Get git info if using HEAD mode
+		var gitInfo *util.GitInfo
+		if useHead {
+			gitInfo, err = util.GetGitInfo(repo.Path)
+			if err != nil {
+				logger.Error("Failed to get git info",
+					zap.String("repo_name", repo.Name),
+					zap.Error(err))
+				continue
+			}
+			if !gitInfo.IsGitRepo {
+				logger.Error("Repository is not a git repository, cannot use --head",
+					zap.String("repo_name", repo.Name),
+					zap.String("path", repo.Path))
+				continue
+			}
+		}
+
+		// Build all indexes using the unified index builder
+		entry := cliAudit(audit.OpIndexBuild, repo.Name)
+		err = indexBuilder.BuildIndexWithGitInfo(ctx, repo, useHead, gitInfo)
+		if progress != nil {
+			progress.finish()
+		}
+		if s := indexBuilder.LastStats(); s != nil {
+			stats = append(stats, s)
+			entry.Details = s.AuditDetails()
+		}
+		controller.RecordAudit(ctx, container.DBConn.GetDB(), entry, err, logger)
+		if err != nil {
+			logger.Error("Failed to build indexes for repository",
+				zap.String("repo_name", repo.Name),
+				zap.Error(err))
+			continue
+		}
+
+		logger.Info("Completed index building for repository",
+			zap.String("repo_name", repo.Name))
+	}
+
+	printBuildStats(os.Stdout, stats)
+	logger.Info("Build index command completed")
+}
+
+// dryRunOptions connects to the configured stores for read-only queries.
+// Stores that cannot be reached are reported instead of failing the run.
+func dryRunOptions(cfg *config.Config, graph, vectors bool) init_services.ServiceInitOptions {
+	return init_services.ServiceInitOptions{
+		EnableDB:         cfg.HasRelationalStore(),
+		EnableCodeGraph:  graph && cfg.Neo4j.URI != "",
+		EnableEmbeddings: vectors && cfg.Qdrant.Host != "",
+		LazyInit:         true,
+		ReadOnly:         true,
+	}
+}
+
+// DryRunBuildCommand prints what BuildIndexCommand would do for each
+// repository: every file with its action, totals and estimated token cost
+func DryRunBuildCommand(cfg *config.Config, logger *zap.Logger, repoNames []string, useHead bool) {
+	ctx := context.Background()
+
+	container, err := init_services.NewServiceContainer(cfg, dryRunOptions(cfg, false, false), logger)
+	if err != nil {
+		logger.Fatal("Failed to initialize services", zap.Error(err))
+		return
+	}
+	defer container.Close(ctx)
+	if container.DBConn == nil {
+		fmt.Println("No relational store available: unchanged files cannot be detected")
+	}
+
+	for _, repoName := range repoNames {
+		repo, err := cfg.GetRepository(repoName)
+		if err != nil {
+			logger.Error("Repository not found in configuration",
+				zap.String("repo_name", repoName),
+				zap.Error(err))
+			continue
+		}
+
+		var gitInfo *util.GitInfo
+		if useHead {
+			if gitInfo, err = util.GetGitInfo(repo.Path); err != nil || !gitInfo.IsGitRepo {
+				logger.Error("Cannot use --head: repository is not a git repository",
+					zap.String("repo_name", repo.Name),
+					zap.Error(err))
+				continue
+			}
+		}
+
+		var fileVersions *db.FileVersionRepository
+		if container.DBConn != nil {
+			fileVersions = db.NewFileVersionReader(container.DBConn.GetDB(), repo.Name, logger)
+		}
+		plan, err := controller.NewIndexPlanner(cfg, fileVersions, logger).Plan(ctx, repo, useHead, gitInfo)
+		if err != nil {
+			logger.Error("Dry run failed",
+				zap.String("repo_name", repo.Name),
+				zap.Error(err))
+			continue
+		}
+
+		fmt.Printf("Repository %s (%s)\n", repo.Name, repo.Path)
+		for _, file := range plan.Files {
+			if file.Reason != "" {
+				fmt.Printf("  %-9s  %s (%s)\n", file.Action, file.Path, file.Reason)
+			} else {
+				fmt.Printf("  %-9s  %s\n", file.Action, file.Path)
+			}
+		}
+		fmt.Printf("  %d to process, %d unchanged, %d skipped\n", plan.Processed, plan.Unchanged, plan.Skipped)
+		if cfg.IndexBuilding.EnableEmbeddings {
+			fmt.Printf("  embeddings: %d chunks, ~%d tokens\n", plan.Chunks, plan.EmbeddingTokens)
+		}
+		if cfg.IndexBuilding.EnableSummary {
+			fmt.Printf("  summaries: %d LLM calls, ~%d input tokens (plus folder and project summaries)\n",
+				plan.SummaryCalls, plan.SummaryInputTokens)
+		}
+	}
+}
+
+// DryRunCleanCommand prints what CleanCommand would delete for each repository
+func DryRunCleanCommand(cfg *config.Config, logger *zap.Logger, repoNames []string) {
+	ctx := context.Background()
+
+	container, err := init_services.NewServiceContainer(cfg, dryRunOptions(cfg, true, true), logger)
+	if err != nil {
+		logger.Fatal("Failed to initialize services", zap.Error(err))
+		return
+	}
+	defer container.Close(ctx)
+
+	var sqlDB *sql.DB
+	if container.DBConn != nil {
+		sqlDB = container.DBConn.GetDB()
+	}
+	for _, dep := range container.Dependencies() {
+		if !dep.Available {
+			fmt.Printf("%s unavailable, not checked: %s\n", dep.Name, dep.Reason)
+		}
+	}
+
+	for _, repoName := range repoNames {
+		fmt.Printf("Repository %s\n", repoName)
+		targets := controller.PlanClean(ctx, repoName, sqlDB, container.CodeGraph, container.VectorDB, logger)
+		for _, t := range targets {
+			if t.Error != "" {
+				fmt.Printf("  %-7s %s: %s\n", t.Store, t.What, t.Error)
+			} else {
+				fmt.Printf("  %-7s %s: %d\n", t.Store, t.What, t.Count)
+			}
+		}
+	}
+}
+
+// CleanCommand performs standalone cleanup of repository data from all databases
+func CleanCommand(cfg *config.Config, logger *zap.Logger, repoNames []string) {
+	ctx := context.Background()
+
+	logger.Info("Clean command started",
+		zap.Strings("repositories", repoNames))
+
+	// Initialize services needed for cleanup
+	opts := init_services.ServiceInitOptions{
+		EnableDB:         cfg.HasRelationalStore(),
+		EnableCodeGraph:  cfg.Neo4j.URI != "",
+		EnableEmbeddings: cfg.Qdrant.Host != "",
+	}
+	container, err := init_services.NewServiceContainer(cfg, opts, logger)
+	if err != nil {
+		logger.Fatal("Failed to initialize services for cleanup", zap.Error(err))
+		return
+	}
+	defer container.Close(ctx)
+
+	// Clean each repository
+	for _, repoName := range repoNames {
+		logger.Info("Cleaning up repository data", zap.String("repo_name", repoName))
+		entry := cliAudit(audit.OpClean, repoName)
+		var failures []error
+
+		// A build running meanwhile would write part of the data back
+		var lock *db.RepoLock
+		if container.DBConn != nil {
+			if lock, err = db.LockRepo(ctx, container.DBConn.GetDB(), repoName, audit.OpClean, 0); err != nil {
+				logger.Error("Cannot clean repository",
+					zap.String("repo_name", repoName),
+					zap.Error(err))
+				// Recorded as skipped, as nothing was deleted
+				controller.RecordAudit(ctx, container.DBConn.GetDB(), entry, err, logger)
+				continue
+			}
+		}
+
+		// Clean Neo4j (CodeGraph)
+		if container.CodeGraph != nil {
+			logger.Info("Cleaning Neo4j data", zap.String("repo_name", repoName))
+			progress := func(deleted, total int64) {
+				fmt.Fprintf(os.Stderr, "[%s] deleted %d/%d graph nodes\n", repoName, deleted, total)
+			}
+			if err := container.CodeGraph.CleanRepositoryWithProgress(ctx, repoName, progress); err != nil {
+				logger.Error("Failed to clean Neo4j data",
+					zap.String("repo_name", repoName),
+					zap.Error(err))
+				failures = append(failures, err)
+			} else {
+				logger.Info("Neo4j data cleaned successfully", zap.String("repo_name", repoName))
+			}
+		}
+
+		// Clean Qdrant (Vector DB)
+		if container.VectorDB != nil {
+			logger.Info("Cleaning Qdrant collection", zap.String("repo_name", repoName))
+			if err := container.VectorDB.DeleteCollection(ctx, repoName); err != nil {
+				logger.Error("Failed to clean Qdrant collection",
+					zap.String("repo_name", repoName),
+					zap.Error(err))
+				failures = append(failures, err)
+			} else {
+				logger.Info("Qdrant collection cleaned successfully", zap.String("repo_name", repoName))
+			}
+		}
+
+		// Clean relational store tables
+		if container.DBConn != nil {
+			// Clean file versions
+			logger.Info("Cleaning file versions", zap.String("repo_name", repoName))
+			fileVersionRepo, err := db.NewFileVersionRepository(container.DBConn.GetDB(), repoName, logger)
+			if err != nil {
+				logger.Error("Failed to create file version repository for cleanup",
+					zap.String("repo_name", repoName),
+					zap.Error(err))
+				failures = append(failures, err)
+			} else {
+				if err := fileVersionRepo.DeleteRepository(); err != nil {
+					logger.Error("Failed to delete file versions",
+						zap.String("repo_name", repoName),
+						zap.Error(err))
+					failures = append(failures, err)
+				} else {
+					logger.Info("File versions deleted successfully", zap.String("repo_name", repoName))
+				}
+			}
+
+			// Clean code summaries
+			logger.Info("Cleaning code summaries", zap.String("repo_name", repoName))
+			summaryStore, err := db.NewSummaryStore(container.DBConn.GetDB(), repoName, logger)
+			if err != nil {
+				logger.Error("Failed to create summary store for cleanup",
+					zap.String("repo_name", repoName),
+					zap.Error(err))
+				failures = append(failures, err)
+			} else {
+				if _, err := summaryStore.DeleteAll(); err != nil {
+					logger.Error("Failed to delete code summaries",
+						zap.String("repo_name", repoName),
+						zap.Error(err))
+					failures = append(failures, err)
+				} else {
+					logger.Info("Code summaries deleted successfully", zap.String("repo_name", repoName))
+				}
+			}
+
+			// Clean code notes
+			logger.Info("Cleaning code notes", zap.String("repo_name", repoName))
+			noteStore, err := db.NewCodeNoteStore(container.DBConn.GetDB(), repoName, logger)
+			if err != nil {
+				logger.Error("Failed to create note store for cleanup",
+					zap.String("repo_name", repoName),
+					zap.Error(err))
+				failures = append(failures, err)
+			} else if _, err := noteStore.DeleteAll(); err != nil {
+				logger.Error("Failed to delete code notes",
+					zap.String("repo_name", repoName),
+					zap.Error(err))
+				failures = append(failures, err)
+			} else {
+				logger.Info("Code notes deleted successfully", zap.String("repo_name", repoName))
+			}
+		}
+
+		// The audit log itself is kept, to tell why the data is gone
+		if container.DBConn != nil {
+			controller.RecordAudit(ctx, container.DBConn.GetDB(), entry, errors.Join(failures...), logger)
+		}
+		if lock != nil {
+			lock.Release()
+		}
+		logger.Info("Cleanup completed for repository", zap.String("repo_name", repoName))
+	}
+
+	logger.Info("Clean command completed")
+}
+
+// CompactCommand applies the file version retention policy to repositories and
+// purges graph, vector and summary data tied to dropped or orphaned FileIDs
+func CompactCommand(cfg *config.Config, logger *zap.Logger, repoNames []string, keepVersions int) {
+	ctx := context.Background()
+
+	retention := cfg.Retention.GetDefaults()
+	if keepVersions > 0 {
+		retention.KeepVersions = keepVersions
+	}
+
+	logger.Info("Compact command started",
+		zap.Strings("repositories", repoNames),
+		zap.Int("keep_versions", retention.KeepVersions))
+
+	// Processors are needed to purge derived data, so initialize like server mode
+	opts := init_services.GetServerModeOptions(cfg)
+	container, err := init_services.NewServiceContainer(cfg, opts, logger)
+	if err != nil {
+		logger.Fatal("Failed to initialize services for compaction", zap.Error(err))
+		return
+	}
+	defer container.Close(ctx)
+
+	if err := container.InitProcessors(cfg); err != nil {
+		logger.Fatal("Failed to initialize processors", zap.Error(err))
+		return
+	}
+
+	compactor := controller.NewCompactor(container.Processors, container.DBConn, container.CodeGraph, logger)
+
+	for _, repoName := range repoNames {
+		repo, err := cfg.GetRepository(repoName)
+		if err != nil {
+			logger.Error("Repository not found in configuration",
+				zap.String("repo_name", repoName),
+				zap.Error(err))
+			continue
+		}
+
+		entry := cliAudit(audit.OpCompact, repo.Name)
+		result, err := compactor.CompactRepository(ctx, repo, retention.KeepVersions)
+		if err != nil {
+			logger.Error("Failed to compact repository",
+				zap.String("repo_name", repoName),
+				zap.Error(err))
+		}
+		entry.Details = map[string]any{"keep_versions": retention.KeepVersions}
+		if result != nil {
+			entry.Details["dropped_versions"] = result.DroppedVersions
+			entry.Details["orphan_file_ids"] = result.OrphanFileIDs
+		}
+		if container.DBConn != nil {
+			controller.RecordAudit(ctx, container.DBConn.GetDB(), entry, err, logger)
+		}
+	}
+
+	logger.Info("Compact command completed")
+}
+
+// interruptibleContext returns a context cancelled by the first SIGINT or
+// SIGTERM, so that a build stops after the files in flight and keeps what it
+// has written. A second signal terminates the process as usual.
+func interruptibleContext() (context.Context, context.CancelFunc) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+	return ctx, stop
+}
+
+// cliAudit starts the audit entry of a command on a repository, attributed
+// to the OS user running it
+func cliAudit(operation, repoName string) *audit.Entry {
+	entry := controller.NewAuditEntry(operation, repoName, audit.SourceCLI)
+	if u, err := user.Current(); err == nil {
+		entry.Actor = u.Username
+	}
+	return entry
+}
@@ -2,130 +2,172 @@ package main
 
 import (
 	"context"
-	"flag"
+	"database/sql"
 	"fmt"
 	"log"
 	"net/http"
-	"strings"
+	"os"
+	"time"
 
+	"github.com/armchr/codeapi/internal/budget"
 	"github.com/armchr/codeapi/internal/codeapi"
 	"github.com/armchr/codeapi/internal/config"
 	"github.com/armchr/codeapi/internal/controller"
 	"github.com/armchr/codeapi/internal/db"
 	"github.com/armchr/codeapi/internal/handler"
 	init_services "github.com/armchr/codeapi/internal/init"
-	"github.com/armchr/codeapi/internal/util"
+	"github.com/armchr/codeapi/internal/logging"
+	"github.com/armchr/codeapi/internal/telemetry"
+	"github.com/armchr/codeapi/internal/tracing"
 	"github.com/armchr/codeapi/pkg/lsp"
 
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/cobra"
 	"go.uber.org/zap"
-	"go.uber.org/zap/zapcore"
 )
 
-// stringSliceFlag is a custom flag type that allows multiple values
-type stringSliceFlag []string
-
-func (s *stringSliceFlag) String() string {
-	return strings.Join(*s, ",")
-}
-
-func (s *stringSliceFlag) Set(value string) error {
-	*s = append(*s, value)
-	return nil
+func main() {
+	if err := newRootCommand().Execute(); err != nil {
+		os.Exit(1)
+	}
 }
 
-// parseLogLevel converts a string log level to zapcore.Level
-func parseLogLevel(level string) zapcore.Level {
-	switch strings.ToLower(level) {
-	case "debug":
-		return zapcore.DebugLevel
-	case "info":
-		return zapcore.InfoLevel
-	case "warn", "warning":
-		return zapcore.WarnLevel
-	case "error":
-		return zapcore.ErrorLevel
-	default:
-		return zapcore.InfoLevel // default to info
-	}
+// cliOptions holds the flags shared by every command
+type cliOptions struct {
+	appConfigPath    string
+	sourceConfigPath string
+	workDir          string
 }
 
-func main() {
-	var sourceConfigPath = flag.String("source", "source.yaml", "Path to source configuration file")
-	var appConfigPath = flag.String("app", "app.yaml", "Path to app configuration file")
-	var workDir = flag.String("workdir", "", "Working directory to store files")
-	//var port = flag.String("port", "8080", "Server port")
-	var test = flag.Bool("test", false, "Run in test mode")
-	var buildIndex stringSliceFlag
-	flag.Var(&buildIndex, "build-index", "Repository name to build index for (can be specified multiple times)")
-	var useHead = flag.Bool("head", false, "Use git HEAD version instead of working directory (only valid with --build-index)")
-	var testDump = flag.String("test-dump", "", "Path to output file for dumping code graph after index building (only valid with --build-index)")
-	var clean = flag.Bool("clean", false, "Clean up all DB entries (MySQL, Neo4j, Qdrant) for the repository (can be used standalone or with --build-index)")
-	var cleanRepos stringSliceFlag
-	flag.Var(&cleanRepos, "clean-repo", "Repository name to clean (can be specified multiple times, use with --clean for standalone cleanup)")
-	flag.Parse()
-
-	cfg, err := config.LoadConfig(*appConfigPath, *sourceConfigPath)
+// load reads the configuration and builds the logger. Failures are fatal,
+// since no command can do anything useful without them.
+func (o *cliOptions) load() (*config.Config, *zap.Logger) {
+	cfg, err := config.LoadConfig(o.appConfigPath, o.sourceConfigPath)
 	if err != nil {
 		log.Fatal("Failed to load configuration:", err)
 	}
 
-	cfgZap := zap.NewProductionConfig()
-	cfgZap.Level.SetLevel(parseLogLevel(cfg.App.LogLevel))
-	cfgZap.OutputPaths = []string{"stdout", "all.log"}
-	logger, err := cfgZap.Build()
+	logger, err := logging.New(cfg.App.LogLevel, cfg.Logging)
 	if err != nil {
 		log.Fatal("Failed to initialize logger:", err)
 	}
 
-	defer logger.Sync()
-
 	// Override workdir from command line if provided
-	if *workDir != "" {
-		cfg.App.WorkDir = *workDir
+	if o.workDir != "" {
+		cfg.App.WorkDir = o.workDir
 	}
 
 	logger.Info("Configuration loaded successfully", zap.Any("config", cfg))
+	return cfg, logger
+}
 
-	if test != nil && *test {
-		logger.Info("Running in test mode")
-		LSPTest(cfg, logger)
-		return
+// loadQuiet is load for commands whose stdout is their result: the logger
+// only reports warnings and errors, on stderr
+func (o *cliOptions) loadQuiet() (*config.Config, *zap.Logger) {
+	cfg, err := config.LoadConfig(o.appConfigPath, o.sourceConfigPath)
+	if err != nil {
+		log.Fatal("Failed to load configuration:", err)
 	}
-
-	// Check if we're in standalone clean mode (--clean with --clean-repo but no --build-index)
-	if *clean && len(cleanRepos) > 0 && len(buildIndex) == 0 {
-		logger.Info("Running in CLI mode - standalone clean")
-		CleanCommand(cfg, logger, cleanRepos)
-		return
+	if o.workDir != "" {
+		cfg.App.WorkDir = o.workDir
 	}
 
-	// Check if we're in CLI mode (build-index specified)
-	if len(buildIndex) > 0 {
-		logger.Info("Running in CLI mode - build-index")
-		BuildIndexCommand(cfg, logger, buildIndex, *useHead, *testDump, *clean)
-		return
+	logger, err := logging.New("warn", config.LoggingConfig{
+		Encoding: "console",
+		Outputs:  []config.LogOutput{{Type: "stderr"}},
+	})
+	if err != nil {
+		log.Fatal("Failed to initialize logger:", err)
 	}
+	return cfg, logger
+}
+
+// run wraps a command body with configuration loading and logger flushing
+func (o *cliOptions) run(fn func(cfg *config.Config, logger *zap.Logger, args []string)) func(*cobra.Command, []string) {
+	return func(cmd *cobra.Command, args []string) {
+		cfg, logger := o.load()
+		defer logger.Sync()
 
-	// Validate --test-dump flag usage
-	if *testDump != "" {
-		logger.Fatal("--test-dump flag is only valid with --build-index")
+		shutdownTracing, err := tracing.Init(cmd.Context(), cfg.Tracing, logger)
+		if err != nil {
+			logger.Fatal("Failed to initialize tracing", zap.Error(err))
+		}
+		defer func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := shutdownTracing(ctx); err != nil {
+				logger.Warn("Failed to flush traces", zap.Error(err))
+			}
+		}()
+
+		budget.Init(cfg.Resources, logger)
+
+		logger.Info("Running command", zap.String("command", cmd.CommandPath()))
+		fn(cfg, logger, args)
 	}
+}
 
-	// Validate --clean flag usage (needs either --build-index or --clean-repo)
-	if *clean {
-		logger.Fatal("--clean flag requires either --build-index or --clean-repo")
+func newRootCommand() *cobra.Command {
+	opts := &cliOptions{}
+
+	root := &cobra.Command{
+		Use:   "codeapi",
+		Short: "Code graph, embedding and summary index for source repositories",
+		Long: `codeapi indexes repositories into a Neo4j code graph, Qdrant embeddings and
+LLM summaries, and serves them over HTTP. Without a subcommand it starts the
+server, same as "codeapi serve".`,
+		SilenceUsage: true,
+		Args:         cobra.NoArgs,
+		Run: opts.run(func(cfg *config.Config, logger *zap.Logger, _ []string) {
+			runServer(cfg, logger, opts.sourceConfigPath)
+		}),
 	}
 
-	// Validate --clean-repo flag usage
-	if len(cleanRepos) > 0 {
-		logger.Fatal("--clean-repo flag requires --clean flag")
+	flags := root.PersistentFlags()
+	flags.StringVar(&opts.appConfigPath, "app", "app.yaml", "Path to app configuration file")
+	flags.StringVar(&opts.sourceConfigPath, "source", "source.yaml", "Path to source configuration file")
+	flags.StringVar(&opts.workDir, "workdir", "", "Working directory to store files")
+
+	root.AddCommand(
+		newServeCommand(opts),
+		newIndexCommand(opts),
+		newGraphCommand(opts),
+		newParseCommand(opts),
+		newSummaryCommand(opts),
+		newDBCommand(opts),
+		newConfigCommand(opts),
+		newBackupCommand(opts),
+		newRestoreCommand(opts),
+		newQueryCommand(opts),
+		newLSPTestCommand(opts),
+	)
+	return root
+}
+
+func newServeCommand(opts *cliOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:   "serve",
+		Short: "Start the HTTP API server",
+		Args:  cobra.NoArgs,
+		Run: opts.run(func(cfg *config.Config, logger *zap.Logger, _ []string) {
+			runServer(cfg, logger, opts.sourceConfigPath)
+		}),
 	}
+}
 
-	// Validate --head flag usage
-	if *useHead {
-		logger.Fatal("--head flag is only valid with --build-index")
+// newLSPTestCommand exercises a language server client during development
+func newLSPTestCommand(opts *cliOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:    "lsp-test",
+		Short:  "Run the LSP client smoke test",
+		Hidden: true,
+		Args:   cobra.NoArgs,
+		Run:    opts.run(func(cfg *config.Config, logger *zap.Logger, _ []string) { LSPTest(cfg, logger) }),
 	}
+}
 
+// runServer starts the HTTP API and blocks until it fails
+func runServer(cfg *config.Config, logger *zap.Logger, sourceConfigPath string) {
 	// Initialize all services using the new initialization module
 	opts := init_services.GetServerModeOptions(cfg)
 	container, err := init_services.NewServiceContainer(cfg, opts, logger)
@@ -139,28 +181,62 @@ func main() {
 		logger.Fatal("Failed to initialize processors", zap.Error(err))
 	}
 
+	// Create or report missing collections, tables and graph indexes before
+	// the first request needs them
+	if cfg.Provisioning.GetDefaults().Mode != config.ProvisionOff {
+		controller.NewProvisioner(container.ChunkService, container.DBConn, container.CodeGraph, cfg, logger).Run(context.Background())
+	}
 
-	repoController := controller.NewRepoController(container.RepoService, container.ChunkService, container.Processors, container.MySQLConn, cfg, logger)
+	// Pick up repositories added to or removed from source.yaml
+	if !cfg.App.DisableSourceReload {
+		sourceWatcher := controller.NewSourceWatcher(sourceConfigPath, cfg, container.RepoService, logger)
+		go sourceWatcher.Run(context.Background())
+	}
 
-	// Initialize CodeAPI controller if CodeGraph is available
-	var codeAPIController *controller.CodeAPIController
-	if container.CodeGraph != nil {
-		codeAPI := codeapi.NewCodeAPI(container.CodeGraph, logger)
-		codeAPIController = controller.NewCodeAPIController(codeAPI, cfg, logger)
+	// Opt-in usage report, see telemetry in app.yaml
+	if cfg.Telemetry.Enabled {
+		go telemetry.NewReporter(cfg, logger).Run(context.Background())
 	}
 
-	// Initialize Summary controller if MySQL is available
-	var summaryController *controller.SummaryController
-	if container.MySQLConn != nil {
-		summaryController = controller.NewSummaryController(
-			container.MySQLConn.GetDB(),
-			cfg,
-			container.SummaryProcessor, // May be nil if summary is disabled
-			logger,
-		)
+	// Start language servers and fill caches before the first requests
+	if cfg.Warmup.Enabled {
+		warmer := controller.NewWarmer(container.RepoService, container.ChunkService, container.DBConn, container.CodeGraph, cfg, logger)
+		go warmer.Run(context.Background())
 	}
 
-	router := handler.SetupRouter(repoController, codeAPIController, summaryController, cfg, logger)
+	// Controllers are rebuilt whenever a dependency that was down at startup
+	// comes up; background jobs of the previous wiring are stopped once the
+	// new one is in place
+	router := &handler.SwappableHandler{}
+	limits := handler.NewConcurrencyLimits(cfg.App.Concurrency)
+	var stopBackground context.CancelFunc
+	wire := func() error {
+		bgCtx, stop := context.WithCancel(context.Background())
+		engine, err := buildRouter(bgCtx, container, limits, cfg, logger)
+		if err != nil {
+			stop()
+			return err
+		}
+		router.Set(engine)
+		if stopBackground != nil {
+			stopBackground()
+		}
+		stopBackground = stop
+		return nil
+	}
+	if err := wire(); err != nil {
+		logger.Fatal("Failed to create controllers", zap.Error(err))
+	}
+
+	if container.Degraded() {
+		logger.Warn("Starting in degraded mode", zap.Any("dependencies", container.Dependencies()))
+		go container.RetryUnavailable(context.Background(), cfg, func() {
+			logger.Info("Rewiring controllers after dependency recovery")
+			if err := wire(); err != nil {
+				logger.Error("Failed to rewire controllers", zap.Error(err))
+			}
+		})
+	}
 
 	logger.Info("Starting server", zap.Int("port", cfg.App.Port))
 	if err := http.ListenAndServe(fmt.Sprintf(":%d", cfg.App.Port), router); err != nil {
@@ -168,291 +244,76 @@ func main() {
 	}
 }
 
-func LSPTest(cfg *config.Config, logger *zap.Logger) {
-	logger.Info("Testing LSP client")
-	repo, _ := cfg.GetRepository("mcp-server")
-
-	// Initialize the LSP client
-	ls, err := lsp.NewLSPLanguageServer(cfg, repo.Language, repo.Path, logger)
-	if err != nil {
-		logger.Fatal("Failed to create LSP client", zap.Error(err))
-	}
-
-	// Create a context for the LSP operations
-	ctx := context.Background()
-
-	defer ls.Shutdown(ctx)
-
-	// Initialize the LSP client
-
-	baseClient := ls.(*lsp.TypeScriptLanguageServerClient).BaseClient
-
-	baseClient.TestCommand(ctx)
-}
-
-func BuildIndexCommand(cfg *config.Config, logger *zap.Logger, repoNames []string, useHead bool, testDumpPath string, clean bool) {
-	ctx := context.Background()
-
-	logger.Info("Build index command started",
-		zap.Strings("repositories", repoNames),
-		zap.Bool("use_head", useHead),
-		zap.String("test_dump_path", testDumpPath),
-		zap.Bool("clean", clean),
-		zap.Bool("code_graph_enabled", cfg.IndexBuilding.EnableCodeGraph),
-		zap.Bool("embeddings_enabled", cfg.IndexBuilding.EnableEmbeddings))
-
-	// Initialize all services using the new initialization module
-	opts := init_services.GetIndexBuildingOptions(cfg)
-	container, err := init_services.NewServiceContainer(cfg, opts, logger)
+// buildRouter creates the controllers for the services currently available
+// in the container and starts their background jobs under ctx
+func buildRouter(ctx context.Context, container *init_services.ServiceContainer, limits *handler.ConcurrencyLimits, cfg *config.Config, logger *zap.Logger) (*gin.Engine, error) {
+	repoController, err := controller.NewRepoController(container.RepoService, container.ChunkService, container.Processors, container.DBConn, container.CodeGraph, cfg, logger)
 	if err != nil {
-		logger.Fatal("Failed to initialize services", zap.Error(err))
-		return
+		return nil, err
 	}
-	defer container.Close(ctx)
 
-	// Initialize processors based on configuration
-	if err := container.InitProcessors(cfg); err != nil {
-		logger.Fatal("Failed to initialize processors", zap.Error(err))
-		return
+	// Expire data created by ad-hoc file indexing
+	if container.DBConn != nil && !cfg.Sandbox.DisableCleanup {
+		sandboxCleaner := controller.NewSandboxCleaner(container.Processors, container.DBConn, cfg, logger)
+		go sandboxCleaner.Run(ctx)
 	}
 
-	// Process each repository
-	for _, repoName := range repoNames {
-		logger.Info("Processing repository for index building",
-			zap.String("repo_name", repoName))
-
-		// Validate repository exists in config
-		repo, err := cfg.GetRepository(repoName)
-		if err != nil {
-			logger.Error("Repository not found in configuration",
-				zap.String("repo_name", repoName),
-				zap.Error(err))
-			continue
-		}
-
-		logger.Info("Building indexes for repository",
-			zap.String("repo_name", repo.Name),
-			zap.String("path", repo.Path),
-			zap.String("language", repo.Language))
-
-		// Create FileVersionRepository for this repository
-		fileVersionRepo, err := db.NewFileVersionRepository(container.MySQLConn.GetDB(), repo.Name, logger)
-		if err != nil {
-			logger.Error("Failed to create file version repository",
-				zap.String("repo_name", repo.Name),
-				zap.Error(err))
-			continue
-		}
-
-		// Create index builder with FileVersionRepository for this specific repo
-		indexBuilder := controller.NewIndexBuilder(cfg, container.Processors, fileVersionRepo, logger)
-
-		// Get git info if using HEAD mode
-		var gitInfo *util.GitInfo
-		if useHead {
-			gitInfo, err = util.GetGitInfo(repo.Path)
-			if err != nil {
-				logger.Error("Failed to get git info",
-					zap.String("repo_name", repo.Name),
-					zap.Error(err))
-				continue
-			}
-			if !gitInfo.IsGitRepo {
-				logger.Error("Repository is not a git repository, cannot use --head flag",
-					zap.String("repo_name", repo.Name),
-					zap.String("path", repo.Path))
-				continue
-			}
-		}
+	// Initialize CodeAPI controller if CodeGraph is available
+	var codeAPIController *controller.CodeAPIController
+	var diffController *controller.DiffController
+	if container.CodeGraph != nil {
+		codeAPI := codeapi.NewCodeAPI(container.CodeGraph, logger)
 
-		// Build all indexes using the unified index builder
-		if err := indexBuilder.BuildIndexWithGitInfo(ctx, repo, useHead, gitInfo); err != nil {
-			logger.Error("Failed to build indexes for repository",
-				zap.String("repo_name", repo.Name),
-				zap.Error(err))
-			continue
+		var mysqlDB *sql.DB
+		if container.DBConn != nil {
+			mysqlDB = container.DBConn.GetDB()
 		}
-
-		logger.Info("Completed index building for repository",
-			zap.String("repo_name", repo.Name))
+		codeAPIController = controller.NewCodeAPIController(codeAPI, mysqlDB, cfg, logger)
+		diffController = controller.NewDiffController(codeAPI, mysqlDB, cfg, logger)
 	}
 
-	// If test-dump is specified, dump the code graph after all processing is complete
-	if testDumpPath != "" && container.CodeGraph != nil {
-		logger.Info("Dumping code graph to file", zap.String("path", testDumpPath))
-		if err := container.CodeGraph.DumpToFile(ctx, testDumpPath, repoNames); err != nil {
-			logger.Error("Failed to dump code graph", zap.Error(err))
-		} else {
-			logger.Info("Code graph dumped successfully", zap.String("path", testDumpPath))
+	// Initialize Summary controller if the relational store is available
+	var summaryController *controller.SummaryController
+	if container.DBConn != nil {
+		summaryProcessor := container.SummaryProcessor // May be nil if summary is disabled
+		if cfg.App.PublicMode {
+			// Read-only: stored summaries are served, missing ones not generated
+			summaryProcessor = nil
 		}
-	} else if testDumpPath != "" && container.CodeGraph == nil {
-		logger.Warn("Cannot dump code graph: CodeGraph is not enabled")
-	}
-
-	// If clean is specified, clean up all DB entries for each repository
-	if clean {
-		logger.Info("Starting cleanup phase for all repositories")
-		for _, repoName := range repoNames {
-			logger.Info("Cleaning up repository data", zap.String("repo_name", repoName))
-
-			// Clean Neo4j (CodeGraph)
-			if container.CodeGraph != nil {
-				logger.Info("Cleaning Neo4j data", zap.String("repo_name", repoName))
-				if err := container.CodeGraph.CleanRepository(ctx, repoName); err != nil {
-					logger.Error("Failed to clean Neo4j data",
-						zap.String("repo_name", repoName),
-						zap.Error(err))
-				} else {
-					logger.Info("Neo4j data cleaned successfully", zap.String("repo_name", repoName))
-				}
-			}
-
-			// Clean Qdrant (Vector DB)
-			if container.VectorDB != nil {
-				logger.Info("Cleaning Qdrant collection", zap.String("repo_name", repoName))
-				// Use repo name as collection name (default convention)
-				if err := container.VectorDB.DeleteCollection(ctx, repoName); err != nil {
-					logger.Error("Failed to clean Qdrant collection",
-						zap.String("repo_name", repoName),
-						zap.Error(err))
-				} else {
-					logger.Info("Qdrant collection cleaned successfully", zap.String("repo_name", repoName))
-				}
-			}
-
-			// Clean MySQL (FileVersionRepository)
-			if container.MySQLConn != nil {
-				logger.Info("Cleaning MySQL file_versions table", zap.String("repo_name", repoName))
-				fileVersionRepo, err := db.NewFileVersionRepository(container.MySQLConn.GetDB(), repoName, logger)
-				if err != nil {
-					logger.Error("Failed to create file version repository for cleanup",
-						zap.String("repo_name", repoName),
-						zap.Error(err))
-				} else {
-					if err := fileVersionRepo.DropTable(); err != nil {
-						logger.Error("Failed to drop MySQL file_versions table",
-							zap.String("repo_name", repoName),
-							zap.Error(err))
-					} else {
-						logger.Info("MySQL file_versions table dropped successfully", zap.String("repo_name", repoName))
-					}
-				}
-
-				// Clean MySQL (SummaryStore)
-				logger.Info("Cleaning MySQL code_summaries table", zap.String("repo_name", repoName))
-				summaryStore, err := db.NewSummaryStore(container.MySQLConn.GetDB(), repoName, logger)
-				if err != nil {
-					logger.Error("Failed to create summary store for cleanup",
-						zap.String("repo_name", repoName),
-						zap.Error(err))
-				} else {
-					if err := summaryStore.DropTable(); err != nil {
-						logger.Error("Failed to drop MySQL code_summaries table",
-							zap.String("repo_name", repoName),
-							zap.Error(err))
-					} else {
-						logger.Info("MySQL code_summaries table dropped successfully", zap.String("repo_name", repoName))
-					}
-				}
-			}
-
-			logger.Info("Cleanup completed for repository", zap.String("repo_name", repoName))
+		summaryController = controller.NewSummaryController(
+			container.DBConn.GetDB(),
+			cfg,
+			summaryProcessor,
+			logger,
+		)
+		if container.CodeGraph != nil {
+			summaryController.SetCodeGraph(container.CodeGraph)
 		}
-		logger.Info("Cleanup phase completed for all repositories")
 	}
 
-	logger.Info("Build index command completed")
+	return handler.SetupRouter(repoController, codeAPIController, diffController, summaryController, container, limits, cfg, logger), nil
 }
 
-// CleanCommand performs standalone cleanup of repository data from all databases
-func CleanCommand(cfg *config.Config, logger *zap.Logger, repoNames []string) {
-	ctx := context.Background()
-
-	logger.Info("Clean command started",
-		zap.Strings("repositories", repoNames))
+func LSPTest(cfg *config.Config, logger *zap.Logger) {
+	logger.Info("Testing LSP client")
+	repo, _ := cfg.GetRepository("mcp-server")
 
-	// Initialize services needed for cleanup
-	opts := init_services.ServiceInitOptions{
-		EnableMySQL:      cfg.MySQL.Host != "",
-		EnableCodeGraph:  cfg.Neo4j.URI != "",
-		EnableEmbeddings: cfg.Qdrant.Host != "",
-	}
-	container, err := init_services.NewServiceContainer(cfg, opts, logger)
+	// Initialize the LSP client
+	ls, err := lsp.NewLSPLanguageServer(cfg, repo.Language, repo, logger)
 	if err != nil {
-		logger.Fatal("Failed to initialize services for cleanup", zap.Error(err))
-		return
+		logger.Fatal("Failed to create LSP client", zap.Error(err))
 	}
-	defer container.Close(ctx)
-
-	// Clean each repository
-	for _, repoName := range repoNames {
-		logger.Info("Cleaning up repository data", zap.String("repo_name", repoName))
-
-		// Clean Neo4j (CodeGraph)
-		if container.CodeGraph != nil {
-			logger.Info("Cleaning Neo4j data", zap.String("repo_name", repoName))
-			if err := container.CodeGraph.CleanRepository(ctx, repoName); err != nil {
-				logger.Error("Failed to clean Neo4j data",
-					zap.String("repo_name", repoName),
-					zap.Error(err))
-			} else {
-				logger.Info("Neo4j data cleaned successfully", zap.String("repo_name", repoName))
-			}
-		}
 
-		// Clean Qdrant (Vector DB)
-		if container.VectorDB != nil {
-			logger.Info("Cleaning Qdrant collection", zap.String("repo_name", repoName))
-			if err := container.VectorDB.DeleteCollection(ctx, repoName); err != nil {
-				logger.Error("Failed to clean Qdrant collection",
-					zap.String("repo_name", repoName),
-					zap.Error(err))
-			} else {
-				logger.Info("Qdrant collection cleaned successfully", zap.String("repo_name", repoName))
-			}
-		}
+	// Create a context for the LSP operations
+	ctx := context.Background()
 
-		// Clean MySQL tables
-		if container.MySQLConn != nil {
-			// Clean file_versions table
-			logger.Info("Cleaning MySQL file_versions table", zap.String("repo_name", repoName))
-			fileVersionRepo, err := db.NewFileVersionRepository(container.MySQLConn.GetDB(), repoName, logger)
-			if err != nil {
-				logger.Error("Failed to create file version repository for cleanup",
-					zap.String("repo_name", repoName),
-					zap.Error(err))
-			} else {
-				if err := fileVersionRepo.DropTable(); err != nil {
-					logger.Error("Failed to drop MySQL file_versions table",
-						zap.String("repo_name", repoName),
-						zap.Error(err))
-				} else {
-					logger.Info("MySQL file_versions table dropped successfully", zap.String("repo_name", repoName))
-				}
-			}
+	defer ls.Shutdown(ctx)
 
-			// Clean code_summaries table
-			logger.Info("Cleaning MySQL code_summaries table", zap.String("repo_name", repoName))
-			summaryStore, err := db.NewSummaryStore(container.MySQLConn.GetDB(), repoName, logger)
-			if err != nil {
-				logger.Error("Failed to create summary store for cleanup",
-					zap.String("repo_name", repoName),
-					zap.Error(err))
-			} else {
-				if err := summaryStore.DropTable(); err != nil {
-					logger.Error("Failed to drop MySQL code_summaries table",
-						zap.String("repo_name", repoName),
-						zap.Error(err))
-				} else {
-					logger.Info("MySQL code_summaries table dropped successfully", zap.String("repo_name", repoName))
-				}
-			}
-		}
+	// Initialize the LSP client
 
-		logger.Info("Cleanup completed for repository", zap.String("repo_name", repoName))
-	}
+	baseClient := ls.(*lsp.TypeScriptLanguageServerClient).BaseClient
 
-	logger.Info("Clean command completed")
+	baseClient.TestCommand(ctx)
 }
 
 func CodeGraphEntry(cfg *config.Config, logger *zap.Logger, container *init_services.ServiceContainer) {
@@ -480,11 +341,11 @@ func CodeGraphEntry(cfg *config.Config, logger *zap.Logger, container *init_serv
 
 			logger.Info("Processing repository", zap.String("name", repo.Name))
 
-			// Create FileVersionRepository for this repository if MySQL is available
+			// Create FileVersionRepository for this repository if the relational store is available
 			var fileVersionRepo *db.FileVersionRepository
 			var err error
-			if container.MySQLConn != nil {
-				fileVersionRepo, err = db.NewFileVersionRepository(container.MySQLConn.GetDB(), repo.Name, logger)
+			if container.DBConn != nil {
+				fileVersionRepo, err = db.NewFileVersionRepository(container.DBConn.GetDB(), repo.Name, logger)
 				if err != nil {
 					logger.Error("Failed to create file version repository, will process without FileID tracking",
 						zap.String("name", repo.Name),
@@ -494,9 +355,9 @@ func CodeGraphEntry(cfg *config.Config, logger *zap.Logger, container *init_serv
 			}
 
 			// Create index builder for this repository
-			// If fileVersionRepo is nil, IndexBuilder will fail - this is intentional to enforce MySQL requirement
+			// If fileVersionRepo is nil, IndexBuilder will fail - this is intentional to enforce the FileID tracking requirement
 			if fileVersionRepo == nil {
-				logger.Error("Skipping repository - MySQL FileID tracking is required",
+				logger.Error("Skipping repository - FileID tracking requires a relational store",
 					zap.String("name", repo.Name))
 				continue
 			}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/armchr/codeapi/internal/config"
+	"github.com/armchr/codeapi/internal/parseonly"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+// Output formats of the parse command
+const (
+	parseFormatJSONL = "jsonl"
+	parseFormatDump  = "dump"
+)
+
+func newParseCommand(opts *cliOptions) *cobra.Command {
+	var repoName, outPath, format string
+	parse := &cobra.Command{
+		Use:   "parse --repo REPO --out FILE",
+		Short: "Parse a repository into a graph file without any store",
+		Long: `Parse a repository with the tree-sitter visitors alone and write the nodes
+and edges they create to a file. Neo4j, the relational store, Qdrant and the
+language servers are not used, so no call edges are resolved. The output is
+the same for the same checkout, which makes it suitable for CI checks and for
+comparing visitor output before and after a change.
+
+--format jsonl (the default) writes one node or relationship per line, in the
+shape of repository backups; --format dump writes the text of "graph dump".`,
+		Args: cobra.NoArgs,
+		Run: opts.run(func(cfg *config.Config, logger *zap.Logger, _ []string) {
+			if err := ParseCommand(cfg, logger, repoName, outPath, format); err != nil {
+				logger.Fatal("Failed to parse repository", zap.String("repo_name", repoName), zap.Error(err))
+			}
+		}),
+	}
+	parse.Flags().StringVar(&repoName, "repo", "", "Repository of source.yaml to parse")
+	parse.Flags().StringVarP(&outPath, "out", "o", "", "File to write the graph to")
+	parse.Flags().StringVar(&format, "format", parseFormatJSONL, "Output format: jsonl or dump")
+	parse.MarkFlagRequired("repo")
+	parse.MarkFlagRequired("out")
+	return parse
+}
+
+// ParseCommand parses a configured repository into an in-memory graph and
+// writes it to outPath in format
+func ParseCommand(cfg *config.Config, logger *zap.Logger, repoName, outPath, format string) error {
+	if format != parseFormatJSONL && format != parseFormatDump {
+		return fmt.Errorf("unknown format %q, want %s or %s", format, parseFormatJSONL, parseFormatDump)
+	}
+	repo, err := cfg.GetRepository(repoName)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	start := time.Now()
+	graph, err := parseonly.Parse(ctx, repo, cfg, logger)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Create(outPath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	w := bufio.NewWriter(file)
+	if format == parseFormatDump {
+		err = graph.Dump(ctx, w, []string{repo.Name})
+	} else {
+		err = graph.WriteJSONL(w)
+	}
+	if err != nil {
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	logger.Info("Parsed repository",
+		zap.String("repo_name", repo.Name),
+		zap.Int("files", graph.Files),
+		zap.String("path", outPath),
+		zap.Duration("elapsed", time.Since(start)))
+	return nil
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/armchr/codeapi/internal/controller"
+)
+
+// progressPrinter renders index build progress on a terminal. On a terminal
+// a single status line is redrawn in place; otherwise (CI logs, pipes) a line
+// is printed at every 10% step so the output stays readable.
+type progressPrinter struct {
+	out         io.Writer
+	interactive bool
+
+	mu         sync.Mutex
+	repo       string
+	phase      string
+	total      int
+	done       int
+	start      time.Time
+	lastDraw   time.Time
+	lastStep   int
+	lineLength int
+}
+
+func newProgressPrinter(out *os.File) *progressPrinter {
+	interactive := false
+	if info, err := out.Stat(); err == nil {
+		interactive = info.Mode()&os.ModeCharDevice != 0
+	}
+	return &progressPrinter{out: out, interactive: interactive}
+}
+
+func (p *progressPrinter) PhaseStarted(repoName, phase string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if repoName != p.repo {
+		p.repo, p.total, p.done, p.lastStep = repoName, 0, 0, 0
+	}
+	p.phase = phase
+	if phase == controller.PhaseFiles {
+		p.start = time.Now()
+	}
+	p.draw(true)
+}
+
+func (p *progressPrinter) FilesCounted(repoName string, total int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.total = total
+}
+
+func (p *progressPrinter) FileDone(repoName, relativePath string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.done++
+	p.draw(false)
+}
+
+// finish ends the status line of the current repository
+func (p *progressPrinter) finish() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.interactive && p.lineLength > 0 {
+		fmt.Fprintln(p.out)
+		p.lineLength = 0
+	}
+}
+
+// draw prints the status line. Redraws are throttled unless forced.
+func (p *progressPrinter) draw(force bool) {
+	if p.interactive {
+		if !force && time.Since(p.lastDraw) < 100*time.Millisecond && p.done != p.total {
+			return
+		}
+		p.lastDraw = time.Now()
+		line := p.statusLine()
+		pad := ""
+		if n := p.lineLength - len(line); n > 0 {
+			pad = strings.Repeat(" ", n)
+		}
+		fmt.Fprintf(p.out, "\r%s%s", line, pad)
+		p.lineLength = len(line)
+		return
+	}
+
+	step := 0
+	if p.total > 0 {
+		step = p.done * 10 / p.total
+	}
+	if force || step > p.lastStep {
+		p.lastStep = step
+		fmt.Fprintln(p.out, p.statusLine())
+	}
+}
+
+func (p *progressPrinter) statusLine() string {
+	if p.phase != controller.PhaseFiles {
+		return fmt.Sprintf("[%s] %s", p.repo, p.phase)
+	}
+	if p.total == 0 {
+		return fmt.Sprintf("[%s] %s %d", p.repo, p.phase, p.done)
+	}
+	line := fmt.Sprintf("[%s] %s %d/%d (%d%%)", p.repo, p.phase, p.done, p.total, p.done*100/p.total)
+	if eta := estimateRemaining(time.Since(p.start), p.done, p.total); eta > 0 {
+		line += " ETA " + eta.String()
+	}
+	return line
+}
+
+// estimateRemaining extrapolates the time left from the average time per
+// file so far. It returns 0 when there is nothing to extrapolate from.
+func estimateRemaining(elapsed time.Duration, done, total int) time.Duration {
+	if done == 0 || done >= total {
+		return 0
+	}
+	perFile := elapsed / time.Duration(done)
+	return (perFile * time.Duration(total-done)).Round(time.Second)
+}
+
+// printBuildStats writes the statistics of finished builds as a table
+func printBuildStats(out io.Writer, stats []*controller.BuildStats) {
+	if len(stats) == 0 {
+		return
+	}
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "REPOSITORY\tFILES\tUNCHANGED\tNODES\tCHUNKS\tSUMMARIES\tTOKENS\tDURATION\t")
+	for _, s := range stats {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%d\t%d\t%d\t%s\t\n",
+			s.RepoName, s.FilesProcessed, s.FilesUnchanged,
+			s.Counter(controller.StatNodesCreated),
+			s.Counter(controller.StatChunksEmbedded),
+			s.Counter(controller.StatSummariesGenerated),
+			s.Counter(controller.StatTokensUsed),
+			s.Duration.Round(time.Millisecond))
+	}
+	fmt.Fprintln(tw)
+
+	fmt.Fprintln(tw, "REPOSITORY\tPROCESSOR\tFILE TIME\tPOST-PROCESS\t")
+	for _, s := range stats {
+		processors := append([]controller.ProcessorStats(nil), s.Processors...)
+		sort.SliceStable(processors, func(i, j int) bool {
+			return processors[i].FileTime+processors[i].PostProcessTime > processors[j].FileTime+processors[j].PostProcessTime
+		})
+		for _, p := range processors {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t\n", s.RepoName, p.Name,
+				p.FileTime.Round(time.Millisecond), p.PostProcessTime.Round(time.Millisecond))
+		}
+	}
+	tw.Flush()
+}
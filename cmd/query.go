@@ -0,0 +1,278 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/armchr/codeapi/internal/codeapi"
+	"github.com/armchr/codeapi/internal/controller"
+	"github.com/armchr/codeapi/internal/db"
+	init_services "github.com/armchr/codeapi/internal/init"
+	"github.com/armchr/codeapi/internal/service/summary"
+	"github.com/armchr/codeapi/pkg/client"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+// queryOptions holds the flags shared by the query subcommands
+type queryOptions struct {
+	cli     *cliOptions
+	server  string
+	apiKey  string
+	output  string
+	timeout time.Duration
+}
+
+func newQueryCommand(opts *cliOptions) *cobra.Command {
+	q := &queryOptions{cli: opts}
+	query := &cobra.Command{
+		Use:   "query",
+		Short: "Run graph, search and summary queries from the terminal",
+		Long: `Run common queries against a running server (--server) or, without
+--server, directly against the stores configured in app.yaml.`,
+	}
+	flags := query.PersistentFlags()
+	flags.StringVar(&q.server, "server", "", "Base URL of a running server, e.g. http://localhost:8181")
+	flags.StringVar(&q.apiKey, "api-key", "", "API key sent to the server when tenancy is enabled (default $CODEAPI_API_KEY)")
+	flags.StringVarP(&q.output, "output", "o", "table", "Output format: table or json")
+	flags.DurationVar(&q.timeout, "timeout", time.Minute, "Time limit for the query")
+
+	var depth int
+	var className, filePath string
+	callers := &cobra.Command{
+		Use:   "callers REPO FUNCTION",
+		Short: "List the callers of a function, transitively up to --depth",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			needs := init_services.ServiceInitOptions{EnableCodeGraph: true}
+			return runQuery(q, needs, func(ctx context.Context, b queryBackend) (*codeapi.CallGraph, error) {
+				return b.Callers(ctx, args[0], args[1], className, filePath, depth)
+			}, printCallGraph)
+		},
+	}
+	callers.Flags().IntVar(&depth, "depth", 3, "Maximum call depth")
+	callers.Flags().StringVar(&className, "class", "", "Class the function belongs to")
+	callers.Flags().StringVar(&filePath, "file", "", "File the function is defined in")
+
+	var limit int
+	search := &cobra.Command{
+		Use:   "search REPO QUERY",
+		Short: "Search methods by a natural language description of their signature",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			needs := init_services.ServiceInitOptions{EnableEmbeddings: true}
+			return runQuery(q, needs, func(ctx context.Context, b queryBackend) ([]controller.MethodSignatureResult, error) {
+				return b.Search(ctx, args[0], args[1], limit)
+			}, printSearchResults)
+		},
+	}
+	search.Flags().IntVar(&limit, "limit", 10, "Maximum number of results")
+
+	summaryCmd := &cobra.Command{
+		Use:   "summary REPO FILE",
+		Short: "Show the stored summary of a file",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			needs := init_services.ServiceInitOptions{EnableDB: true, RequireDB: true, ReadOnly: true}
+			return runQuery(q, needs, func(ctx context.Context, b queryBackend) (*summary.CodeSummary, error) {
+				return b.FileSummary(ctx, args[0], args[1])
+			}, printSummary)
+		},
+	}
+
+	query.AddCommand(callers, search, summaryCmd)
+	return query
+}
+
+// runQuery runs a query against the selected backend and prints the result
+// as JSON or through printTable
+func runQuery[T any](q *queryOptions, needs init_services.ServiceInitOptions, query func(context.Context, queryBackend) (T, error), printTable func(io.Writer, T)) error {
+	if q.output != "table" && q.output != "json" {
+		return fmt.Errorf("unsupported output format %q (table or json)", q.output)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), q.timeout)
+	defer cancel()
+
+	var backend queryBackend
+	if q.server != "" {
+		apiKey := q.apiKey
+		if apiKey == "" {
+			apiKey = os.Getenv("CODEAPI_API_KEY")
+		}
+		var opts []client.Option
+		if apiKey != "" {
+			opts = append(opts, client.WithAPIKey(apiKey))
+		}
+		backend = &httpQueryBackend{client: client.New(q.server, opts...)}
+	} else {
+		cfg, logger := q.cli.loadQuiet()
+		defer logger.Sync()
+		container, err := init_services.NewServiceContainer(cfg, needs, logger)
+		if err != nil {
+			return err
+		}
+		defer container.Close(context.Background())
+		backend = &storeQueryBackend{container: container, logger: logger}
+	}
+
+	result, err := query(ctx, backend)
+	if err != nil {
+		return err
+	}
+	if q.output == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(result)
+	}
+	printTable(os.Stdout, result)
+	return nil
+}
+
+// queryBackend answers queries either through a server's HTTP API or
+// directly from the stores
+type queryBackend interface {
+	Callers(ctx context.Context, repoName, function, className, filePath string, depth int) (*codeapi.CallGraph, error)
+	Search(ctx context.Context, repoName, text string, limit int) ([]controller.MethodSignatureResult, error)
+	FileSummary(ctx context.Context, repoName, filePath string) (*summary.CodeSummary, error)
+}
+
+// storeQueryBackend queries the stores of a service container
+type storeQueryBackend struct {
+	container *init_services.ServiceContainer
+	logger    *zap.Logger
+}
+
+func (b *storeQueryBackend) Callers(ctx context.Context, repoName, function, className, filePath string, depth int) (*codeapi.CallGraph, error) {
+	if b.container.CodeGraph == nil {
+		return nil, fmt.Errorf("neo4j is not configured")
+	}
+	api := codeapi.NewCodeAPI(b.container.CodeGraph, b.logger)
+	return api.Analyzer().GetCallGraphByName(ctx, repoName, filePath, className, function, codeapi.CallGraphOptions{
+		Direction: codeapi.DirectionIncoming,
+		MaxDepth:  depth,
+	})
+}
+
+func (b *storeQueryBackend) Search(ctx context.Context, repoName, text string, limit int) ([]controller.MethodSignatureResult, error) {
+	if b.container.ChunkService == nil {
+		return nil, fmt.Errorf("qdrant and ollama are not configured")
+	}
+	chunks, scores, err := b.container.ChunkService.SearchMethodSignatures(ctx, repoName, text, limit)
+	if err != nil {
+		return nil, err
+	}
+	return controller.NewMethodSignatureResults(chunks, scores), nil
+}
+
+func (b *storeQueryBackend) FileSummary(ctx context.Context, repoName, filePath string) (*summary.CodeSummary, error) {
+	store := db.NewSummaryReader(b.container.DBConn.GetDB(), repoName, b.logger).WithContext(ctx)
+	result, err := store.GetFileSummary(filePath)
+	if err != nil {
+		return nil, err
+	}
+	if result == nil {
+		return nil, fmt.Errorf("file summary not found")
+	}
+	return result, nil
+}
+
+// httpQueryBackend calls the HTTP API of a running server
+type httpQueryBackend struct {
+	client *client.Client
+}
+
+func (b *httpQueryBackend) Callers(ctx context.Context, repoName, function, className, filePath string, depth int) (*codeapi.CallGraph, error) {
+	return b.client.GetCallers(ctx, &client.GetCallGraphRequest{
+		RepoName:     repoName,
+		FunctionName: function,
+		ClassName:    className,
+		FilePath:     filePath,
+		MaxDepth:     depth,
+	})
+}
+
+func (b *httpQueryBackend) Search(ctx context.Context, repoName, text string, limit int) ([]controller.MethodSignatureResult, error) {
+	resp, err := b.client.SearchMethodsBySignature(ctx, &client.SearchMethodsBySignatureRequest{
+		RepoName: repoName,
+		Query:    text,
+		Limit:    limit,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !resp.Success {
+		return nil, fmt.Errorf("%s", resp.Message)
+	}
+	return resp.Results, nil
+}
+
+func (b *httpQueryBackend) FileSummary(ctx context.Context, repoName, filePath string) (*summary.CodeSummary, error) {
+	return b.client.GetFileSummary(ctx, &client.GetFileSummaryRequest{
+		RepoName: repoName,
+		FilePath: filePath,
+	})
+}
+
+func printCallGraph(out io.Writer, graph *codeapi.CallGraph) {
+	if graph == nil || len(graph.Nodes) == 0 {
+		fmt.Fprintln(out, "No callers found")
+		return
+	}
+	nodes := make([]*codeapi.CallNode, 0, len(graph.Nodes))
+	for _, node := range graph.Nodes {
+		nodes = append(nodes, node)
+	}
+	sort.Slice(nodes, func(i, j int) bool {
+		if nodes[i].Depth != nodes[j].Depth {
+			return nodes[i].Depth < nodes[j].Depth
+		}
+		return qualifiedName(nodes[i].ClassName, nodes[i].Name) < qualifiedName(nodes[j].ClassName, nodes[j].Name)
+	})
+
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "DEPTH\tFUNCTION\tFILE\tLINE")
+	for _, node := range nodes {
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%d\n", node.Depth, qualifiedName(node.ClassName, node.Name), node.FilePath, node.Range.Start.Line+1)
+	}
+	tw.Flush()
+	if graph.Truncated {
+		fmt.Fprintln(out, "(truncated)")
+	}
+}
+
+func printSearchResults(out io.Writer, results []controller.MethodSignatureResult) {
+	if len(results) == 0 {
+		fmt.Fprintln(out, "No matches")
+		return
+	}
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "SCORE\tMETHOD\tLOCATION\tSIGNATURE")
+	for _, r := range results {
+		fmt.Fprintf(tw, "%.3f\t%s\t%s:%d-%d\t%s\n", r.Score, qualifiedName(r.ClassName, r.MethodName), r.FilePath, r.StartLine, r.EndLine, r.Signature)
+	}
+	tw.Flush()
+}
+
+func printSummary(out io.Writer, s *summary.CodeSummary) {
+	fmt.Fprintf(out, "%s (%s)\n", s.FilePath, s.EntityType)
+	if s.LLMModel != "" {
+		fmt.Fprintf(out, "Generated by %s/%s on %s\n", s.LLMProvider, s.LLMModel, s.UpdatedAt.Format(time.RFC3339))
+	}
+	fmt.Fprintf(out, "\n%s\n", strings.TrimSpace(s.Summary))
+}
+
+func qualifiedName(className, name string) string {
+	if className == "" {
+		return name
+	}
+	return className + "." + name
+}
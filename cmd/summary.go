@@ -0,0 +1,451 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/armchr/codeapi/internal/config"
+	"github.com/armchr/codeapi/internal/controller"
+	"github.com/armchr/codeapi/internal/db"
+	init_services "github.com/armchr/codeapi/internal/init"
+	"github.com/armchr/codeapi/internal/model/ast"
+	"github.com/armchr/codeapi/internal/service/audit"
+	"github.com/armchr/codeapi/internal/service/summary"
+	"github.com/armchr/codeapi/internal/util"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+func newSummaryCommand(opts *cliOptions) *cobra.Command {
+	summaryCmd := &cobra.Command{
+		Use:   "summary",
+		Short: "Generate LLM code summaries",
+	}
+
+	var useHead, noProgress, dryRun bool
+	var repoFlags, levelFlags []string
+	var selection controller.SummarySelection
+	build := &cobra.Command{
+		Use:   "build [REPO...]",
+		Short: "Generate summaries for repositories whose code graph is already built",
+		Long: `Generate summaries for repositories whose code graph is already built.
+
+--level and --path regenerate only part of a repository's summaries, e.g.
+--level function --path src/billing/... for the functions under src/billing.
+--force regenerates them even when their code is unchanged. --dry-run prints
+how many stored summaries are selected and the LLM tokens they used when last
+generated, without calling the LLM.`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 && len(repoFlags) == 0 {
+				return fmt.Errorf("requires at least one repository, as an argument or with --repo")
+			}
+			return nil
+		},
+		Run: opts.run(func(cfg *config.Config, logger *zap.Logger, args []string) {
+			levels, err := controller.ParseSummaryLevels(levelFlags)
+			if err != nil {
+				logger.Fatal("Invalid --level", zap.Error(err))
+			}
+			selection.Levels = levels
+			repoNames := append(append([]string{}, repoFlags...), args...)
+			if dryRun {
+				SummaryEstimateCommand(cfg, logger, repoNames, selection)
+				return
+			}
+			SummaryBuildCommand(cfg, logger, repoNames, useHead, !noProgress, selection)
+		}),
+	}
+	build.Flags().BoolVar(&useHead, "head", false, "Summarize the committed git HEAD version instead of the working directory")
+	build.Flags().BoolVar(&noProgress, "no-progress", false, "Do not print progress while summarizing")
+	build.Flags().StringSliceVar(&repoFlags, "repo", nil, "Repository to summarize (repeatable, in addition to arguments)")
+	build.Flags().StringSliceVar(&levelFlags, "level", nil, "Only generate these levels: function, class, file, folder, project (default all)")
+	build.Flags().StringSliceVar(&selection.Paths, "path", nil, "Only generate summaries under these paths; dir/... selects a whole directory, other values are globs")
+	build.Flags().BoolVar(&selection.Force, "force", false, "Regenerate the selected summaries even when unchanged, ignoring summary.skip_if_exists")
+	build.Flags().BoolVar(&dryRun, "dry-run", false, "Estimate the LLM tokens of regenerating the selected summaries instead of generating them")
+
+	var outDir, format string
+	export := &cobra.Command{
+		Use:   "export REPO...",
+		Short: "Write the stored summaries of repositories as browsable markdown or HTML pages",
+		Args:  cobra.MinimumNArgs(1),
+		Run: opts.run(func(cfg *config.Config, logger *zap.Logger, args []string) {
+			SummaryExportCommand(cfg, logger, args, outDir, format)
+		}),
+	}
+	export.Flags().StringVarP(&outDir, "out", "o", "", "Directory to write the pages to, one subdirectory per repository (default <workdir>/summaries)")
+	export.Flags().StringVar(&format, "format", summary.ExportMarkdown, "Page format: markdown or html, or jsonl to write <out>/<repo>.jsonl for summary import")
+
+	var inPath string
+	var importDryRun bool
+	importCmd := &cobra.Command{
+		Use:   "import REPO",
+		Short: "Store the summaries of a jsonl export, matched to the code graph of this environment",
+		Args:  cobra.ExactArgs(1),
+		Run: opts.run(func(cfg *config.Config, logger *zap.Logger, args []string) {
+			SummaryImportCommand(cfg, logger, args[0], inPath, importDryRun)
+		}),
+	}
+	importCmd.Flags().StringVar(&inPath, "in", "", "File written by summary export --format jsonl")
+	importCmd.Flags().BoolVar(&importDryRun, "dry-run", false, "Report what would be imported without storing anything")
+	importCmd.MarkFlagRequired("in")
+
+	summaryCmd.AddCommand(build, export, importCmd)
+	return summaryCmd
+}
+
+// summaryFormatJSONL is the export format summary import reads
+const summaryFormatJSONL = "jsonl"
+
+// SummaryBuildCommand runs only the summary processor over each repository.
+// Summaries are built from code graph nodes, so the graph must already be
+// indexed; unchanged entities are skipped when summary.skip_if_exists is set,
+// unless the selection forces them.
+func SummaryBuildCommand(cfg *config.Config, logger *zap.Logger, repoNames []string, useHead, showProgress bool, selection controller.SummarySelection) {
+	ctx, stop := interruptibleContext()
+	defer stop()
+
+	cfg.IndexBuilding.EnableSummary = true
+	opts := init_services.ServiceInitOptions{
+		EnableDB:          true,
+		RequireDB:         true,
+		EnableCodeGraph:   true,
+		EnableRepoService: true,
+		EnableSummary:     true,
+	}
+	container, err := init_services.NewServiceContainer(cfg, opts, logger)
+	if err != nil {
+		logger.Fatal("Failed to initialize services", zap.Error(err))
+		return
+	}
+	defer container.Close(context.WithoutCancel(ctx))
+
+	if err := container.InitProcessors(cfg); err != nil {
+		logger.Fatal("Failed to initialize processors", zap.Error(err))
+		return
+	}
+	if container.SummaryProcessor == nil {
+		logger.Fatal("Summary processor unavailable: summary and neo4j must be configured")
+		return
+	}
+	container.SummaryProcessor.SetSelection(selection)
+	processors := []controller.FileProcessor{container.SummaryProcessor}
+
+	var progress *progressPrinter
+	if showProgress {
+		progress = newProgressPrinter(os.Stderr)
+	}
+	var stats []*controller.BuildStats
+
+	for _, repoName := range repoNames {
+		if ctx.Err() != nil {
+			logger.Warn("Summary build interrupted, skipping remaining repositories")
+			break
+		}
+		repo, err := cfg.GetRepository(repoName)
+		if err != nil {
+			logger.Error("Repository not found in configuration",
+				zap.String("repo_name", repoName),
+				zap.Error(err))
+			continue
+		}
+
+		fileVersionRepo, err := db.NewFileVersionRepository(container.DBConn.GetDB(), repo.Name, logger)
+		if err != nil {
+			logger.Error("Failed to create file version repository",
+				zap.String("repo_name", repo.Name),
+				zap.Error(err))
+			continue
+		}
+
+		var gitInfo *util.GitInfo
+		if useHead {
+			if gitInfo, err = util.GetGitInfo(repo.Path); err != nil || !gitInfo.IsGitRepo {
+				logger.Error("Cannot use --head: repository is not a git repository",
+					zap.String("repo_name", repo.Name),
+					zap.Error(err))
+				continue
+			}
+		}
+
+		indexBuilder := controller.NewIndexBuilder(cfg, processors, fileVersionRepo, logger)
+		// The files were marked done by the original build
+		indexBuilder.SetReprocessDone(true)
+		if progress != nil {
+			indexBuilder.SetProgressListener(progress)
+		}
+		entry := cliAudit(audit.OpSummaryBuild, repo.Name)
+		err = indexBuilder.BuildIndexWithGitInfo(ctx, repo, useHead, gitInfo)
+		if progress != nil {
+			progress.finish()
+		}
+		if s := indexBuilder.LastStats(); s != nil {
+			stats = append(stats, s)
+			entry.Details = s.AuditDetails()
+		}
+		controller.RecordAudit(ctx, container.DBConn.GetDB(), entry, err, logger)
+		if err != nil {
+			logger.Error("Failed to build summaries for repository",
+				zap.String("repo_name", repo.Name),
+				zap.Error(err))
+			continue
+		}
+		logger.Info("Completed summaries for repository", zap.String("repo_name", repo.Name))
+	}
+
+	printBuildStats(os.Stdout, stats)
+	logger.Info("Summary build command completed")
+}
+
+// summaryEstimate is the estimated cost of rebuilding the selected summaries
+// of one repository
+type summaryEstimate struct {
+	repoName string
+	levels   []controller.SummaryLevelEstimate
+}
+
+// SummaryEstimateCommand is the dry run of SummaryBuildCommand: it prints,
+// per repository and level, the stored summaries the selection covers and
+// the tokens their last generation used. Without --force, unchanged entities
+// are skipped by the real build, so this is an upper bound.
+func SummaryEstimateCommand(cfg *config.Config, logger *zap.Logger, repoNames []string, selection controller.SummarySelection) {
+	ctx := context.Background()
+
+	opts := init_services.ServiceInitOptions{
+		EnableDB:  true,
+		RequireDB: true,
+		LazyInit:  true,
+		ReadOnly:  true,
+	}
+	container, err := init_services.NewServiceContainer(cfg, opts, logger)
+	if err != nil {
+		logger.Fatal("Failed to initialize services", zap.Error(err))
+		return
+	}
+	defer container.Close(ctx)
+
+	var estimates []summaryEstimate
+	for _, repoName := range repoNames {
+		repo, err := cfg.GetRepository(repoName)
+		if err != nil {
+			logger.Error("Repository not found in configuration",
+				zap.String("repo_name", repoName),
+				zap.Error(err))
+			continue
+		}
+
+		stored, err := db.NewSummaryReader(container.DBConn.GetDB(), repo.Name, logger).WithContext(ctx).GetAllSummaries()
+		if err != nil {
+			logger.Error("Failed to read summaries",
+				zap.String("repo_name", repo.Name),
+				zap.Error(err))
+			continue
+		}
+		estimates = append(estimates, summaryEstimate{
+			repoName: repo.Name,
+			levels:   controller.EstimateSummaryCost(stored, selection),
+		})
+	}
+
+	printSummaryEstimates(os.Stdout, estimates, selection.Force)
+}
+
+func printSummaryEstimates(out io.Writer, estimates []summaryEstimate, force bool) {
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "REPOSITORY\tLEVEL\tSUMMARIES\tPROMPT TOKENS\tOUTPUT TOKENS\t")
+	for _, e := range estimates {
+		if len(e.levels) == 0 {
+			fmt.Fprintf(tw, "%s\t-\t0\t0\t0\t\n", e.repoName)
+		}
+		for _, l := range e.levels {
+			fmt.Fprintf(tw, "%s\t%s\t%d\t%d\t%d\t\n", e.repoName, l.Level, l.Summaries, l.PromptTokens, l.OutputTokens)
+		}
+	}
+	tw.Flush()
+	fmt.Fprintln(out, "Estimated from the stored summaries; entities never summarized are not counted.")
+	if !force {
+		fmt.Fprintln(out, "Without --force, summaries whose code is unchanged are skipped, so the build uses at most this much.")
+	}
+}
+
+// SummaryExportCommand renders the stored summaries of each repository into
+// <outDir>/<repo> as linked pages, for teams that browse the documentation
+// without the API. Methods are listed under their class when the code graph
+// is reachable and with the file's functions otherwise.
+func SummaryExportCommand(cfg *config.Config, logger *zap.Logger, repoNames []string, outDir, format string) {
+	ctx := context.Background()
+
+	if outDir == "" {
+		outDir = filepath.Join(cfg.App.WorkDir, "summaries")
+	}
+	opts := init_services.ServiceInitOptions{
+		EnableDB:        true,
+		RequireDB:       true,
+		EnableCodeGraph: cfg.Neo4j.URI != "",
+		LazyInit:        true,
+		ReadOnly:        true,
+	}
+	container, err := init_services.NewServiceContainer(cfg, opts, logger)
+	if err != nil {
+		logger.Fatal("Failed to initialize services", zap.Error(err))
+		return
+	}
+	defer container.Close(ctx)
+
+	for _, repoName := range repoNames {
+		repo, err := cfg.GetRepository(repoName)
+		if err != nil {
+			logger.Error("Repository not found in configuration",
+				zap.String("repo_name", repoName),
+				zap.Error(err))
+			continue
+		}
+
+		summaries, err := db.NewSummaryReader(container.DBConn.GetDB(), repo.Name, logger).WithContext(ctx).GetAllSummaries()
+		if err != nil {
+			logger.Error("Failed to read summaries",
+				zap.String("repo_name", repo.Name),
+				zap.Error(err))
+			continue
+		}
+		if len(summaries) == 0 {
+			logger.Warn("No summaries stored for repository, run summary build first", zap.String("repo_name", repo.Name))
+			continue
+		}
+
+		if format == summaryFormatJSONL {
+			path := filepath.Join(outDir, repo.Name+".jsonl")
+			if err := writeSummariesFile(path, summaries); err != nil {
+				logger.Error("Failed to export summaries",
+					zap.String("repo_name", repo.Name),
+					zap.Error(err))
+				continue
+			}
+			logger.Info("Exported summaries",
+				zap.String("repo_name", repo.Name),
+				zap.Int("summaries", len(summaries)),
+				zap.String("path", path))
+			continue
+		}
+
+		dir := filepath.Join(outDir, repo.Name)
+		pages, err := summary.Export(summaries, repo.Name, dir, summary.ExportOptions{
+			Format:        format,
+			MethodClasses: methodClasses(ctx, container, summaries, logger),
+		})
+		if err != nil {
+			logger.Error("Failed to export summaries",
+				zap.String("repo_name", repo.Name),
+				zap.Error(err))
+			continue
+		}
+		logger.Info("Exported summaries",
+			zap.String("repo_name", repo.Name),
+			zap.Int("pages", pages),
+			zap.String("path", dir))
+	}
+}
+
+// writeSummariesFile writes summaries as JSONL to path, creating its directory
+func writeSummariesFile(path string, summaries []*summary.CodeSummary) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := controller.WriteSummariesJSONL(f, summaries); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// SummaryImportCommand stores the summaries of a jsonl export for a
+// repository, keeping those whose entities exist in this environment's code
+// graph, and prints the import report
+func SummaryImportCommand(cfg *config.Config, logger *zap.Logger, repoName, inPath string, dryRun bool) {
+	ctx := context.Background()
+
+	repo, err := cfg.GetRepository(repoName)
+	if err != nil {
+		logger.Fatal("Repository not found in configuration", zap.String("repo_name", repoName), zap.Error(err))
+		return
+	}
+	f, err := os.Open(inPath)
+	if err != nil {
+		logger.Fatal("Failed to open summary export", zap.Error(err))
+		return
+	}
+	defer f.Close()
+
+	opts := init_services.ServiceInitOptions{
+		EnableDB:        true,
+		RequireDB:       true,
+		EnableCodeGraph: cfg.Neo4j.URI != "",
+		LazyInit:        true,
+		ReadOnly:        dryRun,
+	}
+	container, err := init_services.NewServiceContainer(cfg, opts, logger)
+	if err != nil {
+		logger.Fatal("Failed to initialize services", zap.Error(err))
+		return
+	}
+	defer container.Close(ctx)
+
+	if container.CodeGraph == nil {
+		logger.Warn("Code graph unavailable, function and class summaries cannot be matched and are skipped")
+	}
+	transfer := controller.NewSummaryTransfer(container.DBConn.GetDB(), container.CodeGraph, logger)
+	result, err := transfer.Import(ctx, repo.Name, f, dryRun)
+	if err != nil {
+		logger.Fatal("Summary import failed", zap.String("path", inPath), zap.Error(err))
+		return
+	}
+
+	fmt.Printf("Read %d summaries: %d imported (%d under new entity IDs), %d unresolved, %d superseded\n",
+		result.Read, result.Imported, result.Remapped, result.Unresolved, result.Superseded)
+	for _, entity := range result.UnresolvedEntities {
+		fmt.Printf("  unresolved: %s\n", entity)
+	}
+	if dryRun {
+		fmt.Println("Dry run: nothing was stored.")
+	}
+}
+
+// methodClasses maps the entity ID of each method to that of its class by
+// asking the code graph for the methods of every summarized class. It
+// returns nil when the graph is unavailable.
+func methodClasses(ctx context.Context, container *init_services.ServiceContainer, summaries []*summary.CodeSummary, logger *zap.Logger) map[string]string {
+	if container.CodeGraph == nil {
+		logger.Info("Code graph unavailable, listing methods with their file's functions")
+		return nil
+	}
+	result := make(map[string]string)
+	for _, s := range summaries {
+		if s.EntityType != summary.LevelClass {
+			continue
+		}
+		classID, err := strconv.ParseInt(s.EntityID, 10, 64)
+		if err != nil {
+			continue
+		}
+		methods, err := container.CodeGraph.GetMethodsOfClass(ctx, ast.NodeID(classID))
+		if err != nil {
+			logger.Warn("Failed to get methods of class",
+				zap.String("class", s.EntityName),
+				zap.Error(err))
+			continue
+		}
+		for _, m := range methods {
+			result[strconv.FormatInt(int64(m.ID), 10)] = s.EntityID
+		}
+	}
+	return result
+}
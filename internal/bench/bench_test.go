@@ -0,0 +1,302 @@
+package bench
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/armchr/codeapi/internal/config"
+	"github.com/armchr/codeapi/internal/controller"
+	"github.com/armchr/codeapi/internal/db"
+	"github.com/armchr/codeapi/internal/model"
+	"github.com/armchr/codeapi/internal/parse"
+	"github.com/armchr/codeapi/internal/service/codegraph"
+	"github.com/armchr/codeapi/internal/service/vector"
+
+	"go.uber.org/zap"
+)
+
+// The benchmarks run against stand-in stores so that they measure codeapi
+// rather than Neo4j, Qdrant or an embedding model. Set CODEAPI_BENCH_NEO4J_URI
+// (with CODEAPI_BENCH_NEO4J_USER and CODEAPI_BENCH_NEO4J_PASSWORD) to have
+// BenchmarkGraphWrite write to a real database instead.
+
+type fixtureFile struct {
+	path     string
+	content  []byte
+	language string
+}
+
+// loadFixture generates f and reads its files back in a stable order
+func loadFixture(b *testing.B, f Fixture) (string, []fixtureFile, int64) {
+	b.Helper()
+	dir := b.TempDir()
+	size, err := f.Generate(dir)
+	if err != nil {
+		b.Fatal(err)
+	}
+	var files []fixtureFile
+	for i := 0; i < f.Files; i++ {
+		rel, _ := f.file(f, i)
+		content, err := os.ReadFile(filepath.Join(dir, rel))
+		if err != nil {
+			b.Fatal(err)
+		}
+		files = append(files, fixtureFile{path: filepath.Join(dir, rel), content: content, language: f.Language})
+	}
+	return dir, files, size
+}
+
+func reportFiles(b *testing.B, files int) {
+	b.ReportMetric(float64(files*b.N)/b.Elapsed().Seconds(), "files/s")
+}
+
+func BenchmarkParse(b *testing.B) {
+	for _, f := range Fixtures {
+		b.Run(f.Name, func(b *testing.B) {
+			_, files, size := loadFixture(b, f)
+			fp := parse.NewFileParser(zap.NewNop(), nil, &config.Config{})
+			ctx := context.Background()
+
+			b.SetBytes(size)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for n := 0; n < b.N; n++ {
+				for i, file := range files {
+					lang := fp.DetectLanguage(file.path)
+					tree, _, err := fp.CreateTranslatorWithContent(ctx, file.path, int32(i+1), lang, 1, file.content)
+					if err != nil {
+						b.Fatal(err)
+					}
+					tree.Close()
+				}
+			}
+			reportFiles(b, len(files))
+		})
+	}
+}
+
+func BenchmarkGraphWrite(b *testing.B) {
+	cfg := &config.Config{}
+	cfg.CodeGraph.EnableBatchWrites = true
+	graphDB := codegraph.GraphDatabase(discardGraph{})
+	if uri := os.Getenv("CODEAPI_BENCH_NEO4J_URI"); uri != "" {
+		neo4jDB, err := codegraph.NewNeo4jDatabase(uri, os.Getenv("CODEAPI_BENCH_NEO4J_USER"), os.Getenv("CODEAPI_BENCH_NEO4J_PASSWORD"), cfg.Neo4j.Pool, zap.NewNop())
+		if err != nil {
+			b.Fatal(err)
+		}
+		defer neo4jDB.Close(context.Background())
+		graphDB = neo4jDB
+	}
+
+	for _, f := range Fixtures {
+		b.Run(f.Name, func(b *testing.B) {
+			dir, files, size := loadFixture(b, f)
+			repo := f.Repository(dir)
+			graph := codegraph.NewCodeGraphWithDatabase(graphDB, cfg, zap.NewNop())
+			fp := parse.NewFileParser(zap.NewNop(), graph, cfg)
+			ctx := context.Background()
+			info := fileInfo{}
+
+			b.SetBytes(size)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for n := 0; n < b.N; n++ {
+				for i, file := range files {
+					fileID := int32(n*len(files) + i + 1)
+					graph.InitializeFileBuffers(fileID)
+					if err := fp.ParseAndTraverseWithContent(ctx, repo, info, file.path, fileID, 1, file.content); err != nil {
+						b.Fatal(err)
+					}
+					if err := graph.CleanupFileBuffers(ctx, fileID); err != nil {
+						b.Fatal(err)
+					}
+				}
+			}
+			reportFiles(b, len(files))
+		})
+	}
+}
+
+func BenchmarkChunk(b *testing.B) {
+	for _, f := range Fixtures {
+		b.Run(f.Name, func(b *testing.B) {
+			_, files, size := loadFixture(b, f)
+			chunker := vector.NewCodeChunkService(nil, nil, 5, 5, 0, 1, zap.NewNop())
+			ctx := context.Background()
+
+			b.SetBytes(size)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for n := 0; n < b.N; n++ {
+				for _, file := range files {
+					if _, err := chunker.ChunkContent(ctx, file.path, file.language, file.content, vector.ChunkOptions{}); err != nil {
+						b.Fatal(err)
+					}
+				}
+			}
+			reportFiles(b, len(files))
+		})
+	}
+}
+
+// BenchmarkIndex runs full builds: walking, reading and hashing files,
+// recording file versions in SQLite, building the graph and chunking and
+// embedding. Language server post-processing is left out.
+func BenchmarkIndex(b *testing.B) {
+	for _, f := range Fixtures {
+		b.Run(f.Name, func(b *testing.B) {
+			dir, files, size := loadFixture(b, f)
+			logger := zap.NewNop()
+			cfg := &config.Config{}
+			cfg.CodeGraph.EnableBatchWrites = true
+
+			conn, err := db.NewSQLiteConnection(filepath.Join(b.TempDir(), "codeapi.db"), logger)
+			if err != nil {
+				b.Fatal(err)
+			}
+			defer conn.Close()
+			versions, err := db.NewFileVersionRepository(conn.GetDB(), f.Name, logger)
+			if err != nil {
+				b.Fatal(err)
+			}
+
+			graph := codegraph.NewCodeGraphWithDatabase(discardGraph{}, cfg, logger)
+			chunker := vector.NewCodeChunkService(discardVectors{}, constEmbedding{}, 5, 5, 0, 2, logger)
+			processors := []controller.FileProcessor{
+				filesOnly{controller.NewCodeGraphProcessor(cfg, graph, nil, logger)},
+				controller.NewEmbeddingProcessor(chunker, logger),
+			}
+			builder := controller.NewIndexBuilder(cfg, processors, versions, logger)
+			builder.SetReprocessDone(true)
+			repo := f.Repository(dir)
+
+			b.SetBytes(size)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for n := 0; n < b.N; n++ {
+				if err := builder.BuildIndex(context.Background(), repo); err != nil {
+					b.Fatal(err)
+				}
+			}
+			reportFiles(b, len(files))
+		})
+	}
+}
+
+// TestFixturesAreDeterministic guards the premise of comparing runs: the
+// same fixture must produce the same bytes every time
+func TestFixturesAreDeterministic(t *testing.T) {
+	for _, f := range Fixtures {
+		first, err := f.Generate(t.TempDir())
+		if err != nil {
+			t.Fatal(err)
+		}
+		dir := t.TempDir()
+		second, err := f.Generate(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if first != second || first == 0 {
+			t.Errorf("%s: generated %d then %d bytes", f.Name, first, second)
+		}
+
+		// Every file must parse into a graph without errors
+		graph := codegraph.NewCodeGraphWithDatabase(discardGraph{}, &config.Config{}, zap.NewNop())
+		fp := parse.NewFileParser(zap.NewNop(), graph, &config.Config{})
+		rel, content := f.file(f, f.Files-1)
+		if err := fp.ParseAndTraverseWithContent(context.Background(), f.Repository(dir), fileInfo{}, filepath.Join(dir, rel), 1, 1, []byte(content)); err != nil {
+			t.Errorf("%s: %v", f.Name, err)
+		}
+	}
+}
+
+// filesOnly runs a processor's per-file work but skips its repository
+// setup and post-processing, which need a language server
+type filesOnly struct {
+	controller.FileProcessor
+}
+
+func (filesOnly) Init(ctx context.Context, repo *config.Repository) error        { return nil }
+func (filesOnly) PostProcess(ctx context.Context, repo *config.Repository) error { return nil }
+
+// fileInfo stands in for a stat result; the parser only reads ModTime
+type fileInfo struct{}
+
+func (fileInfo) Name() string       { return "" }
+func (fileInfo) Size() int64        { return 0 }
+func (fileInfo) Mode() os.FileMode  { return 0 }
+func (fileInfo) ModTime() time.Time { return time.Time{} }
+func (fileInfo) IsDir() bool        { return false }
+func (fileInfo) Sys() interface{}   { return nil }
+
+// discardGraph accepts every write and finds nothing
+type discardGraph struct{}
+
+func (discardGraph) ExecuteRead(ctx context.Context, query string, params map[string]any) ([]map[string]any, error) {
+	return nil, nil
+}
+func (discardGraph) ExecuteWrite(ctx context.Context, query string, params map[string]any) ([]map[string]any, error) {
+	return nil, nil
+}
+func (discardGraph) ExecuteReadSingle(ctx context.Context, query string, params map[string]any) (map[string]any, error) {
+	return map[string]any{}, nil
+}
+func (discardGraph) ExecuteWriteSingle(ctx context.Context, query string, params map[string]any) (map[string]any, error) {
+	return map[string]any{}, nil
+}
+func (discardGraph) Close(ctx context.Context) error              { return nil }
+func (discardGraph) VerifyConnectivity(ctx context.Context) error { return nil }
+
+// discardVectors accepts every upsert and finds nothing
+type discardVectors struct{}
+
+func (discardVectors) CreateCollection(ctx context.Context, name string, dim int, distance vector.DistanceMetric) error {
+	return nil
+}
+func (discardVectors) DeleteCollection(ctx context.Context, name string) error { return nil }
+func (discardVectors) CollectionExists(ctx context.Context, name string) (bool, error) {
+	return true, nil
+}
+func (discardVectors) UpsertChunks(ctx context.Context, name string, chunks []*model.CodeChunk) error {
+	return nil
+}
+func (discardVectors) SearchSimilar(ctx context.Context, name string, query []float32, limit int, filter map[string]interface{}) ([]*model.CodeChunk, []float32, error) {
+	return nil, nil, nil
+}
+func (discardVectors) GetChunkByID(ctx context.Context, name, id string) (*model.CodeChunk, error) {
+	return nil, nil
+}
+func (discardVectors) DeleteChunk(ctx context.Context, name, id string) error { return nil }
+func (discardVectors) DeleteChunksByFileIDs(ctx context.Context, name string, fileIDs []int32) error {
+	return nil
+}
+func (discardVectors) GetChunksByFilePath(ctx context.Context, name, path string) ([]*model.CodeChunk, error) {
+	return nil, nil
+}
+func (discardVectors) ScrollChunks(ctx context.Context, name, offset string, limit int) ([]*model.CodeChunk, string, error) {
+	return nil, "", nil
+}
+func (discardVectors) CountChunks(ctx context.Context, name string) (uint64, error) { return 0, nil }
+func (discardVectors) Close() error                                                 { return nil }
+func (discardVectors) Health(ctx context.Context) error                             { return nil }
+
+// constEmbedding returns the same small vector for every text
+type constEmbedding struct{}
+
+const embeddingDim = 8
+
+func (constEmbedding) GenerateEmbedding(ctx context.Context, text string) ([]float32, error) {
+	return make([]float32, embeddingDim), nil
+}
+func (constEmbedding) GenerateEmbeddings(ctx context.Context, texts []string) ([][]float32, error) {
+	out := make([][]float32, len(texts))
+	for i := range out {
+		out[i] = make([]float32, embeddingDim)
+	}
+	return out, nil
+}
+func (constEmbedding) GetDimension() int    { return embeddingDim }
+func (constEmbedding) GetModelName() string { return "constant" }
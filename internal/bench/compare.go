@@ -0,0 +1,98 @@
+package bench
+
+import (
+	"bufio"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Results holds the values of each benchmark by unit, one value per run
+type Results map[string]map[string][]float64
+
+// procSuffix is the -GOMAXPROCS suffix go test appends to benchmark names
+var procSuffix = regexp.MustCompile(`-\d+$`)
+
+// ParseResults reads the output of go test -bench. Lines that are not
+// benchmark results are ignored, so the output can be piped in as is.
+func ParseResults(r io.Reader) (Results, error) {
+	results := make(Results)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		// Name, iterations, then value/unit pairs
+		if len(fields) < 4 || !strings.HasPrefix(fields[0], "Benchmark") || len(fields)%2 != 0 {
+			continue
+		}
+		if _, err := strconv.Atoi(fields[1]); err != nil {
+			continue
+		}
+		name := procSuffix.ReplaceAllString(fields[0], "")
+		for i := 2; i+1 < len(fields); i += 2 {
+			value, err := strconv.ParseFloat(fields[i], 64)
+			if err != nil {
+				continue
+			}
+			if results[name] == nil {
+				results[name] = make(map[string][]float64)
+			}
+			results[name][fields[i+1]] = append(results[name][fields[i+1]], value)
+		}
+	}
+	return results, scanner.Err()
+}
+
+// Delta is the change of one benchmark metric between two runs
+type Delta struct {
+	Benchmark string
+	Unit      string
+	Base      float64 // median of the base runs
+	Head      float64 // median of the head runs
+	Change    float64 // percent, positive when head is larger
+	Regressed bool
+}
+
+// Compare reports the change of each unit present in both runs. The units
+// must be ones where lower is better, such as ns/op or B/op; a change above
+// threshold percent is a regression. Benchmarks missing from either run are
+// not compared.
+func Compare(base, head Results, units []string, threshold float64) []Delta {
+	names := make([]string, 0, len(head))
+	for name := range head {
+		if _, ok := base[name]; ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var deltas []Delta
+	for _, name := range names {
+		for _, unit := range units {
+			b, h := base[name][unit], head[name][unit]
+			if len(b) == 0 || len(h) == 0 {
+				continue
+			}
+			d := Delta{Benchmark: name, Unit: unit, Base: median(b), Head: median(h)}
+			if d.Base > 0 {
+				d.Change = (d.Head - d.Base) / d.Base * 100
+			}
+			d.Regressed = d.Change > threshold
+			deltas = append(deltas, d)
+		}
+	}
+	return deltas
+}
+
+// median is used rather than the mean so a single noisy run does not decide
+// the outcome
+func median(values []float64) float64 {
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[mid-1] + sorted[mid]) / 2
+	}
+	return sorted[mid]
+}
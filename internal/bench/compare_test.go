@@ -0,0 +1,69 @@
+package bench
+
+import (
+	"strings"
+	"testing"
+)
+
+const baseOutput = `goos: linux
+pkg: github.com/armchr/codeapi/internal/bench
+BenchmarkParse/small-go-8     	 10	 1000 ns/op	 2.66 MB/s	 500 B/op	 10 allocs/op
+BenchmarkParse/small-go-8     	 10	 1200 ns/op	 2.66 MB/s	 500 B/op	 10 allocs/op
+BenchmarkParse/small-go-8     	 10	 1100 ns/op	 2.66 MB/s	 500 B/op	 10 allocs/op
+BenchmarkChunk/large-ts-8     	  2	 5000 ns/op	 1.00 MB/s	 900 B/op	 90 allocs/op
+BenchmarkRemoved-8            	  2	 5000 ns/op
+PASS
+ok  	github.com/armchr/codeapi/internal/bench	3.2s
+`
+
+const headOutput = `BenchmarkParse/small-go-16    	 10	 1150 ns/op	 2.50 MB/s	 500 B/op	 10 allocs/op
+BenchmarkParse/small-go-16    	 10	 9000 ns/op	 2.50 MB/s	 500 B/op	 10 allocs/op
+BenchmarkParse/small-go-16    	 10	 1100 ns/op	 2.50 MB/s	 500 B/op	 10 allocs/op
+BenchmarkChunk/large-ts-16    	  2	 5100 ns/op	 1.00 MB/s	 1200 B/op	 90 allocs/op
+BenchmarkAdded-16             	  2	 5000 ns/op
+`
+
+func TestCompare(t *testing.T) {
+	base, err := ParseResults(strings.NewReader(baseOutput))
+	if err != nil {
+		t.Fatal(err)
+	}
+	head, err := ParseResults(strings.NewReader(headOutput))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := len(base["BenchmarkParse/small-go"]["ns/op"]); got != 3 {
+		t.Fatalf("parsed %d small-go runs, want 3", got)
+	}
+
+	deltas := Compare(base, head, []string{"ns/op", "B/op"}, 10)
+	type key struct{ name, unit string }
+	got := make(map[key]Delta)
+	for _, d := range deltas {
+		got[key{d.Benchmark, d.Unit}] = d
+	}
+	if len(got) != 4 {
+		t.Fatalf("compared %d metrics, want 4: %+v", len(got), deltas)
+	}
+
+	tests := []struct {
+		name, unit string
+		regressed  bool
+	}{
+		// The 9000 ns outlier does not move the median past the threshold
+		{"BenchmarkParse/small-go", "ns/op", false},
+		{"BenchmarkParse/small-go", "B/op", false},
+		{"BenchmarkChunk/large-ts", "ns/op", false},
+		{"BenchmarkChunk/large-ts", "B/op", true},
+	}
+	for _, tt := range tests {
+		d, ok := got[key{tt.name, tt.unit}]
+		if !ok {
+			t.Errorf("%s %s not compared", tt.name, tt.unit)
+			continue
+		}
+		if d.Regressed != tt.regressed {
+			t.Errorf("%s %s: regressed = %v (%+.1f%%), want %v", tt.name, tt.unit, d.Regressed, d.Change, tt.regressed)
+		}
+	}
+}
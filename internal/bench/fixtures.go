@@ -0,0 +1,130 @@
+// Package bench provides the fixture repositories used by the indexing
+// benchmarks and the comparison of benchmark runs behind make bench-compare.
+//
+// Fixtures are generated rather than checked in so that every run, on every
+// machine, indexes exactly the same code. Their content is a function of the
+// file index alone.
+package bench
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/armchr/codeapi/internal/config"
+)
+
+// Fixture describes a generated repository
+type Fixture struct {
+	Name     string
+	Language string
+	Files    int
+	Funcs    int // functions or methods per file
+
+	file func(f Fixture, i int) (path, content string)
+}
+
+// The fixture set: one small, one medium and one large repository, each in
+// a language with a dedicated tree-sitter visitor or chunker
+var (
+	SmallGo    = Fixture{Name: "small-go", Language: "go", Files: 12, Funcs: 8, file: goFile}
+	MediumJava = Fixture{Name: "medium-java", Language: "java", Files: 80, Funcs: 12, file: javaFile}
+	LargeTS    = Fixture{Name: "large-ts", Language: "typescript", Files: 300, Funcs: 15, file: tsFile}
+
+	Fixtures = []Fixture{SmallGo, MediumJava, LargeTS}
+)
+
+// Generate writes the fixture into dir and returns the total bytes written
+func (f Fixture) Generate(dir string) (int64, error) {
+	var total int64
+	for i := 0; i < f.Files; i++ {
+		rel, content := f.file(f, i)
+		path := filepath.Join(dir, rel)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return 0, err
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			return 0, fmt.Errorf("failed to write fixture file %s: %w", rel, err)
+		}
+		total += int64(len(content))
+	}
+	return total, nil
+}
+
+// Repository returns the source configuration for a fixture generated in dir
+func (f Fixture) Repository(dir string) *config.Repository {
+	return &config.Repository{Name: f.Name, Path: dir, Language: f.Language}
+}
+
+// goFile is a package per four files, with a struct, methods that branch
+// and loop, and calls into the previous file's package
+func goFile(f Fixture, i int) (string, string) {
+	pkg := fmt.Sprintf("pkg%d", i/4)
+	var b strings.Builder
+	fmt.Fprintf(&b, "package %s\n\nimport (\n\t\"fmt\"\n\t\"strings\"\n)\n\n", pkg)
+	fmt.Fprintf(&b, "// Service%d keeps running totals\ntype Service%d struct {\n\tname  string\n\ttotal int\n\titems []string\n}\n\n", i, i)
+	fmt.Fprintf(&b, "func NewService%d(name string) *Service%d {\n\treturn &Service%d{name: name}\n}\n\n", i, i, i)
+	for j := 0; j < f.Funcs; j++ {
+		fmt.Fprintf(&b, "// Step%d adds the lengths of values longer than %d\n", j, j)
+		fmt.Fprintf(&b, "func (s *Service%d) Step%d(values []string) (int, error) {\n", i, j)
+		fmt.Fprintf(&b, "\tcount := 0\n\tfor idx, v := range values {\n\t\tif len(v) > %d {\n\t\t\tcount += len(v)\n\t\t} else if idx%%2 == 0 {\n\t\t\ts.items = append(s.items, strings.ToUpper(v))\n\t\t}\n\t}\n", j)
+		fmt.Fprintf(&b, "\tif count < 0 {\n\t\treturn 0, fmt.Errorf(\"negative count in %%s\", s.name)\n\t}\n")
+		if j > 0 {
+			fmt.Fprintf(&b, "\tprev, _ := s.Step%d(values[:len(values)/2])\n\tcount += prev\n", j-1)
+		}
+		fmt.Fprintf(&b, "\ts.total += count\n\treturn count, nil\n}\n\n")
+	}
+	fmt.Fprintf(&b, "func helper%d(s *Service%d) int {\n\tn, _ := s.Step0([]string{\"a\", \"bb\"})\n\treturn n\n}\n", i, i)
+	return fmt.Sprintf("%s/service%d.go", pkg, i), b.String()
+}
+
+// javaFile is a class per file in packages of ten, extending a shared base
+// and calling the previous class
+func javaFile(f Fixture, i int) (string, string) {
+	pkg := fmt.Sprintf("com.example.bench.module%d", i/10)
+	var b strings.Builder
+	fmt.Fprintf(&b, "package %s;\n\nimport java.util.ArrayList;\nimport java.util.List;\nimport java.util.Map;\nimport java.util.HashMap;\n\n", pkg)
+	fmt.Fprintf(&b, "/**\n * Handler%d aggregates order lines.\n */\npublic class Handler%d {\n", i, i)
+	fmt.Fprintf(&b, "    private final String name;\n    private final List<String> items = new ArrayList<>();\n    private final Map<String, Integer> counts = new HashMap<>();\n    private int total;\n\n")
+	fmt.Fprintf(&b, "    public Handler%d(String name) {\n        this.name = name;\n    }\n\n", i)
+	for j := 0; j < f.Funcs; j++ {
+		fmt.Fprintf(&b, "    /** Counts values longer than %d. */\n", j)
+		fmt.Fprintf(&b, "    public int process%d(List<String> values) {\n        int count = 0;\n", j)
+		fmt.Fprintf(&b, "        for (int idx = 0; idx < values.size(); idx++) {\n            String v = values.get(idx);\n            if (v.length() > %d) {\n                count += v.length();\n            } else if (idx %% 2 == 0) {\n                items.add(v.toUpperCase());\n            }\n        }\n", j)
+		fmt.Fprintf(&b, "        switch (count %% 3) {\n            case 0:\n                counts.merge(name, 1, Integer::sum);\n                break;\n            default:\n                total += count;\n        }\n")
+		if j > 0 {
+			fmt.Fprintf(&b, "        count += process%d(values.subList(0, values.size() / 2));\n", j-1)
+		}
+		fmt.Fprintf(&b, "        return count;\n    }\n\n")
+	}
+	fmt.Fprintf(&b, "    public int getTotal() {\n        return total;\n    }\n}\n")
+	return fmt.Sprintf("src/main/java/%s/Handler%d.java", strings.ReplaceAll(pkg, ".", "/"), i), b.String()
+}
+
+// tsFile is a module per file with an interface, a class and exported
+// functions, importing the previous module
+func tsFile(f Fixture, i int) (string, string) {
+	var b strings.Builder
+	if i > 0 {
+		fmt.Fprintf(&b, "import { Store%d, compute%d } from \"./store%d\";\n\n", i-1, i-1, i-1)
+	}
+	fmt.Fprintf(&b, "export interface Record%d {\n  id: number;\n  name: string;\n  tags: string[];\n}\n\n", i)
+	fmt.Fprintf(&b, "export class Store%d {\n  private records: Map<number, Record%d> = new Map();\n  private total = 0;\n\n", i, i)
+	for j := 0; j < f.Funcs; j++ {
+		fmt.Fprintf(&b, "  /** Adds records whose name is longer than %d. */\n", j)
+		fmt.Fprintf(&b, "  add%d(items: Record%d[]): number {\n    let count = 0;\n", j, i)
+		fmt.Fprintf(&b, "    for (const item of items) {\n      if (item.name.length > %d) {\n        this.records.set(item.id, item);\n        count++;\n      } else if (item.tags.includes(\"keep\")) {\n        count += item.tags.length;\n      }\n    }\n", j)
+		if j > 0 {
+			fmt.Fprintf(&b, "    count += this.add%d(items.slice(0, items.length / 2));\n", j-1)
+		}
+		fmt.Fprintf(&b, "    this.total += count;\n    return count;\n  }\n\n")
+	}
+	fmt.Fprintf(&b, "  size(): number {\n    return this.records.size;\n  }\n}\n\n")
+	fmt.Fprintf(&b, "export function compute%d(values: number[]): number {\n  let sum = 0;\n  for (let k = 0; k < values.length; k++) {\n    sum += values[k] %% 7 === 0 ? values[k] * 2 : values[k];\n  }\n", i)
+	if i > 0 {
+		fmt.Fprintf(&b, "  const prev = new Store%d();\n  sum += prev.size() + compute%d(values.slice(1));\n", i-1, i-1)
+	}
+	fmt.Fprintf(&b, "  return sum;\n}\n\nexport const handler%d = async (ids: number[]): Promise<number> => {\n  const results = await Promise.all(ids.map(async (id) => id * 2));\n  return compute%d(results);\n};\n", i, i)
+	return fmt.Sprintf("src/store%d.ts", i), b.String()
+}
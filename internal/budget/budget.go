@@ -0,0 +1,81 @@
+// Package budget enforces the process-wide resource budget configured under
+// resources. Subsystems that fan out work take a slot of the resource they
+// use for the duration of each unit of work, whatever their own worker
+// counts. Until Init is called every resource is unbounded.
+package budget
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/armchr/codeapi/internal/config"
+	"github.com/armchr/codeapi/internal/metrics"
+
+	"go.uber.org/zap"
+)
+
+// Budgeted resources
+const (
+	CPU       = "cpu"       // parsing, graph translation and chunking of one file
+	LLM       = "llm"       // one LLM generation call
+	Embedding = "embedding" // one embedding model call
+)
+
+type limits struct {
+	slots map[string]chan struct{}
+}
+
+var current atomic.Pointer[limits]
+
+// Init sizes the budget from cfg. Work already holding slots of a previous
+// budget releases them there.
+func Init(cfg config.ResourcesConfig, logger *zap.Logger) {
+	cfg = cfg.GetDefaults()
+	l := &limits{slots: make(map[string]chan struct{})}
+	for resource, size := range map[string]int{
+		CPU:       cfg.MaxCPUWorkers,
+		LLM:       cfg.MaxLLMRequests,
+		Embedding: cfg.MaxEmbeddingRequests,
+	} {
+		if size > 0 {
+			l.slots[resource] = make(chan struct{}, size)
+		}
+	}
+	current.Store(l)
+
+	logger.Info("Resource budget",
+		zap.Int("max_cpu_workers", cfg.MaxCPUWorkers),
+		zap.Int("max_llm_requests", cfg.MaxLLMRequests),
+		zap.Int("max_embedding_requests", cfg.MaxEmbeddingRequests))
+}
+
+// Acquire waits for a slot of resource and returns the function releasing
+// it. It fails only when ctx is done first.
+func Acquire(ctx context.Context, resource string) (release func(), err error) {
+	l := current.Load()
+	if l == nil || l.slots[resource] == nil {
+		return func() {}, nil
+	}
+	slots := l.slots[resource]
+
+	start := time.Now()
+	select {
+	case slots <- struct{}{}:
+	default:
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	metrics.ResourceAcquired(resource, time.Since(start))
+
+	var once atomic.Bool
+	return func() {
+		if once.CompareAndSwap(false, true) {
+			<-slots
+			metrics.ResourceReleased(resource)
+		}
+	}, nil
+}
@@ -0,0 +1,76 @@
+package budget
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/armchr/codeapi/internal/config"
+
+	"go.uber.org/zap"
+)
+
+func TestAcquire(t *testing.T) {
+	defer current.Store(nil)
+
+	tests := []struct {
+		name     string
+		cfg      *config.ResourcesConfig // nil: budget not initialized
+		resource string
+		slots    int // acquisitions that succeed without waiting; -1 = unbounded
+	}{
+		{"not initialized", nil, CPU, -1},
+		{"cpu", &config.ResourcesConfig{MaxCPUWorkers: 2}, CPU, 2},
+		{"llm default", &config.ResourcesConfig{}, LLM, 4},
+		{"unbounded embedding", &config.ResourcesConfig{MaxEmbeddingRequests: -1}, Embedding, -1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			current.Store(nil)
+			if tt.cfg != nil {
+				Init(*tt.cfg, zap.NewNop())
+			}
+
+			held := tt.slots
+			if held < 0 {
+				held = 10
+			}
+			var releases []func()
+			for i := 0; i < held; i++ {
+				release, err := Acquire(context.Background(), tt.resource)
+				if err != nil {
+					t.Fatalf("acquisition %d: %v", i, err)
+				}
+				releases = append(releases, release)
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+			defer cancel()
+			release, err := Acquire(ctx, tt.resource)
+			if tt.slots < 0 {
+				if err != nil {
+					t.Fatalf("unbounded resource: %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, context.DeadlineExceeded) {
+				t.Fatalf("acquiring past the budget: err = %v, want deadline exceeded", err)
+			}
+
+			// Releasing twice must not free a slot held by someone else
+			releases[0]()
+			releases[0]()
+			if release, err = Acquire(context.Background(), tt.resource); err != nil {
+				t.Fatal(err)
+			}
+			ctx, cancel = context.WithTimeout(context.Background(), 20*time.Millisecond)
+			defer cancel()
+			if _, err := Acquire(ctx, tt.resource); err == nil {
+				t.Fatal("double release freed an extra slot")
+			}
+			release()
+		})
+	}
+}
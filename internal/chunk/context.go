@@ -0,0 +1,131 @@
+package chunk
+
+import (
+	"strings"
+
+	"github.com/armchr/codeapi/internal/model"
+)
+
+// maxHeaderImports caps the imports listed in a context header
+const maxHeaderImports = 10
+
+// Import is an import statement of a file and the names it brings into
+// scope, used to pick the imports relevant to a chunk
+type Import struct {
+	Text  string   // the statement, on one line
+	Names []string // names code refers to it by; empty for wildcard imports
+}
+
+// AddContextHeaders sets the embedding header of chunks to the repository,
+// the file path and the imports each chunk refers to. Short methods say
+// little on their own about where they live and what they work with; the
+// header puts that into their embedding. File chunks list all imports.
+func AddContextHeaders(chunks []*model.CodeChunk, repoName string, imports []Import) {
+	for _, c := range chunks {
+		var header strings.Builder
+		if repoName != "" {
+			header.WriteString("Repository: " + repoName + "\n")
+		}
+		header.WriteString("File: " + c.FilePath + "\n")
+
+		var used []string
+		for _, imp := range imports {
+			if len(used) == maxHeaderImports {
+				break
+			}
+			if c.ChunkType == model.ChunkTypeFile || refersToAny(c.Content, imp.Names) {
+				used = append(used, imp.Text)
+			}
+		}
+		if len(used) > 0 {
+			header.WriteString("Imports: " + strings.Join(used, "; ") + "\n")
+		}
+		c.EmbeddingHeader = header.String()
+	}
+}
+
+// refersToAny reports whether content mentions one of names as a whole word
+func refersToAny(content string, names []string) bool {
+	for _, name := range names {
+		for i := 0; ; {
+			j := strings.Index(content[i:], name)
+			if j < 0 {
+				break
+			}
+			start, end := i+j, i+j+len(name)
+			if (start == 0 || !isASCIIWord(content[start-1])) && (end == len(content) || !isASCIIWord(content[end])) {
+				return true
+			}
+			i = end
+		}
+	}
+	return false
+}
+
+// parseImport derives the names an import statement binds from its text.
+// It does not need to be exact: a missed name only leaves the import out of
+// some headers.
+func parseImport(language, text string) Import {
+	text = strings.Join(strings.Fields(text), " ")
+	imp := Import{Text: strings.TrimSuffix(text, ";")}
+
+	switch language {
+	case "go":
+		// An import_spec: optional alias, then the quoted path
+		fields := strings.Fields(text)
+		if len(fields) == 2 {
+			if alias := fields[0]; alias != "_" && alias != "." {
+				imp.Names = []string{alias}
+			}
+			return imp
+		}
+		path := strings.Trim(text, "\"`")
+		imp.Names = []string{path[strings.LastIndexByte(path, '/')+1:]}
+	case "java":
+		name := strings.TrimSuffix(imp.Text, ";")
+		name = name[strings.LastIndexByte(name, '.')+1:]
+		if name != "*" {
+			imp.Names = []string{name}
+		}
+	case "python":
+		if after, ok := strings.CutPrefix(text, "from "); ok {
+			_, names, _ := strings.Cut(after, " import ")
+			imp.Names = boundNames(strings.Trim(names, "()"), false)
+		} else {
+			imp.Names = boundNames(strings.TrimPrefix(text, "import "), true)
+		}
+	default:
+		// JavaScript and TypeScript: the identifiers of the import clause
+		clause, _, found := strings.Cut(strings.TrimPrefix(text, "import "), " from ")
+		if !found {
+			return imp // side-effect import
+		}
+		for _, word := range strings.FieldsFunc(clause, func(r rune) bool {
+			return r >= 0x80 || !isASCIIWord(byte(r)) && r != '$'
+		}) {
+			if word != "type" && word != "as" {
+				imp.Names = append(imp.Names, word)
+			}
+		}
+	}
+	return imp
+}
+
+// boundNames returns the names a Python import list binds: the alias when
+// there is one, otherwise the name itself, or its first dotted part for
+// module imports
+func boundNames(list string, modules bool) []string {
+	var names []string
+	for _, item := range strings.Split(list, ",") {
+		item = strings.TrimSpace(item)
+		if _, alias, ok := strings.Cut(item, " as "); ok {
+			item = strings.TrimSpace(alias)
+		} else if modules {
+			item, _, _ = strings.Cut(item, ".")
+		}
+		if item != "" && item != "*" {
+			names = append(names, item)
+		}
+	}
+	return names
+}
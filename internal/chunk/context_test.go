@@ -0,0 +1,72 @@
+package chunk
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/armchr/codeapi/internal/model"
+	"github.com/armchr/codeapi/pkg/lsp/base"
+)
+
+func TestParseImport(t *testing.T) {
+	tests := []struct {
+		language string
+		text     string
+		want     []string
+	}{
+		{"go", `"net/http"`, []string{"http"}},
+		{"go", `h "net/http"`, []string{"h"}},
+		{"go", `_ "embed"`, nil},
+		{"java", "import java.util.List;", []string{"List"}},
+		{"java", "import static org.junit.Assert.assertEquals;", []string{"assertEquals"}},
+		{"java", "import java.util.*;", nil},
+		{"python", "import os.path", []string{"os"}},
+		{"python", "import numpy as np, sys", []string{"np", "sys"}},
+		{"python", "from typing import (Any,\n    Optional as Opt)", []string{"Any", "Opt"}},
+		{"typescript", "import { a, b as c } from './x';", []string{"a", "b", "c"}},
+		{"typescript", "import type { T } from './t';", []string{"T"}},
+		{"javascript", "import React, * as all from 'react';", []string{"React", "all"}},
+		{"javascript", "import './side-effect';", nil},
+	}
+	for _, tt := range tests {
+		got := parseImport(tt.language, tt.text)
+		if fmt.Sprint(got.Names) != fmt.Sprint(tt.want) {
+			t.Errorf("parseImport(%s, %q) names = %v, want %v", tt.language, tt.text, got.Names, tt.want)
+		}
+		if strings.Contains(got.Text, "\n") {
+			t.Errorf("parseImport(%s, %q) text %q spans lines", tt.language, tt.text, got.Text)
+		}
+	}
+}
+
+func TestAddContextHeaders(t *testing.T) {
+	imports := []Import{
+		{Text: `"net/http"`, Names: []string{"http"}},
+		{Text: `"strings"`, Names: []string{"strings"}},
+	}
+	file := model.NewCodeChunk("f", model.ChunkTypeFile, 1, "package api", "go", "api/server.go", base.Range{})
+	fn := model.NewCodeChunk("g", model.ChunkTypeFunction, 3,
+		"func serve(w http.ResponseWriter) { httpx.Do() }", "go", "api/server.go", base.Range{})
+
+	AddContextHeaders([]*model.CodeChunk{file, fn}, "shop", imports)
+
+	tests := []struct {
+		chunk *model.CodeChunk
+		want  string
+	}{
+		{file, "Repository: shop\nFile: api/server.go\nImports: \"net/http\"; \"strings\"\n"},
+		{fn, "Repository: shop\nFile: api/server.go\nImports: \"net/http\"\n"},
+	}
+	for _, tt := range tests {
+		if tt.chunk.EmbeddingHeader != tt.want {
+			t.Errorf("%s header = %q, want %q", tt.chunk.ChunkType, tt.chunk.EmbeddingHeader, tt.want)
+		}
+		if text := tt.chunk.GetSearchableText(true); !strings.HasPrefix(text, tt.want) {
+			t.Errorf("%s searchable text does not start with the header: %q", tt.chunk.ChunkType, text)
+		}
+		if text := tt.chunk.GetSearchableText(false); strings.Contains(text, "Repository:") {
+			t.Errorf("%s text without context has the header: %q", tt.chunk.ChunkType, text)
+		}
+	}
+}
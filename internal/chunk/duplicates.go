@@ -0,0 +1,159 @@
+package chunk
+
+import (
+	"math"
+	"sort"
+
+	"github.com/armchr/codeapi/internal/model"
+)
+
+// DefaultDuplicateThreshold is the cosine similarity above which two
+// function chunks are reported as duplicates when no threshold is given
+const DefaultDuplicateThreshold = 0.95
+
+// DefaultDuplicateMinLines is the size below which functions are too small
+// to be worth reporting as duplicates: accessors and one-line wrappers
+const DefaultDuplicateMinLines = 5
+
+// DuplicatePair is two chunks, by index, whose embeddings are at least as
+// similar as the threshold
+type DuplicatePair struct {
+	A, B       int
+	Similarity float64
+}
+
+// DuplicateCluster is a set of chunks, by index, linked by duplicate pairs.
+// Members are sorted by file and line; pairs refer to the chunks passed to
+// FindDuplicates, not to positions in Members.
+type DuplicateCluster struct {
+	Members []int
+	Pairs   []DuplicatePair
+}
+
+// Similarity is the similarity of the closest pair of the cluster
+func (c DuplicateCluster) Similarity() float64 {
+	best := 0.0
+	for _, p := range c.Pairs {
+		best = max(best, p.Similarity)
+	}
+	return best
+}
+
+// FindDuplicates clusters the function chunks whose embeddings have a cosine
+// similarity of at least threshold. Functions shorter than minLines, chunks
+// without embeddings and pairs where one function encloses the other are
+// left out. Clusters are ordered by size, then by similarity.
+func FindDuplicates(chunks []*model.CodeChunk, threshold float64, minLines int) []DuplicateCluster {
+	var candidates []int
+	unit := make([][]float64, len(chunks))
+	for i, c := range chunks {
+		if c.ChunkType != model.ChunkTypeFunction || c.EndLine-c.StartLine+1 < minLines {
+			continue
+		}
+		if unit[i] = normalize(c.Embedding); unit[i] != nil {
+			candidates = append(candidates, i)
+		}
+	}
+
+	parent := make(map[int]int)
+	var find func(int) int
+	find = func(i int) int {
+		p, ok := parent[i]
+		if !ok || p == i {
+			return i
+		}
+		root := find(p)
+		parent[i] = root
+		return root
+	}
+
+	var pairs []DuplicatePair
+	for x, i := range candidates {
+		for _, j := range candidates[x+1:] {
+			if len(unit[i]) != len(unit[j]) || overlaps(chunks[i], chunks[j]) {
+				continue
+			}
+			similarity := dot(unit[i], unit[j])
+			if similarity < threshold {
+				continue
+			}
+			pairs = append(pairs, DuplicatePair{A: i, B: j, Similarity: similarity})
+			if ri, rj := find(i), find(j); ri != rj {
+				parent[rj] = ri
+			}
+		}
+	}
+
+	byRoot := make(map[int]*DuplicateCluster)
+	var clusters []*DuplicateCluster
+	for _, p := range pairs {
+		root := find(p.A)
+		cluster, ok := byRoot[root]
+		if !ok {
+			cluster = &DuplicateCluster{}
+			byRoot[root] = cluster
+			clusters = append(clusters, cluster)
+		}
+		cluster.Pairs = append(cluster.Pairs, p)
+	}
+	for _, i := range candidates {
+		if cluster, ok := byRoot[find(i)]; ok {
+			cluster.Members = append(cluster.Members, i)
+		}
+	}
+
+	result := make([]DuplicateCluster, len(clusters))
+	for n, cluster := range clusters {
+		sort.Slice(cluster.Members, func(a, b int) bool {
+			ca, cb := chunks[cluster.Members[a]], chunks[cluster.Members[b]]
+			if ca.FilePath != cb.FilePath {
+				return ca.FilePath < cb.FilePath
+			}
+			return ca.StartLine < cb.StartLine
+		})
+		sort.Slice(cluster.Pairs, func(a, b int) bool {
+			return cluster.Pairs[a].Similarity > cluster.Pairs[b].Similarity
+		})
+		result[n] = *cluster
+	}
+	sort.SliceStable(result, func(a, b int) bool {
+		if len(result[a].Members) != len(result[b].Members) {
+			return len(result[a].Members) > len(result[b].Members)
+		}
+		return result[a].Similarity() > result[b].Similarity()
+	})
+	return result
+}
+
+// overlaps reports whether two chunks cover some of the same lines of the
+// same file, as a function and a closure declared in it do
+func overlaps(a, b *model.CodeChunk) bool {
+	return a.FileID == b.FileID && a.FilePath == b.FilePath &&
+		a.StartLine <= b.EndLine && b.StartLine <= a.EndLine
+}
+
+// normalize returns v scaled to unit length, or nil for an empty or zero
+// vector
+func normalize(v []float32) []float64 {
+	var norm float64
+	for _, x := range v {
+		norm += float64(x) * float64(x)
+	}
+	if norm == 0 {
+		return nil
+	}
+	norm = math.Sqrt(norm)
+	unit := make([]float64, len(v))
+	for i, x := range v {
+		unit[i] = float64(x) / norm
+	}
+	return unit
+}
+
+func dot(a, b []float64) float64 {
+	var sum float64
+	for i := range a {
+		sum += a[i] * b[i]
+	}
+	return sum
+}
@@ -0,0 +1,56 @@
+package chunk
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/armchr/codeapi/internal/model"
+	"github.com/armchr/codeapi/pkg/lsp/base"
+)
+
+func TestFindDuplicates(t *testing.T) {
+	newChunk := func(id, file string, chunkType model.ChunkType, start, end int, embedding ...float32) *model.CodeChunk {
+		c := model.NewCodeChunk(id, chunkType, 3, "", "go", file,
+			base.Range{Start: base.Position{Line: start}, End: base.Position{Line: end}}).WithFileID(1)
+		c.Embedding = embedding
+		return c
+	}
+	chunks := []*model.CodeChunk{
+		newChunk("a", "b.go", model.ChunkTypeFunction, 10, 30, 1, 0, 0),
+		newChunk("b", "a.go", model.ChunkTypeFunction, 1, 20, 2, 0.1, 0),
+		newChunk("c", "c.go", model.ChunkTypeFunction, 1, 20, 1, 0.2, 0),
+		// encloses a, so the two are not compared
+		newChunk("outer", "b.go", model.ChunkTypeFunction, 1, 40, 1, 0, 0),
+		newChunk("short", "d.go", model.ChunkTypeFunction, 1, 2, 1, 0, 0),
+		newChunk("loop", "d.go", model.ChunkTypeLoop, 10, 30, 1, 0, 0),
+		newChunk("other", "d.go", model.ChunkTypeFunction, 10, 30, 0, 1, 0),
+		newChunk("twin", "e.go", model.ChunkTypeFunction, 10, 30, 0, 1, 0.01),
+		newChunk("unembedded", "e.go", model.ChunkTypeFunction, 40, 60),
+	}
+
+	clusters := FindDuplicates(chunks, DefaultDuplicateThreshold, DefaultDuplicateMinLines)
+	var got [][]string
+	for _, cluster := range clusters {
+		var ids []string
+		for _, i := range cluster.Members {
+			ids = append(ids, chunks[i].ID)
+		}
+		got = append(got, ids)
+	}
+	want := [][]string{{"b", "outer", "a", "c"}, {"other", "twin"}}
+	if !slices.EqualFunc(got, want, slices.Equal[[]string]) {
+		t.Fatalf("clusters = %v, want %v", got, want)
+	}
+
+	for _, p := range clusters[0].Pairs {
+		if ids := []string{chunks[p.A].ID, chunks[p.B].ID}; slices.Contains(ids, "a") && slices.Contains(ids, "outer") {
+			t.Errorf("pair of a function and the one enclosing it: %v", ids)
+		}
+		if p.Similarity < DefaultDuplicateThreshold {
+			t.Errorf("pair %s-%s has similarity %f", chunks[p.A].ID, chunks[p.B].ID, p.Similarity)
+		}
+	}
+	if got := clusters[1].Similarity(); got < 0.99 {
+		t.Errorf("cluster similarity = %f, want the closest pair's", got)
+	}
+}
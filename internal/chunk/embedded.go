@@ -0,0 +1,239 @@
+package chunk
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/armchr/codeapi/internal/model"
+	"github.com/armchr/codeapi/internal/util"
+)
+
+// Sections of a file a region can be cut from
+const (
+	SectionScript   = "script"
+	SectionTemplate = "template"
+	SectionStyle    = "style"
+	SectionCode     = "code" // a fenced code block
+)
+
+// Region is a run of lines of a file written in another language than the
+// file itself, such as the <script> of a Vue component or a fenced code block
+// in markdown
+type Region struct {
+	Section  string
+	Language string
+	Line     int // zero-based line of the file the region starts at
+	Content  []byte
+}
+
+// container describes a file format embedding other languages. Prose formats
+// keep their line windows over the whole file, since the text between the
+// regions is worth searching too.
+type container struct {
+	regions func(content []byte) []Region
+	prose   bool
+}
+
+var containers = map[string]container{
+	"vue":      {regions: sectionRegions},
+	"svelte":   {regions: sectionRegions, prose: true},
+	"markdown": {regions: fencedRegions, prose: true},
+}
+
+// sectionDefaults are the languages of component sections without a lang
+// attribute
+var sectionDefaults = map[string]string{
+	SectionScript:   "javascript",
+	SectionTemplate: "html",
+	SectionStyle:    "css",
+}
+
+// EmbedsLanguages reports whether files of a language hold regions in other
+// languages that EmbeddedRegions can extract
+func EmbedsLanguages(language string) bool {
+	_, ok := containers[language]
+	return ok
+}
+
+// EmbeddedRegions returns the regions of a file in other languages than its
+// own: the <script>, <template> and <style> sections of Vue and Svelte
+// components, and the fenced code blocks of markdown with an info string.
+// Files of other languages have none.
+func EmbeddedRegions(language string, content []byte) []Region {
+	c, ok := containers[language]
+	if !ok {
+		return nil
+	}
+	return c.regions(content)
+}
+
+var (
+	sectionOpen = regexp.MustCompile(`(?m)^<(script|template|style)(\s[^>]*)?>[ \t]*\r?$`)
+	langAttr    = regexp.MustCompile(`\blang\s*=\s*["']?([\w+#-]+)`)
+)
+
+// sectionRegions returns the top-level sections of a single-file component.
+// A section opens with its tag alone on a line at the start of it and closes
+// at the first closing tag starting a line, so nested <template> tags, which
+// are indented, are kept inside their section.
+func sectionRegions(content []byte) []Region {
+	var regions []Region
+	for offset := 0; offset < len(content); {
+		loc := sectionOpen.FindSubmatchIndex(content[offset:])
+		if loc == nil {
+			break
+		}
+		section := string(content[offset+loc[2] : offset+loc[3]])
+		var attrs string
+		if loc[4] >= 0 {
+			attrs = string(content[offset+loc[4] : offset+loc[5]])
+		}
+		start := offset + loc[1]
+		if start < len(content) && content[start] == '\n' {
+			start++
+		}
+
+		end := len(content)
+		closing := []byte("\n</" + section + ">")
+		if i := bytes.Index(content[start-1:], closing); i >= 0 {
+			end = start - 1 + i + 1
+		}
+		offset = end + len(closing) - 1
+
+		language := sectionDefaults[section]
+		if m := langAttr.FindStringSubmatch(attrs); m != nil {
+			language = tagLanguage(m[1])
+		}
+		regions = appendRegion(regions, Region{
+			Section:  section,
+			Language: language,
+			Line:     bytes.Count(content[:start], []byte("\n")),
+			Content:  content[start:end],
+		})
+	}
+	return regions
+}
+
+var fenceOpen = regexp.MustCompile("^ {0,3}(`{3,}|~{3,})[ \t]*([^`\\s]*)")
+
+// fencedRegions returns the fenced code blocks of a markdown file that name
+// their language. A fence left open runs to the end of the file.
+func fencedRegions(content []byte) []Region {
+	lines := strings.SplitAfter(string(content), "\n")
+	var regions []Region
+	for i := 0; i < len(lines); i++ {
+		m := fenceOpen.FindStringSubmatch(lines[i])
+		if m == nil {
+			continue
+		}
+		fence, info := m[1], m[2]
+		end := len(lines)
+		for j := i + 1; j < len(lines); j++ {
+			if isFenceClose(lines[j], fence) {
+				end = j
+				break
+			}
+		}
+		if info != "" {
+			regions = appendRegion(regions, Region{
+				Section:  SectionCode,
+				Language: tagLanguage(info),
+				Line:     i + 1,
+				Content:  []byte(strings.Join(lines[i+1:end], "")),
+			})
+		}
+		i = end
+	}
+	return regions
+}
+
+// isFenceClose reports whether a line closes a code block opened by fence:
+// a run of at least as many of the same character, and nothing else
+func isFenceClose(line, fence string) bool {
+	trimmed := strings.TrimLeft(line, " ")
+	if len(line)-len(trimmed) > 3 {
+		return false
+	}
+	rest := strings.TrimLeft(trimmed, fence[:1])
+	return len(trimmed)-len(rest) >= len(fence) && strings.TrimSpace(rest) == ""
+}
+
+// tagLanguage names the language of a lang attribute or info string, keeping
+// tags it does not know, like pug or mermaid, as they are
+func tagLanguage(tag string) string {
+	if language := util.LanguageForTag(tag); language != "" {
+		return language
+	}
+	return strings.ToLower(tag)
+}
+
+// appendRegion adds a region unless it holds only whitespace
+func appendRegion(regions []Region, region Region) []Region {
+	if len(bytes.TrimSpace(region.Content)) == 0 {
+		return regions
+	}
+	return append(regions, region)
+}
+
+// EmbeddedChunks chunks a file that embeds other languages. The file gets a
+// file chunk, and line windows when it is a prose format; each region is
+// chunked by chunkRegion in its own language, and its chunks are moved under
+// a block chunk for the region, to the lines of the file they were cut from.
+// Chunks of a region carry its language and a "region" metadata naming its
+// section.
+func EmbeddedChunks(filePath, language string, content []byte, maxTokens, overlapTokens int, chunkRegion func(Region) ([]*model.CodeChunk, []Import, error)) ([]*model.CodeChunk, []Import, error) {
+	chunks := WindowChunks(filePath, language, content, maxTokens, overlapTokens)
+	if len(chunks) == 0 {
+		return nil, nil, nil
+	}
+	file := chunks[0]
+	if !containers[language].prose {
+		chunks = chunks[:1]
+		delete(file.Metadata, "windows")
+	}
+
+	regions := EmbeddedRegions(language, content)
+	file.WithMetadata("regions", len(regions))
+	var imports []Import
+	for _, region := range regions {
+		regionChunks, regionImports, err := chunkRegion(region)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to chunk %s region at line %d: %w", region.Section, region.Line+1, err)
+		}
+		imports = append(imports, regionImports...)
+		key := fmt.Sprintf("%s:region:%d", filePath, region.Line)
+		chunks = append(chunks, moveToRegion(regionChunks, file.ID, key, region)...)
+	}
+	return chunks, imports, nil
+}
+
+// moveToRegion turns the chunks of a region, cut as if it were a file of its
+// own, into chunks of the file holding it. IDs are rehashed with key so that
+// regions chunked alike do not collide, the region's file chunk becomes a
+// block under fileID, and ranges are shifted by the region's first line.
+func moveToRegion(chunks []*model.CodeChunk, fileID, key string, region Region) []*model.CodeChunk {
+	ids := make(map[string]string, len(chunks))
+	for _, c := range chunks {
+		ids[c.ID] = hashID(key + ":" + c.ID)
+	}
+	for _, c := range chunks {
+		c.ID = ids[c.ID]
+		if parent, ok := ids[c.ParentID]; ok {
+			c.ParentID = parent
+		}
+		if c.ChunkType == model.ChunkTypeFile {
+			c.ChunkType = model.ChunkTypeBlock
+			c.Level = 2
+			c.ParentID = fileID
+			c.Name = region.Section
+			delete(c.Metadata, "fallback")
+		}
+		c.Range.Start.Line += region.Line
+		c.Range.End.Line += region.Line
+		c.StartLine, c.EndLine = c.Range.Start.Line, c.Range.End.Line
+		c.WithMetadata("region", region.Section)
+	}
+	return chunks
+}
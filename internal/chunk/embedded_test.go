@@ -0,0 +1,126 @@
+package chunk
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/armchr/codeapi/internal/model"
+)
+
+const vueComponent = `<template>
+  <div>
+    <template v-if="ok">{{ msg }}</template>
+  </div>
+</template>
+
+<script lang="ts">
+export default { data: () => ({ msg: "hi" }) }
+</script>
+
+<style scoped lang="scss">
+div { color: red; }
+</style>
+`
+
+const markdownDoc = "# Usage\n\n" +
+	"```go\nfmt.Println(\"hi\")\n```\n\n" +
+	"```\nplain\n```\n\n" +
+	"~~~~ Python\nprint(1)\n~~~\n~~~~\n" +
+	"```mermaid\ngraph TD\n"
+
+func TestEmbeddedRegions(t *testing.T) {
+	type region struct {
+		Section  string
+		Language string
+		Line     int
+		Content  string
+	}
+	tests := []struct {
+		name     string
+		language string
+		content  string
+		want     []region
+	}{
+		{
+			name:     "vue",
+			language: "vue",
+			content:  vueComponent,
+			want: []region{
+				{SectionTemplate, "html", 1, "  <div>\n    <template v-if=\"ok\">{{ msg }}</template>\n  </div>\n"},
+				{SectionScript, "typescript", 7, "export default { data: () => ({ msg: \"hi\" }) }\n"},
+				{SectionStyle, "css", 11, "div { color: red; }\n"},
+			},
+		},
+		{
+			name:     "svelte without lang",
+			language: "svelte",
+			content:  "<script>\nlet n = 0\n</script>\n\n<button on:click={() => n++}>{n}</button>\n",
+			want:     []region{{SectionScript, "javascript", 1, "let n = 0\n"}},
+		},
+		{
+			name:     "markdown",
+			language: "markdown",
+			content:  markdownDoc,
+			want: []region{
+				{SectionCode, "go", 3, "fmt.Println(\"hi\")\n"},
+				{SectionCode, "python", 11, "print(1)\n~~~\n"},
+				{SectionCode, "mermaid", 15, "graph TD\n"},
+			},
+		},
+		{
+			name:     "not a container",
+			language: "go",
+			content:  "package main\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []region
+			for _, r := range EmbeddedRegions(tt.language, []byte(tt.content)) {
+				got = append(got, region{r.Section, r.Language, r.Line, string(r.Content)})
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("EmbeddedRegions = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEmbeddedChunks(t *testing.T) {
+	chunkRegion := func(r Region) ([]*model.CodeChunk, []Import, error) {
+		return WindowChunks("App.vue", r.Language, r.Content, 0, 0), nil, nil
+	}
+	chunks, _, err := EmbeddedChunks("App.vue", "vue", []byte(vueComponent), 0, 0, chunkRegion)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The file chunk, then a block and a window for each of the 3 sections
+	if len(chunks) != 7 {
+		t.Fatalf("got %d chunks, want 7", len(chunks))
+	}
+	file := chunks[0]
+	if file.ChunkType != model.ChunkTypeFile || file.Language != "vue" || file.Metadata["windows"] != nil {
+		t.Errorf("file chunk = %+v", file)
+	}
+
+	ids := map[string]bool{file.ID: true}
+	for _, c := range chunks[1:] {
+		if ids[c.ID] {
+			t.Errorf("duplicate chunk ID %s", c.ID)
+		}
+		ids[c.ID] = true
+	}
+
+	script, window := chunks[3], chunks[4]
+	if script.ChunkType != model.ChunkTypeBlock || script.Name != SectionScript || script.ParentID != file.ID {
+		t.Errorf("script block = %+v", script)
+	}
+	if window.ParentID != script.ID || window.Language != "typescript" || window.Metadata["region"] != SectionScript {
+		t.Errorf("script window = %+v", window)
+	}
+	if window.StartLine != 7 || window.EndLine != 7 {
+		t.Errorf("script window lines = %d-%d, want 7-7", window.StartLine, window.EndLine)
+	}
+}
@@ -0,0 +1,64 @@
+package chunk
+
+import (
+	"strings"
+
+	"github.com/armchr/codeapi/internal/model"
+	"github.com/armchr/codeapi/internal/util"
+	"github.com/armchr/codeapi/pkg/lsp/base"
+)
+
+// DefaultWindowTokens sizes fallback windows when token-aware splitting is
+// disabled, so that unsupported files still fit the embedding model
+const DefaultWindowTokens = 1024
+
+// FallbackLanguage is recorded on chunks of files whose language could not
+// be detected
+const FallbackLanguage = "text"
+
+// WindowChunks chunks a file that has no syntax-aware visitor: a file chunk
+// holding the start of the file, and window chunks of overlapping line runs
+// covering all of it. Every text file is made searchable this way, if less
+// precisely than with functions and classes. A maxTokens of zero or less
+// uses DefaultWindowTokens.
+func WindowChunks(filePath, language string, content []byte, maxTokens, overlapTokens int) []*model.CodeChunk {
+	if language == "" {
+		language = FallbackLanguage
+	}
+	if maxTokens <= 0 {
+		maxTokens = DefaultWindowTokens
+		overlapTokens = max(overlapTokens, 0)
+	}
+	text := string(content)
+	if strings.TrimSpace(text) == "" {
+		return nil
+	}
+
+	lines := strings.Count(text, "\n")
+	lastLine := text[strings.LastIndexByte(text, '\n')+1:]
+	fileContent := text
+	if len(content) > model.MaxChunkContentBytes {
+		fileContent = strings.TrimSuffix(string(util.TruncateAtLine(content, model.MaxChunkContentBytes)), "\n") + "\n// ... (truncated)"
+	}
+
+	// Same ID as a visitor gives the file chunk
+	file := model.NewCodeChunk(hashID(filePath+":file:0"), model.ChunkTypeFile, 1,
+		fileContent, language, filePath,
+		base.Range{End: base.Position{Line: lines, Character: len(lastLine)}}).
+		WithName(filePath).
+		WithMetadata("fallback", true)
+
+	chunks := []*model.CodeChunk{file}
+	windows := SplitWindows(text, maxTokens, min(overlapTokens, maxTokens/2))
+	for i, w := range windows {
+		window := model.NewCodeChunk(windowChunkID(file.ID, i), model.ChunkTypeWindow, 2,
+			w.Text, language, filePath, windowRange(w, 0, 0)).
+			WithParent(file.ID).
+			WithName(filePath).
+			WithMetadata("window", i).
+			WithMetadata("windows", len(windows))
+		chunks = append(chunks, window)
+	}
+	file.WithMetadata("windows", len(windows))
+	return chunks
+}
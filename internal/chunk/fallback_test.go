@@ -0,0 +1,52 @@
+package chunk
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/armchr/codeapi/internal/model"
+)
+
+func TestWindowChunks(t *testing.T) {
+	var src strings.Builder
+	for i := 0; i < 300; i++ {
+		fmt.Fprintf(&src, "key_%d: value %d\n", i, i)
+	}
+
+	chunks := WindowChunks("conf/app.yaml", "yaml", []byte(src.String()), 200, 20)
+	file, windows := chunks[0], chunks[1:]
+	if file.ChunkType != model.ChunkTypeFile || file.Level != 1 || file.Language != "yaml" {
+		t.Fatalf("unexpected file chunk %+v", file)
+	}
+	if file.EndLine != 300 || file.Metadata["windows"] != len(windows) {
+		t.Errorf("file chunk ends at line %d with %v windows, want 300 and %d", file.EndLine, file.Metadata["windows"], len(windows))
+	}
+	if len(windows) < 2 {
+		t.Fatalf("got %d windows, want the file split", len(windows))
+	}
+
+	next := 0
+	for i, w := range windows {
+		if w.ChunkType != model.ChunkTypeWindow || w.ParentID != file.ID || w.Level != 2 {
+			t.Errorf("window %d: unexpected chunk %+v", i, w)
+		}
+		if CountTokens(w.Content) > 200 {
+			t.Errorf("window %d holds %d tokens", i, CountTokens(w.Content))
+		}
+		if w.StartLine > next {
+			t.Errorf("window %d starts at line %d, lines from %d missing", i, w.StartLine, next)
+		}
+		next = w.EndLine + 1
+	}
+	if next != 300 {
+		t.Errorf("windows end before line %d, want 300", next)
+	}
+
+	if got := WindowChunks("notes", "", []byte("some notes\n"), 0, 0); len(got) != 2 || got[0].Language != FallbackLanguage {
+		t.Errorf("undetected language: got %d chunks, language %q", len(got), got[0].Language)
+	}
+	if got := WindowChunks("blank.txt", "", []byte(" \n\t\n"), 200, 20); got != nil {
+		t.Errorf("blank file: got %d chunks, want none", len(got))
+	}
+}
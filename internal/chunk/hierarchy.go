@@ -0,0 +1,51 @@
+package chunk
+
+import (
+	"sort"
+
+	"github.com/armchr/codeapi/internal/model"
+)
+
+// Hierarchy places target among the chunks stored for its file. Ancestors
+// run from the outermost chunk, usually the file, down to target's parent;
+// siblings share target's parent and are ordered by position. Chunks of
+// another version of the file are ignored. A chunk without a parent has no
+// siblings.
+func Hierarchy(target *model.CodeChunk, fileChunks []*model.CodeChunk) (ancestors, siblings []*model.CodeChunk) {
+	byID := make(map[string]*model.CodeChunk, len(fileChunks))
+	for _, c := range fileChunks {
+		if c.FileID == target.FileID {
+			byID[c.ID] = c
+		}
+	}
+
+	seen := map[string]bool{target.ID: true}
+	for parentID := target.ParentID; parentID != "" && !seen[parentID]; {
+		parent, ok := byID[parentID]
+		if !ok {
+			break
+		}
+		seen[parentID] = true
+		ancestors = append(ancestors, parent)
+		parentID = parent.ParentID
+	}
+	for i, j := 0, len(ancestors)-1; i < j; i, j = i+1, j-1 {
+		ancestors[i], ancestors[j] = ancestors[j], ancestors[i]
+	}
+
+	if target.ParentID == "" {
+		return ancestors, nil
+	}
+	for _, c := range byID {
+		if c.ParentID == target.ParentID && c.ID != target.ID {
+			siblings = append(siblings, c)
+		}
+	}
+	sort.Slice(siblings, func(i, j int) bool {
+		if siblings[i].StartLine != siblings[j].StartLine {
+			return siblings[i].StartLine < siblings[j].StartLine
+		}
+		return siblings[i].ID < siblings[j].ID
+	})
+	return ancestors, siblings
+}
@@ -0,0 +1,55 @@
+package chunk
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/armchr/codeapi/internal/model"
+	"github.com/armchr/codeapi/pkg/lsp/base"
+)
+
+func TestHierarchy(t *testing.T) {
+	newChunk := func(id, parent string, chunkType model.ChunkType, startLine int, fileID int32) *model.CodeChunk {
+		return model.NewCodeChunk(id, chunkType, 1, "", "go", "a.go",
+			base.Range{Start: base.Position{Line: startLine}}).WithParent(parent).WithFileID(fileID)
+	}
+	file := newChunk("file", "", model.ChunkTypeFile, 0, 1)
+	class := newChunk("class", "file", model.ChunkTypeClass, 2, 1)
+	second := newChunk("second", "class", model.ChunkTypeFunction, 20, 1)
+	first := newChunk("first", "class", model.ChunkTypeFunction, 5, 1)
+	loop := newChunk("loop", "first", model.ChunkTypeLoop, 7, 1)
+	window := newChunk("window", "first", model.ChunkTypeWindow, 5, 1)
+	oldVersion := newChunk("old", "class", model.ChunkTypeFunction, 9, 2)
+	chunks := []*model.CodeChunk{file, class, second, first, loop, window, oldVersion}
+
+	tests := []struct {
+		name          string
+		target        *model.CodeChunk
+		wantAncestors []string
+		wantSiblings  []string
+	}{
+		{"nested", loop, []string{"file", "class", "first"}, []string{"window"}},
+		{"method", first, []string{"file", "class"}, []string{"second"}},
+		{"file", file, nil, nil},
+		{"missing parent", newChunk("orphan", "gone", model.ChunkTypeFunction, 1, 1), nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ancestors, siblings := Hierarchy(tt.target, chunks)
+			if got := chunkIDs(ancestors); !slices.Equal(got, tt.wantAncestors) {
+				t.Errorf("ancestors = %v, want %v", got, tt.wantAncestors)
+			}
+			if got := chunkIDs(siblings); !slices.Equal(got, tt.wantSiblings) {
+				t.Errorf("siblings = %v, want %v", got, tt.wantSiblings)
+			}
+		})
+	}
+}
+
+func chunkIDs(chunks []*model.CodeChunk) []string {
+	var ids []string
+	for _, c := range chunks {
+		ids = append(ids, c.ID)
+	}
+	return ids
+}
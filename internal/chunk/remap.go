@@ -0,0 +1,171 @@
+package chunk
+
+import (
+	"hash/fnv"
+	"strings"
+)
+
+// RemapStatus says how the lines of a chunk changed between the file version
+// it was indexed from and the current one
+type RemapStatus string
+
+const (
+	RemapUnchanged RemapStatus = "unchanged" // same lines at the same place
+	RemapMoved     RemapStatus = "moved"     // same lines, shifted by edits elsewhere
+	RemapChanged   RemapStatus = "changed"   // some lines of the chunk were edited
+	RemapDeleted   RemapStatus = "deleted"   // none of the lines are left
+	RemapUnknown   RemapStatus = "unknown"   // indexed without line hashes
+)
+
+// maxRemapCells bounds the table of the line alignment in RemapRange; larger
+// chunks are placed by their best offset alone
+const maxRemapCells = 4_000_000
+
+// maxAnchorRepeats leaves lines that occur more often than this in the
+// current file, such as closing braces, out of the offset vote
+const maxAnchorRepeats = 16
+
+// LineHashes returns a short hash of every line of text. Trailing
+// whitespace is ignored so that line ending changes do not count as edits.
+func LineHashes(text string) []uint32 {
+	lines := strings.Split(text, "\n")
+	hashes := make([]uint32, len(lines))
+	for i, line := range lines {
+		hashes[i] = lineHash(line)
+	}
+	return hashes
+}
+
+// LineRange returns the hashes of lines startLine to endLine (0-based,
+// inclusive) out of the hashes of a whole file, clamped to the file
+func LineRange(fileHashes []uint32, startLine, endLine int) []uint32 {
+	startLine = max(startLine, 0)
+	endLine = min(endLine, len(fileHashes)-1)
+	if startLine > endLine {
+		return nil
+	}
+	return fileHashes[startLine : endLine+1 : endLine+1]
+}
+
+func lineHash(line string) uint32 {
+	h := fnv.New32a()
+	h.Write([]byte(strings.TrimRight(line, " \t\r")))
+	return h.Sum32()
+}
+
+// RemapRange finds the lines a chunk, given by the hashes of its lines and
+// its old start line, occupies in the current text of its file. The chunk is
+// first placed at the offset most of its distinctive lines agree on, then
+// aligned line by line with the text around that place, so that lines
+// inserted into or removed from the chunk widen or narrow the range.
+func RemapRange(hashes []uint32, oldStart int, current string) (start, end int, status RemapStatus) {
+	m := len(hashes)
+	if m == 0 {
+		return oldStart, oldStart, RemapUnknown
+	}
+	newHashes := LineHashes(current)
+	n := len(newHashes)
+	blank := lineHash("")
+
+	positions := make(map[uint32][]int)
+	for j, h := range newHashes {
+		if h != blank {
+			positions[h] = append(positions[h], j)
+		}
+	}
+	votes := make(map[int]int)
+	nonBlank := 0
+	for i, h := range hashes {
+		if h != blank {
+			nonBlank++
+		}
+		if ps := positions[h]; len(ps) <= maxAnchorRepeats {
+			for _, p := range ps {
+				votes[p-i]++
+			}
+		}
+	}
+	best, bestVotes := 0, 0
+	for offset, v := range votes {
+		if v > bestVotes || v == bestVotes && absInt(offset-oldStart) < absInt(best-oldStart) {
+			best, bestVotes = offset, v
+		}
+	}
+	// A closing brace or two left over do not make the chunk found
+	if bestVotes == 0 || bestVotes < max((nonBlank+3)/4, min(2, nonBlank)) {
+		return oldStart, oldStart + m - 1, RemapDeleted
+	}
+
+	// Lines inserted into the chunk push its end down, and its first lines
+	// below the offset voted for; the window leaves room for both without
+	// reaching far enough up to match a common first line elsewhere
+	lo, hi := max(best-m/4-4, 0), min(best+m+min(m+16, 500), n)
+	var pairs [][2]int
+	if m*(hi-lo) <= maxRemapCells {
+		pairs = alignLines(hashes, newHashes[lo:hi], lo)
+	} else {
+		for i, h := range hashes {
+			if j := best + i; j >= 0 && j < n && newHashes[j] == h {
+				pairs = append(pairs, [2]int{i, j})
+			}
+		}
+	}
+	if len(pairs) == 0 {
+		return oldStart, oldStart + m - 1, RemapDeleted
+	}
+
+	first, last := pairs[0], pairs[len(pairs)-1]
+	start = max(first[1]-first[0], 0)
+	end = max(min(last[1]+m-1-last[0], n-1), start)
+	switch {
+	case len(pairs) < m || last[1]-first[1] != m-1:
+		status = RemapChanged
+	case start == oldStart:
+		status = RemapUnchanged
+	default:
+		status = RemapMoved
+	}
+	return start, end, status
+}
+
+// alignLines returns the pairs of equal lines of a longest common
+// subsequence of old and window, with window lines numbered from offset
+func alignLines(old, window []uint32, offset int) [][2]int {
+	m, w := len(old), len(window)
+	// lcs[i*(w+1)+j] is the LCS length of old[i:] and window[j:]
+	lcs := make([]int32, (m+1)*(w+1))
+	for i := m - 1; i >= 0; i-- {
+		for j := w - 1; j >= 0; j-- {
+			switch {
+			case old[i] == window[j]:
+				lcs[i*(w+1)+j] = lcs[(i+1)*(w+1)+j+1] + 1
+			case lcs[(i+1)*(w+1)+j] >= lcs[i*(w+1)+j+1]:
+				lcs[i*(w+1)+j] = lcs[(i+1)*(w+1)+j]
+			default:
+				lcs[i*(w+1)+j] = lcs[i*(w+1)+j+1]
+			}
+		}
+	}
+
+	var pairs [][2]int
+	for i, j := 0, 0; i < m && j < w; {
+		switch {
+		case old[i] == window[j]:
+			pairs = append(pairs, [2]int{i, offset + j})
+			i++
+			j++
+		case lcs[(i+1)*(w+1)+j] >= lcs[i*(w+1)+j+1]:
+			i++
+		default:
+			j++
+		}
+	}
+	return pairs
+}
+
+func absInt(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
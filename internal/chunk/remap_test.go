@@ -0,0 +1,50 @@
+package chunk
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRemapRange(t *testing.T) {
+	old := strings.Join([]string{
+		"package p",  // 0
+		"",           // 1
+		"func A() {", // 2
+		"\ta := 1",   // 3
+		"\treturn a", // 4
+		"}",          // 5
+		"",           // 6
+		"func B() {", // 7
+		"\treturn",   // 8
+		"}",          // 9
+	}, "\n")
+	chunkA := LineRange(LineHashes(old), 2, 5)
+
+	tests := []struct {
+		name      string
+		current   string
+		wantStart int
+		wantEnd   int
+		want      RemapStatus
+	}{
+		{"same file", old, 2, 5, RemapUnchanged},
+		{"lines added above", "// header\n// more\n" + old, 4, 7, RemapMoved},
+		{"line edited", strings.Replace(old, "\ta := 1", "\ta := 2", 1), 2, 5, RemapChanged},
+		{"line inserted", strings.Replace(old, "\treturn a", "\ta++\n\treturn a", 1), 2, 6, RemapChanged},
+		{"line removed", strings.Replace(old, "\ta := 1\n", "", 1), 2, 4, RemapChanged},
+		{"trailing whitespace", strings.ReplaceAll(old, "\n", "\r\n"), 2, 5, RemapUnchanged},
+		{"function removed", "package p\n\nfunc B() {\n\treturn\n}", 2, 5, RemapDeleted},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, end, status := RemapRange(chunkA, 2, tt.current)
+			if start != tt.wantStart || end != tt.wantEnd || status != tt.want {
+				t.Errorf("RemapRange() = %d-%d %s, want %d-%d %s", start, end, status, tt.wantStart, tt.wantEnd, tt.want)
+			}
+		})
+	}
+
+	if _, _, status := RemapRange(nil, 2, old); status != RemapUnknown {
+		t.Errorf("RemapRange without hashes = %s, want %s", status, RemapUnknown)
+	}
+}
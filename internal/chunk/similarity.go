@@ -0,0 +1,158 @@
+package chunk
+
+import (
+	"sort"
+
+	"github.com/armchr/codeapi/internal/model"
+)
+
+// DefaultSimilarEntities is the number of similar classes or files returned
+// when no limit is given
+const DefaultSimilarEntities = 10
+
+// Entity is a class or a whole file, described by the mean of the
+// embeddings of its chunks
+type Entity struct {
+	Repo      string // Set by the caller
+	FilePath  string
+	FileID    int32
+	ClassName string // Empty for a file
+	StartLine int
+	EndLine   int
+	Chunks    int // Chunks averaged into the vector
+
+	vector []float64 // Unit length
+}
+
+// SimilarEntity is an entity and its cosine similarity to the query entity
+type SimilarEntity struct {
+	*Entity
+	Similarity float64
+}
+
+// aggregatedTypes are the chunks describing the code of a class or file.
+// Windows stand in for files without a syntax-aware chunker; conditionals
+// and loops repeat the text of their functions.
+var aggregatedTypes = map[model.ChunkType]bool{
+	model.ChunkTypeFile:     true,
+	model.ChunkTypeClass:    true,
+	model.ChunkTypeFunction: true,
+	model.ChunkTypeWindow:   true,
+}
+
+// AggregateFiles returns one entity per file, averaging the embeddings of
+// its file, class, function and window chunks. Only the newest version of
+// each path is kept.
+func AggregateFiles(chunks []*model.CodeChunk) []*Entity {
+	return aggregate(latestVersions(chunks), func(c *model.CodeChunk) (string, bool) {
+		return "", aggregatedTypes[c.ChunkType]
+	})
+}
+
+// AggregateClasses returns one entity per class, averaging the embeddings of
+// its class chunk and the function chunks of its methods. Only the newest
+// version of each path is kept.
+func AggregateClasses(chunks []*model.CodeChunk) []*Entity {
+	return aggregate(latestVersions(chunks), func(c *model.CodeChunk) (string, bool) {
+		switch c.ChunkType {
+		case model.ChunkTypeClass:
+			return c.Name, c.Name != ""
+		case model.ChunkTypeFunction:
+			return c.ClassName, c.ClassName != ""
+		}
+		return "", false
+	})
+}
+
+// latestVersions drops the chunks of file versions older than the newest
+// one indexed for their path
+func latestVersions(chunks []*model.CodeChunk) []*model.CodeChunk {
+	newest := make(map[string]int32)
+	for _, c := range chunks {
+		if id, ok := newest[c.FilePath]; !ok || c.FileID > id {
+			newest[c.FilePath] = c.FileID
+		}
+	}
+	kept := make([]*model.CodeChunk, 0, len(chunks))
+	for _, c := range chunks {
+		if c.FileID == newest[c.FilePath] {
+			kept = append(kept, c)
+		}
+	}
+	return kept
+}
+
+// aggregate groups chunks by file and the class named by classOf, skipping
+// chunks it rejects, and averages the unit embeddings of each group
+func aggregate(chunks []*model.CodeChunk, classOf func(*model.CodeChunk) (string, bool)) []*Entity {
+	type entityKey struct {
+		filePath  string
+		className string
+	}
+	byKey := make(map[entityKey]*Entity)
+	sums := make(map[entityKey][]float64)
+	var entities []*Entity
+	for _, c := range chunks {
+		className, ok := classOf(c)
+		if !ok {
+			continue
+		}
+		unit := normalize(c.Embedding)
+		if unit == nil {
+			continue
+		}
+		key := entityKey{c.FilePath, className}
+		e, ok := byKey[key]
+		if !ok {
+			e = &Entity{FilePath: c.FilePath, FileID: c.FileID, ClassName: className, StartLine: c.StartLine, EndLine: c.EndLine}
+			byKey[key] = e
+			sums[key] = make([]float64, len(unit))
+			entities = append(entities, e)
+		}
+		sum := sums[key]
+		if len(sum) != len(unit) {
+			continue
+		}
+		for i, x := range unit {
+			sum[i] += x
+		}
+		e.Chunks++
+		e.StartLine = min(e.StartLine, c.StartLine)
+		e.EndLine = max(e.EndLine, c.EndLine)
+	}
+
+	for key, e := range byKey {
+		sum := sums[key]
+		mean := make([]float32, len(sum))
+		for i, x := range sum {
+			mean[i] = float32(x)
+		}
+		e.vector = normalize(mean)
+	}
+	return entities
+}
+
+// RankSimilar returns up to limit candidates most similar to query, most
+// similar first, leaving out query itself, candidates below minSimilarity
+// and candidates whose embeddings have another dimension
+func RankSimilar(query *Entity, candidates []*Entity, limit int, minSimilarity float64) []SimilarEntity {
+	var results []SimilarEntity
+	for _, c := range candidates {
+		if c.vector == nil || len(c.vector) != len(query.vector) {
+			continue
+		}
+		if c.Repo == query.Repo && c.FilePath == query.FilePath && c.ClassName == query.ClassName {
+			continue
+		}
+		if similarity := dot(query.vector, c.vector); similarity >= minSimilarity {
+			results = append(results, SimilarEntity{Entity: c, Similarity: similarity})
+		}
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Similarity > results[j].Similarity
+	})
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+	return results
+}
@@ -0,0 +1,100 @@
+package chunk
+
+import (
+	"math"
+	"testing"
+
+	"github.com/armchr/codeapi/internal/model"
+	"github.com/armchr/codeapi/pkg/lsp/base"
+)
+
+func similarityChunk(file string, fileID int32, chunkType model.ChunkType, name, className string, start, end int, embedding ...float32) *model.CodeChunk {
+	return model.NewCodeChunk(file+name, chunkType, 2, "", "go", file,
+		base.Range{Start: base.Position{Line: start}, End: base.Position{Line: end}}).
+		WithFileID(fileID).WithName(name).WithContext("", className).WithEmbedding(embedding)
+}
+
+func TestAggregateClasses(t *testing.T) {
+	chunks := []*model.CodeChunk{
+		similarityChunk("order.py", 2, model.ChunkTypeClass, "Order", "", 1, 40, 1, 0),
+		similarityChunk("order.py", 2, model.ChunkTypeFunction, "total", "Order", 10, 20, 0, 1),
+		similarityChunk("order.py", 2, model.ChunkTypeFunction, "helper", "", 50, 60, 1, 1),
+		similarityChunk("order.py", 2, model.ChunkTypeLoop, "", "Order", 12, 15, 5, 5),
+		// an older version of the file
+		similarityChunk("order.py", 1, model.ChunkTypeClass, "Order", "", 1, 30, 0, 1),
+	}
+
+	entities := AggregateClasses(chunks)
+	if len(entities) != 1 {
+		t.Fatalf("got %d entities, want 1", len(entities))
+	}
+	e := entities[0]
+	if e.ClassName != "Order" || e.FileID != 2 || e.Chunks != 2 || e.StartLine != 1 || e.EndLine != 40 {
+		t.Errorf("entity = %+v", e)
+	}
+	if want := 1 / math.Sqrt2; math.Abs(e.vector[0]-want) > 1e-9 || math.Abs(e.vector[1]-want) > 1e-9 {
+		t.Errorf("vector = %v, want the normalized mean of the class and its method", e.vector)
+	}
+}
+
+func TestAggregateFiles(t *testing.T) {
+	chunks := []*model.CodeChunk{
+		similarityChunk("a.go", 1, model.ChunkTypeFile, "a.go", "", 1, 100, 1, 0),
+		similarityChunk("a.go", 1, model.ChunkTypeFunction, "Run", "", 10, 20, 1, 0),
+		similarityChunk("README.md", 3, model.ChunkTypeWindow, "", "", 1, 40, 0, 1),
+		similarityChunk("b.go", 4, model.ChunkTypeMethodSignature, "Run", "", 10, 10, 1, 1),
+		similarityChunk("c.go", 5, model.ChunkTypeFunction, "Unembedded", "", 1, 10),
+	}
+
+	entities := AggregateFiles(chunks)
+	got := make(map[string]int)
+	for _, e := range entities {
+		got[e.FilePath] = e.Chunks
+	}
+	if len(got) != 2 || got["a.go"] != 2 || got["README.md"] != 1 {
+		t.Errorf("files = %v, want a.go with 2 chunks and README.md with 1", got)
+	}
+}
+
+func TestRankSimilar(t *testing.T) {
+	entity := func(repo, file, class string, v ...float32) *Entity {
+		return &Entity{Repo: repo, FilePath: file, ClassName: class, vector: normalize(v)}
+	}
+	query := entity("shop", "order.py", "Order", 1, 0, 0)
+	candidates := []*Entity{
+		entity("shop", "order.py", "Order", 1, 0, 0), // the query itself
+		entity("billing", "invoice.py", "Invoice", 1, 0.2, 0),
+		entity("billing", "order.py", "Order", 1, 0.05, 0),
+		entity("shop", "user.py", "User", 0, 1, 0),
+		entity("legacy", "order.py", "Order", 1, 0),
+	}
+
+	tests := []struct {
+		name          string
+		limit         int
+		minSimilarity float64
+		want          []string
+	}{
+		{"all", 0, 0, []string{"billing/Order", "billing/Invoice", "shop/User"}},
+		{"limit", 1, 0, []string{"billing/Order"}},
+		{"min similarity", 0, 0.5, []string{"billing/Order", "billing/Invoice"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results := RankSimilar(query, candidates, tt.limit, tt.minSimilarity)
+			var got []string
+			for _, r := range results {
+				got = append(got, r.Repo+"/"+r.ClassName)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("got %v, want %v", got, tt.want)
+					break
+				}
+			}
+		})
+	}
+}
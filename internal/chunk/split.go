@@ -0,0 +1,164 @@
+package chunk
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/armchr/codeapi/internal/model"
+	"github.com/armchr/codeapi/pkg/lsp/base"
+)
+
+// Window is a run of lines of a text, as produced by SplitWindows
+type Window struct {
+	Text      string
+	StartLine int // first line, counted from 0 at the start of the text
+	EndLine   int // last line, inclusive
+}
+
+// SplitWindows cuts text into windows of at most maxTokens tokens each.
+// Windows end at line breaks, and every window after the first repeats the
+// last lines of the one before it, up to overlapTokens worth, so that code
+// cut at a window boundary is still seen whole by one of them. A line too
+// long for a window on its own is cut at rune boundaries into several
+// windows of that line. A maxTokens of zero or less yields a single window.
+func SplitWindows(text string, maxTokens, overlapTokens int) []Window {
+	lines := strings.SplitAfter(text, "\n")
+	if len(lines) > 1 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	if maxTokens <= 0 {
+		return []Window{{Text: text, StartLine: 0, EndLine: len(lines) - 1}}
+	}
+
+	// Word runs never span a line break, so the token count of a run of
+	// lines is the sum of the counts of the lines
+	costs := make([]int, len(lines))
+	for i, line := range lines {
+		costs[i] = CountTokens(line)
+	}
+
+	var windows []Window
+	for start := 0; start < len(lines); {
+		if costs[start] > maxTokens {
+			for rest := lines[start]; rest != ""; {
+				piece := TruncateToTokens(rest, maxTokens)
+				if piece == "" {
+					_, size := utf8.DecodeRuneInString(rest)
+					piece = rest[:size]
+				}
+				windows = append(windows, Window{Text: piece, StartLine: start, EndLine: start})
+				rest = rest[len(piece):]
+			}
+			start++
+			continue
+		}
+
+		end, tokens := start, 0
+		for end < len(lines) && tokens+costs[end] <= maxTokens {
+			tokens += costs[end]
+			end++
+		}
+		windows = append(windows, Window{
+			Text:      strings.Join(lines[start:end], ""),
+			StartLine: start,
+			EndLine:   end - 1,
+		})
+		if end == len(lines) {
+			break
+		}
+
+		next, overlap := end, 0
+		for next-1 > start && overlap+costs[next-1] <= overlapTokens {
+			next--
+			overlap += costs[next]
+		}
+		start = next
+	}
+	return windows
+}
+
+// SplitOversized returns chunks with a window chunk added after every
+// function or class chunk whose content is over maxTokens. The windows are
+// children of the oversized chunk and together cover all of its content, so
+// the embedding model, which only sees the first maxTokens of a text, never
+// silently drops the rest of a long function. The oversized chunk itself is
+// kept for the hierarchy and its nested chunks. A maxTokens of zero or less
+// disables splitting.
+func SplitOversized(chunks []*model.CodeChunk, maxTokens, overlapTokens int) []*model.CodeChunk {
+	if maxTokens <= 0 {
+		return chunks
+	}
+
+	out := make([]*model.CodeChunk, 0, len(chunks))
+	for _, c := range chunks {
+		out = append(out, c)
+		if c.ChunkType != model.ChunkTypeFunction && c.ChunkType != model.ChunkTypeClass {
+			continue
+		}
+		if CountTokens(c.Content) <= maxTokens {
+			continue
+		}
+
+		// Window embeddings are made with the module, class and signature of
+		// the chunk in front of the content; leave room for them, but never
+		// shrink windows below half the limit for an outsized signature
+		header := model.CodeChunk{ModuleName: c.ModuleName, ClassName: c.ClassName, Signature: c.Signature}
+		budget := max(maxTokens-CountTokens(header.GetSearchableText(true)), maxTokens/2)
+
+		windows := SplitWindows(c.Content, budget, min(overlapTokens, budget/2))
+		c.WithMetadata("windows", len(windows))
+		for i, w := range windows {
+			rng := windowRange(w, c.Range.Start.Line, c.Range.Start.Character)
+			window := model.NewCodeChunk(windowChunkID(c.ID, i), model.ChunkTypeWindow, c.Level+1,
+				w.Text, c.Language, c.FilePath, rng).
+				WithFileID(c.FileID).
+				WithParent(c.ID).
+				WithName(c.Name).
+				WithSignature(c.Signature).
+				WithContext(c.ModuleName, c.ClassName).
+				WithMetadata("window", i).
+				WithMetadata("windows", len(windows))
+			out = append(out, window)
+		}
+	}
+	return out
+}
+
+// windowRange returns the range of a window of text that starts at line
+// firstLine, column firstChar of the file
+func windowRange(w Window, firstLine, firstChar int) base.Range {
+	lastLine := strings.TrimSuffix(w.Text, "\n")
+	lastLine = lastLine[strings.LastIndexByte(lastLine, '\n')+1:]
+	rng := base.Range{
+		Start: base.Position{Line: firstLine + w.StartLine},
+		End:   base.Position{Line: firstLine + w.EndLine, Character: base.UTF16Len([]byte(strings.TrimSuffix(lastLine, "\r")))},
+	}
+	if w.StartLine == 0 {
+		rng.Start.Character = firstChar
+		if w.EndLine == 0 {
+			rng.End.Character += firstChar
+		}
+	}
+	return rng
+}
+
+// windowChunkID derives the ID of window i of a chunk
+func windowChunkID(parentID string, i int) string {
+	return hashID(fmt.Sprintf("%s:window:%d", parentID, i))
+}
+
+// hashID hashes input into the UUID format Qdrant requires for point IDs
+func hashID(input string) string {
+	hash := sha256.Sum256([]byte(input))
+	hashStr := hex.EncodeToString(hash[:])
+	return fmt.Sprintf("%s-%s-%s-%s-%s",
+		hashStr[0:8],
+		hashStr[8:12],
+		hashStr[12:16],
+		hashStr[16:20],
+		hashStr[20:32],
+	)
+}
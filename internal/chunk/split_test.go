@@ -0,0 +1,161 @@
+package chunk
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/armchr/codeapi/internal/model"
+	"github.com/armchr/codeapi/pkg/lsp/base"
+)
+
+func TestCountTokens(t *testing.T) {
+	tests := []struct {
+		text string
+		want int
+	}{
+		{"", 0},
+		{"   \t", 0},
+		{"x", 1},
+		{"return", 2},
+		{"a.b(c)", 6},
+		{"x := 1\n", 5},
+		{"naïve", 2},
+		{"数据", 2},
+	}
+	for _, tt := range tests {
+		if got := CountTokens(tt.text); got != tt.want {
+			t.Errorf("CountTokens(%q) = %d, want %d", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestTruncateToTokens(t *testing.T) {
+	tests := []struct {
+		text      string
+		maxTokens int
+		want      string
+	}{
+		{"a.b(c)", 0, "a.b(c)"},
+		{"a.b(c)", 10, "a.b(c)"},
+		{"a.b(c)", 3, "a.b"},
+		{"returnValue", 2, "returnVa"},
+		{"数据库", 2, "数据"},
+	}
+	for _, tt := range tests {
+		got := TruncateToTokens(tt.text, tt.maxTokens)
+		if got != tt.want {
+			t.Errorf("TruncateToTokens(%q, %d) = %q, want %q", tt.text, tt.maxTokens, got, tt.want)
+		}
+		if tt.maxTokens > 0 && CountTokens(got) > tt.maxTokens {
+			t.Errorf("TruncateToTokens(%q, %d) kept %d tokens", tt.text, tt.maxTokens, CountTokens(got))
+		}
+	}
+}
+
+func TestSplitWindows(t *testing.T) {
+	// Every line "sN();\n" is 5 tokens
+	var lines []string
+	for i := 0; i < 10; i++ {
+		lines = append(lines, fmt.Sprintf("s%d();\n", i))
+	}
+	text := strings.Join(lines, "")
+
+	tests := []struct {
+		name          string
+		text          string
+		maxTokens     int
+		overlapTokens int
+		want          [][2]int // start and end line of each window
+	}{
+		{"fits", text, 50, 10, [][2]int{{0, 9}}},
+		{"no overlap", text, 20, 0, [][2]int{{0, 3}, {4, 7}, {8, 9}}},
+		{"overlap", text, 20, 10, [][2]int{{0, 3}, {2, 5}, {4, 7}, {6, 9}}},
+		{"overlap never stalls", text, 10, 10, [][2]int{{0, 1}, {1, 2}, {2, 3}, {3, 4}, {4, 5}, {5, 6}, {6, 7}, {7, 8}, {8, 9}}},
+		{"disabled", text, 0, 0, [][2]int{{0, 9}}},
+		{"long line", "a;\n" + strings.Repeat(";", 25) + "\nb;", 10, 0, [][2]int{{0, 0}, {1, 1}, {1, 1}, {1, 1}, {2, 2}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			windows := SplitWindows(tt.text, tt.maxTokens, tt.overlapTokens)
+			var got [][2]int
+			for _, w := range windows {
+				got = append(got, [2]int{w.StartLine, w.EndLine})
+				if tt.maxTokens > 0 && CountTokens(w.Text) > tt.maxTokens {
+					t.Errorf("window %v has %d tokens, over %d", w, CountTokens(w.Text), tt.maxTokens)
+				}
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Fatalf("windows = %v, want %v", got, tt.want)
+			}
+
+			// Without overlap the windows put back together are the text
+			if tt.overlapTokens == 0 {
+				var joined strings.Builder
+				for _, w := range windows {
+					joined.WriteString(w.Text)
+				}
+				if joined.String() != tt.text {
+					t.Errorf("windows join to %q, want %q", joined.String(), tt.text)
+				}
+			}
+		})
+	}
+}
+
+func TestSplitOversized(t *testing.T) {
+	var body strings.Builder
+	body.WriteString("func big() {\n")
+	for i := 0; i < 200; i++ {
+		fmt.Fprintf(&body, "\tcall%d(x)\n", i)
+	}
+	body.WriteString("}")
+
+	rng := base.Range{Start: base.Position{Line: 10, Character: 0}, End: base.Position{Line: 211, Character: 1}}
+	big := model.NewCodeChunk("big", model.ChunkTypeFunction, 3, body.String(), "go", "a.go", rng).
+		WithParent("file").WithName("big").WithSignature("func big()").WithFileID(7)
+	small := model.NewCodeChunk("small", model.ChunkTypeFunction, 3, "func small() {}", "go", "a.go", base.Range{})
+	loop := model.NewCodeChunk("loop", model.ChunkTypeLoop, 4, body.String(), "go", "a.go", rng)
+
+	out := SplitOversized([]*model.CodeChunk{big, small, loop}, 256, 32)
+
+	if out[0] != big || out[len(out)-2] != small || out[len(out)-1] != loop {
+		t.Fatalf("original chunks not kept in order")
+	}
+	windows := out[1 : len(out)-2]
+	if len(windows) < 2 {
+		t.Fatalf("got %d windows, want the function split", len(windows))
+	}
+	if big.Metadata["windows"] != len(windows) {
+		t.Errorf("windows metadata = %v, want %d", big.Metadata["windows"], len(windows))
+	}
+
+	prevEnd := rng.Start.Line - 1
+	for i, w := range windows {
+		if w.ChunkType != model.ChunkTypeWindow || w.ParentID != "big" || w.Level != 4 || w.FileID != 7 || w.Name != "big" {
+			t.Errorf("window %d: unexpected chunk %+v", i, w)
+		}
+		if w.Metadata["window"] != i {
+			t.Errorf("window %d: window metadata = %v", i, w.Metadata["window"])
+		}
+		if tokens := CountTokens(w.GetSearchableText(true)); tokens > 256 {
+			t.Errorf("window %d: %d tokens to embed, over the limit", i, tokens)
+		}
+		if w.StartLine > prevEnd+1 {
+			t.Errorf("window %d starts at line %d, leaving a gap after %d", i, w.StartLine, prevEnd)
+		}
+		if i > 0 && w.StartLine > prevEnd {
+			t.Errorf("window %d does not overlap the previous one", i)
+		}
+		prevEnd = w.EndLine
+	}
+	if prevEnd != rng.End.Line {
+		t.Errorf("last window ends at line %d, want %d", prevEnd, rng.End.Line)
+	}
+
+	// IDs are stable across runs
+	again := SplitOversized([]*model.CodeChunk{model.NewCodeChunk("big", model.ChunkTypeFunction, 3, body.String(), "go", "a.go", rng)}, 256, 32)
+	if again[1].ID != windows[0].ID || windows[0].ID == windows[1].ID {
+		t.Errorf("window IDs not deterministic and distinct: %s, %s, %s", again[1].ID, windows[0].ID, windows[1].ID)
+	}
+}
@@ -0,0 +1,75 @@
+package chunk
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+// bytesPerWordToken is how many bytes of an identifier or number one token
+// covers. Subword tokenizers split long identifiers into pieces of three to
+// five characters, so four errs on the side of counting too many.
+const bytesPerWordToken = 4
+
+// CountTokens estimates the number of embedding model tokens in text. It
+// mimics a subword tokenizer on source code: every run of letters and digits
+// costs one token per bytesPerWordToken bytes, every punctuation or symbol
+// character costs one, and whitespace costs one per line break. The estimate
+// is meant to stay above what BPE and WordPiece tokenizers produce, so that
+// text under the limit is never cut by the model.
+func CountTokens(text string) int {
+	tokens, _ := scanTokens(text, -1)
+	return tokens
+}
+
+// TruncateToTokens returns the longest prefix of text whose CountTokens is
+// at most maxTokens, cut at a rune boundary. A limit of zero or less
+// returns text unchanged.
+func TruncateToTokens(text string, maxTokens int) string {
+	if maxTokens <= 0 {
+		return text
+	}
+	_, end := scanTokens(text, maxTokens)
+	return text[:end]
+}
+
+// scanTokens counts the tokens of text up to the first rune that would take
+// the count past limit, and returns the count and the byte offset of that
+// rune. A negative limit scans all of text.
+func scanTokens(text string, limit int) (tokens, end int) {
+	word := 0 // bytes of the current letter/digit run
+	for i := 0; i < len(text); {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		cost := 0
+		switch {
+		case r < utf8.RuneSelf && isASCIIWord(byte(r)),
+			r >= utf8.RuneSelf && unicode.IsLetter(r) && !unicode.Is(unicode.Han, r):
+			// Non-ASCII letters are counted by bytes too; alphabets other
+			// than Latin tokenize into shorter pieces
+			cost = wordTokens(word+size) - wordTokens(word)
+			word += size
+		case r == '\n':
+			word = 0
+			cost = 1
+		case unicode.IsSpace(r):
+			word = 0
+		default:
+			// Punctuation, symbols and CJK characters
+			word = 0
+			cost = 1
+		}
+		if limit >= 0 && tokens+cost > limit {
+			return tokens, i
+		}
+		tokens += cost
+		i += size
+	}
+	return tokens, len(text)
+}
+
+func wordTokens(bytes int) int {
+	return (bytes + bytesPerWordToken - 1) / bytesPerWordToken
+}
+
+func isASCIIWord(b byte) bool {
+	return b == '_' || '0' <= b && b <= '9' || 'a' <= b && b <= 'z' || 'A' <= b && b <= 'Z'
+}
@@ -2,11 +2,11 @@ package chunk
 
 import (
 	"github.com/armchr/codeapi/internal/model"
+	"github.com/armchr/codeapi/internal/util"
 	"github.com/armchr/codeapi/pkg/lsp/base"
 	"context"
-	"crypto/sha256"
-	"encoding/hex"
 	"fmt"
+	"slices"
 	"strings"
 
 	tree_sitter "github.com/tree-sitter/go-tree-sitter"
@@ -24,8 +24,11 @@ type ChunkVisitor struct {
 	currentFile         *model.CodeChunk
 	currentClass        *model.CodeChunk
 	moduleName          string
+	imports             []Import
 	minConditionalLines int
 	minLoopLines        int
+	chunkTypes          []model.ChunkType
+	visiting            *tree_sitter.Node // innermost node entered through traverseChildren
 }
 
 // NewChunkVisitor creates a new chunk visitor
@@ -43,7 +46,65 @@ func NewChunkVisitor(logger *zap.Logger, language, filePath string, sourceCode [
 
 // GetChunks returns all collected code chunks
 func (cv *ChunkVisitor) GetChunks() []*model.CodeChunk {
-	return cv.chunks
+	if len(cv.chunkTypes) == 0 {
+		return cv.chunks
+	}
+
+	byID := make(map[string]*model.CodeChunk, len(cv.chunks))
+	for _, c := range cv.chunks {
+		byID[c.ID] = c
+	}
+	kept := make([]*model.CodeChunk, 0, len(cv.chunks))
+	for _, c := range cv.chunks {
+		if !slices.Contains(cv.chunkTypes, c.ChunkType) {
+			continue
+		}
+		parentID := c.ParentID
+		for parentID != "" {
+			parent, ok := byID[parentID]
+			if !ok || slices.Contains(cv.chunkTypes, parent.ChunkType) {
+				break
+			}
+			parentID = parent.ParentID
+		}
+		c.ParentID = parentID
+		kept = append(kept, c)
+	}
+	return kept
+}
+
+// SetChunkTypes limits the chunks returned by GetChunks to the given types.
+// A chunk whose parent is left out is attached to its nearest kept ancestor.
+// Nil returns every chunk.
+func (cv *ChunkVisitor) SetChunkTypes(types []model.ChunkType) {
+	cv.chunkTypes = types
+}
+
+// GetImports returns the import statements of the file
+func (cv *ChunkVisitor) GetImports() []Import {
+	return cv.imports
+}
+
+// Traverse chunks the syntax tree of the file from its root. A panic while
+// visiting is logged and returned as a *util.TraversalPanic naming the node
+// being visited; the chunks collected until then are kept.
+func (cv *ChunkVisitor) Traverse(ctx context.Context, root *tree_sitter.Node) (err error) {
+	cv.visiting = root
+	defer func() {
+		if r := recover(); r != nil {
+			p := util.NewTraversalPanic(cv.filePath, cv.visiting, r)
+			cv.logger.Error("Recovered from panic while chunking syntax tree",
+				zap.String("path", cv.filePath),
+				zap.String("kind", p.Kind),
+				zap.Uint("line", p.Line),
+				zap.Uint("column", p.Column),
+				zap.Any("panic", r),
+				zap.Stack("stack"))
+			err = p
+		}
+	}()
+	cv.TraverseNode(ctx, root, nil)
+	return nil
 }
 
 // TraverseNode is the main entry point for traversing syntax tree nodes
@@ -88,6 +149,8 @@ func (cv *ChunkVisitor) traverseGoNode(ctx context.Context, tsNode *tree_sitter.
 		return cv.handleSourceFile(ctx, tsNode)
 	case "package_clause":
 		cv.extractPackageName(tsNode)
+	case "import_spec":
+		cv.addImport(tsNode)
 	case "function_declaration":
 		return cv.handleFunctionDeclaration(ctx, tsNode, false)
 	case "method_declaration":
@@ -111,6 +174,8 @@ func (cv *ChunkVisitor) traversePythonNode(ctx context.Context, tsNode *tree_sit
 	switch kind {
 	case "module":
 		return cv.handleSourceFile(ctx, tsNode)
+	case "import_statement", "import_from_statement":
+		cv.addImport(tsNode)
 	case "class_definition":
 		return cv.handleClassDefinition(ctx, tsNode)
 	case "function_definition":
@@ -136,6 +201,8 @@ func (cv *ChunkVisitor) traverseJavaNode(ctx context.Context, tsNode *tree_sitte
 		return cv.handleSourceFile(ctx, tsNode)
 	case "package_declaration":
 		cv.extractJavaPackageName(tsNode)
+	case "import_declaration":
+		cv.addImport(tsNode)
 	case "class_declaration", "interface_declaration":
 		return cv.handleJavaClass(ctx, tsNode)
 	case "method_declaration":
@@ -161,6 +228,8 @@ func (cv *ChunkVisitor) traverseJavaScriptNode(ctx context.Context, tsNode *tree
 	switch kind {
 	case "program":
 		return cv.handleSourceFile(ctx, tsNode)
+	case "import_statement":
+		cv.addImport(tsNode)
 	case "class_declaration":
 		return cv.handleJSClass(ctx, tsNode)
 	case "function_declaration":
@@ -185,7 +254,7 @@ func (cv *ChunkVisitor) traverseJavaScriptNode(ctx context.Context, tsNode *tree
 
 // handleSourceFile creates a file-level chunk
 func (cv *ChunkVisitor) handleSourceFile(ctx context.Context, tsNode *tree_sitter.Node) any {
-	content := cv.getNodeText(tsNode)
+	content := cv.getChunkContent(tsNode)
 	rng := cv.toRange(tsNode)
 
 	chunkID := cv.generateChunkID(cv.filePath, "file", 0)
@@ -215,9 +284,8 @@ func (cv *ChunkVisitor) handleFunctionDeclaration(ctx context.Context, tsNode *t
 	}
 
 	name := cv.getNodeText(nameNode)
-	content := cv.getNodeText(tsNode)
+	content, docstring := cv.getDocumentedContent(tsNode)
 	signature := cv.extractGoFunctionSignature(tsNode)
-	docstring := cv.extractGoDocstring(tsNode)
 
 	chunkID := cv.generateChunkID(cv.filePath, name, tsNode.StartPosition().Row)
 
@@ -260,8 +328,11 @@ func (cv *ChunkVisitor) handleClassDefinition(ctx context.Context, tsNode *tree_
 	}
 
 	name := cv.getNodeText(nameNode)
-	content := cv.getNodeText(tsNode)
+	content, comment := cv.getDocumentedContent(tsNode)
 	docstring := cv.extractPythonDocstring(tsNode)
+	if docstring == "" {
+		docstring = comment
+	}
 
 	chunkID := cv.generateChunkID(cv.filePath, name, tsNode.StartPosition().Row)
 
@@ -301,9 +372,12 @@ func (cv *ChunkVisitor) handlePythonFunction(ctx context.Context, tsNode *tree_s
 	}
 
 	name := cv.getNodeText(nameNode)
-	content := cv.getNodeText(tsNode)
+	content, comment := cv.getDocumentedContent(tsNode)
 	signature := cv.extractPythonFunctionSignature(tsNode)
 	docstring := cv.extractPythonDocstring(tsNode)
+	if docstring == "" {
+		docstring = comment
+	}
 
 	chunkID := cv.generateChunkID(cv.filePath, name, tsNode.StartPosition().Row)
 
@@ -346,7 +420,7 @@ func (cv *ChunkVisitor) handleJavaClass(ctx context.Context, tsNode *tree_sitter
 	}
 
 	name := cv.getNodeText(nameNode)
-	content := cv.getNodeText(tsNode)
+	content, docstring := cv.getDocumentedContent(tsNode)
 
 	chunkID := cv.generateChunkID(cv.filePath, name, tsNode.StartPosition().Row)
 
@@ -365,6 +439,7 @@ func (cv *ChunkVisitor) handleJavaClass(ctx context.Context, tsNode *tree_sitter
 		cv.toRange(tsNode),
 	).WithParent(parentID).
 		WithName(name).
+		WithDocstring(docstring).
 		WithContext(cv.moduleName, "")
 
 	oldClass := cv.currentClass
@@ -385,7 +460,7 @@ func (cv *ChunkVisitor) handleJavaMethod(ctx context.Context, tsNode *tree_sitte
 	}
 
 	name := cv.getNodeText(nameNode)
-	content := cv.getNodeText(tsNode)
+	content, docstring := cv.getDocumentedContent(tsNode)
 	signature := cv.extractJavaMethodSignature(tsNode)
 
 	chunkID := cv.generateChunkID(cv.filePath, name, tsNode.StartPosition().Row)
@@ -410,6 +485,7 @@ func (cv *ChunkVisitor) handleJavaMethod(ctx context.Context, tsNode *tree_sitte
 	).WithParent(parentID).
 		WithName(name).
 		WithSignature(signature).
+		WithDocstring(docstring).
 		WithContext(cv.moduleName, className)
 
 	cv.chunks = append(cv.chunks, chunk)
@@ -428,7 +504,7 @@ func (cv *ChunkVisitor) handleJSClass(ctx context.Context, tsNode *tree_sitter.N
 	}
 
 	name := cv.getNodeText(nameNode)
-	content := cv.getNodeText(tsNode)
+	content, docstring := cv.getDocumentedContent(tsNode)
 
 	chunkID := cv.generateChunkID(cv.filePath, name, tsNode.StartPosition().Row)
 
@@ -447,6 +523,7 @@ func (cv *ChunkVisitor) handleJSClass(ctx context.Context, tsNode *tree_sitter.N
 		cv.toRange(tsNode),
 	).WithParent(parentID).
 		WithName(name).
+		WithDocstring(docstring).
 		WithContext(cv.moduleName, "")
 
 	oldClass := cv.currentClass
@@ -467,7 +544,7 @@ func (cv *ChunkVisitor) handleJSFunction(ctx context.Context, tsNode *tree_sitte
 	}
 
 	name := cv.getNodeText(nameNode)
-	content := cv.getNodeText(tsNode)
+	content, docstring := cv.getDocumentedContent(tsNode)
 	signature := cv.extractJSFunctionSignature(tsNode)
 
 	chunkID := cv.generateChunkID(cv.filePath, name, tsNode.StartPosition().Row)
@@ -488,6 +565,7 @@ func (cv *ChunkVisitor) handleJSFunction(ctx context.Context, tsNode *tree_sitte
 	).WithParent(parentID).
 		WithName(name).
 		WithSignature(signature).
+		WithDocstring(docstring).
 		WithContext(cv.moduleName, "")
 
 	cv.chunks = append(cv.chunks, chunk)
@@ -506,7 +584,7 @@ func (cv *ChunkVisitor) handleJSMethod(ctx context.Context, tsNode *tree_sitter.
 	}
 
 	name := cv.getNodeText(nameNode)
-	content := cv.getNodeText(tsNode)
+	content, docstring := cv.getDocumentedContent(tsNode)
 	signature := cv.extractJSFunctionSignature(tsNode)
 
 	chunkID := cv.generateChunkID(cv.filePath, name, tsNode.StartPosition().Row)
@@ -531,6 +609,7 @@ func (cv *ChunkVisitor) handleJSMethod(ctx context.Context, tsNode *tree_sitter.
 	).WithParent(parentID).
 		WithName(name).
 		WithSignature(signature).
+		WithDocstring(docstring).
 		WithContext(cv.moduleName, className)
 
 	cv.chunks = append(cv.chunks, chunk)
@@ -561,7 +640,7 @@ func (cv *ChunkVisitor) handleTypeDeclaration(ctx context.Context, tsNode *tree_
 // handleGoTypeSpec handles Go struct/interface type specifications
 func (cv *ChunkVisitor) handleGoTypeSpec(ctx context.Context, tsNode, nameNode, typeNode *tree_sitter.Node) {
 	name := cv.getNodeText(nameNode)
-	content := cv.getNodeText(tsNode)
+	content, docstring := cv.getDocumentedContent(tsNode)
 
 	chunkID := cv.generateChunkID(cv.filePath, name, tsNode.StartPosition().Row)
 
@@ -580,6 +659,7 @@ func (cv *ChunkVisitor) handleGoTypeSpec(ctx context.Context, tsNode, nameNode,
 		cv.toRange(tsNode),
 	).WithParent(parentID).
 		WithName(name).
+		WithDocstring(docstring).
 		WithContext(cv.moduleName, "")
 
 	cv.chunks = append(cv.chunks, chunk)
@@ -590,11 +670,17 @@ func (cv *ChunkVisitor) handleGoTypeSpec(ctx context.Context, tsNode, nameNode,
 func (cv *ChunkVisitor) traverseChildren(ctx context.Context, tsNode *tree_sitter.Node) {
 	for i := uint(0); i < tsNode.ChildCount(); i++ {
 		child := tsNode.Child(i)
+		parent := cv.visiting
+		cv.visiting = child
 		cv.TraverseNode(ctx, child, nil)
+		cv.visiting = parent
 	}
 }
 
 func (cv *ChunkVisitor) getChildByFieldName(tsNode *tree_sitter.Node, fieldName string) *tree_sitter.Node {
+	if tsNode == nil {
+		return nil
+	}
 	return tsNode.ChildByFieldName(fieldName)
 }
 
@@ -605,38 +691,130 @@ func (cv *ChunkVisitor) getNodeText(tsNode *tree_sitter.Node) string {
 	if endByte > uint(len(cv.sourceCode)) {
 		endByte = uint(len(cv.sourceCode))
 	}
+	if startByte > endByte {
+		return ""
+	}
 
 	return string(cv.sourceCode[startByte:endByte])
 }
 
+// getChunkContent returns the text of a chunk, cut at the last line that
+// fits in model.MaxChunkContentBytes. Only the kept part is copied out of
+// the source.
+func (cv *ChunkVisitor) getChunkContent(tsNode *tree_sitter.Node) string {
+	return cv.getContentFrom(tsNode.StartByte(), tsNode)
+}
+
+// getContentFrom is getChunkContent for text starting at startByte, before
+// the node, rather than at the node itself
+func (cv *ChunkVisitor) getContentFrom(startByte uint, tsNode *tree_sitter.Node) string {
+	endByte := min(tsNode.EndByte(), uint(len(cv.sourceCode)))
+	if endByte-startByte <= model.MaxChunkContentBytes {
+		return string(cv.sourceCode[startByte:endByte])
+	}
+
+	text := util.TruncateAtLine(cv.sourceCode[startByte:endByte], model.MaxChunkContentBytes)
+	return strings.TrimSuffix(string(text), "\n") + "\n// ... (truncated)"
+}
+
+// getDocumentedContent returns the chunk content of a declaration together
+// with the comment block above it, and that comment with the comment markers
+// stripped. The chunk range still starts at the declaration itself.
+func (cv *ChunkVisitor) getDocumentedContent(tsNode *tree_sitter.Node) (content, doc string) {
+	first, last := cv.leadingComments(tsNode)
+	if first == nil {
+		return cv.getChunkContent(tsNode), ""
+	}
+	comments := string(cv.sourceCode[first.StartByte():last.EndByte()])
+	return cv.getContentFrom(first.StartByte(), tsNode), cleanComment(comments)
+}
+
+// leadingComments returns the first and last of the comments directly above
+// a declaration, or nils if there are none. Comments count when no blank line
+// separates them from each other or from the declaration, and when they do
+// not trail code on the same line. Export statements, decorators and Go type
+// keywords wrapping the declaration are looked through.
+func (cv *ChunkVisitor) leadingComments(tsNode *tree_sitter.Node) (first, last *tree_sitter.Node) {
+	anchor := cv.commentAnchor(tsNode)
+
+	line := anchor.StartPosition().Row
+	for prev := anchor.PrevSibling(); prev != nil && isComment(prev.Kind()); prev = prev.PrevSibling() {
+		if prev.EndPosition().Row+1 < line {
+			break
+		}
+		if before := prev.PrevSibling(); before != nil && !isComment(before.Kind()) &&
+			before.EndPosition().Row == prev.StartPosition().Row {
+			break
+		}
+		if last == nil {
+			last = prev
+		}
+		first = prev
+		line = prev.StartPosition().Row
+	}
+	return first, last
+}
+
+func (cv *ChunkVisitor) commentAnchor(tsNode *tree_sitter.Node) *tree_sitter.Node {
+	anchor := tsNode
+	for parent := anchor.Parent(); parent != nil; parent = anchor.Parent() {
+		switch parent.Kind() {
+		case "export_statement", "decorated_definition":
+		case "type_declaration":
+			// Only "type X ..." on one line; in a group the comments of a
+			// type are siblings of its type_spec
+			if parent.StartPosition().Row != anchor.StartPosition().Row {
+				return anchor
+			}
+		default:
+			return anchor
+		}
+		anchor = parent
+	}
+	return anchor
+}
+
+func isComment(kind string) bool {
+	return kind == "comment" || kind == "line_comment" || kind == "block_comment"
+}
+
+// cleanComment strips comment markers (//, /* */, /** */, leading * and #)
+// from the lines of a comment block
+func cleanComment(text string) string {
+	lines := strings.Split(text, "\n")
+	cleaned := make([]string, 0, len(lines))
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		line = strings.TrimSpace(strings.TrimSuffix(line, "*/"))
+		switch {
+		case strings.HasPrefix(line, "///"):
+			line = line[3:]
+		case strings.HasPrefix(line, "//"), strings.HasPrefix(line, "/*"):
+			line = strings.TrimLeft(line[2:], "*")
+		case strings.HasPrefix(line, "*"), strings.HasPrefix(line, "#"):
+			line = line[1:]
+		}
+		cleaned = append(cleaned, strings.TrimSpace(line))
+	}
+	return strings.TrimSpace(strings.Join(cleaned, "\n"))
+}
+
+// toRange returns the range of tsNode with columns in UTF-16 units, as the
+// code graph and language servers count them
 func (cv *ChunkVisitor) toRange(tsNode *tree_sitter.Node) base.Range {
 	return base.Range{
-		Start: base.Position{
-			Line:      int(tsNode.StartPosition().Row),
-			Character: int(tsNode.StartPosition().Column),
-		},
-		End: base.Position{
-			Line:      int(tsNode.EndPosition().Row),
-			Character: int(tsNode.EndPosition().Column),
-		},
+		Start: base.PositionAt(cv.sourceCode, int(tsNode.StartByte()), int(tsNode.StartPosition().Row), int(tsNode.StartPosition().Column)),
+		End:   base.PositionAt(cv.sourceCode, int(tsNode.EndByte()), int(tsNode.EndPosition().Row), int(tsNode.EndPosition().Column)),
 	}
 }
 
 func (cv *ChunkVisitor) generateChunkID(filePath, name string, line uint) string {
 	// Generate a unique ID based on file path, name, and line number
-	input := fmt.Sprintf("%s:%s:%d", filePath, name, line)
-	hash := sha256.Sum256([]byte(input))
-	hashStr := hex.EncodeToString(hash[:])
+	return hashID(fmt.Sprintf("%s:%s:%d", filePath, name, line))
+}
 
-	// Convert hash to UUID format (8-4-4-4-12)
-	// Qdrant requires valid UUID format
-	return fmt.Sprintf("%s-%s-%s-%s-%s",
-		hashStr[0:8],
-		hashStr[8:12],
-		hashStr[12:16],
-		hashStr[16:20],
-		hashStr[20:32],
-	)
+func (cv *ChunkVisitor) addImport(tsNode *tree_sitter.Node) {
+	cv.imports = append(cv.imports, parseImport(cv.language, cv.getNodeText(tsNode)))
 }
 
 func (cv *ChunkVisitor) extractPackageName(tsNode *tree_sitter.Node) {
@@ -739,12 +917,6 @@ func (cv *ChunkVisitor) extractJSFunctionSignature(tsNode *tree_sitter.Node) str
 	return sig
 }
 
-func (cv *ChunkVisitor) extractGoDocstring(tsNode *tree_sitter.Node) string {
-	// Go docstrings are comments immediately before the function
-	// This is a simplified implementation
-	return ""
-}
-
 func (cv *ChunkVisitor) extractPythonDocstring(tsNode *tree_sitter.Node) string {
 	bodyNode := cv.getChildByFieldName(tsNode, "body")
 	if bodyNode == nil {
@@ -772,7 +944,7 @@ func (cv *ChunkVisitor) extractPythonDocstring(tsNode *tree_sitter.Node) string
 
 // handleConditional creates a chunk for conditional statements (if, switch, etc.)
 func (cv *ChunkVisitor) handleConditional(ctx context.Context, tsNode *tree_sitter.Node, condType string) any {
-	content := cv.getNodeText(tsNode)
+	content := cv.getChunkContent(tsNode)
 	rng := cv.toRange(tsNode)
 	/*
 		cv.logger.Debug("handleConditional called",
@@ -844,7 +1016,7 @@ func (cv *ChunkVisitor) handleConditional(ctx context.Context, tsNode *tree_sitt
 
 // handleLoop creates a chunk for loop statements (for, while, etc.)
 func (cv *ChunkVisitor) handleLoop(ctx context.Context, tsNode *tree_sitter.Node, loopType string) any {
-	content := cv.getNodeText(tsNode)
+	content := cv.getChunkContent(tsNode)
 	rng := cv.toRange(tsNode)
 
 	/*
@@ -0,0 +1,181 @@
+package chunk
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+	golang "github.com/tree-sitter/tree-sitter-go/bindings/go"
+	java "github.com/tree-sitter/tree-sitter-java/bindings/go"
+	python "github.com/tree-sitter/tree-sitter-python/bindings/go"
+	typescript "github.com/tree-sitter/tree-sitter-typescript/bindings/go"
+	"go.uber.org/zap"
+)
+
+func TestLeadingComments(t *testing.T) {
+	tests := []struct {
+		name        string
+		language    string
+		grammar     *tree_sitter.Language
+		source      string
+		chunk       string // name of the chunk checked
+		wantDoc     string
+		wantContent string // prefix of the chunk content
+	}{
+		{
+			name:     "go function",
+			language: "go",
+			grammar:  tree_sitter.NewLanguage(golang.Language()),
+			source: `package p
+
+// Add returns the sum
+// of a and b.
+func Add(a, b int) int { return a + b }
+`,
+			chunk:       "Add",
+			wantDoc:     "Add returns the sum\nof a and b.",
+			wantContent: "// Add returns the sum\n// of a and b.\nfunc Add",
+		},
+		{
+			name:     "go type",
+			language: "go",
+			grammar:  tree_sitter.NewLanguage(golang.Language()),
+			source: `package p
+
+// Point is a location.
+type Point struct{ X, Y int }
+`,
+			chunk:       "Point",
+			wantDoc:     "Point is a location.",
+			wantContent: "// Point is a location.\ntype Point",
+		},
+		{
+			name:     "blank line separates",
+			language: "go",
+			grammar:  tree_sitter.NewLanguage(golang.Language()),
+			source: `package p
+
+// Section header
+
+func Lone() {}
+`,
+			chunk:       "Lone",
+			wantDoc:     "",
+			wantContent: "func Lone",
+		},
+		{
+			name:     "trailing comment of previous line",
+			language: "go",
+			grammar:  tree_sitter.NewLanguage(golang.Language()),
+			source: `package p
+
+var x = 1 // not documentation
+func After() {}
+`,
+			chunk:       "After",
+			wantDoc:     "",
+			wantContent: "func After",
+		},
+		{
+			name:     "javadoc before annotation",
+			language: "java",
+			grammar:  tree_sitter.NewLanguage(java.Language()),
+			source: `class A {
+    /**
+     * Runs the job.
+     */
+    @Override
+    public void run() {}
+}
+`,
+			chunk:       "run",
+			wantDoc:     "Runs the job.",
+			wantContent: "/**\n     * Runs the job.\n     */\n    @Override",
+		},
+		{
+			name:     "exported typescript function",
+			language: "typescript",
+			grammar:  tree_sitter.NewLanguage(typescript.LanguageTypescript()),
+			source: `/** Formats a name. */
+export function format(name: string): string { return name; }
+`,
+			chunk:       "format",
+			wantDoc:     "Formats a name.",
+			wantContent: "/** Formats a name. */\nexport function format",
+		},
+		{
+			name:     "python docstring wins",
+			language: "python",
+			grammar:  tree_sitter.NewLanguage(python.Language()),
+			source: `# Helper
+def helper():
+    """Does the work."""
+    pass
+`,
+			chunk:       "helper",
+			wantDoc:     "Does the work.",
+			wantContent: "# Helper\ndef helper",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parser := tree_sitter.NewParser()
+			defer parser.Close()
+			if err := parser.SetLanguage(tt.grammar); err != nil {
+				t.Fatal(err)
+			}
+			tree := parser.Parse([]byte(tt.source), nil)
+			defer tree.Close()
+
+			visitor := NewChunkVisitor(zap.NewNop(), tt.language, "src", []byte(tt.source), 5, 5)
+			visitor.TraverseNode(context.Background(), tree.RootNode(), nil)
+
+			for _, c := range visitor.GetChunks() {
+				if c.Name != tt.chunk {
+					continue
+				}
+				if c.Docstring != tt.wantDoc {
+					t.Errorf("Docstring = %q, want %q", c.Docstring, tt.wantDoc)
+				}
+				if !strings.HasPrefix(c.Content, tt.wantContent) {
+					t.Errorf("Content = %q, want prefix %q", c.Content, tt.wantContent)
+				}
+				return
+			}
+			t.Fatalf("no chunk named %s", tt.chunk)
+		})
+	}
+}
+
+func TestGetImports(t *testing.T) {
+	source := `package p
+
+import (
+	"fmt"
+	str "strings"
+)
+
+func F() { fmt.Println(str.ToUpper("x")) }
+`
+	parser := tree_sitter.NewParser()
+	defer parser.Close()
+	if err := parser.SetLanguage(tree_sitter.NewLanguage(golang.Language())); err != nil {
+		t.Fatal(err)
+	}
+	tree := parser.Parse([]byte(source), nil)
+	defer tree.Close()
+
+	visitor := NewChunkVisitor(zap.NewNop(), "go", "p.go", []byte(source), 5, 5)
+	visitor.TraverseNode(context.Background(), tree.RootNode(), nil)
+
+	var got []string
+	for _, imp := range visitor.GetImports() {
+		got = append(got, imp.Text+"="+strings.Join(imp.Names, ","))
+	}
+	want := []string{`"fmt"=fmt`, `str "strings"=str`}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("imports = %v, want %v", got, want)
+	}
+}
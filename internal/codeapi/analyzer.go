@@ -4,6 +4,7 @@ import (
 	"context"
 
 	"github.com/armchr/codeapi/internal/model/ast"
+	"github.com/armchr/codeapi/pkg/lsp/base"
 )
 
 // GraphAnalyzer provides graph traversal operations on the code graph.
@@ -31,11 +32,13 @@ type GraphAnalyzer interface {
 	// --- Data Flow Operations ---
 
 	// GetDataDependents returns nodes that depend on the value of the specified node.
-	// Follows DATA_FLOW edges outward to find what uses this value.
+	// Follows DATA_FLOW edges outward to find what uses this value, and ARG_OF
+	// edges from call arguments into the parameters of the called functions.
 	GetDataDependents(ctx context.Context, nodeID ast.NodeID, opts DependencyOptions) (*DependencyGraph, error)
 
 	// GetDataSources returns nodes that contribute to the value of the specified node.
-	// Follows DATA_FLOW edges backward to find where values come from.
+	// Follows DATA_FLOW edges backward to find where values come from; for a
+	// parameter, ARG_OF edges lead to the arguments of the calls passing it.
 	GetDataSources(ctx context.Context, nodeID ast.NodeID, opts DependencyOptions) (*DependencyGraph, error)
 
 	// GetVariableDependents returns functions/methods that depend on a variable's value.
@@ -50,6 +53,74 @@ type GraphAnalyzer interface {
 	// GetFieldAccessorsByName finds a field by name and returns its accessors.
 	GetFieldAccessorsByName(ctx context.Context, repoName, className, fieldName string) (*FieldAccessResult, error)
 
+	// --- Table Operations ---
+
+	// GetTableAccessors returns the functions and query constants whose SQL
+	// reads or writes a database table, named with or without its schema.
+	GetTableAccessors(ctx context.Context, repoName, table string) (*TableAccessResult, error)
+
+	// --- Configuration Operations ---
+
+	// GetConfigUsages returns where a configuration key is defined and every
+	// place code reads it. Keys match the way Spring binds them, so
+	// SERVER_PORT also finds server.port.
+	GetConfigUsages(ctx context.Context, repoName, key string) (*ConfigUsageResult, error)
+
+	// --- Feature Flag Operations ---
+
+	// ListFeatureFlags returns the feature flags checked by the code of a
+	// repository, each with its call sites. A non-empty key lists only that
+	// flag.
+	ListFeatureFlags(ctx context.Context, repoName, key string) ([]*FeatureFlagInfo, error)
+
+	// --- Security Operations ---
+
+	// GetSinkPaths returns the call chains leading from HTTP endpoint
+	// handlers, found by their Spring and JAX-RS request mapping annotations,
+	// to calls of the security-sensitive sinks tagged at parse time.
+	GetSinkPaths(ctx context.Context, repoName string, opts SinkPathOptions) ([]*SinkPath, error)
+
+	// --- Lineage Operations ---
+
+	// GetEndpointLineage returns, for each HTTP endpoint handler, the tables
+	// read and written and the outbound calls made by the functions it
+	// reaches through the call graph.
+	GetEndpointLineage(ctx context.Context, repoName string, opts LineageOptions) ([]*EndpointLineage, error)
+
+	// --- Code Metrics ---
+
+	// ListComplexFunctions returns the functions of a repository with the
+	// highest value of a metric computed at parse time, like cyclomatic
+	// complexity or lines of code.
+	ListComplexFunctions(ctx context.Context, repoName string, opts ComplexityOptions) ([]*FunctionMetricsInfo, error)
+
+	// ListHotspots returns the functions of a repository ranked by their
+	// churn score times their cyclomatic complexity: code that is both hard
+	// to follow and changed often. Only functions the git churn processor
+	// has scored are considered.
+	ListHotspots(ctx context.Context, repoName string, opts HotspotOptions) ([]*FunctionHotspot, error)
+
+	// ListPackageCoupling returns the packages of a repository ranked by
+	// their fan-in or fan-out, read from the materialized views stored when
+	// code_graph.materialized_views is enabled.
+	ListPackageCoupling(ctx context.Context, repoName string, opts PackageCouplingOptions) ([]*PackageMetricsInfo, error)
+
+	// --- Version History ---
+
+	// GetFunctionHistory returns a function in every indexed version of its
+	// file, newest first, following the PREVIOUS_VERSION relations linking
+	// each version to the one before it. Any version of the function can be
+	// given. At most maxVersions are returned (default: 20).
+	GetFunctionHistory(ctx context.Context, functionID ast.NodeID, maxVersions int) (*FunctionHistory, error)
+
+	// GetFunctionHistoryByName finds a function by name and returns its history.
+	GetFunctionHistoryByName(ctx context.Context, repoName, filePath, className, functionName string, maxVersions int) (*FunctionHistory, error)
+
+	// ListFunctions returns the functions of the newest version of each file
+	// of a repository whose path starts with pathPrefix, ordered by path,
+	// class and name, for comparing repositories
+	ListFunctions(ctx context.Context, repoName, pathPrefix string) ([]*FunctionVersion, error)
+
 	// --- Inheritance Operations ---
 
 	// GetInheritanceTree returns the inheritance hierarchy for a class.
@@ -62,6 +133,17 @@ type GraphAnalyzer interface {
 	// GetChildClasses returns direct and indirect child classes.
 	GetChildClasses(ctx context.Context, classID ast.NodeID, maxDepth int) ([]*ClassInfo, error)
 
+	// GetClassHierarchy returns the classes and interfaces of a repository as
+	// a forest built from INHERITS relations, for architecture views.
+	GetClassHierarchy(ctx context.Context, repoName string, opts ClassHierarchyOptions) (*ClassHierarchy, error)
+
+	// --- Dependency Injection ---
+
+	// GetInjectionWiring returns the classes injected into a class, with the
+	// implementations of injected interfaces, and the classes it is injected
+	// into, following INJECTS relations.
+	GetInjectionWiring(ctx context.Context, classID ast.NodeID) (*InjectionWiring, error)
+
 	// --- Impact Analysis ---
 
 	// GetImpact returns all code elements that could be affected by changes to the specified node.
@@ -86,6 +168,246 @@ type MethodAccessInfo struct {
 	Locations   []Location // where in the method the access occurs
 }
 
+// TableAccessResult contains the code reading and writing a database table
+type TableAccessResult struct {
+	Table   string
+	Readers []*TableAccessor // code whose SQL reads the table
+	Writers []*TableAccessor // code whose SQL inserts, updates or deletes rows
+}
+
+// TableAccessor is a function, or a constant holding a query, that accesses
+// a table
+type TableAccessor struct {
+	ID       ast.NodeID
+	Name     string
+	NodeType ast.NodeType
+	FilePath string
+	FileID   int32
+}
+
+// ConfigUsageResult contains the definitions of a configuration key and the
+// code reading it
+type ConfigUsageResult struct {
+	Key         string
+	Definitions []*ConfigDefinition // keys of configuration files
+	Consumers   []*ConfigConsumer   // reads by the code, including keys no file defines
+}
+
+// ConfigDefinition is a key defined by a configuration file
+type ConfigDefinition struct {
+	ID       ast.NodeID
+	Key      string
+	Value    string
+	FilePath string
+	Line     int // 1-based
+}
+
+// ConfigConsumer is a read of a configuration key by a function, or by a
+// class or file when the key is read outside functions
+type ConfigConsumer struct {
+	ID       ast.NodeID
+	Name     string
+	NodeType ast.NodeType
+	FilePath string
+	FileID   int32
+	Key      string // key as the code names it
+	Source   string // "env" or "property"
+	Line     int    // 1-based line of the read
+}
+
+// FeatureFlagInfo is a feature flag and the calls checking it
+type FeatureFlagInfo struct {
+	Key       string
+	Providers []string // SDKs or helpers the flag is checked with
+	CallSites []*FlagCallSite
+}
+
+// FlagCallSite is a call checking a feature flag
+type FlagCallSite struct {
+	CallID       ast.NodeID
+	Call         string     // name of the called function, like boolVariation
+	FunctionID   ast.NodeID // function making the call, 0 outside functions
+	FunctionName string
+	FilePath     string
+	FileID       int32
+	Line         int // 1-based
+}
+
+// SinkPathOptions controls the search for paths from endpoints to sinks
+type SinkPathOptions struct {
+	Category string // only sinks of this category, like "exec"; empty for all
+	MaxDepth int    // longest chain of calls from a handler (default: 5)
+
+	// IncludePossibleCalls follows calls of interface methods into their
+	// implementations
+	IncludePossibleCalls bool
+}
+
+// SinkPath is a chain of calls from an HTTP endpoint handler to a function
+// calling a security-sensitive sink
+type SinkPath struct {
+	Method    string      // HTTP method, or "ANY" if not constrained
+	Route     string      // path declared on the handler
+	Functions []*CallNode // the handler first, the function making the sink call last
+	Sink      *SinkCall
+}
+
+// SinkCall is a call of a security-sensitive sink
+type SinkCall struct {
+	CallID   ast.NodeID
+	Call     string
+	Category string // exec, eval, sql_concat or deserialization
+	FilePath string
+	FileID   int32
+	Line     int // 1-based
+}
+
+// LineageOptions controls the traversal from endpoint handlers
+type LineageOptions struct {
+	MaxDepth int // longest chain of calls followed from a handler (default: 5)
+
+	// IncludePossibleCalls follows calls of interface methods into their
+	// implementations
+	IncludePossibleCalls bool
+}
+
+// EndpointLineage is what an HTTP endpoint touches downstream of its handler
+type EndpointLineage struct {
+	Method        string    // HTTP method, or "ANY" if not constrained
+	Route         string    // path declared on the handler
+	Handler       *CallNode // the handler
+	Functions     int       // functions reached, the handler included
+	TablesRead    []string  // tables the reached code selects from, with their schema if given
+	TablesWritten []string  // tables it inserts into, updates or deletes from
+	Services      []*OutboundCall
+	ExternalAPIs  []*OutboundCall
+}
+
+// OutboundCall is an HTTP request or gRPC call made by a function
+type OutboundCall struct {
+	CallID       ast.NodeID
+	Call         string
+	Protocol     string // http or grpc
+	Target       string // URL the request is made to, when given literally
+	Host         string // host of Target
+	FunctionID   ast.NodeID
+	FunctionName string
+	FilePath     string
+	FileID       int32
+	Line         int // 1-based
+}
+
+// ComplexityOptions controls the listing of complex functions
+type ComplexityOptions struct {
+	SortBy string // "complexity" (default), "loc", "params", "nesting" or "callees"
+	Limit  int    // number of functions listed (default: 20)
+}
+
+// FunctionMetricsInfo is a function with its size and complexity metrics
+type FunctionMetricsInfo struct {
+	ID         ast.NodeID
+	Name       string
+	ClassName  string // empty for a top-level function
+	FilePath   string
+	FileID     int32
+	Line       int // 1-based
+	Complexity int // cyclomatic complexity
+	LOC        int // lines holding code
+	Params     int
+	Nesting    int // deepest nesting of control structures
+
+	// TransitiveCallees is the number of functions reached through calls,
+	// from the materialized views; 0 when they are not enabled
+	TransitiveCallees int
+}
+
+// PackageCouplingOptions controls the listing of package coupling
+type PackageCouplingOptions struct {
+	SortBy string // "fan_in" (default) or "fan_out"
+	Limit  int    // number of packages listed (default: 20)
+}
+
+// PackageMetricsInfo is a package, the directory of its files, with the
+// number of other packages calling into it and called by it
+type PackageMetricsInfo struct {
+	Package string
+	Files   int
+	FanIn   int
+	FanOut  int
+}
+
+// HotspotOptions controls the listing of hotspots
+type HotspotOptions struct {
+	Limit int // number of functions listed (default: 20)
+}
+
+// FunctionHotspot is a function with its complexity and git churn
+type FunctionHotspot struct {
+	ID               ast.NodeID
+	Name             string
+	ClassName        string // empty for a top-level function
+	FilePath         string
+	FileID           int32
+	Line             int // 1-based
+	Complexity       int
+	ChurnScore       float64
+	Commits          int      // commits that changed the function
+	LastCommit       string   // RFC 3339 date of the latest of them
+	MainContributors []string // authors with the most of them
+	Score            float64  // ChurnScore × Complexity
+}
+
+// FunctionHistory is a function traced through the versions of its file
+type FunctionHistory struct {
+	Versions  []*FunctionVersion // newest first
+	Truncated bool               // older versions were left out
+}
+
+// FunctionVersion is a function as it was in one version of its file
+type FunctionVersion struct {
+	ID        ast.NodeID
+	Name      string
+	ClassName string // empty for a top-level function
+	FilePath  string
+	FileID    int32
+	Range     base.Range
+	BodyHash  string
+	Renamed   bool // the name differs from the previous version's
+	Changed   bool // the body differs from the previous version's
+}
+
+// ClassHierarchyOptions filters the classes of a repository hierarchy. A
+// class is listed when its file matches both prefixes; empty prefixes match
+// every class.
+type ClassHierarchyOptions struct {
+	PackagePrefix string // package of the file: a Java package, a Go package name
+	PathPrefix    string // path of the file in the repository
+}
+
+// ClassHierarchy is the inheritance forest of the classes of a repository.
+// A class that extends or implements several listed classes appears under
+// each of them.
+type ClassHierarchy struct {
+	Roots      []*ClassHierarchyNode // classes with no parent among the listed ones
+	Classes    int                   // listed classes, interfaces included
+	Interfaces int
+}
+
+// ClassHierarchyNode is a class or interface with the listed classes that
+// extend or implement it
+type ClassHierarchyNode struct {
+	ID          ast.NodeID
+	Name        string
+	Package     string
+	FilePath    string
+	FileID      int32
+	Line        int // 1-based
+	IsInterface bool
+	ParentIDs   []ast.NodeID // every resolved parent, listed or not
+	Summary     string       // left empty here; callers with a summary store fill it in
+	Children    []*ClassHierarchyNode
+}
+
 // ImpactOptions controls impact analysis behavior
 type ImpactOptions struct {
 	MaxDepth         int  // max traversal depth (-1 for unlimited)
@@ -93,6 +415,10 @@ type ImpactOptions struct {
 	IncludeDataFlow  bool // include data dependents in impact
 	IncludeTests     bool // include test files
 	Scope            ImpactScope
+
+	// IncludePossibleCalls counts callers of an interface method as callers
+	// of its implementations
+	IncludePossibleCalls bool
 }
 
 // ImpactScope defines the boundary for impact analysis
@@ -113,6 +439,8 @@ func DefaultImpactOptions() ImpactOptions {
 		IncludeDataFlow:  true,
 		IncludeTests:     false,
 		Scope:            ImpactScopeRepo,
+
+		IncludePossibleCalls: true,
 	}
 }
 
@@ -2,9 +2,13 @@ package codeapi
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"slices"
+	"strings"
 
 	"github.com/armchr/codeapi/internal/model/ast"
+	"github.com/armchr/codeapi/internal/parse"
 	"github.com/armchr/codeapi/internal/service/codegraph"
 
 	"go.uber.org/zap"
@@ -101,12 +105,12 @@ func (a *graphAnalyzerImpl) traverseCallees(ctx context.Context, functionID ast.
 	}
 
 	// Query: function -[:CONTAINS]-> functionCall -[:CALLS_FUNCTION]-> callee
-	query := `
-		MATCH (f:Function {id: $functionId})-[:CONTAINS*]->(fc:FunctionCall)-[:CALLS_FUNCTION]->(callee:Function)
+	query := fmt.Sprintf(`
+		MATCH (f:Function {id: $functionId})-[:CONTAINS*]->(fc:FunctionCall)-[r:%s]->(callee:Function)
 		RETURN DISTINCT callee.id AS calleeId, callee.name AS calleeName,
 		       callee.fileId AS fileId, callee.range AS range,
-		       fc.id AS callSiteId, fc.range AS callSiteRange
-	`
+		       fc.id AS callSiteId, fc.range AS callSiteRange, type(r) AS relation
+	`, callRelations(opts))
 	records, err := a.graph.ExecuteRead(ctx, query, map[string]any{"functionId": int64(functionID)})
 	if err != nil {
 		return fmt.Errorf("failed to query callees: %w", err)
@@ -123,6 +127,7 @@ func (a *graphAnalyzerImpl) traverseCallees(ctx context.Context, functionID ast.
 				FileID: int32(toInt64(record["fileId"])),
 				Range:  parseRange(toString(record["callSiteRange"])),
 			},
+			Possible: toString(record["relation"]) == relPossibleCalls,
 		})
 
 		// Skip if already visited
@@ -159,12 +164,12 @@ func (a *graphAnalyzerImpl) traverseCallers(ctx context.Context, functionID ast.
 	}
 
 	// Query: caller -[:CONTAINS]-> functionCall -[:CALLS_FUNCTION]-> function
-	query := `
-		MATCH (caller:Function)-[:CONTAINS*]->(fc:FunctionCall)-[:CALLS_FUNCTION]->(f:Function {id: $functionId})
+	query := fmt.Sprintf(`
+		MATCH (caller:Function)-[:CONTAINS*]->(fc:FunctionCall)-[r:%s]->(f:Function {id: $functionId})
 		RETURN DISTINCT caller.id AS callerId, caller.name AS callerName,
 		       caller.fileId AS fileId, caller.range AS range,
-		       fc.id AS callSiteId, fc.range AS callSiteRange
-	`
+		       fc.id AS callSiteId, fc.range AS callSiteRange, type(r) AS relation
+	`, callRelations(opts))
 	records, err := a.graph.ExecuteRead(ctx, query, map[string]any{"functionId": int64(functionID)})
 	if err != nil {
 		return fmt.Errorf("failed to query callers: %w", err)
@@ -181,6 +186,7 @@ func (a *graphAnalyzerImpl) traverseCallers(ctx context.Context, functionID ast.
 				FileID: int32(toInt64(record["fileId"])),
 				Range:  parseRange(toString(record["callSiteRange"])),
 			},
+			Possible: toString(record["relation"]) == relPossibleCalls,
 		})
 
 		// Skip if already visited
@@ -210,6 +216,18 @@ func (a *graphAnalyzerImpl) traverseCallers(ctx context.Context, functionID ast.
 	return nil
 }
 
+// relPossibleCalls links a call of an interface method to an implementation
+const relPossibleCalls = "POSSIBLE_CALLS"
+
+// callRelations returns the relationship types from a call site to the
+// functions it may run
+func callRelations(opts CallGraphOptions) string {
+	if opts.IncludePossibleCalls {
+		return "CALLS_FUNCTION|" + relPossibleCalls
+	}
+	return "CALLS_FUNCTION"
+}
+
 // -----------------------------------------------------------------------------
 // Data Flow Operations
 // -----------------------------------------------------------------------------
@@ -310,15 +328,15 @@ func (a *graphAnalyzerImpl) traverseDataFlow(ctx context.Context, nodeID ast.Nod
 	var query string
 	if direction == DirectionOutgoing {
 		query = `
-			MATCH (source {id: $nodeId})-[:DATA_FLOW]->(target)
+			MATCH (source {id: $nodeId})-[r:DATA_FLOW|ARG_OF]->(target)
 			RETURN target.id AS targetId, target.name AS name, target.nodeType AS nodeType,
-			       target.fileId AS fileId
+			       target.fileId AS fileId, type(r) AS relType
 		`
 	} else {
 		query = `
-			MATCH (source)-[:DATA_FLOW]->(target {id: $nodeId})
+			MATCH (source)-[r:DATA_FLOW|ARG_OF]->(target {id: $nodeId})
 			RETURN source.id AS targetId, source.name AS name, source.nodeType AS nodeType,
-			       source.fileId AS fileId
+			       source.fileId AS fileId, type(r) AS relType
 		`
 	}
 
@@ -330,18 +348,24 @@ func (a *graphAnalyzerImpl) traverseDataFlow(ctx context.Context, nodeID ast.Nod
 	for _, record := range records {
 		targetID := ast.NodeID(toInt64(record["targetId"]))
 
+		// Arguments flow into the parameters of the functions they are passed to
+		flowType := "data_flow"
+		if toString(record["relType"]) == "ARG_OF" {
+			flowType = "parameter"
+		}
+
 		// Add edge
 		if direction == DirectionOutgoing {
 			result.Edges = append(result.Edges, &DependencyEdge{
 				SourceID: nodeID,
 				TargetID: targetID,
-				FlowType: "data_flow",
+				FlowType: flowType,
 			})
 		} else {
 			result.Edges = append(result.Edges, &DependencyEdge{
 				SourceID: targetID,
 				TargetID: nodeID,
-				FlowType: "data_flow",
+				FlowType: flowType,
 			})
 		}
 
@@ -607,6 +631,985 @@ func (a *graphAnalyzerImpl) collectChildren(ctx context.Context, classID ast.Nod
 	}
 }
 
+func (a *graphAnalyzerImpl) GetClassHierarchy(ctx context.Context, repoName string, opts ClassHierarchyOptions) (*ClassHierarchy, error) {
+	query := `
+		MATCH (f:FileScope {repo: $repo})
+		MATCH (c:Class {fileId: f.fileId})
+		OPTIONAL MATCH (m:ModuleScope {fileId: f.fileId})
+		WITH c, f, head(collect(m.name)) AS package
+		WHERE ($packagePrefix = '' OR package STARTS WITH $packagePrefix)
+		  AND ($pathPrefix = '' OR f.path STARTS WITH $pathPrefix)
+		OPTIONAL MATCH (c)-[:INHERITS]->(parent:Class)
+		RETURN c.id AS id, c.name AS name, package, f.path AS path, f.fileId AS fileId,
+		       c.range AS range, c.md_is_interface AS isInterface, collect(parent.id) AS parents
+		ORDER BY path, name
+	`
+	params := map[string]any{
+		"repo":          repoName,
+		"packagePrefix": opts.PackagePrefix,
+		"pathPrefix":    opts.PathPrefix,
+	}
+	records, err := a.graph.ExecuteRead(ctx, query, params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list class hierarchy: %w", err)
+	}
+
+	result := &ClassHierarchy{Roots: make([]*ClassHierarchyNode, 0)}
+	nodes := make([]*ClassHierarchyNode, 0, len(records))
+	byID := make(map[ast.NodeID]*ClassHierarchyNode, len(records))
+	for _, record := range records {
+		isInterface, _ := record["isInterface"].(bool)
+		node := &ClassHierarchyNode{
+			ID:          ast.NodeID(toInt64(record["id"])),
+			Name:        toString(record["name"]),
+			Package:     toString(record["package"]),
+			FilePath:    toString(record["path"]),
+			FileID:      int32(toInt64(record["fileId"])),
+			Line:        parseRange(toString(record["range"])).Start.Line + 1,
+			IsInterface: isInterface,
+		}
+		parents, _ := record["parents"].([]any)
+		for _, p := range parents {
+			node.ParentIDs = append(node.ParentIDs, ast.NodeID(toInt64(p)))
+		}
+		nodes = append(nodes, node)
+		byID[node.ID] = node
+
+		result.Classes++
+		if isInterface {
+			result.Interfaces++
+		}
+	}
+
+	// Hang each class under its listed parents, in the order of the query
+	children := make(map[ast.NodeID][]*ClassHierarchyNode)
+	for _, node := range nodes {
+		listedParent := false
+		for _, parentID := range node.ParentIDs {
+			if _, ok := byID[parentID]; ok && parentID != node.ID {
+				children[parentID] = append(children[parentID], node)
+				listedParent = true
+			}
+		}
+		if !listedParent {
+			result.Roots = append(result.Roots, node)
+		}
+	}
+	reached := make(map[ast.NodeID]bool, len(nodes))
+	for _, root := range result.Roots {
+		addHierarchyChildren(root, children, map[ast.NodeID]bool{}, reached)
+	}
+	// Classes that only inherit from each other in a cycle have no root
+	// above them; list each such class as a root of its own
+	for _, node := range nodes {
+		if !reached[node.ID] {
+			result.Roots = append(result.Roots, node)
+			addHierarchyChildren(node, children, map[ast.NodeID]bool{}, reached)
+		}
+	}
+	return result, nil
+}
+
+// addHierarchyChildren attaches the children of node, and theirs, copying a node
+// wherever it has a second parent. path holds the classes above node, so
+// inheritance cycles in unresolved code end instead of recursing forever;
+// reached collects every class attached.
+func addHierarchyChildren(node *ClassHierarchyNode, children map[ast.NodeID][]*ClassHierarchyNode, path, reached map[ast.NodeID]bool) {
+	path[node.ID] = true
+	reached[node.ID] = true
+	defer delete(path, node.ID)
+
+	for _, child := range children[node.ID] {
+		if path[child.ID] {
+			continue
+		}
+		copied := *child
+		copied.Children = nil
+		addHierarchyChildren(&copied, children, path, reached)
+		node.Children = append(node.Children, &copied)
+	}
+}
+
+// -----------------------------------------------------------------------------
+// Table Operations
+// -----------------------------------------------------------------------------
+
+func (a *graphAnalyzerImpl) GetTableAccessors(ctx context.Context, repoName, table string) (*TableAccessResult, error) {
+	schema, name := "", strings.ToLower(table)
+	if dot := strings.LastIndex(name, "."); dot >= 0 {
+		schema, name = name[:dot], name[dot+1:]
+	}
+
+	query := `
+		MATCH (f:FileScope {repo: $repo})
+		MATCH (t:Table {fileId: f.fileId, name: $name})
+		WHERE $schema = '' OR t.md_schema = $schema
+		MATCH (code)-[r:READS_TABLE|WRITES_TABLE]->(t)
+		RETURN DISTINCT code.id AS id, code.name AS name, code.nodeType AS nodeType,
+		       code.fileId AS fileId, f.path AS path, type(r) AS access
+	`
+	records, err := a.graph.ExecuteRead(ctx, query, map[string]any{"repo": repoName, "name": name, "schema": schema})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get table accessors: %w", err)
+	}
+
+	result := &TableAccessResult{
+		Table:   table,
+		Readers: make([]*TableAccessor, 0),
+		Writers: make([]*TableAccessor, 0),
+	}
+	for _, record := range records {
+		accessor := &TableAccessor{
+			ID:       ast.NodeID(toInt64(record["id"])),
+			Name:     toString(record["name"]),
+			NodeType: ast.NodeType(toInt64(record["nodeType"])),
+			FilePath: toString(record["path"]),
+			FileID:   int32(toInt64(record["fileId"])),
+		}
+		if toString(record["access"]) == "WRITES_TABLE" {
+			result.Writers = append(result.Writers, accessor)
+		} else {
+			result.Readers = append(result.Readers, accessor)
+		}
+	}
+	return result, nil
+}
+
+// -----------------------------------------------------------------------------
+// Configuration
+// -----------------------------------------------------------------------------
+
+func (a *graphAnalyzerImpl) GetConfigUsages(ctx context.Context, repoName, key string) (*ConfigUsageResult, error) {
+	params := map[string]any{"repo": repoName, "lookup": parse.ConfigLookupKey(key)}
+	result := &ConfigUsageResult{
+		Key:         key,
+		Definitions: make([]*ConfigDefinition, 0),
+		Consumers:   make([]*ConfigConsumer, 0),
+	}
+
+	defQuery := `
+		MATCH (f:FileScope {repo: $repo})
+		MATCH (c:Config {fileId: f.fileId, md_lookup: $lookup})
+		RETURN c.id AS id, c.name AS key, c.md_value AS value, c.range AS range, f.path AS path
+	`
+	defRecords, err := a.graph.ExecuteRead(ctx, defQuery, params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get config definitions: %w", err)
+	}
+	for _, record := range defRecords {
+		result.Definitions = append(result.Definitions, &ConfigDefinition{
+			ID:       ast.NodeID(toInt64(record["id"])),
+			Key:      toString(record["key"]),
+			Value:    toString(record["value"]),
+			FilePath: toString(record["path"]),
+			Line:     parseRange(toString(record["range"])).Start.Line + 1,
+		})
+	}
+
+	// Reads are recorded by the parser, so keys only set in the environment
+	// are found too
+	readQuery := `
+		MATCH (f:FileScope {repo: $repo})
+		MATCH (v:Variable {fileId: f.fileId, md_lookup: $lookup})
+		WHERE v.md_config_key IS NOT NULL
+		OPTIONAL MATCH (owner {id: v.md_config_owner})
+		RETURN v.md_config_key AS key, v.md_config_source AS source, v.range AS range,
+		       f.path AS path, f.fileId AS fileId,
+		       owner.id AS id, owner.name AS name, owner.nodeType AS nodeType
+	`
+	readRecords, err := a.graph.ExecuteRead(ctx, readQuery, params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get config reads: %w", err)
+	}
+	for _, record := range readRecords {
+		result.Consumers = append(result.Consumers, &ConfigConsumer{
+			ID:       ast.NodeID(toInt64(record["id"])),
+			Name:     toString(record["name"]),
+			NodeType: ast.NodeType(toInt64(record["nodeType"])),
+			FilePath: toString(record["path"]),
+			FileID:   int32(toInt64(record["fileId"])),
+			Key:      toString(record["key"]),
+			Source:   toString(record["source"]),
+			Line:     parseRange(toString(record["range"])).Start.Line + 1,
+		})
+	}
+	return result, nil
+}
+
+// -----------------------------------------------------------------------------
+// Feature Flags
+// -----------------------------------------------------------------------------
+
+func (a *graphAnalyzerImpl) ListFeatureFlags(ctx context.Context, repoName, key string) ([]*FeatureFlagInfo, error) {
+	query := `
+		MATCH (f:FileScope {repo: $repo})
+		MATCH (flag:FeatureFlag {fileId: f.fileId})
+		WHERE $key = '' OR flag.name = $key
+		MATCH (call)-[r:USES_FLAG]->(flag)
+		OPTIONAL MATCH (fn:Function {id: r.md_function})
+		RETURN flag.name AS key, flag.md_provider AS provider,
+		       call.id AS callId, call.name AS callName, call.range AS range,
+		       f.path AS path, f.fileId AS fileId, fn.id AS functionId, fn.name AS functionName
+		ORDER BY key, path
+	`
+	records, err := a.graph.ExecuteRead(ctx, query, map[string]any{"repo": repoName, "key": key})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list feature flags: %w", err)
+	}
+
+	flags := make([]*FeatureFlagInfo, 0)
+	byKey := make(map[string]*FeatureFlagInfo)
+	for _, record := range records {
+		flagKey := toString(record["key"])
+		flag, ok := byKey[flagKey]
+		if !ok {
+			flag = &FeatureFlagInfo{Key: flagKey, CallSites: make([]*FlagCallSite, 0)}
+			byKey[flagKey] = flag
+			flags = append(flags, flag)
+		}
+		if provider := toString(record["provider"]); provider != "" && !slices.Contains(flag.Providers, provider) {
+			flag.Providers = append(flag.Providers, provider)
+		}
+		flag.CallSites = append(flag.CallSites, &FlagCallSite{
+			CallID:       ast.NodeID(toInt64(record["callId"])),
+			Call:         toString(record["callName"]),
+			FunctionID:   ast.NodeID(toInt64(record["functionId"])),
+			FunctionName: toString(record["functionName"]),
+			FilePath:     toString(record["path"]),
+			FileID:       int32(toInt64(record["fileId"])),
+			Line:         parseRange(toString(record["range"])).Start.Line + 1,
+		})
+	}
+	return flags, nil
+}
+
+// -----------------------------------------------------------------------------
+// Security Sinks
+// -----------------------------------------------------------------------------
+
+// defaultSinkPathDepth is the longest chain of calls searched from a handler
+const defaultSinkPathDepth = 5
+
+func (a *graphAnalyzerImpl) GetSinkPaths(ctx context.Context, repoName string, opts SinkPathOptions) ([]*SinkPath, error) {
+	if opts.MaxDepth <= 0 {
+		opts.MaxDepth = defaultSinkPathDepth
+	}
+	params := map[string]any{"repo": repoName, "category": opts.Category}
+
+	// Sink calls, grouped by the functions making them
+	sinkQuery := `
+		MATCH (f:FileScope {repo: $repo})
+		MATCH (call:FunctionCall {fileId: f.fileId})
+		WHERE call.md_sink IS NOT NULL AND ($category = '' OR call.md_sink = $category)
+		MATCH (fn:Function)-[:CONTAINS*]->(call)
+		RETURN call.id AS callId, call.name AS callName, call.md_sink AS category, call.range AS range,
+		       f.path AS path, f.fileId AS fileId, fn.id AS functionId, fn.name AS functionName,
+		       fn.range AS functionRange
+		ORDER BY path, callId
+	`
+	sinkRecords, err := a.graph.ExecuteRead(ctx, sinkQuery, params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find sink calls: %w", err)
+	}
+	paths := make([]*SinkPath, 0)
+	if len(sinkRecords) == 0 {
+		return paths, nil
+	}
+
+	var sinkFunctions []*CallNode
+	sinks := make(map[ast.NodeID][]*SinkCall)
+	for _, record := range sinkRecords {
+		fnID := ast.NodeID(toInt64(record["functionId"]))
+		if _, ok := sinks[fnID]; !ok {
+			sinkFunctions = append(sinkFunctions, &CallNode{
+				ID:       fnID,
+				Name:     toString(record["functionName"]),
+				FilePath: toString(record["path"]),
+				FileID:   int32(toInt64(record["fileId"])),
+				Range:    parseRange(toString(record["functionRange"])),
+			})
+		}
+		sinks[fnID] = append(sinks[fnID], &SinkCall{
+			CallID:   ast.NodeID(toInt64(record["callId"])),
+			Call:     toString(record["callName"]),
+			Category: toString(record["category"]),
+			FilePath: toString(record["path"]),
+			FileID:   int32(toInt64(record["fileId"])),
+			Line:     parseRange(toString(record["range"])).Start.Line + 1,
+		})
+	}
+
+	handlers, err := a.findEndpointHandlers(ctx, repoName)
+	if err != nil {
+		return nil, err
+	}
+	if len(handlers) == 0 {
+		return paths, nil
+	}
+
+	// Walk back from each function making sink calls through its callers,
+	// remembering the callee each caller leads to, and report the handlers met
+	for _, sinkFn := range sinkFunctions {
+		toward := map[ast.NodeID]ast.NodeID{sinkFn.ID: ast.InvalidNodeID}
+		nodes := map[ast.NodeID]*CallNode{sinkFn.ID: sinkFn}
+		frontier := []ast.NodeID{sinkFn.ID}
+		for depth := 0; len(frontier) > 0; depth++ {
+			for _, id := range frontier {
+				endpoint, ok := handlers[id]
+				if !ok {
+					continue
+				}
+				var chain []*CallNode
+				for step := id; step != ast.InvalidNodeID; step = toward[step] {
+					node := *nodes[step]
+					node.Depth = len(chain)
+					chain = append(chain, &node)
+				}
+				for _, sink := range sinks[sinkFn.ID] {
+					paths = append(paths, &SinkPath{Method: endpoint.method, Route: endpoint.route, Functions: chain, Sink: sink})
+				}
+			}
+			if depth == opts.MaxDepth {
+				break
+			}
+			if frontier, err = a.stepToCallers(ctx, frontier, toward, nodes, opts); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return paths, nil
+}
+
+// stepToCallers returns the callers of the given functions not reached yet,
+// recording for each the callee it was reached from
+func (a *graphAnalyzerImpl) stepToCallers(ctx context.Context, functions []ast.NodeID, toward map[ast.NodeID]ast.NodeID, nodes map[ast.NodeID]*CallNode, opts SinkPathOptions) ([]ast.NodeID, error) {
+	ids := make([]int64, len(functions))
+	for i, id := range functions {
+		ids[i] = int64(id)
+	}
+	query := fmt.Sprintf(`
+		MATCH (caller:Function)-[:CONTAINS*]->(:FunctionCall)-[:%s]->(f:Function)
+		WHERE f.id IN $ids
+		MATCH (file:FileScope {fileId: caller.fileId})
+		RETURN DISTINCT caller.id AS callerId, caller.name AS callerName, caller.range AS range,
+		       caller.fileId AS fileId, file.path AS path, f.id AS calleeId
+	`, callRelations(CallGraphOptions{IncludePossibleCalls: opts.IncludePossibleCalls}))
+	records, err := a.graph.ExecuteRead(ctx, query, map[string]any{"ids": ids})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query callers: %w", err)
+	}
+
+	var next []ast.NodeID
+	for _, record := range records {
+		callerID := ast.NodeID(toInt64(record["callerId"]))
+		if _, seen := toward[callerID]; seen {
+			continue
+		}
+		toward[callerID] = ast.NodeID(toInt64(record["calleeId"]))
+		nodes[callerID] = &CallNode{
+			ID:       callerID,
+			Name:     toString(record["callerName"]),
+			FilePath: toString(record["path"]),
+			FileID:   int32(toInt64(record["fileId"])),
+			Range:    parseRange(toString(record["range"])),
+		}
+		next = append(next, callerID)
+	}
+	return next, nil
+}
+
+// endpoint is the HTTP method and route an endpoint handler serves
+type endpoint struct {
+	method  string
+	route   string
+	handler *CallNode
+}
+
+// findEndpointHandlers returns the functions of a repository annotated as
+// HTTP endpoint handlers
+func (a *graphAnalyzerImpl) findEndpointHandlers(ctx context.Context, repoName string) (map[ast.NodeID]endpoint, error) {
+	query := `
+		MATCH (f:FileScope {repo: $repo})
+		MATCH (m:Function {fileId: f.fileId})
+		WHERE m.md_annotations IS NOT NULL
+		RETURN m.id AS id, m.name AS name, m.range AS range, m.fileId AS fileId,
+		       f.path AS path, m.md_annotations AS annotations
+	`
+	records, err := a.graph.ExecuteRead(ctx, query, map[string]any{"repo": repoName})
+	if err != nil {
+		return nil, fmt.Errorf("failed to find endpoint handlers: %w", err)
+	}
+
+	handlers := make(map[ast.NodeID]endpoint)
+	for _, record := range records {
+		rawAnnotations, _ := record["annotations"].([]any)
+		for _, raw := range rawAnnotations {
+			encoded, ok := raw.(string)
+			if !ok {
+				continue
+			}
+			if method, route, ok := ParseEndpointAnnotation(encoded); ok {
+				id := ast.NodeID(toInt64(record["id"]))
+				handlers[id] = endpoint{method: method, route: route, handler: &CallNode{
+					ID:       id,
+					Name:     toString(record["name"]),
+					FilePath: toString(record["path"]),
+					FileID:   int32(toInt64(record["fileId"])),
+					Range:    parseRange(toString(record["range"])),
+				}}
+				break
+			}
+		}
+	}
+	return handlers, nil
+}
+
+// endpointAnnotations maps request mapping annotation names to HTTP methods
+var endpointAnnotations = map[string]string{
+	// Spring
+	"GetMapping":     "GET",
+	"PostMapping":    "POST",
+	"PutMapping":     "PUT",
+	"DeleteMapping":  "DELETE",
+	"PatchMapping":   "PATCH",
+	"RequestMapping": "ANY",
+	// JAX-RS
+	"GET":    "GET",
+	"POST":   "POST",
+	"PUT":    "PUT",
+	"DELETE": "DELETE",
+	"PATCH":  "PATCH",
+}
+
+// ParseEndpointAnnotation decodes an annotation stored by the Java visitor
+// ({"name": ..., "arguments": {...}}) and returns the HTTP method and path
+// if it declares a request mapping
+func ParseEndpointAnnotation(encoded string) (string, string, bool) {
+	var annotation struct {
+		Name      string            `json:"name"`
+		Arguments map[string]string `json:"arguments"`
+	}
+	if err := json.Unmarshal([]byte(encoded), &annotation); err != nil {
+		return "", "", false
+	}
+
+	method, ok := endpointAnnotations[annotation.Name]
+	if !ok {
+		return "", "", false
+	}
+	if m := annotation.Arguments["method"]; method == "ANY" && m != "" {
+		// RequestMethod.POST -> POST
+		method = strings.ToUpper(m[strings.LastIndex(m, ".")+1:])
+	}
+
+	path := annotation.Arguments["value"]
+	if path == "" {
+		path = annotation.Arguments["path"]
+	}
+	return method, path, true
+}
+
+// -----------------------------------------------------------------------------
+// Lineage
+// -----------------------------------------------------------------------------
+
+// defaultLineageDepth is the longest chain of calls followed from a handler
+const defaultLineageDepth = 5
+
+func (a *graphAnalyzerImpl) GetEndpointLineage(ctx context.Context, repoName string, opts LineageOptions) ([]*EndpointLineage, error) {
+	if opts.MaxDepth <= 0 {
+		opts.MaxDepth = defaultLineageDepth
+	}
+	handlers, err := a.findEndpointHandlers(ctx, repoName)
+	if err != nil {
+		return nil, err
+	}
+
+	lineage := make([]*EndpointLineage, 0, len(handlers))
+	for id, endpoint := range handlers {
+		reached := map[ast.NodeID]bool{id: true}
+		frontier := []ast.NodeID{id}
+		for depth := 0; depth < opts.MaxDepth && len(frontier) > 0; depth++ {
+			if frontier, err = a.stepToCallees(ctx, frontier, reached, opts); err != nil {
+				return nil, err
+			}
+		}
+		ids := make([]int64, 0, len(reached))
+		for fn := range reached {
+			ids = append(ids, int64(fn))
+		}
+
+		entry := &EndpointLineage{
+			Method:        endpoint.method,
+			Route:         endpoint.route,
+			Handler:       endpoint.handler,
+			Functions:     len(ids),
+			TablesRead:    make([]string, 0),
+			TablesWritten: make([]string, 0),
+			Services:      make([]*OutboundCall, 0),
+			ExternalAPIs:  make([]*OutboundCall, 0),
+		}
+		if err := a.addLineageTables(ctx, entry, ids); err != nil {
+			return nil, err
+		}
+		if err := a.addLineageCalls(ctx, entry, ids); err != nil {
+			return nil, err
+		}
+		lineage = append(lineage, entry)
+	}
+
+	slices.SortFunc(lineage, func(x, y *EndpointLineage) int {
+		if c := strings.Compare(x.Route, y.Route); c != 0 {
+			return c
+		}
+		return strings.Compare(x.Method, y.Method)
+	})
+	return lineage, nil
+}
+
+// stepToCallees returns the functions called by the given ones that are not
+// reached yet, marking them reached
+func (a *graphAnalyzerImpl) stepToCallees(ctx context.Context, functions []ast.NodeID, reached map[ast.NodeID]bool, opts LineageOptions) ([]ast.NodeID, error) {
+	ids := make([]int64, len(functions))
+	for i, id := range functions {
+		ids[i] = int64(id)
+	}
+	query := fmt.Sprintf(`
+		MATCH (caller:Function)-[:CONTAINS*]->(:FunctionCall)-[:%s]->(callee:Function)
+		WHERE caller.id IN $ids
+		RETURN DISTINCT callee.id AS id
+	`, callRelations(CallGraphOptions{IncludePossibleCalls: opts.IncludePossibleCalls}))
+	records, err := a.graph.ExecuteRead(ctx, query, map[string]any{"ids": ids})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query callees: %w", err)
+	}
+
+	var next []ast.NodeID
+	for _, record := range records {
+		id := ast.NodeID(toInt64(record["id"]))
+		if !reached[id] {
+			reached[id] = true
+			next = append(next, id)
+		}
+	}
+	return next, nil
+}
+
+// addLineageTables records the tables the given functions read and write
+func (a *graphAnalyzerImpl) addLineageTables(ctx context.Context, entry *EndpointLineage, ids []int64) error {
+	query := `
+		MATCH (fn:Function)-[r:READS_TABLE|WRITES_TABLE]->(t:Table)
+		WHERE fn.id IN $ids
+		RETURN DISTINCT t.name AS name, t.md_schema AS schema, type(r) AS access
+	`
+	records, err := a.graph.ExecuteRead(ctx, query, map[string]any{"ids": ids})
+	if err != nil {
+		return fmt.Errorf("failed to query tables: %w", err)
+	}
+	for _, record := range records {
+		table := toString(record["name"])
+		if schema := toString(record["schema"]); schema != "" {
+			table = schema + "." + table
+		}
+		if toString(record["access"]) == "WRITES_TABLE" {
+			entry.TablesWritten = append(entry.TablesWritten, table)
+		} else {
+			entry.TablesRead = append(entry.TablesRead, table)
+		}
+	}
+	entry.TablesRead = slices.Compact(slices.Sorted(slices.Values(entry.TablesRead)))
+	entry.TablesWritten = slices.Compact(slices.Sorted(slices.Values(entry.TablesWritten)))
+	return nil
+}
+
+// addLineageCalls records the outbound calls the given functions make,
+// split into calls to other services and to external APIs
+func (a *graphAnalyzerImpl) addLineageCalls(ctx context.Context, entry *EndpointLineage, ids []int64) error {
+	query := `
+		MATCH (fn:Function)-[:CONTAINS*]->(call:FunctionCall)
+		WHERE fn.id IN $ids AND call.md_outbound IS NOT NULL
+		MATCH (file:FileScope {fileId: call.fileId})
+		RETURN call.id AS callId, call.name AS callName, call.md_outbound AS protocol,
+		       call.md_outbound_target AS target, call.range AS range, call.fileId AS fileId,
+		       file.path AS path, fn.id AS functionId, fn.name AS functionName
+		ORDER BY path, callId
+	`
+	records, err := a.graph.ExecuteRead(ctx, query, map[string]any{"ids": ids})
+	if err != nil {
+		return fmt.Errorf("failed to query outbound calls: %w", err)
+	}
+	seen := make(map[ast.NodeID]bool)
+	for _, record := range records {
+		callID := ast.NodeID(toInt64(record["callId"]))
+		if seen[callID] {
+			// Reached again through a function enclosing its own
+			continue
+		}
+		seen[callID] = true
+		call := &OutboundCall{
+			CallID:       callID,
+			Call:         toString(record["callName"]),
+			Protocol:     toString(record["protocol"]),
+			Target:       toString(record["target"]),
+			FunctionID:   ast.NodeID(toInt64(record["functionId"])),
+			FunctionName: toString(record["functionName"]),
+			FilePath:     toString(record["path"]),
+			FileID:       int32(toInt64(record["fileId"])),
+			Line:         parseRange(toString(record["range"])).Start.Line + 1,
+		}
+		var kind string
+		kind, call.Host = parse.ClassifyOutbound(call.Protocol, call.Target)
+		if kind == parse.OutboundService {
+			entry.Services = append(entry.Services, call)
+		} else {
+			entry.ExternalAPIs = append(entry.ExternalAPIs, call)
+		}
+	}
+	return nil
+}
+
+// -----------------------------------------------------------------------------
+// Code Metrics
+// -----------------------------------------------------------------------------
+
+// complexityMetrics maps the metrics functions can be sorted by to their
+// node properties
+var complexityMetrics = map[string]string{
+	"complexity": "md_" + parse.MetaComplexity,
+	"loc":        "md_" + parse.MetaLOC,
+	"params":     "md_" + parse.MetaParams,
+	"nesting":    "md_" + parse.MetaNesting,
+	"callees":    "md_" + codegraph.ViewTransitiveCallees,
+}
+
+func (a *graphAnalyzerImpl) ListComplexFunctions(ctx context.Context, repoName string, opts ComplexityOptions) ([]*FunctionMetricsInfo, error) {
+	if opts.SortBy == "" {
+		opts.SortBy = "complexity"
+	}
+	property, ok := complexityMetrics[opts.SortBy]
+	if !ok {
+		return nil, fmt.Errorf("unknown metric %q", opts.SortBy)
+	}
+	if opts.Limit <= 0 {
+		opts.Limit = 20
+	}
+
+	query := fmt.Sprintf(`
+		MATCH (f:FileScope {repo: $repo})
+		MATCH (fn:Function {fileId: f.fileId})
+		WHERE fn.md_complexity IS NOT NULL
+		OPTIONAL MATCH (c:Class)-[:CONTAINS]->(fn)
+		RETURN fn.id AS id, fn.name AS name, c.name AS className, f.path AS path, f.fileId AS fileId,
+		       fn.range AS range, fn.md_complexity AS complexity, fn.md_loc AS loc,
+		       fn.md_params AS params, fn.md_nesting AS nesting, fn.md_view_transitive_callees AS callees
+		ORDER BY fn.%s DESC, fn.md_complexity DESC, path, name
+		LIMIT $limit
+	`, property)
+	records, err := a.graph.ExecuteRead(ctx, query, map[string]any{"repo": repoName, "limit": int64(opts.Limit)})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list complex functions: %w", err)
+	}
+
+	functions := make([]*FunctionMetricsInfo, 0, len(records))
+	for _, record := range records {
+		functions = append(functions, &FunctionMetricsInfo{
+			ID:         ast.NodeID(toInt64(record["id"])),
+			Name:       toString(record["name"]),
+			ClassName:  toString(record["className"]),
+			FilePath:   toString(record["path"]),
+			FileID:     int32(toInt64(record["fileId"])),
+			Line:       parseRange(toString(record["range"])).Start.Line + 1,
+			Complexity: int(toInt64(record["complexity"])),
+			LOC:        int(toInt64(record["loc"])),
+			Params:     int(toInt64(record["params"])),
+			Nesting:    int(toInt64(record["nesting"])),
+
+			TransitiveCallees: int(toInt64(record["callees"])),
+		})
+	}
+	return functions, nil
+}
+
+func (a *graphAnalyzerImpl) ListPackageCoupling(ctx context.Context, repoName string, opts PackageCouplingOptions) ([]*PackageMetricsInfo, error) {
+	order := "fanIn DESC, fanOut DESC"
+	switch opts.SortBy {
+	case "", "fan_in":
+	case "fan_out":
+		order = "fanOut DESC, fanIn DESC"
+	default:
+		return nil, fmt.Errorf("unknown sort %q", opts.SortBy)
+	}
+	if opts.Limit <= 0 {
+		opts.Limit = 20
+	}
+
+	query := fmt.Sprintf(`
+		MATCH (f:FileScope {repo: $repo})
+		WITH f.path AS path, max(f.fileId) AS fileId
+		MATCH (fs:FileScope {repo: $repo, fileId: fileId})
+		WHERE fs.md_view_package IS NOT NULL
+		RETURN fs.md_view_package AS package, count(fs) AS files,
+		       max(fs.md_view_package_fan_in) AS fanIn, max(fs.md_view_package_fan_out) AS fanOut
+		ORDER BY %s, package
+		LIMIT $limit
+	`, order)
+	records, err := a.graph.ExecuteRead(ctx, query, map[string]any{"repo": repoName, "limit": int64(opts.Limit)})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list package coupling: %w", err)
+	}
+
+	packages := make([]*PackageMetricsInfo, 0, len(records))
+	for _, record := range records {
+		packages = append(packages, &PackageMetricsInfo{
+			Package: toString(record["package"]),
+			Files:   int(toInt64(record["files"])),
+			FanIn:   int(toInt64(record["fanIn"])),
+			FanOut:  int(toInt64(record["fanOut"])),
+		})
+	}
+	return packages, nil
+}
+
+func (a *graphAnalyzerImpl) ListHotspots(ctx context.Context, repoName string, opts HotspotOptions) ([]*FunctionHotspot, error) {
+	if opts.Limit <= 0 {
+		opts.Limit = 20
+	}
+
+	query := `
+		MATCH (f:FileScope {repo: $repo})
+		MATCH (fn:Function {fileId: f.fileId})
+		WHERE fn.md_complexity IS NOT NULL AND fn.md_churn_score > 0
+		OPTIONAL MATCH (c:Class)-[:CONTAINS]->(fn)
+		WITH fn, c, f, fn.md_churn_score * fn.md_complexity AS score
+		RETURN fn.id AS id, fn.name AS name, c.name AS className, f.path AS path, f.fileId AS fileId,
+		       fn.range AS range, fn.md_complexity AS complexity, fn.md_churn_score AS churnScore,
+		       fn.md_churn_commit_count AS commits, fn.md_churn_last_commit AS lastCommit,
+		       fn.md_churn_main_contributors AS contributors, score
+		ORDER BY score DESC, path, name
+		LIMIT $limit
+	`
+	records, err := a.graph.ExecuteRead(ctx, query, map[string]any{"repo": repoName, "limit": int64(opts.Limit)})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list hotspots: %w", err)
+	}
+
+	hotspots := make([]*FunctionHotspot, 0, len(records))
+	for _, record := range records {
+		hotspot := &FunctionHotspot{
+			ID:         ast.NodeID(toInt64(record["id"])),
+			Name:       toString(record["name"]),
+			ClassName:  toString(record["className"]),
+			FilePath:   toString(record["path"]),
+			FileID:     int32(toInt64(record["fileId"])),
+			Line:       parseRange(toString(record["range"])).Start.Line + 1,
+			Complexity: int(toInt64(record["complexity"])),
+			ChurnScore: toFloat64(record["churnScore"]),
+			Commits:    int(toInt64(record["commits"])),
+			LastCommit: toString(record["lastCommit"]),
+			Score:      toFloat64(record["score"]),
+		}
+		contributors, _ := record["contributors"].([]any)
+		for _, c := range contributors {
+			if name, ok := c.(string); ok {
+				hotspot.MainContributors = append(hotspot.MainContributors, name)
+			}
+		}
+		hotspots = append(hotspots, hotspot)
+	}
+	return hotspots, nil
+}
+
+// -----------------------------------------------------------------------------
+// Version History
+// -----------------------------------------------------------------------------
+
+func (a *graphAnalyzerImpl) GetFunctionHistory(ctx context.Context, functionID ast.NodeID, maxVersions int) (*FunctionHistory, error) {
+	if maxVersions <= 0 {
+		maxVersions = 20
+	}
+
+	// Start from the newest version so an older ID returns the whole chain
+	headQuery := `
+		MATCH (f:Function {id: $id})
+		OPTIONAL MATCH (newer:Function)-[:PREVIOUS_VERSION*1..]->(f)
+		WHERE NOT ()-[:PREVIOUS_VERSION]->(newer)
+		RETURN coalesce(newer.id, f.id) AS headId
+		LIMIT 1
+	`
+	records, err := a.graph.ExecuteRead(ctx, headQuery, map[string]any{"id": int64(functionID)})
+	if err != nil {
+		return nil, fmt.Errorf("failed to find function: %w", err)
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("function not found: %d", functionID)
+	}
+
+	query := `
+		MATCH p = (head:Function {id: $id})-[:PREVIOUS_VERSION*0..]->(fn:Function)
+		WITH fn, length(p) AS depth
+		ORDER BY depth
+		LIMIT $limit
+		OPTIONAL MATCH (f:FileScope {fileId: fn.fileId})
+		OPTIONAL MATCH (c:Class)-[:CONTAINS]->(fn)
+		RETURN fn.id AS id, fn.name AS name, c.name AS className, f.path AS path, fn.fileId AS fileId,
+		       fn.range AS range, fn.md_body_hash AS bodyHash, depth
+		ORDER BY depth
+	`
+	params := map[string]any{"id": toInt64(records[0]["headId"]), "limit": int64(maxVersions + 1)}
+	records, err = a.graph.ExecuteRead(ctx, query, params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get function versions: %w", err)
+	}
+
+	history := &FunctionHistory{Versions: make([]*FunctionVersion, 0, len(records))}
+	for _, record := range records {
+		history.Versions = append(history.Versions, &FunctionVersion{
+			ID:        ast.NodeID(toInt64(record["id"])),
+			Name:      toString(record["name"]),
+			ClassName: toString(record["className"]),
+			FilePath:  toString(record["path"]),
+			FileID:    int32(toInt64(record["fileId"])),
+			Range:     parseRange(toString(record["range"])),
+			BodyHash:  toString(record["bodyHash"]),
+		})
+	}
+	if len(history.Versions) > maxVersions {
+		history.Versions = history.Versions[:maxVersions]
+		history.Truncated = true
+	}
+	markVersionChanges(history.Versions)
+	return history, nil
+}
+
+func (a *graphAnalyzerImpl) ListFunctions(ctx context.Context, repoName, pathPrefix string) ([]*FunctionVersion, error) {
+	query := `
+		MATCH (f:FileScope {repo: $repo})
+		WHERE f.path STARTS WITH $prefix
+		WITH f.path AS path, max(f.fileId) AS fileId
+		MATCH (fn:Function {fileId: fileId})
+		OPTIONAL MATCH (c:Class)-[:CONTAINS]->(fn)
+		RETURN fn.id AS id, fn.name AS name, c.name AS className, path, fileId,
+		       fn.range AS range, fn.md_body_hash AS bodyHash
+		ORDER BY path, className, name
+	`
+	records, err := a.graph.ExecuteRead(ctx, query, map[string]any{"repo": repoName, "prefix": pathPrefix})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list functions: %w", err)
+	}
+
+	functions := make([]*FunctionVersion, 0, len(records))
+	for _, record := range records {
+		functions = append(functions, &FunctionVersion{
+			ID:        ast.NodeID(toInt64(record["id"])),
+			Name:      toString(record["name"]),
+			ClassName: toString(record["className"]),
+			FilePath:  toString(record["path"]),
+			FileID:    int32(toInt64(record["fileId"])),
+			Range:     parseRange(toString(record["range"])),
+			BodyHash:  toString(record["bodyHash"]),
+		})
+	}
+	return functions, nil
+}
+
+// markVersionChanges compares each version with the next older one. The
+// oldest version listed has nothing to compare with.
+func markVersionChanges(versions []*FunctionVersion) {
+	for i := 0; i+1 < len(versions); i++ {
+		current, previous := versions[i], versions[i+1]
+		current.Renamed = current.Name != previous.Name
+		current.Changed = current.BodyHash == "" || current.BodyHash != previous.BodyHash
+	}
+}
+
+func (a *graphAnalyzerImpl) GetFunctionHistoryByName(ctx context.Context, repoName, filePath, className, functionName string, maxVersions int) (*FunctionHistory, error) {
+	functionID, err := a.findFunctionID(ctx, repoName, filePath, className, functionName)
+	if err != nil {
+		return nil, err
+	}
+	return a.GetFunctionHistory(ctx, functionID, maxVersions)
+}
+
+// -----------------------------------------------------------------------------
+// Dependency Injection
+// -----------------------------------------------------------------------------
+
+func (a *graphAnalyzerImpl) GetInjectionWiring(ctx context.Context, classID ast.NodeID) (*InjectionWiring, error) {
+	rootQuery := `
+		MATCH (c:Class {id: $classId})
+		RETURN c.id AS id, c.name AS name, c.path AS path
+	`
+	params := map[string]any{"classId": int64(classID)}
+	rootRecords, err := a.graph.ExecuteRead(ctx, rootQuery, params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get class: %w", err)
+	}
+	if len(rootRecords) == 0 {
+		return nil, fmt.Errorf("class not found: %d", classID)
+	}
+
+	result := &InjectionWiring{
+		Class:        classInfoFromRecord(rootRecords[0]),
+		Dependencies: make([]*InjectedClass, 0),
+		Dependents:   make([]*InjectedClass, 0),
+	}
+
+	// Dependencies, with the subclasses that could be injected in their place
+	depQuery := `
+		MATCH (c:Class {id: $classId})-[r:INJECTS]->(dep:Class)
+		OPTIONAL MATCH (impl:Class)-[:INHERITS]->(dep)
+		RETURN dep.id AS id, dep.name AS name, dep.path AS path,
+		       r.md_via AS via, r.md_name AS injectedAs,
+		       collect(impl {.id, .name, .path}) AS implementations
+	`
+	depRecords, err := a.graph.ExecuteRead(ctx, depQuery, params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get injected classes: %w", err)
+	}
+	for _, record := range depRecords {
+		dep := injectedClassFromRecord(record)
+		if impls, ok := record["implementations"].([]any); ok {
+			for _, impl := range impls {
+				if implRecord, ok := impl.(map[string]any); ok {
+					dep.Implementations = append(dep.Implementations, classInfoFromRecord(implRecord))
+				}
+			}
+		}
+		result.Dependencies = append(result.Dependencies, dep)
+	}
+
+	dependentQuery := `
+		MATCH (user:Class)-[r:INJECTS]->(c:Class {id: $classId})
+		RETURN user.id AS id, user.name AS name, user.path AS path,
+		       r.md_via AS via, r.md_name AS injectedAs
+	`
+	dependentRecords, err := a.graph.ExecuteRead(ctx, dependentQuery, params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get injecting classes: %w", err)
+	}
+	for _, record := range dependentRecords {
+		result.Dependents = append(result.Dependents, injectedClassFromRecord(record))
+	}
+
+	return result, nil
+}
+
+func classInfoFromRecord(record map[string]any) *ClassInfo {
+	return &ClassInfo{
+		ID:       ast.NodeID(toInt64(record["id"])),
+		Name:     toString(record["name"]),
+		FilePath: toString(record["path"]),
+	}
+}
+
+func injectedClassFromRecord(record map[string]any) *InjectedClass {
+	return &InjectedClass{
+		Class: classInfoFromRecord(record),
+		Via:   toString(record["via"]),
+		Name:  toString(record["injectedAs"]),
+	}
+}
+
 func (a *graphAnalyzerImpl) GetParentClasses(ctx context.Context, classID ast.NodeID, maxDepth int) ([]*ClassInfo, error) {
 	tree, err := a.GetInheritanceTree(ctx, classID)
 	if err != nil {
@@ -672,7 +1675,11 @@ func (a *graphAnalyzerImpl) GetImpact(ctx context.Context, nodeID ast.NodeID, op
 
 	// Collect call graph impact
 	if opts.IncludeCallGraph {
-		callGraph, err := a.GetCallers(ctx, nodeID, opts.MaxDepth)
+		callGraph, err := a.GetCallGraph(ctx, nodeID, CallGraphOptions{
+			Direction:            DirectionIncoming,
+			MaxDepth:             opts.MaxDepth,
+			IncludePossibleCalls: opts.IncludePossibleCalls,
+		})
 		if err == nil && callGraph != nil {
 			for id, node := range callGraph.Nodes {
 				if seen[id] {
@@ -3,6 +3,7 @@ package codeapi
 import (
 	"context"
 
+	"github.com/armchr/codeapi/internal/entity"
 	"github.com/armchr/codeapi/internal/model/ast"
 )
 
@@ -93,6 +94,17 @@ type RepoReader interface {
 
 	// GetMethodClass returns the class containing a method (nil if top-level function)
 	GetMethodClass(ctx context.Context, methodID ast.NodeID) (*ClassInfo, error)
+
+	// --- Entity References ---
+
+	// EntityRefs returns the public references of the files, classes and
+	// functions among ids. Other nodes are left out.
+	EntityRefs(ctx context.Context, ids []ast.NodeID) (map[ast.NodeID]entity.Ref, error)
+
+	// ResolveEntity returns the node a reference names in the latest version
+	// of its file, or ErrEntityNotFound. Of overloads, the first in the file
+	// is returned.
+	ResolveEntity(ctx context.Context, ref entity.Ref) (*EntityNode, error)
 }
 
 // FileReader provides access to code entities within a specific file
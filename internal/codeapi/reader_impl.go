@@ -3,7 +3,9 @@ package codeapi
 import (
 	"context"
 	"fmt"
+	"slices"
 
+	"github.com/armchr/codeapi/internal/entity"
 	"github.com/armchr/codeapi/internal/model/ast"
 	"github.com/armchr/codeapi/internal/service/codegraph"
 	"github.com/armchr/codeapi/pkg/lsp/base"
@@ -534,6 +536,108 @@ func (r *repoReaderImpl) GetMethodClass(ctx context.Context, methodID ast.NodeID
 	return classes[0], nil
 }
 
+// --- Entity References ---
+
+func (r *repoReaderImpl) EntityRefs(ctx context.Context, ids []ast.NodeID) (map[ast.NodeID]entity.Ref, error) {
+	refs := make(map[ast.NodeID]entity.Ref, len(ids))
+	if len(ids) == 0 {
+		return refs, nil
+	}
+	nodeIDs := make([]int64, len(ids))
+	for i, id := range ids {
+		nodeIDs[i] = int64(id)
+	}
+
+	// Only FileScope nodes carry the path, and a method's class is the one
+	// containing it
+	query := `
+		MATCH (n) WHERE n.id IN $ids AND (n:Class OR n:Function OR n:FileScope)
+		MATCH (f:FileScope {fileId: n.fileId, repo: $repo})
+		OPTIONAL MATCH (c:Class)-[:CONTAINS]->(n)
+		RETURN n.id AS id, labels(n) AS labels, n.name AS name, f.path AS path, c.name AS className
+	`
+	records, err := r.graph.ExecuteRead(ctx, query, map[string]any{"ids": nodeIDs, "repo": r.repoName})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read entity references: %w", err)
+	}
+	for _, record := range records {
+		path := toString(record["path"])
+		var labels []string
+		if values, ok := record["labels"].([]any); ok {
+			for _, v := range values {
+				labels = append(labels, toString(v))
+			}
+		}
+		var ref entity.Ref
+		switch {
+		case slices.Contains(labels, "FileScope"):
+			ref = entity.File(r.repoName, path)
+		case slices.Contains(labels, "Class"):
+			ref = entity.Class(r.repoName, path, toString(record["name"]))
+		default:
+			ref = entity.Function(r.repoName, path, toString(record["className"]), toString(record["name"]))
+		}
+		refs[ast.NodeID(toInt64(record["id"]))] = ref
+	}
+	return refs, nil
+}
+
+func (r *repoReaderImpl) ResolveEntity(ctx context.Context, ref entity.Ref) (*EntityNode, error) {
+	params := map[string]any{"repo": r.repoName, "path": ref.Path}
+	// Entities resolve in the latest version of their file
+	query := `
+		MATCH (f:FileScope {repo: $repo, path: $path})
+		WITH max(f.fileId) AS fileId
+		MATCH (f:FileScope {fileId: fileId})
+	`
+	switch {
+	case ref.Kind == entity.KindFile:
+		query += " RETURN f.id AS id, f.fileId AS fileId, null AS nodeRange"
+	case ref.Kind == entity.KindClass:
+		query += `
+			MATCH (n:Class {fileId: fileId, name: $name})
+			RETURN n.id AS id, n.fileId AS fileId, n.range AS nodeRange
+		`
+		params["name"] = ref.Name
+	case ref.ClassName() != "":
+		query += `
+			MATCH (c:Class {fileId: fileId, name: $className})-[:CONTAINS]->(n:Function {name: $name})
+			RETURN n.id AS id, n.fileId AS fileId, n.range AS nodeRange
+		`
+		params["className"] = ref.ClassName()
+		params["name"] = ref.FunctionName()
+	default:
+		query += `
+			MATCH (n:Function {fileId: fileId, name: $name})
+			WHERE NOT EXISTS { MATCH (:Class)-[:CONTAINS]->(n) }
+			RETURN n.id AS id, n.fileId AS fileId, n.range AS nodeRange
+		`
+		params["name"] = ref.FunctionName()
+	}
+
+	records, err := r.graph.ExecuteRead(ctx, query, params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve %s: %w", ref, err)
+	}
+	var found *EntityNode
+	for _, record := range records {
+		node := &EntityNode{
+			ID:     ast.NodeID(toInt64(record["id"])),
+			FileID: int32(toInt64(record["fileId"])),
+		}
+		if rangeStr, ok := record["nodeRange"].(string); ok {
+			node.Range = parseRange(rangeStr)
+		}
+		if found == nil || node.Range.Start.Line < found.Range.Start.Line {
+			found = node
+		}
+	}
+	if found == nil {
+		return nil, fmt.Errorf("%w: %s", ErrEntityNotFound, ref)
+	}
+	return found, nil
+}
+
 // -----------------------------------------------------------------------------
 // Record Conversion Helpers
 // -----------------------------------------------------------------------------
@@ -553,6 +657,7 @@ func (r *repoReaderImpl) recordsToFileInfos(records []map[string]any) ([]*FileIn
 		}
 		if path, ok := nodeData["path"].(string); ok {
 			file.Path = path
+			file.EntityRef = entity.File(r.repoName, path).String()
 		}
 		if lang, ok := nodeData["language"].(string); ok {
 			file.Language = lang
@@ -861,6 +966,17 @@ func toInt64(v any) int64 {
 	}
 }
 
+func toFloat64(v any) float64 {
+	switch val := v.(type) {
+	case float64:
+		return val
+	case int64:
+		return float64(val)
+	default:
+		return 0
+	}
+}
+
 func toString(v any) string {
 	if s, ok := v.(string); ok {
 		return s
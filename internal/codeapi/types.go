@@ -7,6 +7,8 @@
 package codeapi
 
 import (
+	"errors"
+
 	"github.com/armchr/codeapi/internal/model/ast"
 	"github.com/armchr/codeapi/pkg/lsp/base"
 )
@@ -50,6 +52,10 @@ type ClassInfo struct {
 	Range    base.Range
 	Language string
 
+	// EntityRef is the public reference of the class (see package entity),
+	// set by the API from EntityRefs
+	EntityRef string `json:"entity_ref,omitempty"`
+
 	// Metadata contains additional attributes (e.g., annotations, modifiers)
 	Metadata map[string]any `json:"metadata,omitempty"`
 
@@ -70,6 +76,10 @@ type MethodInfo struct {
 	FileID   int32
 	Range    base.Range
 
+	// EntityRef is the public reference of the function, set by the API
+	// from EntityRefs
+	EntityRef string `json:"entity_ref,omitempty"`
+
 	// Context
 	ClassName   string
 	ClassID     ast.NodeID
@@ -111,6 +121,17 @@ type ParameterInfo struct {
 	Position int
 }
 
+// ErrEntityNotFound is returned by ResolveEntity for references naming no
+// entity of the latest version of their file
+var ErrEntityNotFound = errors.New("entity not found")
+
+// EntityNode is the graph node an entity reference resolves to
+type EntityNode struct {
+	ID     ast.NodeID
+	FileID int32
+	Range  base.Range
+}
+
 // FileInfo contains information about a source file
 type FileInfo struct {
 	ID       ast.NodeID
@@ -119,6 +140,9 @@ type FileInfo struct {
 	FileID   int32
 	RepoName string
 
+	// EntityRef is the public reference of the file
+	EntityRef string `json:"entity_ref,omitempty"`
+
 	// Metadata contains additional attributes
 	Metadata map[string]any `json:"metadata,omitempty"`
 
@@ -203,6 +227,7 @@ type CallNode struct {
 	FileID    int32
 	Depth     int // distance from root
 	Range     base.Range
+	EntityRef string `json:"entity_ref,omitempty"` // set by the API
 }
 
 // CallEdge represents a call relationship
@@ -210,6 +235,7 @@ type CallEdge struct {
 	CallerID ast.NodeID
 	CalleeID ast.NodeID
 	CallSite *Location // where the call occurs
+	Possible bool      // interface call that may dispatch to the callee
 }
 
 // DependencyGraph represents data dependencies
@@ -255,6 +281,24 @@ type InheritanceNode struct {
 	Depth    int
 }
 
+// InjectionWiring represents the dependency injection wiring of a class
+type InjectionWiring struct {
+	Class        *ClassInfo
+	Dependencies []*InjectedClass // classes injected into Class
+	Dependents   []*InjectedClass // classes Class is injected into
+}
+
+// InjectedClass is one end of an INJECTS relation
+type InjectedClass struct {
+	Class *ClassInfo
+	Via   string // "field", "constructor" or "method"
+	Name  string // field or parameter receiving the dependency
+
+	// Implementations lists the classes implementing an injected interface or
+	// extending an injected class, any of which the container may inject
+	Implementations []*ClassInfo `json:"implementations,omitempty"`
+}
+
 // -----------------------------------------------------------------------------
 // Options Types - For controlling query behavior
 // -----------------------------------------------------------------------------
@@ -274,6 +318,10 @@ type CallGraphOptions struct {
 	IncludeExternal bool         // include calls to external packages
 	IncludeTests    bool         // include test files
 	StopAt          []ast.NodeID // don't traverse past these nodes
+
+	// IncludePossibleCalls also follows POSSIBLE_CALLS edges from calls of
+	// interface methods to their implementations
+	IncludePossibleCalls bool
 }
 
 // DefaultCallGraphOptions returns sensible defaults
@@ -4,7 +4,13 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"regexp"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"time"
 
 	"gopkg.in/yaml.v2"
 )
@@ -20,6 +26,50 @@ type Repository struct {
 	Language           string `yaml:"language"`
 	Disabled           bool   `yaml:"disabled,omitempty"`
 	SkipOtherLanguages bool   `yaml:"skip_other_languages,omitempty"`
+	// Languages optionally restricts a mixed-language ("auto") repository to
+	// the listed languages. Ignored for single-language repositories.
+	Languages []string `yaml:"languages,omitempty"`
+	// Tenant owns the repository when tenancy is enabled. Name is then
+	// qualified as <tenant>__<name> after loading.
+	Tenant string `yaml:"tenant,omitempty"`
+	// Chunking overrides the chunking settings of app.yaml for this
+	// repository, field by field
+	Chunking *ChunkingConfig `yaml:"chunking,omitempty"`
+	// VectorShards spreads the chunks of a very large repository over this
+	// many Qdrant collections, by hash of the file path. Changing it needs a
+	// clean re-index of the embeddings.
+	VectorShards int `yaml:"vector_shards,omitempty"`
+	// Encrypt stores the repository's summaries and chunk text encrypted
+	// with its tenant's encryption key, or encryption.key without tenancy
+	Encrypt bool `yaml:"encrypt,omitempty"`
+	// ExternalPaths are path patterns of code kept in the repository but not
+	// part of it, like vendored libraries or generated protobuf output. Such
+	// files are not indexed and calls into them are not followed.
+	ExternalPaths []string `yaml:"external_paths,omitempty"`
+}
+
+// LanguageAuto is the repository language value that enables per-file
+// language detection for mixed-language repositories.
+const LanguageAuto = "auto"
+
+// IsMultiLanguage reports whether the repository's files should be routed by
+// their own detected language rather than by the repository-level Language.
+func (r *Repository) IsMultiLanguage() bool {
+	return strings.EqualFold(r.Language, LanguageAuto)
+}
+
+// IncludesLanguage reports whether a file detected as lang belongs to a
+// mixed-language repository. With no Languages list every language is included.
+func (r *Repository) IncludesLanguage(lang string) bool {
+	if len(r.Languages) == 0 {
+		return true
+	}
+	for _, l := range r.Languages {
+		if strings.EqualFold(l, lang) {
+			return true
+		}
+	}
+	return false
 }
 
 type App struct {
@@ -31,6 +81,180 @@ type App struct {
 	MaxConcurrentFileProcessing int    `yaml:"max_concurrent_file_processing,omitempty"`
 	DebugHTTP                   bool   `yaml:"debug_http,omitempty"` // Log full request/response bodies
 	LogLevel                    string `yaml:"log_level,omitempty"` // debug, info, warn, error (default: info)
+	// RequestTimeoutSeconds bounds query endpoints (/codeapi/v1); the deadline
+	// is carried into graph, vector and relational store calls. 0 disables it.
+	RequestTimeoutSeconds int `yaml:"request_timeout_seconds,omitempty"`
+	// SourceReloadSeconds is how often server mode checks source.yaml for
+	// repository changes (default: 10)
+	SourceReloadSeconds int  `yaml:"source_reload_seconds,omitempty"`
+	DisableSourceReload bool `yaml:"disable_source_reload,omitempty"`
+	// DependencyRetryMaxSeconds caps the backoff between reconnection attempts
+	// to Neo4j, Qdrant and the relational store in server mode (default: 300)
+	DependencyRetryMaxSeconds int `yaml:"dependency_retry_max_seconds,omitempty"`
+	// DisableMetrics turns off the Prometheus /metrics endpoint
+	DisableMetrics bool `yaml:"disable_metrics,omitempty"`
+	// EnablePprof serves Go runtime profiles under /debug/pprof
+	EnablePprof bool `yaml:"enable_pprof,omitempty"`
+	// PublicMode serves a read-only API without raw source: chunk content
+	// and code snippets are left out, summaries are not generated on demand
+	// and endpoints that write or return source answer 403
+	PublicMode bool `yaml:"public_mode,omitempty"`
+	// CursorSecret signs the continuation cursors of function dependency
+	// traversals. Without it a random key is drawn at startup, and cursors
+	// only work on the instance that issued them until it restarts.
+	CursorSecret string `yaml:"cursor_secret,omitempty"`
+	// Concurrency bounds the expensive endpoints so they cannot crowd out
+	// interactive queries
+	Concurrency ConcurrencyConfig `yaml:"concurrency,omitempty"`
+}
+
+// Endpoint classes with their own concurrency limit
+const (
+	EndpointSummaries = "summaries" // summary queries, which may generate summaries with the LLM
+	EndpointTraversal = "traversal" // call graph, impact, data flow, inheritance and diff analysis
+	EndpointIndexing  = "indexing"  // index builds and directory processing
+)
+
+// ConcurrencyConfig limits how many requests of each endpoint class run at
+// once. A request beyond the limit waits up to QueueTimeoutSeconds for a
+// slot and is then answered with 429 Too Many Requests and Retry-After.
+type ConcurrencyConfig struct {
+	Disabled            bool `yaml:"disabled,omitempty"`
+	QueueTimeoutSeconds int  `yaml:"queue_timeout_seconds,omitempty"` // default: 10
+	// Limits maps an endpoint class to its concurrent request limit. Classes
+	// left out keep their default (summaries 4, traversal 8, indexing 2); a
+	// negative limit removes the bound.
+	Limits map[string]int `yaml:"limits,omitempty"`
+}
+
+// GetDefaults returns ConcurrencyConfig with default values applied
+func (c *ConcurrencyConfig) GetDefaults() ConcurrencyConfig {
+	result := *c
+	if result.QueueTimeoutSeconds <= 0 {
+		result.QueueTimeoutSeconds = 10
+	}
+	result.Limits = map[string]int{
+		EndpointSummaries: 4,
+		EndpointTraversal: 8,
+		EndpointIndexing:  2,
+	}
+	for class, limit := range c.Limits {
+		if limit != 0 {
+			result.Limits[class] = limit
+		}
+	}
+	return result
+}
+
+// QueueTimeout returns how long a request may wait for a slot
+func (c *ConcurrencyConfig) QueueTimeout() time.Duration {
+	return time.Duration(c.QueueTimeoutSeconds) * time.Second
+}
+
+// DependencyRetryMaxInterval returns the longest wait between reconnection attempts
+func (a *App) DependencyRetryMaxInterval() time.Duration {
+	if a.DependencyRetryMaxSeconds <= 0 {
+		return 5 * time.Minute
+	}
+	return time.Duration(a.DependencyRetryMaxSeconds) * time.Second
+}
+
+// LoggingConfig controls where logs go and how verbose each module is.
+// The base level is app.log_level.
+type LoggingConfig struct {
+	// Encoding is "json" (default) or "console"; outputs may override it
+	Encoding string `yaml:"encoding,omitempty"`
+	// Outputs default to stdout plus all.log
+	Outputs []LogOutput `yaml:"outputs,omitempty"`
+	// Modules overrides the level per module, e.g. parse: warn, lsp: debug.
+	// Modules: http, db, codegraph, vector, lsp, parse, summary, index.
+	Modules map[string]string `yaml:"modules,omitempty"`
+}
+
+// TracingConfig controls OpenTelemetry tracing of index builds and the
+// store, language server and LLM calls they make. Spans are exported over
+// OTLP/gRPC.
+type TracingConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Endpoint is the collector's host:port (default: localhost:4317)
+	Endpoint string `yaml:"endpoint,omitempty"`
+	// Insecure disables TLS towards the collector
+	Insecure bool              `yaml:"insecure,omitempty"`
+	Headers  map[string]string `yaml:"headers,omitempty"`
+	// ServiceName is reported as service.name (default: codeapi)
+	ServiceName string `yaml:"service_name,omitempty"`
+	// SampleRatio is the fraction of root spans kept, 0 to 1 (default: 1)
+	SampleRatio float64 `yaml:"sample_ratio,omitempty"`
+}
+
+// GetDefaults returns TracingConfig with default values applied
+func (c *TracingConfig) GetDefaults() TracingConfig {
+	result := *c
+	if result.Endpoint == "" {
+		result.Endpoint = "localhost:4317"
+	}
+	if result.ServiceName == "" {
+		result.ServiceName = "codeapi"
+	}
+	if result.SampleRatio <= 0 || result.SampleRatio > 1 {
+		result.SampleRatio = 1
+	}
+	return result
+}
+
+// ResourcesConfig is the machine-wide budget shared by every subsystem that
+// fans out work, so that index builds, ad-hoc file indexing, summaries and
+// embeddings running at the same time cannot oversubscribe the host or the
+// model servers. Per-subsystem settings such as app.num_file_threads still
+// apply; the budget caps their sum. A negative value removes a bound.
+type ResourcesConfig struct {
+	// MaxCPUWorkers bounds files parsed and chunked at once (default: number of CPUs)
+	MaxCPUWorkers int `yaml:"max_cpu_workers,omitempty"`
+	// MaxLLMRequests bounds summary generation calls in flight (default: 4)
+	MaxLLMRequests int `yaml:"max_llm_requests,omitempty"`
+	// MaxEmbeddingRequests bounds embedding calls in flight (default: 4)
+	MaxEmbeddingRequests int `yaml:"max_embedding_requests,omitempty"`
+}
+
+// GetDefaults returns ResourcesConfig with default values applied
+func (c *ResourcesConfig) GetDefaults() ResourcesConfig {
+	result := *c
+	if result.MaxCPUWorkers == 0 {
+		result.MaxCPUWorkers = runtime.NumCPU()
+	}
+	if result.MaxLLMRequests == 0 {
+		result.MaxLLMRequests = 4
+	}
+	if result.MaxEmbeddingRequests == 0 {
+		result.MaxEmbeddingRequests = 4
+	}
+	return result
+}
+
+// LogOutput is one log destination
+type LogOutput struct {
+	Type     string `yaml:"type"`               // stdout, stderr or file
+	Path     string `yaml:"path,omitempty"`     // Log file for type file
+	Encoding string `yaml:"encoding,omitempty"` // Overrides logging.encoding
+	Level    string `yaml:"level,omitempty"`    // Minimum level written to this output
+	// Rotation for file outputs; MaxSizeMB 0 disables rotation
+	MaxSizeMB  int  `yaml:"max_size_mb,omitempty"`
+	MaxBackups int  `yaml:"max_backups,omitempty"`
+	MaxAgeDays int  `yaml:"max_age_days,omitempty"`
+	Compress   bool `yaml:"compress,omitempty"`
+}
+
+// SourceReloadInterval returns the period between source.yaml checks
+func (a *App) SourceReloadInterval() time.Duration {
+	if a.SourceReloadSeconds <= 0 {
+		return 10 * time.Second
+	}
+	return time.Duration(a.SourceReloadSeconds) * time.Second
+}
+
+// RequestTimeout returns the deadline applied to query endpoint requests
+func (a *App) RequestTimeout() time.Duration {
+	return time.Duration(a.RequestTimeoutSeconds) * time.Second
 }
 
 // LanguageServersConfig holds paths to language server executables
@@ -46,16 +270,118 @@ func (lsc LanguageServersConfig) GetLSPPath(language string) string {
 	return lsc[language]
 }
 
+// PoolConfig tunes a store's connection pool and bounds how long a single
+// query may run. Zero fields keep the store's built-in defaults.
+type PoolConfig struct {
+	MaxOpenConns           int `yaml:"max_open_conns"`            // Pool size; gRPC connections for Qdrant
+	MaxIdleConns           int `yaml:"max_idle_conns"`            // Relational stores only
+	ConnMaxLifetimeSeconds int `yaml:"conn_max_lifetime_seconds"` // Recycle connections older than this
+	AcquireTimeoutSeconds  int `yaml:"acquire_timeout_seconds"`   // Neo4j only: wait for a free connection
+	QueryTimeoutSeconds    int `yaml:"query_timeout_seconds"`     // Per-query limit; 0 means none
+}
+
+// WithDefaults fills the unset fields of c from defaults
+func (c PoolConfig) WithDefaults(defaults PoolConfig) PoolConfig {
+	if c.MaxOpenConns <= 0 {
+		c.MaxOpenConns = defaults.MaxOpenConns
+	}
+	if c.MaxIdleConns <= 0 {
+		c.MaxIdleConns = defaults.MaxIdleConns
+	}
+	if c.ConnMaxLifetimeSeconds <= 0 {
+		c.ConnMaxLifetimeSeconds = defaults.ConnMaxLifetimeSeconds
+	}
+	if c.AcquireTimeoutSeconds <= 0 {
+		c.AcquireTimeoutSeconds = defaults.AcquireTimeoutSeconds
+	}
+	if c.QueryTimeoutSeconds <= 0 {
+		c.QueryTimeoutSeconds = defaults.QueryTimeoutSeconds
+	}
+	return c
+}
+
+// ConnMaxLifetime returns the maximum age of a pooled connection
+func (c PoolConfig) ConnMaxLifetime() time.Duration {
+	return time.Duration(c.ConnMaxLifetimeSeconds) * time.Second
+}
+
+// AcquireTimeout returns how long to wait for a free pooled connection
+func (c PoolConfig) AcquireTimeout() time.Duration {
+	return time.Duration(c.AcquireTimeoutSeconds) * time.Second
+}
+
+// QueryTimeout returns the per-query limit, or 0 when queries are unbounded
+func (c PoolConfig) QueryTimeout() time.Duration {
+	return time.Duration(c.QueryTimeoutSeconds) * time.Second
+}
+
 type Neo4jConfig struct {
-	URI      string `yaml:"uri"`
-	Username string `yaml:"username"`
-	Password string `yaml:"password"`
+	URI      string           `yaml:"uri"`
+	Username string           `yaml:"username"`
+	Password string           `yaml:"password"`
+	Pool     PoolConfig       `yaml:"pool"`
+	Retry    Neo4jRetryConfig `yaml:"retry"`
+}
+
+// Neo4jRetryConfig controls how graph queries ride out transient Neo4j
+// failures such as a cluster leader switch: failed queries are retried with
+// jittered exponential backoff, and after repeated failures a circuit
+// breaker pauses graph writes, appending them to a write-ahead log that is
+// replayed once the database answers again. Zero fields keep the defaults.
+type Neo4jRetryConfig struct {
+	MaxAttempts            int    `yaml:"max_attempts"`             // Attempts per query, the first included (default: 4; 1 disables retries)
+	InitialBackoffMs       int    `yaml:"initial_backoff_ms"`       // Wait before the first retry, doubled on each one (default: 200)
+	MaxBackoffMs           int    `yaml:"max_backoff_ms"`           // Longest wait between attempts (default: 5000)
+	BreakerThreshold       int    `yaml:"breaker_threshold"`        // Consecutive failed queries that open the breaker (default: 5)
+	BreakerCooldownSeconds int    `yaml:"breaker_cooldown_seconds"` // Wait between recovery probes while open (default: 15)
+	WALPath                string `yaml:"wal_path"`                 // Write-ahead log of paused writes (default: <workdir>/graph-writes.wal)
+}
+
+// GetDefaults returns Neo4jRetryConfig with default values applied
+func (c Neo4jRetryConfig) GetDefaults() Neo4jRetryConfig {
+	if c.MaxAttempts <= 0 {
+		c.MaxAttempts = 4
+	}
+	if c.InitialBackoffMs <= 0 {
+		c.InitialBackoffMs = 200
+	}
+	if c.MaxBackoffMs <= 0 {
+		c.MaxBackoffMs = 5000
+	}
+	if c.BreakerThreshold <= 0 {
+		c.BreakerThreshold = 5
+	}
+	if c.BreakerCooldownSeconds <= 0 {
+		c.BreakerCooldownSeconds = 15
+	}
+	return c
+}
+
+// InitialBackoff returns the wait before the first retry
+func (c Neo4jRetryConfig) InitialBackoff() time.Duration {
+	return time.Duration(c.InitialBackoffMs) * time.Millisecond
+}
+
+// MaxBackoff returns the longest wait between two attempts
+func (c Neo4jRetryConfig) MaxBackoff() time.Duration {
+	return time.Duration(c.MaxBackoffMs) * time.Millisecond
+}
+
+// BreakerCooldown returns the wait between recovery probes of an open breaker
+func (c Neo4jRetryConfig) BreakerCooldown() time.Duration {
+	return time.Duration(c.BreakerCooldownSeconds) * time.Second
 }
 
 type QdrantConfig struct {
-	Host   string `yaml:"host"`
-	Port   int    `yaml:"port"`
-	APIKey string `yaml:"apikey"`
+	Host   string     `yaml:"host"`
+	Port   int        `yaml:"port"`
+	APIKey string     `yaml:"apikey"`
+	Pool   PoolConfig `yaml:"pool"`
+	// NamedVectors creates new collections with a "content" and a
+	// "signature" named vector, so the signature embedding of a function
+	// lives in the point of its chunk rather than in a point of its own.
+	// Existing collections keep the layout they were created with.
+	NamedVectors bool `yaml:"named_vectors,omitempty"`
 }
 
 type OllamaConfig struct {
@@ -65,9 +391,73 @@ type OllamaConfig struct {
 	Dimension int    `yaml:"dimension"`
 }
 
+// ChunkingConfig controls how files are split into chunks for embedding.
+// Set under chunking in app.yaml, it is the default for every repository;
+// the chunking section of a repository in source.yaml overrides the fields
+// it sets.
 type ChunkingConfig struct {
-	MinConditionalLines int `yaml:"min_conditional_lines"`
-	MinLoopLines        int `yaml:"min_loop_lines"`
+	MinConditionalLines int `yaml:"min_conditional_lines,omitempty"` // default: 5
+	MinLoopLines        int `yaml:"min_loop_lines,omitempty"`        // default: 5
+	MaxChunkTokens      int `yaml:"max_chunk_tokens,omitempty"`      // Function/class chunks over this are also embedded in windows (default: 1024, negative disables)
+	OverlapTokens       int `yaml:"overlap_tokens,omitempty"`        // Tokens repeated between consecutive windows (default: 64, negative for none)
+	// ChunkTypes lists the chunk types emitted for syntax-aware languages,
+	// out of ChunkTypeNames. Empty emits all of them.
+	ChunkTypes []string `yaml:"chunk_types,omitempty"`
+	// ContextHeader prepends the repository, file path and referenced
+	// imports to the text embedded for each chunk (default: false)
+	ContextHeader *bool `yaml:"context_header,omitempty"`
+}
+
+// ChunkTypeNames are the chunk types that chunk_types can select
+var ChunkTypeNames = []string{"file", "class", "function", "conditional", "loop"}
+
+// GetDefaults returns ChunkingConfig with default values applied
+func (c *ChunkingConfig) GetDefaults() ChunkingConfig {
+	result := *c
+	if result.MinConditionalLines <= 0 {
+		result.MinConditionalLines = 5
+	}
+	if result.MinLoopLines <= 0 {
+		result.MinLoopLines = 5
+	}
+	if result.MaxChunkTokens == 0 {
+		result.MaxChunkTokens = 1024
+	}
+	if result.OverlapTokens == 0 {
+		result.OverlapTokens = 64
+	}
+	if result.ContextHeader == nil {
+		result.ContextHeader = new(bool)
+	}
+	return result
+}
+
+// Merge returns c with the fields set in override replacing its own. A nil
+// override returns c unchanged.
+func (c *ChunkingConfig) Merge(override *ChunkingConfig) ChunkingConfig {
+	result := *c
+	if override == nil {
+		return result
+	}
+	if override.MinConditionalLines != 0 {
+		result.MinConditionalLines = override.MinConditionalLines
+	}
+	if override.MinLoopLines != 0 {
+		result.MinLoopLines = override.MinLoopLines
+	}
+	if override.MaxChunkTokens != 0 {
+		result.MaxChunkTokens = override.MaxChunkTokens
+	}
+	if override.OverlapTokens != 0 {
+		result.OverlapTokens = override.OverlapTokens
+	}
+	if len(override.ChunkTypes) > 0 {
+		result.ChunkTypes = override.ChunkTypes
+	}
+	if override.ContextHeader != nil {
+		result.ContextHeader = override.ContextHeader
+	}
+	return result
 }
 
 type BloomFilterConfig struct {
@@ -81,20 +471,197 @@ type IndexBuildingConfig struct {
 	EnableCodeGraph  bool `yaml:"enable_code_graph"`
 	EnableEmbeddings bool `yaml:"enable_embeddings"`
 	EnableSummary    bool `yaml:"enable_summary"`
+
+	// MaxFileSizeKB is the largest file parsed in full (default: 2048).
+	// Generated files above it are handled per LargeFileAction.
+	MaxFileSizeKB int `yaml:"max_file_size_kb"`
+
+	// LargeFileAction is "skip" (default) or "truncate", which indexes the
+	// file up to the last complete line below the limit
+	LargeFileAction string `yaml:"large_file_action"`
+
+	// Profile is "full" (default) or "lite". The lite preset builds the
+	// tree-sitter code graph, chunks and embeddings without starting language
+	// servers or calling an LLM, so CI can build a usable index quickly.
+	Profile string `yaml:"profile"`
+
+	// QualityAlerts compares the quality of each build with the previous
+	// build of the repository and sends a notification when it regresses
+	QualityAlerts QualityAlertsConfig `yaml:"quality_alerts"`
+}
+
+// QualityAlertsConfig sets how much worse than the previous build of a
+// repository a build may be before an alert is sent
+type QualityAlertsConfig struct {
+	Enabled bool `yaml:"enabled"`
+
+	// MaxRatioIncrease is the rise of the unresolved or external call ratio
+	// tolerated, in absolute terms (default: 0.05, five points)
+	MaxRatioIncrease float64 `yaml:"max_ratio_increase,omitempty"`
+
+	// MaxCountIncrease is the rise of the parse failures or of the files
+	// left without chunks tolerated (default: 10)
+	MaxCountIncrease int64 `yaml:"max_count_increase,omitempty"`
+}
+
+// GetDefaults returns QualityAlertsConfig with default values applied
+func (c *QualityAlertsConfig) GetDefaults() QualityAlertsConfig {
+	result := *c
+	if result.MaxRatioIncrease <= 0 {
+		result.MaxRatioIncrease = 0.05
+	}
+	if result.MaxCountIncrease <= 0 {
+		result.MaxCountIncrease = 10
+	}
+	return result
+}
+
+// NotificationsConfig sends alerts as a JSON POST to a webhook. Without a
+// WebhookURL alerts are only logged.
+type NotificationsConfig struct {
+	WebhookURL string `yaml:"webhook_url"`
+
+	// Headers are added to every request, such as an authorization token
+	Headers map[string]string `yaml:"headers,omitempty"`
+
+	// TimeoutSeconds bounds each request (default: 10)
+	TimeoutSeconds int `yaml:"timeout_seconds,omitempty"`
+}
+
+// Timeout returns the time allowed for each webhook request
+func (c *NotificationsConfig) Timeout() time.Duration {
+	if c.TimeoutSeconds <= 0 {
+		return 10 * time.Second
+	}
+	return time.Duration(c.TimeoutSeconds) * time.Second
+}
+
+// Indexing profiles selected by IndexBuildingConfig.Profile
+const (
+	IndexProfileFull = "full"
+	IndexProfileLite = "lite"
+)
+
+// Ways of handling files larger than IndexBuildingConfig.MaxFileSizeKB
+const (
+	LargeFileSkip     = "skip"
+	LargeFileTruncate = "truncate"
+)
+
+// GetDefaults returns IndexBuildingConfig with default values applied
+func (c *IndexBuildingConfig) GetDefaults() IndexBuildingConfig {
+	result := *c
+	if result.MaxFileSizeKB <= 0 {
+		result.MaxFileSizeKB = 2048
+	}
+	if result.LargeFileAction == "" {
+		result.LargeFileAction = LargeFileSkip
+	}
+	return result
+}
+
+// MaxFileSize returns the file size limit in bytes
+func (c *IndexBuildingConfig) MaxFileSize() int64 {
+	return int64(c.MaxFileSizeKB) * 1024
+}
+
+// UsesLanguageServer reports whether post-processing asks language servers
+// to resolve calls. Without them calls are resolved from the code graph
+// alone, which leaves calls the tree-sitter resolvers miss unlinked.
+func (c *IndexBuildingConfig) UsesLanguageServer() bool {
+	return c.Profile != IndexProfileLite
+}
+
+// applyIndexProfile applies the preset of the configured profile, warning
+// about settings it overrides
+func applyIndexProfile(c *IndexBuildingConfig, report *ValidationReport) {
+	if c.Profile != IndexProfileLite {
+		return
+	}
+	if c.EnableSummary {
+		report.warnf("index_building.enable_summary", "ignored by the lite profile")
+	}
+	c.EnableCodeGraph = true
+	c.EnableEmbeddings = true
+	c.EnableSummary = false
 }
 
 type MySQLConfig struct {
-	Host     string `yaml:"host"`
-	Port     int    `yaml:"port"`
-	Username string `yaml:"username"`
-	Password string `yaml:"password"`
-	Database string `yaml:"database"`
+	Host     string     `yaml:"host"`
+	Port     int        `yaml:"port"`
+	Username string     `yaml:"username"`
+	Password string     `yaml:"password"`
+	Database string     `yaml:"database"`
+	Pool     PoolConfig `yaml:"pool"`
+}
+
+// PostgresConfig holds PostgreSQL connection settings, used when db.driver is "postgres"
+type PostgresConfig struct {
+	Host     string     `yaml:"host"`
+	Port     int        `yaml:"port"`
+	Username string     `yaml:"username"`
+	Password string     `yaml:"password"`
+	Database string     `yaml:"database"` // Database used to bootstrap the armchair database (default: postgres)
+	SSLMode  string     `yaml:"sslmode"`  // disable, require, verify-ca, verify-full (default: disable)
+	Pool     PoolConfig `yaml:"pool"`
+}
+
+// SQLiteConfig holds settings for the embedded SQLite store, used when
+// db.driver is "sqlite"
+type SQLiteConfig struct {
+	Path string `yaml:"path"` // Database file (default: <workdir>/codeapi.db)
+}
+
+// Supported relational store drivers
+const (
+	DBDriverMySQL    = "mysql"
+	DBDriverPostgres = "postgres"
+	DBDriverSQLite   = "sqlite"
+)
+
+// DBConfig selects the relational store used for file versions and summaries
+type DBConfig struct {
+	Driver string `yaml:"driver"` // "mysql" (default), "postgres" or "sqlite"
+}
+
+// GetDefaults returns DBConfig with default values applied
+func (c *DBConfig) GetDefaults() DBConfig {
+	result := *c
+	if result.Driver == "" {
+		result.Driver = DBDriverMySQL
+	}
+	return result
 }
 
 type CodeGraphConfig struct {
 	EnableBatchWrites bool `yaml:"enable_batch_writes"`
 	BatchSize         int  `yaml:"batch_size"` // Number of nodes/relations to batch before writing
 	PrintParseTree    bool `yaml:"print_parse_tree"`
+	DeleteBatchSize   int  `yaml:"delete_batch_size"` // Nodes deleted per transaction when cleaning a repository (default: 10000)
+	// Metadata values larger than these are moved from the graph to the
+	// relational store (defaults: 32768 and 4096 bytes, -1 keeps every value
+	// in the graph)
+	MaxNodePropertyBytes int `yaml:"max_node_property_bytes"`
+	MaxEdgePropertyBytes int `yaml:"max_edge_property_bytes"`
+	// MaterializedViews stores transitive call counts on functions and the
+	// fan-in and fan-out of packages after post-processing, refreshed on
+	// re-index for the files that changed
+	MaterializedViews bool `yaml:"materialized_views"`
+}
+
+// GetDefaults returns CodeGraphConfig with default values applied
+func (c *CodeGraphConfig) GetDefaults() CodeGraphConfig {
+	result := *c
+	if result.DeleteBatchSize <= 0 {
+		result.DeleteBatchSize = 10000
+	}
+	if result.MaxNodePropertyBytes == 0 {
+		result.MaxNodePropertyBytes = 32768
+	}
+	if result.MaxEdgePropertyBytes == 0 {
+		result.MaxEdgePropertyBytes = 4096
+	}
+	return result
 }
 
 // GitAnalysisMode defines how git analysis is performed
@@ -159,6 +726,10 @@ type GitChurnConfig struct {
 	// FunctionChurnThreshold is the percentile threshold for function-level analysis
 	// Only files in the top N% by churn will get function-level analysis (default: 10)
 	FunctionChurnThreshold float64 `yaml:"function_churn_threshold"`
+
+	// MainContributors is the number of authors with the most commits recorded
+	// on each file and function (default: 3)
+	MainContributors int `yaml:"main_contributors"`
 }
 
 // ChurnWeights holds the weights for churn score calculation
@@ -189,6 +760,225 @@ func (c *GitChurnConfig) GetDefaults() GitChurnConfig {
 	if result.FunctionChurnThreshold == 0 {
 		result.FunctionChurnThreshold = 10.0
 	}
+	if result.MainContributors == 0 {
+		result.MainContributors = 3
+	}
+	return result
+}
+
+// SandboxConfig controls cleanup of data created by ad-hoc file indexing (indexFile)
+type SandboxConfig struct {
+	// TTLMinutes is how long a sandbox file version is kept after it was last indexed (default: 1440)
+	TTLMinutes int `yaml:"ttl_minutes"`
+
+	// CleanupIntervalMinutes is how often the TTL sweep runs in server mode (default: 60)
+	CleanupIntervalMinutes int `yaml:"cleanup_interval_minutes"`
+
+	// DisableCleanup turns off the periodic sweep; explicit purges still work
+	DisableCleanup bool `yaml:"disable_cleanup"`
+}
+
+// GetDefaults returns SandboxConfig with default values applied
+func (c *SandboxConfig) GetDefaults() SandboxConfig {
+	result := *c
+	if result.TTLMinutes <= 0 {
+		result.TTLMinutes = 24 * 60
+	}
+	if result.CleanupIntervalMinutes <= 0 {
+		result.CleanupIntervalMinutes = 60
+	}
+	return result
+}
+
+// TTL returns the sandbox retention period
+func (c *SandboxConfig) TTL() time.Duration {
+	return time.Duration(c.TTLMinutes) * time.Minute
+}
+
+// TelemetryConfig controls the opt-in usage report. Nothing is collected or
+// sent unless Enabled is true.
+type TelemetryConfig struct {
+	Enabled bool `yaml:"enabled"`
+
+	// Endpoint receives the report as a JSON POST
+	Endpoint string `yaml:"endpoint"`
+
+	// IntervalMinutes is how often server mode sends a report (default: 1440)
+	IntervalMinutes int `yaml:"interval_minutes,omitempty"`
+}
+
+// GetDefaults returns TelemetryConfig with default values applied
+func (c *TelemetryConfig) GetDefaults() TelemetryConfig {
+	result := *c
+	if result.IntervalMinutes <= 0 {
+		result.IntervalMinutes = 24 * 60
+	}
+	return result
+}
+
+// CleanupInterval returns the period between TTL sweeps
+func (c *SandboxConfig) CleanupInterval() time.Duration {
+	return time.Duration(c.CleanupIntervalMinutes) * time.Minute
+}
+
+// WarmupConfig controls the warm-up run of server mode. Nothing is cached
+// until the first request otherwise, so the first queries after a restart
+// wait for language servers to start and caches to fill.
+type WarmupConfig struct {
+	Enabled bool `yaml:"enabled"`
+
+	// Repositories limits warm-up to the named repositories (default: all
+	// enabled ones)
+	Repositories []string `yaml:"repositories,omitempty"`
+
+	// HotFiles is the number of most recently indexed files per repository
+	// preloaded into the graph cache and opened in the language server
+	// (default: 20)
+	HotFiles int `yaml:"hot_files,omitempty"`
+
+	// SkipLanguageServers leaves starting language servers to the first
+	// request that needs one
+	SkipLanguageServers bool `yaml:"skip_language_servers,omitempty"`
+
+	// TimeoutSeconds bounds the whole warm-up (default: 600)
+	TimeoutSeconds int `yaml:"timeout_seconds,omitempty"`
+}
+
+// GetDefaults returns WarmupConfig with default values applied
+func (c *WarmupConfig) GetDefaults() WarmupConfig {
+	result := *c
+	if result.HotFiles <= 0 {
+		result.HotFiles = 20
+	}
+	if result.TimeoutSeconds <= 0 {
+		result.TimeoutSeconds = 600
+	}
+	return result
+}
+
+// Includes reports whether repoName is warmed up
+func (c *WarmupConfig) Includes(repoName string) bool {
+	return len(c.Repositories) == 0 || slices.Contains(c.Repositories, repoName)
+}
+
+// ProvisioningConfig controls the reconciliation of the stores at server
+// startup: the vector collection of every enabled repository, the relational
+// tables and the code graph indexes are checked, and what is missing is
+// created, so that the first request does not find them absent.
+type ProvisioningConfig struct {
+	// Mode is "create" (default) to create what is missing and report what
+	// cannot be fixed, "report" to only report, or "off"
+	Mode string `yaml:"mode"`
+
+	// TimeoutSeconds bounds the reconciliation, which runs before the server
+	// accepts requests (default: 60)
+	TimeoutSeconds int `yaml:"timeout_seconds,omitempty"`
+}
+
+// Provisioning modes
+const (
+	ProvisionCreate = "create"
+	ProvisionReport = "report"
+	ProvisionOff    = "off"
+)
+
+// GetDefaults returns ProvisioningConfig with default values applied
+func (c *ProvisioningConfig) GetDefaults() ProvisioningConfig {
+	result := *c
+	if result.Mode == "" {
+		result.Mode = ProvisionCreate
+	}
+	if result.TimeoutSeconds <= 0 {
+		result.TimeoutSeconds = 60
+	}
+	return result
+}
+
+// RetentionConfig controls how many file versions are kept per path when a
+// repository is compacted
+type RetentionConfig struct {
+	// KeepVersions is the number of most recent versions retained per relative path (default: 2)
+	KeepVersions int `yaml:"keep_versions"`
+}
+
+// GetDefaults returns RetentionConfig with default values applied
+func (c *RetentionConfig) GetDefaults() RetentionConfig {
+	result := *c
+	if result.KeepVersions <= 0 {
+		result.KeepVersions = 2
+	}
+	return result
+}
+
+// NotesConfig controls extraction of TODO-style comments and license
+// headers into the relational store
+type NotesConfig struct {
+	// Enabled adds the notes processor to the indexing pipeline
+	Enabled bool `yaml:"enabled"`
+
+	// Markers are the comment markers recorded, matched case-sensitively as
+	// whole words (default: TODO, FIXME, HACK, XXX)
+	Markers []string `yaml:"markers"`
+
+	// Licenses records the license header at the top of each file (default: true)
+	Licenses *bool `yaml:"licenses"`
+
+	// Blame looks up the author of each note with git blame (default: true)
+	Blame *bool `yaml:"blame"`
+
+	// Embed also stores notes in the vector store for semantic search
+	Embed bool `yaml:"embed"`
+}
+
+// GetDefaults returns NotesConfig with default values applied
+func (c *NotesConfig) GetDefaults() NotesConfig {
+	result := *c
+	if len(result.Markers) == 0 {
+		result.Markers = []string{"TODO", "FIXME", "HACK", "XXX"}
+	}
+	if result.Licenses == nil {
+		enabled := true
+		result.Licenses = &enabled
+	}
+	if result.Blame == nil {
+		enabled := true
+		result.Blame = &enabled
+	}
+	return result
+}
+
+// Feature flag providers with a built-in detector
+const (
+	FlagProviderLaunchDarkly = "launchdarkly"
+	FlagProviderUnleash      = "unleash"
+)
+
+// FeatureFlagsConfig controls which calls are recognised as feature flag
+// checks while building the code graph
+type FeatureFlagsConfig struct {
+	// Providers are the SDKs detected (default: launchdarkly, unleash)
+	Providers []string `yaml:"providers"`
+
+	// Helpers are homegrown flag helpers of the indexed code
+	Helpers []FlagHelperConfig `yaml:"helpers"`
+}
+
+// FlagHelperConfig describes a homegrown feature flag helper. A call is a
+// flag check when its function or method name is one of Methods, or when
+// the called expression, like features.IsEnabled, matches Pattern.
+type FlagHelperConfig struct {
+	Name    string   `yaml:"name"`    // provider recorded on the flags found
+	Methods []string `yaml:"methods"` // e.g. isFeatureOn, flag_enabled
+	Pattern string   `yaml:"pattern"` // regular expression, e.g. ^features\.Is\w+$
+	KeyArg  int      `yaml:"key_arg"` // argument holding the flag key (default: 0)
+}
+
+// GetDefaults returns FeatureFlagsConfig with default values applied
+func (c *FeatureFlagsConfig) GetDefaults() FeatureFlagsConfig {
+	result := *c
+	if len(result.Providers) == 0 {
+		result.Providers = []string{FlagProviderLaunchDarkly, FlagProviderUnleash}
+	}
 	return result
 }
 
@@ -200,13 +990,35 @@ type Config struct {
 	Ollama          OllamaConfig          `yaml:"ollama"`
 	BloomFilter     BloomFilterConfig     `yaml:"bloom_filter"`
 	IndexBuilding   IndexBuildingConfig   `yaml:"index_building"`
+	DB              DBConfig              `yaml:"db"`
 	MySQL           MySQLConfig           `yaml:"mysql"`
+	Postgres        PostgresConfig        `yaml:"postgres"`
+	SQLite          SQLiteConfig          `yaml:"sqlite"`
 	CodeGraph       CodeGraphConfig       `yaml:"code_graph"`
 	GitAnalysis     GitAnalysisConfig     `yaml:"git_analysis"`
 	GitChurn        GitChurnConfig        `yaml:"git_churn"`
 	Summary         SummaryConfig         `yaml:"summary"`
+	Sandbox         SandboxConfig         `yaml:"sandbox"`
+	Retention       RetentionConfig       `yaml:"retention"`
+	Notes           NotesConfig           `yaml:"notes"`
+	FeatureFlags    FeatureFlagsConfig    `yaml:"feature_flags"`
 	LanguageServers LanguageServersConfig `yaml:"language_servers"`
+	Secrets         SecretsConfig         `yaml:"secrets"`
+	Tenancy         TenancyConfig         `yaml:"tenancy"`
+	Encryption      EncryptionConfig      `yaml:"encryption"`
+	Telemetry       TelemetryConfig       `yaml:"telemetry"`
+	Notifications   NotificationsConfig   `yaml:"notifications"`
+	Warmup          WarmupConfig          `yaml:"warmup"`
+	Provisioning    ProvisioningConfig    `yaml:"provisioning"`
+	ExternalPaths   ExternalPathsConfig   `yaml:"external_paths"`
+	Logging         LoggingConfig         `yaml:"logging"`
+	Tracing         TracingConfig         `yaml:"tracing"`
+	Resources       ResourcesConfig       `yaml:"resources"`
 	App             App                   `yaml:"app"`
+
+	// sourceMu guards Source.Repositories, which the server replaces when
+	// source.yaml changes
+	sourceMu sync.RWMutex
 }
 
 // expandEnvVars expands environment variables in the given string
@@ -247,22 +1059,38 @@ func expandEnvVars(s string) string {
 	return s
 }
 
+// LoadConfig loads and validates the app and source configuration. Any
+// error-level validation issue fails the load.
 func LoadConfig(appConfigPath string, sourceConfigPath string) (*Config, error) {
+	cfg, report, err := LoadConfigWithReport(appConfigPath, sourceConfigPath)
+	if err != nil {
+		return nil, err
+	}
+	if err := report.Err(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// LoadConfigWithReport loads the configuration and returns every validation
+// issue alongside it. The error is reserved for files that cannot be read or
+// parsed at all, so callers such as the config check can print the report.
+func LoadConfigWithReport(appConfigPath string, sourceConfigPath string) (*Config, *ValidationReport, error) {
 	if _, err := os.Stat(appConfigPath); os.IsNotExist(err) {
-		return nil, fmt.Errorf("app config file does not exist: %s", appConfigPath)
+		return nil, nil, fmt.Errorf("app config file does not exist: %s", appConfigPath)
 	}
 	if _, err := os.Stat(sourceConfigPath); os.IsNotExist(err) {
-		return nil, fmt.Errorf("source config file does not exist: %s", sourceConfigPath)
+		return nil, nil, fmt.Errorf("source config file does not exist: %s", sourceConfigPath)
 	}
 
 	dataApp, err := ioutil.ReadFile(appConfigPath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read app config file: %w", err)
+		return nil, nil, fmt.Errorf("failed to read app config file: %w", err)
 	}
 
 	dataSource, err := ioutil.ReadFile(sourceConfigPath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read source config file: %w", err)
+		return nil, nil, fmt.Errorf("failed to read source config file: %w", err)
 	}
 
 	// Expand environment variables in both config files
@@ -271,27 +1099,33 @@ func LoadConfig(appConfigPath string, sourceConfigPath string) (*Config, error)
 
 	var configApp Config
 	if err := yaml.Unmarshal(dataApp, &configApp); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal app config: %w", err)
+		return nil, nil, fmt.Errorf("failed to unmarshal app config: %w", err)
 	}
 
 	var configSource Config
 	if err := yaml.Unmarshal(dataSource, &configSource); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal source config: %w", err)
+		return nil, nil, fmt.Errorf("failed to unmarshal source config: %w", err)
 	}
 
+	report := &ValidationReport{}
+	checkUnknownFields(report, appConfigPath, dataApp)
+	checkUnknownFields(report, sourceConfigPath, dataSource)
+
 	// Merge SourceConfig into configApp
 	configApp.Source = configSource.Source
 
-	// Validate repository configurations
-	if err := validateRepositories(&configApp); err != nil {
-		return nil, fmt.Errorf("invalid repository configuration: %w", err)
-	}
-
+	// Pool settings stay in app.yaml unless source.yaml overrides them too
 	if configSource.Neo4j.URI != "" {
+		if configSource.Neo4j.Pool == (PoolConfig{}) {
+			configSource.Neo4j.Pool = configApp.Neo4j.Pool
+		}
 		configApp.Neo4j = configSource.Neo4j
 	}
 
 	if configSource.Qdrant.Host != "" {
+		if configSource.Qdrant.Pool == (PoolConfig{}) {
+			configSource.Qdrant.Pool = configApp.Qdrant.Pool
+		}
 		configApp.Qdrant = configSource.Qdrant
 	}
 
@@ -299,25 +1133,176 @@ func LoadConfig(appConfigPath string, sourceConfigPath string) (*Config, error)
 		configApp.Ollama = configSource.Ollama
 	}
 
-	return &configApp, nil
+	// Resolve secret:// credentials last so references from either file are covered
+	if err := resolveSecrets(&configApp); err != nil {
+		report.errorf("secrets", "failed to resolve secrets: %v", err)
+	}
+
+	qualifyRepositories(configApp.Tenancy, configApp.Source.Repositories)
+	applyIndexProfile(&configApp.IndexBuilding, report)
+
+	report.Issues = append(report.Issues, Validate(&configApp).Issues...)
+	return &configApp, report, nil
+}
+
+// HasRelationalStore reports whether connection settings are present for the
+// configured db.driver. SQLite needs no settings and is always available.
+func (c *Config) HasRelationalStore() bool {
+	switch c.DB.GetDefaults().Driver {
+	case DBDriverPostgres:
+		return c.Postgres.Host != ""
+	case DBDriverSQLite:
+		return true
+	default:
+		return c.MySQL.Host != ""
+	}
 }
 
+// SQLitePath returns the SQLite database file, defaulting to codeapi.db in
+// the working directory
+func (c *Config) SQLitePath() string {
+	if c.SQLite.Path != "" {
+		return c.SQLite.Path
+	}
+	return filepath.Join(c.App.WorkDir, "codeapi.db")
+}
+
+// GraphWALPath returns the write-ahead log that holds graph writes paused
+// while Neo4j is unavailable
+func (c *Config) GraphWALPath() string {
+	if c.Neo4j.Retry.WALPath != "" {
+		return c.Neo4j.Retry.WALPath
+	}
+	return filepath.Join(c.App.WorkDir, "graph-writes.wal")
+}
+
+// GetRepository returns a copy of the named repository's settings. Disabled
+// repositories are not served and are reported as an error.
 func (c *Config) GetRepository(name string) (*Repository, error) {
+	c.sourceMu.RLock()
+	defer c.sourceMu.RUnlock()
+
 	for _, repo := range c.Source.Repositories {
 		if repo.Name == name {
+			if repo.Disabled {
+				return nil, fmt.Errorf("repository is disabled: %s", name)
+			}
 			return &repo, nil
 		}
 	}
 	return nil, fmt.Errorf("repository not found: %s", name)
 }
 
-// validateRepositories validates repository configurations
-func validateRepositories(config *Config) error {
-	for _, repo := range config.Source.Repositories {
-		// If skip_other_languages is true, language must be specified
-		if repo.SkipOtherLanguages && repo.Language == "" {
-			return fmt.Errorf("repository '%s': skip_other_languages is true but language is not specified", repo.Name)
+// MaxVectorShards bounds the collections a repository is spread over; every
+// search queries all of them
+const MaxVectorShards = 64
+
+// VectorShards returns the number of Qdrant collections the chunks of a
+// repository are spread over, 1 when it is not sharded. Disabled
+// repositories keep their shards, so their data can still be cleaned.
+func (c *Config) VectorShards(repoName string) int {
+	c.sourceMu.RLock()
+	defer c.sourceMu.RUnlock()
+
+	for _, repo := range c.Source.Repositories {
+		if repo.Name == repoName {
+			return max(repo.VectorShards, 1)
 		}
 	}
-	return nil
+	return 1
+}
+
+// Repositories returns a snapshot of the configured repositories, safe to
+// iterate while source.yaml is reloaded
+func (c *Config) Repositories() []Repository {
+	c.sourceMu.RLock()
+	defer c.sourceMu.RUnlock()
+	return append([]Repository(nil), c.Source.Repositories...)
+}
+
+// RepositoryDiff lists repository names affected by a source.yaml reload.
+// Enabling a repository counts as adding it and disabling it as removing it.
+type RepositoryDiff struct {
+	Added   []string
+	Removed []string
+	Changed []string // Path, language or language list changed
+}
+
+// Empty reports whether the reload changed nothing that is served
+func (d RepositoryDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// SetRepositories replaces the repository list and reports what changed
+func (c *Config) SetRepositories(repos []Repository) RepositoryDiff {
+	c.sourceMu.Lock()
+	defer c.sourceMu.Unlock()
+
+	diff := DiffRepositories(c.Source.Repositories, repos)
+	c.Source.Repositories = append([]Repository(nil), repos...)
+	return diff
+}
+
+// DiffRepositories compares two repository lists, ignoring disabled entries
+func DiffRepositories(oldRepos, newRepos []Repository) RepositoryDiff {
+	enabled := func(repos []Repository) map[string]Repository {
+		m := make(map[string]Repository, len(repos))
+		for _, r := range repos {
+			if !r.Disabled {
+				m[r.Name] = r
+			}
+		}
+		return m
+	}
+	before, after := enabled(oldRepos), enabled(newRepos)
+
+	var diff RepositoryDiff
+	for _, r := range newRepos {
+		old, existed := before[r.Name]
+		if _, served := after[r.Name]; !served {
+			continue
+		}
+		switch {
+		case !existed:
+			diff.Added = append(diff.Added, r.Name)
+		case old.Path != r.Path || !strings.EqualFold(old.Language, r.Language) ||
+			strings.Join(old.Languages, ",") != strings.Join(r.Languages, ","):
+			diff.Changed = append(diff.Changed, r.Name)
+		}
+	}
+	for _, r := range oldRepos {
+		if _, served := before[r.Name]; !served {
+			continue
+		}
+		if _, still := after[r.Name]; !still {
+			diff.Removed = append(diff.Removed, r.Name)
+		}
+	}
+	return diff
+}
+
+// LoadSourceConfig reads the repository list from source.yaml for a reload.
+// Repository definitions are validated and qualified with their tenant;
+// other sections are ignored because they only take effect at startup.
+func LoadSourceConfig(sourceConfigPath string, tenancy TenancyConfig) ([]Repository, error) {
+	data, err := os.ReadFile(sourceConfigPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read source config file: %w", err)
+	}
+
+	var source Config
+	if err := yaml.Unmarshal([]byte(expandEnvVars(string(data))), &source); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal source config: %w", err)
+	}
+
+	source.Tenancy = tenancy
+	qualifyRepositories(tenancy, source.Source.Repositories)
+
+	report := &ValidationReport{}
+	validateRepositoryDefinitions(&source, report)
+	validateTenancy(&source, report)
+	if err := report.Err(); err != nil {
+		return nil, err
+	}
+	return source.Source.Repositories, nil
 }
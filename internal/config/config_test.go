@@ -2,7 +2,10 @@ package config
 
 import (
 	"os"
+	"path/filepath"
+	"reflect"
 	"testing"
+	"time"
 )
 
 func TestExpandEnvVars(t *testing.T) {
@@ -98,3 +101,237 @@ func TestExpandEnvVars(t *testing.T) {
 		})
 	}
 }
+
+func TestSandboxConfigGetDefaults(t *testing.T) {
+	tests := []struct {
+		name             string
+		input            SandboxConfig
+		expectedTTL      time.Duration
+		expectedInterval time.Duration
+	}{
+		{"zero values use defaults", SandboxConfig{}, 24 * time.Hour, time.Hour},
+		{"explicit values kept", SandboxConfig{TTLMinutes: 30, CleanupIntervalMinutes: 5}, 30 * time.Minute, 5 * time.Minute},
+		{"negative values use defaults", SandboxConfig{TTLMinutes: -1, CleanupIntervalMinutes: -1}, 24 * time.Hour, time.Hour},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := tt.input.GetDefaults()
+			if result.TTL() != tt.expectedTTL {
+				t.Errorf("TTL() = %v, want %v", result.TTL(), tt.expectedTTL)
+			}
+			if result.CleanupInterval() != tt.expectedInterval {
+				t.Errorf("CleanupInterval() = %v, want %v", result.CleanupInterval(), tt.expectedInterval)
+			}
+		})
+	}
+}
+
+func TestRetentionConfigGetDefaults(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    RetentionConfig
+		expected int
+	}{
+		{"zero value uses default", RetentionConfig{}, 2},
+		{"explicit value kept", RetentionConfig{KeepVersions: 5}, 5},
+		{"negative value uses default", RetentionConfig{KeepVersions: -3}, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.input.GetDefaults().KeepVersions; got != tt.expected {
+				t.Errorf("KeepVersions = %d, want %d", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestWarmupConfigIncludes(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    WarmupConfig
+		repoName string
+		expected bool
+	}{
+		{"no list warms all", WarmupConfig{}, "api", true},
+		{"listed repository", WarmupConfig{Repositories: []string{"web", "api"}}, "api", true},
+		{"unlisted repository", WarmupConfig{Repositories: []string{"web"}}, "api", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.input.Includes(tt.repoName); got != tt.expected {
+				t.Errorf("Includes(%q) = %v, want %v", tt.repoName, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestApplyIndexProfile(t *testing.T) {
+	tests := []struct {
+		name         string
+		input        IndexBuildingConfig
+		expected     IndexBuildingConfig
+		wantWarnings int
+	}{
+		{"full profile untouched", IndexBuildingConfig{EnableSummary: true}, IndexBuildingConfig{EnableSummary: true}, 0},
+		{"lite enables graph and embeddings", IndexBuildingConfig{Profile: IndexProfileLite},
+			IndexBuildingConfig{Profile: IndexProfileLite, EnableCodeGraph: true, EnableEmbeddings: true}, 0},
+		{"lite overrides summaries", IndexBuildingConfig{Profile: IndexProfileLite, EnableSummary: true},
+			IndexBuildingConfig{Profile: IndexProfileLite, EnableCodeGraph: true, EnableEmbeddings: true}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := &ValidationReport{}
+			result := tt.input
+			applyIndexProfile(&result, report)
+			if result != tt.expected {
+				t.Errorf("applyIndexProfile = %+v, want %+v", result, tt.expected)
+			}
+			if len(report.Warnings()) != tt.wantWarnings {
+				t.Errorf("got warnings %v, want %d", report.Warnings(), tt.wantWarnings)
+			}
+			if got := result.UsesLanguageServer(); got != (tt.input.Profile != IndexProfileLite) {
+				t.Errorf("UsesLanguageServer() = %v", got)
+			}
+		})
+	}
+}
+
+func TestChunkingConfigMerge(t *testing.T) {
+	enabled := true
+	global := ChunkingConfig{MinConditionalLines: 8, MaxChunkTokens: 512, ChunkTypes: []string{"function"}}
+
+	tests := []struct {
+		name     string
+		override *ChunkingConfig
+		expected ChunkingConfig
+	}{
+		{"nil keeps global", nil, global},
+		{"empty keeps global", &ChunkingConfig{}, global},
+		{"set fields override", &ChunkingConfig{MinLoopLines: 3, MaxChunkTokens: -1, ChunkTypes: []string{"file", "class"}, ContextHeader: &enabled},
+			ChunkingConfig{MinConditionalLines: 8, MinLoopLines: 3, MaxChunkTokens: -1, ChunkTypes: []string{"file", "class"}, ContextHeader: &enabled}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := global.Merge(tt.override); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Merge() = %+v, want %+v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestPoolConfigWithDefaults(t *testing.T) {
+	defaults := PoolConfig{MaxOpenConns: 25, MaxIdleConns: 5, ConnMaxLifetimeSeconds: 300}
+
+	tests := []struct {
+		name         string
+		input        PoolConfig
+		expected     PoolConfig
+		queryTimeout time.Duration
+	}{
+		{"zero values use defaults", PoolConfig{}, defaults, 0},
+		{
+			"explicit values kept",
+			PoolConfig{MaxOpenConns: 50, MaxIdleConns: 10, ConnMaxLifetimeSeconds: 60, QueryTimeoutSeconds: 30},
+			PoolConfig{MaxOpenConns: 50, MaxIdleConns: 10, ConnMaxLifetimeSeconds: 60, QueryTimeoutSeconds: 30},
+			30 * time.Second,
+		},
+		{
+			"negative values use defaults",
+			PoolConfig{MaxOpenConns: -1, QueryTimeoutSeconds: -5},
+			defaults,
+			0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := tt.input.WithDefaults(defaults)
+			if result != tt.expected {
+				t.Errorf("WithDefaults() = %+v, want %+v", result, tt.expected)
+			}
+			if result.QueryTimeout() != tt.queryTimeout {
+				t.Errorf("QueryTimeout() = %v, want %v", result.QueryTimeout(), tt.queryTimeout)
+			}
+		})
+	}
+}
+
+func TestDiffRepositories(t *testing.T) {
+	oldRepos := []Repository{
+		{Name: "kept", Path: "/src/kept", Language: "go"},
+		{Name: "moved", Path: "/src/moved", Language: "go"},
+		{Name: "dropped", Path: "/src/dropped", Language: "java"},
+		{Name: "disabled-later", Path: "/src/d", Language: "go"},
+		{Name: "enabled-later", Path: "/src/e", Language: "go", Disabled: true},
+	}
+	newRepos := []Repository{
+		{Name: "kept", Path: "/src/kept", Language: "go", SkipOtherLanguages: true},
+		{Name: "moved", Path: "/src/elsewhere", Language: "go"},
+		{Name: "disabled-later", Path: "/src/d", Language: "go", Disabled: true},
+		{Name: "enabled-later", Path: "/src/e", Language: "go"},
+		{Name: "new", Path: "/src/new", Language: "python"},
+	}
+
+	got := DiffRepositories(oldRepos, newRepos)
+	want := RepositoryDiff{
+		Added:   []string{"enabled-later", "new"},
+		Removed: []string{"dropped", "disabled-later"},
+		Changed: []string{"moved"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("DiffRepositories() = %+v, want %+v", got, want)
+	}
+	if !DiffRepositories(oldRepos, oldRepos).Empty() {
+		t.Error("diff of identical lists should be empty")
+	}
+}
+
+func TestSetRepositories(t *testing.T) {
+	cfg := &Config{}
+	cfg.Source.Repositories = []Repository{{Name: "a", Path: "/a"}}
+
+	diff := cfg.SetRepositories([]Repository{{Name: "b", Path: "/b"}, {Name: "c", Path: "/c", Disabled: true}})
+	if !reflect.DeepEqual(diff.Added, []string{"b"}) || !reflect.DeepEqual(diff.Removed, []string{"a"}) {
+		t.Errorf("SetRepositories() diff = %+v", diff)
+	}
+
+	if _, err := cfg.GetRepository("a"); err == nil {
+		t.Error("GetRepository(a) should fail after removal")
+	}
+	if _, err := cfg.GetRepository("c"); err == nil {
+		t.Error("GetRepository(c) should fail for a disabled repository")
+	}
+	if repo, err := cfg.GetRepository("b"); err != nil || repo.Path != "/b" {
+		t.Errorf("GetRepository(b) = %v, %v", repo, err)
+	}
+}
+
+func TestLoadSourceConfig(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "source.yaml")
+
+	valid := "source:\n  repositories:\n    - name: repo\n      path: " + dir + "\n      language: go\n"
+	if err := os.WriteFile(path, []byte(valid), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	repos, err := LoadSourceConfig(path, TenancyConfig{})
+	if err != nil {
+		t.Fatalf("LoadSourceConfig() error = %v", err)
+	}
+	if len(repos) != 1 || repos[0].Name != "repo" {
+		t.Errorf("LoadSourceConfig() = %+v", repos)
+	}
+
+	missingPath := "source:\n  repositories:\n    - name: repo\n      path: " + filepath.Join(dir, "missing") + "\n"
+	if err := os.WriteFile(path, []byte(missingPath), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadSourceConfig(path, TenancyConfig{}); err == nil {
+		t.Error("LoadSourceConfig() should reject a repository path that does not exist")
+	}
+}
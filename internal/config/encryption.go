@@ -0,0 +1,89 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/armchr/codeapi/internal/encryption"
+)
+
+// EncryptionConfig holds the key of repositories that set encrypt but have
+// no tenant, or whose tenant has no encryption_key of its own
+type EncryptionConfig struct {
+	// Key is a base64 AES-256 key, normally a secret:// reference
+	Key string `yaml:"key,omitempty"`
+}
+
+// encryptionKey returns the key a repository's values are encrypted with,
+// empty when none is configured. Callers hold sourceMu.
+func (c *Config) encryptionKey(repo *Repository) string {
+	if c.Tenancy.Enabled && repo.Tenant != "" {
+		for _, tenant := range c.Tenancy.Tenants {
+			if tenant.Name == repo.Tenant && tenant.EncryptionKey != "" {
+				return tenant.EncryptionKey
+			}
+		}
+	}
+	return c.Encryption.Key
+}
+
+// RepoCipher returns the cipher for the summaries and chunk text of a
+// repository, nil when it has no key. A repository with a key that does not
+// set encrypt gets a decrypt-only cipher, so what it wrote while encrypt was
+// set stays readable after it is turned off.
+func (c *Config) RepoCipher(repoName string) (*encryption.Cipher, error) {
+	c.sourceMu.RLock()
+	defer c.sourceMu.RUnlock()
+
+	for i := range c.Source.Repositories {
+		repo := &c.Source.Repositories[i]
+		if repo.Name != repoName {
+			continue
+		}
+		encoded := c.encryptionKey(repo)
+		if encoded == "" {
+			if repo.Encrypt {
+				return nil, fmt.Errorf("repository %s sets encrypt but has no encryption key", repoName)
+			}
+			return nil, nil
+		}
+		key, err := encryption.ParseKey(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("encryption key of repository %s: %w", repoName, err)
+		}
+		cipher, err := encryption.NewCipher(key, repoName)
+		if err != nil {
+			return nil, err
+		}
+		if !repo.Encrypt {
+			return cipher.DecryptOnly(), nil
+		}
+		return cipher, nil
+	}
+	return nil, nil
+}
+
+// validateEncryption checks that every configured key parses and that
+// repositories setting encrypt have one. Keys still holding a secret
+// reference were not resolved, which is reported under secrets already.
+func validateEncryption(c *Config, report *ValidationReport) {
+	checkKey := func(field, key string) {
+		if key == "" || IsSecretRef(key) {
+			return
+		}
+		if _, err := encryption.ParseKey(key); err != nil {
+			report.errorf(field, "%v", err)
+		}
+	}
+	checkKey("encryption.key", c.Encryption.Key)
+	for i, tenant := range c.Tenancy.Tenants {
+		checkKey(fmt.Sprintf("tenancy.tenants[%d].encryption_key", i), tenant.EncryptionKey)
+	}
+
+	for i := range c.Source.Repositories {
+		repo := &c.Source.Repositories[i]
+		if repo.Encrypt && c.encryptionKey(repo) == "" {
+			report.errorf(fmt.Sprintf("source.repositories[%s].encrypt", repo.Name),
+				"no encryption key: set encryption_key on the tenant or encryption.key")
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package config
+
+import (
+	"bytes"
+	"encoding/base64"
+	"testing"
+
+	"github.com/armchr/codeapi/internal/encryption"
+)
+
+func TestRepoCipher(t *testing.T) {
+	tenantKey := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{1}, 32))
+	globalKey := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{2}, 32))
+	cfg := &Config{
+		Tenancy: TenancyConfig{Enabled: true, Tenants: []TenantConfig{
+			{Name: "acme", EncryptionKey: tenantKey},
+			{Name: "globex"},
+		}},
+		Encryption: EncryptionConfig{Key: globalKey},
+		Source: SourceConfig{Repositories: []Repository{
+			{Name: "acme__api", Tenant: "acme", Encrypt: true},
+			{Name: "acme__web", Tenant: "acme"},
+			{Name: "globex__api", Tenant: "globex", Encrypt: true},
+		}},
+	}
+
+	tenantCipher, err := cfg.RepoCipher("acme__api")
+	if err != nil || tenantCipher == nil {
+		t.Fatalf("RepoCipher(acme__api) = %v, %v", tenantCipher, err)
+	}
+	encrypted, _ := tenantCipher.Encrypt("summary")
+
+	tests := []struct {
+		name        string
+		repo        string
+		wantCipher  bool
+		wantEncrypt bool
+		readsAcme   bool
+	}{
+		{name: "tenant key", repo: "acme__api", wantCipher: true, wantEncrypt: true, readsAcme: true},
+		{name: "encrypt off keeps reads", repo: "acme__web", wantCipher: true},
+		{name: "tenant without key uses global key", repo: "globex__api", wantCipher: true, wantEncrypt: true},
+		{name: "unknown repository", repo: "other"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := cfg.RepoCipher(tt.repo)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if (c != nil) != tt.wantCipher {
+				t.Fatalf("RepoCipher() = %v, want cipher %v", c, tt.wantCipher)
+			}
+			if c == nil {
+				return
+			}
+			written, _ := c.Encrypt("x")
+			if (written != "x") != tt.wantEncrypt {
+				t.Errorf("Encrypt() = %q, want encrypted %v", written, tt.wantEncrypt)
+			}
+			// Values are bound to their repository, so only acme__api
+			// itself reads what it wrote
+			if _, err := c.Decrypt(encrypted, encryption.Version); (err == nil) != tt.readsAcme {
+				t.Errorf("Decrypt() error = %v", err)
+			}
+		})
+	}
+
+	cfg.Encryption.Key = ""
+	if _, err := cfg.RepoCipher("globex__api"); err == nil {
+		t.Error("RepoCipher() without any key succeeded")
+	}
+}
@@ -0,0 +1,107 @@
+package config
+
+import (
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// defaultExternalPaths are where the dependencies, toolchains and build
+// output of each language live. A definition found under one of them is
+// external to the repository: calls to it are not followed and it is not
+// indexed.
+var defaultExternalPaths = map[string][]string{
+	"go":         {"vendor"},
+	"python":     {"site-packages", "dist-packages", ".venv", "node_modules"},
+	"typescript": {"node_modules"},
+	"java": {
+		".m2", ".gradle", "target", "build", "out",
+		"jdk*", "jre*", "Library/Java", "usr/lib/jvm", "rt.jar",
+		"jdt.ls", "jdt-language-server",
+	},
+	"csharp": {
+		".nuget", "packages", "dotnet",
+		"Microsoft.NETCore.App", "Microsoft.AspNetCore.App",
+	},
+}
+
+// externalPathLanguages maps the language names repositories may use to the
+// keys of defaultExternalPaths
+var externalPathLanguages = map[string]string{
+	"golang":     "go",
+	"py":         "python",
+	"javascript": "typescript",
+	"js":         "typescript",
+	"ts":         "typescript",
+	"kotlin":     "java",
+	"c#":         "csharp",
+}
+
+// ExternalPathsConfig replaces the built-in external path patterns of
+// languages, keyed by language. Repositories add their own with
+// external_paths in source.yaml.
+type ExternalPathsConfig map[string][]string
+
+// ExternalPathPatterns returns the path patterns marking code outside repo
+// for files of language: the patterns of the language, from app.yaml or
+// built in, followed by the repository's own. repo may be nil.
+func (c *Config) ExternalPathPatterns(language string, repo *Repository) []string {
+	language = strings.ToLower(language)
+	if alias, ok := externalPathLanguages[language]; ok {
+		language = alias
+	}
+
+	patterns, ok := c.ExternalPaths[language]
+	if !ok {
+		patterns = defaultExternalPaths[language]
+	}
+	if repo == nil || len(repo.ExternalPaths) == 0 {
+		return patterns
+	}
+	return append(append([]string{}, patterns...), repo.ExternalPaths...)
+}
+
+// IsExternalPath reports whether a path lies under one of patterns. A
+// pattern is a slash-separated run of path segments, each a path.Match
+// glob, matching anywhere in the path: "vendor" matches any directory named
+// vendor, "gen/proto" a proto directory inside gen, "*.pb.go" generated Go
+// protobuf files. Paths inside root are matched relative to it, so the
+// directories above a repository never make its files external.
+func IsExternalPath(p, root string, patterns []string) bool {
+	if len(patterns) == 0 {
+		return false
+	}
+	p = filepath.ToSlash(p)
+	if root != "" {
+		if rel, err := filepath.Rel(root, filepath.FromSlash(p)); err == nil && filepath.IsLocal(rel) {
+			p = filepath.ToSlash(rel)
+		}
+	}
+	segments := strings.FieldsFunc(p, func(r rune) bool { return r == '/' })
+	for _, pattern := range patterns {
+		if matchSegments(segments, strings.FieldsFunc(pattern, func(r rune) bool { return r == '/' })) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchSegments reports whether pattern matches a contiguous run of segments
+func matchSegments(segments, pattern []string) bool {
+	if len(pattern) == 0 {
+		return false
+	}
+	for start := 0; start+len(pattern) <= len(segments); start++ {
+		matched := true
+		for i, glob := range pattern {
+			if ok, err := path.Match(glob, segments[start+i]); err != nil || !ok {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
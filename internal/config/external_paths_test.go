@@ -0,0 +1,59 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestIsExternalPath(t *testing.T) {
+	tests := []struct {
+		name     string
+		path     string
+		root     string
+		patterns []string
+		want     bool
+	}{
+		{"directory name", "/usr/lib/python3/site-packages/requests/api.py", "/src/app", []string{"site-packages"}, true},
+		{"segment, not substring", "/src/app/my-site-packages-tool/main.py", "/src/app", []string{"site-packages"}, false},
+		{"multi-segment pattern", "/home/me/.m2/repository/org/lib.jar", "/src/app", []string{".m2/repository"}, true},
+		{"glob on file name", "/src/app/api/order.pb.go", "/src/app", []string{"*.pb.go"}, true},
+		{"glob on directory", "/opt/jdk-17/lib/src.zip", "/src/app", []string{"jdk*"}, true},
+		{"directories above root ignored", "/home/build/app/main.go", "/home/build/app", []string{"build"}, false},
+		{"outside root matched whole", "/home/build/lib/util.go", "/home/build/app", []string{"build"}, true},
+		{"no patterns", "/src/app/vendor/x.go", "/src/app", nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsExternalPath(tt.path, tt.root, tt.patterns); got != tt.want {
+				t.Errorf("IsExternalPath(%q, %q, %v) = %v, want %v", tt.path, tt.root, tt.patterns, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExternalPathPatterns(t *testing.T) {
+	cfg := &Config{ExternalPaths: ExternalPathsConfig{"python": {".venv", "site-packages"}}}
+	repo := &Repository{ExternalPaths: []string{"gen"}}
+
+	tests := []struct {
+		name     string
+		language string
+		repo     *Repository
+		want     []string
+	}{
+		{"built in", "go", nil, []string{"vendor"}},
+		{"alias", "golang", repo, []string{"vendor", "gen"}},
+		{"configured replaces built in", "Python", nil, []string{".venv", "site-packages"}},
+		{"unknown language keeps repository patterns", "ruby", repo, []string{"gen"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := cfg.ExternalPathPatterns(tt.language, tt.repo); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ExternalPathPatterns(%q) = %v, want %v", tt.language, got, tt.want)
+			}
+		})
+	}
+	if defaults := defaultExternalPaths["go"]; len(defaults) != 1 {
+		t.Errorf("repository patterns leaked into the defaults: %v", defaults)
+	}
+}
@@ -0,0 +1,275 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+)
+
+// SecretRefPrefix marks a credential value that is looked up in the
+// configured secret provider instead of being read literally. The form is
+// secret://<path>#<key>; without #<key> the whole secret value is used.
+const SecretRefPrefix = "secret://"
+
+// Supported secret providers
+const (
+	SecretProviderVault = "vault"
+	SecretProviderAWS   = "aws"
+)
+
+// SecretsConfig selects an external secret store for credentials
+type SecretsConfig struct {
+	Provider string            `yaml:"provider"` // "vault" or "aws"; empty disables secret:// lookups
+	Vault    VaultSecretConfig `yaml:"vault"`
+	AWS      AWSSecretConfig   `yaml:"aws"`
+	// TimeoutSeconds bounds each provider request (default: 10)
+	TimeoutSeconds int `yaml:"timeout_seconds"`
+}
+
+// VaultSecretConfig holds settings for HashiCorp Vault's KV v2 engine
+type VaultSecretConfig struct {
+	Address   string `yaml:"address"`   // Default: VAULT_ADDR
+	Token     string `yaml:"token"`     // Default: VAULT_TOKEN
+	Mount     string `yaml:"mount"`     // KV v2 mount path (default: secret)
+	Namespace string `yaml:"namespace"` // Vault Enterprise namespace, optional
+}
+
+// AWSSecretConfig holds settings for AWS Secrets Manager. Credentials come
+// from the default AWS chain (environment, shared config, instance role).
+type AWSSecretConfig struct {
+	Region string `yaml:"region"` // Default: AWS_REGION / shared config
+}
+
+// SecretProvider fetches a secret and returns its key/value pairs. Secrets
+// stored as plain strings are returned under the empty key.
+type SecretProvider interface {
+	GetSecret(ctx context.Context, path string) (map[string]string, error)
+}
+
+// IsSecretRef reports whether value references an external secret
+func IsSecretRef(value string) bool {
+	return strings.HasPrefix(value, SecretRefPrefix)
+}
+
+// parseSecretRef splits secret://<path>#<key> into path and key
+func parseSecretRef(ref string) (path, key string, err error) {
+	path = strings.TrimPrefix(ref, SecretRefPrefix)
+	if i := strings.LastIndex(path, "#"); i >= 0 {
+		path, key = path[:i], path[i+1:]
+	}
+	if path == "" {
+		return "", "", fmt.Errorf("secret reference %q has no path", ref)
+	}
+	return path, key, nil
+}
+
+// credentialFields returns the config values that may hold secret
+// references, keyed by their YAML location for error messages
+func (c *Config) credentialFields() map[string]*string {
+	fields := map[string]*string{
+		"mysql.username":         &c.MySQL.Username,
+		"mysql.password":         &c.MySQL.Password,
+		"postgres.username":      &c.Postgres.Username,
+		"postgres.password":      &c.Postgres.Password,
+		"neo4j.username":         &c.Neo4j.Username,
+		"neo4j.password":         &c.Neo4j.Password,
+		"qdrant.apikey":          &c.Qdrant.APIKey,
+		"ollama.apikey":          &c.Ollama.APIKey,
+		"summary.claude_api_key": &c.Summary.ClaudeAPIKey,
+		"summary.openai_api_key": &c.Summary.OpenAIAPIKey,
+		"encryption.key":         &c.Encryption.Key,
+		"app.cursor_secret":      &c.App.CursorSecret,
+	}
+	for i := range c.Tenancy.Tenants {
+		fields[fmt.Sprintf("tenancy.tenants[%d].encryption_key", i)] = &c.Tenancy.Tenants[i].EncryptionKey
+		for j := range c.Tenancy.Tenants[i].APIKeys {
+			fields[fmt.Sprintf("tenancy.tenants[%d].api_keys[%d]", i, j)] = &c.Tenancy.Tenants[i].APIKeys[j]
+		}
+	}
+	return fields
+}
+
+// resolveSecrets replaces secret:// references in credential fields with
+// values from the configured provider. Each secret path is fetched once.
+func resolveSecrets(c *Config) error {
+	fields := c.credentialFields()
+
+	var refs []string
+	for name, value := range fields {
+		if IsSecretRef(*value) {
+			refs = append(refs, name)
+		}
+	}
+	if len(refs) == 0 {
+		return nil
+	}
+
+	provider, err := newSecretProvider(c.Secrets)
+	if err != nil {
+		return err
+	}
+
+	timeout := time.Duration(c.Secrets.TimeoutSeconds) * time.Second
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+
+	fetched := make(map[string]map[string]string)
+	for _, name := range refs {
+		field := fields[name]
+		path, key, err := parseSecretRef(*field)
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+
+		values, ok := fetched[path]
+		if !ok {
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			values, err = provider.GetSecret(ctx, path)
+			cancel()
+			if err != nil {
+				return fmt.Errorf("%s: failed to fetch secret %s: %w", name, path, err)
+			}
+			fetched[path] = values
+		}
+
+		value, ok := values[key]
+		if !ok {
+			return fmt.Errorf("%s: secret %s has no key %q", name, path, key)
+		}
+		*field = value
+	}
+	return nil
+}
+
+// newSecretProvider creates the provider selected by cfg.Provider
+func newSecretProvider(cfg SecretsConfig) (SecretProvider, error) {
+	switch cfg.Provider {
+	case SecretProviderVault:
+		return newVaultProvider(cfg.Vault)
+	case SecretProviderAWS:
+		return newAWSProvider(cfg.AWS)
+	case "":
+		return nil, fmt.Errorf("config uses %s references but secrets.provider is not set", SecretRefPrefix)
+	default:
+		return nil, fmt.Errorf("unsupported secrets.provider %q (expected %q or %q)", cfg.Provider, SecretProviderVault, SecretProviderAWS)
+	}
+}
+
+// vaultProvider reads secrets from a Vault KV v2 engine over its HTTP API
+type vaultProvider struct {
+	cfg    VaultSecretConfig
+	client *http.Client
+}
+
+func newVaultProvider(cfg VaultSecretConfig) (*vaultProvider, error) {
+	if cfg.Address == "" {
+		cfg.Address = os.Getenv("VAULT_ADDR")
+	}
+	if cfg.Token == "" {
+		cfg.Token = os.Getenv("VAULT_TOKEN")
+	}
+	if cfg.Mount == "" {
+		cfg.Mount = "secret"
+	}
+	if cfg.Address == "" || cfg.Token == "" {
+		return nil, fmt.Errorf("vault secrets need secrets.vault.address and token (or VAULT_ADDR and VAULT_TOKEN)")
+	}
+	return &vaultProvider{cfg: cfg, client: &http.Client{}}, nil
+}
+
+// GetSecret reads the latest version of path from the KV v2 mount
+func (v *vaultProvider) GetSecret(ctx context.Context, path string) (map[string]string, error) {
+	url := fmt.Sprintf("%s/v1/%s/data/%s",
+		strings.TrimRight(v.cfg.Address, "/"), strings.Trim(v.cfg.Mount, "/"), strings.TrimLeft(path, "/"))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Vault-Token", v.cfg.Token)
+	if v.cfg.Namespace != "" {
+		req.Header.Set("X-Vault-Namespace", v.cfg.Namespace)
+	}
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("vault returned %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	var payload struct {
+		Data struct {
+			Data map[string]any `json:"data"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
+		return nil, fmt.Errorf("failed to decode vault response: %w", err)
+	}
+
+	values := make(map[string]string, len(payload.Data.Data))
+	for k, val := range payload.Data.Data {
+		values[k] = fmt.Sprint(val)
+	}
+	return values, nil
+}
+
+// awsProvider reads secrets from AWS Secrets Manager
+type awsProvider struct {
+	client *secretsmanager.Client
+}
+
+func newAWSProvider(cfg AWSSecretConfig) (*awsProvider, error) {
+	var opts []func(*awsconfig.LoadOptions) error
+	if cfg.Region != "" {
+		opts = append(opts, awsconfig.WithRegion(cfg.Region))
+	}
+	awsCfg, err := awsconfig.LoadDefaultConfig(context.Background(), opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS configuration: %w", err)
+	}
+	return &awsProvider{client: secretsmanager.NewFromConfig(awsCfg)}, nil
+}
+
+// GetSecret returns the fields of a JSON secret, or the raw string under the
+// empty key when the secret is not a JSON object
+func (a *awsProvider) GetSecret(ctx context.Context, path string) (map[string]string, error) {
+	out, err := a.client.GetSecretValue(ctx, &secretsmanager.GetSecretValueInput{SecretId: aws.String(path)})
+	if err != nil {
+		return nil, err
+	}
+	return parseSecretString(aws.ToString(out.SecretString)), nil
+}
+
+// parseSecretString decodes a JSON object secret into its fields. Anything
+// else is kept whole under the empty key.
+func parseSecretString(raw string) map[string]string {
+	values := map[string]string{"": raw}
+
+	var fields map[string]any
+	if err := json.Unmarshal([]byte(raw), &fields); err != nil {
+		return values
+	}
+	for k, v := range fields {
+		if s, ok := v.(string); ok {
+			values[k] = s
+		} else {
+			values[k] = fmt.Sprint(v)
+		}
+	}
+	return values
+}
@@ -0,0 +1,108 @@
+package config
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestParseSecretRef(t *testing.T) {
+	tests := []struct {
+		name     string
+		ref      string
+		wantPath string
+		wantKey  string
+		wantErr  bool
+	}{
+		{"path and key", "secret://codeapi/prod#mysql_password", "codeapi/prod", "mysql_password", false},
+		{"whole secret", "secret://codeapi/neo4j", "codeapi/neo4j", "", false},
+		{"last hash splits key", "secret://team#a/creds#token", "team#a/creds", "token", false},
+		{"missing path", "secret://#key", "", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path, key, err := parseSecretRef(tt.ref)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseSecretRef() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if path != tt.wantPath || key != tt.wantKey {
+				t.Errorf("parseSecretRef() = (%q, %q), want (%q, %q)", path, key, tt.wantPath, tt.wantKey)
+			}
+		})
+	}
+}
+
+func TestParseSecretString(t *testing.T) {
+	values := parseSecretString(`{"password": "s3cret", "port": 5432}`)
+	if values["password"] != "s3cret" || values["port"] != "5432" {
+		t.Errorf("unexpected JSON secret fields: %v", values)
+	}
+
+	values = parseSecretString("plain-token")
+	if values[""] != "plain-token" || len(values) != 1 {
+		t.Errorf("expected raw secret under empty key, got %v", values)
+	}
+}
+
+func TestResolveSecretsVault(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.Header.Get("X-Vault-Token") != "test-token" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		if r.URL.Path != "/v1/kv/data/codeapi" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{
+			"data": map[string]any{
+				"data": map[string]any{"mysql_password": "db-pass", "neo4j_password": "graph-pass"},
+			},
+		})
+	}))
+	defer server.Close()
+
+	cfg := &Config{
+		Secrets: SecretsConfig{
+			Provider: SecretProviderVault,
+			Vault:    VaultSecretConfig{Address: server.URL, Token: "test-token", Mount: "kv"},
+		},
+	}
+	cfg.MySQL.Password = "secret://codeapi#mysql_password"
+	cfg.Neo4j.Password = "secret://codeapi#neo4j_password"
+	cfg.Qdrant.APIKey = "literal-key"
+
+	if err := resolveSecrets(cfg); err != nil {
+		t.Fatalf("resolveSecrets: %v", err)
+	}
+	if cfg.MySQL.Password != "db-pass" || cfg.Neo4j.Password != "graph-pass" {
+		t.Errorf("secrets not resolved: mysql=%q neo4j=%q", cfg.MySQL.Password, cfg.Neo4j.Password)
+	}
+	if cfg.Qdrant.APIKey != "literal-key" {
+		t.Errorf("literal value changed to %q", cfg.Qdrant.APIKey)
+	}
+	if requests != 1 {
+		t.Errorf("expected one fetch per secret path, got %d", requests)
+	}
+
+	cfg.MySQL.Password = "secret://codeapi#missing"
+	if err := resolveSecrets(cfg); err == nil {
+		t.Error("expected error for missing secret key")
+	}
+}
+
+func TestResolveSecretsWithoutProvider(t *testing.T) {
+	cfg := &Config{}
+	if err := resolveSecrets(cfg); err != nil {
+		t.Errorf("no references should need no provider, got %v", err)
+	}
+
+	cfg.MySQL.Password = "secret://codeapi#mysql_password"
+	if err := resolveSecrets(cfg); err == nil {
+		t.Error("expected error when secrets.provider is not set")
+	}
+}
@@ -0,0 +1,151 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// TenantSeparator joins a tenant and a repository name into the qualified
+// name under which the repository's graph nodes, Qdrant collection and
+// relational rows are stored. It is valid in Qdrant collection names and
+// cannot occur in a tenant name.
+const TenantSeparator = "__"
+
+// TenancyConfig lets one instance serve several isolated teams. Each
+// repository belongs to a tenant, and API callers are mapped to a tenant by
+// the key they present.
+type TenancyConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Header carries the API key (default: X-API-Key). "Authorization: Bearer
+	// <key>" is accepted as well.
+	Header  string         `yaml:"header,omitempty"`
+	Tenants []TenantConfig `yaml:"tenants"`
+}
+
+// TenantConfig is one tenant and the API keys that act on its behalf
+type TenantConfig struct {
+	Name    string   `yaml:"name"`
+	APIKeys []string `yaml:"api_keys"`
+	// Admin tenants are not scoped to their own repositories and may use the
+	// raw Cypher endpoints
+	Admin bool `yaml:"admin,omitempty"`
+	// EncryptionKey is the base64 AES-256 key of the tenant's repositories
+	// that set encrypt, normally a secret:// reference
+	EncryptionKey string `yaml:"encryption_key,omitempty"`
+}
+
+// GetHeader returns the API key header name
+func (t *TenancyConfig) GetHeader() string {
+	if t.Header == "" {
+		return "X-API-Key"
+	}
+	return t.Header
+}
+
+// TenantForKey returns the tenant owning apiKey
+func (t *TenancyConfig) TenantForKey(apiKey string) (*TenantConfig, bool) {
+	if apiKey == "" {
+		return nil, false
+	}
+	for i := range t.Tenants {
+		for _, key := range t.Tenants[i].APIKeys {
+			if key == apiKey {
+				return &t.Tenants[i], true
+			}
+		}
+	}
+	return nil, false
+}
+
+// QualifiedRepoName returns the storage name of repository name in tenant.
+// Names that already carry the tenant's prefix are returned unchanged.
+func QualifiedRepoName(tenant, name string) string {
+	if tenant == "" || strings.HasPrefix(name, tenant+TenantSeparator) {
+		return name
+	}
+	return tenant + TenantSeparator + name
+}
+
+// qualifyRepositories prefixes repository names with their tenant when
+// tenancy is enabled, so every store keyed by repository name is namespaced
+func qualifyRepositories(tenancy TenancyConfig, repos []Repository) {
+	if !tenancy.Enabled {
+		return
+	}
+	for i := range repos {
+		repos[i].Name = QualifiedRepoName(repos[i].Tenant, repos[i].Name)
+	}
+}
+
+// tenantNamePattern keeps tenant names usable as name prefixes in every store
+var tenantNamePattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+func validateTenancy(c *Config, report *ValidationReport) {
+	if !c.Tenancy.Enabled {
+		for _, repo := range c.Source.Repositories {
+			if repo.Tenant != "" {
+				report.warnf(fmt.Sprintf("source.repositories[%s].tenant", repo.Name), "ignored because tenancy.enabled is false")
+			}
+		}
+		return
+	}
+
+	tenants := make(map[string]bool)
+	keys := make(map[string]string)
+	for i, tenant := range c.Tenancy.Tenants {
+		field := fmt.Sprintf("tenancy.tenants[%d]", i)
+		if !tenantNamePattern.MatchString(tenant.Name) {
+			report.errorf(field+".name", "%q must be lowercase letters, digits and single dashes", tenant.Name)
+		}
+		if tenants[tenant.Name] {
+			report.errorf(field+".name", "duplicate tenant %q", tenant.Name)
+		}
+		tenants[tenant.Name] = true
+
+		if len(tenant.APIKeys) == 0 {
+			report.warnf(field+".api_keys", "tenant %q has no API keys and cannot be reached through the API", tenant.Name)
+		}
+		for _, key := range tenant.APIKeys {
+			if owner, dup := keys[key]; dup {
+				report.errorf(field+".api_keys", "API key is also assigned to tenant %q", owner)
+			}
+			keys[key] = tenant.Name
+		}
+	}
+
+	for _, repo := range c.Source.Repositories {
+		field := fmt.Sprintf("source.repositories[%s].tenant", repo.Name)
+		switch {
+		case repo.Tenant == "":
+			report.errorf(field, "required when tenancy is enabled")
+		case !tenants[repo.Tenant]:
+			report.errorf(field, "unknown tenant %q", repo.Tenant)
+		}
+	}
+}
+
+type tenantContextKey struct{}
+
+// WithTenant returns a context carrying the tenant of the current request
+func WithTenant(ctx context.Context, tenant *TenantConfig) context.Context {
+	return context.WithValue(ctx, tenantContextKey{}, tenant)
+}
+
+// CallerFromContext returns the tenant whose API key made the current
+// request, admins included, or nil when tenancy is disabled
+func CallerFromContext(ctx context.Context) *TenantConfig {
+	tenant, _ := ctx.Value(tenantContextKey{}).(*TenantConfig)
+	return tenant
+}
+
+// TenantFromContext returns the tenant of the current request, or nil when
+// tenancy is disabled or the caller is an admin
+func TenantFromContext(ctx context.Context) *TenantConfig {
+	tenant, _ := ctx.Value(tenantContextKey{}).(*TenantConfig)
+	if tenant == nil || tenant.Admin {
+		return nil
+	}
+	return tenant
+}
@@ -0,0 +1,102 @@
+package config
+
+import (
+	"context"
+	"testing"
+)
+
+func TestQualifiedRepoName(t *testing.T) {
+	tests := []struct {
+		tenant, name, want string
+	}{
+		{"acme", "api", "acme__api"},
+		{"acme", "acme__api", "acme__api"},
+		{"acme", "other__api", "acme__other__api"},
+		{"", "api", "api"},
+	}
+	for _, tt := range tests {
+		if got := QualifiedRepoName(tt.tenant, tt.name); got != tt.want {
+			t.Errorf("QualifiedRepoName(%q, %q) = %q, want %q", tt.tenant, tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestValidateTenancy(t *testing.T) {
+	tenancy := TenancyConfig{
+		Enabled: true,
+		Tenants: []TenantConfig{
+			{Name: "acme", APIKeys: []string{"k1"}},
+			{Name: "globex", APIKeys: []string{"k2"}},
+		},
+	}
+
+	tests := []struct {
+		name       string
+		tenancy    TenancyConfig
+		repos      []Repository
+		wantErrors int
+	}{
+		{
+			name:    "valid",
+			tenancy: tenancy,
+			repos:   []Repository{{Name: "api", Tenant: "acme"}, {Name: "api", Tenant: "globex"}},
+		},
+		{
+			name:       "missing and unknown tenant",
+			tenancy:    tenancy,
+			repos:      []Repository{{Name: "a"}, {Name: "b", Tenant: "initech"}},
+			wantErrors: 2,
+		},
+		{
+			name: "bad tenant name and shared key",
+			tenancy: TenancyConfig{Enabled: true, Tenants: []TenantConfig{
+				{Name: "Acme__x", APIKeys: []string{"k"}},
+				{Name: "ok", APIKeys: []string{"k"}},
+			}},
+			wantErrors: 2,
+		},
+		{
+			name:  "disabled ignores tenants",
+			repos: []Repository{{Name: "a", Tenant: "nobody"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{Tenancy: tt.tenancy}
+			cfg.Source.Repositories = tt.repos
+			report := &ValidationReport{}
+			validateTenancy(cfg, report)
+			if got := len(report.Errors()); got != tt.wantErrors {
+				t.Errorf("errors = %d, want %d: %v", got, tt.wantErrors, report.Errors())
+			}
+		})
+	}
+}
+
+func TestTenantFromContext(t *testing.T) {
+	tenancy := TenancyConfig{Tenants: []TenantConfig{
+		{Name: "acme", APIKeys: []string{"k1"}},
+		{Name: "ops", APIKeys: []string{"k2"}, Admin: true},
+	}}
+
+	if _, ok := tenancy.TenantForKey(""); ok {
+		t.Error("empty key should not match a tenant")
+	}
+
+	acme, _ := tenancy.TenantForKey("k1")
+	if got := TenantFromContext(WithTenant(context.Background(), acme)); got == nil || got.Name != "acme" {
+		t.Errorf("TenantFromContext() = %v, want acme", got)
+	}
+
+	ops, _ := tenancy.TenantForKey("k2")
+	if got := TenantFromContext(WithTenant(context.Background(), ops)); got != nil {
+		t.Errorf("admin tenant should not be scoped, got %v", got)
+	}
+	if got := CallerFromContext(WithTenant(context.Background(), ops)); got == nil || got.Name != "ops" {
+		t.Errorf("CallerFromContext() = %v, want ops", got)
+	}
+	if got := CallerFromContext(context.Background()); got != nil {
+		t.Errorf("CallerFromContext() without tenancy = %v, want nil", got)
+	}
+}
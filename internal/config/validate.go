@@ -0,0 +1,524 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Severity ranks a validation issue. Errors stop LoadConfig; warnings are
+// reported by the config check but do not block startup.
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+// ValidationIssue is a single problem found in the configuration
+type ValidationIssue struct {
+	Severity Severity `json:"severity"`
+	Field    string   `json:"field"` // YAML location, e.g. "source.repositories[my-repo].path"
+	Message  string   `json:"message"`
+}
+
+// ValidationReport collects every issue found while loading a configuration
+type ValidationReport struct {
+	Issues []ValidationIssue `json:"issues"`
+}
+
+func (r *ValidationReport) add(severity Severity, field, format string, args ...any) {
+	r.Issues = append(r.Issues, ValidationIssue{Severity: severity, Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+func (r *ValidationReport) errorf(field, format string, args ...any) {
+	r.add(SeverityError, field, format, args...)
+}
+
+func (r *ValidationReport) warnf(field, format string, args ...any) {
+	r.add(SeverityWarning, field, format, args...)
+}
+
+// Errors returns the issues that make the configuration unusable
+func (r *ValidationReport) Errors() []ValidationIssue {
+	return r.filter(SeverityError)
+}
+
+// Warnings returns issues that do not block startup
+func (r *ValidationReport) Warnings() []ValidationIssue {
+	return r.filter(SeverityWarning)
+}
+
+func (r *ValidationReport) filter(severity Severity) []ValidationIssue {
+	var out []ValidationIssue
+	for _, issue := range r.Issues {
+		if issue.Severity == severity {
+			out = append(out, issue)
+		}
+	}
+	return out
+}
+
+// HasErrors reports whether any error-level issue was found
+func (r *ValidationReport) HasErrors() bool {
+	return len(r.Errors()) > 0
+}
+
+// Err returns an error summarizing the error-level issues, or nil
+func (r *ValidationReport) Err() error {
+	errs := r.Errors()
+	if len(errs) == 0 {
+		return nil
+	}
+	lines := make([]string, len(errs))
+	for i, issue := range errs {
+		lines[i] = fmt.Sprintf("%s: %s", issue.Field, issue.Message)
+	}
+	return fmt.Errorf("invalid configuration:\n  %s", strings.Join(lines, "\n  "))
+}
+
+// String renders the report for the config check command
+func (r *ValidationReport) String() string {
+	if len(r.Issues) == 0 {
+		return "Configuration OK: no issues found\n"
+	}
+
+	var sb strings.Builder
+	for _, group := range []struct {
+		title  string
+		issues []ValidationIssue
+	}{
+		{"Errors", r.Errors()},
+		{"Warnings", r.Warnings()},
+	} {
+		if len(group.issues) == 0 {
+			continue
+		}
+		fmt.Fprintf(&sb, "%s (%d):\n", group.title, len(group.issues))
+		for _, issue := range group.issues {
+			fmt.Fprintf(&sb, "  - %s: %s\n", issue.Field, issue.Message)
+		}
+	}
+	return sb.String()
+}
+
+// checkUnknownFields re-parses data strictly and reports keys that do not map
+// to a config field, which are otherwise silently ignored
+func checkUnknownFields(report *ValidationReport, file string, data []byte) {
+	var strict Config
+	err := yaml.UnmarshalStrict(data, &strict)
+
+	var typeErr *yaml.TypeError
+	if !errors.As(err, &typeErr) {
+		return
+	}
+	for _, msg := range typeErr.Errors {
+		report.errorf(file, "%s", msg)
+	}
+}
+
+// parsedLanguages are the repository languages with a tree-sitter parser
+var parsedLanguages = []string{"go", "python", "java", "javascript", "typescript", "csharp"}
+
+// Validate checks settings that LoadConfig cannot express through YAML
+// types: required settings of enabled features, repository paths and
+// patterns. It never contacts external services.
+func Validate(c *Config) *ValidationReport {
+	report := &ValidationReport{}
+	validateRepositoryDefinitions(c, report)
+	validateFeatures(c, report)
+	validateStores(c, report)
+	validatePatterns(c, report)
+	validateTenancy(c, report)
+	validateEncryption(c, report)
+	validateTelemetry(c, report)
+	validateWarmup(c, report)
+	validateProvisioning(c, report)
+	for _, language := range slices.Sorted(maps.Keys(c.ExternalPaths)) {
+		validatePathPatterns("external_paths."+language, c.ExternalPaths[language], report)
+	}
+	return report
+}
+
+func validateTelemetry(c *Config, report *ValidationReport) {
+	if !c.Telemetry.Enabled {
+		return
+	}
+	u, err := url.Parse(c.Telemetry.Endpoint)
+	if c.Telemetry.Endpoint == "" || err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		report.errorf("telemetry.endpoint", "an http or https URL is required when telemetry is enabled")
+	}
+}
+
+// validateWarmup warns about warm-up repositories that are not configured;
+// source.yaml may still add them on reload
+func validateWarmup(c *Config, report *ValidationReport) {
+	if !c.Warmup.Enabled {
+		return
+	}
+	for i, name := range c.Warmup.Repositories {
+		if _, err := c.GetRepository(name); err != nil {
+			report.warnf(fmt.Sprintf("warmup.repositories[%d]", i), "unknown repository %q", name)
+		}
+	}
+}
+
+func validateProvisioning(c *Config, report *ValidationReport) {
+	switch c.Provisioning.Mode {
+	case "", ProvisionCreate, ProvisionReport, ProvisionOff:
+	default:
+		report.errorf("provisioning.mode", "unsupported mode %q (expected %q, %q or %q)",
+			c.Provisioning.Mode, ProvisionCreate, ProvisionReport, ProvisionOff)
+	}
+}
+
+// validatePathPatterns reports external path patterns that are not valid
+// globs
+func validatePathPatterns(field string, patterns []string, report *ValidationReport) {
+	for i, pattern := range patterns {
+		if strings.Trim(pattern, "/") == "" {
+			report.errorf(fmt.Sprintf("%s[%d]", field, i), "empty pattern")
+			continue
+		}
+		for _, segment := range strings.Split(pattern, "/") {
+			if _, err := path.Match(segment, ""); err != nil {
+				report.errorf(fmt.Sprintf("%s[%d]", field, i), "invalid pattern %q: %v", pattern, err)
+				break
+			}
+		}
+	}
+}
+
+func validateRepositoryDefinitions(c *Config, report *ValidationReport) {
+	if len(c.Source.Repositories) == 0 {
+		report.warnf("source.repositories", "no repositories configured")
+	}
+
+	seen := make(map[string]bool)
+	for i, repo := range c.Source.Repositories {
+		field := fmt.Sprintf("source.repositories[%d]", i)
+		if repo.Name == "" {
+			report.errorf(field+".name", "repository name is required")
+		} else {
+			field = fmt.Sprintf("source.repositories[%s]", repo.Name)
+			if seen[repo.Name] {
+				report.errorf(field+".name", "duplicate repository name")
+			}
+			seen[repo.Name] = true
+		}
+
+		// If skip_other_languages is true, language must be specified
+		if repo.SkipOtherLanguages && repo.Language == "" {
+			report.errorf(field+".language", "skip_other_languages is true but language is not specified")
+		}
+		if len(repo.Languages) > 0 && !repo.IsMultiLanguage() {
+			report.errorf(field+".languages", "languages is only supported with language: %s", LanguageAuto)
+		}
+		if repo.Language != "" && !repo.IsMultiLanguage() && !containsFold(parsedLanguages, repo.Language) {
+			report.warnf(field+".language", "%q has no parser; supported: %s or %s",
+				repo.Language, strings.Join(parsedLanguages, ", "), LanguageAuto)
+		}
+
+		if repo.Chunking != nil {
+			validateChunking(field+".chunking", c.Chunking.Merge(repo.Chunking), report)
+		}
+		if repo.VectorShards < 0 || repo.VectorShards > MaxVectorShards {
+			report.errorf(field+".vector_shards", "%d is outside 0..%d", repo.VectorShards, MaxVectorShards)
+		}
+		validatePathPatterns(field+".external_paths", repo.ExternalPaths, report)
+
+		if repo.Disabled {
+			continue
+		}
+		if repo.Path == "" {
+			report.errorf(field+".path", "repository path is required")
+		} else if info, err := os.Stat(repo.Path); err != nil {
+			report.errorf(field+".path", "%s does not exist", repo.Path)
+		} else if !info.IsDir() {
+			report.errorf(field+".path", "%s is not a directory", repo.Path)
+		}
+		if repo.Test != "" {
+			testPath := repo.Test
+			if !filepath.IsAbs(testPath) {
+				testPath = filepath.Join(repo.Path, testPath)
+			}
+			if _, err := os.Stat(testPath); err != nil {
+				report.warnf(field+".test", "%s does not exist", testPath)
+			}
+		}
+	}
+}
+
+func validateFeatures(c *Config, report *ValidationReport) {
+	if (c.App.CodeGraph || c.IndexBuilding.EnableCodeGraph) && c.Neo4j.URI == "" {
+		report.errorf("neo4j.uri", "required when the code graph is enabled (app.codegraph or index_building.enable_code_graph)")
+	}
+
+	if r := c.Neo4j.Retry; r.MaxAttempts < 0 || r.InitialBackoffMs < 0 || r.MaxBackoffMs < 0 ||
+		r.BreakerThreshold < 0 || r.BreakerCooldownSeconds < 0 {
+		report.errorf("neo4j.retry", "attempts, backoffs, threshold and cooldown must not be negative")
+	} else if r = r.GetDefaults(); r.MaxBackoffMs < r.InitialBackoffMs {
+		report.errorf("neo4j.retry.max_backoff_ms", "%d is below initial_backoff_ms (%d)", r.MaxBackoffMs, r.InitialBackoffMs)
+	}
+
+	if c.IndexBuilding.EnableEmbeddings {
+		if c.Qdrant.Host == "" {
+			report.errorf("qdrant.host", "required when index_building.enable_embeddings is true")
+		}
+		if c.Ollama.URL == "" {
+			report.errorf("ollama.url", "required when index_building.enable_embeddings is true")
+		}
+		if c.Ollama.Model == "" {
+			report.errorf("ollama.model", "required when index_building.enable_embeddings is true")
+		}
+		if c.Ollama.Dimension <= 0 {
+			report.errorf("ollama.dimension", "must be positive when index_building.enable_embeddings is true")
+		}
+	}
+
+	switch c.IndexBuilding.LargeFileAction {
+	case "", LargeFileSkip, LargeFileTruncate:
+	default:
+		report.errorf("index_building.large_file_action", "unsupported action %q (expected %q or %q)",
+			c.IndexBuilding.LargeFileAction, LargeFileSkip, LargeFileTruncate)
+	}
+
+	switch c.IndexBuilding.Profile {
+	case "", IndexProfileFull, IndexProfileLite:
+	default:
+		report.errorf("index_building.profile", "unsupported profile %q (expected %q or %q)",
+			c.IndexBuilding.Profile, IndexProfileFull, IndexProfileLite)
+	}
+
+	validateChunking("chunking", c.Chunking, report)
+
+	for i, marker := range c.Notes.Markers {
+		if !isMarkerWord(marker) {
+			report.errorf(fmt.Sprintf("notes.markers[%d]", i), "%q must be a word of letters, digits, _ or -, starting with a letter", marker)
+		}
+	}
+	if c.Notes.Embed && !c.IndexBuilding.EnableEmbeddings {
+		report.warnf("notes.embed", "ignored unless index_building.enable_embeddings is true")
+	}
+
+	validateFeatureFlags(c.FeatureFlags, report)
+
+	for class := range c.App.Concurrency.Limits {
+		switch class {
+		case EndpointSummaries, EndpointTraversal, EndpointIndexing:
+		default:
+			report.errorf("app.concurrency.limits."+class, "unknown endpoint class (expected %s, %s or %s)",
+				EndpointSummaries, EndpointTraversal, EndpointIndexing)
+		}
+	}
+
+	summaryConfigured := c.Summary.LLMProvider != "" && c.Summary.LLMModel != ""
+	if c.IndexBuilding.EnableSummary && !summaryConfigured {
+		report.errorf("summary", "llm_provider and llm_model are required when index_building.enable_summary is true")
+	}
+	if c.IndexBuilding.EnableSummary || summaryConfigured {
+		validateSummary(c, report)
+	}
+
+	if c.BloomFilter.Enabled && (c.BloomFilter.FalsePositiveRate <= 0 || c.BloomFilter.FalsePositiveRate >= 1) {
+		report.errorf("bloom_filter.false_positive_rate", "must be between 0 and 1, got %v", c.BloomFilter.FalsePositiveRate)
+	}
+
+	if c.GitAnalysis.Enabled {
+		switch c.GitAnalysis.Mode {
+		case "", GitAnalysisModeOnDemand, GitAnalysisModePrecompute:
+		default:
+			report.errorf("git_analysis.mode", "unsupported mode %q (expected %q or %q)",
+				c.GitAnalysis.Mode, GitAnalysisModeOnDemand, GitAnalysisModePrecompute)
+		}
+	}
+
+	if !isLogLevel(c.App.LogLevel) {
+		report.warnf("app.log_level", "unknown level %q, info is used", c.App.LogLevel)
+	}
+	validateLogging(c, report)
+}
+
+func isLogLevel(level string) bool {
+	switch strings.ToLower(level) {
+	case "", "debug", "info", "warn", "warning", "error":
+		return true
+	}
+	return false
+}
+
+func validateLogging(c *Config, report *ValidationReport) {
+	validEncoding := func(field, encoding string) {
+		if encoding != "" && encoding != "json" && encoding != "console" {
+			report.errorf(field, "unsupported encoding %q (expected json or console)", encoding)
+		}
+	}
+	validEncoding("logging.encoding", c.Logging.Encoding)
+
+	for module, level := range c.Logging.Modules {
+		if !isLogLevel(level) {
+			report.warnf("logging.modules."+module, "unknown level %q, info is used", level)
+		}
+	}
+
+	for i, out := range c.Logging.Outputs {
+		field := fmt.Sprintf("logging.outputs[%d]", i)
+		switch out.Type {
+		case "stdout", "stderr":
+		case "file":
+			if out.Path == "" {
+				report.errorf(field+".path", "required for file outputs")
+			}
+		default:
+			report.errorf(field+".type", "unsupported output %q (expected stdout, stderr or file)", out.Type)
+		}
+		validEncoding(field+".encoding", out.Encoding)
+		if !isLogLevel(out.Level) {
+			report.warnf(field+".level", "unknown level %q, info is used", out.Level)
+		}
+	}
+}
+
+func validateSummary(c *Config, report *ValidationReport) {
+	switch c.Summary.LLMProvider {
+	case "", "ollama":
+		if c.Summary.OllamaURL == "" && c.Ollama.URL == "" {
+			report.errorf("summary.ollama_url", "required for the ollama provider (or set ollama.url)")
+		}
+	case "claude":
+		if c.Summary.ClaudeAPIKey == "" && os.Getenv("ANTHROPIC_API_KEY") == "" {
+			report.errorf("summary.claude_api_key", "required for the claude provider (or set ANTHROPIC_API_KEY)")
+		}
+	case "openai":
+		if c.Summary.OpenAIAPIKey == "" && os.Getenv("OPENAI_API_KEY") == "" {
+			report.errorf("summary.openai_api_key", "required for the openai provider (or set OPENAI_API_KEY)")
+		}
+	default:
+		report.errorf("summary.llm_provider", "unsupported provider %q (expected ollama, claude or openai)", c.Summary.LLMProvider)
+	}
+
+	if c.Summary.PromptsFile == "" {
+		report.errorf("summary.prompts_file", "required when summaries are enabled")
+	} else if _, err := os.Stat(c.Summary.PromptsFile); err != nil {
+		report.errorf("summary.prompts_file", "%s does not exist", c.Summary.PromptsFile)
+	}
+}
+
+func validateStores(c *Config, report *ValidationReport) {
+	switch driver := c.DB.GetDefaults().Driver; driver {
+	case DBDriverMySQL:
+		if c.MySQL.Host == "" {
+			report.warnf("mysql.host", "not set; file version tracking, summaries and index building are unavailable")
+		} else if c.MySQL.Port <= 0 {
+			report.errorf("mysql.port", "must be positive")
+		}
+	case DBDriverPostgres:
+		if c.Postgres.Host == "" {
+			report.warnf("postgres.host", "not set; file version tracking, summaries and index building are unavailable")
+		} else if c.Postgres.Port <= 0 {
+			report.errorf("postgres.port", "must be positive")
+		}
+		switch c.Postgres.SSLMode {
+		case "", "disable", "require", "verify-ca", "verify-full":
+		default:
+			report.errorf("postgres.sslmode", "unsupported value %q", c.Postgres.SSLMode)
+		}
+	case DBDriverSQLite:
+	default:
+		report.errorf("db.driver", "unsupported driver %q (expected %q, %q or %q)", driver, DBDriverMySQL, DBDriverPostgres, DBDriverSQLite)
+	}
+
+	if c.Qdrant.Host != "" && c.Qdrant.Port <= 0 {
+		report.errorf("qdrant.port", "must be positive")
+	}
+
+	switch c.Secrets.Provider {
+	case "", SecretProviderVault, SecretProviderAWS:
+	default:
+		report.errorf("secrets.provider", "unsupported provider %q (expected %q or %q)", c.Secrets.Provider, SecretProviderVault, SecretProviderAWS)
+	}
+}
+
+// validatePatterns checks glob patterns. "**" segments are matched by the
+// churn analyzer itself, so only the pieces between them must be valid globs.
+func validatePatterns(c *Config, report *ValidationReport) {
+	for i, pattern := range c.GitChurn.ExcludePatterns {
+		for _, part := range strings.Split(pattern, "**") {
+			if _, err := filepath.Match(part, ""); err != nil {
+				report.errorf(fmt.Sprintf("git_churn.exclude_patterns[%d]", i), "invalid glob %q: %v", pattern, err)
+				break
+			}
+		}
+	}
+}
+
+// isMarkerWord reports whether a notes marker can be matched as a whole word
+func isMarkerWord(marker string) bool {
+	for i, r := range marker {
+		letter := r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z'
+		if !letter && (i == 0 || !(r >= '0' && r <= '9' || r == '_' || r == '-')) {
+			return false
+		}
+	}
+	return marker != ""
+}
+
+// validateChunking checks chunking settings, for repositories merged with
+// the app.yaml defaults
+func validateFeatureFlags(flags FeatureFlagsConfig, report *ValidationReport) {
+	for i, provider := range flags.Providers {
+		switch provider {
+		case FlagProviderLaunchDarkly, FlagProviderUnleash:
+		default:
+			report.errorf(fmt.Sprintf("feature_flags.providers[%d]", i), "unknown provider %q (expected %q or %q)",
+				provider, FlagProviderLaunchDarkly, FlagProviderUnleash)
+		}
+	}
+	for i, helper := range flags.Helpers {
+		field := fmt.Sprintf("feature_flags.helpers[%d]", i)
+		if helper.Name == "" {
+			report.errorf(field+".name", "required")
+		}
+		if len(helper.Methods) == 0 && helper.Pattern == "" {
+			report.errorf(field, "methods or pattern is required")
+		}
+		if helper.Pattern != "" {
+			if _, err := regexp.Compile(helper.Pattern); err != nil {
+				report.errorf(field+".pattern", "invalid regular expression: %v", err)
+			}
+		}
+		if helper.KeyArg < 0 {
+			report.errorf(field+".key_arg", "must not be negative")
+		}
+	}
+}
+
+func validateChunking(field string, chunking ChunkingConfig, report *ValidationReport) {
+	chunking = chunking.GetDefaults()
+	if chunking.MaxChunkTokens > 0 && chunking.OverlapTokens >= chunking.MaxChunkTokens {
+		report.errorf(field+".overlap_tokens", "must be less than max_chunk_tokens (%d)", chunking.MaxChunkTokens)
+	}
+	for _, chunkType := range chunking.ChunkTypes {
+		if !containsFold(ChunkTypeNames, chunkType) {
+			report.errorf(field+".chunk_types", "unknown chunk type %q (expected one of %s)", chunkType, strings.Join(ChunkTypeNames, ", "))
+		}
+	}
+}
+
+func containsFold(values []string, s string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,130 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	repoDir := t.TempDir()
+
+	tests := []struct {
+		name      string
+		mutate    func(c *Config)
+		wantField string // empty means no error expected
+	}{
+		{"valid config", func(c *Config) {}, ""},
+		{"missing repo path", func(c *Config) { c.Source.Repositories[0].Path = filepath.Join(repoDir, "missing") }, "source.repositories[repo].path"},
+		{"disabled repo path not checked", func(c *Config) {
+			c.Source.Repositories[0].Path = filepath.Join(repoDir, "missing")
+			c.Source.Repositories[0].Disabled = true
+		}, ""},
+		{"duplicate repo", func(c *Config) { c.Source.Repositories = append(c.Source.Repositories, c.Source.Repositories[0]) }, "source.repositories[repo].name"},
+		{"skip_other_languages without language", func(c *Config) {
+			c.Source.Repositories[0].Language = ""
+			c.Source.Repositories[0].SkipOtherLanguages = true
+		}, "source.repositories[repo].language"},
+		{"code graph without neo4j", func(c *Config) { c.Neo4j.URI = "" }, "neo4j.uri"},
+		{"embeddings without qdrant", func(c *Config) { c.IndexBuilding.EnableEmbeddings = true }, "qdrant.host"},
+		{"summary without provider", func(c *Config) { c.IndexBuilding.EnableSummary = true }, "summary"},
+		{"unsupported llm provider", func(c *Config) {
+			c.Summary.LLMProvider = "bard"
+			c.Summary.LLMModel = "x"
+		}, "summary.llm_provider"},
+		{"invalid glob", func(c *Config) { c.GitChurn.ExcludePatterns = []string{"vendor/**", "[a-"} }, "git_churn.exclude_patterns[1]"},
+		{"unsupported driver", func(c *Config) { c.DB.Driver = "oracle" }, "db.driver"},
+		{"unsupported large file action", func(c *Config) { c.IndexBuilding.LargeFileAction = "split" }, "index_building.large_file_action"},
+		{"unsupported index profile", func(c *Config) { c.IndexBuilding.Profile = "minimal" }, "index_building.profile"},
+		{"unsupported provisioning mode", func(c *Config) { c.Provisioning.Mode = "repair" }, "provisioning.mode"},
+		{"too many vector shards", func(c *Config) { c.Source.Repositories[0].VectorShards = 100 }, "source.repositories[repo].vector_shards"},
+		{"encrypt without key", func(c *Config) { c.Source.Repositories[0].Encrypt = true }, "source.repositories[repo].encrypt"},
+		{"telemetry without endpoint", func(c *Config) { c.Telemetry.Enabled = true }, "telemetry.endpoint"},
+		{"telemetry endpoint not a URL", func(c *Config) {
+			c.Telemetry.Enabled = true
+			c.Telemetry.Endpoint = "telemetry.example.com/report"
+		}, "telemetry.endpoint"},
+		{"encryption key not 32 bytes", func(c *Config) { c.Encryption.Key = "c2hvcnQ=" }, "encryption.key"},
+		{"negative retry attempts", func(c *Config) { c.Neo4j.Retry.MaxAttempts = -1 }, "neo4j.retry"},
+		{"max backoff under default initial", func(c *Config) { c.Neo4j.Retry.MaxBackoffMs = 100 }, "neo4j.retry.max_backoff_ms"},
+		{"overlap not under chunk limit", func(c *Config) { c.Chunking.OverlapTokens = 2048 }, "chunking.overlap_tokens"},
+		{"unknown chunk type", func(c *Config) { c.Chunking.ChunkTypes = []string{"function", "method"} }, "chunking.chunk_types"},
+		{"repo overlap not under inherited limit", func(c *Config) {
+			c.Chunking.MaxChunkTokens = 256
+			c.Source.Repositories[0].Chunking = &ChunkingConfig{OverlapTokens: 300}
+		}, "source.repositories[repo].chunking.overlap_tokens"},
+		{"notes marker not a word", func(c *Config) { c.Notes.Markers = []string{"TODO", "NOTE:"} }, "notes.markers[1]"},
+		{"unknown flag provider", func(c *Config) { c.FeatureFlags.Providers = []string{"unleash", "split"} }, "feature_flags.providers[1]"},
+		{"flag helper without methods", func(c *Config) { c.FeatureFlags.Helpers = []FlagHelperConfig{{Name: "features"}} }, "feature_flags.helpers[0]"},
+		{"invalid flag helper pattern", func(c *Config) {
+			c.FeatureFlags.Helpers = []FlagHelperConfig{{Name: "features", Pattern: "^features\\.(Is"}}
+		}, "feature_flags.helpers[0].pattern"},
+		{"unknown endpoint class", func(c *Config) { c.App.Concurrency.Limits = map[string]int{"search": 2} }, "app.concurrency.limits.search"},
+		{"file log output without path", func(c *Config) { c.Logging.Outputs = []LogOutput{{Type: "file"}} }, "logging.outputs[0].path"},
+		{"unsupported log output", func(c *Config) { c.Logging.Outputs = []LogOutput{{Type: "syslog"}} }, "logging.outputs[0].type"},
+		{"invalid external path pattern", func(c *Config) {
+			c.Source.Repositories[0].ExternalPaths = []string{"gen", "proto/[a-"}
+		}, "source.repositories[repo].external_paths[1]"},
+		{"empty language external path", func(c *Config) { c.ExternalPaths = ExternalPathsConfig{"go": {"/"}} }, "external_paths.go[0]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{
+				App:    App{CodeGraph: true},
+				Neo4j:  Neo4jConfig{URI: "bolt://localhost:7687"},
+				DB:     DBConfig{Driver: DBDriverSQLite},
+				Source: SourceConfig{Repositories: []Repository{{Name: "repo", Path: repoDir, Language: "go"}}},
+			}
+			tt.mutate(cfg)
+
+			report := Validate(cfg)
+			errs := report.Errors()
+			if tt.wantField == "" {
+				if len(errs) != 0 {
+					t.Fatalf("expected no errors, got %v", errs)
+				}
+				return
+			}
+			for _, issue := range errs {
+				if issue.Field == tt.wantField {
+					return
+				}
+			}
+			t.Errorf("expected error on %s, got %v", tt.wantField, errs)
+		})
+	}
+}
+
+func TestLoadConfigWithReportUnknownFields(t *testing.T) {
+	dir := t.TempDir()
+	appPath := filepath.Join(dir, "app.yaml")
+	sourcePath := filepath.Join(dir, "source.yaml")
+
+	app := "app:\n  port: 8181\n  log_levle: debug\ndb:\n  driver: sqlite\n"
+	source := "source:\n  repositories:\n    - name: repo\n      path: " + dir + "\n      language: go\n"
+	if err := os.WriteFile(appPath, []byte(app), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(sourcePath, []byte(source), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, report, err := LoadConfigWithReport(appPath, sourcePath)
+	if err != nil {
+		t.Fatalf("LoadConfigWithReport: %v", err)
+	}
+	if cfg.App.Port != 8181 {
+		t.Errorf("expected config to be returned alongside the report, got port %d", cfg.App.Port)
+	}
+
+	errs := report.Errors()
+	if len(errs) != 1 || errs[0].Field != appPath || !strings.Contains(errs[0].Message, "log_levle") {
+		t.Errorf("expected unknown field log_levle reported, got %v", errs)
+	}
+
+	if _, err := LoadConfig(appPath, sourcePath); err == nil {
+		t.Error("LoadConfig should reject unknown fields")
+	}
+}
@@ -0,0 +1,186 @@
+package controller
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/armchr/codeapi/internal/config"
+	"github.com/armchr/codeapi/internal/db"
+	"github.com/armchr/codeapi/internal/logging"
+	"github.com/armchr/codeapi/internal/service/audit"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// AuditLogRequest selects the audit entries of a repository, newest first
+type AuditLogRequest struct {
+	RepoName  string     `json:"repo_name" binding:"required"`
+	Operation string     `json:"operation,omitempty"`
+	Source    string     `json:"source,omitempty"`
+	Actor     string     `json:"actor,omitempty"`
+	Outcome   string     `json:"outcome,omitempty"`
+	Path      string     `json:"path,omitempty"`
+	Since     *time.Time `json:"since,omitempty"`
+	Until     *time.Time `json:"until,omitempty"`
+	Limit     int        `json:"limit,omitempty"`
+	Offset    int        `json:"offset,omitempty"`
+}
+
+// AuditLogResponse lists the matching audit entries
+type AuditLogResponse struct {
+	RepoName string        `json:"repo_name"`
+	Entries  []audit.Entry `json:"entries"`
+}
+
+// NewAuditEntry starts the audit entry of an operation on a repository. The
+// caller fills in who started it and records it with RecordAudit once the
+// operation is done.
+func NewAuditEntry(operation, repoName, source string, paths ...string) *audit.Entry {
+	return &audit.Entry{
+		RepoName:  repoName,
+		Operation: operation,
+		Source:    source,
+		Paths:     paths,
+		StartedAt: time.Now(),
+	}
+}
+
+// RecordAudit completes entry with the duration and the outcome of err and
+// stores it. Failing to store it is only logged, so auditing never fails the
+// operation audited.
+func RecordAudit(ctx context.Context, sqlDB *sql.DB, entry *audit.Entry, err error, logger *zap.Logger) {
+	entry.DurationMs = time.Since(entry.StartedAt).Milliseconds()
+	entry.Outcome = audit.OutcomeSuccess
+	if errors.Is(err, db.ErrRepoLocked) {
+		entry.Outcome = audit.OutcomeSkipped
+		entry.Error = "skipped: " + err.Error()
+	} else if err != nil {
+		entry.Outcome = audit.OutcomeFailure
+		entry.Error = err.Error()
+	}
+	if sqlDB == nil {
+		return
+	}
+
+	store, storeErr := db.NewAuditStore(sqlDB, entry.RepoName, logger)
+	if storeErr == nil {
+		// A request cancelled by its client has still changed the data
+		storeErr = store.WithContext(context.WithoutCancel(ctx)).Record(entry)
+	}
+	if storeErr != nil {
+		logger.Warn("Failed to record audit entry",
+			zap.String("repo_name", entry.RepoName),
+			zap.String("operation", entry.Operation),
+			zap.Error(storeErr))
+	}
+}
+
+// requestAudit starts the audit entry of an operation requested over the
+// API, attributed to the tenant of the request
+func requestAudit(ctx context.Context, operation, repoName string, paths ...string) *audit.Entry {
+	entry := NewAuditEntry(operation, repoName, audit.SourceAPI, paths...)
+	if tenant := config.CallerFromContext(ctx); tenant != nil {
+		entry.Actor = tenant.Name
+	}
+	return entry
+}
+
+// apiAudit is requestAudit recording the client address as well
+func apiAudit(c *gin.Context, operation, repoName string, paths ...string) *audit.Entry {
+	entry := requestAudit(c.Request.Context(), operation, repoName, paths...)
+	entry.Client = c.ClientIP()
+	return entry
+}
+
+// recordAudit stores entry in the relational store of the controller
+func (rc *RepoController) recordAudit(c *gin.Context, entry *audit.Entry, err error) {
+	var sqlDB *sql.DB
+	if rc.dbConn != nil {
+		sqlDB = rc.dbConn.GetDB()
+	}
+	RecordAudit(c.Request.Context(), sqlDB, entry, err, rc.logger)
+}
+
+// ListAuditLog returns who indexed, cleaned or generated what in a
+// repository and how it went, to reconstruct why its data changed
+func (rc *RepoController) ListAuditLog(c *gin.Context) {
+	logger := logging.FromContext(c.Request.Context(), rc.logger)
+	var request AuditLogRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		logger.Error("Invalid request payload", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request payload",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	if request.Operation != "" && !audit.Operations[request.Operation] {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Invalid operation %q", request.Operation),
+		})
+		return
+	}
+	switch request.Outcome {
+	case "", audit.OutcomeSuccess, audit.OutcomeFailure, audit.OutcomeSkipped:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Invalid outcome %q: must be %s, %s or %s", request.Outcome,
+				audit.OutcomeSuccess, audit.OutcomeFailure, audit.OutcomeSkipped),
+		})
+		return
+	}
+
+	if rc.dbConn == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error": "Database connection not available. The audit log requires a relational store.",
+		})
+		return
+	}
+	if _, err := rc.config.GetRepository(request.RepoName); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "Repository not found",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	store, err := db.NewAuditStore(rc.dbConn.GetDB(), request.RepoName, rc.logger)
+	if err != nil {
+		logger.Error("Failed to open audit log", zap.String("repo_name", request.RepoName), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to open audit log",
+			"details": err.Error(),
+		})
+		return
+	}
+	entries, err := store.WithContext(c.Request.Context()).List(audit.Filter{
+		Operation: request.Operation,
+		Source:    request.Source,
+		Actor:     request.Actor,
+		Outcome:   request.Outcome,
+		Path:      request.Path,
+		Since:     request.Since,
+		Until:     request.Until,
+		Limit:     request.Limit,
+		Offset:    request.Offset,
+	})
+	if err != nil {
+		logger.Error("Failed to query audit log", zap.String("repo_name", request.RepoName), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to query audit log",
+			"details": err.Error(),
+		})
+		return
+	}
+	if entries == nil {
+		entries = []audit.Entry{}
+	}
+
+	c.JSON(http.StatusOK, AuditLogResponse{RepoName: request.RepoName, Entries: entries})
+}
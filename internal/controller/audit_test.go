@@ -0,0 +1,56 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/armchr/codeapi/internal/config"
+	"github.com/armchr/codeapi/internal/db"
+	"github.com/armchr/codeapi/internal/service/audit"
+
+	"go.uber.org/zap"
+)
+
+func TestRecordAudit(t *testing.T) {
+	logger := zap.NewNop()
+	conn := newBackupTestDB(t)
+	ctx := config.WithTenant(context.Background(), &config.TenantConfig{Name: "ops", Admin: true})
+
+	build := requestAudit(ctx, audit.OpIndexBuild, "api")
+	RecordAudit(ctx, conn.GetDB(), build, nil, logger)
+	generate := requestAudit(context.Background(), audit.OpSummaryGenerate, "api", "main.go")
+	RecordAudit(context.Background(), conn.GetDB(), generate, errors.New("llm unavailable"), logger)
+	locked := NewAuditEntry(audit.OpClean, "api", audit.SourceCLI)
+	RecordAudit(context.Background(), conn.GetDB(), locked, fmt.Errorf("%w: index_build of api running", db.ErrRepoLocked), logger)
+	// Without a relational store the entry is completed but not stored
+	RecordAudit(context.Background(), nil, NewAuditEntry(audit.OpClean, "api", audit.SourceCLI), nil, logger)
+
+	store, err := db.NewAuditStore(conn.GetDB(), "api", logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, err := store.List(audit.Filter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("got %d entries, want 3: %+v", len(entries), entries)
+	}
+
+	byOp := map[string]audit.Entry{}
+	for _, e := range entries {
+		byOp[e.Operation] = e
+	}
+	if e := byOp[audit.OpIndexBuild]; e.Actor != "ops" || e.Source != audit.SourceAPI || e.Outcome != audit.OutcomeSuccess {
+		t.Errorf("unexpected build entry %+v", e)
+	}
+	if e := byOp[audit.OpSummaryGenerate]; e.Actor != "" || e.Outcome != audit.OutcomeFailure || e.Error != "llm unavailable" || len(e.Paths) != 1 {
+		t.Errorf("unexpected generation entry %+v", e)
+	}
+	if e := byOp[audit.OpClean]; e.Outcome != audit.OutcomeSkipped || !strings.HasPrefix(e.Error, "skipped: repository is locked") {
+		t.Errorf("unexpected clean entry %+v", e)
+	}
+}
@@ -0,0 +1,558 @@
+package controller
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/armchr/codeapi/internal/db"
+	"github.com/armchr/codeapi/internal/model"
+	"github.com/armchr/codeapi/internal/service/codegraph"
+	"github.com/armchr/codeapi/internal/service/summary"
+	"github.com/armchr/codeapi/internal/service/vector"
+
+	"go.uber.org/zap"
+)
+
+// BackupFormatVersion is the archive layout written by Backup. Restore
+// rejects archives with a newer version.
+const BackupFormatVersion = 1
+
+// Archive entries. The manifest always comes first; graph nodes precede
+// relationships so both ends exist when relationships are restored.
+const (
+	backupManifestEntry     = "manifest.json"
+	backupFileVersionsEntry = "db/file_versions.jsonl"
+	backupSummariesEntry    = "db/code_summaries.jsonl"
+	backupNodesEntry        = "neo4j/nodes.jsonl"
+	backupRelationsEntry    = "neo4j/relationships.jsonl"
+	backupChunksEntry       = "qdrant/points.jsonl"
+)
+
+const (
+	// backupFileBatch is the number of files whose graph nodes are exported per query
+	backupFileBatch = 100
+	// backupWriteBatch is the number of records written to a store per call on restore
+	backupWriteBatch = 500
+)
+
+// BackupManifest describes the contents of a repository backup archive
+type BackupManifest struct {
+	FormatVersion int       `json:"format_version"`
+	RepoName      string    `json:"repo_name"`
+	CreatedAt     time.Time `json:"created_at"`
+	// LastFileID is the repository's FileID sequence, restored so new files
+	// never reuse IDs referenced by the archived graph and vectors
+	LastFileID int32 `json:"last_file_id"`
+	// Entries maps each archive entry to its number of records. Stores that
+	// were not configured when the backup was taken have no entry.
+	Entries map[string]int `json:"entries"`
+}
+
+// BackupManager exports the graph nodes, vector points and relational rows
+// of one repository into a single archive and imports them again, so an
+// index can be moved between environments without rebuilding it. Any store
+// may be nil; its part of the archive is then skipped.
+type BackupManager struct {
+	dbConn    db.Connection
+	codeGraph *codegraph.CodeGraph
+	vectorDB  vector.VectorDatabase
+	logger    *zap.Logger
+}
+
+// NewBackupManager creates a backup manager over the given stores
+func NewBackupManager(dbConn db.Connection, codeGraph *codegraph.CodeGraph, vectorDB vector.VectorDatabase, logger *zap.Logger) *BackupManager {
+	return &BackupManager{
+		dbConn:    dbConn,
+		codeGraph: codeGraph,
+		vectorDB:  vectorDB,
+		logger:    logger,
+	}
+}
+
+// Backup writes a gzipped tar archive of repoName to w
+func (b *BackupManager) Backup(ctx context.Context, repoName string, w io.Writer) (*BackupManifest, error) {
+	// Entry sizes must be known before they are added to the tar stream, so
+	// sections are staged in temporary files first
+	stageDir, err := os.MkdirTemp("", "codeapi-backup-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create staging directory: %w", err)
+	}
+	defer os.RemoveAll(stageDir)
+
+	manifest := &BackupManifest{
+		FormatVersion: BackupFormatVersion,
+		RepoName:      repoName,
+		CreatedAt:     time.Now().UTC(),
+		Entries:       make(map[string]int),
+	}
+
+	stage := func(entry string, export func(emit func(any) error) error) error {
+		path := filepath.Join(stageDir, filepath.FromSlash(entry))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return err
+		}
+		f, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		buf := bufio.NewWriter(f)
+		enc := json.NewEncoder(buf)
+		count := 0
+		err = export(func(record any) error {
+			count++
+			return enc.Encode(record)
+		})
+		if err != nil {
+			return fmt.Errorf("failed to export %s: %w", entry, err)
+		}
+		if err := buf.Flush(); err != nil {
+			return err
+		}
+		manifest.Entries[entry] = count
+		return nil
+	}
+
+	if b.dbConn != nil {
+		fileVersionRepo, err := db.NewFileVersionRepository(b.dbConn.GetDB(), repoName, b.logger)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create file version repository: %w", err)
+		}
+		fileVersionRepo = fileVersionRepo.WithContext(ctx)
+		if manifest.LastFileID, err = fileVersionRepo.LastFileID(); err != nil {
+			return nil, err
+		}
+		err = stage(backupFileVersionsEntry, func(emit func(any) error) error {
+			versions, err := fileVersionRepo.GetAllVersions()
+			if err != nil {
+				return err
+			}
+			for _, fv := range versions {
+				if err := emit(fv); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		summaryStore, err := db.NewSummaryStore(b.dbConn.GetDB(), repoName, b.logger)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create summary store: %w", err)
+		}
+		err = stage(backupSummariesEntry, func(emit func(any) error) error {
+			summaries, err := summaryStore.WithContext(ctx).GetAllSummaries()
+			if err != nil {
+				return err
+			}
+			for _, cs := range summaries {
+				if err := emit(cs); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if b.codeGraph != nil {
+		fileIDs, err := b.codeGraph.GetFileIDs(ctx, repoName)
+		if err != nil {
+			return nil, err
+		}
+		var relations []codegraph.ExportedRelation
+		err = stage(backupNodesEntry, func(emit func(any) error) error {
+			for start := 0; start < len(fileIDs); start += backupFileBatch {
+				end := min(start+backupFileBatch, len(fileIDs))
+				nodes, rels, err := b.codeGraph.ExportFiles(ctx, fileIDs[start:end])
+				if err != nil {
+					return err
+				}
+				for _, node := range nodes {
+					if err := emit(node); err != nil {
+						return err
+					}
+				}
+				relations = append(relations, rels...)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		err = stage(backupRelationsEntry, func(emit func(any) error) error {
+			for _, rel := range relations {
+				if err := emit(rel); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if b.vectorDB != nil {
+		exists, err := b.vectorDB.CollectionExists(ctx, repoName)
+		if err != nil {
+			return nil, err
+		}
+		if exists {
+			err = stage(backupChunksEntry, func(emit func(any) error) error {
+				offset := ""
+				for {
+					chunks, next, err := b.vectorDB.ScrollChunks(ctx, repoName, offset, backupWriteBatch)
+					if err != nil {
+						return err
+					}
+					for _, chunk := range chunks {
+						if err := emit(chunk); err != nil {
+							return err
+						}
+					}
+					if next == "" {
+						return nil
+					}
+					offset = next
+				}
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if err := writeBackupArchive(w, stageDir, manifest); err != nil {
+		return nil, err
+	}
+
+	b.logger.Info("Repository backup written",
+		zap.String("repo_name", repoName),
+		zap.Any("entries", manifest.Entries))
+	return manifest, nil
+}
+
+// writeBackupArchive writes the manifest followed by the staged entries
+func writeBackupArchive(w io.Writer, stageDir string, manifest *BackupManifest) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	header := &tar.Header{Name: backupManifestEntry, Mode: 0o644, Size: int64(len(data)), ModTime: manifest.CreatedAt}
+	if err := tw.WriteHeader(header); err != nil {
+		return fmt.Errorf("failed to write archive: %w", err)
+	}
+	if _, err := tw.Write(data); err != nil {
+		return fmt.Errorf("failed to write archive: %w", err)
+	}
+
+	for _, entry := range []string{backupFileVersionsEntry, backupSummariesEntry, backupNodesEntry, backupRelationsEntry, backupChunksEntry} {
+		if _, ok := manifest.Entries[entry]; !ok {
+			continue
+		}
+		if err := addArchiveFile(tw, entry, filepath.Join(stageDir, filepath.FromSlash(entry)), manifest.CreatedAt); err != nil {
+			return err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return fmt.Errorf("failed to write archive: %w", err)
+	}
+	return gz.Close()
+}
+
+func addArchiveFile(tw *tar.Writer, entry, path string, modTime time.Time) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	header := &tar.Header{Name: entry, Mode: 0o644, Size: info.Size(), ModTime: modTime}
+	if err := tw.WriteHeader(header); err != nil {
+		return fmt.Errorf("failed to write archive: %w", err)
+	}
+	if _, err := io.Copy(tw, f); err != nil {
+		return fmt.Errorf("failed to write archive: %w", err)
+	}
+	return nil
+}
+
+// Restore replaces the data of the archived repository with the contents
+// of the archive read from r. repoName, when set, must match the archive:
+// node IDs are kept as they are, so an archive cannot be restored under a
+// different name next to the original.
+func (b *BackupManager) Restore(ctx context.Context, r io.Reader, repoName string) (*BackupManifest, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("not a backup archive: %w", err)
+	}
+	defer gz.Close()
+	tr := tar.NewReader(gz)
+
+	header, err := tr.Next()
+	if err != nil || header.Name != backupManifestEntry {
+		return nil, fmt.Errorf("not a backup archive: missing %s", backupManifestEntry)
+	}
+	var manifest BackupManifest
+	if err := json.NewDecoder(tr).Decode(&manifest); err != nil {
+		return nil, fmt.Errorf("invalid backup manifest: %w", err)
+	}
+	if manifest.FormatVersion > BackupFormatVersion {
+		return nil, fmt.Errorf("backup format version %d is newer than supported version %d", manifest.FormatVersion, BackupFormatVersion)
+	}
+	if repoName != "" && repoName != manifest.RepoName {
+		return nil, fmt.Errorf("archive holds repository %q, not %q", manifest.RepoName, repoName)
+	}
+	repoName = manifest.RepoName
+
+	if b.dbConn != nil {
+		lock, err := db.LockRepo(ctx, b.dbConn.GetDB(), repoName, "restore", 0)
+		if err != nil {
+			return nil, err
+		}
+		defer lock.Release()
+	}
+
+	if err := b.clearRepository(ctx, repoName); err != nil {
+		return nil, err
+	}
+
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read archive: %w", err)
+		}
+
+		count, err := b.restoreEntry(ctx, repoName, header.Name, tr, manifest.LastFileID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to restore %s: %w", header.Name, err)
+		}
+		if count >= 0 {
+			b.logger.Info("Restored archive entry",
+				zap.String("repo_name", repoName),
+				zap.String("entry", header.Name),
+				zap.Int("records", count))
+		}
+	}
+
+	return &manifest, nil
+}
+
+// restoreEntry imports one archive entry and returns its record count, or
+// -1 when the entry was skipped because its store is not configured
+func (b *BackupManager) restoreEntry(ctx context.Context, repoName, entry string, r io.Reader, lastFileID int32) (int, error) {
+	skip := func(store string) (int, error) {
+		b.logger.Warn("Store not configured, skipping archive entry",
+			zap.String("store", store),
+			zap.String("entry", entry))
+		return -1, nil
+	}
+
+	switch entry {
+	case backupFileVersionsEntry:
+		if b.dbConn == nil {
+			return skip("relational store")
+		}
+		fileVersionRepo, err := db.NewFileVersionRepository(b.dbConn.GetDB(), repoName, b.logger)
+		if err != nil {
+			return 0, err
+		}
+		var versions []*db.FileVersion
+		count, err := readJSONLines(r, backupWriteBatch, func(batch []*db.FileVersion) error {
+			versions = append(versions, batch...)
+			return nil
+		})
+		if err != nil {
+			return 0, err
+		}
+		return count, fileVersionRepo.WithContext(ctx).RestoreVersions(versions, lastFileID)
+
+	case backupSummariesEntry:
+		if b.dbConn == nil {
+			return skip("relational store")
+		}
+		summaryStore, err := db.NewSummaryStore(b.dbConn.GetDB(), repoName, b.logger)
+		if err != nil {
+			return 0, err
+		}
+		summaryStore = summaryStore.WithContext(ctx)
+		return readJSONLines(r, backupWriteBatch, func(batch []*summary.CodeSummary) error {
+			return summaryStore.SaveSummaries(batch)
+		})
+
+	case backupNodesEntry:
+		if b.codeGraph == nil {
+			return skip("neo4j")
+		}
+		return readJSONLines(r, backupWriteBatch, func(batch []codegraph.ExportedNode) error {
+			for i := range batch {
+				batch[i].Properties = normalizeJSONNumbers(batch[i].Properties).(map[string]any)
+			}
+			return b.codeGraph.ImportNodes(ctx, batch)
+		})
+
+	case backupRelationsEntry:
+		if b.codeGraph == nil {
+			return skip("neo4j")
+		}
+		return readJSONLines(r, backupWriteBatch, func(batch []codegraph.ExportedRelation) error {
+			for i := range batch {
+				if batch[i].Properties != nil {
+					batch[i].Properties = normalizeJSONNumbers(batch[i].Properties).(map[string]any)
+				}
+			}
+			return b.codeGraph.ImportRelations(ctx, batch)
+		})
+
+	case backupChunksEntry:
+		if b.vectorDB == nil {
+			return skip("qdrant")
+		}
+		created := false
+		return readJSONLines(r, backupWriteBatch, func(batch []*model.CodeChunk) error {
+			if !created && len(batch[0].Embedding) > 0 {
+				if err := b.vectorDB.CreateCollection(ctx, repoName, len(batch[0].Embedding), vector.DistanceMetricCosine); err != nil {
+					return err
+				}
+				created = true
+			}
+			for _, chunk := range batch {
+				if chunk.Metadata != nil {
+					chunk.Metadata = normalizeJSONNumbers(chunk.Metadata).(map[string]any)
+				}
+			}
+			return b.vectorDB.UpsertChunks(ctx, repoName, batch)
+		})
+
+	default:
+		b.logger.Warn("Ignoring unknown archive entry", zap.String("entry", entry))
+		return -1, nil
+	}
+}
+
+// clearRepository removes the repository's existing data from every
+// configured store so the restored data does not mix with it
+func (b *BackupManager) clearRepository(ctx context.Context, repoName string) error {
+	if b.codeGraph != nil {
+		if err := b.codeGraph.CleanRepository(ctx, repoName); err != nil {
+			return fmt.Errorf("failed to clear graph data: %w", err)
+		}
+	}
+
+	if b.vectorDB != nil {
+		exists, err := b.vectorDB.CollectionExists(ctx, repoName)
+		if err != nil {
+			return err
+		}
+		if exists {
+			if err := b.vectorDB.DeleteCollection(ctx, repoName); err != nil {
+				return fmt.Errorf("failed to clear vector data: %w", err)
+			}
+		}
+	}
+
+	if b.dbConn != nil {
+		fileVersionRepo, err := db.NewFileVersionRepository(b.dbConn.GetDB(), repoName, b.logger)
+		if err != nil {
+			return err
+		}
+		if err := fileVersionRepo.WithContext(ctx).DeleteRepository(); err != nil {
+			return err
+		}
+		summaryStore, err := db.NewSummaryStore(b.dbConn.GetDB(), repoName, b.logger)
+		if err != nil {
+			return err
+		}
+		if _, err := summaryStore.WithContext(ctx).DeleteAll(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readJSONLines decodes a stream of JSON records and hands them to fn in
+// batches of up to batchSize. Numbers in untyped fields are decoded as
+// json.Number so integers survive; see normalizeJSONNumbers.
+func readJSONLines[T any](r io.Reader, batchSize int, fn func([]T) error) (int, error) {
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+
+	count := 0
+	batch := make([]T, 0, batchSize)
+	for {
+		var record T
+		err := dec.Decode(&record)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return count, fmt.Errorf("record %d: %w", count+1, err)
+		}
+		batch = append(batch, record)
+		count++
+		if len(batch) == batchSize {
+			if err := fn(batch); err != nil {
+				return count, err
+			}
+			batch = make([]T, 0, batchSize)
+		}
+	}
+	if len(batch) > 0 {
+		if err := fn(batch); err != nil {
+			return count, err
+		}
+	}
+	return count, nil
+}
+
+// normalizeJSONNumbers converts json.Number values to int64 where possible
+// and float64 otherwise. Graph properties such as id and fileId are
+// integers, and restoring them as floats would break every lookup.
+func normalizeJSONNumbers(v any) any {
+	switch val := v.(type) {
+	case json.Number:
+		if i, err := val.Int64(); err == nil {
+			return i
+		}
+		f, _ := val.Float64()
+		return f
+	case map[string]any:
+		for key, item := range val {
+			val[key] = normalizeJSONNumbers(item)
+		}
+		return val
+	case []any:
+		for i, item := range val {
+			val[i] = normalizeJSONNumbers(item)
+		}
+		return val
+	default:
+		return v
+	}
+}
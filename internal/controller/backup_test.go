@@ -0,0 +1,104 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/armchr/codeapi/internal/db"
+	"github.com/armchr/codeapi/internal/service/summary"
+
+	"go.uber.org/zap"
+)
+
+func newBackupTestDB(t *testing.T) *db.SQLiteConnection {
+	t.Helper()
+	conn, err := db.NewSQLiteConnection(filepath.Join(t.TempDir(), "codeapi.db"), zap.NewNop())
+	if err != nil {
+		t.Fatalf("failed to open SQLite: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func TestBackupRestoreRelationalStore(t *testing.T) {
+	ctx := context.Background()
+	logger := zap.NewNop()
+
+	source := newBackupTestDB(t)
+	versions, err := db.NewFileVersionRepository(source.GetDB(), "api", logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fileID, _ := versions.GetOrCreateFileID("sha1", "main.go", false, nil)
+	summaries, err := db.NewSummaryStore(source.GetDB(), "api", logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	summaries.SaveSummary(&summary.CodeSummary{EntityID: "main.go", EntityType: summary.LevelFile, FilePath: "main.go", Summary: "entry point"})
+
+	var archive bytes.Buffer
+	manifest, err := NewBackupManager(source, nil, nil, logger).Backup(ctx, "api", &archive)
+	if err != nil {
+		t.Fatalf("Backup: %v", err)
+	}
+	if manifest.Entries[backupFileVersionsEntry] != 1 || manifest.Entries[backupSummariesEntry] != 1 {
+		t.Errorf("unexpected manifest entries %v", manifest.Entries)
+	}
+	if _, ok := manifest.Entries[backupNodesEntry]; ok {
+		t.Error("graph entries written without a code graph")
+	}
+
+	target := newBackupTestDB(t)
+	restorer := NewBackupManager(target, nil, nil, logger)
+	if _, err := restorer.Restore(ctx, bytes.NewReader(archive.Bytes()), "other"); err == nil {
+		t.Error("restoring under a different repository name should fail")
+	}
+	if _, err := restorer.Restore(ctx, bytes.NewReader(archive.Bytes()), ""); err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+
+	restoredVersions, _ := db.NewFileVersionRepository(target.GetDB(), "api", logger)
+	fv, err := restoredVersions.GetFileByID(fileID)
+	if err != nil || fv == nil || fv.RelativePath != "main.go" {
+		t.Errorf("expected restored file version %d, got %+v (err %v)", fileID, fv, err)
+	}
+	restoredSummaries, _ := db.NewSummaryStore(target.GetDB(), "api", logger)
+	file, err := restoredSummaries.GetFileSummary("main.go")
+	if err != nil || file == nil || file.Summary != "entry point" {
+		t.Errorf("expected restored summary, got %+v (err %v)", file, err)
+	}
+
+	// Restoring twice replaces rather than duplicates
+	if _, err := restorer.Restore(ctx, bytes.NewReader(archive.Bytes()), "api"); err != nil {
+		t.Fatalf("second Restore: %v", err)
+	}
+	if ids, _ := restoredVersions.GetAllFileIDs(); len(ids) != 1 {
+		t.Errorf("expected 1 file version after second restore, got %v", ids)
+	}
+}
+
+func TestReadJSONLinesNormalizesNumbers(t *testing.T) {
+	input := `{"id": 42, "ratio": 0.5, "tags": [1, "a"]}
+{"id": 9007199254740993}
+`
+	var got []map[string]any
+	count, err := readJSONLines(strings.NewReader(input), 1, func(batch []map[string]any) error {
+		for _, record := range batch {
+			got = append(got, normalizeJSONNumbers(record).(map[string]any))
+		}
+		return nil
+	})
+	if err != nil || count != 2 {
+		t.Fatalf("readJSONLines() = %d, %v", count, err)
+	}
+	if got[0]["id"] != int64(42) || got[0]["ratio"] != 0.5 || got[0]["tags"].([]any)[0] != int64(1) {
+		t.Errorf("unexpected first record %v", got[0])
+	}
+	// Integers beyond float64 precision must not be rounded
+	if got[1]["id"] != int64(9007199254740993) {
+		t.Errorf("large id decoded as %v", got[1]["id"])
+	}
+}
@@ -0,0 +1,181 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/armchr/codeapi/internal/config"
+	"github.com/armchr/codeapi/internal/db"
+	"github.com/armchr/codeapi/internal/service/audit"
+	"github.com/armchr/codeapi/internal/service/codegraph"
+	"github.com/armchr/codeapi/internal/service/notify"
+	"go.uber.org/zap"
+)
+
+// auditQualityKey is the audit detail holding the quality of a build
+const auditQualityKey = "quality"
+
+// CallResolutionCounter is implemented by processors that can count how the
+// calls of a repository were resolved
+type CallResolutionCounter interface {
+	CountCallResolution(ctx context.Context, repoName string) (*codegraph.CallResolution, error)
+}
+
+// BuildQuality measures how much of a repository a build understood. The
+// call ratios cover the whole repository, the counts only the files the
+// build processed.
+type BuildQuality struct {
+	Calls               int64   `json:"calls"`
+	UnresolvedCallRatio float64 `json:"unresolved_call_ratio"`
+	ExternalCallRatio   float64 `json:"external_call_ratio"`
+	ParseFailures       int64   `json:"parse_failures"`
+	EmptyChunkFiles     int64   `json:"empty_chunk_files"`
+}
+
+// QualityRegression is a metric that got worse than in the previous build
+type QualityRegression struct {
+	Metric   string  `json:"metric"`
+	Previous float64 `json:"previous"`
+	Current  float64 `json:"current"`
+}
+
+// newBuildQuality combines the call resolution of the repository with the
+// counters the processors reported for the build
+func newBuildQuality(calls *codegraph.CallResolution, stats *BuildStats) *BuildQuality {
+	q := &BuildQuality{Calls: calls.Calls}
+	if calls.Calls > 0 {
+		q.UnresolvedCallRatio = float64(calls.Unresolved) / float64(calls.Calls)
+		q.ExternalCallRatio = float64(calls.External) / float64(calls.Calls)
+	}
+	for _, p := range stats.Processors {
+		q.ParseFailures += p.Counters[StatParseFailures]
+		q.EmptyChunkFiles += p.Counters[StatEmptyChunkFiles]
+	}
+	return q
+}
+
+// FindQualityRegressions returns the metrics of cur that grew past the
+// thresholds of cfg compared with prev. Ratios are skipped when either build
+// has no calls, as they are meaningless then.
+func FindQualityRegressions(prev, cur *BuildQuality, cfg config.QualityAlertsConfig) []QualityRegression {
+	var regressions []QualityRegression
+	if prev.Calls > 0 && cur.Calls > 0 {
+		for _, m := range []struct {
+			name      string
+			prev, cur float64
+		}{
+			{"unresolved_call_ratio", prev.UnresolvedCallRatio, cur.UnresolvedCallRatio},
+			{"external_call_ratio", prev.ExternalCallRatio, cur.ExternalCallRatio},
+		} {
+			if m.cur-m.prev > cfg.MaxRatioIncrease {
+				regressions = append(regressions, QualityRegression{m.name, m.prev, m.cur})
+			}
+		}
+	}
+	for _, m := range []struct {
+		name      string
+		prev, cur int64
+	}{
+		{"parse_failures", prev.ParseFailures, cur.ParseFailures},
+		{"empty_chunk_files", prev.EmptyChunkFiles, cur.EmptyChunkFiles},
+	} {
+		if m.cur-m.prev > cfg.MaxCountIncrease {
+			regressions = append(regressions, QualityRegression{m.name, float64(m.prev), float64(m.cur)})
+		}
+	}
+	return regressions
+}
+
+// previousBuildQuality returns the quality recorded in the first of entries,
+// or nil when it has none, such as a build without the code graph
+func previousBuildQuality(entries []audit.Entry) (*BuildQuality, error) {
+	if len(entries) == 0 || entries[0].Details[auditQualityKey] == nil {
+		return nil, nil
+	}
+	// Details come back from the audit log as generic JSON
+	encoded, err := json.Marshal(entries[0].Details[auditQualityKey])
+	if err != nil {
+		return nil, err
+	}
+	var q BuildQuality
+	if err := json.Unmarshal(encoded, &q); err != nil {
+		return nil, fmt.Errorf("failed to decode build quality: %w", err)
+	}
+	return &q, nil
+}
+
+// checkQuality measures the quality of a successful build into stats and,
+// with quality alerts enabled, notifies when it regressed against the
+// previous successful build. Failures are logged, never failing the build.
+func (ib *IndexBuilder) checkQuality(ctx context.Context, repo *config.Repository, stats *BuildStats) {
+	var counter CallResolutionCounter
+	for _, p := range ib.processors {
+		if c, ok := p.(CallResolutionCounter); ok {
+			counter = c
+			break
+		}
+	}
+	if counter == nil {
+		return
+	}
+	calls, err := counter.CountCallResolution(ctx, repo.Name)
+	if err != nil {
+		ib.logger.Warn("Failed to count call resolution",
+			zap.String("repo_name", repo.Name), zap.Error(err))
+		return
+	}
+	stats.Quality = newBuildQuality(calls, stats)
+
+	alerts := ib.config.IndexBuilding.QualityAlerts.GetDefaults()
+	if !alerts.Enabled {
+		return
+	}
+	// The audit entry of this build is only recorded once it returns, so
+	// the newest one is the previous build
+	store, err := db.NewAuditStore(ib.fileVersionRepo.DB(), repo.Name, ib.logger)
+	if err != nil {
+		ib.logger.Warn("Failed to open audit log", zap.String("repo_name", repo.Name), zap.Error(err))
+		return
+	}
+	entries, err := store.WithContext(ctx).List(audit.Filter{
+		Operation: audit.OpIndexBuild,
+		Outcome:   audit.OutcomeSuccess,
+		Limit:     1,
+	})
+	if err != nil {
+		ib.logger.Warn("Failed to read previous build", zap.String("repo_name", repo.Name), zap.Error(err))
+		return
+	}
+	prev, err := previousBuildQuality(entries)
+	if err != nil || prev == nil {
+		if err != nil {
+			ib.logger.Warn("Failed to read previous build quality", zap.String("repo_name", repo.Name), zap.Error(err))
+		}
+		return
+	}
+
+	stats.QualityRegressions = FindQualityRegressions(prev, stats.Quality, alerts)
+	if len(stats.QualityRegressions) == 0 {
+		return
+	}
+	lines := make([]string, len(stats.QualityRegressions))
+	for i, r := range stats.QualityRegressions {
+		lines[i] = fmt.Sprintf("%s: %g -> %g", r.Metric, r.Previous, r.Current)
+	}
+	err = notify.New(ib.config.Notifications, ib.logger).Notify(ctx, &notify.Notification{
+		Event:    notify.EventQualityRegression,
+		RepoName: repo.Name,
+		Title:    "Index quality regressed",
+		Text:     strings.Join(lines, "\n"),
+		Details: map[string]any{
+			"previous":    prev,
+			"current":     stats.Quality,
+			"regressions": stats.QualityRegressions,
+		},
+	})
+	if err != nil {
+		ib.logger.Warn("Failed to send quality notification", zap.String("repo_name", repo.Name), zap.Error(err))
+	}
+}
@@ -0,0 +1,103 @@
+package controller
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/armchr/codeapi/internal/config"
+	"github.com/armchr/codeapi/internal/service/audit"
+	"github.com/armchr/codeapi/internal/service/codegraph"
+)
+
+func TestNewBuildQuality(t *testing.T) {
+	stats := &BuildStats{Processors: []ProcessorStats{
+		{Name: "CodeGraph", Counters: map[string]int64{StatNodesCreated: 40, StatParseFailures: 2}},
+		{Name: "Embedding", Counters: map[string]int64{StatEmptyChunkFiles: 3}},
+		{Name: "GitChurn"},
+	}}
+	got := newBuildQuality(&codegraph.CallResolution{Calls: 200, External: 50, Unresolved: 20}, stats)
+	want := &BuildQuality{Calls: 200, UnresolvedCallRatio: 0.1, ExternalCallRatio: 0.25, ParseFailures: 2, EmptyChunkFiles: 3}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("newBuildQuality = %+v, want %+v", got, want)
+	}
+}
+
+func TestFindQualityRegressions(t *testing.T) {
+	cfg := (&config.QualityAlertsConfig{}).GetDefaults()
+	prev := &BuildQuality{Calls: 100, UnresolvedCallRatio: 0.1, ExternalCallRatio: 0.3, ParseFailures: 1, EmptyChunkFiles: 4}
+
+	tests := []struct {
+		name string
+		cur  BuildQuality
+		want []QualityRegression
+	}{
+		{
+			name: "within thresholds",
+			cur:  BuildQuality{Calls: 90, UnresolvedCallRatio: 0.14, ExternalCallRatio: 0.2, ParseFailures: 11, EmptyChunkFiles: 0},
+		},
+		{
+			name: "unresolved calls and parse failures",
+			cur:  BuildQuality{Calls: 100, UnresolvedCallRatio: 0.4, ExternalCallRatio: 0.3, ParseFailures: 12, EmptyChunkFiles: 4},
+			want: []QualityRegression{
+				{"unresolved_call_ratio", 0.1, 0.4},
+				{"parse_failures", 1, 12},
+			},
+		},
+		{
+			name: "no calls skips ratios",
+			cur:  BuildQuality{EmptyChunkFiles: 20},
+			want: []QualityRegression{{"empty_chunk_files", 4, 20}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FindQualityRegressions(prev, &tt.cur, cfg); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FindQualityRegressions = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPreviousBuildQuality(t *testing.T) {
+	// Details as read back from the audit log
+	recorded := audit.Entry{Details: map[string]any{
+		"files_total": float64(10),
+		auditQualityKey: map[string]any{
+			"calls": float64(100), "unresolved_call_ratio": 0.1, "parse_failures": float64(2),
+		},
+	}}
+
+	tests := []struct {
+		name    string
+		entries []audit.Entry
+		want    *BuildQuality
+	}{
+		{"recorded", []audit.Entry{recorded}, &BuildQuality{Calls: 100, UnresolvedCallRatio: 0.1, ParseFailures: 2}},
+		{"without quality", []audit.Entry{{Details: map[string]any{"files_total": float64(10)}}}, nil},
+		{"first build", nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := previousBuildQuality(tt.entries)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("previousBuildQuality = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAuditDetailsQuality(t *testing.T) {
+	stats := &BuildStats{FilesTotal: 3}
+	if _, ok := stats.AuditDetails()[auditQualityKey]; ok {
+		t.Error("AuditDetails has a quality before it was measured")
+	}
+	stats.Quality = &BuildQuality{Calls: 5}
+	stats.QualityRegressions = []QualityRegression{{"parse_failures", 0, 20}}
+	details := stats.AuditDetails()
+	if details[auditQualityKey] != stats.Quality || details["quality_regressions"] == nil {
+		t.Errorf("AuditDetails = %v", details)
+	}
+}
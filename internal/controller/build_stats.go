@@ -0,0 +1,211 @@
+package controller
+
+import (
+	"io/fs"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/armchr/codeapi/internal/config"
+	"github.com/armchr/codeapi/internal/metrics"
+	"github.com/armchr/codeapi/internal/util"
+)
+
+// Phases of a repository build, as reported to a ProgressListener
+const (
+	PhaseInit        = "init"
+	PhaseScan        = "scan"
+	PhaseFiles       = "files"
+	PhasePostProcess = "post-process"
+)
+
+// Counter names reported by the built-in processors
+const (
+	StatNodesCreated       = "nodes_created"
+	StatChunksEmbedded     = "chunks_embedded"
+	StatSummariesGenerated = "summaries_generated"
+	StatTokensUsed         = "tokens_used"
+	StatParseFailures      = "parse_failures"    // files the code graph parser failed on
+	StatEmptyChunkFiles    = "empty_chunk_files" // text files left without any chunk
+)
+
+// ProgressListener receives progress events while an IndexBuilder runs.
+// FileDone is called from the file worker goroutines, so implementations
+// must be safe for concurrent use.
+type ProgressListener interface {
+	// PhaseStarted is called when the build of repoName enters a new phase
+	PhaseStarted(repoName, phase string)
+
+	// FilesCounted reports the number of files the files phase will visit
+	FilesCounted(repoName string, total int)
+
+	// FileDone is called once per visited file, whether it was processed,
+	// skipped as unchanged or failed
+	FileDone(repoName, relativePath string)
+}
+
+// StatsProvider is implemented by processors that keep counters worth
+// reporting at the end of a build. Counters are cumulative over the life of
+// the processor; the index builder reports their change during a build.
+type StatsProvider interface {
+	Stats() map[string]int64
+}
+
+// ProcessorStats is the time a processor spent and the counters it reported
+// during one repository build
+type ProcessorStats struct {
+	Name            string           `json:"name"`
+	FileTime        time.Duration    `json:"file_time"`
+	PostProcessTime time.Duration    `json:"post_process_time"`
+	Counters        map[string]int64 `json:"counters,omitempty"`
+}
+
+// BuildStats summarizes one repository build
+type BuildStats struct {
+	RepoName       string `json:"repo_name"`
+	FilesTotal     int    `json:"files_total"`
+	FilesProcessed int    `json:"files_processed"`
+	FilesUnchanged int    `json:"files_unchanged"`
+	FilesOversized int    `json:"files_oversized,omitempty"`
+	// FilesByLanguage counts the visited files per detected language
+	FilesByLanguage map[string]int   `json:"files_by_language,omitempty"`
+	Duration        time.Duration    `json:"duration"`
+	Processors      []ProcessorStats `json:"processors"`
+
+	// Quality is measured after a successful build with the code graph, and
+	// QualityRegressions lists how it is worse than the previous build's
+	Quality            *BuildQuality       `json:"quality,omitempty"`
+	QualityRegressions []QualityRegression `json:"quality_regressions,omitempty"`
+}
+
+// Counter returns the sum of a counter across all processors
+func (s *BuildStats) Counter(name string) int64 {
+	var total int64
+	for _, p := range s.Processors {
+		total += p.Counters[name]
+	}
+	return total
+}
+
+// AuditDetails returns the file counts of the build for its audit entry,
+// and its quality, which the next build is compared with
+func (s *BuildStats) AuditDetails() map[string]any {
+	details := map[string]any{
+		"files_total":     s.FilesTotal,
+		"files_processed": s.FilesProcessed,
+		"files_unchanged": s.FilesUnchanged,
+		"files_oversized": s.FilesOversized,
+	}
+	if s.Quality != nil {
+		details[auditQualityKey] = s.Quality
+	}
+	if len(s.QualityRegressions) > 0 {
+		details["quality_regressions"] = s.QualityRegressions
+	}
+	return details
+}
+
+// buildRecorder collects BuildStats while the files of a build are processed
+// concurrently
+type buildRecorder struct {
+	mu       sync.Mutex
+	stats    *BuildStats
+	before   []map[string]int64
+	fileTime []time.Duration
+}
+
+func newBuildRecorder(repoName string, processors []FileProcessor) *buildRecorder {
+	r := &buildRecorder{
+		stats:    &BuildStats{RepoName: repoName, Processors: make([]ProcessorStats, len(processors))},
+		before:   make([]map[string]int64, len(processors)),
+		fileTime: make([]time.Duration, len(processors)),
+	}
+	for i, p := range processors {
+		r.stats.Processors[i].Name = p.Name()
+		if sp, ok := p.(StatsProvider); ok {
+			r.before[i] = sp.Stats()
+		}
+	}
+	return r
+}
+
+func (r *buildRecorder) addFileTime(processor int, d time.Duration) {
+	r.mu.Lock()
+	r.fileTime[processor] += d
+	r.mu.Unlock()
+	metrics.ObserveProcessorFile(r.stats.RepoName, r.stats.Processors[processor].Name, d)
+}
+
+func (r *buildRecorder) fileDone(language string, processed bool) {
+	r.mu.Lock()
+	if language != "" {
+		if r.stats.FilesByLanguage == nil {
+			r.stats.FilesByLanguage = make(map[string]int)
+		}
+		r.stats.FilesByLanguage[language]++
+	}
+	if processed {
+		r.stats.FilesProcessed++
+	} else {
+		r.stats.FilesUnchanged++
+	}
+	r.mu.Unlock()
+	metrics.IndexFileDone(r.stats.RepoName, processed)
+}
+
+// fileOversized counts a file over the size limit, whether it was skipped or
+// truncated
+func (r *buildRecorder) fileOversized() {
+	r.mu.Lock()
+	r.stats.FilesOversized++
+	r.mu.Unlock()
+}
+
+func (r *buildRecorder) setPostProcessTime(processor int, d time.Duration) {
+	r.mu.Lock()
+	r.stats.Processors[processor].PostProcessTime = d
+	r.mu.Unlock()
+	metrics.ObserveProcessorPostProcess(r.stats.RepoName, r.stats.Processors[processor].Name, d)
+}
+
+// finish computes counter deltas and returns the completed stats
+func (r *buildRecorder) finish(processors []FileProcessor, duration time.Duration) *BuildStats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.stats.Duration = duration
+	for i, p := range processors {
+		r.stats.Processors[i].FileTime = r.fileTime[i]
+		sp, ok := p.(StatsProvider)
+		if !ok {
+			continue
+		}
+		counters := make(map[string]int64)
+		for name, value := range sp.Stats() {
+			counters[name] = value - r.before[i][name]
+		}
+		r.stats.Processors[i].Counters = counters
+	}
+	return r.stats
+}
+
+// countFiles counts the files the files phase will visit, applying the same
+// directory and file filters as the walk itself
+func countFiles(repo *config.Repository) int {
+	count := 0
+	filepath.WalkDir(repo.Path, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() {
+			if path != repo.Path && util.ShouldSkipDirectory(path) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.Type().IsRegular() && !util.ShouldSkipFile(path, repo) {
+			count++
+		}
+		return nil
+	})
+	return count
+}
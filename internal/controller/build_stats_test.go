@@ -0,0 +1,120 @@
+package controller
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/armchr/codeapi/internal/config"
+	"github.com/armchr/codeapi/internal/db"
+
+	"go.uber.org/zap"
+)
+
+type countingProcessor struct {
+	mu    sync.Mutex
+	files int64
+}
+
+func (p *countingProcessor) Init(ctx context.Context, repo *config.Repository) error { return nil }
+func (p *countingProcessor) PostProcess(ctx context.Context, repo *config.Repository) error {
+	return nil
+}
+func (p *countingProcessor) Name() string { return "Counting" }
+
+func (p *countingProcessor) ProcessFile(ctx context.Context, repo *config.Repository, fileCtx *FileContext) error {
+	p.mu.Lock()
+	p.files++
+	p.mu.Unlock()
+	return nil
+}
+
+func (p *countingProcessor) Stats() map[string]int64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return map[string]int64{"files_seen": p.files}
+}
+
+type recordingListener struct {
+	mu     sync.Mutex
+	phases []string
+	total  int
+	done   int
+}
+
+func (l *recordingListener) PhaseStarted(repoName, phase string) {
+	l.mu.Lock()
+	l.phases = append(l.phases, phase)
+	l.mu.Unlock()
+}
+
+func (l *recordingListener) FilesCounted(repoName string, total int) {
+	l.mu.Lock()
+	l.total = total
+	l.mu.Unlock()
+}
+
+func (l *recordingListener) FileDone(repoName, relativePath string) {
+	l.mu.Lock()
+	l.done++
+	l.mu.Unlock()
+}
+
+func TestIndexBuilderProgressAndStats(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"main.go", "util/util.go", "node_modules/dep/index.js"} {
+		path := filepath.Join(dir, name)
+		os.MkdirAll(filepath.Dir(path), 0o755)
+		if err := os.WriteFile(path, []byte("package main\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	logger := zap.NewNop()
+	conn := newBackupTestDB(t)
+	versions, err := db.NewFileVersionRepository(conn.GetDB(), "api", logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo := &config.Repository{Name: "api", Path: dir, Language: "go"}
+	processor := &countingProcessor{}
+	listener := &recordingListener{}
+
+	builder := NewIndexBuilder(&config.Config{}, []FileProcessor{processor}, versions, logger)
+	builder.SetProgressListener(listener)
+	if err := builder.BuildIndex(context.Background(), repo); err != nil {
+		t.Fatalf("BuildIndex: %v", err)
+	}
+
+	want := []string{PhaseInit, PhaseScan, PhaseFiles, PhasePostProcess}
+	if len(listener.phases) != len(want) {
+		t.Fatalf("phases = %v, want %v", listener.phases, want)
+	}
+	for i := range want {
+		if listener.phases[i] != want[i] {
+			t.Errorf("phase %d = %s, want %s", i, listener.phases[i], want[i])
+		}
+	}
+	if listener.total != 2 || listener.done != 2 {
+		t.Errorf("progress %d/%d, want 2/2", listener.done, listener.total)
+	}
+
+	stats := builder.LastStats()
+	if stats == nil || stats.FilesTotal != 2 || stats.FilesProcessed != 2 || stats.FilesUnchanged != 0 {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+	if got := stats.Counter("files_seen"); got != 2 {
+		t.Errorf("files_seen = %d, want 2", got)
+	}
+
+	// A second build sees unchanged files and reports only its own counters
+	if err := builder.BuildIndex(context.Background(), repo); err != nil {
+		t.Fatalf("second BuildIndex: %v", err)
+	}
+	stats = builder.LastStats()
+	if stats.FilesProcessed != 0 || stats.FilesUnchanged != 2 || stats.Counter("files_seen") != 0 {
+		t.Errorf("unexpected stats for unchanged rebuild %+v", stats)
+	}
+}
@@ -0,0 +1,216 @@
+package controller
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/armchr/codeapi/internal/logging"
+	"github.com/armchr/codeapi/internal/model"
+	"github.com/armchr/codeapi/internal/service/vector"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// chunkExportPage is the number of chunks read from the vector database at a
+// time. Only one page is held in memory.
+const chunkExportPage = 256
+
+// ChunkExportFilter selects the chunks of an export. Empty fields match
+// every chunk.
+type ChunkExportFilter struct {
+	Language   string
+	ChunkTypes []model.ChunkType
+	PathPrefix string // repository-relative
+	Embeddings bool   // keep the vectors of each chunk
+}
+
+// matches reports whether chunk, whose repository-relative path is path, is
+// selected
+func (f *ChunkExportFilter) matches(chunk *model.CodeChunk, path string) bool {
+	if f.Language != "" && chunk.Language != f.Language {
+		return false
+	}
+	if len(f.ChunkTypes) > 0 && !slices.Contains(f.ChunkTypes, chunk.ChunkType) {
+		return false
+	}
+	return f.PathPrefix == "" || strings.HasPrefix(path, f.PathPrefix)
+}
+
+// ChunkExportLine is one line of a chunk export. Chunk lines are followed,
+// after each page, by a cursor line from which the export can be resumed;
+// the last line has Done set.
+type ChunkExportLine struct {
+	Chunk  *model.CodeChunk `json:"chunk,omitempty"`
+	Cursor string           `json:"cursor,omitempty"`
+	Done   bool             `json:"done,omitempty"`
+	Count  int              `json:"count,omitempty"` // chunks written, on the last line
+}
+
+// chunkExportCursor is the position of an export, handed to the client as
+// an opaque string. It names the repository so a cursor cannot be replayed
+// against another collection.
+type chunkExportCursor struct {
+	RepoName string `json:"r"`
+	Offset   string `json:"o"`
+}
+
+func encodeChunkExportCursor(repoName, offset string) string {
+	data, _ := json.Marshal(&chunkExportCursor{RepoName: repoName, Offset: offset})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeChunkExportCursor returns the vector database offset an export
+// cursor of repoName resumes from
+func decodeChunkExportCursor(repoName, cursor string) (string, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return "", errors.New("malformed cursor")
+	}
+	var c chunkExportCursor
+	if err := json.Unmarshal(data, &c); err != nil {
+		return "", errors.New("malformed cursor")
+	}
+	if c.RepoName != repoName {
+		return "", errors.New("cursor was issued for another repository")
+	}
+	return c.Offset, nil
+}
+
+// ChunkExporter writes the chunks of a repository's collection as JSON
+// lines, one page at a time. Writes block while the client is slow to read,
+// which holds back the next page.
+type ChunkExporter struct {
+	vectorDB vector.VectorDatabase
+	repoName string
+	repoPath string
+	filter   ChunkExportFilter
+	prepare  func(*model.CodeChunk) *model.CodeChunk // applied to each chunk written
+}
+
+// Export writes the selected chunks from offset to w, calling flush after
+// each page, and returns the number written. The first page is read before
+// anything is written, so a failure to start the export leaves w untouched.
+func (e *ChunkExporter) Export(ctx context.Context, offset string, w io.Writer, flush func()) (int, error) {
+	enc := json.NewEncoder(w)
+	count := 0
+	for {
+		chunks, next, err := e.vectorDB.ScrollChunks(ctx, e.repoName, offset, chunkExportPage)
+		if err != nil {
+			return count, fmt.Errorf("failed to read chunks: %w", err)
+		}
+		for _, chunk := range chunks {
+			path, ok := repoRelativePath(e.repoPath, chunk.FilePath)
+			if !ok || !e.filter.matches(chunk, path) {
+				continue
+			}
+			if !e.filter.Embeddings {
+				chunk.Embedding = nil
+				chunk.SignatureEmbedding = nil
+			}
+			if e.prepare != nil {
+				chunk = e.prepare(chunk)
+			}
+			if err := enc.Encode(&ChunkExportLine{Chunk: chunk}); err != nil {
+				return count, err
+			}
+			count++
+		}
+
+		line := &ChunkExportLine{Cursor: encodeChunkExportCursor(e.repoName, next)}
+		if next == "" {
+			line = &ChunkExportLine{Done: true, Count: count}
+		}
+		if err := enc.Encode(line); err != nil {
+			return count, err
+		}
+		flush()
+		if next == "" {
+			return count, nil
+		}
+		offset = next
+	}
+}
+
+// ExportChunks streams the chunks of a repository with their metadata as
+// newline-delimited JSON, for consumers that need the whole collection. The
+// optional query parameters language, chunk_type (comma-separated),
+// path_prefix and embeddings=true filter and widen the output; cursor
+// resumes an export from one of its cursor lines. In public mode the source
+// text of each chunk is left out.
+func (rc *RepoController) ExportChunks(c *gin.Context) {
+	logger := logging.FromContext(c.Request.Context(), rc.logger)
+	repoName := c.Param("repo")
+	repo, err := rc.config.GetRepository(repoName)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "Repository not found",
+			"details": err.Error(),
+		})
+		return
+	}
+	if rc.chunkService == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error": "Code chunk service not available",
+		})
+		return
+	}
+
+	offset := ""
+	if cursor := c.Query("cursor"); cursor != "" {
+		if offset, err = decodeChunkExportCursor(repoName, cursor); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid cursor",
+				"details": err.Error(),
+			})
+			return
+		}
+	}
+
+	filter := ChunkExportFilter{
+		Language:   c.Query("language"),
+		PathPrefix: c.Query("path_prefix"),
+		Embeddings: c.Query("embeddings") == "true",
+	}
+	for _, t := range strings.Split(c.Query("chunk_type"), ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			filter.ChunkTypes = append(filter.ChunkTypes, model.ChunkType(t))
+		}
+	}
+
+	exporter := &ChunkExporter{
+		vectorDB: rc.chunkService.GetVectorDB(),
+		repoName: repoName,
+		repoPath: repo.Path,
+		filter:   filter,
+		prepare: func(chunk *model.CodeChunk) *model.CodeChunk {
+			rc.setChunkRefs(repoName, chunk)
+			if rc.publicMode() {
+				return redactChunk(chunk)
+			}
+			return chunk
+		},
+	}
+
+	c.Header("Content-Type", "application/x-ndjson")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", repoName+"-chunks.jsonl"))
+	count, err := exporter.Export(c.Request.Context(), offset, c.Writer, c.Writer.Flush)
+	if err != nil {
+		logger.Error("Failed to export chunks", zap.String("repo_name", repoName), zap.Int("count", count), zap.Error(err))
+		if !c.Writer.Written() {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Failed to export chunks",
+				"details": err.Error(),
+			})
+		}
+		return
+	}
+	logger.Info("Exported chunks", zap.String("repo_name", repoName), zap.Int("count", count))
+}
@@ -0,0 +1,150 @@
+package controller
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"testing"
+
+	"github.com/armchr/codeapi/internal/model"
+	"github.com/armchr/codeapi/internal/service/vector"
+)
+
+// pagedDB serves the chunks of one collection to ScrollChunks, with the
+// index of the next chunk as offset
+type pagedDB struct {
+	vector.VectorDatabase
+	chunks   []*model.CodeChunk
+	pageSize int
+	reads    int
+}
+
+func (d *pagedDB) ScrollChunks(ctx context.Context, collectionName string, offset string, limit int) ([]*model.CodeChunk, string, error) {
+	d.reads++
+	start := 0
+	if offset != "" {
+		start, _ = strconv.Atoi(offset)
+	}
+	end := min(start+d.pageSize, len(d.chunks))
+	next := ""
+	if end < len(d.chunks) {
+		next = strconv.Itoa(end)
+	}
+	return d.chunks[start:end], next, nil
+}
+
+func exportTestChunks() []*model.CodeChunk {
+	chunks := make([]*model.CodeChunk, 5)
+	for i := range chunks {
+		chunks[i] = &model.CodeChunk{
+			ID:        fmt.Sprintf("c%d", i),
+			ChunkType: model.ChunkTypeFunction,
+			Language:  "go",
+			FilePath:  "/src/shop/api/handler.go",
+			Content:   "func f() {}",
+			Embedding: []float32{1, 0},
+		}
+	}
+	chunks[1].FilePath = "/src/shop/web/app.ts"
+	chunks[1].Language = "typescript"
+	chunks[3].ChunkType = model.ChunkTypeFile
+	return chunks
+}
+
+func readExport(t *testing.T, data []byte) []ChunkExportLine {
+	t.Helper()
+	var lines []ChunkExportLine
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		var line ChunkExportLine
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			t.Fatalf("line %q: %v", scanner.Text(), err)
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func TestChunkExport(t *testing.T) {
+	tests := []struct {
+		name    string
+		filter  ChunkExportFilter
+		wantIDs []string
+	}{
+		{"all", ChunkExportFilter{}, []string{"c0", "c1", "c2", "c3", "c4"}},
+		{"language", ChunkExportFilter{Language: "go"}, []string{"c0", "c2", "c3", "c4"}},
+		{"chunk type", ChunkExportFilter{ChunkTypes: []model.ChunkType{model.ChunkTypeFile}}, []string{"c3"}},
+		{"path prefix", ChunkExportFilter{PathPrefix: "web/"}, []string{"c1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := &pagedDB{chunks: exportTestChunks(), pageSize: 2}
+			exporter := &ChunkExporter{vectorDB: db, repoName: "shop", repoPath: "/src/shop", filter: tt.filter}
+			var buf bytes.Buffer
+			flushes := 0
+			count, err := exporter.Export(context.Background(), "", &buf, func() { flushes++ })
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			lines := readExport(t, buf.Bytes())
+			var ids []string
+			for _, line := range lines {
+				if line.Chunk != nil {
+					ids = append(ids, line.Chunk.ID)
+					if line.Chunk.Embedding != nil {
+						t.Errorf("chunk %s kept its embedding", line.Chunk.ID)
+					}
+				}
+			}
+			if fmt.Sprint(ids) != fmt.Sprint(tt.wantIDs) || count != len(tt.wantIDs) {
+				t.Errorf("exported %v (count %d), want %v", ids, count, tt.wantIDs)
+			}
+			last := lines[len(lines)-1]
+			if !last.Done || last.Count != count {
+				t.Errorf("last line = %+v, want done with count %d", last, count)
+			}
+			if flushes != 3 || db.reads != 3 {
+				t.Errorf("flushed %d times over %d reads, want one per page of 3", flushes, db.reads)
+			}
+		})
+	}
+}
+
+func TestChunkExportResume(t *testing.T) {
+	db := &pagedDB{chunks: exportTestChunks(), pageSize: 2}
+	exporter := &ChunkExporter{vectorDB: db, repoName: "shop", repoPath: "/src/shop", filter: ChunkExportFilter{Embeddings: true}}
+	var buf bytes.Buffer
+	if _, err := exporter.Export(context.Background(), "", &buf, func() {}); err != nil {
+		t.Fatal(err)
+	}
+	lines := readExport(t, buf.Bytes())
+	if lines[0].Chunk.Embedding == nil {
+		t.Error("embeddings were dropped")
+	}
+
+	// resume from the first cursor line, after c0 and c1
+	cursor := lines[2].Cursor
+	if cursor == "" {
+		t.Fatalf("line 3 = %+v, want a cursor", lines[2])
+	}
+	if _, err := decodeChunkExportCursor("web", cursor); err == nil {
+		t.Error("cursor of shop was accepted for web")
+	}
+	offset, err := decodeChunkExportCursor("shop", cursor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf.Reset()
+	count, err := exporter.Export(context.Background(), offset, &buf, func() {})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resumed := readExport(t, buf.Bytes())
+	if count != 3 || resumed[0].Chunk.ID != "c2" {
+		t.Errorf("resumed export wrote %d chunks starting at %+v, want 3 from c2", count, resumed[0])
+	}
+}
@@ -2,7 +2,10 @@ package controller
 
 import (
 	"bufio"
+	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
@@ -12,7 +15,11 @@ import (
 
 	"github.com/armchr/codeapi/internal/codeapi"
 	"github.com/armchr/codeapi/internal/config"
+	"github.com/armchr/codeapi/internal/db"
+	"github.com/armchr/codeapi/internal/entity"
+	"github.com/armchr/codeapi/internal/logging"
 	"github.com/armchr/codeapi/internal/model/ast"
+	"github.com/armchr/codeapi/internal/service/summary"
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
@@ -60,19 +67,35 @@ func (f *FlexibleFunctionID) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
+// MarshalJSON writes the ID when set and the (qualified) name otherwise, the
+// forms UnmarshalJSON reads
+func (f FlexibleFunctionID) MarshalJSON() ([]byte, error) {
+	switch {
+	case f.ID != 0:
+		return json.Marshal(f.ID)
+	case f.ClassName != "":
+		return json.Marshal(f.ClassName + "." + f.FunctionName)
+	default:
+		return json.Marshal(f.FunctionName)
+	}
+}
+
 // CodeAPIController handles HTTP requests for the CodeAPI
 type CodeAPIController struct {
-	api    codeapi.CodeAPI
-	cfg    *config.Config
-	logger *zap.Logger
+	api     codeapi.CodeAPI
+	mysqlDB *sql.DB // optional, used to attach summaries to class hierarchies
+	cfg     *config.Config
+	logger  *zap.Logger
 }
 
-// NewCodeAPIController creates a new CodeAPIController
-func NewCodeAPIController(api codeapi.CodeAPI, cfg *config.Config, logger *zap.Logger) *CodeAPIController {
+// NewCodeAPIController creates a new CodeAPIController. mysqlDB may be nil,
+// in which case class hierarchies come without summaries.
+func NewCodeAPIController(api codeapi.CodeAPI, mysqlDB *sql.DB, cfg *config.Config, logger *zap.Logger) *CodeAPIController {
 	return &CodeAPIController{
-		api:    api,
-		cfg:    cfg,
-		logger: logger,
+		api:     api,
+		mysqlDB: mysqlDB,
+		cfg:     cfg,
+		logger:  logger,
 	}
 }
 
@@ -133,15 +156,17 @@ type FindMethodsRequest struct {
 // GetClassRequest is the request for getting a class by ID
 type GetClassRequest struct {
 	RepoName       string `json:"repo_name" binding:"required"`
-	ClassID        int64  `json:"class_id" binding:"required"`
+	ClassID        int64  `json:"class_id"`
+	EntityRef      string `json:"entity_ref"` // instead of class_id
 	IncludeMethods bool   `json:"include_methods"`
 	IncludeFields  bool   `json:"include_fields"`
 }
 
 // GetMethodRequest is the request for getting a method by ID
 type GetMethodRequest struct {
-	RepoName string `json:"repo_name" binding:"required"`
-	MethodID int64  `json:"method_id" binding:"required"`
+	RepoName  string `json:"repo_name" binding:"required"`
+	MethodID  int64  `json:"method_id"`
+	EntityRef string `json:"entity_ref"` // instead of method_id
 }
 
 // GetCallGraphRequest is the request for getting a call graph.
@@ -152,12 +177,16 @@ type GetMethodRequest struct {
 type GetCallGraphRequest struct {
 	RepoName        string              `json:"repo_name" binding:"required"`
 	FunctionID      *FlexibleFunctionID `json:"function_id"`
+	EntityRef       string              `json:"entity_ref"` // instead of function_id or function_name
 	FunctionName    string              `json:"function_name"`
 	ClassName       string              `json:"class_name"`
 	FilePath        string              `json:"file_path"`
 	Direction       string              `json:"direction"` // "outgoing", "incoming", "both"
 	MaxDepth        int                 `json:"max_depth"`
 	IncludeExternal bool                `json:"include_external"`
+
+	// IncludePossibleCalls follows calls of interface methods to their implementations
+	IncludePossibleCalls bool `json:"include_possible_calls"`
 }
 
 // GetDataDependentsRequest is the request for getting data dependents
@@ -174,12 +203,17 @@ type GetDataDependentsRequest struct {
 type GetImpactRequest struct {
 	RepoName         string `json:"repo_name" binding:"required"`
 	NodeID           int64  `json:"node_id"`
+	EntityRef        string `json:"entity_ref"` // instead of node_id or name
 	Name             string `json:"name"`
 	NodeType         string `json:"node_type"` // "function", "class", "field", "variable"
 	FilePath         string `json:"file_path"`
 	MaxDepth         int    `json:"max_depth"`
 	IncludeCallGraph bool   `json:"include_call_graph"`
 	IncludeDataFlow  bool   `json:"include_data_flow"`
+
+	// IncludePossibleCalls counts callers of interface methods as callers of
+	// their implementations; defaults to true
+	IncludePossibleCalls *bool `json:"include_possible_calls"`
 }
 
 // ExecuteCypherRequest is the request for executing raw Cypher
@@ -206,6 +240,71 @@ type GetCodeSnippetResponse struct {
 	TotalLines int    `json:"total_lines"`
 }
 
+// FieldAccessorsRequest selects a field by ID, or by class and field name
+type FieldAccessorsRequest struct {
+	RepoName  string `json:"repo_name" binding:"required"`
+	FieldID   int64  `json:"field_id"`
+	ClassName string `json:"class_name"`
+	FieldName string `json:"field_name"`
+}
+
+// TableAccessorsRequest is the request for the code accessing a table
+type TableAccessorsRequest struct {
+	RepoName string `json:"repo_name" binding:"required"`
+	Table    string `json:"table" binding:"required"`
+}
+
+// ConfigUsagesRequest is the request for the usages of a configuration key
+type ConfigUsagesRequest struct {
+	RepoName string `json:"repo_name" binding:"required"`
+	Key      string `json:"key" binding:"required"`
+}
+
+// FeatureFlagsRequest lists the feature flags of a repository, all of them
+// when Key is empty
+type FeatureFlagsRequest struct {
+	RepoName string `json:"repo_name" binding:"required"`
+	Key      string `json:"key"`
+}
+
+// SinkPathsRequest is the request for paths from handlers to security sinks
+type SinkPathsRequest struct {
+	RepoName             string `json:"repo_name" binding:"required"`
+	Category             string `json:"category"`
+	MaxDepth             int    `json:"max_depth"`
+	IncludePossibleCalls bool   `json:"include_possible_calls"`
+}
+
+// EndpointLineageRequest is the request for what the endpoints of a
+// repository touch downstream
+type EndpointLineageRequest struct {
+	RepoName             string `json:"repo_name" binding:"required"`
+	MaxDepth             int    `json:"max_depth"`
+	IncludePossibleCalls bool   `json:"include_possible_calls"`
+}
+
+// ComplexFunctionsRequest ranks functions by SortBy: complexity (default),
+// loc, params or nesting
+type ComplexFunctionsRequest struct {
+	RepoName string `json:"repo_name" binding:"required"`
+	SortBy   string `json:"sort_by"`
+	Limit    int    `json:"limit"`
+}
+
+// PackageCouplingRequest ranks packages by SortBy: fan_in (default) or
+// fan_out
+type PackageCouplingRequest struct {
+	RepoName string `json:"repo_name" binding:"required"`
+	SortBy   string `json:"sort_by"`
+	Limit    int    `json:"limit"`
+}
+
+// HotspotsRequest is the request for complex, frequently changed functions
+type HotspotsRequest struct {
+	RepoName string `json:"repo_name" binding:"required"`
+	Limit    int    `json:"limit"`
+}
+
 // -----------------------------------------------------------------------------
 // Reader Endpoints
 // -----------------------------------------------------------------------------
@@ -217,6 +316,17 @@ func (c *CodeAPIController) ListRepos(ctx *gin.Context) {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+
+	// Tenant callers only see their own repositories
+	if tenant := config.TenantFromContext(ctx.Request.Context()); tenant != nil {
+		owned := repos[:0]
+		for _, repo := range repos {
+			if strings.HasPrefix(repo, tenant.Name+config.TenantSeparator) {
+				owned = append(owned, repo)
+			}
+		}
+		repos = owned
+	}
 	ctx.JSON(http.StatusOK, ListReposResponse{Repos: repos})
 }
 
@@ -244,11 +354,13 @@ func (c *CodeAPIController) ListClasses(ctx *gin.Context) {
 		return
 	}
 
-	classes, err := c.api.Reader().Repo(req.RepoName).ListClasses(ctx.Request.Context(), req.Limit, req.Offset)
+	repo := c.api.Reader().Repo(req.RepoName)
+	classes, err := repo.ListClasses(ctx.Request.Context(), req.Limit, req.Offset)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	addEntityRefs(ctx.Request.Context(), repo, classes, nil, c.logger)
 	ctx.JSON(http.StatusOK, gin.H{"classes": classes})
 }
 
@@ -260,35 +372,40 @@ func (c *CodeAPIController) ListMethods(ctx *gin.Context) {
 		return
 	}
 
-	methods, err := c.api.Reader().Repo(req.RepoName).ListMethods(ctx.Request.Context(), req.Limit, req.Offset)
+	repo := c.api.Reader().Repo(req.RepoName)
+	methods, err := repo.ListMethods(ctx.Request.Context(), req.Limit, req.Offset)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	addEntityRefs(ctx.Request.Context(), repo, nil, methods, c.logger)
 	ctx.JSON(http.StatusOK, gin.H{"methods": methods})
 }
 
 // ListFunctions returns top-level functions in a repository
 func (c *CodeAPIController) ListFunctions(ctx *gin.Context) {
+	logger := logging.FromContext(ctx.Request.Context(), c.logger)
 	var req ListMethodsRequest
 	if err := ctx.ShouldBindJSON(&req); err != nil {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.logger.Debug("ListFunctions called",
+	logger.Debug("ListFunctions called",
 		zap.String("repo_name", req.RepoName),
 		zap.Int("limit", req.Limit),
 		zap.Int("offset", req.Offset))
 
-	functions, err := c.api.Reader().Repo(req.RepoName).ListFunctions(ctx.Request.Context(), req.Limit, req.Offset)
+	repo := c.api.Reader().Repo(req.RepoName)
+	functions, err := repo.ListFunctions(ctx.Request.Context(), req.Limit, req.Offset)
 	if err != nil {
-		c.logger.Error("ListFunctions failed", zap.Error(err))
+		logger.Error("ListFunctions failed", zap.Error(err))
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	addEntityRefs(ctx.Request.Context(), repo, nil, functions, c.logger)
 
-	c.logger.Debug("ListFunctions completed",
+	logger.Debug("ListFunctions completed",
 		zap.String("repo_name", req.RepoName),
 		zap.Int("result_count", len(functions)))
 
@@ -312,11 +429,13 @@ func (c *CodeAPIController) FindClasses(ctx *gin.Context) {
 		Offset:   req.Offset,
 	}
 
-	classes, err := c.api.Reader().Repo(req.RepoName).FindClasses(ctx.Request.Context(), filter)
+	repo := c.api.Reader().Repo(req.RepoName)
+	classes, err := repo.FindClasses(ctx.Request.Context(), filter)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	addEntityRefs(ctx.Request.Context(), repo, classes, nil, c.logger)
 	ctx.JSON(http.StatusOK, gin.H{"classes": classes})
 }
 
@@ -339,11 +458,13 @@ func (c *CodeAPIController) FindMethods(ctx *gin.Context) {
 		Offset:    req.Offset,
 	}
 
-	methods, err := c.api.Reader().Repo(req.RepoName).FindMethods(ctx.Request.Context(), filter)
+	repo := c.api.Reader().Repo(req.RepoName)
+	methods, err := repo.FindMethods(ctx.Request.Context(), filter)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	addEntityRefs(ctx.Request.Context(), repo, nil, methods, c.logger)
 	ctx.JSON(http.StatusOK, gin.H{"methods": methods})
 }
 
@@ -356,22 +477,27 @@ func (c *CodeAPIController) GetClass(ctx *gin.Context) {
 	}
 
 	repo := c.api.Reader().Repo(req.RepoName)
+	classID, ok := c.classID(ctx, repo, &req)
+	if !ok {
+		return
+	}
 	var class *codeapi.ClassInfo
 	var err error
 
 	if req.IncludeMethods || req.IncludeFields {
-		class, err = repo.GetClassFull(ctx.Request.Context(), ast.NodeID(req.ClassID), codeapi.LoadOptions{
+		class, err = repo.GetClassFull(ctx.Request.Context(), classID, codeapi.LoadOptions{
 			IncludeMethods: req.IncludeMethods,
 			IncludeFields:  req.IncludeFields,
 		})
 	} else {
-		class, err = repo.GetClass(ctx.Request.Context(), ast.NodeID(req.ClassID))
+		class, err = repo.GetClass(ctx.Request.Context(), classID)
 	}
 
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	addEntityRefs(ctx.Request.Context(), repo, []*codeapi.ClassInfo{class}, nil, c.logger)
 	ctx.JSON(http.StatusOK, gin.H{"class": class})
 }
 
@@ -383,11 +509,25 @@ func (c *CodeAPIController) GetMethod(ctx *gin.Context) {
 		return
 	}
 
-	method, err := c.api.Reader().Repo(req.RepoName).GetMethod(ctx.Request.Context(), ast.NodeID(req.MethodID))
+	repo := c.api.Reader().Repo(req.RepoName)
+	methodID := ast.NodeID(req.MethodID)
+	if req.EntityRef != "" {
+		id, ok := c.resolveEntityRef(ctx, repo, req.EntityRef, entity.KindFunction)
+		if !ok {
+			return
+		}
+		methodID = id
+	} else if methodID == 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "either method_id or entity_ref is required"})
+		return
+	}
+
+	method, err := repo.GetMethod(ctx.Request.Context(), methodID)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	addEntityRefs(ctx.Request.Context(), repo, nil, []*codeapi.MethodInfo{method}, c.logger)
 	ctx.JSON(http.StatusOK, gin.H{"method": method})
 }
 
@@ -399,11 +539,17 @@ func (c *CodeAPIController) GetClassMethods(ctx *gin.Context) {
 		return
 	}
 
-	methods, err := c.api.Reader().Repo(req.RepoName).GetClassMethods(ctx.Request.Context(), ast.NodeID(req.ClassID))
+	repo := c.api.Reader().Repo(req.RepoName)
+	classID, ok := c.classID(ctx, repo, &req)
+	if !ok {
+		return
+	}
+	methods, err := repo.GetClassMethods(ctx.Request.Context(), classID)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	addEntityRefs(ctx.Request.Context(), repo, nil, methods, c.logger)
 	ctx.JSON(http.StatusOK, gin.H{"methods": methods})
 }
 
@@ -415,7 +561,12 @@ func (c *CodeAPIController) GetClassFields(ctx *gin.Context) {
 		return
 	}
 
-	fields, err := c.api.Reader().Repo(req.RepoName).GetClassFields(ctx.Request.Context(), ast.NodeID(req.ClassID))
+	repo := c.api.Reader().Repo(req.RepoName)
+	classID, ok := c.classID(ctx, repo, &req)
+	if !ok {
+		return
+	}
+	fields, err := repo.GetClassFields(ctx.Request.Context(), classID)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -423,6 +574,39 @@ func (c *CodeAPIController) GetClassFields(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, gin.H{"fields": fields})
 }
 
+// classID returns the class a GetClassRequest names by ID or entity
+// reference, answering the request itself when it names none
+func (c *CodeAPIController) classID(ctx *gin.Context, repo codeapi.RepoReader, req *GetClassRequest) (ast.NodeID, bool) {
+	if req.EntityRef != "" {
+		return c.resolveEntityRef(ctx, repo, req.EntityRef, entity.KindClass)
+	}
+	if req.ClassID == 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "either class_id or entity_ref is required"})
+		return 0, false
+	}
+	return ast.NodeID(req.ClassID), true
+}
+
+// resolveEntityRef returns the graph node of the entity reference of a
+// request, answering the request itself when it cannot be resolved
+func (c *CodeAPIController) resolveEntityRef(ctx *gin.Context, repo codeapi.RepoReader, s string, kinds ...entity.Kind) (ast.NodeID, bool) {
+	ref, err := parseEntityRef(repo.Name(), s, kinds...)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return 0, false
+	}
+	node, err := repo.ResolveEntity(ctx.Request.Context(), ref)
+	if errors.Is(err, codeapi.ErrEntityNotFound) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return 0, false
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return 0, false
+	}
+	return node.ID, true
+}
+
 // -----------------------------------------------------------------------------
 // Analyzer Endpoints
 // -----------------------------------------------------------------------------
@@ -472,12 +656,21 @@ func (c *CodeAPIController) GetCallGraph(ctx *gin.Context) {
 		Direction:       direction,
 		MaxDepth:        req.MaxDepth,
 		IncludeExternal: req.IncludeExternal,
+
+		IncludePossibleCalls: req.IncludePossibleCalls,
 	}
 
 	var callGraph *codeapi.CallGraph
 	var err error
 
 	funcID, funcName, className := req.resolveFunctionRef()
+	if req.EntityRef != "" {
+		id, ok := c.resolveEntityRef(ctx, c.api.Reader().Repo(req.RepoName), req.EntityRef, entity.KindFunction)
+		if !ok {
+			return
+		}
+		funcID = int64(id)
+	}
 	if funcID > 0 {
 		callGraph, err = c.api.Analyzer().GetCallGraph(ctx.Request.Context(), ast.NodeID(funcID), opts)
 	} else if funcName != "" {
@@ -487,7 +680,7 @@ func (c *CodeAPIController) GetCallGraph(ctx *gin.Context) {
 			opts,
 		)
 	} else {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "either function_id or function_name is required"})
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "either function_id, function_name or entity_ref is required"})
 		return
 	}
 
@@ -495,6 +688,7 @@ func (c *CodeAPIController) GetCallGraph(ctx *gin.Context) {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	setCallGraphRefs(req.RepoName, callGraph)
 	ctx.JSON(http.StatusOK, gin.H{"call_graph": callGraph})
 }
 
@@ -514,12 +708,21 @@ func (c *CodeAPIController) GetCallers(ctx *gin.Context) {
 		Direction:       codeapi.DirectionIncoming,
 		MaxDepth:        req.MaxDepth,
 		IncludeExternal: req.IncludeExternal,
+
+		IncludePossibleCalls: req.IncludePossibleCalls,
 	}
 
 	var callGraph *codeapi.CallGraph
 	var err error
 
 	funcID, funcName, className := req.resolveFunctionRef()
+	if req.EntityRef != "" {
+		id, ok := c.resolveEntityRef(ctx, c.api.Reader().Repo(req.RepoName), req.EntityRef, entity.KindFunction)
+		if !ok {
+			return
+		}
+		funcID = int64(id)
+	}
 	if funcID > 0 {
 		callGraph, err = c.api.Analyzer().GetCallGraph(ctx.Request.Context(), ast.NodeID(funcID), opts)
 	} else if funcName != "" {
@@ -529,7 +732,7 @@ func (c *CodeAPIController) GetCallers(ctx *gin.Context) {
 			opts,
 		)
 	} else {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "either function_id or function_name is required"})
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "either function_id, function_name or entity_ref is required"})
 		return
 	}
 
@@ -537,6 +740,7 @@ func (c *CodeAPIController) GetCallers(ctx *gin.Context) {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	setCallGraphRefs(req.RepoName, callGraph)
 	ctx.JSON(http.StatusOK, gin.H{"call_graph": callGraph})
 }
 
@@ -556,12 +760,21 @@ func (c *CodeAPIController) GetCallees(ctx *gin.Context) {
 		Direction:       codeapi.DirectionOutgoing,
 		MaxDepth:        req.MaxDepth,
 		IncludeExternal: req.IncludeExternal,
+
+		IncludePossibleCalls: req.IncludePossibleCalls,
 	}
 
 	var callGraph *codeapi.CallGraph
 	var err error
 
 	funcID, funcName, className := req.resolveFunctionRef()
+	if req.EntityRef != "" {
+		id, ok := c.resolveEntityRef(ctx, c.api.Reader().Repo(req.RepoName), req.EntityRef, entity.KindFunction)
+		if !ok {
+			return
+		}
+		funcID = int64(id)
+	}
 	if funcID > 0 {
 		callGraph, err = c.api.Analyzer().GetCallGraph(ctx.Request.Context(), ast.NodeID(funcID), opts)
 	} else if funcName != "" {
@@ -571,7 +784,7 @@ func (c *CodeAPIController) GetCallees(ctx *gin.Context) {
 			opts,
 		)
 	} else {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "either function_id or function_name is required"})
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "either function_id, function_name or entity_ref is required"})
 		return
 	}
 
@@ -579,6 +792,7 @@ func (c *CodeAPIController) GetCallees(ctx *gin.Context) {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	setCallGraphRefs(req.RepoName, callGraph)
 	ctx.JSON(http.StatusOK, gin.H{"call_graph": callGraph})
 }
 
@@ -660,11 +874,20 @@ func (c *CodeAPIController) GetImpact(ctx *gin.Context) {
 		MaxDepth:         req.MaxDepth,
 		IncludeCallGraph: req.IncludeCallGraph,
 		IncludeDataFlow:  req.IncludeDataFlow,
+
+		IncludePossibleCalls: req.IncludePossibleCalls == nil || *req.IncludePossibleCalls,
 	}
 
 	var impact *codeapi.ImpactResult
 	var err error
 
+	if req.EntityRef != "" {
+		id, ok := c.resolveEntityRef(ctx, c.api.Reader().Repo(req.RepoName), req.EntityRef, entity.KindClass, entity.KindFunction)
+		if !ok {
+			return
+		}
+		req.NodeID = int64(id)
+	}
 	if req.NodeID != 0 {
 		impact, err = c.api.Analyzer().GetImpact(ctx.Request.Context(), ast.NodeID(req.NodeID), opts)
 	} else if req.Name != "" {
@@ -683,7 +906,7 @@ func (c *CodeAPIController) GetImpact(ctx *gin.Context) {
 			opts,
 		)
 	} else {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "either node_id or name is required"})
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "either node_id, name or entity_ref is required"})
 		return
 	}
 
@@ -710,15 +933,74 @@ func (c *CodeAPIController) GetInheritanceTree(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, gin.H{"inheritance_tree": tree})
 }
 
-// GetFieldAccessors returns methods that access a field
-func (c *CodeAPIController) GetFieldAccessors(ctx *gin.Context) {
-	type FieldAccessorsRequest struct {
-		RepoName  string `json:"repo_name" binding:"required"`
-		FieldID   int64  `json:"field_id"`
-		ClassName string `json:"class_name"`
-		FieldName string `json:"field_name"`
+// GetClassHierarchy returns the inheritance forest of a repository's classes
+// and interfaces, with the stored summary of each class. The package and
+// path query parameters keep the classes whose package or file path starts
+// with them.
+func (c *CodeAPIController) GetClassHierarchy(ctx *gin.Context) {
+	logger := logging.FromContext(ctx.Request.Context(), c.logger)
+	repoName := ctx.Param("repo")
+	opts := codeapi.ClassHierarchyOptions{
+		PackagePrefix: ctx.Query("package"),
+		PathPrefix:    ctx.Query("path"),
+	}
+
+	hierarchy, err := c.api.Analyzer().GetClassHierarchy(ctx.Request.Context(), repoName, opts)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if c.mysqlDB != nil && hierarchy.Classes > 0 {
+		if err := c.addClassSummaries(ctx.Request.Context(), repoName, hierarchy.Roots); err != nil {
+			// The hierarchy is still useful without them
+			logger.Warn("Failed to attach class summaries", zap.String("repo_name", repoName), zap.Error(err))
+		}
+	}
+	ctx.JSON(http.StatusOK, gin.H{"repo_name": repoName, "hierarchy": hierarchy})
+}
+
+// addClassSummaries fills in the stored summary of every class of a hierarchy
+func (c *CodeAPIController) addClassSummaries(ctx context.Context, repoName string, roots []*codeapi.ClassHierarchyNode) error {
+	exists, err := db.TableExists(c.mysqlDB, db.CodeSummariesTable)
+	if err != nil || !exists {
+		return err
+	}
+	summaries, err := db.NewSummaryReader(c.mysqlDB, repoName, c.logger).WithContext(ctx).GetSummaryMap(summary.LevelClass)
+	if err != nil {
+		return err
+	}
+
+	var fill func(nodes []*codeapi.ClassHierarchyNode)
+	fill = func(nodes []*codeapi.ClassHierarchyNode) {
+		for _, node := range nodes {
+			if cs, ok := summaries[strconv.FormatInt(int64(node.ID), 10)]; ok {
+				node.Summary = cs.Summary
+			}
+			fill(node.Children)
+		}
+	}
+	fill(roots)
+	return nil
+}
+
+// GetInjectionWiring returns the dependency injection wiring of a class
+func (c *CodeAPIController) GetInjectionWiring(ctx *gin.Context) {
+	var req GetClassRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	wiring, err := c.api.Analyzer().GetInjectionWiring(ctx.Request.Context(), ast.NodeID(req.ClassID))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
 	}
+	ctx.JSON(http.StatusOK, gin.H{"injection_wiring": wiring})
+}
 
+// GetFieldAccessors returns methods that access a field
+func (c *CodeAPIController) GetFieldAccessors(ctx *gin.Context) {
 	var req FieldAccessorsRequest
 	if err := ctx.ShouldBindJSON(&req); err != nil {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
@@ -747,6 +1029,165 @@ func (c *CodeAPIController) GetFieldAccessors(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, gin.H{"field_accessors": result})
 }
 
+// GetTableAccessors returns the code that reads or writes a database table
+func (c *CodeAPIController) GetTableAccessors(ctx *gin.Context) {
+	var req TableAccessorsRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := c.api.Analyzer().GetTableAccessors(ctx.Request.Context(), req.RepoName, req.Table)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"table_accessors": result})
+}
+
+// GetConfigUsages returns where a configuration key is defined and read
+func (c *CodeAPIController) GetConfigUsages(ctx *gin.Context) {
+	var req ConfigUsagesRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := c.api.Analyzer().GetConfigUsages(ctx.Request.Context(), req.RepoName, req.Key)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"config_usages": result})
+}
+
+// ListFeatureFlags returns the feature flags of a repository and the calls
+// checking them
+func (c *CodeAPIController) ListFeatureFlags(ctx *gin.Context) {
+	var req FeatureFlagsRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	flags, err := c.api.Analyzer().ListFeatureFlags(ctx.Request.Context(), req.RepoName, req.Key)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"feature_flags": flags})
+}
+
+// GetSinkPaths returns the call paths from HTTP endpoint handlers to calls
+// of security-sensitive sinks
+func (c *CodeAPIController) GetSinkPaths(ctx *gin.Context) {
+	var req SinkPathsRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	paths, err := c.api.Analyzer().GetSinkPaths(ctx.Request.Context(), req.RepoName, codeapi.SinkPathOptions{
+		Category:             req.Category,
+		MaxDepth:             req.MaxDepth,
+		IncludePossibleCalls: req.IncludePossibleCalls,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"sink_paths": paths})
+}
+
+// GetEndpointLineage returns, for each HTTP endpoint of a repository, the
+// tables, services and external APIs its handler reaches
+func (c *CodeAPIController) GetEndpointLineage(ctx *gin.Context) {
+	var req EndpointLineageRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	lineage, err := c.api.Analyzer().GetEndpointLineage(ctx.Request.Context(), req.RepoName, codeapi.LineageOptions{
+		MaxDepth:             req.MaxDepth,
+		IncludePossibleCalls: req.IncludePossibleCalls,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"endpoints": lineage})
+}
+
+// ListComplexFunctions returns the functions of a repository ranked by a
+// complexity or size metric
+func (c *CodeAPIController) ListComplexFunctions(ctx *gin.Context) {
+	var req ComplexFunctionsRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	switch req.SortBy {
+	case "", "complexity", "loc", "params", "nesting", "callees":
+	default:
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "sort_by must be one of complexity, loc, params, nesting, callees"})
+		return
+	}
+
+	functions, err := c.api.Analyzer().ListComplexFunctions(ctx.Request.Context(), req.RepoName, codeapi.ComplexityOptions{
+		SortBy: req.SortBy,
+		Limit:  req.Limit,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"functions": functions})
+}
+
+// ListPackageCoupling returns the packages of a repository ranked by how
+// many other packages call into them or are called by them
+func (c *CodeAPIController) ListPackageCoupling(ctx *gin.Context) {
+	var req PackageCouplingRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	switch req.SortBy {
+	case "", "fan_in", "fan_out":
+	default:
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "sort_by must be one of fan_in, fan_out"})
+		return
+	}
+
+	packages, err := c.api.Analyzer().ListPackageCoupling(ctx.Request.Context(), req.RepoName, codeapi.PackageCouplingOptions{
+		SortBy: req.SortBy,
+		Limit:  req.Limit,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"packages": packages})
+}
+
+// ListHotspots returns the functions of a repository that are both complex
+// and frequently changed
+func (c *CodeAPIController) ListHotspots(ctx *gin.Context) {
+	var req HotspotsRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	hotspots, err := c.api.Analyzer().ListHotspots(ctx.Request.Context(), req.RepoName, codeapi.HotspotOptions{Limit: req.Limit})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"hotspots": hotspots})
+}
+
 // -----------------------------------------------------------------------------
 // Raw Cypher Endpoints
 // -----------------------------------------------------------------------------
@@ -789,6 +1230,7 @@ func (c *CodeAPIController) ExecuteCypherWrite(ctx *gin.Context) {
 
 // GetCodeSnippet returns a code snippet from a file in a repository
 func (c *CodeAPIController) GetCodeSnippet(ctx *gin.Context) {
+	logger := logging.FromContext(ctx.Request.Context(), c.logger)
 	var req GetCodeSnippetRequest
 	if err := ctx.ShouldBindJSON(&req); err != nil {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
@@ -855,7 +1297,7 @@ func (c *CodeAPIController) GetCodeSnippet(ctx *gin.Context) {
 			ctx.JSON(http.StatusNotFound, gin.H{"error": "file not found"})
 			return
 		}
-		c.logger.Error("Failed to read file", zap.Error(err), zap.String("path", realFilePath))
+		logger.Error("Failed to read file", zap.Error(err), zap.String("path", realFilePath))
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to read file"})
 		return
 	}
@@ -0,0 +1,50 @@
+package controller
+
+import (
+	"context"
+	"testing"
+
+	"github.com/armchr/codeapi/internal/codeapi"
+	"github.com/armchr/codeapi/internal/db"
+	"github.com/armchr/codeapi/internal/service/summary"
+
+	"go.uber.org/zap"
+)
+
+func TestAddClassSummaries(t *testing.T) {
+	logger := zap.NewNop()
+	conn := newBackupTestDB(t)
+	c := NewCodeAPIController(nil, conn.GetDB(), nil, logger)
+
+	child := &codeapi.ClassHierarchyNode{ID: 22, Name: "FileStore"}
+	roots := []*codeapi.ClassHierarchyNode{
+		{ID: 21, Name: "Store", IsInterface: true, Children: []*codeapi.ClassHierarchyNode{child}},
+	}
+
+	// Nothing summarized yet
+	if err := c.addClassSummaries(context.Background(), "api", roots); err != nil {
+		t.Fatal(err)
+	}
+	if roots[0].Summary != "" || child.Summary != "" {
+		t.Fatalf("summaries before summarizing: %q, %q", roots[0].Summary, child.Summary)
+	}
+
+	store, err := db.NewSummaryStore(conn.GetDB(), "api", logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.SaveSummaries([]*summary.CodeSummary{
+		{EntityID: "22", EntityType: summary.LevelClass, EntityName: "FileStore", FilePath: "store/file.go", Summary: "keeps blobs on disk"},
+		{EntityID: "21", EntityType: summary.LevelFunction, EntityName: "Store", FilePath: "store/store.go", Summary: "not a class summary"},
+	})
+
+	if err := c.addClassSummaries(context.Background(), "api", roots); err != nil {
+		t.Fatal(err)
+	}
+	if roots[0].Summary != "" {
+		t.Errorf("root got summary %q from another level", roots[0].Summary)
+	}
+	if child.Summary != "keeps blobs on disk" {
+		t.Errorf("child summary = %q", child.Summary)
+	}
+}
@@ -2,11 +2,15 @@ package controller
 
 import (
 	"github.com/armchr/codeapi/internal/config"
+	"github.com/armchr/codeapi/internal/logging"
 	"github.com/armchr/codeapi/internal/parse"
 	"github.com/armchr/codeapi/internal/service"
 	"github.com/armchr/codeapi/internal/service/codegraph"
+	"github.com/armchr/codeapi/pkg/lsp"
 	"context"
 	"os"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"go.uber.org/zap"
@@ -18,6 +22,13 @@ type CodeGraphProcessor struct {
 	codeGraph   *codegraph.CodeGraph
 	repoService *service.RepoService
 	logger      *zap.Logger
+
+	// Files parsed per repository since its last post-processing, whose
+	// functions need their materialized views refreshed
+	changedMu    sync.Mutex
+	changedFiles map[string][]int32
+
+	parseFailures atomic.Int64 // Not reset between repositories
 }
 
 // NewCodeGraphProcessor creates a new code graph processor
@@ -28,10 +39,11 @@ func NewCodeGraphProcessor(
 	logger *zap.Logger,
 ) *CodeGraphProcessor {
 	return &CodeGraphProcessor{
-		config:      config,
-		codeGraph:   codeGraph,
-		repoService: repoService,
-		logger:      logger,
+		config:       config,
+		codeGraph:    codeGraph,
+		repoService:  repoService,
+		logger:       logger,
+		changedFiles: make(map[string][]int32),
 	}
 }
 
@@ -40,6 +52,29 @@ func (cgp *CodeGraphProcessor) Name() string {
 	return "CodeGraph"
 }
 
+// Stats reports the graph nodes created and the files that failed to parse so far
+func (cgp *CodeGraphProcessor) Stats() map[string]int64 {
+	return map[string]int64{
+		StatNodesCreated:  cgp.codeGraph.NodesWritten(),
+		StatParseFailures: cgp.parseFailures.Load(),
+	}
+}
+
+// CountCallResolution counts the calls of a repository by how they were
+// resolved, for the quality of its build
+func (cgp *CodeGraphProcessor) CountCallResolution(ctx context.Context, repoName string) (*codegraph.CallResolution, error) {
+	return cgp.codeGraph.CountCallResolution(ctx, repoName)
+}
+
+// CacheSizes reports the graph caches, which are shared by all repositories
+func (cgp *CodeGraphProcessor) CacheSizes() []CacheSize {
+	filePaths, bufferedFiles := cgp.codeGraph.CacheSizes()
+	return []CacheSize{
+		{Cache: "graph_file_paths", Entries: filePaths},
+		{Cache: "graph_write_buffers", Entries: bufferedFiles},
+	}
+}
+
 // Init initializes the processor for a repository.
 // This pre-initializes the language server to ensure it's ready for post-processing.
 func (cgp *CodeGraphProcessor) Init(ctx context.Context, repo *config.Repository) error {
@@ -47,6 +82,13 @@ func (cgp *CodeGraphProcessor) Init(ctx context.Context, repo *config.Repository
 		zap.String("repo_name", repo.Name),
 		zap.String("language", repo.Language))
 
+	if !cgp.config.IndexBuilding.UsesLanguageServer() {
+		cgp.logger.Info("Language server disabled by the indexing profile, calls are resolved from the code graph only",
+			zap.String("repo_name", repo.Name),
+			zap.String("profile", cgp.config.IndexBuilding.Profile))
+		return nil
+	}
+
 	// Pre-initialize the language server for this repository
 	// This ensures the LSP is ready before we start post-processing (call hierarchy resolution)
 	if err := cgp.repoService.PrepareLanguageServer(repo.Name); err != nil {
@@ -64,13 +106,13 @@ func (cgp *CodeGraphProcessor) Init(ctx context.Context, repo *config.Repository
 
 // ProcessFile processes a single file for code graph building
 func (cgp *CodeGraphProcessor) ProcessFile(ctx context.Context, repo *config.Repository, fileCtx *FileContext) error {
-	fileParser := parse.NewFileParser(cgp.logger, cgp.codeGraph, cgp.config)
+	fileParser := parse.NewFileParser(logging.Module(cgp.logger, logging.ModuleParse), cgp.codeGraph, cgp.config)
 
 	// Create a minimal FileInfo for compatibility (we don't need stat anymore)
 	// We'll use a dummy FileInfo that only provides what's needed
 	info := &dummyFileInfo{}
 
-	if fileParser.ShouldSkipFile(ctx, repo, info, fileCtx.FilePath) {
+	if fileParser.ShouldSkipFile(ctx, repo, info, fileCtx.FilePath, fileCtx.Content) {
 		return nil
 	}
 
@@ -93,6 +135,7 @@ func (cgp *CodeGraphProcessor) ProcessFile(ctx context.Context, repo *config.Rep
 			zap.String("path", fileCtx.FilePath),
 			zap.Int32("file_id", fileCtx.FileID),
 			zap.Error(err))
+		cgp.parseFailures.Add(1)
 		// Still cleanup buffers even on error
 		cgp.codeGraph.CleanupFileBuffers(ctx, fileCtx.FileID)
 		return nil // Continue processing other files
@@ -108,13 +151,41 @@ func (cgp *CodeGraphProcessor) ProcessFile(ctx context.Context, repo *config.Rep
 		return nil // Continue processing other files
 	}
 
+	// Carry function identity over from the previous version of the file, so
+	// that renamed functions are not taken for a deletion and an addition
+	matches, err := cgp.codeGraph.LinkPreviousVersions(ctx, repo.Name, fileCtx.FileID)
+	if err != nil {
+		cgp.logger.Warn("Failed to link functions to the previous file version",
+			zap.String("path", fileCtx.FilePath),
+			zap.Int32("file_id", fileCtx.FileID),
+			zap.Error(err))
+	}
+	for _, m := range matches {
+		if m.Renamed() {
+			cgp.logger.Debug("Detected renamed function",
+				zap.String("path", fileCtx.RelativePath),
+				zap.String("from", m.Previous.Name),
+				zap.String("to", m.Current.Name))
+		}
+	}
+
+	cgp.changedMu.Lock()
+	cgp.changedFiles[repo.Name] = append(cgp.changedFiles[repo.Name], fileCtx.FileID)
+	cgp.changedMu.Unlock()
+
 	cgp.logger.Debug("Successfully parsed file for code graph",
 		zap.String("path", fileCtx.FilePath),
 		zap.Int32("file_id", fileCtx.FileID))
 	return nil
 }
 
-// PostProcess performs LSP-based post-processing on the repository
+// PurgeFiles deletes the graph nodes created for the given file versions
+func (cgp *CodeGraphProcessor) PurgeFiles(ctx context.Context, repo *config.Repository, fileIDs []int32) error {
+	return cgp.codeGraph.DeleteFiles(ctx, repo.Name, fileIDs)
+}
+
+// PostProcess resolves calls, inheritance and other cross-file relations
+// of the repository, asking the language server unless the profile is lite
 func (cgp *CodeGraphProcessor) PostProcess(ctx context.Context, repo *config.Repository) error {
 	cgp.logger.Info("Running code graph post-processing", zap.String("repo_name", repo.Name))
 
@@ -127,7 +198,12 @@ func (cgp *CodeGraphProcessor) PostProcess(ctx context.Context, repo *config.Rep
 		return err
 	}
 
-	postProcessor := NewPostProcessor(cgp.codeGraph, cgp.repoService.GetLspService(), cgp.logger)
+	// Without a language server only the tree-sitter resolvers link calls
+	var lspService *lsp.LspService
+	if cgp.config.IndexBuilding.UsesLanguageServer() {
+		lspService = cgp.repoService.GetLspService()
+	}
+	postProcessor := NewPostProcessor(cgp.codeGraph, lspService, cgp.logger)
 	err := postProcessor.PostProcessRepository(ctx, repo)
 	if err != nil {
 		cgp.logger.Error("Code graph post-processing failed",
@@ -145,10 +221,33 @@ func (cgp *CodeGraphProcessor) PostProcess(ctx context.Context, repo *config.Rep
 		return err
 	}
 
+	if cgp.config.CodeGraph.MaterializedViews {
+		cgp.refreshViews(ctx, repo)
+	}
+
 	cgp.logger.Info("Code graph post-processing completed", zap.String("repo_name", repo.Name))
 	return nil
 }
 
+// refreshViews refreshes the materialized views for the files parsed since
+// the last post-processing of the repository. A failed refresh leaves the
+// views stale rather than failing the build; its files are retried next time.
+func (cgp *CodeGraphProcessor) refreshViews(ctx context.Context, repo *config.Repository) {
+	cgp.changedMu.Lock()
+	changed := cgp.changedFiles[repo.Name]
+	delete(cgp.changedFiles, repo.Name)
+	cgp.changedMu.Unlock()
+
+	if _, err := cgp.codeGraph.RefreshViews(ctx, repo.Name, changed); err != nil {
+		cgp.logger.Error("Failed to refresh materialized views",
+			zap.String("repo_name", repo.Name),
+			zap.Error(err))
+		cgp.changedMu.Lock()
+		cgp.changedFiles[repo.Name] = append(cgp.changedFiles[repo.Name], changed...)
+		cgp.changedMu.Unlock()
+	}
+}
+
 // dummyFileInfo is a minimal implementation of os.FileInfo
 // Used when we already have file content and don't need to stat the file
 type dummyFileInfo struct{}
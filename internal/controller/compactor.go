@@ -0,0 +1,138 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/armchr/codeapi/internal/config"
+	"github.com/armchr/codeapi/internal/db"
+	"github.com/armchr/codeapi/internal/service/audit"
+	"github.com/armchr/codeapi/internal/service/codegraph"
+
+	"go.uber.org/zap"
+)
+
+// CompactionResult describes what a compaction run removed for a repository
+type CompactionResult struct {
+	RepoName string `json:"repo_name"`
+	// DroppedVersions is the number of file versions removed by the retention policy
+	DroppedVersions int `json:"dropped_versions"`
+	// OrphanFileIDs is the number of FileIDs found in the code graph with no
+	// matching file version row
+	OrphanFileIDs int `json:"orphan_file_ids"`
+}
+
+// Compactor enforces file version retention. It drops versions beyond the
+// configured keep-last-N limit and removes graph, vector and summary data tied
+// to dropped or orphaned FileIDs.
+type Compactor struct {
+	processors []FileProcessor
+	dbConn     db.Connection
+	codeGraph  *codegraph.CodeGraph
+	logger     *zap.Logger
+}
+
+// NewCompactor creates a compactor that purges through the given processors.
+// codeGraph may be nil, in which case orphan detection is skipped.
+func NewCompactor(processors []FileProcessor, dbConn db.Connection, codeGraph *codegraph.CodeGraph, logger *zap.Logger) *Compactor {
+	return &Compactor{
+		processors: processors,
+		dbConn:     dbConn,
+		codeGraph:  codeGraph,
+		logger:     logger,
+	}
+}
+
+// CompactRepository keeps the newest keep versions of every path in a
+// repository and purges everything else
+func (c *Compactor) CompactRepository(ctx context.Context, repo *config.Repository, keep int) (*CompactionResult, error) {
+	if c.dbConn == nil {
+		return nil, fmt.Errorf("database connection not available for file tracking")
+	}
+
+	fileVersionRepo, err := db.NewFileVersionRepository(c.dbConn.GetDB(), repo.Name, c.logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create file version repository: %w", err)
+	}
+	fileVersionRepo = fileVersionRepo.WithContext(ctx)
+
+	lock, err := fileVersionRepo.Lock(audit.OpCompact)
+	if err != nil {
+		return nil, err
+	}
+	defer lock.Release()
+
+	dropped, err := fileVersionRepo.GetVersionsBeyondRetention(keep)
+	if err != nil {
+		return nil, err
+	}
+	droppedIDs := make([]int32, len(dropped))
+	for i, v := range dropped {
+		droppedIDs[i] = v.FileID
+	}
+
+	orphanIDs, err := c.findOrphanFileIDs(ctx, repo.Name, fileVersionRepo)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &CompactionResult{
+		RepoName:        repo.Name,
+		DroppedVersions: len(droppedIDs),
+		OrphanFileIDs:   len(orphanIDs),
+	}
+
+	purgeIDs := append(append([]int32{}, droppedIDs...), orphanIDs...)
+	if len(purgeIDs) == 0 {
+		c.logger.Info("Nothing to compact", zap.String("repo_name", repo.Name))
+		return result, nil
+	}
+
+	// Remove derived data first so a failure leaves the version rows in place
+	// and the next run can retry
+	if err := purgeFiles(ctx, c.processors, repo, purgeIDs); err != nil {
+		return nil, err
+	}
+
+	if _, err := fileVersionRepo.DeleteVersions(droppedIDs); err != nil {
+		return nil, err
+	}
+
+	c.logger.Info("Compacted repository",
+		zap.String("repo_name", repo.Name),
+		zap.Int("keep_versions", keep),
+		zap.Int("dropped_versions", result.DroppedVersions),
+		zap.Int("orphan_file_ids", result.OrphanFileIDs))
+
+	return result, nil
+}
+
+// findOrphanFileIDs returns FileIDs present in the code graph that no longer
+// have a file version row, e.g. left behind by an interrupted cleanup
+func (c *Compactor) findOrphanFileIDs(ctx context.Context, repoName string, fileVersionRepo *db.FileVersionRepository) ([]int32, error) {
+	if c.codeGraph == nil {
+		return nil, nil
+	}
+
+	graphIDs, err := c.codeGraph.GetFileIDs(ctx, repoName)
+	if err != nil {
+		return nil, err
+	}
+
+	knownIDs, err := fileVersionRepo.GetAllFileIDs()
+	if err != nil {
+		return nil, err
+	}
+	known := make(map[int32]struct{}, len(knownIDs))
+	for _, id := range knownIDs {
+		known[id] = struct{}{}
+	}
+
+	var orphans []int32
+	for _, id := range graphIDs {
+		if _, ok := known[id]; !ok {
+			orphans = append(orphans, id)
+		}
+	}
+	return orphans, nil
+}
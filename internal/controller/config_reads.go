@@ -0,0 +1,59 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/armchr/codeapi/internal/config"
+	"github.com/armchr/codeapi/internal/model/ast"
+	"github.com/armchr/codeapi/internal/parse"
+
+	"go.uber.org/zap"
+)
+
+// processConfigReads links the code of a file reading configuration keys to
+// the Config nodes of the repository defining them. The parser recorded each
+// read as a __config__ variable naming the key and the function, class or
+// file reading it; a key defined in several files, say once per profile,
+// gets a READS_CONFIG relation to each definition.
+func (pp *PostProcessor) processConfigReads(ctx context.Context, repo *config.Repository, fileScope *ast.Node) error {
+	reads, err := pp.codeGraph.FindConfigReadsInFile(ctx, fileScope.FileID)
+	if err != nil {
+		return fmt.Errorf("failed to find config reads: %w", err)
+	}
+
+	definitions := make(map[string][]*ast.Node)
+	linked := make(map[string]bool)
+	for _, read := range reads {
+		key, _ := read.MetaData[parse.MetaConfigKey].(string)
+		lookup, _ := read.MetaData[parse.MetaConfigLookup].(string)
+		owner, _ := read.MetaData[parse.MetaConfigOwner].(int64)
+		// A function reading a key several times is linked to it once
+		readKey := fmt.Sprintf("%d:%s", owner, lookup)
+		if lookup == "" || owner == 0 || linked[readKey] {
+			continue
+		}
+		linked[readKey] = true
+
+		configs, ok := definitions[lookup]
+		if !ok {
+			configs, err = pp.codeGraph.FindConfigsByLookup(ctx, repo.Name, lookup)
+			if err != nil {
+				pp.logger.Warn("Failed to find config definitions",
+					zap.String("key", key),
+					zap.Error(err))
+				continue
+			}
+			definitions[lookup] = configs
+		}
+
+		for _, cfg := range configs {
+			if err := pp.codeGraph.CreateReadsConfigRelation(ctx, ast.NodeID(owner), cfg.ID, key, fileScope.FileID); err != nil {
+				pp.logger.Error("Failed to create READS_CONFIG relation",
+					zap.String("key", key),
+					zap.Error(err))
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package controller
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/armchr/codeapi/internal/config"
+	"github.com/armchr/codeapi/internal/model"
+	"github.com/armchr/codeapi/internal/util"
+)
+
+// Errors of cursors the server did not issue for the request
+var (
+	errCursorMismatch  = errors.New("cursor was issued for another function")
+	errCursorSignature = errors.New("cursor signature does not match")
+	errCursorOutside   = errors.New("cursor names a file outside the repository")
+)
+
+// dependencyCursor is the state of a time-boxed function dependency
+// traversal, handed to the client as an opaque string. It names the
+// traversal so a cursor cannot be replayed against another function or
+// repository.
+type dependencyCursor struct {
+	RepoName     string                  `json:"r"`
+	RelativePath string                  `json:"p"`
+	FunctionName string                  `json:"f"`
+	Pending      []model.PendingFunction `json:"q"`
+}
+
+// newCursorKey returns the key signing dependency cursors: the configured
+// secret, or a random key that lasts as long as the process
+func newCursorKey(cfg *config.Config) ([]byte, error) {
+	if cfg != nil && cfg.App.CursorSecret != "" {
+		return []byte(cfg.App.CursorSecret), nil
+	}
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("failed to generate cursor key: %w", err)
+	}
+	return key, nil
+}
+
+func signCursor(key, payload []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
+
+// encodeDependencyCursor returns the cursor continuing the traversal of
+// request from pending, signed with key. The pending functions carry file
+// URIs the language server opens, so a cursor is only accepted back with a
+// valid signature.
+func encodeDependencyCursor(key []byte, request *model.GetFunctionDependenciesRequest, pending []model.PendingFunction) (string, error) {
+	data, err := json.Marshal(&dependencyCursor{
+		RepoName:     request.RepoName,
+		RelativePath: request.RelativePath,
+		FunctionName: request.FunctionName,
+		Pending:      pending,
+	})
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data) + "." + base64.RawURLEncoding.EncodeToString(signCursor(key, data)), nil
+}
+
+// decodeDependencyCursor returns the functions left by the traversal the
+// cursor of request continues. The cursor must be signed with key, and its
+// functions must lie in files under repoRoot.
+func decodeDependencyCursor(key []byte, request *model.GetFunctionDependenciesRequest, repoRoot string) ([]model.PendingFunction, error) {
+	encoded, encodedSig, ok := strings.Cut(request.Cursor, ".")
+	if !ok {
+		return nil, errors.New("malformed cursor")
+	}
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, errors.New("malformed cursor")
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(encodedSig)
+	if err != nil {
+		return nil, errors.New("malformed cursor")
+	}
+	if !hmac.Equal(sig, signCursor(key, data)) {
+		return nil, errCursorSignature
+	}
+
+	var cursor dependencyCursor
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return nil, errors.New("malformed cursor")
+	}
+	if cursor.RepoName != request.RepoName || cursor.RelativePath != request.RelativePath || cursor.FunctionName != request.FunctionName {
+		return nil, errCursorMismatch
+	}
+	for _, p := range cursor.Pending {
+		if !util.URIWithinRoot(repoRoot, p.Function.Location.URI) {
+			return nil, errCursorOutside
+		}
+	}
+	return cursor.Pending, nil
+}
+
+// timeBudgetDeadline returns when a time budget in milliseconds that started
+// at start runs out, or the zero time for no budget
+func timeBudgetDeadline(start time.Time, budgetMs int) time.Time {
+	if budgetMs <= 0 {
+		return time.Time{}
+	}
+	return start.Add(time.Duration(budgetMs) * time.Millisecond)
+}
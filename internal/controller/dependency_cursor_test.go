@@ -0,0 +1,91 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/armchr/codeapi/internal/model"
+	"github.com/armchr/codeapi/pkg/lsp/base"
+)
+
+func TestDependencyCursor(t *testing.T) {
+	key := []byte("test key")
+	root := t.TempDir()
+	fileURI := "file://" + filepath.Join(root, "order.go")
+	request := &model.GetFunctionDependenciesRequest{RepoName: "shop", RelativePath: "order.go", FunctionName: "Total"}
+	pending := []model.PendingFunction{
+		{Function: model.FunctionDefinition{Name: "Round", Location: base.Location{URI: fileURI}}, Depth: 2},
+		{Function: model.FunctionDefinition{Name: "Tax", Location: base.Location{URI: fileURI}}, Depth: 1},
+	}
+	cursor, err := encodeDependencyCursor(key, request, pending)
+	if err != nil {
+		t.Fatal(err)
+	}
+	outside, err := encodeDependencyCursor(key, request, []model.PendingFunction{
+		{Function: model.FunctionDefinition{Name: "Passwd", Location: base.Location{URI: "file:///etc/passwd"}}, Depth: 1},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	encoded, err := encodeDependencyCursor(key, request, []model.PendingFunction{
+		{Function: model.FunctionDefinition{Name: "Passwd", Location: base.Location{URI: "file://" + root + "/%2e%2e/%2E%2E/../../../../etc/passwd"}}, Depth: 1},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	forged, err := encodeDependencyCursor([]byte("other key"), request, pending)
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload, sig, _ := strings.Cut(cursor, ".")
+	data, _ := base64.RawURLEncoding.DecodeString(payload)
+	tampered := base64.RawURLEncoding.EncodeToString(bytes.Replace(data, []byte(root), []byte("/etc"), 1)) + "." + sig
+
+	tests := []struct {
+		name    string
+		request model.GetFunctionDependenciesRequest
+		want    []model.PendingFunction
+		wantErr error
+	}{
+		{"same traversal", model.GetFunctionDependenciesRequest{RepoName: "shop", RelativePath: "order.go", FunctionName: "Total", Cursor: cursor}, pending, nil},
+		{"other function", model.GetFunctionDependenciesRequest{RepoName: "shop", RelativePath: "order.go", FunctionName: "Add", Cursor: cursor}, nil, errCursorMismatch},
+		{"other repository", model.GetFunctionDependenciesRequest{RepoName: "web", RelativePath: "order.go", FunctionName: "Total", Cursor: cursor}, nil, errCursorMismatch},
+		{"other key", model.GetFunctionDependenciesRequest{RepoName: "shop", RelativePath: "order.go", FunctionName: "Total", Cursor: forged}, nil, errCursorSignature},
+		{"tampered payload", model.GetFunctionDependenciesRequest{RepoName: "shop", RelativePath: "order.go", FunctionName: "Total", Cursor: tampered}, nil, errCursorSignature},
+		{"file outside the repository", model.GetFunctionDependenciesRequest{RepoName: "shop", RelativePath: "order.go", FunctionName: "Total", Cursor: outside}, nil, errCursorOutside},
+		{"percent-encoded dot dot", model.GetFunctionDependenciesRequest{RepoName: "shop", RelativePath: "order.go", FunctionName: "Total", Cursor: encoded}, nil, errCursorOutside},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decodeDependencyCursor(key, &tt.request, root)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("decodeDependencyCursor error = %v, want %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("decodeDependencyCursor = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	for _, malformed := range []string{"not a cursor!", payload, payload + ".!"} {
+		if _, err := decodeDependencyCursor(key, &model.GetFunctionDependenciesRequest{Cursor: malformed}, root); err == nil {
+			t.Errorf("decodeDependencyCursor accepted the malformed cursor %q", malformed)
+		}
+	}
+}
+
+func TestTimeBudgetDeadline(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	if got := timeBudgetDeadline(start, 1500); !got.Equal(start.Add(1500 * time.Millisecond)) {
+		t.Errorf("timeBudgetDeadline(1500) = %v", got)
+	}
+	if got := timeBudgetDeadline(start, 0); !got.IsZero() {
+		t.Errorf("timeBudgetDeadline(0) = %v, want no deadline", got)
+	}
+}
@@ -0,0 +1,89 @@
+package controller
+
+import (
+	"net/http"
+	"runtime"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CacheReporter is implemented by processors that keep data in memory
+// between files or builds
+type CacheReporter interface {
+	CacheSizes() []CacheSize
+}
+
+// CacheSize is the number of entries in one in-memory cache. Repo is empty
+// for caches shared by all repositories.
+type CacheSize struct {
+	Repo    string `json:"repo,omitempty"`
+	Cache   string `json:"cache"`
+	Entries int    `json:"entries"`
+}
+
+// HeapStats is the part of runtime.MemStats useful for spotting growth
+type HeapStats struct {
+	AllocBytes    uint64        `json:"alloc_bytes"`
+	InUseBytes    uint64        `json:"in_use_bytes"`
+	IdleBytes     uint64        `json:"idle_bytes"`
+	ReleasedBytes uint64        `json:"released_bytes"`
+	SysBytes      uint64        `json:"sys_bytes"`
+	Objects       uint64        `json:"objects"`
+	NumGC         uint32        `json:"num_gc"`
+	LastGC        time.Time     `json:"last_gc"`
+	GCPauseTotal  time.Duration `json:"gc_pause_total"`
+}
+
+// Diagnostics is a snapshot of the server's runtime state
+type Diagnostics struct {
+	Goroutines      int         `json:"goroutines"`
+	Heap            HeapStats   `json:"heap"`
+	LanguageServers []string    `json:"language_servers"`
+	Caches          []CacheSize `json:"caches"`
+}
+
+// CollectDiagnostics reads runtime statistics and asks each processor that
+// implements CacheReporter for its cache sizes. openServers lists the running
+// language servers.
+func CollectDiagnostics(processors []FileProcessor, openServers []string) *Diagnostics {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	d := &Diagnostics{
+		Goroutines: runtime.NumGoroutine(),
+		Heap: HeapStats{
+			AllocBytes:    mem.HeapAlloc,
+			InUseBytes:    mem.HeapInuse,
+			IdleBytes:     mem.HeapIdle,
+			ReleasedBytes: mem.HeapReleased,
+			SysBytes:      mem.Sys,
+			Objects:       mem.HeapObjects,
+			NumGC:         mem.NumGC,
+			GCPauseTotal:  time.Duration(mem.PauseTotalNs),
+		},
+		LanguageServers: openServers,
+		Caches:          []CacheSize{},
+	}
+	if mem.LastGC > 0 {
+		d.Heap.LastGC = time.Unix(0, int64(mem.LastGC))
+	}
+	if d.LanguageServers == nil {
+		d.LanguageServers = []string{}
+	}
+	for _, p := range processors {
+		if reporter, ok := p.(CacheReporter); ok {
+			d.Caches = append(d.Caches, reporter.CacheSizes()...)
+		}
+	}
+	return d
+}
+
+// Diagnostics handles GET /api/v1/admin/diagnostics
+func (rc *RepoController) Diagnostics(c *gin.Context) {
+	var servers []string
+	if rc.repoService != nil {
+		servers = rc.repoService.GetLspService().OpenServers()
+	}
+	c.JSON(http.StatusOK, CollectDiagnostics(rc.processors, servers))
+}
@@ -0,0 +1,33 @@
+package controller
+
+import (
+	"testing"
+)
+
+type cachingProcessor struct {
+	countingProcessor
+}
+
+func (p *cachingProcessor) CacheSizes() []CacheSize {
+	return []CacheSize{{Repo: "api", Cache: "test_cache", Entries: 3}}
+}
+
+func TestCollectDiagnostics(t *testing.T) {
+	d := CollectDiagnostics([]FileProcessor{&countingProcessor{}, &cachingProcessor{}}, []string{"api", "web:python"})
+
+	if d.Goroutines == 0 || d.Heap.AllocBytes == 0 || d.Heap.SysBytes == 0 {
+		t.Errorf("runtime stats missing: %+v", d)
+	}
+	if len(d.LanguageServers) != 2 {
+		t.Errorf("language servers = %v", d.LanguageServers)
+	}
+	if len(d.Caches) != 1 || d.Caches[0] != (CacheSize{Repo: "api", Cache: "test_cache", Entries: 3}) {
+		t.Errorf("caches = %+v", d.Caches)
+	}
+
+	// Empty lists rather than null in the JSON response
+	d = CollectDiagnostics(nil, nil)
+	if d.LanguageServers == nil || d.Caches == nil {
+		t.Errorf("expected empty lists, got %+v", d)
+	}
+}
@@ -0,0 +1,451 @@
+package controller
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/armchr/codeapi/internal/codeapi"
+	"github.com/armchr/codeapi/internal/config"
+	"github.com/armchr/codeapi/internal/db"
+	"github.com/armchr/codeapi/internal/logging"
+	"github.com/armchr/codeapi/internal/model/ast"
+	"github.com/armchr/codeapi/internal/service/summary"
+	"github.com/armchr/codeapi/internal/util"
+	"github.com/armchr/codeapi/pkg/lsp/base"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// DiffController maps code changes onto the code graph to report what a
+// change touches: symbols, their callers, HTTP endpoints, tests and summaries
+type DiffController struct {
+	api     codeapi.CodeAPI
+	mysqlDB *sql.DB // optional, used for stale summary detection
+	cfg     *config.Config
+	logger  *zap.Logger
+}
+
+// NewDiffController creates a new DiffController. mysqlDB may be nil, in
+// which case stale summaries are not reported.
+func NewDiffController(api codeapi.CodeAPI, mysqlDB *sql.DB, cfg *config.Config, logger *zap.Logger) *DiffController {
+	return &DiffController{
+		api:     api,
+		mysqlDB: mysqlDB,
+		cfg:     cfg,
+		logger:  logger,
+	}
+}
+
+// -----------------------------------------------------------------------------
+// Request/Response Types
+// -----------------------------------------------------------------------------
+
+// AnalyzeDiffRequest is the request for analyzing a diff. Either Diff or both
+// BaseRef and HeadRef must be provided.
+type AnalyzeDiffRequest struct {
+	Diff     string `json:"diff"`      // unified diff text
+	BaseRef  string `json:"base_ref"`  // git ref to diff from
+	HeadRef  string `json:"head_ref"`  // git ref to diff to
+	MaxDepth int    `json:"max_depth"` // caller traversal depth (default: 3)
+	// LineSide selects which side of the hunks is matched against the graph:
+	// "new" (default) when the index reflects the changed code, "old" when it
+	// was built from the base revision
+	LineSide string `json:"line_side"`
+}
+
+// ChangedFile describes a file touched by the diff
+type ChangedFile struct {
+	Path         string `json:"path"`
+	OldPath      string `json:"old_path,omitempty"`
+	Status       string `json:"status"` // "added", "modified", "deleted", "renamed"
+	LinesAdded   int    `json:"lines_added"`
+	LinesDeleted int    `json:"lines_deleted"`
+	Indexed      bool   `json:"indexed"`
+}
+
+// DiffSymbol is a function or class reported by diff analysis
+type DiffSymbol struct {
+	ID        ast.NodeID `json:"id"`
+	Name      string     `json:"name"`
+	Kind      string     `json:"kind"` // "function", "method", "class"
+	ClassName string     `json:"class_name,omitempty"`
+	FilePath  string     `json:"file_path"`
+	StartLine int        `json:"start_line"`      // 1-based
+	EndLine   int        `json:"end_line"`        // 1-based, inclusive
+	Depth     int        `json:"depth,omitempty"` // caller distance from a changed symbol
+}
+
+// AffectedEndpoint is an HTTP handler that is changed or reaches changed code
+type AffectedEndpoint struct {
+	Method  string      `json:"method"` // e.g. "GET", or "ANY" if not constrained
+	Path    string      `json:"path,omitempty"`
+	Handler *DiffSymbol `json:"handler"`
+}
+
+// StaleSummary is a stored summary whose entity is touched by the diff
+type StaleSummary struct {
+	EntityID   string `json:"entity_id"`
+	EntityType string `json:"entity_type"`
+	EntityName string `json:"entity_name"`
+	FilePath   string `json:"file_path"`
+}
+
+// AnalyzeDiffResponse is the response for AnalyzeDiff
+type AnalyzeDiffResponse struct {
+	RepoName          string              `json:"repo_name"`
+	ChangedFiles      []*ChangedFile      `json:"changed_files"`
+	ChangedSymbols    []*DiffSymbol       `json:"changed_symbols"`
+	ImpactedSymbols   []*DiffSymbol       `json:"impacted_symbols"`
+	AffectedEndpoints []*AffectedEndpoint `json:"affected_endpoints"`
+	RelatedTests      []*DiffSymbol       `json:"related_tests"`
+	StaleSummaries    []*StaleSummary     `json:"stale_summaries"`
+}
+
+// -----------------------------------------------------------------------------
+// Handlers
+// -----------------------------------------------------------------------------
+
+// AnalyzeDiff maps the hunks of a diff to functions and classes and reports
+// their impact. The repository is taken from the :repo path parameter.
+func (c *DiffController) AnalyzeDiff(ctx *gin.Context) {
+	logger := logging.FromContext(ctx.Request.Context(), c.logger)
+	repoName := ctx.Param("repo")
+
+	var req AnalyzeDiffRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	repo, err := c.cfg.GetRepository(repoName)
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("repository not found: %s", repoName)})
+		return
+	}
+
+	if req.LineSide != "" && req.LineSide != "new" && req.LineSide != "old" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "line_side must be \"new\" or \"old\""})
+		return
+	}
+	if req.MaxDepth <= 0 {
+		req.MaxDepth = 3
+	}
+
+	diff := req.Diff
+	if diff == "" {
+		if req.BaseRef == "" || req.HeadRef == "" {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "either diff or both base_ref and head_ref are required"})
+			return
+		}
+		diff, err = util.GetGitDiff(repo.Path, req.BaseRef, req.HeadRef)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	result, err := c.analyze(ctx.Request.Context(), repo.Name, parseUnifiedDiff(diff), req)
+	if err != nil {
+		logger.Error("Diff analysis failed", zap.String("repo_name", repo.Name), zap.Error(err))
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, result)
+}
+
+// -----------------------------------------------------------------------------
+// Analysis
+// -----------------------------------------------------------------------------
+
+func (c *DiffController) analyze(ctx context.Context, repoName string, files []*FileDiff, req AnalyzeDiffRequest) (*AnalyzeDiffResponse, error) {
+	result := &AnalyzeDiffResponse{
+		RepoName:          repoName,
+		ChangedFiles:      []*ChangedFile{},
+		ChangedSymbols:    []*DiffSymbol{},
+		ImpactedSymbols:   []*DiffSymbol{},
+		AffectedEndpoints: []*AffectedEndpoint{},
+		RelatedTests:      []*DiffSymbol{},
+		StaleSummaries:    []*StaleSummary{},
+	}
+
+	repoReader := c.api.Reader().Repo(repoName)
+	changed := make(map[ast.NodeID]*DiffSymbol)
+	var changedPaths []string
+
+	for _, fd := range files {
+		changedFile := newChangedFile(fd)
+		result.ChangedFiles = append(result.ChangedFiles, changedFile)
+
+		// Deleted files only exist in the graph on their old side
+		oldSide := req.LineSide == "old" || fd.IsDeleted()
+		path := fd.NewPath
+		if oldSide {
+			path = fd.OldPath
+		}
+		if path == devNull {
+			continue
+		}
+
+		symbols, err := c.symbolsInFile(ctx, repoReader.File(path))
+		if err != nil {
+			c.logger.Debug("File not found in code graph", zap.String("path", path), zap.Error(err))
+			continue
+		}
+		changedFile.Indexed = true
+		changedPaths = append(changedPaths, path)
+
+		for _, sym := range symbols {
+			if _, seen := changed[sym.ID]; seen {
+				continue
+			}
+			for _, hunk := range fd.Hunks {
+				start, end := hunkLineRange(hunk, oldSide)
+				if overlaps(sym.StartLine-1, sym.EndLine-1, start, end) {
+					changed[sym.ID] = sym
+					result.ChangedSymbols = append(result.ChangedSymbols, sym)
+					break
+				}
+			}
+		}
+	}
+
+	impacted := make(map[ast.NodeID]*DiffSymbol)
+	for _, sym := range result.ChangedSymbols {
+		if sym.Kind == "class" {
+			continue
+		}
+		callers, err := c.api.Analyzer().GetCallers(ctx, sym.ID, req.MaxDepth)
+		if err != nil {
+			c.logger.Warn("Failed to get callers", zap.String("function", sym.Name), zap.Error(err))
+			continue
+		}
+		for id, node := range callers.Nodes {
+			if _, isChanged := changed[id]; isChanged {
+				continue
+			}
+			if existing, ok := impacted[id]; ok {
+				if node.Depth < existing.Depth {
+					existing.Depth = node.Depth
+				}
+				continue
+			}
+			impacted[id] = callNodeToDiffSymbol(node)
+		}
+	}
+	for _, sym := range impacted {
+		result.ImpactedSymbols = append(result.ImpactedSymbols, sym)
+	}
+	sortDiffSymbols(result.ChangedSymbols)
+	sortDiffSymbols(result.ImpactedSymbols)
+
+	for _, syms := range [][]*DiffSymbol{result.ChangedSymbols, result.ImpactedSymbols} {
+		for _, sym := range syms {
+			if sym.Kind != "class" && util.IsTestFile(sym.FilePath) {
+				result.RelatedTests = append(result.RelatedTests, sym)
+			}
+		}
+	}
+
+	endpoints, err := c.findEndpoints(ctx, append(append([]*DiffSymbol{}, result.ChangedSymbols...), result.ImpactedSymbols...))
+	if err != nil {
+		return nil, err
+	}
+	result.AffectedEndpoints = endpoints
+
+	if c.mysqlDB != nil {
+		stale, err := c.findStaleSummaries(ctx, repoName, result.ChangedSymbols, changedPaths)
+		if err != nil {
+			return nil, err
+		}
+		result.StaleSummaries = stale
+	}
+
+	return result, nil
+}
+
+// symbolsInFile returns the functions, methods and classes of an indexed file
+func (c *DiffController) symbolsInFile(ctx context.Context, file codeapi.FileReader) ([]*DiffSymbol, error) {
+	methods, err := file.ListMethods(ctx)
+	if err != nil {
+		return nil, err
+	}
+	classes, err := file.ListClasses(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	symbols := make([]*DiffSymbol, 0, len(methods)+len(classes))
+	for _, m := range methods {
+		kind := "function"
+		if m.IsMethod || m.ClassName != "" {
+			kind = "method"
+		}
+		symbols = append(symbols, newDiffSymbol(m.ID, m.Name, kind, m.FilePath, m.Range))
+		symbols[len(symbols)-1].ClassName = m.ClassName
+	}
+	for _, cls := range classes {
+		symbols = append(symbols, newDiffSymbol(cls.ID, cls.Name, "class", cls.FilePath, cls.Range))
+	}
+	return symbols, nil
+}
+
+// findEndpoints returns the HTTP handlers among the given functions, detected
+// from request mapping annotations (Spring and JAX-RS)
+func (c *DiffController) findEndpoints(ctx context.Context, symbols []*DiffSymbol) ([]*AffectedEndpoint, error) {
+	endpoints := []*AffectedEndpoint{}
+
+	byID := make(map[int64]*DiffSymbol)
+	ids := make([]int64, 0, len(symbols))
+	for _, sym := range symbols {
+		if sym.Kind == "class" {
+			continue
+		}
+		byID[int64(sym.ID)] = sym
+		ids = append(ids, int64(sym.ID))
+	}
+	if len(ids) == 0 {
+		return endpoints, nil
+	}
+
+	query := `
+		MATCH (m:Function)
+		WHERE m.id IN $ids AND m.md_annotations IS NOT NULL
+		RETURN m.id AS id, m.md_annotations AS annotations
+	`
+	records, err := c.api.ExecuteCypher(ctx, query, map[string]any{"ids": ids})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query handler annotations: %w", err)
+	}
+
+	for _, record := range records {
+		id, _ := record["id"].(int64)
+		sym, ok := byID[id]
+		if !ok {
+			continue
+		}
+		rawAnnotations, _ := record["annotations"].([]any)
+		for _, raw := range rawAnnotations {
+			encoded, ok := raw.(string)
+			if !ok {
+				continue
+			}
+			if method, path, ok := codeapi.ParseEndpointAnnotation(encoded); ok {
+				endpoints = append(endpoints, &AffectedEndpoint{Method: method, Path: path, Handler: sym})
+				break
+			}
+		}
+	}
+	return endpoints, nil
+}
+
+// findStaleSummaries returns stored function, class and file summaries for
+// entities touched by the diff
+func (c *DiffController) findStaleSummaries(ctx context.Context, repoName string, changed []*DiffSymbol, changedPaths []string) ([]*StaleSummary, error) {
+	store, err := db.NewSummaryStore(c.mysqlDB, repoName, c.logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open summary store: %w", err)
+	}
+	store = store.WithContext(ctx)
+
+	stale := []*StaleSummary{}
+	add := func(cs *summary.CodeSummary) {
+		stale = append(stale, &StaleSummary{
+			EntityID:   cs.EntityID,
+			EntityType: cs.EntityType.String(),
+			EntityName: cs.EntityName,
+			FilePath:   cs.FilePath,
+		})
+	}
+
+	for _, sym := range changed {
+		level := summary.LevelFunction
+		if sym.Kind == "class" {
+			level = summary.LevelClass
+		}
+		cs, err := store.GetSummary(strconv.FormatInt(int64(sym.ID), 10), level)
+		if err != nil {
+			return nil, err
+		}
+		if cs != nil {
+			add(cs)
+		}
+	}
+
+	for _, path := range changedPaths {
+		cs, err := store.GetFileSummary(path)
+		if err != nil {
+			return nil, err
+		}
+		if cs != nil {
+			add(cs)
+		}
+	}
+
+	return stale, nil
+}
+
+// -----------------------------------------------------------------------------
+// Helpers
+// -----------------------------------------------------------------------------
+
+func newChangedFile(fd *FileDiff) *ChangedFile {
+	cf := &ChangedFile{Path: fd.Path()}
+	switch {
+	case fd.OldPath == devNull:
+		cf.Status = "added"
+	case fd.IsDeleted():
+		cf.Status = "deleted"
+	case fd.OldPath != fd.NewPath:
+		cf.Status = "renamed"
+		cf.OldPath = fd.OldPath
+	default:
+		cf.Status = "modified"
+	}
+	for _, hunk := range fd.Hunks {
+		cf.LinesAdded += hunk.LinesAdded
+		cf.LinesDeleted += hunk.LinesDeleted
+	}
+	return cf
+}
+
+func newDiffSymbol(id ast.NodeID, name, kind, filePath string, rng base.Range) *DiffSymbol {
+	return &DiffSymbol{
+		ID:        id,
+		Name:      name,
+		Kind:      kind,
+		FilePath:  filePath,
+		StartLine: rng.Start.Line + 1,
+		EndLine:   rng.End.Line + 1,
+	}
+}
+
+func callNodeToDiffSymbol(node *codeapi.CallNode) *DiffSymbol {
+	kind := "function"
+	if node.ClassName != "" {
+		kind = "method"
+	}
+	sym := newDiffSymbol(node.ID, node.Name, kind, node.FilePath, node.Range)
+	sym.ClassName = node.ClassName
+	sym.Depth = node.Depth
+	return sym
+}
+
+// sortDiffSymbols orders symbols by caller depth, then file and position
+func sortDiffSymbols(symbols []*DiffSymbol) {
+	sort.Slice(symbols, func(i, j int) bool {
+		a, b := symbols[i], symbols[j]
+		if a.Depth != b.Depth {
+			return a.Depth < b.Depth
+		}
+		if a.FilePath != b.FilePath {
+			return a.FilePath < b.FilePath
+		}
+		return a.StartLine < b.StartLine
+	})
+}
@@ -0,0 +1,118 @@
+package controller
+
+import (
+	"bufio"
+	"strings"
+)
+
+// devNull is the path git uses for the missing side of an added or deleted file
+const devNull = "/dev/null"
+
+// FileDiff holds the hunks of a unified diff for a single file
+type FileDiff struct {
+	OldPath string
+	NewPath string
+	Hunks   []HunkData
+}
+
+// IsDeleted reports whether the diff removes the file
+func (fd *FileDiff) IsDeleted() bool {
+	return fd.NewPath == devNull
+}
+
+// Path returns the path the file has after the change, or its old path if
+// the file was deleted
+func (fd *FileDiff) Path() string {
+	if fd.IsDeleted() {
+		return fd.OldPath
+	}
+	return fd.NewPath
+}
+
+// parseUnifiedDiff parses a unified diff as produced by git diff or diff -u.
+// Binary file entries and files without hunks (pure renames, mode changes)
+// are dropped.
+func parseUnifiedDiff(diff string) []*FileDiff {
+	var files []*FileDiff
+	var current *FileDiff
+	// Lines still expected on each side of the current hunk. Headers are only
+	// recognised outside hunks, so a removed "-- comment" line is not mistaken
+	// for a "--- path" header.
+	oldRemaining, newRemaining := 0, 0
+
+	scanner := bufio.NewScanner(strings.NewReader(diff))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		if current != nil && (oldRemaining > 0 || newRemaining > 0) {
+			hunk := &current.Hunks[len(current.Hunks)-1]
+			switch {
+			case strings.HasPrefix(line, "+"):
+				hunk.LinesAdded++
+				newRemaining--
+			case strings.HasPrefix(line, "-"):
+				hunk.LinesDeleted++
+				oldRemaining--
+			case strings.HasPrefix(line, " "), line == "":
+				oldRemaining--
+				newRemaining--
+			}
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(line, "--- "):
+			current = &FileDiff{OldPath: diffPath(line[4:])}
+			files = append(files, current)
+		case strings.HasPrefix(line, "+++ ") && current != nil && current.NewPath == "":
+			current.NewPath = diffPath(line[4:])
+		case strings.HasPrefix(line, "@@") && current != nil:
+			if hunk := parseHunkHeader(line); hunk != nil {
+				current.Hunks = append(current.Hunks, *hunk)
+				oldRemaining, newRemaining = hunk.OldCount, hunk.NewCount
+			}
+		}
+	}
+
+	result := files[:0]
+	for _, fd := range files {
+		if len(fd.Hunks) > 0 && fd.NewPath != "" {
+			result = append(result, fd)
+		}
+	}
+	return result
+}
+
+// diffPath strips the a/ or b/ prefix and any trailing timestamp from a
+// ---/+++ header path
+func diffPath(raw string) string {
+	path := raw
+	if idx := strings.Index(path, "\t"); idx >= 0 {
+		path = path[:idx]
+	}
+	path = strings.TrimSpace(path)
+	if path == devNull {
+		return path
+	}
+	if strings.HasPrefix(path, "a/") || strings.HasPrefix(path, "b/") {
+		path = path[2:]
+	}
+	return path
+}
+
+// hunkLineRange returns the 0-based, inclusive line range a hunk touches on
+// the new (or, for deleted files, old) side of the diff. Pure deletions have
+// a zero count and are mapped to the line the content was removed after.
+func hunkLineRange(hunk HunkData, oldSide bool) (int, int) {
+	start, count := hunk.NewStart, hunk.NewCount
+	if oldSide {
+		start, count = hunk.OldStart, hunk.OldCount
+	}
+	if count == 0 {
+		// "@@ -10,2 +9,0 @@": the removed lines sat right after line 9
+		return start, start
+	}
+	return start - 1, start + count - 2
+}
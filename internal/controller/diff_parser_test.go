@@ -0,0 +1,90 @@
+package controller
+
+import "testing"
+
+const sampleDiff = `diff --git a/src/orders/service.go b/src/orders/service.go
+index 3b18e51..a9c2f04 100644
+--- a/src/orders/service.go
++++ b/src/orders/service.go
+@@ -12,0 +13,2 @@ func (s *Service) Create(o Order) error {
++	if o.ID == "" {
++		return ErrMissingID
+@@ -40,3 +42,1 @@ func (s *Service) Cancel(id string) error {
+-	s.mu.Lock()
+-	defer s.mu.Unlock()
+-	-- not a header
++	s.lock(id)
+diff --git a/db/schema.sql b/db/schema.sql
+deleted file mode 100644
+--- a/db/schema.sql
++++ /dev/null
+@@ -1,2 +0,0 @@
+-CREATE TABLE orders (id TEXT);
+--- legacy
+diff --git a/logo.png b/logo.png
+Binary files a/logo.png and b/logo.png differ
+diff --git a/README b/README
+new file mode 100644
+--- /dev/null
++++ b/README	2026-01-02 10:00:00
+@@ -0,0 +1 @@
++hello
+`
+
+func TestParseUnifiedDiff(t *testing.T) {
+	files := parseUnifiedDiff(sampleDiff)
+	if len(files) != 3 {
+		t.Fatalf("expected 3 files, got %d", len(files))
+	}
+
+	svc := files[0]
+	if svc.Path() != "src/orders/service.go" || svc.IsDeleted() {
+		t.Errorf("unexpected first file: %+v", svc)
+	}
+	if len(svc.Hunks) != 2 {
+		t.Fatalf("expected 2 hunks, got %d", len(svc.Hunks))
+	}
+	if h := svc.Hunks[0]; h.NewStart != 13 || h.NewCount != 2 || h.LinesAdded != 2 || h.LinesDeleted != 0 {
+		t.Errorf("unexpected first hunk: %+v", h)
+	}
+	if h := svc.Hunks[1]; h.OldStart != 40 || h.OldCount != 3 || h.LinesAdded != 1 || h.LinesDeleted != 3 {
+		t.Errorf("unexpected second hunk: %+v", h)
+	}
+
+	schema := files[1]
+	if !schema.IsDeleted() || schema.Path() != "db/schema.sql" {
+		t.Errorf("expected deleted db/schema.sql, got %+v", schema)
+	}
+	if len(schema.Hunks) != 1 || schema.Hunks[0].LinesDeleted != 2 {
+		t.Errorf("removed '-- legacy' line should stay in the hunk, got %+v", schema.Hunks)
+	}
+
+	readme := files[2]
+	if readme.OldPath != devNull || readme.Path() != "README" {
+		t.Errorf("expected added README without timestamp, got %+v", readme)
+	}
+}
+
+func TestHunkLineRange(t *testing.T) {
+	tests := []struct {
+		name      string
+		hunk      HunkData
+		oldSide   bool
+		wantStart int
+		wantEnd   int
+	}{
+		{"added lines", HunkData{NewStart: 13, NewCount: 2}, false, 12, 13},
+		{"single line", HunkData{NewStart: 5, NewCount: 1}, false, 4, 4},
+		{"pure deletion", HunkData{OldStart: 10, OldCount: 2, NewStart: 9, NewCount: 0}, false, 9, 9},
+		{"old side", HunkData{OldStart: 40, OldCount: 3, NewStart: 42, NewCount: 1}, true, 39, 41},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, end := hunkLineRange(tt.hunk, tt.oldSide)
+			if start != tt.wantStart || end != tt.wantEnd {
+				t.Errorf("hunkLineRange() = (%d, %d), want (%d, %d)", start, end, tt.wantStart, tt.wantEnd)
+			}
+		})
+	}
+}
@@ -0,0 +1,289 @@
+package controller
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"io/fs"
+	"path/filepath"
+	"strings"
+
+	"github.com/armchr/codeapi/internal/config"
+	"github.com/armchr/codeapi/internal/db"
+	"github.com/armchr/codeapi/internal/model"
+	"github.com/armchr/codeapi/internal/service/codegraph"
+	"github.com/armchr/codeapi/internal/service/vector"
+	"github.com/armchr/codeapi/internal/util"
+
+	"go.uber.org/zap"
+)
+
+// Actions an index build would take for a file
+const (
+	PlanProcess   = "process"
+	PlanUnchanged = "unchanged"
+	PlanSkip      = "skip"
+)
+
+// PlannedFile is what an index build would do with one file
+type PlannedFile struct {
+	Path   string `json:"path"`
+	Action string `json:"action"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// IndexPlan is the outcome of a dry run of an index build. Token counts are
+// estimates at roughly four characters per token.
+type IndexPlan struct {
+	RepoName  string        `json:"repo_name"`
+	Files     []PlannedFile `json:"files"`
+	Processed int           `json:"processed"`
+	Unchanged int           `json:"unchanged"`
+	Skipped   int           `json:"skipped"`
+
+	Chunks          int   `json:"chunks"`
+	EmbeddingTokens int64 `json:"embedding_tokens"`
+
+	// Function, class and file summaries; folder and project summaries
+	// depend on the graph and are not counted
+	SummaryCalls       int   `json:"summary_calls"`
+	SummaryInputTokens int64 `json:"summary_input_tokens"`
+}
+
+// IndexPlanner works out what an index build would do without writing to
+// any store. Files are looked up in the file version store to find the ones
+// a build would skip as unchanged.
+type IndexPlanner struct {
+	config       *config.Config
+	fileVersions *db.FileVersionRepository
+	chunker      *vector.CodeChunkService
+	logger       *zap.Logger
+}
+
+// NewIndexPlanner creates a planner. fileVersions should come from
+// db.NewFileVersionReader and may be nil when no relational store is
+// configured, in which case every indexable file is planned for processing.
+func NewIndexPlanner(cfg *config.Config, fileVersions *db.FileVersionRepository, logger *zap.Logger) *IndexPlanner {
+	chunking := cfg.Chunking.GetDefaults()
+	chunker := vector.NewCodeChunkService(nil, nil, chunking.MinConditionalLines, chunking.MinLoopLines, 0, 1, logger)
+	chunker.SetChunking(chunking)
+	return &IndexPlanner{
+		config:       cfg,
+		fileVersions: fileVersions,
+		chunker:      chunker,
+		logger:       logger,
+	}
+}
+
+// Plan walks a repository the way IndexBuilder does and classifies each file
+func (p *IndexPlanner) Plan(ctx context.Context, repo *config.Repository, useHead bool, gitInfo *util.GitInfo) (*IndexPlan, error) {
+	plan := &IndexPlan{RepoName: repo.Name}
+	estimate := p.config.IndexBuilding.EnableEmbeddings || p.config.IndexBuilding.EnableSummary
+	sizeLimits := p.config.IndexBuilding.GetDefaults()
+
+	err := filepath.WalkDir(repo.Path, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		if d.IsDir() {
+			if path != repo.Path && util.ShouldSkipDirectory(path) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		relPath, _ := util.GetRelativePath(repo.Path, path)
+		if util.ShouldSkipFile(path, repo) {
+			plan.add(PlannedFile{Path: relPath, Action: PlanSkip, Reason: "excluded"})
+			return nil
+		}
+
+		if tooLargeOnDisk(sizeLimits, path) {
+			plan.add(PlannedFile{Path: relPath, Action: PlanSkip, Reason: "too large"})
+			return nil
+		}
+
+		content, err := util.ReadFileOptimized(repo.Path, path, useHead, gitInfo)
+		if err != nil {
+			reason := "unreadable"
+			if useHead && strings.Contains(err.Error(), "file not tracked by git") {
+				reason = "untracked"
+			}
+			plan.add(PlannedFile{Path: relPath, Action: PlanSkip, Reason: reason})
+			return nil
+		}
+		content, oversized := limitFileSize(sizeLimits, content)
+		if oversized && content == nil {
+			plan.add(PlannedFile{Path: relPath, Action: PlanSkip, Reason: "too large"})
+			return nil
+		}
+
+		if p.isUnchanged(repo, path, content, useHead, gitInfo) {
+			plan.add(PlannedFile{Path: relPath, Action: PlanUnchanged})
+			return nil
+		}
+
+		plan.add(PlannedFile{Path: relPath, Action: PlanProcess})
+		if estimate {
+			p.estimate(ctx, plan, repo, relPath, fileLanguage(repo, relPath, content), content)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
+
+// isUnchanged reports whether a build would skip the file because the same
+// version was already processed completely
+func (p *IndexPlanner) isUnchanged(repo *config.Repository, path string, content []byte, useHead bool, gitInfo *util.GitInfo) bool {
+	if p.fileVersions == nil {
+		return false
+	}
+	id, err := resolveFileIdentity(repo, path, content, useHead, gitInfo, p.logger)
+	if err != nil {
+		return false
+	}
+	fv, err := p.fileVersions.FindFileVersion(id.FileSHA, id.RelativePath, id.CommitID)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			p.logger.Debug("File version lookup failed", zap.String("path", id.RelativePath), zap.Error(err))
+		}
+		return false
+	}
+	return fv.Status == "done"
+}
+
+// estimate adds the embedding and summary cost of a file to the plan
+func (p *IndexPlanner) estimate(ctx context.Context, plan *IndexPlan, repo *config.Repository, relPath, language string, content []byte) {
+	chunks, err := p.chunker.ChunkContent(ctx, relPath, language, content, vector.RepoChunkOptions(repo))
+	if err != nil {
+		// Files that fail to parse produce no chunks when indexed either
+		return
+	}
+
+	if p.config.IndexBuilding.EnableEmbeddings {
+		plan.Chunks += len(chunks)
+		for _, chunk := range chunks {
+			plan.EmbeddingTokens += estimateTokens(chunk.GetSearchableText(true))
+			// Conditionals and loops get a second embedding without context
+			if chunk.ChunkType == model.ChunkTypeConditional || chunk.ChunkType == model.ChunkTypeLoop {
+				plan.EmbeddingTokens += estimateTokens(chunk.GetSearchableText(false))
+			}
+		}
+	}
+
+	if p.config.IndexBuilding.EnableSummary {
+		plan.SummaryCalls++
+		plan.SummaryInputTokens += estimateTokens(string(content))
+		for _, chunk := range chunks {
+			if chunk.ChunkType == model.ChunkTypeFunction || chunk.ChunkType == model.ChunkTypeClass {
+				plan.SummaryCalls++
+				plan.SummaryInputTokens += estimateTokens(chunk.Content)
+			}
+		}
+	}
+}
+
+func (plan *IndexPlan) add(file PlannedFile) {
+	plan.Files = append(plan.Files, file)
+	switch file.Action {
+	case PlanProcess:
+		plan.Processed++
+	case PlanUnchanged:
+		plan.Unchanged++
+	default:
+		plan.Skipped++
+	}
+}
+
+// estimateTokens approximates the token count of text for cost estimates
+func estimateTokens(text string) int64 {
+	return int64(len(text)+3) / 4
+}
+
+// CleanTarget is data a clean would delete from one store
+type CleanTarget struct {
+	Store string `json:"store"`
+	What  string `json:"what"`
+	Count int64  `json:"count"`
+	Error string `json:"error,omitempty"`
+}
+
+// PlanClean lists what cleaning a repository would delete from each
+// configured store, using read-only queries. Stores passed as nil are not
+// configured and left out.
+func PlanClean(ctx context.Context, repoName string, sqlDB *sql.DB, codeGraph *codegraph.CodeGraph, vectorDB vector.VectorDatabase, logger *zap.Logger) []CleanTarget {
+	var targets []CleanTarget
+	failed := func(store, what string, err error) {
+		targets = append(targets, CleanTarget{Store: store, What: what, Error: err.Error()})
+	}
+
+	if codeGraph != nil {
+		fileScopes, nodes, err := codeGraph.CountRepositoryNodes(ctx, repoName)
+		if err != nil {
+			failed("neo4j", "nodes", err)
+		} else {
+			targets = append(targets,
+				CleanTarget{Store: "neo4j", What: "FileScope nodes", Count: fileScopes},
+				CleanTarget{Store: "neo4j", What: "nodes (all, with relationships)", Count: nodes})
+		}
+	}
+
+	if vectorDB != nil {
+		what := "collection " + repoName
+		exists, err := vectorDB.CollectionExists(ctx, repoName)
+		switch {
+		case err != nil:
+			failed("qdrant", what, err)
+		case !exists:
+			targets = append(targets, CleanTarget{Store: "qdrant", What: what + " (missing)"})
+		default:
+			points, err := vectorDB.CountChunks(ctx, repoName)
+			if err != nil {
+				failed("qdrant", what, err)
+			} else {
+				targets = append(targets, CleanTarget{Store: "qdrant", What: what + " points", Count: int64(points)})
+			}
+		}
+	}
+
+	if sqlDB != nil {
+		// A table that was never created has nothing to delete
+		countRows := func(table string, count func() (int64, error)) {
+			exists, err := db.TableExists(sqlDB, table)
+			if err == nil && exists {
+				var rows int64
+				if rows, err = count(); err == nil {
+					targets = append(targets, CleanTarget{Store: "db", What: table + " rows", Count: rows})
+					return
+				}
+			}
+			if err != nil {
+				failed("db", table, err)
+				return
+			}
+			targets = append(targets, CleanTarget{Store: "db", What: table + " rows"})
+		}
+		countRows(db.FileVersionsTable, func() (int64, error) {
+			total, _, _, err := db.NewFileVersionReader(sqlDB, repoName, logger).GetStats()
+			return total, err
+		})
+		countRows(db.CodeSummariesTable, func() (int64, error) {
+			stats, err := db.NewSummaryReader(sqlDB, repoName, logger).GetStats()
+			if err != nil {
+				return 0, err
+			}
+			return stats.Total, nil
+		})
+	}
+
+	return targets
+}
@@ -0,0 +1,99 @@
+package controller
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/armchr/codeapi/internal/config"
+	"github.com/armchr/codeapi/internal/db"
+
+	"go.uber.org/zap"
+)
+
+func TestIndexPlannerPlan(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		path := filepath.Join(dir, name)
+		os.MkdirAll(filepath.Dir(path), 0o755)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("main.go", "package main\n\nfunc main() {\n\tprintln(\"hi\")\n}\n")
+	write("Dockerfile", "FROM scratch\n")
+
+	logger := zap.NewNop()
+	conn := newBackupTestDB(t)
+	versions, err := db.NewFileVersionRepository(conn.GetDB(), "api", logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo := &config.Repository{Name: "api", Path: dir, Language: "go"}
+	if err := NewIndexBuilder(&config.Config{}, []FileProcessor{&countingProcessor{}}, versions, logger).BuildIndex(context.Background(), repo); err != nil {
+		t.Fatalf("BuildIndex: %v", err)
+	}
+	write("util.go", "package main\n\nfunc helper() int {\n\treturn 1\n}\n")
+
+	cfg := &config.Config{}
+	cfg.IndexBuilding.EnableEmbeddings = true
+	cfg.IndexBuilding.EnableSummary = true
+	planner := NewIndexPlanner(cfg, db.NewFileVersionReader(conn.GetDB(), "api", logger), logger)
+	plan, err := planner.Plan(context.Background(), repo, false, nil)
+	if err != nil {
+		t.Fatalf("Plan: %v", err)
+	}
+
+	actions := make(map[string]string)
+	for _, f := range plan.Files {
+		actions[f.Path] = f.Action
+	}
+	want := map[string]string{"main.go": PlanUnchanged, "util.go": PlanProcess, "Dockerfile": PlanSkip}
+	for path, action := range want {
+		if actions[path] != action {
+			t.Errorf("%s planned as %q, want %q", path, actions[path], action)
+		}
+	}
+	if plan.Processed != 1 || plan.Unchanged != 1 || plan.Skipped != 1 {
+		t.Errorf("totals %d/%d/%d, want 1/1/1", plan.Processed, plan.Unchanged, plan.Skipped)
+	}
+	if plan.Chunks == 0 || plan.EmbeddingTokens == 0 {
+		t.Errorf("expected an embedding estimate for util.go, got %d chunks, %d tokens", plan.Chunks, plan.EmbeddingTokens)
+	}
+	// One file summary and one function summary
+	if plan.SummaryCalls != 2 || plan.SummaryInputTokens == 0 {
+		t.Errorf("expected 2 summary calls, got %d (%d tokens)", plan.SummaryCalls, plan.SummaryInputTokens)
+	}
+
+	// Without a file version store every indexable file is processed
+	plan, err = NewIndexPlanner(&config.Config{}, nil, logger).Plan(context.Background(), repo, false, nil)
+	if err != nil || plan.Processed != 2 || plan.Chunks != 0 {
+		t.Errorf("unexpected plan without store: %+v (err %v)", plan, err)
+	}
+}
+
+func TestPlanCleanRelationalStore(t *testing.T) {
+	logger := zap.NewNop()
+	conn := newBackupTestDB(t)
+	versions, err := db.NewFileVersionRepository(conn.GetDB(), "api", logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	versions.GetOrCreateFileID("sha1", "a.go", false, nil)
+	versions.GetOrCreateFileID("sha2", "b.go", false, nil)
+
+	targets := PlanClean(context.Background(), "api", conn.GetDB(), nil, nil, logger)
+	if len(targets) != 2 {
+		t.Fatalf("expected file version and summary targets, got %+v", targets)
+	}
+	if targets[0].What != db.FileVersionsTable+" rows" || targets[0].Count != 2 {
+		t.Errorf("unexpected file version target %+v", targets[0])
+	}
+	if targets[1].Count != 0 || targets[1].Error != "" {
+		t.Errorf("unexpected summary target %+v", targets[1])
+	}
+	if ids, _ := versions.GetAllFileIDs(); len(ids) != 2 {
+		t.Errorf("dry run changed file versions: %v", ids)
+	}
+}
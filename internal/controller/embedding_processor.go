@@ -2,9 +2,11 @@ package controller
 
 import (
 	"context"
+	"fmt"
 	"sync"
 	"sync/atomic"
 
+	"github.com/armchr/codeapi/internal/chunk"
 	"github.com/armchr/codeapi/internal/config"
 	"github.com/armchr/codeapi/internal/model"
 	"github.com/armchr/codeapi/internal/service/vector"
@@ -18,6 +20,8 @@ type EmbeddingProcessor struct {
 	chunkService          *vector.CodeChunkService
 	logger                *zap.Logger
 	chunkCount            atomic.Int64
+	chunksEmbedded        atomic.Int64    // Not reset between repositories
+	emptyChunkFiles       atomic.Int64    // Not reset between repositories
 	collectionInitialized map[string]bool // Track which collections have been created
 	collectionMu          sync.Mutex      // Protects collectionInitialized map
 }
@@ -74,6 +78,16 @@ func (ep *EmbeddingProcessor) ensureCollection(ctx context.Context, collectionNa
 	return nil
 }
 
+// chunkLanguage returns the language a file is chunked as. Vue and Svelte
+// components and markdown files keep their own language even in a
+// single-language repository, so that their regions are chunked apart.
+func chunkLanguage(fileCtx *FileContext) string {
+	if detected := util.DetectLanguage(fileCtx.RelativePath, nil); chunk.EmbedsLanguages(detected) {
+		return detected
+	}
+	return fileCtx.Language
+}
+
 // ProcessFile processes a single file for embedding generation
 func (ep *EmbeddingProcessor) ProcessFile(ctx context.Context, repo *config.Repository, fileCtx *FileContext) error {
 	ep.logger.Debug("Processing file for embeddings",
@@ -95,10 +109,11 @@ func (ep *EmbeddingProcessor) ProcessFile(ctx context.Context, repo *config.Repo
 	chunks, err := ep.chunkService.ProcessFileWithContentAndFileID(
 		ctx,
 		fileCtx.RelativePath,
-		repo.Language,
+		chunkLanguage(fileCtx),
 		collectionName,
 		fileCtx.Content,
 		fileCtx.FileID,
+		vector.RepoChunkOptions(repo),
 	)
 	if err != nil {
 		ep.logger.Error("Failed to process file for embeddings",
@@ -108,11 +123,17 @@ func (ep *EmbeddingProcessor) ProcessFile(ctx context.Context, repo *config.Repo
 		return nil // Continue processing other files
 	}
 
+	// Text files without a chunk cannot be found by semantic search
+	if len(chunks) == 0 && len(fileCtx.Content) > 0 && util.IsText(fileCtx.Content) {
+		ep.emptyChunkFiles.Add(1)
+	}
+
 	// Track total chunks processed
 	ep.chunkCount.Add(int64(len(chunks)))
+	ep.chunksEmbedded.Add(int64(len(chunks)))
 
 	// Index method signatures for semantic signature search
-	ep.indexMethodSignatures(ctx, repo.Language, collectionName, chunks, fileCtx.FileID)
+	ep.indexMethodSignatures(ctx, fileCtx.Language, collectionName, chunks, fileCtx.FileID)
 
 	ep.logger.Debug("Successfully processed file for embeddings",
 		zap.String("path", fileCtx.RelativePath),
@@ -123,6 +144,11 @@ func (ep *EmbeddingProcessor) ProcessFile(ctx context.Context, repo *config.Repo
 
 // indexMethodSignatures extracts and indexes method signatures from function chunks
 func (ep *EmbeddingProcessor) indexMethodSignatures(ctx context.Context, language, collectionName string, chunks []*model.CodeChunk, fileID int32) {
+	// The function chunks already carry their signature embedding
+	if ep.chunkService.SignatureVectors(ctx, collectionName) {
+		return
+	}
+
 	var signatures []vector.MethodSignatureData
 
 	for _, chunk := range chunks {
@@ -162,6 +188,27 @@ func (ep *EmbeddingProcessor) indexMethodSignatures(ctx context.Context, languag
 	}
 }
 
+// PurgeFiles deletes the chunks and method signatures stored for the given file versions
+func (ep *EmbeddingProcessor) PurgeFiles(ctx context.Context, repo *config.Repository, fileIDs []int32) error {
+	collectionName := repo.Name
+	exists, err := ep.chunkService.GetVectorDB().CollectionExists(ctx, collectionName)
+	if err != nil {
+		return fmt.Errorf("failed to check collection %s: %w", collectionName, err)
+	}
+	if !exists {
+		return nil
+	}
+	return ep.chunkService.GetVectorDB().DeleteChunksByFileIDs(ctx, collectionName, fileIDs)
+}
+
+// Stats reports the chunks embedded and the text files left without chunks so far
+func (ep *EmbeddingProcessor) Stats() map[string]int64 {
+	return map[string]int64{
+		StatChunksEmbedded:  ep.chunksEmbedded.Load(),
+		StatEmptyChunkFiles: ep.emptyChunkFiles.Load(),
+	}
+}
+
 // PostProcess performs any cleanup or finalization after all files are processed
 func (ep *EmbeddingProcessor) PostProcess(ctx context.Context, repo *config.Repository) error {
 	totalChunks := ep.chunkCount.Load()
@@ -0,0 +1,247 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"strconv"
+
+	"github.com/armchr/codeapi/internal/codeapi"
+	"github.com/armchr/codeapi/internal/entity"
+	"github.com/armchr/codeapi/internal/logging"
+	"github.com/armchr/codeapi/internal/model"
+	"github.com/armchr/codeapi/internal/model/ast"
+	"github.com/armchr/codeapi/pkg/lsp/base"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// ResolveEntityRequest names an entity by its public reference, see package
+// entity
+type ResolveEntityRequest struct {
+	EntityRef string `json:"entity_ref" binding:"required"`
+}
+
+// ResolvedEntity maps an entity reference to the keys each store knows the
+// entity by, in the latest indexed version of its file. Stores that could
+// not be asked are listed in Errors.
+type ResolvedEntity struct {
+	EntityRef string     `json:"entity_ref"`
+	RepoName  string     `json:"repo_name"`
+	Kind      string     `json:"kind"`
+	FilePath  string     `json:"file_path"`
+	Name      string     `json:"name,omitempty"`
+	FileID    int32      `json:"file_id,omitempty"`
+	NodeID    ast.NodeID `json:"node_id,omitempty"` // code graph node
+	Range     base.Range `json:"range"`
+	// SummaryEntityID is the entity_id of its summary: the node ID, or the
+	// path of a file
+	SummaryEntityID string            `json:"summary_entity_id,omitempty"`
+	ChunkIDs        []string          `json:"chunk_ids,omitempty"`
+	Errors          map[string]string `json:"errors,omitempty"`
+}
+
+// ResolveEntity maps an entity reference to its graph node, summary and
+// chunks
+func (rc *RepoController) ResolveEntity(c *gin.Context) {
+	logger := logging.FromContext(c.Request.Context(), rc.logger)
+	var request ResolveEntityRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request",
+			"details": err.Error(),
+		})
+		return
+	}
+	ref, err := entity.Parse(request.EntityRef)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if rc.codeGraph == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Code graph not available"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	node, err := codeapi.NewCodeAPI(rc.codeGraph, rc.logger).Reader().Repo(ref.Repo).ResolveEntity(ctx, ref)
+	if errors.Is(err, codeapi.ErrEntityNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to resolve entity", zap.String("entity_ref", request.EntityRef), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to resolve entity",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	resolved := &ResolvedEntity{
+		EntityRef:       ref.String(),
+		RepoName:        ref.Repo,
+		Kind:            string(ref.Kind),
+		FilePath:        ref.Path,
+		Name:            ref.Name,
+		FileID:          node.FileID,
+		NodeID:          node.ID,
+		Range:           node.Range,
+		SummaryEntityID: strconv.FormatInt(int64(node.ID), 10),
+	}
+	if ref.Kind == entity.KindFile {
+		resolved.SummaryEntityID = ref.Path
+	}
+
+	if rc.chunkService == nil {
+		resolved.Errors = map[string]string{"qdrant": errNotConfigured.Error()}
+	} else if chunks, err := rc.chunkService.GetVectorDB().GetChunksByFilePath(ctx, ref.Repo, ref.Path); err != nil {
+		resolved.Errors = map[string]string{"qdrant": err.Error()}
+		logger.Warn("Failed to read chunks of entity", zap.String("entity_ref", request.EntityRef), zap.Error(err))
+	} else {
+		name := ref.Name
+		if ref.Kind == entity.KindFunction {
+			name = ref.FunctionName()
+		}
+		for _, chunk := range chunks {
+			if chunkOfEntity(chunk, ref.Kind, name, node.Range) {
+				resolved.ChunkIDs = append(resolved.ChunkIDs, chunk.ID)
+			}
+		}
+	}
+
+	c.JSON(http.StatusOK, resolved)
+}
+
+// chunkOfEntity reports whether chunk was cut from the entity of kind named
+// name at rng: chunks named after it that start inside it. A class only
+// takes class chunks, so constructors named after it stay with the method.
+func chunkOfEntity(chunk *model.CodeChunk, kind entity.Kind, name string, rng base.Range) bool {
+	if kind == entity.KindFile {
+		return chunk.ChunkType == model.ChunkTypeFile
+	}
+	if chunk.Name != name || chunk.ChunkType == model.ChunkTypeFile ||
+		(kind == entity.KindClass) != (chunk.ChunkType == model.ChunkTypeClass) {
+		return false
+	}
+	return chunk.StartLine >= rng.Start.Line && chunk.StartLine <= rng.End.Line
+}
+
+// parseEntityRef parses the entity reference of a request, which must name
+// an entity of repoName of one of kinds
+func parseEntityRef(repoName, s string, kinds ...entity.Kind) (entity.Ref, error) {
+	ref, err := entity.Parse(s)
+	if err != nil {
+		return entity.Ref{}, err
+	}
+	if ref.Repo != repoName {
+		return entity.Ref{}, fmt.Errorf("entity_ref %q is not in repository %s", s, repoName)
+	}
+	if !slices.Contains(kinds, ref.Kind) {
+		return entity.Ref{}, fmt.Errorf("entity_ref %q names a %s, expected %v", s, ref.Kind, kinds)
+	}
+	return ref, nil
+}
+
+// repoRelativePath returns path relative to the repository root. Chunks may
+// hold absolute paths; entities are keyed by the path in the repository.
+func repoRelativePath(repoPath, path string) (string, bool) {
+	if !filepath.IsAbs(path) {
+		return path, true
+	}
+	rel, err := filepath.Rel(repoPath, path)
+	if err != nil || !filepath.IsLocal(rel) {
+		return "", false
+	}
+	return filepath.ToSlash(rel), true
+}
+
+// chunkEntityRef returns the reference of the file, class or function a
+// chunk was cut from, or "" for blocks and other partial chunks
+func chunkEntityRef(repoName, repoPath string, chunk *model.CodeChunk) string {
+	path, ok := repoRelativePath(repoPath, chunk.FilePath)
+	if !ok {
+		return ""
+	}
+	switch chunk.ChunkType {
+	case model.ChunkTypeFile:
+		return entity.File(repoName, path).String()
+	case model.ChunkTypeClass:
+		return entity.Class(repoName, path, chunk.Name).String()
+	case model.ChunkTypeFunction, model.ChunkTypeMethodSignature:
+		return entity.Function(repoName, path, chunk.ClassName, chunk.Name).String()
+	}
+	return ""
+}
+
+// setChunkRefs sets the entity reference of chunks of repoName
+func (rc *RepoController) setChunkRefs(repoName string, chunks ...*model.CodeChunk) {
+	repoPath := ""
+	if repo, err := rc.config.GetRepository(repoName); err == nil {
+		repoPath = repo.Path
+	}
+	for _, chunk := range chunks {
+		if chunk != nil {
+			chunk.EntityRef = chunkEntityRef(repoName, repoPath, chunk)
+		}
+	}
+}
+
+// setCallGraphRefs sets the entity reference of the functions of a call
+// graph. External functions, without a file, go without.
+func setCallGraphRefs(repoName string, graph *codeapi.CallGraph) {
+	if graph == nil {
+		return
+	}
+	for _, node := range graph.Nodes {
+		if node.FilePath != "" {
+			node.EntityRef = entity.Function(repoName, node.FilePath, node.ClassName, node.Name).String()
+		}
+	}
+	if root := graph.Root; root != nil && root.FilePath != "" {
+		root.EntityRef = entity.Function(repoName, root.FilePath, root.ClassName, root.Name).String()
+	}
+}
+
+// addEntityRefs sets the entity reference of classes and methods, whose
+// graph nodes carry neither their file path nor their class. A failure is
+// logged and leaves them without.
+func addEntityRefs(ctx context.Context, repo codeapi.RepoReader, classes []*codeapi.ClassInfo, methods []*codeapi.MethodInfo, logger *zap.Logger) {
+	ids := make([]ast.NodeID, 0, len(classes)+len(methods))
+	for _, class := range classes {
+		ids = append(ids, class.ID)
+		for _, method := range class.Methods {
+			ids = append(ids, method.ID)
+		}
+	}
+	for _, method := range methods {
+		ids = append(ids, method.ID)
+	}
+	if len(ids) == 0 {
+		return
+	}
+	refs, err := repo.EntityRefs(ctx, ids)
+	if err != nil {
+		logger.Warn("Failed to read entity references", zap.String("repo_name", repo.Name()), zap.Error(err))
+		return
+	}
+	for _, class := range classes {
+		if ref, ok := refs[class.ID]; ok {
+			class.EntityRef = ref.String()
+		}
+		for _, method := range class.Methods {
+			if ref, ok := refs[method.ID]; ok {
+				method.EntityRef = ref.String()
+			}
+		}
+	}
+	for _, method := range methods {
+		if ref, ok := refs[method.ID]; ok {
+			method.EntityRef = ref.String()
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package controller
+
+import (
+	"testing"
+
+	"github.com/armchr/codeapi/internal/codeapi"
+	"github.com/armchr/codeapi/internal/entity"
+	"github.com/armchr/codeapi/internal/model"
+	"github.com/armchr/codeapi/internal/model/ast"
+	"github.com/armchr/codeapi/pkg/lsp/base"
+)
+
+func TestChunkEntityRef(t *testing.T) {
+	tests := []struct {
+		name  string
+		chunk *model.CodeChunk
+		want  string
+	}{
+		{"file", &model.CodeChunk{ChunkType: model.ChunkTypeFile, FilePath: "/src/shop/order.go"}, "shop:file:order.go"},
+		{"class", &model.CodeChunk{ChunkType: model.ChunkTypeClass, FilePath: "order.go", Name: "Order"}, "shop:class:order.go#Order"},
+		{"method", &model.CodeChunk{ChunkType: model.ChunkTypeFunction, FilePath: "/src/shop/order.go", ClassName: "Order", Name: "Total"}, "shop:function:order.go#Order.Total"},
+		{"function", &model.CodeChunk{ChunkType: model.ChunkTypeFunction, FilePath: "util/money.go", Name: "Round"}, "shop:function:util/money.go#Round"},
+		{"block", &model.CodeChunk{ChunkType: model.ChunkTypeBlock, FilePath: "order.go", Name: "Total"}, ""},
+		{"outside the repository", &model.CodeChunk{ChunkType: model.ChunkTypeFile, FilePath: "/src/other/order.go"}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := chunkEntityRef("shop", "/src/shop", tt.chunk); got != tt.want {
+				t.Errorf("chunkEntityRef = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseEntityRef(t *testing.T) {
+	tests := []struct {
+		name    string
+		ref     string
+		wantErr bool
+	}{
+		{"function", "shop:function:order.go#Order.Total", false},
+		{"other repository", "web:function:order.go#Order.Total", true},
+		{"kind not accepted", "shop:file:order.go", true},
+		{"malformed", "shop", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseEntityRef("shop", tt.ref, entity.KindFunction, entity.KindClass)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("parseEntityRef(%q) error = %v, want error %v", tt.ref, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestChunkOfEntity(t *testing.T) {
+	rng := base.Range{Start: base.Position{Line: 10}, End: base.Position{Line: 30}}
+	tests := []struct {
+		name  string
+		chunk *model.CodeChunk
+		kind  entity.Kind
+		want  bool
+	}{
+		{"file chunk of file", &model.CodeChunk{ChunkType: model.ChunkTypeFile}, entity.KindFile, true},
+		{"function of file", &model.CodeChunk{ChunkType: model.ChunkTypeFunction, Name: "Order"}, entity.KindFile, false},
+		{"class", &model.CodeChunk{ChunkType: model.ChunkTypeClass, Name: "Order", StartLine: 10}, entity.KindClass, true},
+		{"constructor of class", &model.CodeChunk{ChunkType: model.ChunkTypeFunction, Name: "Order", StartLine: 12}, entity.KindClass, false},
+		{"function", &model.CodeChunk{ChunkType: model.ChunkTypeFunction, Name: "Order", StartLine: 12}, entity.KindFunction, true},
+		{"outside the range", &model.CodeChunk{ChunkType: model.ChunkTypeFunction, Name: "Order", StartLine: 40}, entity.KindFunction, false},
+		{"other name", &model.CodeChunk{ChunkType: model.ChunkTypeFunction, Name: "Total", StartLine: 12}, entity.KindFunction, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := chunkOfEntity(tt.chunk, tt.kind, "Order", rng); got != tt.want {
+				t.Errorf("chunkOfEntity = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetCallGraphRefs(t *testing.T) {
+	root := &codeapi.CallNode{Name: "Total", ClassName: "Order", FilePath: "order.go"}
+	external := &codeapi.CallNode{Name: "Println"}
+	graph := &codeapi.CallGraph{Root: root, Nodes: map[ast.NodeID]*codeapi.CallNode{1: root, 2: external}}
+
+	setCallGraphRefs("shop", graph)
+	if root.EntityRef != "shop:function:order.go#Order.Total" {
+		t.Errorf("root entity_ref = %q", root.EntityRef)
+	}
+	if external.EntityRef != "" {
+		t.Errorf("external entity_ref = %q, want none", external.EntityRef)
+	}
+}
@@ -0,0 +1,186 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/armchr/codeapi/internal/db"
+	"github.com/armchr/codeapi/internal/logging"
+	"github.com/armchr/codeapi/internal/service/feedback"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// FeedbackVote is a client's vote on a search result or summary
+type FeedbackVote struct {
+	EntityType string `json:"entity_type" binding:"required"`
+	EntityID   string `json:"entity_id" binding:"required,max=255"`
+	Query      string `json:"query,omitempty"`
+	Vote       int    `json:"vote" binding:"required,oneof=1 -1"`
+	Source     string `json:"source,omitempty" binding:"max=100"`
+	Comment    string `json:"comment,omitempty" binding:"max=2000"`
+}
+
+// RecordFeedbackRequest records votes on results of a repository
+type RecordFeedbackRequest struct {
+	RepoName string         `json:"repo_name" binding:"required"`
+	Votes    []FeedbackVote `json:"votes" binding:"required,min=1,dive"`
+}
+
+// AggregateFeedbackRequest selects the feedback to total. Order is "worst"
+// (default) for the lowest scores first or "best".
+type AggregateFeedbackRequest struct {
+	RepoName     string     `json:"repo_name" binding:"required"`
+	EntityType   string     `json:"entity_type,omitempty"`
+	EntityID     string     `json:"entity_id,omitempty"`
+	Query        string     `json:"query,omitempty"`
+	Source       string     `json:"source,omitempty"`
+	Since        *time.Time `json:"since,omitempty"`
+	GroupByQuery bool       `json:"group_by_query,omitempty"`
+	Order        string     `json:"order,omitempty"`
+	Limit        int        `json:"limit,omitempty"`
+	Offset       int        `json:"offset,omitempty"`
+}
+
+// AggregateFeedbackResponse lists the vote totals in the requested order
+type AggregateFeedbackResponse struct {
+	RepoName   string               `json:"repo_name"`
+	Aggregates []feedback.Aggregate `json:"aggregates"`
+}
+
+// RecordFeedback stores upvotes and downvotes clients give on search results
+// and summaries
+func (rc *RepoController) RecordFeedback(c *gin.Context) {
+	logger := logging.FromContext(c.Request.Context(), rc.logger)
+	var request RecordFeedbackRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		logger.Error("Invalid request payload", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request payload",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	votes := make([]feedback.Feedback, len(request.Votes))
+	for i, v := range request.Votes {
+		if !feedback.EntityTypes[v.EntityType] {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": fmt.Sprintf("Invalid entity_type %q: must be %s or %s", v.EntityType, feedback.EntitySearchResult, feedback.EntitySummary),
+			})
+			return
+		}
+		votes[i] = feedback.Feedback{
+			EntityType: v.EntityType,
+			EntityID:   v.EntityID,
+			Query:      v.Query,
+			Vote:       v.Vote,
+			Source:     v.Source,
+			Comment:    v.Comment,
+		}
+	}
+
+	store, ok := rc.feedbackStore(c, request.RepoName)
+	if !ok {
+		return
+	}
+	if err := store.WithContext(c.Request.Context()).RecordVotes(votes); err != nil {
+		logger.Error("Failed to record feedback", zap.String("repo_name", request.RepoName), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to record feedback",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"repo_name": request.RepoName, "recorded": len(votes)})
+}
+
+// AggregateFeedback totals the recorded votes per entity, or per entity and
+// query, to find the results and summaries users disagree with
+func (rc *RepoController) AggregateFeedback(c *gin.Context) {
+	logger := logging.FromContext(c.Request.Context(), rc.logger)
+	var request AggregateFeedbackRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		logger.Error("Invalid request payload", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request payload",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	if request.EntityType != "" && !feedback.EntityTypes[request.EntityType] {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Invalid entity_type %q: must be %s or %s", request.EntityType, feedback.EntitySearchResult, feedback.EntitySummary),
+		})
+		return
+	}
+	if request.Order != "" && request.Order != "worst" && request.Order != "best" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Invalid order %q: must be worst or best", request.Order),
+		})
+		return
+	}
+
+	store, ok := rc.feedbackStore(c, request.RepoName)
+	if !ok {
+		return
+	}
+	aggregates, err := store.WithContext(c.Request.Context()).Aggregate(feedback.Filter{
+		EntityType:   request.EntityType,
+		EntityID:     request.EntityID,
+		Query:        request.Query,
+		Source:       request.Source,
+		Since:        request.Since,
+		GroupByQuery: request.GroupByQuery,
+		BestFirst:    request.Order == "best",
+		Limit:        request.Limit,
+		Offset:       request.Offset,
+	})
+	if err != nil {
+		logger.Error("Failed to aggregate feedback", zap.String("repo_name", request.RepoName), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to aggregate feedback",
+			"details": err.Error(),
+		})
+		return
+	}
+	if aggregates == nil {
+		aggregates = []feedback.Aggregate{}
+	}
+
+	c.JSON(http.StatusOK, AggregateFeedbackResponse{RepoName: request.RepoName, Aggregates: aggregates})
+}
+
+// feedbackStore opens the feedback store of a configured repository, writing
+// the error response when it cannot
+func (rc *RepoController) feedbackStore(c *gin.Context, repoName string) (*db.FeedbackStore, bool) {
+	if rc.dbConn == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error": "Database connection not available. Feedback requires a relational store.",
+		})
+		return nil, false
+	}
+
+	if _, err := rc.config.GetRepository(repoName); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "Repository not found",
+			"details": err.Error(),
+		})
+		return nil, false
+	}
+
+	store, err := db.NewFeedbackStore(rc.dbConn.GetDB(), repoName, rc.logger)
+	if err != nil {
+		rc.logger.Error("Failed to open feedback store", zap.String("repo_name", repoName), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to open feedback store",
+			"details": err.Error(),
+		})
+		return nil, false
+	}
+	return store, true
+}
@@ -0,0 +1,302 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/armchr/codeapi/internal/codeapi"
+	"github.com/armchr/codeapi/internal/db"
+	"github.com/armchr/codeapi/internal/entity"
+	"github.com/armchr/codeapi/internal/logging"
+	"github.com/armchr/codeapi/internal/model"
+	"github.com/armchr/codeapi/internal/model/ast"
+	"github.com/armchr/codeapi/internal/service/summary"
+	"github.com/armchr/codeapi/pkg/lsp/base"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+var errNotConfigured = errors.New("not configured")
+
+// OutlineMetrics are the size and complexity metrics of a function, as
+// computed by the metrics and git churn processors
+type OutlineMetrics struct {
+	Complexity int     `json:"complexity"`
+	LOC        int     `json:"loc"`
+	Params     int     `json:"params"`
+	Nesting    int     `json:"nesting"`
+	ChurnScore float64 `json:"churn_score,omitempty"`
+}
+
+// OutlineEntry is a class, method or function of a file outline. Ranges
+// are zero-based as in the code graph.
+type OutlineEntry struct {
+	ID        ast.NodeID      `json:"id"`
+	Kind      string          `json:"kind"` // class, method or function
+	Name      string          `json:"name"`
+	EntityRef string          `json:"entity_ref,omitempty"`
+	Range     base.Range      `json:"range"`
+	Signature string          `json:"signature,omitempty"`
+	Metrics   *OutlineMetrics `json:"metrics,omitempty"`
+	Summary   string          `json:"summary,omitempty"`
+	ChunkIDs  []string        `json:"chunk_ids,omitempty"`
+	Methods   []*OutlineEntry `json:"methods,omitempty"`
+}
+
+// FileOutline is everything codeapi knows about a file in one document: its
+// classes with their methods and its top-level functions from the code
+// graph, signatures and chunk IDs from the vector store, and summaries from
+// the relational store. Stores that are not configured or failed to answer
+// are named in Errors and their fields left empty.
+type FileOutline struct {
+	RepoName  string            `json:"repo_name"`
+	FilePath  string            `json:"file_path"`
+	FileID    int32             `json:"file_id"`
+	EntityRef string            `json:"entity_ref"`
+	Language  string            `json:"language,omitempty"`
+	Summary   string            `json:"summary,omitempty"`
+	Classes   []*OutlineEntry   `json:"classes"`
+	Functions []*OutlineEntry   `json:"functions"`
+	Errors    map[string]string `json:"errors,omitempty"`
+}
+
+// GetFileOutline returns the outline of a file, the single call an editor
+// needs to render a file overview. The route is
+// /repos/:repo/files/<path>/outline with the file path unescaped.
+func (rc *RepoController) GetFileOutline(c *gin.Context) {
+	logger := logging.FromContext(c.Request.Context(), rc.logger)
+	relPath, ok := strings.CutSuffix(strings.TrimPrefix(c.Param("path"), "/"), "/outline")
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Unknown file endpoint, expected /files/<path>/outline"})
+		return
+	}
+	if !filepath.IsLocal(relPath) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "File path must be a path inside the repository"})
+		return
+	}
+	if rc.codeGraph == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Code graph not available"})
+		return
+	}
+
+	repoName := c.Param("repo")
+	ctx := c.Request.Context()
+	repo := codeapi.NewCodeAPI(rc.codeGraph, rc.logger).Reader().Repo(repoName)
+	files, err := repo.FindFiles(ctx, codeapi.FileFilter{Path: relPath, Limit: 1})
+	if err != nil {
+		logger.Error("Failed to look up file", zap.String("repo_name", repoName), zap.String("path", relPath), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to look up file",
+			"details": err.Error(),
+		})
+		return
+	}
+	if len(files) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "File not found in the code graph", "file_path": relPath})
+		return
+	}
+
+	outline, err := graphOutline(ctx, repo, files[0])
+	if err != nil {
+		logger.Error("Failed to read file outline", zap.String("repo_name", repoName), zap.String("path", relPath), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to read file outline",
+			"details": err.Error(),
+		})
+		return
+	}
+	outline.RepoName = repoName
+
+	failed := func(store string, err error) {
+		if outline.Errors == nil {
+			outline.Errors = map[string]string{}
+		}
+		outline.Errors[store] = err.Error()
+		logger.Warn("Failed to complete file outline", zap.String("repo_name", repoName), zap.String("store", store), zap.Error(err))
+	}
+
+	if rc.chunkService == nil {
+		failed("qdrant", errNotConfigured)
+	} else if chunks, err := rc.chunkService.GetVectorDB().GetChunksByFilePath(ctx, repoName, relPath); err != nil {
+		failed("qdrant", err)
+	} else {
+		outline.addChunks(chunks)
+	}
+
+	if rc.dbConn == nil {
+		failed("db", errNotConfigured)
+	} else if exists, err := db.TableExists(rc.dbConn.GetDB(), db.CodeSummariesTable); err != nil {
+		failed("db", err)
+	} else if exists {
+		summaries, err := db.NewSummaryReader(rc.dbConn.GetDB(), repoName, rc.logger).WithContext(ctx).GetSummariesByFile(relPath)
+		if err != nil {
+			failed("db", err)
+		} else {
+			outline.addSummaries(summaries)
+		}
+	}
+
+	c.JSON(http.StatusOK, outline)
+}
+
+// graphOutline reads the classes, methods and functions of a file, in
+// source order. Methods of a class declared in another file, like Go methods
+// on a type of another file, are listed as functions.
+func graphOutline(ctx context.Context, repo codeapi.RepoReader, info *codeapi.FileInfo) (*FileOutline, error) {
+	outline := &FileOutline{
+		FilePath:  info.Path,
+		FileID:    info.FileID,
+		EntityRef: entity.File(repo.Name(), info.Path).String(),
+		Language:  info.Language,
+		Classes:   []*OutlineEntry{},
+		Functions: []*OutlineEntry{},
+	}
+
+	file := repo.FileByID(info.FileID)
+	classes, err := file.ListClasses(ctx)
+	if err != nil {
+		return nil, err
+	}
+	functions, err := file.ListMethods(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	inClass := make(map[ast.NodeID]bool)
+	for _, class := range classes {
+		entry := &OutlineEntry{ID: class.ID, Kind: "class", Name: class.Name, Range: class.Range}
+		methods, err := repo.GetClassMethods(ctx, class.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, method := range methods {
+			if method.FileID != info.FileID {
+				continue
+			}
+			inClass[method.ID] = true
+			entry.Methods = append(entry.Methods, functionEntry(method, "method"))
+		}
+		sortOutline(entry.Methods)
+		outline.Classes = append(outline.Classes, entry)
+	}
+	for _, fn := range functions {
+		if !inClass[fn.ID] {
+			outline.Functions = append(outline.Functions, functionEntry(fn, "function"))
+		}
+	}
+	sortOutline(outline.Classes)
+	sortOutline(outline.Functions)
+
+	// Functions listed here may still be methods, of a class of another file
+	var ids []ast.NodeID
+	outline.each(func(entry *OutlineEntry) { ids = append(ids, entry.ID) })
+	refs, err := repo.EntityRefs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	outline.each(func(entry *OutlineEntry) {
+		if ref, ok := refs[entry.ID]; ok {
+			entry.EntityRef = ref.String()
+		}
+	})
+	return outline, nil
+}
+
+func functionEntry(fn *codeapi.MethodInfo, kind string) *OutlineEntry {
+	entry := &OutlineEntry{ID: fn.ID, Kind: kind, Name: fn.Name, Range: fn.Range}
+	if _, ok := fn.Metadata["complexity"]; ok {
+		entry.Metrics = &OutlineMetrics{
+			Complexity: metadataInt(fn.Metadata, "complexity"),
+			LOC:        metadataInt(fn.Metadata, "loc"),
+			Params:     metadataInt(fn.Metadata, "params"),
+			Nesting:    metadataInt(fn.Metadata, "nesting"),
+		}
+		switch v := fn.Metadata["churn_score"].(type) {
+		case float64:
+			entry.Metrics.ChurnScore = v
+		case int64:
+			entry.Metrics.ChurnScore = float64(v)
+		}
+	}
+	return entry
+}
+
+func metadataInt(metadata map[string]any, key string) int {
+	switch v := metadata[key].(type) {
+	case int64:
+		return int(v)
+	case int:
+		return v
+	case float64:
+		return int(v)
+	}
+	return 0
+}
+
+func sortOutline(entries []*OutlineEntry) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Range.Start.Line < entries[j].Range.Start.Line
+	})
+}
+
+// each calls fn on every entry of the outline, classes before their methods
+func (o *FileOutline) each(fn func(*OutlineEntry)) {
+	for _, class := range o.Classes {
+		fn(class)
+		for _, method := range class.Methods {
+			fn(method)
+		}
+	}
+	for _, function := range o.Functions {
+		fn(function)
+	}
+}
+
+// addChunks records the chunks of each entry, see chunkOfEntity. A function
+// takes its signature from them.
+func (o *FileOutline) addChunks(chunks []*model.CodeChunk) {
+	o.each(func(entry *OutlineEntry) {
+		kind := entity.KindFunction
+		if entry.Kind == "class" {
+			kind = entity.KindClass
+		}
+		for _, chunk := range chunks {
+			if !chunkOfEntity(chunk, kind, entry.Name, entry.Range) {
+				continue
+			}
+			entry.ChunkIDs = append(entry.ChunkIDs, chunk.ID)
+			if entry.Signature == "" && chunk.Signature != "" {
+				entry.Signature = chunk.Signature
+			}
+		}
+	})
+}
+
+// addSummaries sets the summary of the file and of each entry, matching
+// entries by node ID
+func (o *FileOutline) addSummaries(summaries []*summary.CodeSummary) {
+	byID := make(map[string]*summary.CodeSummary, len(summaries))
+	for _, cs := range summaries {
+		if cs.EntityType == summary.LevelFile {
+			o.Summary = cs.Summary
+			continue
+		}
+		byID[cs.EntityID] = cs
+	}
+
+	o.each(func(entry *OutlineEntry) {
+		level := summary.LevelFunction
+		if entry.Kind == "class" {
+			level = summary.LevelClass
+		}
+		if cs, ok := byID[strconv.FormatInt(int64(entry.ID), 10)]; ok && cs.EntityType == level {
+			entry.Summary = cs.Summary
+		}
+	})
+}
@@ -0,0 +1,84 @@
+package controller
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/armchr/codeapi/internal/model"
+	"github.com/armchr/codeapi/internal/service/summary"
+	"github.com/armchr/codeapi/pkg/lsp/base"
+)
+
+func lines(start, end int) base.Range {
+	return base.Range{Start: base.Position{Line: start}, End: base.Position{Line: end}}
+}
+
+func testOutline() *FileOutline {
+	return &FileOutline{
+		FilePath: "store/file.go",
+		Classes: []*OutlineEntry{{
+			ID: 10, Kind: "class", Name: "FileStore", Range: lines(4, 30),
+			Methods: []*OutlineEntry{
+				{ID: 11, Kind: "method", Name: "FileStore", Range: lines(8, 12)},
+				{ID: 12, Kind: "method", Name: "Get", Range: lines(14, 30)},
+			},
+		}},
+		Functions: []*OutlineEntry{{ID: 20, Kind: "function", Name: "Get", Range: lines(32, 40)}},
+	}
+}
+
+func TestFileOutlineAddChunks(t *testing.T) {
+	outline := testOutline()
+	outline.addChunks([]*model.CodeChunk{
+		{ID: "file", ChunkType: model.ChunkTypeFile, Name: "file.go", StartLine: 0},
+		{ID: "class", ChunkType: model.ChunkTypeClass, Name: "FileStore", StartLine: 4},
+		{ID: "ctor", ChunkType: model.ChunkTypeFunction, Name: "FileStore", StartLine: 8, Signature: "FileStore(String root)"},
+		{ID: "method", ChunkType: model.ChunkTypeFunction, Name: "Get", StartLine: 14, Signature: "Get(key string) ([]byte, error)"},
+		{ID: "method-window", ChunkType: model.ChunkTypeWindow, Name: "Get", StartLine: 22},
+		{ID: "func", ChunkType: model.ChunkTypeFunction, Name: "Get", StartLine: 32, Signature: "Get(path string) []byte"},
+	})
+
+	class := outline.Classes[0]
+	got := map[string][]string{
+		"class":    class.ChunkIDs,
+		"ctor":     class.Methods[0].ChunkIDs,
+		"method":   class.Methods[1].ChunkIDs,
+		"function": outline.Functions[0].ChunkIDs,
+	}
+	want := map[string][]string{
+		"class":    {"class"},
+		"ctor":     {"ctor"},
+		"method":   {"method", "method-window"},
+		"function": {"func"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("chunk IDs = %v, want %v", got, want)
+	}
+	if class.Methods[1].Signature != "Get(key string) ([]byte, error)" || outline.Functions[0].Signature != "Get(path string) []byte" {
+		t.Errorf("signatures %q, %q", class.Methods[1].Signature, outline.Functions[0].Signature)
+	}
+	if class.Signature != "" {
+		t.Errorf("class signature %q", class.Signature)
+	}
+}
+
+func TestFileOutlineAddSummaries(t *testing.T) {
+	outline := testOutline()
+	outline.addSummaries([]*summary.CodeSummary{
+		{EntityID: "store/file.go", EntityType: summary.LevelFile, Summary: "disk-backed store"},
+		{EntityID: "10", EntityType: summary.LevelClass, EntityName: "FileStore", Summary: "stores blobs as files"},
+		{EntityID: "12", EntityType: summary.LevelFunction, EntityName: "Get", Summary: "reads a blob"},
+		// Summary of a node of an earlier index of the file
+		{EntityID: "7", EntityType: summary.LevelFunction, EntityName: "FileStore", Summary: "opens the root directory"},
+	})
+
+	if outline.Summary != "disk-backed store" {
+		t.Errorf("file summary %q", outline.Summary)
+	}
+	class := outline.Classes[0]
+	got := []string{class.Summary, class.Methods[0].Summary, class.Methods[1].Summary, outline.Functions[0].Summary}
+	want := []string{"stores blobs as files", "", "reads a blob", ""}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("summaries = %q, want %q", got, want)
+	}
+}
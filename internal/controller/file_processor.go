@@ -2,7 +2,9 @@ package controller
 
 import (
 	"github.com/armchr/codeapi/internal/config"
+	"github.com/armchr/codeapi/internal/util"
 	"context"
+	"fmt"
 )
 
 // FileContext contains metadata about a file being processed
@@ -27,6 +29,31 @@ type FileContext struct {
 
 	// Ephemeral indicates if this is an uncommitted/working directory version
 	Ephemeral bool
+
+	// Language is the language detected for this file. For single-language
+	// repositories it is the repository language; for mixed-language ("auto")
+	// repositories it is detected per file and may be empty if unknown.
+	Language string
+
+	// Sandbox indicates the file was indexed ad hoc (indexFile) rather than by a
+	// repository build. Sandbox data is subject to TTL cleanup.
+	Sandbox bool
+}
+
+// fileLanguage returns the language a file should be processed as
+func fileLanguage(repo *config.Repository, filePath string, content []byte) string {
+	if repo.IsMultiLanguage() {
+		return util.DetectLanguage(filePath, content)
+	}
+	return repo.Language
+}
+
+// repoLanguages returns the languages a repository is known to contain
+func repoLanguages(repo *config.Repository) []string {
+	if repo.IsMultiLanguage() {
+		return repo.Languages
+	}
+	return []string{repo.Language}
 }
 
 // FileProcessor defines the interface for processing individual files
@@ -51,3 +78,30 @@ type FileProcessor interface {
 	// Name returns the name of this processor (for logging purposes)
 	Name() string
 }
+
+// FilePurger is implemented by processors that can remove the data they
+// produced for specific file versions. It is used to clean up sandbox
+// versions created by ad-hoc file indexing and versions dropped by compaction.
+type FilePurger interface {
+	// PurgeFiles removes all data this processor stored for the given FileIDs
+	PurgeFiles(ctx context.Context, repo *config.Repository, fileIDs []int32) error
+}
+
+// purgeFiles runs every FilePurger among processors for the given FileIDs.
+// Processors are visited in reverse pipeline order: later processors (e.g.
+// summaries) resolve their data through graph nodes that earlier ones own.
+func purgeFiles(ctx context.Context, processors []FileProcessor, repo *config.Repository, fileIDs []int32) error {
+	if len(fileIDs) == 0 {
+		return nil
+	}
+	for i := len(processors) - 1; i >= 0; i-- {
+		purger, ok := processors[i].(FilePurger)
+		if !ok {
+			continue
+		}
+		if err := purger.PurgeFiles(ctx, repo, fileIDs); err != nil {
+			return fmt.Errorf("processor %s failed to purge files: %w", processors[i].Name(), err)
+		}
+	}
+	return nil
+}
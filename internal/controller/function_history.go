@@ -0,0 +1,252 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/armchr/codeapi/internal/codeapi"
+	"github.com/armchr/codeapi/internal/db"
+	"github.com/armchr/codeapi/internal/entity"
+	"github.com/armchr/codeapi/internal/logging"
+	"github.com/armchr/codeapi/internal/model"
+	"github.com/armchr/codeapi/internal/model/ast"
+	"github.com/armchr/codeapi/internal/service/summary"
+	"github.com/armchr/codeapi/pkg/lsp/base"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// FunctionHistoryRequest names a function by ID, in any version of its
+// file, or by name as the call graph endpoints do
+type FunctionHistoryRequest struct {
+	RepoName     string              `json:"repo_name" binding:"required"`
+	FunctionID   *FlexibleFunctionID `json:"function_id"`
+	EntityRef    string              `json:"entity_ref"` // instead of function_id or function_name
+	FunctionName string              `json:"function_name"`
+	ClassName    string              `json:"class_name"`
+	FilePath     string              `json:"file_path"`
+	MaxVersions  int                 `json:"max_versions"` // default: 20
+}
+
+// FunctionHistoryEntry is a function as it was in one indexed version of its
+// file. Ranges are zero-based as in the code graph.
+type FunctionHistoryEntry struct {
+	ID        ast.NodeID `json:"id"`
+	EntityRef string     `json:"entity_ref,omitempty"` // by the name of this version
+	Name      string     `json:"name"`
+	ClassName string     `json:"class_name,omitempty"`
+	FileID    int32      `json:"file_id"`
+	FileSHA   string     `json:"file_sha,omitempty"`
+	CommitID  string     `json:"commit_id,omitempty"`
+	IndexedAt *time.Time `json:"indexed_at,omitempty"`
+	Range     base.Range `json:"range"`
+	Renamed   bool       `json:"renamed"` // name differs from the next older version
+	Changed   bool       `json:"changed"` // body differs from the next older version
+	Signature string     `json:"signature,omitempty"`
+	Summary   string     `json:"summary,omitempty"`
+
+	// SummaryCarried is set when the summary was copied from the next older
+	// version rather than generated for this one
+	SummaryCarried bool `json:"summary_carried,omitempty"`
+}
+
+// FunctionHistoryResponse lists a function across the indexed versions of
+// its file, newest first. Versions come from the code graph; file SHAs and
+// commits, summaries and signatures are added from the relational and
+// vector stores, and stores that are not configured or failed to answer are
+// named in Errors.
+type FunctionHistoryResponse struct {
+	RepoName  string                  `json:"repo_name"`
+	FilePath  string                  `json:"file_path"`
+	Versions  []*FunctionHistoryEntry `json:"versions"`
+	Truncated bool                    `json:"truncated"`
+	Errors    map[string]string       `json:"errors,omitempty"`
+}
+
+// GetFunctionHistory returns how a function, its signature and its summary
+// changed across the indexed versions of its file
+func (rc *RepoController) GetFunctionHistory(c *gin.Context) {
+	logger := logging.FromContext(c.Request.Context(), rc.logger)
+	var req FunctionHistoryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request payload",
+			"details": err.Error(),
+		})
+		return
+	}
+	if rc.codeGraph == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Code graph not available"})
+		return
+	}
+
+	if req.EntityRef != "" {
+		// A reference names the function in every version of its file
+		ref, err := parseEntityRef(req.RepoName, req.EntityRef, entity.KindFunction)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		req.FunctionID, req.FunctionName, req.ClassName, req.FilePath = nil, ref.FunctionName(), ref.ClassName(), ref.Path
+	}
+
+	ctx := c.Request.Context()
+	analyzer := codeapi.NewCodeAPI(rc.codeGraph, rc.logger).Analyzer()
+	ref := GetCallGraphRequest{FunctionID: req.FunctionID, FunctionName: req.FunctionName, ClassName: req.ClassName}
+	var history *codeapi.FunctionHistory
+	var err error
+	funcID, funcName, className := ref.resolveFunctionRef()
+	if funcID > 0 {
+		history, err = analyzer.GetFunctionHistory(ctx, ast.NodeID(funcID), req.MaxVersions)
+	} else if funcName != "" {
+		history, err = analyzer.GetFunctionHistoryByName(ctx, req.RepoName, req.FilePath, className, funcName, req.MaxVersions)
+	} else {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "either function_id, function_name or entity_ref is required"})
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to read function history", zap.String("repo_name", req.RepoName), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to read function history",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	resp := newFunctionHistoryResponse(req.RepoName, history)
+	failed := func(store string, err error) {
+		if resp.Errors == nil {
+			resp.Errors = map[string]string{}
+		}
+		resp.Errors[store] = err.Error()
+		logger.Warn("Failed to complete function history", zap.String("repo_name", req.RepoName), zap.String("store", store), zap.Error(err))
+	}
+	if resp.FilePath == "" {
+		c.JSON(http.StatusOK, resp)
+		return
+	}
+
+	if rc.dbConn == nil {
+		failed("db", errNotConfigured)
+	} else {
+		if fileVersionRepo, err := db.NewFileVersionRepository(rc.dbConn.GetDB(), req.RepoName, rc.logger); err != nil {
+			failed("db", err)
+		} else if versions, err := fileVersionRepo.WithContext(ctx).GetFilesByPath(resp.FilePath); err != nil {
+			failed("db", err)
+		} else {
+			resp.addFileVersions(versions)
+		}
+
+		if exists, err := db.TableExists(rc.dbConn.GetDB(), db.CodeSummariesTable); err != nil {
+			failed("db", err)
+		} else if exists {
+			entityIDs := make([]string, len(resp.Versions))
+			for i, v := range resp.Versions {
+				entityIDs[i] = strconv.FormatInt(int64(v.ID), 10)
+			}
+			summaries, err := db.NewSummaryReader(rc.dbConn.GetDB(), req.RepoName, rc.logger).WithContext(ctx).GetSummariesByEntityIDs(summary.LevelFunction, entityIDs)
+			if err != nil {
+				failed("db", err)
+			} else {
+				resp.addSummaries(summaries)
+			}
+		}
+	}
+
+	if rc.chunkService == nil {
+		failed("qdrant", errNotConfigured)
+	} else if chunks, err := rc.chunkService.GetVectorDB().GetChunksByFilePath(ctx, req.RepoName, resp.FilePath); err != nil {
+		failed("qdrant", err)
+	} else {
+		resp.addChunks(chunks)
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+func newFunctionHistoryResponse(repoName string, history *codeapi.FunctionHistory) *FunctionHistoryResponse {
+	resp := &FunctionHistoryResponse{
+		RepoName:  repoName,
+		Versions:  make([]*FunctionHistoryEntry, 0, len(history.Versions)),
+		Truncated: history.Truncated,
+	}
+	for _, v := range history.Versions {
+		if resp.FilePath == "" {
+			resp.FilePath = v.FilePath
+		}
+		entry := &FunctionHistoryEntry{
+			ID:        v.ID,
+			Name:      v.Name,
+			ClassName: v.ClassName,
+			FileID:    v.FileID,
+			Range:     v.Range,
+			Renamed:   v.Renamed,
+			Changed:   v.Changed,
+		}
+		if v.FilePath != "" {
+			entry.EntityRef = entity.Function(repoName, v.FilePath, v.ClassName, v.Name).String()
+		}
+		resp.Versions = append(resp.Versions, entry)
+	}
+	return resp
+}
+
+// addFileVersions sets the SHA, commit and indexing time of each version.
+// Versions removed by compaction keep these empty.
+func (r *FunctionHistoryResponse) addFileVersions(versions []*db.FileVersion) {
+	byID := make(map[int32]*db.FileVersion, len(versions))
+	for _, v := range versions {
+		byID[v.FileID] = v
+	}
+	for _, entry := range r.Versions {
+		v, ok := byID[entry.FileID]
+		if !ok {
+			continue
+		}
+		entry.FileSHA = v.FileSHA
+		if v.CommitID != nil {
+			entry.CommitID = *v.CommitID
+		}
+		indexedAt := v.CreatedAt
+		entry.IndexedAt = &indexedAt
+	}
+}
+
+// addSummaries sets the summary of each version, noting the summaries
+// carried over from the version before instead of generated again
+func (r *FunctionHistoryResponse) addSummaries(summaries []*summary.CodeSummary) {
+	byID := make(map[string]*summary.CodeSummary, len(summaries))
+	for _, cs := range summaries {
+		byID[cs.EntityID] = cs
+	}
+	for i, entry := range r.Versions {
+		cs, ok := byID[strconv.FormatInt(int64(entry.ID), 10)]
+		if !ok {
+			continue
+		}
+		entry.Summary = cs.Summary
+		if i+1 < len(r.Versions) && cs.PreviousEntityID != "" {
+			entry.SummaryCarried = cs.PreviousEntityID == strconv.FormatInt(int64(r.Versions[i+1].ID), 10)
+		}
+	}
+}
+
+// addChunks sets the signature of each version from the function chunk of
+// the same file version with its name that starts inside it
+func (r *FunctionHistoryResponse) addChunks(chunks []*model.CodeChunk) {
+	for _, entry := range r.Versions {
+		for _, chunk := range chunks {
+			if chunk.FileID != entry.FileID || chunk.Name != entry.Name ||
+				chunk.ChunkType != model.ChunkTypeFunction || chunk.Signature == "" {
+				continue
+			}
+			if line := chunk.StartLine; line < entry.Range.Start.Line || line > entry.Range.End.Line {
+				continue
+			}
+			entry.Signature = chunk.Signature
+			break
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package controller
+
+import (
+	"testing"
+	"time"
+
+	"github.com/armchr/codeapi/internal/codeapi"
+	"github.com/armchr/codeapi/internal/db"
+	"github.com/armchr/codeapi/internal/model"
+	"github.com/armchr/codeapi/internal/service/summary"
+)
+
+// testHistory is a function renamed from process to processOrder in file
+// version 9 after being edited in version 5
+func testHistory() *FunctionHistoryResponse {
+	return newFunctionHistoryResponse("shop", &codeapi.FunctionHistory{
+		Versions: []*codeapi.FunctionVersion{
+			{ID: 300, Name: "processOrder", FilePath: "order.go", FileID: 9, Range: lines(10, 20), Renamed: true},
+			{ID: 200, Name: "process", FilePath: "order.go", FileID: 5, Range: lines(12, 22), Changed: true},
+			{ID: 100, Name: "process", FilePath: "order.go", FileID: 2, Range: lines(12, 18)},
+		},
+	})
+}
+
+func TestFunctionHistoryAddChunks(t *testing.T) {
+	history := testHistory()
+	history.addChunks([]*model.CodeChunk{
+		{ChunkType: model.ChunkTypeFunction, Name: "processOrder", FileID: 9, StartLine: 10, Signature: "processOrder(o Order) error"},
+		{ChunkType: model.ChunkTypeFunction, Name: "process", FileID: 5, StartLine: 12, Signature: "process(o Order) error"},
+		{ChunkType: model.ChunkTypeFunction, Name: "process", FileID: 2, StartLine: 12, Signature: "process(o Order)"},
+		{ChunkType: model.ChunkTypeFunction, Name: "process", FileID: 9, StartLine: 30, Signature: "process(o *Order)"},
+		{ChunkType: model.ChunkTypeWindow, Name: "processOrder", FileID: 9, StartLine: 15},
+	})
+
+	want := []string{"processOrder(o Order) error", "process(o Order) error", "process(o Order)"}
+	for i, v := range history.Versions {
+		if v.Signature != want[i] {
+			t.Errorf("version %d signature = %q, want %q", v.FileID, v.Signature, want[i])
+		}
+	}
+}
+
+func TestFunctionHistoryAddSummaries(t *testing.T) {
+	history := testHistory()
+	history.addSummaries([]*summary.CodeSummary{
+		{EntityID: "300", Summary: "Saves an order.", PreviousEntityID: "200"},
+		{EntityID: "200", Summary: "Saves an order."},
+		{EntityID: "100", Summary: "Saves an order without validation.", PreviousEntityID: "50"},
+	})
+
+	tests := []struct {
+		summary string
+		carried bool
+	}{
+		{"Saves an order.", true},
+		{"Saves an order.", false},
+		{"Saves an order without validation.", false},
+	}
+	for i, tt := range tests {
+		v := history.Versions[i]
+		if v.Summary != tt.summary || v.SummaryCarried != tt.carried {
+			t.Errorf("version %d = %q carried %v, want %q carried %v", v.FileID, v.Summary, v.SummaryCarried, tt.summary, tt.carried)
+		}
+	}
+}
+
+func TestFunctionHistoryAddFileVersions(t *testing.T) {
+	history := testHistory()
+	commit := "a41b7d0"
+	indexed := time.Date(2026, 10, 2, 9, 0, 0, 0, time.UTC)
+	history.addFileVersions([]*db.FileVersion{
+		{FileID: 9, FileSHA: "sha-9", CommitID: &commit, CreatedAt: indexed},
+		{FileID: 5, FileSHA: "sha-5", CreatedAt: indexed},
+	})
+
+	latest, middle, oldest := history.Versions[0], history.Versions[1], history.Versions[2]
+	if latest.FileSHA != "sha-9" || latest.CommitID != commit || latest.IndexedAt == nil || !latest.IndexedAt.Equal(indexed) {
+		t.Errorf("latest version = %+v", latest)
+	}
+	if middle.FileSHA != "sha-5" || middle.CommitID != "" {
+		t.Errorf("middle version = %+v", middle)
+	}
+	if oldest.FileSHA != "" || oldest.IndexedAt != nil {
+		t.Errorf("compacted version should have no file details, got %+v", oldest)
+	}
+	if history.FilePath != "order.go" {
+		t.Errorf("file path = %q", history.FilePath)
+	}
+}
@@ -22,6 +22,12 @@ type GitChurnProcessor struct {
 	// Cached data per repository
 	repoPath string
 	gitLog   *GitLogCache
+
+	// Size of the built git log cache, for diagnostics
+	cacheMu      sync.Mutex
+	cacheRepo    string
+	cacheFiles   int
+	cacheCommits int
 }
 
 // Ensure interface compliance
@@ -45,6 +51,19 @@ func (gcp *GitChurnProcessor) Name() string {
 	return "GitChurn"
 }
 
+// CacheSizes reports the git history held for the last analyzed repository
+func (gcp *GitChurnProcessor) CacheSizes() []CacheSize {
+	gcp.cacheMu.Lock()
+	defer gcp.cacheMu.Unlock()
+	if gcp.cacheRepo == "" {
+		return nil
+	}
+	return []CacheSize{
+		{Repo: gcp.cacheRepo, Cache: "git_log_files", Entries: gcp.cacheFiles},
+		{Repo: gcp.cacheRepo, Cache: "git_log_commits", Entries: gcp.cacheCommits},
+	}
+}
+
 // Init initializes the processor for a repository
 func (gcp *GitChurnProcessor) Init(ctx context.Context, repo *config.Repository) error {
 	if !gcp.config.Enabled {
@@ -83,6 +102,9 @@ func (gcp *GitChurnProcessor) PostProcess(ctx context.Context, repo *config.Repo
 	if err := gcp.gitLog.Build(ctx); err != nil {
 		return fmt.Errorf("failed to build git log cache: %w", err)
 	}
+	gcp.cacheMu.Lock()
+	gcp.cacheRepo, gcp.cacheFiles, gcp.cacheCommits = repo.Name, len(gcp.gitLog.fileMetrics), len(gcp.gitLog.commits)
+	gcp.cacheMu.Unlock()
 
 	// Step 2: Process file-level churn
 	if gcp.config.EnableFileLevel {
@@ -164,7 +186,7 @@ func (gcp *GitChurnProcessor) updateFileChurnMetrics(ctx context.Context, fileSc
 	churnData := gcp.gitLog.GetFileMetrics(relativePath)
 	if churnData == nil {
 		// No commits for this file in time window - set zero metrics
-		churnData = &FileChurnData{Authors: make(map[string]bool)}
+		churnData = &FileChurnData{Authors: make(map[string]int)}
 	}
 
 	// Get current LOC for density calculation
@@ -194,6 +216,9 @@ func (gcp *GitChurnProcessor) updateFileChurnMetrics(ctx context.Context, fileSc
 		"churn_density":       density,
 		"churn_window_days":   gcp.config.TimeWindowDays,
 	}
+	if contributors := mainContributors(churnData.Authors, gcp.config.MainContributors); len(contributors) > 0 {
+		metadata["churn_main_contributors"] = contributors
+	}
 
 	if !churnData.FirstCommit.IsZero() {
 		metadata["churn_first_commit"] = churnData.FirstCommit.Format(time.RFC3339)
@@ -228,32 +253,29 @@ func (gcp *GitChurnProcessor) getFileLineCount(fileScope *ast.Node) int {
 // Only processes files with high churn (hybrid approach for performance)
 func (gcp *GitChurnProcessor) processFunctionLevelChurn(ctx context.Context, repo *config.Repository) error {
 	// Get all files with high churn (returns FileScope nodes)
-	return nil
-	/*
-		highChurnFiles, err := gcp.getHighChurnFiles(ctx, repo)
-		if err != nil {
-			return fmt.Errorf("failed to identify high churn files: %w", err)
-		}
+	highChurnFiles, err := gcp.getHighChurnFiles(ctx, repo)
+	if err != nil {
+		return fmt.Errorf("failed to identify high churn files: %w", err)
+	}
 
-		if len(highChurnFiles) == 0 {
-			gcp.logger.Debug("No high churn files found for function-level analysis")
-			return nil
-		}
+	if len(highChurnFiles) == 0 {
+		gcp.logger.Debug("No high churn files found for function-level analysis")
+		return nil
+	}
 
-		gcp.logger.Debug("Processing function-level churn for high-churn files",
-			zap.Int("fileCount", len(highChurnFiles)))
+	gcp.logger.Debug("Processing function-level churn for high-churn files",
+		zap.Int("fileCount", len(highChurnFiles)))
 
-		for _, fileScope := range highChurnFiles {
-			relativePath, _ := fileScope.MetaData["path"].(string)
-			if err := gcp.processFunctionsInFile(ctx, repo, fileScope.FileID, relativePath); err != nil {
-				gcp.logger.Warn("Failed to process functions in file",
-					zap.String("file", relativePath),
-					zap.Error(err))
-			}
+	for _, fileScope := range highChurnFiles {
+		relativePath, _ := fileScope.MetaData["path"].(string)
+		if err := gcp.processFunctionsInFile(ctx, repo, fileScope.FileID, relativePath); err != nil {
+			gcp.logger.Warn("Failed to process functions in file",
+				zap.String("file", relativePath),
+				zap.Error(err))
 		}
+	}
 
-		return nil
-	*/
+	return nil
 }
 
 // getHighChurnFiles returns FileScope nodes with churn score in the top N%
@@ -353,54 +375,122 @@ type FunctionChurnMetrics struct {
 	LinesAdded   int
 	LinesDeleted int
 	CommitCount  int
-	Authors      map[string]bool
+	Authors      map[string]int // commits per author
+	FirstCommit  time.Time
+	LastCommit   time.Time
 }
 
-// attributeChangesToFunction calculates churn metrics for a function based on diff data
+// attributeChangesToFunction calculates churn metrics for a function based on diff data.
+// Commits are walked from the newest, carrying the function's lines back through each
+// diff so that edits elsewhere in the file do not shift older hunks into or out of it.
+// The walk stops at the commit that added the whole function.
 func (gcp *GitChurnProcessor) attributeChangesToFunction(fn *ast.Node, diffData *FileDiffData) *FunctionChurnMetrics {
 	metrics := &FunctionChurnMetrics{
-		Authors: make(map[string]bool),
+		Authors: make(map[string]int),
 	}
 
-	fnStartLine := fn.Range.Start.Line
-	fnEndLine := fn.Range.End.Line
+	// Hunks number lines from 1, ranges from 0
+	fnStartLine := fn.Range.Start.Line + 1
+	fnEndLine := fn.Range.End.Line + 1
 
-	// For each commit that touched this file
 	for _, commit := range diffData.Commits {
+		if fnStartLine > fnEndLine {
+			break
+		}
 		touchedFunction := false
 
 		for _, hunk := range commit.Hunks {
-			// Check if hunk overlaps with function range
-			// We use the NEW file line numbers since that's the current state
-			hunkStart := hunk.NewStart
-			hunkEnd := hunk.NewStart + hunk.NewCount
+			if hunk.NewCount == 0 {
+				// Pure deletion after line NewStart: inside the function if
+				// both neighbouring lines are
+				if hunk.NewStart >= fnStartLine && hunk.NewStart < fnEndLine {
+					touchedFunction = true
+					metrics.LinesDeleted += hunk.LinesDeleted
+				}
+				continue
+			}
 
+			hunkStart := hunk.NewStart
+			hunkEnd := hunk.NewStart + hunk.NewCount - 1
 			if overlaps(fnStartLine, fnEndLine, hunkStart, hunkEnd) {
 				touchedFunction = true
 
-				// Count lines within function range
-				overlapStart := maxInt(fnStartLine, hunkStart)
-				overlapEnd := minInt(fnEndLine, hunkEnd)
-				overlapLines := overlapEnd - overlapStart
-
 				// Proportionally attribute added/deleted lines
-				if hunk.NewCount > 0 {
-					ratio := float64(overlapLines) / float64(hunk.NewCount)
-					metrics.LinesAdded += int(float64(hunk.LinesAdded) * ratio)
-					metrics.LinesDeleted += int(float64(hunk.LinesDeleted) * ratio)
-				}
+				overlapLines := minInt(fnEndLine, hunkEnd) - maxInt(fnStartLine, hunkStart) + 1
+				ratio := float64(overlapLines) / float64(hunk.NewCount)
+				metrics.LinesAdded += int(float64(hunk.LinesAdded) * ratio)
+				metrics.LinesDeleted += int(float64(hunk.LinesDeleted) * ratio)
 			}
 		}
 
 		if touchedFunction {
 			metrics.CommitCount++
-			metrics.Authors[commit.Author] = true
+			metrics.Authors[commit.Author]++
+			if metrics.LastCommit.IsZero() || commit.Date.After(metrics.LastCommit) {
+				metrics.LastCommit = commit.Date
+			}
+			if metrics.FirstCommit.IsZero() || commit.Date.Before(metrics.FirstCommit) {
+				metrics.FirstCommit = commit.Date
+			}
 		}
+
+		fnStartLine = lineBeforeCommit(fnStartLine, commit.Hunks, false)
+		fnEndLine = lineBeforeCommit(fnEndLine, commit.Hunks, true)
 	}
 
 	return metrics
 }
 
+// lineBeforeCommit maps a line of the file as a commit left it to the file
+// before the commit. A line the commit changed maps to the first line of the
+// hunk's old side, or its last for the end of a range; a range whose lines
+// were all added comes out with its start after its end.
+func lineBeforeCommit(line int, hunks []HunkData, rangeEnd bool) int {
+	shift := 0
+	for _, hunk := range hunks {
+		newEnd := hunk.NewStart + hunk.NewCount - 1
+		if hunk.NewCount == 0 {
+			newEnd = hunk.NewStart
+		}
+		if line > newEnd {
+			shift += hunk.OldCount - hunk.NewCount
+			continue
+		}
+		if line < hunk.NewStart || hunk.NewCount == 0 {
+			break
+		}
+		// Inside the hunk
+		oldStart := hunk.OldStart
+		if hunk.OldCount == 0 {
+			// Pure addition after line OldStart
+			oldStart++
+		}
+		if rangeEnd {
+			return oldStart + hunk.OldCount - 1
+		}
+		return oldStart
+	}
+	return line + shift
+}
+
+// mainContributors returns up to n authors with the most commits, most first
+func mainContributors(authors map[string]int, n int) []string {
+	names := make([]string, 0, len(authors))
+	for name := range authors {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if authors[names[i]] != authors[names[j]] {
+			return authors[names[i]] > authors[names[j]]
+		}
+		return names[i] < names[j]
+	})
+	if len(names) > n {
+		names = names[:n]
+	}
+	return names
+}
+
 // updateFunctionChurnMetrics updates a function node with churn metrics
 func (gcp *GitChurnProcessor) updateFunctionChurnMetrics(ctx context.Context, fn *ast.Node, metrics *FunctionChurnMetrics) error {
 	// Calculate composite score
@@ -429,6 +519,14 @@ func (gcp *GitChurnProcessor) updateFunctionChurnMetrics(ctx context.Context, fn
 		"churn_density":       density,
 	}
 
+	if !metrics.LastCommit.IsZero() {
+		metadata["churn_first_commit"] = metrics.FirstCommit.Format(time.RFC3339)
+		metadata["churn_last_commit"] = metrics.LastCommit.Format(time.RFC3339)
+	}
+	if contributors := mainContributors(metrics.Authors, gcp.config.MainContributors); len(contributors) > 0 {
+		metadata["churn_main_contributors"] = contributors
+	}
+
 	return gcp.codeGraph.UpdateNodeMetaData(ctx, fn.ID, fn.FileID, metadata)
 }
 
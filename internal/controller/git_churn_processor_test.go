@@ -0,0 +1,87 @@
+package controller
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/armchr/codeapi/internal/config"
+)
+
+func TestLineBeforeCommit(t *testing.T) {
+	modify := HunkData{OldStart: 10, OldCount: 2, NewStart: 10, NewCount: 3}
+	add := HunkData{OldStart: 4, OldCount: 0, NewStart: 5, NewCount: 2}
+	del := HunkData{OldStart: 5, OldCount: 3, NewStart: 4, NewCount: 0}
+
+	tests := []struct {
+		name     string
+		line     int
+		hunks    []HunkData
+		rangeEnd bool
+		want     int
+	}{
+		{"before every hunk", 3, []HunkData{modify}, false, 3},
+		{"after a modification", 20, []HunkData{modify}, false, 19},
+		{"start inside a modification", 11, []HunkData{modify}, false, 10},
+		{"end inside a modification", 11, []HunkData{modify}, true, 11},
+		{"after an addition", 9, []HunkData{add}, false, 7},
+		{"added line as start", 5, []HunkData{add}, false, 5},
+		{"added line as end", 6, []HunkData{add}, true, 4},
+		{"line the deletion follows", 4, []HunkData{del}, false, 4},
+		{"after a deletion", 6, []HunkData{del}, false, 9},
+		{"after several hunks", 30, []HunkData{add, modify}, false, 27},
+	}
+	for _, tt := range tests {
+		if got := lineBeforeCommit(tt.line, tt.hunks, tt.rangeEnd); got != tt.want {
+			t.Errorf("%s: lineBeforeCommit(%d) = %d, want %d", tt.name, tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestAttributeChangesToFunction(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2026, 3, d, 12, 0, 0, 0, time.UTC) }
+	gcp := NewGitChurnProcessor(nil, &config.GitChurnConfig{}, nil)
+
+	// Lines 10-15 of the current file
+	fn := rangedNode(1, "settle", 9, 0, 14, 1)
+	diff := &FileDiffData{Commits: []CommitDiffData{
+		// Two lines added at the top move the function down
+		{SHA: "c3", Author: "alice", Date: day(4), Hunks: []HunkData{{OldStart: 0, OldCount: 0, NewStart: 1, NewCount: 2, LinesAdded: 2}}},
+		// Edits the body, then at lines 8-13
+		{SHA: "c2", Author: "bob", Date: day(3), Hunks: []HunkData{{OldStart: 10, OldCount: 2, NewStart: 10, NewCount: 3, LinesAdded: 2, LinesDeleted: 1}}},
+		// Creates the file, the function at lines 8-12
+		{SHA: "c1", Author: "alice", Date: day(2), Hunks: []HunkData{{OldStart: 0, OldCount: 0, NewStart: 1, NewCount: 20, LinesAdded: 20}}},
+		// Older than the function: never attributed
+		{SHA: "c0", Author: "carol", Date: day(1), Hunks: []HunkData{{OldStart: 8, OldCount: 1, NewStart: 8, NewCount: 1, LinesAdded: 1, LinesDeleted: 1}}},
+	}}
+
+	got := gcp.attributeChangesToFunction(fn, diff)
+	want := &FunctionChurnMetrics{
+		LinesAdded:   7,
+		LinesDeleted: 1,
+		CommitCount:  2,
+		Authors:      map[string]int{"alice": 1, "bob": 1},
+		FirstCommit:  day(2),
+		LastCommit:   day(3),
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("attributeChangesToFunction = %+v, want %+v", got, want)
+	}
+}
+
+func TestMainContributors(t *testing.T) {
+	authors := map[string]int{"dana": 2, "alice": 5, "bob": 2, "carol": 1}
+	tests := []struct {
+		n    int
+		want []string
+	}{
+		{3, []string{"alice", "bob", "dana"}},
+		{1, []string{"alice"}},
+		{10, []string{"alice", "bob", "dana", "carol"}},
+	}
+	for _, tt := range tests {
+		if got := mainContributors(authors, tt.n); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("mainContributors(%d) = %v, want %v", tt.n, got, tt.want)
+		}
+	}
+}
@@ -32,7 +32,7 @@ type FileChurnData struct {
 	LinesAdded   int
 	LinesDeleted int
 	CommitCount  int
-	Authors      map[string]bool
+	Authors      map[string]int // commits per author
 	FirstCommit  time.Time
 	LastCommit   time.Time
 }
@@ -226,7 +226,7 @@ func (glc *GitLogCache) aggregateFileMetrics(
 	metrics, exists := glc.fileMetrics[filePath]
 	if !exists {
 		metrics = &FileChurnData{
-			Authors:     make(map[string]bool),
+			Authors:     make(map[string]int),
 			FirstCommit: commit.Date,
 			LastCommit:  commit.Date,
 		}
@@ -236,7 +236,7 @@ func (glc *GitLogCache) aggregateFileMetrics(
 	metrics.LinesAdded += added
 	metrics.LinesDeleted += deleted
 	metrics.CommitCount++
-	metrics.Authors[commit.Author] = true
+	metrics.Authors[commit.Author]++
 
 	if commit.Date.Before(metrics.FirstCommit) {
 		metrics.FirstCommit = commit.Date
@@ -396,7 +396,7 @@ func (glc *GitLogCache) BuildDiffData(ctx context.Context, filePath string) (*Fi
 
 // FileDiffData holds detailed diff data for function attribution
 type FileDiffData struct {
-	Commits []CommitDiffData
+	Commits []CommitDiffData // newest first, as git log lists them
 }
 
 // CommitDiffData holds diff data for a single commit
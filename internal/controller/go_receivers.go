@@ -0,0 +1,238 @@
+package controller
+
+import (
+	"context"
+	"strings"
+
+	"github.com/armchr/codeapi/internal/config"
+	"github.com/armchr/codeapi/internal/model/ast"
+	"github.com/armchr/codeapi/internal/parse"
+
+	"go.uber.org/zap"
+)
+
+// maxEmbedDepth bounds how deep method and field promotion is followed
+const maxEmbedDepth = 8
+
+// goType is what post-processing knows of a Go named type: its methods and
+// the types of its embedded and named fields
+type goType struct {
+	methods map[string]ast.NodeID
+	embeds  []string
+	fields  map[string]string // field name -> type name
+}
+
+// goTypeLookup returns the named type called name, or nil if unknown
+type goTypeLookup func(name string) *goType
+
+// goPromoted looks typeName and the types it embeds up breadth first, the
+// way Go promotes fields and methods: the shallowest match wins, and several
+// matches at the same depth make the selector ambiguous.
+func goPromoted[T any](typeName string, lookup goTypeLookup, pick func(t *goType) (T, bool)) (T, bool) {
+	var zero T
+	level := []string{typeName}
+	seen := map[string]bool{typeName: true}
+	for depth := 0; len(level) > 0 && depth <= maxEmbedDepth; depth++ {
+		var found []T
+		var next []string
+		for _, name := range level {
+			t := lookup(name)
+			if t == nil {
+				continue
+			}
+			if v, ok := pick(t); ok {
+				found = append(found, v)
+			}
+			for _, embedded := range t.embeds {
+				if !seen[embedded] {
+					seen[embedded] = true
+					next = append(next, embedded)
+				}
+			}
+		}
+		switch len(found) {
+		case 0:
+			level = next
+		case 1:
+			return found[0], true
+		default:
+			return zero, false
+		}
+	}
+	return zero, false
+}
+
+// findGoMethod returns the method a value of typeName calls as name, which
+// may be promoted from an embedded type
+func findGoMethod(typeName, name string, lookup goTypeLookup) (ast.NodeID, bool) {
+	return goPromoted(typeName, lookup, func(t *goType) (ast.NodeID, bool) {
+		id, ok := t.methods[name]
+		return id, ok
+	})
+}
+
+// findGoField returns the type of field name of typeName. An embedded type
+// is a field named after the type.
+func findGoField(typeName, name string, lookup goTypeLookup) string {
+	fieldType, _ := goPromoted(typeName, lookup, func(t *goType) (string, bool) {
+		if fieldType, ok := t.fields[name]; ok {
+			return fieldType, true
+		}
+		for _, embedded := range t.embeds {
+			if embedded == name {
+				return embedded, true
+			}
+		}
+		return "", false
+	})
+	return fieldType
+}
+
+// goCallTarget resolves a call like s.store.Get made in a method whose
+// receiver is s, walking the fields of the receiver type to the method
+func goCallTarget(selector string, owner *ast.Node, lookup goTypeLookup) (ast.NodeID, bool) {
+	parts := strings.Split(selector, ".")
+	if len(parts) < 2 {
+		return ast.InvalidNodeID, false
+	}
+	for _, part := range parts {
+		if !isGoIdentifier(part) {
+			return ast.InvalidNodeID, false
+		}
+	}
+
+	receiver, _ := owner.MetaData[parse.MetaReceiver].(string)
+	typeName, _ := owner.MetaData[parse.MetaReceiverType].(string)
+	if receiver == "" || receiver == "_" || typeName == "" || parts[0] != receiver {
+		return ast.InvalidNodeID, false
+	}
+	for _, field := range parts[1 : len(parts)-1] {
+		if typeName = findGoField(typeName, field, lookup); typeName == "" {
+			return ast.InvalidNodeID, false
+		}
+	}
+	return findGoMethod(typeName, parts[len(parts)-1], lookup)
+}
+
+func isGoIdentifier(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i, r := range s {
+		if !(r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r > 127 || i > 0 && r >= '0' && r <= '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// goTypeResolver loads the named types of a Go package from the code graph
+// as calls need them
+type goTypeResolver struct {
+	pp      *PostProcessor
+	repo    string
+	module  string
+	types   map[string]*goType // nil for names that are not repository types
+	modules map[int32]string   // package of each file seen
+}
+
+func (pp *PostProcessor) newGoTypeResolver(ctx context.Context, repo *config.Repository, fileID int32) *goTypeResolver {
+	module, err := pp.codeGraph.GetModuleName(ctx, fileID)
+	if err != nil {
+		return nil
+	}
+	return &goTypeResolver{
+		pp:      pp,
+		repo:    repo.Name,
+		module:  module,
+		types:   make(map[string]*goType),
+		modules: map[int32]string{fileID: module},
+	}
+}
+
+// lookup returns a goTypeLookup bound to ctx
+func (r *goTypeResolver) lookup(ctx context.Context) goTypeLookup {
+	return func(name string) *goType {
+		t, ok := r.types[name]
+		if !ok {
+			t = r.load(ctx, name)
+			r.types[name] = t
+		}
+		return t
+	}
+}
+
+// load reads the type called name, preferring the package being resolved.
+// A type of another package is only used when its name is unique in the
+// repository, since the graph does not record which package an embedded
+// pkg.Type refers to.
+func (r *goTypeResolver) load(ctx context.Context, name string) *goType {
+	classes, err := r.pp.codeGraph.FindClassesByNameInRepo(ctx, name, r.repo)
+	if err != nil || len(classes) == 0 {
+		return nil
+	}
+	var local []*ast.Node
+	for _, class := range classes {
+		if r.moduleOf(ctx, class.FileID) == r.module {
+			local = append(local, class)
+		}
+	}
+	if len(local) > 0 {
+		classes = local
+	}
+	if len(classes) != 1 {
+		return nil
+	}
+
+	class := classes[0]
+	t := &goType{
+		methods: make(map[string]ast.NodeID),
+		embeds:  metadataStrings(class.MetaData[parse.MetaEmbeds]),
+		fields:  make(map[string]string),
+	}
+	for _, field := range metadataStrings(class.MetaData[parse.MetaFieldTypes]) {
+		if fieldName, fieldType, ok := strings.Cut(field, ":"); ok {
+			t.fields[fieldName] = fieldType
+		}
+	}
+	methods, _ := r.pp.codeGraph.GetMethodsOfClass(ctx, class.ID)
+	for _, method := range methods {
+		t.methods[method.Name] = method.ID
+	}
+	return t
+}
+
+func (r *goTypeResolver) moduleOf(ctx context.Context, fileID int32) string {
+	module, ok := r.modules[fileID]
+	if !ok {
+		module, _ = r.pp.codeGraph.GetModuleName(ctx, fileID)
+		r.modules[fileID] = module
+	}
+	return module
+}
+
+// resolveGoMethodCall links a call the language server did not resolve,
+// made on the receiver of owner or one of its fields, to the method it
+// calls, possibly promoted from an embedded struct. It reports whether a
+// target was found.
+func (pp *PostProcessor) resolveGoMethodCall(ctx context.Context, call, owner *ast.Node, goTypes *goTypeResolver) bool {
+	selector, _ := call.MetaData[parse.MetaSelector].(string)
+	if selector == "" {
+		return false
+	}
+	target, ok := goCallTarget(selector, owner, goTypes.lookup(ctx))
+	if !ok {
+		return false
+	}
+	if err := pp.codeGraph.CreateCallsFunctionRelation(ctx, call.ID, target, call.FileID); err != nil {
+		pp.logger.Error("Failed to create CALLS_FUNCTION relation for Go method",
+			zap.Int64("callId", int64(call.ID)),
+			zap.Error(err))
+		return false
+	}
+	pp.linkArguments(ctx, call, target)
+	pp.logger.Info("Resolved call through receiver type",
+		zap.String("selector", selector),
+		zap.Int64("targetFunctionId", int64(target)))
+	return true
+}
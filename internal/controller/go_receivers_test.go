@@ -0,0 +1,96 @@
+package controller
+
+import (
+	"testing"
+
+	"github.com/armchr/codeapi/internal/model/ast"
+	"github.com/armchr/codeapi/internal/parse"
+)
+
+// Types of
+//
+//	type Base struct{}                 func (b *Base) Close(); func (b *Base) ID()
+//	type Logger struct{}               func (l Logger) Log(); func (l Logger) Close()
+//	type Handler struct{ Base }        func (h *Handler) Serve()
+//	type Store struct{ *Base }         func (s *Store) Get()
+//	type Server struct {               func (s *Server) Start()
+//		*Handler
+//		Logger
+//		store *Store
+//		peers []*Peer
+//	}
+//	type Loop struct{ *Loop }
+var goFixture = map[string]*goType{
+	"Base":    {methods: map[string]ast.NodeID{"Close": 1, "ID": 2}},
+	"Logger":  {methods: map[string]ast.NodeID{"Log": 3, "Close": 4}},
+	"Handler": {methods: map[string]ast.NodeID{"Serve": 5}, embeds: []string{"Base"}},
+	"Store":   {methods: map[string]ast.NodeID{"Get": 6}, embeds: []string{"Base"}},
+	"Server": {
+		methods: map[string]ast.NodeID{"Start": 7},
+		embeds:  []string{"Handler", "Logger"},
+		fields:  map[string]string{"store": "Store"},
+	},
+	"Loop": {embeds: []string{"Loop"}},
+}
+
+func goFixtureLookup(name string) *goType {
+	return goFixture[name]
+}
+
+func TestGoCallTarget(t *testing.T) {
+	method := &ast.Node{Name: "Start", MetaData: map[string]any{
+		parse.MetaReceiver:     "s",
+		parse.MetaReceiverType: "Server",
+	}}
+
+	tests := []struct {
+		name     string
+		selector string
+		want     ast.NodeID
+	}{
+		{"own method", "s.Start", 7},
+		{"promoted from embedded pointer", "s.Serve", 5},
+		{"promoted from embedded value", "s.Log", 3},
+		{"promoted two levels deep", "s.ID", 2},
+		{"shallower embedding wins", "s.Close", 4},
+		{"through field", "s.store.Get", 6},
+		{"promoted through field", "s.store.ID", 2},
+		{"embedded type as field", "s.Handler.Serve", 5},
+		{"field of embedded type", "s.Handler.Base.Close", 1},
+		{"unknown method", "s.Stop", ast.InvalidNodeID},
+		{"unknown field", "s.cache.Get", ast.InvalidNodeID},
+		{"not the receiver", "srv.Start", ast.InvalidNodeID},
+		{"package function", "fmt.Println", ast.InvalidNodeID},
+		{"call in chain", "s.store().Get", ast.InvalidNodeID},
+		{"no selector", "Start", ast.InvalidNodeID},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := goCallTarget(tt.selector, method, goFixtureLookup)
+			if ok != (tt.want != ast.InvalidNodeID) || got != tt.want {
+				t.Errorf("goCallTarget(%q) = %d, %v, want %d", tt.selector, got, ok, tt.want)
+			}
+		})
+	}
+}
+
+func TestGoPromotionAmbiguity(t *testing.T) {
+	// Logger and Base both have Close, so a type embedding both at the same
+	// depth has an ambiguous Close
+	types := map[string]*goType{
+		"Base":   goFixture["Base"],
+		"Logger": goFixture["Logger"],
+		"Both":   {embeds: []string{"Logger", "Base"}},
+	}
+	lookup := func(name string) *goType { return types[name] }
+
+	if id, ok := findGoMethod("Both", "Close", lookup); ok {
+		t.Errorf("ambiguous selector resolved to %d", id)
+	}
+	if id, ok := findGoMethod("Both", "Log", lookup); !ok || id != 3 {
+		t.Errorf("findGoMethod(Both, Log) = %d, %v, want 3", id, ok)
+	}
+	if _, ok := findGoMethod("Loop", "Close", goFixtureLookup); ok {
+		t.Error("self-embedding type resolved a method it does not have")
+	}
+}
@@ -1,14 +1,20 @@
 package controller
 
 import (
+	"github.com/armchr/codeapi/internal/budget"
 	"github.com/armchr/codeapi/internal/config"
 	"github.com/armchr/codeapi/internal/db"
+	"github.com/armchr/codeapi/internal/service/audit"
+	"github.com/armchr/codeapi/internal/telemetry"
+	"github.com/armchr/codeapi/internal/tracing"
 	"github.com/armchr/codeapi/internal/util"
 	"context"
 	"fmt"
 	"strings"
 	"sync"
+	"time"
 
+	"go.opentelemetry.io/otel/attribute"
 	"go.uber.org/zap"
 )
 
@@ -19,6 +25,9 @@ type IndexBuilder struct {
 	processors      []FileProcessor
 	logger          *zap.Logger
 	fileVersionRepo *db.FileVersionRepository
+	reprocessDone   bool
+	progress        ProgressListener
+	lastStats       *BuildStats
 }
 
 // NewIndexBuilder creates a new index builder with the specified processors
@@ -31,13 +40,42 @@ func NewIndexBuilder(config *config.Config, processors []FileProcessor, fileVers
 	}
 }
 
+// SetReprocessDone makes subsequent builds run files whose version is already
+// marked done, for processors added after the original build
+func (ib *IndexBuilder) SetReprocessDone(reprocess bool) {
+	ib.reprocessDone = reprocess
+}
+
+// SetProgressListener registers a listener for progress events of
+// subsequent builds. Counting the files up front costs an extra directory
+// walk, so it is only done when a listener is set.
+func (ib *IndexBuilder) SetProgressListener(listener ProgressListener) {
+	ib.progress = listener
+}
+
+// LastStats returns the statistics of the most recent build, or nil before
+// the first build completes
+func (ib *IndexBuilder) LastStats() *BuildStats {
+	return ib.lastStats
+}
+
+func (ib *IndexBuilder) phaseStarted(repo *config.Repository, phase string) {
+	if ib.progress != nil {
+		ib.progress.PhaseStarted(repo.Name, phase)
+	}
+}
+
 // BuildIndex processes a repository through all registered processors
 func (ib *IndexBuilder) BuildIndex(ctx context.Context, repo *config.Repository) error {
 	return ib.BuildIndexWithGitInfo(ctx, repo, false, nil)
 }
 
 // BuildIndexWithGitInfo processes a repository with optional git HEAD optimization
-func (ib *IndexBuilder) BuildIndexWithGitInfo(ctx context.Context, repo *config.Repository, useHead bool, gitInfo *util.GitInfo) error {
+func (ib *IndexBuilder) BuildIndexWithGitInfo(ctx context.Context, repo *config.Repository, useHead bool, gitInfo *util.GitInfo) (err error) {
+	ctx, span := tracing.Start(ctx, "index.build",
+		attribute.String("repo", repo.Name), attribute.Bool("use_head", useHead))
+	defer func() { tracing.End(span, err) }()
+
 	if len(ib.processors) == 0 {
 		ib.logger.Warn("No processors registered, skipping index building",
 			zap.String("repo_name", repo.Name))
@@ -64,24 +102,59 @@ func (ib *IndexBuilder) BuildIndexWithGitInfo(ctx context.Context, repo *config.
 			zap.Int("modified_files", len(gitInfo.ModifiedFiles)))
 	}
 
+	// Two builds of a repository would interleave their file version updates
+	lock, err := ib.fileVersionRepo.Lock(audit.OpIndexBuild)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if releaseErr := lock.Release(); releaseErr != nil {
+			ib.logger.Warn("Failed to release repository lock",
+				zap.String("repo_name", repo.Name), zap.Error(releaseErr))
+		}
+	}()
+
+	start := time.Now()
+	recorder := newBuildRecorder(repo.Name, ib.processors)
+
 	// Phase 0: Initialize all processors
+	ib.phaseStarted(repo, PhaseInit)
 	for _, processor := range ib.processors {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 		if err := processor.Init(ctx, repo); err != nil {
 			return fmt.Errorf("failed to initialize processor %s: %w", processor.Name(), err)
 		}
 	}
 
+	if ib.progress != nil {
+		ib.phaseStarted(repo, PhaseScan)
+		total := countFiles(repo)
+		recorder.stats.FilesTotal = total
+		ib.progress.FilesCounted(repo.Name, total)
+	}
+
 	// Phase 1: Process all files in parallel
-	err := ib.processFiles(ctx, repo, useHead, gitInfo)
+	ib.phaseStarted(repo, PhaseFiles)
+	err = ib.processFiles(ctx, repo, useHead, gitInfo, recorder)
 	if err != nil {
+		// Files finished before a cancellation are recorded as done and
+		// skipped by the next build, so the statistics cover them
+		ib.lastStats = recorder.finish(ib.processors, time.Since(start))
+		telemetry.RecordIndexBuild(ib.lastStats.Duration, ib.lastStats.FilesByLanguage, err)
 		return fmt.Errorf("failed to process files for repository %s: %w", repo.Name, err)
 	}
 
 	// Phase 2: Run post-processing steps in parallel
-	err = ib.postProcessRepository(ctx, repo)
+	ib.phaseStarted(repo, PhasePostProcess)
+	err = ib.postProcessRepository(ctx, repo, recorder)
+	ib.lastStats = recorder.finish(ib.processors, time.Since(start))
+	telemetry.RecordIndexBuild(ib.lastStats.Duration, ib.lastStats.FilesByLanguage, err)
 	if err != nil {
 		return fmt.Errorf("failed to post-process repository %s: %w", repo.Name, err)
 	}
+	ib.checkQuality(ctx, repo, ib.lastStats)
 
 	ib.logger.Info("Completed index building for repository",
 		zap.String("repo_name", repo.Name))
@@ -89,7 +162,7 @@ func (ib *IndexBuilder) BuildIndexWithGitInfo(ctx context.Context, repo *config.
 }
 
 // processFiles walks the repository directory and processes each file through all processors in parallel
-func (ib *IndexBuilder) processFiles(ctx context.Context, repo *config.Repository, useHead bool, gitInfo *util.GitInfo) error {
+func (ib *IndexBuilder) processFiles(ctx context.Context, repo *config.Repository, useHead bool, gitInfo *util.GitInfo, recorder *buildRecorder) error {
 	ib.logger.Info("Processing files",
 		zap.String("repo_name", repo.Name),
 		zap.String("path", repo.Path))
@@ -110,6 +183,8 @@ func (ib *IndexBuilder) processFiles(ctx context.Context, repo *config.Repositor
 		numThreads = 2 // default
 	}
 
+	sizeLimits := ib.config.IndexBuilding.GetDefaults()
+
 	// Define the skip function for WalkDirTree
 	skipFunc := func(path string, isDir bool) bool {
 		// Skip hidden directories and common directories to ignore
@@ -143,6 +218,29 @@ func (ib *IndexBuilder) processFiles(ctx context.Context, repo *config.Repositor
 			return nil // Continue processing other files
 		}
 
+		if ib.progress != nil {
+			relPath, _ := util.GetRelativePath(repo.Path, filePath)
+			defer ib.progress.FileDone(repo.Name, relPath)
+		}
+
+		// Generated files can be tens of megabytes; skip them before reading
+		if tooLargeOnDisk(sizeLimits, filePath) {
+			ib.logger.Info("Skipping file over size limit",
+				zap.String("path", filePath),
+				zap.Int("max_file_size_kb", sizeLimits.MaxFileSizeKB))
+			recorder.fileOversized()
+			return nil
+		}
+
+		// The file holds a CPU slot of the shared budget while it goes through
+		// the processors, so concurrent builds and ad-hoc indexing together
+		// stay within resources.max_cpu_workers
+		release, err := budget.Acquire(ctx, budget.CPU)
+		if err != nil {
+			return err
+		}
+		defer release()
+
 		// Read file content once, centrally
 		// Use optimized reading if useHead is enabled (read from git HEAD for unmodified files)
 		content, err := util.ReadFileOptimized(repo.Path, filePath, useHead, gitInfo)
@@ -158,6 +256,21 @@ func (ib *IndexBuilder) processFiles(ctx context.Context, repo *config.Repositor
 			return nil // Continue processing other files
 		}
 
+		// The HEAD version may differ in size from the file on disk
+		content, oversized := limitFileSize(sizeLimits, content)
+		if oversized {
+			recorder.fileOversized()
+			if content == nil {
+				ib.logger.Info("Skipping file over size limit",
+					zap.String("path", filePath),
+					zap.Int("max_file_size_kb", sizeLimits.MaxFileSizeKB))
+				return nil
+			}
+			ib.logger.Info("Truncated file over size limit",
+				zap.String("path", filePath),
+				zap.Int("max_file_size_kb", sizeLimits.MaxFileSizeKB))
+		}
+
 		// Track source of file content for logging
 		if useHead && gitInfo != nil && gitInfo.IsGitRepo {
 			mu.Lock()
@@ -170,7 +283,7 @@ func (ib *IndexBuilder) processFiles(ctx context.Context, repo *config.Repositor
 		}
 
 		// Generate FileContext with FileID from MySQL
-		fileCtx, err := ib.createFileContext(repo.Path, filePath, content, useHead, gitInfo)
+		fileCtx, err := ib.createFileContext(repo, filePath, content, useHead, gitInfo)
 		if err != nil {
 			ib.logger.Error("Failed to create file context", zap.String("path", filePath), zap.Error(err))
 			return nil // Continue processing other files
@@ -179,13 +292,14 @@ func (ib *IndexBuilder) processFiles(ctx context.Context, repo *config.Repositor
 		// Check if file was already fully processed (same SHA/commit, status="done")
 		// This optimization skips reprocessing unchanged files
 		existingFile, err := ib.fileVersionRepo.GetFileByID(fileCtx.FileID)
-		if err == nil && existingFile.Status == "done" {
+		if err == nil && existingFile.Status == "done" && !ib.reprocessDone {
 			// File already fully processed with this exact SHA and commit
 			ib.logger.Debug("Skipping already processed file",
 				zap.String("path", fileCtx.RelativePath),
 				zap.Int32("file_id", fileCtx.FileID),
 				zap.String("sha", fileCtx.FileSHA),
 				zap.String("status", existingFile.Status))
+			recorder.fileDone(fileCtx.Language, false)
 			return nil // Skip this file
 		}
 
@@ -207,8 +321,19 @@ func (ib *IndexBuilder) processFiles(ctx context.Context, repo *config.Repositor
 			wg.Wait()
 		*/
 
-		for _, processor := range ib.processors {
-			err := processor.ProcessFile(ctx, repo, fileCtx)
+		fileSpanCtx, fileSpan := tracing.Start(ctx, "index.file",
+			attribute.String("repo", repo.Name), attribute.String("path", fileCtx.RelativePath))
+		defer fileSpan.End()
+
+		for i, processor := range ib.processors {
+			if ctx.Err() != nil {
+				break
+			}
+			processorStart := time.Now()
+			processorCtx, span := tracing.Start(fileSpanCtx, "processor.file", attribute.String("processor", processor.Name()))
+			err := processor.ProcessFile(processorCtx, repo, fileCtx)
+			tracing.End(span, err)
+			recorder.addFileTime(i, time.Since(processorStart))
 			if err != nil {
 				ib.logger.Error("Processor failed to process file",
 					zap.String("processor", processor.Name()),
@@ -227,6 +352,12 @@ func (ib *IndexBuilder) processFiles(ctx context.Context, repo *config.Repositor
 			}
 		}
 
+		// A file interrupted by a cancellation keeps the statuses of the
+		// processors that finished it and is processed again by the next build
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
 		// Mark file as fully processed (all processors done)
 		if err := ib.fileVersionRepo.UpdateStatus(fileCtx.FileID, "done"); err != nil {
 			ib.logger.Warn("Failed to update final status",
@@ -238,13 +369,20 @@ func (ib *IndexBuilder) processFiles(ctx context.Context, repo *config.Repositor
 		mu.Lock()
 		fileCount++
 		mu.Unlock()
+		recorder.fileDone(fileCtx.Language, true)
 
 		return nil
 	}
 
 	// Walk the directory tree using the utility function
-	err := util.WalkDirTree(repo.Path, walkFunc, skipFunc, ib.logger, gcThreshold, numThreads)
+	err := util.WalkDirTree(ctx, repo.Path, walkFunc, skipFunc, ib.logger, gcThreshold, numThreads)
 	if err != nil {
+		if ctx.Err() != nil {
+			ib.logger.Info("File processing cancelled",
+				zap.String("repo_name", repo.Name),
+				zap.Int("files_processed", fileCount))
+			return err
+		}
 		return fmt.Errorf("failed to walk directory tree: %w", err)
 	}
 
@@ -264,23 +402,31 @@ func (ib *IndexBuilder) processFiles(ctx context.Context, repo *config.Repositor
 }
 
 // postProcessRepository runs post-processing steps for all processors in parallel
-func (ib *IndexBuilder) postProcessRepository(ctx context.Context, repo *config.Repository) error {
+func (ib *IndexBuilder) postProcessRepository(ctx context.Context, repo *config.Repository, recorder *buildRecorder) error {
 	ib.logger.Info("Running post-processing steps",
 		zap.String("repo_name", repo.Name))
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 
 	var wg sync.WaitGroup
 	errChan := make(chan error, len(ib.processors))
 
 	// Run each processor's post-processing in parallel
-	for _, processor := range ib.processors {
+	for i, processor := range ib.processors {
 		wg.Add(1)
-		go func(p FileProcessor) {
+		go func(i int, p FileProcessor) {
 			defer wg.Done()
+			start := time.Now()
+			defer func() { recorder.setPostProcessTime(i, time.Since(start)) }()
 			ib.logger.Info("Starting post-processing",
 				zap.String("processor", p.Name()),
 				zap.String("repo_name", repo.Name))
 
-			if err := p.PostProcess(ctx, repo); err != nil {
+			ctx, span := tracing.Start(ctx, "processor.post_process", attribute.String("processor", p.Name()))
+			err := p.PostProcess(ctx, repo)
+			tracing.End(span, err)
+			if err != nil {
 				ib.logger.Error("Post-processing failed",
 					zap.String("processor", p.Name()),
 					zap.String("repo_name", repo.Name),
@@ -292,12 +438,15 @@ func (ib *IndexBuilder) postProcessRepository(ctx context.Context, repo *config.
 			ib.logger.Info("Completed post-processing",
 				zap.String("processor", p.Name()),
 				zap.String("repo_name", repo.Name))
-		}(processor)
+		}(i, processor)
 	}
 
 	// Wait for all post-processing to complete
 	wg.Wait()
 	close(errChan)
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 
 	// Check if any errors occurred
 	var errors []error
@@ -315,10 +464,20 @@ func (ib *IndexBuilder) postProcessRepository(ctx context.Context, repo *config.
 	return nil
 }
 
-// createFileContext generates a FileContext with FileID from MySQL
-func (ib *IndexBuilder) createFileContext(repoPath, filePath string, content []byte, useHead bool, gitInfo *util.GitInfo) (*FileContext, error) {
-	// Calculate file SHA256
-	fileSHA := util.CalculateFileSHA256(content)
+// fileIdentity is what a file version is recorded under in the file
+// version store
+type fileIdentity struct {
+	FileSHA      string
+	RelativePath string
+	CommitID     *string
+	Ephemeral    bool
+}
+
+// resolveFileIdentity computes the identity of a file's current content. A
+// file is ephemeral when it is modified, untracked or outside a git repository;
+// otherwise it is tied to its last commit (or HEAD in useHead mode).
+func resolveFileIdentity(repo *config.Repository, filePath string, content []byte, useHead bool, gitInfo *util.GitInfo, logger *zap.Logger) (*fileIdentity, error) {
+	repoPath := repo.Path
 
 	// Get relative path
 	relativePath, err := util.GetRelativePath(repoPath, filePath)
@@ -326,37 +485,48 @@ func (ib *IndexBuilder) createFileContext(repoPath, filePath string, content []b
 		return nil, fmt.Errorf("failed to get relative path: %w", err)
 	}
 
-	// Determine if file is ephemeral and get commit ID
-	var commitID *string
-	var ephemeral bool
+	id := &fileIdentity{
+		FileSHA:      util.CalculateFileSHA256(content),
+		RelativePath: relativePath,
+	}
 
 	if gitInfo != nil && gitInfo.IsGitRepo {
 		// Check if file is modified (ephemeral)
-		ephemeral = util.IsFileModified(gitInfo, filePath)
+		id.Ephemeral = util.IsFileModified(gitInfo, filePath)
 
-		if !ephemeral && !useHead {
+		if !id.Ephemeral && !useHead {
 			// File is not modified, get its last commit
 			lastCommit, err := util.GetLastCommitForFile(repoPath, filePath)
 			if err != nil {
 				// File might be untracked
-				ib.logger.Debug("Could not get last commit for file, treating as ephemeral",
+				logger.Debug("Could not get last commit for file, treating as ephemeral",
 					zap.String("path", relativePath),
 					zap.Error(err))
-				ephemeral = true
+				id.Ephemeral = true
 			} else {
-				commitID = &lastCommit
+				id.CommitID = &lastCommit
 			}
-		} else if useHead && !ephemeral {
+		} else if useHead && !id.Ephemeral {
 			// Using HEAD mode and file is unmodified, use HEAD commit
-			commitID = &gitInfo.HeadCommitSHA
+			id.CommitID = &gitInfo.HeadCommitSHA
 		}
 	} else {
 		// Not a git repo, all files are ephemeral
-		ephemeral = true
+		id.Ephemeral = true
+	}
+
+	return id, nil
+}
+
+// createFileContext generates a FileContext with FileID from MySQL
+func (ib *IndexBuilder) createFileContext(repo *config.Repository, filePath string, content []byte, useHead bool, gitInfo *util.GitInfo) (*FileContext, error) {
+	id, err := resolveFileIdentity(repo, filePath, content, useHead, gitInfo, ib.logger)
+	if err != nil {
+		return nil, err
 	}
 
 	// Get or create FileID from MySQL
-	fileID, err := ib.fileVersionRepo.GetOrCreateFileID(fileSHA, relativePath, ephemeral, commitID)
+	fileID, err := ib.fileVersionRepo.GetOrCreateFileID(id.FileSHA, id.RelativePath, id.Ephemeral, id.CommitID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get or create FileID: %w", err)
 	}
@@ -364,10 +534,11 @@ func (ib *IndexBuilder) createFileContext(repoPath, filePath string, content []b
 	return &FileContext{
 		FileID:       fileID,
 		FilePath:     filePath,
-		RelativePath: relativePath,
+		RelativePath: id.RelativePath,
 		Content:      content,
-		FileSHA:      fileSHA,
-		CommitID:     commitID,
-		Ephemeral:    ephemeral,
+		FileSHA:      id.FileSHA,
+		CommitID:     id.CommitID,
+		Ephemeral:    id.Ephemeral,
+		Language:     fileLanguage(repo, id.RelativePath, content),
 	}, nil
 }
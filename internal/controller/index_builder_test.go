@@ -0,0 +1,154 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/armchr/codeapi/internal/config"
+	"github.com/armchr/codeapi/internal/db"
+
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.uber.org/zap"
+)
+
+func TestIndexBuilderTracing(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	logger := zap.NewNop()
+	conn := newBackupTestDB(t)
+	versions, err := db.NewFileVersionRepository(conn.GetDB(), "api", logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo := &config.Repository{Name: "api", Path: dir, Language: "go"}
+	builder := NewIndexBuilder(&config.Config{}, []FileProcessor{&countingProcessor{}}, versions, logger)
+	if err := builder.BuildIndex(context.Background(), repo); err != nil {
+		t.Fatalf("BuildIndex: %v", err)
+	}
+
+	spans := make(map[string]sdktrace.ReadOnlySpan)
+	for _, span := range recorder.Ended() {
+		spans[span.Name()] = span
+	}
+	for _, name := range []string{"index.build", "index.file", "processor.file", "processor.post_process"} {
+		if _, ok := spans[name]; !ok {
+			t.Fatalf("no %s span among %d ended spans", name, len(recorder.Ended()))
+		}
+	}
+
+	parentOf := func(name string) string {
+		parent := spans[name].Parent().SpanID()
+		for n, s := range spans {
+			if s.SpanContext().SpanID() == parent {
+				return n
+			}
+		}
+		return ""
+	}
+	for child, parent := range map[string]string{
+		"index.file":             "index.build",
+		"processor.file":         "index.file",
+		"processor.post_process": "index.build",
+	} {
+		if got := parentOf(child); got != parent {
+			t.Errorf("parent of %s = %q, want %q", child, got, parent)
+		}
+	}
+}
+
+// cancellingProcessor cancels the build while processing the first file it
+// is given, as an interrupt arriving mid-file would
+type cancellingProcessor struct {
+	countingProcessor
+	cancel        context.CancelFunc
+	postProcessed bool
+}
+
+func (p *cancellingProcessor) ProcessFile(ctx context.Context, repo *config.Repository, fileCtx *FileContext) error {
+	p.cancel()
+	return p.countingProcessor.ProcessFile(ctx, repo, fileCtx)
+}
+
+func (p *cancellingProcessor) PostProcess(ctx context.Context, repo *config.Repository) error {
+	p.postProcessed = true
+	return nil
+}
+
+func TestIndexBuilderCancellation(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	logger := zap.NewNop()
+	conn := newBackupTestDB(t)
+	versions, err := db.NewFileVersionRepository(conn.GetDB(), "api", logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo := &config.Repository{Name: "api", Path: dir, Language: "go"}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	first := &cancellingProcessor{cancel: cancel}
+	second := &countingProcessor{}
+	builder := NewIndexBuilder(&config.Config{}, []FileProcessor{first, second}, versions, logger)
+
+	err = builder.BuildIndex(ctx, repo)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("BuildIndex = %v, want context.Canceled", err)
+	}
+	if second.files != 0 {
+		t.Errorf("processor after the cancellation saw %d file(s)", second.files)
+	}
+	if first.postProcessed {
+		t.Error("post-processing ran after the cancellation")
+	}
+	if builder.LastStats() == nil {
+		t.Error("no statistics for the cancelled build")
+	}
+
+	// The interrupted file keeps the status of the processor that finished
+	// it and is not marked done, so the next build processes it again
+	files, err := versions.GetFilesByPath("main.go")
+	if err != nil || len(files) != 1 {
+		t.Fatalf("GetFilesByPath = %v, %v", files, err)
+	}
+	if files[0].Status != "Counting_done" {
+		t.Errorf("status = %q, want Counting_done", files[0].Status)
+	}
+}
+
+func TestIndexBuilderRepoLock(t *testing.T) {
+	dir := t.TempDir()
+	logger := zap.NewNop()
+	conn := newBackupTestDB(t)
+	versions, err := db.NewFileVersionRepository(conn.GetDB(), "api", logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lock, err := versions.Lock("clean")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lock.Release()
+
+	processor := &countingProcessor{}
+	builder := NewIndexBuilder(&config.Config{}, []FileProcessor{processor}, versions, logger)
+	err = builder.BuildIndex(context.Background(), &config.Repository{Name: "api", Path: dir, Language: "go"})
+	if !errors.Is(err, db.ErrRepoLocked) {
+		t.Fatalf("BuildIndex while cleaning = %v, want ErrRepoLocked", err)
+	}
+}
@@ -0,0 +1,117 @@
+package controller
+
+import (
+	"context"
+	"strings"
+	"unicode"
+
+	"github.com/armchr/codeapi/internal/model/ast"
+	"github.com/armchr/codeapi/internal/parse"
+)
+
+// javaImports is what a Java file can refer to by simple name besides its
+// own package, read back from its import nodes and module scope
+type javaImports struct {
+	module        string            // package of the file
+	explicit      map[string]string // simple name -> path, from import a.b.C
+	packages      []string          // from import a.b.*
+	staticMembers map[string]string // member name -> class path, from import static a.B.m
+	staticClasses []string          // from import static a.B.*
+}
+
+// Ranks returned by classRank, strongest first
+const (
+	rankExplicitImport = 3
+	rankSamePackage    = 2
+	rankWildcardImport = 1
+)
+
+// loadJavaImports reads the imports of a file from the code graph
+func (pp *PostProcessor) loadJavaImports(ctx context.Context, fileID int32) *javaImports {
+	imports := &javaImports{
+		explicit:      make(map[string]string),
+		staticMembers: make(map[string]string),
+	}
+
+	importNodes, _ := pp.codeGraph.GetNodesByTypeAndFileID(ctx, ast.NodeTypeImport, fileID)
+	for _, node := range importNodes {
+		path, _ := node.MetaData["importPath"].(string)
+		if path == "" {
+			continue
+		}
+		if static, _ := node.MetaData["static"].(bool); static {
+			imports.staticMembers[node.Name] = path[:max(strings.LastIndex(path, "."), 0)]
+		} else {
+			imports.explicit[node.Name] = path
+		}
+	}
+
+	modules, _ := pp.codeGraph.GetNodesByTypeAndFileID(ctx, ast.NodeTypeModuleScope, fileID)
+	for _, module := range modules {
+		imports.module = module.Name
+		imports.packages = append(imports.packages, metadataStrings(module.MetaData[parse.MetaWildcardImports])...)
+		imports.staticClasses = append(imports.staticClasses, metadataStrings(module.MetaData[parse.MetaStaticWildcardImports])...)
+	}
+	return imports
+}
+
+// classRank says how strongly the imports point at class name declared in
+// package classModule; 0 means the file cannot refer to it by simple name
+func (ji *javaImports) classRank(name, classModule string) int {
+	if path, ok := ji.explicit[name]; ok {
+		// The path ends in name; a nested class has its outer class between.
+		// Package names are lowercase by convention, class names are not.
+		if rest, ok := strings.CutPrefix(path, classModule+"."); ok && rest != "" && unicode.IsUpper(rune(rest[0])) {
+			return rankExplicitImport
+		}
+		// An explicit import shadows same-named classes from anywhere else
+		return 0
+	}
+	if classModule == ji.module {
+		return rankSamePackage
+	}
+	for _, pkg := range ji.packages {
+		if pkg == classModule {
+			return rankWildcardImport
+		}
+	}
+	return 0
+}
+
+// staticClassesFor returns the classes a call to an unqualified name may
+// target through static imports: the explicitly imported member first, then
+// every class imported with import static a.B.*
+func (ji *javaImports) staticClassesFor(name string) []string {
+	var classes []string
+	if class, ok := ji.staticMembers[name]; ok {
+		classes = append(classes, class)
+	}
+	return append(classes, ji.staticClasses...)
+}
+
+// splitClassPath splits "a.b.C" into its package "a.b" and simple name "C"
+func splitClassPath(path string) (module, name string) {
+	lastDot := strings.LastIndex(path, ".")
+	if lastDot == -1 {
+		return "", path
+	}
+	return path[:lastDot], path[lastDot+1:]
+}
+
+// metadataStrings returns a string list stored in node metadata, which
+// comes back from Neo4j as []any
+func metadataStrings(value any) []string {
+	switch v := value.(type) {
+	case []string:
+		return v
+	case []any:
+		var result []string
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				result = append(result, s)
+			}
+		}
+		return result
+	}
+	return nil
+}
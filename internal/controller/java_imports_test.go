@@ -0,0 +1,51 @@
+package controller
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestJavaImportsClassRank(t *testing.T) {
+	imports := &javaImports{
+		module:        "com.shop.orders",
+		explicit:      map[string]string{"Money": "com.shop.billing.Money", "Entry": "com.shop.ledger.Book.Entry"},
+		packages:      []string{"com.shop.model"},
+		staticMembers: map[string]string{"checkNotNull": "com.shop.util.Preconditions"},
+		staticClasses: []string{"com.shop.util.Strings"},
+	}
+
+	tests := []struct {
+		name   string
+		class  string
+		module string
+		want   int
+	}{
+		{"explicit import", "Money", "com.shop.billing", rankExplicitImport},
+		{"explicit import shadows own package", "Money", "com.shop.orders", 0},
+		{"nested class", "Entry", "com.shop.ledger", rankExplicitImport},
+		{"parent package of import", "Money", "com.shop", 0},
+		{"same package", "Order", "com.shop.orders", rankSamePackage},
+		{"wildcard import", "Customer", "com.shop.model", rankWildcardImport},
+		{"not imported", "Customer", "com.shop.admin", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := imports.classRank(tt.class, tt.module); got != tt.want {
+				t.Errorf("classRank(%q, %q) = %d, want %d", tt.class, tt.module, got, tt.want)
+			}
+		})
+	}
+
+	if got, want := imports.staticClassesFor("checkNotNull"), []string{"com.shop.util.Preconditions", "com.shop.util.Strings"}; !slices.Equal(got, want) {
+		t.Errorf("staticClassesFor(checkNotNull) = %v, want %v", got, want)
+	}
+	if got, want := imports.staticClassesFor("isBlank"), []string{"com.shop.util.Strings"}; !slices.Equal(got, want) {
+		t.Errorf("staticClassesFor(isBlank) = %v, want %v", got, want)
+	}
+	if module, name := splitClassPath("com.shop.util.Strings"); module != "com.shop.util" || name != "Strings" {
+		t.Errorf("splitClassPath = %q, %q", module, name)
+	}
+	if got := metadataStrings([]any{"a.b", 3, "c"}); !slices.Equal(got, []string{"a.b", "c"}) {
+		t.Errorf("metadataStrings = %v", got)
+	}
+}
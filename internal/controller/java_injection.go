@@ -0,0 +1,64 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/armchr/codeapi/internal/config"
+	"github.com/armchr/codeapi/internal/model/ast"
+	"github.com/armchr/codeapi/internal/parse"
+
+	"go.uber.org/zap"
+)
+
+// processInjection links the Java classes of a file to the repository
+// classes a DI container injects into them. The injection points come from
+// the class metadata the parser recorded; each resolvable type gets an
+// INJECTS relation, while dependencies from libraries are left out.
+func (pp *PostProcessor) processInjection(ctx context.Context, repo *config.Repository, fileScope *ast.Node, imports *javaImports) error {
+	classes, err := pp.codeGraph.FindAllClassesInFile(ctx, fileScope.FileID)
+	if err != nil {
+		return fmt.Errorf("failed to find classes in file: %w", err)
+	}
+
+	for _, class := range classes {
+		if class.MetaData == nil {
+			continue
+		}
+		for _, inj := range parse.DecodeInjections(class.MetaData[parse.MetaInjects]) {
+			simpleName := extractSimpleName(inj.Type)
+			candidates, err := pp.codeGraph.FindClassesByNameInRepo(ctx, simpleName, repo.Name)
+			if err != nil {
+				pp.logger.Warn("Failed to find injected class",
+					zap.String("class", class.Name),
+					zap.String("dependency", simpleName),
+					zap.Error(err))
+				continue
+			}
+			if len(candidates) == 0 {
+				continue
+			}
+
+			dependency := pp.selectImportedClass(ctx, imports, simpleName, candidates)
+			if dependency == nil || dependency.ID == class.ID {
+				continue
+			}
+
+			err = pp.codeGraph.CreateInjectsRelation(ctx, class.ID, dependency.ID, inj.Via, inj.Name, class.FileID)
+			if err != nil {
+				pp.logger.Error("Failed to create INJECTS relation",
+					zap.String("class", class.Name),
+					zap.String("dependency", dependency.Name),
+					zap.Error(err))
+				continue
+			}
+
+			pp.logger.Debug("Created INJECTS relation",
+				zap.String("class", class.Name),
+				zap.String("dependency", dependency.Name),
+				zap.String("via", inj.Via),
+				zap.String("name", inj.Name))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,164 @@
+package controller
+
+import (
+	"context"
+	"strings"
+
+	"github.com/armchr/codeapi/internal/model/ast"
+	"github.com/armchr/codeapi/pkg/lsp/base"
+
+	"go.uber.org/zap"
+)
+
+// lambdaFunctionName is the name the Java visitor gives lambda expressions
+const lambdaFunctionName = "__lambda__"
+
+// functionalMethods are the abstract methods of the common functional
+// interfaces: Runnable.run, Callable.call, Supplier.get, Function.apply, ...
+var functionalMethods = map[string]bool{
+	"run": true, "call": true, "get": true, "accept": true, "apply": true, "test": true, "compare": true,
+	"applyAsInt": true, "applyAsLong": true, "applyAsDouble": true,
+	"getAsInt": true, "getAsLong": true, "getAsDouble": true, "getAsBoolean": true,
+}
+
+// fileFunctions holds the functions of a file needed to attribute lambda
+// bodies and resolve calls through functional interfaces
+type fileFunctions struct {
+	byID      map[ast.NodeID]*ast.Node
+	all       []*ast.Node
+	lambdas   []*ast.Node
+	variables []*ast.Node
+}
+
+// loadFileFunctions reads the functions and variables of a file from the code graph
+func (pp *PostProcessor) loadFileFunctions(ctx context.Context, fileID int32) *fileFunctions {
+	ff := &fileFunctions{byID: make(map[ast.NodeID]*ast.Node)}
+	ff.all, _ = pp.codeGraph.GetNodesByTypeAndFileID(ctx, ast.NodeTypeFunction, fileID)
+	for _, fn := range ff.all {
+		ff.byID[fn.ID] = fn
+		if isLambda(fn) {
+			ff.lambdas = append(ff.lambdas, fn)
+		}
+	}
+	if len(ff.lambdas) > 0 {
+		ff.variables, _ = pp.codeGraph.GetNodesByTypeAndFileID(ctx, ast.NodeTypeVariable, fileID)
+	}
+	return ff
+}
+
+func isLambda(fn *ast.Node) bool {
+	return strings.HasPrefix(fn.Name, lambdaFunctionName)
+}
+
+// enclosingNamedFunction returns the innermost function that is not a
+// lambda and whose body contains fn, or nil for lambdas outside any method
+// such as field initializers
+func enclosingNamedFunction(fn *ast.Node, functions []*ast.Node) *ast.Node {
+	var best *ast.Node
+	for _, candidate := range functions {
+		if candidate.ID == fn.ID || isLambda(candidate) || !candidate.Range.ContainsRange(&fn.Range) {
+			continue
+		}
+		if best == nil || best.Range.ContainsRange(&candidate.Range) {
+			best = candidate
+		}
+	}
+	return best
+}
+
+// lambdaArguments returns the lambdas passed directly as arguments of call,
+// e.g. the predicate of stream.filter(x -> x.ok()). A lambda nested in
+// another call or lambda within call belongs to that one instead.
+func lambdaArguments(call *ast.Node, calls, lambdas []*ast.Node) []*ast.Node {
+	var result []*ast.Node
+	for _, lambda := range lambdas {
+		if !call.Range.ContainsRange(&lambda.Range) {
+			continue
+		}
+		if !nestedIn(lambda, call, calls) && !nestedIn(lambda, call, lambdas) {
+			result = append(result, lambda)
+		}
+	}
+	return result
+}
+
+// nestedIn reports whether one of nodes lies within outer and contains inner
+func nestedIn(inner, outer *ast.Node, nodes []*ast.Node) bool {
+	for _, n := range nodes {
+		if n.ID == inner.ID || n.ID == outer.ID || n.Range == outer.Range {
+			continue
+		}
+		if outer.Range.ContainsRange(&n.Range) && n.Range.ContainsRange(&inner.Range) {
+			return true
+		}
+	}
+	return false
+}
+
+// boundLambda returns the lambda a call like r.run() invokes when r is a
+// local variable of owner initialized with a lambda, as in
+// Runnable r = () -> work(). The declaration nearest before the call wins.
+func boundLambda(call, owner *ast.Node, variables, lambdas []*ast.Node) *ast.Node {
+	receiver, method, ok := strings.Cut(call.Name, ".")
+	if !ok || strings.Contains(method, ".") || !functionalMethods[method] {
+		return nil
+	}
+
+	var decl *ast.Node
+	for _, v := range variables {
+		if v.Name != receiver || !owner.Range.ContainsRange(&v.Range) || !positionBefore(v.Range.Start, call.Range.Start) {
+			continue
+		}
+		if decl == nil || positionBefore(decl.Range.Start, v.Range.Start) {
+			decl = v
+		}
+	}
+	if decl == nil {
+		return nil
+	}
+
+	// The initializer follows the name on the line it is declared on
+	for _, lambda := range lambdas {
+		start := lambda.Range.Start
+		if start.Line == decl.Range.End.Line && !positionBefore(start, decl.Range.End) && owner.Range.ContainsRange(&lambda.Range) {
+			return lambda
+		}
+	}
+	return nil
+}
+
+// resolveLambdaTargets links a call the language server could not resolve
+// to a repository function to the lambdas it runs: those passed as its
+// arguments, or the one bound to its functional interface receiver. It
+// reports whether any link was created.
+func (pp *PostProcessor) resolveLambdaTargets(ctx context.Context, call, owner *ast.Node, calls []*ast.Node, ff *fileFunctions) bool {
+	if ff == nil || len(ff.lambdas) == 0 {
+		return false
+	}
+
+	targets := lambdaArguments(call, calls, ff.lambdas)
+	if lambda := boundLambda(call, owner, ff.variables, ff.lambdas); lambda != nil {
+		targets = append(targets, lambda)
+	}
+
+	linked := false
+	for _, lambda := range targets {
+		if err := pp.codeGraph.CreateCallsFunctionRelation(ctx, call.ID, lambda.ID, call.FileID); err != nil {
+			pp.logger.Error("Failed to create CALLS_FUNCTION relation to lambda",
+				zap.Int64("callId", int64(call.ID)),
+				zap.Error(err))
+			continue
+		}
+		linked = true
+		pp.logger.Debug("Resolved call to lambda",
+			zap.String("callName", call.Name),
+			zap.Int64("lambdaId", int64(lambda.ID)),
+			zap.Int("lambdaLine", lambda.Range.Start.Line))
+	}
+	return linked
+}
+
+// positionBefore reports whether a comes strictly before b
+func positionBefore(a, b base.Position) bool {
+	return a.Line < b.Line || (a.Line == b.Line && a.Character < b.Character)
+}
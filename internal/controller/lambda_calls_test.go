@@ -0,0 +1,118 @@
+package controller
+
+import (
+	"testing"
+
+	"github.com/armchr/codeapi/internal/model/ast"
+	"github.com/armchr/codeapi/pkg/lsp/base"
+)
+
+func rangedNode(id ast.NodeID, name string, startLine, startChar, endLine, endChar int) *ast.Node {
+	return &ast.Node{
+		ID:   id,
+		Name: name,
+		Range: base.Range{
+			Start: base.Position{Line: startLine, Character: startChar},
+			End:   base.Position{Line: endLine, Character: endChar},
+		},
+	}
+}
+
+func nodeIDs(nodes []*ast.Node) []ast.NodeID {
+	var ids []ast.NodeID
+	for _, n := range nodes {
+		ids = append(ids, n.ID)
+	}
+	return ids
+}
+
+// Shapes of
+//
+//	void process(List<Order> orders) {          // 1
+//	    Runnable r = () -> audit();              // 2
+//	    orders.stream()                          // 3
+//	        .filter(o -> o.isOpen())             // 4
+//	        .map(o -> price(o, x -> x.tax()))    // 5
+//	        .forEach(this::ship);                // 6
+//	    r.run();                                 // 7
+//	}                                           // 8
+var (
+	processFn   = rangedNode(1, "process", 1, 0, 8, 1)
+	runLambda   = rangedNode(2, lambdaFunctionName, 2, 17, 2, 30)
+	filterArg   = rangedNode(3, lambdaFunctionName, 4, 16, 4, 32)
+	mapArg      = rangedNode(4, lambdaFunctionName, 5, 13, 5, 40)
+	priceArg    = rangedNode(5, lambdaFunctionName, 5, 27, 5, 39)
+	innerMethod = rangedNode(6, "helper", 10, 0, 12, 1)
+
+	streamCall  = rangedNode(10, "orders.stream", 3, 4, 3, 19)
+	filterCall  = rangedNode(11, "filter", 3, 4, 4, 33)
+	mapCall     = rangedNode(12, "map", 3, 4, 5, 41)
+	priceCall   = rangedNode(13, "price", 5, 18, 5, 40)
+	forEachCall = rangedNode(14, "forEach", 3, 4, 6, 28)
+	runCall     = rangedNode(15, "r.run", 7, 4, 7, 11)
+
+	rVariable = rangedNode(20, "r", 2, 13, 2, 14)
+)
+
+func TestEnclosingNamedFunction(t *testing.T) {
+	functions := []*ast.Node{processFn, runLambda, filterArg, mapArg, priceArg, innerMethod}
+	for _, lambda := range []*ast.Node{runLambda, filterArg, mapArg, priceArg} {
+		if got := enclosingNamedFunction(lambda, functions); got != processFn {
+			t.Errorf("enclosingNamedFunction(%d) = %v, want process", lambda.ID, got)
+		}
+	}
+
+	fieldLambda := rangedNode(7, lambdaFunctionName, 0, 30, 0, 50)
+	if got := enclosingNamedFunction(fieldLambda, functions); got != nil {
+		t.Errorf("lambda outside any method attributed to %s", got.Name)
+	}
+}
+
+func TestLambdaArguments(t *testing.T) {
+	calls := []*ast.Node{streamCall, filterCall, mapCall, priceCall, forEachCall, runCall}
+	lambdas := []*ast.Node{runLambda, filterArg, mapArg, priceArg}
+
+	tests := []struct {
+		call *ast.Node
+		want []ast.NodeID
+	}{
+		{streamCall, nil},
+		{filterCall, []ast.NodeID{filterArg.ID}},
+		{mapCall, []ast.NodeID{mapArg.ID}},
+		{priceCall, []ast.NodeID{priceArg.ID}},
+		{forEachCall, nil},
+		{runCall, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.call.Name, func(t *testing.T) {
+			got := nodeIDs(lambdaArguments(tt.call, calls, lambdas))
+			if len(got) != len(tt.want) || (len(got) > 0 && got[0] != tt.want[0]) {
+				t.Errorf("lambdaArguments(%s) = %v, want %v", tt.call.Name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBoundLambda(t *testing.T) {
+	variables := []*ast.Node{rVariable}
+	lambdas := []*ast.Node{runLambda, filterArg, mapArg, priceArg}
+
+	if got := boundLambda(runCall, processFn, variables, lambdas); got != runLambda {
+		t.Errorf("boundLambda(r.run) = %v, want the lambda r is initialized with", got)
+	}
+
+	notFunctional := rangedNode(16, "r.close", 7, 4, 7, 13)
+	if got := boundLambda(notFunctional, processFn, variables, lambdas); got != nil {
+		t.Errorf("boundLambda(r.close) = %d, want nil", got.ID)
+	}
+
+	otherReceiver := rangedNode(17, "task.run", 7, 4, 7, 14)
+	if got := boundLambda(otherReceiver, processFn, variables, lambdas); got != nil {
+		t.Errorf("boundLambda(task.run) = %d, want nil", got.ID)
+	}
+
+	beforeDeclaration := rangedNode(18, "r.run", 1, 30, 1, 37)
+	if got := boundLambda(beforeDeclaration, processFn, variables, lambdas); got != nil {
+		t.Errorf("call before the declaration bound to lambda %d", got.ID)
+	}
+}
@@ -0,0 +1,35 @@
+package controller
+
+import (
+	"bytes"
+	"os"
+
+	"github.com/armchr/codeapi/internal/config"
+	"github.com/armchr/codeapi/internal/util"
+)
+
+// tooLargeOnDisk reports whether a file can be skipped for size before it is
+// read. Only the skip action allows this; truncation needs the content.
+func tooLargeOnDisk(cfg config.IndexBuildingConfig, filePath string) bool {
+	if cfg.LargeFileAction != config.LargeFileSkip {
+		return false
+	}
+	info, err := os.Stat(filePath)
+	return err == nil && info.Size() > cfg.MaxFileSize()
+}
+
+// limitFileSize applies the large file policy to content read for indexing.
+// It returns the content to index and whether the file was over the limit;
+// nil content means the file is skipped. Truncated content ends at the last
+// complete line within the limit so parsers never see a split line.
+func limitFileSize(cfg config.IndexBuildingConfig, content []byte) ([]byte, bool) {
+	limit := cfg.MaxFileSize()
+	if int64(len(content)) <= limit {
+		return content, false
+	}
+	if cfg.LargeFileAction != config.LargeFileTruncate {
+		return nil, true
+	}
+	// Copy so the full file can be released while the prefix is indexed
+	return bytes.Clone(util.TruncateAtLine(content, int(limit))), true
+}
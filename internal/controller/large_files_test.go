@@ -0,0 +1,97 @@
+package controller
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/armchr/codeapi/internal/config"
+	"github.com/armchr/codeapi/internal/db"
+
+	"go.uber.org/zap"
+)
+
+func TestLimitFileSize(t *testing.T) {
+	line := strings.Repeat("x", 99) + "\n"
+	tests := []struct {
+		name          string
+		action        string
+		content       string
+		wantLen       int // -1 means skipped
+		wantOversized bool
+	}{
+		{"under limit", config.LargeFileSkip, strings.Repeat(line, 10), 1000, false},
+		{"exactly at limit", config.LargeFileSkip, strings.Repeat(line, 1024/100) + strings.Repeat("y", 1024%100), 1024, false},
+		{"skip over limit", config.LargeFileSkip, strings.Repeat(line, 20), -1, true},
+		{"truncate at line boundary", config.LargeFileTruncate, strings.Repeat(line, 20), 1000, true},
+		{"truncate without newline", config.LargeFileTruncate, strings.Repeat("z", 2000), 1024, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.IndexBuildingConfig{MaxFileSizeKB: 1, LargeFileAction: tt.action}
+			got, oversized := limitFileSize(cfg, []byte(tt.content))
+			if oversized != tt.wantOversized {
+				t.Errorf("oversized = %v, want %v", oversized, tt.wantOversized)
+			}
+			if tt.wantLen < 0 {
+				if got != nil {
+					t.Errorf("expected file to be skipped, got %d bytes", len(got))
+				}
+				return
+			}
+			if len(got) != tt.wantLen {
+				t.Errorf("len = %d, want %d", len(got), tt.wantLen)
+			}
+			if !strings.HasPrefix(tt.content, string(got)) {
+				t.Error("result is not a prefix of the content")
+			}
+		})
+	}
+}
+
+func TestIndexBuilderSkipsLargeFiles(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"main.go":      "package main\n",
+		"generated.go": "package main\n\nvar data = []byte{\n" + strings.Repeat("\t0x00, 0x01, 0x02, 0x03,\n", 100) + "}\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, action := range []string{config.LargeFileSkip, config.LargeFileTruncate} {
+		t.Run(action, func(t *testing.T) {
+			logger := zap.NewNop()
+			versions, err := db.NewFileVersionRepository(newBackupTestDB(t).GetDB(), "api", logger)
+			if err != nil {
+				t.Fatal(err)
+			}
+			cfg := &config.Config{}
+			cfg.IndexBuilding.MaxFileSizeKB = 1
+			cfg.IndexBuilding.LargeFileAction = action
+
+			processor := &countingProcessor{}
+			builder := NewIndexBuilder(cfg, []FileProcessor{processor}, versions, logger)
+			repo := &config.Repository{Name: "api", Path: dir, Language: "go"}
+			if err := builder.BuildIndex(context.Background(), repo); err != nil {
+				t.Fatalf("BuildIndex: %v", err)
+			}
+
+			wantProcessed := int64(1)
+			if action == config.LargeFileTruncate {
+				wantProcessed = 2
+			}
+			if processor.files != wantProcessed {
+				t.Errorf("processed %d files, want %d", processor.files, wantProcessed)
+			}
+			if stats := builder.LastStats(); stats.FilesOversized != 1 {
+				t.Errorf("FilesOversized = %d, want 1", stats.FilesOversized)
+			}
+		})
+	}
+}
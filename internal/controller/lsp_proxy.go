@@ -0,0 +1,143 @@
+package controller
+
+import (
+	"net/http"
+	"path/filepath"
+
+	"github.com/armchr/codeapi/internal/util"
+	"github.com/armchr/codeapi/pkg/lsp/base"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// LspPositionRequest is a position in a repository file, zero-based as in
+// the LSP specification. IncludeDeclaration is only read by references.
+type LspPositionRequest struct {
+	RepoName           string `json:"repo_name" binding:"required"`
+	RelativePath       string `json:"relative_path" binding:"required"`
+	Line               int    `json:"line" binding:"min=0"`
+	Character          int    `json:"character" binding:"min=0"`
+	IncludeDeclaration bool   `json:"include_declaration,omitempty"`
+}
+
+// LspLocation is a location returned by the language server. RelativePath
+// is set when the location lies inside the repository; locations in the
+// standard library or module cache only have a URI.
+type LspLocation struct {
+	URI          string     `json:"uri"`
+	RelativePath string     `json:"relative_path,omitempty"`
+	Range        base.Range `json:"range"`
+}
+
+// LspHoverResponse is the hover text of a position, empty when the server
+// has none
+type LspHoverResponse struct {
+	Contents string      `json:"contents"`
+	Range    *base.Range `json:"range,omitempty"`
+}
+
+// LspDefinition returns where the symbol at a position is defined, using the
+// repository's already-initialized language server
+func (rc *RepoController) LspDefinition(c *gin.Context) {
+	request, repoPath, ok := rc.bindLspPosition(c)
+	if !ok {
+		return
+	}
+	locations, err := rc.repoService.GetDefinition(c.Request.Context(), request.RepoName, request.RelativePath, request.position())
+	if err != nil {
+		rc.lspFailed(c, "definition", request, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"locations": lspLocations(repoPath, locations)})
+}
+
+// LspReferences returns where the symbol at a position is used
+func (rc *RepoController) LspReferences(c *gin.Context) {
+	request, repoPath, ok := rc.bindLspPosition(c)
+	if !ok {
+		return
+	}
+	locations, err := rc.repoService.GetReferences(c.Request.Context(), request.RepoName, request.RelativePath, request.position(), request.IncludeDeclaration)
+	if err != nil {
+		rc.lspFailed(c, "references", request, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"locations": lspLocations(repoPath, locations)})
+}
+
+// LspHover returns the type and documentation the language server shows for
+// the symbol at a position
+func (rc *RepoController) LspHover(c *gin.Context) {
+	request, _, ok := rc.bindLspPosition(c)
+	if !ok {
+		return
+	}
+	contents, hoverRange, err := rc.repoService.GetHover(c.Request.Context(), request.RepoName, request.RelativePath, request.position())
+	if err != nil {
+		rc.lspFailed(c, "hover", request, err)
+		return
+	}
+	c.JSON(http.StatusOK, LspHoverResponse{Contents: contents, Range: hoverRange})
+}
+
+func (r *LspPositionRequest) position() base.Position {
+	return base.Position{Line: r.Line, Character: r.Character}
+}
+
+// bindLspPosition reads a position request and returns it with the path of
+// the repository, writing the error response when the request is invalid.
+// Paths must stay inside the repository: the proxy opens the file from disk.
+func (rc *RepoController) bindLspPosition(c *gin.Context) (*LspPositionRequest, string, bool) {
+	var request LspPositionRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		rc.logger.Error("Invalid request payload", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request payload",
+			"details": err.Error(),
+		})
+		return nil, "", false
+	}
+	if !filepath.IsLocal(request.RelativePath) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "relative_path must be a path inside the repository",
+		})
+		return nil, "", false
+	}
+
+	repo, err := rc.config.GetRepository(request.RepoName)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "Repository not found",
+			"details": err.Error(),
+		})
+		return nil, "", false
+	}
+	return &request, repo.Path, true
+}
+
+func (rc *RepoController) lspFailed(c *gin.Context, method string, request *LspPositionRequest, err error) {
+	rc.logger.Error("Language server request failed",
+		zap.String("method", method),
+		zap.String("repo_name", request.RepoName),
+		zap.String("relative_path", request.RelativePath),
+		zap.Error(err))
+	c.JSON(http.StatusInternalServerError, gin.H{
+		"error":   "Language server request failed",
+		"details": err.Error(),
+	})
+}
+
+// lspLocations converts language server locations to the response, adding
+// the repository-relative path of locations inside repoPath
+func lspLocations(repoPath string, locations []base.Location) []LspLocation {
+	result := make([]LspLocation, 0, len(locations))
+	for _, loc := range locations {
+		l := LspLocation{URI: loc.URI, Range: loc.Range}
+		if rel := util.ToRelativePath(repoPath, util.ExtractPathFromURI(loc.URI)); filepath.IsLocal(rel) {
+			l.RelativePath = filepath.ToSlash(rel)
+		}
+		result = append(result, l)
+	}
+	return result
+}
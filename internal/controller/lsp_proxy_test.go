@@ -0,0 +1,29 @@
+package controller
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/armchr/codeapi/pkg/lsp/base"
+)
+
+func TestLspLocations(t *testing.T) {
+	at := base.Range{Start: base.Position{Line: 4, Character: 5}, End: base.Position{Line: 4, Character: 9}}
+	got := lspLocations("/src/api", []base.Location{
+		{URI: "file:///src/api/internal/server.go", Range: at},
+		{URI: "file:///usr/local/go/src/net/http/server.go", Range: at},
+		{URI: "file:///src/api-client/main.go", Range: at},
+	})
+	want := []LspLocation{
+		{URI: "file:///src/api/internal/server.go", RelativePath: "internal/server.go", Range: at},
+		{URI: "file:///usr/local/go/src/net/http/server.go", Range: at},
+		{URI: "file:///src/api-client/main.go", Range: at},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("lspLocations = %+v, want %+v", got, want)
+	}
+
+	if got := lspLocations("/src/api", nil); got == nil || len(got) != 0 {
+		t.Errorf("lspLocations(nil) = %#v, want an empty slice", got)
+	}
+}
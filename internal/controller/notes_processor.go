@@ -0,0 +1,137 @@
+package controller
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/armchr/codeapi/internal/config"
+	"github.com/armchr/codeapi/internal/db"
+	"github.com/armchr/codeapi/internal/service/notes"
+	"github.com/armchr/codeapi/internal/service/vector"
+	"github.com/armchr/codeapi/internal/util"
+
+	"go.uber.org/zap"
+)
+
+// NotesProcessor records TODO-style comments and license headers of each
+// file in the relational store, with their authors from git blame
+type NotesProcessor struct {
+	db           *sql.DB
+	chunkService *vector.CodeChunkService // nil unless notes are embedded
+	extractor    *notes.Extractor
+	blame        bool
+	logger       *zap.Logger
+
+	storesMu sync.Mutex
+	stores   map[string]*db.CodeNoteStore
+
+	notesFound atomic.Int64
+}
+
+var _ FileProcessor = (*NotesProcessor)(nil)
+
+// NewNotesProcessor creates a notes processor. With a chunk service, notes
+// are embedded into the repository's collection as well; the embedding
+// processor must run first so the collection exists.
+func NewNotesProcessor(sqlDB *sql.DB, chunkService *vector.CodeChunkService, cfg *config.NotesConfig, logger *zap.Logger) *NotesProcessor {
+	settings := cfg.GetDefaults()
+	return &NotesProcessor{
+		db:           sqlDB,
+		chunkService: chunkService,
+		extractor:    notes.NewExtractor(settings.Markers, *settings.Licenses),
+		blame:        *settings.Blame,
+		logger:       logger,
+		stores:       make(map[string]*db.CodeNoteStore),
+	}
+}
+
+// Name returns the processor name
+func (np *NotesProcessor) Name() string {
+	return "Notes"
+}
+
+// Init creates the note store of the repository
+func (np *NotesProcessor) Init(ctx context.Context, repo *config.Repository) error {
+	store, err := db.NewCodeNoteStore(np.db, repo.Name, np.logger)
+	if err != nil {
+		return fmt.Errorf("failed to create note store: %w", err)
+	}
+	np.storesMu.Lock()
+	np.stores[repo.Name] = store
+	np.storesMu.Unlock()
+	np.notesFound.Store(0)
+	return nil
+}
+
+// ProcessFile extracts the notes of a file and replaces those stored for its path
+func (np *NotesProcessor) ProcessFile(ctx context.Context, repo *config.Repository, fileCtx *FileContext) error {
+	np.storesMu.Lock()
+	store := np.stores[repo.Name]
+	np.storesMu.Unlock()
+	if store == nil {
+		return fmt.Errorf("notes processor not initialized for repository %s", repo.Name)
+	}
+
+	// Notes are stored by path, so ad-hoc sandbox content would replace the
+	// notes of the checked out file
+	if fileCtx.Sandbox {
+		return nil
+	}
+
+	fileNotes := np.extractor.Extract(fileCtx.RelativePath, fileCtx.Content)
+	if np.blame && len(fileNotes) > 0 {
+		np.addBlame(repo, fileCtx, fileNotes)
+	}
+
+	if err := store.WithContext(ctx).ReplaceFileNotes(fileCtx.RelativePath, fileCtx.FileID, fileNotes); err != nil {
+		return err
+	}
+	np.notesFound.Add(int64(len(fileNotes)))
+
+	if np.chunkService != nil {
+		if err := np.chunkService.IndexNotes(ctx, repo.Name, fileCtx.FileID, fileNotes); err != nil {
+			np.logger.Warn("Failed to embed notes",
+				zap.String("path", fileCtx.RelativePath),
+				zap.Error(err))
+		}
+	}
+	return nil
+}
+
+// addBlame fills in the author of each note. Files outside git or not yet
+// committed simply keep notes without authors.
+func (np *NotesProcessor) addBlame(repo *config.Repository, fileCtx *FileContext, fileNotes []notes.CodeNote) {
+	lines := make([]int, len(fileNotes))
+	for i, n := range fileNotes {
+		lines[i] = n.Line
+	}
+	blamed, err := util.BlameLines(repo.Path, fileCtx.RelativePath, lines)
+	if err != nil {
+		np.logger.Debug("git blame unavailable for notes",
+			zap.String("path", fileCtx.RelativePath),
+			zap.Error(err))
+		return
+	}
+	for i := range fileNotes {
+		b, ok := blamed[fileNotes[i].Line]
+		if !ok {
+			continue
+		}
+		authoredAt := b.AuthorTime
+		fileNotes[i].Author = b.Author
+		fileNotes[i].AuthorEmail = b.AuthorEmail
+		fileNotes[i].CommitID = b.CommitID
+		fileNotes[i].AuthoredAt = &authoredAt
+	}
+}
+
+// PostProcess logs the number of notes found in the repository
+func (np *NotesProcessor) PostProcess(ctx context.Context, repo *config.Repository) error {
+	np.logger.Info("Notes extracted",
+		zap.String("repo_name", repo.Name),
+		zap.Int64("notes", np.notesFound.Load()))
+	return nil
+}
@@ -44,10 +44,17 @@ func (pp *PostProcessor) PostProcessRepository(ctx context.Context, repo *config
 
 	pp.logger.Info("Found file scopes", zap.Int("count", len(fileScopes)))
 
+	// Python imports are followed across files, so the module index covers
+	// the whole repository
+	python := pp.newPythonResolver(ctx, fileScopes)
+
 	for _, fileScope := range fileScopes {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 		pp.logger.Info("Post-processing file", zap.String("path", fileScope.MetaData["path"].(string)), zap.Int64("fileId", int64(fileScope.ID)))
 
-		if err := pp.processOneFile(ctx, repo, fileScope); err != nil {
+		if err := pp.processOneFile(ctx, repo, fileScope, python); err != nil {
 			pp.logger.Error("Failed to post-process file", zap.String("path", fileScope.MetaData["path"].(string)), zap.Int64("fileId", int64(fileScope.ID)), zap.Error(err))
 			continue
 		}
@@ -55,13 +62,21 @@ func (pp *PostProcessor) PostProcessRepository(ctx context.Context, repo *config
 		pp.logger.Info("Completed post-processing for file", zap.String("path", fileScope.MetaData["path"].(string)), zap.Int64("fileId", int64(fileScope.ID)))
 	}
 
+	if err := pp.processInterfaceDispatch(ctx, repo); err != nil {
+		pp.logger.Error("Failed to create interface dispatch edges", zap.String("name", repo.Name), zap.Error(err))
+	}
+
 	pp.logger.Info("Completed post-processing for repository", zap.String("name", repo.Name))
 
 	return nil
 }
 
-func (pp *PostProcessor) processOneFile(ctx context.Context, repo *config.Repository, fileScope *ast.Node) error {
+func (pp *PostProcessor) processOneFile(ctx context.Context, repo *config.Repository, fileScope *ast.Node, python *pythonResolver) error {
 	language := fileScope.MetaData["language"].(string)
+	if language == parse.ConfigLanguage {
+		// Configuration files have no code; reads link to them from code files
+		return nil
+	}
 	langType := parse.NewLanguageTypeFromString(language)
 	if langType == parse.Go {
 		if err := pp.ProcessFakeClasses(ctx, fileScope); err != nil {
@@ -69,25 +84,47 @@ func (pp *PostProcessor) processOneFile(ctx context.Context, repo *config.Reposi
 		}
 	}
 
-	if err := pp.processFunctionCalls(ctx, repo, fileScope); err != nil {
+	// Java imports decide which of several same-named classes a file means
+	var imports *javaImports
+	if langType == parse.Java {
+		imports = pp.loadJavaImports(ctx, fileScope.FileID)
+	}
+	// Go receiver and field types resolve calls the language server misses
+	var goTypes *goTypeResolver
+	if langType == parse.Go {
+		goTypes = pp.newGoTypeResolver(ctx, repo, fileScope.FileID)
+	}
+	if langType != parse.Python {
+		python = nil
+	}
+
+	if err := pp.processFunctionCalls(ctx, repo, fileScope, imports, goTypes, python); err != nil {
 		return fmt.Errorf("failed to process function calls: %w", err)
 	}
 
 	// Process inheritance for Java files
 	if langType == parse.Java {
-		if err := pp.processInheritance(ctx, repo, fileScope); err != nil {
+		if err := pp.processInheritance(ctx, repo, fileScope, imports); err != nil {
 			pp.logger.Error("Failed to process inheritance", zap.Error(err))
 		}
 
-		if err := pp.processConstructorCalls(ctx, repo, fileScope); err != nil {
+		if err := pp.processConstructorCalls(ctx, repo, fileScope, imports); err != nil {
 			pp.logger.Error("Failed to process constructor calls", zap.Error(err))
 		}
+
+		if err := pp.processInjection(ctx, repo, fileScope, imports); err != nil {
+			pp.logger.Error("Failed to process injection", zap.Error(err))
+		}
+	}
+
+	if err := pp.processConfigReads(ctx, repo, fileScope); err != nil {
+		pp.logger.Error("Failed to process config reads", zap.Error(err))
 	}
 
 	return nil
 }
 
-func (pp *PostProcessor) processFunctionCalls(ctx context.Context, repo *config.Repository, fileScope *ast.Node) error {
+func (pp *PostProcessor) processFunctionCalls(ctx context.Context, repo *config.Repository, fileScope *ast.Node, imports *javaImports, goTypes *goTypeResolver, python *pythonResolver) error {
 	functionCallsInFunction, err := pp.codeGraph.FindFunctionCalls(ctx, fileScope.ID)
 	if err != nil {
 		return fmt.Errorf("failed to find orphan function calls: %w", err)
@@ -97,13 +134,50 @@ func (pp *PostProcessor) processFunctionCalls(ctx context.Context, repo *config.
 
 	fileUri, _ := util.ToUri(fileScope.MetaData["path"].(string), repo.Path)
 
-	for containerFunctionId, fnCalls := range functionCallsInFunction {
-		pp.processFunctionCallsInContainerFunction(ctx, repo, fileUri, containerFunctionId, fnCalls)
+	ff := pp.loadFileFunctions(ctx, fileScope.FileID)
+	for ownerID, fnCalls := range pp.callsByNamedFunction(functionCallsInFunction, ff) {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		pp.processFunctionCallsInContainerFunction(ctx, repo, fileUri, ownerID, fnCalls, imports, ff, goTypes, python)
 	}
 
 	return nil
 }
 
+// callsByNamedFunction attributes the calls inside lambdas to the method
+// enclosing the lambda, since the language server only knows named
+// functions. A call is listed once per function even when it is reached both
+// directly and through a lambda.
+func (pp *PostProcessor) callsByNamedFunction(functionCalls map[ast.NodeID][]*ast.Node, ff *fileFunctions) map[ast.NodeID][]*ast.Node {
+	result := make(map[ast.NodeID][]*ast.Node, len(functionCalls))
+	seen := make(map[ast.NodeID]map[ast.NodeID]bool)
+	for containerID, fnCalls := range functionCalls {
+		ownerID := containerID
+		if container := ff.byID[containerID]; container != nil && isLambda(container) {
+			owner := enclosingNamedFunction(container, ff.all)
+			if owner == nil {
+				pp.logger.Debug("Skipping lambda outside any named function",
+					zap.Int64("lambdaId", int64(containerID)),
+					zap.Int("callCount", len(fnCalls)))
+				continue
+			}
+			ownerID = owner.ID
+		}
+
+		if seen[ownerID] == nil {
+			seen[ownerID] = make(map[ast.NodeID]bool)
+		}
+		for _, call := range fnCalls {
+			if !seen[ownerID][call.ID] {
+				seen[ownerID][call.ID] = true
+				result[ownerID] = append(result[ownerID], call)
+			}
+		}
+	}
+	return result
+}
+
 func (pp *PostProcessor) nodeToFunctionDefinition(ctx context.Context, fileUri string, functionNode *ast.Node) *model.FunctionDefinition {
 	return &model.FunctionDefinition{
 		Name: functionNode.Name,
@@ -128,6 +202,10 @@ func (pp *PostProcessor) processFunctionCallsInContainerFunction(ctx context.Con
 	fileUri string,
 	containerFunctionID ast.NodeID,
 	fnCalls []*ast.Node,
+	imports *javaImports,
+	ff *fileFunctions,
+	goTypes *goTypeResolver,
+	python *pythonResolver,
 ) error {
 	containingFunction, err := pp.codeGraph.ReadFunction(ctx, containerFunctionID)
 	if err != nil {
@@ -137,30 +215,29 @@ func (pp *PostProcessor) processFunctionCallsInContainerFunction(ctx context.Con
 		return fmt.Errorf("no function found for call node id %d", containerFunctionID)
 	}
 
-	// Skip lambda functions - they don't have real names in the source file
-	// and LSP can't find them. Their calls will be processed by the parent function.
-	if strings.HasPrefix(containingFunction.Name, "__lambda__") {
-		pp.logger.Debug("Skipping lambda container function",
-			zap.String("functionName", containingFunction.Name),
-			zap.Int("callCount", len(fnCalls)))
-		return nil
-	}
-
 	containingFnDefn := pp.nodeToFunctionDefinition(ctx, fileUri, containingFunction)
 
-	deps, err := pp.lspService.GetFunctionCallsAndDefinitions(ctx, repo.Name, containingFnDefn)
-	if err != nil {
-		return fmt.Errorf("failed to get function dependencies: %w", err)
+	// A nil language server, as in the lite indexing profile, leaves the
+	// calls to the tree-sitter resolvers below
+	var deps []model.FunctionDependency
+	if pp.lspService != nil {
+		deps, err = pp.lspService.GetFunctionCallsAndDefinitions(ctx, repo.Name, containingFnDefn)
+		if err != nil {
+			return fmt.Errorf("failed to get function dependencies: %w", err)
+		}
 	}
 
-	if len(deps) == 0 {
+	// Calls into statically imported members, through Go receivers or
+	// through Python imports can still be resolved when the language server
+	// reports nothing
+	if len(deps) == 0 && imports == nil && goTypes == nil && python == nil {
 		pp.logger.Info("No dependencies found for containing function",
 			zap.String("functionName", containingFnDefn.Name),
 			zap.String("functionPath", containingFnDefn.Location.URI))
 		return nil
 	}
 
-	err = pp.createCallsRelations(ctx, repo, fnCalls, deps)
+	err = pp.createCallsRelations(ctx, repo, containingFunction, fnCalls, deps, imports, ff, goTypes, python)
 	if err != nil {
 		pp.logger.Error("Failed to create calls relations",
 			zap.Error(err))
@@ -193,7 +270,7 @@ func (pp *PostProcessor) findCallInDependency(call *ast.Node, dependencies []mod
 	return nil
 }
 
-func (pp *PostProcessor) createCallsRelations(ctx context.Context, repo *config.Repository, calls []*ast.Node, dependencies []model.FunctionDependency) error {
+func (pp *PostProcessor) createCallsRelations(ctx context.Context, repo *config.Repository, owner *ast.Node, calls []*ast.Node, dependencies []model.FunctionDependency, imports *javaImports, ff *fileFunctions, goTypes *goTypeResolver, python *pythonResolver) error {
 	for _, call := range calls {
 		// Skip constructor calls - they are processed separately by processConstructorCalls
 		if call.MetaData != nil {
@@ -203,6 +280,18 @@ func (pp *PostProcessor) createCallsRelations(ctx context.Context, repo *config.
 		}
 
 		dep := pp.findCallInDependency(call, dependencies)
+		if dep == nil && imports != nil && pp.resolveStaticImportCall(ctx, repo, imports, call) {
+			continue
+		}
+		if dep == nil && goTypes != nil && pp.resolveGoMethodCall(ctx, call, owner, goTypes) {
+			continue
+		}
+		if dep == nil && python != nil && pp.resolvePythonImportCall(ctx, python, call) {
+			continue
+		}
+		if dep == nil && pp.resolveLambdaTargets(ctx, call, owner, calls, ff) {
+			continue
+		}
 		if dep == nil {
 			pp.logger.Warn("No matching dependency found for function call",
 				zap.Int64("callNodeId", int64(call.ID)),
@@ -217,6 +306,8 @@ func (pp *PostProcessor) createCallsRelations(ctx context.Context, repo *config.
 			}
 			call.MetaData["external"] = true
 			pp.codeGraph.CreateFunctionCall(ctx, call)
+			// Library calls such as stream.map still run the lambdas given to them
+			pp.resolveLambdaTargets(ctx, call, owner, calls, ff)
 			continue
 		}
 
@@ -232,6 +323,11 @@ func (pp *PostProcessor) createCallsRelations(ctx context.Context, repo *config.
 
 		targetFileScope := fileScopes[0]
 		targetDefns, err := pp.codeGraph.FindFunctionsByName(ctx, int(targetFileScope.FileID), dep.Definition.Name)
+		// The language server may stop at the import re-exporting the name,
+		// such as one in a package __init__.py
+		if err == nil && len(targetDefns) == 0 && python != nil && pp.resolvePythonImportCall(ctx, python, call) {
+			continue
+		}
 		if err != nil || len(targetDefns) == 0 {
 			pp.logger.Error("Failed to find target function for dependency",
 				zap.String("functionName", dep.Definition.Name),
@@ -252,6 +348,7 @@ func (pp *PostProcessor) createCallsRelations(ctx context.Context, repo *config.
 
 		if targetDefnID != ast.InvalidNodeID {
 			pp.codeGraph.CreateCallsFunctionRelation(ctx, call.ID, targetDefnID, call.FileID)
+			pp.linkArguments(ctx, call, targetDefnID)
 			// log
 			pp.logger.Info("Created CALLS_FUNCTION relation",
 				zap.Int64("callNodeId", int64(call.ID)),
@@ -264,6 +361,19 @@ func (pp *PostProcessor) createCallsRelations(ctx context.Context, repo *config.
 	return nil
 }
 
+// linkArguments links the arguments of a call to the parameters of the
+// function it was resolved to with ARG_OF relations. Calls resolved to a
+// lambda passed as an argument are not linked: the lambda's parameters
+// receive what the callee passes it, not the arguments of the call.
+func (pp *PostProcessor) linkArguments(ctx context.Context, call *ast.Node, target ast.NodeID) {
+	if _, err := pp.codeGraph.CreateArgOfRelations(ctx, call.ID, target, call.FileID); err != nil {
+		pp.logger.Error("Failed to create ARG_OF relations",
+			zap.Int64("callId", int64(call.ID)),
+			zap.Int64("targetFunctionId", int64(target)),
+			zap.Error(err))
+	}
+}
+
 /*
 func (pp *PostProcessor) getDependenciesFromCallGraph(callGraph *model.CallGraph, root model.FunctionDefinition) []model.FunctionDependency {
 	var dependencies []model.FunctionDependency
@@ -312,7 +422,7 @@ func (pp *PostProcessor) matchesFunctionCall(callNode *ast.Node, dependency *mod
 // processInheritance resolves inheritance relationships for classes in a file.
 // It looks at the extends/implements metadata captured during parsing and creates
 // INHERITS relationships to the resolved parent classes/interfaces.
-func (pp *PostProcessor) processInheritance(ctx context.Context, repo *config.Repository, fileScope *ast.Node) error {
+func (pp *PostProcessor) processInheritance(ctx context.Context, repo *config.Repository, fileScope *ast.Node, imports *javaImports) error {
 	// Find all classes in this file
 	classes, err := pp.codeGraph.FindAllClassesInFile(ctx, fileScope.FileID)
 	if err != nil {
@@ -330,12 +440,12 @@ func (pp *PostProcessor) processInheritance(ctx context.Context, repo *config.Re
 
 		// Process extends (single parent class or interface extends)
 		if extends, ok := class.MetaData["extends"]; ok {
-			pp.resolveAndCreateInheritance(ctx, repo, class, extends)
+			pp.resolveAndCreateInheritance(ctx, repo, class, extends, imports)
 		}
 
 		// Process implements (multiple interfaces)
 		if implements, ok := class.MetaData["implements"]; ok {
-			pp.resolveAndCreateInheritance(ctx, repo, class, implements)
+			pp.resolveAndCreateInheritance(ctx, repo, class, implements, imports)
 		}
 	}
 
@@ -344,7 +454,7 @@ func (pp *PostProcessor) processInheritance(ctx context.Context, repo *config.Re
 
 // resolveAndCreateInheritance resolves parent type names and creates INHERITS relationships.
 // The parentTypes parameter can be a string (single parent) or []string (multiple parents).
-func (pp *PostProcessor) resolveAndCreateInheritance(ctx context.Context, repo *config.Repository, childClass *ast.Node, parentTypes any) {
+func (pp *PostProcessor) resolveAndCreateInheritance(ctx context.Context, repo *config.Repository, childClass *ast.Node, parentTypes any, imports *javaImports) {
 	var typeNames []string
 
 	switch v := parentTypes.(type) {
@@ -387,14 +497,17 @@ func (pp *PostProcessor) resolveAndCreateInheritance(ctx context.Context, repo *
 			continue
 		}
 
-		// If multiple matches, try to pick the best one (same package if possible)
-		parentClass := pp.selectBestParentMatch(ctx, childClass, parentClasses)
+		// Pick the class the imports point at
+		parentClass := pp.selectImportedClass(ctx, imports, simpleName, parentClasses)
 		if parentClass == nil {
-			parentClass = parentClasses[0] // Default to first match
+			pp.logger.Debug("Parent class imported from outside the repo",
+				zap.String("childClass", childClass.Name),
+				zap.String("parentName", typeName))
+			continue
 		}
 
 		// Create INHERITS relationship: childClass INHERITS parentClass
-		err = pp.codeGraph.CreateInheritsRelation(ctx, parentClass.ID, childClass.ID, childClass.FileID)
+		err = pp.codeGraph.CreateInheritsRelation(ctx, childClass.ID, parentClass.ID, childClass.FileID)
 		if err != nil {
 			pp.logger.Error("Failed to create INHERITS relation",
 				zap.String("childClass", childClass.Name),
@@ -411,31 +524,46 @@ func (pp *PostProcessor) resolveAndCreateInheritance(ctx context.Context, repo *
 	}
 }
 
-// selectBestParentMatch selects the best matching parent class when multiple classes
-// with the same name exist. Prefers classes in the same package/module.
-func (pp *PostProcessor) selectBestParentMatch(ctx context.Context, childClass *ast.Node, parentClasses []*ast.Node) *ast.Node {
-	if len(parentClasses) == 1 {
-		return parentClasses[0]
+// processInterfaceDispatch links calls of interface methods to the methods
+// implementing them, so traversals can follow polymorphic calls. Calls and
+// inheritance of other files may still sit in the write buffers.
+func (pp *PostProcessor) processInterfaceDispatch(ctx context.Context, repo *config.Repository) error {
+	if err := pp.codeGraph.Flush(ctx, nil); err != nil {
+		return fmt.Errorf("failed to flush code graph: %w", err)
 	}
-
-	// Get the child's module name for comparison
-	childModuleName, err := pp.codeGraph.GetModuleName(ctx, childClass.FileID)
+	edges, err := pp.codeGraph.CreatePossibleCallsRelations(ctx, repo.Name)
 	if err != nil {
-		return nil
+		return err
 	}
+	pp.logger.Info("Created POSSIBLE_CALLS relations", zap.String("name", repo.Name), zap.Int64("edges", edges))
+	return nil
+}
 
-	// Prefer parent in the same module/package
-	for _, parent := range parentClasses {
-		parentModuleName, err := pp.codeGraph.GetModuleName(ctx, parent.FileID)
+// selectImportedClass picks which of the repository classes named name a
+// Java file refers to: the one it imports explicitly, else one in its own
+// package, else one from a wildcard import. Without any of those the first
+// candidate is used, unless the name is imported from a package with no
+// candidate, in which case the class is outside the repository and nil is
+// returned.
+func (pp *PostProcessor) selectImportedClass(ctx context.Context, imports *javaImports, name string, classes []*ast.Node) *ast.Node {
+	var best *ast.Node
+	bestRank := 0
+	for _, class := range classes {
+		module, err := pp.codeGraph.GetModuleName(ctx, class.FileID)
 		if err != nil {
 			continue
 		}
-		if parentModuleName == childModuleName {
-			return parent
+		if rank := imports.classRank(name, module); rank > bestRank {
+			best, bestRank = class, rank
 		}
 	}
-
-	return nil
+	if best != nil {
+		return best
+	}
+	if _, imported := imports.explicit[name]; imported {
+		return nil
+	}
+	return classes[0]
 }
 
 // extractSimpleName extracts the simple class name from a potentially qualified name.
@@ -450,7 +578,7 @@ func extractSimpleName(name string) string {
 
 // processConstructorCalls resolves constructor calls (new expressions) to their constructor definitions.
 // This uses the code graph to find matching classes and their constructors without LSP calls.
-func (pp *PostProcessor) processConstructorCalls(ctx context.Context, repo *config.Repository, fileScope *ast.Node) error {
+func (pp *PostProcessor) processConstructorCalls(ctx context.Context, repo *config.Repository, fileScope *ast.Node, imports *javaImports) error {
 	// Find all constructor calls in this file
 	constructorCalls, err := pp.codeGraph.FindConstructorCallsInFile(ctx, fileScope.FileID)
 	if err != nil {
@@ -466,14 +594,14 @@ func (pp *PostProcessor) processConstructorCalls(ctx context.Context, repo *conf
 		zap.String("file", fileScope.MetaData["path"].(string)))
 
 	for _, call := range constructorCalls {
-		pp.resolveConstructorCall(ctx, repo, call)
+		pp.resolveConstructorCall(ctx, repo, call, imports)
 	}
 
 	return nil
 }
 
 // resolveConstructorCall resolves a single constructor call to its definition.
-func (pp *PostProcessor) resolveConstructorCall(ctx context.Context, repo *config.Repository, call *ast.Node) {
+func (pp *PostProcessor) resolveConstructorCall(ctx context.Context, repo *config.Repository, call *ast.Node, imports *javaImports) {
 	// The call name is the class name being constructed (e.g., "Pet" from "new Pet()")
 	className := call.Name
 	if className == "" {
@@ -492,7 +620,13 @@ func (pp *PostProcessor) resolveConstructorCall(ctx context.Context, repo *confi
 		return
 	}
 
-	if len(classes) == 0 {
+	// Select the class the imports point at
+	var targetClass *ast.Node
+	if len(classes) > 0 {
+		targetClass = pp.selectImportedClass(ctx, imports, simpleName, classes)
+	}
+
+	if targetClass == nil {
 		// Class not found - likely external (e.g., java.util.ArrayList)
 		pp.logger.Debug("Class not found for constructor call (likely external)",
 			zap.String("className", simpleName),
@@ -507,12 +641,6 @@ func (pp *PostProcessor) resolveConstructorCall(ctx context.Context, repo *confi
 		return
 	}
 
-	// Select the best matching class (prefer same package)
-	targetClass := pp.selectBestClassMatch(ctx, call, classes)
-	if targetClass == nil {
-		targetClass = classes[0]
-	}
-
 	// Find constructors of the target class
 	constructors, err := pp.codeGraph.GetConstructorsOfClass(ctx, targetClass.ID)
 	if err != nil {
@@ -541,6 +669,7 @@ func (pp *PostProcessor) resolveConstructorCall(ctx context.Context, repo *confi
 			zap.Error(err))
 		return
 	}
+	pp.linkArguments(ctx, call, constructor.ID)
 
 	pp.logger.Info("Resolved constructor call",
 		zap.String("className", className),
@@ -549,29 +678,46 @@ func (pp *PostProcessor) resolveConstructorCall(ctx context.Context, repo *confi
 		zap.Int64("constructorId", int64(constructor.ID)))
 }
 
-// selectBestClassMatch selects the best matching class when multiple classes
-// with the same name exist. Prefers class in the same package/module as the caller.
-func (pp *PostProcessor) selectBestClassMatch(ctx context.Context, call *ast.Node, classes []*ast.Node) *ast.Node {
-	if len(classes) == 1 {
-		return classes[0]
-	}
-
-	// Get the caller's module name
-	callerModuleName, err := pp.codeGraph.GetModuleName(ctx, call.FileID)
-	if err != nil {
-		return nil
+// resolveStaticImportCall links an unqualified call the language server did
+// not resolve to a method of a repository class imported with import static.
+// It reports whether a target was found.
+func (pp *PostProcessor) resolveStaticImportCall(ctx context.Context, repo *config.Repository, imports *javaImports, call *ast.Node) bool {
+	if strings.Contains(call.Name, ".") {
+		return false
 	}
 
-	// Prefer class in the same module/package
-	for _, class := range classes {
-		classModuleName, err := pp.codeGraph.GetModuleName(ctx, class.FileID)
+	for _, classPath := range imports.staticClassesFor(call.Name) {
+		module, className := splitClassPath(classPath)
+		classes, err := pp.codeGraph.FindClassesByNameInRepo(ctx, className, repo.Name)
 		if err != nil {
 			continue
 		}
-		if classModuleName == callerModuleName {
-			return class
+		for _, class := range classes {
+			if classModule, err := pp.codeGraph.GetModuleName(ctx, class.FileID); err != nil || classModule != module {
+				continue
+			}
+			methods, err := pp.codeGraph.GetMethodsOfClass(ctx, class.ID)
+			if err != nil {
+				continue
+			}
+			for _, method := range methods {
+				if method.Name != call.Name {
+					continue
+				}
+				if err := pp.codeGraph.CreateCallsFunctionRelation(ctx, call.ID, method.ID, call.FileID); err != nil {
+					pp.logger.Error("Failed to create CALLS_FUNCTION relation for static import",
+						zap.Int64("callId", int64(call.ID)),
+						zap.Error(err))
+					return false
+				}
+				pp.linkArguments(ctx, call, method.ID)
+				pp.logger.Info("Resolved call through static import",
+					zap.String("callName", call.Name),
+					zap.String("class", classPath),
+					zap.Int64("targetFunctionId", int64(method.ID)))
+				return true
+			}
 		}
 	}
-
-	return nil
+	return false
 }
@@ -0,0 +1,296 @@
+package controller
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/armchr/codeapi/internal/config"
+	"github.com/armchr/codeapi/internal/db"
+	"github.com/armchr/codeapi/internal/service/codegraph"
+	"github.com/armchr/codeapi/internal/service/vector"
+
+	"go.uber.org/zap"
+)
+
+// Stores checked by provisioning
+const (
+	StoreVector = "vector"
+	StoreSQL    = "sql"
+	StoreGraph  = "graph"
+)
+
+// ProvisioningFinding is a piece of storage that was missing or out of date
+// at startup
+type ProvisioningFinding struct {
+	Store   string `json:"store"`
+	Name    string `json:"name"`
+	Problem string `json:"problem"`
+	Created bool   `json:"created"` // fixed by provisioning
+}
+
+// ProvisioningReport lists what provisioning found
+type ProvisioningReport struct {
+	Findings []ProvisioningFinding `json:"findings"`
+	Errors   []string              `json:"errors,omitempty"` // stores that could not be checked
+}
+
+// Unresolved returns the findings provisioning did not fix
+func (r *ProvisioningReport) Unresolved() []ProvisioningFinding {
+	var unresolved []ProvisioningFinding
+	for _, f := range r.Findings {
+		if !f.Created {
+			unresolved = append(unresolved, f)
+		}
+	}
+	return unresolved
+}
+
+func (r *ProvisioningReport) add(f ProvisioningFinding) {
+	r.Findings = append(r.Findings, f)
+}
+
+func (r *ProvisioningReport) fail(format string, args ...any) {
+	r.Errors = append(r.Errors, fmt.Sprintf(format, args...))
+}
+
+// Provisioner reconciles the stores with what the enabled repositories need
+// before the server takes requests: the vector collection of each repository
+// with the vector size of the embedding model, the shared relational tables
+// at their latest schema version, and the graph indexes the queries rely on.
+// In create mode what is missing is created; otherwise it is only reported,
+// and requests that need it fail as before. Any of the services may be nil;
+// their store is skipped.
+type Provisioner struct {
+	chunkService *vector.CodeChunkService
+	dbConn       db.Connection
+	codeGraph    *codegraph.CodeGraph
+	config       *config.Config
+	logger       *zap.Logger
+}
+
+// NewProvisioner creates a provisioner over the services of the server
+func NewProvisioner(chunkService *vector.CodeChunkService, dbConn db.Connection, codeGraph *codegraph.CodeGraph, cfg *config.Config, logger *zap.Logger) *Provisioner {
+	return &Provisioner{
+		chunkService: chunkService,
+		dbConn:       dbConn,
+		codeGraph:    codeGraph,
+		config:       cfg,
+		logger:       logger,
+	}
+}
+
+// Run checks every store within the provisioning timeout and logs what it
+// found. A vector collection of the wrong size is never recreated, as that
+// would drop the index; it has to be rebuilt.
+func (p *Provisioner) Run(ctx context.Context) *ProvisioningReport {
+	provCfg := p.config.Provisioning.GetDefaults()
+	create := provCfg.Mode == config.ProvisionCreate
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(provCfg.TimeoutSeconds)*time.Second)
+	defer cancel()
+
+	start := time.Now()
+	report := &ProvisioningReport{}
+	if p.chunkService != nil {
+		p.provisionCollections(ctx, report, create)
+	}
+	if p.dbConn != nil {
+		p.provisionTables(report, create)
+	}
+	if p.codeGraph != nil {
+		p.provisionGraphIndexes(ctx, report, create)
+	}
+
+	for _, f := range report.Findings {
+		fields := []zap.Field{zap.String("store", f.Store), zap.String("name", f.Name), zap.String("problem", f.Problem)}
+		if f.Created {
+			p.logger.Info("Provisioned missing storage", fields...)
+		} else {
+			p.logger.Warn("Storage is not provisioned", fields...)
+		}
+	}
+	for _, e := range report.Errors {
+		p.logger.Warn("Provisioning check failed", zap.String("error", e))
+	}
+	p.logger.Info("Provisioning finished",
+		zap.String("mode", provCfg.Mode),
+		zap.Int("findings", len(report.Findings)),
+		zap.Int("unresolved", len(report.Unresolved())),
+		zap.Duration("elapsed", time.Since(start)))
+	return report
+}
+
+// provisionCollections checks the vector collection of every enabled
+// repository
+func (p *Provisioner) provisionCollections(ctx context.Context, report *ProvisioningReport, create bool) {
+	vectorDB := p.chunkService.GetVectorDB()
+	dimension := p.chunkService.GetEmbeddingModel().GetDimension()
+	sizes, _ := vectorDB.(vector.VectorSizeReader)
+
+	repos := p.config.Repositories()
+	for i := range repos {
+		repo := &repos[i]
+		if repo.Disabled {
+			continue
+		}
+		exists, err := vectorDB.CollectionExists(ctx, repo.Name)
+		if err != nil {
+			report.fail("vector collection %s: %v", repo.Name, err)
+			continue
+		}
+		if !exists {
+			finding := ProvisioningFinding{Store: StoreVector, Name: repo.Name, Problem: "collection missing"}
+			if create {
+				if err := p.chunkService.CreateCollection(ctx, repo.Name); err != nil {
+					report.fail("vector collection %s: %v", repo.Name, err)
+				} else {
+					finding.Created = true
+				}
+			}
+			report.add(finding)
+			continue
+		}
+		if sizes == nil {
+			continue
+		}
+		size, err := sizes.VectorSize(ctx, repo.Name)
+		if err != nil {
+			report.fail("vector collection %s: %v", repo.Name, err)
+			continue
+		}
+		if finding := vectorSizeFinding(repo.Name, size, dimension); finding != nil {
+			report.add(*finding)
+		}
+	}
+}
+
+// vectorSizeFinding reports a collection whose vectors are not the size the
+// embedding model produces, or nil if they are
+func vectorSizeFinding(collection string, size, dimension int) *ProvisioningFinding {
+	if size == dimension {
+		return nil
+	}
+	return &ProvisioningFinding{
+		Store:   StoreVector,
+		Name:    collection,
+		Problem: fmt.Sprintf("vector size %d, the embedding model produces %d; rebuild the index", size, dimension),
+	}
+}
+
+// provisionTables brings the shared tables to their latest version in create
+// mode, and reports those behind and legacy per-repository tables otherwise
+func (p *Provisioner) provisionTables(report *ProvisioningReport, create bool) {
+	sqlDB := p.dbConn.GetDB()
+	migrator := db.NewMigrator(sqlDB, p.logger)
+
+	statuses := make([]*db.MigrationStatus, 0, len(db.SharedTables))
+	for _, t := range db.SharedTables {
+		status, err := migrator.Status(t.Name, t.Migrations)
+		if err != nil {
+			report.fail("table %s: %v", t.Name, err)
+			continue
+		}
+		statuses = append(statuses, status)
+	}
+	findings := pendingTableFindings(statuses)
+
+	repos := p.config.Repositories()
+	for i := range repos {
+		if repos[i].Disabled {
+			continue
+		}
+		has, err := db.HasLegacyTables(sqlDB, repos[i].Name)
+		if err != nil {
+			report.fail("legacy tables of %s: %v", repos[i].Name, err)
+			continue
+		}
+		if has {
+			findings = append(findings, ProvisioningFinding{Store: StoreSQL, Name: repos[i].Name, Problem: "legacy per-repository tables not imported"})
+		}
+	}
+	if len(findings) == 0 || !create {
+		for _, f := range findings {
+			report.add(f)
+		}
+		return
+	}
+
+	failed := p.migrateTables(sqlDB, repos, report)
+	for _, f := range findings {
+		f.Created = !failed
+		report.add(f)
+	}
+}
+
+// migrateTables runs the schema migrations and legacy imports of every
+// shared table, and reports whether any failed
+func (p *Provisioner) migrateTables(sqlDB *sql.DB, repos []config.Repository, report *ProvisioningReport) bool {
+	failed := false
+	check := func(what string, err error) {
+		if err != nil {
+			report.fail("%s: %v", what, err)
+			failed = true
+		}
+	}
+	for i := range repos {
+		if repos[i].Disabled {
+			continue
+		}
+		check("file versions of "+repos[i].Name, db.EnsureFileVersionSchema(sqlDB, repos[i].Name, p.logger))
+		check("summaries of "+repos[i].Name, db.EnsureSummarySchema(sqlDB, repos[i].Name, p.logger))
+	}
+	check("table "+db.CodeNotesTable, db.EnsureCodeNoteSchema(sqlDB, p.logger))
+	check("table "+db.FeedbackTable, db.EnsureFeedbackSchema(sqlDB, p.logger))
+	check("table "+db.AuditLogTable, db.EnsureAuditLogSchema(sqlDB, p.logger))
+	check("table "+db.GraphOverflowTable, db.EnsureGraphOverflowSchema(sqlDB, p.logger))
+	return failed
+}
+
+// pendingTableFindings reports the tables with migrations still to apply
+func pendingTableFindings(statuses []*db.MigrationStatus) []ProvisioningFinding {
+	var findings []ProvisioningFinding
+	for _, s := range statuses {
+		if len(s.Pending) == 0 {
+			continue
+		}
+		problem := fmt.Sprintf("schema version %d of %d", s.CurrentVersion, s.LatestVersion)
+		if s.CurrentVersion == 0 {
+			problem = "table missing"
+		}
+		findings = append(findings, ProvisioningFinding{Store: StoreSQL, Name: s.Table, Problem: problem})
+	}
+	return findings
+}
+
+// provisionGraphIndexes creates or reports the indexes and constraints of
+// the graph schema that do not exist. Extra indexes are logged but kept.
+func (p *Provisioner) provisionGraphIndexes(ctx context.Context, report *ProvisioningReport, create bool) {
+	var schema *codegraph.SchemaReport
+	var err error
+	if create {
+		schema, err = p.codeGraph.EnsureSchema(ctx)
+	} else {
+		schema, err = p.codeGraph.CheckSchema(ctx)
+	}
+	if schema == nil {
+		report.fail("graph schema: %v", err)
+		return
+	}
+	if err != nil {
+		report.fail("graph schema: %v", err)
+	}
+
+	for _, index := range schema.Missing {
+		report.add(ProvisioningFinding{
+			Store:   StoreGraph,
+			Name:    index.String(),
+			Problem: "index missing",
+			Created: slices.ContainsFunc(schema.Created, func(c codegraph.GraphIndex) bool { return c.Name == index.Name }),
+		})
+	}
+	for _, index := range schema.Extra {
+		p.logger.Debug("Graph index not required by codeapi", zap.String("name", index.Name), zap.String("index", index.String()))
+	}
+}
@@ -0,0 +1,44 @@
+package controller
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/armchr/codeapi/internal/db"
+)
+
+func TestPendingTableFindings(t *testing.T) {
+	statuses := []*db.MigrationStatus{
+		{Table: "file_versions", CurrentVersion: 3, LatestVersion: 3},
+		{Table: "code_notes", CurrentVersion: 0, LatestVersion: 2, Pending: []int{1, 2}},
+		{Table: "feedback", CurrentVersion: 1, LatestVersion: 2, Pending: []int{2}},
+	}
+	want := []ProvisioningFinding{
+		{Store: StoreSQL, Name: "code_notes", Problem: "table missing"},
+		{Store: StoreSQL, Name: "feedback", Problem: "schema version 1 of 2"},
+	}
+	if got := pendingTableFindings(statuses); !reflect.DeepEqual(got, want) {
+		t.Errorf("pendingTableFindings() = %+v, want %+v", got, want)
+	}
+}
+
+func TestVectorSizeFinding(t *testing.T) {
+	if f := vectorSizeFinding("shop", 768, 768); f != nil {
+		t.Errorf("vectorSizeFinding() = %+v for matching sizes", f)
+	}
+	f := vectorSizeFinding("shop", 384, 768)
+	if f == nil || f.Store != StoreVector || f.Name != "shop" || f.Created {
+		t.Errorf("vectorSizeFinding() = %+v, want an unresolved finding for shop", f)
+	}
+}
+
+func TestProvisioningReportUnresolved(t *testing.T) {
+	report := &ProvisioningReport{Findings: []ProvisioningFinding{
+		{Store: StoreVector, Name: "shop", Created: true},
+		{Store: StoreGraph, Name: ":Function(id)"},
+	}}
+	unresolved := report.Unresolved()
+	if len(unresolved) != 1 || unresolved[0].Store != StoreGraph {
+		t.Errorf("Unresolved() = %+v", unresolved)
+	}
+}
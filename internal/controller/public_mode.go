@@ -0,0 +1,30 @@
+package controller
+
+import "github.com/armchr/codeapi/internal/model"
+
+// publicMode reports whether responses must leave out raw source, see
+// config.App.PublicMode
+func (rc *RepoController) publicMode() bool {
+	return rc.config != nil && rc.config.App.PublicMode
+}
+
+// redactChunk returns a copy of chunk without its source text. Its ranges,
+// name, signature and docstring are kept, so a hit can still be located and
+// described.
+func redactChunk(chunk *model.CodeChunk) *model.CodeChunk {
+	if chunk == nil {
+		return nil
+	}
+	redacted := *chunk
+	redacted.Content = ""
+	return &redacted
+}
+
+// redactChunks applies redactChunk to each of chunks
+func redactChunks(chunks []*model.CodeChunk) []*model.CodeChunk {
+	redacted := make([]*model.CodeChunk, len(chunks))
+	for i, chunk := range chunks {
+		redacted[i] = redactChunk(chunk)
+	}
+	return redacted
+}
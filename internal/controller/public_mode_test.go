@@ -0,0 +1,46 @@
+package controller
+
+import (
+	"testing"
+
+	"github.com/armchr/codeapi/internal/config"
+	"github.com/armchr/codeapi/internal/model"
+)
+
+func TestRedactChunks(t *testing.T) {
+	chunk := &model.CodeChunk{ID: "c1", Content: "func total() int { return 1 }", Name: "total", Signature: "func total() int", StartLine: 3, EndLine: 5}
+	got := redactChunks([]*model.CodeChunk{chunk, nil})
+
+	if got[0].Content != "" {
+		t.Errorf("content = %q, want it redacted", got[0].Content)
+	}
+	if got[0].ID != "c1" || got[0].Signature != chunk.Signature || got[0].StartLine != 3 || got[0].EndLine != 5 {
+		t.Errorf("redacted chunk lost its metadata: %+v", got[0])
+	}
+	if chunk.Content == "" {
+		t.Error("redactChunks modified the original chunk")
+	}
+	if got[1] != nil {
+		t.Errorf("nil chunk redacted to %+v", got[1])
+	}
+}
+
+func TestPublicMode(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  *config.Config
+		want bool
+	}{
+		{"no config", nil, false},
+		{"private", &config.Config{}, false},
+		{"public", &config.Config{App: config.App{PublicMode: true}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rc := &RepoController{config: tt.cfg}
+			if got := rc.publicMode(); got != tt.want {
+				t.Errorf("publicMode() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
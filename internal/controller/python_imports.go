@@ -0,0 +1,347 @@
+package controller
+
+import (
+	"context"
+	"path"
+	"strings"
+
+	"github.com/armchr/codeapi/internal/model/ast"
+	"github.com/armchr/codeapi/internal/parse"
+
+	"go.uber.org/zap"
+)
+
+// maxReexportDepth bounds how many modules a re-exported name is followed
+// through, which also stops import cycles between packages
+const maxReexportDepth = 6
+
+// pythonModules maps the dotted module names of a repository's Python files
+// to their paths. Source roots are not known, so every path suffix is a
+// name: src/pkg/mod.py is src.pkg.mod, pkg.mod and mod, and a package is
+// named after the directory of its __init__.py.
+type pythonModules struct {
+	paths  map[string]bool
+	byName map[string][]string
+}
+
+func newPythonModules(filePaths []string) *pythonModules {
+	m := &pythonModules{paths: make(map[string]bool), byName: make(map[string][]string)}
+	for _, p := range filePaths {
+		if !strings.HasSuffix(p, ".py") {
+			continue
+		}
+		m.paths[p] = true
+		parts := strings.Split(strings.TrimSuffix(p, ".py"), "/")
+		if parts[len(parts)-1] == "__init__" {
+			parts = parts[:len(parts)-1]
+		}
+		for i := range parts {
+			name := strings.Join(parts[i:], ".")
+			m.byName[name] = append(m.byName[name], p)
+		}
+	}
+	return m
+}
+
+// resolve returns the file of module as imported from fromPath, or "" when
+// the module is not part of the repository. Relative modules (.mod, ..pkg)
+// are looked up from the directory of fromPath. Of several files with the
+// same absolute name, the one sharing the most directories with fromPath wins.
+func (m *pythonModules) resolve(fromPath, module string) string {
+	rest := strings.TrimLeft(module, ".")
+	if dots := len(module) - len(rest); dots > 0 {
+		dir := path.Dir(fromPath)
+		for i := 1; i < dots; i++ {
+			if dir == "." || dir == "/" {
+				return ""
+			}
+			dir = path.Dir(dir)
+		}
+		if rest == "" {
+			if p := path.Join(dir, "__init__.py"); m.paths[p] {
+				return p
+			}
+			return ""
+		}
+		base := path.Join(dir, strings.ReplaceAll(rest, ".", "/"))
+		for _, p := range []string{base + ".py", path.Join(base, "__init__.py")} {
+			if m.paths[p] {
+				return p
+			}
+		}
+		return ""
+	}
+
+	best, bestShared := "", -1
+	for _, candidate := range m.byName[module] {
+		if shared := sharedDirs(fromPath, candidate); shared > bestShared {
+			best, bestShared = candidate, shared
+		}
+	}
+	return best
+}
+
+func sharedDirs(a, b string) int {
+	da := strings.Split(path.Dir(a), "/")
+	db := strings.Split(path.Dir(b), "/")
+	n := 0
+	for n < len(da) && n < len(db) && da[n] == db[n] {
+		n++
+	}
+	return n
+}
+
+// pythonFile holds the module-level names a Python file defines or imports
+type pythonFile struct {
+	path      string
+	classes   map[string]*ast.Node
+	functions map[string]*ast.Node
+	methods   map[ast.NodeID]map[string]*ast.Node // class ID -> methods by name
+	imports   map[string]*ast.Node
+	wildcards []*ast.Node
+}
+
+func newPythonFile(filePath string, classes, functions, imports []*ast.Node) *pythonFile {
+	f := &pythonFile{
+		path:      filePath,
+		classes:   make(map[string]*ast.Node),
+		functions: make(map[string]*ast.Node),
+		methods:   make(map[ast.NodeID]map[string]*ast.Node),
+		imports:   make(map[string]*ast.Node),
+	}
+	for _, class := range classes {
+		if !insideAny(class, classes) && !insideAny(class, functions) {
+			f.classes[class.Name] = class
+		}
+	}
+	for _, fn := range functions {
+		if insideAny(fn, functions) {
+			continue
+		}
+		if class := innermostContaining(fn, classes); class != nil {
+			if f.methods[class.ID] == nil {
+				f.methods[class.ID] = make(map[string]*ast.Node)
+			}
+			f.methods[class.ID][fn.Name] = fn
+			continue
+		}
+		f.functions[fn.Name] = fn
+	}
+	for _, imp := range imports {
+		if imp.Name == parse.PythonWildcardImport {
+			f.wildcards = append(f.wildcards, imp)
+		} else {
+			f.imports[imp.Name] = imp
+		}
+	}
+	return f
+}
+
+// insideAny reports whether n lies within one of nodes other than itself
+func insideAny(n *ast.Node, nodes []*ast.Node) bool {
+	return innermostContaining(n, nodes) != nil
+}
+
+func innermostContaining(n *ast.Node, nodes []*ast.Node) *ast.Node {
+	var best *ast.Node
+	for _, candidate := range nodes {
+		if candidate.ID == n.ID || candidate.Range == n.Range || !candidate.Range.ContainsRange(&n.Range) {
+			continue
+		}
+		if best == nil || best.Range.ContainsRange(&candidate.Range) {
+			best = candidate
+		}
+	}
+	return best
+}
+
+// pythonTarget is what a dotted Python name refers to: a module when node
+// is nil, else a class or function defined in the module at path
+type pythonTarget struct {
+	path string
+	node *ast.Node
+}
+
+// pythonResolver follows Python imports, through package __init__ re-exports
+// and relative imports, to the definitions they name
+type pythonResolver struct {
+	modules *pythonModules
+	paths   map[int32]string       // file ID -> path
+	files   map[string]*pythonFile // nil for files that could not be read
+	load    func(path string) *pythonFile
+}
+
+func (r *pythonResolver) file(filePath string) *pythonFile {
+	f, ok := r.files[filePath]
+	if !ok && r.load != nil {
+		f = r.load(filePath)
+		r.files[filePath] = f
+	}
+	return f
+}
+
+// resolveCall returns the function a call written as selector (f, mod.f,
+// pkg.mod.Class) in the file at fromPath runs. Calling a class runs its
+// __init__.
+func (r *pythonResolver) resolveCall(fromPath, selector string) (*ast.Node, bool) {
+	from := r.file(fromPath)
+	if from == nil {
+		return nil, false
+	}
+	parts := strings.Split(selector, ".")
+	imp := from.imports[parts[0]]
+	if imp == nil {
+		return nil, false
+	}
+
+	target, ok := r.importTarget(from, imp, 0)
+	for _, part := range parts[1:] {
+		if !ok {
+			break
+		}
+		target, ok = r.attribute(target, part, 0)
+	}
+	if !ok || target.node == nil {
+		return nil, false
+	}
+	if target.node.NodeType == ast.NodeTypeClass {
+		init := r.file(target.path).methods[target.node.ID]["__init__"]
+		return init, init != nil
+	}
+	return target.node, target.node.NodeType == ast.NodeTypeFunction
+}
+
+// importTarget returns what the name bound by import node imp refers to
+func (r *pythonResolver) importTarget(from *pythonFile, imp *ast.Node, depth int) (pythonTarget, bool) {
+	module, _ := imp.MetaData[parse.MetaImportModule].(string)
+	member, _ := imp.MetaData[parse.MetaImportMember].(string)
+	if member == "" {
+		// import a.b binds a, import a.b as ab binds a.b
+		if first, _, _ := strings.Cut(module, "."); imp.Name == first {
+			module = first
+		}
+		p := r.modules.resolve(from.path, module)
+		return pythonTarget{path: p}, p != ""
+	}
+
+	p := r.modules.resolve(from.path, module)
+	if p == "" {
+		return pythonTarget{}, false
+	}
+	return r.member(p, member, depth+1)
+}
+
+// attribute returns target.name: a member of a module or a nested
+// definition of a class
+func (r *pythonResolver) attribute(target pythonTarget, name string, depth int) (pythonTarget, bool) {
+	if target.node == nil {
+		return r.member(target.path, name, depth)
+	}
+	if target.node.NodeType != ast.NodeTypeClass {
+		return pythonTarget{}, false
+	}
+	f := r.file(target.path)
+	if method := f.methods[target.node.ID][name]; method != nil {
+		return pythonTarget{path: target.path, node: method}, true
+	}
+	return pythonTarget{}, false
+}
+
+// member returns the module-level name of the module at modulePath,
+// following it through the imports that re-export it. A name the module
+// does not bind may still be one of its submodules.
+func (r *pythonResolver) member(modulePath, name string, depth int) (pythonTarget, bool) {
+	if depth > maxReexportDepth {
+		return pythonTarget{}, false
+	}
+	if f := r.file(modulePath); f != nil {
+		if class := f.classes[name]; class != nil {
+			return pythonTarget{path: modulePath, node: class}, true
+		}
+		if fn := f.functions[name]; fn != nil {
+			return pythonTarget{path: modulePath, node: fn}, true
+		}
+		if imp := f.imports[name]; imp != nil {
+			return r.importTarget(f, imp, depth)
+		}
+		// import * does not bring in private names
+		for _, wildcard := range f.wildcards {
+			if strings.HasPrefix(name, "_") {
+				break
+			}
+			module, _ := wildcard.MetaData[parse.MetaImportModule].(string)
+			if p := r.modules.resolve(modulePath, module); p != "" {
+				if target, ok := r.member(p, name, depth+1); ok {
+					return target, true
+				}
+			}
+		}
+	}
+
+	if path.Base(modulePath) == "__init__.py" {
+		if p := r.modules.resolve(modulePath, "."+name); p != "" {
+			return pythonTarget{path: p}, true
+		}
+	}
+	return pythonTarget{}, false
+}
+
+// newPythonResolver returns a resolver over the Python files among
+// fileScopes, reading their definitions from the code graph on demand. It
+// returns nil when the repository has no Python files.
+func (pp *PostProcessor) newPythonResolver(ctx context.Context, fileScopes []*ast.Node) *pythonResolver {
+	paths := make(map[int32]string)
+	fileIDs := make(map[string]int32)
+	var filePaths []string
+	for _, fileScope := range fileScopes {
+		filePath, _ := fileScope.MetaData["path"].(string)
+		if strings.HasSuffix(filePath, ".py") {
+			paths[fileScope.FileID] = filePath
+			fileIDs[filePath] = fileScope.FileID
+			filePaths = append(filePaths, filePath)
+		}
+	}
+	if len(filePaths) == 0 {
+		return nil
+	}
+	return &pythonResolver{
+		modules: newPythonModules(filePaths),
+		paths:   paths,
+		files:   make(map[string]*pythonFile),
+		load: func(filePath string) *pythonFile {
+			fileID, ok := fileIDs[filePath]
+			if !ok {
+				return nil
+			}
+			classes, _ := pp.codeGraph.GetNodesByTypeAndFileID(ctx, ast.NodeTypeClass, fileID)
+			functions, _ := pp.codeGraph.GetNodesByTypeAndFileID(ctx, ast.NodeTypeFunction, fileID)
+			imports, _ := pp.codeGraph.GetNodesByTypeAndFileID(ctx, ast.NodeTypeImport, fileID)
+			return newPythonFile(filePath, classes, functions, imports)
+		},
+	}
+}
+
+// resolvePythonImportCall links a call the language server did not resolve
+// to a repository function to the definition its imports lead to. It
+// reports whether a target was found.
+func (pp *PostProcessor) resolvePythonImportCall(ctx context.Context, python *pythonResolver, call *ast.Node) bool {
+	selector, _ := call.MetaData[parse.MetaSelector].(string)
+	if selector == "" {
+		selector = call.Name
+	}
+	target, ok := python.resolveCall(python.paths[call.FileID], selector)
+	if !ok {
+		return false
+	}
+	if err := pp.codeGraph.CreateCallsFunctionRelation(ctx, call.ID, target.ID, call.FileID); err != nil {
+		pp.logger.Error("Failed to create CALLS_FUNCTION relation for Python import",
+			zap.Int64("callId", int64(call.ID)),
+			zap.Error(err))
+		return false
+	}
+	pp.linkArguments(ctx, call, target.ID)
+	pp.logger.Info("Resolved call through Python imports",
+		zap.String("selector", selector),
+		zap.Int64("targetFunctionId", int64(target.ID)))
+	return true
+}
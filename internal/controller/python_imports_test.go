@@ -0,0 +1,164 @@
+package controller
+
+import (
+	"testing"
+
+	"github.com/armchr/codeapi/internal/model/ast"
+	"github.com/armchr/codeapi/internal/parse"
+)
+
+var pythonFixturePaths = []string{
+	"src/shop/__init__.py",
+	"src/shop/orders/__init__.py",
+	"src/shop/orders/service.py",
+	"src/shop/orders/_impl.py",
+	"src/shop/billing.py",
+	"src/shop/utils.py",
+	"tests/utils.py",
+	"tests/test_orders.py",
+	"README.md",
+}
+
+func TestPythonModulesResolve(t *testing.T) {
+	modules := newPythonModules(pythonFixturePaths)
+
+	tests := []struct {
+		from   string
+		module string
+		want   string
+	}{
+		{"tests/test_orders.py", "shop", "src/shop/__init__.py"},
+		{"tests/test_orders.py", "shop.orders", "src/shop/orders/__init__.py"},
+		{"tests/test_orders.py", "src.shop.orders.service", "src/shop/orders/service.py"},
+		{"tests/test_orders.py", "utils", "tests/utils.py"},
+		{"src/shop/billing.py", "utils", "src/shop/utils.py"},
+		{"src/shop/orders/service.py", ".", "src/shop/orders/__init__.py"},
+		{"src/shop/orders/service.py", "._impl", "src/shop/orders/_impl.py"},
+		{"src/shop/orders/service.py", "..billing", "src/shop/billing.py"},
+		{"src/shop/orders/service.py", "..", "src/shop/__init__.py"},
+		{"src/shop/orders/__init__.py", ".service", "src/shop/orders/service.py"},
+		{"src/shop/__init__.py", ".orders", "src/shop/orders/__init__.py"},
+		{"src/shop/billing.py", "....shop", ""},
+		{"src/shop/billing.py", "requests", ""},
+		{"src/shop/billing.py", ".missing", ""},
+	}
+	for _, tt := range tests {
+		if got := modules.resolve(tt.from, tt.module); got != tt.want {
+			t.Errorf("resolve(%q, %q) = %q, want %q", tt.from, tt.module, got, tt.want)
+		}
+	}
+}
+
+func pythonImport(id ast.NodeID, name, module, member string) *ast.Node {
+	md := map[string]any{parse.MetaImportModule: module}
+	if member != "" {
+		md[parse.MetaImportMember] = member
+	}
+	return &ast.Node{ID: id, NodeType: ast.NodeTypeImport, Name: name, MetaData: md}
+}
+
+func pythonDef(id ast.NodeID, nodeType ast.NodeType, name string, startLine, endLine int) *ast.Node {
+	n := rangedNode(id, name, startLine, 0, endLine, 0)
+	n.NodeType = nodeType
+	return n
+}
+
+// Files of
+//
+//	src/shop/__init__.py           from .orders import *
+//	                               from .billing import charge as bill
+//	src/shop/orders/__init__.py    from .service import OrderService, place_order
+//	                               from ._impl import _secret
+//	src/shop/orders/service.py     class OrderService: __init__, submit
+//	                               def place_order(): ...
+//	src/shop/orders/_impl.py       def _secret(): ...
+//	src/shop/billing.py            def charge(): ...
+//	                               from . import billing  (the module itself)
+//	                               from .utils import loop
+//	src/shop/utils.py              from .billing import loop
+//	tests/test_orders.py           imports below
+var (
+	orderService = pythonDef(1, ast.NodeTypeClass, "OrderService", 1, 10)
+	serviceInit  = pythonDef(2, ast.NodeTypeFunction, "__init__", 2, 4)
+	submit       = pythonDef(3, ast.NodeTypeFunction, "submit", 5, 9)
+	placeOrder   = pythonDef(4, ast.NodeTypeFunction, "place_order", 12, 14)
+	secret       = pythonDef(5, ast.NodeTypeFunction, "_secret", 1, 2)
+	charge       = pythonDef(6, ast.NodeTypeFunction, "charge", 1, 3)
+)
+
+func pythonFixture() *pythonResolver {
+	files := map[string]*pythonFile{
+		"src/shop/__init__.py": newPythonFile("src/shop/__init__.py", nil, nil, []*ast.Node{
+			pythonImport(10, parse.PythonWildcardImport, ".orders", parse.PythonWildcardImport),
+			pythonImport(11, "bill", ".billing", "charge"),
+		}),
+		"src/shop/orders/__init__.py": newPythonFile("src/shop/orders/__init__.py", nil, nil, []*ast.Node{
+			pythonImport(12, "OrderService", ".service", "OrderService"),
+			pythonImport(13, "place_order", ".service", "place_order"),
+			pythonImport(14, "_secret", "._impl", "_secret"),
+		}),
+		"src/shop/orders/service.py": newPythonFile("src/shop/orders/service.py",
+			[]*ast.Node{orderService},
+			[]*ast.Node{serviceInit, submit, placeOrder}, nil),
+		"src/shop/orders/_impl.py": newPythonFile("src/shop/orders/_impl.py", nil, []*ast.Node{secret}, nil),
+		"src/shop/billing.py": newPythonFile("src/shop/billing.py", nil, []*ast.Node{charge}, []*ast.Node{
+			pythonImport(15, "billing", ".", "billing"),
+			pythonImport(16, "loop", ".utils", "loop"),
+		}),
+		"src/shop/utils.py": newPythonFile("src/shop/utils.py", nil, nil, []*ast.Node{
+			pythonImport(17, "loop", ".billing", "loop"),
+		}),
+		"tests/test_orders.py": newPythonFile("tests/test_orders.py", nil, nil, []*ast.Node{
+			pythonImport(20, "place_order", "shop", "place_order"),
+			pythonImport(21, "Service", "shop.orders", "OrderService"),
+			pythonImport(22, "shop", "shop", ""),
+			pythonImport(23, "orders", "shop", "orders"),
+			pythonImport(24, "svc", "shop.orders.service", ""),
+			pythonImport(25, "bill", "shop", "bill"),
+			pythonImport(26, "_secret", "shop", "_secret"),
+			pythonImport(27, "billing", "shop.billing", "billing"),
+			pythonImport(28, "requests", "requests", ""),
+			pythonImport(29, "loop", "shop.billing", "loop"),
+		}),
+	}
+	return &pythonResolver{modules: newPythonModules(pythonFixturePaths), files: files}
+}
+
+func TestPythonResolveCall(t *testing.T) {
+	r := pythonFixture()
+
+	tests := []struct {
+		selector string
+		want     ast.NodeID
+	}{
+		{"place_order", placeOrder.ID},
+		{"Service", serviceInit.ID},
+		{"shop.place_order", placeOrder.ID},
+		{"shop.orders.OrderService.submit", submit.ID},
+		{"orders.place_order", placeOrder.ID},
+		{"svc.place_order", placeOrder.ID},
+		{"svc.OrderService", serviceInit.ID},
+		{"bill", charge.ID},
+		{"shop.bill", charge.ID},
+		// private names are not brought in by import *
+		{"_secret", ast.InvalidNodeID},
+		{"billing.charge", charge.ID},
+		{"loop", ast.InvalidNodeID},
+		{"shop.missing", ast.InvalidNodeID},
+		{"place_order.submit", ast.InvalidNodeID},
+		{"requests.get", ast.InvalidNodeID},
+		{"print", ast.InvalidNodeID},
+	}
+	for _, tt := range tests {
+		t.Run(tt.selector, func(t *testing.T) {
+			got, ok := r.resolveCall("tests/test_orders.py", tt.selector)
+			gotID := ast.InvalidNodeID
+			if ok {
+				gotID = got.ID
+			}
+			if gotID != tt.want {
+				t.Errorf("resolveCall(%q) = %d, want %d", tt.selector, gotID, tt.want)
+			}
+		})
+	}
+}
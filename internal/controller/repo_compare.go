@@ -0,0 +1,433 @@
+package controller
+
+import (
+	"cmp"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/armchr/codeapi/internal/codeapi"
+	"github.com/armchr/codeapi/internal/db"
+	"github.com/armchr/codeapi/internal/logging"
+	"github.com/armchr/codeapi/internal/service/summary"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// Defaults of a repository comparison
+const (
+	defaultCompareLimit      = 100
+	defaultSummaryDifference = 0.5
+)
+
+// CompareReposRequest names the two repositories to compare, such as a fork
+// and its upstream. Only files whose path starts with PathPrefix are
+// compared.
+type CompareReposRequest struct {
+	RepoName      string `json:"repo_name" binding:"required"`
+	OtherRepoName string `json:"other_repo_name" binding:"required"`
+	PathPrefix    string `json:"path_prefix"`
+	Limit         int    `json:"limit"` // entries per list, default: 100
+
+	// MaxSummarySimilarity lists the functions and files whose summaries
+	// share at most this fraction of their words, default: 0.5
+	MaxSummarySimilarity float64 `json:"max_summary_similarity"`
+}
+
+// MovedFile is a file with the same content at different paths
+type MovedFile struct {
+	Path      string `json:"path"`
+	OtherPath string `json:"other_path"`
+}
+
+// FileComparison compares the newest version of each file of two
+// repositories by path and SHA
+type FileComparison struct {
+	Identical   int         `json:"identical"`
+	Diverged    []string    `json:"diverged"`
+	Moved       []MovedFile `json:"moved"`
+	OnlyInRepo  []string    `json:"only_in_repo"`
+	OnlyInOther []string    `json:"only_in_other"`
+}
+
+// FunctionRef names a function by file, class and name, which is what is
+// matched across repositories
+type FunctionRef struct {
+	FilePath  string `json:"file_path"`
+	ClassName string `json:"class_name,omitempty"`
+	Name      string `json:"name"`
+}
+
+// FunctionComparison compares the functions of two repositories. Functions
+// in both are identical when their bodies hash alike.
+type FunctionComparison struct {
+	Identical   int           `json:"identical"`
+	Changed     []FunctionRef `json:"changed"`
+	OnlyInRepo  []FunctionRef `json:"only_in_repo"`
+	OnlyInOther []FunctionRef `json:"only_in_other"`
+}
+
+// SummaryDifference is a changed function, or a diverged file, whose
+// summaries in the two repositories tell different stories
+type SummaryDifference struct {
+	EntityType   summary.SummaryLevel `json:"entity_type"`
+	FilePath     string               `json:"file_path"`
+	ClassName    string               `json:"class_name,omitempty"`
+	Name         string               `json:"name,omitempty"`
+	Similarity   float64              `json:"similarity"` // shared fraction of words
+	Summary      string               `json:"summary"`
+	OtherSummary string               `json:"other_summary"`
+}
+
+// RepoComparison is the report of GetRepoComparison. Sections of stores
+// that are not configured or failed to answer are left out and the reason
+// is given in Errors, keyed by store; lists longer than the limit are cut
+// and Truncated is set.
+type RepoComparison struct {
+	RepoName           string               `json:"repo_name"`
+	OtherRepoName      string               `json:"other_repo_name"`
+	Files              *FileComparison      `json:"files,omitempty"`
+	Functions          *FunctionComparison  `json:"functions,omitempty"`
+	SummaryDifferences []*SummaryDifference `json:"summary_differences,omitempty"`
+	Truncated          bool                 `json:"truncated"`
+	Errors             map[string]string    `json:"errors,omitempty"`
+}
+
+// GetRepoComparison compares two indexed repositories: the files they share
+// or diverge on, the functions present in one but not the other, and the
+// changed functions and files whose summaries differ, to help consolidate a
+// fork with its upstream
+func (rc *RepoController) GetRepoComparison(c *gin.Context) {
+	logger := logging.FromContext(c.Request.Context(), rc.logger)
+	var req CompareReposRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request payload",
+			"details": err.Error(),
+		})
+		return
+	}
+	if req.Limit <= 0 {
+		req.Limit = defaultCompareLimit
+	}
+	if req.MaxSummarySimilarity <= 0 {
+		req.MaxSummarySimilarity = defaultSummaryDifference
+	}
+
+	ctx := c.Request.Context()
+	resp := &RepoComparison{RepoName: req.RepoName, OtherRepoName: req.OtherRepoName}
+	failed := func(store string, err error) {
+		if resp.Errors == nil {
+			resp.Errors = map[string]string{}
+		}
+		resp.Errors[store] = err.Error()
+		logger.Warn("Failed to compare repositories", zap.String("repo_name", req.RepoName),
+			zap.String("other_repo_name", req.OtherRepoName), zap.String("store", store), zap.Error(err))
+	}
+
+	if rc.dbConn == nil {
+		failed("db", errNotConfigured)
+	} else {
+		versions, err := db.NewFileVersionReader(rc.dbConn.GetDB(), req.RepoName, rc.logger).WithContext(ctx).GetAllVersions()
+		var otherVersions []*db.FileVersion
+		if err == nil {
+			otherVersions, err = db.NewFileVersionReader(rc.dbConn.GetDB(), req.OtherRepoName, rc.logger).WithContext(ctx).GetAllVersions()
+		}
+		if err != nil {
+			failed("db", err)
+		} else {
+			resp.Files = CompareFiles(versions, otherVersions, req.PathPrefix)
+		}
+	}
+
+	var changed [][2]*codeapi.FunctionVersion
+	if rc.codeGraph == nil {
+		failed("neo4j", errNotConfigured)
+	} else {
+		analyzer := codeapi.NewCodeAPI(rc.codeGraph, rc.logger).Analyzer()
+		functions, err := analyzer.ListFunctions(ctx, req.RepoName, req.PathPrefix)
+		var otherFunctions []*codeapi.FunctionVersion
+		if err == nil {
+			otherFunctions, err = analyzer.ListFunctions(ctx, req.OtherRepoName, req.PathPrefix)
+		}
+		if err != nil {
+			failed("neo4j", err)
+		} else {
+			resp.Functions, changed = CompareFunctions(functions, otherFunctions)
+		}
+	}
+
+	if rc.dbConn != nil && (len(changed) > 0 || (resp.Files != nil && len(resp.Files.Diverged) > 0)) {
+		if exists, err := db.TableExists(rc.dbConn.GetDB(), db.CodeSummariesTable); err != nil {
+			failed("db", err)
+		} else if exists {
+			store := db.NewSummaryReader(rc.dbConn.GetDB(), req.RepoName, rc.logger).WithContext(ctx)
+			otherStore := db.NewSummaryReader(rc.dbConn.GetDB(), req.OtherRepoName, rc.logger).WithContext(ctx)
+			differences, err := summaryDifferences(store, otherStore, changed, resp.Files, req.MaxSummarySimilarity)
+			if err != nil {
+				failed("db", err)
+			} else {
+				resp.SummaryDifferences = differences
+			}
+		}
+	}
+
+	resp.truncate(req.Limit)
+	c.JSON(http.StatusOK, resp)
+}
+
+// latestVersions returns the newest version of each file under prefix,
+// leaving out files indexed ad hoc into the sandbox
+func latestVersions(versions []*db.FileVersion, prefix string) map[string]*db.FileVersion {
+	latest := make(map[string]*db.FileVersion)
+	for _, v := range versions {
+		if v.Sandbox || !strings.HasPrefix(v.RelativePath, prefix) {
+			continue
+		}
+		if prev, ok := latest[v.RelativePath]; !ok || v.FileID > prev.FileID {
+			latest[v.RelativePath] = v
+		}
+	}
+	return latest
+}
+
+// CompareFiles compares the newest version of each file of two repositories
+// under prefix. A file found in only one of them is reported as moved when
+// the other holds a file with the same SHA at a path of its own.
+func CompareFiles(versions, otherVersions []*db.FileVersion, prefix string) *FileComparison {
+	files, otherFiles := latestVersions(versions, prefix), latestVersions(otherVersions, prefix)
+	result := &FileComparison{Diverged: []string{}, Moved: []MovedFile{}, OnlyInRepo: []string{}, OnlyInOther: []string{}}
+
+	otherBySHA := make(map[string]string)
+	for path, v := range otherFiles {
+		if _, ok := files[path]; !ok {
+			otherBySHA[v.FileSHA] = path
+		}
+	}
+	for path, v := range files {
+		other, ok := otherFiles[path]
+		switch {
+		case ok && other.FileSHA == v.FileSHA:
+			result.Identical++
+		case ok:
+			result.Diverged = append(result.Diverged, path)
+		case otherBySHA[v.FileSHA] != "":
+			result.Moved = append(result.Moved, MovedFile{Path: path, OtherPath: otherBySHA[v.FileSHA]})
+			delete(otherBySHA, v.FileSHA)
+		default:
+			result.OnlyInRepo = append(result.OnlyInRepo, path)
+		}
+	}
+	moved := make(map[string]bool, len(result.Moved))
+	for _, m := range result.Moved {
+		moved[m.OtherPath] = true
+	}
+	for path := range otherFiles {
+		if _, ok := files[path]; !ok && !moved[path] {
+			result.OnlyInOther = append(result.OnlyInOther, path)
+		}
+	}
+
+	slices.Sort(result.Diverged)
+	slices.Sort(result.OnlyInRepo)
+	slices.Sort(result.OnlyInOther)
+	slices.SortFunc(result.Moved, func(a, b MovedFile) int { return strings.Compare(a.Path, b.Path) })
+	return result
+}
+
+// CompareFunctions matches the functions of two repositories by file, class
+// and name, and returns their comparison with the pairs of changed
+// functions. Overloads sharing a name are compared as a whole.
+func CompareFunctions(functions, otherFunctions []*codeapi.FunctionVersion) (*FunctionComparison, [][2]*codeapi.FunctionVersion) {
+	group := func(fns []*codeapi.FunctionVersion) map[FunctionRef][]*codeapi.FunctionVersion {
+		groups := make(map[FunctionRef][]*codeapi.FunctionVersion)
+		for _, fn := range fns {
+			ref := FunctionRef{FilePath: fn.FilePath, ClassName: fn.ClassName, Name: fn.Name}
+			groups[ref] = append(groups[ref], fn)
+		}
+		return groups
+	}
+	bodies := func(fns []*codeapi.FunctionVersion) []string {
+		hashes := make([]string, len(fns))
+		for i, fn := range fns {
+			hashes[i] = fn.BodyHash
+		}
+		slices.Sort(hashes)
+		return hashes
+	}
+
+	groups, otherGroups := group(functions), group(otherFunctions)
+	result := &FunctionComparison{Changed: []FunctionRef{}, OnlyInRepo: []FunctionRef{}, OnlyInOther: []FunctionRef{}}
+	var changed [][2]*codeapi.FunctionVersion
+	for ref, fns := range groups {
+		others, ok := otherGroups[ref]
+		switch {
+		case !ok:
+			result.OnlyInRepo = append(result.OnlyInRepo, ref)
+		case slices.Equal(bodies(fns), bodies(others)):
+			result.Identical++
+		default:
+			result.Changed = append(result.Changed, ref)
+			changed = append(changed, [2]*codeapi.FunctionVersion{fns[0], others[0]})
+		}
+	}
+	for ref := range otherGroups {
+		if _, ok := groups[ref]; !ok {
+			result.OnlyInOther = append(result.OnlyInOther, ref)
+		}
+	}
+
+	for _, refs := range [][]FunctionRef{result.Changed, result.OnlyInRepo, result.OnlyInOther} {
+		slices.SortFunc(refs, compareFunctionRefs)
+	}
+	slices.SortFunc(changed, func(a, b [2]*codeapi.FunctionVersion) int {
+		return compareFunctionRefs(
+			FunctionRef{FilePath: a[0].FilePath, ClassName: a[0].ClassName, Name: a[0].Name},
+			FunctionRef{FilePath: b[0].FilePath, ClassName: b[0].ClassName, Name: b[0].Name})
+	})
+	return result, changed
+}
+
+func compareFunctionRefs(a, b FunctionRef) int {
+	return cmp.Or(strings.Compare(a.FilePath, b.FilePath), strings.Compare(a.ClassName, b.ClassName), strings.Compare(a.Name, b.Name))
+}
+
+// summaryDifferences returns the changed functions and diverged files whose
+// summaries share at most maxSimilarity of their words, least similar first.
+// Entities without a summary in both repositories are skipped.
+func summaryDifferences(store, otherStore *db.SummaryStore, changed [][2]*codeapi.FunctionVersion, files *FileComparison, maxSimilarity float64) ([]*SummaryDifference, error) {
+	var differences []*SummaryDifference
+	add := func(level summary.SummaryLevel, ref FunctionRef, cs, other *summary.CodeSummary) {
+		if cs == nil || other == nil {
+			return
+		}
+		if similarity := SummarySimilarity(cs.Summary, other.Summary); similarity <= maxSimilarity {
+			differences = append(differences, &SummaryDifference{
+				EntityType: level, FilePath: ref.FilePath, ClassName: ref.ClassName, Name: ref.Name,
+				Similarity: similarity, Summary: cs.Summary, OtherSummary: other.Summary,
+			})
+		}
+	}
+
+	if len(changed) > 0 {
+		ids, otherIDs := make([]string, len(changed)), make([]string, len(changed))
+		for i, pair := range changed {
+			ids[i] = strconv.FormatInt(int64(pair[0].ID), 10)
+			otherIDs[i] = strconv.FormatInt(int64(pair[1].ID), 10)
+		}
+		byID, err := functionSummaries(store, ids)
+		if err != nil {
+			return nil, err
+		}
+		otherByID, err := functionSummaries(otherStore, otherIDs)
+		if err != nil {
+			return nil, err
+		}
+		for i, pair := range changed {
+			ref := FunctionRef{FilePath: pair[0].FilePath, ClassName: pair[0].ClassName, Name: pair[0].Name}
+			add(summary.LevelFunction, ref, byID[ids[i]], otherByID[otherIDs[i]])
+		}
+	}
+
+	if files != nil && len(files.Diverged) > 0 {
+		byPath, err := fileSummaries(store)
+		if err != nil {
+			return nil, err
+		}
+		otherByPath, err := fileSummaries(otherStore)
+		if err != nil {
+			return nil, err
+		}
+		for _, path := range files.Diverged {
+			add(summary.LevelFile, FunctionRef{FilePath: path}, byPath[path], otherByPath[path])
+		}
+	}
+
+	slices.SortStableFunc(differences, func(a, b *SummaryDifference) int { return cmp.Compare(a.Similarity, b.Similarity) })
+	return differences, nil
+}
+
+// summaryLookupBatch bounds the entity IDs looked up by one query, below
+// the bound variables databases allow in a statement
+const summaryLookupBatch = 500
+
+// functionSummaries returns the function summaries of the given entities by
+// entity ID
+func functionSummaries(store *db.SummaryStore, ids []string) (map[string]*summary.CodeSummary, error) {
+	byID := make(map[string]*summary.CodeSummary, len(ids))
+	for batch := range slices.Chunk(ids, summaryLookupBatch) {
+		summaries, err := store.GetSummariesByEntityIDs(summary.LevelFunction, batch)
+		if err != nil {
+			return nil, err
+		}
+		for _, cs := range summaries {
+			byID[cs.EntityID] = cs
+		}
+	}
+	return byID, nil
+}
+
+// fileSummaries returns the file summaries of a repository by path
+func fileSummaries(store *db.SummaryStore) (map[string]*summary.CodeSummary, error) {
+	summaries, err := store.GetSummariesByType(summary.LevelFile)
+	if err != nil {
+		return nil, err
+	}
+	byPath := make(map[string]*summary.CodeSummary, len(summaries))
+	for _, cs := range summaries {
+		byPath[cs.FilePath] = cs
+	}
+	return byPath, nil
+}
+
+// SummarySimilarity is the Jaccard similarity of the sets of lowercased
+// words of two summaries: 1 for the same words, 0 for none in common. Two
+// empty summaries are alike.
+func SummarySimilarity(a, b string) float64 {
+	words := func(s string) map[string]bool {
+		set := make(map[string]bool)
+		for _, w := range strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_'
+		}) {
+			set[w] = true
+		}
+		return set
+	}
+	wa, wb := words(a), words(b)
+	if len(wa) == 0 && len(wb) == 0 {
+		return 1
+	}
+	shared := 0
+	for w := range wa {
+		if wb[w] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(wa)+len(wb)-shared)
+}
+
+// truncate cuts each list of the report to limit entries
+func (r *RepoComparison) truncate(limit int) {
+	cut := func(n int) int {
+		if n > limit {
+			r.Truncated = true
+			return limit
+		}
+		return n
+	}
+	if f := r.Files; f != nil {
+		f.Diverged = f.Diverged[:cut(len(f.Diverged))]
+		f.Moved = f.Moved[:cut(len(f.Moved))]
+		f.OnlyInRepo = f.OnlyInRepo[:cut(len(f.OnlyInRepo))]
+		f.OnlyInOther = f.OnlyInOther[:cut(len(f.OnlyInOther))]
+	}
+	if f := r.Functions; f != nil {
+		f.Changed = f.Changed[:cut(len(f.Changed))]
+		f.OnlyInRepo = f.OnlyInRepo[:cut(len(f.OnlyInRepo))]
+		f.OnlyInOther = f.OnlyInOther[:cut(len(f.OnlyInOther))]
+	}
+	r.SummaryDifferences = r.SummaryDifferences[:cut(len(r.SummaryDifferences))]
+}
@@ -0,0 +1,104 @@
+package controller
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/armchr/codeapi/internal/codeapi"
+	"github.com/armchr/codeapi/internal/db"
+	"github.com/armchr/codeapi/internal/model/ast"
+)
+
+func TestCompareFiles(t *testing.T) {
+	fork := []*db.FileVersion{
+		{FileID: 1, RelativePath: "src/order.go", FileSHA: "a"},
+		{FileID: 4, RelativePath: "src/order.go", FileSHA: "b"}, // newer, diverges
+		{FileID: 2, RelativePath: "src/user.go", FileSHA: "u"},
+		{FileID: 3, RelativePath: "src/util/money.go", FileSHA: "m"},
+		{FileID: 5, RelativePath: "src/tax.go", FileSHA: "t"},
+		{FileID: 6, RelativePath: "src/scratch.go", FileSHA: "s", Sandbox: true},
+		{FileID: 7, RelativePath: "docs/readme.md", FileSHA: "d"},
+	}
+	upstream := []*db.FileVersion{
+		{FileID: 1, RelativePath: "src/order.go", FileSHA: "a"},
+		{FileID: 2, RelativePath: "src/user.go", FileSHA: "u"},
+		{FileID: 3, RelativePath: "src/common/money.go", FileSHA: "m"},
+		{FileID: 4, RelativePath: "src/audit.go", FileSHA: "x"},
+	}
+
+	got := CompareFiles(fork, upstream, "src/")
+	want := &FileComparison{
+		Identical:   1,
+		Diverged:    []string{"src/order.go"},
+		Moved:       []MovedFile{{Path: "src/util/money.go", OtherPath: "src/common/money.go"}},
+		OnlyInRepo:  []string{"src/tax.go"},
+		OnlyInOther: []string{"src/audit.go"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("CompareFiles = %+v, want %+v", got, want)
+	}
+}
+
+func TestCompareFunctions(t *testing.T) {
+	fn := func(id int, file, class, name, hash string) *codeapi.FunctionVersion {
+		return &codeapi.FunctionVersion{ID: ast.NodeID(id), FilePath: file, ClassName: class, Name: name, BodyHash: hash}
+	}
+	fork := []*codeapi.FunctionVersion{
+		fn(1, "order.py", "Order", "total", "t2"),
+		fn(2, "order.py", "Order", "add", "a"),
+		fn(3, "order.py", "", "parse", "p1"), // overloads, in another order
+		fn(4, "order.py", "", "parse", "p2"),
+		fn(5, "tax.py", "", "vat_rate", "v"),
+	}
+	upstream := []*codeapi.FunctionVersion{
+		fn(11, "order.py", "Order", "total", "t1"),
+		fn(12, "order.py", "Order", "add", "a"),
+		fn(13, "order.py", "", "parse", "p2"),
+		fn(14, "order.py", "", "parse", "p1"),
+		fn(15, "order.py", "Order", "discount", "d"),
+	}
+
+	got, changed := CompareFunctions(fork, upstream)
+	want := &FunctionComparison{
+		Identical:   2,
+		Changed:     []FunctionRef{{FilePath: "order.py", ClassName: "Order", Name: "total"}},
+		OnlyInRepo:  []FunctionRef{{FilePath: "tax.py", Name: "vat_rate"}},
+		OnlyInOther: []FunctionRef{{FilePath: "order.py", ClassName: "Order", Name: "discount"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("CompareFunctions = %+v, want %+v", got, want)
+	}
+	if len(changed) != 1 || changed[0][0].ID != 1 || changed[0][1].ID != 11 {
+		t.Errorf("changed pairs = %v, want total of both repositories", changed)
+	}
+}
+
+func TestSummarySimilarity(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want float64
+	}{
+		{"Sums the line items.", "sums the LINE items", 1},
+		{"Sums the line items", "Rounds to cents", 0},
+		{"Sums the items", "Sums the line items", 0.75},
+		{"", "", 1},
+		{"Sums", "", 0},
+	}
+	for _, tt := range tests {
+		if got := SummarySimilarity(tt.a, tt.b); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("SummarySimilarity(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestRepoComparisonTruncate(t *testing.T) {
+	r := &RepoComparison{
+		Files:     &FileComparison{Diverged: []string{"a", "b", "c"}, OnlyInRepo: []string{"d"}},
+		Functions: &FunctionComparison{Changed: []FunctionRef{{Name: "f"}, {Name: "g"}}},
+	}
+	r.truncate(2)
+	if len(r.Files.Diverged) != 2 || len(r.Files.OnlyInRepo) != 1 || len(r.Functions.Changed) != 2 || !r.Truncated {
+		t.Errorf("truncate(2) = %+v, %+v, truncated %v", r.Files, r.Functions, r.Truncated)
+	}
+}
@@ -1,20 +1,29 @@
 package controller
 
 import (
+	"github.com/armchr/codeapi/internal/budget"
+	"github.com/armchr/codeapi/internal/chunk"
 	"github.com/armchr/codeapi/internal/config"
 	"github.com/armchr/codeapi/internal/db"
+	"github.com/armchr/codeapi/internal/logging"
 	"github.com/armchr/codeapi/internal/service/vector"
 	"github.com/armchr/codeapi/internal/util"
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/armchr/codeapi/internal/model"
 	"github.com/armchr/codeapi/internal/service"
+	"github.com/armchr/codeapi/internal/service/audit"
+	"github.com/armchr/codeapi/internal/service/codegraph"
+	"github.com/armchr/codeapi/internal/service/notes"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"go.uber.org/zap"
 )
 
@@ -22,20 +31,51 @@ type RepoController struct {
 	repoService  *service.RepoService
 	chunkService *vector.CodeChunkService
 	processors   []FileProcessor
-	mysqlConn    *db.MySQLConnection
+	dbConn       db.Connection
+	codeGraph    *codegraph.CodeGraph
+	sandbox      *SandboxCleaner
+	cursorKey    []byte // signs function dependency cursors
 	config       *config.Config
 	logger       *zap.Logger
 }
 
-func NewRepoController(repoService *service.RepoService, chunkService *vector.CodeChunkService, processors []FileProcessor, mysqlConn *db.MySQLConnection, config *config.Config, logger *zap.Logger) *RepoController {
+func NewRepoController(repoService *service.RepoService, chunkService *vector.CodeChunkService, processors []FileProcessor, dbConn db.Connection, codeGraph *codegraph.CodeGraph, config *config.Config, logger *zap.Logger) (*RepoController, error) {
+	cursorKey, err := newCursorKey(config)
+	if err != nil {
+		return nil, err
+	}
 	return &RepoController{
 		repoService:  repoService,
 		chunkService: chunkService,
 		processors:   processors,
-		mysqlConn:    mysqlConn,
+		dbConn:       dbConn,
+		codeGraph:    codeGraph,
+		sandbox:      NewSandboxCleaner(processors, dbConn, config, logger),
+		cursorKey:    cursorKey,
 		config:       config,
 		logger:       logger,
+	}, nil
+}
+
+// checkCollectionKey refuses to chunk a repository into a collection named
+// after something else when either has an encryption key. The vector
+// database picks the key by collection name, so the chunks would be stored
+// unencrypted or under the key of another repository.
+func checkCollectionKey(cfg *config.Config, repo *config.Repository, collectionName string) error {
+	if collectionName == repo.Name {
+		return nil
+	}
+	if repo.Encrypt {
+		return fmt.Errorf("repository %s is encrypted and can only be chunked into its own collection", repo.Name)
+	}
+	cipher, err := cfg.RepoCipher(collectionName)
+	if err != nil {
+		return err
+	}
+	if cipher != nil {
+		return fmt.Errorf("collection %s belongs to a repository with an encryption key", collectionName)
 	}
+	return nil
 }
 
 type BuildIndexRequest struct {
@@ -50,9 +90,11 @@ type BuildIndexResponse struct {
 }
 
 func (rc *RepoController) BuildIndex(c *gin.Context) {
+	logger := logging.FromContext(c.Request.Context(), rc.logger)
+
 	var request BuildIndexRequest
 	if err := c.ShouldBindJSON(&request); err != nil {
-		rc.logger.Error("Invalid request payload", zap.Error(err))
+		logger.Error("Invalid request payload", zap.Error(err))
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error":   "Invalid request payload",
 			"details": err.Error(),
@@ -60,7 +102,7 @@ func (rc *RepoController) BuildIndex(c *gin.Context) {
 		return
 	}
 
-	rc.logger.Info("Processing repository",
+	logger.Info("Processing repository",
 		zap.String("repo_name", request.RepoName),
 		zap.Bool("use_head", request.UseHead))
 
@@ -69,7 +111,7 @@ func (rc *RepoController) BuildIndex(c *gin.Context) {
 	// Validate repository exists in config
 	repo, err := rc.config.GetRepository(request.RepoName)
 	if err != nil {
-		rc.logger.Error("Repository not found in configuration",
+		logger.Error("Repository not found in configuration",
 			zap.String("repo_name", request.RepoName),
 			zap.Error(err))
 		c.JSON(http.StatusNotFound, gin.H{
@@ -79,19 +121,19 @@ func (rc *RepoController) BuildIndex(c *gin.Context) {
 		return
 	}
 
-	// Check if MySQL connection is available
-	if rc.mysqlConn == nil {
-		rc.logger.Error("MySQL connection not available")
+	// Check if the database connection is available
+	if rc.dbConn == nil {
+		logger.Error("Database connection not available")
 		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "MySQL connection not available for file tracking",
+			"error": "Database connection not available for file tracking",
 		})
 		return
 	}
 
 	// Create FileVersionRepository for this repository
-	fileVersionRepo, err := db.NewFileVersionRepository(rc.mysqlConn.GetDB(), repo.Name, rc.logger)
+	fileVersionRepo, err := db.NewFileVersionRepository(rc.dbConn.GetDB(), repo.Name, rc.logger)
 	if err != nil {
-		rc.logger.Error("Failed to create file version repository",
+		logger.Error("Failed to create file version repository",
 			zap.String("repo_name", repo.Name),
 			zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -100,6 +142,7 @@ func (rc *RepoController) BuildIndex(c *gin.Context) {
 		})
 		return
 	}
+	fileVersionRepo = fileVersionRepo.WithContext(ctx)
 
 	// Create index builder with processors
 	indexBuilder := NewIndexBuilder(rc.config, rc.processors, fileVersionRepo, rc.logger)
@@ -109,7 +152,7 @@ func (rc *RepoController) BuildIndex(c *gin.Context) {
 	if request.UseHead {
 		gitInfo, err = util.GetGitInfo(repo.Path)
 		if err != nil {
-			rc.logger.Error("Failed to get git info",
+			logger.Error("Failed to get git info",
 				zap.String("repo_name", repo.Name),
 				zap.Error(err))
 			c.JSON(http.StatusInternalServerError, gin.H{
@@ -119,7 +162,7 @@ func (rc *RepoController) BuildIndex(c *gin.Context) {
 			return
 		}
 		if !gitInfo.IsGitRepo {
-			rc.logger.Error("Repository is not a git repository, cannot use use_head flag",
+			logger.Error("Repository is not a git repository, cannot use use_head flag",
 				zap.String("repo_name", repo.Name),
 				zap.String("path", repo.Path))
 			c.JSON(http.StatusBadRequest, gin.H{
@@ -130,8 +173,17 @@ func (rc *RepoController) BuildIndex(c *gin.Context) {
 	}
 
 	// Build indexes
-	if err := indexBuilder.BuildIndexWithGitInfo(ctx, repo, request.UseHead, gitInfo); err != nil {
-		rc.logger.Error("Failed to build indexes for repository",
+	entry := apiAudit(c, audit.OpIndexBuild, repo.Name)
+	err = indexBuilder.BuildIndexWithGitInfo(ctx, repo, request.UseHead, gitInfo)
+	if stats := indexBuilder.LastStats(); stats != nil {
+		entry.Details = stats.AuditDetails()
+	}
+	rc.recordAudit(c, entry, err)
+	if rc.respondRepoLocked(c, repo.Name, err) {
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to build indexes for repository",
 			zap.String("repo_name", repo.Name),
 			zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -141,7 +193,7 @@ func (rc *RepoController) BuildIndex(c *gin.Context) {
 		return
 	}
 
-	rc.logger.Info("Successfully processed repository",
+	logger.Info("Successfully processed repository",
 		zap.String("repo_name", repo.Name),
 		zap.Bool("use_head", request.UseHead))
 
@@ -153,9 +205,10 @@ func (rc *RepoController) BuildIndex(c *gin.Context) {
 }
 
 func (rc *RepoController) GetFunctionsInFile(c *gin.Context) {
+	logger := logging.FromContext(c.Request.Context(), rc.logger)
 	var request model.GetFunctionsInFileRequest
 	if err := c.ShouldBindJSON(&request); err != nil {
-		rc.logger.Error("Invalid request payload", zap.Error(err))
+		logger.Error("Invalid request payload", zap.Error(err))
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error":   "Invalid request payload",
 			"details": err.Error(),
@@ -163,13 +216,13 @@ func (rc *RepoController) GetFunctionsInFile(c *gin.Context) {
 		return
 	}
 
-	rc.logger.Info("Getting functions in file",
+	logger.Info("Getting functions in file",
 		zap.String("repo_name", request.RepoName),
 		zap.String("relative_path", request.RelativePath))
 
 	/*response, err := rc.repoService.GetFunctionsInFile(request.RepoName, request.RelativePath)
 	if err != nil {
-		rc.logger.Error("Failed to get functions in file",
+		logger.Error("Failed to get functions in file",
 			zap.String("repo_name", request.RepoName),
 			zap.String("relative_path", request.RelativePath),
 			zap.Error(err))
@@ -180,22 +233,23 @@ func (rc *RepoController) GetFunctionsInFile(c *gin.Context) {
 		return
 	}
 
-	rc.logger.Info("Successfully got functions in file",
+	logger.Info("Successfully got functions in file",
 		zap.String("repo_name", request.RepoName),
 		zap.String("relative_path", request.RelativePath),
 		zap.Int("function_count", len(response.Functions)))
 
-	rc.logger.Debug("About to send JSON response")
+	logger.Debug("About to send JSON response")
 	c.JSON(http.StatusOK, response)
 	*/
 	c.JSON(http.StatusOK, nil)
-	rc.logger.Debug("JSON response sent successfully")
+	logger.Debug("JSON response sent successfully")
 }
 
 func (rc *RepoController) GetFunctionDetails(c *gin.Context) {
+	logger := logging.FromContext(c.Request.Context(), rc.logger)
 	var request model.GetFunctionDetailsRequest
 	if err := c.ShouldBindJSON(&request); err != nil {
-		rc.logger.Error("Invalid request payload", zap.Error(err))
+		logger.Error("Invalid request payload", zap.Error(err))
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error":   "Invalid request payload",
 			"details": err.Error(),
@@ -203,14 +257,14 @@ func (rc *RepoController) GetFunctionDetails(c *gin.Context) {
 		return
 	}
 
-	rc.logger.Info("Getting function details",
+	logger.Info("Getting function details",
 		zap.String("repo_name", request.RepoName),
 		zap.String("relative_path", request.RelativePath),
 		zap.String("function_name", request.FunctionName))
 
 	response, err := rc.repoService.GetFunctionDetails(request.RepoName, request.RelativePath, request.FunctionName)
 	if err != nil {
-		rc.logger.Error("Failed to get function details",
+		logger.Error("Failed to get function details",
 			zap.String("repo_name", request.RepoName),
 			zap.String("relative_path", request.RelativePath),
 			zap.String("function_name", request.FunctionName),
@@ -222,22 +276,23 @@ func (rc *RepoController) GetFunctionDetails(c *gin.Context) {
 		return
 	}
 
-	rc.logger.Info("Successfully got function details",
+	logger.Info("Successfully got function details",
 		zap.String("repo_name", request.RepoName),
 		zap.String("relative_path", request.RelativePath),
 		zap.String("function_name", request.FunctionName))
 
-	rc.logger.Debug("About to send JSON response")
+	logger.Debug("About to send JSON response")
 	c.JSON(http.StatusOK, response)
-	rc.logger.Debug("JSON response sent successfully")
+	logger.Debug("JSON response sent successfully")
 }
 
 func (rc *RepoController) GetFunctionDependencies(c *gin.Context) {
+	logger := logging.FromContext(c.Request.Context(), rc.logger)
 	request := model.GetFunctionDependenciesRequest{
 		Depth: 2, // Default depth
 	}
 	if err := c.ShouldBindJSON(&request); err != nil {
-		rc.logger.Error("Invalid request payload", zap.Error(err))
+		logger.Error("Invalid request payload", zap.Error(err))
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error":   "Invalid request payload",
 			"details": err.Error(),
@@ -245,15 +300,50 @@ func (rc *RepoController) GetFunctionDependencies(c *gin.Context) {
 		return
 	}
 
-	rc.logger.Info("Getting function dependencies",
+	logger.Info("Getting function dependencies",
 		zap.String("repo_name", request.RepoName),
 		zap.String("relative_path", request.RelativePath),
 		zap.String("function_name", request.FunctionName),
-		zap.Int("depth", request.Depth))
-
-	response, err := rc.repoService.GetFunctionDependencies(c, request.RepoName, request.RelativePath, request.FunctionName, request.Depth)
+		zap.Int("depth", request.Depth),
+		zap.Int("time_budget_ms", request.TimeBudgetMs),
+		zap.Bool("continued", request.Cursor != ""))
+
+	// With a time budget or a cursor the traversal is time-boxed, and what
+	// it did not reach is handed back as a cursor
+	var response *model.CallGraph
+	var pending []model.PendingFunction
+	var err error
+	deadline := timeBudgetDeadline(time.Now(), request.TimeBudgetMs)
+	switch {
+	case request.Cursor != "":
+		repo, repoErr := rc.config.GetRepository(request.RepoName)
+		if repoErr != nil {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "Repository not found",
+				"details": repoErr.Error(),
+			})
+			return
+		}
+		pending, err = decodeDependencyCursor(rc.cursorKey, &request, repo.Path)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid cursor",
+				"details": err.Error(),
+			})
+			return
+		}
+		response, pending, err = rc.repoService.ContinueFunctionDependencies(c, request.RepoName, request.RelativePath, pending, deadline)
+	case request.TimeBudgetMs > 0:
+		response, pending, err = rc.repoService.GetFunctionDependenciesUntil(c, request.RepoName, request.RelativePath, request.FunctionName, request.Depth, deadline)
+	default:
+		response, err = rc.repoService.GetFunctionDependencies(c, request.RepoName, request.RelativePath, request.FunctionName, request.Depth)
+	}
+	if err == nil && len(pending) > 0 {
+		response.Partial = true
+		response.Cursor, err = encodeDependencyCursor(rc.cursorKey, &request, pending)
+	}
 	if err != nil {
-		rc.logger.Error("Failed to get function dependencies",
+		logger.Error("Failed to get function dependencies",
 			zap.String("repo_name", request.RepoName),
 			zap.String("relative_path", request.RelativePath),
 			zap.String("function_name", request.FunctionName),
@@ -265,20 +355,22 @@ func (rc *RepoController) GetFunctionDependencies(c *gin.Context) {
 		return
 	}
 
-	rc.logger.Info("Successfully got function dependencies",
+	logger.Info("Successfully got function dependencies",
 		zap.String("repo_name", request.RepoName),
 		zap.String("relative_path", request.RelativePath),
-		zap.String("function_name", request.FunctionName))
+		zap.String("function_name", request.FunctionName),
+		zap.Int("pending", len(pending)))
 
-	rc.logger.Debug("About to send JSON response")
+	logger.Debug("About to send JSON response")
 	c.JSON(http.StatusOK, response)
-	rc.logger.Debug("JSON response sent successfully")
+	logger.Debug("JSON response sent successfully")
 }
 
 func (rc *RepoController) ProcessDirectory(c *gin.Context) {
+	logger := logging.FromContext(c.Request.Context(), rc.logger)
 	var request model.ProcessDirectoryRequest
 	if err := c.ShouldBindJSON(&request); err != nil {
-		rc.logger.Error("Invalid request payload", zap.Error(err))
+		logger.Error("Invalid request payload", zap.Error(err))
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error":   "Invalid request payload",
 			"details": err.Error(),
@@ -288,7 +380,7 @@ func (rc *RepoController) ProcessDirectory(c *gin.Context) {
 
 	// Check if chunk service is available
 	if rc.chunkService == nil {
-		rc.logger.Error("Code chunk service not available")
+		logger.Error("Code chunk service not available")
 		c.JSON(http.StatusServiceUnavailable, gin.H{
 			"error": "Code chunk service not available",
 		})
@@ -298,7 +390,7 @@ func (rc *RepoController) ProcessDirectory(c *gin.Context) {
 	// Get repository configuration
 	repo, err := rc.repoService.GetConfig().GetRepository(request.RepoName)
 	if err != nil {
-		rc.logger.Error("Repository not found",
+		logger.Error("Repository not found",
 			zap.String("repo_name", request.RepoName),
 			zap.Error(err))
 		c.JSON(http.StatusNotFound, gin.H{
@@ -313,15 +405,22 @@ func (rc *RepoController) ProcessDirectory(c *gin.Context) {
 	if collectionName == "" {
 		collectionName = request.RepoName
 	}
+	if err := checkCollectionKey(rc.repoService.GetConfig(), repo, collectionName); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid collection name",
+			"details": err.Error(),
+		})
+		return
+	}
 
-	rc.logger.Info("Processing directory for code chunking",
+	logger.Info("Processing directory for code chunking",
 		zap.String("repo_name", request.RepoName),
 		zap.String("path", repo.Path),
 		zap.String("collection", collectionName))
 
 	// Create collection if it doesn't exist
 	if err := rc.chunkService.CreateCollection(c.Request.Context(), collectionName); err != nil {
-		rc.logger.Error("Failed to create collection",
+		logger.Error("Failed to create collection",
 			zap.String("collection", collectionName),
 			zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -332,9 +431,12 @@ func (rc *RepoController) ProcessDirectory(c *gin.Context) {
 	}
 
 	// Process directory with repository configuration
+	entry := apiAudit(c, audit.OpProcessDirectory, repo.Name)
 	totalChunks, err := rc.chunkService.ProcessDirectory(c.Request.Context(), repo.Path, collectionName, repo)
+	entry.Details = map[string]any{"collection": collectionName, "total_chunks": totalChunks}
+	rc.recordAudit(c, entry, err)
 	if err != nil {
-		rc.logger.Error("Failed to process directory",
+		logger.Error("Failed to process directory",
 			zap.String("repo_name", request.RepoName),
 			zap.String("path", repo.Path),
 			zap.Error(err))
@@ -348,7 +450,7 @@ func (rc *RepoController) ProcessDirectory(c *gin.Context) {
 		return
 	}
 
-	rc.logger.Info("Successfully processed directory",
+	logger.Info("Successfully processed directory",
 		zap.String("repo_name", request.RepoName),
 		zap.String("collection", collectionName),
 		zap.Int("total_chunks", totalChunks))
@@ -366,10 +468,11 @@ func (rc *RepoController) ProcessDirectory(c *gin.Context) {
 
 // SearchSimilarCode handles searching for similar code using a code snippet
 func (rc *RepoController) SearchSimilarCode(c *gin.Context) {
+	logger := logging.FromContext(c.Request.Context(), rc.logger)
 	var request model.SearchSimilarCodeRequest
 
 	if err := c.ShouldBindJSON(&request); err != nil {
-		rc.logger.Error("Invalid request", zap.Error(err))
+		logger.Error("Invalid request", zap.Error(err))
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error":   "Invalid request",
 			"details": err.Error(),
@@ -379,7 +482,7 @@ func (rc *RepoController) SearchSimilarCode(c *gin.Context) {
 
 	// Check if chunk service is available
 	if rc.chunkService == nil {
-		rc.logger.Error("Code chunk service not available")
+		logger.Error("Code chunk service not available")
 		c.JSON(http.StatusServiceUnavailable, gin.H{
 			"error": "Code chunk service not available",
 		})
@@ -407,18 +510,53 @@ func (rc *RepoController) SearchSimilarCode(c *gin.Context) {
 		collectionName = request.RepoName
 	}
 
+	mode := request.Mode
+	if mode == "" {
+		mode = model.SearchModeDirect
+	}
+	if mode != model.SearchModeDirect && mode != model.SearchModeCoarseToFine {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Invalid mode %q: must be %s or %s", request.Mode, model.SearchModeDirect, model.SearchModeCoarseToFine),
+		})
+		return
+	}
+
 	// Set default limit
 	limit := request.Limit
 	if limit <= 0 {
 		limit = 10
 	}
 
-	rc.logger.Info("Searching for similar code",
+	logger.Info("Searching for similar code",
 		zap.String("repo_name", request.RepoName),
 		zap.String("collection", collectionName),
 		zap.String("language", request.Language),
+		zap.String("mode", mode),
 		zap.Int("limit", limit))
 
+	// In coarse-to-fine mode, restrict the search to the best folders. A
+	// repository without embedded folder summaries is searched whole.
+	var filter map[string]interface{}
+	var folders []model.FolderMatch
+	if mode == model.SearchModeCoarseToFine {
+		folderLimit := request.FolderLimit
+		if folderLimit <= 0 {
+			folderLimit = 5
+		}
+		paths, folderScores, err := rc.chunkService.SelectFolders(c.Request.Context(), collectionName, request.CodeSnippet, folderLimit)
+		if err != nil {
+			logger.Warn("Failed to select folders, searching the whole repository",
+				zap.String("repo_name", request.RepoName),
+				zap.Error(err))
+		}
+		for i, p := range paths {
+			folders = append(folders, model.FolderMatch{Path: p, Score: folderScores[i]})
+		}
+		if len(paths) > 0 {
+			filter = map[string]interface{}{"folders": paths}
+		}
+	}
+
 	// Search for similar code
 	queryChunks, resultChunks, scores, queryChunkIndices, err := rc.chunkService.SearchSimilarCodeBySnippet(
 		c.Request.Context(),
@@ -426,15 +564,17 @@ func (rc *RepoController) SearchSimilarCode(c *gin.Context) {
 		request.CodeSnippet,
 		request.Language,
 		limit,
-		nil, // no filter
+		filter,
 	)
 	if err != nil {
-		rc.logger.Error("Failed to search for similar code",
+		logger.Error("Failed to search for similar code",
 			zap.String("repo_name", request.RepoName),
 			zap.Error(err))
 		c.JSON(http.StatusInternalServerError, model.SearchSimilarCodeResponse{
 			RepoName:       request.RepoName,
 			CollectionName: collectionName,
+			Mode:           mode,
+			Folders:        folders,
 			Query: model.QueryInfo{
 				CodeSnippet: request.CodeSnippet,
 				Language:    request.Language,
@@ -448,6 +588,18 @@ func (rc *RepoController) SearchSimilarCode(c *gin.Context) {
 	}
 
 	// Build results
+	var summaries *searchSummaries
+	if request.IncludeSummaries {
+		summaries = rc.searchSummaries(c.Request.Context(), request.RepoName)
+	}
+	var queryChunkTerms [][]string
+	if request.Explain {
+		queryChunkTerms = make([][]string, len(queryChunks))
+		for i, queryChunk := range queryChunks {
+			queryChunkTerms[i] = searchTerms(queryChunk.Name + " " + queryChunk.Content)
+		}
+	}
+	rc.setChunkRefs(request.RepoName, resultChunks...)
 	results := make([]model.SimilarCodeResult, len(resultChunks))
 	for i, chunk := range resultChunks {
 		result := model.SimilarCodeResult{
@@ -455,12 +607,18 @@ func (rc *RepoController) SearchSimilarCode(c *gin.Context) {
 			Score:           scores[i],
 			QueryChunkIndex: queryChunkIndices[i],
 		}
+		if request.Explain {
+			index := queryChunkIndices[i]
+			result.Explanation = explainHit(queryChunkTerms[index], chunk, scores[i], filter)
+			result.Explanation.QueryChunkIndex = index
+			result.Explanation.QueryChunk = describeQueryChunk(queryChunks[index])
+		}
 
 		// Fetch code from file if requested
-		if request.IncludeCode {
+		if request.IncludeCode && !rc.publicMode() {
 			code, err := rc.chunkService.ReadCodeFromFile(chunk.FilePath, chunk.StartLine, chunk.EndLine)
 			if err != nil {
-				rc.logger.Warn("Failed to read code from file",
+				logger.Warn("Failed to read code from file",
 					zap.String("file", chunk.FilePath),
 					zap.Int("start_line", chunk.StartLine),
 					zap.Int("end_line", chunk.EndLine),
@@ -470,11 +628,17 @@ func (rc *RepoController) SearchSimilarCode(c *gin.Context) {
 				result.Code = code
 			}
 		}
+		result.Summary = summaries.forChunk(chunk)
+
+		if rc.publicMode() {
+			// Redacted last, as explainHit matches the query against the content
+			result.Chunk = redactChunk(chunk)
+		}
 
 		results[i] = result
 	}
 
-	rc.logger.Info("Successfully found similar code",
+	logger.Info("Successfully found similar code",
 		zap.String("repo_name", request.RepoName),
 		zap.String("collection", collectionName),
 		zap.Int("query_chunks", len(queryChunks)),
@@ -484,25 +648,247 @@ func (rc *RepoController) SearchSimilarCode(c *gin.Context) {
 	response := model.SearchSimilarCodeResponse{
 		RepoName:       request.RepoName,
 		CollectionName: collectionName,
+		Mode:           mode,
+		Folders:        folders,
 		Query: model.QueryInfo{
 			CodeSnippet: request.CodeSnippet,
 			Language:    request.Language,
 			ChunksFound: len(queryChunks),
 			Chunks:      queryChunks,
 		},
-		Results: results,
-		Success: true,
-		Message: "Search completed successfully",
+		Results:  results,
+		Redacted: rc.publicMode(),
+		Success:  true,
+		Message:  "Search completed successfully",
 	}
 
 	c.JSON(http.StatusOK, response)
 }
 
+// GetChunkHierarchy returns the chunks enclosing a chunk and those next to
+// it, so a search hit can be shown in its surrounding context
+func (rc *RepoController) GetChunkHierarchy(c *gin.Context) {
+	logger := logging.FromContext(c.Request.Context(), rc.logger)
+	var request model.ChunkHierarchyRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		logger.Error("Invalid request", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	if rc.chunkService == nil {
+		logger.Error("Code chunk service not available")
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error": "Code chunk service not available",
+		})
+		return
+	}
+
+	// Chunk IDs are UUIDs; anything else cannot name a stored chunk
+	if _, err := uuid.Parse(request.ChunkID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Invalid chunk_id %q: chunk IDs are UUIDs", request.ChunkID),
+		})
+		return
+	}
+
+	collectionName := request.CollectionName
+	if collectionName == "" {
+		collectionName = request.RepoName
+	}
+
+	target, ancestors, siblings, err := rc.chunkService.GetChunkHierarchy(c.Request.Context(), collectionName, request.ChunkID)
+	if errors.Is(err, vector.ErrChunkNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": fmt.Sprintf("Chunk not found: %s", request.ChunkID),
+		})
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to get chunk hierarchy",
+			zap.String("repo_name", request.RepoName),
+			zap.String("chunk_id", request.ChunkID),
+			zap.Error(err))
+		c.JSON(http.StatusInternalServerError, model.ChunkHierarchyResponse{
+			RepoName:       request.RepoName,
+			CollectionName: collectionName,
+			Ancestors:      []*model.CodeChunk{},
+			Siblings:       []*model.CodeChunk{},
+			Success:        false,
+			Message:        fmt.Sprintf("Failed to get chunk hierarchy: %v", err),
+		})
+		return
+	}
+
+	if ancestors == nil {
+		ancestors = []*model.CodeChunk{}
+	}
+	if siblings == nil {
+		siblings = []*model.CodeChunk{}
+	}
+	rc.setChunkRefs(request.RepoName, target)
+	rc.setChunkRefs(request.RepoName, ancestors...)
+	rc.setChunkRefs(request.RepoName, siblings...)
+	if rc.publicMode() {
+		target, ancestors, siblings = redactChunk(target), redactChunks(ancestors), redactChunks(siblings)
+	}
+	c.JSON(http.StatusOK, model.ChunkHierarchyResponse{
+		RepoName:       request.RepoName,
+		CollectionName: collectionName,
+		Chunk:          target,
+		Ancestors:      ancestors,
+		Siblings:       siblings,
+		Redacted:       rc.publicMode(),
+		Success:        true,
+	})
+}
+
+// RemapChunks maps the line ranges of search results to the current version
+// of their files, flagging results whose lines were edited since indexing
+func (rc *RepoController) RemapChunks(c *gin.Context) {
+	logger := logging.FromContext(c.Request.Context(), rc.logger)
+	var request model.RemapChunksRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		logger.Error("Invalid request", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	if rc.chunkService == nil {
+		logger.Error("Code chunk service not available")
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error": "Code chunk service not available",
+		})
+		return
+	}
+
+	repo, err := rc.config.GetRepository(request.RepoName)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "Repository not found",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	collectionName := request.CollectionName
+	if collectionName == "" {
+		collectionName = request.RepoName
+	}
+
+	results := rc.chunkService.RemapChunks(c.Request.Context(), collectionName, repo.Path, request.ChunkIDs)
+	changed := 0
+	for _, r := range results {
+		if r.ContentChanged {
+			changed++
+		}
+	}
+	logger.Info("Remapped chunk ranges",
+		zap.String("repo_name", request.RepoName),
+		zap.Int("chunks", len(results)),
+		zap.Int("changed", changed))
+
+	c.JSON(http.StatusOK, model.RemapChunksResponse{
+		RepoName:       request.RepoName,
+		CollectionName: collectionName,
+		Results:        results,
+		Success:        true,
+	})
+}
+
+// FindDuplicates reports clusters of near-identical functions across one or
+// more repositories, comparing the embeddings stored for their chunks
+func (rc *RepoController) FindDuplicates(c *gin.Context) {
+	logger := logging.FromContext(c.Request.Context(), rc.logger)
+	var request model.DuplicatesRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		logger.Error("Invalid request", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	if rc.chunkService == nil {
+		logger.Error("Code chunk service not available")
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error": "Code chunk service not available",
+		})
+		return
+	}
+
+	for _, repoName := range request.RepoNames {
+		if _, err := rc.config.GetRepository(repoName); err != nil {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "Repository not found",
+				"details": err.Error(),
+			})
+			return
+		}
+	}
+
+	if request.Threshold < 0 || request.Threshold > 1 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Invalid threshold %v: must be a cosine similarity between 0 and 1", request.Threshold),
+		})
+		return
+	}
+	threshold := request.Threshold
+	if threshold == 0 {
+		threshold = chunk.DefaultDuplicateThreshold
+	}
+	minLines := request.MinLines
+	if minLines <= 0 {
+		minLines = chunk.DefaultDuplicateMinLines
+	}
+	limit := request.Limit
+	if limit <= 0 {
+		limit = 100
+	}
+
+	compared, clusters, err := rc.chunkService.FindDuplicates(c.Request.Context(), request.RepoNames, threshold, minLines, limit)
+	if err != nil {
+		logger.Error("Failed to find duplicates",
+			zap.Strings("repo_names", request.RepoNames),
+			zap.Error(err))
+		c.JSON(http.StatusInternalServerError, model.DuplicatesResponse{
+			RepoNames: request.RepoNames,
+			Clusters:  []model.DuplicateCluster{},
+			Success:   false,
+			Message:   fmt.Sprintf("Failed to find duplicates: %v", err),
+		})
+		return
+	}
+
+	logger.Info("Found duplicate functions",
+		zap.Strings("repo_names", request.RepoNames),
+		zap.Int("functions", compared),
+		zap.Int("clusters", len(clusters)))
+
+	c.JSON(http.StatusOK, model.DuplicatesResponse{
+		RepoNames:         request.RepoNames,
+		FunctionsCompared: compared,
+		Clusters:          clusters,
+		Success:           true,
+	})
+}
+
 // SearchMethodsBySignatureRequest represents the request for semantic signature search
 type SearchMethodsBySignatureRequest struct {
 	RepoName string `json:"repo_name" binding:"required"`
 	Query    string `json:"query" binding:"required"` // Natural language query like "find user by email"
 	Limit    int    `json:"limit"`                    // Max results (default 10)
+	// IncludeSummaries adds the stored summary of each method
+	IncludeSummaries bool `json:"include_summaries"`
+	// Explain adds to each method why it matched
+	Explain bool `json:"explain,omitempty"`
 }
 
 // SearchMethodsBySignatureResponse represents the response from signature search
@@ -516,6 +902,7 @@ type SearchMethodsBySignatureResponse struct {
 
 // MethodSignatureResult represents a single method found by signature search
 type MethodSignatureResult struct {
+	EntityRef      string   `json:"entity_ref,omitempty"`
 	MethodName     string   `json:"method_name"`
 	ClassName      string   `json:"class_name,omitempty"`
 	Signature      string   `json:"signature"`
@@ -527,13 +914,17 @@ type MethodSignatureResult struct {
 	EndLine        int      `json:"end_line"`
 	Score          float32  `json:"score"`
 	NormalizedText string   `json:"normalized_text,omitempty"` // The normalized text used for embedding
+	Summary        string   `json:"summary,omitempty"`         // Stored summary of the method (if include_summaries is true)
+
+	Explanation *model.SearchExplanation `json:"explanation,omitempty"` // Why the method matched (if explain is true)
 }
 
 // SearchMethodsBySignature searches for methods using natural language queries on signatures
 func (rc *RepoController) SearchMethodsBySignature(c *gin.Context) {
+	logger := logging.FromContext(c.Request.Context(), rc.logger)
 	var request SearchMethodsBySignatureRequest
 	if err := c.ShouldBindJSON(&request); err != nil {
-		rc.logger.Error("Invalid request payload", zap.Error(err))
+		logger.Error("Invalid request payload", zap.Error(err))
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error":   "Invalid request payload",
 			"details": err.Error(),
@@ -543,7 +934,7 @@ func (rc *RepoController) SearchMethodsBySignature(c *gin.Context) {
 
 	// Check if chunk service is available
 	if rc.chunkService == nil {
-		rc.logger.Error("Code chunk service not available")
+		logger.Error("Code chunk service not available")
 		c.JSON(http.StatusServiceUnavailable, gin.H{
 			"error": "Code chunk service not available",
 		})
@@ -559,7 +950,7 @@ func (rc *RepoController) SearchMethodsBySignature(c *gin.Context) {
 	// Use repo name as collection name
 	collectionName := request.RepoName
 
-	rc.logger.Info("Searching methods by signature",
+	logger.Info("Searching methods by signature",
 		zap.String("repo_name", request.RepoName),
 		zap.String("query", request.Query),
 		zap.Int("limit", limit))
@@ -572,7 +963,7 @@ func (rc *RepoController) SearchMethodsBySignature(c *gin.Context) {
 		limit,
 	)
 	if err != nil {
-		rc.logger.Error("Failed to search method signatures",
+		logger.Error("Failed to search method signatures",
 			zap.String("repo_name", request.RepoName),
 			zap.Error(err))
 		c.JSON(http.StatusInternalServerError, SearchMethodsBySignatureResponse{
@@ -585,10 +976,43 @@ func (rc *RepoController) SearchMethodsBySignature(c *gin.Context) {
 		return
 	}
 
-	// Build results
+	rc.setChunkRefs(request.RepoName, chunks...)
+	results := NewMethodSignatureResults(chunks, scores)
+	if request.IncludeSummaries {
+		summaries := rc.searchSummaries(c.Request.Context(), request.RepoName)
+		for i, chunk := range chunks {
+			results[i].Summary = summaries.forChunk(chunk)
+		}
+	}
+	if request.Explain {
+		filter := rc.chunkService.SignatureSearchFilter(c.Request.Context(), collectionName)
+		queryTerms := searchTerms(request.Query)
+		for i, chunk := range chunks {
+			results[i].Explanation = explainHit(queryTerms, chunk, scores[i], filter)
+		}
+	}
+
+	logger.Info("Successfully found methods by signature",
+		zap.String("repo_name", request.RepoName),
+		zap.String("query", request.Query),
+		zap.Int("results", len(results)))
+
+	c.JSON(http.StatusOK, SearchMethodsBySignatureResponse{
+		RepoName: request.RepoName,
+		Query:    request.Query,
+		Results:  results,
+		Success:  true,
+		Message:  fmt.Sprintf("Found %d matching methods", len(results)),
+	})
+}
+
+// NewMethodSignatureResults converts signature search hits and their scores
+// into results
+func NewMethodSignatureResults(chunks []*model.CodeChunk, scores []float32) []MethodSignatureResult {
 	results := make([]MethodSignatureResult, len(chunks))
 	for i, chunk := range chunks {
 		result := MethodSignatureResult{
+			EntityRef:  chunk.EntityRef,
 			MethodName: chunk.Name,
 			ClassName:  chunk.ClassName,
 			Signature:  chunk.Signature,
@@ -628,19 +1052,7 @@ func (rc *RepoController) SearchMethodsBySignature(c *gin.Context) {
 
 		results[i] = result
 	}
-
-	rc.logger.Info("Successfully found methods by signature",
-		zap.String("repo_name", request.RepoName),
-		zap.String("query", request.Query),
-		zap.Int("results", len(results)))
-
-	c.JSON(http.StatusOK, SearchMethodsBySignatureResponse{
-		RepoName: request.RepoName,
-		Query:    request.Query,
-		Results:  results,
-		Success:  true,
-		Message:  fmt.Sprintf("Found %d matching methods", len(results)),
-	})
+	return results
 }
 
 // IndexFileRequest represents the request to index a single file
@@ -668,9 +1080,11 @@ type IndexedFileResult struct {
 
 // IndexFile indexes multiple files through all registered processors in parallel
 func (rc *RepoController) IndexFile(c *gin.Context) {
+	logger := logging.FromContext(c.Request.Context(), rc.logger)
+
 	var request IndexFileRequest
 	if err := c.ShouldBindJSON(&request); err != nil {
-		rc.logger.Error("Invalid request payload", zap.Error(err))
+		logger.Error("Invalid request payload", zap.Error(err))
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error":   "Invalid request payload",
 			"details": err.Error(),
@@ -680,7 +1094,7 @@ func (rc *RepoController) IndexFile(c *gin.Context) {
 
 	// Validate that we have files to process
 	if len(request.RelativePaths) == 0 {
-		rc.logger.Error("No files specified in request")
+		logger.Error("No files specified in request")
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error": "No files specified. Please provide at least one file path.",
 		})
@@ -689,18 +1103,18 @@ func (rc *RepoController) IndexFile(c *gin.Context) {
 
 	// Check if processors are available
 	if len(rc.processors) == 0 {
-		rc.logger.Error("No processors available - processors may not be enabled")
+		logger.Error("No processors available - processors may not be enabled")
 		c.JSON(http.StatusServiceUnavailable, gin.H{
 			"error": "No processors available. Ensure processors are enabled in configuration.",
 		})
 		return
 	}
 
-	// Check if MySQL is available (needed for file version tracking)
-	if rc.mysqlConn == nil {
-		rc.logger.Error("MySQL connection not available")
+	// Check if the relational store is available (needed for file version tracking)
+	if rc.dbConn == nil {
+		logger.Error("Database connection not available")
 		c.JSON(http.StatusServiceUnavailable, gin.H{
-			"error": "MySQL connection not available. File indexing requires MySQL.",
+			"error": "Database connection not available. File indexing requires a relational store.",
 		})
 		return
 	}
@@ -710,7 +1124,7 @@ func (rc *RepoController) IndexFile(c *gin.Context) {
 	// Get repository configuration
 	repo, err := rc.config.GetRepository(request.RepoName)
 	if err != nil {
-		rc.logger.Error("Repository not found", zap.String("repo_name", request.RepoName), zap.Error(err))
+		logger.Error("Repository not found", zap.String("repo_name", request.RepoName), zap.Error(err))
 		c.JSON(http.StatusNotFound, gin.H{
 			"error":   "Repository not found",
 			"details": err.Error(),
@@ -719,9 +1133,9 @@ func (rc *RepoController) IndexFile(c *gin.Context) {
 	}
 
 	// Create FileVersionRepository for this repository (shared across all files)
-	fileVersionRepo, err := db.NewFileVersionRepository(rc.mysqlConn.GetDB(), repo.Name, rc.logger)
+	fileVersionRepo, err := db.NewFileVersionRepository(rc.dbConn.GetDB(), repo.Name, rc.logger)
 	if err != nil {
-		rc.logger.Error("Failed to create file version repository",
+		logger.Error("Failed to create file version repository",
 			zap.String("repo_name", repo.Name),
 			zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -730,6 +1144,7 @@ func (rc *RepoController) IndexFile(c *gin.Context) {
 		})
 		return
 	}
+	fileVersionRepo = fileVersionRepo.WithContext(ctx)
 
 	// Get concurrency limit from config, default to 5
 	maxConcurrent := rc.config.App.MaxConcurrentFileProcessing
@@ -737,12 +1152,27 @@ func (rc *RepoController) IndexFile(c *gin.Context) {
 		maxConcurrent = 5
 	}
 
-	rc.logger.Info("Starting parallel file indexing",
+	logger.Info("Starting parallel file indexing",
 		zap.String("repo_name", request.RepoName),
 		zap.Int("file_count", len(request.RelativePaths)),
 		zap.Int("max_concurrent", maxConcurrent))
 
-	// Process files in parallel using worker pool
+	// Process files in parallel using worker pool, holding the repository
+	// lock so a build or sandbox purge does not rewrite the same versions
+	entry := apiAudit(c, audit.OpIndexFile, repo.Name, request.RelativePaths...)
+	lock, err := fileVersionRepo.Lock(audit.OpIndexFile)
+	if err != nil {
+		rc.recordAudit(c, entry, err)
+		if !rc.respondRepoLocked(c, repo.Name, err) {
+			logger.Error("Failed to lock repository", zap.String("repo_name", repo.Name), zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Failed to lock repository",
+				"details": err.Error(),
+			})
+		}
+		return
+	}
+	defer lock.Release()
 	results := rc.processFilesInParallel(ctx, repo, request.RelativePaths, fileVersionRepo, maxConcurrent)
 
 	// Count successes and failures
@@ -756,7 +1186,14 @@ func (rc *RepoController) IndexFile(c *gin.Context) {
 		}
 	}
 
-	rc.logger.Info("Completed parallel file indexing",
+	entry.Details = map[string]any{"succeeded": successCount, "failed": failureCount}
+	var auditErr error
+	if failureCount > 0 {
+		auditErr = fmt.Errorf("%d of %d file(s) failed", failureCount, len(results))
+	}
+	rc.recordAudit(c, entry, auditErr)
+
+	logger.Info("Completed parallel file indexing",
 		zap.String("repo_name", request.RepoName),
 		zap.Int("total_files", len(request.RelativePaths)),
 		zap.Int("successes", successCount),
@@ -771,6 +1208,98 @@ func (rc *RepoController) IndexFile(c *gin.Context) {
 	c.JSON(http.StatusOK, response)
 }
 
+// respondRepoLocked responds with 409 Conflict and the lock status if err
+// reports that another operation holds the lock of the repository
+func (rc *RepoController) respondRepoLocked(c *gin.Context, repoName string, err error) bool {
+	if !errors.Is(err, db.ErrRepoLocked) {
+		return false
+	}
+	logging.FromContext(c.Request.Context(), rc.logger).Warn("Repository is locked by another operation",
+		zap.String("repo_name", repoName),
+		zap.Error(err))
+	lock, _ := db.GetRepoLockStatus(c.Request.Context(), rc.dbConn.GetDB(), repoName)
+	c.JSON(http.StatusConflict, gin.H{
+		"error":   "Repository is locked by another operation",
+		"details": err.Error(),
+		"lock":    lock,
+	})
+	return true
+}
+
+// PurgeSandboxRequest represents the request to purge ad-hoc indexed files
+type PurgeSandboxRequest struct {
+	RepoName string `json:"repo_name" binding:"required"`
+	// OlderThanMinutes limits the purge to sandbox files last indexed at least
+	// this many minutes ago. Zero purges every sandbox file.
+	OlderThanMinutes int `json:"older_than_minutes"`
+}
+
+// PurgeSandboxResponse represents the response after purging sandbox files
+type PurgeSandboxResponse struct {
+	RepoName       string `json:"repo_name"`
+	PurgedVersions int    `json:"purged_versions"`
+	Message        string `json:"message"`
+}
+
+// PurgeSandbox removes graph nodes, vectors and summaries created by IndexFile
+func (rc *RepoController) PurgeSandbox(c *gin.Context) {
+	logger := logging.FromContext(c.Request.Context(), rc.logger)
+	var request PurgeSandboxRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		logger.Error("Invalid request payload", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request payload",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	if rc.dbConn == nil {
+		logger.Error("Database connection not available")
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error": "Database connection not available. Sandbox purge requires a relational store.",
+		})
+		return
+	}
+
+	repo, err := rc.config.GetRepository(request.RepoName)
+	if err != nil {
+		logger.Error("Repository not found", zap.String("repo_name", request.RepoName), zap.Error(err))
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "Repository not found",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	var cutoff time.Time
+	if request.OlderThanMinutes > 0 {
+		cutoff = time.Now().Add(-time.Duration(request.OlderThanMinutes) * time.Minute)
+	}
+
+	entry := apiAudit(c, audit.OpPurgeSandbox, repo.Name)
+	purged, err := rc.sandbox.PurgeRepository(c.Request.Context(), repo, cutoff)
+	entry.Details = map[string]any{"purged_versions": purged, "older_than_minutes": request.OlderThanMinutes}
+	rc.recordAudit(c, entry, err)
+	if rc.respondRepoLocked(c, repo.Name, err) {
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to purge sandbox files", zap.String("repo_name", repo.Name), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to purge sandbox files",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, PurgeSandboxResponse{
+		RepoName:       repo.Name,
+		PurgedVersions: purged,
+		Message:        fmt.Sprintf("Purged %d sandbox file version(s)", purged),
+	})
+}
+
 // processFilesInParallel processes multiple files concurrently using a worker pool
 func (rc *RepoController) processFilesInParallel(ctx context.Context, repo *config.Repository, relativePaths []string, fileVersionRepo *db.FileVersionRepository, maxConcurrent int) []IndexedFileResult {
 	type fileJob struct {
@@ -790,7 +1319,13 @@ func (rc *RepoController) processFilesInParallel(ctx context.Context, repo *conf
 					zap.Int("worker_id", workerID),
 					zap.String("file", job.relativePath))
 
+				release, err := budget.Acquire(ctx, budget.CPU)
+				if err != nil {
+					results <- IndexedFileResult{RelativePath: job.relativePath, Error: err.Error()}
+					continue
+				}
 				result := rc.processSingleFile(ctx, repo, job.relativePath, fileVersionRepo)
+				release()
 				results <- result
 			}
 		}(w)
@@ -845,7 +1380,7 @@ func (rc *RepoController) processSingleFile(ctx context.Context, repo *config.Re
 	fileSHA := util.CalculateFileSHA256(content)
 
 	// Get or create FileID from MySQL
-	fileID, err := fileVersionRepo.GetOrCreateFileID(fileSHA, relativePath, true, nil)
+	fileID, err := fileVersionRepo.GetOrCreateSandboxFileID(fileSHA, relativePath)
 	if err != nil {
 		rc.logger.Error("Failed to create file ID", zap.String("file_path", filePath), zap.Error(err))
 		return IndexedFileResult{
@@ -864,6 +1399,8 @@ func (rc *RepoController) processSingleFile(ctx context.Context, repo *config.Re
 		FileSHA:      fileSHA,
 		CommitID:     nil,
 		Ephemeral:    true,
+		Language:     fileLanguage(repo, relativePath, content),
+		Sandbox:      true,
 	}
 
 	// Process through all processors
@@ -922,3 +1459,177 @@ func (rc *RepoController) processSingleFile(ctx context.Context, repo *config.Re
 		Success:      true,
 	}
 }
+
+// ListNotesRequest selects notes of a repository. Empty fields match all.
+type ListNotesRequest struct {
+	RepoName   string   `json:"repo_name" binding:"required"`
+	Kinds      []string `json:"kinds,omitempty"`       // e.g. "todo", "fixme", "license"
+	PathPrefix string   `json:"path_prefix,omitempty"` // relative to the repository root
+	Author     string   `json:"author,omitempty"`      // blamed author name or email, or assignee
+	Contains   string   `json:"contains,omitempty"`    // case-insensitive substring of the text
+	Limit      int      `json:"limit,omitempty"`
+	Offset     int      `json:"offset,omitempty"`
+}
+
+// ListNotesResponse lists notes in file and line order
+type ListNotesResponse struct {
+	RepoName string           `json:"repo_name"`
+	Notes    []notes.CodeNote `json:"notes"`
+}
+
+// ListNotes returns TODO-style comments and license headers recorded by the
+// notes processor
+func (rc *RepoController) ListNotes(c *gin.Context) {
+	logger := logging.FromContext(c.Request.Context(), rc.logger)
+	var request ListNotesRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		logger.Error("Invalid request payload", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request payload",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	if rc.dbConn == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error": "Database connection not available. Notes require a relational store.",
+		})
+		return
+	}
+
+	if _, err := rc.config.GetRepository(request.RepoName); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "Repository not found",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	store, err := db.NewCodeNoteStore(rc.dbConn.GetDB(), request.RepoName, rc.logger)
+	if err != nil {
+		logger.Error("Failed to open note store", zap.String("repo_name", request.RepoName), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to open note store",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	found, err := store.WithContext(c.Request.Context()).ListNotes(notes.Filter{
+		Kinds:      request.Kinds,
+		PathPrefix: request.PathPrefix,
+		Author:     request.Author,
+		Contains:   request.Contains,
+		Limit:      request.Limit,
+		Offset:     request.Offset,
+	})
+	if err != nil {
+		logger.Error("Failed to list notes", zap.String("repo_name", request.RepoName), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to list notes",
+			"details": err.Error(),
+		})
+		return
+	}
+	if found == nil {
+		found = []notes.CodeNote{}
+	}
+
+	c.JSON(http.StatusOK, ListNotesResponse{RepoName: request.RepoName, Notes: found})
+}
+
+// SearchNotesRequest searches embedded notes by meaning
+type SearchNotesRequest struct {
+	RepoName string `json:"repo_name" binding:"required"`
+	Query    string `json:"query" binding:"required"`
+	Limit    int    `json:"limit,omitempty"`
+	Explain  bool   `json:"explain,omitempty"` // add why each note matched
+}
+
+// NoteSearchResult is a note found by SearchNotes
+type NoteSearchResult struct {
+	FilePath string  `json:"file_path"`
+	FileID   int32   `json:"file_id"`
+	Line     int     `json:"line"` // 1-based
+	Kind     string  `json:"kind"`
+	Text     string  `json:"text"`
+	Assignee string  `json:"assignee,omitempty"`
+	Author   string  `json:"author,omitempty"`
+	Score    float32 `json:"score"`
+
+	Explanation *model.SearchExplanation `json:"explanation,omitempty"`
+}
+
+// SearchNotesResponse represents the response of a note search
+type SearchNotesResponse struct {
+	RepoName string             `json:"repo_name"`
+	Query    string             `json:"query"`
+	Results  []NoteSearchResult `json:"results"`
+}
+
+// SearchNotes finds notes embedded with notes.embed closest in meaning to
+// the query, such as "slow database access" for TODOs about query performance
+func (rc *RepoController) SearchNotes(c *gin.Context) {
+	logger := logging.FromContext(c.Request.Context(), rc.logger)
+	var request SearchNotesRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		logger.Error("Invalid request payload", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request payload",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	if rc.chunkService == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error": "Code chunk service not available",
+		})
+		return
+	}
+
+	limit := request.Limit
+	if limit <= 0 {
+		limit = 10
+	}
+
+	chunks, scores, err := rc.chunkService.SearchNotes(c.Request.Context(), request.RepoName, request.Query, limit)
+	if err != nil {
+		logger.Error("Failed to search notes", zap.String("repo_name", request.RepoName), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to search notes",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	var queryTerms []string
+	if request.Explain {
+		queryTerms = searchTerms(request.Query)
+	}
+	results := make([]NoteSearchResult, len(chunks))
+	for i, chunk := range chunks {
+		results[i] = NoteSearchResult{
+			FilePath: chunk.FilePath,
+			FileID:   chunk.FileID,
+			Line:     chunk.StartLine + 1,
+			Kind:     metadataString(chunk.Metadata, "kind"),
+			Text:     metadataString(chunk.Metadata, "text"),
+			Assignee: metadataString(chunk.Metadata, "assignee"),
+			Author:   metadataString(chunk.Metadata, "author"),
+			Score:    scores[i],
+		}
+		if request.Explain {
+			results[i].Explanation = explainHit(queryTerms, chunk, scores[i], vector.NoteSearchFilter())
+		}
+	}
+
+	c.JSON(http.StatusOK, SearchNotesResponse{RepoName: request.RepoName, Query: request.Query, Results: results})
+}
+
+// metadataString returns a string value of chunk metadata, or "" if absent
+func metadataString(metadata map[string]interface{}, key string) string {
+	value, _ := metadata[key].(string)
+	return value
+}
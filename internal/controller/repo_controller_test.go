@@ -0,0 +1,46 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/base64"
+	"testing"
+
+	"github.com/armchr/codeapi/internal/config"
+)
+
+func TestCheckCollectionKey(t *testing.T) {
+	key := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{1}, 32))
+	cfg := &config.Config{
+		Encryption: config.EncryptionConfig{Key: key},
+		Source: config.SourceConfig{Repositories: []config.Repository{
+			{Name: "secret", Encrypt: true},
+			{Name: "plain"},
+		}},
+	}
+	repo := func(name string) *config.Repository {
+		r, err := cfg.GetRepository(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return r
+	}
+
+	tests := []struct {
+		name       string
+		repo       string
+		collection string
+		wantErr    bool
+	}{
+		{"encrypted repository, own collection", "secret", "secret", false},
+		{"encrypted repository, custom collection", "secret", "experiments", true},
+		{"plain repository, custom collection", "plain", "experiments", false},
+		{"plain repository, collection of a repository with a key", "plain", "secret", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := checkCollectionKey(cfg, repo(tt.repo), tt.collection); (err != nil) != tt.wantErr {
+				t.Errorf("checkCollectionKey(%s, %s) error = %v, wantErr %v", tt.repo, tt.collection, err, tt.wantErr)
+			}
+		})
+	}
+}
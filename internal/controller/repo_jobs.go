@@ -0,0 +1,104 @@
+package controller
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/armchr/codeapi/internal/config"
+	"github.com/armchr/codeapi/internal/db"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Job states of a repository
+const (
+	JobStateRunning = "running" // an operation holds the repository lock
+	JobStateIdle    = "idle"
+)
+
+// RepoJob is the operation rewriting the stored data of a repository, if
+// any: the holder of its repository lock. The operation and start are known
+// only when this process holds the lock.
+type RepoJob struct {
+	RepoName  string     `json:"repo_name"`
+	State     string     `json:"state"`
+	Operation string     `json:"operation,omitempty"`
+	Since     *time.Time `json:"since,omitempty"`
+	Remote    bool       `json:"remote,omitempty"` // Held by another process
+}
+
+// ListJobsResponse lists the jobs of the repositories visible to the caller
+type ListJobsResponse struct {
+	Jobs []RepoJob `json:"jobs"`
+}
+
+func repoJob(ctx context.Context, sqlDB *sql.DB, repoName string) (RepoJob, error) {
+	job := RepoJob{RepoName: repoName, State: JobStateIdle}
+	lock, err := db.GetRepoLockStatus(ctx, sqlDB, repoName)
+	if err != nil {
+		return job, err
+	}
+	if lock.Locked {
+		job.State = JobStateRunning
+		job.Operation, job.Since, job.Remote = lock.Operation, lock.Since, lock.Remote
+	}
+	return job, nil
+}
+
+// ListJobs reports for each enabled repository the caller may see whether an
+// index build, file indexing, sandbox purge, clean, compaction or restore is
+// running on it. ?state=running leaves out idle repositories.
+func (rc *RepoController) ListJobs(c *gin.Context) {
+	state := c.Query("state")
+	if state != "" && state != JobStateRunning && state != JobStateIdle {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid state: must be " + JobStateRunning + " or " + JobStateIdle,
+		})
+		return
+	}
+
+	tenant := config.TenantFromContext(c.Request.Context())
+	jobs := make([]RepoJob, 0)
+	for _, repo := range rc.config.Repositories() {
+		if repo.Disabled || (tenant != nil && !strings.HasPrefix(repo.Name, tenant.Name+config.TenantSeparator)) {
+			continue
+		}
+		job, err := repoJob(c.Request.Context(), rc.dbConn.GetDB(), repo.Name)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Failed to read repository lock",
+				"details": err.Error(),
+			})
+			return
+		}
+		if state == "" || job.State == state {
+			jobs = append(jobs, job)
+		}
+	}
+	c.JSON(http.StatusOK, ListJobsResponse{Jobs: jobs})
+}
+
+// GetRepoJob reports whether an operation is running on a repository
+func (rc *RepoController) GetRepoJob(c *gin.Context) {
+	repoName := c.Param("repo")
+	if _, err := rc.config.GetRepository(repoName); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "Repository not found",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	job, err := repoJob(c.Request.Context(), rc.dbConn.GetDB(), repoName)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to read repository lock",
+			"details": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, job)
+}
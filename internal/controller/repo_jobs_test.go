@@ -0,0 +1,98 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/armchr/codeapi/internal/config"
+	"github.com/armchr/codeapi/internal/db"
+	"github.com/armchr/codeapi/internal/service/audit"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+func TestRepoJobs(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	conn := newBackupTestDB(t)
+	cfg := &config.Config{Source: config.SourceConfig{Repositories: []config.Repository{
+		{Name: "acme__api"},
+		{Name: "acme__web"},
+		{Name: "globex__api"},
+		{Name: "acme__old", Disabled: true},
+	}}}
+	rc := &RepoController{config: cfg, dbConn: conn, logger: zap.NewNop()}
+
+	lock, err := db.LockRepo(context.Background(), conn.GetDB(), "acme__api", audit.OpIndexBuild, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lock.Release()
+
+	serve := func(handler gin.HandlerFunc, target string, tenant *config.TenantConfig, params ...gin.Param) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, target, nil)
+		if tenant != nil {
+			c.Request = c.Request.WithContext(config.WithTenant(c.Request.Context(), tenant))
+		}
+		c.Params = params
+		handler(c)
+		return w
+	}
+	acme := &config.TenantConfig{Name: "acme"}
+
+	tests := []struct {
+		name   string
+		target string
+		tenant *config.TenantConfig
+		want   map[string]string // repository to state
+	}{
+		{"all", "/jobs", nil, map[string]string{"acme__api": JobStateRunning, "acme__web": JobStateIdle, "globex__api": JobStateIdle}},
+		{"running", "/jobs?state=running", nil, map[string]string{"acme__api": JobStateRunning}},
+		{"tenant", "/jobs", acme, map[string]string{"acme__api": JobStateRunning, "acme__web": JobStateIdle}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(rc.ListJobs, tt.target, tt.tenant)
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d (%s)", w.Code, w.Body.String())
+			}
+			var resp ListJobsResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatal(err)
+			}
+			got := make(map[string]string)
+			for _, job := range resp.Jobs {
+				got[job.RepoName] = job.State
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("jobs = %v, want %v", got, tt.want)
+			}
+			for repo, state := range tt.want {
+				if got[repo] != state {
+					t.Errorf("%s is %q, want %q", repo, got[repo], state)
+				}
+			}
+		})
+	}
+
+	if w := serve(rc.ListJobs, "/jobs?state=done", nil); w.Code != http.StatusBadRequest {
+		t.Errorf("invalid state answered %d, want 400", w.Code)
+	}
+
+	w := serve(rc.GetRepoJob, "/jobs/acme__api", nil, gin.Param{Key: "repo", Value: "acme__api"})
+	var job RepoJob
+	if err := json.Unmarshal(w.Body.Bytes(), &job); err != nil {
+		t.Fatal(err)
+	}
+	if job.State != JobStateRunning || job.Operation != audit.OpIndexBuild || job.Since == nil || job.Remote {
+		t.Errorf("job of acme__api = %+v, want index_build running in this process", job)
+	}
+	if w := serve(rc.GetRepoJob, "/jobs/nope", nil, gin.Param{Key: "repo", Value: "nope"}); w.Code != http.StatusNotFound {
+		t.Errorf("unknown repository answered %d, want 404", w.Code)
+	}
+}
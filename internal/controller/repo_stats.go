@@ -0,0 +1,203 @@
+package controller
+
+import (
+	"context"
+	"database/sql"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/armchr/codeapi/internal/db"
+	"github.com/armchr/codeapi/internal/service/codegraph"
+	"github.com/armchr/codeapi/internal/service/vector"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// SummaryCoverage is the percentage of the functions, classes and files in
+// the code graph that have a summary
+type SummaryCoverage struct {
+	Functions float64 `json:"functions"`
+	Classes   float64 `json:"classes"`
+	Files     float64 `json:"files"`
+}
+
+// TokenSpend is the LLM tokens spent generating the stored summaries
+type TokenSpend struct {
+	Prompt int64 `json:"prompt"`
+	Output int64 `json:"output"`
+	Total  int64 `json:"total"`
+}
+
+// RepoStats is what every store holds for a repository, for dashboards.
+// Sections of stores that are not configured or failed to answer are left
+// out and the reason is given in Errors, keyed by store.
+type RepoStats struct {
+	RepoName string `json:"repo_name"`
+
+	// Code graph
+	Languages map[string]*codegraph.LanguageCounts `json:"languages,omitempty"`
+	Totals    *codegraph.LanguageCounts            `json:"totals,omitempty"`
+	Edges     map[string]int64                     `json:"edges,omitempty"`
+
+	// Vector store
+	Chunks *int64 `json:"chunks,omitempty"`
+
+	// Relational store
+	FileVersions    *int64             `json:"file_versions,omitempty"`
+	LastIndexedAt   *time.Time         `json:"last_indexed_at,omitempty"`
+	Summaries       *db.SummaryStats   `json:"summaries,omitempty"`
+	SummaryCoverage *SummaryCoverage   `json:"summary_coverage,omitempty"`
+	TokenSpend      *TokenSpend        `json:"token_spend,omitempty"`
+	Lock            *db.RepoLockStatus `json:"lock,omitempty"` // Whether an index, clean or compaction is running
+
+	Errors map[string]string `json:"errors,omitempty"`
+}
+
+// CollectRepoStats gathers the statistics of a repository from each store
+// with read-only queries. Stores passed as nil are reported as not
+// configured; a failing store does not keep the others from being read.
+func CollectRepoStats(ctx context.Context, repoName string, sqlDB *sql.DB, codeGraph *codegraph.CodeGraph, vectorDB vector.VectorDatabase, logger *zap.Logger) *RepoStats {
+	stats := &RepoStats{RepoName: repoName}
+	failed := func(store string, err error) {
+		if stats.Errors == nil {
+			stats.Errors = map[string]string{}
+		}
+		stats.Errors[store] = err.Error()
+		logger.Warn("Failed to collect repository stats", zap.String("repo_name", repoName), zap.String("store", store), zap.Error(err))
+	}
+	notConfigured := func(store string) {
+		if stats.Errors == nil {
+			stats.Errors = map[string]string{}
+		}
+		stats.Errors[store] = "not configured"
+	}
+
+	if codeGraph == nil {
+		notConfigured("neo4j")
+	} else if graph, err := codeGraph.RepositoryStats(ctx, repoName); err != nil {
+		failed("neo4j", err)
+	} else {
+		stats.Languages, stats.Edges = graph.Languages, graph.Edges
+		stats.Totals = &codegraph.LanguageCounts{}
+		for _, counts := range graph.Languages {
+			stats.Totals.Files += counts.Files
+			stats.Totals.Functions += counts.Functions
+			stats.Totals.Classes += counts.Classes
+		}
+	}
+
+	if vectorDB == nil {
+		notConfigured("qdrant")
+	} else if exists, err := vectorDB.CollectionExists(ctx, repoName); err != nil {
+		failed("qdrant", err)
+	} else {
+		var points uint64
+		if exists {
+			points, err = vectorDB.CountChunks(ctx, repoName)
+		}
+		if err != nil {
+			failed("qdrant", err)
+		} else {
+			chunks := int64(points)
+			stats.Chunks = &chunks
+		}
+	}
+
+	if sqlDB == nil {
+		notConfigured("db")
+		return stats
+	}
+	if lock, err := db.GetRepoLockStatus(ctx, sqlDB, repoName); err != nil {
+		failed("lock", err)
+	} else {
+		stats.Lock = lock
+	}
+	collectDBStats(ctx, stats, repoName, sqlDB, logger, failed)
+	return stats
+}
+
+// collectDBStats fills in the file version and summary sections of stats.
+// Tables that were never created count as empty.
+func collectDBStats(ctx context.Context, stats *RepoStats, repoName string, sqlDB *sql.DB, logger *zap.Logger, failed func(string, error)) {
+	exists, err := db.TableExists(sqlDB, db.FileVersionsTable)
+	if err != nil {
+		failed("db", err)
+		return
+	}
+	var versions int64
+	if exists {
+		reader := db.NewFileVersionReader(sqlDB, repoName, logger).WithContext(ctx)
+		if versions, _, _, err = reader.GetStats(); err != nil {
+			failed("db", err)
+			return
+		}
+		if stats.LastIndexedAt, err = reader.LastIndexedAt(); err != nil {
+			failed("db", err)
+			return
+		}
+	}
+	stats.FileVersions = &versions
+
+	exists, err = db.TableExists(sqlDB, db.CodeSummariesTable)
+	if err != nil {
+		failed("db", err)
+		return
+	}
+	summaries := &db.SummaryStats{}
+	if exists {
+		if summaries, err = db.NewSummaryReader(sqlDB, repoName, logger).WithContext(ctx).GetStats(); err != nil {
+			failed("db", err)
+			return
+		}
+	}
+	stats.Summaries = summaries
+	stats.TokenSpend = &TokenSpend{
+		Prompt: summaries.TotalPromptTokens,
+		Output: summaries.TotalOutputTokens,
+		Total:  summaries.TotalPromptTokens + summaries.TotalOutputTokens,
+	}
+	if stats.Totals != nil {
+		stats.SummaryCoverage = &SummaryCoverage{
+			Functions: percentage(summaries.Functions, stats.Totals.Functions),
+			Classes:   percentage(summaries.Classes, stats.Totals.Classes),
+			Files:     percentage(summaries.Files, stats.Totals.Files),
+		}
+	}
+}
+
+// percentage returns part as a percentage of total, to one decimal and
+// capped at 100: summaries of file versions no longer in the graph can
+// outnumber the nodes
+func percentage(part, total int64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return min(math.Round(float64(part)*1000/float64(total))/10, 100)
+}
+
+// GetRepoStats returns what every store holds for a repository: graph
+// counts by language and relationship type, chunks, summary coverage, the
+// last index time and the tokens spent on summaries
+func (rc *RepoController) GetRepoStats(c *gin.Context) {
+	repoName := c.Param("repo")
+	if _, err := rc.config.GetRepository(repoName); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "Repository not found",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	var sqlDB *sql.DB
+	if rc.dbConn != nil {
+		sqlDB = rc.dbConn.GetDB()
+	}
+	var vectorDB vector.VectorDatabase
+	if rc.chunkService != nil {
+		vectorDB = rc.chunkService.GetVectorDB()
+	}
+
+	c.JSON(http.StatusOK, CollectRepoStats(c.Request.Context(), repoName, sqlDB, rc.codeGraph, vectorDB, rc.logger))
+}
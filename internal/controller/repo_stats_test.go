@@ -0,0 +1,66 @@
+package controller
+
+import (
+	"context"
+	"testing"
+
+	"github.com/armchr/codeapi/internal/db"
+	"github.com/armchr/codeapi/internal/service/summary"
+
+	"go.uber.org/zap"
+)
+
+func TestCollectRepoStatsRelationalStore(t *testing.T) {
+	logger := zap.NewNop()
+	conn := newBackupTestDB(t)
+
+	// Nothing indexed yet: the tables do not exist
+	stats := CollectRepoStats(context.Background(), "api", conn.GetDB(), nil, nil, logger)
+	if stats.FileVersions == nil || *stats.FileVersions != 0 || stats.LastIndexedAt != nil || stats.Summaries == nil {
+		t.Fatalf("unexpected stats before indexing: %+v", stats)
+	}
+	if stats.Errors["neo4j"] != "not configured" || stats.Errors["qdrant"] != "not configured" || stats.Errors["db"] != "" {
+		t.Errorf("unexpected errors %v", stats.Errors)
+	}
+
+	versions, err := db.NewFileVersionRepository(conn.GetDB(), "api", logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	versions.GetOrCreateFileID("sha1", "a.go", false, nil)
+	versions.GetOrCreateFileID("sha2", "b.go", false, nil)
+	summaries, err := db.NewSummaryStore(conn.GetDB(), "api", logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	summaries.SaveSummary(&summary.CodeSummary{EntityID: "a.go", EntityType: summary.LevelFile, FilePath: "a.go", Summary: "entry point", PromptTokens: 120, OutputTokens: 30})
+
+	stats = CollectRepoStats(context.Background(), "api", conn.GetDB(), nil, nil, logger)
+	if *stats.FileVersions != 2 || stats.LastIndexedAt == nil {
+		t.Errorf("unexpected file version stats: %d versions, last indexed %v", *stats.FileVersions, stats.LastIndexedAt)
+	}
+	if stats.Summaries.Files != 1 || *stats.TokenSpend != (TokenSpend{Prompt: 120, Output: 30, Total: 150}) {
+		t.Errorf("unexpected summary stats %+v, tokens %+v", stats.Summaries, stats.TokenSpend)
+	}
+	// Coverage needs the graph's counts
+	if stats.SummaryCoverage != nil {
+		t.Errorf("coverage without a code graph: %+v", stats.SummaryCoverage)
+	}
+}
+
+func TestPercentage(t *testing.T) {
+	tests := []struct {
+		part, total int64
+		want        float64
+	}{
+		{1, 3, 33.3},
+		{2, 3, 66.7},
+		{0, 0, 0},
+		{5, 4, 100},
+	}
+	for _, tt := range tests {
+		if got := percentage(tt.part, tt.total); got != tt.want {
+			t.Errorf("percentage(%d, %d) = %v, want %v", tt.part, tt.total, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/armchr/codeapi/internal/config"
+	"github.com/armchr/codeapi/internal/db"
+	"github.com/armchr/codeapi/internal/service/audit"
+
+	"go.uber.org/zap"
+)
+
+// SandboxCleaner removes graph nodes, vectors and summaries created by ad-hoc
+// file indexing (indexFile) once their sandbox file versions expire.
+type SandboxCleaner struct {
+	processors []FileProcessor
+	dbConn     db.Connection
+	config     *config.Config
+	logger     *zap.Logger
+}
+
+// NewSandboxCleaner creates a cleaner that purges through the given processors
+func NewSandboxCleaner(processors []FileProcessor, dbConn db.Connection, cfg *config.Config, logger *zap.Logger) *SandboxCleaner {
+	return &SandboxCleaner{
+		processors: processors,
+		dbConn:     dbConn,
+		config:     cfg,
+		logger:     logger,
+	}
+}
+
+// PurgeRepository purges sandbox file versions of a repository last indexed
+// before cutoff. A zero cutoff purges every sandbox version. Returns the number
+// of file versions removed.
+func (sc *SandboxCleaner) PurgeRepository(ctx context.Context, repo *config.Repository, cutoff time.Time) (int, error) {
+	if sc.dbConn == nil {
+		return 0, fmt.Errorf("database connection not available for file tracking")
+	}
+
+	fileVersionRepo, err := db.NewFileVersionRepository(sc.dbConn.GetDB(), repo.Name, sc.logger)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create file version repository: %w", err)
+	}
+	fileVersionRepo = fileVersionRepo.WithContext(ctx)
+
+	// A build or ad-hoc indexing run may be writing the same file versions
+	lock, err := fileVersionRepo.Lock(audit.OpPurgeSandbox)
+	if err != nil {
+		return 0, err
+	}
+	defer lock.Release()
+
+	versions, err := fileVersionRepo.GetSandboxVersions(cutoff)
+	if err != nil {
+		return 0, err
+	}
+	if len(versions) == 0 {
+		return 0, nil
+	}
+
+	fileIDs := make([]int32, len(versions))
+	for i, v := range versions {
+		fileIDs[i] = v.FileID
+	}
+
+	// Keep the file versions on failure so the next sweep retries
+	if err := purgeFiles(ctx, sc.processors, repo, fileIDs); err != nil {
+		return 0, err
+	}
+
+	deleted, err := fileVersionRepo.DeleteVersions(fileIDs)
+	if err != nil {
+		return 0, err
+	}
+
+	sc.logger.Info("Purged sandbox file versions",
+		zap.String("repo_name", repo.Name),
+		zap.Int64("count", deleted))
+
+	return int(deleted), nil
+}
+
+// Run sweeps expired sandbox data for all enabled repositories on every
+// cleanup interval until ctx is cancelled.
+func (sc *SandboxCleaner) Run(ctx context.Context) {
+	sandboxCfg := sc.config.Sandbox.GetDefaults()
+
+	sc.logger.Info("Starting sandbox cleanup",
+		zap.Duration("ttl", sandboxCfg.TTL()),
+		zap.Duration("interval", sandboxCfg.CleanupInterval()))
+
+	ticker := time.NewTicker(sandboxCfg.CleanupInterval())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			cutoff := time.Now().Add(-sandboxCfg.TTL())
+			repos := sc.config.Repositories()
+			for i := range repos {
+				repo := &repos[i]
+				if repo.Disabled {
+					continue
+				}
+				entry := NewAuditEntry(audit.OpPurgeSandbox, repo.Name, audit.SourceScheduler)
+				purged, err := sc.PurgeRepository(ctx, repo, cutoff)
+				if errors.Is(err, db.ErrRepoLocked) {
+					// Retried on the next sweep
+					sc.logger.Debug("Skipping sandbox cleanup of a locked repository",
+						zap.String("repo_name", repo.Name),
+						zap.Error(err))
+					continue
+				}
+				if err != nil {
+					sc.logger.Error("Sandbox cleanup failed",
+						zap.String("repo_name", repo.Name),
+						zap.Error(err))
+				}
+				// Rounds that found nothing to purge changed nothing
+				if sc.dbConn != nil && (purged > 0 || err != nil) {
+					entry.Details = map[string]any{"purged_versions": purged}
+					RecordAudit(ctx, sc.dbConn.GetDB(), entry, err, sc.logger)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/armchr/codeapi/internal/config"
+	"github.com/armchr/codeapi/internal/db"
+
+	"go.uber.org/zap"
+)
+
+func TestSandboxCleanerRepoLock(t *testing.T) {
+	logger := zap.NewNop()
+	conn := newBackupTestDB(t)
+	versions, err := db.NewFileVersionRepository(conn.GetDB(), "api", logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lock, err := versions.Lock("index_build")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cleaner := NewSandboxCleaner(nil, conn, &config.Config{}, logger)
+	repo := &config.Repository{Name: "api", Path: t.TempDir(), Language: "go"}
+	if _, err := cleaner.PurgeRepository(context.Background(), repo, time.Time{}); !errors.Is(err, db.ErrRepoLocked) {
+		t.Fatalf("PurgeRepository during a build = %v, want ErrRepoLocked", err)
+	}
+
+	// The purge releases the lock it takes
+	lock.Release()
+	for i := 0; i < 2; i++ {
+		if _, err := cleaner.PurgeRepository(context.Background(), repo, time.Time{}); err != nil {
+			t.Fatalf("PurgeRepository #%d = %v", i+1, err)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package controller
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/armchr/codeapi/internal/model"
+)
+
+// explainStopWords are left out of lexical overlap: English filler and
+// keywords shared by most code, which would match nearly every hit
+var explainStopWords = map[string]bool{
+	"the": true, "and": true, "for": true, "with": true, "from": true, "that": true, "this": true, "into": true,
+	"func": true, "function": true, "def": true, "class": true, "return": true, "returns": true,
+	"public": true, "private": true, "protected": true, "static": true, "final": true, "void": true,
+	"var": true, "let": true, "const": true, "new": true, "import": true, "package": true,
+	"self": true, "nil": true, "null": true, "none": true, "true": true, "false": true,
+	"int": true, "string": true, "bool": true, "error": true, "err": true,
+}
+
+// searchTerms splits text into lowercase words, breaking identifiers at
+// camelCase humps and underscores, without short words and stop words. The
+// words are returned once each, in the order they first appear.
+func searchTerms(text string) []string {
+	var terms []string
+	seen := map[string]bool{}
+	add := func(word string) {
+		word = strings.ToLower(word)
+		if len(word) < 3 || explainStopWords[word] || seen[word] {
+			return
+		}
+		seen[word] = true
+		terms = append(terms, word)
+	}
+
+	fields := strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for _, field := range fields {
+		start := 0
+		runes := []rune(field)
+		for i := 1; i < len(runes); i++ {
+			if unicode.IsUpper(runes[i]) && unicode.IsLower(runes[i-1]) {
+				add(string(runes[start:i]))
+				start = i
+			}
+		}
+		add(string(runes[start:]))
+	}
+	return terms
+}
+
+// lexicalOverlap returns the query terms found in the text of a hit. The
+// vector store may not keep chunk content, so names, signatures and the text
+// kept in the metadata are searched as well.
+func lexicalOverlap(queryTerms []string, chunk *model.CodeChunk) []string {
+	texts := []string{chunk.Name, chunk.ClassName, chunk.Signature, chunk.Docstring, chunk.Content}
+	for _, key := range []string{"normalized_text", "text", "summary"} {
+		texts = append(texts, metadataString(chunk.Metadata, key))
+	}
+	hitTerms := map[string]bool{}
+	for _, term := range searchTerms(strings.Join(texts, " ")) {
+		hitTerms[term] = true
+	}
+
+	overlap := []string{}
+	for _, term := range queryTerms {
+		if hitTerms[term] {
+			overlap = append(overlap, term)
+		}
+	}
+	return overlap
+}
+
+// explainHit describes why chunk matched a query
+func explainHit(queryTerms []string, chunk *model.CodeChunk, score float32, filter map[string]interface{}) *model.SearchExplanation {
+	return &model.SearchExplanation{
+		VectorScore:    score,
+		LexicalOverlap: lexicalOverlap(queryTerms, chunk),
+		Filters:        filter,
+	}
+}
+
+// describeQueryChunk names a chunk of a snippet query, e.g. "function
+// processOrder"
+func describeQueryChunk(chunk *model.CodeChunk) string {
+	if chunk.Name == "" {
+		return string(chunk.ChunkType)
+	}
+	return string(chunk.ChunkType) + " " + chunk.Name
+}
@@ -0,0 +1,61 @@
+package controller
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/armchr/codeapi/internal/model"
+)
+
+func TestSearchTerms(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{"natural language", "find user by email", []string{"find", "user", "email"}},
+		{"camel case", "findUserByEmail(String email)", []string{"find", "user", "email"}},
+		{"snake case and keywords", "def load_user_config(self):\n    return None", []string{"load", "user", "config"}},
+		{"repeated words once", "Order order ORDER orders", []string{"order", "orders"}},
+		{"empty", "", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := searchTerms(tt.text); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("searchTerms(%q) = %v, want %v", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLexicalOverlap(t *testing.T) {
+	queryTerms := searchTerms("find user by email address")
+	tests := []struct {
+		name  string
+		chunk *model.CodeChunk
+		want  []string
+	}{
+		{
+			name:  "name and signature",
+			chunk: &model.CodeChunk{Name: "findByEmail", ClassName: "UserRepository", Signature: "User findByEmail(String email)"},
+			want:  []string{"find", "user", "email"},
+		},
+		{
+			name:  "metadata text without content",
+			chunk: &model.CodeChunk{Metadata: map[string]interface{}{"text": "TODO: validate the address format"}},
+			want:  []string{"address"},
+		},
+		{
+			name:  "no overlap is empty, not nil",
+			chunk: &model.CodeChunk{Name: "parseConfig"},
+			want:  []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := lexicalOverlap(queryTerms, tt.chunk); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("lexicalOverlap() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package controller
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/armchr/codeapi/internal/db"
+	"github.com/armchr/codeapi/internal/model"
+	"github.com/armchr/codeapi/internal/service/summary"
+
+	"go.uber.org/zap"
+)
+
+// searchSummaries finds the stored summaries of search hits. Summaries of a
+// file are loaded once, on the first hit in it.
+type searchSummaries struct {
+	store    *db.SummaryStore
+	repoPath string
+	byFile   map[string][]*summary.CodeSummary
+	logger   *zap.Logger
+}
+
+// newSearchSummaries returns a lookup over the summaries of a repository, or
+// nil when it has none stored
+func newSearchSummaries(ctx context.Context, sqlDB *sql.DB, repoName, repoPath string, logger *zap.Logger) (*searchSummaries, error) {
+	exists, err := db.TableExists(sqlDB, db.CodeSummariesTable)
+	if err != nil || !exists {
+		return nil, err
+	}
+	return &searchSummaries{
+		store:    db.NewSummaryReader(sqlDB, repoName, logger).WithContext(ctx),
+		repoPath: repoPath,
+		byFile:   map[string][]*summary.CodeSummary{},
+		logger:   logger,
+	}, nil
+}
+
+// forChunk returns the summary of the function, class or file a chunk was
+// cut from, or "" when it has none. Blocks and other partial chunks are
+// not summarized.
+func (s *searchSummaries) forChunk(chunk *model.CodeChunk) string {
+	if s == nil || chunk == nil {
+		return ""
+	}
+	var level summary.SummaryLevel
+	switch chunk.ChunkType {
+	case model.ChunkTypeFunction, model.ChunkTypeMethodSignature:
+		level = summary.LevelFunction
+	case model.ChunkTypeClass:
+		level = summary.LevelClass
+	case model.ChunkTypeFile:
+		level = summary.LevelFile
+	default:
+		return ""
+	}
+
+	// Chunks may hold absolute paths, summaries are keyed by the path in
+	// the repository
+	path, ok := repoRelativePath(s.repoPath, chunk.FilePath)
+	if !ok {
+		return ""
+	}
+
+	summaries, ok := s.byFile[path]
+	if !ok {
+		var err error
+		if summaries, err = s.store.GetSummariesByFile(path); err != nil {
+			s.logger.Warn("Failed to load summaries of search hit",
+				zap.String("file", path),
+				zap.Error(err))
+		}
+		s.byFile[path] = summaries
+	}
+	for _, cs := range summaries {
+		if cs.EntityType == level && (level == summary.LevelFile || cs.EntityName == chunk.Name) {
+			return cs.Summary
+		}
+	}
+	return ""
+}
+
+// searchSummaries returns the summary lookup for a search over a repository.
+// Hits go without summaries when no relational store is configured or the
+// summaries cannot be read; the search itself still succeeds.
+func (rc *RepoController) searchSummaries(ctx context.Context, repoName string) *searchSummaries {
+	if rc.dbConn == nil {
+		rc.logger.Warn("Summaries requested but no database is configured", zap.String("repo_name", repoName))
+		return nil
+	}
+	repo, err := rc.config.GetRepository(repoName)
+	if err != nil {
+		rc.logger.Warn("Summaries requested for unknown repository", zap.String("repo_name", repoName), zap.Error(err))
+		return nil
+	}
+	summaries, err := newSearchSummaries(ctx, rc.dbConn.GetDB(), repoName, repo.Path, rc.logger)
+	if err != nil {
+		rc.logger.Warn("Failed to open summaries for search results", zap.String("repo_name", repoName), zap.Error(err))
+		return nil
+	}
+	return summaries
+}
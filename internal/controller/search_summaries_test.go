@@ -0,0 +1,61 @@
+package controller
+
+import (
+	"context"
+	"testing"
+
+	"github.com/armchr/codeapi/internal/db"
+	"github.com/armchr/codeapi/internal/model"
+	"github.com/armchr/codeapi/internal/service/summary"
+
+	"go.uber.org/zap"
+)
+
+func TestSearchSummariesForChunk(t *testing.T) {
+	logger := zap.NewNop()
+	conn := newBackupTestDB(t)
+
+	// No summaries stored yet: no lookup
+	if s, err := newSearchSummaries(context.Background(), conn.GetDB(), "api", "/src/api", logger); err != nil || s != nil {
+		t.Fatalf("newSearchSummaries before summarizing = %v, %v", s, err)
+	}
+
+	store, err := db.NewSummaryStore(conn.GetDB(), "api", logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.SaveSummaries([]*summary.CodeSummary{
+		{EntityID: "11", EntityType: summary.LevelFunction, EntityName: "Serve", FilePath: "server/http.go", Summary: "serves requests"},
+		{EntityID: "12", EntityType: summary.LevelClass, EntityName: "Server", FilePath: "server/http.go", Summary: "an HTTP server"},
+		{EntityID: "server/http.go", EntityType: summary.LevelFile, EntityName: "http.go", FilePath: "server/http.go", Summary: "the HTTP front end"},
+	})
+
+	s, err := newSearchSummaries(context.Background(), conn.GetDB(), "api", "/src/api", logger)
+	if err != nil || s == nil {
+		t.Fatalf("newSearchSummaries = %v, %v", s, err)
+	}
+	tests := []struct {
+		name  string
+		chunk *model.CodeChunk
+		want  string
+	}{
+		{"function", &model.CodeChunk{ChunkType: model.ChunkTypeFunction, Name: "Serve", FilePath: "server/http.go"}, "serves requests"},
+		{"absolute path", &model.CodeChunk{ChunkType: model.ChunkTypeFunction, Name: "Serve", FilePath: "/src/api/server/http.go"}, "serves requests"},
+		{"method signature", &model.CodeChunk{ChunkType: model.ChunkTypeMethodSignature, Name: "Serve", FilePath: "server/http.go"}, "serves requests"},
+		{"class", &model.CodeChunk{ChunkType: model.ChunkTypeClass, Name: "Server", FilePath: "server/http.go"}, "an HTTP server"},
+		{"file", &model.CodeChunk{ChunkType: model.ChunkTypeFile, Name: "http.go", FilePath: "server/http.go"}, "the HTTP front end"},
+		{"unsummarized function", &model.CodeChunk{ChunkType: model.ChunkTypeFunction, Name: "Close", FilePath: "server/http.go"}, ""},
+		{"block", &model.CodeChunk{ChunkType: model.ChunkTypeBlock, Name: "Serve", FilePath: "server/http.go"}, ""},
+		{"outside the repository", &model.CodeChunk{ChunkType: model.ChunkTypeFunction, Name: "Serve", FilePath: "/src/other/server/http.go"}, ""},
+	}
+	for _, tt := range tests {
+		if got := s.forChunk(tt.chunk); got != tt.want {
+			t.Errorf("%s: forChunk = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+
+	var none *searchSummaries
+	if got := none.forChunk(tests[0].chunk); got != "" {
+		t.Errorf("forChunk without summaries = %q", got)
+	}
+}
@@ -0,0 +1,108 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/armchr/codeapi/internal/chunk"
+	"github.com/armchr/codeapi/internal/logging"
+	"github.com/armchr/codeapi/internal/model"
+	"github.com/armchr/codeapi/internal/service/vector"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// FindSimilarEntities returns the classes or files across repositories most
+// similar to a given class or file, for spotting duplicated services and
+// finding related code while exploring an unfamiliar codebase
+func (rc *RepoController) FindSimilarEntities(c *gin.Context) {
+	logger := logging.FromContext(c.Request.Context(), rc.logger)
+	var request model.SimilarEntitiesRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		logger.Error("Invalid request", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	if rc.chunkService == nil {
+		logger.Error("Code chunk service not available")
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error": "Code chunk service not available",
+		})
+		return
+	}
+
+	repoNames := request.RepoNames
+	if len(repoNames) == 0 {
+		repoNames = []string{request.RepoName}
+	}
+	for _, repoName := range append([]string{request.RepoName}, repoNames...) {
+		if _, err := rc.config.GetRepository(repoName); err != nil {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "Repository not found",
+				"details": err.Error(),
+			})
+			return
+		}
+	}
+
+	if request.MinSimilarity < 0 || request.MinSimilarity > 1 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Invalid min_similarity %v: must be a cosine similarity between 0 and 1", request.MinSimilarity),
+		})
+		return
+	}
+	limit := request.Limit
+	if limit <= 0 {
+		limit = chunk.DefaultSimilarEntities
+	}
+
+	resp := model.SimilarEntitiesResponse{
+		RepoName:  request.RepoName,
+		FilePath:  request.FilePath,
+		ClassName: request.ClassName,
+		Kind:      "file",
+		Results:   []model.SimilarEntity{},
+	}
+	if request.ClassName != "" {
+		resp.Kind = "class"
+	}
+
+	compared, similar, err := rc.chunkService.FindSimilarEntities(c.Request.Context(), request.RepoName,
+		request.FilePath, request.ClassName, repoNames, limit, request.MinSimilarity)
+	if err != nil {
+		logger.Error("Failed to find similar entities",
+			zap.String("repo_name", request.RepoName),
+			zap.String("file_path", request.FilePath),
+			zap.String("class_name", request.ClassName),
+			zap.Error(err))
+		resp.Message = fmt.Sprintf("Failed to find similar %ss: %v", resp.Kind, err)
+		status := http.StatusInternalServerError
+		if errors.Is(err, vector.ErrEntityNotFound) {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, resp)
+		return
+	}
+
+	resp.Compared = compared
+	for _, s := range similar {
+		resp.Results = append(resp.Results, model.SimilarEntity{
+			RepoName:   s.Repo,
+			FilePath:   s.FilePath,
+			FileID:     s.FileID,
+			ClassName:  s.ClassName,
+			StartLine:  s.StartLine,
+			EndLine:    s.EndLine,
+			Chunks:     s.Chunks,
+			Similarity: s.Similarity,
+		})
+	}
+	resp.Success = true
+	c.JSON(http.StatusOK, resp)
+}
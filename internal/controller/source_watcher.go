@@ -0,0 +1,113 @@
+package controller
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"github.com/armchr/codeapi/internal/config"
+	"github.com/armchr/codeapi/internal/service"
+
+	"go.uber.org/zap"
+)
+
+// SourceWatcher reloads repository definitions when source.yaml changes.
+// Added repositories need no setup here: file version tables and language
+// servers are created on their first request. Removed or relocated
+// repositories have their language servers stopped; requests for removed
+// repositories fail from then on because the config no longer lists them.
+type SourceWatcher struct {
+	path        string
+	config      *config.Config
+	repoService *service.RepoService
+	logger      *zap.Logger
+
+	modTime time.Time
+	size    int64
+}
+
+// NewSourceWatcher creates a watcher for the source config at path
+func NewSourceWatcher(path string, cfg *config.Config, repoService *service.RepoService, logger *zap.Logger) *SourceWatcher {
+	sw := &SourceWatcher{
+		path:        path,
+		config:      cfg,
+		repoService: repoService,
+		logger:      logger,
+	}
+	// The config was loaded from the current file, so only later edits reload
+	if info, err := os.Stat(path); err == nil {
+		sw.modTime, sw.size = info.ModTime(), info.Size()
+	}
+	return sw
+}
+
+// Run polls source.yaml on every reload interval until ctx is cancelled.
+// Polling is used instead of filesystem events so edits made by replacing the
+// file (editors, ConfigMap updates) are picked up as well.
+func (sw *SourceWatcher) Run(ctx context.Context) {
+	interval := sw.config.App.SourceReloadInterval()
+	sw.logger.Info("Watching source config for repository changes",
+		zap.String("path", sw.path),
+		zap.Duration("interval", interval))
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if !sw.changed() {
+				continue
+			}
+			if _, err := sw.Reload(ctx); err != nil {
+				sw.logger.Error("Failed to reload source config, keeping current repositories",
+					zap.String("path", sw.path),
+					zap.Error(err))
+			}
+		}
+	}
+}
+
+// changed reports whether the file was modified since it was last seen
+func (sw *SourceWatcher) changed() bool {
+	info, err := os.Stat(sw.path)
+	if err != nil {
+		return false
+	}
+	if info.ModTime().Equal(sw.modTime) && info.Size() == sw.size {
+		return false
+	}
+	sw.modTime, sw.size = info.ModTime(), info.Size()
+	return true
+}
+
+// Reload reads source.yaml and swaps in its repository list. An invalid file
+// leaves the current repositories untouched.
+func (sw *SourceWatcher) Reload(ctx context.Context) (config.RepositoryDiff, error) {
+	repos, err := config.LoadSourceConfig(sw.path, sw.config.Tenancy)
+	if err != nil {
+		return config.RepositoryDiff{}, err
+	}
+
+	diff := sw.config.SetRepositories(repos)
+	if diff.Empty() {
+		sw.logger.Debug("Source config reloaded, repositories unchanged")
+		return diff, nil
+	}
+
+	sw.logger.Info("Source config reloaded",
+		zap.Strings("added", diff.Added),
+		zap.Strings("removed", diff.Removed),
+		zap.Strings("changed", diff.Changed))
+
+	if sw.repoService != nil {
+		for _, names := range [][]string{diff.Removed, diff.Changed} {
+			for _, name := range names {
+				sw.repoService.ReleaseRepository(ctx, name)
+			}
+		}
+	}
+	return diff, nil
+}
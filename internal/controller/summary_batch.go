@@ -0,0 +1,197 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/armchr/codeapi/internal/db"
+	"github.com/armchr/codeapi/internal/service/summary"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// SummaryRef names an entity whose summary is wanted, either by entity ID
+// (a node ID, or the path of a file or folder) or by file path and, for
+// functions and classes, entity name
+type SummaryRef struct {
+	EntityType string `json:"entity_type" binding:"required"`
+	EntityID   string `json:"entity_id,omitempty"`
+	FilePath   string `json:"file_path,omitempty"`
+	EntityName string `json:"entity_name,omitempty"`
+}
+
+// BatchSummariesRequest asks for the summaries of many entities at once.
+// With GenerateMissing, summaries that are not stored are generated in the
+// background for the entities named by file path; fetch them again later.
+type BatchSummariesRequest struct {
+	Entities        []SummaryRef `json:"entities" binding:"required,min=1,max=500,dive"`
+	GenerateMissing bool         `json:"generate_missing,omitempty"`
+}
+
+// BatchSummariesResponse holds the stored summaries found, in request
+// order, and the entities without one
+type BatchSummariesResponse struct {
+	RepoName   string                 `json:"repo_name"`
+	Summaries  []*summary.CodeSummary `json:"summaries"`
+	Missing    []SummaryRef           `json:"missing"`
+	Generating int                    `json:"generating,omitempty"` // missing entities queued for generation
+}
+
+// GetSummariesBatch returns the stored summaries of a list of entities and
+// the entities that have none, optionally generating those in the background
+func (c *SummaryController) GetSummariesBatch(ctx *gin.Context) {
+	var req BatchSummariesRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	repoName := ctx.Param("repo")
+	levels := make([]summary.SummaryLevel, len(req.Entities))
+	for i, ref := range req.Entities {
+		level, err := ref.level()
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("entities[%d]: %v", i, err)})
+			return
+		}
+		levels[i] = level
+	}
+
+	store, err := c.getStore(ctx.Request.Context(), repoName)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to access summary store: " + err.Error()})
+		return
+	}
+	found, missing, err := lookupSummaries(store, req.Entities, levels)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to query summaries: " + err.Error()})
+		return
+	}
+
+	resp := BatchSummariesResponse{RepoName: repoName, Summaries: found, Missing: missing}
+	if req.GenerateMissing && len(missing) > 0 {
+		resp.Generating = c.generateMissing(ctx.Request.Context(), repoName, missing)
+	}
+	ctx.JSON(http.StatusOK, resp)
+}
+
+// level validates a reference and returns its summary level
+func (r SummaryRef) level() (summary.SummaryLevel, error) {
+	level := summary.ParseSummaryLevel(r.EntityType)
+	if level == 0 {
+		return 0, fmt.Errorf("invalid entity_type %q: must be 'function', 'class', 'file', 'folder', or 'project'", r.EntityType)
+	}
+	if r.EntityID != "" {
+		return level, nil
+	}
+	switch level {
+	case summary.LevelFunction, summary.LevelClass:
+		if r.FilePath == "" || r.EntityName == "" {
+			return 0, fmt.Errorf("entity_id, or file_path and entity_name, is required")
+		}
+	case summary.LevelFile:
+		if r.FilePath == "" {
+			return 0, fmt.Errorf("entity_id or file_path is required")
+		}
+	default:
+		return 0, fmt.Errorf("entity_id is required for %s summaries", level)
+	}
+	return level, nil
+}
+
+// lookupSummaries finds the stored summary of each reference. References by
+// entity ID are read with one query per level.
+func lookupSummaries(store *db.SummaryStore, refs []SummaryRef, levels []summary.SummaryLevel) ([]*summary.CodeSummary, []SummaryRef, error) {
+	idsByLevel := make(map[summary.SummaryLevel][]string)
+	for i, ref := range refs {
+		if ref.EntityID != "" {
+			idsByLevel[levels[i]] = append(idsByLevel[levels[i]], ref.EntityID)
+		}
+	}
+	byID := make(map[string]*summary.CodeSummary)
+	for level, ids := range idsByLevel {
+		summaries, err := store.GetSummariesByEntityIDs(level, ids)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, cs := range summaries {
+			byID[level.String()+"/"+cs.EntityID] = cs
+		}
+	}
+
+	found := make([]*summary.CodeSummary, 0, len(refs))
+	missing := make([]SummaryRef, 0)
+	for i, ref := range refs {
+		var cs *summary.CodeSummary
+		var err error
+		switch {
+		case ref.EntityID != "":
+			cs = byID[levels[i].String()+"/"+ref.EntityID]
+		case levels[i] == summary.LevelFile:
+			cs, err = store.GetFileSummary(ref.FilePath)
+		default:
+			cs, err = store.GetSummaryByFileAndName(ref.FilePath, levels[i], ref.EntityName)
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		if cs == nil {
+			missing = append(missing, ref)
+		} else {
+			found = append(found, cs)
+		}
+	}
+	return found, missing, nil
+}
+
+// generateMissing starts generating the summaries of missing function, class
+// and file entities named by file path, one at a time so a large batch does
+// not flood the LLM. It returns how many were queued. Generation outlives
+// the request.
+func (c *SummaryController) generateMissing(ctx context.Context, repoName string, missing []SummaryRef) int {
+	if c.summaryProcessor == nil || c.config == nil {
+		c.logger.Info("Background summary generation skipped: summary processor not available")
+		return 0
+	}
+
+	var queued []SummaryRef
+	for _, ref := range missing {
+		level := summary.ParseSummaryLevel(ref.EntityType)
+		if ref.FilePath != "" && (level == summary.LevelFile || ref.EntityName != "") {
+			queued = append(queued, ref)
+		}
+	}
+	if len(queued) == 0 {
+		return 0
+	}
+
+	ctx = context.WithoutCancel(ctx)
+	go func() {
+		generated := 0
+		for _, ref := range queued {
+			level := summary.ParseSummaryLevel(ref.EntityType)
+			var err error
+			if level == summary.LevelFile {
+				_, err = c.generateFileSummaryOnDemand(ctx, repoName, ref.FilePath)
+			} else {
+				_, err = c.generateEntitySummaryOnDemand(ctx, repoName, ref.FilePath, level, ref.EntityName)
+			}
+			if err != nil {
+				c.logger.Warn("Background summary generation failed",
+					zap.String("repo_name", repoName),
+					zap.String("file", ref.FilePath),
+					zap.String("entity", ref.EntityName),
+					zap.Error(err))
+				continue
+			}
+			generated++
+		}
+		c.logger.Info("Background summary generation completed",
+			zap.String("repo_name", repoName),
+			zap.Int("queued", len(queued)),
+			zap.Int("generated", generated))
+	}()
+	return len(queued)
+}
@@ -0,0 +1,72 @@
+package controller
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/armchr/codeapi/internal/db"
+	"github.com/armchr/codeapi/internal/service/summary"
+
+	"go.uber.org/zap"
+)
+
+func TestSummaryRefLevel(t *testing.T) {
+	tests := []struct {
+		ref  SummaryRef
+		want summary.SummaryLevel
+	}{
+		{SummaryRef{EntityType: "function", EntityID: "12"}, summary.LevelFunction},
+		{SummaryRef{EntityType: "class", FilePath: "a.go", EntityName: "Server"}, summary.LevelClass},
+		{SummaryRef{EntityType: "file", FilePath: "a.go"}, summary.LevelFile},
+		{SummaryRef{EntityType: "folder", EntityID: "internal"}, summary.LevelFolder},
+		{SummaryRef{EntityType: "function", FilePath: "a.go"}, 0},
+		{SummaryRef{EntityType: "folder", FilePath: "internal"}, 0},
+		{SummaryRef{EntityType: "module", EntityID: "12"}, 0},
+	}
+	for _, tt := range tests {
+		got, err := tt.ref.level()
+		if got != tt.want || (err != nil) != (tt.want == 0) {
+			t.Errorf("%+v: level() = %v, %v, want %v", tt.ref, got, err, tt.want)
+		}
+	}
+}
+
+func TestLookupSummaries(t *testing.T) {
+	conn := newBackupTestDB(t)
+	store, err := db.NewSummaryStore(conn.GetDB(), "api", zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.SaveSummaries([]*summary.CodeSummary{
+		{EntityID: "11", EntityType: summary.LevelFunction, EntityName: "Serve", FilePath: "server.go", Summary: "serves"},
+		{EntityID: "12", EntityType: summary.LevelClass, EntityName: "Server", FilePath: "server.go", Summary: "a server"},
+		{EntityID: "server.go", EntityType: summary.LevelFile, EntityName: "server.go", FilePath: "server.go", Summary: "the front end"},
+	})
+
+	refs := []SummaryRef{
+		{EntityType: "class", EntityID: "12"},
+		{EntityType: "function", EntityID: "12"}, // a class ID asked for as a function
+		{EntityType: "file", FilePath: "server.go"},
+		{EntityType: "function", FilePath: "server.go", EntityName: "Serve"},
+		{EntityType: "function", FilePath: "server.go", EntityName: "Close"},
+	}
+	levels := make([]summary.SummaryLevel, len(refs))
+	for i, ref := range refs {
+		levels[i], _ = ref.level()
+	}
+
+	found, missing, err := lookupSummaries(store, refs, levels)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, cs := range found {
+		got = append(got, cs.Summary)
+	}
+	if want := []string{"a server", "the front end", "serves"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("found %q, want %q", got, want)
+	}
+	if want := []SummaryRef{refs[1], refs[4]}; !reflect.DeepEqual(missing, want) {
+		t.Errorf("missing %+v, want %+v", missing, want)
+	}
+}
@@ -4,9 +4,16 @@ import (
 	"context"
 	"database/sql"
 	"net/http"
+	"strconv"
 
+	"github.com/armchr/codeapi/internal/codeapi"
 	"github.com/armchr/codeapi/internal/config"
 	"github.com/armchr/codeapi/internal/db"
+	"github.com/armchr/codeapi/internal/entity"
+	"github.com/armchr/codeapi/internal/logging"
+	"github.com/armchr/codeapi/internal/model/ast"
+	"github.com/armchr/codeapi/internal/service/audit"
+	"github.com/armchr/codeapi/internal/service/codegraph"
 	"github.com/armchr/codeapi/internal/service/summary"
 
 	"github.com/gin-gonic/gin"
@@ -17,7 +24,8 @@ import (
 type SummaryController struct {
 	mysqlDB          *sql.DB
 	config           *config.Config
-	summaryProcessor *SummaryProcessor // For on-demand generation
+	summaryProcessor *SummaryProcessor    // For on-demand generation
+	codeGraph        *codegraph.CodeGraph // Validates imported summaries, if set
 	logger           *zap.Logger
 }
 
@@ -36,6 +44,12 @@ func NewSummaryController(
 	}
 }
 
+// SetCodeGraph lets summary imports match function and class summaries to
+// the entities of the code graph
+func (c *SummaryController) SetCodeGraph(codeGraph *codegraph.CodeGraph) {
+	c.codeGraph = codeGraph
+}
+
 // -----------------------------------------------------------------------------
 // Request/Response Types
 // -----------------------------------------------------------------------------
@@ -54,18 +68,22 @@ type GetFileSummariesResponse struct {
 	Count     int                     `json:"count"`
 }
 
-// GetEntitySummaryRequest is the request for getting a specific entity summary
+// GetEntitySummaryRequest is the request for getting a specific entity summary.
+// The entity is named by file_path, entity_type and entity_name, or by
+// entity_ref alone.
 type GetEntitySummaryRequest struct {
 	RepoName   string `json:"repo_name" binding:"required"`
-	FilePath   string `json:"file_path" binding:"required"`
-	EntityType string `json:"entity_type" binding:"required"` // "function" or "class"
-	EntityName string `json:"entity_name" binding:"required"`
+	FilePath   string `json:"file_path" binding:"required_without=EntityRef"`
+	EntityType string `json:"entity_type" binding:"required_without=EntityRef"` // "function" or "class"
+	EntityName string `json:"entity_name" binding:"required_without=EntityRef"`
+	EntityRef  string `json:"entity_ref"`
 }
 
 // GetFileSummaryRequest is the request for getting a file-level summary
 type GetFileSummaryRequest struct {
-	RepoName string `json:"repo_name" binding:"required"`
-	FilePath string `json:"file_path" binding:"required"`
+	RepoName  string `json:"repo_name" binding:"required"`
+	FilePath  string `json:"file_path" binding:"required_without=EntityRef"`
+	EntityRef string `json:"entity_ref"`
 }
 
 // GetSummaryStatsRequest is the request for getting summary statistics
@@ -83,21 +101,27 @@ type GetSummaryStatsResponse struct {
 // Handlers
 // -----------------------------------------------------------------------------
 
-// getStore returns a SummaryStore for the given repository
-func (c *SummaryController) getStore(repoName string) (*db.SummaryStore, error) {
-	return db.NewSummaryStore(c.mysqlDB, repoName, c.logger)
+// getStore returns a SummaryStore for the given repository whose queries are
+// bound to the request context
+func (c *SummaryController) getStore(ctx context.Context, repoName string) (*db.SummaryStore, error) {
+	store, err := db.NewSummaryStore(c.mysqlDB, repoName, c.logger)
+	if err != nil {
+		return nil, err
+	}
+	return store.WithContext(ctx), nil
 }
 
 // GetFileSummaries returns all summaries for a file, optionally filtered by entity type.
 // If no summaries exist and on-demand generation is available, summaries will be generated.
 func (c *SummaryController) GetFileSummaries(ctx *gin.Context) {
+	logger := logging.FromContext(ctx.Request.Context(), c.logger)
 	var req GetFileSummariesRequest
 	if err := ctx.ShouldBindJSON(&req); err != nil {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	store, err := c.getStore(req.RepoName)
+	store, err := c.getStore(ctx.Request.Context(), req.RepoName)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to access summary store: " + err.Error()})
 		return
@@ -127,21 +151,21 @@ func (c *SummaryController) GetFileSummaries(ctx *gin.Context) {
 	// If no summaries found and on-demand generation is available, generate them
 	if len(summaries) == 0 {
 		if c.summaryProcessor == nil {
-			c.logger.Info("On-demand generation skipped: summary processor not available")
+			logger.Info("On-demand generation skipped: summary processor not available")
 		} else if c.config == nil {
-			c.logger.Info("On-demand generation skipped: config not available")
+			logger.Info("On-demand generation skipped: config not available")
 		} else {
-			c.logger.Info("Attempting on-demand summary generation",
+			logger.Info("Attempting on-demand summary generation",
 				zap.String("file", req.FilePath),
 				zap.String("entityType", req.EntityType))
 			generated, genErr := c.generateFileSummariesOnDemand(ctx.Request.Context(), req.RepoName, req.FilePath, entityType)
 			if genErr != nil {
-				c.logger.Warn("On-demand file summaries generation failed",
+				logger.Warn("On-demand file summaries generation failed",
 					zap.String("file", req.FilePath),
 					zap.Error(genErr))
 				// Fall through to return empty result
 			} else if len(generated) > 0 {
-				c.logger.Info("On-demand generation completed",
+				logger.Info("On-demand generation completed",
 					zap.String("file", req.FilePath),
 					zap.Int("count", len(generated)))
 				summaries = generated
@@ -149,6 +173,7 @@ func (c *SummaryController) GetFileSummaries(ctx *gin.Context) {
 		}
 	}
 
+	c.setEntityRefs(ctx.Request.Context(), req.RepoName, summaries...)
 	ctx.JSON(http.StatusOK, GetFileSummariesResponse{
 		FilePath:  req.FilePath,
 		Summaries: summaries,
@@ -159,11 +184,24 @@ func (c *SummaryController) GetFileSummaries(ctx *gin.Context) {
 // GetEntitySummary returns a specific function or class summary
 // If the summary doesn't exist and on-demand generation is available, it will be generated
 func (c *SummaryController) GetEntitySummary(ctx *gin.Context) {
+	logger := logging.FromContext(ctx.Request.Context(), c.logger)
 	var req GetEntitySummaryRequest
 	if err := ctx.ShouldBindJSON(&req); err != nil {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if req.EntityRef != "" {
+		ref, err := parseEntityRef(req.RepoName, req.EntityRef, entity.KindFunction, entity.KindClass)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		// Summaries of methods are stored under the method name
+		req.FilePath, req.EntityType, req.EntityName = ref.Path, string(ref.Kind), ref.FunctionName()
+		if ref.Kind == entity.KindClass {
+			req.EntityName = ref.Name
+		}
+	}
 
 	entityType := summary.ParseSummaryLevel(req.EntityType)
 	if entityType == 0 || (entityType != summary.LevelFunction && entityType != summary.LevelClass) {
@@ -171,7 +209,7 @@ func (c *SummaryController) GetEntitySummary(ctx *gin.Context) {
 		return
 	}
 
-	store, err := c.getStore(req.RepoName)
+	store, err := c.getStore(ctx.Request.Context(), req.RepoName)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to access summary store: " + err.Error()})
 		return
@@ -187,7 +225,7 @@ func (c *SummaryController) GetEntitySummary(ctx *gin.Context) {
 	if result == nil && c.summaryProcessor != nil && c.config != nil {
 		result, err = c.generateEntitySummaryOnDemand(ctx.Request.Context(), req.RepoName, req.FilePath, entityType, req.EntityName)
 		if err != nil {
-			c.logger.Debug("On-demand summary generation failed",
+			logger.Debug("On-demand summary generation failed",
 				zap.String("entity", req.EntityName),
 				zap.Error(err))
 			// Fall through to return not found
@@ -199,19 +237,29 @@ func (c *SummaryController) GetEntitySummary(ctx *gin.Context) {
 		return
 	}
 
+	c.setEntityRefs(ctx.Request.Context(), req.RepoName, result)
 	ctx.JSON(http.StatusOK, result)
 }
 
 // GetFileSummary returns the file-level summary for a file
 // If the summary doesn't exist and on-demand generation is available, it will be generated
 func (c *SummaryController) GetFileSummary(ctx *gin.Context) {
+	logger := logging.FromContext(ctx.Request.Context(), c.logger)
 	var req GetFileSummaryRequest
 	if err := ctx.ShouldBindJSON(&req); err != nil {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if req.EntityRef != "" {
+		ref, err := parseEntityRef(req.RepoName, req.EntityRef, entity.KindFile)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		req.FilePath = ref.Path
+	}
 
-	store, err := c.getStore(req.RepoName)
+	store, err := c.getStore(ctx.Request.Context(), req.RepoName)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to access summary store: " + err.Error()})
 		return
@@ -227,7 +275,7 @@ func (c *SummaryController) GetFileSummary(ctx *gin.Context) {
 	if result == nil && c.summaryProcessor != nil && c.config != nil {
 		result, err = c.generateFileSummaryOnDemand(ctx.Request.Context(), req.RepoName, req.FilePath)
 		if err != nil {
-			c.logger.Debug("On-demand file summary generation failed",
+			logger.Debug("On-demand file summary generation failed",
 				zap.String("file", req.FilePath),
 				zap.Error(err))
 			// Fall through to return not found
@@ -239,6 +287,7 @@ func (c *SummaryController) GetFileSummary(ctx *gin.Context) {
 		return
 	}
 
+	c.setEntityRefs(ctx.Request.Context(), req.RepoName, result)
 	ctx.JSON(http.StatusOK, result)
 }
 
@@ -250,7 +299,7 @@ func (c *SummaryController) GetSummaryStats(ctx *gin.Context) {
 		return
 	}
 
-	store, err := c.getStore(req.RepoName)
+	store, err := c.getStore(ctx.Request.Context(), req.RepoName)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to access summary store: " + err.Error()})
 		return
@@ -268,6 +317,41 @@ func (c *SummaryController) GetSummaryStats(ctx *gin.Context) {
 	})
 }
 
+// setEntityRefs sets the entity reference of file, class and function
+// summaries. Functions take their class from the code graph and go without
+// when it is not available.
+func (c *SummaryController) setEntityRefs(ctx context.Context, repoName string, summaries ...*summary.CodeSummary) {
+	var ids []ast.NodeID
+	for _, s := range summaries {
+		switch s.EntityType {
+		case summary.LevelFile:
+			s.EntityRef = entity.File(repoName, s.FilePath).String()
+		case summary.LevelClass:
+			s.EntityRef = entity.Class(repoName, s.FilePath, s.EntityName).String()
+		case summary.LevelFunction:
+			if id, err := strconv.ParseInt(s.EntityID, 10, 64); err == nil {
+				ids = append(ids, ast.NodeID(id))
+			}
+		}
+	}
+	if len(ids) == 0 || c.codeGraph == nil {
+		return
+	}
+
+	refs, err := codeapi.NewCodeAPI(c.codeGraph, c.logger).Reader().Repo(repoName).EntityRefs(ctx, ids)
+	if err != nil {
+		c.logger.Warn("Failed to read entity references of summaries", zap.String("repo_name", repoName), zap.Error(err))
+		return
+	}
+	for _, s := range summaries {
+		if id, err := strconv.ParseInt(s.EntityID, 10, 64); err == nil && s.EntityType == summary.LevelFunction {
+			if ref, ok := refs[ast.NodeID(id)]; ok {
+				s.EntityRef = ref.String()
+			}
+		}
+	}
+}
+
 // -----------------------------------------------------------------------------
 // On-Demand Generation Helpers
 // -----------------------------------------------------------------------------
@@ -285,14 +369,19 @@ func (c *SummaryController) generateEntitySummaryOnDemand(
 		return nil, err
 	}
 
+	entry := requestAudit(ctx, audit.OpSummaryGenerate, repo.Name, filePath)
+	entry.Details = map[string]any{"entity_type": entityType.String(), "entity_name": entityName}
+	var result *summary.CodeSummary
 	switch entityType {
 	case summary.LevelFunction:
-		return c.summaryProcessor.GenerateFunctionSummaryOnDemand(ctx, repo, filePath, entityName)
+		result, err = c.summaryProcessor.GenerateFunctionSummaryOnDemand(ctx, repo, filePath, entityName)
 	case summary.LevelClass:
-		return c.summaryProcessor.GenerateClassSummaryOnDemand(ctx, repo, filePath, entityName)
+		result, err = c.summaryProcessor.GenerateClassSummaryOnDemand(ctx, repo, filePath, entityName)
 	default:
 		return nil, nil
 	}
+	RecordAudit(ctx, c.mysqlDB, entry, err, c.logger)
+	return result, err
 }
 
 // generateFileSummaryOnDemand generates a file summary on-demand
@@ -306,7 +395,11 @@ func (c *SummaryController) generateFileSummaryOnDemand(
 		return nil, err
 	}
 
-	return c.summaryProcessor.GenerateFileSummaryOnDemand(ctx, repo, filePath)
+	entry := requestAudit(ctx, audit.OpSummaryGenerate, repo.Name, filePath)
+	entry.Details = map[string]any{"entity_type": summary.LevelFile.String()}
+	result, err := c.summaryProcessor.GenerateFileSummaryOnDemand(ctx, repo, filePath)
+	RecordAudit(ctx, c.mysqlDB, entry, err, c.logger)
+	return result, err
 }
 
 // generateFileSummariesOnDemand generates summaries for all entities in a file on-demand
@@ -322,5 +415,12 @@ func (c *SummaryController) generateFileSummariesOnDemand(
 		return nil, err
 	}
 
-	return c.summaryProcessor.GenerateFileSummariesOnDemand(ctx, repo, filePath, entityType)
+	entry := requestAudit(ctx, audit.OpSummaryGenerate, repo.Name, filePath)
+	summaries, err := c.summaryProcessor.GenerateFileSummariesOnDemand(ctx, repo, filePath, entityType)
+	entry.Details = map[string]any{"generated": len(summaries)}
+	if entityType != 0 {
+		entry.Details["entity_type"] = entityType.String()
+	}
+	RecordAudit(ctx, c.mysqlDB, entry, err, c.logger)
+	return summaries, err
 }
@@ -11,13 +11,18 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 
 	"github.com/armchr/codeapi/internal/config"
 	"github.com/armchr/codeapi/internal/db"
+	"github.com/armchr/codeapi/internal/metrics"
+	"github.com/armchr/codeapi/internal/model"
 	"github.com/armchr/codeapi/internal/model/ast"
+	"github.com/armchr/codeapi/internal/parse"
 	"github.com/armchr/codeapi/internal/service/codegraph"
 	"github.com/armchr/codeapi/internal/service/llm"
 	"github.com/armchr/codeapi/internal/service/summary"
+	"github.com/armchr/codeapi/internal/service/vector"
 	"github.com/armchr/codeapi/pkg/lsp/base"
 
 	"go.uber.org/zap"
@@ -28,14 +33,19 @@ type SummaryProcessor struct {
 	llmService    llm.LLMService
 	promptManager *summary.PromptManager
 	codeGraph     *codegraph.CodeGraph
-	mysqlDB       *sql.DB // For creating per-repo summary stores
+	mysqlDB       *sql.DB                  // For creating per-repo summary stores
+	chunkService  *vector.CodeChunkService // Embeds folder and project summaries, if set
 	config        *SummaryProcessorConfig
+	selection     SummarySelection // Levels and paths to (re)generate; everything by default
 	logger        *zap.Logger
 
 	// Per-repo summary stores (created in Init)
 	storesMu     sync.RWMutex
 	stores       map[string]*db.SummaryStore
 	currentStore *db.SummaryStore // Store for the current repository being processed
+
+	generated  atomic.Int64
+	tokensUsed atomic.Int64
 }
 
 // SummaryProcessorConfig holds configuration for the summary processor
@@ -79,6 +89,24 @@ func NewSummaryProcessor(
 	}
 }
 
+// SetChunkService makes PostProcess embed the folder and project summaries
+// into the repository's collection, for coarse-to-fine search
+func (p *SummaryProcessor) SetChunkService(chunkService *vector.CodeChunkService) {
+	p.chunkService = chunkService
+}
+
+// SetSelection limits the summaries generated to some levels and paths, and
+// with Force regenerates them even when unchanged
+func (p *SummaryProcessor) SetSelection(selection SummarySelection) {
+	p.selection = selection
+}
+
+// skipUnchanged reports whether summaries whose context is unchanged keep
+// their stored text
+func (p *SummaryProcessor) skipUnchanged() bool {
+	return p.config.SkipIfExists && !p.selection.Force
+}
+
 // Name returns the processor name
 func (p *SummaryProcessor) Name() string {
 	return "SummaryProcessor"
@@ -119,7 +147,7 @@ func (p *SummaryProcessor) getOrCreateStore(repoName string) (*db.SummaryStore,
 	}
 
 	if p.mysqlDB == nil {
-		return nil, fmt.Errorf("MySQL database connection required for summary storage")
+		return nil, fmt.Errorf("database connection required for summary storage")
 	}
 
 	var err error
@@ -150,41 +178,61 @@ func (p *SummaryProcessor) ProcessFile(ctx context.Context, repo *config.Reposit
 			zap.String("file", fileCtx.RelativePath))
 		return nil
 	}
+	if !p.selection.IncludesPath(fileCtx.RelativePath) {
+		return nil
+	}
 
 	p.logger.Debug("Processing file for summaries",
 		zap.String("file", fileCtx.RelativePath),
 		zap.Int32("fileID", fileCtx.FileID))
 
 	// Step 1: Summarize all functions in this file
-	functions, err := p.codeGraph.GetNodesByTypeAndFileID(ctx, ast.NodeTypeFunction, fileCtx.FileID)
-	if err != nil {
-		p.logger.Error("Failed to get functions for file", zap.Error(err))
-		// Continue - we can still try to process other entities
-	} else {
-		for _, fn := range functions {
-			if err := p.summarizeFunction(ctx, fn, repo, p.currentStore); err != nil {
-				p.logger.Error("Failed to summarize function",
-					zap.String("function", fn.Name),
-					zap.Error(err))
-				// Continue with other functions
+	if p.selection.IncludesLevel(summary.LevelFunction) {
+		functions, err := p.codeGraph.GetNodesByTypeAndFileID(ctx, ast.NodeTypeFunction, fileCtx.FileID)
+		if err != nil {
+			p.logger.Error("Failed to get functions for file", zap.Error(err))
+			// Continue - we can still try to process other entities
+		} else {
+			related := p.newRelatedFunctions(fileCtx.FileID, p.currentStore)
+			for _, fn := range functions {
+				if err := ctx.Err(); err != nil {
+					return err
+				}
+				if _, err := p.generateFunctionSummary(ctx, fn, repo, p.currentStore, related); err != nil {
+					p.logger.Error("Failed to summarize function",
+						zap.String("function", fn.Name),
+						zap.Error(err))
+					// Continue with other functions
+				}
 			}
 		}
 	}
 
 	// Step 2: Summarize all classes in this file (using function summaries)
-	classes, err := p.codeGraph.GetNodesByTypeAndFileID(ctx, ast.NodeTypeClass, fileCtx.FileID)
-	if err != nil {
-		p.logger.Error("Failed to get classes for file", zap.Error(err))
-	} else {
-		for _, cls := range classes {
-			if err := p.summarizeClass(ctx, cls, repo, p.currentStore); err != nil {
-				p.logger.Error("Failed to summarize class",
-					zap.String("class", cls.Name),
-					zap.Error(err))
+	if p.selection.IncludesLevel(summary.LevelClass) {
+		classes, err := p.codeGraph.GetNodesByTypeAndFileID(ctx, ast.NodeTypeClass, fileCtx.FileID)
+		if err != nil {
+			p.logger.Error("Failed to get classes for file", zap.Error(err))
+		} else {
+			for _, cls := range classes {
+				if err := ctx.Err(); err != nil {
+					return err
+				}
+				if err := p.summarizeClass(ctx, cls, repo, p.currentStore); err != nil {
+					p.logger.Error("Failed to summarize class",
+						zap.String("class", cls.Name),
+						zap.Error(err))
+				}
 			}
 		}
 	}
 
+	// File summaries are keyed by path, so a sandbox version would overwrite the
+	// summary of the indexed version; leave those to repository builds
+	if fileCtx.Sandbox || !p.selection.IncludesLevel(summary.LevelFile) {
+		return nil
+	}
+
 	// Step 3: Summarize the file itself (using function and class summaries)
 	if err := p.summarizeFile(ctx, fileCtx, repo, p.currentStore); err != nil {
 		p.logger.Error("Failed to summarize file",
@@ -196,6 +244,38 @@ func (p *SummaryProcessor) ProcessFile(ctx context.Context, repo *config.Reposit
 	return nil
 }
 
+// PurgeFiles deletes function and class summaries generated for the given file versions.
+// Must run before the graph nodes are deleted, since entity IDs are resolved through them.
+// Runs even when index-time summarization is disabled, as on-demand summaries may exist.
+func (p *SummaryProcessor) PurgeFiles(ctx context.Context, repo *config.Repository, fileIDs []int32) error {
+	store, err := p.getOrCreateStore(repo.Name)
+	if err != nil {
+		return err
+	}
+
+	for _, level := range []struct {
+		nodeType ast.NodeType
+		summary  summary.SummaryLevel
+	}{
+		{ast.NodeTypeFunction, summary.LevelFunction},
+		{ast.NodeTypeClass, summary.LevelClass},
+	} {
+		// Summaries of nodes another version of a file shares stay with them
+		nodes, err := p.codeGraph.GetUnsharedNodesOfFiles(ctx, repo.Name, level.nodeType, fileIDs)
+		if err != nil {
+			return fmt.Errorf("failed to get nodes of the purged files: %w", err)
+		}
+		entityIDs := make([]string, 0, len(nodes))
+		for _, node := range nodes {
+			entityIDs = append(entityIDs, strconv.FormatInt(int64(node.ID), 10))
+		}
+		if _, err := store.DeleteByEntityIDs(level.summary, entityIDs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // PostProcess generates folder and project level summaries
 // These require all files to be processed first
 func (p *SummaryProcessor) PostProcess(ctx context.Context, repo *config.Repository) error {
@@ -211,49 +291,144 @@ func (p *SummaryProcessor) PostProcess(ctx context.Context, repo *config.Reposit
 	p.logger.Info("Starting folder and project summary generation", zap.String("repo", repo.Name))
 
 	// Level 4: Folders (bottom-up)
-	if err := p.summarizeFolders(ctx, repo, p.currentStore); err != nil {
-		p.logger.Error("Failed to summarize folders", zap.Error(err))
-		return err
+	if p.selection.IncludesLevel(summary.LevelFolder) {
+		if err := p.summarizeFolders(ctx, repo, p.currentStore); err != nil {
+			p.logger.Error("Failed to summarize folders", zap.Error(err))
+			return err
+		}
 	}
 
 	// Level 5: Project
-	if err := p.summarizeProject(ctx, repo, p.currentStore); err != nil {
-		p.logger.Error("Failed to summarize project", zap.Error(err))
-		return err
+	if p.selection.IncludesLevel(summary.LevelProject) {
+		if err := p.summarizeProject(ctx, repo, p.currentStore); err != nil {
+			p.logger.Error("Failed to summarize project", zap.Error(err))
+			return err
+		}
+	}
+
+	// Search still works without them, only not coarse-to-fine
+	if err := p.embedSummaries(ctx, repo, p.currentStore); err != nil {
+		p.logger.Warn("Failed to embed folder and project summaries",
+			zap.String("repo", repo.Name), zap.Error(err))
 	}
 
 	p.logger.Info("Completed folder and project summary generation", zap.String("repo", repo.Name))
 	return nil
 }
 
-// summarizeFunction generates a summary for a single function
+// embedSummaries embeds the stored folder and project summaries of a
+// repository into its collection. Nothing is embedded when the embedding
+// processor has not created the collection.
+func (p *SummaryProcessor) embedSummaries(ctx context.Context, repo *config.Repository, store *db.SummaryStore) error {
+	if p.chunkService == nil {
+		return nil
+	}
+	exists, err := p.chunkService.GetVectorDB().CollectionExists(ctx, repo.Name)
+	if err != nil || !exists {
+		return err
+	}
+
+	for _, level := range []struct {
+		summary summary.SummaryLevel
+		chunk   model.ChunkType
+	}{
+		{summary.LevelFolder, model.ChunkTypeFolderSummary},
+		{summary.LevelProject, model.ChunkTypeProjectSummary},
+	} {
+		stored, err := store.GetSummariesByType(level.summary)
+		if err != nil {
+			return fmt.Errorf("failed to get %s summaries: %w", level.summary, err)
+		}
+		data := make([]vector.SummaryData, 0, len(stored))
+		for _, cs := range stored {
+			path := cs.FilePath
+			if level.summary == summary.LevelProject {
+				path = repo.Name
+			}
+			data = append(data, vector.SummaryData{Path: filepath.ToSlash(path), Summary: cs.Summary})
+		}
+		if err := p.chunkService.IndexSummaries(ctx, repo.Name, level.chunk, data); err != nil {
+			return err
+		}
+		p.logger.Info("Embedded summaries",
+			zap.String("repo", repo.Name),
+			zap.Stringer("level", level.summary),
+			zap.Int("count", len(data)))
+	}
+	return nil
+}
+
+// saveSummary stores a generated summary and counts it with its token usage
+func (p *SummaryProcessor) saveSummary(store *db.SummaryStore, cs *summary.CodeSummary) error {
+	if err := store.SaveSummary(cs); err != nil {
+		return err
+	}
+	p.generated.Add(1)
+	p.tokensUsed.Add(int64(cs.PromptTokens + cs.OutputTokens))
+	metrics.AddLLMTokens(store.RepoName(), cs.LLMProvider, cs.LLMModel, cs.PromptTokens, cs.OutputTokens)
+	return nil
+}
+
+// Stats reports the summaries generated and LLM tokens used so far
+func (p *SummaryProcessor) Stats() map[string]int64 {
+	return map[string]int64{
+		StatSummariesGenerated: p.generated.Load(),
+		StatTokensUsed:         p.tokensUsed.Load(),
+	}
+}
+
+// summarizeFunction generates a summary for a single function, with the
+// related functions of its file
 func (p *SummaryProcessor) summarizeFunction(
 	ctx context.Context,
 	node *ast.Node,
 	repo *config.Repository,
 	store *db.SummaryStore,
+	related *relatedFunctions,
 ) error {
+	_, err := p.generateFunctionSummary(ctx, node, repo, store, related)
+	return err
+}
+
+// generateFunctionSummary generates and stores the summary of a function,
+// returning it with the related context it was given, or nil if the stored
+// summary is up to date. related is shared by the functions of a file.
+func (p *SummaryProcessor) generateFunctionSummary(
+	ctx context.Context,
+	node *ast.Node,
+	repo *config.Repository,
+	store *db.SummaryStore,
+	related *relatedFunctions,
+) (*summary.CodeSummary, error) {
 	entityID := strconv.FormatInt(int64(node.ID), 10)
 	contextBuilder := summary.NewContextBuilder(4000)
 	fnCtx := p.buildFunctionContext(ctx, node, repo)
 	contextHash := contextBuilder.HashContext(fnCtx)
 
+	if err := p.carrySummary(ctx, store, node, contextHash); err != nil {
+		p.logger.Warn("Failed to carry summary over from the previous version",
+			zap.String("name", node.Name), zap.Error(err))
+	}
+
 	// Check if update needed
-	if p.config.SkipIfExists {
+	if p.skipUnchanged() {
 		needsUpdate, err := store.NeedsUpdate(entityID, summary.LevelFunction, contextHash)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		if !needsUpdate {
 			p.logger.Debug("Skipping function - unchanged", zap.String("name", node.Name))
-			return nil
+			return nil, nil
 		}
 	}
 
+	// Looked up only now, as unchanged functions do not need it
+	fnCtx.RelatedContext = related.context(ctx, node)
+
 	// Generate summary
 	systemPrompt, userPrompt, err := p.promptManager.RenderPrompt(summary.LevelFunction, fnCtx)
 	if err != nil {
-		return fmt.Errorf("failed to render prompt: %w", err)
+		return nil, fmt.Errorf("failed to render prompt: %w", err)
 	}
 
 	tmpl, _ := p.promptManager.GetTemplate(summary.LevelFunction)
@@ -264,7 +439,7 @@ func (p *SummaryProcessor) summarizeFunction(
 
 	resp, err := p.llmService.GenerateWithSystem(ctx, systemPrompt, userPrompt, opts)
 	if err != nil {
-		return fmt.Errorf("failed to generate summary: %w", err)
+		return nil, fmt.Errorf("failed to generate summary: %w", err)
 	}
 
 	// Get file path
@@ -283,8 +458,170 @@ func (p *SummaryProcessor) summarizeFunction(
 		PromptTokens: resp.PromptTokens,
 		OutputTokens: resp.OutputTokens,
 	}
+	if err := p.saveSummary(store, cs); err != nil {
+		return nil, err
+	}
+	related.summarized(cs)
+	cs.ContextPieces = fnCtx.RelatedContext
+	return cs, nil
+}
+
+// relatedContextChars is the budget for the related context of a function,
+// on top of its own code
+const relatedContextChars = 1500
+
+// maxRelatedPerRelation bounds the callees, callers or sibling methods
+// looked up for a function, as a widely used function has many callers
+const maxRelatedPerRelation = 20
+
+// relatedFunctions finds the callees, callers and sibling methods of the
+// functions of a file to give the LLM along with their code. The graph and
+// the stored summaries are read once per file, on first use, since most
+// functions of an unchanged file need neither.
+type relatedFunctions struct {
+	p         *SummaryProcessor
+	fileID    int32
+	store     *db.SummaryStore
+	loaded    bool
+	relations *codegraph.FunctionRelations
+	summaries map[string]string // function entity ID to stored summary
+}
+
+func (p *SummaryProcessor) newRelatedFunctions(fileID int32, store *db.SummaryStore) *relatedFunctions {
+	return &relatedFunctions{p: p, fileID: fileID, store: store}
+}
+
+// load reads the relations of the file's functions and the summaries of the
+// functions they relate to
+func (r *relatedFunctions) load(ctx context.Context) {
+	r.loaded = true
+	relations, err := r.p.codeGraph.GetFunctionRelationsOfFile(ctx, r.fileID)
+	if err != nil {
+		r.p.logger.Debug("Failed to find related functions",
+			zap.Int32("file_id", r.fileID), zap.Error(err))
+		return
+	}
+	r.relations = relations
+
+	seen := make(map[string]bool)
+	var ids []string
+	for _, byFunction := range []map[ast.NodeID][]*ast.Node{relations.Callees, relations.Callers, relations.SameClass} {
+		for _, related := range byFunction {
+			for _, n := range limitRelated(related) {
+				id := strconv.FormatInt(int64(n.ID), 10)
+				if !seen[id] {
+					seen[id] = true
+					ids = append(ids, id)
+				}
+			}
+		}
+	}
+	stored, err := functionSummaries(r.store, ids)
+	if err != nil {
+		r.p.logger.Debug("Failed to read summaries of related functions",
+			zap.Int32("file_id", r.fileID), zap.Error(err))
+	}
+	r.summaries = make(map[string]string, len(stored))
+	for id, cs := range stored {
+		r.summaries[id] = cs.Summary
+	}
+}
 
-	return store.SaveSummary(cs)
+// summarized records a summary generated after the file was loaded, so the
+// functions summarized after it are given the summary rather than the
+// signature
+func (r *relatedFunctions) summarized(cs *summary.CodeSummary) {
+	if r.summaries != nil {
+		r.summaries[cs.EntityID] = cs.Summary
+	}
+}
+
+// limitRelated keeps the first maxRelatedPerRelation related functions
+func limitRelated(related []*ast.Node) []*ast.Node {
+	if len(related) > maxRelatedPerRelation {
+		return related[:maxRelatedPerRelation]
+	}
+	return related
+}
+
+// context picks the summaries of the callees, callers and sibling methods
+// of a function of the file. A related function without a summary yet is
+// described by its signature.
+func (r *relatedFunctions) context(ctx context.Context, node *ast.Node) []summary.ContextPiece {
+	if !r.loaded {
+		r.load(ctx)
+	}
+	if r.relations == nil {
+		return nil
+	}
+
+	var candidates []summary.ContextPiece
+	add := func(relation summary.ContextRelation, related []*ast.Node) {
+		for _, n := range limitRelated(related) {
+			if n.ID == node.ID {
+				continue
+			}
+			entityID := strconv.FormatInt(int64(n.ID), 10)
+			text := r.summaries[entityID]
+			if text == "" {
+				text, _ = n.MetaData["signature"].(string)
+			}
+			if text == "" {
+				continue
+			}
+			candidates = append(candidates, summary.ContextPiece{
+				EntityID: entityID,
+				Name:     n.Name,
+				FilePath: r.p.codeGraph.GetFilePath(ctx, n.FileID),
+				Relation: relation,
+				Hops:     1,
+				Text:     text,
+			})
+		}
+	}
+	add(summary.RelationCallee, r.relations.Callees[node.ID])
+	add(summary.RelationCaller, r.relations.Callers[node.ID])
+	add(summary.RelationSameClass, r.relations.SameClass[node.ID])
+
+	pieces, _ := summary.NewContextBuilder(relatedContextChars).PackContext(candidates)
+	return pieces
+}
+
+// carrySummary gives a function without a summary the summary of the same
+// function in the previous version of its file, found through the
+// PREVIOUS_VERSION relation. If the body is unchanged the summary still
+// describes the function, even under a new name, and is stored with the new
+// context hash so it is not regenerated.
+func (p *SummaryProcessor) carrySummary(ctx context.Context, store *db.SummaryStore, node *ast.Node, contextHash string) error {
+	entityID := strconv.FormatInt(int64(node.ID), 10)
+	if existing, err := store.GetSummary(entityID, summary.LevelFunction); err != nil || existing != nil {
+		return err
+	}
+
+	previousID, err := p.codeGraph.GetPreviousVersion(ctx, node.ID)
+	if err != nil || previousID == ast.InvalidNodeID {
+		return err
+	}
+	previousEntityID := strconv.FormatInt(int64(previousID), 10)
+	previous, err := store.GetSummary(previousEntityID, summary.LevelFunction)
+	if err != nil || previous == nil {
+		return err
+	}
+
+	carried := *previous
+	carried.EntityID = entityID
+	carried.PreviousEntityID = previousEntityID
+	carried.EntityName = node.Name
+	carried.PromptTokens, carried.OutputTokens = 0, 0
+	if previousNode, err := p.codeGraph.ReadFunction(ctx, previousID); err == nil && previousNode != nil &&
+		previousNode.MetaData[parse.MetaBodyHash] != nil && previousNode.MetaData[parse.MetaBodyHash] == node.MetaData[parse.MetaBodyHash] {
+		carried.ContextHash = contextHash
+	}
+
+	p.logger.Debug("Carried summary over from the previous version",
+		zap.String("from", previous.EntityName),
+		zap.String("to", node.Name))
+	return store.SaveSummary(&carried)
 }
 
 // summarizeClass generates a summary for a single class using method summaries
@@ -300,7 +637,7 @@ func (p *SummaryProcessor) summarizeClass(
 	contextHash := contextBuilder.HashContext(clsCtx)
 
 	// Check if update needed
-	if p.config.SkipIfExists {
+	if p.skipUnchanged() {
 		needsUpdate, err := store.NeedsUpdate(entityID, summary.LevelClass, contextHash)
 		if err != nil {
 			return err
@@ -345,7 +682,7 @@ func (p *SummaryProcessor) summarizeClass(
 		OutputTokens: resp.OutputTokens,
 	}
 
-	return store.SaveSummary(cs)
+	return p.saveSummary(store, cs)
 }
 
 // summarizeFile generates a summary for a file using class and function summaries
@@ -362,7 +699,7 @@ func (p *SummaryProcessor) summarizeFile(
 	contextHash := contextBuilder.HashContext(fileSummaryCtx)
 
 	// Check if update needed
-	if p.config.SkipIfExists {
+	if p.skipUnchanged() {
 		needsUpdate, err := store.NeedsUpdate(entityID, summary.LevelFile, contextHash)
 		if err != nil {
 			return err
@@ -409,7 +746,7 @@ func (p *SummaryProcessor) summarizeFile(
 		zap.Int("prompt_tokens", resp.PromptTokens),
 		zap.Int("output_tokens", resp.OutputTokens))
 
-	return store.SaveSummary(cs)
+	return p.saveSummary(store, cs)
 }
 
 // summarizeFolders generates summaries for folders bottom-up
@@ -443,7 +780,9 @@ func (p *SummaryProcessor) summarizeFolders(ctx context.Context, repo *config.Re
 	// Sort folders by depth (deepest first for bottom-up processing)
 	sortedFolders := make([]string, 0, len(allFolders))
 	for folder := range allFolders {
-		sortedFolders = append(sortedFolders, folder)
+		if p.selection.IncludesPath(folder) {
+			sortedFolders = append(sortedFolders, folder)
+		}
 	}
 	sort.Slice(sortedFolders, func(i, j int) bool {
 		return strings.Count(sortedFolders[i], string(filepath.Separator)) >
@@ -456,6 +795,10 @@ func (p *SummaryProcessor) summarizeFolders(ctx context.Context, repo *config.Re
 
 	// Process folders bottom-up (deepest first)
 	for _, folder := range sortedFolders {
+		// Folders summarized before a cancellation stay stored
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 		if err := p.summarizeFolder(ctx, folder, folderFiles, repo, store); err != nil {
 			p.logger.Error("Failed to summarize folder",
 				zap.String("folder", folder),
@@ -499,12 +842,12 @@ func (p *SummaryProcessor) summarizeFolder(
 		folderPath,
 		fileSummaries,
 		subfolderSummaries,
-		[]string{repo.Language},
+		repoLanguages(repo),
 	)
 
 	// Check if update needed
 	contextHash := contextBuilder.HashContext(folderCtx)
-	if p.config.SkipIfExists {
+	if p.skipUnchanged() {
 		needsUpdate, err := store.NeedsUpdate(folderPath, summary.LevelFolder, contextHash)
 		if err != nil {
 			return err
@@ -546,7 +889,7 @@ func (p *SummaryProcessor) summarizeFolder(
 		OutputTokens: resp.OutputTokens,
 	}
 
-	return store.SaveSummary(cs)
+	return p.saveSummary(store, cs)
 }
 
 // summarizeProject generates a project-level summary
@@ -597,7 +940,7 @@ func (p *SummaryProcessor) summarizeProject(ctx context.Context, repo *config.Re
 	contextBuilder := summary.NewContextBuilder(16000)
 	projectCtx := contextBuilder.BuildProjectContext(
 		repo.Name,
-		[]string{repo.Language},
+		repoLanguages(repo),
 		topLevelSummaries,
 		entryPoints,
 		len(fileSummaries),
@@ -607,7 +950,7 @@ func (p *SummaryProcessor) summarizeProject(ctx context.Context, repo *config.Re
 
 	// Check if update needed
 	contextHash := contextBuilder.HashContext(projectCtx)
-	if p.config.SkipIfExists {
+	if p.skipUnchanged() {
 		needsUpdate, err := store.NeedsUpdate(repo.Name, summary.LevelProject, contextHash)
 		if err != nil {
 			return err
@@ -649,7 +992,7 @@ func (p *SummaryProcessor) summarizeProject(ctx context.Context, repo *config.Re
 		OutputTokens: resp.OutputTokens,
 	}
 
-	return store.SaveSummary(cs)
+	return p.saveSummary(store, cs)
 }
 
 // buildFunctionContext builds context for function summarization
@@ -694,11 +1037,30 @@ func (p *SummaryProcessor) buildFunctionContext(ctx context.Context, node *ast.N
 		SourceCode:  sourceCode,
 		Parameters:  params,
 		ReturnType:  returnType,
-		Language:    repo.Language,
+		Language:    fileLanguage(repo, filePath, nil),
 		FilePath:    filePath,
 		ClassName:   className,
 		Annotations: annotations,
 		Modifiers:   modifiers,
+		Metrics:     functionMetrics(node.MetaData),
+	}
+}
+
+// functionMetrics returns the metrics the parser stored on a function node,
+// or nil for a function without a body
+func functionMetrics(metadata map[string]any) *summary.FunctionMetrics {
+	complexity, ok := metadata[parse.MetaComplexity].(int64)
+	if !ok {
+		return nil
+	}
+	loc, _ := metadata[parse.MetaLOC].(int64)
+	params, _ := metadata[parse.MetaParams].(int64)
+	nesting, _ := metadata[parse.MetaNesting].(int64)
+	return &summary.FunctionMetrics{
+		Complexity: int(complexity),
+		LOC:        int(loc),
+		Params:     int(params),
+		Nesting:    int(nesting),
 	}
 }
 
@@ -774,7 +1136,7 @@ func (p *SummaryProcessor) buildClassContext(
 		Implements:      implements,
 		Fields:          fields,
 		MethodSummaries: methodSummaries,
-		Language:        repo.Language,
+		Language:        fileLanguage(repo, filePath, nil),
 		FilePath:        filePath,
 		Annotations:     annotations,
 		Modifiers:       modifiers,
@@ -835,7 +1197,7 @@ func (p *SummaryProcessor) buildFileContextFromFileCtx(
 	return &summary.FileContext{
 		FilePath:          fileCtx.RelativePath,
 		FileName:          filepath.Base(fileCtx.RelativePath),
-		Language:          repo.Language,
+		Language:          fileLanguage(repo, fileCtx.RelativePath, fileCtx.Content),
 		Imports:           importNames,
 		ClassSummaries:    classSummaries,
 		FunctionSummaries: functionSummaries,
@@ -936,13 +1298,19 @@ func (p *SummaryProcessor) GenerateFunctionSummaryOnDemand(
 	}
 
 	// Generate the summary
-	if err := p.summarizeFunction(ctx, node, repo, store); err != nil {
+	generated, err := p.generateFunctionSummary(ctx, node, repo, store, p.newRelatedFunctions(node.FileID, store))
+	if err != nil {
 		return nil, fmt.Errorf("failed to generate function summary: %w", err)
 	}
 
 	// Retrieve and return the generated summary
 	entityID := strconv.FormatInt(int64(node.ID), 10)
-	return store.GetSummary(entityID, summary.LevelFunction)
+	result, err := store.GetSummary(entityID, summary.LevelFunction)
+	if err != nil || result == nil || generated == nil {
+		return result, err
+	}
+	result.ContextPieces = generated.ContextPieces
+	return result, nil
 }
 
 // GenerateClassSummaryOnDemand generates a summary for a class by name
@@ -970,13 +1338,14 @@ func (p *SummaryProcessor) GenerateClassSummaryOnDemand(
 
 	// First, ensure all methods in the class have summaries (for hierarchical summarization)
 	methods, _ := p.codeGraph.GetClassMethods(ctx, node.ID)
+	related := p.newRelatedFunctions(node.FileID, store)
 	for _, method := range methods {
 		// Check if method summary exists
 		methodEntityID := strconv.FormatInt(int64(method.ID), 10)
 		existing, _ := store.GetSummary(methodEntityID, summary.LevelFunction)
 		if existing == nil {
 			// Generate method summary first
-			_ = p.summarizeFunction(ctx, method, repo, store)
+			_ = p.summarizeFunction(ctx, method, repo, store, related)
 		}
 	}
 
@@ -1019,11 +1388,12 @@ func (p *SummaryProcessor) GenerateFileSummaryOnDemand(
 
 	// First, generate summaries for all functions and classes in the file
 	functions, _ := p.codeGraph.GetNodesByTypeAndFileID(ctx, ast.NodeTypeFunction, fileNode.FileID)
+	related := p.newRelatedFunctions(fileNode.FileID, store)
 	for _, fn := range functions {
 		fnEntityID := strconv.FormatInt(int64(fn.ID), 10)
 		existing, _ := store.GetSummary(fnEntityID, summary.LevelFunction)
 		if existing == nil {
-			_ = p.summarizeFunction(ctx, fn, repo, store)
+			_ = p.summarizeFunction(ctx, fn, repo, store, related)
 		}
 	}
 
@@ -1038,7 +1408,7 @@ func (p *SummaryProcessor) GenerateFileSummaryOnDemand(
 				methodEntityID := strconv.FormatInt(int64(method.ID), 10)
 				methodExisting, _ := store.GetSummary(methodEntityID, summary.LevelFunction)
 				if methodExisting == nil {
-					_ = p.summarizeFunction(ctx, method, repo, store)
+					_ = p.summarizeFunction(ctx, method, repo, store, related)
 				}
 			}
 			_ = p.summarizeClass(ctx, cls, repo, store)
@@ -1097,6 +1467,7 @@ func (p *SummaryProcessor) GenerateFileSummariesOnDemand(
 		zap.Int64("nodeID", int64(fileNode.ID)))
 
 	var generatedSummaries []*summary.CodeSummary
+	related := p.newRelatedFunctions(fileNode.FileID, store)
 
 	// Generate function summaries if requested or if no filter
 	if entityType == 0 || entityType == summary.LevelFunction {
@@ -1105,7 +1476,7 @@ func (p *SummaryProcessor) GenerateFileSummariesOnDemand(
 			fnEntityID := strconv.FormatInt(int64(fn.ID), 10)
 			existing, _ := store.GetSummary(fnEntityID, summary.LevelFunction)
 			if existing == nil {
-				if err := p.summarizeFunction(ctx, fn, repo, store); err != nil {
+				if err := p.summarizeFunction(ctx, fn, repo, store, related); err != nil {
 					p.logger.Debug("Failed to generate function summary",
 						zap.String("function", fn.Name),
 						zap.Error(err))
@@ -1134,7 +1505,7 @@ func (p *SummaryProcessor) GenerateFileSummariesOnDemand(
 					methodEntityID := strconv.FormatInt(int64(method.ID), 10)
 					methodExisting, _ := store.GetSummary(methodEntityID, summary.LevelFunction)
 					if methodExisting == nil {
-						_ = p.summarizeFunction(ctx, method, repo, store)
+						_ = p.summarizeFunction(ctx, method, repo, store, related)
 					}
 				}
 				if err := p.summarizeClass(ctx, cls, repo, store); err != nil {
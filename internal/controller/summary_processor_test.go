@@ -0,0 +1,90 @@
+package controller
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/armchr/codeapi/internal/config"
+	"github.com/armchr/codeapi/internal/db"
+	"github.com/armchr/codeapi/internal/model/ast"
+	"github.com/armchr/codeapi/internal/service/codegraph"
+	"github.com/armchr/codeapi/internal/service/summary"
+
+	"go.uber.org/zap"
+)
+
+// relationsDB answers the function relations query of a file with fixed
+// records and counts how often it is asked
+type relationsDB struct {
+	codegraph.GraphDatabase
+	records []map[string]any
+	queries int
+}
+
+func (d *relationsDB) ExecuteRead(ctx context.Context, query string, params map[string]any) ([]map[string]any, error) {
+	if !strings.Contains(query, "AS relation") {
+		return nil, nil
+	}
+	d.queries++
+	return d.records, nil
+}
+
+func (d *relationsDB) ExecuteReadSingle(ctx context.Context, query string, params map[string]any) (map[string]any, error) {
+	return nil, nil
+}
+
+func TestRelatedFunctions(t *testing.T) {
+	function := func(id int64, name string) map[string]any {
+		return map[string]any{"id": id, "nodeType": int64(ast.NodeTypeFunction), "fileId": int64(1), "name": name, "md_signature": "func " + name + "()"}
+	}
+	relation := func(functionID int64, relation string, related map[string]any) map[string]any {
+		return map[string]any{"functionId": functionID, "relation": relation, "r": related}
+	}
+	graph := &relationsDB{records: []map[string]any{
+		relation(1, "callee", function(2, "parse")),
+		relation(1, "caller", function(3, "main")),
+		relation(2, "caller", function(1, "run")),
+		relation(2, "same_class", function(4, "close")),
+	}}
+
+	conn := newBackupTestDB(t)
+	store, err := db.NewSummaryStore(conn.GetDB(), "api", zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SaveSummary(&summary.CodeSummary{EntityID: "2", EntityType: summary.LevelFunction, Summary: "Parses the input"}); err != nil {
+		t.Fatal(err)
+	}
+
+	p := &SummaryProcessor{codeGraph: codegraph.NewCodeGraphWithDatabase(graph, &config.Config{}, zap.NewNop()), logger: zap.NewNop()}
+	related := p.newRelatedFunctions(1, store)
+	ctx := context.Background()
+
+	texts := func(pieces []summary.ContextPiece) map[string]string {
+		byName := make(map[string]string, len(pieces))
+		for _, piece := range pieces {
+			byName[piece.Name] = string(piece.Relation) + ": " + piece.Text
+		}
+		return byName
+	}
+
+	got := texts(related.context(ctx, &ast.Node{ID: 1, Name: "run", FileID: 1}))
+	want := map[string]string{"parse": "callee: Parses the input", "main": "caller: func main()"}
+	if len(got) != len(want) || got["parse"] != want["parse"] || got["main"] != want["main"] {
+		t.Errorf("context of run = %v, want %v", got, want)
+	}
+
+	// A summary generated for a function of the file is given to the
+	// functions summarized after it
+	related.summarized(&summary.CodeSummary{EntityID: "1", Summary: "Runs the tool"})
+	got = texts(related.context(ctx, &ast.Node{ID: 2, Name: "parse", FileID: 1}))
+	want = map[string]string{"run": "caller: Runs the tool", "close": "same_class: func close()"}
+	if len(got) != len(want) || got["run"] != want["run"] || got["close"] != want["close"] {
+		t.Errorf("context of parse = %v, want %v", got, want)
+	}
+
+	if graph.queries != 1 {
+		t.Errorf("queried the relations of the file %d times, want once", graph.queries)
+	}
+}
@@ -0,0 +1,136 @@
+package controller
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/armchr/codeapi/internal/service/summary"
+)
+
+// SummarySelection limits a summary build to some levels and paths, for
+// regenerating part of a repository's summaries. The zero value selects
+// everything.
+type SummarySelection struct {
+	Levels []summary.SummaryLevel // empty: every level
+
+	// Paths are relative to the repository. "dir/..." selects dir and
+	// everything under it; any other pattern is a path.Match glob or an exact
+	// path. Empty selects every path. The project summary has no path and is
+	// left alone when paths are given.
+	Paths []string
+
+	// Force regenerates the selected summaries even when their context is
+	// unchanged, overriding summary.skip_if_exists
+	Force bool
+}
+
+// ParseSummaryLevels parses level names such as "function" or "folder"
+func ParseSummaryLevels(names []string) ([]summary.SummaryLevel, error) {
+	levels := make([]summary.SummaryLevel, 0, len(names))
+	for _, name := range names {
+		level := summary.ParseSummaryLevel(strings.ToLower(strings.TrimSpace(name)))
+		if level == 0 {
+			return nil, fmt.Errorf("unknown summary level %q (want function, class, file, folder or project)", name)
+		}
+		levels = append(levels, level)
+	}
+	return levels, nil
+}
+
+// IncludesLevel reports whether summaries of level are selected
+func (s SummarySelection) IncludesLevel(level summary.SummaryLevel) bool {
+	if level == summary.LevelProject && len(s.Paths) > 0 {
+		return false
+	}
+	if len(s.Levels) == 0 {
+		return true
+	}
+	for _, l := range s.Levels {
+		if l == level {
+			return true
+		}
+	}
+	return false
+}
+
+// IncludesPath reports whether the summaries of a file or folder are selected
+func (s SummarySelection) IncludesPath(p string) bool {
+	if len(s.Paths) == 0 {
+		return true
+	}
+	p = strings.TrimPrefix(filepath.ToSlash(p), "./")
+	for _, pattern := range s.Paths {
+		pattern = strings.TrimPrefix(filepath.ToSlash(pattern), "./")
+		if dir, ok := strings.CutSuffix(pattern, "..."); ok {
+			dir = strings.TrimSuffix(dir, "/")
+			if dir == "" || p == dir || strings.HasPrefix(p, dir+"/") {
+				return true
+			}
+			continue
+		}
+		if matched, err := path.Match(pattern, p); err == nil && matched {
+			return true
+		}
+	}
+	return false
+}
+
+// Includes reports whether a stored summary is selected
+func (s SummarySelection) Includes(cs *summary.CodeSummary) bool {
+	if !s.IncludesLevel(cs.EntityType) {
+		return false
+	}
+	return cs.EntityType == summary.LevelProject || s.IncludesPath(cs.FilePath)
+}
+
+// SummaryLevelEstimate is the expected LLM cost of regenerating the stored
+// summaries of one level
+type SummaryLevelEstimate struct {
+	Level        summary.SummaryLevel
+	Summaries    int
+	PromptTokens int64
+	OutputTokens int64
+}
+
+// EstimateSummaryCost estimates the tokens a forced rebuild of the selected
+// summaries would use, from the prompt and output sizes recorded when they
+// were last generated. Carried-over summaries record no tokens and are
+// counted at the average of their level. Entities never summarized are not
+// known here and not counted. Levels are returned in build order.
+func EstimateSummaryCost(stored []*summary.CodeSummary, sel SummarySelection) []SummaryLevelEstimate {
+	type levelTotals struct {
+		SummaryLevelEstimate
+		measured int
+	}
+	var totals [summary.LevelProject + 1]levelTotals
+	for _, cs := range stored {
+		if cs.EntityType < summary.LevelFunction || cs.EntityType > summary.LevelProject || !sel.Includes(cs) {
+			continue
+		}
+		t := &totals[cs.EntityType]
+		t.Summaries++
+		if cs.PromptTokens+cs.OutputTokens > 0 {
+			t.measured++
+			t.PromptTokens += int64(cs.PromptTokens)
+			t.OutputTokens += int64(cs.OutputTokens)
+		}
+	}
+
+	var estimates []SummaryLevelEstimate
+	for level := summary.LevelFunction; level <= summary.LevelProject; level++ {
+		t := totals[level]
+		if t.Summaries == 0 {
+			continue
+		}
+		if t.measured > 0 && t.measured < t.Summaries {
+			unmeasured := int64(t.Summaries - t.measured)
+			t.PromptTokens += t.PromptTokens * unmeasured / int64(t.measured)
+			t.OutputTokens += t.OutputTokens * unmeasured / int64(t.measured)
+		}
+		t.Level = level
+		estimates = append(estimates, t.SummaryLevelEstimate)
+	}
+	return estimates
+}
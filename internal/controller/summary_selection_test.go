@@ -0,0 +1,101 @@
+package controller
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/armchr/codeapi/internal/service/summary"
+)
+
+func TestSummarySelectionIncludesPath(t *testing.T) {
+	tests := []struct {
+		name  string
+		paths []string
+		path  string
+		want  bool
+	}{
+		{"no paths", nil, "main.go", true},
+		{"directory itself", []string{"src/billing/..."}, "src/billing", true},
+		{"under directory", []string{"src/billing/..."}, "src/billing/tax/rates.go", true},
+		{"sibling prefix", []string{"src/billing/..."}, "src/billingv2/a.go", false},
+		{"outside directory", []string{"src/billing/..."}, "src/orders/a.go", false},
+		{"everything", []string{"..."}, "a/b.go", true},
+		{"exact file", []string{"cmd/main.go"}, "cmd/main.go", true},
+		{"glob", []string{"cmd/*.go"}, "cmd/graph.go", true},
+		{"glob stays in its directory", []string{"cmd/*.go"}, "cmd/sub/x.go", false},
+		{"leading dot slash", []string{"./src/..."}, "src/a.go", true},
+		{"any of several", []string{"docs/...", "src/..."}, "src/a.go", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sel := SummarySelection{Paths: tt.paths}
+			if got := sel.IncludesPath(tt.path); got != tt.want {
+				t.Errorf("IncludesPath(%q) = %v, want %v", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSummarySelectionIncludesLevel(t *testing.T) {
+	tests := []struct {
+		name  string
+		sel   SummarySelection
+		level summary.SummaryLevel
+		want  bool
+	}{
+		{"all levels", SummarySelection{}, summary.LevelFolder, true},
+		{"selected level", SummarySelection{Levels: []summary.SummaryLevel{summary.LevelFunction}}, summary.LevelFunction, true},
+		{"other level", SummarySelection{Levels: []summary.SummaryLevel{summary.LevelFunction}}, summary.LevelClass, false},
+		{"project without paths", SummarySelection{}, summary.LevelProject, true},
+		{"project with paths", SummarySelection{Paths: []string{"src/..."}}, summary.LevelProject, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.sel.IncludesLevel(tt.level); got != tt.want {
+				t.Errorf("IncludesLevel(%v) = %v, want %v", tt.level, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSummaryLevels(t *testing.T) {
+	levels, err := ParseSummaryLevels([]string{"function", " Class "})
+	if err != nil {
+		t.Fatalf("ParseSummaryLevels: %v", err)
+	}
+	if want := []summary.SummaryLevel{summary.LevelFunction, summary.LevelClass}; !reflect.DeepEqual(levels, want) {
+		t.Errorf("levels = %v, want %v", levels, want)
+	}
+	if _, err := ParseSummaryLevels([]string{"method"}); err == nil {
+		t.Error("expected an error for an unknown level")
+	}
+}
+
+func TestEstimateSummaryCost(t *testing.T) {
+	stored := []*summary.CodeSummary{
+		{EntityType: summary.LevelFunction, FilePath: "src/billing/a.go", PromptTokens: 300, OutputTokens: 100},
+		{EntityType: summary.LevelFunction, FilePath: "src/billing/b.go", PromptTokens: 500, OutputTokens: 50},
+		{EntityType: summary.LevelFunction, FilePath: "src/billing/b.go"}, // carried over
+		{EntityType: summary.LevelFunction, FilePath: "src/orders/c.go", PromptTokens: 900, OutputTokens: 90},
+		{EntityType: summary.LevelFile, FilePath: "src/billing/a.go", PromptTokens: 1000, OutputTokens: 200},
+		{EntityType: summary.LevelFolder, FilePath: "src/billing", PromptTokens: 2000, OutputTokens: 300},
+		{EntityType: summary.LevelProject, FilePath: "/repo", PromptTokens: 4000, OutputTokens: 400},
+	}
+
+	got := EstimateSummaryCost(stored, SummarySelection{
+		Levels: []summary.SummaryLevel{summary.LevelFunction, summary.LevelFolder, summary.LevelProject},
+		Paths:  []string{"src/billing/..."},
+	})
+	want := []SummaryLevelEstimate{
+		{Level: summary.LevelFunction, Summaries: 3, PromptTokens: 1200, OutputTokens: 225},
+		{Level: summary.LevelFolder, Summaries: 1, PromptTokens: 2000, OutputTokens: 300},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("EstimateSummaryCost = %+v, want %+v", got, want)
+	}
+
+	all := EstimateSummaryCost(stored, SummarySelection{})
+	if len(all) != 4 || all[3].Level != summary.LevelProject || all[3].PromptTokens != 4000 {
+		t.Errorf("EstimateSummaryCost without selection = %+v", all)
+	}
+}
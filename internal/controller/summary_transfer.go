@@ -0,0 +1,370 @@
+package controller
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strconv"
+
+	"github.com/armchr/codeapi/internal/db"
+	"github.com/armchr/codeapi/internal/logging"
+	"github.com/armchr/codeapi/internal/model/ast"
+	"github.com/armchr/codeapi/internal/service/codegraph"
+	"github.com/armchr/codeapi/internal/service/summary"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+const (
+	// maxSummaryLineBytes bounds one line of a summary export
+	maxSummaryLineBytes = 4 << 20
+
+	// summaryImportBatch is the number of summaries saved per statement,
+	// well below the bind parameter limits of the relational stores
+	summaryImportBatch = 500
+
+	// maxReportedUnresolved caps the entities listed in an import result
+	maxReportedUnresolved = 100
+)
+
+// ErrInvalidSummaryExport is returned by imports of input that is not a
+// summary export
+var ErrInvalidSummaryExport = errors.New("invalid summary export")
+
+// SummaryTransfer moves the stored summaries of a repository between
+// environments as JSONL, one summary.CodeSummary per line, so summaries
+// generated in one environment can be promoted to another without spending
+// LLM tokens again
+type SummaryTransfer struct {
+	mysqlDB   *sql.DB
+	codeGraph *codegraph.CodeGraph // Validates imported entity IDs; may be nil
+	logger    *zap.Logger
+}
+
+// NewSummaryTransfer creates a SummaryTransfer. Without a code graph, imports
+// keep only file, folder and project summaries.
+func NewSummaryTransfer(mysqlDB *sql.DB, codeGraph *codegraph.CodeGraph, logger *zap.Logger) *SummaryTransfer {
+	return &SummaryTransfer{
+		mysqlDB:   mysqlDB,
+		codeGraph: codeGraph,
+		logger:    logger,
+	}
+}
+
+// SummaryImportResult reports what an import stored and what it left out
+type SummaryImportResult struct {
+	RepoName string `json:"repo_name"`
+	Read     int    `json:"read"`
+	Imported int    `json:"imported"`
+	DryRun   bool   `json:"dry_run,omitempty"`
+
+	// Remapped counts the function and class summaries stored under the ID
+	// the entity has in the target graph
+	Remapped int `json:"remapped"`
+
+	// Unresolved counts the summaries of entities the target graph does not
+	// have, or has several of with the same file and name
+	Unresolved         int      `json:"unresolved"`
+	UnresolvedEntities []string `json:"unresolved_entities,omitempty"` // first 100, as "level path name"
+
+	// Superseded counts older summaries dropped because a newer one in the
+	// import maps to the same target entity
+	Superseded int `json:"superseded"`
+}
+
+// WriteSummariesJSONL writes summaries one JSON object per line
+func WriteSummariesJSONL(w io.Writer, summaries []*summary.CodeSummary) error {
+	enc := json.NewEncoder(w)
+	for _, cs := range summaries {
+		if err := enc.Encode(cs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReadSummariesJSONL reads summaries written by WriteSummariesJSONL. Blank
+// lines are skipped; any other line that is not a summary is an error.
+func ReadSummariesJSONL(r io.Reader) ([]*summary.CodeSummary, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxSummaryLineBytes)
+
+	var summaries []*summary.CodeSummary
+	for line := 1; scanner.Scan(); line++ {
+		text := bytes.TrimSpace(scanner.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+		var cs summary.CodeSummary
+		if err := json.Unmarshal(text, &cs); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		if cs.EntityType < summary.LevelFunction || cs.EntityType > summary.LevelProject {
+			return nil, fmt.Errorf("line %d: unknown entity type %d", line, cs.EntityType)
+		}
+		if cs.EntityID == "" {
+			return nil, fmt.Errorf("line %d: missing entity_id", line)
+		}
+		summaries = append(summaries, &cs)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return summaries, nil
+}
+
+// Export writes every stored summary of a repository to w and returns how
+// many were written
+func (t *SummaryTransfer) Export(ctx context.Context, repoName string, w io.Writer) (int, error) {
+	summaries, err := db.NewSummaryReader(t.mysqlDB, repoName, t.logger).WithContext(ctx).GetAllSummaries()
+	if err != nil {
+		return 0, fmt.Errorf("failed to read summaries: %w", err)
+	}
+	if err := WriteSummariesJSONL(w, summaries); err != nil {
+		return 0, fmt.Errorf("failed to write summaries: %w", err)
+	}
+	return len(summaries), nil
+}
+
+// Import reads an export and stores its summaries for repoName, matching
+// each to the entities of the target code graph: a function or class keeps
+// its ID when the target has that node under the same name and file, and is
+// otherwise looked up by file and name in the newest version of its file.
+// Files must exist in the target graph and folders must contain one. The
+// context hashes are kept, so the next build skips the imported summaries
+// whose code is unchanged. With dryRun nothing is stored.
+func (t *SummaryTransfer) Import(ctx context.Context, repoName string, r io.Reader, dryRun bool) (*SummaryImportResult, error) {
+	imported, err := ReadSummariesJSONL(r)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSummaryExport, err)
+	}
+
+	var targets *summaryTargets
+	if t.codeGraph != nil {
+		if targets, err = t.loadTargets(ctx, repoName); err != nil {
+			return nil, fmt.Errorf("failed to read target code graph: %w", err)
+		}
+	}
+
+	resolved, result := resolveSummaries(repoName, imported, targets)
+	result.DryRun = dryRun
+	if dryRun || len(resolved) == 0 {
+		return result, nil
+	}
+
+	store, err := db.NewSummaryStore(t.mysqlDB, repoName, t.logger)
+	if err != nil {
+		return nil, err
+	}
+	store = store.WithContext(ctx)
+	for start := 0; start < len(resolved); start += summaryImportBatch {
+		end := min(start+summaryImportBatch, len(resolved))
+		if err := store.SaveSummaries(resolved[start:end]); err != nil {
+			return nil, fmt.Errorf("failed to save summaries: %w", err)
+		}
+	}
+
+	t.logger.Info("Imported summaries",
+		zap.String("repo_name", repoName),
+		zap.Int("imported", result.Imported),
+		zap.Int("remapped", result.Remapped),
+		zap.Int("unresolved", result.Unresolved))
+	return result, nil
+}
+
+// loadTargets reads the functions, classes and files of the newest version
+// of each file of the target repository
+func (t *SummaryTransfer) loadTargets(ctx context.Context, repoName string) (*summaryTargets, error) {
+	functions, err := t.codeGraph.GetLatestEntities(ctx, repoName, ast.NodeTypeFunction)
+	if err != nil {
+		return nil, err
+	}
+	classes, err := t.codeGraph.GetLatestEntities(ctx, repoName, ast.NodeTypeClass)
+	if err != nil {
+		return nil, err
+	}
+	files, err := t.codeGraph.GetLatestEntities(ctx, repoName, ast.NodeTypeFileScope)
+	if err != nil {
+		return nil, err
+	}
+	return newSummaryTargets(functions, classes, files), nil
+}
+
+// entityName is a function or class by file and name
+type entityName struct {
+	filePath string
+	name     string
+}
+
+// summaryTargets indexes the entities of the target graph that imported
+// summaries may attach to
+type summaryTargets struct {
+	byID    map[summary.SummaryLevel]map[string]codegraph.EntityRef
+	byName  map[summary.SummaryLevel]map[entityName][]codegraph.EntityRef
+	files   map[string]bool
+	folders map[string]bool
+}
+
+func newSummaryTargets(functions, classes, files []codegraph.EntityRef) *summaryTargets {
+	targets := &summaryTargets{
+		byID:    make(map[summary.SummaryLevel]map[string]codegraph.EntityRef),
+		byName:  make(map[summary.SummaryLevel]map[entityName][]codegraph.EntityRef),
+		files:   make(map[string]bool, len(files)),
+		folders: make(map[string]bool),
+	}
+	for level, entities := range map[summary.SummaryLevel][]codegraph.EntityRef{
+		summary.LevelFunction: functions,
+		summary.LevelClass:    classes,
+	} {
+		byID := make(map[string]codegraph.EntityRef, len(entities))
+		byName := make(map[entityName][]codegraph.EntityRef, len(entities))
+		for _, e := range entities {
+			byID[strconv.FormatInt(int64(e.ID), 10)] = e
+			key := entityName{e.FilePath, e.Name}
+			byName[key] = append(byName[key], e)
+		}
+		targets.byID[level], targets.byName[level] = byID, byName
+	}
+	for _, f := range files {
+		targets.files[f.FilePath] = true
+		for d := filepath.Dir(f.FilePath); d != "." && d != "/" && d != ""; d = filepath.Dir(d) {
+			targets.folders[d] = true
+		}
+	}
+	return targets
+}
+
+// resolve returns the target entity ID of a summary, whether it differs
+// from the exported one, and false when the summary has no target
+func (t *summaryTargets) resolve(repoName string, cs *summary.CodeSummary) (string, bool, bool) {
+	switch cs.EntityType {
+	case summary.LevelProject:
+		return repoName, cs.EntityID != repoName, true
+	case summary.LevelFile:
+		if t == nil || t.files[cs.EntityID] {
+			return cs.EntityID, false, true
+		}
+	case summary.LevelFolder:
+		if t == nil || t.folders[cs.EntityID] {
+			return cs.EntityID, false, true
+		}
+	case summary.LevelFunction, summary.LevelClass:
+		if t == nil {
+			return "", false, false
+		}
+		if e, ok := t.byID[cs.EntityType][cs.EntityID]; ok && e.Name == cs.EntityName && e.FilePath == cs.FilePath {
+			return cs.EntityID, false, true
+		}
+		if matches := t.byName[cs.EntityType][entityName{cs.FilePath, cs.EntityName}]; len(matches) == 1 {
+			id := strconv.FormatInt(int64(matches[0].ID), 10)
+			return id, id != cs.EntityID, true
+		}
+	}
+	return "", false, false
+}
+
+// resolveSummaries returns the imported summaries that have an entity in the
+// target, under their target IDs. Without targets, function and class
+// summaries are unresolved and the others are kept as they are. When several
+// summaries map to one entity, the most recently updated is kept.
+func resolveSummaries(repoName string, imported []*summary.CodeSummary, targets *summaryTargets) ([]*summary.CodeSummary, *SummaryImportResult) {
+	result := &SummaryImportResult{RepoName: repoName, Read: len(imported)}
+
+	type targetKey struct {
+		level    summary.SummaryLevel
+		entityID string
+	}
+	kept := make(map[targetKey]int)
+	var resolved []*summary.CodeSummary
+	remapped := make(map[targetKey]bool)
+	for _, cs := range imported {
+		id, changed, ok := targets.resolve(repoName, cs)
+		if !ok {
+			result.Unresolved++
+			if len(result.UnresolvedEntities) < maxReportedUnresolved {
+				result.UnresolvedEntities = append(result.UnresolvedEntities,
+					fmt.Sprintf("%s %s %s", cs.EntityType, cs.FilePath, cs.EntityName))
+			}
+			continue
+		}
+
+		copied := *cs
+		copied.ID = 0
+		copied.EntityID = id
+		if changed {
+			// The previous version the summary was carried from is an entity
+			// of the source graph
+			copied.PreviousEntityID = ""
+		}
+		key := targetKey{cs.EntityType, id}
+		if i, ok := kept[key]; ok {
+			result.Superseded++
+			if copied.UpdatedAt.Before(resolved[i].UpdatedAt) {
+				continue
+			}
+			resolved[i] = &copied
+			remapped[key] = changed
+			continue
+		}
+		kept[key] = len(resolved)
+		resolved = append(resolved, &copied)
+		remapped[key] = changed
+	}
+
+	result.Imported = len(resolved)
+	for key, changed := range remapped {
+		if changed && key.level != summary.LevelProject {
+			result.Remapped++
+		}
+	}
+	return resolved, result
+}
+
+// ExportSummaries streams every stored summary of a repository as JSONL
+func (c *SummaryController) ExportSummaries(ctx *gin.Context) {
+	logger := logging.FromContext(ctx.Request.Context(), c.logger)
+	repoName := ctx.Param("repo")
+	ctx.Header("Content-Type", "application/x-ndjson")
+	ctx.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", repoName+"-summaries.jsonl"))
+
+	transfer := NewSummaryTransfer(c.mysqlDB, c.codeGraph, c.logger)
+	count, err := transfer.Export(ctx.Request.Context(), repoName, ctx.Writer)
+	if err != nil {
+		logger.Error("Failed to export summaries", zap.String("repo_name", repoName), zap.Error(err))
+		if !ctx.Writer.Written() {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to export summaries: " + err.Error()})
+		}
+		return
+	}
+	logger.Info("Exported summaries", zap.String("repo_name", repoName), zap.Int("count", count))
+}
+
+// ImportSummaries stores the summaries of a JSONL export, sent as the
+// request body, for a repository. With ?dry_run=true only the report of what
+// would be imported is returned.
+func (c *SummaryController) ImportSummaries(ctx *gin.Context) {
+	logger := logging.FromContext(ctx.Request.Context(), c.logger)
+	repoName := ctx.Param("repo")
+	dryRun := ctx.Query("dry_run") == "true"
+
+	transfer := NewSummaryTransfer(c.mysqlDB, c.codeGraph, c.logger)
+	result, err := transfer.Import(ctx.Request.Context(), repoName, ctx.Request.Body, dryRun)
+	if errors.Is(err, ErrInvalidSummaryExport) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to import summaries", zap.String("repo_name", repoName), zap.Error(err))
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to import summaries: " + err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,111 @@
+package controller
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/armchr/codeapi/internal/service/codegraph"
+	"github.com/armchr/codeapi/internal/service/summary"
+)
+
+func TestSummariesJSONLRoundTrip(t *testing.T) {
+	summaries := []*summary.CodeSummary{
+		{EntityID: "42", EntityType: summary.LevelFunction, EntityName: "Charge", FilePath: "billing/charge.go", Summary: "Charges a card.\nRetries once.", ContextHash: "abc"},
+		{EntityID: "billing", EntityType: summary.LevelFolder, EntityName: "billing", FilePath: "billing", Summary: "Billing."},
+	}
+	var buf bytes.Buffer
+	if err := WriteSummariesJSONL(&buf, summaries); err != nil {
+		t.Fatalf("WriteSummariesJSONL: %v", err)
+	}
+	if lines := strings.Count(buf.String(), "\n"); lines != 2 {
+		t.Fatalf("wrote %d lines, want 2", lines)
+	}
+
+	read, err := ReadSummariesJSONL(strings.NewReader(buf.String() + "\n"))
+	if err != nil {
+		t.Fatalf("ReadSummariesJSONL: %v", err)
+	}
+	if len(read) != 2 || read[0].Summary != summaries[0].Summary || read[0].ContextHash != "abc" || read[1].EntityType != summary.LevelFolder {
+		t.Errorf("read back %+v", read)
+	}
+}
+
+func TestReadSummariesJSONLErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"not json", "{\"entity_id\":\"1\",\"entity_type\":1}\nnot json\n", "line 2"},
+		{"unknown level", "{\"entity_id\":\"1\",\"entity_type\":9}\n", "unknown entity type"},
+		{"missing id", "{\"entity_type\":3}\n", "missing entity_id"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ReadSummariesJSONL(strings.NewReader(tt.input))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error = %v, want it to mention %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveSummaries(t *testing.T) {
+	targets := newSummaryTargets(
+		[]codegraph.EntityRef{
+			{ID: 42, Name: "Charge", FilePath: "billing/charge.go"},
+			{ID: 900, Name: "Refund", FilePath: "billing/charge.go"},
+			{ID: 901, Name: "String", FilePath: "billing/types.go"},
+			{ID: 902, Name: "String", FilePath: "billing/types.go"},
+		},
+		[]codegraph.EntityRef{{ID: 950, Name: "Card", FilePath: "billing/types.go"}},
+		[]codegraph.EntityRef{{FilePath: "billing/charge.go"}, {FilePath: "billing/types.go"}},
+	)
+	older := time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC)
+	newer := older.Add(24 * time.Hour)
+
+	resolved, result := resolveSummaries("shop", []*summary.CodeSummary{
+		{EntityID: "42", EntityType: summary.LevelFunction, EntityName: "Charge", FilePath: "billing/charge.go", Summary: "same id"},
+		{EntityID: "7", PreviousEntityID: "3", EntityType: summary.LevelFunction, EntityName: "Refund", FilePath: "billing/charge.go", Summary: "old refund", UpdatedAt: older},
+		{EntityID: "8", EntityType: summary.LevelFunction, EntityName: "Refund", FilePath: "billing/charge.go", Summary: "new refund", UpdatedAt: newer},
+		{EntityID: "9", EntityType: summary.LevelFunction, EntityName: "String", FilePath: "billing/types.go"},
+		{EntityID: "10", EntityType: summary.LevelFunction, EntityName: "Gone", FilePath: "billing/charge.go"},
+		{EntityID: "11", EntityType: summary.LevelClass, EntityName: "Card", FilePath: "billing/types.go"},
+		{EntityID: "billing/types.go", EntityType: summary.LevelFile, FilePath: "billing/types.go"},
+		{EntityID: "billing/old.go", EntityType: summary.LevelFile, FilePath: "billing/old.go"},
+		{EntityID: "billing", EntityType: summary.LevelFolder, FilePath: "billing"},
+		{EntityID: "shop-staging", EntityType: summary.LevelProject, EntityName: "shop-staging"},
+	}, targets)
+
+	got := make(map[string]string, len(resolved))
+	for _, cs := range resolved {
+		got[cs.EntityType.String()+":"+cs.EntityID] = cs.Summary
+		if cs.EntityID == "900" && cs.PreviousEntityID != "" {
+			t.Errorf("remapped summary kept previous entity %q of the source graph", cs.PreviousEntityID)
+		}
+	}
+	for _, key := range []string{"function:42", "function:900", "class:950", "file:billing/types.go", "folder:billing", "project:shop"} {
+		if _, ok := got[key]; !ok {
+			t.Errorf("missing %s in %v", key, got)
+		}
+	}
+	if got["function:900"] != "new refund" {
+		t.Errorf("function 900 = %q, want the newer summary", got["function:900"])
+	}
+	if result.Imported != 6 || result.Remapped != 2 || result.Unresolved != 3 || result.Superseded != 1 {
+		t.Errorf("result = %+v", result)
+	}
+}
+
+func TestResolveSummariesWithoutGraph(t *testing.T) {
+	resolved, result := resolveSummaries("shop", []*summary.CodeSummary{
+		{EntityID: "42", EntityType: summary.LevelFunction, EntityName: "Charge", FilePath: "billing/charge.go"},
+		{EntityID: "billing/charge.go", EntityType: summary.LevelFile, FilePath: "billing/charge.go"},
+		{EntityID: "billing", EntityType: summary.LevelFolder, FilePath: "billing"},
+	}, nil)
+	if len(resolved) != 2 || result.Unresolved != 1 || result.UnresolvedEntities[0] != "function billing/charge.go Charge" {
+		t.Errorf("resolved %d, result %+v", len(resolved), result)
+	}
+}
@@ -0,0 +1,137 @@
+package controller
+
+import (
+	"context"
+	"time"
+
+	"github.com/armchr/codeapi/internal/config"
+	"github.com/armchr/codeapi/internal/db"
+	"github.com/armchr/codeapi/internal/service"
+	"github.com/armchr/codeapi/internal/service/codegraph"
+	"github.com/armchr/codeapi/internal/service/vector"
+
+	"go.uber.org/zap"
+)
+
+// Warmer prepares repositories for their first requests after the server
+// starts: it starts language servers, loads the most recently indexed files
+// into the graph cache and the language server, and checks that the vector
+// collections exist. Any of the services may be nil; their steps are skipped.
+type Warmer struct {
+	repoService  *service.RepoService
+	chunkService *vector.CodeChunkService
+	dbConn       db.Connection
+	codeGraph    *codegraph.CodeGraph
+	config       *config.Config
+	logger       *zap.Logger
+}
+
+// NewWarmer creates a warmer over the services of the server
+func NewWarmer(repoService *service.RepoService, chunkService *vector.CodeChunkService, dbConn db.Connection, codeGraph *codegraph.CodeGraph, cfg *config.Config, logger *zap.Logger) *Warmer {
+	return &Warmer{
+		repoService:  repoService,
+		chunkService: chunkService,
+		dbConn:       dbConn,
+		codeGraph:    codeGraph,
+		config:       cfg,
+		logger:       logger,
+	}
+}
+
+// Run warms up every enabled repository selected by the warm-up config, one
+// after the other, until done or the warm-up timeout passes. Failures are
+// logged; a repository that cannot be warmed is served cold.
+func (w *Warmer) Run(ctx context.Context) {
+	warmupCfg := w.config.Warmup.GetDefaults()
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(warmupCfg.TimeoutSeconds)*time.Second)
+	defer cancel()
+
+	start := time.Now()
+	warmed := 0
+	repos := w.config.Repositories()
+	for i := range repos {
+		repo := &repos[i]
+		if repo.Disabled || !warmupCfg.Includes(repo.Name) {
+			continue
+		}
+		if ctx.Err() != nil {
+			w.logger.Warn("Warm-up timed out", zap.String("next_repo", repo.Name))
+			break
+		}
+		w.WarmRepository(ctx, repo, warmupCfg)
+		warmed++
+	}
+
+	w.logger.Info("Warm-up finished",
+		zap.Int("repositories", warmed),
+		zap.Duration("elapsed", time.Since(start)))
+}
+
+// WarmRepository runs the warm-up steps for one repository
+func (w *Warmer) WarmRepository(ctx context.Context, repo *config.Repository, warmupCfg config.WarmupConfig) {
+	start := time.Now()
+	logger := w.logger.With(zap.String("repo_name", repo.Name))
+
+	lspReady := false
+	if w.repoService != nil && !warmupCfg.SkipLanguageServers {
+		if err := w.repoService.PrepareLanguageServer(repo.Name); err != nil {
+			logger.Warn("Failed to start language server during warm-up", zap.Error(err))
+		} else {
+			lspReady = true
+		}
+	}
+
+	hotFiles := w.hotFiles(ctx, repo.Name, warmupCfg.HotFiles, logger)
+	if w.codeGraph != nil {
+		for _, v := range hotFiles {
+			w.codeGraph.GetFilePath(ctx, v.FileID)
+		}
+	}
+	opened := 0
+	if lspReady && len(hotFiles) > 0 {
+		paths := make([]string, len(hotFiles))
+		for i, v := range hotFiles {
+			paths[i] = v.RelativePath
+		}
+		var err error
+		opened, err = w.repoService.WarmFiles(ctx, repo.Name, paths)
+		if err != nil {
+			logger.Debug("Some hot files could not be opened", zap.Error(err))
+		}
+	}
+
+	if w.chunkService != nil {
+		exists, err := w.chunkService.GetVectorDB().CollectionExists(ctx, repo.Name)
+		switch {
+		case err != nil:
+			logger.Warn("Failed to check vector collection", zap.Error(err))
+		case !exists:
+			logger.Warn("Vector collection missing, build the index to enable search")
+		}
+	}
+
+	logger.Info("Warmed up repository",
+		zap.Bool("language_server", lspReady),
+		zap.Int("hot_files", len(hotFiles)),
+		zap.Int("opened_files", opened),
+		zap.Duration("elapsed", time.Since(start)))
+}
+
+// hotFiles returns the newest versions of the most recently indexed files of
+// a repository, or nil without a relational store
+func (w *Warmer) hotFiles(ctx context.Context, repoName string, limit int, logger *zap.Logger) []*db.FileVersion {
+	if w.dbConn == nil {
+		return nil
+	}
+	fileVersionRepo, err := db.NewFileVersionRepository(w.dbConn.GetDB(), repoName, w.logger)
+	if err != nil {
+		logger.Warn("Failed to open file versions for warm-up", zap.Error(err))
+		return nil
+	}
+	versions, err := fileVersionRepo.WithContext(ctx).GetRecentVersions(limit)
+	if err != nil {
+		logger.Warn("Failed to load recently indexed files", zap.Error(err))
+		return nil
+	}
+	return versions
+}
@@ -0,0 +1,178 @@
+package db
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/armchr/codeapi/internal/metrics"
+	"github.com/armchr/codeapi/internal/service/audit"
+	"go.uber.org/zap"
+)
+
+// defaultAuditLimit caps List when the filter sets no limit
+const defaultAuditLimit = 100
+
+// AuditStore records the operations that changed the data of a repository
+// in the shared audit_log table
+type AuditStore struct {
+	db       *sql.DB
+	dialect  Dialect
+	repoName string
+	ctx      context.Context
+	logger   *zap.Logger
+}
+
+// NewAuditStore creates an audit store for a repository, migrating the
+// audit_log table first
+func NewAuditStore(db *sql.DB, repoName string, logger *zap.Logger) (*AuditStore, error) {
+	if err := EnsureAuditLogSchema(db, logger); err != nil {
+		return nil, fmt.Errorf("failed to ensure table: %w", err)
+	}
+	return &AuditStore{
+		db:       db,
+		dialect:  dialectFor(db),
+		repoName: repoName,
+		ctx:      context.Background(),
+		logger:   logger,
+	}, nil
+}
+
+// WithContext returns a copy of the store bound to ctx
+func (s *AuditStore) WithContext(ctx context.Context) *AuditStore {
+	bound := *s
+	bound.ctx = ctx
+	return &bound
+}
+
+func (s *AuditStore) tableName() string {
+	return s.dialect.QuoteIdent(AuditLogTable)
+}
+
+// Record stores an entry for the repository of the store
+func (s *AuditStore) Record(entry *audit.Entry) error {
+	var paths, details sql.NullString
+	if len(entry.Paths) > 0 {
+		encoded, err := encodeJSON(entry.Paths)
+		if err != nil {
+			return fmt.Errorf("failed to encode audit paths: %w", err)
+		}
+		paths = sql.NullString{String: encoded, Valid: true}
+	}
+	if len(entry.Details) > 0 {
+		encoded, err := encodeJSON(entry.Details)
+		if err != nil {
+			return fmt.Errorf("failed to encode audit details: %w", err)
+		}
+		details = sql.NullString{String: encoded, Valid: true}
+	}
+
+	query := fmt.Sprintf(`
+		INSERT INTO %s (repo_name, operation, source, actor, client, paths, outcome, error, details, started_at, duration_ms)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, s.tableName())
+
+	done := metrics.TimeStoreQuery(metrics.StoreDB, "exec", s.repoName)
+	_, err := s.db.ExecContext(s.ctx, s.dialect.Rebind(query), s.repoName, entry.Operation, entry.Source,
+		nullString(entry.Actor), nullString(entry.Client), paths, entry.Outcome, nullString(entry.Error), details,
+		entry.StartedAt.UTC(), entry.DurationMs)
+	done(err)
+	if err != nil {
+		return fmt.Errorf("failed to record audit entry: %w", err)
+	}
+	return nil
+}
+
+// List returns the entries matching filter, newest first
+func (s *AuditStore) List(filter audit.Filter) ([]audit.Entry, error) {
+	where := []string{"repo_name = ?"}
+	args := []any{s.repoName}
+	for _, eq := range []struct{ column, value string }{
+		{"operation", filter.Operation},
+		{"source", filter.Source},
+		{"actor", filter.Actor},
+		{"outcome", filter.Outcome},
+	} {
+		if eq.value != "" {
+			where = append(where, eq.column+" = ?")
+			args = append(args, eq.value)
+		}
+	}
+	if filter.Path != "" {
+		// Paths are stored as a JSON array, so every path follows a quote
+		encoded, err := encodeJSON(filter.Path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode path: %w", err)
+		}
+		where = append(where, "(paths IS NULL OR paths LIKE ? ESCAPE '!')")
+		args = append(args, "%"+escapeLike(strings.TrimSuffix(encoded, `"`))+"%")
+	}
+	if filter.Since != nil {
+		where = append(where, "started_at >= ?")
+		args = append(args, filter.Since.UTC())
+	}
+	if filter.Until != nil {
+		where = append(where, "started_at < ?")
+		args = append(args, filter.Until.UTC())
+	}
+
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = defaultAuditLimit
+	}
+	query := fmt.Sprintf(`
+		SELECT id, operation, source, actor, client, paths, outcome, error, details, started_at, duration_ms
+		FROM %s
+		WHERE %s
+		ORDER BY started_at DESC, id DESC
+		LIMIT %d OFFSET %d
+	`, s.tableName(), strings.Join(where, " AND "), limit, max(filter.Offset, 0))
+
+	done := metrics.TimeStoreQuery(metrics.StoreDB, "query", s.repoName)
+	rows, err := s.db.QueryContext(s.ctx, s.dialect.Rebind(query), args...)
+	done(err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query audit log: %w", err)
+	}
+	defer rows.Close()
+
+	var result []audit.Entry
+	for rows.Next() {
+		e := audit.Entry{RepoName: s.repoName}
+		var actor, client, paths, errText, details sql.NullString
+		if err := rows.Scan(&e.ID, &e.Operation, &e.Source, &actor, &client, &paths, &e.Outcome,
+			&errText, &details, &e.StartedAt, &e.DurationMs); err != nil {
+			return nil, fmt.Errorf("failed to scan audit entry: %w", err)
+		}
+		e.Actor, e.Client, e.Error = actor.String, client.String, errText.String
+		e.StartedAt = e.StartedAt.In(time.UTC)
+		if paths.Valid {
+			if err := json.Unmarshal([]byte(paths.String), &e.Paths); err != nil {
+				return nil, fmt.Errorf("failed to decode audit paths: %w", err)
+			}
+		}
+		if details.Valid {
+			if err := json.Unmarshal([]byte(details.String), &e.Details); err != nil {
+				return nil, fmt.Errorf("failed to decode audit details: %w", err)
+			}
+		}
+		result = append(result, e)
+	}
+	return result, rows.Err()
+}
+
+// encodeJSON encodes v without escaping HTML characters, which keeps paths
+// searchable with LIKE
+func encodeJSON(v any) (string, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(buf.String(), "\n"), nil
+}
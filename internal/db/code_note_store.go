@@ -0,0 +1,187 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/armchr/codeapi/internal/metrics"
+	"github.com/armchr/codeapi/internal/service/notes"
+	"go.uber.org/zap"
+)
+
+// defaultNoteLimit caps ListNotes when the filter sets no limit
+const defaultNoteLimit = 100
+
+// CodeNoteStore manages TODO-style notes and license headers of a
+// repository in the shared code_notes table
+type CodeNoteStore struct {
+	db       *sql.DB
+	dialect  Dialect
+	repoName string
+	ctx      context.Context
+	logger   *zap.Logger
+}
+
+// NewCodeNoteStore creates a note store for a repository, migrating the
+// code_notes table first
+func NewCodeNoteStore(db *sql.DB, repoName string, logger *zap.Logger) (*CodeNoteStore, error) {
+	if err := EnsureCodeNoteSchema(db, logger); err != nil {
+		return nil, fmt.Errorf("failed to ensure table: %w", err)
+	}
+	return NewCodeNoteReader(db, repoName, logger), nil
+}
+
+// NewCodeNoteReader returns a note store for reads that does not create or
+// migrate the table
+func NewCodeNoteReader(db *sql.DB, repoName string, logger *zap.Logger) *CodeNoteStore {
+	return &CodeNoteStore{
+		db:       db,
+		dialect:  dialectFor(db),
+		repoName: repoName,
+		ctx:      context.Background(),
+		logger:   logger,
+	}
+}
+
+// WithContext returns a copy of the store bound to ctx
+func (s *CodeNoteStore) WithContext(ctx context.Context) *CodeNoteStore {
+	bound := *s
+	bound.ctx = ctx
+	return &bound
+}
+
+func (s *CodeNoteStore) tableName() string {
+	return s.dialect.QuoteIdent(CodeNotesTable)
+}
+
+// ReplaceFileNotes swaps the notes stored for a file path for those found in
+// its latest version, so notes removed from the file disappear too
+func (s *CodeNoteStore) ReplaceFileNotes(filePath string, fileID int32, fileNotes []notes.CodeNote) error {
+	done := metrics.TimeStoreQuery(metrics.StoreDB, "replace_notes", s.repoName)
+	err := s.replaceFileNotes(filePath, fileID, fileNotes)
+	done(err)
+	return err
+}
+
+func (s *CodeNoteStore) replaceFileNotes(filePath string, fileID int32, fileNotes []notes.CodeNote) error {
+	tx, err := s.db.BeginTx(s.ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	del := fmt.Sprintf(`DELETE FROM %s WHERE repo_name = ? AND file_path = ?`, s.tableName())
+	if _, err := tx.ExecContext(s.ctx, s.dialect.Rebind(del), s.repoName, filePath); err != nil {
+		return fmt.Errorf("failed to delete notes of %s: %w", filePath, err)
+	}
+
+	if len(fileNotes) > 0 {
+		valueStrings := make([]string, 0, len(fileNotes))
+		valueArgs := make([]any, 0, len(fileNotes)*11)
+		for _, n := range fileNotes {
+			var authoredAt sql.NullTime
+			if n.AuthoredAt != nil {
+				authoredAt = sql.NullTime{Time: n.AuthoredAt.UTC(), Valid: true}
+			}
+			valueStrings = append(valueStrings, "(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)")
+			valueArgs = append(valueArgs, s.repoName, fileID, filePath, n.Line, n.Kind, n.Text,
+				n.Assignee, n.Author, n.AuthorEmail, n.CommitID, authoredAt)
+		}
+		insert := fmt.Sprintf(`
+			INSERT INTO %s (repo_name, file_id, file_path, line, kind, text, assignee, author, author_email, commit_id, authored_at)
+			VALUES %s
+		`, s.tableName(), strings.Join(valueStrings, ","))
+		if _, err := tx.ExecContext(s.ctx, s.dialect.Rebind(insert), valueArgs...); err != nil {
+			return fmt.Errorf("failed to insert notes of %s: %w", filePath, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit notes of %s: %w", filePath, err)
+	}
+	return nil
+}
+
+// ListNotes returns the notes matching filter, ordered by file and line
+func (s *CodeNoteStore) ListNotes(filter notes.Filter) ([]notes.CodeNote, error) {
+	where := []string{"repo_name = ?"}
+	args := []any{s.repoName}
+	if len(filter.Kinds) > 0 {
+		placeholders := make([]string, len(filter.Kinds))
+		for i, kind := range filter.Kinds {
+			placeholders[i] = "?"
+			args = append(args, strings.ToLower(kind))
+		}
+		where = append(where, fmt.Sprintf("kind IN (%s)", strings.Join(placeholders, ", ")))
+	}
+	if filter.PathPrefix != "" {
+		where = append(where, "file_path LIKE ? ESCAPE '!'")
+		args = append(args, escapeLike(filter.PathPrefix)+"%")
+	}
+	if filter.Author != "" {
+		where = append(where, "(author = ? OR author_email = ? OR assignee = ?)")
+		args = append(args, filter.Author, filter.Author, filter.Author)
+	}
+	if filter.Contains != "" {
+		where = append(where, "LOWER(text) LIKE ? ESCAPE '!'")
+		args = append(args, "%"+escapeLike(strings.ToLower(filter.Contains))+"%")
+	}
+
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = defaultNoteLimit
+	}
+	query := fmt.Sprintf(`
+		SELECT id, file_id, file_path, line, kind, text, assignee, author, author_email, commit_id, authored_at, created_at
+		FROM %s
+		WHERE %s
+		ORDER BY file_path, line
+		LIMIT %d OFFSET %d
+	`, s.tableName(), strings.Join(where, " AND "), limit, max(filter.Offset, 0))
+
+	done := metrics.TimeStoreQuery(metrics.StoreDB, "query", s.repoName)
+	rows, err := s.db.QueryContext(s.ctx, s.dialect.Rebind(query), args...)
+	done(err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query notes: %w", err)
+	}
+	defer rows.Close()
+
+	var result []notes.CodeNote
+	for rows.Next() {
+		var n notes.CodeNote
+		var assignee, author, authorEmail, commitID sql.NullString
+		var authoredAt sql.NullTime
+		if err := rows.Scan(&n.ID, &n.FileID, &n.FilePath, &n.Line, &n.Kind, &n.Text,
+			&assignee, &author, &authorEmail, &commitID, &authoredAt, &n.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan note: %w", err)
+		}
+		n.Assignee, n.Author, n.AuthorEmail, n.CommitID = assignee.String, author.String, authorEmail.String, commitID.String
+		if authoredAt.Valid {
+			t := authoredAt.Time.In(time.UTC)
+			n.AuthoredAt = &t
+		}
+		result = append(result, n)
+	}
+	return result, rows.Err()
+}
+
+// DeleteAll deletes all notes of the repository
+func (s *CodeNoteStore) DeleteAll() (int64, error) {
+	query := fmt.Sprintf(`DELETE FROM %s WHERE repo_name = ?`, s.tableName())
+	done := metrics.TimeStoreQuery(metrics.StoreDB, "exec", s.repoName)
+	result, err := s.db.ExecContext(s.ctx, s.dialect.Rebind(query), s.repoName)
+	done(err)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete notes: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// escapeLike escapes the LIKE wildcards of s for an ESCAPE '!' clause
+func escapeLike(s string) string {
+	return strings.NewReplacer("!", "!!", "%", "!%", "_", "!_").Replace(s)
+}
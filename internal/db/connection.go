@@ -0,0 +1,78 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/armchr/codeapi/internal/config"
+
+	"go.uber.org/zap"
+)
+
+// Connection is a relational store connection. FileVersionRepository and
+// SummaryStore work on the *sql.DB it exposes and pick their SQL dialect from
+// the underlying driver.
+type Connection interface {
+	// EnsureDatabase creates the database if needed and switches to it
+	EnsureDatabase(dbName string) error
+	GetDB() *sql.DB
+	Ping() error
+	Close() error
+	Stats() sql.DBStats
+}
+
+var (
+	_ Connection = (*MySQLConnection)(nil)
+	_ Connection = (*PostgresConnection)(nil)
+	_ Connection = (*SQLiteConnection)(nil)
+)
+
+// NewConnection opens a connection for the driver selected by db.driver
+func NewConnection(cfg *config.Config, logger *zap.Logger) (Connection, error) {
+	var conn Connection
+	var err error
+
+	// Assign through typed results so a failed open never yields a non-nil
+	// interface wrapping a nil pointer
+	switch driver := cfg.DB.GetDefaults().Driver; driver {
+	case config.DBDriverMySQL:
+		var mysqlConn *MySQLConnection
+		if mysqlConn, err = NewMySQLConnection(cfg.MySQL, logger); err == nil {
+			conn = mysqlConn
+		}
+	case config.DBDriverPostgres:
+		var pgConn *PostgresConnection
+		if pgConn, err = NewPostgresConnection(cfg.Postgres, logger); err == nil {
+			conn = pgConn
+		}
+	case config.DBDriverSQLite:
+		var sqliteConn *SQLiteConnection
+		if sqliteConn, err = NewSQLiteConnection(cfg.SQLitePath(), logger); err == nil {
+			conn = sqliteConn
+		}
+	default:
+		err = fmt.Errorf("unsupported database driver: %s", driver)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+	return conn, nil
+}
+
+// sqlPoolDefaults are the MySQL and PostgreSQL pool settings used when the
+// pool section of the store config leaves them unset
+var sqlPoolDefaults = config.PoolConfig{
+	MaxOpenConns:           25,
+	MaxIdleConns:           5,
+	ConnMaxLifetimeSeconds: int((5 * time.Minute).Seconds()),
+}
+
+// configurePool applies pool settings, with defaults filled in, to db
+func configurePool(db *sql.DB, pool config.PoolConfig) {
+	pool = pool.WithDefaults(sqlPoolDefaults)
+	db.SetMaxOpenConns(pool.MaxOpenConns)
+	db.SetMaxIdleConns(pool.MaxIdleConns)
+	db.SetConnMaxLifetime(pool.ConnMaxLifetime())
+}
@@ -0,0 +1,285 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
+	"modernc.org/sqlite"
+)
+
+// Dialect captures the SQL differences between the supported relational
+// stores. Queries are written with MySQL-style ? placeholders and rebound
+// per dialect before execution.
+type Dialect interface {
+	// Name returns the driver name ("mysql", "postgres" or "sqlite")
+	Name() string
+	// Rebind rewrites ? placeholders into the dialect's bind syntax
+	Rebind(query string) string
+	// QuoteIdent quotes a table or column identifier
+	QuoteIdent(name string) string
+	// AutoIncrementKey returns the column type for an auto-generated primary key
+	AutoIncrementKey(bigint bool) string
+	// UpdatedAtColumn returns the definition of an updated_at timestamp column
+	UpdatedAtColumn() string
+	// LargeTextType returns the column type for text of several megabytes,
+	// beyond the 64KB of a MySQL TEXT column
+	LargeTextType() string
+	// TableOptions returns the trailing clause for CREATE TABLE statements
+	TableOptions() string
+	// InlineIndexes reports whether secondary indexes can be declared inside
+	// CREATE TABLE. When false they are created with CREATE INDEX statements.
+	InlineIndexes() bool
+	// NullSafeEqual returns an equality comparison that treats NULLs as equal
+	NullSafeEqual(column string) string
+	// UpsertClause returns the conflict clause that updates columns when a row
+	// with the same conflict key already exists
+	UpsertClause(conflictColumns []string, updateColumns []string) string
+	// ConflictDoNothing returns the clause that makes an INSERT skip rows whose
+	// conflict key already exists
+	ConflictDoNothing(conflictColumns []string) string
+	// ColumnExistsQuery returns a query taking (table, column) that counts
+	// matching columns in the current schema
+	ColumnExistsQuery() string
+	// TableExistsQuery returns a query taking (table) that counts matching
+	// tables in the current schema
+	TableExistsQuery() string
+}
+
+// dialectFor picks the dialect matching the driver behind db
+func dialectFor(db *sql.DB) Dialect {
+	if db != nil {
+		switch db.Driver().(type) {
+		case *pq.Driver:
+			return postgresDialect{}
+		case *sqlite.Driver:
+			return sqliteDialect{}
+		}
+	}
+	return mysqlDialect{}
+}
+
+type mysqlDialect struct{}
+
+func (mysqlDialect) Name() string { return "mysql" }
+
+func (mysqlDialect) Rebind(query string) string { return query }
+
+func (mysqlDialect) QuoteIdent(name string) string {
+	return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+}
+
+func (mysqlDialect) AutoIncrementKey(bigint bool) string {
+	if bigint {
+		return "BIGINT AUTO_INCREMENT PRIMARY KEY"
+	}
+	return "INT AUTO_INCREMENT PRIMARY KEY"
+}
+
+func (mysqlDialect) UpdatedAtColumn() string {
+	return "updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP"
+}
+
+func (mysqlDialect) LargeTextType() string { return "LONGTEXT" }
+
+func (mysqlDialect) TableOptions() string {
+	return "ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci"
+}
+
+func (mysqlDialect) InlineIndexes() bool { return true }
+
+func (mysqlDialect) NullSafeEqual(column string) string {
+	return column + " <=> ?"
+}
+
+func (mysqlDialect) UpsertClause(_ []string, updateColumns []string) string {
+	sets := make([]string, len(updateColumns))
+	for i, col := range updateColumns {
+		sets[i] = fmt.Sprintf("%s = VALUES(%s)", col, col)
+	}
+	return "ON DUPLICATE KEY UPDATE " + strings.Join(sets, ", ")
+}
+
+// ConflictDoNothing uses a self-assignment since MySQL has no DO NOTHING form
+func (mysqlDialect) ConflictDoNothing(conflictColumns []string) string {
+	return fmt.Sprintf("ON DUPLICATE KEY UPDATE %s = %s", conflictColumns[0], conflictColumns[0])
+}
+
+func (mysqlDialect) ColumnExistsQuery() string {
+	return `SELECT COUNT(*) FROM information_schema.COLUMNS
+		WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ? AND COLUMN_NAME = ?`
+}
+
+func (mysqlDialect) TableExistsQuery() string {
+	return `SELECT COUNT(*) FROM information_schema.TABLES
+		WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ?`
+}
+
+type postgresDialect struct{}
+
+func (postgresDialect) Name() string { return "postgres" }
+
+// Rebind converts ? placeholders to $1, $2, ... and leaves question marks
+// inside quoted literals untouched
+func (postgresDialect) Rebind(query string) string {
+	var sb strings.Builder
+	sb.Grow(len(query) + 8)
+	n := 0
+	inQuote := false
+	for _, r := range query {
+		switch {
+		case r == '\'':
+			inQuote = !inQuote
+			sb.WriteRune(r)
+		case r == '?' && !inQuote:
+			n++
+			fmt.Fprintf(&sb, "$%d", n)
+		default:
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
+
+func (postgresDialect) QuoteIdent(name string) string {
+	return pq.QuoteIdentifier(name)
+}
+
+func (postgresDialect) AutoIncrementKey(bigint bool) string {
+	if bigint {
+		return "BIGSERIAL PRIMARY KEY"
+	}
+	return "SERIAL PRIMARY KEY"
+}
+
+// UpdatedAtColumn has no ON UPDATE equivalent in Postgres; stores set
+// updated_at explicitly in their UPDATE and upsert statements
+func (postgresDialect) UpdatedAtColumn() string {
+	return "updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP"
+}
+
+func (postgresDialect) LargeTextType() string { return "TEXT" }
+
+func (postgresDialect) TableOptions() string { return "" }
+
+func (postgresDialect) InlineIndexes() bool { return false }
+
+func (postgresDialect) NullSafeEqual(column string) string {
+	return column + " IS NOT DISTINCT FROM ?"
+}
+
+func (postgresDialect) UpsertClause(conflictColumns []string, updateColumns []string) string {
+	sets := make([]string, len(updateColumns))
+	for i, col := range updateColumns {
+		sets[i] = fmt.Sprintf("%s = EXCLUDED.%s", col, col)
+	}
+	return fmt.Sprintf("ON CONFLICT (%s) DO UPDATE SET %s",
+		strings.Join(conflictColumns, ", "), strings.Join(sets, ", "))
+}
+
+func (postgresDialect) ConflictDoNothing(conflictColumns []string) string {
+	return fmt.Sprintf("ON CONFLICT (%s) DO NOTHING", strings.Join(conflictColumns, ", "))
+}
+
+func (postgresDialect) ColumnExistsQuery() string {
+	return `SELECT COUNT(*) FROM information_schema.columns
+		WHERE table_schema = current_schema() AND table_name = ? AND column_name = ?`
+}
+
+func (postgresDialect) TableExistsQuery() string {
+	return `SELECT COUNT(*) FROM information_schema.tables
+		WHERE table_schema = current_schema() AND table_name = ?`
+}
+
+// sqliteDialect targets the embedded SQLite store. SQLite accepts ?
+// placeholders natively and supports upserts and window functions since 3.25.
+type sqliteDialect struct{}
+
+func (sqliteDialect) Name() string { return "sqlite" }
+
+func (sqliteDialect) Rebind(query string) string { return query }
+
+func (sqliteDialect) QuoteIdent(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// AutoIncrementKey maps both widths to INTEGER, which SQLite aliases to the
+// 64-bit rowid
+func (sqliteDialect) AutoIncrementKey(bool) string {
+	return "INTEGER PRIMARY KEY AUTOINCREMENT"
+}
+
+func (sqliteDialect) UpdatedAtColumn() string {
+	return "updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP"
+}
+
+func (sqliteDialect) LargeTextType() string { return "TEXT" }
+
+func (sqliteDialect) TableOptions() string { return "" }
+
+func (sqliteDialect) InlineIndexes() bool { return false }
+
+func (sqliteDialect) NullSafeEqual(column string) string {
+	return column + " IS ?"
+}
+
+func (sqliteDialect) UpsertClause(conflictColumns []string, updateColumns []string) string {
+	return postgresDialect{}.UpsertClause(conflictColumns, updateColumns)
+}
+
+func (sqliteDialect) ConflictDoNothing(conflictColumns []string) string {
+	return postgresDialect{}.ConflictDoNothing(conflictColumns)
+}
+
+func (sqliteDialect) ColumnExistsQuery() string {
+	return `SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?`
+}
+
+func (sqliteDialect) TableExistsQuery() string {
+	return `SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?`
+}
+
+// IndexDef describes a secondary index on a table
+type IndexDef struct {
+	Name    string
+	Columns string // Comma-separated column list
+	Unique  bool
+}
+
+// createTableStatements builds the statements creating a table and its
+// indexes. Inline dialects get a single CREATE TABLE; others get a CREATE
+// TABLE followed by CREATE INDEX statements. Postgres index names are global
+// to the schema, so they are prefixed with the table name.
+func createTableStatements(d Dialect, table string, columns []string, indexes []IndexDef) []string {
+	defs := append([]string{}, columns...)
+	var extra []string
+	for _, idx := range indexes {
+		if d.InlineIndexes() {
+			kind := "INDEX"
+			if idx.Unique {
+				kind = "UNIQUE KEY"
+			}
+			defs = append(defs, fmt.Sprintf("%s %s (%s)", kind, idx.Name, idx.Columns))
+			continue
+		}
+		extra = append(extra, createIndexStatement(d, table, idx))
+	}
+
+	create := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (\n\t%s\n)", d.QuoteIdent(table), strings.Join(defs, ",\n\t"))
+	if opts := d.TableOptions(); opts != "" {
+		create += " " + opts
+	}
+	return append([]string{create}, extra...)
+}
+
+// createIndexStatement builds a standalone CREATE INDEX for dialects without
+// inline index support
+func createIndexStatement(d Dialect, table string, idx IndexDef) string {
+	kind := "INDEX"
+	if idx.Unique {
+		kind = "UNIQUE INDEX"
+	}
+	return fmt.Sprintf("CREATE %s IF NOT EXISTS %s ON %s (%s)",
+		kind, d.QuoteIdent(table+"_"+idx.Name), d.QuoteIdent(table), idx.Columns)
+}
@@ -0,0 +1,75 @@
+package db
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPostgresRebind(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "no placeholders",
+			input:    "SELECT 1",
+			expected: "SELECT 1",
+		},
+		{
+			name:     "sequential placeholders",
+			input:    "WHERE a = ? AND b IN (?, ?)",
+			expected: "WHERE a = $1 AND b IN ($2, $3)",
+		},
+		{
+			name:     "question mark inside literal",
+			input:    "WHERE a = '?' AND b = ?",
+			expected: "WHERE a = '?' AND b = $1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := postgresDialect{}.Rebind(tt.input)
+			if result != tt.expected {
+				t.Errorf("Rebind(%q) = %q, want %q", tt.input, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestUpsertClause(t *testing.T) {
+	conflict := []string{"entity_id", "entity_type"}
+	update := []string{"summary", "context_hash"}
+
+	mysql := mysqlDialect{}.UpsertClause(conflict, update)
+	if mysql != "ON DUPLICATE KEY UPDATE summary = VALUES(summary), context_hash = VALUES(context_hash)" {
+		t.Errorf("unexpected MySQL upsert clause: %s", mysql)
+	}
+
+	postgres := postgresDialect{}.UpsertClause(conflict, update)
+	if postgres != "ON CONFLICT (entity_id, entity_type) DO UPDATE SET summary = EXCLUDED.summary, context_hash = EXCLUDED.context_hash" {
+		t.Errorf("unexpected Postgres upsert clause: %s", postgres)
+	}
+}
+
+func TestCreateTableStatements(t *testing.T) {
+	columns := []string{"id INT", "name VARCHAR(10)"}
+	indexes := []IndexDef{{Name: "idx_name", Columns: "name", Unique: true}}
+
+	mysql := createTableStatements(mysqlDialect{}, "repo_items", columns, indexes)
+	if len(mysql) != 1 {
+		t.Fatalf("expected a single MySQL statement, got %d", len(mysql))
+	}
+	if !strings.Contains(mysql[0], "UNIQUE KEY idx_name (name)") || !strings.Contains(mysql[0], "ENGINE=InnoDB") {
+		t.Errorf("unexpected MySQL statement: %s", mysql[0])
+	}
+
+	postgres := createTableStatements(postgresDialect{}, "repo_items", columns, indexes)
+	if len(postgres) != 2 {
+		t.Fatalf("expected table and index statements for Postgres, got %d", len(postgres))
+	}
+	if postgres[1] != `CREATE UNIQUE INDEX IF NOT EXISTS "repo_items_idx_name" ON "repo_items" (name)` {
+		t.Errorf("unexpected Postgres index statement: %s", postgres[1])
+	}
+}
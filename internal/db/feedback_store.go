@@ -0,0 +1,168 @@
+package db
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/armchr/codeapi/internal/metrics"
+	"github.com/armchr/codeapi/internal/service/feedback"
+	"go.uber.org/zap"
+)
+
+// defaultFeedbackLimit caps Aggregate when the filter sets no limit
+const defaultFeedbackLimit = 100
+
+// FeedbackStore records votes on the search results and summaries of a
+// repository in the shared feedback table
+type FeedbackStore struct {
+	db       *sql.DB
+	dialect  Dialect
+	repoName string
+	ctx      context.Context
+	logger   *zap.Logger
+}
+
+// NewFeedbackStore creates a feedback store for a repository, migrating the
+// feedback table first
+func NewFeedbackStore(db *sql.DB, repoName string, logger *zap.Logger) (*FeedbackStore, error) {
+	if err := EnsureFeedbackSchema(db, logger); err != nil {
+		return nil, fmt.Errorf("failed to ensure table: %w", err)
+	}
+	return &FeedbackStore{
+		db:       db,
+		dialect:  dialectFor(db),
+		repoName: repoName,
+		ctx:      context.Background(),
+		logger:   logger,
+	}, nil
+}
+
+// WithContext returns a copy of the store bound to ctx
+func (s *FeedbackStore) WithContext(ctx context.Context) *FeedbackStore {
+	bound := *s
+	bound.ctx = ctx
+	return &bound
+}
+
+func (s *FeedbackStore) tableName() string {
+	return s.dialect.QuoteIdent(FeedbackTable)
+}
+
+// queryHash keys feedback by query: the text is too long to index
+func queryHash(query string) string {
+	if query == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(query))
+	return hex.EncodeToString(sum[:])
+}
+
+// RecordVotes stores votes, all or none of them
+func (s *FeedbackStore) RecordVotes(votes []feedback.Feedback) error {
+	if len(votes) == 0 {
+		return nil
+	}
+
+	valueStrings := make([]string, 0, len(votes))
+	valueArgs := make([]any, 0, len(votes)*8)
+	for _, f := range votes {
+		valueStrings = append(valueStrings, "(?, ?, ?, ?, ?, ?, ?, ?)")
+		valueArgs = append(valueArgs, s.repoName, f.EntityType, f.EntityID, queryHash(f.Query),
+			nullString(f.Query), f.Vote, nullString(f.Source), nullString(f.Comment))
+	}
+	query := fmt.Sprintf(`
+		INSERT INTO %s (repo_name, entity_type, entity_id, query_hash, query_text, vote, source, comment)
+		VALUES %s
+	`, s.tableName(), strings.Join(valueStrings, ","))
+
+	done := metrics.TimeStoreQuery(metrics.StoreDB, "exec", s.repoName)
+	_, err := s.db.ExecContext(s.ctx, s.dialect.Rebind(query), valueArgs...)
+	done(err)
+	if err != nil {
+		return fmt.Errorf("failed to record feedback: %w", err)
+	}
+	return nil
+}
+
+// Aggregate totals the votes matching filter per entity, or per entity and
+// query, lowest score first unless filter.BestFirst is set
+func (s *FeedbackStore) Aggregate(filter feedback.Filter) ([]feedback.Aggregate, error) {
+	where := []string{"repo_name = ?"}
+	args := []any{s.repoName}
+	if filter.EntityType != "" {
+		where = append(where, "entity_type = ?")
+		args = append(args, filter.EntityType)
+	}
+	if filter.EntityID != "" {
+		where = append(where, "entity_id = ?")
+		args = append(args, filter.EntityID)
+	}
+	if filter.Query != "" {
+		where = append(where, "query_hash = ?")
+		args = append(args, queryHash(filter.Query))
+	}
+	if filter.Source != "" {
+		where = append(where, "source = ?")
+		args = append(args, filter.Source)
+	}
+	if filter.Since != nil {
+		where = append(where, "created_at >= ?")
+		args = append(args, filter.Since.UTC())
+	}
+
+	groupBy := "entity_type, entity_id"
+	queryColumn := "''"
+	if filter.GroupByQuery {
+		groupBy += ", query_hash"
+		queryColumn = "MAX(query_text)"
+	}
+	order := "ASC"
+	if filter.BestFirst {
+		order = "DESC"
+	}
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = defaultFeedbackLimit
+	}
+	query := fmt.Sprintf(`
+		SELECT entity_type, entity_id, %s,
+			SUM(CASE WHEN vote > 0 THEN 1 ELSE 0 END) AS upvotes,
+			SUM(CASE WHEN vote < 0 THEN 1 ELSE 0 END) AS downvotes,
+			SUM(CASE WHEN comment IS NOT NULL AND comment <> '' THEN 1 ELSE 0 END) AS comments
+		FROM %s
+		WHERE %s
+		GROUP BY %s
+		ORDER BY SUM(vote) %s, COUNT(*) DESC, entity_type, entity_id
+		LIMIT %d OFFSET %d
+	`, queryColumn, s.tableName(), strings.Join(where, " AND "), groupBy, order, limit, max(filter.Offset, 0))
+
+	done := metrics.TimeStoreQuery(metrics.StoreDB, "query", s.repoName)
+	rows, err := s.db.QueryContext(s.ctx, s.dialect.Rebind(query), args...)
+	done(err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate feedback: %w", err)
+	}
+	defer rows.Close()
+
+	var result []feedback.Aggregate
+	for rows.Next() {
+		var a feedback.Aggregate
+		var queryText sql.NullString
+		if err := rows.Scan(&a.EntityType, &a.EntityID, &queryText, &a.Upvotes, &a.Downvotes, &a.Comments); err != nil {
+			return nil, fmt.Errorf("failed to scan feedback: %w", err)
+		}
+		a.Query = queryText.String
+		a.Score = a.Upvotes - a.Downvotes
+		result = append(result, a)
+	}
+	return result, rows.Err()
+}
+
+// nullString stores an empty string as NULL
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
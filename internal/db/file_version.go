@@ -1,31 +1,37 @@
 package db
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"regexp"
 	"strings"
 	"time"
 
+	"github.com/armchr/codeapi/internal/metrics"
+
 	"go.uber.org/zap"
 )
 
 // FileVersion represents a versioned file in the repository
 type FileVersion struct {
-	FileID       int32     `db:"file_id"`
-	FileSHA      string    `db:"file_sha"`
-	RelativePath string    `db:"relative_path"`
-	Ephemeral    bool      `db:"ephemeral"`
-	CommitID     *string   `db:"commit_id"`
-	Status       string    `db:"status"`
-	CreatedAt    time.Time `db:"created_at"`
-	UpdatedAt    time.Time `db:"updated_at"`
+	FileID       int32     `json:"file_id" db:"file_id"`
+	FileSHA      string    `json:"file_sha" db:"file_sha"`
+	RelativePath string    `json:"relative_path" db:"relative_path"`
+	Ephemeral    bool      `json:"ephemeral" db:"ephemeral"`
+	CommitID     *string   `json:"commit_id,omitempty" db:"commit_id"`
+	Status       string    `json:"status" db:"status"`
+	Sandbox      bool      `json:"sandbox" db:"sandbox"`
+	CreatedAt    time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at" db:"updated_at"`
 }
 
 // FileVersionRepository manages file version operations
 type FileVersionRepository struct {
 	db       *sql.DB
+	dialect  Dialect
 	repoName string
+	ctx      context.Context
 	logger   *zap.Logger
 }
 
@@ -53,7 +59,9 @@ func sanitizeTableName(repoName string) string {
 func NewFileVersionRepository(db *sql.DB, repoName string, logger *zap.Logger) (*FileVersionRepository, error) {
 	repo := &FileVersionRepository{
 		db:       db,
+		dialect:  dialectFor(db),
 		repoName: repoName,
+		ctx:      context.Background(),
 		logger:   logger,
 	}
 
@@ -65,79 +73,115 @@ func NewFileVersionRepository(db *sql.DB, repoName string, logger *zap.Logger) (
 	return repo, nil
 }
 
-// tableName returns the sanitized table name for this repository with backticks for SQL safety
+// NewFileVersionReader returns a repository for lookups that leaves the
+// schema alone: unlike NewFileVersionRepository it neither creates nor
+// migrates tables, so queries fail if the schema was never set up.
+func NewFileVersionReader(db *sql.DB, repoName string, logger *zap.Logger) *FileVersionRepository {
+	return &FileVersionRepository{
+		db:       db,
+		dialect:  dialectFor(db),
+		repoName: repoName,
+		ctx:      context.Background(),
+		logger:   logger,
+	}
+}
+
+// tableName returns the shared file_versions table quoted for the active dialect
 func (r *FileVersionRepository) tableName() string {
-	sanitized := sanitizeTableName(r.repoName)
-	return fmt.Sprintf("`%s_file_versions`", sanitized)
+	return r.dialect.QuoteIdent(FileVersionsTable)
 }
 
-// EnsureTable creates the file_versions table if it doesn't exist
-// and ensures all required columns are present (handles schema migrations)
-func (r *FileVersionRepository) EnsureTable() error {
-	tableName := r.tableName()
-	r.logger.Info("Ensuring file_versions table exists", zap.String("table", tableName))
+// WithContext returns a copy of the repository whose queries run under ctx,
+// so a cancelled or timed-out request also abandons its database calls
+func (r *FileVersionRepository) WithContext(ctx context.Context) *FileVersionRepository {
+	bound := *r
+	bound.ctx = ctx
+	return &bound
+}
 
-	// Create table if it doesn't exist
-	query := fmt.Sprintf(`
-		CREATE TABLE IF NOT EXISTS %s (
-			file_id INT AUTO_INCREMENT PRIMARY KEY,
-			file_sha VARCHAR(64) NOT NULL,
-			relative_path VARCHAR(512) NOT NULL,
-			ephemeral BOOLEAN NOT NULL DEFAULT FALSE,
-			commit_id VARCHAR(40),
-			status VARCHAR(255) NOT NULL DEFAULT 'processing',
-			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
-			UNIQUE KEY unique_sha_path_commit (file_sha, relative_path, commit_id),
-			INDEX idx_file_sha (file_sha),
-			INDEX idx_relative_path (relative_path),
-			INDEX idx_commit_id (commit_id),
-			INDEX idx_status (status)
-		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci
-	`, tableName)
+// DB returns the database the repository is stored in, for stores kept
+// next to it such as the audit log
+func (r *FileVersionRepository) DB() *sql.DB {
+	return r.db
+}
 
-	if _, err := r.db.Exec(query); err != nil {
-		return fmt.Errorf("failed to create table: %w", err)
-	}
+// Lock takes the lock of the repository for operation without waiting, see
+// LockRepo
+func (r *FileVersionRepository) Lock(operation string) (*RepoLock, error) {
+	return LockRepo(r.ctx, r.db, r.repoName, operation, 0)
+}
 
-	// Check if status column exists, add if missing (for existing tables)
-	// Extract the bare table name without backticks for information_schema query
-	bareTableName := strings.Trim(tableName, "`")
-	checkColumnQuery := fmt.Sprintf(`
-		SELECT COUNT(*)
-		FROM information_schema.COLUMNS
-		WHERE TABLE_SCHEMA = DATABASE()
-		AND TABLE_NAME = '%s'
-		AND COLUMN_NAME = 'status'
-	`, bareTableName)
+// exec, query and queryRow rebind ? placeholders for the active dialect
+func (r *FileVersionRepository) exec(query string, args ...any) (sql.Result, error) {
+	done := metrics.TimeStoreQuery(metrics.StoreDB, "exec", r.repoName)
+	result, err := r.db.ExecContext(r.ctx, r.dialect.Rebind(query), args...)
+	done(err)
+	return result, err
+}
 
-	var columnCount int
-	err := r.db.QueryRow(checkColumnQuery).Scan(&columnCount)
-	if err != nil {
-		return fmt.Errorf("failed to check for status column: %w", err)
-	}
+func (r *FileVersionRepository) query(query string, args ...any) (*sql.Rows, error) {
+	done := metrics.TimeStoreQuery(metrics.StoreDB, "query", r.repoName)
+	rows, err := r.db.QueryContext(r.ctx, r.dialect.Rebind(query), args...)
+	done(err)
+	return rows, err
+}
 
-	if columnCount == 0 {
-		r.logger.Info("Adding missing status column", zap.String("table", tableName))
-		alterQuery := fmt.Sprintf(`
-			ALTER TABLE %s
-			ADD COLUMN status VARCHAR(255) NOT NULL DEFAULT 'processing',
-			ADD INDEX idx_status (status)
-		`, tableName)
+func (r *FileVersionRepository) queryRow(query string, args ...any) *sql.Row {
+	done := metrics.TimeStoreQuery(metrics.StoreDB, "query_row", r.repoName)
+	row := r.db.QueryRowContext(r.ctx, r.dialect.Rebind(query), args...)
+	done(row.Err())
+	return row
+}
 
-		if _, err := r.db.Exec(alterQuery); err != nil {
-			return fmt.Errorf("failed to add status column: %w", err)
-		}
-		r.logger.Info("Status column added successfully", zap.String("table", tableName))
-	}
+// EnsureTable brings the shared file_versions tables up to the latest schema
+// version and moves this repository's legacy per-repo table into them
+func (r *FileVersionRepository) EnsureTable() error {
+	return EnsureFileVersionSchema(r.db, r.repoName, r.logger)
+}
 
-	r.logger.Info("Table ready", zap.String("table", tableName))
-	return nil
+// fileVersionColumns is the column list matching scanFileVersion
+const fileVersionColumns = "file_id, file_sha, relative_path, ephemeral, commit_id, status, sandbox, created_at, updated_at"
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// scanFileVersion scans a row selected with fileVersionColumns
+func scanFileVersion(row rowScanner) (*FileVersion, error) {
+	var fv FileVersion
+	err := row.Scan(
+		&fv.FileID,
+		&fv.FileSHA,
+		&fv.RelativePath,
+		&fv.Ephemeral,
+		&fv.CommitID,
+		&fv.Status,
+		&fv.Sandbox,
+		&fv.CreatedAt,
+		&fv.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &fv, nil
 }
 
 // GetOrCreateFileID retrieves existing FileID or creates a new one
 // This is the core method for FileID management
 func (r *FileVersionRepository) GetOrCreateFileID(fileSHA, relativePath string, ephemeral bool, commitID *string) (int32, error) {
+	return r.getOrCreateFileID(fileSHA, relativePath, ephemeral, commitID, false)
+}
+
+// GetOrCreateSandboxFileID retrieves or creates a FileID for ad-hoc file indexing.
+// New versions are created in the sandbox namespace, which is subject to TTL
+// cleanup. A matching version that already belongs to a regular index build is
+// reused as-is and never garbage collected.
+func (r *FileVersionRepository) GetOrCreateSandboxFileID(fileSHA, relativePath string) (int32, error) {
+	return r.getOrCreateFileID(fileSHA, relativePath, true, nil, true)
+}
+
+func (r *FileVersionRepository) getOrCreateFileID(fileSHA, relativePath string, ephemeral bool, commitID *string, sandbox bool) (int32, error) {
 	tableName := r.tableName()
 
 	// Try to find existing file version
@@ -148,6 +192,13 @@ func (r *FileVersionRepository) GetOrCreateFileID(fileSHA, relativePath string,
 			zap.Int32("file_id", existing.FileID),
 			zap.String("sha", fileSHA),
 			zap.String("path", relativePath))
+
+		// A regular build adopting a sandbox version takes it out of TTL cleanup
+		if existing.Sandbox && !sandbox {
+			if err := r.promoteSandboxVersion(existing.FileID); err != nil {
+				return 0, err
+			}
+		}
 		return existing.FileID, nil
 	}
 
@@ -159,30 +210,90 @@ func (r *FileVersionRepository) GetOrCreateFileID(fileSHA, relativePath string,
 	r.logger.Debug("Creating new FileID",
 		zap.String("sha", fileSHA),
 		zap.String("path", relativePath),
-		zap.Bool("ephemeral", ephemeral))
+		zap.Bool("ephemeral", ephemeral),
+		zap.Bool("sandbox", sandbox))
 
 	query := fmt.Sprintf(`
-		INSERT INTO %s (file_sha, relative_path, ephemeral, commit_id)
-		VALUES (?, ?, ?, ?)
+		INSERT INTO %s (repo_name, file_id, file_sha, relative_path, ephemeral, commit_id, sandbox)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
 	`, tableName)
 
-	result, err := r.db.Exec(query, fileSHA, relativePath, ephemeral, commitID)
+	tx, err := r.db.BeginTx(r.ctx, nil)
 	if err != nil {
-		return 0, fmt.Errorf("failed to insert file version: %w", err)
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
 	}
+	defer tx.Rollback()
 
-	fileID, err := result.LastInsertId()
+	fileID, err := r.nextFileID(tx)
 	if err != nil {
-		return 0, fmt.Errorf("failed to get last insert ID: %w", err)
+		return 0, err
+	}
+	if _, err := tx.ExecContext(r.ctx, r.dialect.Rebind(query), r.repoName, fileID, fileSHA, relativePath, ephemeral, commitID, sandbox); err != nil {
+		return 0, fmt.Errorf("failed to insert file version: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit file version: %w", err)
 	}
 
 	r.logger.Info("Created new FileID",
-		zap.Int32("file_id", int32(fileID)),
+		zap.Int32("file_id", fileID),
 		zap.String("sha", fileSHA),
 		zap.String("path", relativePath),
 		zap.Bool("ephemeral", ephemeral))
 
-	return int32(fileID), nil
+	return fileID, nil
+}
+
+// nextFileID reserves the next FileID of the repository inside tx. The
+// UPDATE locks the sequence row until tx ends, so concurrent builders never
+// receive the same ID.
+func (r *FileVersionRepository) nextFileID(tx *sql.Tx) (int32, error) {
+	seqTable := r.dialect.QuoteIdent(FileIDSequencesTable)
+
+	update := fmt.Sprintf(`UPDATE %s SET last_id = last_id + 1 WHERE repo_name = ?`, seqTable)
+	advanced, err := execAffected(r.ctx, tx, r.dialect.Rebind(update), r.repoName)
+	if err != nil {
+		return 0, fmt.Errorf("failed to advance FileID sequence: %w", err)
+	}
+
+	if !advanced {
+		// First FileID of the repository. Concurrent builders may race to
+		// create the row, so tolerate an existing one and advance again.
+		insert := fmt.Sprintf(`INSERT INTO %s (repo_name, last_id) VALUES (?, 0) %s`,
+			seqTable, r.dialect.ConflictDoNothing([]string{"repo_name"}))
+		if _, err := tx.ExecContext(r.ctx, r.dialect.Rebind(insert), r.repoName); err != nil {
+			return 0, fmt.Errorf("failed to start FileID sequence: %w", err)
+		}
+		if _, err := tx.ExecContext(r.ctx, r.dialect.Rebind(update), r.repoName); err != nil {
+			return 0, fmt.Errorf("failed to advance FileID sequence: %w", err)
+		}
+	}
+
+	var fileID int32
+	query := fmt.Sprintf(`SELECT last_id FROM %s WHERE repo_name = ?`, seqTable)
+	if err := tx.QueryRowContext(r.ctx, r.dialect.Rebind(query), r.repoName).Scan(&fileID); err != nil {
+		return 0, fmt.Errorf("failed to read FileID sequence: %w", err)
+	}
+	return fileID, nil
+}
+
+// execAffected runs a statement and reports whether it changed any row
+func execAffected(ctx context.Context, tx *sql.Tx, query string, args ...any) (bool, error) {
+	result, err := tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+// promoteSandboxVersion moves a sandbox file version into the regular index
+func (r *FileVersionRepository) promoteSandboxVersion(fileID int32) error {
+	query := fmt.Sprintf(`UPDATE %s SET sandbox = FALSE, updated_at = CURRENT_TIMESTAMP WHERE repo_name = ? AND file_id = ?`, r.tableName())
+	if _, err := r.exec(query, r.repoName, fileID); err != nil {
+		return fmt.Errorf("failed to promote sandbox version %d: %w", fileID, err)
+	}
+	return nil
 }
 
 // findFileVersion finds a file version by SHA, path, and commit
@@ -190,29 +301,19 @@ func (r *FileVersionRepository) findFileVersion(fileSHA, relativePath string, co
 	tableName := r.tableName()
 
 	query := fmt.Sprintf(`
-		SELECT file_id, file_sha, relative_path, ephemeral, commit_id, status, created_at, updated_at
+		SELECT %s
 		FROM %s
-		WHERE file_sha = ? AND relative_path = ? AND commit_id <=> ?
+		WHERE repo_name = ? AND file_sha = ? AND relative_path = ? AND %s
 		LIMIT 1
-	`, tableName)
+	`, fileVersionColumns, tableName, r.dialect.NullSafeEqual("commit_id"))
 
-	var fv FileVersion
-	err := r.db.QueryRow(query, fileSHA, relativePath, commitID).Scan(
-		&fv.FileID,
-		&fv.FileSHA,
-		&fv.RelativePath,
-		&fv.Ephemeral,
-		&fv.CommitID,
-		&fv.Status,
-		&fv.CreatedAt,
-		&fv.UpdatedAt,
-	)
-
-	if err != nil {
-		return nil, err
-	}
+	return scanFileVersion(r.queryRow(query, r.repoName, fileSHA, relativePath, commitID))
+}
 
-	return &fv, nil
+// FindFileVersion returns the version matching a file's SHA, path and commit,
+// or sql.ErrNoRows if it was never indexed
+func (r *FileVersionRepository) FindFileVersion(fileSHA, relativePath string, commitID *string) (*FileVersion, error) {
+	return r.findFileVersion(fileSHA, relativePath, commitID)
 }
 
 // GetFileByID retrieves a file version by its ID
@@ -220,28 +321,12 @@ func (r *FileVersionRepository) GetFileByID(fileID int32) (*FileVersion, error)
 	tableName := r.tableName()
 
 	query := fmt.Sprintf(`
-		SELECT file_id, file_sha, relative_path, ephemeral, commit_id, status, created_at, updated_at
+		SELECT %s
 		FROM %s
-		WHERE file_id = ?
-	`, tableName)
+		WHERE repo_name = ? AND file_id = ?
+	`, fileVersionColumns, tableName)
 
-	var fv FileVersion
-	err := r.db.QueryRow(query, fileID).Scan(
-		&fv.FileID,
-		&fv.FileSHA,
-		&fv.RelativePath,
-		&fv.Ephemeral,
-		&fv.CommitID,
-		&fv.Status,
-		&fv.CreatedAt,
-		&fv.UpdatedAt,
-	)
-
-	if err != nil {
-		return nil, err
-	}
-
-	return &fv, nil
+	return scanFileVersion(r.queryRow(query, r.repoName, fileID))
 }
 
 // GetFilesBySHA retrieves all file versions with a specific SHA
@@ -249,13 +334,13 @@ func (r *FileVersionRepository) GetFilesBySHA(fileSHA string) ([]*FileVersion, e
 	tableName := r.tableName()
 
 	query := fmt.Sprintf(`
-		SELECT file_id, file_sha, relative_path, ephemeral, commit_id, status, created_at, updated_at
+		SELECT %s
 		FROM %s
-		WHERE file_sha = ?
+		WHERE repo_name = ? AND file_sha = ?
 		ORDER BY created_at DESC
-	`, tableName)
+	`, fileVersionColumns, tableName)
 
-	rows, err := r.db.Query(query, fileSHA)
+	rows, err := r.query(query, r.repoName, fileSHA)
 	if err != nil {
 		return nil, err
 	}
@@ -263,21 +348,11 @@ func (r *FileVersionRepository) GetFilesBySHA(fileSHA string) ([]*FileVersion, e
 
 	var files []*FileVersion
 	for rows.Next() {
-		var fv FileVersion
-		err := rows.Scan(
-			&fv.FileID,
-			&fv.FileSHA,
-			&fv.RelativePath,
-			&fv.Ephemeral,
-			&fv.CommitID,
-			&fv.Status,
-			&fv.CreatedAt,
-			&fv.UpdatedAt,
-		)
+		fv, err := scanFileVersion(rows)
 		if err != nil {
 			return nil, err
 		}
-		files = append(files, &fv)
+		files = append(files, fv)
 	}
 
 	return files, rows.Err()
@@ -288,13 +363,13 @@ func (r *FileVersionRepository) GetFilesByPath(relativePath string) ([]*FileVers
 	tableName := r.tableName()
 
 	query := fmt.Sprintf(`
-		SELECT file_id, file_sha, relative_path, ephemeral, commit_id, status, created_at, updated_at
+		SELECT %s
 		FROM %s
-		WHERE relative_path = ?
+		WHERE repo_name = ? AND relative_path = ?
 		ORDER BY created_at DESC
-	`, tableName)
+	`, fileVersionColumns, tableName)
 
-	rows, err := r.db.Query(query, relativePath)
+	rows, err := r.query(query, r.repoName, relativePath)
 	if err != nil {
 		return nil, err
 	}
@@ -302,21 +377,11 @@ func (r *FileVersionRepository) GetFilesByPath(relativePath string) ([]*FileVers
 
 	var files []*FileVersion
 	for rows.Next() {
-		var fv FileVersion
-		err := rows.Scan(
-			&fv.FileID,
-			&fv.FileSHA,
-			&fv.RelativePath,
-			&fv.Ephemeral,
-			&fv.CommitID,
-			&fv.Status,
-			&fv.CreatedAt,
-			&fv.UpdatedAt,
-		)
+		fv, err := scanFileVersion(rows)
 		if err != nil {
 			return nil, err
 		}
-		files = append(files, &fv)
+		files = append(files, fv)
 	}
 
 	return files, rows.Err()
@@ -330,10 +395,10 @@ func (r *FileVersionRepository) DeleteEphemeralVersions() (int64, error) {
 
 	query := fmt.Sprintf(`
 		DELETE FROM %s
-		WHERE ephemeral = TRUE
+		WHERE repo_name = ? AND ephemeral = TRUE
 	`, tableName)
 
-	result, err := r.db.Exec(query)
+	result, err := r.exec(query, r.repoName)
 	if err != nil {
 		return 0, fmt.Errorf("failed to delete ephemeral versions: %w", err)
 	}
@@ -350,17 +415,234 @@ func (r *FileVersionRepository) DeleteEphemeralVersions() (int64, error) {
 	return rowsAffected, nil
 }
 
+// GetSandboxVersions returns sandbox file versions last touched before cutoff.
+// Pass a zero cutoff to return every sandbox version.
+func (r *FileVersionRepository) GetSandboxVersions(cutoff time.Time) ([]*FileVersion, error) {
+	tableName := r.tableName()
+
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM %s
+		WHERE repo_name = ? AND sandbox = TRUE AND (? OR updated_at < ?)
+		ORDER BY updated_at
+	`, fileVersionColumns, tableName)
+
+	rows, err := r.query(query, r.repoName, cutoff.IsZero(), cutoff.UTC())
+	if err != nil {
+		return nil, fmt.Errorf("failed to query sandbox versions: %w", err)
+	}
+	defer rows.Close()
+
+	var files []*FileVersion
+	for rows.Next() {
+		fv, err := scanFileVersion(rows)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, fv)
+	}
+
+	return files, rows.Err()
+}
+
+// GetVersionsBeyondRetention returns the file versions that fall outside a
+// keep-last-N retention policy. Versions are ranked per relative path by
+// file_id (newest first); everything past the first keep entries is returned.
+// Sandbox versions are excluded since they have their own TTL-based cleanup.
+func (r *FileVersionRepository) GetVersionsBeyondRetention(keep int) ([]*FileVersion, error) {
+	if keep < 1 {
+		return nil, fmt.Errorf("retention must keep at least one version, got %d", keep)
+	}
+
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM (
+			SELECT *, ROW_NUMBER() OVER (PARTITION BY relative_path ORDER BY file_id DESC) AS version_rank
+			FROM %s
+			WHERE repo_name = ? AND sandbox = FALSE
+		) ranked
+		WHERE version_rank > ?
+		ORDER BY relative_path, file_id
+	`, fileVersionColumns, r.tableName())
+
+	rows, err := r.query(query, r.repoName, keep)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query versions beyond retention: %w", err)
+	}
+	defer rows.Close()
+
+	var files []*FileVersion
+	for rows.Next() {
+		fv, err := scanFileVersion(rows)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, fv)
+	}
+
+	return files, rows.Err()
+}
+
+// GetRecentVersions returns the newest version of the limit paths indexed
+// most recently, most recent first. Sandbox and ephemeral versions are left
+// out.
+func (r *FileVersionRepository) GetRecentVersions(limit int) ([]*FileVersion, error) {
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM (
+			SELECT *, ROW_NUMBER() OVER (PARTITION BY relative_path ORDER BY file_id DESC) AS version_rank
+			FROM %s
+			WHERE repo_name = ? AND sandbox = FALSE AND ephemeral = FALSE
+		) ranked
+		WHERE version_rank = 1
+		ORDER BY updated_at DESC, file_id DESC
+		LIMIT ?
+	`, fileVersionColumns, r.tableName())
+
+	rows, err := r.query(query, r.repoName, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query recent versions: %w", err)
+	}
+	defer rows.Close()
+
+	var files []*FileVersion
+	for rows.Next() {
+		fv, err := scanFileVersion(rows)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, fv)
+	}
+
+	return files, rows.Err()
+}
+
+// GetAllFileIDs returns the IDs of every tracked file version
+func (r *FileVersionRepository) GetAllFileIDs() ([]int32, error) {
+	rows, err := r.query(fmt.Sprintf(`SELECT file_id FROM %s WHERE repo_name = ?`, r.tableName()), r.repoName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query file IDs: %w", err)
+	}
+	defer rows.Close()
+
+	var fileIDs []int32
+	for rows.Next() {
+		var id int32
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan file ID: %w", err)
+		}
+		fileIDs = append(fileIDs, id)
+	}
+
+	return fileIDs, rows.Err()
+}
+
+// GetAllVersions returns every file version of the repository ordered by FileID
+func (r *FileVersionRepository) GetAllVersions() ([]*FileVersion, error) {
+	query := fmt.Sprintf(`SELECT %s FROM %s WHERE repo_name = ? ORDER BY file_id`, fileVersionColumns, r.tableName())
+	rows, err := r.query(query, r.repoName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query file versions: %w", err)
+	}
+	defer rows.Close()
+
+	var files []*FileVersion
+	for rows.Next() {
+		fv, err := scanFileVersion(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan file version: %w", err)
+		}
+		files = append(files, fv)
+	}
+
+	return files, rows.Err()
+}
+
+// LastFileID returns the last FileID handed out for the repository, or 0
+// when none has been
+func (r *FileVersionRepository) LastFileID() (int32, error) {
+	var lastID int32
+	query := fmt.Sprintf(`SELECT last_id FROM %s WHERE repo_name = ?`, r.dialect.QuoteIdent(FileIDSequencesTable))
+	err := r.queryRow(query, r.repoName).Scan(&lastID)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to read FileID sequence: %w", err)
+	}
+	return lastID, nil
+}
+
+// RestoreVersions inserts file versions with their original FileIDs and sets
+// the FileID sequence to lastID, so IDs referenced by the code graph and
+// vector store stay valid and new files never reuse them. The repository is
+// expected to have no file versions yet.
+func (r *FileVersionRepository) RestoreVersions(versions []*FileVersion, lastID int32) error {
+	tx, err := r.db.BeginTx(r.ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	insert := r.dialect.Rebind(fmt.Sprintf(`INSERT INTO %s (repo_name, %s) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		r.tableName(), fileVersionColumns))
+	for _, fv := range versions {
+		if _, err := tx.ExecContext(r.ctx, insert, r.repoName, fv.FileID, fv.FileSHA, fv.RelativePath,
+			fv.Ephemeral, fv.CommitID, fv.Status, fv.Sandbox, fv.CreatedAt, fv.UpdatedAt); err != nil {
+			return fmt.Errorf("failed to restore file version %d: %w", fv.FileID, err)
+		}
+		lastID = max(lastID, fv.FileID)
+	}
+
+	seqTable := r.dialect.QuoteIdent(FileIDSequencesTable)
+	if _, err := tx.ExecContext(r.ctx, r.dialect.Rebind(fmt.Sprintf(`DELETE FROM %s WHERE repo_name = ?`, seqTable)), r.repoName); err != nil {
+		return fmt.Errorf("failed to reset FileID sequence: %w", err)
+	}
+	if _, err := tx.ExecContext(r.ctx, r.dialect.Rebind(fmt.Sprintf(`INSERT INTO %s (repo_name, last_id) VALUES (?, ?)`, seqTable)), r.repoName, lastID); err != nil {
+		return fmt.Errorf("failed to set FileID sequence: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to restore file versions of %s: %w", r.repoName, err)
+	}
+	return nil
+}
+
+// DeleteVersions deletes the given file versions by ID
+func (r *FileVersionRepository) DeleteVersions(fileIDs []int32) (int64, error) {
+	if len(fileIDs) == 0 {
+		return 0, nil
+	}
+
+	placeholders := make([]string, len(fileIDs))
+	args := make([]any, 0, len(fileIDs)+1)
+	args = append(args, r.repoName)
+	for i, id := range fileIDs {
+		placeholders[i] = "?"
+		args = append(args, id)
+	}
+
+	query := fmt.Sprintf(`DELETE FROM %s WHERE repo_name = ? AND file_id IN (%s)`, r.tableName(), strings.Join(placeholders, ", "))
+
+	result, err := r.exec(query, args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete file versions: %w", err)
+	}
+
+	return result.RowsAffected()
+}
+
 // UpdateStatus updates the processing status of a file version
 func (r *FileVersionRepository) UpdateStatus(fileID int32, status string) error {
 	tableName := r.tableName()
 
 	query := fmt.Sprintf(`
 		UPDATE %s
-		SET status = ?
-		WHERE file_id = ?
+		SET status = ?, updated_at = CURRENT_TIMESTAMP
+		WHERE repo_name = ? AND file_id = ?
 	`, tableName)
 
-	_, err := r.db.Exec(query, status, fileID)
+	_, err := r.exec(query, status, r.repoName, fileID)
 	if err != nil {
 		return fmt.Errorf("failed to update status: %w", err)
 	}
@@ -379,29 +661,54 @@ func (r *FileVersionRepository) GetStats() (total int64, ephemeral int64, commit
 	query := fmt.Sprintf(`
 		SELECT
 			COUNT(*) as total,
-			SUM(CASE WHEN ephemeral = TRUE THEN 1 ELSE 0 END) as ephemeral,
-			SUM(CASE WHEN ephemeral = FALSE THEN 1 ELSE 0 END) as committed
+			COALESCE(SUM(CASE WHEN ephemeral = TRUE THEN 1 ELSE 0 END), 0) as ephemeral,
+			COALESCE(SUM(CASE WHEN ephemeral = FALSE THEN 1 ELSE 0 END), 0) as committed
 		FROM %s
+		WHERE repo_name = ?
 	`, tableName)
 
-	err = r.db.QueryRow(query).Scan(&total, &ephemeral, &committed)
+	err = r.queryRow(query, r.repoName).Scan(&total, &ephemeral, &committed)
 	return
 }
 
-// DropTable drops the file_versions table for this repository.
-// This permanently deletes all file version tracking data for the repository.
-func (r *FileVersionRepository) DropTable() error {
-	tableName := r.tableName()
-
-	r.logger.Info("Dropping file versions table", zap.String("table", tableName))
+// LastIndexedAt returns when a file version of the repository was last
+// created or updated, or nil when the repository has none
+func (r *FileVersionRepository) LastIndexedAt() (*time.Time, error) {
+	var updatedAt time.Time
+	query := fmt.Sprintf(`SELECT updated_at FROM %s WHERE repo_name = ? ORDER BY updated_at DESC LIMIT 1`, r.tableName())
+	err := r.queryRow(query, r.repoName).Scan(&updatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read last update: %w", err)
+	}
+	return &updatedAt, nil
+}
 
-	query := fmt.Sprintf(`DROP TABLE IF EXISTS %s`, tableName)
+// DeleteRepository removes every file version of this repository and resets
+// its FileID sequence. This permanently deletes all file version tracking data
+// for the repository.
+func (r *FileVersionRepository) DeleteRepository() error {
+	r.logger.Info("Deleting file versions", zap.String("repo_name", r.repoName))
 
-	_, err := r.db.Exec(query)
+	tx, err := r.db.BeginTx(r.ctx, nil)
 	if err != nil {
-		return fmt.Errorf("failed to drop table %s: %w", tableName, err)
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, table := range []string{FileVersionsTable, FileIDSequencesTable} {
+		query := fmt.Sprintf(`DELETE FROM %s WHERE repo_name = ?`, r.dialect.QuoteIdent(table))
+		if _, err := tx.ExecContext(r.ctx, r.dialect.Rebind(query), r.repoName); err != nil {
+			return fmt.Errorf("failed to delete from %s: %w", table, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to delete file versions of %s: %w", r.repoName, err)
 	}
 
-	r.logger.Info("File versions table dropped successfully", zap.String("table", tableName))
+	r.logger.Info("File versions deleted successfully", zap.String("repo_name", r.repoName))
 	return nil
 }
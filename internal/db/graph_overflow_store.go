@@ -0,0 +1,129 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/armchr/codeapi/internal/metrics"
+	"github.com/armchr/codeapi/internal/service/codegraph"
+	"go.uber.org/zap"
+)
+
+// overflowBatch bounds the rows written and the node IDs matched by a single
+// statement
+const overflowBatch = 500
+
+// GraphOverflowStore holds the code graph metadata values that exceed the
+// configured property limits in the shared graph_property_overflow table.
+// It serves every repository, since node IDs do not repeat across them.
+type GraphOverflowStore struct {
+	db      *sql.DB
+	dialect Dialect
+	logger  *zap.Logger
+}
+
+var _ codegraph.OverflowStore = (*GraphOverflowStore)(nil)
+
+// NewGraphOverflowStore creates the overflow store, migrating its table first
+func NewGraphOverflowStore(db *sql.DB, logger *zap.Logger) (*GraphOverflowStore, error) {
+	if err := EnsureGraphOverflowSchema(db, logger); err != nil {
+		return nil, fmt.Errorf("failed to ensure table: %w", err)
+	}
+	return NewGraphOverflowReader(db, logger), nil
+}
+
+// NewGraphOverflowReader returns an overflow store that does not create or
+// migrate the table, for read-only use
+func NewGraphOverflowReader(db *sql.DB, logger *zap.Logger) *GraphOverflowStore {
+	return &GraphOverflowStore{db: db, dialect: dialectFor(db), logger: logger}
+}
+
+func (s *GraphOverflowStore) tableName() string {
+	return s.dialect.QuoteIdent(GraphOverflowTable)
+}
+
+// SaveOverflow stores values, replacing those already stored for the same
+// node, relationship and key
+func (s *GraphOverflowStore) SaveOverflow(ctx context.Context, values []codegraph.OverflowValue) error {
+	upsert := s.dialect.UpsertClause([]string{"node_id", "edge", "prop_key"}, []string{"prop_value"})
+	for start := 0; start < len(values); start += overflowBatch {
+		batch := values[start:min(start+overflowBatch, len(values))]
+		placeholders := make([]string, len(batch))
+		args := make([]any, 0, len(batch)*4)
+		for i, v := range batch {
+			placeholders[i] = "(?, ?, ?, ?)"
+			args = append(args, v.OwnerID, v.Edge, v.Key, v.Value)
+		}
+		query := fmt.Sprintf(`INSERT INTO %s (node_id, edge, prop_key, prop_value) VALUES %s %s`,
+			s.tableName(), strings.Join(placeholders, ", "), upsert)
+
+		done := metrics.TimeStoreQuery(metrics.StoreDB, "save_overflow", "")
+		_, err := s.db.ExecContext(ctx, s.dialect.Rebind(query), args...)
+		done(err)
+		if err != nil {
+			return fmt.Errorf("failed to save overflow values: %w", err)
+		}
+	}
+	return nil
+}
+
+// LoadOverflow returns the values stored for the given nodes and the
+// relationships leaving them
+func (s *GraphOverflowStore) LoadOverflow(ctx context.Context, ownerIDs []int64) ([]codegraph.OverflowValue, error) {
+	var values []codegraph.OverflowValue
+	for start := 0; start < len(ownerIDs); start += overflowBatch {
+		batch := ownerIDs[start:min(start+overflowBatch, len(ownerIDs))]
+		in, args := int64InClause(batch)
+		query := fmt.Sprintf(`SELECT node_id, edge, prop_key, prop_value FROM %s WHERE node_id IN %s`, s.tableName(), in)
+
+		done := metrics.TimeStoreQuery(metrics.StoreDB, "load_overflow", "")
+		rows, err := s.db.QueryContext(ctx, s.dialect.Rebind(query), args...)
+		done(err)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load overflow values: %w", err)
+		}
+		for rows.Next() {
+			var v codegraph.OverflowValue
+			if err := rows.Scan(&v.OwnerID, &v.Edge, &v.Key, &v.Value); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("failed to scan overflow value: %w", err)
+			}
+			values = append(values, v)
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read overflow values: %w", err)
+		}
+	}
+	return values, nil
+}
+
+// DeleteOverflow deletes the values of the given nodes and the
+// relationships leaving them
+func (s *GraphOverflowStore) DeleteOverflow(ctx context.Context, ownerIDs []int64) error {
+	for start := 0; start < len(ownerIDs); start += overflowBatch {
+		batch := ownerIDs[start:min(start+overflowBatch, len(ownerIDs))]
+		in, args := int64InClause(batch)
+		query := fmt.Sprintf(`DELETE FROM %s WHERE node_id IN %s`, s.tableName(), in)
+
+		done := metrics.TimeStoreQuery(metrics.StoreDB, "delete_overflow", "")
+		_, err := s.db.ExecContext(ctx, s.dialect.Rebind(query), args...)
+		done(err)
+		if err != nil {
+			return fmt.Errorf("failed to delete overflow values: %w", err)
+		}
+	}
+	return nil
+}
+
+// int64InClause returns "(?, ?, ...)" for ids and the ids as arguments
+func int64InClause(ids []int64) (string, []any) {
+	args := make([]any, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+	return "(" + strings.TrimSuffix(strings.Repeat("?, ", len(ids)), ", ") + ")", args
+}
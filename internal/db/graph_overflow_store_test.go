@@ -0,0 +1,84 @@
+package db
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/armchr/codeapi/internal/service/codegraph"
+	"go.uber.org/zap"
+)
+
+func TestGraphOverflowStore(t *testing.T) {
+	ctx := context.Background()
+	conn := newTestSQLite(t)
+	store, err := NewGraphOverflowStore(conn.GetDB(), zap.NewNop())
+	if err != nil {
+		t.Fatalf("NewGraphOverflowStore: %v", err)
+	}
+
+	large := `"` + strings.Repeat("x", 100000) + `"`
+	values := []codegraph.OverflowValue{
+		{OwnerID: 1, Key: "doc", Value: `"old"`},
+		{OwnerID: 1, Edge: "CALLS->2", Key: "args", Value: `["a"]`},
+		{OwnerID: 2, Key: "doc", Value: large},
+	}
+	// Spread over several statements
+	for i := int64(0); i < overflowBatch; i++ {
+		values = append(values, codegraph.OverflowValue{OwnerID: 1000 + i, Key: "doc", Value: `"v"`})
+	}
+	if err := store.SaveOverflow(ctx, values); err != nil {
+		t.Fatalf("SaveOverflow: %v", err)
+	}
+	if err := store.SaveOverflow(ctx, []codegraph.OverflowValue{{OwnerID: 1, Key: "doc", Value: `"new"`}}); err != nil {
+		t.Fatalf("SaveOverflow replacing a value: %v", err)
+	}
+
+	load := func(ids ...int64) []codegraph.OverflowValue {
+		t.Helper()
+		got, err := store.LoadOverflow(ctx, ids)
+		if err != nil {
+			t.Fatalf("LoadOverflow: %v", err)
+		}
+		sort.Slice(got, func(i, j int) bool {
+			if got[i].OwnerID != got[j].OwnerID {
+				return got[i].OwnerID < got[j].OwnerID
+			}
+			return got[i].Edge < got[j].Edge
+		})
+		return got
+	}
+
+	got := load(1, 2, 3)
+	want := []codegraph.OverflowValue{
+		{OwnerID: 1, Key: "doc", Value: `"new"`},
+		{OwnerID: 1, Edge: "CALLS->2", Key: "args", Value: `["a"]`},
+		{OwnerID: 2, Key: "doc", Value: large},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("loaded %d values, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("value %d = node %d %q %s (%d bytes), want node %d %q %s (%d bytes)", i,
+				got[i].OwnerID, got[i].Edge, got[i].Key, len(got[i].Value),
+				want[i].OwnerID, want[i].Edge, want[i].Key, len(want[i].Value))
+		}
+	}
+
+	ids := make([]int64, overflowBatch+1)
+	for i := range ids {
+		ids[i] = 1000 + int64(i)
+	}
+	if n := len(load(ids...)); n != overflowBatch {
+		t.Errorf("loaded %d values across batches, want %d", n, overflowBatch)
+	}
+
+	if err := store.DeleteOverflow(ctx, append(ids, 1)); err != nil {
+		t.Fatalf("DeleteOverflow: %v", err)
+	}
+	if got := load(append(ids, 1, 2)...); len(got) != 1 || got[0].OwnerID != 2 {
+		t.Errorf("after delete loaded %d values, want only node 2's", len(got))
+	}
+}
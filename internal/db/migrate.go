@@ -0,0 +1,281 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+// migrationsTable records which schema versions have been applied to each table
+const migrationsTable = "schema_migrations"
+
+// Migration is a versioned schema change for one table. Up receives a
+// SchemaEditor bound to the target table and must be idempotent: MySQL
+// commits DDL implicitly, so a migration interrupted half way is re-run from
+// the start, and tables created before versioning existed are brought under
+// it by replaying every migration.
+type Migration struct {
+	Version     int
+	Description string
+	Up          func(e *SchemaEditor) error
+}
+
+// MigrationStatus describes the schema version of one table
+type MigrationStatus struct {
+	Table          string `json:"table"`
+	CurrentVersion int    `json:"current_version"`
+	LatestVersion  int    `json:"latest_version"`
+	Pending        []int  `json:"pending,omitempty"`
+}
+
+// SchemaEditor runs DDL against a single table using the active dialect
+type SchemaEditor struct {
+	db      *sql.DB
+	dialect Dialect
+	table   string
+	logger  *zap.Logger
+}
+
+// Table returns the unquoted name of the table being migrated
+func (e *SchemaEditor) Table() string {
+	return e.table
+}
+
+// Dialect returns the SQL dialect of the database being migrated
+func (e *SchemaEditor) Dialect() Dialect {
+	return e.dialect
+}
+
+// CreateTable creates the table and its indexes if they do not exist
+func (e *SchemaEditor) CreateTable(columns []string, indexes []IndexDef) error {
+	for _, query := range createTableStatements(e.dialect, e.table, columns, indexes) {
+		if _, err := e.db.Exec(query); err != nil {
+			return fmt.Errorf("failed to create table %s: %w", e.table, err)
+		}
+	}
+	return nil
+}
+
+// HasColumn reports whether the table already has column
+func (e *SchemaEditor) HasColumn(column string) (bool, error) {
+	var count int
+	err := e.db.QueryRow(e.dialect.Rebind(e.dialect.ColumnExistsQuery()), e.table, column).Scan(&count)
+	if err != nil {
+		return false, fmt.Errorf("failed to check for %s column: %w", column, err)
+	}
+	return count > 0, nil
+}
+
+// AddColumn adds a column with an index on it unless the column already exists
+func (e *SchemaEditor) AddColumn(column, definition, indexName string) error {
+	exists, err := e.HasColumn(column)
+	if err != nil || exists {
+		return err
+	}
+
+	e.logger.Info("Adding missing column", zap.String("table", e.table), zap.String("column", column))
+
+	quoted := e.dialect.QuoteIdent(e.table)
+	statements := []string{fmt.Sprintf(`ALTER TABLE %s ADD COLUMN %s %s`, quoted, column, definition)}
+	if e.dialect.InlineIndexes() {
+		statements[0] += fmt.Sprintf(", ADD INDEX %s (%s)", indexName, column)
+	} else {
+		statements = append(statements, createIndexStatement(e.dialect, e.table, IndexDef{Name: indexName, Columns: column}))
+	}
+
+	for _, query := range statements {
+		if _, err := e.db.Exec(query); err != nil {
+			return fmt.Errorf("failed to add %s column: %w", column, err)
+		}
+	}
+	return nil
+}
+
+// statement is a parameterized SQL statement written with ? placeholders
+type statement struct {
+	query string
+	args  []any
+}
+
+// stmt builds a statement for SchemaEditor.InTx
+func stmt(query string, args ...any) statement {
+	return statement{query: query, args: args}
+}
+
+// InTx runs data-moving statements in a single transaction
+func (e *SchemaEditor) InTx(statements ...statement) error {
+	tx, err := e.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	for _, st := range statements {
+		if _, err := tx.Exec(e.dialect.Rebind(st.query), st.args...); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// Migrator applies versioned migrations and tracks them in schema_migrations
+type Migrator struct {
+	db      *sql.DB
+	dialect Dialect
+	logger  *zap.Logger
+}
+
+// NewMigrator creates a migrator for db, picking the dialect from its driver
+func NewMigrator(db *sql.DB, logger *zap.Logger) *Migrator {
+	return &Migrator{
+		db:      db,
+		dialect: dialectFor(db),
+		logger:  logger,
+	}
+}
+
+// migratedTables caches tables already brought up to date by this process so
+// stores created per request skip the version lookup
+var migratedTables sync.Map
+
+// ensureMigrationsTable creates the schema_migrations table if needed
+func (m *Migrator) ensureMigrationsTable() error {
+	statements := createTableStatements(m.dialect, migrationsTable,
+		[]string{
+			"table_name VARCHAR(255) NOT NULL",
+			"version INT NOT NULL",
+			"description VARCHAR(255)",
+			"applied_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP",
+			"PRIMARY KEY (table_name, version)",
+		}, nil)
+
+	for _, query := range statements {
+		if _, err := m.db.Exec(query); err != nil {
+			return fmt.Errorf("failed to create %s table: %w", migrationsTable, err)
+		}
+	}
+	return nil
+}
+
+// appliedVersions returns the versions recorded for table
+func (m *Migrator) appliedVersions(table string) (map[int]bool, error) {
+	query := fmt.Sprintf(`SELECT version FROM %s WHERE table_name = ?`, m.dialect.QuoteIdent(migrationsTable))
+	rows, err := m.db.Query(m.dialect.Rebind(query), table)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read applied migrations for %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	applied := make(map[int]bool)
+	for rows.Next() {
+		var version int
+		if err := rows.Scan(&version); err != nil {
+			return nil, fmt.Errorf("failed to scan migration version: %w", err)
+		}
+		applied[version] = true
+	}
+	return applied, rows.Err()
+}
+
+// Status reports the current and pending versions of table
+func (m *Migrator) Status(table string, migrations []Migration) (*MigrationStatus, error) {
+	if err := m.ensureMigrationsTable(); err != nil {
+		return nil, err
+	}
+
+	applied, err := m.appliedVersions(table)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &MigrationStatus{Table: table}
+	sorted := append([]Migration{}, migrations...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Version < sorted[j].Version })
+	migrations = sorted
+
+	for _, mig := range migrations {
+		if mig.Version > status.LatestVersion {
+			status.LatestVersion = mig.Version
+		}
+		if applied[mig.Version] {
+			if mig.Version > status.CurrentVersion {
+				status.CurrentVersion = mig.Version
+			}
+		} else {
+			status.Pending = append(status.Pending, mig.Version)
+		}
+	}
+	return status, nil
+}
+
+// Migrate applies pending migrations to table in version order and returns
+// the number applied
+func (m *Migrator) Migrate(table string, migrations []Migration) (int, error) {
+	cacheKey := m.cacheKey(table)
+	if _, done := migratedTables.Load(cacheKey); done {
+		return 0, nil
+	}
+
+	status, err := m.Status(table, migrations)
+	if err != nil {
+		return 0, err
+	}
+
+	byVersion := make(map[int]Migration, len(migrations))
+	for _, mig := range migrations {
+		byVersion[mig.Version] = mig
+	}
+
+	editor := &SchemaEditor{db: m.db, dialect: m.dialect, table: table, logger: m.logger}
+	for _, version := range status.Pending {
+		mig := byVersion[version]
+		m.logger.Info("Applying migration",
+			zap.String("table", table),
+			zap.Int("version", mig.Version),
+			zap.String("description", mig.Description))
+
+		if err := mig.Up(editor); err != nil {
+			return 0, fmt.Errorf("migration %d (%s) failed on %s: %w", mig.Version, mig.Description, table, err)
+		}
+		if err := m.recordVersion(table, mig); err != nil {
+			return 0, err
+		}
+	}
+
+	migratedTables.Store(cacheKey, struct{}{})
+	return len(status.Pending), nil
+}
+
+// recordVersion marks a migration as applied. Concurrent migrators may both
+// run an idempotent migration, so an existing record is updated in place.
+func (m *Migrator) recordVersion(table string, mig Migration) error {
+	query := fmt.Sprintf(`INSERT INTO %s (table_name, version, description) VALUES (?, ?, ?) %s`,
+		m.dialect.QuoteIdent(migrationsTable),
+		m.dialect.UpsertClause([]string{"table_name", "version"}, []string{"description"}))
+
+	if _, err := m.db.Exec(m.dialect.Rebind(query), table, mig.Version, mig.Description); err != nil {
+		return fmt.Errorf("failed to record migration %d for %s: %w", mig.Version, table, err)
+	}
+	return nil
+}
+
+// Forget drops the version history of table, e.g. after the table is dropped
+func (m *Migrator) Forget(table string) error {
+	migratedTables.Delete(m.cacheKey(table))
+
+	if err := m.ensureMigrationsTable(); err != nil {
+		return err
+	}
+	query := fmt.Sprintf(`DELETE FROM %s WHERE table_name = ?`, m.dialect.QuoteIdent(migrationsTable))
+	if _, err := m.db.Exec(m.dialect.Rebind(query), table); err != nil {
+		return fmt.Errorf("failed to clear migrations for %s: %w", table, err)
+	}
+	return nil
+}
+
+// cacheKey scopes the migrated-table cache to this database handle
+func (m *Migrator) cacheKey(table string) string {
+	return fmt.Sprintf("%p/%s", m.db, table)
+}
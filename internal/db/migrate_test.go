@@ -0,0 +1,103 @@
+package db
+
+import (
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestMigratorSQLite(t *testing.T) {
+	conn := newTestSQLite(t)
+	sqlDB := conn.GetDB()
+	migrator := NewMigrator(sqlDB, zap.NewNop())
+
+	status, err := migrator.Status(FileVersionsTable, FileVersionMigrations)
+	if err != nil {
+		t.Fatalf("Status: %v", err)
+	}
+	if status.CurrentVersion != 0 || status.LatestVersion != 1 || len(status.Pending) != 1 {
+		t.Errorf("unexpected status before migrating: %+v", status)
+	}
+
+	if applied, err := migrator.Migrate(FileVersionsTable, FileVersionMigrations); err != nil || applied != 1 {
+		t.Fatalf("expected 1 migration applied, got %d (err %v)", applied, err)
+	}
+
+	status, err = migrator.Status(FileVersionsTable, FileVersionMigrations)
+	if err != nil {
+		t.Fatalf("Status: %v", err)
+	}
+	if status.CurrentVersion != 1 || len(status.Pending) != 0 {
+		t.Errorf("unexpected status after migrating: %+v", status)
+	}
+
+	// Forgetting the history replays idempotent migrations without error
+	if err := migrator.Forget(FileVersionsTable); err != nil {
+		t.Fatalf("Forget: %v", err)
+	}
+	if applied, err := migrator.Migrate(FileVersionsTable, FileVersionMigrations); err != nil || applied != 1 {
+		t.Errorf("expected 1 replayed migration, got %d (err %v)", applied, err)
+	}
+}
+
+func TestLegacyTableImportSQLite(t *testing.T) {
+	conn := newTestSQLite(t)
+	sqlDB := conn.GetDB()
+
+	// A per-repo table created before versioning existed, without the sandbox column
+	legacy := LegacyFileVersionTable("legacy-repo")
+	_, err := sqlDB.Exec(`CREATE TABLE "` + legacy + `" (
+		file_id INTEGER PRIMARY KEY AUTOINCREMENT,
+		file_sha VARCHAR(64) NOT NULL,
+		relative_path VARCHAR(512) NOT NULL,
+		ephemeral BOOLEAN NOT NULL DEFAULT FALSE,
+		commit_id VARCHAR(40),
+		status VARCHAR(255) NOT NULL DEFAULT 'processing',
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	)`)
+	if err != nil {
+		t.Fatalf("failed to create legacy table: %v", err)
+	}
+	_, err = sqlDB.Exec(`INSERT INTO "` + legacy + `" (file_id, file_sha, relative_path, status) VALUES
+		(3, 'sha-a', 'a.go', 'done'), (7, 'sha-b', 'b.go', 'done')`)
+	if err != nil {
+		t.Fatalf("failed to seed legacy table: %v", err)
+	}
+
+	if legacy, err := HasLegacyTables(sqlDB, "legacy-repo"); err != nil || !legacy {
+		t.Fatalf("expected legacy tables to be detected (err %v)", err)
+	}
+
+	repo, err := NewFileVersionRepository(sqlDB, "legacy-repo", zap.NewNop())
+	if err != nil {
+		t.Fatalf("NewFileVersionRepository: %v", err)
+	}
+
+	// FileIDs are referenced by the code graph and must survive the move
+	fv, err := repo.GetFileByID(7)
+	if err != nil || fv == nil || fv.FileSHA != "sha-b" || fv.RelativePath != "b.go" {
+		t.Fatalf("expected migrated FileID 7, got %+v (err %v)", fv, err)
+	}
+
+	next, err := repo.GetOrCreateFileID("sha-c", "c.go", false, nil)
+	if err != nil {
+		t.Fatalf("GetOrCreateFileID: %v", err)
+	}
+	if next != 8 {
+		t.Errorf("expected numbering to continue at 8, got %d", next)
+	}
+
+	if legacy, err := HasLegacyTables(sqlDB, "legacy-repo"); err != nil || legacy {
+		t.Errorf("expected legacy table to be dropped (err %v)", err)
+	}
+
+	// Another repository allocates its own FileIDs from 1
+	other, err := NewFileVersionRepository(sqlDB, "other-repo", zap.NewNop())
+	if err != nil {
+		t.Fatalf("NewFileVersionRepository: %v", err)
+	}
+	if id, err := other.GetOrCreateFileID("sha-a", "a.go", false, nil); err != nil || id != 1 {
+		t.Errorf("expected FileID 1 for other-repo, got %d (err %v)", id, err)
+	}
+}
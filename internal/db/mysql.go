@@ -3,7 +3,6 @@ package db
 import (
 	"database/sql"
 	"fmt"
-	"time"
 
 	"github.com/armchr/codeapi/internal/config"
 
@@ -21,15 +20,7 @@ type MySQLConnection struct {
 // NewMySQLConnection creates a new MySQL connection pool
 func NewMySQLConnection(cfg config.MySQLConfig, logger *zap.Logger) (*MySQLConnection, error) {
 	// Build DSN (Data Source Name) without database name first
-	dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/",
-		cfg.Username,
-		cfg.Password,
-		cfg.Host,
-		cfg.Port,
-	)
-
-	// Add connection parameters
-	dsn += "?parseTime=true&charset=utf8mb4&collation=utf8mb4_unicode_ci"
+	dsn := mysqlDSN(cfg, "")
 
 	logger.Info("Connecting to MySQL",
 		zap.String("host", cfg.Host),
@@ -42,9 +33,7 @@ func NewMySQLConnection(cfg config.MySQLConfig, logger *zap.Logger) (*MySQLConne
 	}
 
 	// Configure connection pool
-	db.SetMaxOpenConns(25)
-	db.SetMaxIdleConns(5)
-	db.SetConnMaxLifetime(5 * time.Minute)
+	configurePool(db, cfg.Pool)
 
 	// Test the connection
 	if err := db.Ping(); err != nil {
@@ -62,6 +51,26 @@ func NewMySQLConnection(cfg config.MySQLConfig, logger *zap.Logger) (*MySQLConne
 	return conn, nil
 }
 
+// mysqlDSN builds the DSN for dbName, or for no database when it is empty.
+// The query timeout becomes the driver's read and write timeouts, so a
+// stalled query fails instead of holding its connection indefinitely.
+func mysqlDSN(cfg config.MySQLConfig, dbName string) string {
+	dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s",
+		cfg.Username,
+		cfg.Password,
+		cfg.Host,
+		cfg.Port,
+		dbName,
+	)
+
+	// Add connection parameters
+	dsn += "?parseTime=true&charset=utf8mb4&collation=utf8mb4_unicode_ci"
+	if timeout := cfg.Pool.QueryTimeout(); timeout > 0 {
+		dsn += fmt.Sprintf("&readTimeout=%s&writeTimeout=%s", timeout, timeout)
+	}
+	return dsn
+}
+
 // EnsureDatabase creates the database if it doesn't exist and reconnects to use it
 func (m *MySQLConnection) EnsureDatabase(dbName string) error {
 	m.logger.Info("Ensuring database exists", zap.String("database", dbName))
@@ -76,14 +85,7 @@ func (m *MySQLConnection) EnsureDatabase(dbName string) error {
 	m.db.Close()
 
 	// Reconnect with database selected
-	dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s",
-		m.config.Username,
-		m.config.Password,
-		m.config.Host,
-		m.config.Port,
-		dbName,
-	)
-	dsn += "?parseTime=true&charset=utf8mb4&collation=utf8mb4_unicode_ci"
+	dsn := mysqlDSN(m.config, dbName)
 
 	db, err := sql.Open("mysql", dsn)
 	if err != nil {
@@ -91,9 +93,7 @@ func (m *MySQLConnection) EnsureDatabase(dbName string) error {
 	}
 
 	// Configure connection pool
-	db.SetMaxOpenConns(25)
-	db.SetMaxIdleConns(5)
-	db.SetConnMaxLifetime(5 * time.Minute)
+	configurePool(db, m.config.Pool)
 
 	// Test the connection
 	if err := db.Ping(); err != nil {
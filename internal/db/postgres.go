@@ -0,0 +1,143 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/armchr/codeapi/internal/config"
+
+	"github.com/lib/pq"
+	"go.uber.org/zap"
+)
+
+// PostgresConnection manages the PostgreSQL database connection
+type PostgresConnection struct {
+	db     *sql.DB
+	config config.PostgresConfig
+	logger *zap.Logger
+}
+
+// NewPostgresConnection creates a new PostgreSQL connection pool. It connects
+// to the configured maintenance database (default "postgres") until
+// EnsureDatabase selects the working database.
+func NewPostgresConnection(cfg config.PostgresConfig, logger *zap.Logger) (*PostgresConnection, error) {
+	conn := &PostgresConnection{
+		config: cfg,
+		logger: logger,
+	}
+
+	logger.Info("Connecting to PostgreSQL",
+		zap.String("host", cfg.Host),
+		zap.Int("port", cfg.Port),
+		zap.String("username", cfg.Username))
+
+	maintenanceDB := cfg.Database
+	if maintenanceDB == "" {
+		maintenanceDB = "postgres"
+	}
+
+	db, err := conn.open(maintenanceDB)
+	if err != nil {
+		return nil, err
+	}
+	conn.db = db
+
+	logger.Info("PostgreSQL connection established successfully")
+	return conn, nil
+}
+
+// open connects to dbName with the configured pool settings
+func (p *PostgresConnection) open(dbName string) (*sql.DB, error) {
+	sslMode := p.config.SSLMode
+	if sslMode == "" {
+		sslMode = "disable"
+	}
+
+	dsn := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
+		quoteDSNValue(p.config.Host),
+		p.config.Port,
+		quoteDSNValue(p.config.Username),
+		quoteDSNValue(p.config.Password),
+		quoteDSNValue(dbName),
+		quoteDSNValue(sslMode),
+	)
+
+	// Unknown keywords are passed to the server as run-time parameters;
+	// statement_timeout makes it cancel queries running past the limit
+	if timeout := p.config.Pool.QueryTimeout(); timeout > 0 {
+		dsn += fmt.Sprintf(" statement_timeout=%d", timeout.Milliseconds())
+	}
+
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open PostgreSQL connection: %w", err)
+	}
+
+	// Configure connection pool
+	configurePool(db, p.config.Pool)
+
+	// Test the connection
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to ping PostgreSQL database %s: %w", dbName, err)
+	}
+
+	return db, nil
+}
+
+// quoteDSNValue quotes a keyword/value connection string value
+func quoteDSNValue(v string) string {
+	return pq.QuoteLiteral(v)
+}
+
+// EnsureDatabase creates the database if it doesn't exist and reconnects to use it
+func (p *PostgresConnection) EnsureDatabase(dbName string) error {
+	p.logger.Info("Ensuring database exists", zap.String("database", dbName))
+
+	// Postgres has no CREATE DATABASE IF NOT EXISTS
+	var exists bool
+	if err := p.db.QueryRow(`SELECT EXISTS (SELECT 1 FROM pg_database WHERE datname = $1)`, dbName).Scan(&exists); err != nil {
+		return fmt.Errorf("failed to check database: %w", err)
+	}
+	if !exists {
+		query := fmt.Sprintf("CREATE DATABASE %s ENCODING 'UTF8'", pq.QuoteIdentifier(dbName))
+		if _, err := p.db.Exec(query); err != nil {
+			return fmt.Errorf("failed to create database: %w", err)
+		}
+	}
+
+	db, err := p.open(dbName)
+	if err != nil {
+		return fmt.Errorf("failed to reconnect to database %s: %w", dbName, err)
+	}
+
+	p.db.Close()
+	p.db = db
+
+	p.logger.Info("Database ready", zap.String("database", dbName))
+	return nil
+}
+
+// GetDB returns the underlying sql.DB connection
+func (p *PostgresConnection) GetDB() *sql.DB {
+	return p.db
+}
+
+// Ping checks if the database connection is alive
+func (p *PostgresConnection) Ping() error {
+	return p.db.Ping()
+}
+
+// Close closes the database connection
+func (p *PostgresConnection) Close() error {
+	if p.db != nil {
+		p.logger.Info("Closing PostgreSQL connection")
+		return p.db.Close()
+	}
+	return nil
+}
+
+// Stats returns database statistics
+func (p *PostgresConnection) Stats() sql.DBStats {
+	return p.db.Stats()
+}
@@ -0,0 +1,187 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"sync"
+	"time"
+)
+
+// ErrRepoLocked is returned when another operation holds the lock of a
+// repository
+var ErrRepoLocked = errors.New("repository is locked by another operation")
+
+// repoLockPoll is how often a waiting LockRepo tries again
+const repoLockPoll = 500 * time.Millisecond
+
+// RepoLock is the exclusive lock an operation that rewrites the stored data
+// of a repository (index build, clean, compaction) holds until it is done.
+// Within a process the lock is kept in memory; across processes it is an
+// advisory lock of MySQL or PostgreSQL, held by a dedicated connection, so
+// it is released by the server when the process dies. SQLite databases are
+// local to one process and only get the in-memory lock.
+type RepoLock struct {
+	RepoName   string
+	Operation  string
+	AcquiredAt time.Time
+
+	conn    *sql.Conn // Session holding the advisory lock, nil for SQLite
+	dialect Dialect
+	once    sync.Once
+}
+
+// RepoLockStatus tells whether a repository is locked. The operation and
+// start of a lock are known only when it is held by this process.
+type RepoLockStatus struct {
+	Locked    bool       `json:"locked"`
+	Operation string     `json:"operation,omitempty"`
+	Since     *time.Time `json:"since,omitempty"`
+	Remote    bool       `json:"remote,omitempty"` // Held by another process
+}
+
+// heldRepoLocks are the locks held by this process, by repository
+var heldRepoLocks = struct {
+	sync.Mutex
+	locks map[string]*RepoLock
+}{locks: make(map[string]*RepoLock)}
+
+// LockRepo takes the lock of a repository for operation, trying again for
+// up to wait while another operation holds it. A lock that cannot be taken
+// is reported with an error wrapping ErrRepoLocked.
+func LockRepo(ctx context.Context, sqlDB *sql.DB, repoName, operation string, wait time.Duration) (*RepoLock, error) {
+	deadline := time.Now().Add(wait)
+	for {
+		lock, err := tryLockRepo(ctx, sqlDB, repoName, operation)
+		if !errors.Is(err, ErrRepoLocked) || !time.Now().Before(deadline) {
+			return lock, err
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(repoLockPoll):
+		}
+	}
+}
+
+func tryLockRepo(ctx context.Context, sqlDB *sql.DB, repoName, operation string) (*RepoLock, error) {
+	heldRepoLocks.Lock()
+	defer heldRepoLocks.Unlock()
+	if held, ok := heldRepoLocks.locks[repoName]; ok {
+		return nil, fmt.Errorf("%w: %s of %s running since %s", ErrRepoLocked,
+			held.Operation, repoName, held.AcquiredAt.Format(time.RFC3339))
+	}
+
+	lock := &RepoLock{RepoName: repoName, Operation: operation, AcquiredAt: time.Now(), dialect: dialectFor(sqlDB)}
+	if lock.dialect.Name() != "sqlite" {
+		conn, err := sqlDB.Conn(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get connection for repository lock: %w", err)
+		}
+		var acquired bool
+		switch lock.dialect.Name() {
+		case "postgres":
+			err = conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", repoLockKey(repoName)).Scan(&acquired)
+		default:
+			// GET_LOCK returns NULL on errors such as a killed session
+			var result sql.NullInt64
+			err = conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, 0)", repoLockName(repoName)).Scan(&result)
+			acquired = result.Valid && result.Int64 == 1
+		}
+		if err != nil || !acquired {
+			conn.Close()
+			if err != nil {
+				return nil, fmt.Errorf("failed to take repository lock: %w", err)
+			}
+			return nil, fmt.Errorf("%w: %s is locked by another process", ErrRepoLocked, repoName)
+		}
+		lock.conn = conn
+	}
+	heldRepoLocks.locks[repoName] = lock
+	return lock, nil
+}
+
+// Release releases the lock. Releasing it again does nothing.
+func (l *RepoLock) Release() error {
+	var err error
+	l.once.Do(func() {
+		heldRepoLocks.Lock()
+		delete(heldRepoLocks.locks, l.RepoName)
+		heldRepoLocks.Unlock()
+		if l.conn == nil {
+			return
+		}
+
+		// The operation may have been cancelled, the lock is released anyway
+		ctx := context.Background()
+		var released sql.NullBool
+		if l.dialect.Name() == "postgres" {
+			err = l.conn.QueryRowContext(ctx, "SELECT pg_advisory_unlock($1)", repoLockKey(l.RepoName)).Scan(&released)
+		} else {
+			err = l.conn.QueryRowContext(ctx, "SELECT RELEASE_LOCK(?)", repoLockName(l.RepoName)).Scan(&released)
+		}
+		if err != nil {
+			// A session going back to the pool would keep holding the lock,
+			// so it is closed instead, which releases it
+			l.conn.Raw(func(any) error { return driver.ErrBadConn })
+			err = fmt.Errorf("failed to release repository lock: %w", err)
+		}
+		if closeErr := l.conn.Close(); err == nil && closeErr != nil && !errors.Is(closeErr, driver.ErrBadConn) {
+			err = closeErr
+		}
+	})
+	return err
+}
+
+// GetRepoLockStatus reports whether a repository is locked by this process
+// or, through its advisory lock, by another one
+func GetRepoLockStatus(ctx context.Context, sqlDB *sql.DB, repoName string) (*RepoLockStatus, error) {
+	heldRepoLocks.Lock()
+	held, ok := heldRepoLocks.locks[repoName]
+	heldRepoLocks.Unlock()
+	if ok {
+		since := held.AcquiredAt
+		return &RepoLockStatus{Locked: true, Operation: held.Operation, Since: &since}, nil
+	}
+
+	status := &RepoLockStatus{}
+	var err error
+	switch dialectFor(sqlDB).Name() {
+	case "sqlite":
+		return status, nil
+	case "postgres":
+		// A bigint key is split into classid (high half) and objid (low half)
+		err = sqlDB.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM pg_locks
+			WHERE locktype = 'advisory' AND granted AND objsubid = 1
+			AND ((classid::bigint << 32) | objid::bigint) = $1)`, repoLockKey(repoName)).Scan(&status.Locked)
+	default:
+		var free sql.NullInt64
+		err = sqlDB.QueryRowContext(ctx, "SELECT IS_FREE_LOCK(?)", repoLockName(repoName)).Scan(&free)
+		status.Locked = free.Valid && free.Int64 == 0
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read repository lock: %w", err)
+	}
+	status.Remote = status.Locked
+	return status, nil
+}
+
+// repoLockName is the MySQL lock name of a repository. Lock names are
+// limited to 64 characters, so the repository name is hashed.
+func repoLockName(repoName string) string {
+	return fmt.Sprintf("codeapi.repo.%016x", repoLockHash(repoName))
+}
+
+// repoLockKey is the PostgreSQL advisory lock key of a repository
+func repoLockKey(repoName string) int64 {
+	return int64(repoLockHash(repoName))
+}
+
+func repoLockHash(repoName string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(repoName))
+	return h.Sum64()
+}
@@ -0,0 +1,66 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRepoLock(t *testing.T) {
+	ctx := context.Background()
+	sqlDB := newTestSQLite(t).GetDB()
+
+	lock, err := LockRepo(ctx, sqlDB, "api", "index_build", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LockRepo(ctx, sqlDB, "api", "clean", 0); !errors.Is(err, ErrRepoLocked) {
+		t.Fatalf("second lock = %v, want ErrRepoLocked", err)
+	}
+
+	// Other repositories are not affected
+	other, err := LockRepo(ctx, sqlDB, "web", "clean", 0)
+	if err != nil {
+		t.Fatalf("lock of another repository: %v", err)
+	}
+	other.Release()
+
+	status, err := GetRepoLockStatus(ctx, sqlDB, "api")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !status.Locked || status.Operation != "index_build" || status.Since == nil || status.Remote {
+		t.Errorf("status = %+v, want locked by index_build in this process", status)
+	}
+
+	// A waiting lock is taken once the holder releases it
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		lock.Release()
+	}()
+	waited, err := LockRepo(ctx, sqlDB, "api", "clean", 5*time.Second)
+	if err != nil {
+		t.Fatalf("waiting lock: %v", err)
+	}
+	if err := waited.Release(); err != nil {
+		t.Fatal(err)
+	}
+	if err := waited.Release(); err != nil {
+		t.Errorf("second release: %v", err)
+	}
+
+	if status, _ := GetRepoLockStatus(ctx, sqlDB, "api"); status.Locked {
+		t.Errorf("status after release = %+v, want unlocked", status)
+	}
+}
+
+func TestRepoLockNames(t *testing.T) {
+	long := "a-repository-name-that-is-much-longer-than-the-sixty-four-characters-mysql-allows"
+	if name := repoLockName(long); len(name) > 64 {
+		t.Errorf("lock name %q is %d characters long", name, len(name))
+	}
+	if repoLockName("api") == repoLockName("web") || repoLockKey("api") == repoLockKey("web") {
+		t.Error("different repositories share a lock")
+	}
+}
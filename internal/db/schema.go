@@ -0,0 +1,478 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+// Shared relational store tables. Rows of every repository live in the same
+// tables, keyed by repo_name.
+const (
+	FileVersionsTable    = "file_versions"
+	FileIDSequencesTable = "file_id_sequences"
+	CodeSummariesTable   = "code_summaries"
+	CodeNotesTable       = "code_notes"
+	FeedbackTable        = "feedback"
+	AuditLogTable        = "audit_log"
+	GraphOverflowTable   = "graph_property_overflow"
+)
+
+// FileVersionMigrations is the schema history of the shared file_versions
+// table. Append new versions; never edit applied ones.
+var FileVersionMigrations = []Migration{
+	{
+		Version:     1,
+		Description: "create shared file_versions table",
+		Up: func(e *SchemaEditor) error {
+			return e.CreateTable(
+				[]string{
+					"repo_name VARCHAR(255) NOT NULL",
+					"file_id INT NOT NULL",
+					"file_sha VARCHAR(64) NOT NULL",
+					"relative_path VARCHAR(512) NOT NULL",
+					"ephemeral BOOLEAN NOT NULL DEFAULT FALSE",
+					"commit_id VARCHAR(40)",
+					"status VARCHAR(255) NOT NULL DEFAULT 'processing'",
+					"sandbox BOOLEAN NOT NULL DEFAULT FALSE",
+					"created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP",
+					e.Dialect().UpdatedAtColumn(),
+					"PRIMARY KEY (repo_name, file_id)",
+				},
+				[]IndexDef{
+					{Name: "unique_sha_path_commit", Columns: "repo_name, file_sha, relative_path, commit_id", Unique: true},
+					{Name: "idx_repo_file_sha", Columns: "repo_name, file_sha"},
+					{Name: "idx_repo_relative_path", Columns: "repo_name, relative_path"},
+					{Name: "idx_repo_commit_id", Columns: "repo_name, commit_id"},
+					{Name: "idx_repo_status", Columns: "repo_name, status"},
+					{Name: "idx_repo_sandbox", Columns: "repo_name, sandbox"},
+				})
+		},
+	},
+}
+
+// FileIDSequenceMigrations is the schema history of file_id_sequences, which
+// hands out FileIDs per repository so IDs carried over from the per-repo
+// tables stay valid in the code graph and vector store
+var FileIDSequenceMigrations = []Migration{
+	{
+		Version:     1,
+		Description: "create file_id_sequences table",
+		Up: func(e *SchemaEditor) error {
+			return e.CreateTable(
+				[]string{
+					"repo_name VARCHAR(255) NOT NULL PRIMARY KEY",
+					"last_id INT NOT NULL DEFAULT 0",
+				}, nil)
+		},
+	},
+}
+
+// SummaryMigrations is the schema history of the shared code_summaries table
+var SummaryMigrations = []Migration{
+	{
+		Version:     1,
+		Description: "create shared code_summaries table",
+		Up: func(e *SchemaEditor) error {
+			return e.CreateTable(
+				[]string{
+					"id " + e.Dialect().AutoIncrementKey(true),
+					"repo_name VARCHAR(255) NOT NULL",
+					"entity_id VARCHAR(255) NOT NULL",
+					"entity_type VARCHAR(50) NOT NULL",
+					"entity_name VARCHAR(255)",
+					"file_path VARCHAR(500)",
+					"summary TEXT NOT NULL",
+					"context_hash VARCHAR(64)",
+					"llm_provider VARCHAR(50)",
+					"llm_model VARCHAR(100)",
+					"prompt_tokens INT DEFAULT 0",
+					"output_tokens INT DEFAULT 0",
+					"created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP",
+					e.Dialect().UpdatedAtColumn(),
+				},
+				[]IndexDef{
+					{Name: "idx_repo_entity", Columns: "repo_name, entity_id, entity_type", Unique: true},
+					{Name: "idx_repo_file_path", Columns: "repo_name, file_path"},
+					{Name: "idx_repo_entity_type", Columns: "repo_name, entity_type"},
+					{Name: "idx_repo_updated_at", Columns: "repo_name, updated_at"},
+				})
+		},
+	},
+	{
+		Version:     2,
+		Description: "add previous_entity_id column",
+		Up: func(e *SchemaEditor) error {
+			return e.AddColumn("previous_entity_id", "VARCHAR(255)", "idx_previous_entity_id")
+		},
+	},
+	{
+		Version:     3,
+		Description: "add encryption column",
+		Up: func(e *SchemaEditor) error {
+			return e.AddColumn("encryption", "VARCHAR(16)", "idx_encryption")
+		},
+	},
+}
+
+// CodeNoteMigrations is the schema history of the shared code_notes table,
+// which holds TODO-style comments and license headers found while indexing
+var CodeNoteMigrations = []Migration{
+	{
+		Version:     1,
+		Description: "create shared code_notes table",
+		Up: func(e *SchemaEditor) error {
+			return e.CreateTable(
+				[]string{
+					"id " + e.Dialect().AutoIncrementKey(true),
+					"repo_name VARCHAR(255) NOT NULL",
+					"file_id INT NOT NULL",
+					"file_path VARCHAR(512) NOT NULL",
+					"line INT NOT NULL",
+					"kind VARCHAR(50) NOT NULL",
+					"text TEXT NOT NULL",
+					"assignee VARCHAR(255)",
+					"author VARCHAR(255)",
+					"author_email VARCHAR(255)",
+					"commit_id VARCHAR(40)",
+					"authored_at TIMESTAMP NULL",
+					"created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP",
+				},
+				[]IndexDef{
+					{Name: "idx_repo_file_path", Columns: "repo_name, file_path"},
+					{Name: "idx_repo_kind", Columns: "repo_name, kind"},
+					{Name: "idx_repo_author", Columns: "repo_name, author"},
+				})
+		},
+	},
+}
+
+// FeedbackMigrations is the schema history of the shared feedback table,
+// which holds client votes on search results and summaries
+var FeedbackMigrations = []Migration{
+	{
+		Version:     1,
+		Description: "create shared feedback table",
+		Up: func(e *SchemaEditor) error {
+			return e.CreateTable(
+				[]string{
+					"id " + e.Dialect().AutoIncrementKey(true),
+					"repo_name VARCHAR(255) NOT NULL",
+					"entity_type VARCHAR(50) NOT NULL",
+					"entity_id VARCHAR(255) NOT NULL",
+					"query_hash VARCHAR(64) NOT NULL",
+					"query_text TEXT",
+					"vote SMALLINT NOT NULL",
+					"source VARCHAR(100)",
+					"comment TEXT",
+					"created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP",
+				},
+				[]IndexDef{
+					{Name: "idx_repo_entity_query", Columns: "repo_name, entity_type, entity_id, query_hash"},
+					{Name: "idx_repo_query", Columns: "repo_name, query_hash"},
+					{Name: "idx_repo_created_at", Columns: "repo_name, created_at"},
+				})
+		},
+	},
+}
+
+// AuditLogMigrations is the schema history of the shared audit_log table,
+// which records the operations that changed a repository's stored data
+var AuditLogMigrations = []Migration{
+	{
+		Version:     1,
+		Description: "create shared audit_log table",
+		Up: func(e *SchemaEditor) error {
+			return e.CreateTable(
+				[]string{
+					"id " + e.Dialect().AutoIncrementKey(true),
+					"repo_name VARCHAR(255) NOT NULL",
+					"operation VARCHAR(50) NOT NULL",
+					"source VARCHAR(20) NOT NULL",
+					"actor VARCHAR(255)",
+					"client VARCHAR(255)",
+					"paths TEXT",
+					"outcome VARCHAR(20) NOT NULL",
+					"error TEXT",
+					"details TEXT",
+					"started_at TIMESTAMP NOT NULL",
+					"duration_ms BIGINT NOT NULL DEFAULT 0",
+				},
+				[]IndexDef{
+					{Name: "idx_repo_started_at", Columns: "repo_name, started_at"},
+					{Name: "idx_repo_operation", Columns: "repo_name, operation"},
+					{Name: "idx_repo_actor", Columns: "repo_name, actor"},
+				})
+		},
+	},
+}
+
+// GraphOverflowMigrations is the schema history of graph_property_overflow,
+// which holds node and relationship metadata too large for the code graph.
+// Node IDs are unique across repositories, so rows are keyed by node alone.
+var GraphOverflowMigrations = []Migration{
+	{
+		Version:     1,
+		Description: "create graph_property_overflow table",
+		Up: func(e *SchemaEditor) error {
+			return e.CreateTable(
+				[]string{
+					"node_id BIGINT NOT NULL",
+					"edge VARCHAR(255) NOT NULL DEFAULT ''",
+					"prop_key VARCHAR(255) NOT NULL",
+					"prop_value " + e.Dialect().LargeTextType() + " NOT NULL",
+					"created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP",
+					"PRIMARY KEY (node_id, edge, prop_key)",
+				}, nil)
+		},
+	},
+}
+
+// SharedTable names a shared table and its schema history
+type SharedTable struct {
+	Name       string
+	Migrations []Migration
+}
+
+// SharedTables lists every shared table, for status reports
+var SharedTables = []SharedTable{
+	{FileVersionsTable, FileVersionMigrations},
+	{FileIDSequencesTable, FileIDSequenceMigrations},
+	{CodeSummariesTable, SummaryMigrations},
+	{CodeNotesTable, CodeNoteMigrations},
+	{FeedbackTable, FeedbackMigrations},
+	{AuditLogTable, AuditLogMigrations},
+	{GraphOverflowTable, GraphOverflowMigrations},
+}
+
+// LegacyFileVersionTable returns the per-repository file_versions table used
+// before the shared schema
+func LegacyFileVersionTable(repoName string) string {
+	return sanitizeTableName(repoName) + "_file_versions"
+}
+
+// LegacySummaryTable returns the per-repository code_summaries table used
+// before the shared schema
+func LegacySummaryTable(repoName string) string {
+	return sanitizeTableName(repoName) + "_code_summaries"
+}
+
+// legacyFileVersionMigrations brings a per-repo file_versions table to its
+// final layout and moves its rows into the shared table. The table is
+// dropped afterwards, so these only ever run on tables that already exist.
+func legacyFileVersionMigrations(repoName string) []Migration {
+	return []Migration{
+		{
+			Version:     2,
+			Description: "add status column",
+			Up: func(e *SchemaEditor) error {
+				return e.AddColumn("status", "VARCHAR(255) NOT NULL DEFAULT 'processing'", "idx_status")
+			},
+		},
+		{
+			Version:     3,
+			Description: "add sandbox column",
+			Up: func(e *SchemaEditor) error {
+				return e.AddColumn("sandbox", "BOOLEAN NOT NULL DEFAULT FALSE", "idx_sandbox")
+			},
+		},
+		{
+			Version:     4,
+			Description: "move rows into shared file_versions table",
+			Up: func(e *SchemaEditor) error {
+				const columns = "file_id, file_sha, relative_path, ephemeral, commit_id, status, sandbox, created_at, updated_at"
+				d := e.Dialect()
+				return e.InTx(
+					// Clear rows left by an interrupted earlier attempt
+					stmt(fmt.Sprintf(`DELETE FROM %s WHERE repo_name = ?`, d.QuoteIdent(FileVersionsTable)), repoName),
+					stmt(fmt.Sprintf(`INSERT INTO %s (repo_name, %s) SELECT ?, %s FROM %s`,
+						d.QuoteIdent(FileVersionsTable), columns, columns, d.QuoteIdent(e.Table())), repoName),
+					stmt(fmt.Sprintf(`DELETE FROM %s WHERE repo_name = ?`, d.QuoteIdent(FileIDSequencesTable)), repoName),
+					// Continue numbering after the highest migrated FileID
+					stmt(fmt.Sprintf(`INSERT INTO %s (repo_name, last_id) SELECT ?, COALESCE(MAX(file_id), 0) FROM %s`,
+						d.QuoteIdent(FileIDSequencesTable), d.QuoteIdent(e.Table())), repoName),
+				)
+			},
+		},
+	}
+}
+
+// legacySummaryMigrations moves a per-repo code_summaries table into the
+// shared table
+func legacySummaryMigrations(repoName string) []Migration {
+	return []Migration{
+		{
+			Version:     2,
+			Description: "move rows into shared code_summaries table",
+			Up: func(e *SchemaEditor) error {
+				const columns = "entity_id, entity_type, entity_name, file_path, summary, context_hash, llm_provider, llm_model, prompt_tokens, output_tokens, created_at, updated_at"
+				d := e.Dialect()
+				return e.InTx(
+					stmt(fmt.Sprintf(`DELETE FROM %s WHERE repo_name = ?`, d.QuoteIdent(CodeSummariesTable)), repoName),
+					stmt(fmt.Sprintf(`INSERT INTO %s (repo_name, %s) SELECT ?, %s FROM %s`,
+						d.QuoteIdent(CodeSummariesTable), columns, columns, d.QuoteIdent(e.Table())), repoName),
+				)
+			},
+		},
+	}
+}
+
+// schemaReady caches repositories whose shared tables and legacy imports
+// have been handled by this process
+var schemaReady sync.Map
+
+// EnsureFileVersionSchema migrates the shared file_versions tables and moves
+// the repository's legacy per-repo table into them if one still exists
+func EnsureFileVersionSchema(db *sql.DB, repoName string, logger *zap.Logger) error {
+	migrator := NewMigrator(db, logger)
+	key := migrator.cacheKey("file_versions/" + repoName)
+	if _, ok := schemaReady.Load(key); ok {
+		return nil
+	}
+
+	if _, err := migrator.Migrate(FileVersionsTable, FileVersionMigrations); err != nil {
+		return err
+	}
+	if _, err := migrator.Migrate(FileIDSequencesTable, FileIDSequenceMigrations); err != nil {
+		return err
+	}
+	if err := migrator.importLegacyTable(LegacyFileVersionTable(repoName), legacyFileVersionMigrations(repoName)); err != nil {
+		return err
+	}
+
+	schemaReady.Store(key, struct{}{})
+	return nil
+}
+
+// EnsureSummarySchema migrates the shared code_summaries table and moves the
+// repository's legacy per-repo table into it if one still exists
+func EnsureSummarySchema(db *sql.DB, repoName string, logger *zap.Logger) error {
+	migrator := NewMigrator(db, logger)
+	key := migrator.cacheKey("code_summaries/" + repoName)
+	if _, ok := schemaReady.Load(key); ok {
+		return nil
+	}
+
+	if _, err := migrator.Migrate(CodeSummariesTable, SummaryMigrations); err != nil {
+		return err
+	}
+	if err := migrator.importLegacyTable(LegacySummaryTable(repoName), legacySummaryMigrations(repoName)); err != nil {
+		return err
+	}
+
+	schemaReady.Store(key, struct{}{})
+	return nil
+}
+
+// EnsureCodeNoteSchema migrates the shared code_notes table. Notes have no
+// per-repo predecessor, so there is nothing to import.
+func EnsureCodeNoteSchema(db *sql.DB, logger *zap.Logger) error {
+	migrator := NewMigrator(db, logger)
+	key := migrator.cacheKey(CodeNotesTable)
+	if _, ok := schemaReady.Load(key); ok {
+		return nil
+	}
+
+	if _, err := migrator.Migrate(CodeNotesTable, CodeNoteMigrations); err != nil {
+		return err
+	}
+
+	schemaReady.Store(key, struct{}{})
+	return nil
+}
+
+// EnsureFeedbackSchema migrates the shared feedback table
+func EnsureFeedbackSchema(db *sql.DB, logger *zap.Logger) error {
+	migrator := NewMigrator(db, logger)
+	key := migrator.cacheKey(FeedbackTable)
+	if _, ok := schemaReady.Load(key); ok {
+		return nil
+	}
+
+	if _, err := migrator.Migrate(FeedbackTable, FeedbackMigrations); err != nil {
+		return err
+	}
+
+	schemaReady.Store(key, struct{}{})
+	return nil
+}
+
+// EnsureAuditLogSchema migrates the shared audit_log table
+func EnsureAuditLogSchema(db *sql.DB, logger *zap.Logger) error {
+	migrator := NewMigrator(db, logger)
+	key := migrator.cacheKey(AuditLogTable)
+	if _, ok := schemaReady.Load(key); ok {
+		return nil
+	}
+
+	if _, err := migrator.Migrate(AuditLogTable, AuditLogMigrations); err != nil {
+		return err
+	}
+
+	schemaReady.Store(key, struct{}{})
+	return nil
+}
+
+// EnsureGraphOverflowSchema migrates the graph_property_overflow table
+func EnsureGraphOverflowSchema(db *sql.DB, logger *zap.Logger) error {
+	migrator := NewMigrator(db, logger)
+	key := migrator.cacheKey(GraphOverflowTable)
+	if _, ok := schemaReady.Load(key); ok {
+		return nil
+	}
+
+	if _, err := migrator.Migrate(GraphOverflowTable, GraphOverflowMigrations); err != nil {
+		return err
+	}
+
+	schemaReady.Store(key, struct{}{})
+	return nil
+}
+
+// HasLegacyTables reports whether per-repo tables of repoName are still
+// waiting to be moved into the shared schema
+func HasLegacyTables(db *sql.DB, repoName string) (bool, error) {
+	d := dialectFor(db)
+	for _, table := range []string{LegacyFileVersionTable(repoName), LegacySummaryTable(repoName)} {
+		exists, err := tableExists(db, d, table)
+		if err != nil || exists {
+			return exists, err
+		}
+	}
+	return false, nil
+}
+
+// importLegacyTable runs the legacy migrations of table, whose last step
+// copies its rows into a shared table, then drops it and its version history
+func (m *Migrator) importLegacyTable(table string, migrations []Migration) error {
+	exists, err := tableExists(m.db, m.dialect, table)
+	if err != nil || !exists {
+		return err
+	}
+
+	m.logger.Info("Moving legacy per-repository table into shared schema", zap.String("table", table))
+	if _, err := m.Migrate(table, migrations); err != nil {
+		return err
+	}
+
+	if _, err := m.db.Exec(fmt.Sprintf(`DROP TABLE IF EXISTS %s`, m.dialect.QuoteIdent(table))); err != nil {
+		return fmt.Errorf("failed to drop legacy table %s: %w", table, err)
+	}
+	return m.Forget(table)
+}
+
+// tableExists reports whether table exists in the current schema
+func tableExists(db *sql.DB, d Dialect, table string) (bool, error) {
+	var count int
+	if err := db.QueryRow(d.Rebind(d.TableExistsQuery()), table).Scan(&count); err != nil {
+		return false, fmt.Errorf("failed to check for table %s: %w", table, err)
+	}
+	return count > 0, nil
+}
+
+// TableExists reports whether a table exists, without creating anything
+func TableExists(db *sql.DB, table string) (bool, error) {
+	return tableExists(db, dialectFor(db), table)
+}
@@ -0,0 +1,86 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"go.uber.org/zap"
+	_ "modernc.org/sqlite"
+)
+
+// SQLiteConnection manages an embedded SQLite database file. It lets the
+// index builder run locally without a database server.
+type SQLiteConnection struct {
+	db     *sql.DB
+	path   string
+	logger *zap.Logger
+}
+
+// NewSQLiteConnection opens (creating if needed) the SQLite database at path
+func NewSQLiteConnection(path string, logger *zap.Logger) (*SQLiteConnection, error) {
+	logger.Info("Opening SQLite database", zap.String("path", path))
+
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, fmt.Errorf("failed to create directory for SQLite database: %w", err)
+		}
+	}
+
+	// WAL lets readers proceed alongside the single writer; the busy timeout
+	// covers short lock waits between index builder workers
+	dsn := fmt.Sprintf("file:%s?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_pragma=foreign_keys(1)&_time_format=sqlite", path)
+
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open SQLite database: %w", err)
+	}
+
+	// SQLite allows a single writer; serialize access through one connection
+	db.SetMaxOpenConns(1)
+
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to ping SQLite database: %w", err)
+	}
+
+	logger.Info("SQLite database ready", zap.String("path", path))
+	return &SQLiteConnection{
+		db:     db,
+		path:   path,
+		logger: logger,
+	}, nil
+}
+
+// EnsureDatabase is a no-op: all tables live in the single database file
+func (s *SQLiteConnection) EnsureDatabase(dbName string) error {
+	s.logger.Debug("SQLite uses a single database file, ignoring database name",
+		zap.String("database", dbName),
+		zap.String("path", s.path))
+	return nil
+}
+
+// GetDB returns the underlying sql.DB connection
+func (s *SQLiteConnection) GetDB() *sql.DB {
+	return s.db
+}
+
+// Ping checks if the database connection is alive
+func (s *SQLiteConnection) Ping() error {
+	return s.db.Ping()
+}
+
+// Close closes the database connection
+func (s *SQLiteConnection) Close() error {
+	if s.db != nil {
+		s.logger.Info("Closing SQLite database")
+		return s.db.Close()
+	}
+	return nil
+}
+
+// Stats returns database statistics
+func (s *SQLiteConnection) Stats() sql.DBStats {
+	return s.db.Stats()
+}
@@ -0,0 +1,421 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/armchr/codeapi/internal/service/audit"
+	"github.com/armchr/codeapi/internal/service/feedback"
+	"github.com/armchr/codeapi/internal/service/notes"
+	"github.com/armchr/codeapi/internal/service/summary"
+	"go.uber.org/zap"
+)
+
+func newTestSQLite(t *testing.T) *SQLiteConnection {
+	t.Helper()
+	conn, err := NewSQLiteConnection(filepath.Join(t.TempDir(), "codeapi.db"), zap.NewNop())
+	if err != nil {
+		t.Fatalf("failed to open SQLite: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func TestFileVersionRepositorySQLite(t *testing.T) {
+	conn := newTestSQLite(t)
+	repo, err := NewFileVersionRepository(conn.GetDB(), "my-repo", zap.NewNop())
+	if err != nil {
+		t.Fatalf("NewFileVersionRepository: %v", err)
+	}
+	if _, ok := repo.dialect.(sqliteDialect); !ok {
+		t.Fatalf("expected sqlite dialect, got %s", repo.dialect.Name())
+	}
+
+	if last, err := repo.LastIndexedAt(); err != nil || last != nil {
+		t.Fatalf("LastIndexedAt() = %v, %v before indexing; want nil", last, err)
+	}
+
+	// NULL commit IDs must match each other for the ID to be reused
+	first, err := repo.GetOrCreateFileID("sha1", "a.go", false, nil)
+	if err != nil {
+		t.Fatalf("GetOrCreateFileID: %v", err)
+	}
+	again, err := repo.GetOrCreateFileID("sha1", "a.go", false, nil)
+	if err != nil || again != first {
+		t.Fatalf("expected reuse of FileID %d, got %d (err %v)", first, again, err)
+	}
+
+	second, _ := repo.GetOrCreateFileID("sha2", "a.go", false, nil)
+	third, _ := repo.GetOrCreateFileID("sha3", "a.go", false, nil)
+
+	dropped, err := repo.GetVersionsBeyondRetention(2)
+	if err != nil {
+		t.Fatalf("GetVersionsBeyondRetention: %v", err)
+	}
+	if len(dropped) != 1 || dropped[0].FileID != first {
+		t.Errorf("expected FileID %d beyond retention, got %+v", first, dropped)
+	}
+
+	sandboxID, err := repo.GetOrCreateSandboxFileID("sha4", "b.go")
+	if err != nil {
+		t.Fatalf("GetOrCreateSandboxFileID: %v", err)
+	}
+	expired, err := repo.GetSandboxVersions(time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("GetSandboxVersions: %v", err)
+	}
+	if len(expired) != 1 || expired[0].FileID != sandboxID || !expired[0].Sandbox {
+		t.Errorf("expected sandbox version %d, got %+v", sandboxID, expired)
+	}
+
+	if err := repo.UpdateStatus(second, "done"); err != nil {
+		t.Fatalf("UpdateStatus: %v", err)
+	}
+	deleted, err := repo.DeleteVersions([]int32{first, third})
+	if err != nil || deleted != 2 {
+		t.Fatalf("expected 2 deleted versions, got %d (err %v)", deleted, err)
+	}
+
+	ids, err := repo.GetAllFileIDs()
+	if err != nil || len(ids) != 2 {
+		t.Errorf("expected 2 remaining FileIDs, got %v (err %v)", ids, err)
+	}
+	if last, err := repo.LastIndexedAt(); err != nil || last == nil || time.Since(*last) > time.Hour {
+		t.Errorf("LastIndexedAt() = %v, %v; want the last update", last, err)
+	}
+}
+
+func TestGetRecentVersionsSQLite(t *testing.T) {
+	conn := newTestSQLite(t)
+	repo, err := NewFileVersionRepository(conn.GetDB(), "my-repo", zap.NewNop())
+	if err != nil {
+		t.Fatalf("NewFileVersionRepository: %v", err)
+	}
+	repo.GetOrCreateFileID("sha1", "a.go", false, nil)
+	b, _ := repo.GetOrCreateFileID("sha2", "b.go", false, nil)
+	a, _ := repo.GetOrCreateFileID("sha3", "a.go", false, nil)
+	repo.GetOrCreateSandboxFileID("sha4", "c.go")
+	repo.GetOrCreateFileID("sha5", "d.go", true, nil)
+
+	recent, err := repo.GetRecentVersions(10)
+	if err != nil {
+		t.Fatalf("GetRecentVersions: %v", err)
+	}
+	if len(recent) != 2 || recent[0].FileID != a || recent[1].FileID != b {
+		t.Errorf("GetRecentVersions(10) = %+v, want FileIDs %d and %d", recent, a, b)
+	}
+
+	if recent, _ := repo.GetRecentVersions(1); len(recent) != 1 || recent[0].FileID != a {
+		t.Errorf("GetRecentVersions(1) = %+v, want FileID %d", recent, a)
+	}
+}
+
+func TestRestoreVersionsSQLite(t *testing.T) {
+	conn := newTestSQLite(t)
+	source, err := NewFileVersionRepository(conn.GetDB(), "source", zap.NewNop())
+	if err != nil {
+		t.Fatalf("NewFileVersionRepository: %v", err)
+	}
+	commit := "abc123"
+	source.GetOrCreateFileID("sha1", "a.go", false, &commit)
+	second, _ := source.GetOrCreateFileID("sha2", "b.go", false, nil)
+	dropped, _ := source.GetOrCreateFileID("sha3", "c.go", false, nil)
+	source.DeleteVersions([]int32{dropped})
+
+	versions, err := source.GetAllVersions()
+	if err != nil || len(versions) != 2 {
+		t.Fatalf("expected 2 versions, got %d (err %v)", len(versions), err)
+	}
+	lastID, err := source.LastFileID()
+	if err != nil || lastID != dropped {
+		t.Fatalf("LastFileID() = %d, want %d (err %v)", lastID, dropped, err)
+	}
+
+	target, err := NewFileVersionRepository(conn.GetDB(), "target", zap.NewNop())
+	if err != nil {
+		t.Fatalf("NewFileVersionRepository: %v", err)
+	}
+	if err := target.RestoreVersions(versions, lastID); err != nil {
+		t.Fatalf("RestoreVersions: %v", err)
+	}
+
+	restored, err := target.GetFileByID(second)
+	if err != nil || restored == nil || restored.FileSHA != "sha2" {
+		t.Errorf("expected restored FileID %d, got %+v (err %v)", second, restored, err)
+	}
+	// The sequence continues after the highest FileID ever handed out, not
+	// after the highest one restored
+	next, err := target.GetOrCreateFileID("sha4", "d.go", false, nil)
+	if err != nil || next != dropped+1 {
+		t.Errorf("expected next FileID %d, got %d (err %v)", dropped+1, next, err)
+	}
+}
+
+func TestSummaryStoreSQLite(t *testing.T) {
+	conn := newTestSQLite(t)
+	store, err := NewSummaryStore(conn.GetDB(), "my-repo", zap.NewNop())
+	if err != nil {
+		t.Fatalf("NewSummaryStore: %v", err)
+	}
+
+	err = store.SaveSummaries([]*summary.CodeSummary{
+		{EntityID: "f1", EntityType: summary.LevelFunction, EntityName: "Run", FilePath: "a.go", Summary: "old"},
+		{EntityID: "f1", EntityType: summary.LevelFunction, EntityName: "Run", FilePath: "a.go", Summary: "new"},
+		{EntityID: "a.go", EntityType: summary.LevelFile, FilePath: "a.go", Summary: "file"},
+	})
+	if err != nil {
+		t.Fatalf("SaveSummaries: %v", err)
+	}
+
+	// Saving again must update in place rather than fail on the unique key
+	if err := store.SaveSummary(&summary.CodeSummary{EntityID: "a.go", EntityType: summary.LevelFile, FilePath: "a.go", Summary: "file v2"}); err != nil {
+		t.Fatalf("SaveSummary: %v", err)
+	}
+
+	fn, err := store.GetSummary("f1", summary.LevelFunction)
+	if err != nil || fn == nil || fn.Summary != "new" {
+		t.Errorf("expected last batch entry to win, got %+v (err %v)", fn, err)
+	}
+	file, err := store.GetFileSummary("a.go")
+	if err != nil || file == nil || file.Summary != "file v2" {
+		t.Errorf("expected upserted file summary, got %+v (err %v)", file, err)
+	}
+
+	stats, err := store.GetStats()
+	if err != nil || stats.Total != 2 {
+		t.Errorf("expected 2 summaries, got %+v (err %v)", stats, err)
+	}
+
+	// A summary carried over from a previous version keeps its lineage when regenerated
+	carried := &summary.CodeSummary{EntityID: "f2", PreviousEntityID: "f1", EntityType: summary.LevelFunction, EntityName: "Start", FilePath: "a.go", Summary: "new"}
+	if err := store.SaveSummary(carried); err != nil {
+		t.Fatalf("SaveSummary: %v", err)
+	}
+	if err := store.SaveSummary(&summary.CodeSummary{EntityID: "f2", EntityType: summary.LevelFunction, EntityName: "Start", FilePath: "a.go", Summary: "regenerated"}); err != nil {
+		t.Fatalf("SaveSummary: %v", err)
+	}
+	fn, err = store.GetSummary("f2", summary.LevelFunction)
+	if err != nil || fn == nil || fn.Summary != "regenerated" || fn.PreviousEntityID != "f1" {
+		t.Errorf("expected regenerated summary carried from f1, got %+v (err %v)", fn, err)
+	}
+
+	found, err := store.GetSummariesByEntityIDs(summary.LevelFunction, []string{"f1", "f2", "a.go", "missing"})
+	if err != nil || len(found) != 2 {
+		t.Errorf("expected the two function summaries by ID, got %d (err %v)", len(found), err)
+	}
+}
+
+func TestCodeNoteStoreSQLite(t *testing.T) {
+	conn := newTestSQLite(t)
+	store, err := NewCodeNoteStore(conn.GetDB(), "my-repo", zap.NewNop())
+	if err != nil {
+		t.Fatalf("NewCodeNoteStore: %v", err)
+	}
+
+	authored := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	err = store.ReplaceFileNotes("pkg/a.go", 1, []notes.CodeNote{
+		{Line: 3, Kind: "todo", Text: "handle 100%_ cases", Author: "Ada", AuthoredAt: &authored},
+		{Line: 9, Kind: "fixme", Text: "overflow", Assignee: "bob"},
+	})
+	if err != nil {
+		t.Fatalf("ReplaceFileNotes: %v", err)
+	}
+	if err := store.ReplaceFileNotes("pkg/b.go", 2, []notes.CodeNote{{Line: 1, Kind: "license", Text: "MIT"}}); err != nil {
+		t.Fatalf("ReplaceFileNotes: %v", err)
+	}
+	// A new version of a.go replaces its notes
+	if err := store.ReplaceFileNotes("pkg/a.go", 3, []notes.CodeNote{{Line: 4, Kind: "todo", Text: "handle 100%_ cases", Author: "Ada", AuthoredAt: &authored}}); err != nil {
+		t.Fatalf("ReplaceFileNotes: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		filter notes.Filter
+		want   []string // path:line
+	}{
+		{"all", notes.Filter{}, []string{"pkg/a.go:4", "pkg/b.go:1"}},
+		{"kind", notes.Filter{Kinds: []string{"LICENSE"}}, []string{"pkg/b.go:1"}},
+		{"path prefix", notes.Filter{PathPrefix: "pkg/a"}, []string{"pkg/a.go:4"}},
+		{"author", notes.Filter{Author: "Ada"}, []string{"pkg/a.go:4"}},
+		{"contains wildcards literally", notes.Filter{Contains: "100%_"}, []string{"pkg/a.go:4"}},
+		{"wildcard does not match", notes.Filter{Contains: "1_0"}, nil},
+		{"offset", notes.Filter{Offset: 1}, []string{"pkg/b.go:1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := store.ListNotes(tt.filter)
+			if err != nil {
+				t.Fatalf("ListNotes: %v", err)
+			}
+			var keys []string
+			for _, n := range got {
+				keys = append(keys, fmt.Sprintf("%s:%d", n.FilePath, n.Line))
+			}
+			if fmt.Sprint(keys) != fmt.Sprint(tt.want) {
+				t.Errorf("notes = %v, want %v", keys, tt.want)
+			}
+		})
+	}
+
+	got, err := store.ListNotes(notes.Filter{Kinds: []string{"todo"}})
+	if err != nil || len(got) != 1 || got[0].FileID != 3 || got[0].AuthoredAt == nil || !got[0].AuthoredAt.Equal(authored) {
+		t.Errorf("expected the replaced note with its blame time, got %+v (err %v)", got, err)
+	}
+
+	if deleted, err := store.DeleteAll(); err != nil || deleted != 2 {
+		t.Errorf("DeleteAll = %d, %v; want 2", deleted, err)
+	}
+}
+
+func TestWithContextCancelsQueries(t *testing.T) {
+	conn := newTestSQLite(t)
+	store, err := NewSummaryStore(conn.GetDB(), "my-repo", zap.NewNop())
+	if err != nil {
+		t.Fatalf("NewSummaryStore: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := store.WithContext(ctx).GetStats(); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled from a cancelled store, got %v", err)
+	}
+	if _, err := store.GetStats(); err != nil {
+		t.Errorf("original store should be unaffected, got %v", err)
+	}
+}
+
+func TestFeedbackStoreSQLite(t *testing.T) {
+	conn := newTestSQLite(t)
+	store, err := NewFeedbackStore(conn.GetDB(), "my-repo", zap.NewNop())
+	if err != nil {
+		t.Fatalf("NewFeedbackStore: %v", err)
+	}
+	other, err := NewFeedbackStore(conn.GetDB(), "other-repo", zap.NewNop())
+	if err != nil {
+		t.Fatalf("NewFeedbackStore: %v", err)
+	}
+
+	err = store.RecordVotes([]feedback.Feedback{
+		{EntityType: feedback.EntitySearchResult, EntityID: "chunk-a", Query: "parse config", Vote: feedback.Upvote, Source: "searchSimilarCode"},
+		{EntityType: feedback.EntitySearchResult, EntityID: "chunk-a", Query: "parse config", Vote: feedback.Upvote},
+		{EntityType: feedback.EntitySearchResult, EntityID: "chunk-a", Query: "open socket", Vote: feedback.Downvote, Comment: "unrelated"},
+		{EntityType: feedback.EntitySearchResult, EntityID: "chunk-b", Query: "parse config", Vote: feedback.Downvote},
+		{EntityType: feedback.EntitySummary, EntityID: "pkg.Parse", Vote: feedback.Downvote, Comment: "misses the error path"},
+	})
+	if err != nil {
+		t.Fatalf("RecordVotes: %v", err)
+	}
+	if err := other.RecordVotes([]feedback.Feedback{{EntityType: feedback.EntitySummary, EntityID: "pkg.Parse", Vote: feedback.Upvote}}); err != nil {
+		t.Fatalf("RecordVotes: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		filter feedback.Filter
+		want   []string // entity[query]:upvotes/downvotes/comments
+	}{
+		{"per entity, worst first", feedback.Filter{}, []string{"chunk-b[]:0/1/0", "pkg.Parse[]:0/1/1", "chunk-a[]:2/1/1"}},
+		{"best first", feedback.Filter{BestFirst: true, Limit: 1}, []string{"chunk-a[]:2/1/1"}},
+		{"per query", feedback.Filter{EntityID: "chunk-a", GroupByQuery: true}, []string{"chunk-a[open socket]:0/1/1", "chunk-a[parse config]:2/0/0"}},
+		{"one query", feedback.Filter{Query: "parse config", GroupByQuery: true}, []string{"chunk-b[parse config]:0/1/0", "chunk-a[parse config]:2/0/0"}},
+		{"entity type", feedback.Filter{EntityType: feedback.EntitySummary}, []string{"pkg.Parse[]:0/1/1"}},
+		{"source", feedback.Filter{Source: "searchSimilarCode"}, []string{"chunk-a[]:1/0/0"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := store.Aggregate(tt.filter)
+			if err != nil {
+				t.Fatalf("Aggregate: %v", err)
+			}
+			var keys []string
+			for _, a := range got {
+				if a.Score != a.Upvotes-a.Downvotes {
+					t.Errorf("%s has score %d", a.EntityID, a.Score)
+				}
+				keys = append(keys, fmt.Sprintf("%s[%s]:%d/%d/%d", a.EntityID, a.Query, a.Upvotes, a.Downvotes, a.Comments))
+			}
+			if fmt.Sprint(keys) != fmt.Sprint(tt.want) {
+				t.Errorf("aggregates = %v, want %v", keys, tt.want)
+			}
+		})
+	}
+}
+
+func TestAuditStoreSQLite(t *testing.T) {
+	conn := newTestSQLite(t)
+	store, err := NewAuditStore(conn.GetDB(), "my-repo", zap.NewNop())
+	if err != nil {
+		t.Fatalf("NewAuditStore: %v", err)
+	}
+	other, err := NewAuditStore(conn.GetDB(), "other-repo", zap.NewNop())
+	if err != nil {
+		t.Fatalf("NewAuditStore: %v", err)
+	}
+
+	at := func(hour int) time.Time { return time.Date(2026, 5, 1, hour, 0, 0, 0, time.UTC) }
+	entries := []*audit.Entry{
+		{Operation: audit.OpIndexBuild, Source: audit.SourceCLI, Actor: "dana", Outcome: audit.OutcomeSuccess,
+			Details: map[string]any{"files_processed": 12}, StartedAt: at(1), DurationMs: 5400},
+		{Operation: audit.OpIndexFile, Source: audit.SourceAPI, Actor: "acme", Client: "10.0.0.7", Paths: []string{"src/api/a&b.go", "src/db/c.go"},
+			Outcome: audit.OutcomeSuccess, StartedAt: at(2)},
+		{Operation: audit.OpSummaryGenerate, Source: audit.SourceAPI, Paths: []string{"src/db/c.go"},
+			Outcome: audit.OutcomeFailure, Error: "llm unavailable", StartedAt: at(3)},
+	}
+	for _, e := range entries {
+		if err := store.Record(e); err != nil {
+			t.Fatalf("Record: %v", err)
+		}
+	}
+	if err := other.Record(&audit.Entry{Operation: audit.OpClean, Source: audit.SourceCLI, Outcome: audit.OutcomeSuccess, StartedAt: at(4)}); err != nil {
+		t.Fatalf("Record: %v", err)
+	}
+
+	since := at(2)
+	tests := []struct {
+		name   string
+		filter audit.Filter
+		want   []string // operations, newest first
+	}{
+		{"all, newest first", audit.Filter{}, []string{audit.OpSummaryGenerate, audit.OpIndexFile, audit.OpIndexBuild}},
+		{"operation", audit.Filter{Operation: audit.OpIndexFile}, []string{audit.OpIndexFile}},
+		{"actor", audit.Filter{Actor: "dana"}, []string{audit.OpIndexBuild}},
+		{"outcome", audit.Filter{Outcome: audit.OutcomeFailure}, []string{audit.OpSummaryGenerate}},
+		{"path prefix includes whole-repo builds", audit.Filter{Path: "src/api/"}, []string{audit.OpIndexFile, audit.OpIndexBuild}},
+		{"path with special characters", audit.Filter{Path: "src/api/a&b"}, []string{audit.OpIndexFile, audit.OpIndexBuild}},
+		{"since", audit.Filter{Since: &since}, []string{audit.OpSummaryGenerate, audit.OpIndexFile}},
+		{"paged", audit.Filter{Limit: 1, Offset: 1}, []string{audit.OpIndexFile}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := store.List(tt.filter)
+			if err != nil {
+				t.Fatalf("List: %v", err)
+			}
+			var ops []string
+			for _, e := range got {
+				ops = append(ops, e.Operation)
+			}
+			if fmt.Sprint(ops) != fmt.Sprint(tt.want) {
+				t.Errorf("operations = %v, want %v", ops, tt.want)
+			}
+		})
+	}
+
+	got, err := store.List(audit.Filter{Operation: audit.OpIndexFile})
+	if err != nil || len(got) != 1 {
+		t.Fatalf("List = %v, %v", got, err)
+	}
+	e := got[0]
+	if e.RepoName != "my-repo" || e.Client != "10.0.0.7" || len(e.Paths) != 2 || e.Paths[0] != "src/api/a&b.go" || !e.StartedAt.Equal(at(2)) {
+		t.Errorf("unexpected entry %+v", e)
+	}
+	got, _ = store.List(audit.Filter{Operation: audit.OpIndexBuild})
+	if len(got) != 1 || got[0].Details["files_processed"] != float64(12) || got[0].DurationMs != 5400 {
+		t.Errorf("unexpected build entry %+v", got)
+	}
+}
@@ -1,19 +1,25 @@
 package db
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"strings"
+	"sync/atomic"
 	"time"
 
+	"github.com/armchr/codeapi/internal/encryption"
+	"github.com/armchr/codeapi/internal/metrics"
 	"github.com/armchr/codeapi/internal/service/summary"
 	"go.uber.org/zap"
 )
 
-// SummaryStore manages code summary storage in MySQL
+// SummaryStore manages code summary storage in the relational store
 type SummaryStore struct {
 	db       *sql.DB
+	dialect  Dialect
 	repoName string
+	ctx      context.Context
 	logger   *zap.Logger
 }
 
@@ -21,7 +27,9 @@ type SummaryStore struct {
 func NewSummaryStore(db *sql.DB, repoName string, logger *zap.Logger) (*SummaryStore, error) {
 	store := &SummaryStore{
 		db:       db,
+		dialect:  dialectFor(db),
 		repoName: repoName,
+		ctx:      context.Background(),
 		logger:   logger,
 	}
 
@@ -33,72 +41,144 @@ func NewSummaryStore(db *sql.DB, repoName string, logger *zap.Logger) (*SummaryS
 	return store, nil
 }
 
-// tableName returns the sanitized table name for this repository
-func (s *SummaryStore) tableName() string {
-	sanitized := sanitizeTableName(s.repoName)
-	return fmt.Sprintf("`%s_code_summaries`", sanitized)
+// NewSummaryReader returns a store for reads that does not create or
+// migrate the summaries table
+func NewSummaryReader(db *sql.DB, repoName string, logger *zap.Logger) *SummaryStore {
+	return &SummaryStore{
+		db:       db,
+		dialect:  dialectFor(db),
+		repoName: repoName,
+		ctx:      context.Background(),
+		logger:   logger,
+	}
 }
 
-// EnsureTable creates the code_summaries table if it doesn't exist
-func (s *SummaryStore) EnsureTable() error {
-	tableName := s.tableName()
-	s.logger.Info("Ensuring code_summaries table exists", zap.String("table", tableName))
+// summaryCiphers returns the cipher of a repository's summary text. Stores
+// are created in many places that do not see the configuration, so it is
+// set once for the process.
+var summaryCiphers atomic.Pointer[func(repoName string) (*encryption.Cipher, error)]
 
-	query := fmt.Sprintf(`
-		CREATE TABLE IF NOT EXISTS %s (
-			id BIGINT AUTO_INCREMENT PRIMARY KEY,
-			entity_id VARCHAR(255) NOT NULL,
-			entity_type VARCHAR(50) NOT NULL,
-			entity_name VARCHAR(255),
-			file_path VARCHAR(500),
-			summary TEXT NOT NULL,
-			context_hash VARCHAR(64),
-			llm_provider VARCHAR(50),
-			llm_model VARCHAR(100),
-			prompt_tokens INT DEFAULT 0,
-			output_tokens INT DEFAULT 0,
-			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
-			UNIQUE KEY idx_entity (entity_id, entity_type),
-			INDEX idx_file_path (file_path),
-			INDEX idx_entity_type (entity_type),
-			INDEX idx_context_hash (context_hash)
-		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci
-	`, tableName)
+// SetSummaryCiphers makes summary stores encrypt the summary text of the
+// repositories ciphers returns a cipher for
+func SetSummaryCiphers(ciphers func(repoName string) (*encryption.Cipher, error)) {
+	summaryCiphers.Store(&ciphers)
+}
 
-	if _, err := s.db.Exec(query); err != nil {
-		return fmt.Errorf("failed to create table: %w", err)
+// cipher returns the cipher of the store's repository, nil without one
+func (s *SummaryStore) cipher() (*encryption.Cipher, error) {
+	ciphers := summaryCiphers.Load()
+	if ciphers == nil {
+		return nil, nil
 	}
+	return (*ciphers)(s.repoName)
+}
 
-	s.logger.Info("Table ready", zap.String("table", tableName))
+// decryptSummaries decrypts the text of summaries read from the table that
+// was stored encrypted
+func (s *SummaryStore) decryptSummaries(summaries ...*summary.CodeSummary) error {
+	c, err := s.cipher()
+	if err != nil {
+		return err
+	}
+	for _, cs := range summaries {
+		text, err := c.Decrypt(cs.Summary, cs.Encryption)
+		if err != nil {
+			return fmt.Errorf("summary of %s: %w", cs.EntityID, err)
+		}
+		cs.Summary, cs.Encryption = text, ""
+	}
 	return nil
 }
 
+// RepoName returns the repository the store is scoped to
+func (s *SummaryStore) RepoName() string {
+	return s.repoName
+}
+
+// tableName returns the shared code_summaries table quoted for the active dialect
+func (s *SummaryStore) tableName() string {
+	return s.dialect.QuoteIdent(CodeSummariesTable)
+}
+
+// summaryUpsertColumns are overwritten when a summary for the same entity is
+// saved again. previous_entity_id is kept, so regenerating a carried summary
+// does not lose where it came from.
+var summaryUpsertColumns = []string{
+	"entity_name", "file_path", "summary", "encryption", "context_hash",
+	"llm_provider", "llm_model", "prompt_tokens", "output_tokens",
+}
+
+// upsertClause returns the conflict clause for saving summaries, refreshing
+// updated_at on both dialects
+func (s *SummaryStore) upsertClause() string {
+	return s.dialect.UpsertClause([]string{"repo_name", "entity_id", "entity_type"}, summaryUpsertColumns) +
+		", updated_at = CURRENT_TIMESTAMP"
+}
+
+// WithContext returns a copy of the store bound to ctx. HTTP handlers pass
+// the request context so summary queries stop with the request.
+func (s *SummaryStore) WithContext(ctx context.Context) *SummaryStore {
+	bound := *s
+	bound.ctx = ctx
+	return &bound
+}
+
+// exec, query and queryRow rebind ? placeholders for the active dialect
+func (s *SummaryStore) exec(query string, args ...any) (sql.Result, error) {
+	done := metrics.TimeStoreQuery(metrics.StoreDB, "exec", s.repoName)
+	result, err := s.db.ExecContext(s.ctx, s.dialect.Rebind(query), args...)
+	done(err)
+	return result, err
+}
+
+func (s *SummaryStore) query(query string, args ...any) (*sql.Rows, error) {
+	done := metrics.TimeStoreQuery(metrics.StoreDB, "query", s.repoName)
+	rows, err := s.db.QueryContext(s.ctx, s.dialect.Rebind(query), args...)
+	done(err)
+	return rows, err
+}
+
+func (s *SummaryStore) queryRow(query string, args ...any) *sql.Row {
+	done := metrics.TimeStoreQuery(metrics.StoreDB, "query_row", s.repoName)
+	row := s.db.QueryRowContext(s.ctx, s.dialect.Rebind(query), args...)
+	done(row.Err())
+	return row
+}
+
+// EnsureTable brings the shared code_summaries table up to the latest schema
+// version and moves this repository's legacy per-repo table into it
+func (s *SummaryStore) EnsureTable() error {
+	return EnsureSummarySchema(s.db, s.repoName, s.logger)
+}
+
 // SaveSummary saves or updates a code summary
 func (s *SummaryStore) SaveSummary(cs *summary.CodeSummary) error {
 	tableName := s.tableName()
 
 	query := fmt.Sprintf(`
-		INSERT INTO %s (entity_id, entity_type, entity_name, file_path, summary, context_hash, llm_provider, llm_model, prompt_tokens, output_tokens)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
-		ON DUPLICATE KEY UPDATE
-			entity_name = VALUES(entity_name),
-			file_path = VALUES(file_path),
-			summary = VALUES(summary),
-			context_hash = VALUES(context_hash),
-			llm_provider = VALUES(llm_provider),
-			llm_model = VALUES(llm_model),
-			prompt_tokens = VALUES(prompt_tokens),
-			output_tokens = VALUES(output_tokens),
-			updated_at = CURRENT_TIMESTAMP
-	`, tableName)
+		INSERT INTO %s (repo_name, entity_id, previous_entity_id, entity_type, entity_name, file_path, summary, encryption, context_hash, llm_provider, llm_model, prompt_tokens, output_tokens)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		%s
+	`, tableName, s.upsertClause())
 
-	_, err := s.db.Exec(query,
+	c, err := s.cipher()
+	if err != nil {
+		return err
+	}
+	text, err := c.Encrypt(cs.Summary)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt summary: %w", err)
+	}
+
+	_, err = s.exec(query,
+		s.repoName,
 		cs.EntityID,
+		cs.PreviousEntityID,
 		cs.EntityType.String(),
 		cs.EntityName,
 		cs.FilePath,
-		cs.Summary,
+		text,
+		nullString(c.Version()),
 		cs.ContextHash,
 		cs.LLMProvider,
 		cs.LLMModel,
@@ -126,18 +206,34 @@ func (s *SummaryStore) SaveSummaries(summaries []*summary.CodeSummary) error {
 
 	tableName := s.tableName()
 
+	// Postgres rejects an upsert that touches the same row twice, so keep only
+	// the last summary per entity
+	summaries = dedupeSummaries(summaries)
+
+	c, err := s.cipher()
+	if err != nil {
+		return err
+	}
+
 	// Build batch insert query
 	valueStrings := make([]string, 0, len(summaries))
-	valueArgs := make([]any, 0, len(summaries)*10)
+	valueArgs := make([]any, 0, len(summaries)*13)
 
 	for _, cs := range summaries {
-		valueStrings = append(valueStrings, "(?, ?, ?, ?, ?, ?, ?, ?, ?, ?)")
+		text, err := c.Encrypt(cs.Summary)
+		if err != nil {
+			return fmt.Errorf("failed to encrypt summary: %w", err)
+		}
+		valueStrings = append(valueStrings, "(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)")
 		valueArgs = append(valueArgs,
+			s.repoName,
 			cs.EntityID,
+			cs.PreviousEntityID,
 			cs.EntityType.String(),
 			cs.EntityName,
 			cs.FilePath,
-			cs.Summary,
+			text,
+			nullString(c.Version()),
 			cs.ContextHash,
 			cs.LLMProvider,
 			cs.LLMModel,
@@ -147,21 +243,12 @@ func (s *SummaryStore) SaveSummaries(summaries []*summary.CodeSummary) error {
 	}
 
 	query := fmt.Sprintf(`
-		INSERT INTO %s (entity_id, entity_type, entity_name, file_path, summary, context_hash, llm_provider, llm_model, prompt_tokens, output_tokens)
+		INSERT INTO %s (repo_name, entity_id, previous_entity_id, entity_type, entity_name, file_path, summary, encryption, context_hash, llm_provider, llm_model, prompt_tokens, output_tokens)
 		VALUES %s
-		ON DUPLICATE KEY UPDATE
-			entity_name = VALUES(entity_name),
-			file_path = VALUES(file_path),
-			summary = VALUES(summary),
-			context_hash = VALUES(context_hash),
-			llm_provider = VALUES(llm_provider),
-			llm_model = VALUES(llm_model),
-			prompt_tokens = VALUES(prompt_tokens),
-			output_tokens = VALUES(output_tokens),
-			updated_at = CURRENT_TIMESTAMP
-	`, tableName, strings.Join(valueStrings, ","))
-
-	_, err := s.db.Exec(query, valueArgs...)
+		%s
+	`, tableName, strings.Join(valueStrings, ","), s.upsertClause())
+
+	_, err = s.exec(query, valueArgs...)
 	if err != nil {
 		return fmt.Errorf("failed to save summaries batch: %w", err)
 	}
@@ -170,43 +257,71 @@ func (s *SummaryStore) SaveSummaries(summaries []*summary.CodeSummary) error {
 	return nil
 }
 
+// dedupeSummaries drops all but the last summary for each entity, preserving order
+func dedupeSummaries(summaries []*summary.CodeSummary) []*summary.CodeSummary {
+	type entityKey struct{ id, level string }
+	last := make(map[entityKey]int, len(summaries))
+	for i, cs := range summaries {
+		last[entityKey{cs.EntityID, cs.EntityType.String()}] = i
+	}
+	if len(last) == len(summaries) {
+		return summaries
+	}
+
+	result := make([]*summary.CodeSummary, 0, len(last))
+	for i, cs := range summaries {
+		if last[entityKey{cs.EntityID, cs.EntityType.String()}] == i {
+			result = append(result, cs)
+		}
+	}
+	return result
+}
+
 // GetSummary retrieves a summary by entity ID and type
 func (s *SummaryStore) GetSummary(entityID string, entityType summary.SummaryLevel) (*summary.CodeSummary, error) {
 	tableName := s.tableName()
 
 	query := fmt.Sprintf(`
-		SELECT id, entity_id, entity_type, entity_name, file_path, summary, context_hash, llm_provider, llm_model, prompt_tokens, output_tokens, created_at, updated_at
+		SELECT %s
 		FROM %s
-		WHERE entity_id = ? AND entity_type = ?
-	`, tableName)
-
-	var cs summary.CodeSummary
-	var entityTypeStr string
-	err := s.db.QueryRow(query, entityID, entityType.String()).Scan(
-		&cs.ID,
-		&cs.EntityID,
-		&entityTypeStr,
-		&cs.EntityName,
-		&cs.FilePath,
-		&cs.Summary,
-		&cs.ContextHash,
-		&cs.LLMProvider,
-		&cs.LLMModel,
-		&cs.PromptTokens,
-		&cs.OutputTokens,
-		&cs.CreatedAt,
-		&cs.UpdatedAt,
-	)
+		WHERE repo_name = ? AND entity_id = ? AND entity_type = ?
+	`, summaryColumns, tableName)
 
+	cs, err := scanSummary(s.queryRow(query, s.repoName, entityID, entityType.String()))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
 		}
 		return nil, fmt.Errorf("failed to get summary: %w", err)
 	}
+	if err := s.decryptSummaries(cs); err != nil {
+		return nil, err
+	}
+	return cs, nil
+}
 
-	cs.EntityType = summary.ParseSummaryLevel(entityTypeStr)
-	return &cs, nil
+// GetSummariesByEntityIDs retrieves the summaries of the given type whose
+// entity IDs are listed. IDs without a summary are left out.
+func (s *SummaryStore) GetSummariesByEntityIDs(entityType summary.SummaryLevel, entityIDs []string) ([]*summary.CodeSummary, error) {
+	if len(entityIDs) == 0 {
+		return nil, nil
+	}
+
+	placeholders := make([]string, len(entityIDs))
+	args := make([]any, 0, len(entityIDs)+2)
+	args = append(args, s.repoName, entityType.String())
+	for i, id := range entityIDs {
+		placeholders[i] = "?"
+		args = append(args, id)
+	}
+
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM %s
+		WHERE repo_name = ? AND entity_type = ? AND entity_id IN (%s)
+	`, summaryColumns, s.tableName(), strings.Join(placeholders, ", "))
+
+	return s.querySummaries(query, args...)
 }
 
 // GetSummariesByFile retrieves all summaries for a file path
@@ -214,13 +329,13 @@ func (s *SummaryStore) GetSummariesByFile(filePath string) ([]*summary.CodeSumma
 	tableName := s.tableName()
 
 	query := fmt.Sprintf(`
-		SELECT id, entity_id, entity_type, entity_name, file_path, summary, context_hash, llm_provider, llm_model, prompt_tokens, output_tokens, created_at, updated_at
+		SELECT %s
 		FROM %s
-		WHERE file_path = ?
+		WHERE repo_name = ? AND file_path = ?
 		ORDER BY entity_type, entity_name
-	`, tableName)
+	`, summaryColumns, tableName)
 
-	return s.querySummaries(query, filePath)
+	return s.querySummaries(query, s.repoName, filePath)
 }
 
 // GetSummariesByType retrieves all summaries of a specific type
@@ -228,13 +343,13 @@ func (s *SummaryStore) GetSummariesByType(entityType summary.SummaryLevel) ([]*s
 	tableName := s.tableName()
 
 	query := fmt.Sprintf(`
-		SELECT id, entity_id, entity_type, entity_name, file_path, summary, context_hash, llm_provider, llm_model, prompt_tokens, output_tokens, created_at, updated_at
+		SELECT %s
 		FROM %s
-		WHERE entity_type = ?
+		WHERE repo_name = ? AND entity_type = ?
 		ORDER BY entity_name
-	`, tableName)
+	`, summaryColumns, tableName)
 
-	return s.querySummaries(query, entityType.String())
+	return s.querySummaries(query, s.repoName, entityType.String())
 }
 
 // GetAllSummaries retrieves all summaries
@@ -242,17 +357,18 @@ func (s *SummaryStore) GetAllSummaries() ([]*summary.CodeSummary, error) {
 	tableName := s.tableName()
 
 	query := fmt.Sprintf(`
-		SELECT id, entity_id, entity_type, entity_name, file_path, summary, context_hash, llm_provider, llm_model, prompt_tokens, output_tokens, created_at, updated_at
+		SELECT %s
 		FROM %s
+		WHERE repo_name = ?
 		ORDER BY entity_type, entity_name
-	`, tableName)
+	`, summaryColumns, tableName)
 
-	return s.querySummaries(query)
+	return s.querySummaries(query, s.repoName)
 }
 
 // querySummaries is a helper to execute a query and return summaries
 func (s *SummaryStore) querySummaries(query string, args ...any) ([]*summary.CodeSummary, error) {
-	rows, err := s.db.Query(query, args...)
+	rows, err := s.query(query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query summaries: %w", err)
 	}
@@ -260,31 +376,54 @@ func (s *SummaryStore) querySummaries(query string, args ...any) ([]*summary.Cod
 
 	var summaries []*summary.CodeSummary
 	for rows.Next() {
-		var cs summary.CodeSummary
-		var entityTypeStr string
-		err := rows.Scan(
-			&cs.ID,
-			&cs.EntityID,
-			&entityTypeStr,
-			&cs.EntityName,
-			&cs.FilePath,
-			&cs.Summary,
-			&cs.ContextHash,
-			&cs.LLMProvider,
-			&cs.LLMModel,
-			&cs.PromptTokens,
-			&cs.OutputTokens,
-			&cs.CreatedAt,
-			&cs.UpdatedAt,
-		)
+		cs, err := scanSummary(rows)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan summary: %w", err)
 		}
-		cs.EntityType = summary.ParseSummaryLevel(entityTypeStr)
-		summaries = append(summaries, &cs)
+		summaries = append(summaries, cs)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := s.decryptSummaries(summaries...); err != nil {
+		return nil, err
 	}
+	return summaries, nil
+}
 
-	return summaries, rows.Err()
+// summaryColumns are the columns scanSummary reads, in order
+const summaryColumns = "id, entity_id, previous_entity_id, entity_type, entity_name, file_path, summary, encryption, context_hash, llm_provider, llm_model, prompt_tokens, output_tokens, created_at, updated_at"
+
+// scanSummary scans a row selected with summaryColumns
+func scanSummary(row rowScanner) (*summary.CodeSummary, error) {
+	var cs summary.CodeSummary
+	var previousEntityID, encryptionVersion sql.NullString
+	var entityTypeStr string
+	err := row.Scan(
+		&cs.ID,
+		&cs.EntityID,
+		&previousEntityID,
+		&entityTypeStr,
+		&cs.EntityName,
+		&cs.FilePath,
+		&cs.Summary,
+		&encryptionVersion,
+		&cs.ContextHash,
+		&cs.LLMProvider,
+		&cs.LLMModel,
+		&cs.PromptTokens,
+		&cs.OutputTokens,
+		&cs.CreatedAt,
+		&cs.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	cs.PreviousEntityID = previousEntityID.String
+	cs.Encryption = encryptionVersion.String
+	cs.EntityType = summary.ParseSummaryLevel(entityTypeStr)
+	return &cs, nil
 }
 
 // NeedsUpdate checks if a summary needs to be regenerated based on context hash
@@ -307,8 +446,32 @@ func (s *SummaryStore) NeedsUpdate(entityID string, entityType summary.SummaryLe
 func (s *SummaryStore) DeleteByFile(filePath string) (int64, error) {
 	tableName := s.tableName()
 
-	query := fmt.Sprintf(`DELETE FROM %s WHERE file_path = ?`, tableName)
-	result, err := s.db.Exec(query, filePath)
+	query := fmt.Sprintf(`DELETE FROM %s WHERE repo_name = ? AND file_path = ?`, tableName)
+	result, err := s.exec(query, s.repoName, filePath)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete summaries: %w", err)
+	}
+
+	return result.RowsAffected()
+}
+
+// DeleteByEntityIDs deletes summaries of the given type whose entity IDs are listed
+func (s *SummaryStore) DeleteByEntityIDs(entityType summary.SummaryLevel, entityIDs []string) (int64, error) {
+	if len(entityIDs) == 0 {
+		return 0, nil
+	}
+
+	placeholders := make([]string, len(entityIDs))
+	args := make([]any, 0, len(entityIDs)+2)
+	args = append(args, s.repoName, entityType.String())
+	for i, id := range entityIDs {
+		placeholders[i] = "?"
+		args = append(args, id)
+	}
+
+	query := fmt.Sprintf(`DELETE FROM %s WHERE repo_name = ? AND entity_type = ? AND entity_id IN (%s)`,
+		s.tableName(), strings.Join(placeholders, ", "))
+	result, err := s.exec(query, args...)
 	if err != nil {
 		return 0, fmt.Errorf("failed to delete summaries: %w", err)
 	}
@@ -320,8 +483,8 @@ func (s *SummaryStore) DeleteByFile(filePath string) (int64, error) {
 func (s *SummaryStore) DeleteByType(entityType summary.SummaryLevel) (int64, error) {
 	tableName := s.tableName()
 
-	query := fmt.Sprintf(`DELETE FROM %s WHERE entity_type = ?`, tableName)
-	result, err := s.db.Exec(query, entityType.String())
+	query := fmt.Sprintf(`DELETE FROM %s WHERE repo_name = ? AND entity_type = ?`, tableName)
+	result, err := s.exec(query, s.repoName, entityType.String())
 	if err != nil {
 		return 0, fmt.Errorf("failed to delete summaries: %w", err)
 	}
@@ -333,8 +496,8 @@ func (s *SummaryStore) DeleteByType(entityType summary.SummaryLevel) (int64, err
 func (s *SummaryStore) DeleteAll() (int64, error) {
 	tableName := s.tableName()
 
-	query := fmt.Sprintf(`DELETE FROM %s`, tableName)
-	result, err := s.db.Exec(query)
+	query := fmt.Sprintf(`DELETE FROM %s WHERE repo_name = ?`, tableName)
+	result, err := s.exec(query, s.repoName)
 	if err != nil {
 		return 0, fmt.Errorf("failed to delete all summaries: %w", err)
 	}
@@ -357,10 +520,11 @@ func (s *SummaryStore) GetStats() (*SummaryStats, error) {
 			COALESCE(SUM(prompt_tokens), 0) as total_prompt_tokens,
 			COALESCE(SUM(output_tokens), 0) as total_output_tokens
 		FROM %s
+		WHERE repo_name = ?
 	`, tableName)
 
 	var stats SummaryStats
-	err := s.db.QueryRow(query).Scan(
+	err := s.queryRow(query, s.repoName).Scan(
 		&stats.Total,
 		&stats.Functions,
 		&stats.Classes,
@@ -390,23 +554,6 @@ type SummaryStats struct {
 	TotalOutputTokens int64 `json:"total_output_tokens"`
 }
 
-// DropTable drops the summaries table for this repository
-func (s *SummaryStore) DropTable() error {
-	tableName := s.tableName()
-
-	s.logger.Info("Dropping code summaries table", zap.String("table", tableName))
-
-	query := fmt.Sprintf(`DROP TABLE IF EXISTS %s`, tableName)
-
-	_, err := s.db.Exec(query)
-	if err != nil {
-		return fmt.Errorf("failed to drop table %s: %w", tableName, err)
-	}
-
-	s.logger.Info("Code summaries table dropped successfully", zap.String("table", tableName))
-	return nil
-}
-
 // GetSummaryMap returns a map of entity ID to summary for quick lookups
 func (s *SummaryStore) GetSummaryMap(entityType summary.SummaryLevel) (map[string]*summary.CodeSummary, error) {
 	summaries, err := s.GetSummariesByType(entityType)
@@ -427,13 +574,13 @@ func (s *SummaryStore) GetRecentSummaries(since time.Time) ([]*summary.CodeSumma
 	tableName := s.tableName()
 
 	query := fmt.Sprintf(`
-		SELECT id, entity_id, entity_type, entity_name, file_path, summary, context_hash, llm_provider, llm_model, prompt_tokens, output_tokens, created_at, updated_at
+		SELECT %s
 		FROM %s
-		WHERE updated_at > ?
+		WHERE repo_name = ? AND updated_at > ?
 		ORDER BY updated_at DESC
-	`, tableName)
+	`, summaryColumns, tableName)
 
-	return s.querySummaries(query, since)
+	return s.querySummaries(query, s.repoName, since.UTC())
 }
 
 // GetSummariesByFileAndType retrieves summaries for a file filtered by entity type
@@ -441,13 +588,13 @@ func (s *SummaryStore) GetSummariesByFileAndType(filePath string, entityType sum
 	tableName := s.tableName()
 
 	query := fmt.Sprintf(`
-		SELECT id, entity_id, entity_type, entity_name, file_path, summary, context_hash, llm_provider, llm_model, prompt_tokens, output_tokens, created_at, updated_at
+		SELECT %s
 		FROM %s
-		WHERE file_path = ? AND entity_type = ?
+		WHERE repo_name = ? AND file_path = ? AND entity_type = ?
 		ORDER BY entity_name
-	`, tableName)
+	`, summaryColumns, tableName)
 
-	return s.querySummaries(query, filePath, entityType.String())
+	return s.querySummaries(query, s.repoName, filePath, entityType.String())
 }
 
 // GetSummaryByFileAndName retrieves a specific summary by file path, entity type and name
@@ -455,38 +602,22 @@ func (s *SummaryStore) GetSummaryByFileAndName(filePath string, entityType summa
 	tableName := s.tableName()
 
 	query := fmt.Sprintf(`
-		SELECT id, entity_id, entity_type, entity_name, file_path, summary, context_hash, llm_provider, llm_model, prompt_tokens, output_tokens, created_at, updated_at
+		SELECT %s
 		FROM %s
-		WHERE file_path = ? AND entity_type = ? AND entity_name = ?
-	`, tableName)
-
-	var cs summary.CodeSummary
-	var entityTypeStr string
-	err := s.db.QueryRow(query, filePath, entityType.String(), entityName).Scan(
-		&cs.ID,
-		&cs.EntityID,
-		&entityTypeStr,
-		&cs.EntityName,
-		&cs.FilePath,
-		&cs.Summary,
-		&cs.ContextHash,
-		&cs.LLMProvider,
-		&cs.LLMModel,
-		&cs.PromptTokens,
-		&cs.OutputTokens,
-		&cs.CreatedAt,
-		&cs.UpdatedAt,
-	)
+		WHERE repo_name = ? AND file_path = ? AND entity_type = ? AND entity_name = ?
+	`, summaryColumns, tableName)
 
+	cs, err := scanSummary(s.queryRow(query, s.repoName, filePath, entityType.String(), entityName))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
 		}
 		return nil, fmt.Errorf("failed to get summary: %w", err)
 	}
-
-	cs.EntityType = summary.ParseSummaryLevel(entityTypeStr)
-	return &cs, nil
+	if err := s.decryptSummaries(cs); err != nil {
+		return nil, err
+	}
+	return cs, nil
 }
 
 // GetFileSummary retrieves the file-level summary for a file path
@@ -494,36 +625,20 @@ func (s *SummaryStore) GetFileSummary(filePath string) (*summary.CodeSummary, er
 	tableName := s.tableName()
 
 	query := fmt.Sprintf(`
-		SELECT id, entity_id, entity_type, entity_name, file_path, summary, context_hash, llm_provider, llm_model, prompt_tokens, output_tokens, created_at, updated_at
+		SELECT %s
 		FROM %s
-		WHERE file_path = ? AND entity_type = 'file'
-	`, tableName)
-
-	var cs summary.CodeSummary
-	var entityTypeStr string
-	err := s.db.QueryRow(query, filePath).Scan(
-		&cs.ID,
-		&cs.EntityID,
-		&entityTypeStr,
-		&cs.EntityName,
-		&cs.FilePath,
-		&cs.Summary,
-		&cs.ContextHash,
-		&cs.LLMProvider,
-		&cs.LLMModel,
-		&cs.PromptTokens,
-		&cs.OutputTokens,
-		&cs.CreatedAt,
-		&cs.UpdatedAt,
-	)
+		WHERE repo_name = ? AND file_path = ? AND entity_type = 'file'
+	`, summaryColumns, tableName)
 
+	cs, err := scanSummary(s.queryRow(query, s.repoName, filePath))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
 		}
 		return nil, fmt.Errorf("failed to get file summary: %w", err)
 	}
-
-	cs.EntityType = summary.ParseSummaryLevel(entityTypeStr)
-	return &cs, nil
+	if err := s.decryptSummaries(cs); err != nil {
+		return nil, err
+	}
+	return cs, nil
 }
@@ -0,0 +1,80 @@
+package db
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/armchr/codeapi/internal/encryption"
+	"github.com/armchr/codeapi/internal/service/summary"
+	"go.uber.org/zap"
+)
+
+func TestSummaryEncryption(t *testing.T) {
+	conn := newTestSQLite(t)
+	key := bytes.Repeat([]byte{7}, encryption.KeySize)
+	SetSummaryCiphers(func(repoName string) (*encryption.Cipher, error) {
+		if repoName != "secret" {
+			return nil, nil
+		}
+		return encryption.NewCipher(key, repoName)
+	})
+	t.Cleanup(func() { summaryCiphers.Store(nil) })
+
+	for _, repo := range []string{"secret", "plain"} {
+		store, err := NewSummaryStore(conn.GetDB(), repo, zap.NewNop())
+		if err != nil {
+			t.Fatalf("NewSummaryStore: %v", err)
+		}
+		if err := store.SaveSummary(&summary.CodeSummary{EntityID: "f1", EntityType: summary.LevelFile, FilePath: "a.go", Summary: "Parses a.go"}); err != nil {
+			t.Fatalf("SaveSummary: %v", err)
+		}
+		if err := store.SaveSummaries([]*summary.CodeSummary{{EntityID: "f2", EntityType: summary.LevelFile, FilePath: "b.go", Summary: "Parses b.go"}}); err != nil {
+			t.Fatalf("SaveSummaries: %v", err)
+		}
+
+		var stored []string
+		rows, err := conn.GetDB().Query(`SELECT summary FROM code_summaries WHERE repo_name = ? ORDER BY entity_id`, repo)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for rows.Next() {
+			var text string
+			rows.Scan(&text)
+			stored = append(stored, text)
+		}
+		rows.Close()
+		for _, text := range stored {
+			if encrypted := !strings.Contains(text, "Parses"); encrypted != (repo == "secret") {
+				t.Errorf("%s: stored summary %q", repo, text)
+			}
+		}
+
+		all, err := store.GetAllSummaries()
+		if err != nil || len(all) != 2 || all[0].Summary != "Parses a.go" || all[1].Summary != "Parses b.go" {
+			t.Errorf("%s: GetAllSummaries() = %v, %v", repo, all, err)
+		}
+		if cs, err := store.GetFileSummary("b.go"); err != nil || cs.Summary != "Parses b.go" {
+			t.Errorf("%s: GetFileSummary() = %v, %v", repo, cs, err)
+		}
+	}
+
+	// Plaintext is told from ciphertext by the stored version, not its form
+	lookalike := "enc:v1:" + strings.Repeat("A", 40)
+	plain := NewSummaryReader(conn.GetDB(), "plain", zap.NewNop())
+	if err := plain.SaveSummary(&summary.CodeSummary{EntityID: "f3", EntityType: summary.LevelFile, FilePath: "c.go", Summary: lookalike}); err != nil {
+		t.Fatalf("SaveSummary: %v", err)
+	}
+	if cs, err := plain.GetSummary("f3", summary.LevelFile); err != nil || cs.Summary != lookalike {
+		t.Errorf("GetSummary() of plaintext in the encrypted form = %v, %v", cs, err)
+	}
+
+	// Another key cannot read the summaries
+	SetSummaryCiphers(func(repoName string) (*encryption.Cipher, error) {
+		return encryption.NewCipher(bytes.Repeat([]byte{8}, encryption.KeySize), repoName)
+	})
+	if _, err := NewSummaryReader(conn.GetDB(), "secret", zap.NewNop()).GetSummary("f1", summary.LevelFile); !errors.Is(err, encryption.ErrDecrypt) {
+		t.Errorf("GetSummary() with another key error = %v, want ErrDecrypt", err)
+	}
+}
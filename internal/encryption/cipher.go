@@ -0,0 +1,129 @@
+// Package encryption encrypts the text a repository stores outside its
+// source tree (summaries and chunk content) with a key held in the secrets
+// provider, so a dump of the stores does not reveal the code.
+package encryption
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Version names the format Encrypt writes. It is stored next to each
+// encrypted value, in its own column or payload field, since only that
+// tells an encrypted value from plaintext that happens to look like one.
+const Version = "v1"
+
+// prefix starts every value of the current format
+const prefix = "enc:" + Version + ":"
+
+// KeySize is the length of a key in bytes (AES-256)
+const KeySize = 32
+
+// ErrDecrypt is returned for a value that was encrypted with another key,
+// for another repository or was altered
+var ErrDecrypt = errors.New("failed to decrypt value")
+
+// Cipher encrypts the values of one repository with AES-GCM. The repository
+// name is authenticated with every value, so a value copied to another
+// repository of the same tenant does not decrypt. A nil Cipher leaves values
+// unchanged.
+type Cipher struct {
+	aead        cipher.AEAD
+	repoName    string
+	decryptOnly bool
+}
+
+// ParseKey decodes a base64 key of KeySize bytes
+func ParseKey(encoded string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return nil, fmt.Errorf("key is not valid base64: %w", err)
+	}
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("key is %d bytes, want %d", len(key), KeySize)
+	}
+	return key, nil
+}
+
+// NewCipher returns the cipher of a repository
+func NewCipher(key []byte, repoName string) (*Cipher, error) {
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("key is %d bytes, want %d", len(key), KeySize)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &Cipher{aead: aead, repoName: repoName}, nil
+}
+
+// DecryptOnly returns a copy of the cipher that writes plaintext but still
+// reads encrypted values, for a repository whose encryption was turned off
+func (c *Cipher) DecryptOnly() *Cipher {
+	if c == nil {
+		return nil
+	}
+	copied := *c
+	copied.decryptOnly = true
+	return &copied
+}
+
+// Encrypt returns value encrypted under a fresh nonce. Empty values stay
+// empty, they reveal nothing and keep "no summary" checks working.
+func (c *Cipher) Encrypt(value string) (string, error) {
+	if c == nil || c.decryptOnly || value == "" {
+		return value, nil
+	}
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	sealed := c.aead.Seal(nonce, nonce, []byte(value), []byte(c.repoName))
+	return prefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Version returns the version to store next to the values Encrypt returns,
+// empty when it returns them unchanged
+func (c *Cipher) Version() string {
+	if c == nil || c.decryptOnly {
+		return ""
+	}
+	return Version
+}
+
+// Decrypt reverses Encrypt for a value stored with version. Values stored
+// without a version are plaintext and are returned unchanged, so a
+// repository can turn encryption on without rewriting what it stored.
+func (c *Cipher) Decrypt(value, version string) (string, error) {
+	if version == "" || value == "" {
+		return value, nil
+	}
+	if version != Version {
+		return "", fmt.Errorf("%w: unknown encryption version %q", ErrDecrypt, version)
+	}
+	if c == nil {
+		return "", fmt.Errorf("%w: repository has no encryption key", ErrDecrypt)
+	}
+	if !strings.HasPrefix(value, prefix) {
+		return "", ErrDecrypt
+	}
+	sealed, err := base64.StdEncoding.DecodeString(value[len(prefix):])
+	if err != nil || len(sealed) < c.aead.NonceSize() {
+		return "", ErrDecrypt
+	}
+	nonce, ciphertext := sealed[:c.aead.NonceSize()], sealed[c.aead.NonceSize():]
+	plain, err := c.aead.Open(nil, nonce, ciphertext, []byte(c.repoName))
+	if err != nil {
+		return "", ErrDecrypt
+	}
+	return string(plain), nil
+}
@@ -0,0 +1,115 @@
+package encryption
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func testKey(b byte) []byte {
+	return bytes.Repeat([]byte{b}, KeySize)
+}
+
+func TestParseKey(t *testing.T) {
+	tests := []struct {
+		name    string
+		encoded string
+		wantErr bool
+	}{
+		{"valid", base64.StdEncoding.EncodeToString(testKey(1)), false},
+		{"surrounding whitespace", " " + base64.StdEncoding.EncodeToString(testKey(1)) + "\n", false},
+		{"not base64", "not a key!", true},
+		{"too short", base64.StdEncoding.EncodeToString(testKey(1)[:16]), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, err := ParseKey(tt.encoded)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseKey() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && !bytes.Equal(key, testKey(1)) {
+				t.Errorf("ParseKey() = %x", key)
+			}
+		})
+	}
+}
+
+func TestCipherRoundTrip(t *testing.T) {
+	c, err := NewCipher(testKey(1), "acme__api")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, value := range []string{"", "func main() {}", strings.Repeat("é", 1000)} {
+		encrypted, err := c.Encrypt(value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if value != "" && (encrypted == value || !strings.HasPrefix(encrypted, prefix)) {
+			t.Errorf("Encrypt(%q) = %q", value, encrypted)
+		}
+		decrypted, err := c.Decrypt(encrypted, c.Version())
+		if err != nil || decrypted != value {
+			t.Errorf("Decrypt(Encrypt(%q)) = %q, %v", value, decrypted, err)
+		}
+	}
+
+	readOnly := c.DecryptOnly()
+	if got, _ := readOnly.Encrypt("plain"); got != "plain" || readOnly.Version() != "" {
+		t.Errorf("decrypt-only Encrypt() = %q under version %q, want plaintext", got, readOnly.Version())
+	}
+	encrypted, _ := c.Encrypt("value")
+	if got, err := readOnly.Decrypt(encrypted, Version); err != nil || got != "value" {
+		t.Errorf("decrypt-only Decrypt() = %q, %v", got, err)
+	}
+
+	first, _ := c.Encrypt("same")
+	second, _ := c.Encrypt("same")
+	if first == second {
+		t.Error("equal values encrypted to the same text")
+	}
+}
+
+func TestDecrypt(t *testing.T) {
+	c, _ := NewCipher(testKey(1), "acme__api")
+	encrypted, _ := c.Encrypt("secret")
+	otherKey, _ := NewCipher(testKey(2), "acme__api")
+	otherRepo, _ := NewCipher(testKey(1), "acme__web")
+
+	tests := []struct {
+		name    string
+		cipher  *Cipher
+		value   string
+		version string
+		want    string
+		wantErr bool
+	}{
+		{name: "plaintext passes through", cipher: c, value: "legacy", want: "legacy"},
+		{name: "nil cipher passes plaintext", value: "legacy", want: "legacy"},
+		{name: "plaintext that looks encrypted", cipher: c, value: encrypted, want: encrypted},
+		{name: "encrypted", cipher: c, value: encrypted, version: Version, want: "secret"},
+		{name: "empty", cipher: c, version: Version},
+		{name: "nil cipher cannot decrypt", value: encrypted, version: Version, wantErr: true},
+		{name: "unknown version", cipher: c, value: encrypted, version: "v0", wantErr: true},
+		{name: "no prefix", cipher: c, value: "secret", version: Version, wantErr: true},
+		{name: "other key", cipher: otherKey, value: encrypted, version: Version, wantErr: true},
+		{name: "other repository", cipher: otherRepo, value: encrypted, version: Version, wantErr: true},
+		{name: "truncated", cipher: c, value: encrypted[:len(prefix)+4], version: Version, wantErr: true},
+		{name: "altered", cipher: c, value: encrypted[:len(encrypted)-4] + "AAA=", version: Version, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.cipher.Decrypt(tt.value, tt.version)
+			if tt.wantErr {
+				if !errors.Is(err, ErrDecrypt) {
+					t.Errorf("Decrypt() error = %v, want ErrDecrypt", err)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("Decrypt() = %q, %v, want %q", got, err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,128 @@
+// Package entity defines the public reference of a file, class or function,
+// the one identifier of an entity shared by every endpoint. Graph node IDs,
+// summary entity IDs and chunk IDs change with each indexed version of a
+// file and differ per store; a reference names the entity itself:
+//
+//	<repo>:<kind>:<path>[#<qualified name>]
+//
+// for example "shop:file:src/order.go", "shop:class:src/order.go#Order" or
+// "shop:function:src/order.go#Order.Total". The qualified name of a method
+// is its class name, a dot and its name; that of a top-level function is its
+// name. Overloads share a reference.
+package entity
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Kind is the kind of entity a reference names
+type Kind string
+
+// Kinds of entities
+const (
+	KindFile     Kind = "file"
+	KindClass    Kind = "class"
+	KindFunction Kind = "function" // functions and methods
+)
+
+// ErrInvalidRef is returned by Parse for malformed references
+var ErrInvalidRef = errors.New("invalid entity reference")
+
+// Ref is a parsed entity reference
+type Ref struct {
+	Repo string
+	Kind Kind
+	Path string // relative to the repository root
+	Name string // qualified name; empty for files
+}
+
+// File returns the reference of a file
+func File(repo, path string) Ref {
+	return Ref{Repo: repo, Kind: KindFile, Path: path}
+}
+
+// Class returns the reference of a class declared in path
+func Class(repo, path, name string) Ref {
+	return Ref{Repo: repo, Kind: KindClass, Path: path, Name: name}
+}
+
+// Function returns the reference of a function declared in path, a method
+// when className is set
+func Function(repo, path, className, name string) Ref {
+	if className != "" {
+		name = className + "." + name
+	}
+	return Ref{Repo: repo, Kind: KindFunction, Path: path, Name: name}
+}
+
+// String formats the reference, the form Parse reads
+func (r Ref) String() string {
+	s := r.Repo + ":" + string(r.Kind) + ":" + r.Path
+	if r.Kind != KindFile {
+		s += "#" + r.Name
+	}
+	return s
+}
+
+// ClassName returns the class of a method or the name of a class, and ""
+// for top-level functions and files
+func (r Ref) ClassName() string {
+	switch r.Kind {
+	case KindClass:
+		return r.Name
+	case KindFunction:
+		if i := strings.LastIndex(r.Name, "."); i > 0 {
+			return r.Name[:i]
+		}
+	}
+	return ""
+}
+
+// FunctionName returns the unqualified name of a function or method
+func (r Ref) FunctionName() string {
+	if r.Kind != KindFunction {
+		return ""
+	}
+	return r.Name[strings.LastIndex(r.Name, ".")+1:]
+}
+
+// WithRepo returns the reference with its repository replaced, such as by
+// the name qualified with a tenant
+func (r Ref) WithRepo(repo string) Ref {
+	r.Repo = repo
+	return r
+}
+
+// Parse reads a reference formatted by Ref.String. The repository name ends
+// at the first colon, so repositories with a colon in their name cannot be
+// referenced.
+func Parse(s string) (Ref, error) {
+	repo, rest, ok := strings.Cut(s, ":")
+	if !ok || repo == "" {
+		return Ref{}, fmt.Errorf("%w %q: expected <repo>:<kind>:<path>[#<name>]", ErrInvalidRef, s)
+	}
+	kind, rest, ok := strings.Cut(rest, ":")
+	if !ok {
+		return Ref{}, fmt.Errorf("%w %q: expected <repo>:<kind>:<path>[#<name>]", ErrInvalidRef, s)
+	}
+
+	ref := Ref{Repo: repo, Kind: Kind(kind), Path: rest}
+	switch ref.Kind {
+	case KindFile:
+	case KindClass, KindFunction:
+		// Paths may hold a '#', names do not
+		i := strings.LastIndex(rest, "#")
+		if i < 0 || i == len(rest)-1 {
+			return Ref{}, fmt.Errorf("%w %q: a %s needs #<name> after its path", ErrInvalidRef, s, kind)
+		}
+		ref.Path, ref.Name = rest[:i], rest[i+1:]
+	default:
+		return Ref{}, fmt.Errorf("%w %q: unknown kind %q, expected file, class or function", ErrInvalidRef, s, kind)
+	}
+	if ref.Path == "" {
+		return Ref{}, fmt.Errorf("%w %q: missing path", ErrInvalidRef, s)
+	}
+	return ref, nil
+}
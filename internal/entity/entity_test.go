@@ -0,0 +1,68 @@
+package entity
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Ref
+	}{
+		{"shop:file:src/order.go", File("shop", "src/order.go")},
+		{"shop:class:src/order.go#Order", Class("shop", "src/order.go", "Order")},
+		{"shop:function:src/order.go#Order.Total", Function("shop", "src/order.go", "Order", "Total")},
+		{"shop:function:main.go#main", Function("shop", "main.go", "", "main")},
+		{"acme__shop:function:docs/c#/a.cs#A.B.Run", Function("acme__shop", "docs/c#/a.cs", "A.B", "Run")},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.in)
+		if err != nil {
+			t.Errorf("Parse(%q) error = %v", tt.in, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Parse(%q) = %+v, want %+v", tt.in, got, tt.want)
+		}
+		if got.String() != tt.in {
+			t.Errorf("Parse(%q).String() = %q", tt.in, got.String())
+		}
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, in := range []string{
+		"",
+		"shop",
+		":file:a.go",
+		"shop:file:",
+		"shop:class:a.go",
+		"shop:function:a.go#",
+		"shop:field:a.go#x",
+	} {
+		if _, err := Parse(in); !errors.Is(err, ErrInvalidRef) {
+			t.Errorf("Parse(%q) error = %v, want ErrInvalidRef", in, err)
+		}
+	}
+}
+
+func TestRefNames(t *testing.T) {
+	tests := []struct {
+		ref           Ref
+		class, method string
+	}{
+		{Function("shop", "a.py", "Outer.Inner", "run"), "Outer.Inner", "run"},
+		{Function("shop", "a.go", "", "main"), "", "main"},
+		{Class("shop", "a.java", "Order"), "Order", ""},
+		{File("shop", "a.go"), "", ""},
+	}
+	for _, tt := range tests {
+		if got := tt.ref.ClassName(); got != tt.class {
+			t.Errorf("%s ClassName() = %q, want %q", tt.ref, got, tt.class)
+		}
+		if got := tt.ref.FunctionName(); got != tt.method {
+			t.Errorf("%s FunctionName() = %q, want %q", tt.ref, got, tt.method)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+// Package golden indexes the small per-language fixture repositories under
+// tests/repos into an in-memory code graph and renders each with the graph
+// dump, so that visitor regressions show up as differences to the dumps
+// checked in under tests/golden/parse.
+//
+// Only the parser runs: there is no language server, so the dumps hold the
+// nodes and edges of each file as the visitors create them and none of the
+// call edges post-processing adds.
+package golden
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/armchr/codeapi/internal/config"
+	"github.com/armchr/codeapi/internal/parseonly"
+
+	"go.uber.org/zap"
+)
+
+// Fixture is a repository under tests/repos with a golden dump
+type Fixture struct {
+	Name     string
+	Language string
+}
+
+// Fixtures are the calculator repositories, one per language visitor. The
+// larger repositories under tests/repos are left to tests/run_tests.sh.
+var Fixtures = []Fixture{
+	{Name: "python-calculator", Language: "python"},
+	{Name: "go-calculator", Language: "go"},
+	{Name: "typescript-calculator", Language: "typescript"},
+	{Name: "java-modern-calculator", Language: "java"},
+	{Name: "java8-calculator", Language: "java"},
+	{Name: "csharp-service", Language: "csharp"},
+}
+
+// Repository returns the fixture as a repository rooted in reposDir
+func (f Fixture) Repository(reposDir string) *config.Repository {
+	return &config.Repository{Name: f.Name, Path: filepath.Join(reposDir, f.Name), Language: f.Language}
+}
+
+// GoldenPath returns the path of the fixture's golden dump in goldenDir
+func (f Fixture) GoldenPath(goldenDir string) string {
+	return filepath.Join(goldenDir, f.Name+".dump.txt")
+}
+
+// Dump parses every file of the fixture and returns the graph dump, without
+// the line recording when it was generated
+func (f Fixture) Dump(ctx context.Context, reposDir string) ([]byte, error) {
+	repo := f.Repository(reposDir)
+	graph, err := parseonly.Parse(ctx, repo, &config.Config{}, zap.NewNop())
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := graph.Dump(ctx, &buf, []string{repo.Name}); err != nil {
+		return nil, err
+	}
+	return StripTimestamp(buf.Bytes()), nil
+}
+
+// StripTimestamp removes the "# Generated at:" line from a dump, which is
+// the only part that differs between runs
+func StripTimestamp(dump []byte) []byte {
+	var out []byte
+	for _, line := range bytes.SplitAfter(dump, []byte("\n")) {
+		if !bytes.HasPrefix(line, []byte("# Generated at:")) {
+			out = append(out, line...)
+		}
+	}
+	return out
+}
+
+// Diff describes the first lines where got differs from want, or returns ""
+// when they are equal
+func Diff(want, got []byte, context int) string {
+	if bytes.Equal(want, got) {
+		return ""
+	}
+	wantLines := strings.Split(string(want), "\n")
+	gotLines := strings.Split(string(got), "\n")
+	first := 0
+	for first < len(wantLines) && first < len(gotLines) && wantLines[first] == gotLines[first] {
+		first++
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "first difference at line %d (golden has %d lines, output %d)\n", first+1, len(wantLines), len(gotLines))
+	for i := first; i < first+context; i++ {
+		if i < len(wantLines) {
+			fmt.Fprintf(&b, "- %s\n", wantLines[i])
+		}
+		if i < len(gotLines) {
+			fmt.Fprintf(&b, "+ %s\n", gotLines[i])
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,79 @@
+package golden
+
+import (
+	"context"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var updateGolden = flag.Bool("update-golden", false, "rewrite the golden dumps from the current parser output")
+
+const (
+	reposDir  = "../../tests/repos"
+	goldenDir = "../../tests/golden/parse"
+)
+
+// TestGoldenDumps compares the graph of every fixture with its golden dump.
+// After an intended change to a visitor, review the differences and run
+//
+//	go test ./internal/golden -update-golden
+func TestGoldenDumps(t *testing.T) {
+	for _, f := range Fixtures {
+		t.Run(f.Name, func(t *testing.T) {
+			if _, err := os.Stat(filepath.Join(reposDir, f.Name)); err != nil {
+				t.Skipf("fixture repository not available: %v", err)
+			}
+			got, err := f.Dump(context.Background(), reposDir)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			path := f.GoldenPath(goldenDir)
+			if *updateGolden {
+				if err := os.MkdirAll(goldenDir, 0o755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, got, 0o644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+
+			want, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("no golden dump, create it with -update-golden: %v", err)
+			}
+			if diff := Diff(want, got, 10); diff != "" {
+				t.Errorf("graph differs from %s, rerun with -update-golden if intended:\n%s", path, diff)
+			}
+		})
+	}
+}
+
+func TestDumpIsDeterministic(t *testing.T) {
+	f := Fixtures[0]
+	if _, err := os.Stat(filepath.Join(reposDir, f.Name)); err != nil {
+		t.Skipf("fixture repository not available: %v", err)
+	}
+	first, err := f.Dump(context.Background(), reposDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := f.Dump(context.Background(), reposDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := Diff(first, second, 5); diff != "" {
+		t.Errorf("two dumps of %s differ:\n%s", f.Name, diff)
+	}
+}
+
+func TestStripTimestamp(t *testing.T) {
+	dump := "# Code Graph Dump\n# Repositories: r\n# Generated at: 2026-01-01T00:00:00Z\n\nbody\n"
+	want := "# Code Graph Dump\n# Repositories: r\n\nbody\n"
+	if got := string(StripTimestamp([]byte(dump))); got != want {
+		t.Errorf("StripTimestamp() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,85 @@
+package handler
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/armchr/codeapi/internal/config"
+	"github.com/armchr/codeapi/internal/metrics"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ConcurrencyLimits holds one semaphore per endpoint class. The server
+// creates it once and hands it to every router it builds, so requests still
+// running on a replaced router keep holding their slots.
+type ConcurrencyLimits struct {
+	queueTimeout time.Duration
+	slots        map[string]chan struct{}
+}
+
+// NewConcurrencyLimits creates the semaphores of the configured classes.
+// Classes with a negative limit, or all of them when limits are disabled,
+// are not bounded.
+func NewConcurrencyLimits(cfg config.ConcurrencyConfig) *ConcurrencyLimits {
+	limits := &ConcurrencyLimits{slots: make(map[string]chan struct{})}
+	if cfg.Disabled {
+		return limits
+	}
+	cfg = cfg.GetDefaults()
+	limits.queueTimeout = cfg.QueueTimeout()
+	for class, limit := range cfg.Limits {
+		if limit > 0 {
+			limits.slots[class] = make(chan struct{}, limit)
+		}
+	}
+	return limits
+}
+
+// Limit admits at most the class's limit of requests at once. Requests over
+// the limit queue for up to the queue timeout and are then rejected with 429
+// and a Retry-After header, instead of piling up behind each other while
+// holding connections, LLM calls and graph traversals.
+func (l *ConcurrencyLimits) Limit(class string) gin.HandlerFunc {
+	var slots chan struct{}
+	if l != nil {
+		slots = l.slots[class]
+	}
+	if slots == nil {
+		return func(c *gin.Context) { c.Next() }
+	}
+
+	retryAfter := strconv.Itoa(int(math.Ceil(l.queueTimeout.Seconds())))
+	return func(c *gin.Context) {
+		start := time.Now()
+		select {
+		case slots <- struct{}{}:
+		default:
+			timer := time.NewTimer(l.queueTimeout)
+			defer timer.Stop()
+			select {
+			case slots <- struct{}{}:
+			case <-timer.C:
+				metrics.LimitedRequestRejected(class)
+				c.Header("Retry-After", retryAfter)
+				c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+					"error": "too many concurrent " + class + " requests, retry later",
+				})
+				return
+			case <-c.Request.Context().Done():
+				// The client went away or the request deadline passed while queued
+				c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": "request cancelled while queued"})
+				return
+			}
+		}
+
+		metrics.LimitedRequestStarted(class, time.Since(start))
+		defer func() {
+			<-slots
+			metrics.LimitedRequestDone(class)
+		}()
+		c.Next()
+	}
+}
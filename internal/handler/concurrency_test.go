@@ -0,0 +1,108 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/armchr/codeapi/internal/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestConcurrencyLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	limits := &ConcurrencyLimits{
+		queueTimeout: 200 * time.Millisecond,
+		slots:        map[string]chan struct{}{config.EndpointTraversal: make(chan struct{}, 1)},
+	}
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	router := gin.New()
+	router.GET("/slow", limits.Limit(config.EndpointTraversal), func(c *gin.Context) {
+		entered <- struct{}{}
+		<-release
+		c.Status(http.StatusOK)
+	})
+	router.GET("/search", limits.Limit(config.EndpointSummaries), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	serve := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+
+	var wg sync.WaitGroup
+	var first, queued *httptest.ResponseRecorder
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		first = serve("/slow")
+	}()
+	<-entered
+
+	// Over the limit: rejected once the queue timeout passes
+	w := serve("/slow")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("status = %d, want 429", w.Code)
+	}
+	if got := w.Header().Get("Retry-After"); got != "1" {
+		t.Errorf("Retry-After = %q, want 1", got)
+	}
+
+	// Unlimited classes are not held up
+	if w := serve("/search"); w.Code != http.StatusOK {
+		t.Errorf("unlimited class status = %d, want 200", w.Code)
+	}
+
+	// A queued request gets the slot when it frees up in time
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		queued = serve("/slow")
+	}()
+	time.Sleep(20 * time.Millisecond)
+	release <- struct{}{}
+	<-entered
+	release <- struct{}{}
+	wg.Wait()
+
+	if first.Code != http.StatusOK || queued.Code != http.StatusOK {
+		t.Errorf("statuses = %d, %d, want 200, 200", first.Code, queued.Code)
+	}
+}
+
+func TestNewConcurrencyLimits(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  config.ConcurrencyConfig
+		want map[string]int // slots per class; missing means unbounded
+	}{
+		{"defaults", config.ConcurrencyConfig{}, map[string]int{
+			config.EndpointSummaries: 4, config.EndpointTraversal: 8, config.EndpointIndexing: 2,
+		}},
+		{"overrides", config.ConcurrencyConfig{Limits: map[string]int{config.EndpointSummaries: 1, config.EndpointIndexing: -1}}, map[string]int{
+			config.EndpointSummaries: 1, config.EndpointTraversal: 8,
+		}},
+		{"disabled", config.ConcurrencyConfig{Disabled: true}, map[string]int{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limits := NewConcurrencyLimits(tt.cfg)
+			if len(limits.slots) != len(tt.want) {
+				t.Errorf("bounded %d classes, want %d", len(limits.slots), len(tt.want))
+			}
+			for class, want := range tt.want {
+				if got := cap(limits.slots[class]); got != want {
+					t.Errorf("%s limit = %d, want %d", class, got, want)
+				}
+			}
+		})
+	}
+}
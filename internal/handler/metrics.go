@@ -0,0 +1,40 @@
+package handler
+
+import (
+	"time"
+
+	"github.com/armchr/codeapi/internal/metrics"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MetricsPath is where Prometheus scrapes the server
+const MetricsPath = "/metrics"
+
+// MetricsMiddleware records the count and latency of every request. It runs
+// after TenantMiddleware so the repo label carries the tenant-qualified name;
+// names that are not configured repositories are folded into "other" so
+// callers cannot grow the label set at will.
+func MetricsMiddleware(knownRepo func(name string) bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.URL.Path == MetricsPath {
+			c.Next()
+			return
+		}
+
+		repo := requestRepo(c)
+		if repo != "" && !knownRepo(repo) {
+			repo = "other"
+		}
+
+		start := time.Now()
+		c.Next()
+
+		// Unmatched paths share one label value
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		metrics.ObserveHTTPRequest(c.Request.Method, route, c.Writer.Status(), repo, time.Since(start))
+	}
+}
@@ -0,0 +1,51 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+func TestMetricsMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(MetricsMiddleware(func(name string) bool { return name == "metrics-api" }))
+	router.GET(MetricsPath, gin.WrapH(promhttp.Handler()))
+	router.POST("/codeapi/v1/callers", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	send := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	send(http.MethodPost, "/codeapi/v1/callers", `{"repo_name":"metrics-api"}`)
+	send(http.MethodPost, "/codeapi/v1/callers", `{"repo_name":"not-configured"}`)
+	send(http.MethodGet, "/no/such/path", "")
+
+	w := send(http.MethodGet, MetricsPath, "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("/metrics status = %d", w.Code)
+	}
+	out := w.Body.String()
+	for _, want := range []string{
+		`codeapi_http_requests_total{method="POST",repo="metrics-api",route="/codeapi/v1/callers",status="200"} 1`,
+		`codeapi_http_requests_total{method="POST",repo="other",route="/codeapi/v1/callers",status="200"} 1`,
+		`codeapi_http_requests_total{method="GET",repo="",route="unmatched",status="404"} 1`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %s", want)
+		}
+	}
+	if strings.Contains(out, "not-configured") {
+		t.Error("unknown repository name leaked into labels")
+	}
+	if strings.Contains(out, `route="`+MetricsPath+`"`) {
+		t.Error("scrapes should not be counted")
+	}
+}
@@ -0,0 +1,20 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DenyInPublicMode answers 403 when the server runs in public mode, for
+// endpoints that change the stores or hand out raw source. Otherwise the
+// request passes through.
+func DenyInPublicMode(publicMode bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if publicMode {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "endpoint is not available in public mode"})
+			return
+		}
+		c.Next()
+	}
+}
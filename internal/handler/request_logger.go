@@ -0,0 +1,136 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/armchr/codeapi/internal/logging"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// RequestIDHeader carries the request ID; a client-supplied value is kept so
+// calls can be correlated across services
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength bounds a client-supplied request ID
+const maxRequestIDLength = 128
+
+// RequestLoggerMiddleware attaches a logger with the request ID and, when the
+// request names one, the repository to the request context. Handlers and
+// stores retrieve it with logging.FromContext. JSON error responses get the
+// ID as their request_id field, so a failed call can be matched with its
+// log lines.
+func RequestLoggerMiddleware(logger *zap.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader(RequestIDHeader)
+		if !validRequestID(requestID) {
+			requestID = uuid.NewString()
+		}
+		c.Header(RequestIDHeader, requestID)
+		c.Writer = &requestIDWriter{ResponseWriter: c.Writer, requestID: requestID}
+
+		fields := []zap.Field{zap.String("request_id", requestID)}
+		if repo := requestRepo(c); repo != "" {
+			fields = append(fields, zap.String("repo", repo))
+		}
+
+		ctx := logging.WithLogger(c.Request.Context(), logger.With(fields...))
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
+
+// requestRepo returns the repository named by the :repo path parameter or
+// the repo_name field of a JSON body
+func requestRepo(c *gin.Context) string {
+	if repo := c.Param("repo"); repo != "" {
+		return repo
+	}
+	raw, err := peekJSONBody(c.Request)
+	if err != nil || len(raw) == 0 {
+		return ""
+	}
+	var body struct {
+		RepoName string `json:"repo_name"`
+	}
+	if json.Unmarshal(raw, &body) != nil {
+		return ""
+	}
+	return body.RepoName
+}
+
+// peekJSONBody reads a JSON request body and puts it back for the handler.
+// Other content types are not read.
+func peekJSONBody(r *http.Request) ([]byte, error) {
+	if !strings.Contains(r.Header.Get("Content-Type"), "json") {
+		return nil, nil
+	}
+	return peekBody(r)
+}
+
+// peekBody reads a request body of any content type and puts it back for
+// the handler
+func peekBody(r *http.Request) ([]byte, error) {
+	if r.Body == nil || r.Body == http.NoBody {
+		return nil, nil
+	}
+	raw, err := io.ReadAll(r.Body)
+	r.Body.Close()
+	r.Body = io.NopCloser(bytes.NewReader(raw))
+	return raw, err
+}
+
+// validRequestID reports whether a client-supplied request ID can be kept:
+// it is short and printable ASCII, so it cannot forge log lines
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// requestIDWriter adds the request ID to JSON error bodies written in one
+// piece, as c.JSON does. Streamed and successful responses pass through.
+type requestIDWriter struct {
+	gin.ResponseWriter
+	requestID string
+}
+
+func (w *requestIDWriter) Write(data []byte) (int, error) {
+	if w.Written() || w.Status() < http.StatusBadRequest || !strings.Contains(w.Header().Get("Content-Type"), "json") {
+		return w.ResponseWriter.Write(data)
+	}
+	if _, err := w.ResponseWriter.Write(withRequestID(data, w.requestID)); err != nil {
+		return 0, err
+	}
+	return len(data), nil
+}
+
+// withRequestID adds a request_id field to a JSON object that has none.
+// Anything else is returned unchanged.
+func withRequestID(body []byte, requestID string) []byte {
+	var fields map[string]json.RawMessage
+	if json.Unmarshal(body, &fields) != nil || fields == nil {
+		return body
+	}
+	if _, ok := fields["request_id"]; ok {
+		return body
+	}
+	fields["request_id"], _ = json.Marshal(requestID)
+	tagged, err := json.Marshal(fields)
+	if err != nil {
+		return body
+	}
+	return tagged
+}
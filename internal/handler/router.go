@@ -2,15 +2,22 @@ package handler
 
 import (
 	"bytes"
+	"context"
 	"io"
 	"net/http"
+	"net/http/pprof"
 	"runtime/debug"
+	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/armchr/codeapi/internal/config"
 	"github.com/armchr/codeapi/internal/controller"
+	init_services "github.com/armchr/codeapi/internal/init"
+	"github.com/armchr/codeapi/internal/logging"
 
 	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"go.uber.org/zap"
 )
 
@@ -25,38 +32,118 @@ func (w *responseWriter) Write(b []byte) (int, error) {
 	return w.ResponseWriter.Write(b)
 }
 
-func SetupRouter(repoController *controller.RepoController, codeAPIController *controller.CodeAPIController, summaryController *controller.SummaryController, cfg *config.Config, logger *zap.Logger) *gin.Engine {
+// DependencyChecker reports external dependencies that are configured but
+// currently unreachable. It is implemented by the service container.
+type DependencyChecker interface {
+	Unavailable(name string) (reason string, down bool)
+	Dependencies() []init_services.DependencyStatus
+}
+
+// SetupRouter registers all routes. Route groups whose dependency failed to
+// initialize are still registered, with their controller nil: the dependency
+// gate answers 503 before the handler runs, and the router is rebuilt with
+// real controllers once the dependency recovers. Expensive endpoints share
+// the semaphores in limits across rebuilds.
+func SetupRouter(repoController *controller.RepoController, codeAPIController *controller.CodeAPIController, diffController *controller.DiffController, summaryController *controller.SummaryController, deps DependencyChecker, limits *ConcurrencyLimits, cfg *config.Config, logger *zap.Logger) *gin.Engine {
 	gin.SetMode(gin.ReleaseMode)
 
+	httpLogger := logging.Module(logger, logging.ModuleHTTP)
+
 	router := gin.New()
-	router.Use(CustomRecoveryMiddleware(logger))
-	router.Use(LoggerMiddleware(cfg.App.DebugHTTP, logger))
+	router.Use(CustomRecoveryMiddleware(httpLogger))
+	router.Use(RequestLoggerMiddleware(httpLogger))
+	router.Use(TenantMiddleware(cfg.Tenancy))
+	router.Use(LoggerMiddleware(cfg.App.DebugHTTP, httpLogger))
+	if !cfg.App.DisableMetrics {
+		router.Use(MetricsMiddleware(func(name string) bool {
+			_, err := cfg.GetRepository(name)
+			return err == nil
+		}))
+		// Labels name every tenant's repositories
+		router.GET(MetricsPath, RequireAdminTenant(), gin.WrapH(promhttp.Handler()))
+	}
+
+	requireDB := RequireDependencies(deps, init_services.DependencyDatabase)
+	requireQdrant := RequireDependencies(deps, init_services.DependencyQdrant)
+
+	limitIndexing := limits.Limit(config.EndpointIndexing)
+	limitTraversal := limits.Limit(config.EndpointTraversal)
+
+	// Endpoints that write or return raw source are closed in public mode
+	notPublic := DenyInPublicMode(cfg.App.PublicMode)
 
 	v1 := router.Group("/api/v1")
 	{
-		v1.POST("/buildIndex", repoController.BuildIndex)
+		v1.POST("/buildIndex", notPublic, requireDB, limitIndexing, repoController.BuildIndex)
 		//v1.POST("/getFunctionsInFile", repoController.GetFunctionsInFile)
 		//v1.POST("/getFunctionDetails", repoController.GetFunctionDetails)
-		v1.POST("/functionDependencies", repoController.GetFunctionDependencies)
-		v1.POST("/processDirectory", repoController.ProcessDirectory)
-		v1.POST("/searchSimilarCode", repoController.SearchSimilarCode)
+		v1.POST("/functionDependencies", limitTraversal, repoController.GetFunctionDependencies)
+		v1.POST("/functionHistory", repoController.GetFunctionHistory)
+		v1.POST("/compareRepos", limitTraversal, repoController.GetRepoComparison)
+		v1.POST("/processDirectory", notPublic, requireQdrant, limitIndexing, repoController.ProcessDirectory)
+		v1.POST("/searchSimilarCode", requireQdrant, repoController.SearchSimilarCode)
+		v1.POST("/chunkHierarchy", requireQdrant, repoController.GetChunkHierarchy)
+		v1.POST("/remapChunks", requireQdrant, repoController.RemapChunks)
+		v1.POST("/duplicates", requireQdrant, limitIndexing, repoController.FindDuplicates)
+		v1.POST("/similarEntities", requireQdrant, limitIndexing, repoController.FindSimilarEntities)
 
 		// Semantic signature search endpoint
-		v1.POST("/searchMethodsBySignature", repoController.SearchMethodsBySignature)
+		v1.POST("/searchMethodsBySignature", requireQdrant, repoController.SearchMethodsBySignature)
 
 		// Index building endpoints
-		v1.POST("/indexFile", repoController.IndexFile)
+		v1.POST("/indexFile", notPublic, requireDB, limitIndexing, repoController.IndexFile)
+		v1.POST("/purgeSandbox", notPublic, requireDB, repoController.PurgeSandbox)
 
-		v1.GET("/health", func(c *gin.Context) {
-			c.JSON(200, gin.H{
-				"status": "healthy",
-			})
-		})
+		// TODO-style comments and license headers
+		v1.POST("/notes", requireDB, repoController.ListNotes)
+		v1.POST("/searchNotes", requireQdrant, repoController.SearchNotes)
+
+		// Votes on search results and summaries
+		v1.POST("/feedback", notPublic, requireDB, repoController.RecordFeedback)
+		v1.POST("/feedback/aggregate", requireDB, repoController.AggregateFeedback)
+
+		// Who indexed, cleaned or generated what, and how it went
+		v1.POST("/audit", requireDB, repoController.ListAuditLog)
+
+		// Editor navigation through the pooled language servers
+		v1.POST("/lsp/definition", repoController.LspDefinition)
+		v1.POST("/lsp/hover", repoController.LspHover)
+		v1.POST("/lsp/references", repoController.LspReferences)
+
+		// Operations running on repositories, from their repository locks
+		v1.GET("/jobs", requireDB, repoController.ListJobs)
+		v1.GET("/jobs/:repo", requireDB, repoController.GetRepoJob)
+
+		// Counts from every store, for dashboards
+		v1.GET("/repos/:repo/stats", repoController.GetRepoStats)
+
+		// Every chunk of a repository as JSON lines, for offline pipelines
+		v1.GET("/repos/:repo/chunks/export", requireQdrant, repoController.ExportChunks)
+
+		// Classes, functions, chunks and summaries of a file, for editors
+		v1.GET("/repos/:repo/files/*path", repoController.GetFileOutline)
+
+		// Graph node, summary and chunks of a public entity reference
+		v1.POST("/entities/resolve", repoController.ResolveEntity)
+
+		v1.GET("/health", HealthHandler(deps))
+
+		// Runtime diagnostics: goroutines, heap, language servers, caches
+		v1.GET("/admin/diagnostics", RequireAdminTenant(), repoController.Diagnostics)
 	}
 
+	if cfg.App.EnablePprof {
+		registerPprof(router)
+	}
+
+	_, graphDown := deps.Unavailable(init_services.DependencyNeo4j)
+	_, dbDown := deps.Unavailable(init_services.DependencyDatabase)
+
 	// CodeAPI routes
-	if codeAPIController != nil {
+	if codeAPIController != nil || graphDown {
 		codeAPI := router.Group("/codeapi/v1")
+		codeAPI.Use(RequestTimeoutMiddleware(cfg.App.RequestTimeout()))
+		codeAPI.Use(RequireDependencies(deps, init_services.DependencyNeo4j))
 		{
 			// Reader endpoints
 			codeAPI.GET("/repos", codeAPIController.ListRepos)
@@ -72,32 +159,48 @@ func SetupRouter(repoController *controller.RepoController, codeAPIController *c
 			codeAPI.POST("/class/fields", codeAPIController.GetClassFields)
 
 			// Analyzer endpoints
-			codeAPI.POST("/callgraph", codeAPIController.GetCallGraph)
-			codeAPI.POST("/callers", codeAPIController.GetCallers)
-			codeAPI.POST("/callees", codeAPIController.GetCallees)
-			codeAPI.POST("/data/dependents", codeAPIController.GetDataDependents)
-			codeAPI.POST("/data/sources", codeAPIController.GetDataSources)
-			codeAPI.POST("/impact", codeAPIController.GetImpact)
-			codeAPI.POST("/inheritance", codeAPIController.GetInheritanceTree)
+			codeAPI.POST("/callgraph", limitTraversal, codeAPIController.GetCallGraph)
+			codeAPI.POST("/callers", limitTraversal, codeAPIController.GetCallers)
+			codeAPI.POST("/callees", limitTraversal, codeAPIController.GetCallees)
+			codeAPI.POST("/data/dependents", limitTraversal, codeAPIController.GetDataDependents)
+			codeAPI.POST("/data/sources", limitTraversal, codeAPIController.GetDataSources)
+			codeAPI.POST("/impact", limitTraversal, codeAPIController.GetImpact)
+			codeAPI.POST("/inheritance", limitTraversal, codeAPIController.GetInheritanceTree)
+			codeAPI.GET("/repos/:repo/classes", codeAPIController.GetClassHierarchy)
+			codeAPI.POST("/injection", codeAPIController.GetInjectionWiring)
 			codeAPI.POST("/field/accessors", codeAPIController.GetFieldAccessors)
+			codeAPI.POST("/table/accessors", codeAPIController.GetTableAccessors)
+			codeAPI.POST("/config/usages", codeAPIController.GetConfigUsages)
+			codeAPI.POST("/flags", codeAPIController.ListFeatureFlags)
+			codeAPI.POST("/security/sink-paths", limitTraversal, codeAPIController.GetSinkPaths)
+			codeAPI.POST("/lineage/endpoints", limitTraversal, codeAPIController.GetEndpointLineage)
+			codeAPI.POST("/metrics/complex-functions", codeAPIController.ListComplexFunctions)
+			codeAPI.POST("/metrics/hotspots", codeAPIController.ListHotspots)
+			codeAPI.POST("/metrics/packages", codeAPIController.ListPackageCoupling)
 
 			// Raw Cypher endpoints
-			codeAPI.POST("/cypher", codeAPIController.ExecuteCypher)
-			codeAPI.POST("/cypher/write", codeAPIController.ExecuteCypherWrite)
+			codeAPI.POST("/cypher", notPublic, RequireAdminTenant(), codeAPIController.ExecuteCypher)
+			codeAPI.POST("/cypher/write", notPublic, RequireAdminTenant(), codeAPIController.ExecuteCypherWrite)
 
 			// Code snippet endpoint
-			codeAPI.POST("/snippet", codeAPIController.GetCodeSnippet)
+			codeAPI.POST("/snippet", notPublic, codeAPIController.GetCodeSnippet)
+
+			// Diff analysis endpoint
+			if diffController != nil || graphDown {
+				codeAPI.POST("/repos/:repo/analyze-diff", limitTraversal, diffController.AnalyzeDiff)
+			}
 
 			// Health check
-			codeAPI.GET("/health", func(c *gin.Context) {
-				c.JSON(200, gin.H{"status": "healthy"})
-			})
+			codeAPI.GET("/health", HealthHandler(deps))
 		}
 	}
 
 	// Summary query routes
-	if summaryController != nil {
+	if summaryController != nil || dbDown {
 		summaryAPI := router.Group("/codeapi/v1/summaries")
+		summaryAPI.Use(RequestTimeoutMiddleware(cfg.App.RequestTimeout()))
+		summaryAPI.Use(RequireDependencies(deps, init_services.DependencyDatabase))
+		summaryAPI.Use(limits.Limit(config.EndpointSummaries))
 		{
 			// Get all summaries for a file (optionally filtered by entity_type)
 			summaryAPI.POST("/file", summaryController.GetFileSummaries)
@@ -111,14 +214,123 @@ func SetupRouter(repoController *controller.RepoController, codeAPIController *c
 			// Get summary statistics for a repository
 			summaryAPI.POST("/stats", summaryController.GetSummaryStats)
 		}
+
+		// Summaries of many entities of a repository at once
+		repoSummaryAPI := router.Group("/codeapi/v1/repos/:repo/summaries")
+		repoSummaryAPI.Use(RequestTimeoutMiddleware(cfg.App.RequestTimeout()))
+		repoSummaryAPI.Use(RequireDependencies(deps, init_services.DependencyDatabase))
+		repoSummaryAPI.Use(limits.Limit(config.EndpointSummaries))
+		repoSummaryAPI.POST("/batch", summaryController.GetSummariesBatch)
+
+		// Move stored summaries between environments as JSONL
+		repoSummaryAPI.GET("/export", summaryController.ExportSummaries)
+		repoSummaryAPI.POST("/import", notPublic, summaryController.ImportSummaries)
 	}
 
 	return router
 }
 
-func LoggerMiddleware(debugHTTP bool, logger *zap.Logger) gin.HandlerFunc {
+// registerPprof serves the net/http/pprof profiles under /debug/pprof.
+// Profiles expose internals of every tenant, so only admin tenants get them.
+func registerPprof(router *gin.Engine) {
+	group := router.Group("/debug/pprof", RequireAdminTenant())
+	group.GET("/", gin.WrapF(pprof.Index))
+	group.GET("/cmdline", gin.WrapF(pprof.Cmdline))
+	group.GET("/profile", gin.WrapF(pprof.Profile))
+	group.GET("/symbol", gin.WrapF(pprof.Symbol))
+	group.POST("/symbol", gin.WrapF(pprof.Symbol))
+	group.GET("/trace", gin.WrapF(pprof.Trace))
+	// heap, goroutine, allocs, block, mutex and threadcreate
+	group.GET("/:profile", gin.WrapF(pprof.Index))
+}
+
+// RequireDependencies answers 503 with the failure reason while any of the
+// named dependencies is unavailable, so endpoints that need it fail fast and
+// the rest of the API keeps working
+func RequireDependencies(deps DependencyChecker, names ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var down, reasons []string
+		for _, name := range names {
+			if reason, unavailable := deps.Unavailable(name); unavailable {
+				down = append(down, name)
+				reasons = append(reasons, name+": "+reason)
+			}
+		}
+		if len(down) == 0 {
+			c.Next()
+			return
+		}
+
+		c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{
+			"error":        "service temporarily unavailable: " + strings.Join(down, ", ") + " not reachable",
+			"reason":       strings.Join(reasons, "; "),
+			"dependencies": down,
+		})
+	}
+}
+
+// HealthHandler reports "healthy", or "degraded" with the dependencies that
+// are down. The server itself is up either way, so both return 200.
+func HealthHandler(deps DependencyChecker) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		status := "healthy"
+		dependencies := deps.Dependencies()
+		for _, dep := range dependencies {
+			if !dep.Available {
+				status = "degraded"
+				break
+			}
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"status":       status,
+			"dependencies": dependencies,
+		})
+	}
+}
+
+// SwappableHandler serves requests through the most recently installed
+// router, letting the server rewire its controllers without restarting the
+// listener
+type SwappableHandler struct {
+	current atomic.Pointer[http.Handler]
+}
+
+// Set installs h for subsequent requests
+func (s *SwappableHandler) Set(h http.Handler) {
+	s.current.Store(&h)
+}
+
+func (s *SwappableHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h := s.current.Load()
+	if h == nil {
+		http.Error(w, "server is starting", http.StatusServiceUnavailable)
+		return
+	}
+	(*h).ServeHTTP(w, r)
+}
+
+// RequestTimeoutMiddleware puts a deadline on the request context. Handlers
+// pass that context to Neo4j, Qdrant and the relational store, so a slow
+// query is abandoned once the deadline passes. A zero timeout leaves the
+// request unbounded.
+func RequestTimeoutMiddleware(timeout time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if timeout <= 0 {
+			c.Next()
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
+
+func LoggerMiddleware(debugHTTP bool, baseLogger *zap.Logger) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		start := time.Now()
+		logger := logging.FromContext(c.Request.Context(), baseLogger)
 
 		var requestBody []byte
 		var responseBody *bytes.Buffer
@@ -196,7 +408,7 @@ func CustomRecoveryMiddleware(logger *zap.Logger) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		defer func() {
 			if err := recover(); err != nil {
-				logger.Error("Panic recovered",
+				logging.FromContext(c.Request.Context(), logger).Error("Panic recovered",
 					zap.Any("error", err),
 					zap.String("stack", string(debug.Stack())),
 					zap.String("path", c.Request.URL.Path),
@@ -0,0 +1,224 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/armchr/codeapi/internal/config"
+	init_services "github.com/armchr/codeapi/internal/init"
+	"github.com/armchr/codeapi/internal/logging"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+type fakeDeps map[string]string
+
+func (f fakeDeps) Unavailable(name string) (string, bool) {
+	reason, down := f[name]
+	return reason, down
+}
+
+func (f fakeDeps) Dependencies() []init_services.DependencyStatus {
+	var out []init_services.DependencyStatus
+	for name, reason := range f {
+		out = append(out, init_services.DependencyStatus{Name: name, Reason: reason})
+	}
+	return out
+}
+
+func TestRequireDependencies(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name       string
+		deps       fakeDeps
+		wantStatus int
+	}{
+		{"all available", fakeDeps{}, http.StatusOK},
+		{"other dependency down", fakeDeps{init_services.DependencyQdrant: "connection refused"}, http.StatusOK},
+		{"required dependency down", fakeDeps{init_services.DependencyNeo4j: "connection refused"}, http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.GET("/graph", RequireDependencies(tt.deps, init_services.DependencyNeo4j), func(c *gin.Context) {
+				c.Status(http.StatusOK)
+			})
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/graph", nil))
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+
+			if tt.wantStatus == http.StatusServiceUnavailable {
+				var body struct {
+					Reason       string   `json:"reason"`
+					Dependencies []string `json:"dependencies"`
+				}
+				if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+					t.Fatal(err)
+				}
+				if body.Reason != "neo4j: connection refused" || len(body.Dependencies) != 1 {
+					t.Errorf("unexpected body %s", w.Body.String())
+				}
+			}
+		})
+	}
+}
+
+func TestHealthHandlerDegraded(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/health", HealthHandler(fakeDeps{init_services.DependencyDatabase: "ping failed"}))
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/health", nil))
+
+	var body struct {
+		Status string `json:"status"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if w.Code != http.StatusOK || body.Status != "degraded" {
+		t.Errorf("health = %d %q, want 200 degraded", w.Code, body.Status)
+	}
+}
+
+func TestRequestLoggerMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	core, logs := observer.New(zapcore.InfoLevel)
+
+	router := gin.New()
+	router.Use(RequestLoggerMiddleware(zap.New(core)))
+	router.POST("/files", func(c *gin.Context) {
+		var body map[string]any
+		if err := c.ShouldBindJSON(&body); err != nil {
+			t.Errorf("body not restored: %v", err)
+		}
+		logging.FromContext(c.Request.Context(), zap.NewNop()).Info("handled")
+		c.Status(http.StatusOK)
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/files", strings.NewReader(`{"repo_name":"api"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(RequestIDHeader, "abc-123")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if got := w.Header().Get(RequestIDHeader); got != "abc-123" {
+		t.Errorf("%s = %q, want abc-123", RequestIDHeader, got)
+	}
+	entries := logs.All()
+	if len(entries) != 1 {
+		t.Fatalf("got %d log entries, want 1", len(entries))
+	}
+	fields := entries[0].ContextMap()
+	if fields["request_id"] != "abc-123" || fields["repo"] != "api" {
+		t.Errorf("request fields = %v", fields)
+	}
+}
+
+func TestRequestIDInResponses(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(RequestLoggerMiddleware(zap.NewNop()))
+	router.GET("/missing", func(c *gin.Context) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Repository not found"})
+	})
+	router.GET("/tagged", func(c *gin.Context) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "bad", "request_id": "own"})
+	})
+	router.GET("/ok", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"status": "ok"})
+	})
+	router.GET("/text", func(c *gin.Context) {
+		c.String(http.StatusInternalServerError, "failed")
+	})
+
+	tests := []struct {
+		path     string
+		header   string
+		wantID   string // "" for a generated ID
+		wantBody string // "" when the body must carry the ID
+	}{
+		{"/missing", "abc-123", "abc-123", ""},
+		{"/missing", "", "", ""},
+		{"/missing", "bad id\n", "", ""},
+		{"/missing", strings.Repeat("x", maxRequestIDLength+1), "", ""},
+		{"/tagged", "abc-123", "abc-123", `{"error":"bad","request_id":"own"}`},
+		{"/ok", "abc-123", "abc-123", `{"status":"ok"}`},
+		{"/text", "abc-123", "abc-123", "failed"},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+		if tt.header != "" {
+			req.Header.Set(RequestIDHeader, tt.header)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		id := w.Header().Get(RequestIDHeader)
+		if tt.wantID != "" && id != tt.wantID || tt.wantID == "" && (id == "" || id == tt.header) {
+			t.Errorf("%s with ID %q: %s = %q", tt.path, tt.header, RequestIDHeader, id)
+		}
+		if tt.wantBody != "" {
+			if got := w.Body.String(); got != tt.wantBody {
+				t.Errorf("%s: body = %s, want %s", tt.path, got, tt.wantBody)
+			}
+			continue
+		}
+		var body map[string]string
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+			t.Fatal(err)
+		}
+		if body["request_id"] != id || body["error"] == "" {
+			t.Errorf("%s: body = %v, want the error with request_id %q", tt.path, body, id)
+		}
+	}
+}
+
+func TestRegisterPprof(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tenancy := config.TenancyConfig{
+		Enabled: true,
+		Tenants: []config.TenantConfig{
+			{Name: "acme", APIKeys: []string{"acme-key"}},
+			{Name: "ops", APIKeys: []string{"ops-key"}, Admin: true},
+		},
+	}
+	router := gin.New()
+	router.Use(TenantMiddleware(tenancy))
+	registerPprof(router)
+
+	tests := []struct {
+		path       string
+		key        string
+		wantStatus int
+	}{
+		{"/debug/pprof/", "ops-key", http.StatusOK},
+		{"/debug/pprof/heap?debug=1", "ops-key", http.StatusOK},
+		{"/debug/pprof/cmdline", "ops-key", http.StatusOK},
+		{"/debug/pprof/goroutine", "acme-key", http.StatusForbidden},
+		{"/debug/pprof/heap", "", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+		if tt.key != "" {
+			req.Header.Set("Authorization", "Bearer "+tt.key)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != tt.wantStatus {
+			t.Errorf("%s with %q: status = %d, want %d", tt.path, tt.key, w.Code, tt.wantStatus)
+		}
+	}
+}
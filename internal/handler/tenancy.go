@@ -0,0 +1,185 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/armchr/codeapi/internal/config"
+	"github.com/armchr/codeapi/internal/entity"
+	"github.com/armchr/codeapi/internal/logging"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// tenantScopedFields are the request fields naming a repository-keyed store.
+// They are qualified with the caller's tenant before the handler sees them.
+var tenantScopedFields = []string{"repo_name", "other_repo_name", "collection_name"}
+
+// tenantScopedListFields are the request fields holding lists of repository
+// names, qualified like tenantScopedFields
+var tenantScopedListFields = []string{"repo_names"}
+
+// entityRefField holds an entity reference, whose repository is qualified
+// like tenantScopedFields
+const entityRefField = "entity_ref"
+
+// TenantMiddleware maps the caller's API key to a tenant and confines the
+// request to that tenant's repositories by qualifying repository and
+// collection names in the JSON body and the :repo path parameter. Names of
+// other tenants' repositories become names that do not exist. Health checks
+// stay open. Does nothing unless tenancy is enabled.
+func TenantMiddleware(tenancy config.TenancyConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !tenancy.Enabled || strings.HasSuffix(c.Request.URL.Path, "/health") {
+			c.Next()
+			return
+		}
+
+		key := c.GetHeader(tenancy.GetHeader())
+		if key == "" {
+			key = strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		}
+		tenant, ok := tenancy.TenantForKey(key)
+		if !ok {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "missing or unknown API key"})
+			return
+		}
+
+		ctx := config.WithTenant(c.Request.Context(), tenant)
+		if reqLogger := logging.FromContext(ctx, nil); reqLogger != nil {
+			ctx = logging.WithLogger(ctx, reqLogger.With(zap.String("tenant", tenant.Name)))
+		}
+		c.Request = c.Request.WithContext(ctx)
+		if tenant.Admin {
+			c.Next()
+			return
+		}
+
+		for i := range c.Params {
+			if c.Params[i].Key == "repo" {
+				c.Params[i].Value = config.QualifiedRepoName(tenant.Name, c.Params[i].Value)
+			}
+		}
+		if err := qualifyJSONBody(c.Request, tenant.Name); err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "invalid JSON body: " + err.Error()})
+			return
+		}
+		c.Next()
+	}
+}
+
+// qualifyJSONBody rewrites tenantScopedFields of a JSON object body. The
+// body is read whatever its Content-Type, since gin's JSON binding decodes
+// a body without looking at the header.
+func qualifyJSONBody(r *http.Request, tenant string) error {
+	raw, err := peekBody(r)
+	if err != nil || len(bytes.TrimSpace(raw)) == 0 {
+		return err
+	}
+
+	var body map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &body); err != nil {
+		// Not an object; leave it for the handler's own binding to reject
+		return nil
+	}
+	if err := checkScopedKeys(raw); err != nil {
+		return err
+	}
+
+	changed := false
+	for _, field := range tenantScopedFields {
+		var name string
+		if value, ok := body[field]; !ok || json.Unmarshal(value, &name) != nil || name == "" {
+			continue
+		}
+		qualified, _ := json.Marshal(config.QualifiedRepoName(tenant, name))
+		body[field] = qualified
+		changed = true
+	}
+	for _, field := range tenantScopedListFields {
+		var names []string
+		if value, ok := body[field]; !ok || json.Unmarshal(value, &names) != nil || len(names) == 0 {
+			continue
+		}
+		for i, name := range names {
+			names[i] = config.QualifiedRepoName(tenant, name)
+		}
+		qualified, _ := json.Marshal(names)
+		body[field] = qualified
+		changed = true
+	}
+	var s string
+	if value, ok := body[entityRefField]; ok && json.Unmarshal(value, &s) == nil && s != "" {
+		// Malformed references are left for the handler to reject
+		if ref, err := entity.Parse(s); err == nil {
+			qualified, _ := json.Marshal(ref.WithRepo(config.QualifiedRepoName(tenant, ref.Repo)).String())
+			body[entityRefField] = qualified
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+
+	rewritten, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	r.Body = io.NopCloser(bytes.NewReader(rewritten))
+	r.ContentLength = int64(len(rewritten))
+	return nil
+}
+
+// checkScopedKeys rejects an object body that spells a tenant-scoped field
+// in another case or repeats it. encoding/json binds such keys to the field
+// too, so they would reach the handler unqualified.
+func checkScopedKeys(raw []byte) error {
+	scoped := append(append([]string{entityRefField}, tenantScopedFields...), tenantScopedListFields...)
+	seen := make(map[string]bool)
+
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	if _, err := dec.Token(); err != nil { // opening brace
+		return err
+	}
+	for dec.More() {
+		token, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		key, _ := token.(string)
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return err
+		}
+		for _, field := range scoped {
+			if !strings.EqualFold(key, field) {
+				continue
+			}
+			if key != field {
+				return fmt.Errorf("field %q must be spelled %q", key, field)
+			}
+			if seen[field] {
+				return fmt.Errorf("duplicate field %q", field)
+			}
+			seen[field] = true
+		}
+	}
+	return nil
+}
+
+// RequireAdminTenant rejects callers scoped to a single tenant. It guards
+// endpoints such as raw Cypher that cannot be confined to one tenant's data.
+func RequireAdminTenant() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if tenant := config.TenantFromContext(c.Request.Context()); tenant != nil {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "endpoint is only available to admin tenants"})
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,235 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/armchr/codeapi/internal/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestTenantMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tenancy := config.TenancyConfig{
+		Enabled: true,
+		Tenants: []config.TenantConfig{
+			{Name: "acme", APIKeys: []string{"acme-key"}},
+			{Name: "ops", APIKeys: []string{"ops-key"}, Admin: true},
+		},
+	}
+
+	router := gin.New()
+	router.Use(TenantMiddleware(tenancy))
+	echo := func(c *gin.Context) {
+		var body map[string]any
+		_ = c.ShouldBindJSON(&body)
+		c.JSON(http.StatusOK, gin.H{"body": body, "repo": c.Param("repo")})
+	}
+	router.POST("/files", echo)
+	router.POST("/repos/:repo/analyze-diff", echo)
+	router.POST("/cypher", RequireAdminTenant(), echo)
+
+	tests := []struct {
+		name       string
+		path       string
+		key        string
+		body       string
+		wantStatus int
+		wantRepo   string
+		wantParam  string
+		wantRepos  []any
+		wantOther  string
+	}{
+		{"no key", "/files", "", `{"repo_name":"api"}`, http.StatusUnauthorized, "", "", nil, ""},
+		{"unknown key", "/files", "nope", `{"repo_name":"api"}`, http.StatusUnauthorized, "", "", nil, ""},
+		{"qualifies body", "/files", "acme-key", `{"repo_name":"api","limit":5}`, http.StatusOK, "acme__api", "", nil, ""},
+		{"other tenant's repo", "/files", "acme-key", `{"repo_name":"globex__api"}`, http.StatusOK, "acme__globex__api", "", nil, ""},
+		{"qualifies path", "/repos/api/analyze-diff", "acme-key", `{}`, http.StatusOK, "", "acme__api", nil, ""},
+		{"qualifies list", "/files", "acme-key", `{"repo_names":["api","web"]}`, http.StatusOK, "", "", []any{"acme__api", "acme__web"}, ""},
+		{"qualifies other repo", "/files", "acme-key", `{"repo_name":"api","other_repo_name":"api-fork"}`, http.StatusOK, "acme__api", "", nil, "acme__api-fork"},
+		{"admin unscoped", "/files", "ops-key", `{"repo_name":"acme__api"}`, http.StatusOK, "acme__api", "", nil, ""},
+		{"cypher forbidden", "/cypher", "acme-key", `{}`, http.StatusForbidden, "", "", nil, ""},
+		{"cypher for admin", "/cypher", "ops-key", `{}`, http.StatusOK, "", "", nil, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			if tt.key != "" {
+				req.Header.Set("X-API-Key", tt.key)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (%s)", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if w.Code != http.StatusOK {
+				return
+			}
+
+			var resp struct {
+				Body map[string]any `json:"body"`
+				Repo string         `json:"repo"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantRepo != "" && resp.Body["repo_name"] != tt.wantRepo {
+				t.Errorf("repo_name = %v, want %s", resp.Body["repo_name"], tt.wantRepo)
+			}
+			if tt.wantRepos != nil && !reflect.DeepEqual(resp.Body["repo_names"], tt.wantRepos) {
+				t.Errorf("repo_names = %v, want %v", resp.Body["repo_names"], tt.wantRepos)
+			}
+			if tt.wantOther != "" && resp.Body["other_repo_name"] != tt.wantOther {
+				t.Errorf("other_repo_name = %v, want %s", resp.Body["other_repo_name"], tt.wantOther)
+			}
+			if resp.Repo != tt.wantParam {
+				t.Errorf(":repo = %q, want %q", resp.Repo, tt.wantParam)
+			}
+		})
+	}
+}
+
+func TestTenantMiddlewareEntityRef(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tenancy := config.TenancyConfig{
+		Enabled: true,
+		Tenants: []config.TenantConfig{{Name: "acme", APIKeys: []string{"acme-key"}}},
+	}
+	router := gin.New()
+	router.Use(TenantMiddleware(tenancy))
+	router.POST("/entities/resolve", func(c *gin.Context) {
+		var body map[string]any
+		_ = c.ShouldBindJSON(&body)
+		c.JSON(http.StatusOK, body)
+	})
+
+	tests := []struct {
+		name string
+		ref  string
+		want string
+	}{
+		{"qualifies repository", "api:function:src/order.go#Order.total", "acme__api:function:src/order.go#Order.total"},
+		{"malformed left alone", "api", "api"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, _ := json.Marshal(map[string]string{"entity_ref": tt.ref})
+			req := httptest.NewRequest(http.MethodPost, "/entities/resolve", strings.NewReader(string(body)))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("X-API-Key", "acme-key")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			var resp map[string]any
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatal(err)
+			}
+			if resp["entity_ref"] != tt.want {
+				t.Errorf("entity_ref = %v, want %s", resp["entity_ref"], tt.want)
+			}
+		})
+	}
+}
+
+func TestTenantMiddlewareNonJSONContentType(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tenancy := config.TenancyConfig{
+		Enabled: true,
+		Tenants: []config.TenantConfig{{Name: "acme", APIKeys: []string{"acme-key"}}},
+	}
+	router := gin.New()
+	router.Use(TenantMiddleware(tenancy))
+	router.POST("/files", func(c *gin.Context) {
+		var body map[string]any
+		_ = c.ShouldBindJSON(&body)
+		c.JSON(http.StatusOK, body)
+	})
+
+	for _, contentType := range []string{"text/plain", "application/x-www-form-urlencoded", ""} {
+		t.Run(contentType, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/files", strings.NewReader(`{"repo_name":"globex__api"}`))
+			if contentType != "" {
+				req.Header.Set("Content-Type", contentType)
+			}
+			req.Header.Set("X-API-Key", "acme-key")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			var resp map[string]any
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatal(err)
+			}
+			if resp["repo_name"] != "acme__globex__api" {
+				t.Errorf("repo_name = %v, want acme__globex__api", resp["repo_name"])
+			}
+		})
+	}
+}
+
+func TestTenantMiddlewareScopedKeySpelling(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tenancy := config.TenancyConfig{
+		Enabled: true,
+		Tenants: []config.TenantConfig{{Name: "acme", APIKeys: []string{"acme-key"}}},
+	}
+	router := gin.New()
+	router.Use(TenantMiddleware(tenancy))
+	router.POST("/files", func(c *gin.Context) {
+		var req struct {
+			RepoName string `json:"repo_name"`
+		}
+		_ = c.ShouldBindJSON(&req)
+		c.JSON(http.StatusOK, gin.H{"repo_name": req.RepoName})
+	})
+
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+		wantRepo   string
+	}{
+		{"exact key", `{"repo_name":"api"}`, http.StatusOK, "acme__api"},
+		{"case variant", `{"Repo_Name":"globex__api"}`, http.StatusBadRequest, ""},
+		{"case variant after exact key", `{"repo_name":"api","REPO_NAME":"globex__api"}`, http.StatusBadRequest, ""},
+		{"unicode fold", `{"repo_names":["api"],"repo_nameſ":["globex__api"]}`, http.StatusBadRequest, ""},
+		{"duplicate key", `{"repo_name":"api","repo_name":"globex__api"}`, http.StatusBadRequest, ""},
+		{"duplicate entity ref", `{"entity_ref":"api:file:a.go","entity_ref":"globex__api:file:a.go"}`, http.StatusBadRequest, ""},
+		{"unscoped keys in any case", `{"repo_name":"api","Limit":5,"limit":6}`, http.StatusOK, "acme__api"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/files", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("X-API-Key", "acme-key")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (%s)", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if w.Code != http.StatusOK {
+				return
+			}
+			var resp map[string]string
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatal(err)
+			}
+			if resp["repo_name"] != tt.wantRepo {
+				t.Errorf("repo_name = %q, want %q", resp["repo_name"], tt.wantRepo)
+			}
+		})
+	}
+}
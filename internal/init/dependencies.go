@@ -0,0 +1,163 @@
+package init
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/armchr/codeapi/internal/config"
+
+	"go.uber.org/zap"
+)
+
+// External dependencies that can be unavailable while the server keeps running
+const (
+	DependencyDatabase = "database" // MySQL, PostgreSQL or SQLite, per db.driver
+	DependencyNeo4j    = "neo4j"
+	DependencyQdrant   = "qdrant"
+)
+
+// firstRetryDelay is the wait before the first reconnection attempt; later
+// attempts back off exponentially up to app.dependency_retry_max_seconds
+const firstRetryDelay = 5 * time.Second
+
+// DependencyStatus reports whether an external dependency is usable
+type DependencyStatus struct {
+	Name      string     `json:"name"`
+	Available bool       `json:"available"`
+	Reason    string     `json:"reason,omitempty"` // Last connection error while unavailable
+	Attempts  int        `json:"attempts,omitempty"`
+	NextRetry *time.Time `json:"next_retry,omitempty"`
+}
+
+// pendingDependency is a dependency whose initialization failed and is retried
+type pendingDependency struct {
+	init  func() error
+	delay time.Duration
+	next  time.Time
+}
+
+// markAvailable records that a dependency initialized successfully
+func (sc *ServiceContainer) markAvailable(name string) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	sc.status[name] = &DependencyStatus{Name: name, Available: true}
+	delete(sc.pending, name)
+}
+
+// markUnavailable records a failed initialization and schedules init to be
+// retried by RetryUnavailable
+func (sc *ServiceContainer) markUnavailable(name string, err error, initFn func() error) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	p, ok := sc.pending[name]
+	if !ok {
+		p = &pendingDependency{init: initFn, delay: firstRetryDelay}
+		sc.pending[name] = p
+	} else {
+		p.delay *= 2
+	}
+	if p.delay > sc.retryMax {
+		p.delay = sc.retryMax
+	}
+	p.next = time.Now().Add(p.delay)
+
+	status, ok := sc.status[name]
+	if !ok || status.Available {
+		status = &DependencyStatus{Name: name}
+		sc.status[name] = status
+	}
+	status.Reason = err.Error()
+	status.Attempts++
+	next := p.next
+	status.NextRetry = &next
+
+	sc.logger.Warn("Dependency unavailable, affected endpoints return 503 until it recovers",
+		zap.String("dependency", name),
+		zap.Int("attempts", status.Attempts),
+		zap.Duration("retry_in", p.delay),
+		zap.Error(err))
+}
+
+// Unavailable reports whether dependency name is configured but currently
+// unusable, and why. Dependencies that are not configured are not reported.
+func (sc *ServiceContainer) Unavailable(name string) (string, bool) {
+	sc.mu.RLock()
+	defer sc.mu.RUnlock()
+	status, ok := sc.status[name]
+	if !ok || status.Available {
+		return "", false
+	}
+	return status.Reason, true
+}
+
+// Dependencies returns the status of every configured dependency, sorted by name
+func (sc *ServiceContainer) Dependencies() []DependencyStatus {
+	sc.mu.RLock()
+	defer sc.mu.RUnlock()
+	out := make([]DependencyStatus, 0, len(sc.status))
+	for _, status := range sc.status {
+		out = append(out, *status)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// Degraded reports whether any configured dependency is unavailable
+func (sc *ServiceContainer) Degraded() bool {
+	sc.mu.RLock()
+	defer sc.mu.RUnlock()
+	return len(sc.pending) > 0
+}
+
+// RetryUnavailable retries failed dependencies with exponential backoff until
+// all are up or ctx is cancelled. After each recovery the processors are
+// rebuilt and onRecovered is called so the caller can rewire its controllers.
+func (sc *ServiceContainer) RetryUnavailable(ctx context.Context, cfg *config.Config, onRecovered func()) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for sc.Degraded() {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if !sc.retryDue(now) {
+				continue
+			}
+			if err := sc.InitProcessors(cfg); err != nil {
+				sc.logger.Error("Failed to rebuild processors after dependency recovery", zap.Error(err))
+				continue
+			}
+			if onRecovered != nil {
+				onRecovered()
+			}
+		}
+	}
+}
+
+// retryDue runs the initializers whose backoff has elapsed and reports
+// whether any of them succeeded
+func (sc *ServiceContainer) retryDue(now time.Time) bool {
+	sc.mu.RLock()
+	due := make(map[string]func() error)
+	for name, p := range sc.pending {
+		if !now.Before(p.next) {
+			due[name] = p.init
+		}
+	}
+	sc.mu.RUnlock()
+
+	recovered := false
+	for name, initFn := range due {
+		if err := initFn(); err != nil {
+			sc.markUnavailable(name, err, initFn)
+			continue
+		}
+		sc.markAvailable(name)
+		sc.logger.Info("Dependency recovered", zap.String("dependency", name))
+		recovered = true
+	}
+	return recovered
+}
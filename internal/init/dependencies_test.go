@@ -0,0 +1,74 @@
+package init
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func newTestContainer() *ServiceContainer {
+	return &ServiceContainer{
+		status:   make(map[string]*DependencyStatus),
+		pending:  make(map[string]*pendingDependency),
+		retryMax: 20 * time.Second,
+		logger:   zap.NewNop(),
+	}
+}
+
+func TestLazyDependencyRetry(t *testing.T) {
+	sc := newTestContainer()
+
+	attempts := 0
+	initFn := func() error {
+		attempts++
+		if attempts < 4 {
+			return errors.New("connection refused")
+		}
+		return nil
+	}
+
+	if err := sc.initDependency(DependencyNeo4j, true, initFn); err != nil {
+		t.Fatalf("lazy init returned %v, want nil", err)
+	}
+	if reason, down := sc.Unavailable(DependencyNeo4j); !down || reason != "connection refused" {
+		t.Fatalf("Unavailable() = %q, %v", reason, down)
+	}
+	if !sc.Degraded() {
+		t.Fatal("container should be degraded")
+	}
+
+	// Backoff doubles from the first delay and is capped at retryMax
+	now := time.Now()
+	for _, want := range []time.Duration{10 * time.Second, 20 * time.Second} {
+		now = now.Add(time.Hour)
+		if sc.retryDue(now) {
+			t.Fatal("retry should still fail")
+		}
+		if got := sc.pending[DependencyNeo4j].delay; got != want {
+			t.Errorf("delay = %v, want %v", got, want)
+		}
+	}
+
+	if sc.retryDue(time.Now()) {
+		t.Error("retry ran before its backoff elapsed")
+	}
+	if !sc.retryDue(now.Add(time.Hour)) {
+		t.Fatal("fourth attempt should recover")
+	}
+	if _, down := sc.Unavailable(DependencyNeo4j); down || sc.Degraded() {
+		t.Error("dependency should be available after recovery")
+	}
+}
+
+func TestEagerDependencyFailure(t *testing.T) {
+	sc := newTestContainer()
+	err := sc.initDependency(DependencyQdrant, false, func() error { return errors.New("boom") })
+	if err == nil {
+		t.Fatal("non-lazy init should return the error")
+	}
+	if sc.Degraded() {
+		t.Error("non-lazy failure should not be scheduled for retry")
+	}
+}
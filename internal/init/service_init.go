@@ -3,10 +3,13 @@ package init
 import (
 	"context"
 	"fmt"
+	"sync"
+	"time"
 
 	"github.com/armchr/codeapi/internal/config"
 	"github.com/armchr/codeapi/internal/controller"
 	"github.com/armchr/codeapi/internal/db"
+	"github.com/armchr/codeapi/internal/logging"
 	"github.com/armchr/codeapi/internal/service"
 	"github.com/armchr/codeapi/internal/service/codegraph"
 	"github.com/armchr/codeapi/internal/service/llm"
@@ -19,7 +22,7 @@ import (
 // ServiceContainer holds all initialized services and their lifecycle management
 type ServiceContainer struct {
 	// Database connections
-	MySQLConn *db.MySQLConnection
+	DBConn db.Connection // MySQL or PostgreSQL, selected by db.driver
 
 	// Core services
 	CodeGraph      *codegraph.CodeGraph
@@ -36,69 +39,118 @@ type ServiceContainer struct {
 	// Processors
 	Processors []controller.FileProcessor
 
+	// Dependency state for lazy initialization; the service fields above are
+	// only assigned while holding mu
+	mu       sync.RWMutex
+	status   map[string]*DependencyStatus
+	pending  map[string]*pendingDependency
+	retryMax time.Duration
+
 	logger *zap.Logger
 }
 
 // ServiceInitOptions configures which services to initialize
 type ServiceInitOptions struct {
-	EnableMySQL       bool
+	EnableDB          bool
 	EnableCodeGraph   bool
 	EnableEmbeddings  bool
 	EnableRepoService bool
 	EnableSummary     bool // Enable hierarchical code summarization
 
 	// For index building CLI mode
-	RequireMySQL bool // If true, fail if MySQL is not available
+	RequireDB bool // If true, fail if the relational store is not available
+
+	// LazyInit keeps going when the relational store, Neo4j or Qdrant cannot
+	// be reached. The failure is recorded for Unavailable and retried by
+	// RetryUnavailable instead of failing startup.
+	LazyInit bool
+
+	// ReadOnly connects without creating anything, for dry runs. The
+	// relational database is expected to exist already.
+	ReadOnly bool
 }
 
 // NewServiceContainer initializes all requested services based on options
 func NewServiceContainer(cfg *config.Config, opts ServiceInitOptions, logger *zap.Logger) (*ServiceContainer, error) {
 	container := &ServiceContainer{
-		logger: logger,
+		status:   make(map[string]*DependencyStatus),
+		pending:  make(map[string]*pendingDependency),
+		retryMax: cfg.App.DependencyRetryMaxInterval(),
+		logger:   logger,
 	}
 
 	var err error
 
-	// Initialize MySQL if enabled
-	if opts.EnableMySQL && cfg.MySQL.Host != "" {
-		container.MySQLConn, err = initMySQL(cfg, logger, opts.RequireMySQL)
-		if err != nil {
-			if opts.RequireMySQL {
-				return nil, fmt.Errorf("MySQL initialization failed (required): %w", err)
+	// Initialize the relational store if enabled
+	driver := cfg.DB.GetDefaults().Driver
+	if opts.EnableDB && cfg.HasRelationalStore() {
+		initDB := func() error {
+			conn, err := initDatabase(cfg, logging.Module(logger, logging.ModuleDB), opts.RequireDB, opts.ReadOnly)
+			if err != nil {
+				return err
 			}
-			logger.Warn("MySQL initialization failed, continuing without it", zap.Error(err))
+			container.mu.Lock()
+			container.DBConn = conn
+			container.attachGraphOverflow(opts.ReadOnly)
+			container.mu.Unlock()
+			return nil
 		}
-	} else if opts.RequireMySQL {
-		return nil, fmt.Errorf("MySQL configuration is required but not provided")
+		if err = container.initDependency(DependencyDatabase, opts.LazyInit, initDB); err != nil {
+			if opts.RequireDB {
+				return nil, fmt.Errorf("%s initialization failed (required): %w", driver, err)
+			}
+			logger.Warn("Database initialization failed, continuing without it", zap.String("driver", driver), zap.Error(err))
+		}
+	} else if opts.RequireDB {
+		return nil, fmt.Errorf("%s configuration is required but not provided", driver)
 	}
 
 	// Initialize RepoService if enabled (needed for LSP operations)
 	if opts.EnableRepoService {
-		container.RepoService = service.NewRepoService(cfg, logger)
+		container.RepoService = service.NewRepoService(cfg, logging.Module(logger, logging.ModuleLSP))
 		logger.Info("RepoService initialized")
 	}
 
 	// Initialize CodeGraph if enabled
 	if opts.EnableCodeGraph {
-		container.CodeGraph, err = initCodeGraph(cfg, logger)
-		if err != nil {
+		initGraph := func() error {
+			codeGraph, err := initCodeGraph(cfg, logging.Module(logger, logging.ModuleCodeGraph))
+			if err != nil {
+				return err
+			}
+			container.mu.Lock()
+			container.CodeGraph = codeGraph
+			container.attachGraphOverflow(opts.ReadOnly)
+			container.mu.Unlock()
+			logger.Info("CodeGraph initialized")
+			return nil
+		}
+		if err := container.initDependency(DependencyNeo4j, opts.LazyInit, initGraph); err != nil {
 			return nil, fmt.Errorf("CodeGraph initialization failed: %w", err)
 		}
-		logger.Info("CodeGraph initialized")
 	}
 
 	// Initialize Vector DB and Embeddings if enabled
 	if opts.EnableEmbeddings {
-		container.VectorDB, container.EmbeddingModel, container.ChunkService, err = initVectorServices(cfg, logger)
-		if err != nil {
+		initVector := func() error {
+			vectorDB, embeddingModel, chunkService, err := initVectorServices(cfg, logging.Module(logger, logging.ModuleVector))
+			if err != nil {
+				return err
+			}
+			container.mu.Lock()
+			container.VectorDB, container.EmbeddingModel, container.ChunkService = vectorDB, embeddingModel, chunkService
+			container.mu.Unlock()
+			logger.Info("Vector services initialized")
+			return nil
+		}
+		if err := container.initDependency(DependencyQdrant, opts.LazyInit, initVector); err != nil {
 			return nil, fmt.Errorf("Vector services initialization failed: %w", err)
 		}
-		logger.Info("Vector services initialized")
 	}
 
 	// Initialize Summary services if enabled
 	if opts.EnableSummary {
-		container.LLMService, container.PromptManager, err = initSummaryServices(cfg, logger)
+		container.LLMService, container.PromptManager, err = initSummaryServices(cfg, logging.Module(logger, logging.ModuleSummary))
 		if err != nil {
 			// Summary is optional, log warning but don't fail
 			logger.Warn("Summary services initialization failed, summarization will be disabled", zap.Error(err))
@@ -112,30 +164,69 @@ func NewServiceContainer(cfg *config.Config, opts ServiceInitOptions, logger *za
 	return container, nil
 }
 
-// InitProcessors creates FileProcessor instances based on enabled services
+// initDependency runs initFn once. In lazy mode a failure is recorded for
+// retry and swallowed; otherwise it is returned.
+func (sc *ServiceContainer) initDependency(name string, lazy bool, initFn func() error) error {
+	if err := initFn(); err != nil {
+		if !lazy {
+			return err
+		}
+		sc.markUnavailable(name, err, initFn)
+		return nil
+	}
+	sc.markAvailable(name)
+	return nil
+}
+
+// attachGraphOverflow gives the code graph the relational store to move
+// oversized metadata to, once both are up. Without it, large values stay in
+// the graph. Called with mu held.
+func (sc *ServiceContainer) attachGraphOverflow(readOnly bool) {
+	if sc.CodeGraph == nil || sc.DBConn == nil {
+		return
+	}
+	logger := logging.Module(sc.logger, logging.ModuleDB)
+	if readOnly {
+		sc.CodeGraph.SetOverflowStore(db.NewGraphOverflowReader(sc.DBConn.GetDB(), logger))
+		return
+	}
+	store, err := db.NewGraphOverflowStore(sc.DBConn.GetDB(), logger)
+	if err != nil {
+		sc.logger.Warn("Failed to prepare graph metadata overflow store, large metadata stays in the graph", zap.Error(err))
+		return
+	}
+	sc.CodeGraph.SetOverflowStore(store)
+}
+
+// InitProcessors creates FileProcessor instances based on enabled services.
+// It may be called again once a lazily initialized dependency comes up.
 func (sc *ServiceContainer) InitProcessors(cfg *config.Config) error {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
 	var processors []controller.FileProcessor
+	indexLogger := logging.Module(sc.logger, logging.ModuleIndex)
 
 	// Add CodeGraph processor if available
 	if sc.CodeGraph != nil {
 		if sc.RepoService == nil {
 			return fmt.Errorf("CodeGraph processor requires RepoService but it's not initialized")
 		}
-		codeGraphProcessor := controller.NewCodeGraphProcessor(cfg, sc.CodeGraph, sc.RepoService, sc.logger)
+		codeGraphProcessor := controller.NewCodeGraphProcessor(cfg, sc.CodeGraph, sc.RepoService, indexLogger)
 		processors = append(processors, codeGraphProcessor)
 		sc.logger.Info("CodeGraph processor added to pipeline")
 	}
 
 	// Add Embedding processor if available
 	if sc.ChunkService != nil {
-		embeddingProcessor := controller.NewEmbeddingProcessor(sc.ChunkService, sc.logger)
+		embeddingProcessor := controller.NewEmbeddingProcessor(sc.ChunkService, indexLogger)
 		processors = append(processors, embeddingProcessor)
 		sc.logger.Info("Embedding processor added to pipeline")
 	}
 
 	// Add Summary processor if LLM service is available
 	// Note: Summary processor requires CodeGraph to be available for entity queries
-	if sc.LLMService != nil && sc.PromptManager != nil && sc.CodeGraph != nil && sc.MySQLConn != nil {
+	if sc.LLMService != nil && sc.PromptManager != nil && sc.CodeGraph != nil && sc.DBConn != nil {
 		summaryConfig := &controller.SummaryProcessorConfig{
 			Enabled:      cfg.IndexBuilding.EnableSummary,
 			WorkerCount:  cfg.Summary.WorkerCount,
@@ -149,15 +240,18 @@ func (sc *ServiceContainer) InitProcessors(cfg *config.Config) error {
 			summaryConfig.BatchSize = 50
 		}
 
-		// Pass MySQL DB for creating per-repo summary stores
+		// Pass the relational store for creating per-repo summary stores
 		summaryProcessor := controller.NewSummaryProcessor(
 			sc.LLMService,
 			sc.PromptManager,
-			sc.MySQLConn.GetDB(), // MySQL DB for per-repo stores
+			sc.DBConn.GetDB(), // Relational store for per-repo stores
 			sc.CodeGraph,
 			summaryConfig,
-			sc.logger,
+			logging.Module(sc.logger, logging.ModuleSummary),
 		)
+		if sc.ChunkService != nil {
+			summaryProcessor.SetChunkService(sc.ChunkService)
+		}
 		processors = append(processors, summaryProcessor)
 		sc.SummaryProcessor = summaryProcessor // Store for on-demand API access
 		sc.logger.Info("Summary processor added to pipeline")
@@ -168,7 +262,7 @@ func (sc *ServiceContainer) InitProcessors(cfg *config.Config) error {
 		gitChurnProcessor := controller.NewGitChurnProcessor(
 			sc.CodeGraph,
 			&cfg.GitChurn,
-			sc.logger,
+			indexLogger,
 		)
 		processors = append(processors, gitChurnProcessor)
 		sc.logger.Info("Git Churn processor added to pipeline",
@@ -177,15 +271,29 @@ func (sc *ServiceContainer) InitProcessors(cfg *config.Config) error {
 			zap.Bool("functionLevel", cfg.GitChurn.EnableFunctionLevel))
 	}
 
+	// Add Notes processor if enabled; it embeds notes only when the
+	// embedding processor above has created the collection
+	if cfg.Notes.Enabled && sc.DBConn != nil {
+		var chunkService *vector.CodeChunkService
+		if cfg.Notes.Embed && sc.ChunkService != nil {
+			chunkService = sc.ChunkService
+		}
+		processors = append(processors, controller.NewNotesProcessor(sc.DBConn.GetDB(), chunkService, &cfg.Notes, indexLogger))
+		sc.logger.Info("Notes processor added to pipeline", zap.Bool("embed", chunkService != nil))
+	}
+
 	sc.Processors = processors
 	return nil
 }
 
 // Close cleans up all resources
 func (sc *ServiceContainer) Close(ctx context.Context) {
-	if sc.MySQLConn != nil {
-		sc.MySQLConn.Close()
-		sc.logger.Info("MySQL connection closed")
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	if sc.DBConn != nil {
+		sc.DBConn.Close()
+		sc.logger.Info("Database connection closed")
 	}
 
 	if sc.CodeGraph != nil {
@@ -199,20 +307,26 @@ func (sc *ServiceContainer) Close(ctx context.Context) {
 	}
 }
 
-// initMySQL initializes MySQL connection and ensures database exists
-func initMySQL(cfg *config.Config, logger *zap.Logger, required bool) (*db.MySQLConnection, error) {
-	mysqlConn, err := db.NewMySQLConnection(cfg.MySQL, logger)
+// initDatabase opens the configured relational store and, unless readOnly,
+// ensures the database exists
+func initDatabase(cfg *config.Config, logger *zap.Logger, required, readOnly bool) (db.Connection, error) {
+	db.SetSummaryCiphers(cfg.RepoCipher)
+	conn, err := db.NewConnection(cfg, logger)
 	if err != nil {
 		if required {
-			return nil, fmt.Errorf("failed to initialize MySQL connection: %w", err)
+			return nil, fmt.Errorf("failed to initialize database connection: %w", err)
 		}
-		logger.Error("Failed to initialize MySQL connection, FileID tracking will be disabled", zap.Error(err))
+		logger.Error("Failed to initialize database connection, FileID tracking will be disabled", zap.Error(err))
 		return nil, err
 	}
 
+	if readOnly {
+		return conn, nil
+	}
+
 	// Ensure armchair database exists
-	if err := mysqlConn.EnsureDatabase("armchair"); err != nil {
-		mysqlConn.Close()
+	if err := conn.EnsureDatabase("armchair"); err != nil {
+		conn.Close()
 		if required {
 			return nil, fmt.Errorf("failed to ensure armchair database: %w", err)
 		}
@@ -220,8 +334,9 @@ func initMySQL(cfg *config.Config, logger *zap.Logger, required bool) (*db.MySQL
 		return nil, err
 	}
 
-	logger.Info("MySQL connection established and armchair database verified")
-	return mysqlConn, nil
+	logger.Info("Database connection established and armchair database verified",
+		zap.String("driver", cfg.DB.GetDefaults().Driver))
+	return conn, nil
 }
 
 // initCodeGraph initializes the CodeGraph service
@@ -248,13 +363,15 @@ func initVectorServices(cfg *config.Config, logger *zap.Logger) (vector.VectorDa
 	}
 
 	// Initialize Qdrant
-	vectorDB, err := vector.NewQdrantDatabase(cfg.Qdrant.Host, cfg.Qdrant.Port, cfg.Qdrant.APIKey, logger)
+	qdrantDB, err := vector.NewQdrantDatabase(cfg.Qdrant.Host, cfg.Qdrant.Port, cfg.Qdrant.APIKey, cfg.Qdrant.Pool, logger)
 	if err != nil {
 		return nil, nil, nil, fmt.Errorf("failed to initialize Qdrant database: %w", err)
 	}
+	qdrantDB.SetNamedVectors(cfg.Qdrant.NamedVectors)
+	var vectorDB vector.VectorDatabase = vector.NewEncryptedDatabase(vector.NewShardedDatabase(qdrantDB, cfg.VectorShards), cfg.RepoCipher)
 
 	// Initialize Ollama embedding model
-	embeddingModel, err := vector.NewOllamaEmbedding(vector.OllamaEmbeddingConfig{
+	ollamaEmbedding, err := vector.NewOllamaEmbedding(vector.OllamaEmbeddingConfig{
 		APIURL:    cfg.Ollama.URL,
 		APIKey:    cfg.Ollama.APIKey,
 		Model:     cfg.Ollama.Model,
@@ -264,17 +381,18 @@ func initVectorServices(cfg *config.Config, logger *zap.Logger) (vector.VectorDa
 		vectorDB.Close()
 		return nil, nil, nil, fmt.Errorf("failed to initialize Ollama embedding model: %w", err)
 	}
+	embeddingModel := vector.BudgetedEmbedding(ollamaEmbedding)
 
-	// Set default thresholds
-	minConditionalLines := cfg.Chunking.MinConditionalLines
-	minLoopLines := cfg.Chunking.MinLoopLines
-	if minConditionalLines == 0 {
-		minConditionalLines = 5
-	}
-	if minLoopLines == 0 {
-		minLoopLines = 5
+	// The client connects lazily, so check that Qdrant actually answers
+	healthCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := vectorDB.Health(healthCtx); err != nil {
+		vectorDB.Close()
+		return nil, nil, nil, fmt.Errorf("failed to reach Qdrant at %s:%d: %w", cfg.Qdrant.Host, cfg.Qdrant.Port, err)
 	}
 
+	chunking := cfg.Chunking.GetDefaults()
+
 	gcThreshold := cfg.App.GCThreshold
 	if gcThreshold == 0 {
 		gcThreshold = 100
@@ -289,19 +407,22 @@ func initVectorServices(cfg *config.Config, logger *zap.Logger) (vector.VectorDa
 	chunkService := vector.NewCodeChunkService(
 		vectorDB,
 		embeddingModel,
-		minConditionalLines,
-		minLoopLines,
+		chunking.MinConditionalLines,
+		chunking.MinLoopLines,
 		gcThreshold,
 		numFileThreads,
 		logger,
 	)
+	chunkService.SetChunking(chunking)
 
 	logger.Info("Vector services initialized",
 		zap.String("qdrant_host", cfg.Qdrant.Host),
 		zap.Int("qdrant_port", cfg.Qdrant.Port),
 		zap.String("ollama_url", cfg.Ollama.URL),
-		zap.Int("min_conditional_lines", minConditionalLines),
-		zap.Int("min_loop_lines", minLoopLines),
+		zap.Bool("named_vectors", cfg.Qdrant.NamedVectors),
+		zap.Int("min_conditional_lines", chunking.MinConditionalLines),
+		zap.Int("min_loop_lines", chunking.MinLoopLines),
+		zap.Int("max_chunk_tokens", chunking.MaxChunkTokens),
 		zap.Int64("gc_threshold", gcThreshold))
 
 	return vectorDB, embeddingModel, chunkService, nil
@@ -310,8 +431,8 @@ func initVectorServices(cfg *config.Config, logger *zap.Logger) (vector.VectorDa
 // GetIndexBuildingOptions returns ServiceInitOptions configured for index building CLI
 func GetIndexBuildingOptions(cfg *config.Config) ServiceInitOptions {
 	return ServiceInitOptions{
-		EnableMySQL:       true,
-		RequireMySQL:      true, // MySQL is required for FileID tracking
+		EnableDB:          true,
+		RequireDB:         true, // The relational store is required for FileID tracking
 		EnableCodeGraph:   cfg.IndexBuilding.EnableCodeGraph,
 		EnableEmbeddings:  cfg.IndexBuilding.EnableEmbeddings,
 		EnableRepoService: cfg.IndexBuilding.EnableCodeGraph, // Only needed for CodeGraph
@@ -325,12 +446,13 @@ func GetServerModeOptions(cfg *config.Config) ServiceInitOptions {
 	enableSummary := cfg.Summary.LLMProvider != "" && cfg.Summary.LLMModel != ""
 
 	return ServiceInitOptions{
-		EnableMySQL:       cfg.MySQL.Host != "",
-		RequireMySQL:      false, // Optional in server mode
+		EnableDB:          cfg.HasRelationalStore(),
+		RequireDB:         false, // Optional in server mode
 		EnableCodeGraph:   cfg.App.CodeGraph,
 		EnableEmbeddings:  cfg.Qdrant.Host != "" && cfg.Ollama.URL != "",
-		EnableRepoService: true,         // Always needed in server mode
+		EnableRepoService: true,          // Always needed in server mode
 		EnableSummary:     enableSummary, // Enable for on-demand summary generation if LLM is configured
+		LazyInit:          true,          // Serve what is available, reconnect to the rest in the background
 	}
 }
 
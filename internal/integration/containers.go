@@ -0,0 +1,131 @@
+//go:build integration
+
+package integration
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Images of the stores, overridable to test against other versions
+var (
+	mysqlImage  = imageFromEnv("CODEAPI_IT_MYSQL_IMAGE", "mysql:8.0")
+	neo4jImage  = imageFromEnv("CODEAPI_IT_NEO4J_IMAGE", "neo4j:5")
+	qdrantImage = imageFromEnv("CODEAPI_IT_QDRANT_IMAGE", "qdrant/qdrant:v1.15.1")
+)
+
+func imageFromEnv(name, fallback string) string {
+	if image := os.Getenv(name); image != "" {
+		return image
+	}
+	return fallback
+}
+
+// container is a store started for one test run
+type container struct {
+	id   string
+	host string
+	port int // Host port mapped to the port the store listens on
+}
+
+// Addr returns the host:port the store is reachable at
+func (c *container) Addr() string {
+	return net.JoinHostPort(c.host, fmt.Sprint(c.port))
+}
+
+// requireDocker skips the test when no docker daemon answers, so the suite
+// can be run where docker is only sometimes available
+func requireDocker(t *testing.T) {
+	t.Helper()
+	if _, err := exec.LookPath("docker"); err != nil {
+		t.Skip("docker not found, skipping integration test")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if out, err := exec.CommandContext(ctx, "docker", "info").CombinedOutput(); err != nil {
+		t.Skipf("docker not available, skipping integration test: %v: %s", err, firstLine(out))
+	}
+}
+
+// startContainer runs image with env, publishing port on a free loopback
+// port, and removes the container when the test ends. The store may still
+// be starting up when it returns.
+func startContainer(t *testing.T, image string, port int, env ...string) *container {
+	t.Helper()
+	args := []string{"run", "-d", "--rm", "-p", fmt.Sprintf("127.0.0.1::%d", port)}
+	for _, e := range env {
+		args = append(args, "-e", e)
+	}
+	args = append(args, image)
+
+	// Pulling an image on a cold cache takes a while
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+	out, err := docker(ctx, args...)
+	if err != nil {
+		t.Fatalf("failed to start %s: %v", image, err)
+	}
+	id := strings.TrimSpace(out)
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+		if _, err := docker(ctx, "rm", "-f", id); err != nil {
+			t.Logf("failed to remove container %s of %s: %v", id, image, err)
+		}
+	})
+
+	out, err = docker(ctx, "port", id, fmt.Sprintf("%d/tcp", port))
+	if err != nil {
+		t.Fatalf("failed to find the port of %s: %v", image, err)
+	}
+	host, mapped, err := net.SplitHostPort(firstLine([]byte(out)))
+	if err != nil {
+		t.Fatalf("unexpected port mapping of %s: %q", image, out)
+	}
+	c := &container{id: id, host: host}
+	if _, err := fmt.Sscan(mapped, &c.port); err != nil {
+		t.Fatalf("unexpected port mapping of %s: %q", image, out)
+	}
+	return c
+}
+
+// waitFor calls ready until it succeeds or timeout passes, failing the test
+// with the last error then
+func waitFor(t *testing.T, what string, timeout time.Duration, ready func(ctx context.Context) error) {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		err := ready(ctx)
+		cancel()
+		if err == nil {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%s not ready after %v: %v", what, timeout, err)
+		}
+		time.Sleep(time.Second)
+	}
+}
+
+func docker(ctx context.Context, args ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "docker", args...)
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("docker %s: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
+
+func firstLine(out []byte) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(string(out)), "\n")
+	return line
+}
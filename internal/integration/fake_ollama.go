@@ -0,0 +1,97 @@
+//go:build integration
+
+package integration
+
+import (
+	"encoding/json"
+	"hash/fnv"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"unicode"
+)
+
+// embeddingDim is the dimension of the fake embeddings
+const embeddingDim = 64
+
+// fakeOllama answers the embedding and generate calls of the Ollama API,
+// so a run needs neither a model nor a GPU. Embeddings hash the words of a
+// text into a bag-of-words vector: texts sharing words are close, and the
+// same text always gets the same vector.
+type fakeOllama struct {
+	*httptest.Server
+	embedded  atomic.Int64
+	generated atomic.Int64
+}
+
+func newFakeOllama(t *testing.T) *fakeOllama {
+	t.Helper()
+	f := &fakeOllama{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/embeddings", f.embeddings)
+	mux.HandleFunc("POST /api/generate", f.generate)
+	f.Server = httptest.NewServer(mux)
+	t.Cleanup(f.Close)
+	return f
+}
+
+func (f *fakeOllama) embeddings(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Prompt string `json:"prompt"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	f.embedded.Add(1)
+	json.NewEncoder(w).Encode(map[string]any{"embedding": hashEmbedding(req.Prompt)})
+}
+
+// generate summarizes every prompt with the same sentence, which is all the
+// summary processor needs to store a row
+func (f *fakeOllama) generate(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Model  string `json:"model"`
+		Prompt string `json:"prompt"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	f.generated.Add(1)
+	json.NewEncoder(w).Encode(map[string]any{
+		"model":             req.Model,
+		"response":          "Performs calculator operations.",
+		"done":              true,
+		"prompt_eval_count": len(strings.Fields(req.Prompt)),
+		"eval_count":        3,
+	})
+}
+
+func hashEmbedding(text string) []float64 {
+	vec := make([]float64, embeddingDim)
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for _, word := range words {
+		h := fnv.New32a()
+		h.Write([]byte(word))
+		vec[h.Sum32()%embeddingDim]++
+	}
+
+	var norm float64
+	for _, v := range vec {
+		norm += v * v
+	}
+	if norm == 0 {
+		vec[0], norm = 1, 1
+	}
+	norm = math.Sqrt(norm)
+	for i := range vec {
+		vec[i] /= norm
+	}
+	return vec
+}
@@ -0,0 +1,174 @@
+//go:build integration
+
+// Package integration runs the indexing pipeline end to end against real
+// stores: MySQL, Neo4j and Qdrant run in docker containers started for the
+// test run, and a fake Ollama server stands in for the embedding model and
+// the LLM. The tests are built with the integration tag only:
+//
+//	go test -tags=integration ./internal/integration/
+//
+// They are skipped when no docker daemon is available.
+package integration
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/armchr/codeapi/internal/config"
+	"github.com/armchr/codeapi/internal/controller"
+	"github.com/armchr/codeapi/internal/db"
+	init_services "github.com/armchr/codeapi/internal/init"
+	"github.com/armchr/codeapi/internal/service/vector"
+
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest"
+)
+
+const (
+	reposDir    = "../../tests/repos"
+	goldenDir   = "../../tests/golden/parse"
+	promptsFile = "../../config/summary_prompts.yaml"
+
+	mysqlPassword = "codeapi-test"
+	neo4jPassword = "codeapi-test"
+
+	// Stores take a while to accept connections after their container starts
+	startupTimeout = 3 * time.Minute
+)
+
+// Harness is a service container wired to freshly started stores
+type Harness struct {
+	Config   *config.Config
+	Services *init_services.ServiceContainer
+	LLM      *fakeOllama
+	Logger   *zap.Logger
+}
+
+// NewHarness starts the stores and the fake model server and initializes
+// the services and processors of an index build against them, with every
+// index enabled. Everything is torn down when the test ends.
+func NewHarness(t *testing.T, repos ...config.Repository) *Harness {
+	t.Helper()
+	requireDocker(t)
+	logger := zaptest.NewLogger(t, zaptest.Level(zap.WarnLevel))
+
+	mysql := startContainer(t, mysqlImage, 3306, "MYSQL_ROOT_PASSWORD="+mysqlPassword)
+	neo := startContainer(t, neo4jImage, 7687, "NEO4J_AUTH=neo4j/"+neo4jPassword)
+	qdrant := startContainer(t, qdrantImage, 6334)
+	llm := newFakeOllama(t)
+
+	cfg := &config.Config{
+		DB: config.DBConfig{Driver: config.DBDriverMySQL},
+		MySQL: config.MySQLConfig{
+			Host:     mysql.host,
+			Port:     mysql.port,
+			Username: "root",
+			Password: mysqlPassword,
+		},
+		Neo4j: config.Neo4jConfig{
+			URI:      "bolt://" + neo.Addr(),
+			Username: "neo4j",
+			Password: neo4jPassword,
+		},
+		Qdrant: config.QdrantConfig{Host: qdrant.host, Port: qdrant.port},
+		Ollama: config.OllamaConfig{URL: llm.URL, Model: "fake-embed", Dimension: embeddingDim},
+		// Language servers cannot be assumed on a CI runner, so calls are
+		// resolved by the tree-sitter resolvers. Summaries stay on, which
+		// loading the lite profile from a file would turn off.
+		IndexBuilding: config.IndexBuildingConfig{
+			EnableCodeGraph:  true,
+			EnableEmbeddings: true,
+			EnableSummary:    true,
+			Profile:          config.IndexProfileLite,
+		},
+		Summary: config.SummaryConfig{
+			LLMProvider: "ollama",
+			LLMModel:    "fake-llm",
+			OllamaURL:   llm.URL,
+			PromptsFile: promptsFile,
+			WorkerCount: 2,
+			BatchSize:   10,
+		},
+		App:    config.App{WorkDir: t.TempDir()},
+		Source: config.SourceConfig{Repositories: repos},
+	}
+
+	waitFor(t, "MySQL", startupTimeout, func(ctx context.Context) error {
+		conn, err := db.NewMySQLConnection(cfg.MySQL, zap.NewNop())
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	})
+	waitFor(t, "Neo4j", startupTimeout, func(ctx context.Context) error {
+		driver, err := neo4j.NewDriverWithContext(cfg.Neo4j.URI, neo4j.BasicAuth(cfg.Neo4j.Username, cfg.Neo4j.Password, ""))
+		if err != nil {
+			return err
+		}
+		defer driver.Close(ctx)
+		return driver.VerifyConnectivity(ctx)
+	})
+	waitFor(t, "Qdrant", startupTimeout, func(ctx context.Context) error {
+		qdb, err := vector.NewQdrantDatabase(cfg.Qdrant.Host, cfg.Qdrant.Port, "", config.PoolConfig{}, zap.NewNop())
+		if err != nil {
+			return err
+		}
+		defer qdb.Close()
+		return qdb.Health(ctx)
+	})
+
+	services, err := init_services.NewServiceContainer(cfg, init_services.GetIndexBuildingOptions(cfg), logger)
+	if err != nil {
+		t.Fatalf("failed to initialize services: %v", err)
+	}
+	t.Cleanup(func() { services.Close(context.Background()) })
+	if err := services.InitProcessors(cfg); err != nil {
+		t.Fatalf("failed to initialize processors: %v", err)
+	}
+
+	return &Harness{Config: cfg, Services: services, LLM: llm, Logger: logger}
+}
+
+// Fixture returns the repository of a fixture under tests/repos
+func Fixture(t *testing.T, name, language string) config.Repository {
+	t.Helper()
+	path, err := filepath.Abs(filepath.Join(reposDir, name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return config.Repository{Name: name, Path: path, Language: language}
+}
+
+// Index builds every index of a configured repository, as the build-index
+// command does, and returns the statistics of the build
+func (h *Harness) Index(t *testing.T, repoName string) *controller.BuildStats {
+	t.Helper()
+	repo, err := h.Config.GetRepository(repoName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fileVersions, err := db.NewFileVersionRepository(h.Services.DBConn.GetDB(), repo.Name, h.Logger)
+	if err != nil {
+		t.Fatalf("failed to create file version repository: %v", err)
+	}
+
+	builder := controller.NewIndexBuilder(h.Config, h.Services.Processors, fileVersions, h.Logger)
+	if err := builder.BuildIndex(context.Background(), repo); err != nil {
+		t.Fatalf("failed to index %s: %v", repo.Name, err)
+	}
+	return builder.LastStats()
+}
+
+// Stats reads what every store holds for a repository
+func (h *Harness) Stats(t *testing.T, repoName string) *controller.RepoStats {
+	t.Helper()
+	stats := controller.CollectRepoStats(context.Background(), repoName, h.Services.DBConn.GetDB(),
+		h.Services.CodeGraph, h.Services.VectorDB, h.Logger)
+	for store, reason := range stats.Errors {
+		t.Errorf("stats of %s: %s: %s", repoName, store, reason)
+	}
+	return stats
+}
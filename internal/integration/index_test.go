@@ -0,0 +1,115 @@
+//go:build integration
+
+package integration
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// goldenCounts reads the files, functions and classes of a fixture from its
+// parse golden dump, so the expected graph follows the parser's output
+func goldenCounts(t *testing.T, name string) (files, functions, classes int64) {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(goldenDir, name+".dump.txt"))
+	if err != nil {
+		t.Fatalf("failed to read golden dump: %v", err)
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(line, "Total files:"):
+			files, err = strconv.ParseInt(strings.TrimSpace(strings.TrimPrefix(line, "Total files:")), 10, 64)
+			if err != nil {
+				t.Fatalf("unexpected golden line %q", line)
+			}
+		case strings.Contains(line, "[Function]"):
+			functions++
+		case strings.Contains(line, "[Class]"):
+			classes++
+		}
+	}
+	return files, functions, classes
+}
+
+func TestIndexFixture(t *testing.T) {
+	const repoName = "go-calculator"
+	h := NewHarness(t, Fixture(t, repoName, "go"))
+	ctx := context.Background()
+
+	build := h.Index(t, repoName)
+	if build == nil || build.FilesProcessed == 0 {
+		t.Fatalf("build stats = %+v, want processed files", build)
+	}
+
+	t.Run("graph", func(t *testing.T) {
+		files, functions, classes := goldenCounts(t, repoName)
+		stats := h.Stats(t, repoName)
+		if stats.Totals == nil {
+			t.Fatal("no graph totals")
+		}
+		if stats.Totals.Files != files || stats.Totals.Functions != functions || stats.Totals.Classes != classes {
+			t.Errorf("graph holds %d files, %d functions, %d classes; golden dump has %d, %d, %d",
+				stats.Totals.Files, stats.Totals.Functions, stats.Totals.Classes, files, functions, classes)
+		}
+		if stats.FileVersions == nil || *stats.FileVersions != files {
+			t.Errorf("file versions = %v, want %d", stats.FileVersions, files)
+		}
+		if stats.Chunks == nil || *stats.Chunks == 0 {
+			t.Errorf("chunks = %v, want some", stats.Chunks)
+		}
+	})
+
+	t.Run("summaries", func(t *testing.T) {
+		sums := h.Stats(t, repoName).Summaries
+		if sums == nil {
+			t.Fatal("no summary stats")
+		}
+		if sums.Functions == 0 || sums.Files == 0 || sums.Folders == 0 || sums.Projects != 1 {
+			t.Errorf("summaries = %+v, want functions, files, folders and one project", sums)
+		}
+		if h.LLM.generated.Load() < sums.Total {
+			t.Errorf("%d summaries stored from %d generate calls", sums.Total, h.LLM.generated.Load())
+		}
+	})
+
+	t.Run("search", func(t *testing.T) {
+		snippet := `func DecimalAdd(a, b string) (string, error) {
+	da, err := decimal.NewFromString(a)
+	if err != nil {
+		return "", err
+	}
+	db, err := decimal.NewFromString(b)
+	if err != nil {
+		return "", err
+	}
+	return da.Add(db).String(), nil
+}`
+		_, chunks, scores, _, err := h.Services.ChunkService.SearchSimilarCodeBySnippet(ctx, repoName, snippet, "go", 5, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(chunks) == 0 || chunks[0].Name != "DecimalAdd" {
+			var names []string
+			for _, c := range chunks {
+				names = append(names, c.Name)
+			}
+			t.Errorf("search returned %v (scores %v), want DecimalAdd first", names, scores)
+		}
+	})
+
+	t.Run("folders", func(t *testing.T) {
+		folders, _, err := h.Services.ChunkService.SelectFolders(ctx, repoName, "calculator operations", 5)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Contains(folders, "operations") {
+			t.Errorf("SelectFolders = %v, want operations among them", folders)
+		}
+	})
+}
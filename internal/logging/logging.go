@@ -0,0 +1,196 @@
+// Package logging builds the application logger from the logging config:
+// output destinations with optional rotation, JSON or console encoding,
+// per-module level overrides and request-scoped loggers carried in contexts.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/armchr/codeapi/internal/config"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// Modules with their own level override (logging.modules)
+const (
+	ModuleHTTP      = "http"
+	ModuleDB        = "db"
+	ModuleCodeGraph = "codegraph"
+	ModuleVector    = "vector"
+	ModuleLSP       = "lsp"
+	ModuleParse     = "parse"
+	ModuleSummary   = "summary"
+	ModuleIndex     = "index"
+)
+
+// ParseLevel converts a config level name to a zap level. Unknown names
+// fall back to info and report false.
+func ParseLevel(level string) (zapcore.Level, bool) {
+	switch strings.ToLower(level) {
+	case "debug":
+		return zapcore.DebugLevel, true
+	case "", "info":
+		return zapcore.InfoLevel, true
+	case "warn", "warning":
+		return zapcore.WarnLevel, true
+	case "error":
+		return zapcore.ErrorLevel, true
+	default:
+		return zapcore.InfoLevel, false
+	}
+}
+
+// New builds the root logger. baseLevel (app.log_level) applies to every
+// module without an override in cfg.Modules.
+func New(baseLevel string, cfg config.LoggingConfig) (*zap.Logger, error) {
+	levels := &moduleLevels{modules: make(map[string]zapcore.Level)}
+	levels.base, _ = ParseLevel(baseLevel)
+	for module, name := range cfg.Modules {
+		levels.modules[module], _ = ParseLevel(name)
+	}
+
+	outputs := cfg.Outputs
+	if len(outputs) == 0 {
+		outputs = []config.LogOutput{{Type: "stdout"}, {Type: "file", Path: "all.log"}}
+	}
+
+	cores := make([]zapcore.Core, 0, len(outputs))
+	for _, out := range outputs {
+		core, err := newOutputCore(out, cfg.Encoding)
+		if err != nil {
+			return nil, err
+		}
+		cores = append(cores, core)
+	}
+
+	// Same sampling as zap's production preset
+	sampled := zapcore.NewSamplerWithOptions(zapcore.NewTee(cores...), time.Second, 100, 100)
+	root := &moduleCore{Core: sampled, level: levels.base, levels: levels}
+	return zap.New(root, zap.AddCaller(), zap.AddStacktrace(zapcore.ErrorLevel)), nil
+}
+
+// newOutputCore creates the core writing to one output
+func newOutputCore(out config.LogOutput, defaultEncoding string) (zapcore.Core, error) {
+	encoding := out.Encoding
+	if encoding == "" {
+		encoding = defaultEncoding
+	}
+
+	var encoder zapcore.Encoder
+	switch encoding {
+	case "", "json":
+		encoder = zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig())
+	case "console":
+		encCfg := zap.NewProductionEncoderConfig()
+		encCfg.EncodeTime = zapcore.ISO8601TimeEncoder
+		encCfg.EncodeLevel = zapcore.CapitalLevelEncoder
+		encoder = zapcore.NewConsoleEncoder(encCfg)
+	default:
+		return nil, fmt.Errorf("unsupported log encoding %q", encoding)
+	}
+
+	var sink zapcore.WriteSyncer
+	switch out.Type {
+	case "stdout":
+		sink = zapcore.Lock(os.Stdout)
+	case "stderr":
+		sink = zapcore.Lock(os.Stderr)
+	case "file":
+		if out.Path == "" {
+			return nil, fmt.Errorf("file log output needs a path")
+		}
+		if out.MaxSizeMB > 0 {
+			sink = zapcore.AddSync(&lumberjack.Logger{
+				Filename:   out.Path,
+				MaxSize:    out.MaxSizeMB,
+				MaxBackups: out.MaxBackups,
+				MaxAge:     out.MaxAgeDays,
+				Compress:   out.Compress,
+			})
+		} else {
+			f, err := os.OpenFile(out.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+			if err != nil {
+				return nil, fmt.Errorf("failed to open log file: %w", err)
+			}
+			sink = zapcore.Lock(f)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported log output type %q", out.Type)
+	}
+
+	// Module levels do the filtering; an output level only raises the floor
+	minLevel := zapcore.DebugLevel
+	if out.Level != "" {
+		minLevel, _ = ParseLevel(out.Level)
+	}
+	return zapcore.NewCore(encoder, sink, minLevel), nil
+}
+
+// moduleLevels holds the configured level of each module
+type moduleLevels struct {
+	base    zapcore.Level
+	modules map[string]zapcore.Level
+}
+
+func (l *moduleLevels) forModule(name string) zapcore.Level {
+	if level, ok := l.modules[name]; ok {
+		return level
+	}
+	return l.base
+}
+
+// moduleCore filters entries by the level of the module it was created for
+type moduleCore struct {
+	zapcore.Core
+	level  zapcore.Level
+	levels *moduleLevels
+}
+
+func (c *moduleCore) Enabled(level zapcore.Level) bool {
+	return level >= c.level && c.Core.Enabled(level)
+}
+
+func (c *moduleCore) With(fields []zapcore.Field) zapcore.Core {
+	return &moduleCore{Core: c.Core.With(fields), level: c.level, levels: c.levels}
+}
+
+func (c *moduleCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if entry.Level < c.level {
+		return checked
+	}
+	return c.Core.Check(entry, checked)
+}
+
+// Module returns a logger named after module that logs at the module's
+// configured level. Loggers not built by New are only renamed.
+func Module(logger *zap.Logger, module string) *zap.Logger {
+	return logger.WithOptions(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		mc, ok := core.(*moduleCore)
+		if !ok {
+			return core
+		}
+		return &moduleCore{Core: mc.Core, level: mc.levels.forModule(module), levels: mc.levels}
+	})).Named(module)
+}
+
+type loggerKey struct{}
+
+// WithLogger returns a context carrying a request-scoped logger
+func WithLogger(ctx context.Context, logger *zap.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, logger)
+}
+
+// FromContext returns the request-scoped logger of ctx, or fallback when the
+// context carries none
+func FromContext(ctx context.Context, fallback *zap.Logger) *zap.Logger {
+	if logger, ok := ctx.Value(loggerKey{}).(*zap.Logger); ok {
+		return logger
+	}
+	return fallback
+}
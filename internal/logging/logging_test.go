@@ -0,0 +1,96 @@
+package logging
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/armchr/codeapi/internal/config"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func newObservedLogger(base zapcore.Level, modules map[string]zapcore.Level) (*zap.Logger, *observer.ObservedLogs) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	levels := &moduleLevels{base: base, modules: modules}
+	return zap.New(&moduleCore{Core: core, level: base, levels: levels}), logs
+}
+
+func TestModuleLevels(t *testing.T) {
+	root, logs := newObservedLogger(zapcore.InfoLevel, map[string]zapcore.Level{
+		ModuleParse: zapcore.WarnLevel,
+		ModuleLSP:   zapcore.DebugLevel,
+	})
+
+	root.Debug("root debug")
+	root.Info("root info")
+
+	parse := Module(root, ModuleParse)
+	parse.Info("parse info")
+	parse.Warn("parse warn")
+
+	// Fields added to a module logger keep its level
+	lsp := Module(root, ModuleLSP).With(zap.String("repo", "r"))
+	lsp.Debug("lsp debug")
+
+	// Re-scoping a module logger applies the new module's level, not both
+	Module(parse, ModuleLSP).Debug("parse to lsp debug")
+
+	Module(root, ModuleDB).Debug("db debug")
+
+	var got []string
+	for _, entry := range logs.All() {
+		got = append(got, entry.LoggerName+":"+entry.Message)
+	}
+	want := []string{":root info", "parse:parse warn", "lsp:lsp debug", "parse.lsp:parse to lsp debug"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("logged %v, want %v", got, want)
+	}
+}
+
+func TestModuleWithoutNew(t *testing.T) {
+	logger := Module(zap.NewNop(), ModuleParse)
+	logger.Info("no panic")
+}
+
+func TestNewFileOutput(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	logger, err := New("info", config.LoggingConfig{
+		Encoding: "console",
+		Outputs:  []config.LogOutput{{Type: "file", Path: path, MaxSizeMB: 1}},
+		Modules:  map[string]string{ModuleParse: "error"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger.Info("kept")
+	Module(logger, ModuleParse).Warn("dropped")
+	logger.Sync()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "kept") || strings.Contains(string(data), "dropped") {
+		t.Errorf("unexpected log contents: %s", data)
+	}
+
+	if _, err := New("info", config.LoggingConfig{Outputs: []config.LogOutput{{Type: "syslog"}}}); err == nil {
+		t.Error("unsupported output type should fail")
+	}
+}
+
+func TestFromContext(t *testing.T) {
+	fallback := zap.NewNop()
+	if FromContext(context.Background(), fallback) != fallback {
+		t.Error("FromContext() should return the fallback for a bare context")
+	}
+	scoped := zap.NewExample()
+	if FromContext(WithLogger(context.Background(), scoped), fallback) != scoped {
+		t.Error("FromContext() should return the request logger")
+	}
+}
@@ -0,0 +1,254 @@
+// Package metrics defines the Prometheus metrics exported on /metrics. The
+// collectors are registered with the default registry on package init, so
+// instrumented code only calls the helpers below.
+package metrics
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/armchr/codeapi/internal/telemetry"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const namespace = "codeapi"
+
+// Store label values
+const (
+	StoreNeo4j  = "neo4j"
+	StoreQdrant = "qdrant"
+	StoreDB     = "db"
+)
+
+// LSP request outcomes
+const (
+	LSPOutcomeOK      = "ok"
+	LSPOutcomeError   = "error"
+	LSPOutcomeTimeout = "timeout"
+	LSPOutcomeCancel  = "cancelled"
+)
+
+var (
+	httpRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests by route, method, status and repository.",
+	}, []string{"method", "route", "status", "repo"})
+
+	httpDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by route and method.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "repo"})
+
+	indexFiles = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "index_files_total",
+		Help:      "Files visited by index builds, by result (processed or unchanged).",
+	}, []string{"repo", "result"})
+
+	indexProcessorDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "index_processor_duration_seconds",
+		Help:      "Time a processor spent on one file (phase=file) or on post-processing a repository (phase=post_process).",
+		Buckets:   []float64{.001, .005, .01, .05, .1, .5, 1, 5, 30, 120, 600},
+	}, []string{"repo", "processor", "phase"})
+
+	storeDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "store_query_duration_seconds",
+		Help:      "Latency of Neo4j, Qdrant and relational store queries.",
+		Buckets:   []float64{.0005, .001, .005, .01, .025, .05, .1, .25, .5, 1, 5, 30},
+	}, []string{"store", "operation", "repo"})
+
+	storeErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "store_query_errors_total",
+		Help:      "Failed Neo4j, Qdrant and relational store queries.",
+	}, []string{"store", "operation", "repo"})
+
+	storeRetries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "store_query_retries_total",
+		Help:      "Store queries retried after a transient failure.",
+	}, []string{"store", "operation"})
+
+	graphBreakerOpen = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "graph_breaker_open",
+		Help:      "1 while the Neo4j circuit breaker is open and graph writes are paused, 0 otherwise.",
+	})
+
+	graphWALPending = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "graph_wal_pending_writes",
+		Help:      "Graph writes waiting in the write-ahead log for Neo4j to recover.",
+	})
+
+	lspRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "lsp_requests_total",
+		Help:      "Language server requests by server, method and outcome.",
+	}, []string{"server", "method", "outcome"})
+
+	lspDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "lsp_request_duration_seconds",
+		Help:      "Language server request latency.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"server", "method"})
+
+	httpInFlight = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "http_limited_in_flight",
+		Help:      "Requests running in each concurrency-limited endpoint class.",
+	}, []string{"class"})
+
+	httpQueueWait = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_queue_wait_seconds",
+		Help:      "Time requests waited for a slot in a concurrency-limited endpoint class.",
+		Buckets:   []float64{.001, .01, .1, .5, 1, 2.5, 5, 10, 30},
+	}, []string{"class"})
+
+	httpRejected = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_rejected_total",
+		Help:      "Requests rejected with 429 because their endpoint class stayed at its concurrency limit.",
+	}, []string{"class"})
+
+	resourceInUse = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "resource_in_use",
+		Help:      "Slots of the resource budget (cpu, llm, embedding) currently held.",
+	}, []string{"resource"})
+
+	resourceWait = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "resource_wait_seconds",
+		Help:      "Time spent waiting for a slot of the resource budget.",
+		Buckets:   []float64{.001, .01, .1, .5, 1, 5, 30, 120},
+	}, []string{"resource"})
+
+	llmTokens = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "llm_tokens_total",
+		Help:      "LLM tokens used for summaries, by kind (prompt or output).",
+	}, []string{"repo", "provider", "model", "kind"})
+)
+
+// ObserveHTTPRequest records a served request. route is the registered route
+// pattern rather than the raw path, keeping the label set bounded.
+func ObserveHTTPRequest(method, route string, status int, repo string, d time.Duration) {
+	httpRequests.WithLabelValues(method, route, strconv.Itoa(status), repo).Inc()
+	httpDuration.WithLabelValues(method, route, repo).Observe(d.Seconds())
+	telemetry.RecordFeature(method + " " + route)
+	if status >= 500 {
+		telemetry.RecordError("http_5xx")
+	}
+}
+
+// LimitedRequestStarted records a request of a concurrency-limited class
+// getting its slot after waiting for wait
+func LimitedRequestStarted(class string, wait time.Duration) {
+	httpQueueWait.WithLabelValues(class).Observe(wait.Seconds())
+	httpInFlight.WithLabelValues(class).Inc()
+}
+
+// LimitedRequestDone releases the slot taken in LimitedRequestStarted
+func LimitedRequestDone(class string) {
+	httpInFlight.WithLabelValues(class).Dec()
+}
+
+// LimitedRequestRejected counts a request turned away by a concurrency limit
+func LimitedRequestRejected(class string) {
+	httpRejected.WithLabelValues(class).Inc()
+}
+
+// ResourceAcquired records a budget slot taken after waiting for wait
+func ResourceAcquired(resource string, wait time.Duration) {
+	resourceWait.WithLabelValues(resource).Observe(wait.Seconds())
+	resourceInUse.WithLabelValues(resource).Inc()
+}
+
+// ResourceReleased records a budget slot given back
+func ResourceReleased(resource string) {
+	resourceInUse.WithLabelValues(resource).Dec()
+}
+
+// IndexFileDone counts a file visited by an index build
+func IndexFileDone(repo string, processed bool) {
+	result := "unchanged"
+	if processed {
+		result = "processed"
+	}
+	indexFiles.WithLabelValues(repo, result).Inc()
+}
+
+// ObserveProcessorFile records the time a processor spent on one file
+func ObserveProcessorFile(repo, processor string, d time.Duration) {
+	indexProcessorDuration.WithLabelValues(repo, processor, "file").Observe(d.Seconds())
+}
+
+// ObserveProcessorPostProcess records the post-processing time of a processor
+func ObserveProcessorPostProcess(repo, processor string, d time.Duration) {
+	indexProcessorDuration.WithLabelValues(repo, processor, "post_process").Observe(d.Seconds())
+}
+
+// TimeStoreQuery starts timing a store query. Call the returned function
+// with the query's error when it completes:
+//
+//	done := metrics.TimeStoreQuery(metrics.StoreQdrant, "search", collection)
+//	defer func() { done(err) }()
+func TimeStoreQuery(store, operation, repo string) func(err error) {
+	start := time.Now()
+	return func(err error) {
+		storeDuration.WithLabelValues(store, operation, repo).Observe(time.Since(start).Seconds())
+		if err != nil {
+			storeErrors.WithLabelValues(store, operation, repo).Inc()
+			telemetry.RecordError(store + "_query")
+		}
+	}
+}
+
+// StoreQueryRetried counts a store query attempted again after a transient
+// failure
+func StoreQueryRetried(store, operation string) {
+	storeRetries.WithLabelValues(store, operation).Inc()
+}
+
+// SetGraphBreakerOpen records whether the Neo4j circuit breaker is open
+func SetGraphBreakerOpen(open bool) {
+	if open {
+		graphBreakerOpen.Set(1)
+	} else {
+		graphBreakerOpen.Set(0)
+	}
+}
+
+// SetGraphWALPending records the graph writes waiting for Neo4j to recover
+func SetGraphWALPending(n int) {
+	graphWALPending.Set(float64(n))
+}
+
+// ObserveLSPRequest records a language server request
+func ObserveLSPRequest(server, method, outcome string, d time.Duration) {
+	lspRequests.WithLabelValues(server, method, outcome).Inc()
+	lspDuration.WithLabelValues(server, method).Observe(d.Seconds())
+	if outcome == LSPOutcomeError || outcome == LSPOutcomeTimeout {
+		telemetry.RecordError("lsp_" + outcome)
+	}
+}
+
+// AddLLMTokens counts the tokens one LLM call used
+func AddLLMTokens(repo, provider, model string, promptTokens, outputTokens int) {
+	if promptTokens > 0 {
+		llmTokens.WithLabelValues(repo, provider, model, "prompt").Add(float64(promptTokens))
+	}
+	if outputTokens > 0 {
+		llmTokens.WithLabelValues(repo, provider, model, "output").Add(float64(outputTokens))
+	}
+}
@@ -20,6 +20,11 @@ const (
 	NodeTypeFileNumber   NodeType = 11
 	NodeTypeLoop         NodeType = 12
 	NodeTypeImport       NodeType = 13
+	NodeTypeTryCatch     NodeType = 14
+	NodeTypeConstant     NodeType = 15
+	NodeTypeTable        NodeType = 16
+	NodeTypeConfig       NodeType = 17
+	NodeTypeFeatureFlag  NodeType = 18
 )
 
 type NodeID int64
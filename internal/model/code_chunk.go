@@ -1,6 +1,9 @@
 package model
 
 import (
+	"path"
+	"strings"
+
 	"github.com/armchr/codeapi/pkg/lsp/base"
 )
 
@@ -15,8 +18,17 @@ const (
 	ChunkTypeConditional     ChunkType = "conditional"      // if, else, switch, case
 	ChunkTypeLoop            ChunkType = "loop"             // for, while, do-while
 	ChunkTypeMethodSignature ChunkType = "method_signature" // For semantic signature search
+	ChunkTypeWindow          ChunkType = "window"           // Line window of a chunk too large to embed whole
+	ChunkTypeNote            ChunkType = "note"             // TODO-style comment, for search over technical debt
+	ChunkTypeFolderSummary   ChunkType = "folder_summary"   // LLM summary of a folder, for picking folders to search
+	ChunkTypeProjectSummary  ChunkType = "project_summary"  // LLM summary of the whole repository
 )
 
+// MaxChunkContentBytes caps the content kept in a chunk. File and class
+// chunks would otherwise each hold a copy of most of the source; embeddings
+// only use the first few thousand characters anyway.
+const MaxChunkContentBytes = 64 * 1024
+
 // CodeChunk represents a hierarchical piece of code with vector embedding
 type CodeChunk struct {
 	// Unique identifier for this chunk
@@ -25,6 +37,12 @@ type CodeChunk struct {
 	// FileID from MySQL file_versions table (shared with CodeGraph)
 	FileID int32 `json:"file_id"`
 
+	// FileSHA is the SHA256 of the file content the chunk was cut from, and
+	// LineHashes hash its lines there, so its range can be followed into
+	// later versions of the file (see chunk.RemapRange)
+	FileSHA    string   `json:"file_sha,omitempty"`
+	LineHashes []uint32 `json:"-"`
+
 	// Hierarchical metadata
 	ChunkType ChunkType `json:"chunk_type"`
 	Level     int       `json:"level"` // 1=file, 2=class, 3=function, 4=block
@@ -47,9 +65,21 @@ type CodeChunk struct {
 	ModuleName string `json:"module_name,omitempty"` // Package/module name
 	ClassName  string `json:"class_name,omitempty"`  // Parent class if method
 
+	// EntityRef is the public reference of the file, class or function the
+	// chunk was cut from, set on chunks returned by the API. It is not stored.
+	EntityRef string `json:"entity_ref,omitempty"`
+
+	// EmbeddingHeader is prepended to the text embedded with context, see
+	// chunk.AddContextHeaders. It is not stored.
+	EmbeddingHeader string `json:"-"`
+
 	// Vector embedding (generated by embedding model)
 	Embedding []float32 `json:"embedding,omitempty"`
 
+	// SignatureEmbedding embeds the normalized signature of a function. It
+	// is stored in the chunk's point when the collection has named vectors.
+	SignatureEmbedding []float32 `json:"signature_embedding,omitempty"`
+
 	// Additional metadata
 	Metadata map[string]interface{} `json:"metadata,omitempty"`
 }
@@ -122,6 +152,27 @@ func (c *CodeChunk) WithMetadata(key string, value interface{}) *CodeChunk {
 	return c
 }
 
+// Folders returns the folders holding the chunk's file, outermost first,
+// e.g. "a" and "a/b" for "a/b/c.go". Summary chunks describe a folder
+// rather than sit in one and have none.
+func (c *CodeChunk) Folders() []string {
+	if c.ChunkType == ChunkTypeFolderSummary || c.ChunkType == ChunkTypeProjectSummary {
+		return nil
+	}
+	dir := path.Dir(strings.ReplaceAll(c.FilePath, "\\", "/"))
+	if dir == "." || dir == "/" {
+		return nil
+	}
+	var folders []string
+	for d := dir; d != "." && d != "/"; d = path.Dir(d) {
+		folders = append(folders, d)
+	}
+	for i, j := 0, len(folders)-1; i < j; i, j = i+1, j-1 {
+		folders[i], folders[j] = folders[j], folders[i]
+	}
+	return folders
+}
+
 // GetSearchableText returns the text representation for embedding generation
 // Truncates content to avoid exceeding embedding model context limits
 // includeContext: if true, includes module/class context; if false, only includes the code content
@@ -132,6 +183,7 @@ func (c *CodeChunk) GetSearchableText(includeContext bool) string {
 
 	// Add context information only if requested
 	if includeContext {
+		text += c.EmbeddingHeader
 		if c.ModuleName != "" {
 			text += "Module: " + c.ModuleName + "\n"
 		}
@@ -56,6 +56,8 @@ type GetFunctionDependenciesRequest struct {
 	RelativePath string `json:"relative_path" binding:"required"`
 	FunctionName string `json:"function_name" binding:"required"`
 	Depth        int    `json:"depth"`
+	TimeBudgetMs int    `json:"time_budget_ms"` // 0: no budget
+	Cursor       string `json:"cursor"`         // from a partial response, to continue it
 }
 
 type GetFunctionDependenciesResponse struct {
@@ -86,6 +88,18 @@ type CallGraph struct {
 	Edges        []CallEdge                     `json:"edges"`
 	functionsMap map[string]*FunctionDefinition `json:"-"`
 	edgesMap     map[string]*CallEdge           `json:"-"`
+
+	// Partial is set when the time budget of the request ran out before the
+	// traversal finished. Cursor continues it from the functions left.
+	Partial bool   `json:"partial,omitempty"`
+	Cursor  string `json:"cursor,omitempty"`
+}
+
+// PendingFunction is a function whose calls are still to be traversed, with
+// the depth left below it
+type PendingFunction struct {
+	Function FunctionDefinition `json:"function"`
+	Depth    int                `json:"depth"`
 }
 
 type CallEdge struct {
@@ -284,17 +298,44 @@ type SearchSimilarCodeRequest struct {
 	Language       string `json:"language" binding:"required"`
 	Limit          int    `json:"limit"`
 	IncludeCode    bool   `json:"include_code"`
+	// IncludeSummaries adds the stored summary of each hit's function, class
+	// or file
+	IncludeSummaries bool `json:"include_summaries"`
+	// Mode is SearchModeDirect (default) or SearchModeCoarseToFine
+	Mode string `json:"mode,omitempty"`
+	// FolderLimit is the number of folders searched in coarse-to-fine mode
+	FolderLimit int `json:"folder_limit,omitempty"`
+	// Explain adds to each hit why it matched
+	Explain bool `json:"explain,omitempty"`
 }
 
+// Search modes of SearchSimilarCodeRequest
+const (
+	// SearchModeDirect searches all chunks of the collection
+	SearchModeDirect = "direct"
+	// SearchModeCoarseToFine first picks the folders whose summaries are
+	// closest to the snippet, then searches the chunks inside them only
+	SearchModeCoarseToFine = "coarse_to_fine"
+)
+
 type SearchSimilarCodeResponse struct {
 	RepoName       string              `json:"repo_name"`
 	CollectionName string              `json:"collection_name"`
+	Mode           string              `json:"mode"`
+	Folders        []FolderMatch       `json:"folders,omitempty"` // Folders searched in coarse-to-fine mode
 	Query          QueryInfo           `json:"query"`
 	Results        []SimilarCodeResult `json:"results"`
+	Redacted       bool                `json:"redacted,omitempty"` // Chunk content left out in public mode
 	Success        bool                `json:"success"`
 	Message        string              `json:"message,omitempty"`
 }
 
+// FolderMatch is a folder picked by its summary in coarse-to-fine search
+type FolderMatch struct {
+	Path  string  `json:"path"`
+	Score float32 `json:"score"`
+}
+
 type QueryInfo struct {
 	CodeSnippet string       `json:"code_snippet"`
 	Language    string       `json:"language"`
@@ -307,6 +348,165 @@ type SimilarCodeResult struct {
 	Score           float32    `json:"score"`
 	QueryChunkIndex int        `json:"query_chunk_index"` // Index of the input chunk that matched this result (0-based)
 	Code            string     `json:"code,omitempty"`    // Actual code content from file (if include_code is true)
+	Summary         string     `json:"summary,omitempty"` // Stored summary of the hit (if include_summaries is true)
+
+	Explanation *SearchExplanation `json:"explanation,omitempty"` // Why the hit matched (if explain is true)
+}
+
+// SearchExplanation tells why a search hit matched, for debugging relevance
+type SearchExplanation struct {
+	// QueryChunkIndex and QueryChunk identify the part of the query that
+	// matched, for snippet searches that split the query into chunks
+	QueryChunkIndex int    `json:"query_chunk_index"`
+	QueryChunk      string `json:"query_chunk,omitempty"` // e.g. "function processOrder"
+	// VectorScore is the similarity the vector database returned
+	VectorScore float32 `json:"vector_score"`
+	// LexicalOverlap lists the words of the query found in the hit's name,
+	// signature, docstring or text
+	LexicalOverlap []string `json:"lexical_overlap"`
+	// RerankScore is the score after reranking. Searches do not rerank yet,
+	// so it is left out and hits are ordered by VectorScore.
+	RerankScore *float32 `json:"rerank_score,omitempty"`
+	// Filters are the payload filters the vector search applied
+	Filters map[string]interface{} `json:"filters,omitempty"`
+}
+
+// ChunkHierarchyRequest asks for the chunks around a chunk, usually a search hit
+type ChunkHierarchyRequest struct {
+	RepoName       string `json:"repo_name" binding:"required"`
+	CollectionName string `json:"collection_name"`
+	ChunkID        string `json:"chunk_id" binding:"required"`
+}
+
+// ChunkHierarchyResponse holds a chunk with its enclosing chunks, outermost
+// first, and the chunks that share its parent in source order
+type ChunkHierarchyResponse struct {
+	RepoName       string       `json:"repo_name"`
+	CollectionName string       `json:"collection_name"`
+	Chunk          *CodeChunk   `json:"chunk"`
+	Ancestors      []*CodeChunk `json:"ancestors"`
+	Siblings       []*CodeChunk `json:"siblings"`
+	Redacted       bool         `json:"redacted,omitempty"` // Chunk content left out in public mode
+	Success        bool         `json:"success"`
+	Message        string       `json:"message,omitempty"`
+}
+
+// RemapChunksRequest asks where chunks, usually search hits, are in the
+// current version of their files
+type RemapChunksRequest struct {
+	RepoName       string   `json:"repo_name" binding:"required"`
+	CollectionName string   `json:"collection_name"`
+	ChunkIDs       []string `json:"chunk_ids" binding:"required,min=1"`
+}
+
+// ChunkRemap is the range of a chunk in the version of its file it was
+// indexed from and in the current one. Status is one of unchanged, moved,
+// changed, deleted or unknown (indexed before line hashes were stored).
+type ChunkRemap struct {
+	ChunkID        string `json:"chunk_id"`
+	FilePath       string `json:"file_path,omitempty"`
+	FileID         int32  `json:"file_id,omitempty"`
+	FileSHA        string `json:"file_sha,omitempty"`
+	CurrentSHA     string `json:"current_sha,omitempty"`
+	OldStartLine   int    `json:"old_start_line"`
+	OldEndLine     int    `json:"old_end_line"`
+	StartLine      int    `json:"start_line"`
+	EndLine        int    `json:"end_line"`
+	Status         string `json:"status,omitempty"`
+	ContentChanged bool   `json:"content_changed"`
+	Error          string `json:"error,omitempty"`
+}
+
+// RemapChunksResponse lists a ChunkRemap per requested chunk, in order
+type RemapChunksResponse struct {
+	RepoName       string       `json:"repo_name"`
+	CollectionName string       `json:"collection_name"`
+	Results        []ChunkRemap `json:"results"`
+	Success        bool         `json:"success"`
+	Message        string       `json:"message,omitempty"`
+}
+
+// DuplicatesRequest asks for the near-identical functions of one or more
+// repositories, compared by the embeddings of their chunks. Threshold is the
+// cosine similarity from which two functions count as duplicates.
+type DuplicatesRequest struct {
+	RepoNames []string `json:"repo_names" binding:"required,min=1"`
+	Threshold float64  `json:"threshold"`
+	MinLines  int      `json:"min_lines"`
+	Limit     int      `json:"limit"`
+}
+
+// DuplicateLocation is a function chunk reported as duplicated
+type DuplicateLocation struct {
+	RepoName  string `json:"repo_name"`
+	ChunkID   string `json:"chunk_id"`
+	Name      string `json:"name,omitempty"`
+	ClassName string `json:"class_name,omitempty"`
+	FilePath  string `json:"file_path"`
+	FileID    int32  `json:"file_id,omitempty"`
+	StartLine int    `json:"start_line"`
+	EndLine   int    `json:"end_line"`
+}
+
+// DuplicatePair is two members of a cluster, by index, and their similarity
+type DuplicatePair struct {
+	A          int     `json:"a"`
+	B          int     `json:"b"`
+	Similarity float64 `json:"similarity"`
+}
+
+// DuplicateCluster is a group of functions linked by duplicate pairs;
+// Similarity is that of its closest pair
+type DuplicateCluster struct {
+	Similarity float64             `json:"similarity"`
+	Members    []DuplicateLocation `json:"members"`
+	Pairs      []DuplicatePair     `json:"pairs"`
+}
+
+// DuplicatesResponse lists the duplicate clusters, largest first
+type DuplicatesResponse struct {
+	RepoNames         []string           `json:"repo_names"`
+	FunctionsCompared int                `json:"functions_compared"`
+	Clusters          []DuplicateCluster `json:"clusters"`
+	Success           bool               `json:"success"`
+	Message           string             `json:"message,omitempty"`
+}
+
+// SimilarEntitiesRequest names a class, by ClassName within FilePath, or a
+// whole file when ClassName is empty, and the repositories to look for
+// similar ones in. RepoNames defaults to RepoName alone.
+type SimilarEntitiesRequest struct {
+	RepoName      string   `json:"repo_name" binding:"required"`
+	FilePath      string   `json:"file_path" binding:"required"`
+	ClassName     string   `json:"class_name"`
+	RepoNames     []string `json:"repo_names"`
+	Limit         int      `json:"limit"`
+	MinSimilarity float64  `json:"min_similarity"`
+}
+
+// SimilarEntity is a class or file similar to the requested one
+type SimilarEntity struct {
+	RepoName   string  `json:"repo_name"`
+	FilePath   string  `json:"file_path"`
+	FileID     int32   `json:"file_id,omitempty"`
+	ClassName  string  `json:"class_name,omitempty"`
+	StartLine  int     `json:"start_line"`
+	EndLine    int     `json:"end_line"`
+	Chunks     int     `json:"chunks"` // chunks whose embeddings were averaged
+	Similarity float64 `json:"similarity"`
+}
+
+// SimilarEntitiesResponse lists the most similar classes or files, most
+// similar first
+type SimilarEntitiesResponse struct {
+	RepoName  string          `json:"repo_name"`
+	FilePath  string          `json:"file_path"`
+	ClassName string          `json:"class_name,omitempty"`
+	Kind      string          `json:"kind"` // class or file
+	Compared  int             `json:"compared"`
+	Results   []SimilarEntity `json:"results"`
+	Success   bool            `json:"success"`
+	Message   string          `json:"message,omitempty"`
 }
 
 func (fd *FunctionDependency) IsIn(rng *base.Range) bool {
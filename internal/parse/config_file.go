@@ -0,0 +1,331 @@
+package parse
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/armchr/codeapi/internal/config"
+	"github.com/armchr/codeapi/internal/model/ast"
+	"github.com/armchr/codeapi/internal/util"
+	"github.com/armchr/codeapi/pkg/lsp/base"
+
+	"gopkg.in/yaml.v2"
+)
+
+// ConfigLanguage is the language of the file scope of a configuration file
+const ConfigLanguage = "config"
+
+// MetaConfigLookup is the normalized key that configuration reads are
+// matched with definitions on, see ConfigLookupKey
+const MetaConfigLookup = "lookup"
+
+// ConfigEntry is a key defined by a configuration file, with its value and
+// the 0-based line it is defined on
+type ConfigEntry struct {
+	Key   string
+	Value string
+	Line  int
+}
+
+// Formats of configuration files
+const (
+	configFormatProperties = "properties"
+	configFormatYAML       = "yaml"
+	configFormatEnv        = "env"
+)
+
+// configFormat returns the format of an application configuration file:
+// Spring Boot application and bootstrap files, with or without a profile,
+// and dotenv files. It returns "" for any other file.
+func configFormat(filePath string) string {
+	name := strings.ToLower(filepath.Base(filePath))
+	if name == ".env" || strings.HasPrefix(name, ".env.") {
+		return configFormatEnv
+	}
+
+	ext := filepath.Ext(name)
+	stem := strings.TrimSuffix(name, ext)
+	isApplication := false
+	for _, prefix := range []string{"application", "bootstrap"} {
+		if stem == prefix || strings.HasPrefix(stem, prefix+"-") {
+			isApplication = true
+		}
+	}
+	if !isApplication {
+		return ""
+	}
+
+	switch ext {
+	case ".properties":
+		return configFormatProperties
+	case ".yml", ".yaml":
+		return configFormatYAML
+	}
+	return ""
+}
+
+// IsConfigFile reports whether filePath is a configuration file whose keys
+// are indexed as Config nodes
+func IsConfigFile(filePath string) bool {
+	return configFormat(filePath) != ""
+}
+
+// ParseConfigFile returns the keys a configuration file defines, in file
+// order. YAML keys are flattened to the dotted form code reads them by, with
+// [i] for the items of a list.
+func ParseConfigFile(filePath string, content []byte) ([]ConfigEntry, error) {
+	switch configFormat(filePath) {
+	case configFormatProperties:
+		return parseProperties(content), nil
+	case configFormatEnv:
+		return parseEnv(content), nil
+	case configFormatYAML:
+		return parseYAMLConfig(content)
+	}
+	return nil, fmt.Errorf("not a configuration file: %s", filePath)
+}
+
+// ConfigLookupKey normalizes a configuration key the way Spring's relaxed
+// binding compares them, so that a read of SPRING_DATASOURCE_URL or
+// spring.dataSource.url matches spring.datasource.url: case is ignored,
+// dashes are dropped and underscores and colons separate like dots.
+func ConfigLookupKey(key string) string {
+	key = strings.ToLower(strings.TrimSpace(key))
+	key = strings.ReplaceAll(key, "-", "")
+	return strings.NewReplacer("_", ".", ":", ".").Replace(key)
+}
+
+// parseProperties parses a Java .properties file: key=value, key: value or
+// key value, with # and ! comments and lines continued by a trailing \
+func parseProperties(content []byte) []ConfigEntry {
+	lines := strings.Split(string(content), "\n")
+	var entries []ConfigEntry
+	for i := 0; i < len(lines); i++ {
+		start := i
+		line := strings.TrimLeft(strings.TrimRight(lines[i], "\r"), " \t\f")
+		if line == "" || line[0] == '#' || line[0] == '!' {
+			continue
+		}
+		for continued(line) && i+1 < len(lines) {
+			i++
+			line = line[:len(line)-1] + strings.TrimLeft(strings.TrimRight(lines[i], "\r"), " \t\f")
+		}
+
+		end := len(line)
+		for j := 0; j < len(line); j++ {
+			if line[j] == '\\' {
+				j++
+				continue
+			}
+			if line[j] == '=' || line[j] == ':' || line[j] == ' ' || line[j] == '\t' {
+				end = j
+				break
+			}
+		}
+		value := strings.TrimLeft(line[end:], " \t\f")
+		if value != "" && (value[0] == '=' || value[0] == ':') {
+			value = strings.TrimLeft(value[1:], " \t\f")
+		}
+		key := strings.ReplaceAll(line[:end], `\`, "")
+		if key != "" {
+			entries = append(entries, ConfigEntry{Key: key, Value: value, Line: start})
+		}
+	}
+	return entries
+}
+
+// continued reports whether a properties line ends with an unescaped \
+func continued(line string) bool {
+	n := 0
+	for i := len(line) - 1; i >= 0 && line[i] == '\\'; i-- {
+		n++
+	}
+	return n%2 == 1
+}
+
+// parseEnv parses a dotenv file: KEY=value lines, optionally exported, with
+// quoted values and # comments
+func parseEnv(content []byte) []ConfigEntry {
+	var entries []ConfigEntry
+	for i, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+		key, value, ok := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" || strings.ContainsAny(key, " \t") {
+			continue
+		}
+
+		value = strings.TrimSpace(value)
+		if value != "" && (value[0] == '"' || value[0] == '\'') {
+			if end := strings.IndexByte(value[1:], value[0]); end >= 0 {
+				value = value[1 : end+1]
+			}
+		} else if comment := strings.Index(value, " #"); comment >= 0 {
+			value = strings.TrimSpace(value[:comment])
+		}
+		entries = append(entries, ConfigEntry{Key: key, Value: value, Line: i})
+	}
+	return entries
+}
+
+// parseYAMLConfig flattens every document of a YAML file, so the keys of
+// each Spring profile document are all indexed
+func parseYAMLConfig(content []byte) ([]ConfigEntry, error) {
+	lines := yamlKeyLines(content)
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	var entries []ConfigEntry
+	for doc := 0; ; doc++ {
+		var values yaml.MapSlice
+		err := decoder.Decode(&values)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse YAML: %w", err)
+		}
+		var docLines map[string]int
+		if doc < len(lines) {
+			docLines = lines[doc]
+		}
+		flattenYAML("", values, docLines, &entries)
+	}
+	return entries, nil
+}
+
+// flattenYAML appends an entry for every scalar under prefix
+func flattenYAML(prefix string, value any, lines map[string]int, entries *[]ConfigEntry) {
+	switch v := value.(type) {
+	case yaml.MapSlice:
+		for _, item := range v {
+			key := fmt.Sprint(item.Key)
+			if prefix != "" {
+				key = prefix + "." + key
+			}
+			flattenYAML(key, item.Value, lines, entries)
+		}
+	case []any:
+		for i, item := range v {
+			flattenYAML(fmt.Sprintf("%s[%d]", prefix, i), item, lines, entries)
+		}
+	default:
+		if prefix == "" {
+			return
+		}
+		scalar := ""
+		if v != nil {
+			scalar = fmt.Sprint(v)
+		}
+		*entries = append(*entries, ConfigEntry{Key: prefix, Value: scalar, Line: yamlLine(prefix, lines)})
+	}
+}
+
+// yamlLine returns the line of a flattened key, or of the closest enclosing
+// key when the key is a list item
+func yamlLine(key string, lines map[string]int) int {
+	for key != "" {
+		if line, ok := lines[key]; ok {
+			return line
+		}
+		cut := strings.LastIndexAny(key, ".[")
+		if cut < 0 {
+			break
+		}
+		key = key[:cut]
+	}
+	return 0
+}
+
+// yamlKeyLines finds the line of each block mapping key of every document
+// of a YAML file, by dotted path. The decoder does not report positions, so
+// the keys are tracked by their indentation.
+func yamlKeyLines(content []byte) []map[string]int {
+	type level struct {
+		indent int
+		path   string
+	}
+	docs := []map[string]int{{}}
+	var stack []level
+	for i, line := range strings.Split(string(content), "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "---") {
+			if len(docs[len(docs)-1]) > 0 {
+				docs = append(docs, map[string]int{})
+			}
+			stack = stack[:0]
+			continue
+		}
+		if trimmed == "" || trimmed[0] == '#' || trimmed[0] == '-' {
+			continue
+		}
+		colon := strings.Index(trimmed, ":")
+		if colon <= 0 || (colon+1 < len(trimmed) && trimmed[colon+1] != ' ') {
+			continue
+		}
+
+		indent := len(line) - len(strings.TrimLeft(line, " "))
+		for len(stack) > 0 && stack[len(stack)-1].indent >= indent {
+			stack = stack[:len(stack)-1]
+		}
+		path := strings.Trim(trimmed[:colon], `"'`)
+		if len(stack) > 0 {
+			path = stack[len(stack)-1].path + "." + path
+		}
+		stack = append(stack, level{indent: indent, path: path})
+		if _, ok := docs[len(docs)-1][path]; !ok {
+			docs[len(docs)-1][path] = i
+		}
+	}
+	return docs
+}
+
+// parseConfigFile indexes the keys of a configuration file as Config nodes
+// contained in its file scope; there is no syntax tree to traverse
+func (fp *FileParser) parseConfigFile(ctx context.Context, repo *config.Repository, info os.FileInfo, filePath string, fileID int32, version int32, content []byte) error {
+	entries, err := ParseConfigFile(filePath, content)
+	if err != nil {
+		return fmt.Errorf("failed to parse config file %s: %w", filePath, err)
+	}
+
+	translator := NewTranslateFromSyntaxTree(fileID, version, fp.CodeGraph, content, fp.logger)
+	translator.SetStableNodeIDs(repo.Name, filepath.ToSlash(fp.relativePath(repo, filePath)), util.CalculateFileSHA256(content))
+
+	lines := strings.Split(string(content), "\n")
+	fileScope := ast.NewNode(
+		ast.NodeID(fileID), ast.NodeTypeFileScope, fileID,
+		filepath.Base(filePath),
+		base.Range{End: base.Position{Line: len(lines) - 1, Character: len(lines[len(lines)-1])}},
+		version, ast.InvalidNodeID,
+	)
+	fileScope.MetaData = map[string]any{
+		"repo":     repo.Name,
+		"path":     fp.relativePath(repo, filePath),
+		"modified": info.ModTime().Unix(),
+		"language": ConfigLanguage,
+	}
+	fp.CodeGraph.CreateFileScope(ctx, fileScope)
+
+	for _, entry := range entries {
+		rng := base.Range{
+			Start: base.Position{Line: entry.Line},
+			End:   base.Position{Line: entry.Line, Character: len(strings.TrimRight(lines[entry.Line], "\r"))},
+		}
+		node := translator.AllocNode(ast.NodeTypeConfig, entry.Key, rng, fileScope.ID)
+		node.MetaData = map[string]any{
+			MetaValue:        entry.Value,
+			MetaConfigLookup: ConfigLookupKey(entry.Key),
+		}
+		fp.CodeGraph.CreateConfig(ctx, node)
+		fp.CodeGraph.CreateContainsRelation(ctx, fileScope.ID, node.ID, fileID)
+	}
+	return nil
+}
@@ -0,0 +1,171 @@
+package parse
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseConfigFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		path    string
+		content string
+		want    []ConfigEntry
+	}{
+		{
+			name: "properties",
+			path: "src/main/resources/application.properties",
+			content: "# database\n" +
+				"spring.datasource.url=jdbc:h2:mem:test\n" +
+				"server.port: 8080\n" +
+				"! legacy comment\n" +
+				"app.greeting Hello \\\n" +
+				"    world\n" +
+				"app.escaped\\=key=1\n",
+			want: []ConfigEntry{
+				{Key: "spring.datasource.url", Value: "jdbc:h2:mem:test", Line: 1},
+				{Key: "server.port", Value: "8080", Line: 2},
+				{Key: "app.greeting", Value: "Hello world", Line: 4},
+				{Key: "app.escaped=key", Value: "1", Line: 6},
+			},
+		},
+		{
+			name: "yaml with profiles and lists",
+			path: "config/application-dev.yml",
+			content: "server:\n" +
+				"  port: 8080\n" +
+				"app:\n" +
+				"  hosts:\n" +
+				"    - a.example.com\n" +
+				"    - b.example.com\n" +
+				"  empty:\n" +
+				"---\n" +
+				"server:\n" +
+				"  port: 9090\n",
+			want: []ConfigEntry{
+				{Key: "server.port", Value: "8080", Line: 1},
+				{Key: "app.hosts[0]", Value: "a.example.com", Line: 3},
+				{Key: "app.hosts[1]", Value: "b.example.com", Line: 3},
+				{Key: "app.empty", Value: "", Line: 6},
+				{Key: "server.port", Value: "9090", Line: 9},
+			},
+		},
+		{
+			name: "dotenv",
+			path: ".env.local",
+			content: "# local settings\n" +
+				"DB_HOST=localhost # dev database\n" +
+				"export API_KEY=\"abc # not a comment\"\n" +
+				"EMPTY=\n" +
+				"not a line\n",
+			want: []ConfigEntry{
+				{Key: "DB_HOST", Value: "localhost", Line: 1},
+				{Key: "API_KEY", Value: "abc # not a comment", Line: 2},
+				{Key: "EMPTY", Value: "", Line: 3},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseConfigFile(tt.path, []byte(tt.content))
+			if err != nil {
+				t.Fatalf("ParseConfigFile() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseConfigFile() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsConfigFile(t *testing.T) {
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"src/main/resources/application.yml", true},
+		{"src/main/resources/application-prod.yaml", true},
+		{"bootstrap.properties", true},
+		{".env", true},
+		{"deploy/.env.production", true},
+		{"pom.xml", false},
+		{"docker-compose.yml", false},
+		{"applications.yml", false},
+		{"messages.properties", false},
+		{"application.json", false},
+	}
+
+	for _, tt := range tests {
+		if got := IsConfigFile(tt.path); got != tt.want {
+			t.Errorf("IsConfigFile(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestConfigLookupKey(t *testing.T) {
+	tests := []struct {
+		key  string
+		want string
+	}{
+		{"spring.datasource.url", "spring.datasource.url"},
+		{"SPRING_DATASOURCE_URL", "spring.datasource.url"},
+		{"spring.dataSource.url", "spring.datasource.url"},
+		{"my-service.base-url", "myservice.baseurl"},
+		{"ConnectionStrings:Default", "connectionstrings.default"},
+	}
+
+	for _, tt := range tests {
+		if got := ConfigLookupKey(tt.key); got != tt.want {
+			t.Errorf("ConfigLookupKey(%q) = %q, want %q", tt.key, got, tt.want)
+		}
+	}
+}
+
+func TestConfigCallSource(t *testing.T) {
+	tests := []struct {
+		callee     string
+		wantSource string
+		wantPrefix string
+	}{
+		{"os.Getenv", ConfigSourceEnv, ""},
+		{"System.getenv", ConfigSourceEnv, ""},
+		{"os.environ.get", ConfigSourceEnv, ""},
+		{"Environment.GetEnvironmentVariable", ConfigSourceEnv, ""},
+		{"env.getProperty", ConfigSourceProperty, ""},
+		{"viper.GetString", ConfigSourceProperty, ""},
+		{"s.cfg.GetDuration", ConfigSourceProperty, ""},
+		{"_configuration.GetValue", ConfigSourceProperty, ""},
+		{"Configuration.GetConnectionString", ConfigSourceProperty, "ConnectionStrings:"},
+		{"cache.Get", "", ""},
+		{"requests.get", "", ""},
+		{"Get", "", ""},
+	}
+
+	for _, tt := range tests {
+		source, prefix := configCallSource(tt.callee)
+		if source != tt.wantSource || prefix != tt.wantPrefix {
+			t.Errorf("configCallSource(%q) = %q, %q, want %q, %q", tt.callee, source, prefix, tt.wantSource, tt.wantPrefix)
+		}
+	}
+}
+
+func TestPlaceholderKeys(t *testing.T) {
+	tests := []struct {
+		expr string
+		want []string
+	}{
+		{"${server.port}", []string{"server.port"}},
+		{"${app.timeout:30}", []string{"app.timeout"}},
+		{"${primary.url:${fallback.url}}", []string{"primary.url", "fallback.url"}},
+		{"http://${host}:${port}/api", []string{"host", "port"}},
+		{"#{systemProperties['user.home']}", nil},
+		{"plain", nil},
+	}
+
+	for _, tt := range tests {
+		if got := PlaceholderKeys(tt.expr); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("PlaceholderKeys(%q) = %v, want %v", tt.expr, got, tt.want)
+		}
+	}
+}
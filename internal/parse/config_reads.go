@@ -0,0 +1,166 @@
+package parse
+
+import (
+	"context"
+	"strings"
+
+	"github.com/armchr/codeapi/internal/model/ast"
+	"github.com/armchr/codeapi/pkg/lsp/base"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+// Metadata of the __config__ variables recording a configuration read
+const (
+	MetaConfigKey    = "config_key"    // key as the code names it
+	MetaConfigSource = "config_source" // ConfigSourceEnv or ConfigSourceProperty
+	MetaConfigOwner  = "config_owner"  // function, or class or file for reads outside functions
+)
+
+const (
+	ConfigSourceEnv      = "env"      // os.Getenv, System.getenv, process.env, os.environ
+	ConfigSourceProperty = "property" // @Value, Environment.getProperty, viper, IConfiguration
+)
+
+// envCallNames are the functions reading an environment variable
+var envCallNames = map[string]bool{
+	"Getenv": true, "LookupEnv": true, "getenv": true, "GetEnvironmentVariable": true,
+}
+
+// propertyCallNames read a property from any receiver, like
+// Environment.getProperty or java.util.Properties
+var propertyCallNames = map[string]bool{
+	"getProperty": true, "getRequiredProperty": true, "GetConnectionString": true,
+}
+
+// configGetterNames read a property when called on a configuration object:
+// viper in Go, IConfiguration in C#, node-config and Python settings
+var configGetterNames = map[string]bool{
+	"Get": true, "GetString": true, "GetInt": true, "GetInt32": true, "GetInt64": true,
+	"GetUint": true, "GetFloat64": true, "GetBool": true, "GetDuration": true, "GetTime": true,
+	"GetStringSlice": true, "GetIntSlice": true, "GetStringMap": true, "GetStringMapString": true,
+	"IsSet": true, "GetValue": true, "GetSection": true, "GetRequiredSection": true,
+	"get": true, "has": true, "getString": true, "getInt": true, "getBoolean": true,
+}
+
+// configReceivers are the words naming a configuration object
+var configReceivers = []string{"viper", "config", "cfg", "settings", "environment", "env"}
+
+// HandleConfigRead records that code reads a configuration key, as a
+// __config__ variable the value flows out of; callers add the data flow. The innermost function being
+// traversed, or scopeID outside functions, is stored as the reader, which
+// post-processing links to the Config nodes defining the key.
+func (t *TranslateFromSyntaxTree) HandleConfigRead(ctx context.Context, key, source string, rng base.Range, scopeID ast.NodeID) ast.NodeID {
+	owner := scopeID
+	if len(t.functions) > 0 {
+		owner = t.functions[len(t.functions)-1]
+	}
+	configID := t.CreateFakeVariable(ctx, scopeID, "__config__", rng, map[string]any{
+		MetaConfigKey:    key,
+		MetaConfigSource: source,
+		MetaConfigLookup: ConfigLookupKey(key),
+		MetaConfigOwner:  int64(owner),
+	})
+	return configID
+}
+
+// handleConfigCall records the configuration read of a call like
+// os.Getenv("PORT") or env.getProperty("server.port"), whose first argument
+// is the key, with the value flowing into the call
+func (t *TranslateFromSyntaxTree) handleConfigCall(ctx context.Context, callNode *ast.Node, args []*tree_sitter.Node, scopeID ast.NodeID) {
+	if len(args) == 0 {
+		return
+	}
+	source, prefix := configCallSource(t.calleeText(args[0]))
+	if source == "" {
+		return
+	}
+	key, ok := t.StringValue(args[0])
+	if !ok || key == "" {
+		return
+	}
+	configID := t.HandleConfigRead(ctx, prefix+key, source, t.ToRange(args[0]), scopeID)
+	t.CodeGraph.CreateDataFlowRelation(ctx, configID, callNode.ID, t.FileID)
+}
+
+// configCallSource tells whether a call, given by the source text of its
+// callee, reads configuration, and the prefix its key argument is under
+func configCallSource(callee string) (source, prefix string) {
+	receiver, name := splitCallee(callee)
+	receiver = strings.ToLower(receiver[strings.LastIndex(receiver, ".")+1:])
+
+	switch {
+	case envCallNames[name]:
+		return ConfigSourceEnv, ""
+	case name == "get" && receiver == "environ":
+		return ConfigSourceEnv, ""
+	case name == "GetConnectionString":
+		return ConfigSourceProperty, "ConnectionStrings:"
+	case propertyCallNames[name]:
+		return ConfigSourceProperty, ""
+	case configGetterNames[name] && isConfigReceiver(receiver):
+		return ConfigSourceProperty, ""
+	}
+	return "", ""
+}
+
+// isConfigReceiver reports whether the name of a receiver, lowercased,
+// names a configuration object
+func isConfigReceiver(receiver string) bool {
+	receiver = strings.TrimLeft(receiver, "_")
+	for _, word := range configReceivers {
+		if strings.Contains(receiver, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// HandleEnvAccess records a read of an environment variable written as an
+// index or member of the environment, like process.env.PORT,
+// process.env["PORT"] or os.environ["PORT"]. It returns InvalidNodeID when
+// object is not the environment or the key is not a literal.
+func (t *TranslateFromSyntaxTree) HandleEnvAccess(ctx context.Context, object, key *tree_sitter.Node, scopeID ast.NodeID) ast.NodeID {
+	if object == nil || key == nil {
+		return ast.InvalidNodeID
+	}
+	switch t.String(object) {
+	case "process.env", "import.meta.env", "os.environ", "environ":
+	default:
+		return ast.InvalidNodeID
+	}
+
+	name, ok := t.StringValue(key)
+	if !ok && key.Kind() == "property_identifier" {
+		name, ok = t.String(key), true
+	}
+	if !ok || name == "" {
+		return ast.InvalidNodeID
+	}
+	configID := t.HandleConfigRead(ctx, name, ConfigSourceEnv, t.ToRange(key), scopeID)
+	if t.CurrentScope.IsRhs() {
+		t.CurrentScope.AddRhsVar(configID)
+	}
+	return configID
+}
+
+// PlaceholderKeys returns the keys of the ${key} and ${key:default}
+// placeholders of a Spring property expression, including placeholders
+// nested in a default
+func PlaceholderKeys(expr string) []string {
+	var keys []string
+	for {
+		start := strings.Index(expr, "${")
+		if start < 0 {
+			return keys
+		}
+		expr = expr[start+2:]
+		end := strings.IndexAny(expr, ":}")
+		if end < 0 {
+			return keys
+		}
+		if key := strings.TrimSpace(expr[:end]); key != "" {
+			keys = append(keys, key)
+		}
+	}
+}
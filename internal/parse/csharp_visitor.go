@@ -70,6 +70,10 @@ func (cv *CSharpVisitor) TraverseNode(ctx context.Context, tsNode *tree_sitter.N
 		return cv.handleDoStatement(ctx, tsNode, scopeID)
 	case "return_statement":
 		return cv.handleReturnStatement(ctx, tsNode, scopeID)
+	case "try_statement":
+		return cv.handleTryStatement(ctx, tsNode, scopeID)
+	case "throw_statement", "throw_expression":
+		return cv.handleThrow(ctx, tsNode, scopeID)
 	case "local_declaration_statement":
 		return cv.handleLocalDeclarationStatement(ctx, tsNode, scopeID)
 	case "expression_statement":
@@ -116,7 +120,7 @@ func (cv *CSharpVisitor) handleCompilationUnit(ctx context.Context, tsNode *tree
 
 	// Create module scope node
 	moduleNode := ast.NewNode(
-		cv.translate.NextNodeID(), ast.NodeTypeModuleScope, cv.translate.FileID,
+		cv.translate.NodeIDFor(ast.NodeTypeModuleScope, cv.translate.ToRange(tsNode)), ast.NodeTypeModuleScope, cv.translate.FileID,
 		namespaceName, cv.translate.ToRange(tsNode), cv.translate.Version,
 		ast.NodeID(cv.translate.FileID),
 	)
@@ -178,15 +182,7 @@ func (cv *CSharpVisitor) handleUsingDirective(ctx context.Context, tsNode *tree_
 	}
 
 	// Create Import node
-	importNode := ast.NewNode(
-		cv.translate.NextNodeID(),
-		ast.NodeTypeImport,
-		cv.translate.FileID,
-		symbolName,
-		cv.translate.ToRange(tsNode),
-		cv.translate.Version,
-		scopeID,
-	)
+	importNode := cv.translate.AllocNode(ast.NodeTypeImport, symbolName, cv.translate.ToRange(tsNode), scopeID)
 
 	importNode.MetaData = map[string]any{
 		"importPath": importPath,
@@ -194,7 +190,6 @@ func (cv *CSharpVisitor) handleUsingDirective(ctx context.Context, tsNode *tree_
 
 	cv.translate.CodeGraph.CreateImport(ctx, importNode)
 	cv.translate.CurrentScope.AddSymbol(NewSymbol(importNode))
-	cv.translate.Nodes[importNode.ID] = importNode
 
 	return importNode.ID
 }
@@ -260,15 +255,29 @@ func (cv *CSharpVisitor) handleMethodDeclaration(ctx context.Context, tsNode *tr
 	paramListNode := cv.translate.TreeChildByKind(tsNode, "parameter_list")
 	bodyNode := cv.translate.TreeChildByKind(tsNode, "block")
 
-	var params []*tree_sitter.Node
-	if paramListNode != nil {
-		params = cv.translate.TreeChildrenByKind(paramListNode, "parameter")
-	}
+	params := cv.parameters(paramListNode)
 
 	// For interface methods without body, bodyNode will be nil
 	return cv.translate.CreateFunction(ctx, scopeID, tsNode, methodName, params, bodyNode)
 }
 
+// parameters returns the parameters of a parameter_list. The grammar puts
+// the type and name of a trailing params array directly in the list, so its
+// name identifier stands for the parameter.
+func (cv *CSharpVisitor) parameters(paramListNode *tree_sitter.Node) []*tree_sitter.Node {
+	if paramListNode == nil {
+		return nil
+	}
+	var params []*tree_sitter.Node
+	for i := uint(0); i < paramListNode.ChildCount(); i++ {
+		child := paramListNode.Child(i)
+		if child.Kind() == "parameter" || paramListNode.FieldNameForChild(uint32(i)) == "name" {
+			params = append(params, child)
+		}
+	}
+	return params
+}
+
 // handleConstructorDeclaration handles constructor declarations
 func (cv *CSharpVisitor) handleConstructorDeclaration(ctx context.Context, tsNode *tree_sitter.Node, scopeID ast.NodeID) ast.NodeID {
 	nameNode := cv.translate.TreeChildByKind(tsNode, "identifier")
@@ -280,10 +289,7 @@ func (cv *CSharpVisitor) handleConstructorDeclaration(ctx context.Context, tsNod
 	paramListNode := cv.translate.TreeChildByKind(tsNode, "parameter_list")
 	bodyNode := cv.translate.TreeChildByKind(tsNode, "block")
 
-	var params []*tree_sitter.Node
-	if paramListNode != nil {
-		params = cv.translate.TreeChildrenByKind(paramListNode, "parameter")
-	}
+	params := cv.parameters(paramListNode)
 
 	return cv.translate.CreateFunction(ctx, scopeID, tsNode, ctorName, params, bodyNode)
 }
@@ -357,6 +363,46 @@ func (cv *CSharpVisitor) getIfConditionsAndBranches(tsNode *tree_sitter.Node) ([
 	return conditions, branches
 }
 
+// handleTryStatement handles try statements with their catch and finally clauses
+func (cv *CSharpVisitor) handleTryStatement(ctx context.Context, tsNode *tree_sitter.Node, scopeID ast.NodeID) ast.NodeID {
+	var handlers []CatchHandler
+	for _, clause := range cv.translate.TreeChildrenByKind(tsNode, "catch_clause") {
+		handler := CatchHandler{Body: cv.translate.TreeChildByFieldName(clause, "body")}
+		// catch without a declaration catches everything
+		if declaration := cv.translate.TreeChildByKind(clause, "catch_declaration"); declaration != nil {
+			if typeNode := cv.translate.TreeChildByFieldName(declaration, "type"); typeNode != nil {
+				handler.Types = []string{cv.translate.String(typeNode)}
+			}
+		}
+		handlers = append(handlers, handler)
+	}
+
+	var finallyBody *tree_sitter.Node
+	if finallyClause := cv.translate.TreeChildByKind(tsNode, "finally_clause"); finallyClause != nil {
+		finallyBody = cv.translate.TreeChildByKind(finallyClause, "block")
+	}
+
+	bodyNode := cv.translate.TreeChildByFieldName(tsNode, "body")
+	return cv.translate.HandleTryCatch(ctx, tsNode, bodyNode, handlers, finallyBody, scopeID)
+}
+
+// handleThrow handles throw statements and throw expressions; a throw without
+// an operand rethrows the caught exception and records nothing
+func (cv *CSharpVisitor) handleThrow(ctx context.Context, tsNode *tree_sitter.Node, scopeID ast.NodeID) ast.NodeID {
+	thrown := tsNode.NamedChild(0)
+	if thrown == nil {
+		return ast.InvalidNodeID
+	}
+
+	thrownType := ""
+	if thrown.Kind() == "object_creation_expression" {
+		if typeNode := cv.translate.TreeChildByFieldName(thrown, "type"); typeNode != nil {
+			thrownType = cv.translate.String(typeNode)
+		}
+	}
+	return cv.translate.HandleThrow(ctx, thrown, thrownType, scopeID)
+}
+
 // handleSwitchStatement handles switch statements
 func (cv *CSharpVisitor) handleSwitchStatement(ctx context.Context, tsNode *tree_sitter.Node, scopeID ast.NodeID) ast.NodeID {
 	var conditions []*tree_sitter.Node
@@ -821,7 +867,7 @@ func (cv *CSharpVisitor) HasSpecialName(kind string) bool {
 	// C# has special naming for method declarations where the first identifier
 	// may be the return type, not the method name
 	switch kind {
-	case "method_declaration", "constructor_declaration":
+	case "method_declaration", "constructor_declaration", "parameter":
 		return true
 	default:
 		return false
@@ -852,6 +898,12 @@ func (cv *CSharpVisitor) GetName(tsNode *tree_sitter.Node) string {
 		if nameNode != nil {
 			return cv.translate.GetTreeNodeName(nameNode)
 		}
+	case "parameter":
+		// The type of a parameter may be an identifier too: Order order
+		nameNode := cv.translate.TreeChildByFieldName(tsNode, "name")
+		if nameNode != nil {
+			return cv.translate.GetTreeNodeName(nameNode)
+		}
 	}
 
 	return ""
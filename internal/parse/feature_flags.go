@@ -0,0 +1,213 @@
+package parse
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/armchr/codeapi/internal/config"
+	"github.com/armchr/codeapi/internal/model/ast"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+// MetaProvider is the SDK or helper a feature flag is checked with
+const MetaProvider = "provider"
+
+// FlagCall is a call examined by the FlagDetectors
+type FlagCall struct {
+	Callee   string // source text of what is called, like ldClient.boolVariation
+	Receiver string // callee without the name; empty for a plain function
+	Name     string // function or method name
+	args     []*tree_sitter.Node
+	t        *TranslateFromSyntaxTree
+}
+
+// StringArg returns the value of argument i when it is a string literal
+func (c FlagCall) StringArg(i int) (string, bool) {
+	if i < 0 || i >= len(c.args) {
+		return "", false
+	}
+	return c.t.StringValue(c.args[i])
+}
+
+// FlagDetector recognises the calls checking a feature flag with one
+// provider's SDK or helper
+type FlagDetector interface {
+	// Provider names the SDK or helper, recorded on the flags found
+	Provider() string
+	// Detect returns the key of the flag a call checks
+	Detect(call FlagCall) (string, bool)
+}
+
+// NewFlagDetectors returns the detectors of the configured providers and
+// homegrown helpers
+func NewFlagDetectors(cfg config.FeatureFlagsConfig) ([]FlagDetector, error) {
+	cfg = cfg.GetDefaults()
+	var detectors []FlagDetector
+	for _, provider := range cfg.Providers {
+		switch provider {
+		case config.FlagProviderLaunchDarkly:
+			detectors = append(detectors, launchDarklyDetector{})
+		case config.FlagProviderUnleash:
+			detectors = append(detectors, unleashDetector{})
+		default:
+			return nil, fmt.Errorf("unknown feature flag provider %q", provider)
+		}
+	}
+	for _, helper := range cfg.Helpers {
+		detector := &helperDetector{name: helper.Name, keyArg: helper.KeyArg, methods: make(map[string]bool)}
+		for _, method := range helper.Methods {
+			detector.methods[method] = true
+		}
+		if helper.Pattern != "" {
+			pattern, err := regexp.Compile(helper.Pattern)
+			if err != nil {
+				return nil, fmt.Errorf("invalid pattern of flag helper %s: %w", helper.Name, err)
+			}
+			detector.pattern = pattern
+		}
+		detectors = append(detectors, detector)
+	}
+	return detectors, nil
+}
+
+// launchDarklyVariations are the typed evaluation methods of the LaunchDarkly
+// server SDKs, whose first argument is the flag key
+var launchDarklyVariations = map[string]bool{
+	// Java, JavaScript
+	"boolVariation": true, "stringVariation": true, "intVariation": true, "doubleVariation": true,
+	"jsonValueVariation": true, "boolVariationDetail": true, "stringVariationDetail": true,
+	"intVariationDetail": true, "doubleVariationDetail": true, "jsonValueVariationDetail": true,
+	// Go, C#
+	"BoolVariation": true, "StringVariation": true, "IntVariation": true, "Float64Variation": true,
+	"JSONVariation": true, "BoolVariationDetail": true, "StringVariationDetail": true,
+	"IntVariationDetail": true, "Float64VariationDetail": true, "JSONVariationDetail": true,
+	"FloatVariation": true, "JsonVariation": true,
+}
+
+type launchDarklyDetector struct{}
+
+func (launchDarklyDetector) Provider() string { return config.FlagProviderLaunchDarkly }
+
+// Detect also accepts the untyped variation calls of the Python and
+// JavaScript SDKs, when made on something named like the LaunchDarkly client
+func (launchDarklyDetector) Detect(call FlagCall) (string, bool) {
+	switch call.Name {
+	case "variation", "variationDetail", "variation_detail":
+		if !isLaunchDarklyClient(strings.ToLower(call.Receiver)) {
+			return "", false
+		}
+	default:
+		if !launchDarklyVariations[call.Name] {
+			return "", false
+		}
+	}
+	return call.StringArg(0)
+}
+
+// isLaunchDarklyClient reports whether a lowercased receiver looks like the
+// LaunchDarkly client: ld, ldClient, ldclient.get(), self.client
+func isLaunchDarklyClient(receiver string) bool {
+	for _, word := range []string{"ldclient", "ld_client", "launchdarkly"} {
+		if strings.Contains(receiver, word) {
+			return true
+		}
+	}
+	return receiver == "ld" || strings.HasSuffix(receiver, "client")
+}
+
+type unleashDetector struct{}
+
+func (unleashDetector) Provider() string { return config.FlagProviderUnleash }
+
+// Detect accepts isEnabled and getVariant in the casing of each SDK, called
+// on the Unleash client or package
+func (unleashDetector) Detect(call FlagCall) (string, bool) {
+	switch call.Name {
+	case "isEnabled", "IsEnabled", "is_enabled", "getVariant", "GetVariant", "get_variant":
+	default:
+		return "", false
+	}
+	if !strings.Contains(strings.ToLower(call.Receiver), "unleash") {
+		return "", false
+	}
+	return call.StringArg(0)
+}
+
+// helperDetector recognises a homegrown helper configured by its method
+// names or a pattern of the callee
+type helperDetector struct {
+	name    string
+	methods map[string]bool
+	pattern *regexp.Regexp
+	keyArg  int
+}
+
+func (d *helperDetector) Provider() string { return d.name }
+
+func (d *helperDetector) Detect(call FlagCall) (string, bool) {
+	if !d.methods[call.Name] && !d.methods[call.Callee] && (d.pattern == nil || !d.pattern.MatchString(call.Callee)) {
+		return "", false
+	}
+	return call.StringArg(d.keyArg)
+}
+
+// SetFlagDetectors sets the detectors the calls of the file are checked
+// with for feature flags
+func (t *TranslateFromSyntaxTree) SetFlagDetectors(detectors []FlagDetector) {
+	t.flagDetectors = detectors
+}
+
+// handleFlagCall links a call checking a feature flag to the flag with a
+// USES_FLAG relation, recording the function making the call
+func (t *TranslateFromSyntaxTree) handleFlagCall(ctx context.Context, callNode *ast.Node, args []*tree_sitter.Node) {
+	if len(t.flagDetectors) == 0 || len(args) == 0 {
+		return
+	}
+	key, provider, ok := detectFlag(t.flagDetectors, t.newFlagCall(args))
+	if !ok {
+		return
+	}
+	function := ast.InvalidNodeID
+	if len(t.functions) > 0 {
+		function = t.functions[len(t.functions)-1]
+	}
+	flagID := t.flagNode(ctx, key, provider, callNode)
+	t.CodeGraph.CreateUsesFlagRelation(ctx, callNode.ID, flagID, function, t.FileID)
+}
+
+// newFlagCall describes a call, given by its arguments, for the detectors
+func (t *TranslateFromSyntaxTree) newFlagCall(args []*tree_sitter.Node) FlagCall {
+	call := FlagCall{Callee: t.calleeText(args[0]), args: args, t: t}
+	call.Receiver, call.Name = splitCallee(call.Callee)
+	return call
+}
+
+// detectFlag returns the flag key a call checks and the provider of the
+// first detector recognising it
+func detectFlag(detectors []FlagDetector, call FlagCall) (key, provider string, ok bool) {
+	for _, detector := range detectors {
+		if key, ok := detector.Detect(call); ok && key != "" {
+			return key, detector.Provider(), true
+		}
+	}
+	return "", "", false
+}
+
+// flagNode returns the FeatureFlag node of a file for a flag key, creating
+// it at the first call checking the flag
+func (t *TranslateFromSyntaxTree) flagNode(ctx context.Context, key, provider string, callNode *ast.Node) ast.NodeID {
+	if id, ok := t.flags[key]; ok {
+		return id
+	}
+	node := t.AllocNode(ast.NodeTypeFeatureFlag, key, callNode.Range, ast.InvalidNodeID)
+	node.MetaData = map[string]any{MetaProvider: provider}
+	t.CodeGraph.CreateFeatureFlag(ctx, node)
+	if t.flags == nil {
+		t.flags = make(map[string]ast.NodeID)
+	}
+	t.flags[key] = node.ID
+	return node.ID
+}
@@ -0,0 +1,56 @@
+package parse
+
+import (
+	"testing"
+
+	"github.com/armchr/codeapi/internal/config"
+)
+
+func TestDetectFlag(t *testing.T) {
+	homegrown := config.FeatureFlagsConfig{
+		Providers: []string{config.FlagProviderUnleash},
+		Helpers: []config.FlagHelperConfig{
+			{Name: "flags", Methods: []string{"isOn"}},
+			{Name: "features", Pattern: `^features\.is[A-Z]\w*$`, KeyArg: 1},
+		},
+	}
+
+	tests := []struct {
+		name         string
+		call         string
+		cfg          config.FeatureFlagsConfig
+		wantKey      string
+		wantProvider string
+	}{
+		{name: "launchdarkly typed variation", call: `client.boolVariation("new-checkout", context, false)`, wantKey: "new-checkout", wantProvider: "launchdarkly"},
+		{name: "launchdarkly variation on client", call: `ldClient.variation("banner", user, false)`, wantKey: "banner", wantProvider: "launchdarkly"},
+		{name: "variation on something else", call: `pricing.variation("banner", user, false)`},
+		{name: "unleash", call: `unleash.isEnabled("beta-search")`, wantKey: "beta-search", wantProvider: "unleash"},
+		{name: "isEnabled on another receiver", call: `logger.isEnabled("debug")`},
+		{name: "key not a literal", call: `client.boolVariation(flagKey, context, false)`},
+		{name: "helper method", call: `Flags.isOn("dark-mode")`, cfg: homegrown, wantKey: "dark-mode", wantProvider: "flags"},
+		{name: "helper pattern with key argument", call: `features.isActive(user, "fast-path")`, cfg: homegrown, wantKey: "fast-path", wantProvider: "features"},
+		{name: "provider not configured", call: `client.boolVariation("new-checkout", context, false)`, cfg: homegrown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code := "class A { void f() { " + tt.call + "; } }"
+			tree, root := parseJava(t, code)
+			defer tree.Close()
+
+			detectors, err := NewFlagDetectors(tt.cfg)
+			if err != nil {
+				t.Fatalf("NewFlagDetectors() error = %v", err)
+			}
+			jv := newTestJavaVisitor([]byte(code))
+			argList := jv.translate.TreeChildByFieldName(findNodeByKind(root, "method_invocation"), "arguments")
+			call := jv.translate.newFlagCall(jv.translate.NamedChildren(argList))
+
+			key, provider, ok := detectFlag(detectors, call)
+			if ok != (tt.wantKey != "") || key != tt.wantKey || provider != tt.wantProvider {
+				t.Errorf("detectFlag(%s) = %q, %q, %v, want %q, %q", tt.call, key, provider, ok, tt.wantKey, tt.wantProvider)
+			}
+		})
+	}
+}
@@ -4,11 +4,22 @@ import (
 	"github.com/armchr/codeapi/internal/model/ast"
 	"github.com/armchr/codeapi/pkg/lsp/base"
 	"context"
+	"strings"
 
 	tree_sitter "github.com/tree-sitter/go-tree-sitter"
 	"go.uber.org/zap"
 )
 
+// Metadata recorded for Go types and methods, read back in post-processing to
+// resolve calls through receivers and embedded structs
+const (
+	MetaEmbeds       = "embeds"        // types embedded in a struct
+	MetaFieldTypes   = "field_types"   // "name:Type" for each named struct field
+	MetaReceiver     = "receiver"      // receiver name of a method
+	MetaReceiverType = "receiver_type" // receiver type of a method, without the *
+	MetaSelector     = "selector"      // source of the function of a call like s.store.Get
+)
+
 type GoVisitor struct {
 	translate *TranslateFromSyntaxTree
 	logger    *zap.Logger
@@ -88,7 +99,7 @@ func (gv *GoVisitor) TraverseNode(ctx context.Context, tsNode *tree_sitter.Node,
 func (gv *GoVisitor) handlePackage(ctx context.Context, tsNode *tree_sitter.Node) ast.NodeID {
 	nameNode := gv.translate.TreeChildByKind(tsNode, "package_identifier")
 	moduleNode := ast.NewNode(
-		gv.translate.NextNodeID(), ast.NodeTypeModuleScope, gv.translate.FileID,
+		gv.translate.NodeIDFor(ast.NodeTypeModuleScope, gv.translate.ToRange(tsNode)), ast.NodeTypeModuleScope, gv.translate.FileID,
 		gv.translate.GetTreeNodeName(nameNode), gv.translate.ToRange(tsNode), gv.translate.Version,
 		ast.NodeID(gv.translate.FileID),
 	)
@@ -122,7 +133,7 @@ func (gv *GoVisitor) handleFunctionDeclaration(ctx context.Context, tsNode *tree
 
 func (gv *GoVisitor) createFakeClass(ctx context.Context, className string, fileID int32, scopeID ast.NodeID) *ast.Node {
 	classNode := ast.NewNode(
-		gv.translate.NextNodeID(), ast.NodeTypeClass, fileID,
+		gv.translate.NodeIDFor(ast.NodeTypeClass, base.Range{}), ast.NodeTypeClass, fileID,
 		className, base.Range{}, gv.translate.Version,
 		scopeID,
 	)
@@ -182,11 +193,18 @@ func (gv *GoVisitor) handleMethodDeclaration(ctx context.Context, tsNode *tree_s
 
 	var allParams []*tree_sitter.Node
 	if paramsNode != nil {
-		allParams = append(allParams,
-			gv.translate.TreeChildrenByKind(paramsNode, "parameter_declaration")...)
+		for _, param := range gv.translate.NamedChildren(paramsNode) {
+			if kind := param.Kind(); kind == "parameter_declaration" || kind == "variadic_parameter_declaration" {
+				allParams = append(allParams, param)
+			}
+		}
 	}
 
-	functionId := gv.translate.CreateFunction(ctx, classNode.ID, tsNode, methodName, allParams, bodyNode)
+	metadata := map[string]any{MetaReceiverType: className}
+	if receiverName := gv.translate.SubtreeNodeByKind(receiverNode, "identifier"); receiverName != nil {
+		metadata[MetaReceiver] = gv.translate.String(receiverName)
+	}
+	functionId := gv.translate.CreateFunctionWithMetadata(ctx, classNode.ID, tsNode, methodName, allParams, bodyNode, metadata)
 
 	// TODO: bad design. ideally this function should return the functionId. But that will end up adding functionID
 	// as a CONTAINS in the module.
@@ -278,7 +296,68 @@ func (gv *GoVisitor) handleStructTypeSpec(ctx context.Context, tsNode *tree_sitt
 			fields = append(fields, field)
 		}
 	*/
-	return gv.translate.HandleClass(ctx, scopeID, tsNode, clsName, nil, fieldDecls)
+	var metadata map[string]any
+	embeds, fieldTypes := gv.structFields(fieldDecls)
+	if len(embeds) > 0 || len(fieldTypes) > 0 {
+		metadata = map[string]any{}
+		if len(embeds) > 0 {
+			metadata[MetaEmbeds] = embeds
+		}
+		if len(fieldTypes) > 0 {
+			metadata[MetaFieldTypes] = fieldTypes
+		}
+	}
+	return gv.translate.HandleClassWithMetadata(ctx, scopeID, tsNode, clsName, nil, fieldDecls, metadata)
+}
+
+// structFields returns the types embedded in a struct and "name:Type" for
+// its named fields. Fields whose type has no methods of its own, such as
+// slices and maps, are left out.
+func (gv *GoVisitor) structFields(fieldDecls []*tree_sitter.Node) (embeds, fieldTypes []string) {
+	for _, decl := range fieldDecls {
+		typeNode := gv.translate.TreeChildByFieldName(decl, "type")
+		if typeNode == nil {
+			continue
+		}
+		typeName := goTypeName(gv.translate.String(typeNode))
+		if typeName == "" {
+			continue
+		}
+		names := gv.translate.TreeChildrenByKind(decl, "field_identifier")
+		if len(names) == 0 {
+			embeds = append(embeds, typeName)
+			continue
+		}
+		for _, name := range names {
+			fieldTypes = append(fieldTypes, gv.translate.String(name)+":"+typeName)
+		}
+	}
+	return embeds, fieldTypes
+}
+
+// goTypeName reduces a type expression to the name of the type whose
+// methods it has: *pkg.Store[K] and Store both give Store. It returns "" for
+// slices, maps, channels, funcs and literal struct or interface types.
+func goTypeName(expr string) string {
+	expr = strings.TrimSpace(strings.TrimPrefix(expr, "*"))
+	if i := strings.IndexByte(expr, '['); i == 0 {
+		return ""
+	} else if i > 0 {
+		expr = expr[:i]
+	}
+	if i := strings.LastIndexByte(expr, '.'); i >= 0 {
+		expr = expr[i+1:]
+	}
+	for _, r := range expr {
+		if !(r == '_' || r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r > 127) {
+			return ""
+		}
+	}
+	switch expr {
+	case "", "map", "chan", "func", "struct", "interface":
+		return ""
+	}
+	return expr
 }
 
 func (gv *GoVisitor) handleInterfaceType(ctx context.Context, tsNode *tree_sitter.Node, scopeID ast.NodeID) ast.NodeID {
@@ -312,7 +391,14 @@ func (gv *GoVisitor) handleCallExpression(ctx context.Context, tsNode *tree_sitt
 	}
 
 	fnNameNodeID := gv.translate.HandleRhsWithFakeVariable(ctx, "__fn__", functionNode, scopeID, nil)
-	return gv.translate.HandleCall(ctx, fnNameNodeID, args, scopeID, gv.translate.ToRange(tsNode))
+
+	// The call is named after the last selector only; keep the whole chain
+	// so the receiver can be resolved later
+	var metadata map[string]any
+	if functionNode != nil && functionNode.Kind() == "selector_expression" {
+		metadata = map[string]any{MetaSelector: gv.translate.String(functionNode)}
+	}
+	return gv.translate.HandleCallWithMetadata(ctx, fnNameNodeID, args, scopeID, gv.translate.ToRange(tsNode), metadata)
 }
 
 func (gv *GoVisitor) handleSelectorExpression(ctx context.Context, tsNode *tree_sitter.Node, scopeID ast.NodeID) ast.NodeID {
@@ -448,13 +534,36 @@ func (gv *GoVisitor) handleVarDeclaration(ctx context.Context, tsNode *tree_sitt
 	return ast.InvalidNodeID
 }
 
+// handleConstDeclaration creates a Constant for every name of a const
+// declaration, with iota as its ordinal. A spec without values repeats the
+// values of the one before, which are recorded but not traversed again.
 func (gv *GoVisitor) handleConstDeclaration(ctx context.Context, tsNode *tree_sitter.Node, scopeID ast.NodeID) ast.NodeID {
-	specs := gv.translate.TreeChildrenByKind(tsNode, "const_spec")
-	for _, spec := range specs {
-		nameNode := gv.translate.TreeChildByFieldName(spec, "name")
-		valueNode := gv.translate.TreeChildByFieldName(spec, "value")
-		if nameNode != nil && valueNode != nil {
-			gv.translate.HandleAssignment(ctx, spec, nameNode, valueNode, scopeID)
+	var values []*tree_sitter.Node
+	for iota, spec := range gv.translate.TreeChildrenByKind(tsNode, "const_spec") {
+		var names []*tree_sitter.Node
+		for i := uint(0); i < spec.ChildCount(); i++ {
+			if spec.FieldNameForChild(uint32(i)) == "name" {
+				names = append(names, spec.Child(i))
+			}
+		}
+
+		repeated := true
+		if valueList := gv.translate.TreeChildByFieldName(spec, "value"); valueList != nil {
+			values = gv.translate.NamedChildren(valueList)
+			repeated = false
+		}
+
+		for i, name := range names {
+			metadata := map[string]any{MetaOrdinal: iota}
+			var value *tree_sitter.Node
+			if i < len(values) {
+				if repeated {
+					metadata[MetaValue] = gv.translate.String(values[i])
+				} else {
+					value = values[i]
+				}
+			}
+			gv.translate.HandleConstant(ctx, name, value, scopeID, metadata)
 		}
 	}
 	return ast.InvalidNodeID
@@ -709,15 +818,7 @@ func (gv *GoVisitor) handleImportSpec(ctx context.Context, tsNode *tree_sitter.N
 	}
 
 	// Create the Import node
-	importNode := ast.NewNode(
-		gv.translate.NextNodeID(),
-		ast.NodeTypeImport,
-		gv.translate.FileID,
-		symbolName,
-		gv.translate.ToRange(tsNode),
-		gv.translate.Version,
-		scopeID,
-	)
+	importNode := gv.translate.AllocNode(ast.NodeTypeImport, symbolName, gv.translate.ToRange(tsNode), scopeID)
 
 	// Store the full import path in metadata
 	importNode.MetaData = map[string]any{
@@ -730,9 +831,6 @@ func (gv *GoVisitor) handleImportSpec(ctx context.Context, tsNode *tree_sitter.N
 	// Add to current scope so it can be resolved when used
 	gv.translate.CurrentScope.AddSymbol(NewSymbol(importNode))
 
-	// Track in translator's node map
-	gv.translate.Nodes[importNode.ID] = importNode
-
 	return importNode.ID
 }
 
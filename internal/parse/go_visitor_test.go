@@ -0,0 +1,80 @@
+package parse
+
+import (
+	"slices"
+	"testing"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+	golang "github.com/tree-sitter/tree-sitter-go/bindings/go"
+	"go.uber.org/zap"
+)
+
+func parseGo(t *testing.T, code string) (*tree_sitter.Tree, *GoVisitor) {
+	parser := tree_sitter.NewParser()
+	defer parser.Close()
+
+	if err := parser.SetLanguage(tree_sitter.NewLanguage(golang.Language())); err != nil {
+		t.Fatalf("Failed to set Go language: %v", err)
+	}
+	tree := parser.Parse([]byte(code), nil)
+	if tree == nil {
+		t.Fatal("Failed to parse Go code")
+	}
+
+	logger := zap.NewNop()
+	return tree, NewGoVisitor(logger, NewTranslateFromSyntaxTree(1, 1, nil, []byte(code), logger))
+}
+
+func TestGoStructFields(t *testing.T) {
+	code := `package server
+
+type Server struct {
+	*Handler
+	Logger
+	metrics.Recorder
+	cache.Store[string]
+	store      *Store
+	name, addr string
+	peers      []*Peer
+	routes     map[string]Route
+	onClose    func()
+	opts       struct{ debug bool }
+	clock      pkg.Clock
+}
+`
+	tree, gv := parseGo(t, code)
+	defer tree.Close()
+
+	fieldList := findNodeByKind(tree.RootNode(), "field_declaration_list")
+	embeds, fieldTypes := gv.structFields(gv.translate.TreeChildrenByKind(fieldList, "field_declaration"))
+
+	if want := []string{"Handler", "Logger", "Recorder", "Store"}; !slices.Equal(embeds, want) {
+		t.Errorf("embeds = %v, want %v", embeds, want)
+	}
+	if want := []string{"store:Store", "name:string", "addr:string", "clock:Clock"}; !slices.Equal(fieldTypes, want) {
+		t.Errorf("fieldTypes = %v, want %v", fieldTypes, want)
+	}
+}
+
+func TestGoTypeName(t *testing.T) {
+	tests := []struct {
+		expr string
+		want string
+	}{
+		{"Store", "Store"},
+		{"*Store", "Store"},
+		{"pkg.Store", "Store"},
+		{"*pkg.Store[K, V]", "Store"},
+		{"[]Store", ""},
+		{"map[string]Store", ""},
+		{"chan Store", ""},
+		{"func() error", ""},
+		{"struct{}", ""},
+		{"interface{ Close() }", ""},
+	}
+	for _, tt := range tests {
+		if got := goTypeName(tt.expr); got != tt.want {
+			t.Errorf("goTypeName(%q) = %q, want %q", tt.expr, got, tt.want)
+		}
+	}
+}
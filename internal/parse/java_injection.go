@@ -0,0 +1,236 @@
+package parse
+
+import (
+	"encoding/json"
+	"strings"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+// MetaInjects is the class metadata listing the dependencies a Java class
+// gets injected, as JSON encoded Injection values
+const MetaInjects = "injects"
+
+// Ways a dependency is injected
+const (
+	InjectViaField       = "field"
+	InjectViaConstructor = "constructor"
+	InjectViaMethod      = "method"
+)
+
+// Injection is a dependency injected into a class: its declared type, the
+// field or parameter receiving it, and how it is injected
+type Injection struct {
+	Type string `json:"type"`
+	Name string `json:"name"`
+	Via  string `json:"via"`
+}
+
+// injectAnnotations mark the fields, constructors and methods a container
+// injects (Spring, JSR-330 and JSR-250)
+var injectAnnotations = map[string]bool{"Autowired": true, "Inject": true, "Resource": true}
+
+// beanAnnotations make a class a Spring bean, whose only constructor is used
+// for injection even without an annotation
+var beanAnnotations = map[string]bool{
+	"Component": true, "Service": true, "Repository": true,
+	"Controller": true, "RestController": true, "Configuration": true,
+}
+
+// providerTypes wrap the type of a lazily or optionally injected dependency
+var providerTypes = map[string]bool{"Provider": true, "ObjectProvider": true, "Optional": true, "Lazy": true}
+
+// DecodeInjections reads the MetaInjects metadata of a class, which is a
+// []string when parsed and a []any when read back from the database
+func DecodeInjections(value any) []Injection {
+	var encoded []string
+	switch v := value.(type) {
+	case []string:
+		encoded = v
+	case []any:
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				encoded = append(encoded, s)
+			}
+		}
+	}
+
+	var injections []Injection
+	for _, s := range encoded {
+		var inj Injection
+		if err := json.Unmarshal([]byte(s), &inj); err == nil && inj.Type != "" {
+			injections = append(injections, inj)
+		}
+	}
+	return injections
+}
+
+// injectionPoints finds the dependencies injected into a class: annotated
+// fields, the parameters of an annotated constructor (or of the only
+// constructor of a bean, including one generated by Lombok) and the
+// parameters of annotated methods
+func (jv *JavaVisitor) injectionPoints(classNode, classBody *tree_sitter.Node) []string {
+	if classBody == nil {
+		return nil
+	}
+	var injections []Injection
+
+	fields := jv.translate.TreeChildrenByKind(classBody, "field_declaration")
+	for _, field := range fields {
+		if jv.hasAnnotation(field, injectAnnotations) {
+			injections = append(injections, jv.fieldInjections(field)...)
+		}
+	}
+
+	constructors := jv.translate.TreeChildrenByKind(classBody, "constructor_declaration")
+	var injected *tree_sitter.Node
+	for _, constructor := range constructors {
+		if jv.hasAnnotation(constructor, injectAnnotations) {
+			injected = constructor
+			break
+		}
+	}
+	isBean := jv.hasAnnotation(classNode, beanAnnotations)
+	if injected == nil && isBean && len(constructors) == 1 {
+		injected = constructors[0]
+	}
+	if injected != nil {
+		injections = append(injections, jv.parameterInjections(injected, InjectViaConstructor)...)
+	} else if isBean && len(constructors) == 0 {
+		injections = append(injections, jv.lombokConstructorInjections(classNode, fields)...)
+	}
+
+	for _, method := range jv.translate.TreeChildrenByKind(classBody, "method_declaration") {
+		if jv.hasAnnotation(method, injectAnnotations) {
+			injections = append(injections, jv.parameterInjections(method, InjectViaMethod)...)
+		}
+	}
+
+	encoded := make([]string, 0, len(injections))
+	for _, inj := range injections {
+		if data, err := json.Marshal(inj); err == nil {
+			encoded = append(encoded, string(data))
+		}
+	}
+	return encoded
+}
+
+// lombokConstructorInjections returns the parameters of the constructor
+// Lombok generates for a class: its final fields without an initializer for
+// @RequiredArgsConstructor, or all its instance fields for @AllArgsConstructor
+func (jv *JavaVisitor) lombokConstructorInjections(classNode *tree_sitter.Node, fields []*tree_sitter.Node) []Injection {
+	required := jv.hasAnnotation(classNode, map[string]bool{"RequiredArgsConstructor": true})
+	all := jv.hasAnnotation(classNode, map[string]bool{"AllArgsConstructor": true})
+	if !required && !all {
+		return nil
+	}
+
+	var injections []Injection
+	for _, field := range fields {
+		static, final := jv.fieldModifiers(field)
+		if static || (required && !final) {
+			continue
+		}
+		for _, inj := range jv.fieldInjections(field) {
+			if required && jv.isInitialized(field, inj.Name) {
+				continue
+			}
+			inj.Via = InjectViaConstructor
+			injections = append(injections, inj)
+		}
+	}
+	return injections
+}
+
+// fieldInjections returns a field injection for every variable a field
+// declaration declares
+func (jv *JavaVisitor) fieldInjections(field *tree_sitter.Node) []Injection {
+	typeName := jv.injectedType(jv.translate.TreeChildByFieldName(field, "type"))
+	if typeName == "" {
+		return nil
+	}
+	var injections []Injection
+	for _, declarator := range jv.translate.TreeChildrenByKind(field, "variable_declarator") {
+		if nameNode := jv.translate.TreeChildByFieldName(declarator, "name"); nameNode != nil {
+			injections = append(injections, Injection{Type: typeName, Name: jv.translate.String(nameNode), Via: InjectViaField})
+		}
+	}
+	return injections
+}
+
+// parameterInjections returns an injection for every parameter of a
+// constructor or method
+func (jv *JavaVisitor) parameterInjections(tsNode *tree_sitter.Node, via string) []Injection {
+	paramsNode := jv.translate.TreeChildByFieldName(tsNode, "parameters")
+	if paramsNode == nil {
+		return nil
+	}
+	var injections []Injection
+	for _, param := range jv.translate.TreeChildrenByKind(paramsNode, "formal_parameter") {
+		typeName := jv.injectedType(jv.translate.TreeChildByFieldName(param, "type"))
+		nameNode := jv.translate.TreeChildByFieldName(param, "name")
+		if typeName != "" && nameNode != nil {
+			injections = append(injections, Injection{Type: typeName, Name: jv.translate.String(nameNode), Via: via})
+		}
+	}
+	return injections
+}
+
+// injectedType returns the type a dependency is looked up by: the declared
+// type without type arguments, or the type inside a provider such as
+// ObjectProvider<T> or Optional<T>
+func (jv *JavaVisitor) injectedType(typeNode *tree_sitter.Node) string {
+	if typeNode == nil {
+		return ""
+	}
+	if typeNode.Kind() != "generic_type" {
+		return jv.translate.String(typeNode)
+	}
+	base := ""
+	if typeNode.NamedChildCount() > 0 {
+		base = jv.translate.String(typeNode.NamedChild(0))
+	}
+	if providerTypes[base[strings.LastIndex(base, ".")+1:]] {
+		args := jv.translate.TreeChildByKind(typeNode, "type_arguments")
+		if args != nil && args.NamedChildCount() == 1 {
+			return jv.injectedType(args.NamedChild(0))
+		}
+	}
+	return base
+}
+
+// hasAnnotation reports whether a declaration carries one of the annotations,
+// given by simple name
+func (jv *JavaVisitor) hasAnnotation(tsNode *tree_sitter.Node, names map[string]bool) bool {
+	modifiers := jv.translate.TreeChildByKind(tsNode, "modifiers")
+	if modifiers == nil {
+		return false
+	}
+	for i := uint(0); i < modifiers.NamedChildCount(); i++ {
+		child := modifiers.NamedChild(i)
+		if child.Kind() != "marker_annotation" && child.Kind() != "annotation" {
+			continue
+		}
+		nameNode := jv.translate.TreeChildByFieldName(child, "name")
+		if nameNode == nil {
+			continue
+		}
+		name := jv.translate.String(nameNode)
+		if names[name[strings.LastIndex(name, ".")+1:]] {
+			return true
+		}
+	}
+	return false
+}
+
+// isInitialized reports whether the variable name of a field declaration has
+// an initializer
+func (jv *JavaVisitor) isInitialized(field *tree_sitter.Node, name string) bool {
+	for _, declarator := range jv.translate.TreeChildrenByKind(field, "variable_declarator") {
+		nameNode := jv.translate.TreeChildByFieldName(declarator, "name")
+		if nameNode != nil && jv.translate.String(nameNode) == name {
+			return jv.translate.TreeChildByFieldName(declarator, "value") != nil
+		}
+	}
+	return false
+}
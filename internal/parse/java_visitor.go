@@ -3,6 +3,7 @@ package parse
 import (
 	"context"
 	"encoding/json"
+	"strings"
 
 	"github.com/armchr/codeapi/internal/model/ast"
 	tree_sitter "github.com/tree-sitter/go-tree-sitter"
@@ -134,6 +135,8 @@ func (jv *JavaVisitor) TraverseNode(ctx context.Context, tsNode *tree_sitter.Nod
 		return jv.handleConstructorDeclaration(ctx, tsNode, scopeID)
 	case "field_declaration":
 		return jv.handleFieldDeclaration(ctx, tsNode, scopeID)
+	case "enum_constant":
+		return jv.handleEnumConstant(ctx, tsNode, scopeID)
 	case "block":
 		return jv.translate.HandleBlock(ctx, tsNode, scopeID)
 	case "local_variable_declaration":
@@ -245,10 +248,11 @@ func (jv *JavaVisitor) handleProgram(ctx context.Context, tsNode *tree_sitter.No
 	} else {
 		// Create a default module scope for files without package declaration
 		moduleNode := ast.NewNode(
-			jv.translate.NextNodeID(), ast.NodeTypeModuleScope, jv.translate.FileID,
+			jv.translate.NodeIDFor(ast.NodeTypeModuleScope, jv.translate.ToRange(tsNode)), ast.NodeTypeModuleScope, jv.translate.FileID,
 			"default", jv.translate.ToRange(tsNode), jv.translate.Version,
 			ast.NodeID(jv.translate.FileID),
 		)
+		moduleNode.MetaData = jv.wildcardImportMetadata(tsNode)
 		jv.translate.CodeGraph.CreateModuleScope(ctx, moduleNode)
 		moduleNodeID = moduleNode.ID
 	}
@@ -276,14 +280,47 @@ func (jv *JavaVisitor) handlePackageDeclaration(ctx context.Context, tsNode *tre
 	}
 
 	moduleNode := ast.NewNode(
-		jv.translate.NextNodeID(), ast.NodeTypeModuleScope, jv.translate.FileID,
+		jv.translate.NodeIDFor(ast.NodeTypeModuleScope, jv.translate.ToRange(tsNode)), ast.NodeTypeModuleScope, jv.translate.FileID,
 		packageName, jv.translate.ToRange(tsNode), jv.translate.Version,
 		ast.NodeID(jv.translate.FileID),
 	)
+	if program := tsNode.Parent(); program != nil {
+		moduleNode.MetaData = jv.wildcardImportMetadata(program)
+	}
 	jv.translate.CodeGraph.CreateModuleScope(ctx, moduleNode)
 	return moduleNode.ID
 }
 
+// wildcardImportMetadata returns the module scope metadata listing the
+// wildcard imports of a program. They name no symbol to add to the scope,
+// so post-processing looks classes and static members up in them instead.
+func (jv *JavaVisitor) wildcardImportMetadata(program *tree_sitter.Node) map[string]any {
+	var packages, staticClasses []string
+	for _, decl := range jv.translate.TreeChildrenByKind(program, "import_declaration") {
+		imp, ok := jv.parseImport(decl)
+		if !ok || !imp.wildcard {
+			continue
+		}
+		if imp.static {
+			staticClasses = append(staticClasses, imp.path)
+		} else {
+			packages = append(packages, imp.path)
+		}
+	}
+
+	if len(packages) == 0 && len(staticClasses) == 0 {
+		return nil
+	}
+	metadata := map[string]any{}
+	if len(packages) > 0 {
+		metadata[MetaWildcardImports] = packages
+	}
+	if len(staticClasses) > 0 {
+		metadata[MetaStaticWildcardImports] = staticClasses
+	}
+	return metadata
+}
+
 func (jv *JavaVisitor) handleClassDeclaration(ctx context.Context, tsNode *tree_sitter.Node, scopeID ast.NodeID) ast.NodeID {
 	nameNode := jv.translate.TreeChildByFieldName(tsNode, "name")
 	className := ""
@@ -310,6 +347,11 @@ func (jv *JavaVisitor) handleClassDeclaration(ctx context.Context, tsNode *tree_
 		metadata["annotations"] = annotations
 	}
 
+	// Dependencies injected by Spring or another DI container
+	if injects := jv.injectionPoints(tsNode, classBody); len(injects) > 0 {
+		metadata[MetaInjects] = injects
+	}
+
 	// Extract superclass (extends)
 	superclassNode := jv.translate.TreeChildByKind(tsNode, "superclass")
 	if superclassNode != nil {
@@ -415,12 +457,13 @@ func (jv *JavaVisitor) handleEnumDeclaration(ctx context.Context, tsNode *tree_s
 	}
 
 	enumBody := jv.translate.TreeChildByKind(tsNode, "enum_body")
-	var methods []*tree_sitter.Node
-	var fields []*tree_sitter.Node
+	var members []*tree_sitter.Node
 
 	if enumBody != nil {
-		methods = jv.translate.TreeChildrenByKind(enumBody, "method_declaration")
-		fields = jv.translate.TreeChildrenByKind(enumBody, "enum_constant")
+		// Constants are traversed like methods, as handleEnumConstant
+		// creates them in the scope of the enum
+		members = jv.translate.TreeChildrenByKind(enumBody, "enum_constant")
+		members = append(members, jv.translate.TreeChildrenByKind(enumBody, "method_declaration")...)
 	}
 
 	// Extract annotations and mark as enum
@@ -432,7 +475,35 @@ func (jv *JavaVisitor) handleEnumDeclaration(ctx context.Context, tsNode *tree_s
 		metadata = map[string]any{"is_enum": true}
 	}
 
-	return jv.translate.HandleClassWithMetadata(ctx, scopeID, tsNode, enumName, methods, fields, metadata)
+	return jv.translate.HandleClassWithMetadata(ctx, scopeID, tsNode, enumName, members, nil, metadata)
+}
+
+// handleEnumConstant creates a Constant for an enum constant with its ordinal
+// and the arguments it passes to the enum's constructor
+func (jv *JavaVisitor) handleEnumConstant(ctx context.Context, tsNode *tree_sitter.Node, scopeID ast.NodeID) ast.NodeID {
+	metadata := jv.enumConstantMetadata(tsNode)
+	return jv.translate.HandleConstant(ctx, jv.translate.TreeChildByFieldName(tsNode, "name"), nil, scopeID, metadata)
+}
+
+// enumConstantMetadata returns the ordinal of an enum constant and the source
+// text of its constructor arguments, if it has any
+func (jv *JavaVisitor) enumConstantMetadata(tsNode *tree_sitter.Node) map[string]any {
+	ordinal := 0
+	for prev := tsNode.PrevNamedSibling(); prev != nil; prev = prev.PrevNamedSibling() {
+		if prev.Kind() == "enum_constant" {
+			ordinal++
+		}
+	}
+	metadata := map[string]any{MetaOrdinal: ordinal}
+
+	if argsNode := jv.translate.TreeChildByFieldName(tsNode, "arguments"); argsNode != nil {
+		args := []string{}
+		for _, arg := range jv.translate.NamedChildren(argsNode) {
+			args = append(args, jv.translate.String(arg))
+		}
+		metadata[MetaArguments] = args
+	}
+	return metadata
 }
 
 func (jv *JavaVisitor) handleMethodDeclaration(ctx context.Context, tsNode *tree_sitter.Node, scopeID ast.NodeID) ast.NodeID {
@@ -458,8 +529,59 @@ func (jv *JavaVisitor) handleMethodDeclaration(ctx context.Context, tsNode *tree
 	if len(annotations) > 0 {
 		metadata = map[string]any{"annotations": annotations}
 	}
+	if throws := jv.declaredThrows(tsNode); len(throws) > 0 {
+		if metadata == nil {
+			metadata = make(map[string]any)
+		}
+		metadata[MetaThrows] = throws
+	}
 
-	return jv.translate.CreateFunctionWithMetadata(ctx, scopeID, tsNode, methodName, params, bodyNode, metadata)
+	methodID := jv.translate.CreateFunctionWithMetadata(ctx, scopeID, tsNode, methodName, params, bodyNode, metadata)
+	if queryNode := jv.queryAnnotationValue(tsNode); queryNode != nil {
+		if query, ok := jv.translate.StringValue(queryNode); ok {
+			jv.translate.HandleSQL(ctx, methodID, query, jv.translate.ToRange(queryNode))
+		}
+	}
+	jv.handleValueAnnotation(ctx, tsNode, methodID)
+	for _, param := range params {
+		jv.handleValueAnnotation(ctx, param, methodID)
+	}
+	return methodID
+}
+
+// queryAnnotationValue returns the query of a Spring Data @Query annotation
+// on a method, given as its single argument or as value = ...
+func (jv *JavaVisitor) queryAnnotationValue(tsNode *tree_sitter.Node) *tree_sitter.Node {
+	return jv.annotationValue(tsNode, "Query")
+}
+
+// annotationValue returns the value of the annotation of a declaration with
+// the given simple name, given as its single argument or as value = ...
+func (jv *JavaVisitor) annotationValue(tsNode *tree_sitter.Node, name string) *tree_sitter.Node {
+	modifiers := jv.translate.TreeChildByKind(tsNode, "modifiers")
+	if modifiers == nil {
+		return nil
+	}
+	for _, annotation := range jv.translate.TreeChildrenByKind(modifiers, "annotation") {
+		nameNode := jv.translate.TreeChildByFieldName(annotation, "name")
+		args := jv.translate.TreeChildByFieldName(annotation, "arguments")
+		if nameNode == nil || args == nil {
+			continue
+		}
+		if annotationName := jv.translate.String(nameNode); annotationName[strings.LastIndex(annotationName, ".")+1:] != name {
+			continue
+		}
+		for _, arg := range jv.translate.NamedChildren(args) {
+			if arg.Kind() != "element_value_pair" {
+				return arg
+			}
+			key := jv.translate.TreeChildByFieldName(arg, "key")
+			if key != nil && jv.translate.String(key) == "value" {
+				return jv.translate.TreeChildByFieldName(arg, "value")
+			}
+		}
+	}
+	return nil
 }
 
 func (jv *JavaVisitor) handleConstructorDeclaration(ctx context.Context, tsNode *tree_sitter.Node, scopeID ast.NodeID) ast.NodeID {
@@ -483,22 +605,67 @@ func (jv *JavaVisitor) handleConstructorDeclaration(ctx context.Context, tsNode
 	if len(annotations) > 0 {
 		metadata["annotations"] = annotations
 	}
+	if throws := jv.declaredThrows(tsNode); len(throws) > 0 {
+		metadata[MetaThrows] = throws
+	}
 
-	return jv.translate.CreateFunctionWithMetadata(ctx, scopeID, tsNode, constructorName, params, bodyNode, metadata)
+	constructorID := jv.translate.CreateFunctionWithMetadata(ctx, scopeID, tsNode, constructorName, params, bodyNode, metadata)
+	for _, param := range params {
+		jv.handleValueAnnotation(ctx, param, constructorID)
+	}
+	return constructorID
+}
+
+// handleValueAnnotation records the configuration keys a Spring @Value
+// annotation on a field, method or parameter reads, with the value flowing
+// into targets
+func (jv *JavaVisitor) handleValueAnnotation(ctx context.Context, tsNode *tree_sitter.Node, scopeID ast.NodeID, targets ...ast.NodeID) {
+	valueNode := jv.annotationValue(tsNode, "Value")
+	if valueNode == nil {
+		return
+	}
+	expr, ok := jv.translate.StringValue(valueNode)
+	if !ok {
+		return
+	}
+	for _, key := range PlaceholderKeys(expr) {
+		configID := jv.translate.HandleConfigRead(ctx, key, ConfigSourceProperty, jv.translate.ToRange(valueNode), scopeID)
+		for _, target := range targets {
+			jv.translate.CodeGraph.CreateDataFlowRelation(ctx, configID, target, jv.translate.FileID)
+		}
+	}
 }
 
 func (jv *JavaVisitor) handleFieldDeclaration(ctx context.Context, tsNode *tree_sitter.Node, scopeID ast.NodeID) ast.NodeID {
 	declarators := jv.translate.TreeChildrenByKind(tsNode, "variable_declarator")
 	var firstFieldID ast.NodeID = ast.InvalidNodeID
+	var fieldIDs []ast.NodeID
+	constant := jv.isStaticFinal(tsNode)
 
 	for _, declarator := range declarators {
 		nameNode := jv.translate.TreeChildByFieldName(declarator, "name")
+		valueNode := jv.translate.TreeChildByFieldName(declarator, "value")
+
+		// static final fields initialized with a literal are constants
+		if constant && nameNode != nil && valueNode != nil && isLiteral(valueNode) {
+			constID := jv.translate.HandleConstant(ctx, nameNode, valueNode, scopeID, nil)
+			if constID != ast.InvalidNodeID {
+				if firstFieldID == ast.InvalidNodeID {
+					firstFieldID = constID
+				}
+				jv.translate.CreateContainsRelation(ctx, scopeID, constID, jv.translate.FileID)
+				jv.translate.CodeGraph.CreateHasFieldRelation(ctx, scopeID, constID, jv.translate.FileID)
+			}
+			continue
+		}
+
 		if nameNode != nil {
 			fieldNodeID := jv.translate.HandleVariable(ctx, nameNode, scopeID)
 			if fieldNodeID != ast.InvalidNodeID {
 				if firstFieldID == ast.InvalidNodeID {
 					firstFieldID = fieldNodeID
 				}
+				fieldIDs = append(fieldIDs, fieldNodeID)
 				// Create CONTAINS and HAS_FIELD relations for class fields
 				jv.translate.CreateContainsRelation(ctx, scopeID, fieldNodeID, jv.translate.FileID)
 				jv.translate.CodeGraph.CreateHasFieldRelation(ctx, scopeID, fieldNodeID, jv.translate.FileID)
@@ -506,14 +673,39 @@ func (jv *JavaVisitor) handleFieldDeclaration(ctx context.Context, tsNode *tree_
 		}
 
 		// Handle initialization if present
-		valueNode := jv.translate.TreeChildByFieldName(declarator, "value")
 		if nameNode != nil && valueNode != nil {
 			jv.translate.HandleAssignment(ctx, declarator, nameNode, valueNode, scopeID)
 		}
 	}
+	jv.handleValueAnnotation(ctx, tsNode, scopeID, fieldIDs...)
 	return firstFieldID
 }
 
+// isStaticFinal reports whether a field declaration has both the static and
+// the final modifier
+func (jv *JavaVisitor) isStaticFinal(tsNode *tree_sitter.Node) bool {
+	static, final := jv.fieldModifiers(tsNode)
+	return static && final
+}
+
+// fieldModifiers reports whether a field declaration is static and whether
+// it is final
+func (jv *JavaVisitor) fieldModifiers(tsNode *tree_sitter.Node) (static, final bool) {
+	modifiers := jv.translate.TreeChildByKind(tsNode, "modifiers")
+	if modifiers == nil {
+		return false, false
+	}
+	for i := uint(0); i < modifiers.ChildCount(); i++ {
+		switch modifiers.Child(i).Kind() {
+		case "static":
+			static = true
+		case "final":
+			final = true
+		}
+	}
+	return static, final
+}
+
 func (jv *JavaVisitor) handleLocalVariableDeclaration(ctx context.Context, tsNode *tree_sitter.Node, scopeID ast.NodeID) ast.NodeID {
 	declarators := jv.translate.TreeChildrenByKind(tsNode, "variable_declarator")
 	for _, declarator := range declarators {
@@ -680,41 +872,63 @@ func (jv *JavaVisitor) handleSwitchExpression(ctx context.Context, tsNode *tree_
 
 func (jv *JavaVisitor) handleTryStatement(ctx context.Context, tsNode *tree_sitter.Node, scopeID ast.NodeID) ast.NodeID {
 	bodyNode := jv.translate.TreeChildByFieldName(tsNode, "body")
-	if bodyNode != nil {
-		jv.TraverseNode(ctx, bodyNode, scopeID)
-	}
+	handlers, finallyBody := jv.catchClauses(tsNode)
+	return jv.translate.HandleTryCatch(ctx, tsNode, bodyNode, handlers, finallyBody, scopeID)
+}
 
-	// Handle catch clauses
-	catchClauses := jv.translate.TreeChildrenByKind(tsNode, "catch_clause")
-	for _, clause := range catchClauses {
-		catchBody := jv.translate.TreeChildByFieldName(clause, "body")
-		if catchBody != nil {
-			jv.TraverseNode(ctx, catchBody, scopeID)
+// catchClauses returns the catch clauses of a try statement with the types
+// each catches (several for multi-catch), and the finally block if any
+func (jv *JavaVisitor) catchClauses(tsNode *tree_sitter.Node) ([]CatchHandler, *tree_sitter.Node) {
+	var handlers []CatchHandler
+	for _, clause := range jv.translate.TreeChildrenByKind(tsNode, "catch_clause") {
+		handler := CatchHandler{Body: jv.translate.TreeChildByFieldName(clause, "body")}
+		if param := jv.translate.TreeChildByKind(clause, "catch_formal_parameter"); param != nil {
+			if catchType := jv.translate.TreeChildByKind(param, "catch_type"); catchType != nil {
+				for _, typeNode := range jv.translate.NamedChildren(catchType) {
+					handler.Types = append(handler.Types, jv.translate.String(typeNode))
+				}
+			}
 		}
+		handlers = append(handlers, handler)
 	}
 
-	// Handle finally clause
-	finallyClause := jv.translate.TreeChildByKind(tsNode, "finally_clause")
-	if finallyClause != nil {
-		finallyBody := jv.translate.TreeChildByKind(finallyClause, "block")
-		if finallyBody != nil {
-			jv.TraverseNode(ctx, finallyBody, scopeID)
-		}
+	var finallyBody *tree_sitter.Node
+	if finallyClause := jv.translate.TreeChildByKind(tsNode, "finally_clause"); finallyClause != nil {
+		finallyBody = jv.translate.TreeChildByKind(finallyClause, "block")
 	}
-
-	return ast.InvalidNodeID
+	return handlers, finallyBody
 }
 
 func (jv *JavaVisitor) handleThrowStatement(ctx context.Context, tsNode *tree_sitter.Node, scopeID ast.NodeID) ast.NodeID {
 	for i := uint(0); i < tsNode.ChildCount(); i++ {
 		child := tsNode.Child(i)
 		if child.IsNamed() {
-			return jv.translate.HandleRhsWithFakeVariable(ctx, "__throw__", child, scopeID, nil)
+			thrownType := ""
+			if child.Kind() == "object_creation_expression" {
+				if typeNode := jv.translate.TreeChildByFieldName(child, "type"); typeNode != nil {
+					thrownType = jv.translate.String(typeNode)
+				}
+			}
+			return jv.translate.HandleThrow(ctx, child, thrownType, scopeID)
 		}
 	}
 	return ast.InvalidNodeID
 }
 
+// declaredThrows returns the exception types in the throws clause of a
+// method or constructor declaration
+func (jv *JavaVisitor) declaredThrows(tsNode *tree_sitter.Node) []string {
+	throwsNode := jv.translate.TreeChildByKind(tsNode, "throws")
+	if throwsNode == nil {
+		return nil
+	}
+	var types []string
+	for _, typeNode := range jv.translate.NamedChildren(throwsNode) {
+		types = append(types, jv.translate.String(typeNode))
+	}
+	return types
+}
+
 func (jv *JavaVisitor) handleMethodInvocation(ctx context.Context, tsNode *tree_sitter.Node, scopeID ast.NodeID) ast.NodeID {
 	objectNode := jv.translate.TreeChildByFieldName(tsNode, "object")
 	nameNode := jv.translate.TreeChildByFieldName(tsNode, "name")
@@ -806,41 +1020,65 @@ func (jv *JavaVisitor) handleFieldAccess(ctx context.Context, tsNode *tree_sitte
 	return resolvedNodeId
 }
 
-func (jv *JavaVisitor) handleImportDeclaration(ctx context.Context, tsNode *tree_sitter.Node, scopeID ast.NodeID) ast.NodeID {
-	// Get the scoped_identifier which contains the import path
+// Module scope metadata keys holding the wildcard imports of a Java file
+const (
+	MetaWildcardImports       = "wildcard_imports"        // packages of import a.b.*
+	MetaStaticWildcardImports = "static_wildcard_imports" // classes of import static a.B.*
+)
+
+// javaImport is a parsed import declaration. The path leaves out a trailing
+// ".*", so "import a.b.*" has path "a.b" and wildcard set.
+type javaImport struct {
+	path     string
+	static   bool
+	wildcard bool
+}
+
+// parseImport reads an import declaration
+func (jv *JavaVisitor) parseImport(tsNode *tree_sitter.Node) (javaImport, bool) {
+	// A single-segment import such as "import foo.*" has an identifier
 	nameNode := jv.translate.TreeChildByKind(tsNode, "scoped_identifier")
 	if nameNode == nil {
-		return ast.InvalidNodeID
+		nameNode = jv.translate.TreeChildByKind(tsNode, "identifier")
+	}
+	if nameNode == nil {
+		return javaImport{}, false
 	}
 
-	importPath := jv.translate.String(nameNode)
-	if importPath == "" {
+	imp := javaImport{
+		path:     jv.translate.String(nameNode),
+		static:   jv.translate.TreeChildByKind(tsNode, "static") != nil,
+		wildcard: jv.translate.TreeChildByKind(tsNode, "asterisk") != nil,
+	}
+	return imp, imp.path != ""
+}
+
+func (jv *JavaVisitor) handleImportDeclaration(ctx context.Context, tsNode *tree_sitter.Node, scopeID ast.NodeID) ast.NodeID {
+	imp, ok := jv.parseImport(tsNode)
+	// Wildcard imports are recorded on the module scope
+	if !ok || imp.wildcard {
 		return ast.InvalidNodeID
 	}
+	importPath := imp.path
 
 	// Extract the simple name (last component)
 	symbolName := jv.getSimpleNameFromImport(importPath)
-	if symbolName == "" || symbolName == "*" {
+	if symbolName == "" {
 		return ast.InvalidNodeID
 	}
 
-	importNode := ast.NewNode(
-		jv.translate.NextNodeID(),
-		ast.NodeTypeImport,
-		jv.translate.FileID,
-		symbolName,
-		jv.translate.ToRange(tsNode),
-		jv.translate.Version,
-		scopeID,
-	)
+	importNode := jv.translate.AllocNode(ast.NodeTypeImport, symbolName, jv.translate.ToRange(tsNode), scopeID)
 
 	importNode.MetaData = map[string]any{
 		"importPath": importPath,
 	}
+	if imp.static {
+		// import static a.B.m names the member m of class a.B
+		importNode.MetaData["static"] = true
+	}
 
 	jv.translate.CodeGraph.CreateImport(ctx, importNode)
 	jv.translate.CurrentScope.AddSymbol(NewSymbol(importNode))
-	jv.translate.Nodes[importNode.ID] = importNode
 
 	return importNode.ID
 }
@@ -907,31 +1145,9 @@ func (jv *JavaVisitor) handleTryWithResourcesStatement(ctx context.Context, tsNo
 		jv.translate.TraverseChildren(ctx, resourcesNode, scopeID)
 	}
 
-	// Handle body
 	bodyNode := jv.translate.TreeChildByFieldName(tsNode, "body")
-	if bodyNode != nil {
-		jv.TraverseNode(ctx, bodyNode, scopeID)
-	}
-
-	// Handle catch clauses
-	catchClauses := jv.translate.TreeChildrenByKind(tsNode, "catch_clause")
-	for _, clause := range catchClauses {
-		catchBody := jv.translate.TreeChildByFieldName(clause, "body")
-		if catchBody != nil {
-			jv.TraverseNode(ctx, catchBody, scopeID)
-		}
-	}
-
-	// Handle finally clause
-	finallyClause := jv.translate.TreeChildByKind(tsNode, "finally_clause")
-	if finallyClause != nil {
-		finallyBody := jv.translate.TreeChildByKind(finallyClause, "block")
-		if finallyBody != nil {
-			jv.TraverseNode(ctx, finallyBody, scopeID)
-		}
-	}
-
-	return ast.InvalidNodeID
+	handlers, finallyBody := jv.catchClauses(tsNode)
+	return jv.translate.HandleTryCatch(ctx, tsNode, bodyNode, handlers, finallyBody, scopeID)
 }
 
 func (jv *JavaVisitor) handleArrayAccess(ctx context.Context, tsNode *tree_sitter.Node, scopeID ast.NodeID) ast.NodeID {
@@ -1364,13 +1580,20 @@ func (jv *JavaVisitor) extractTypeList(tsNode *tree_sitter.Node) []string {
 	return types
 }
 
-// HasSpecialName returns false for Java - no special naming conventions like C#
+// HasSpecialName reports the Java nodes whose name is not a direct child:
+// the name of a varargs parameter is inside its variable_declarator
 func (jv *JavaVisitor) HasSpecialName(kind string) bool {
-	return false
+	return kind == "spread_parameter"
 }
 
-// GetName is not implemented for Java visitor
+// GetName returns the name of a node HasSpecialName reports
 func (jv *JavaVisitor) GetName(tsNode *tree_sitter.Node) string {
-	jv.logger.Error("GetName not implemented for Java visitor")
+	if tsNode.Kind() == "spread_parameter" {
+		if declarator := jv.translate.TreeChildByKind(tsNode, "variable_declarator"); declarator != nil {
+			return jv.translate.GetTreeNodeName(declarator)
+		}
+		return ""
+	}
+	jv.logger.Error("GetName not implemented for Java node", zap.String("kind", tsNode.Kind()))
 	return ""
 }
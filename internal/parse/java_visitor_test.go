@@ -3,6 +3,7 @@ package parse
 import (
 	"context"
 	"encoding/json"
+	"reflect"
 	"testing"
 
 	tree_sitter "github.com/tree-sitter/go-tree-sitter"
@@ -372,3 +373,256 @@ func TestJavaVisitor_NilNode(t *testing.T) {
 		t.Error("Expected InvalidNodeID (0) for nil input")
 	}
 }
+
+func TestParseImport(t *testing.T) {
+	code := `
+import foo.*;
+import com.example.Pet;
+import static org.junit.Assert.assertEquals;
+import static org.junit.Assert.*;
+import java.util.*;
+`
+	tree, root := parseJava(t, code)
+	defer tree.Close()
+
+	jv := newTestJavaVisitor([]byte(code))
+
+	want := []javaImport{
+		{path: "foo", wildcard: true},
+		{path: "com.example.Pet"},
+		{path: "org.junit.Assert.assertEquals", static: true},
+		{path: "org.junit.Assert", static: true, wildcard: true},
+		{path: "java.util", wildcard: true},
+	}
+	decls := findAllNodesByKind(root, "import_declaration")
+	if len(decls) != len(want) {
+		t.Fatalf("Expected %d import declarations, got %d", len(want), len(decls))
+	}
+	for i, decl := range decls {
+		got, ok := jv.parseImport(decl)
+		if !ok || got != want[i] {
+			t.Errorf("import %d: got %+v (ok %v), want %+v", i, got, ok, want[i])
+		}
+	}
+
+	metadata := jv.wildcardImportMetadata(root)
+	if packages, _ := metadata[MetaWildcardImports].([]string); len(packages) != 2 || packages[0] != "foo" || packages[1] != "java.util" {
+		t.Errorf("Expected wildcard packages [foo java.util], got %v", metadata[MetaWildcardImports])
+	}
+	if classes, _ := metadata[MetaStaticWildcardImports].([]string); len(classes) != 1 || classes[0] != "org.junit.Assert" {
+		t.Errorf("Expected static wildcard classes [org.junit.Assert], got %v", metadata[MetaStaticWildcardImports])
+	}
+}
+
+func TestJavaCatchClauses(t *testing.T) {
+	code := `
+public class Loader {
+    public void load() throws IOException, java.sql.SQLException {
+        try {
+            read();
+        } catch (FileNotFoundException | java.nio.file.NoSuchFileException e) {
+            missing();
+        } catch (IOException e) {
+            throw new IllegalStateException(e);
+        } finally {
+            close();
+        }
+    }
+}
+`
+	tree, root := parseJava(t, code)
+	defer tree.Close()
+
+	jv := newTestJavaVisitor([]byte(code))
+
+	handlers, finallyBody := jv.catchClauses(findNodeByKind(root, "try_statement"))
+	want := [][]string{
+		{"FileNotFoundException", "java.nio.file.NoSuchFileException"},
+		{"IOException"},
+	}
+	if len(handlers) != len(want) {
+		t.Fatalf("Expected %d handlers, got %d", len(want), len(handlers))
+	}
+	for i, handler := range handlers {
+		if !equalStrings(handler.Types, want[i]) {
+			t.Errorf("handler %d: Types = %v, want %v", i, handler.Types, want[i])
+		}
+		if handler.Body == nil || handler.Body.Kind() != "block" {
+			t.Errorf("handler %d: Body = %v, want a block", i, handler.Body)
+		}
+	}
+	if finallyBody == nil || finallyBody.Kind() != "block" {
+		t.Errorf("finally body = %v, want a block", finallyBody)
+	}
+
+	throws := jv.declaredThrows(findNodeByKind(root, "method_declaration"))
+	if want := []string{"IOException", "java.sql.SQLException"}; !equalStrings(throws, want) {
+		t.Errorf("declaredThrows() = %v, want %v", throws, want)
+	}
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestJavaEnumConstantMetadata(t *testing.T) {
+	code := `
+public enum Status {
+    OK(200, "ok"),
+    NOT_FOUND(404, "not found"),
+    UNKNOWN;
+
+    Status() {}
+    Status(int code, String text) {}
+}
+`
+	tree, root := parseJava(t, code)
+	defer tree.Close()
+
+	jv := newTestJavaVisitor([]byte(code))
+
+	constants := findAllNodesByKind(root, "enum_constant")
+	want := []map[string]any{
+		{MetaOrdinal: 0, MetaArguments: []string{"200", `"ok"`}},
+		{MetaOrdinal: 1, MetaArguments: []string{"404", `"not found"`}},
+		{MetaOrdinal: 2},
+	}
+	if len(constants) != len(want) {
+		t.Fatalf("Expected %d enum constants, got %d", len(want), len(constants))
+	}
+	for i, constant := range constants {
+		if got := jv.enumConstantMetadata(constant); !reflect.DeepEqual(got, want[i]) {
+			t.Errorf("constant %d: metadata = %v, want %v", i, got, want[i])
+		}
+	}
+}
+
+func TestJavaInjectionPoints(t *testing.T) {
+	tests := []struct {
+		name string
+		code string
+		want []Injection
+	}{
+		{
+			name: "annotated fields and setter",
+			code: `class OwnerController {
+    @Autowired private OwnerRepository owners;
+    @Inject private javax.inject.Provider<PetValidator> validator;
+    private Clock clock;
+    @Autowired void setVets(List<Vet> vets) {}
+}`,
+			want: []Injection{
+				{Type: "OwnerRepository", Name: "owners", Via: InjectViaField},
+				{Type: "PetValidator", Name: "validator", Via: InjectViaField},
+				{Type: "List", Name: "vets", Via: InjectViaMethod},
+			},
+		},
+		{
+			name: "single constructor of a bean",
+			code: `@Service
+class VisitService {
+    private final VisitRepository visits;
+    VisitService(VisitRepository visits, Optional<Clock> clock) { this.visits = visits; }
+}`,
+			want: []Injection{
+				{Type: "VisitRepository", Name: "visits", Via: InjectViaConstructor},
+				{Type: "Clock", Name: "clock", Via: InjectViaConstructor},
+			},
+		},
+		{
+			name: "annotated constructor among several",
+			code: `class Report {
+    Report() {}
+    @org.springframework.beans.factory.annotation.Autowired
+    Report(Formatter formatter) {}
+}`,
+			want: []Injection{{Type: "Formatter", Name: "formatter", Via: InjectViaConstructor}},
+		},
+		{
+			name: "constructor of a plain class",
+			code: `class Money { Money(Currency currency) {} }`,
+		},
+		{
+			name: "lombok required args constructor",
+			code: `@Component
+@RequiredArgsConstructor
+class Scheduler {
+    private static final int LIMIT = 3;
+    private final TaskQueue queue;
+    private final Executor executor = Runnable::run;
+    private Clock clock;
+}`,
+			want: []Injection{{Type: "TaskQueue", Name: "queue", Via: InjectViaConstructor}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tree, root := parseJava(t, tt.code)
+			defer tree.Close()
+
+			jv := newTestJavaVisitor([]byte(tt.code))
+			classNode := findNodeByKind(root, "class_declaration")
+			classBody := jv.translate.TreeChildByKind(classNode, "class_body")
+
+			got := DecodeInjections(jv.injectionPoints(classNode, classBody))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("injectionPoints() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestJavaQueryAnnotationValue(t *testing.T) {
+	tests := []struct {
+		name string
+		code string
+		want string
+		ok   bool
+	}{
+		{
+			name: "single argument",
+			code: `interface Owners { @Query("SELECT o FROM Owner o") List<Owner> all(); }`,
+			want: "SELECT o FROM Owner o",
+			ok:   true,
+		},
+		{
+			name: "value with native query",
+			code: `interface Owners { @Query(value = "SELECT * FROM owners " + "WHERE id = ?1", nativeQuery = true) Owner byId(int id); }`,
+			want: "SELECT * FROM owners WHERE id = ?1",
+			ok:   true,
+		},
+		{
+			name: "other annotation",
+			code: `interface Owners { @Transactional(readOnly = true) List<Owner> all(); }`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tree, root := parseJava(t, tt.code)
+			defer tree.Close()
+
+			jv := newTestJavaVisitor([]byte(tt.code))
+			valueNode := jv.queryAnnotationValue(findNodeByKind(root, "method_declaration"))
+			if valueNode == nil {
+				if tt.ok {
+					t.Fatal("queryAnnotationValue() = nil, want the query")
+				}
+				return
+			}
+			got, ok := jv.translate.StringValue(valueNode)
+			if ok != tt.ok || got != tt.want {
+				t.Errorf("StringValue() = %q, %v, want %q, %v", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
@@ -36,7 +36,7 @@ func (jsv *JavaScriptVisitor) TraverseNode(ctx context.Context, tsNode *tree_sit
 		return jsv.handleFunctionExpression(ctx, tsNode, scopeID)
 	case "method_definition":
 		return jsv.handleMethodDefinition(ctx, tsNode, scopeID)
-	case "class_declaration":
+	case "class_declaration", "abstract_class_declaration":
 		return jsv.handleClassDeclaration(ctx, tsNode, scopeID)
 	case "class_expression":
 		return jsv.handleClassExpression(ctx, tsNode, scopeID)
@@ -104,7 +104,7 @@ func (jsv *JavaScriptVisitor) TraverseNode(ctx context.Context, tsNode *tree_sit
 
 func (jsv *JavaScriptVisitor) handleProgram(ctx context.Context, tsNode *tree_sitter.Node) ast.NodeID {
 	moduleNode := ast.NewNode(
-		jsv.translate.NextNodeID(), ast.NodeTypeModuleScope, jsv.translate.FileID,
+		jsv.translate.NodeIDFor(ast.NodeTypeModuleScope, jsv.translate.ToRange(tsNode)), ast.NodeTypeModuleScope, jsv.translate.FileID,
 		jsv.translate.GetTreeNodeName(tsNode), jsv.translate.ToRange(tsNode), jsv.translate.Version,
 		ast.NodeID(jsv.translate.FileID),
 	)
@@ -155,7 +155,9 @@ func (jsv *JavaScriptVisitor) handleClassDeclaration(ctx context.Context, tsNode
 	if bodyNode != nil {
 		methods = jsv.translate.TreeChildrenByKind(bodyNode, "method_definition")
 	}
-	return jsv.translate.HandleClass(ctx, scopeID, tsNode, "", methods, nil)
+	// TypeScript names classes with a type_identifier rather than an identifier
+	className := jsv.translate.String(jsv.translate.TreeChildByFieldName(tsNode, "name"))
+	return jsv.translate.HandleClass(ctx, scopeID, tsNode, className, methods, nil)
 }
 
 func (jsv *JavaScriptVisitor) handleClassExpression(ctx context.Context, tsNode *tree_sitter.Node, scopeID ast.NodeID) ast.NodeID {
@@ -205,6 +207,9 @@ func (jsv *JavaScriptVisitor) handleNewExpression(ctx context.Context, tsNode *t
 func (jsv *JavaScriptVisitor) handleMemberExpression(ctx context.Context, tsNode *tree_sitter.Node, scopeID ast.NodeID) ast.NodeID {
 	objectNode := jsv.translate.TreeChildByFieldName(tsNode, "object")
 	propertyNode := jsv.translate.TreeChildByFieldName(tsNode, "property")
+	if configID := jsv.translate.HandleEnvAccess(ctx, objectNode, propertyNode, scopeID); configID != ast.InvalidNodeID {
+		return configID
+	}
 
 	var names []*tree_sitter.Node
 	if objectNode != nil {
@@ -224,6 +229,9 @@ func (jsv *JavaScriptVisitor) handleMemberExpression(ctx context.Context, tsNode
 func (jsv *JavaScriptVisitor) handleSubscriptExpression(ctx context.Context, tsNode *tree_sitter.Node, scopeID ast.NodeID) ast.NodeID {
 	objectNode := jsv.translate.TreeChildByFieldName(tsNode, "object")
 	indexNode := jsv.translate.TreeChildByFieldName(tsNode, "index")
+	if configID := jsv.translate.HandleEnvAccess(ctx, objectNode, indexNode, scopeID); configID != ast.InvalidNodeID {
+		return configID
+	}
 
 	var names []*tree_sitter.Node
 	if objectNode != nil {
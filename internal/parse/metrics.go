@@ -0,0 +1,163 @@
+package parse
+
+import (
+	"strings"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+// Size and complexity metrics of a function with a body, set on its node
+const (
+	MetaComplexity = "complexity" // cyclomatic complexity
+	MetaLOC        = "loc"        // lines holding code, not blank or comment-only
+	MetaParams     = "params"     // number of parameters declared
+	MetaNesting    = "nesting"    // deepest nesting of control structures in the body
+)
+
+// FunctionMetrics are the size and complexity metrics of a function
+type FunctionMetrics struct {
+	Complexity int
+	LOC        int
+	Params     int
+	Nesting    int
+}
+
+// MetaData returns the metrics as function node metadata
+func (m FunctionMetrics) MetaData() map[string]any {
+	return map[string]any{
+		MetaComplexity: m.Complexity,
+		MetaLOC:        m.LOC,
+		MetaParams:     m.Params,
+		MetaNesting:    m.Nesting,
+	}
+}
+
+// decisionKinds are the syntax nodes adding a path through a function, in
+// any of the supported languages. Cases and logical operators are counted
+// separately since they share their node kinds with what is not a decision.
+var decisionKinds = map[string]bool{
+	"if_statement": true, "elif_clause": true,
+	"for_statement": true, "enhanced_for_statement": true, "for_in_statement": true, "foreach_statement": true,
+	"while_statement": true, "do_statement": true,
+	"catch_clause": true, "except_clause": true, "except_group_clause": true,
+	"ternary_expression": true, "conditional_expression": true,
+	"for_in_clause": true, "if_clause": true, // comprehensions
+	"expression_case": true, "type_case": true, "communication_case": true, "switch_case": true,
+	"boolean_operator": true,
+}
+
+// caseKinds are the switch cases that add a path unless they are the default
+var caseKinds = map[string]bool{
+	"switch_label": true, "switch_section": true, "switch_expression_arm": true, "case_clause": true,
+}
+
+// nestingKinds are the control structures whose bodies are a level deeper
+var nestingKinds = map[string]bool{
+	"if_statement": true, "for_statement": true, "enhanced_for_statement": true, "for_in_statement": true,
+	"foreach_statement": true, "while_statement": true, "do_statement": true,
+	"switch_statement": true, "switch_expression": true, "expression_switch_statement": true,
+	"type_switch_statement": true, "select_statement": true, "match_statement": true,
+	"try_statement": true, "try_with_resources_statement": true,
+}
+
+// nestedFunctionKinds are functions declared inside a function, which get
+// metrics of their own
+var nestedFunctionKinds = map[string]bool{
+	"lambda_expression": true, "method_declaration": true, "constructor_declaration": true,
+	"local_function_statement": true, "anonymous_method_expression": true,
+	"arrow_function": true, "function_declaration": true, "function_expression": true,
+	"generator_function": true, "generator_function_declaration": true, "method_definition": true,
+	"func_literal": true, "function_definition": true, "lambda": true,
+}
+
+// FunctionMetrics measures a function from its syntax: the complexity and
+// nesting of its body, leaving out nested functions, the lines of its whole
+// declaration holding code and its parameters
+func (t *TranslateFromSyntaxTree) FunctionMetrics(fn *tree_sitter.Node, params []*tree_sitter.Node, body *tree_sitter.Node) FunctionMetrics {
+	m := FunctionMetrics{Complexity: 1}
+	if body != nil {
+		t.measureBody(body, 0, &m)
+	}
+
+	lines := make(map[uint]bool)
+	t.codeLines(fn, lines)
+	m.LOC = len(lines)
+
+	for _, param := range params {
+		m.Params += t.paramCount(param)
+	}
+	return m
+}
+
+// measureBody adds the decisions under node to the complexity, and records
+// the nesting reached below depth
+func (t *TranslateFromSyntaxTree) measureBody(node *tree_sitter.Node, depth int, m *FunctionMetrics) {
+	for i := uint(0); i < node.ChildCount(); i++ {
+		child := node.Child(i)
+		kind := child.Kind()
+		if nestedFunctionKinds[kind] {
+			continue
+		}
+
+		switch {
+		case decisionKinds[kind]:
+			m.Complexity++
+		case caseKinds[kind] && !t.isDefaultCase(child):
+			m.Complexity++
+		case kind == "binary_expression":
+			if op := child.ChildByFieldName("operator"); op != nil && (t.String(op) == "&&" || t.String(op) == "||") {
+				m.Complexity++
+			}
+		}
+
+		childDepth := depth
+		// else if continues the chain of the if rather than nesting in it
+		elseIf := kind == "if_statement" && (node.Kind() == "if_statement" || node.Kind() == "else_clause")
+		if nestingKinds[kind] && !elseIf {
+			childDepth++
+			m.Nesting = max(m.Nesting, childDepth)
+		}
+		t.measureBody(child, childDepth, m)
+	}
+}
+
+// isDefaultCase reports whether a switch case is the default or catch-all
+// one: default:, case _:, or a _ => arm
+func (t *TranslateFromSyntaxTree) isDefaultCase(node *tree_sitter.Node) bool {
+	text := t.String(node)
+	if strings.HasPrefix(text, "default") {
+		return true
+	}
+	text = strings.TrimSpace(strings.TrimPrefix(text, "case"))
+	if !strings.HasPrefix(text, "_") {
+		return false
+	}
+	rest := strings.TrimSpace(text[1:])
+	return strings.HasPrefix(rest, ":") || strings.HasPrefix(rest, "=>")
+}
+
+// codeLines marks the lines of the tokens under node that are not comments
+func (t *TranslateFromSyntaxTree) codeLines(node *tree_sitter.Node, lines map[uint]bool) {
+	if strings.Contains(node.Kind(), "comment") {
+		return
+	}
+	if node.ChildCount() == 0 {
+		for line := node.StartPosition().Row; line <= node.EndPosition().Row; line++ {
+			lines[line] = true
+		}
+		return
+	}
+	for i := uint(0); i < node.ChildCount(); i++ {
+		t.codeLines(node.Child(i), lines)
+	}
+}
+
+// paramCount returns the number of parameters a parameter declaration
+// declares: Go declares several in a, b int
+func (t *TranslateFromSyntaxTree) paramCount(param *tree_sitter.Node) int {
+	if param.Kind() != "parameter_declaration" {
+		return 1
+	}
+	names := len(t.TreeChildrenByKind(param, "identifier"))
+	return max(names, 1)
+}
@@ -0,0 +1,90 @@
+package parse
+
+import "testing"
+
+func TestFunctionMetricsJava(t *testing.T) {
+	code := `class A {
+    int f(int a, int b) {
+        // counts only decisions of f
+
+        if (a > 0 && b > 0) {
+            for (int i = 0; i < a; i++) {
+                if (i % 2 == 0) { log(i); }
+            }
+        } else if (a < 0) {
+            return b > 0 ? 1 : 2;
+        }
+        switch (b) {
+            case 1: return 1;
+            case 2: return 2;
+            default: return 0;
+        }
+        Runnable r = () -> { if (a > 1) { log(a); } };
+        try { r.run(); } catch (Exception e) { log(e); }
+        /* done */
+        return 0;
+    }
+}`
+	tree, root := parseJava(t, code)
+	defer tree.Close()
+
+	jv := newTestJavaVisitor([]byte(code))
+	method := findNodeByKind(root, "method_declaration")
+	params := jv.translate.NamedChildren(jv.translate.TreeChildByFieldName(method, "parameters"))
+	got := jv.translate.FunctionMetrics(method, params, jv.translate.TreeChildByFieldName(method, "body"))
+
+	// if, &&, for, if, else if, ?:, two cases and catch
+	want := FunctionMetrics{Complexity: 10, LOC: 17, Params: 2, Nesting: 3}
+	if got != want {
+		t.Errorf("FunctionMetrics() = %+v, want %+v", got, want)
+	}
+}
+
+func TestFunctionMetricsGo(t *testing.T) {
+	tests := []struct {
+		name string
+		code string
+		want FunctionMetrics
+	}{
+		{
+			name: "straight line",
+			code: "package p\n\nfunc f() {\n\tprintln()\n}\n",
+			want: FunctionMetrics{Complexity: 1, LOC: 3},
+		},
+		{
+			name: "grouped parameters and cases",
+			code: `package p
+
+func f(a, b int, opts ...string) int {
+	switch {
+	case a > b || b < 0:
+		return a
+	case a == b:
+		return 0
+	default:
+		go func() {
+			if a > 0 {
+			}
+		}()
+	}
+	return b
+}
+`,
+			want: FunctionMetrics{Complexity: 4, LOC: 14, Params: 3, Nesting: 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tree, gv := parseGo(t, tt.code)
+			defer tree.Close()
+
+			fn := findNodeByKind(tree.RootNode(), "function_declaration")
+			params := gv.translate.NamedChildren(gv.translate.TreeChildByFieldName(fn, "parameters"))
+			got := gv.translate.FunctionMetrics(fn, params, gv.translate.TreeChildByFieldName(fn, "body"))
+			if got != tt.want {
+				t.Errorf("FunctionMetrics() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
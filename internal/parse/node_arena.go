@@ -0,0 +1,116 @@
+package parse
+
+import (
+	"github.com/armchr/codeapi/internal/model/ast"
+	"github.com/armchr/codeapi/pkg/lsp/base"
+)
+
+// arenaBlockSize is the largest number of nodes allocated at once. A block
+// is freed only when none of its nodes is referenced any more, which for a
+// translated file is once the graph buffers of the file have been flushed.
+const arenaBlockSize = 256
+
+// bytesPerNode estimates the source bytes per graph node, used to size the
+// node index up front. Real code produces a node every 20 to 60 bytes.
+const bytesPerNode = 32
+
+// arenaSlot is an entry of the node index. A slot with an ID but no node
+// reserves an ID handed out for a node built outside the arena.
+type arenaSlot struct {
+	id   ast.NodeID
+	node *ast.Node
+}
+
+// nodeArena allocates and indexes the graph nodes of one file. Nodes are
+// carved out of preallocated blocks rather than allocated one by one, and
+// are indexed in an open-addressed slice whose slots are picked by the low
+// bits of the node ID rather than in a map. Both kinds of ID spread well
+// over those bits: stable IDs are hashes, and sequential IDs end with a
+// sequence number that starts at 1 for every file.
+type nodeArena struct {
+	fileID    int32
+	blockSize int
+	block     []ast.Node
+	slots     []arenaSlot // length is a power of two, at most half full
+	used      int
+}
+
+func newNodeArena(fileID int32, contentSize int) *nodeArena {
+	estimate := contentSize/bytesPerNode + 1
+	size := 16
+	for size < 2*estimate {
+		size *= 2
+	}
+	return &nodeArena{
+		fileID: fileID,
+		// Small files do not pay for a full block
+		blockSize: min(max(estimate, 16), arenaBlockSize),
+		slots:     make([]arenaSlot, size),
+	}
+}
+
+// alloc returns a node with the given fields, owned by the arena
+func (a *nodeArena) alloc(id ast.NodeID, nodeType ast.NodeType, name string, rng base.Range, version int32, scopeID ast.NodeID) *ast.Node {
+	if len(a.block) == 0 {
+		a.block = make([]ast.Node, a.blockSize)
+	}
+	node := &a.block[0]
+	a.block = a.block[1:]
+	*node = ast.Node{
+		ID:       id,
+		NodeType: nodeType,
+		FileID:   a.fileID,
+		Name:     name,
+		Range:    rng,
+		Version:  version,
+		ScopeID:  scopeID,
+	}
+	a.add(id, node)
+	return node
+}
+
+// slot returns the index of the slot holding id, or of the empty slot
+// where it belongs
+func (a *nodeArena) slot(id ast.NodeID) int {
+	mask := len(a.slots) - 1
+	i := int(uint64(id)) & mask
+	for a.slots[i].id != ast.InvalidNodeID && a.slots[i].id != id {
+		i = (i + 1) & mask
+	}
+	return i
+}
+
+// add indexes a node under id, or with a nil node reserves id
+func (a *nodeArena) add(id ast.NodeID, node *ast.Node) {
+	if 2*(a.used+1) > len(a.slots) {
+		a.grow()
+	}
+	i := a.slot(id)
+	if a.slots[i].id == ast.InvalidNodeID {
+		a.used++
+	}
+	a.slots[i] = arenaSlot{id: id, node: node}
+}
+
+func (a *nodeArena) grow() {
+	old := a.slots
+	a.slots = make([]arenaSlot, 2*len(old))
+	for _, s := range old {
+		if s.id != ast.InvalidNodeID {
+			a.slots[a.slot(s.id)] = s
+		}
+	}
+}
+
+// has reports whether id was indexed or reserved
+func (a *nodeArena) has(id ast.NodeID) bool {
+	return a.slots[a.slot(id)].id == id
+}
+
+// get returns the node with the given ID, or nil if it is not in the arena
+func (a *nodeArena) get(id ast.NodeID) *ast.Node {
+	if id == ast.InvalidNodeID {
+		return nil
+	}
+	return a.slots[a.slot(id)].node
+}
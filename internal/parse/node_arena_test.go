@@ -0,0 +1,52 @@
+package parse
+
+import (
+	"testing"
+
+	"github.com/armchr/codeapi/internal/model/ast"
+	"github.com/armchr/codeapi/pkg/lsp/base"
+
+	"go.uber.org/zap"
+)
+
+func TestTranslateFromSyntaxTree_Node(t *testing.T) {
+	translator := NewTranslateFromSyntaxTree(7, 3, nil, []byte("package x"), zap.NewNop())
+
+	// Enough nodes to span several arena blocks, with IDs skipped in between
+	// the way fake variable names consume them
+	var nodes []*ast.Node
+	for i := 0; i < 3*arenaBlockSize; i++ {
+		if i%10 == 0 {
+			translator.NextNodeID()
+		}
+		nodes = append(nodes, translator.NewNode(ast.NodeTypeVariable, "v", base.Range{}, ast.NodeID(42)))
+	}
+
+	for i, node := range nodes {
+		got := translator.Node(node.ID)
+		if got != node {
+			t.Fatalf("node %d: Node(%d) = %p, want %p", i, node.ID, got, node)
+		}
+		if got.FileID != 7 || got.Version != 3 || got.ScopeID != 42 || got.Name != "v" {
+			t.Fatalf("node %d has unexpected fields %+v", i, got)
+		}
+	}
+
+	last := nodes[len(nodes)-1].ID
+	tests := []struct {
+		name string
+		id   ast.NodeID
+	}{
+		{"skipped ID", nodes[0].ID - 1},
+		{"not yet allocated", last + 1},
+		{"other file", ast.NodeID(8)<<32 | ast.NodeID(uint32(nodes[0].ID))},
+		{"invalid", ast.InvalidNodeID},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := translator.Node(tt.id); got != nil {
+				t.Errorf("Node(%d) = %+v, want nil", tt.id, got)
+			}
+		})
+	}
+}
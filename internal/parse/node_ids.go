@@ -0,0 +1,73 @@
+package parse
+
+import (
+	"github.com/armchr/codeapi/internal/model/ast"
+	"github.com/armchr/codeapi/pkg/lsp/base"
+)
+
+// FNV-1a parameters, applied inline so that deriving an ID allocates nothing
+const (
+	fnvOffset64 = 14695981039346656037
+	fnvPrime64  = 1099511628211
+)
+
+func fnvString(h uint64, s string) uint64 {
+	for i := 0; i < len(s); i++ {
+		h ^= uint64(s[i])
+		h *= fnvPrime64
+	}
+	// Separate consecutive strings so that ("ab", "c") and ("a", "bc") differ
+	h ^= 0xff
+	h *= fnvPrime64
+	return h
+}
+
+func fnvUint(h uint64, v uint64) uint64 {
+	for i := 0; i < 8; i++ {
+		h ^= v & 0xff
+		h *= fnvPrime64
+		v >>= 8
+	}
+	return h
+}
+
+// stableNodeIDs derives node IDs from the content and position of nodes
+// rather than from the order files are indexed in, so a file that did not
+// change keeps its node IDs across builds, commits and cleans, whatever
+// FileID its version gets. Versions of a file with the same content, such
+// as a sandbox version next to a committed one, share their nodes; see
+// CodeGraph.DeleteFiles for how purging one keeps those of the other.
+type stableNodeIDs struct {
+	seed uint64
+}
+
+func newStableNodeIDs(repoName, relativePath, fileSHA string) *stableNodeIDs {
+	seed := uint64(fnvOffset64)
+	seed = fnvString(seed, repoName)
+	seed = fnvString(seed, relativePath)
+	seed = fnvString(seed, fileSHA)
+	return &stableNodeIDs{seed: seed}
+}
+
+// next returns the ID of the next node of the given type and range. Nodes
+// of the same type and range, such as a call and the fake variable holding
+// its result, are told apart by the order they are created in: each takes
+// the first ordinal whose ID is not yet taken. IDs are positive and never
+// InvalidNodeID.
+func (s *stableNodeIDs) next(nodeType ast.NodeType, rng base.Range, taken func(ast.NodeID) bool) ast.NodeID {
+	h := fnvUint(s.seed, uint64(nodeType))
+	h = fnvUint(h, uint64(rng.Start.Line))
+	h = fnvUint(h, uint64(rng.Start.Character))
+	h = fnvUint(h, uint64(rng.End.Line))
+	h = fnvUint(h, uint64(rng.End.Character))
+
+	for ordinal := uint64(0); ; ordinal++ {
+		id := ast.NodeID(fnvUint(h, ordinal) & (1<<63 - 1))
+		if id == ast.InvalidNodeID {
+			id = 1
+		}
+		if !taken(id) {
+			return id
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package parse
+
+import (
+	"testing"
+
+	"github.com/armchr/codeapi/internal/model/ast"
+	"github.com/armchr/codeapi/pkg/lsp/base"
+
+	"go.uber.org/zap"
+)
+
+func lineRange(line int) base.Range {
+	return base.Range{Start: base.Position{Line: line}, End: base.Position{Line: line, Character: 10}}
+}
+
+func TestStableNodeIDs(t *testing.T) {
+	build := func(fileID int32, repo, path, sha string) []ast.NodeID {
+		translator := NewTranslateFromSyntaxTree(fileID, 1, nil, nil, zap.NewNop())
+		translator.SetStableNodeIDs(repo, path, sha)
+		var ids []ast.NodeID
+		for _, n := range []struct {
+			nodeType ast.NodeType
+			line     int
+		}{
+			{ast.NodeTypeFunction, 1},
+			{ast.NodeTypeFunctionCall, 2},
+			{ast.NodeTypeVariable, 2}, // same range as the call
+			{ast.NodeTypeVariable, 2}, // same type and range again
+			{ast.NodeTypeFunctionCall, 3},
+		} {
+			ids = append(ids, translator.AllocNode(n.nodeType, "n", lineRange(n.line), ast.InvalidNodeID).ID)
+		}
+		return ids
+	}
+
+	first := build(3, "shop", "orders/service.go", "abc")
+	seen := make(map[ast.NodeID]bool)
+	for _, id := range first {
+		if id <= ast.InvalidNodeID || seen[id] {
+			t.Fatalf("IDs %v are not distinct positive IDs", first)
+		}
+		seen[id] = true
+	}
+
+	// A rebuild keeps every ID, also under the new FileID a version gets
+	// at another commit or after a clean
+	for _, fileID := range []int32{3, 17} {
+		if rebuilt := build(fileID, "shop", "orders/service.go", "abc"); !equalIDs(rebuilt, first) {
+			t.Errorf("rebuild as file %d changed IDs: %v, want %v", fileID, rebuilt, first)
+		}
+	}
+
+	for _, tt := range []struct {
+		name            string
+		repo, path, sha string
+	}{
+		{"changed content", "shop", "orders/service.go", "abd"},
+		{"moved file", "shop", "orders/service2.go", "abc"},
+		{"other repository", "shop2", "orders/service.go", "abc"},
+		{"path and repo boundary", "shoporders/", "service.go", "abc"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			for i, id := range build(3, tt.repo, tt.path, tt.sha) {
+				if id == first[i] {
+					t.Errorf("node %d kept ID %d", i, id)
+				}
+			}
+		})
+	}
+}
+
+func equalIDs(a, b []ast.NodeID) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestTranslateFromSyntaxTree_NodeWithStableIDs(t *testing.T) {
+	translator := NewTranslateFromSyntaxTree(7, 1, nil, []byte("package x"), zap.NewNop())
+	translator.SetStableNodeIDs("repo", "x.go", "sha")
+
+	var nodes []*ast.Node
+	for i := 0; i < 2*arenaBlockSize; i++ {
+		nodes = append(nodes, translator.NewNode(ast.NodeTypeVariable, "v", lineRange(i), ast.NodeID(42)))
+	}
+	for i, node := range nodes {
+		if got := translator.Node(node.ID); got != node {
+			t.Fatalf("node %d: Node(%d) = %p, want %p", i, node.ID, got, node)
+		}
+	}
+	if got := translator.Node(ast.NodeID(7)<<32 | 1); got != nil {
+		t.Errorf("sequential ID resolved to %+v", got)
+	}
+
+	// IDs of nodes built outside the arena are reserved, not indexed
+	first := translator.NodeIDFor(ast.NodeTypeClass, base.Range{})
+	second := translator.NodeIDFor(ast.NodeTypeClass, base.Range{})
+	third := translator.AllocNode(ast.NodeTypeClass, "c", base.Range{}, ast.InvalidNodeID)
+	if first == second || third.ID == first || third.ID == second {
+		t.Errorf("IDs %d, %d and %d of the same type and range are not distinct", first, second, third.ID)
+	}
+	if got := translator.Node(first); got != nil {
+		t.Errorf("reserved ID resolved to %+v", got)
+	}
+	if got := translator.Node(third.ID); got != third {
+		t.Errorf("Node(%d) = %p, want %p", third.ID, got, third)
+	}
+}
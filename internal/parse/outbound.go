@@ -0,0 +1,156 @@
+package parse
+
+import (
+	"net/url"
+	"strings"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+// MetaOutbound is the protocol of a call leaving the process, set on its
+// FunctionCall node, and MetaOutboundTarget the URL it is made to when the
+// call is given one as a string literal
+const (
+	MetaOutbound       = "outbound"
+	MetaOutboundTarget = "outbound_target"
+)
+
+// Protocols of outbound calls
+const (
+	OutboundHTTP = "http" // an HTTP request
+	OutboundGRPC = "grpc" // a call on a generated gRPC stub
+)
+
+// Kinds of the destination of an outbound call
+const (
+	OutboundService  = "service"  // another service of the same deployment
+	OutboundExternal = "external" // a third-party or unknown host
+)
+
+// outboundCallees are the library functions making HTTP requests, by the
+// name they are usually called with
+var outboundCallees = map[string]string{
+	// Go
+	"http.Get": OutboundHTTP, "http.Post": OutboundHTTP, "http.Head": OutboundHTTP, "http.PostForm": OutboundHTTP,
+	"http.NewRequest": OutboundHTTP, "http.NewRequestWithContext": OutboundHTTP,
+	// Python
+	"urlopen": OutboundHTTP, "urllib.request.urlopen": OutboundHTTP,
+	// JavaScript
+	"fetch": OutboundHTTP, "axios": OutboundHTTP,
+}
+
+// restTemplateMethods are the Spring RestTemplate methods, named distinctly
+// enough to be recognised whatever they are called on
+var restTemplateMethods = map[string]bool{
+	"getForObject": true, "getForEntity": true, "postForObject": true, "postForEntity": true,
+	"postForLocation": true, "patchForObject": true,
+}
+
+// httpClientMethods make a request when called on something named like an
+// HTTP client: requests, httpx, axios, RestTemplate, WebClient, Java's and
+// .NET's HttpClient, Angular's HttpClient or Go's http.Client
+var httpClientMethods = map[string]bool{
+	"get": true, "post": true, "put": true, "patch": true, "delete": true, "head": true,
+	"options": true, "request": true, "exchange": true, "method": true, "send": true, "sendAsync": true,
+	"Get": true, "Post": true, "Head": true, "PostForm": true, "Do": true,
+	"GetAsync": true, "PostAsync": true, "PutAsync": true, "PatchAsync": true, "DeleteAsync": true,
+	"SendAsync": true, "GetStringAsync": true, "GetStreamAsync": true, "GetByteArrayAsync": true,
+	"GetFromJsonAsync": true, "PostAsJsonAsync": true, "PutAsJsonAsync": true,
+}
+
+// outboundCategory returns the protocol of an outbound call, given by the
+// source text of its callee, or "" if it is not one
+func outboundCategory(callee string) string {
+	callee = strings.TrimSpace(strings.TrimPrefix(callee, "new "))
+	if category, ok := outboundCallees[callee]; ok {
+		return category
+	}
+	receiver, name := splitCallee(callee)
+	if restTemplateMethods[name] || name == "newCall" {
+		// OkHttp's client.newCall(request)
+		return OutboundHTTP
+	}
+
+	lower := strings.ToLower(receiver)
+	switch {
+	case strings.HasSuffix(lower, "stub"):
+		return OutboundGRPC
+	case !httpClientMethods[name]:
+		return ""
+	case lower == "requests" || lower == "httpx" || lower == "axios":
+		return OutboundHTTP
+	}
+	for _, word := range []string{"http", "resttemplate", "restclient", "webclient"} {
+		if strings.Contains(lower, word) {
+			return OutboundHTTP
+		}
+	}
+	return ""
+}
+
+// outboundCall returns the protocol of a call leaving the process and the
+// URL it is made to, if one of its arguments starts with a literal URL
+func (t *TranslateFromSyntaxTree) outboundCall(fnName string, args []*tree_sitter.Node) (category, target string) {
+	callee := fnName
+	if len(args) > 0 {
+		callee = t.calleeText(args[0])
+	}
+	if category = outboundCategory(callee); category == "" {
+		return "", ""
+	}
+	for _, arg := range args {
+		if prefix, ok := t.literalPrefix(arg); ok && isURL(prefix) {
+			return category, prefix
+		}
+	}
+	return category, ""
+}
+
+// literalPrefix returns the string an expression starts with: a literal, or
+// the literal leading a concatenation like "http://billing/invoices/" + id
+func (t *TranslateFromSyntaxTree) literalPrefix(node *tree_sitter.Node) (string, bool) {
+	if s, ok := t.StringValue(node); ok {
+		return s, true
+	}
+	if node.Kind() == "binary_expression" || node.Kind() == "binary_operator" {
+		if op := node.ChildByFieldName("operator"); op != nil && t.String(op) == "+" && node.NamedChildCount() > 0 {
+			return t.literalPrefix(node.NamedChild(0))
+		}
+	}
+	return "", false
+}
+
+// isURL reports whether a string is an absolute URL or a path
+func isURL(s string) bool {
+	return strings.Contains(s, "://") || strings.HasPrefix(s, "/")
+}
+
+// internalHostSuffixes end the names of hosts inside a deployment: Kubernetes
+// services, Consul and mDNS names
+var internalHostSuffixes = []string{".svc", ".cluster.local", ".internal", ".local", ".consul"}
+
+// ClassifyOutbound tells whether an outbound call recorded with the given
+// protocol and target goes to another service of the same deployment or to
+// an external API, and returns the host it goes to. gRPC stubs and hosts
+// without a domain, like http://inventory/items, are services; calls to other
+// hosts, or whose URL is not known, are external.
+func ClassifyOutbound(category, target string) (kind, host string) {
+	if u, err := url.Parse(target); err == nil {
+		host = u.Hostname()
+	}
+	if category == OutboundGRPC {
+		return OutboundService, host
+	}
+	if host == "" {
+		return OutboundExternal, host
+	}
+	if !strings.Contains(host, ".") {
+		return OutboundService, host
+	}
+	for _, suffix := range internalHostSuffixes {
+		if strings.HasSuffix(host, suffix) {
+			return OutboundService, host
+		}
+	}
+	return OutboundExternal, host
+}
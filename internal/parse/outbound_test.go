@@ -0,0 +1,92 @@
+package parse
+
+import "testing"
+
+func TestOutboundCategory(t *testing.T) {
+	tests := []struct {
+		callee string
+		want   string
+	}{
+		{"restTemplate.getForObject", OutboundHTTP},
+		{"restTemplate.exchange", OutboundHTTP},
+		{"webClient.get", OutboundHTTP},
+		{"httpClient.send", OutboundHTTP},
+		{"client.newCall", OutboundHTTP},
+		{"http.Get", OutboundHTTP},
+		{"http.DefaultClient.Do", OutboundHTTP},
+		{"requests.post", OutboundHTTP},
+		{"httpx.get", OutboundHTTP},
+		{"fetch", OutboundHTTP},
+		{"axios.get", OutboundHTTP},
+		{"this.http.get", OutboundHTTP},
+		{"_httpClient.GetAsync", OutboundHTTP},
+		{"inventoryStub.getItem", OutboundGRPC},
+		{"self.stub.GetItem", OutboundGRPC},
+		{"cache.get", ""},
+		{"request.args.get", ""},
+		{"db.Get", ""},
+		{"http.HandleFunc", ""},
+	}
+
+	for _, tt := range tests {
+		if got := outboundCategory(tt.callee); got != tt.want {
+			t.Errorf("outboundCategory(%q) = %q, want %q", tt.callee, got, tt.want)
+		}
+	}
+}
+
+func TestOutboundCall(t *testing.T) {
+	tests := []struct {
+		name         string
+		call         string
+		wantCategory string
+		wantTarget   string
+	}{
+		{name: "literal url", call: `restTemplate.getForObject("https://api.stripe.com/v1/charges", String.class)`, wantCategory: OutboundHTTP, wantTarget: "https://api.stripe.com/v1/charges"},
+		{name: "concatenated url", call: `restTemplate.getForObject("http://inventory/items/" + id, Item.class)`, wantCategory: OutboundHTTP, wantTarget: "http://inventory/items/"},
+		{name: "url in a variable", call: `restTemplate.getForObject(url, Item.class)`, wantCategory: OutboundHTTP},
+		{name: "grpc stub", call: `inventoryStub.getItem(request)`, wantCategory: OutboundGRPC},
+		{name: "not outbound", call: `items.get("key")`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code := "class A { void f() { " + tt.call + "; } }"
+			tree, root := parseJava(t, code)
+			defer tree.Close()
+
+			jv := newTestJavaVisitor([]byte(code))
+			call := findNodeByKind(root, "method_invocation")
+			argList := jv.translate.TreeChildByFieldName(call, "arguments")
+			name := jv.translate.String(jv.translate.TreeChildByFieldName(call, "name"))
+
+			category, target := jv.translate.outboundCall(name, jv.translate.NamedChildren(argList))
+			if category != tt.wantCategory || target != tt.wantTarget {
+				t.Errorf("outboundCall(%s) = %q, %q, want %q, %q", tt.call, category, target, tt.wantCategory, tt.wantTarget)
+			}
+		})
+	}
+}
+
+func TestClassifyOutbound(t *testing.T) {
+	tests := []struct {
+		category string
+		target   string
+		wantKind string
+		wantHost string
+	}{
+		{OutboundHTTP, "http://inventory/items/", OutboundService, "inventory"},
+		{OutboundHTTP, "http://billing.payments.svc:8080/invoices", OutboundService, "billing.payments.svc"},
+		{OutboundHTTP, "https://api.stripe.com/v1/charges", OutboundExternal, "api.stripe.com"},
+		{OutboundHTTP, "/v1/charges", OutboundExternal, ""},
+		{OutboundHTTP, "", OutboundExternal, ""},
+		{OutboundGRPC, "", OutboundService, ""},
+	}
+
+	for _, tt := range tests {
+		kind, host := ClassifyOutbound(tt.category, tt.target)
+		if kind != tt.wantKind || host != tt.wantHost {
+			t.Errorf("ClassifyOutbound(%q, %q) = %q, %q, want %q, %q", tt.category, tt.target, kind, host, tt.wantKind, tt.wantHost)
+		}
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"github.com/armchr/codeapi/internal/config"
 	"github.com/armchr/codeapi/internal/model/ast"
 	"github.com/armchr/codeapi/internal/service/codegraph"
+	"github.com/armchr/codeapi/internal/util"
 	"context"
 	"fmt"
 	"io"
@@ -34,7 +35,6 @@ const (
 )
 
 type FileParser struct {
-	parser    *tree_sitter.Parser
 	CodeGraph *codegraph.CodeGraph
 	logger    *zap.Logger
 	Config    *config.Config
@@ -80,7 +80,6 @@ func NewLanguageTypeFromString(lang string) LanguageType {
 
 func NewFileParser(logger *zap.Logger, cg *codegraph.CodeGraph, cfg *config.Config) *FileParser {
 	return &FileParser{
-		parser:    tree_sitter.NewParser(),
 		CodeGraph: cg,
 		logger:    logger,
 		Config:    cfg,
@@ -107,6 +106,15 @@ func (fp *FileParser) DetectLanguage(filePath string) LanguageType {
 	}
 }
 
+// DetectLanguageWithContent detects the language from the file extension and,
+// for files without a recognised extension, from the shebang line in content.
+func (fp *FileParser) DetectLanguageWithContent(filePath string, content []byte) LanguageType {
+	if lt := fp.DetectLanguage(filePath); lt != Unknown {
+		return lt
+	}
+	return NewLanguageTypeFromString(util.DetectLanguage(filePath, content))
+}
+
 func (fp *FileParser) GetLanguageParser(langType LanguageType) (*tree_sitter.Language, error) {
 	switch langType {
 	case Go:
@@ -146,7 +154,7 @@ func (fp *FileParser) GetLanguageVisitor(langType LanguageType, ts *TranslateFro
 		return NewJavaVisitor(fp.logger, ts), nil
 
 	case JavaScript, TypeScript:
-		return NewPrintVisitor(ts), nil
+		return NewJavaScriptVisitor(fp.logger, ts), nil
 
 	case CSharp:
 		return NewCSharpVisitor(fp.logger, ts), nil
@@ -171,12 +179,16 @@ func (fp *FileParser) CreateTranslatorWithContent(ctx context.Context, filePath
 		return nil, nil, fmt.Errorf("failed to get language parser: %w", err)
 	}
 
-	err = fp.parser.SetLanguage(language)
+	// Parsers are pooled rather than owned: a FileParser is created per file
+	parser := util.AcquireParser()
+	defer util.ReleaseParser(parser)
+
+	err = parser.SetLanguage(language)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to set parser language: %w", err)
 	}
 
-	tree := fp.parser.Parse(content, nil)
+	tree := parser.Parse(content, nil)
 	if tree == nil {
 		return nil, nil, fmt.Errorf("failed to parse file: %s", filePath)
 	}
@@ -219,7 +231,10 @@ func (fp *FileParser) ParseAndTraverse(ctx context.Context, repo *config.Reposit
 */
 
 func (fp *FileParser) ParseAndTraverseWithContent(ctx context.Context, repo *config.Repository, info os.FileInfo, filePath string, fileID int32, version int32, content []byte) error {
-	languageType := fp.DetectLanguage(filePath)
+	if IsConfigFile(filePath) {
+		return fp.parseConfigFile(ctx, repo, info, filePath, fileID, version, content)
+	}
+	languageType := fp.DetectLanguageWithContent(filePath, content)
 	if languageType == Unknown {
 		return fmt.Errorf("unsupported file type for file: %s", filePath)
 	}
@@ -228,6 +243,13 @@ func (fp *FileParser) ParseAndTraverseWithContent(ctx context.Context, repo *con
 		return err
 	}
 	defer tree.Close()
+	translator.SetStableNodeIDs(repo.Name, filepath.ToSlash(fp.relativePath(repo, filePath)), util.CalculateFileSHA256(content))
+
+	detectors, err := NewFlagDetectors(fp.Config.FeatureFlags)
+	if err != nil {
+		fp.logger.Warn("Feature flag detection disabled", zap.String("path", filePath), zap.Error(err))
+	}
+	translator.SetFlagDetectors(detectors)
 
 	rootNode := tree.RootNode()
 	if rootNode == nil {
@@ -257,7 +279,10 @@ func (fp *FileParser) ParseAndTraverseWithContent(ctx context.Context, repo *con
 
 	fp.CodeGraph.CreateFileScope(ctx, fileScope)
 
-	rootNodeId := visitor.TraverseNode(ctx, rootNode, fileScope.ID)
+	rootNodeId, err := translator.Traverse(ctx, filePath, rootNode, fileScope.ID)
+	if err != nil {
+		return err
+	}
 	if rootNodeId != ast.InvalidNodeID {
 		fp.CodeGraph.CreateContainsRelation(ctx, fileScope.ID, rootNodeId, fileID)
 	}
@@ -269,7 +294,7 @@ func (fp *FileParser) ParseAndTraverseWithContent(ctx context.Context, repo *con
 	return nil
 }
 
-func (fp *FileParser) ShouldSkipFile(ctx context.Context, repo *config.Repository, info os.FileInfo, filePath string) bool {
+func (fp *FileParser) ShouldSkipFile(ctx context.Context, repo *config.Repository, info os.FileInfo, filePath string, content []byte) bool {
 	// Skip common directories and files that shouldn't be parsed
 	skipPaths := []string{
 		".git", "node_modules", ".vscode", ".idea", "vendor", "target",
@@ -287,14 +312,16 @@ func (fp *FileParser) ShouldSkipFile(ctx context.Context, repo *config.Repositor
 		return true
 	}
 
-	languageType := fp.DetectLanguage(filePath)
+	// Configuration files are indexed whatever the languages of the repository
+	isConfig := IsConfigFile(filePath)
+	languageType := fp.DetectLanguageWithContent(filePath, content)
 
-	if languageType == Unknown {
+	if languageType == Unknown && !isConfig {
 		fp.logger.Debug("Skipping unsupported file", zap.String("path", filePath))
 		return true
 	}
 
-	if !fp.isAllowedFileExtensionsInRepo(repo, languageType) {
+	if !isConfig && !fp.isAllowedFileExtensionsInRepo(repo, languageType) {
 		fp.logger.Debug("Skipping file due to unsupported language for repository", zap.String("path", filePath), zap.String("repo_language", repo.Language))
 		return true
 	}
@@ -322,6 +349,10 @@ func (fp *FileParser) ShouldSkipFile(ctx context.Context, repo *config.Repositor
 }
 
 func (fp *FileParser) isAllowedFileExtensionsInRepo(repo *config.Repository, languageType LanguageType) bool {
+	if repo.IsMultiLanguage() {
+		return repo.IncludesLanguage(languageType.String())
+	}
+
 	switch repo.Language {
 	case "python":
 		return languageType == Python
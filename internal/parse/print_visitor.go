@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
 
 	tree_sitter "github.com/tree-sitter/go-tree-sitter"
 )
@@ -14,15 +15,14 @@ type PrintVisitor struct {
 	translate *TranslateFromSyntaxTree
 	//logger    *zap.Logger
 	indent  int
-	content string
+	content strings.Builder
 }
 
 func NewPrintVisitor(ts *TranslateFromSyntaxTree) *PrintVisitor {
 	return &PrintVisitor{
 		translate: ts,
 		//logger:    logger,
-		indent:  0,
-		content: "",
+		indent: 0,
 	}
 }
 
@@ -33,7 +33,7 @@ func PrintSyntaxTree(ctx context.Context, tsNode *tree_sitter.Node, content []by
 	pv := NewPrintVisitor(ts)
 	ts.Visitor = pv
 	pv.TraverseNode(ctx, tsNode, ast.InvalidNodeID)
-	return pv.content
+	return pv.content.String()
 }
 
 func (pv *PrintVisitor) TraverseNode(ctx context.Context, tsNode *tree_sitter.Node, scopeID ast.NodeID) ast.NodeID {
@@ -41,13 +41,12 @@ func (pv *PrintVisitor) TraverseNode(ctx context.Context, tsNode *tree_sitter.No
 		return ast.InvalidNodeID
 	}
 
-	for i := 0; i < pv.indent; i++ {
-		pv.content += "  "
-	}
+	// Built incrementally; concatenating strings made large files quadratic
+	pv.content.WriteString(strings.Repeat("  ", pv.indent))
 
 	rangeStr := fmt.Sprintf("[%d:%d - %d:%d]", tsNode.StartPosition().Row, tsNode.StartPosition().Column, tsNode.EndPosition().Row, tsNode.EndPosition().Column)
 
-	pv.content += fmt.Sprintf("kind=%s, named=%s, name=%s, range=%s\n",
+	fmt.Fprintf(&pv.content, "kind=%s, named=%s, name=%s, range=%s\n",
 		tsNode.Kind(), strconv.FormatBool(tsNode.IsNamed()), pv.translate.GetTreeNodeName(tsNode), rangeStr,
 	)
 
@@ -69,7 +68,7 @@ func (pv *PrintVisitor) TraverseNode(ctx context.Context, tsNode *tree_sitter.No
 }
 
 func (pv *PrintVisitor) WriteToFile(filePath string, prefix string) error {
-	return os.WriteFile(filePath, []byte(prefix+"\n\n"+pv.content), 0644)
+	return os.WriteFile(filePath, []byte(prefix+"\n\n"+pv.content.String()), 0644)
 }
 
 // HasSpecialName returns false for PrintVisitor - no special naming conventions
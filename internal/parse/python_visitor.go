@@ -3,11 +3,23 @@ package parse
 import (
 	"github.com/armchr/codeapi/internal/model/ast"
 	"context"
+	"strings"
+	"unicode"
 
 	tree_sitter "github.com/tree-sitter/go-tree-sitter"
 	"go.uber.org/zap"
 )
 
+// Metadata of Python import nodes, which post-processing follows to the
+// module defining an imported name
+const (
+	MetaImportModule = "module" // a.b, or .b relative to the importing file
+	MetaImportMember = "member" // name imported from the module; empty for import a.b
+)
+
+// PythonWildcardImport names the import node of from a.b import *
+const PythonWildcardImport = "*"
+
 type PythonVisitor struct {
 	translate *TranslateFromSyntaxTree
 	logger    *zap.Logger
@@ -40,6 +52,8 @@ func (pv *PythonVisitor) TraverseNode(ctx context.Context, tsNode *tree_sitter.N
 		return pv.handleCall(ctx, tsNode, scopeID)
 	case "attribute":
 		return pv.handleAttribute(ctx, tsNode, scopeID)
+	case "subscript":
+		return pv.handleSubscript(ctx, tsNode, scopeID)
 	case "identifier":
 		return pv.translate.HandleIdentifier(ctx, tsNode, scopeID)
 	case "if_statement":
@@ -48,8 +62,16 @@ func (pv *PythonVisitor) TraverseNode(ctx context.Context, tsNode *tree_sitter.N
 		return pv.handleForStatement(ctx, tsNode, scopeID)
 	case "while_statement":
 		return pv.handleWhileStatement(ctx, tsNode, scopeID)
+	case "match_statement":
+		return pv.handleMatchStatement(ctx, tsNode, scopeID)
+	case "try_statement":
+		return pv.handleTryStatement(ctx, tsNode, scopeID)
+	case "raise_statement":
+		return pv.handleRaiseStatement(ctx, tsNode, scopeID)
 	case "assignment":
 		return pv.handleAssignment(ctx, tsNode, scopeID)
+	case "import_statement", "import_from_statement":
+		return pv.handleImport(ctx, tsNode, scopeID)
 	/*
 
 		case "expression_statement":
@@ -72,7 +94,7 @@ func (pv *PythonVisitor) TraverseNode(ctx context.Context, tsNode *tree_sitter.N
 func (pv *PythonVisitor) handleModule(ctx context.Context, tsNode *tree_sitter.Node) ast.NodeID {
 	// Handle module-level constructs if needed
 	moduleNode := ast.NewNode(
-		pv.translate.NextNodeID(), ast.NodeTypeModuleScope, pv.translate.FileID,
+		pv.translate.NodeIDFor(ast.NodeTypeModuleScope, pv.translate.ToRange(tsNode)), ast.NodeTypeModuleScope, pv.translate.FileID,
 		pv.translate.GetTreeNodeName(tsNode), pv.translate.ToRange(tsNode), pv.translate.Version,
 		ast.NodeID(pv.translate.FileID),
 	)
@@ -118,7 +140,14 @@ func (pv *PythonVisitor) handleCall(ctx context.Context, tsNode *tree_sitter.Nod
 		args = pv.translate.NamedChildren(argList)
 	}
 	fnNameNodeID := pv.translate.HandleRhsWithFakeVariable(ctx, "__fn__", tsNode.Child(0), scopeID, nil)
-	return pv.translate.HandleCall(ctx, fnNameNodeID, args, scopeID, pv.translate.ToRange(tsNode))
+
+	// A call through an attribute is named after the attribute alone; keep
+	// the dotted source to resolve mod.func against the imports
+	var metadata map[string]any
+	if fn := tsNode.Child(0); fn != nil && fn.Kind() == "attribute" {
+		metadata = map[string]any{MetaSelector: pv.translate.String(fn)}
+	}
+	return pv.translate.HandleCallWithMetadata(ctx, fnNameNodeID, args, scopeID, pv.translate.ToRange(tsNode), metadata)
 }
 
 func (pv *PythonVisitor) handleAttribute(ctx context.Context, tsNode *tree_sitter.Node, scopeID ast.NodeID) ast.NodeID {
@@ -138,6 +167,87 @@ func (pv *PythonVisitor) handleAttribute(ctx context.Context, tsNode *tree_sitte
 	return resolvedNodeId
 }
 
+// handleSubscript records os.environ["KEY"] as a read of the environment
+// variable; other subscripts are traversed as usual
+func (pv *PythonVisitor) handleSubscript(ctx context.Context, tsNode *tree_sitter.Node, scopeID ast.NodeID) ast.NodeID {
+	valueNode := pv.translate.TreeChildByFieldName(tsNode, "value")
+	keyNode := pv.translate.TreeChildByFieldName(tsNode, "subscript")
+	if configID := pv.translate.HandleEnvAccess(ctx, valueNode, keyNode, scopeID); configID != ast.InvalidNodeID {
+		return configID
+	}
+	pv.translate.TraverseChildren(ctx, tsNode, scopeID)
+	return ast.InvalidNodeID
+}
+
+// handleImport creates an import node for each name bound by an import
+// statement: d for import c as d, a for import a.b, Y for from m import X as Y.
+// from m import * gets a single node named *.
+func (pv *PythonVisitor) handleImport(ctx context.Context, tsNode *tree_sitter.Node, scopeID ast.NodeID) ast.NodeID {
+	module := ""
+	if moduleNode := pv.translate.TreeChildByFieldName(tsNode, "module_name"); moduleNode != nil {
+		module = pv.translate.String(moduleNode)
+	}
+	isFrom := tsNode.Kind() == "import_from_statement"
+
+	if isFrom && pv.translate.TreeChildByKind(tsNode, "wildcard_import") != nil {
+		pv.createImport(ctx, tsNode, scopeID, PythonWildcardImport, module, PythonWildcardImport)
+		return ast.InvalidNodeID
+	}
+
+	for i := uint(0); i < tsNode.ChildCount(); i++ {
+		if tsNode.FieldNameForChild(uint32(i)) != "name" {
+			continue
+		}
+		child := tsNode.Child(i)
+		nameNode, aliasNode := child, (*tree_sitter.Node)(nil)
+		if child.Kind() == "aliased_import" {
+			nameNode = pv.translate.TreeChildByFieldName(child, "name")
+			aliasNode = pv.translate.TreeChildByFieldName(child, "alias")
+		}
+		if nameNode == nil {
+			continue
+		}
+		name := pv.translate.String(nameNode)
+
+		if isFrom {
+			bound := name
+			if aliasNode != nil {
+				bound = pv.translate.String(aliasNode)
+			}
+			pv.createImport(ctx, child, scopeID, bound, module, name)
+			continue
+		}
+
+		// import a.b binds a; import a.b as ab binds ab to a.b
+		bound, _, _ := strings.Cut(name, ".")
+		if aliasNode != nil {
+			bound = pv.translate.String(aliasNode)
+		}
+		pv.createImport(ctx, child, scopeID, bound, name, "")
+	}
+	return ast.InvalidNodeID
+}
+
+func (pv *PythonVisitor) createImport(ctx context.Context, tsNode *tree_sitter.Node, scopeID ast.NodeID, name, module, member string) {
+	importPath := module
+	if member != "" {
+		importPath = strings.TrimSuffix(module, ".") + "." + member
+	}
+
+	importNode := pv.translate.AllocNode(ast.NodeTypeImport, name, pv.translate.ToRange(tsNode), scopeID)
+	importNode.MetaData = map[string]any{
+		"importPath":     importPath,
+		MetaImportModule: module,
+	}
+	if member != "" {
+		importNode.MetaData[MetaImportMember] = member
+	}
+	pv.translate.CodeGraph.CreateImport(ctx, importNode)
+	if name != PythonWildcardImport {
+		pv.translate.CurrentScope.AddSymbol(NewSymbol(importNode))
+	}
+}
+
 func (pv *PythonVisitor) handleIfStatement(ctx context.Context, tsNode *tree_sitter.Node, scopeID ast.NodeID) ast.NodeID {
 	conditionNode := tsNode.Child(1) // if is 0, condition is 1
 	branch := tsNode.Child(3)        // body is 3
@@ -160,6 +270,112 @@ func (pv *PythonVisitor) handleIfStatement(ctx context.Context, tsNode *tree_sit
 	return pv.translate.HandleConditional(ctx, tsNode, conditions, branches, scopeID)
 }
 
+// handleMatchStatement treats match like a switch: the subject and the
+// pattern of every case are conditions, the case bodies its branches
+func (pv *PythonVisitor) handleMatchStatement(ctx context.Context, tsNode *tree_sitter.Node, scopeID ast.NodeID) ast.NodeID {
+	var conditions []*tree_sitter.Node
+	var branches []*tree_sitter.Node
+
+	if subject := pv.translate.TreeChildByFieldName(tsNode, "subject"); subject != nil {
+		conditions = append(conditions, subject)
+	}
+	if body := pv.translate.TreeChildByFieldName(tsNode, "body"); body != nil {
+		for _, clause := range pv.translate.TreeChildrenByKind(body, "case_clause") {
+			if pattern := pv.translate.TreeChildByKind(clause, "case_pattern"); pattern != nil {
+				conditions = append(conditions, pattern)
+			}
+			if consequence := pv.translate.TreeChildByFieldName(clause, "consequence"); consequence != nil {
+				branches = append(branches, consequence)
+			}
+		}
+	}
+	return pv.translate.HandleConditional(ctx, tsNode, conditions, branches, scopeID)
+}
+
+func (pv *PythonVisitor) handleTryStatement(ctx context.Context, tsNode *tree_sitter.Node, scopeID ast.NodeID) ast.NodeID {
+	var handlers []CatchHandler
+	var finallyBody *tree_sitter.Node
+	for _, child := range pv.translate.NamedChildren(tsNode) {
+		switch child.Kind() {
+		case "except_clause":
+			handlers = append(handlers, CatchHandler{
+				Types: pv.exceptTypes(pv.translate.TreeChildByFieldName(child, "value")),
+				Body:  pv.translate.TreeChildByKind(child, "block"),
+			})
+		case "except_group_clause":
+			handler := CatchHandler{Body: pv.translate.TreeChildByKind(child, "block")}
+			if children := pv.translate.NamedChildren(child); len(children) > 1 {
+				handler.Types = pv.exceptTypes(children[0])
+			}
+			handlers = append(handlers, handler)
+		case "finally_clause":
+			finallyBody = pv.translate.TreeChildByKind(child, "block")
+		}
+	}
+
+	bodyNode := pv.translate.TreeChildByFieldName(tsNode, "body")
+	tryID := pv.translate.HandleTryCatch(ctx, tsNode, bodyNode, handlers, finallyBody, scopeID)
+
+	// The else block runs only when the body raised nothing
+	if elseClause := pv.translate.TreeChildByKind(tsNode, "else_clause"); elseClause != nil {
+		elseID := pv.TraverseNode(ctx, pv.translate.TreeChildByFieldName(elseClause, "body"), tryID)
+		if elseID != ast.InvalidNodeID {
+			pv.translate.CreateContainsRelation(ctx, tryID, elseID, pv.translate.FileID)
+		}
+	}
+	return tryID
+}
+
+// exceptTypes returns the exception types named by the value of an except
+// clause: a single name, a dotted name or a tuple of them
+func (pv *PythonVisitor) exceptTypes(value *tree_sitter.Node) []string {
+	if value == nil {
+		return nil
+	}
+	if value.Kind() == "as_pattern" {
+		value = value.NamedChild(0)
+	}
+	if value.Kind() == "tuple" || value.Kind() == "parenthesized_expression" {
+		var types []string
+		for _, element := range pv.translate.NamedChildren(value) {
+			types = append(types, pv.exceptTypes(element)...)
+		}
+		return types
+	}
+	return []string{pv.translate.String(value)}
+}
+
+func (pv *PythonVisitor) handleRaiseStatement(ctx context.Context, tsNode *tree_sitter.Node, scopeID ast.NodeID) ast.NodeID {
+	// A bare raise re-raises the exception being handled. The exception a
+	// raise ... from names as the cause is not the one raised.
+	var thrown *tree_sitter.Node
+	for i := uint(0); i < tsNode.ChildCount(); i++ {
+		if child := tsNode.Child(i); child.IsNamed() && tsNode.FieldNameForChild(uint32(i)) != "cause" {
+			thrown = child
+			break
+		}
+	}
+	if thrown == nil {
+		return ast.InvalidNodeID
+	}
+
+	thrownType := ""
+	switch thrown.Kind() {
+	case "call":
+		if fn := pv.translate.TreeChildByFieldName(thrown, "function"); fn != nil {
+			thrownType = pv.translate.String(fn)
+		}
+	case "identifier", "attribute":
+		// raise err re-raises a variable; only CapWords names are classes
+		name := pv.translate.String(thrown)
+		last := name[strings.LastIndex(name, ".")+1:]
+		if last != "" && unicode.IsUpper(rune(last[0])) {
+			thrownType = name
+		}
+	}
+	return pv.translate.HandleThrow(ctx, thrown, thrownType, scopeID)
+}
+
 func (pv *PythonVisitor) handleForStatement(ctx context.Context, tsNode *tree_sitter.Node, scopeID ast.NodeID) ast.NodeID {
 	inits := make([]*tree_sitter.Node, 0)
 	initVars := tsNode.Child(1) // target is 1
@@ -208,11 +424,23 @@ func (pv *PythonVisitor) handleAssignment(ctx context.Context, tsNode *tree_sitt
 
 // HasSpecialName returns false for Python - no special naming conventions
 func (pv *PythonVisitor) HasSpecialName(kind string) bool {
-	return false
+	return kind == "typed_parameter"
 }
 
-// GetName is not implemented for Python visitor
+// GetName returns the name of a typed parameter, which for *args: T and
+// **kwargs: T is inside the splat pattern
 func (pv *PythonVisitor) GetName(tsNode *tree_sitter.Node) string {
-	pv.logger.Error("GetName not implemented for Python visitor")
-	return ""
+	if tsNode.Kind() != "typed_parameter" {
+		pv.logger.Error("GetName not implemented for Python node", zap.String("kind", tsNode.Kind()))
+		return ""
+	}
+	name := pv.translate.TreeChildByKind(tsNode, "identifier")
+	for _, splat := range []string{"list_splat_pattern", "dictionary_splat_pattern"} {
+		if name == nil {
+			if pattern := pv.translate.TreeChildByKind(tsNode, splat); pattern != nil {
+				name = pv.translate.TreeChildByKind(pattern, "identifier")
+			}
+		}
+	}
+	return pv.translate.String(name)
 }
@@ -0,0 +1,157 @@
+package parse
+
+import (
+	"strings"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+// MetaSink is the category of a call to a security-sensitive sink, set on
+// its FunctionCall node
+const MetaSink = "sink"
+
+// Categories of security-sensitive sinks
+const (
+	SinkExec            = "exec"            // runs an operating system command
+	SinkEval            = "eval"            // evaluates code given as a string
+	SinkSQLConcat       = "sql_concat"      // runs SQL built from other values
+	SinkDeserialization = "deserialization" // deserializes arbitrary objects
+)
+
+// sinkCallees are the library functions that are sinks, by the name they
+// are usually called with
+var sinkCallees = map[string]string{
+	// Java
+	"ProcessBuilder": SinkExec, "XMLDecoder": SinkDeserialization,
+	// Go
+	"exec.Command": SinkExec, "exec.CommandContext": SinkExec, "syscall.Exec": SinkExec,
+	// Python
+	"os.system": SinkExec, "os.popen": SinkExec, "os.execv": SinkExec, "os.execvp": SinkExec,
+	"subprocess.run": SinkExec, "subprocess.call": SinkExec, "subprocess.check_call": SinkExec,
+	"subprocess.check_output": SinkExec, "subprocess.Popen": SinkExec, "subprocess.getoutput": SinkExec,
+	"pickle.load": SinkDeserialization, "pickle.loads": SinkDeserialization,
+	"cPickle.load": SinkDeserialization, "cPickle.loads": SinkDeserialization,
+	"marshal.load": SinkDeserialization, "marshal.loads": SinkDeserialization,
+	"yaml.load": SinkDeserialization, "yaml.unsafe_load": SinkDeserialization,
+	"jsonpickle.decode": SinkDeserialization,
+	// C#
+	"Process.Start": SinkExec, "System.Diagnostics.Process.Start": SinkExec,
+	// JavaScript
+	"vm.runInThisContext": SinkEval, "vm.runInNewContext": SinkEval,
+	"serialize.unserialize": SinkDeserialization,
+}
+
+// sinkMethods are sinks whatever they are called on
+var sinkMethods = map[string]string{
+	"eval":         SinkEval,
+	"readObject":   SinkDeserialization,
+	"readUnshared": SinkDeserialization,
+	"execSync":     SinkExec,
+	"spawnSync":    SinkExec,
+	"execFileSync": SinkExec,
+}
+
+// childProcessMethods run a command when called on the Node.js child_process
+// module
+var childProcessMethods = map[string]bool{
+	"exec": true, "execFile": true, "spawn": true, "fork": true,
+}
+
+// stringFormatters are the functions building a string from a format
+var stringFormatters = map[string]bool{
+	"format": true, "Format": true, "formatted": true, "Sprintf": true,
+}
+
+// sinkCategory returns the category of sink a call is, given by the source
+// text of its callee, or "" if it is not one
+func sinkCategory(callee string) string {
+	callee = strings.TrimSpace(strings.TrimPrefix(callee, "new "))
+	if category, ok := sinkCallees[callee]; ok {
+		return category
+	}
+	receiver, name := splitCallee(callee)
+	if category, ok := sinkMethods[name]; ok {
+		return category
+	}
+
+	lower := strings.ToLower(receiver)
+	switch {
+	case receiver == "" && name == "exec":
+		// Python's builtin
+		return SinkEval
+	case receiver == "" && name == "Function":
+		// JavaScript's new Function("...")
+		return SinkEval
+	case name == "exec" && strings.Contains(lower, "runtime"):
+		return SinkExec
+	case childProcessMethods[name] && (lower == "child_process" || lower == "childprocess" || lower == "cp"):
+		return SinkExec
+	case name == "Deserialize" && strings.Contains(lower, "formatter"):
+		// BinaryFormatter, SoapFormatter, LosFormatter
+		return SinkDeserialization
+	case name == "fromXML" && strings.Contains(lower, "xstream"):
+		return SinkDeserialization
+	}
+	return ""
+}
+
+// callSink returns the category of sink a call is: a known dangerous
+// function, or a database call given SQL built by concatenation, formatting
+// or interpolation rather than a constant
+func (t *TranslateFromSyntaxTree) callSink(fnName string, args []*tree_sitter.Node) string {
+	callee := fnName
+	if len(args) > 0 {
+		callee = t.calleeText(args[0])
+	}
+	if category := sinkCategory(callee); category != "" {
+		return category
+	}
+	_, name := splitCallee(callee)
+	if sqlCallNames[name] && len(args) > 0 && t.isBuiltString(args[0]) {
+		return SinkSQLConcat
+	}
+	return ""
+}
+
+// isBuiltString reports whether an expression builds a string at run time
+// out of literals and other values
+func (t *TranslateFromSyntaxTree) isBuiltString(node *tree_sitter.Node) bool {
+	switch node.Kind() {
+	case "template_string", "string", "interpolated_string_expression":
+		// `a ${b}`, f"a {b}", $"a {b}"
+		for _, child := range t.NamedChildren(node) {
+			if child.Kind() == "template_substitution" || child.Kind() == "interpolation" {
+				return true
+			}
+		}
+	case "binary_expression", "binary_operator":
+		// "a" + b, or Python's "a %s" % b
+		op := node.ChildByFieldName("operator")
+		if op == nil || (t.String(op) != "+" && t.String(op) != "%") {
+			return false
+		}
+		if _, ok := t.StringValue(node); ok {
+			return false
+		}
+		for _, operand := range t.NamedChildren(node) {
+			if _, ok := t.StringValue(operand); ok || t.isBuiltString(operand) {
+				return true
+			}
+		}
+	case "parenthesized_expression":
+		if node.NamedChildCount() == 1 {
+			return t.isBuiltString(node.NamedChild(0))
+		}
+	case "method_invocation", "call", "call_expression", "invocation_expression":
+		// String.format(...), fmt.Sprintf(...), "...".format(...)
+		fn := node.ChildByFieldName("name")
+		if fn == nil {
+			fn = node.ChildByFieldName("function")
+		}
+		if fn != nil {
+			_, name := splitCallee(t.String(fn))
+			return stringFormatters[name]
+		}
+	}
+	return false
+}
@@ -0,0 +1,69 @@
+package parse
+
+import "testing"
+
+func TestSinkCategory(t *testing.T) {
+	tests := []struct {
+		callee string
+		want   string
+	}{
+		{"Runtime.getRuntime().exec", SinkExec},
+		{"new ProcessBuilder", SinkExec},
+		{"exec.Command", SinkExec},
+		{"subprocess.check_output", SinkExec},
+		{"Process.Start", SinkExec},
+		{"child_process.exec", SinkExec},
+		{"execSync", SinkExec},
+		{"eval", SinkEval},
+		{"engine.eval", SinkEval},
+		{"exec", SinkEval},
+		{"new Function", SinkEval},
+		{"in.readObject", SinkDeserialization},
+		{"pickle.loads", SinkDeserialization},
+		{"formatter.Deserialize", SinkDeserialization},
+		{"xstream.fromXML", SinkDeserialization},
+		{"pattern.exec", ""},
+		{"JsonSerializer.Deserialize", ""},
+		{"cursor.execute", ""},
+		{"yaml.safe_load", ""},
+	}
+
+	for _, tt := range tests {
+		if got := sinkCategory(tt.callee); got != tt.want {
+			t.Errorf("sinkCategory(%q) = %q, want %q", tt.callee, got, tt.want)
+		}
+	}
+}
+
+func TestCallSink(t *testing.T) {
+	tests := []struct {
+		name string
+		call string
+		want string
+	}{
+		{name: "exec", call: `Runtime.getRuntime().exec(cmd)`, want: SinkExec},
+		{name: "script engine", call: `engine.eval(script)`, want: SinkEval},
+		{name: "concatenated query", call: `stmt.executeQuery("SELECT * FROM users WHERE id = " + id)`, want: SinkSQLConcat},
+		{name: "formatted query", call: `jdbc.queryForList(String.format("SELECT * FROM %s", table))`, want: SinkSQLConcat},
+		{name: "constant query", call: `stmt.executeQuery("SELECT * FROM users " + "WHERE active")`},
+		{name: "query in a variable", call: `stmt.executeQuery(sql)`},
+		{name: "not a database call", call: `log.info("user " + id)`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code := "class A { void f() { " + tt.call + "; } }"
+			tree, root := parseJava(t, code)
+			defer tree.Close()
+
+			jv := newTestJavaVisitor([]byte(code))
+			call := findNodeByKind(root, "method_invocation")
+			argList := jv.translate.TreeChildByFieldName(call, "arguments")
+			name := jv.translate.String(jv.translate.TreeChildByFieldName(call, "name"))
+
+			if got := jv.translate.callSink(name, jv.translate.NamedChildren(argList)); got != tt.want {
+				t.Errorf("callSink(%s) = %q, want %q", tt.call, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,447 @@
+package parse
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/armchr/codeapi/internal/model/ast"
+	"github.com/armchr/codeapi/pkg/lsp/base"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+// MetaSchema is the schema a table is qualified with in a statement
+const MetaSchema = "schema"
+
+// sqlCallNames are the methods and functions whose string arguments are SQL:
+// JDBC, Spring JdbcTemplate and JPA in Java, database/sql, sqlx and GORM in
+// Go, DB-API, SQLAlchemy and pandas in Python, ADO.NET, Dapper and EF Core
+// in C#, and the usual Node.js clients
+var sqlCallNames = map[string]bool{
+	// Java
+	"executeQuery": true, "executeUpdate": true, "execute": true, "executeLargeUpdate": true,
+	"prepareStatement": true, "prepareCall": true, "addBatch": true,
+	"query": true, "queryForObject": true, "queryForList": true, "queryForMap": true,
+	"queryForRowSet": true, "queryForStream": true, "update": true, "batchUpdate": true,
+	"createQuery": true, "createNativeQuery": true, "createSQLQuery": true,
+	// Go
+	"Query": true, "QueryContext": true, "QueryRow": true, "QueryRowContext": true,
+	"Exec": true, "ExecContext": true, "Prepare": true, "PrepareContext": true,
+	"Get": true, "GetContext": true, "Select": true, "SelectContext": true,
+	"NamedExec": true, "NamedQuery": true, "MustExec": true, "Raw": true,
+	// Python
+	"executemany": true, "executescript": true, "text": true, "raw": true,
+	"read_sql": true, "read_sql_query": true,
+	// C#
+	"ExecuteSqlRaw": true, "ExecuteSqlRawAsync": true, "FromSqlRaw": true, "SqlQueryRaw": true,
+	"Execute": true, "ExecuteAsync": true, "QueryAsync": true, "QueryFirst": true,
+	"QueryFirstOrDefault": true, "QuerySingle": true, "QueryFirstAsync": true,
+	"QueryFirstOrDefaultAsync": true, "ExecuteScalar": true, "ExecuteScalarAsync": true,
+	"SqlCommand": true, "NpgsqlCommand": true,
+	// JavaScript
+	"all": true, "run": true, "prepare": true, "exec": true,
+}
+
+// HandleSQL links fromID, the code running a SQL statement, to the tables
+// the statement reads and writes with READS_TABLE and WRITES_TABLE
+// relations. It reports false when query is not SQL.
+func (t *TranslateFromSyntaxTree) HandleSQL(ctx context.Context, fromID ast.NodeID, query string, rng base.Range) bool {
+	if fromID == ast.InvalidNodeID {
+		return false
+	}
+	tables, ok := ExtractSQLTables(query)
+	if !ok {
+		return false
+	}
+	for _, name := range tables.Reads {
+		t.CodeGraph.CreateReadsTableRelation(ctx, fromID, t.tableNode(ctx, name, rng), t.FileID)
+	}
+	for _, name := range tables.Writes {
+		t.CodeGraph.CreateWritesTableRelation(ctx, fromID, t.tableNode(ctx, name, rng), t.FileID)
+	}
+	return true
+}
+
+// handleSQLCall links the function making a call to the tables of the SQL
+// statement the call runs, if it is a database call given the statement as
+// a string
+func (t *TranslateFromSyntaxTree) handleSQLCall(ctx context.Context, callNode *ast.Node, args []*tree_sitter.Node) {
+	name := callNode.Name[strings.LastIndex(callNode.Name, ".")+1:]
+	if !sqlCallNames[name] {
+		return
+	}
+	from := callNode.ID
+	if len(t.functions) > 0 {
+		from = t.functions[len(t.functions)-1]
+	}
+	for _, arg := range args {
+		if query, ok := t.StringValue(arg); ok {
+			t.HandleSQL(ctx, from, query, t.ToRange(arg))
+			return
+		}
+	}
+}
+
+// tableNode returns the Table node of a file for the named table, creating
+// it at rng on first use
+func (t *TranslateFromSyntaxTree) tableNode(ctx context.Context, name string, rng base.Range) ast.NodeID {
+	if id, ok := t.tables[name]; ok {
+		return id
+	}
+	tableName, metadata := name, map[string]any(nil)
+	if dot := strings.LastIndex(name, "."); dot >= 0 {
+		tableName, metadata = name[dot+1:], map[string]any{MetaSchema: name[:dot]}
+	}
+	node := t.AllocNode(ast.NodeTypeTable, tableName, rng, ast.InvalidNodeID)
+	node.MetaData = metadata
+	t.CodeGraph.CreateTable(ctx, node)
+	if t.tables == nil {
+		t.tables = make(map[string]ast.NodeID)
+	}
+	t.tables[name] = node.ID
+	return node.ID
+}
+
+// StringValue returns the contents of a string literal, or of a
+// concatenation of string literals, without quotes and prefixes; escapes are
+// left as written
+func (t *TranslateFromSyntaxTree) StringValue(node *tree_sitter.Node) (string, bool) {
+	switch node.Kind() {
+	case "string", "string_literal", "interpreted_string_literal", "raw_string_literal",
+		"verbatim_string_literal", "template_string":
+		return unquoteLiteral(t.String(node))
+	case "binary_expression", "binary_operator", "concatenated_string":
+		if op := node.ChildByFieldName("operator"); node.Kind() != "concatenated_string" && (op == nil || t.String(op) != "+") {
+			return "", false
+		}
+		var b strings.Builder
+		for _, part := range t.NamedChildren(node) {
+			s, ok := t.StringValue(part)
+			if !ok {
+				return "", false
+			}
+			b.WriteString(s)
+		}
+		return b.String(), true
+	case "parenthesized_expression":
+		if node.NamedChildCount() == 1 {
+			return t.StringValue(node.NamedChild(0))
+		}
+	}
+	return "", false
+}
+
+// unquoteLiteral strips the prefix and quotes of a string literal: "a", 'a',
+// `a`, """a""", r"a", f'a', @"a", $"a"
+func unquoteLiteral(text string) (string, bool) {
+	text = strings.TrimLeft(text, "rRbBuUfF@$")
+	for _, q := range []string{`"""`, `'''`, `"`, `'`, "`"} {
+		if len(text) >= 2*len(q) && strings.HasPrefix(text, q) && strings.HasSuffix(text, q) {
+			return text[len(q) : len(text)-len(q)], true
+		}
+	}
+	return "", false
+}
+
+// SQLTables are the tables a SQL statement reads from and writes to, in the
+// order they first appear. Names are lower case and keep their schema, as in
+// "public.orders".
+type SQLTables struct {
+	Reads  []string
+	Writes []string
+}
+
+// sqlStatementKeywords are the keywords a statement extracted from a string
+// must start with to be taken for SQL
+var sqlStatementKeywords = map[string]bool{
+	"select": true, "insert": true, "update": true, "delete": true,
+	"with": true, "merge": true, "replace": true, "upsert": true, "truncate": true,
+}
+
+// sqlKeywords are never table names; a word followed by "(" that is not one
+// of them is a function call
+var sqlKeywords = map[string]bool{
+	"select": true, "from": true, "where": true, "join": true, "inner": true, "left": true,
+	"right": true, "full": true, "outer": true, "cross": true, "natural": true, "on": true,
+	"as": true, "into": true, "values": true, "value": true, "set": true, "update": true,
+	"delete": true, "insert": true, "with": true, "recursive": true, "exists": true, "in": true,
+	"not": true, "and": true, "or": true, "union": true, "intersect": true, "except": true,
+	"all": true, "distinct": true, "group": true, "order": true, "by": true, "having": true,
+	"limit": true, "offset": true, "using": true, "merge": true, "replace": true, "upsert": true,
+	"truncate": true, "table": true, "lateral": true, "returning": true, "when": true,
+	"then": true, "case": true, "else": true, "end": true, "only": true, "ignore": true,
+	"is": true, "null": true, "like": true, "between": true, "any": true, "some": true,
+	"matched": true, "fetch": true, "window": true, "over": true,
+}
+
+// sqlToken is a word (keyword or identifier, with its quotes removed) or a
+// single punctuation character. Literals, parameters and comments are
+// dropped.
+type sqlToken struct {
+	text   string
+	word   bool
+	quoted bool
+}
+
+// ExtractSQLTables finds the tables a SQL statement reads and writes. It
+// reports false when the text does not start like a SQL statement or names
+// no table, which keeps ordinary strings out. JPQL and HQL queries yield
+// their entity names.
+func ExtractSQLTables(query string) (SQLTables, bool) {
+	tokens := tokenizeSQL(query)
+	if len(tokens) == 0 || !tokens[0].word || !sqlStatementKeywords[strings.ToLower(tokens[0].text)] {
+		return SQLTables{}, false
+	}
+
+	var tables SQLTables
+	ctes := make(map[string]bool)
+	add := func(list *[]string, name string) {
+		if name != "" && !ctes[name] && !slices.Contains(*list, name) {
+			*list = append(*list, name)
+		}
+	}
+
+	// inCall tells, per open parenthesis, whether it holds the arguments of
+	// a function, as in EXTRACT(YEAR FROM d) where FROM names no table
+	var inCall []bool
+	// statement is the keyword of the statement a FROM belongs to
+	statement := strings.ToLower(tokens[0].text)
+
+	for i := 0; i < len(tokens); i++ {
+		tok := tokens[i]
+		if !tok.word {
+			switch tok.text {
+			case "(":
+				call := i > 0 && tokens[i-1].word && !tokens[i-1].quoted && !sqlKeywords[strings.ToLower(tokens[i-1].text)]
+				inCall = append(inCall, call)
+			case ")":
+				if len(inCall) > 0 {
+					inCall = inCall[:len(inCall)-1]
+				}
+			}
+			continue
+		}
+		if tok.quoted {
+			continue
+		}
+		if len(inCall) > 0 && inCall[len(inCall)-1] {
+			continue
+		}
+
+		switch strings.ToLower(tok.text) {
+		case "with":
+			for _, name := range cteNames(tokens, i+1) {
+				ctes[name] = true
+			}
+		case "insert", "replace", "upsert":
+			statement = "insert"
+			j := skipWords(tokens, i+1, "into", "ignore", "overwrite", "table")
+			add(&tables.Writes, tableName(tokens, j))
+		case "update":
+			// ON DUPLICATE KEY UPDATE and SELECT ... FOR UPDATE update no table
+			if i > 0 && (isKeyword(tokens[i-1], "key") || isKeyword(tokens[i-1], "for")) {
+				continue
+			}
+			statement = "update"
+			j := skipWords(tokens, i+1, "only")
+			add(&tables.Writes, tableName(tokens, j))
+		case "delete":
+			statement = "delete"
+			j := skipWords(tokens, i+1, "from", "only")
+			add(&tables.Writes, tableName(tokens, j))
+			i = j
+		case "merge":
+			statement = "merge"
+			j := skipWords(tokens, i+1, "into")
+			add(&tables.Writes, tableName(tokens, j))
+		case "truncate":
+			j := skipWords(tokens, i+1, "table", "only")
+			add(&tables.Writes, tableName(tokens, j))
+		case "from", "join", "using":
+			if isKeyword(tok, "using") && statement != "merge" && statement != "delete" {
+				continue
+			}
+			// FROM a, b: each name after a comma at this level is a table too
+			for j := skipWords(tokens, i+1, "only", "lateral"); j < len(tokens); {
+				add(&tables.Reads, tableName(tokens, j))
+				j = skipQualifiedName(tokens, j)
+				if j < len(tokens) && tokens[j].word && isKeyword(tokens[j], "as") {
+					j++
+				}
+				if j < len(tokens) && tokens[j].word && !sqlKeywords[strings.ToLower(tokens[j].text)] {
+					j++ // alias
+				}
+				if j >= len(tokens) || tokens[j].text != "," || !isKeyword(tok, "from") {
+					break
+				}
+				j++
+			}
+		}
+	}
+
+	if len(tables.Reads) == 0 && len(tables.Writes) == 0 {
+		return SQLTables{}, false
+	}
+	return tables, true
+}
+
+// tableName reads the possibly schema qualified name starting at i, or ""
+// when a subquery or keyword is there instead
+func tableName(tokens []sqlToken, i int) string {
+	if i >= len(tokens) || !tokens[i].word {
+		return ""
+	}
+	if !tokens[i].quoted && sqlKeywords[strings.ToLower(tokens[i].text)] {
+		return ""
+	}
+	parts := []string{tokens[i].text}
+	for j := i + 1; j+1 < len(tokens) && tokens[j].text == "." && tokens[j+1].word; j += 2 {
+		parts = append(parts, tokens[j+1].text)
+	}
+	return strings.ToLower(strings.Join(parts, "."))
+}
+
+// skipQualifiedName returns the index after the name starting at i
+func skipQualifiedName(tokens []sqlToken, i int) int {
+	if i >= len(tokens) || !tokens[i].word {
+		return i
+	}
+	i++
+	for i+1 < len(tokens) && tokens[i].text == "." && tokens[i+1].word {
+		i += 2
+	}
+	return i
+}
+
+// skipWords skips the optional keywords starting at i
+func skipWords(tokens []sqlToken, i int, words ...string) int {
+	for i < len(tokens) && tokens[i].word && !tokens[i].quoted && slices.Contains(words, strings.ToLower(tokens[i].text)) {
+		i++
+	}
+	return i
+}
+
+func isKeyword(tok sqlToken, keyword string) bool {
+	return tok.word && !tok.quoted && strings.EqualFold(tok.text, keyword)
+}
+
+// cteNames returns the names of the common table expressions defined by
+// WITH [RECURSIVE] a AS (...), b (x, y) AS (...) from i on
+func cteNames(tokens []sqlToken, i int) []string {
+	i = skipWords(tokens, i, "recursive")
+	var names []string
+	for i < len(tokens) && tokens[i].word {
+		names = append(names, strings.ToLower(tokens[i].text))
+		i++
+		if i < len(tokens) && tokens[i].text == "(" {
+			i = skipParens(tokens, i)
+		}
+		i = skipWords(tokens, i, "as", "not", "materialized")
+		if i >= len(tokens) || tokens[i].text != "(" {
+			break
+		}
+		i = skipParens(tokens, i)
+		if i >= len(tokens) || tokens[i].text != "," {
+			break
+		}
+		i++
+	}
+	return names
+}
+
+// skipParens returns the index after the parenthesis opened at i closes
+func skipParens(tokens []sqlToken, i int) int {
+	depth := 0
+	for ; i < len(tokens); i++ {
+		switch tokens[i].text {
+		case "(":
+			depth++
+		case ")":
+			depth--
+			if depth == 0 {
+				return i + 1
+			}
+		}
+	}
+	return i
+}
+
+// tokenizeSQL splits a statement into words and punctuation, dropping string
+// literals, numbers, bind parameters and comments
+func tokenizeSQL(query string) []sqlToken {
+	var tokens []sqlToken
+	runes := []rune(query)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '-' && i+1 < len(runes) && runes[i+1] == '-':
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+		case r == '/' && i+1 < len(runes) && runes[i+1] == '*':
+			for i += 2; i < len(runes) && !(runes[i-1] == '*' && runes[i] == '/'); i++ {
+			}
+			i++
+		case r == '\'':
+			i = skipQuoted(runes, i, '\'')
+		case r == '"' || r == '`' || r == '[':
+			closing := r
+			if r == '[' {
+				closing = ']'
+			}
+			end := skipQuoted(runes, i, closing)
+			text := string(runes[i+1 : max(i+1, end-1)])
+			tokens = append(tokens, sqlToken{text: text, word: true, quoted: true})
+			i = end
+		case r == '?' || r == ':' || r == '$' || r == '@' || r == '%' || r == '#':
+			// Bind parameters: ?, ?1, :name, $1, @p, %s, #{name}
+			i++
+			if r == '#' && i < len(runes) && runes[i] == '{' {
+				for i < len(runes) && runes[i] != '}' {
+					i++
+				}
+				i++
+				continue
+			}
+			for i < len(runes) && isSQLWordRune(runes[i]) {
+				i++
+			}
+		case isSQLWordRune(r):
+			start := i
+			for i < len(runes) && isSQLWordRune(runes[i]) {
+				i++
+			}
+			if unicode.IsDigit(r) {
+				continue
+			}
+			tokens = append(tokens, sqlToken{text: string(runes[start:i]), word: true})
+		default:
+			tokens = append(tokens, sqlToken{text: string(r)})
+			i++
+		}
+	}
+	return tokens
+}
+
+// skipQuoted returns the index after the quoted text starting at i, where a
+// doubled closing quote stands for itself
+func skipQuoted(runes []rune, i int, closing rune) int {
+	for i++; i < len(runes); i++ {
+		if runes[i] == closing {
+			if i+1 < len(runes) && runes[i+1] == closing && closing != ']' {
+				i++
+				continue
+			}
+			return i + 1
+		}
+	}
+	return len(runes)
+}
+
+func isSQLWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
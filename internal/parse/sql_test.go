@@ -0,0 +1,42 @@
+package parse
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExtractSQLTables(t *testing.T) {
+	tests := []struct {
+		name   string
+		query  string
+		reads  []string
+		writes []string
+		ok     bool
+	}{
+		{name: "select with join", query: "SELECT o.id FROM orders o JOIN customers c ON c.id = o.customer_id WHERE o.id = ?", reads: []string{"orders", "customers"}, ok: true},
+		{name: "comma separated from", query: "select * from orders as o, public.order_items i where i.order_id = o.id", reads: []string{"orders", "public.order_items"}, ok: true},
+		{name: "insert select", query: "INSERT INTO archive (id) SELECT id FROM orders WHERE created < $1", reads: []string{"orders"}, writes: []string{"archive"}, ok: true},
+		{name: "update with subquery", query: "UPDATE orders SET total = (SELECT sum(price) FROM order_items WHERE order_id = orders.id)", reads: []string{"order_items"}, writes: []string{"orders"}, ok: true},
+		{name: "delete", query: "DELETE FROM sessions WHERE expires < now()", writes: []string{"sessions"}, ok: true},
+		{name: "upsert", query: "INSERT INTO counters (k, v) VALUES (:k, 1) ON DUPLICATE KEY UPDATE v = v + 1", writes: []string{"counters"}, ok: true},
+		{name: "common table expression", query: "WITH recent AS (SELECT * FROM orders WHERE created > @since) SELECT * FROM recent", reads: []string{"orders"}, ok: true},
+		{name: "function with from", query: "SELECT EXTRACT(YEAR FROM created) FROM \"Orders\" FOR UPDATE", reads: []string{"orders"}, ok: true},
+		{name: "comments and literals", query: "-- latest\nSELECT 'from users' /* from audit */ FROM `orders`", reads: []string{"orders"}, ok: true},
+		{name: "jpql", query: "SELECT p FROM Pet p WHERE p.owner.id = :ownerId", reads: []string{"pet"}, ok: true},
+		{name: "prose", query: "Please select an option"},
+		{name: "no table", query: "SELECT 1"},
+		{name: "empty", query: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := ExtractSQLTables(tt.query)
+			if ok != tt.ok {
+				t.Fatalf("ExtractSQLTables(%q) ok = %v, want %v", tt.query, ok, tt.ok)
+			}
+			if !reflect.DeepEqual(got.Reads, tt.reads) || !reflect.DeepEqual(got.Writes, tt.writes) {
+				t.Errorf("ExtractSQLTables(%q) = %+v, want reads %v writes %v", tt.query, got, tt.reads, tt.writes)
+			}
+		})
+	}
+}
@@ -3,10 +3,14 @@ package parse
 import (
 	"github.com/armchr/codeapi/internal/model/ast"
 	"github.com/armchr/codeapi/internal/service/codegraph"
+	"github.com/armchr/codeapi/internal/util"
 	"github.com/armchr/codeapi/pkg/lsp/base"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"maps"
+	"slices"
 	"strings"
 
 	tree_sitter "github.com/tree-sitter/go-tree-sitter"
@@ -143,7 +147,15 @@ type TranslateFromSyntaxTree struct {
 	FileContent  []byte
 	Visitor      SyntaxTreeVisitor
 	Logger       *zap.Logger
-	Nodes        map[ast.NodeID]*ast.Node
+	nodes        *nodeArena
+	stableIDs    *stableNodeIDs
+	functions    []ast.NodeID          // functions being traversed, innermost last
+	visiting     *tree_sitter.Node     // innermost node entered through TraverseChildren
+	tables       map[string]ast.NodeID // Table nodes of the file by qualified name
+
+	// Feature flag detection
+	flags         map[string]ast.NodeID // FeatureFlag nodes of the file by key
+	flagDetectors []FlagDetector
 	// Batch writing support
 	EnableBatchWrites bool
 	BatchSize         int
@@ -164,17 +176,53 @@ func NewTranslateFromSyntaxTree(fileID int32, version int32, codeGraph *codegrap
 		CodeGraph:    codeGraph,
 		FileContent:  fileContent,
 		Logger:       logger,
-		Nodes:        make(map[ast.NodeID]*ast.Node),
+		nodes:        newNodeArena(fileID, len(fileContent)),
 	}
 }
 
 func (t *TranslateFromSyntaxTree) NewNode(nodeType ast.NodeType, name string, rng base.Range, parentID ast.NodeID) *ast.Node {
-	node := ast.NewNode(t.NextNodeID(), nodeType, t.FileID, name, rng, t.Version, parentID)
-	t.Nodes[node.ID] = node
+	node := t.AllocNode(nodeType, name, rng, parentID)
 	t.CurrentScope.AddNotContainedNode(node.ID)
 	return node
 }
 
+// AllocNode creates a node with the next ID of the file without tracking it
+// in the current scope
+func (t *TranslateFromSyntaxTree) AllocNode(nodeType ast.NodeType, name string, rng base.Range, parentID ast.NodeID) *ast.Node {
+	return t.nodes.alloc(t.newNodeID(nodeType, rng), nodeType, name, rng, t.Version, parentID)
+}
+
+// SetStableNodeIDs derives the IDs of the nodes created from now on from
+// the repository, path and content hash of the file and from the type and
+// range of each node, instead of from the order nodes are created in. Any
+// build of an unchanged file then recreates the same IDs.
+func (t *TranslateFromSyntaxTree) SetStableNodeIDs(repoName, relativePath, fileSHA string) {
+	t.stableIDs = newStableNodeIDs(repoName, relativePath, fileSHA)
+}
+
+// NodeIDFor returns the ID of a new node of the given type and range that
+// is built outside the arena. The ID is reserved so that no later node of
+// the file is given it.
+func (t *TranslateFromSyntaxTree) NodeIDFor(nodeType ast.NodeType, rng base.Range) ast.NodeID {
+	id := t.newNodeID(nodeType, rng)
+	if t.stableIDs != nil {
+		t.nodes.add(id, nil)
+	}
+	return id
+}
+
+func (t *TranslateFromSyntaxTree) newNodeID(nodeType ast.NodeType, rng base.Range) ast.NodeID {
+	if t.stableIDs == nil {
+		return t.NextNodeID()
+	}
+	return t.stableIDs.next(nodeType, rng, t.nodes.has)
+}
+
+// Node returns the node of this file with the given ID, or nil
+func (t *TranslateFromSyntaxTree) Node(id ast.NodeID) *ast.Node {
+	return t.nodes.get(id)
+}
+
 func (t *TranslateFromSyntaxTree) PushScope(rhs bool) {
 	newScope := NewScope(t.CurrentScope, rhs)
 	t.ScopeStack = append(t.ScopeStack, newScope)
@@ -221,6 +269,9 @@ func (t *TranslateFromSyntaxTree) NextNodeID() ast.NodeID {
 }
 
 func (t *TranslateFromSyntaxTree) TreeChildByKind(node *tree_sitter.Node, kind string) *tree_sitter.Node {
+	if node == nil {
+		return nil
+	}
 	for i := uint(0); i < uint(node.ChildCount()); i++ {
 		child := node.Child(i)
 		if child.Kind() == kind {
@@ -231,6 +282,9 @@ func (t *TranslateFromSyntaxTree) TreeChildByKind(node *tree_sitter.Node, kind s
 }
 
 func (t *TranslateFromSyntaxTree) TreeChildrenByKind(node *tree_sitter.Node, kind string) []*tree_sitter.Node {
+	if node == nil {
+		return nil
+	}
 	var children []*tree_sitter.Node
 	for i := uint(0); i < node.ChildCount(); i++ {
 		child := node.Child(i)
@@ -241,6 +295,9 @@ func (t *TranslateFromSyntaxTree) TreeChildrenByKind(node *tree_sitter.Node, kin
 	return children
 }
 func (t *TranslateFromSyntaxTree) TreeChildByFieldName(node *tree_sitter.Node, fieldName string) *tree_sitter.Node {
+	if node == nil {
+		return nil
+	}
 	for i := uint(0); i < node.ChildCount(); i++ {
 		if node.FieldNameForChild(uint32(i)) == fieldName {
 			return node.Child(i)
@@ -275,7 +332,12 @@ func (t *TranslateFromSyntaxTree) String(node *tree_sitter.Node) string {
 }
 
 func (t *TranslateFromSyntaxTree) getNodeText(node *tree_sitter.Node, sourceCode []byte) string {
-	return string(sourceCode[node.StartByte():node.EndByte()])
+	// A tree parsed from other content than the file's may point past it
+	start, end := node.StartByte(), min(node.EndByte(), uint(len(sourceCode)))
+	if start > end {
+		return ""
+	}
+	return string(sourceCode[start:end])
 }
 
 func (t *TranslateFromSyntaxTree) GetAstNodeText(node *ast.Node) string {
@@ -286,24 +348,10 @@ func (t *TranslateFromSyntaxTree) GetAstNodeText(node *ast.Node) string {
 }
 
 func (t *TranslateFromSyntaxTree) getTextFromSourceAndRange(sourceCode []byte, rng base.Range) string {
-	// iterate over the source code to get the text in the range
-	// for every /n increment line count
-	var currentLine, currentChar int
-	var startByte, endByte int
-	for i := 0; i < len(sourceCode); i++ {
-		if currentLine == rng.Start.Line && currentChar == rng.Start.Character {
-			startByte = i
-		}
-		if currentLine == rng.End.Line && currentChar == rng.End.Character {
-			endByte = i
-			break
-		}
-		if sourceCode[i] == '\n' {
-			currentLine++
-			currentChar = 0
-		} else {
-			currentChar++
-		}
+	startByte := base.ByteOffsetAt(sourceCode, rng.Start)
+	endByte := base.ByteOffsetAt(sourceCode, rng.End)
+	if endByte < startByte {
+		return ""
 	}
 	return string(sourceCode[startByte:endByte])
 }
@@ -328,21 +376,20 @@ func (t *TranslateFromSyntaxTree) ToRange(node *tree_sitter.Node) base.Range {
 	if node == nil {
 		return base.Range{}
 	}
+	// Columns are converted from bytes to the UTF-16 units language servers
+	// report, so that ranges from both sides can be matched
 	startPos := node.StartPosition()
 	endPos := node.EndPosition()
 	return base.Range{
-		Start: base.Position{
-			Line:      int(startPos.Row),
-			Character: int(startPos.Column),
-		},
-		End: base.Position{
-			Line:      int(endPos.Row),
-			Character: int(endPos.Column),
-		},
+		Start: base.PositionAt(t.FileContent, int(node.StartByte()), int(startPos.Row), int(startPos.Column)),
+		End:   base.PositionAt(t.FileContent, int(node.EndByte()), int(endPos.Row), int(endPos.Column)),
 	}
 }
 
 func (t *TranslateFromSyntaxTree) GetTreeNodeName(node *tree_sitter.Node) string {
+	if node == nil {
+		return ""
+	}
 	kind := node.Kind()
 	if t.Visitor.HasSpecialName(kind) {
 		return t.Visitor.GetName(node)
@@ -384,6 +431,28 @@ func (t *TranslateFromSyntaxTree) GetTreeNodeName(node *tree_sitter.Node) string
 	return t.String(idNode)
 }
 
+// Traverse visits the syntax tree of a file from its root. A panic of the
+// visitor, usually a nil child of a malformed tree, is logged with the node
+// being visited and returned as a *util.TraversalPanic, so the build moves
+// on to the next file. Nodes created before the panic are kept.
+func (t *TranslateFromSyntaxTree) Traverse(ctx context.Context, path string, root *tree_sitter.Node, scopeID ast.NodeID) (id ast.NodeID, err error) {
+	t.visiting = root
+	defer func() {
+		if r := recover(); r != nil {
+			p := util.NewTraversalPanic(path, t.visiting, r)
+			t.Logger.Error("Recovered from panic while traversing syntax tree",
+				zap.String("path", path),
+				zap.String("kind", p.Kind),
+				zap.Uint("line", p.Line),
+				zap.Uint("column", p.Column),
+				zap.Any("panic", r),
+				zap.Stack("stack"))
+			id, err = ast.InvalidNodeID, p
+		}
+	}()
+	return t.Visitor.TraverseNode(ctx, root, scopeID), nil
+}
+
 func (t *TranslateFromSyntaxTree) TraverseChildren(ctx context.Context, tsNode *tree_sitter.Node, scopeID ast.NodeID) []ast.NodeID {
 	if tsNode == nil {
 		return nil
@@ -391,7 +460,12 @@ func (t *TranslateFromSyntaxTree) TraverseChildren(ctx context.Context, tsNode *
 	var childIDs []ast.NodeID
 	for i := uint(0); i < tsNode.ChildCount(); i++ {
 		child := tsNode.Child(i)
+		// Not restored by a defer: after a panic it still names the node
+		// whose subtree panicked
+		parent := t.visiting
+		t.visiting = child
 		childID := t.Visitor.TraverseNode(ctx, child, scopeID)
+		t.visiting = parent
 		if childID != ast.InvalidNodeID {
 			childIDs = append(childIDs, childID)
 		}
@@ -444,14 +518,23 @@ func (t *TranslateFromSyntaxTree) CreateFunctionWithMetadata(ctx context.Context
 	if metadata != nil {
 		funcNode.MetaData = metadata
 	}
+	if body != nil {
+		if funcNode.MetaData == nil {
+			funcNode.MetaData = make(map[string]any)
+		}
+		funcNode.MetaData[MetaBodyHash] = BodyHash(t.String(body))
+		maps.Copy(funcNode.MetaData, t.FunctionMetrics(fn, params, body).MetaData())
+	}
 	t.CodeGraph.CreateFunction(ctx, funcNode)
 
 	t.PushScope(false)
 	defer t.PopScope(ctx, funcNode.ID)
+	t.functions = append(t.functions, funcNode.ID)
+	defer func() { t.functions = t.functions[:len(t.functions)-1] }()
 
 	// Handle parameters
 	for idx, param := range params {
-		paramNodeID := t.HandleVariable(ctx, param, funcNode.ID)
+		paramNodeID := t.HandleVariableWithMetadata(ctx, param, funcNode.ID, t.ParameterMetadata(param))
 		t.CreateContainsRelation(ctx, funcNode.ID, paramNodeID, t.FileID)
 		t.CodeGraph.CreateFunctionArgRelation(ctx, funcNode.ID, paramNodeID, idx, t.FileID)
 	}
@@ -467,6 +550,17 @@ func (t *TranslateFromSyntaxTree) CreateFunctionWithMetadata(ctx context.Context
 	return funcNode.ID
 }
 
+// MetaBodyHash is the BodyHash of a function's body. It survives renaming
+// the function, which lets a new file version be matched with the previous.
+const MetaBodyHash = "body_hash"
+
+// BodyHash hashes the source of a function body with runs of whitespace
+// collapsed, so reindenting the body keeps its hash
+func BodyHash(source string) string {
+	sum := sha256.Sum256([]byte(strings.Join(strings.Fields(source), " ")))
+	return hex.EncodeToString(sum[:8])
+}
+
 func (t *TranslateFromSyntaxTree) HandleBlock(ctx context.Context, tsNode *tree_sitter.Node, scopeID ast.NodeID) ast.NodeID {
 	blockNode := t.NewNode(
 		ast.NodeTypeBlock, "", t.ToRange(tsNode), scopeID,
@@ -499,7 +593,130 @@ func (t *TranslateFromSyntaxTree) CreateFakeVariable(ctx context.Context, scopeI
 	return varNode.ID
 }
 
+// Metadata of parameter variables telling how a call can pass them
+const (
+	MetaVariadic = "variadic" // VariadicPositional or VariadicKeyword
+	MetaDefault  = "default"  // source text of the default value
+	MetaOptional = "optional" // true if calls may leave the parameter out
+)
+
+const (
+	VariadicPositional = "positional" // String... a, ...T, *args, ...rest, params T[] a
+	VariadicKeyword    = "keyword"    // **kwargs
+)
+
+// ParameterMetadata describes a parameter declaration of any supported
+// language: whether it collects the remaining arguments and its default
+// value. It returns nil for a plain parameter.
+func (t *TranslateFromSyntaxTree) ParameterMetadata(param *tree_sitter.Node) map[string]any {
+	metadata := make(map[string]any)
+	var defaultValue *tree_sitter.Node
+
+	switch param.Kind() {
+	case "spread_parameter", "variadic_parameter_declaration", "list_splat_pattern", "rest_pattern":
+		metadata[MetaVariadic] = VariadicPositional
+	case "dictionary_splat_pattern":
+		metadata[MetaVariadic] = VariadicKeyword
+	case "typed_parameter":
+		// *args: int and **kwargs: str wrap the splat in a typed parameter
+		if t.TreeChildByKind(param, "list_splat_pattern") != nil {
+			metadata[MetaVariadic] = VariadicPositional
+		} else if t.TreeChildByKind(param, "dictionary_splat_pattern") != nil {
+			metadata[MetaVariadic] = VariadicKeyword
+		}
+	case "default_parameter", "typed_default_parameter":
+		defaultValue = t.TreeChildByFieldName(param, "value")
+	case "assignment_pattern":
+		defaultValue = t.TreeChildByFieldName(param, "right")
+	case "required_parameter", "optional_parameter":
+		if name := t.TreeChildByFieldName(param, "name"); name != nil && name.Kind() == "rest_pattern" {
+			metadata[MetaVariadic] = VariadicPositional
+		}
+		if param.Kind() == "optional_parameter" {
+			metadata[MetaOptional] = true
+		}
+		defaultValue = t.TreeChildByFieldName(param, "value")
+	case "parameter":
+		// C#: a default value after =
+		for i := uint(0); i+1 < param.ChildCount(); i++ {
+			if param.Child(i).Kind() == "=" {
+				defaultValue = param.Child(i + 1)
+			}
+		}
+	case "identifier":
+		// C#: the name of params T[] a, which has no parameter node
+		if parent := param.Parent(); parent != nil && parent.Kind() == "parameter_list" {
+			metadata[MetaVariadic] = VariadicPositional
+		}
+	}
+
+	if defaultValue != nil {
+		metadata[MetaDefault] = t.String(defaultValue)
+		metadata[MetaOptional] = true
+	}
+	if len(metadata) == 0 {
+		return nil
+	}
+	return metadata
+}
+
+// Metadata of Constant nodes
+const (
+	MetaValue     = "value"     // source text of the value, like 42 or "ACTIVE"
+	MetaOrdinal   = "ordinal"   // position of a Java enum constant; iota of a Go constant
+	MetaArguments = "arguments" // constructor arguments of a Java enum constant
+)
+
+// HandleConstant declares the constant named by nameNode. A literal value is
+// only recorded as its value metadata; the variables of any other value flow
+// into the constant as in an assignment.
+func (t *TranslateFromSyntaxTree) HandleConstant(ctx context.Context, nameNode *tree_sitter.Node, value *tree_sitter.Node, scopeID ast.NodeID, metadata map[string]any) ast.NodeID {
+	name := t.GetTreeNodeName(nameNode)
+	if name == "" {
+		return ast.InvalidNodeID
+	}
+
+	constNode := t.NewNode(ast.NodeTypeConstant, name, t.ToRange(nameNode), scopeID)
+	constNode.MetaData = make(map[string]any, len(metadata)+1)
+	maps.Copy(constNode.MetaData, metadata)
+	if value != nil {
+		constNode.MetaData[MetaValue] = t.String(value)
+	}
+	t.CodeGraph.CreateConstant(ctx, constNode)
+	t.CurrentScope.AddSymbol(NewSymbol(constNode))
+
+	// A query kept in a constant links the constant to its tables
+	if value != nil {
+		if query, ok := t.StringValue(value); ok {
+			t.HandleSQL(ctx, constNode.ID, query, t.ToRange(value))
+		}
+	}
+
+	if value != nil && !isLiteral(value) {
+		if rhsID := t.HandleRhsWithFakeVariable(ctx, "__rhs__", value, scopeID, nil); rhsID != ast.InvalidNodeID {
+			t.CodeGraph.CreateDataFlowRelation(ctx, rhsID, constNode.ID, t.FileID)
+		}
+	}
+	return constNode.ID
+}
+
+// isLiteral reports whether node is a number, string, boolean or null literal
+func isLiteral(node *tree_sitter.Node) bool {
+	switch kind := node.Kind(); kind {
+	case "true", "false", "nil", "null", "none", "integer", "float", "number", "string":
+		return true
+	default:
+		return strings.HasSuffix(kind, "_literal")
+	}
+}
+
 func (t *TranslateFromSyntaxTree) HandleVariable(ctx context.Context, tsNode *tree_sitter.Node, scopeID ast.NodeID) ast.NodeID {
+	return t.HandleVariableWithMetadata(ctx, tsNode, scopeID, nil)
+}
+
+// HandleVariableWithMetadata declares a variable like HandleVariable, adding
+// metadata to the variable node
+func (t *TranslateFromSyntaxTree) HandleVariableWithMetadata(ctx context.Context, tsNode *tree_sitter.Node, scopeID ast.NodeID, metadata map[string]any) ast.NodeID {
 	varName := t.GetTreeNodeName(tsNode)
 	if varName == "" {
 		return ast.InvalidNodeID
@@ -516,6 +733,12 @@ func (t *TranslateFromSyntaxTree) HandleVariable(ctx context.Context, tsNode *tr
 			"type": typeName,
 		}
 	}
+	if len(metadata) > 0 {
+		if varNode.MetaData == nil {
+			varNode.MetaData = make(map[string]any, len(metadata))
+		}
+		maps.Copy(varNode.MetaData, metadata)
+	}
 
 	t.CodeGraph.CreateVariable(ctx, varNode)
 	t.CurrentScope.AddSymbol(NewSymbol(varNode))
@@ -536,7 +759,7 @@ func (t *TranslateFromSyntaxTree) ResolveNameChain(ctx context.Context, nameChai
 				t.Logger.Info("Node with empty name", zap.String("node", debugName))
 			}
 			fakeVarID := t.HandleRhsWithFakeVariable(ctx, "__name__", nameNode, scopeID, nil)
-			varNode := t.Nodes[fakeVarID]
+			varNode := t.Node(fakeVarID)
 			sym = NewSymbol(varNode)
 		} else {
 			if sym == nil {
@@ -692,7 +915,7 @@ func (t *TranslateFromSyntaxTree) HandleCallWithMetadata(ctx context.Context, na
 		return ast.InvalidNodeID
 	}
 
-	fnNameNode := t.Nodes[nameID]
+	fnNameNode := t.Node(nameID)
 	if fnNameNode == nil {
 		return ast.InvalidNodeID
 	}
@@ -713,19 +936,79 @@ func (t *TranslateFromSyntaxTree) HandleCallWithMetadata(ctx context.Context, na
 	for k, v := range extraMetadata {
 		callNode.MetaData[k] = v
 	}
+	if sink := t.callSink(fnName, args); sink != "" {
+		callNode.MetaData[MetaSink] = sink
+	}
+	if category, target := t.outboundCall(fnName, args); category != "" {
+		callNode.MetaData[MetaOutbound] = category
+		if target != "" {
+			callNode.MetaData[MetaOutboundTarget] = target
+		}
+	}
 
 	t.CodeGraph.CreateFunctionCall(ctx, callNode)
 
 	for idx, arg := range args {
 		argNodeID := t.HandleRhsWithFakeVariable(ctx, fmt.Sprintf("__arg_%d__", idx), arg, scopeID, nil)
-		t.CodeGraph.CreateFunctionCallArgRelation(ctx, callNode.ID, argNodeID, idx, t.FileID)
+		t.CodeGraph.CreateFunctionCallArgRelation(ctx, callNode.ID, argNodeID, idx, t.argumentMetadata(arg), t.FileID)
 	}
+	t.handleSQLCall(ctx, callNode, args)
+	t.handleConfigCall(ctx, callNode, args, scopeID)
+	t.handleFlagCall(ctx, callNode, args)
 
 	t.CurrentScope.AddRhsVar(callNode.ID)
 
 	return callNode.ID
 }
 
+// MetaKeyword names the parameter a keyword argument of a call is passed to
+const MetaKeyword = "keyword"
+
+// argumentMetadata tells how a call argument is passed when it is not simply
+// by position, for its FUNCTION_CALL_ARG relation: by keyword (Python f(a=1)), or spread over several parameters
+// (f(*xs), f(**kw), f(...xs)), recorded like a variadic parameter
+func (t *TranslateFromSyntaxTree) argumentMetadata(arg *tree_sitter.Node) map[string]any {
+	switch arg.Kind() {
+	case "keyword_argument":
+		if name := arg.ChildByFieldName("name"); name != nil {
+			return map[string]any{MetaKeyword: t.String(name)}
+		}
+	case "list_splat", "spread_element":
+		return map[string]any{MetaVariadic: VariadicPositional}
+	case "dictionary_splat":
+		return map[string]any{MetaVariadic: VariadicKeyword}
+	}
+	return nil
+}
+
+// calleeText returns the source text of what a call calls, like
+// ldClient.boolVariation or os.environ.get, found from one of its arguments;
+// type arguments of a generic method are left out
+func (t *TranslateFromSyntaxTree) calleeText(arg *tree_sitter.Node) string {
+	argList := arg.Parent()
+	if argList != nil && argList.Kind() == "argument" {
+		// C# wraps each argument of the list
+		argList = argList.Parent()
+	}
+	if argList == nil || argList.Parent() == nil {
+		return ""
+	}
+	callee := strings.TrimSpace(string(t.FileContent[argList.Parent().StartByte():argList.StartByte()]))
+	if generic := strings.IndexByte(callee, '<'); generic >= 0 && strings.HasSuffix(callee, ">") {
+		callee = callee[:generic]
+	}
+	return callee
+}
+
+// splitCallee splits the text of a callee into the receiver and the name of
+// the function; the receiver is empty for a plain function call
+func splitCallee(callee string) (receiver, name string) {
+	if dot := strings.LastIndex(callee, "."); dot >= 0 {
+		return callee[:dot], callee[dot+1:]
+	}
+	return "", callee
+}
+
 func (t *TranslateFromSyntaxTree) HandleIdentifier(ctx context.Context, idNode *tree_sitter.Node, scopeID ast.NodeID) ast.NodeID {
 	if idNode == nil {
 		return ast.InvalidNodeID
@@ -757,8 +1040,13 @@ func (t *TranslateFromSyntaxTree) HandleIdentifier(ctx context.Context, idNode *
 }
 
 func (t *TranslateFromSyntaxTree) HandleConditional(ctx context.Context, conditionalNode *tree_sitter.Node, conditions []*tree_sitter.Node, branches []*tree_sitter.Node, scopeID ast.NodeID) ast.NodeID {
+	// A select statement may have no case with a condition
+	rangeNode := conditionalNode
+	if len(conditions) > 0 {
+		rangeNode = conditions[0]
+	}
 	condNode := t.NewNode(
-		ast.NodeTypeConditional, "", t.ToRange(conditions[0]), scopeID,
+		ast.NodeTypeConditional, "", t.ToRange(rangeNode), scopeID,
 	)
 	t.CodeGraph.CreateConditional(ctx, condNode)
 
@@ -792,6 +1080,84 @@ func (t *TranslateFromSyntaxTree) HandleConditional(ctx context.Context, conditi
 	return condNode.ID
 }
 
+// Exception flow metadata. A TryCatch node and each of its CATCH relations
+// list the exception types handled, a __throw__ variable the type thrown
+// and a Java method the types declared in its throws clause.
+const (
+	MetaHandles  = "handles"
+	MetaThrows   = "throws"
+	CatchAllType = "*" // handler that catches every exception, like a bare except
+)
+
+// CatchHandler is a catch or except clause of a try statement
+type CatchHandler struct {
+	Types []string // exception types caught; none for a catch-all handler
+	Body  *tree_sitter.Node
+}
+
+// HandleTryCatch creates a TryCatch node for a try statement. The try block
+// becomes its BODY, each handler body a CATCH at the handler's position and
+// the finally block its FINALLY.
+func (t *TranslateFromSyntaxTree) HandleTryCatch(ctx context.Context, tryNode *tree_sitter.Node,
+	body *tree_sitter.Node, handlers []CatchHandler, finally *tree_sitter.Node,
+	scopeID ast.NodeID) ast.NodeID {
+	node := t.NewNode(ast.NodeTypeTryCatch, "", t.ToRange(tryNode), scopeID)
+
+	var handles []string
+	for i := range handlers {
+		if len(handlers[i].Types) == 0 {
+			handlers[i].Types = []string{CatchAllType}
+		}
+		for _, typ := range handlers[i].Types {
+			if !slices.Contains(handles, typ) {
+				handles = append(handles, typ)
+			}
+		}
+	}
+	if len(handles) > 0 {
+		node.MetaData = map[string]any{MetaHandles: handles}
+	}
+	t.CodeGraph.CreateTryCatch(ctx, node)
+
+	if bodyID := t.Visitor.TraverseNode(ctx, body, node.ID); bodyID != ast.InvalidNodeID {
+		t.CreateContainsRelation(ctx, node.ID, bodyID, t.FileID)
+		t.CodeGraph.CreateBodyRelation(ctx, node.ID, bodyID, t.FileID)
+	}
+	for idx, handler := range handlers {
+		handlerID := t.Visitor.TraverseNode(ctx, handler.Body, node.ID)
+		if handlerID == ast.InvalidNodeID {
+			continue
+		}
+		t.CreateContainsRelation(ctx, node.ID, handlerID, t.FileID)
+		t.CodeGraph.CreateCatchRelation(ctx, node.ID, handlerID, idx, handler.Types, t.FileID)
+	}
+	if finallyID := t.Visitor.TraverseNode(ctx, finally, node.ID); finallyID != ast.InvalidNodeID {
+		t.CreateContainsRelation(ctx, node.ID, finallyID, t.FileID)
+		t.CodeGraph.CreateFinallyRelation(ctx, node.ID, finallyID, t.FileID)
+	}
+	return node.ID
+}
+
+// HandleThrow records a throw or raise statement as a __throw__ variable
+// that the thrown value flows into. thrownType is stored as its throws
+// metadata when the statement names the exception type.
+func (t *TranslateFromSyntaxTree) HandleThrow(ctx context.Context, thrown *tree_sitter.Node, thrownType string, scopeID ast.NodeID) ast.NodeID {
+	if thrown == nil {
+		return ast.InvalidNodeID
+	}
+	var metadata map[string]any
+	if thrownType != "" {
+		metadata = map[string]any{MetaThrows: thrownType}
+	}
+
+	rhsVarIds, _ := t.HandleRhs(ctx, thrown, scopeID)
+	throwID := t.CreateFakeVariable(ctx, scopeID, "__throw__", t.ToRange(thrown), metadata)
+	for _, rhsVarID := range rhsVarIds {
+		t.CodeGraph.CreateDataFlowRelation(ctx, rhsVarID, throwID, t.FileID)
+	}
+	return throwID
+}
+
 func (t *TranslateFromSyntaxTree) HandleLoop(ctx context.Context, loopNode *tree_sitter.Node,
 	initID ast.NodeID, conditionID ast.NodeID, body *tree_sitter.Node,
 	scopeID ast.NodeID) ast.NodeID {
@@ -2,9 +2,15 @@ package parse
 
 import (
 	"context"
+	"errors"
+	"reflect"
 	"testing"
 
 	"github.com/armchr/codeapi/internal/model/ast"
+	"github.com/armchr/codeapi/internal/util"
+	"github.com/armchr/codeapi/pkg/lsp/base"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
 	"go.uber.org/zap"
 )
 
@@ -438,3 +444,224 @@ func TestTranslateFromSyntaxTree_ScopeStackIntegration(t *testing.T) {
 		t.Error("globalFunc should still be resolvable in global scope")
 	}
 }
+
+func TestTranslateFromSyntaxTree_ToRangeUTF16(t *testing.T) {
+	code := "package p\r\n\r\nfunc f() { log(\"café 😀\"); g() } // done\r\n"
+	tree, gv := parseGo(t, code)
+	defer tree.Close()
+
+	calls := findAllNodesByKind(tree.RootNode(), "call_expression")
+	if len(calls) != 2 {
+		t.Fatalf("found %d calls, want 2", len(calls))
+	}
+	// g() starts after 30 bytes of UTF-8 that are 27 UTF-16 units
+	want := base.Range{Start: base.Position{Line: 2, Character: 27}, End: base.Position{Line: 2, Character: 30}}
+	if got := gv.translate.ToRange(calls[1]); got != want {
+		t.Errorf("ToRange(g()) = %+v, want %+v", got, want)
+	}
+	if got := gv.translate.getTextFromSourceAndRange([]byte(code), want); got != "g()" {
+		t.Errorf("text of %+v = %q, want g()", want, got)
+	}
+
+	// The comment runs to the line break, which starts with a carriage return
+	comment := findAllNodesByKind(tree.RootNode(), "comment")
+	if len(comment) != 1 {
+		t.Fatalf("found %d comments, want 1", len(comment))
+	}
+	if got := gv.translate.ToRange(comment[0]).End; got != (base.Position{Line: 2, Character: 40}) {
+		t.Errorf("comment ends at %+v, want 2:40", got)
+	}
+}
+
+func TestTranslateFromSyntaxTree_ParameterMetadata(t *testing.T) {
+	positional := map[string]any{MetaVariadic: VariadicPositional}
+	tests := []struct {
+		name     string
+		lang     LanguageType
+		code     string
+		kind     string // kind of the last parameter, which is checked
+		wantName string
+		want     map[string]any
+	}{
+		{"java plain", Java, "class A { void f(int a) {} }", "formal_parameter", "a", nil},
+		{"java varargs", Java, "class A { void f(int a, String... rest) {} }", "spread_parameter", "rest", positional},
+		{"go variadic", Go, "package p\nfunc f(a int, opts ...Option) {}", "variadic_parameter_declaration", "opts", positional},
+		{"python args", Python, "def f(a, *args): pass", "list_splat_pattern", "args", positional},
+		{"python typed kwargs", Python, "def f(a, **kwargs: str): pass", "typed_parameter", "kwargs", map[string]any{MetaVariadic: VariadicKeyword}},
+		{"python default", Python, "def f(a, b: int = 3): pass", "typed_default_parameter", "b", map[string]any{MetaDefault: "3", MetaOptional: true}},
+		{"javascript rest", JavaScript, "function f(a, ...rest) {}", "rest_pattern", "rest", positional},
+		{"javascript default", JavaScript, "function f(a = [1]) {}", "assignment_pattern", "a", map[string]any{MetaDefault: "[1]", MetaOptional: true}},
+		{"csharp named type", CSharp, "class A { void F(Order order) {} }", "parameter", "order", nil},
+		{"csharp params", CSharp, "class A { void F(int a, params Order[] orders) {} }", "identifier", "orders", positional},
+		{"csharp default", CSharp, "class A { void F(int a, int page = 1) {} }", "parameter", "page", map[string]any{MetaDefault: "1", MetaOptional: true}},
+	}
+
+	fp := &FileParser{logger: zap.NewNop()}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lang, err := fp.GetLanguageParser(tt.lang)
+			if err != nil {
+				t.Fatal(err)
+			}
+			parser := tree_sitter.NewParser()
+			defer parser.Close()
+			if err := parser.SetLanguage(lang); err != nil {
+				t.Fatal(err)
+			}
+			tree := parser.Parse([]byte(tt.code), nil)
+			defer tree.Close()
+
+			translator := NewTranslateFromSyntaxTree(1, 1, nil, []byte(tt.code), zap.NewNop())
+			visitor, err := fp.GetLanguageVisitor(tt.lang, translator)
+			if err != nil {
+				t.Fatal(err)
+			}
+			translator.Visitor = visitor
+
+			params := findAllNodesByKind(tree.RootNode(), tt.kind)
+			if len(params) == 0 {
+				t.Fatalf("no %s in %q", tt.kind, tt.code)
+			}
+			param := params[len(params)-1]
+			if got := translator.GetTreeNodeName(param); got != tt.wantName {
+				t.Errorf("name = %q, want %q", got, tt.wantName)
+			}
+			if got := translator.ParameterMetadata(param); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParameterMetadata() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsLiteral(t *testing.T) {
+	tests := []struct {
+		name string
+		lang LanguageType
+		code string
+		kind string // kind of the first value node, which is checked
+		want bool
+	}{
+		{"go int", Go, "package p\nconst A = 42", "int_literal", true},
+		{"go string", Go, "package p\nconst A = \"a\"", "interpreted_string_literal", true},
+		{"go iota", Go, "package p\nconst A = iota", "iota", false},
+		{"go expression", Go, "package p\nconst A = B << 1", "binary_expression", false},
+		{"java int", Java, "class A { static final int X = 42; }", "decimal_integer_literal", true},
+		{"java string", Java, "class A { static final String X = \"x\"; }", "string_literal", true},
+		{"java boolean", Java, "class A { static final boolean X = true; }", "true", true},
+		{"java call", Java, "class A { static final Object X = make(); }", "method_invocation", false},
+		{"python integer", Python, "X = 42", "integer", true},
+		{"python none", Python, "X = None", "none", true},
+	}
+
+	fp := &FileParser{logger: zap.NewNop()}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lang, err := fp.GetLanguageParser(tt.lang)
+			if err != nil {
+				t.Fatal(err)
+			}
+			parser := tree_sitter.NewParser()
+			defer parser.Close()
+			if err := parser.SetLanguage(lang); err != nil {
+				t.Fatal(err)
+			}
+			tree := parser.Parse([]byte(tt.code), nil)
+			defer tree.Close()
+
+			values := findAllNodesByKind(tree.RootNode(), tt.kind)
+			if len(values) == 0 {
+				t.Fatalf("no %s in %q", tt.kind, tt.code)
+			}
+			if got := isLiteral(values[0]); got != tt.want {
+				t.Errorf("isLiteral(%s) = %v, want %v", tt.kind, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBodyHash(t *testing.T) {
+	body := "{\n\treturn a + b\n}"
+	tests := []struct {
+		name  string
+		other string
+		same  bool
+	}{
+		{"reindented", "{\n        return a + b\n}", true},
+		{"joined lines", "{ return a + b }", true},
+		{"changed", "{\n\treturn a - b\n}", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := BodyHash(tt.other) == BodyHash(body); got != tt.same {
+				t.Errorf("BodyHash(%q) == BodyHash(%q) is %v, want %v", tt.other, body, got, tt.same)
+			}
+		})
+	}
+}
+
+// panickingVisitor walks the tree through TraverseChildren and panics on the
+// first node of a kind, as a visitor dereferencing a missing child would
+type panickingVisitor struct {
+	t    *TranslateFromSyntaxTree
+	kind string
+}
+
+func (v *panickingVisitor) TraverseNode(ctx context.Context, tsNode *tree_sitter.Node, scopeID ast.NodeID) ast.NodeID {
+	if tsNode.Kind() == v.kind {
+		var missing *tree_sitter.Node
+		_ = missing.Kind()
+	}
+	v.t.TraverseChildren(ctx, tsNode, scopeID)
+	return ast.InvalidNodeID
+}
+
+func (v *panickingVisitor) HasSpecialName(kind string) bool         { return false }
+func (v *panickingVisitor) GetName(tsNode *tree_sitter.Node) string { return "" }
+
+func TestTranslateFromSyntaxTree_TraverseRecovers(t *testing.T) {
+	code := "package p\n\nfunc f() int {\n\treturn 1\n}\n"
+	fp := &FileParser{logger: zap.NewNop()}
+	lang, err := fp.GetLanguageParser(Go)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parser := tree_sitter.NewParser()
+	defer parser.Close()
+	if err := parser.SetLanguage(lang); err != nil {
+		t.Fatal(err)
+	}
+	tree := parser.Parse([]byte(code), nil)
+	defer tree.Close()
+
+	tests := []struct {
+		name     string
+		kind     string
+		wantKind string // empty when no panic is expected
+		wantLine uint
+	}{
+		{"panic in a nested node", "return_statement", "return_statement", 4},
+		{"panic at the root", "source_file", "source_file", 1},
+		{"no panic", "goto_statement", "", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			translator := NewTranslateFromSyntaxTree(1, 1, nil, []byte(code), zap.NewNop())
+			translator.Visitor = &panickingVisitor{t: translator, kind: tt.kind}
+
+			_, err := translator.Traverse(context.Background(), "p/f.go", tree.RootNode(), ast.InvalidNodeID)
+			if tt.wantKind == "" {
+				if err != nil {
+					t.Fatalf("Traverse() error = %v", err)
+				}
+				return
+			}
+			var p *util.TraversalPanic
+			if !errors.As(err, &p) {
+				t.Fatalf("Traverse() error = %v, want a TraversalPanic", err)
+			}
+			if p.Path != "p/f.go" || p.Kind != tt.wantKind || p.Line != tt.wantLine {
+				t.Errorf("panic at %s %s %d, want p/f.go %s %d", p.Path, p.Kind, p.Line, tt.wantKind, tt.wantLine)
+			}
+		})
+	}
+}
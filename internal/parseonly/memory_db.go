@@ -0,0 +1,184 @@
+package parseonly
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/armchr/codeapi/internal/model/ast"
+)
+
+// The statements memoryDB understands: the node and relation writes of the
+// parser with batch writes off, the label and property lookups of
+// CodeGraph.readNodes, and the per-file reads of CodeGraph.Dump
+var (
+	mergeNodePattern     = regexp.MustCompile(`MERGE \(n:(\w+) \{id: \$id\}\)`)
+	mergeRelationPattern = regexp.MustCompile(`MERGE \(parent\)-\[r:(\w+)\]->\(child\)`)
+	matchNodePattern     = regexp.MustCompile(`MATCH \(n:(\w+)\)\s+(WHERE|RETURN)`)
+)
+
+type memoryNode struct {
+	label string
+	props map[string]any
+}
+
+type memoryRelation struct {
+	from, to int64
+	label    string
+}
+
+// memoryDB is a GraphDatabase holding the graph in memory. It answers only
+// the statements the parser and the dump issue and returns nothing for any
+// other, which is all a parse-only index needs.
+type memoryDB struct {
+	mu        sync.Mutex
+	nodes     map[int64]*memoryNode
+	relations map[memoryRelation]map[string]any // relation properties
+}
+
+func newMemoryDB() *memoryDB {
+	return &memoryDB{
+		nodes:     make(map[int64]*memoryNode),
+		relations: make(map[memoryRelation]map[string]any),
+	}
+}
+
+func (db *memoryDB) ExecuteWrite(ctx context.Context, query string, params map[string]any) ([]map[string]any, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if m := mergeNodePattern.FindStringSubmatch(query); m != nil {
+		id := toInt64(params["id"])
+		node := db.nodes[id]
+		if node == nil {
+			node = &memoryNode{label: m[1], props: make(map[string]any)}
+			db.nodes[id] = node
+		}
+		for k, v := range params {
+			node.props[k] = v
+		}
+		return nil, nil
+	}
+
+	if m := mergeRelationPattern.FindStringSubmatch(query); m != nil {
+		// Like MATCH ... MERGE, nothing is created unless both ends exist
+		from, to := toInt64(params["parentId"]), toInt64(params["childId"])
+		if db.nodes[from] != nil && db.nodes[to] != nil {
+			props := make(map[string]any, len(params))
+			for k, v := range params {
+				if k != "parentId" && k != "childId" {
+					props[k] = v
+				}
+			}
+			db.relations[memoryRelation{from: from, to: to, label: m[1]}] = props
+		}
+	}
+	return nil, nil
+}
+
+func (db *memoryDB) ExecuteRead(ctx context.Context, query string, params map[string]any) ([]map[string]any, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	switch {
+	case strings.Contains(query, "type(r) as relType"):
+		// Relations touching a file
+		fileID := toInt64(params["fileId"])
+		var records []map[string]any
+		for rel := range db.relations {
+			if db.inFile(rel.from, fileID) || db.inFile(rel.to, fileID) {
+				records = append(records, map[string]any{"fromId": rel.from, "relType": rel.label, "toId": rel.to})
+			}
+		}
+		return records, nil
+
+	case strings.Contains(query, "n.nodeType <> $fileScopeType"):
+		// Nodes of a file other than its file scope
+		fileID := toInt64(params["fileId"])
+		var records []map[string]any
+		for _, node := range db.nodes {
+			nodeType := ast.NodeType(toInt64(node.props["nodeType"]))
+			if toInt64(node.props["fileId"]) == fileID && nodeType != ast.NodeTypeFileScope && nodeType != ast.NodeTypeFileNumber {
+				records = append(records, map[string]any{"n": copyProps(node.props)})
+			}
+		}
+		return records, nil
+	}
+
+	if m := matchNodePattern.FindStringSubmatch(query); m != nil {
+		var records []map[string]any
+		for _, node := range db.nodes {
+			if node.label == m[1] && matchesProps(node.props, params) {
+				records = append(records, map[string]any{"n": copyProps(node.props)})
+			}
+		}
+		return records, nil
+	}
+	return nil, nil
+}
+
+func (db *memoryDB) ExecuteReadSingle(ctx context.Context, query string, params map[string]any) (map[string]any, error) {
+	return map[string]any{}, nil
+}
+
+func (db *memoryDB) ExecuteWriteSingle(ctx context.Context, query string, params map[string]any) (map[string]any, error) {
+	return map[string]any{}, nil
+}
+
+func (db *memoryDB) Close(ctx context.Context) error              { return nil }
+func (db *memoryDB) VerifyConnectivity(ctx context.Context) error { return nil }
+
+func (db *memoryDB) inFile(id, fileID int64) bool {
+	node := db.nodes[id]
+	return node != nil && toInt64(node.props["fileId"]) == fileID
+}
+
+// matchesProps reports whether props has every parameter as a property of
+// equal value. Integers compare equal whatever their Go type.
+func matchesProps(props, params map[string]any) bool {
+	for k, want := range params {
+		got, ok := props[k]
+		if !ok {
+			return false
+		}
+		if isInteger(want) && isInteger(got) {
+			if toInt64(want) != toInt64(got) {
+				return false
+			}
+			continue
+		}
+		if got != want {
+			return false
+		}
+	}
+	return true
+}
+
+func copyProps(props map[string]any) map[string]any {
+	out := make(map[string]any, len(props))
+	for k, v := range props {
+		out[k] = v
+	}
+	return out
+}
+
+func isInteger(v any) bool {
+	switch v.(type) {
+	case int, int32, int64:
+		return true
+	}
+	return false
+}
+
+func toInt64(v any) int64 {
+	switch n := v.(type) {
+	case int:
+		return int64(n)
+	case int32:
+		return int64(n)
+	case int64:
+		return n
+	}
+	return 0
+}
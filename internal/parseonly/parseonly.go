@@ -0,0 +1,150 @@
+// Package parseonly indexes a repository with the tree-sitter visitors alone,
+// into a code graph held in memory. No Neo4j, relational or vector store and
+// no language server is involved, so the graph has the nodes and edges of
+// each file as the visitors create them, without the call edges
+// post-processing adds.
+package parseonly
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/armchr/codeapi/internal/config"
+	"github.com/armchr/codeapi/internal/parse"
+	"github.com/armchr/codeapi/internal/service/codegraph"
+	"github.com/armchr/codeapi/internal/util"
+
+	"go.uber.org/zap"
+)
+
+// Graph is the code graph of a parsed repository
+type Graph struct {
+	*codegraph.CodeGraph
+	db    *memoryDB
+	Files int // files parsed
+}
+
+// Parse parses the files of repo, skipping the directories and files the
+// indexer skips. Files get FileIDs from 1 in lexical path order and are
+// recorded as last modified at the Unix epoch, so the graph of a checkout
+// does not depend on when or where it was made. Only the parse settings of
+// cfg are used.
+func Parse(ctx context.Context, repo *config.Repository, cfg *config.Config, logger *zap.Logger) (*Graph, error) {
+	// Writes are only batched for files whose buffers the code graph
+	// processor sets up, so memoryDB sees every node and edge on its own
+	db := newMemoryDB()
+	graph := &Graph{CodeGraph: codegraph.NewCodeGraphWithDatabase(db, cfg, logger), db: db}
+	fp := parse.NewFileParser(logger, graph.CodeGraph, cfg)
+
+	// WalkDir visits files in lexical order, so FileIDs follow the paths
+	fileID := int32(0)
+	err := filepath.WalkDir(repo.Path, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != repo.Path && util.ShouldSkipDirectory(path) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		info := fixedTimeInfo{name: d.Name(), size: int64(len(content))}
+		if fp.ShouldSkipFile(ctx, repo, info, path, content) {
+			return nil
+		}
+		fileID++
+		if err := fp.ParseAndTraverseWithContent(ctx, repo, info, path, fileID, 1, content); err != nil {
+			return fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+		graph.Files++
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return graph, nil
+}
+
+// Line is one line of the JSON lines output: a node, or a relationship
+// between two nodes named by their id property
+type Line struct {
+	Node     *codegraph.ExportedNode     `json:"node,omitempty"`
+	Relation *codegraph.ExportedRelation `json:"relation,omitempty"`
+}
+
+// WriteJSONL writes the nodes of the graph ordered by ID, then its
+// relationships ordered by source, target and type, one per line. The
+// nodes and relationships have the shape of repository backups.
+func (g *Graph) WriteJSONL(w io.Writer) error {
+	g.db.mu.Lock()
+	defer g.db.mu.Unlock()
+
+	ids := make([]int64, 0, len(g.db.nodes))
+	for id := range g.db.nodes {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	relations := make([]memoryRelation, 0, len(g.db.relations))
+	for rel := range g.db.relations {
+		relations = append(relations, rel)
+	}
+	sort.Slice(relations, func(i, j int) bool {
+		a, b := relations[i], relations[j]
+		if a.from != b.from {
+			return a.from < b.from
+		}
+		if a.to != b.to {
+			return a.to < b.to
+		}
+		return a.label < b.label
+	})
+
+	enc := json.NewEncoder(w)
+	for _, id := range ids {
+		node := g.db.nodes[id]
+		line := Line{Node: &codegraph.ExportedNode{Labels: []string{node.label}, Properties: node.props}}
+		if err := enc.Encode(&line); err != nil {
+			return err
+		}
+	}
+	for _, rel := range relations {
+		line := Line{Relation: &codegraph.ExportedRelation{Type: rel.label, From: rel.from, To: rel.to, Properties: g.db.relations[rel]}}
+		if err := enc.Encode(&line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fixedTimeInfo stands in for the stat result of a file. Its modification
+// time is fixed so the file scope metadata does not depend on when the
+// repository was checked out.
+type fixedTimeInfo struct {
+	name string
+	size int64
+}
+
+func (i fixedTimeInfo) Name() string       { return i.name }
+func (i fixedTimeInfo) Size() int64        { return i.size }
+func (i fixedTimeInfo) Mode() os.FileMode  { return 0o644 }
+func (i fixedTimeInfo) ModTime() time.Time { return time.Unix(0, 0) }
+func (i fixedTimeInfo) IsDir() bool        { return false }
+func (i fixedTimeInfo) Sys() any           { return nil }
@@ -0,0 +1,216 @@
+package parseonly
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/armchr/codeapi/internal/config"
+	"github.com/armchr/codeapi/internal/model/ast"
+	"github.com/armchr/codeapi/internal/parse"
+	"github.com/armchr/codeapi/internal/service/codegraph"
+
+	"go.uber.org/zap"
+)
+
+func writeRepo(t *testing.T, files map[string]string) *config.Repository {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return &config.Repository{Name: "calc", Path: dir, Language: "go"}
+}
+
+func TestParseWriteJSONL(t *testing.T) {
+	repo := writeRepo(t, map[string]string{
+		"calc.go":                  "package calc\n\nfunc Add(a, b int) int {\n\treturn a + b\n}\n",
+		"node_modules/skip/x.go":   "package skip\n\nfunc Skipped() {}\n",
+		"README.md":                "# calc\n",
+		"internal/mul/mul.go":      "package mul\n\nfunc Mul(a, b int) int {\n\treturn a * b\n}\n",
+		"internal/mul/mul_data.go": "package mul\n\nvar Factor = 2\n",
+	})
+	cfg := &config.Config{}
+	cfg.CodeGraph.EnableBatchWrites = true
+
+	graph, err := Parse(context.Background(), repo, cfg, zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if graph.Files != 3 {
+		t.Errorf("parsed %d files, want 3", graph.Files)
+	}
+
+	var buf bytes.Buffer
+	if err := graph.WriteJSONL(&buf); err != nil {
+		t.Fatal(err)
+	}
+	functions := make(map[string]bool)
+	relations := 0
+	nodesDone := false
+	scanner := bufio.NewScanner(bytes.NewReader(buf.Bytes()))
+	for scanner.Scan() {
+		var line Line
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			t.Fatalf("line %q: %v", scanner.Text(), err)
+		}
+		switch {
+		case line.Node != nil:
+			if nodesDone {
+				t.Fatal("node after the relationships")
+			}
+			if line.Node.Labels[0] == "Function" {
+				functions[line.Node.Properties["name"].(string)] = true
+			}
+		case line.Relation != nil:
+			nodesDone = true
+			relations++
+		}
+	}
+	if !functions["Add"] || !functions["Mul"] || functions["Skipped"] {
+		t.Errorf("functions = %v, want Add and Mul", functions)
+	}
+	if relations == 0 {
+		t.Error("no relationships written")
+	}
+
+	again, err := Parse(context.Background(), repo, cfg, zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var second bytes.Buffer
+	if err := again.WriteJSONL(&second); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), second.Bytes()) {
+		t.Error("output differs between two parses of the same repository")
+	}
+}
+
+// contained returns the nodes a file scope contains, directly or not
+func contained(db *memoryDB, fileID int64) map[int64]bool {
+	nodes := make(map[int64]bool)
+	for queue := []int64{fileID}; len(queue) > 0; queue = queue[1:] {
+		for rel := range db.relations {
+			if rel.label == "CONTAINS" && rel.from == queue[0] && !nodes[rel.to] {
+				nodes[rel.to] = true
+				queue = append(queue, rel.to)
+			}
+		}
+	}
+	return nodes
+}
+
+// purgeFile removes a file scope and the nodes it contains, with their
+// relationships, the way CodeGraph.DeleteFiles does: nodes another file
+// scope contains are kept and move to it
+func purgeFile(db *memoryDB, fileID int64) {
+	owners := make(map[int64]int64) // node to the largest other file scope containing it
+	for id, node := range db.nodes {
+		if node.label != "FileScope" || id == fileID {
+			continue
+		}
+		for n := range contained(db, id) {
+			owners[n] = max(owners[n], id)
+		}
+	}
+
+	doomed := map[int64]bool{fileID: true}
+	for id := range contained(db, fileID) {
+		if owner, ok := owners[id]; !ok {
+			doomed[id] = true
+		} else if toInt64(db.nodes[id].props["fileId"]) == fileID {
+			db.nodes[id].props["fileId"] = owner
+		}
+	}
+	for id := range doomed {
+		delete(db.nodes, id)
+	}
+	for rel := range db.relations {
+		if doomed[rel.from] || doomed[rel.to] {
+			delete(db.relations, rel)
+		}
+	}
+}
+
+// nodeIDs returns the IDs of the nodes other than file scopes
+func nodeIDs(db *memoryDB) map[int64]bool {
+	ids := make(map[int64]bool)
+	for id, node := range db.nodes {
+		if node.label != "FileScope" {
+			ids[id] = true
+		}
+	}
+	return ids
+}
+
+func TestSameContentVersionsShareNodes(t *testing.T) {
+	repo := writeRepo(t, map[string]string{
+		"calc.go": "package calc\n\nfunc Add(a, b int) int {\n\treturn a + b\n}\n",
+	})
+	path := filepath.Join(repo.Path, "calc.go")
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info := fixedTimeInfo{name: "calc.go", size: int64(len(content))}
+	parseInto := func(db *memoryDB, fileIDs ...int32) *codegraph.CodeGraph {
+		cg := codegraph.NewCodeGraphWithDatabase(db, &config.Config{}, zap.NewNop())
+		fp := parse.NewFileParser(zap.NewNop(), cg, &config.Config{})
+		for _, fileID := range fileIDs {
+			if err := fp.ParseAndTraverseWithContent(context.Background(), repo, info, path, fileID, 1, content); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return cg
+	}
+
+	// The same file parsed under another FileID, as at a new commit or
+	// after a clean, gets the same node IDs
+	first, second := newMemoryDB(), newMemoryDB()
+	parseInto(first, 1)
+	parseInto(second, 7)
+	if got, want := nodeIDs(second), nodeIDs(first); !reflect.DeepEqual(got, want) {
+		t.Fatalf("node IDs under FileID 7 differ from those under FileID 1: %v, want %v", got, want)
+	}
+
+	// A committed version and a sandbox version share their nodes
+	db := newMemoryDB()
+	cg := parseInto(db, 1, 2)
+	shared := nodeIDs(db)
+	if !reflect.DeepEqual(shared, nodeIDs(first)) {
+		t.Fatalf("versions 1 and 2 have nodes %v, want the shared %v", shared, nodeIDs(first))
+	}
+	functions, err := cg.GetNodesByTypeAndFileID(context.Background(), ast.NodeTypeFunction, 2)
+	if err != nil || len(functions) != 1 {
+		t.Fatalf("functions of version 2 = %v, %v; want Add", functions, err)
+	}
+
+	// Purging the version that wrote the nodes last keeps them for the other
+	purgeFile(db, 2)
+	if got := nodeIDs(db); !reflect.DeepEqual(got, shared) {
+		t.Errorf("nodes after purging version 2 = %v, want %v", got, shared)
+	}
+	// Summaries are keyed by node ID, so the summary of Add survives as
+	// long as its node does
+	remaining, err := cg.GetNodesByTypeAndFileID(context.Background(), ast.NodeTypeFunction, 1)
+	if err != nil || len(remaining) != 1 || remaining[0].ID != functions[0].ID {
+		t.Errorf("functions of version 1 after the purge = %v, %v; want node %d", remaining, err, functions[0].ID)
+	}
+
+	purgeFile(db, 1)
+	if left := nodeIDs(db); len(left) != 0 {
+		t.Errorf("nodes %v left after purging both versions", left)
+	}
+}
@@ -0,0 +1,70 @@
+package audit
+
+import "time"
+
+// Operations recorded in the audit log
+const (
+	OpIndexBuild       = "index_build"       // full or incremental build of a repository
+	OpIndexFile        = "index_file"        // ad-hoc indexing of files into the sandbox
+	OpProcessDirectory = "process_directory" // embeddings of a whole repository
+	OpSummaryBuild     = "summary_build"     // summaries of a whole repository
+	OpSummaryGenerate  = "summary_generate"  // on-demand summaries of a file or entity
+	OpClean            = "clean"
+	OpCompact          = "compact"
+	OpPurgeSandbox     = "purge_sandbox"
+)
+
+// Operations are the recorded operations
+var Operations = map[string]bool{
+	OpIndexBuild: true, OpIndexFile: true, OpProcessDirectory: true, OpSummaryBuild: true,
+	OpSummaryGenerate: true, OpClean: true, OpCompact: true, OpPurgeSandbox: true,
+}
+
+// Where an operation was started
+const (
+	SourceAPI       = "api"
+	SourceCLI       = "cli"
+	SourceScheduler = "scheduler" // background jobs such as the sandbox cleanup
+)
+
+// Outcomes of an operation
+const (
+	OutcomeSuccess = "success"
+	OutcomeFailure = "failure"
+	OutcomeSkipped = "skipped" // not run, as another operation held the repository
+)
+
+// Entry is one operation that changed the stored data of a repository. Actor
+// is the tenant of an API call, the OS user of a CLI command, or empty for
+// unauthenticated API calls, which are known by Client address only. Paths
+// lists the files an operation was limited to; it is empty for operations on
+// the whole repository.
+type Entry struct {
+	ID         int64          `json:"id"`
+	RepoName   string         `json:"repo_name"`
+	Operation  string         `json:"operation"`
+	Source     string         `json:"source"`
+	Actor      string         `json:"actor,omitempty"`
+	Client     string         `json:"client,omitempty"`
+	Paths      []string       `json:"paths,omitempty"`
+	Outcome    string         `json:"outcome"`
+	Error      string         `json:"error,omitempty"`
+	Details    map[string]any `json:"details,omitempty"` // counts reported by the operation
+	StartedAt  time.Time      `json:"started_at"`
+	DurationMs int64          `json:"duration_ms"`
+}
+
+// Filter selects audit entries. Zero fields match everything.
+type Filter struct {
+	Operation string
+	Source    string
+	Actor     string
+	Outcome   string
+	// Path matches entries for paths starting with it, such as the files of
+	// a directory, and entries for the whole repository, which touched them too
+	Path   string
+	Since  *time.Time
+	Until  *time.Time
+	Limit  int
+	Offset int
+}
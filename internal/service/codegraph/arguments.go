@@ -0,0 +1,146 @@
+package codegraph
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/armchr/codeapi/internal/model/ast"
+)
+
+// ArgOfRelation links the argument of a call to the parameter of the called
+// function it is passed to, so data flow can be followed into the callee
+const ArgOfRelation = "ARG_OF"
+
+// CallSlot is an argument of a call (FUNCTION_CALL_ARG) or a parameter of a
+// function (FUNCTION_ARG)
+type CallSlot struct {
+	ID       ast.NodeID
+	Name     string
+	Position int
+	Keyword  string // keyword arguments only: the parameter they name
+	Variadic string // "positional" or "keyword" for *args/**kwargs parameters and spread arguments
+}
+
+// ArgumentBinding is an argument bound to a parameter, with the argument's
+// position at the call
+type ArgumentBinding struct {
+	Arg      ast.NodeID
+	Param    ast.NodeID
+	Position int
+}
+
+// BindArguments binds the arguments of a call to the parameters of the
+// called function the way the call passes them: keyword arguments by name,
+// the others by position, with the surplus going to a variadic parameter.
+// A Python self or cls parameter takes no argument. Spread arguments bind to
+// nothing and leave the positions of the arguments after them unknown.
+func BindArguments(args, params []CallSlot) []ArgumentBinding {
+	args = sortedSlots(args)
+	params = sortedSlots(params)
+	if len(params) > 0 && (params[0].Name == "self" || params[0].Name == "cls") {
+		params = params[1:]
+	}
+
+	byName := make(map[string]CallSlot, len(params))
+	restIndex := -1
+	var kwargs *CallSlot
+	for i, p := range params {
+		switch p.Variadic {
+		case "positional":
+			if restIndex < 0 {
+				restIndex = i
+			}
+		case "keyword":
+			kwargs = &params[i]
+		default:
+			byName[p.Name] = p
+		}
+	}
+
+	var bindings []ArgumentBinding
+	bind := func(arg, param CallSlot) {
+		bindings = append(bindings, ArgumentBinding{Arg: arg.ID, Param: param.ID, Position: arg.Position})
+	}
+
+	next := 0 // parameter index of the next positional argument
+	for _, arg := range args {
+		switch {
+		case arg.Keyword != "":
+			if p, ok := byName[arg.Keyword]; ok {
+				bind(arg, p)
+			} else if kwargs != nil {
+				bind(arg, *kwargs)
+			}
+		case arg.Variadic != "":
+			if arg.Variadic == "positional" {
+				next = -1
+			}
+		case next < 0:
+			// Positions are unknown after a spread argument
+		case restIndex >= 0 && next >= restIndex:
+			bind(arg, params[restIndex])
+		case next < len(params) && params[next].Variadic == "":
+			bind(arg, params[next])
+			next++
+		}
+	}
+	return bindings
+}
+
+func sortedSlots(slots []CallSlot) []CallSlot {
+	sorted := append([]CallSlot(nil), slots...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Position < sorted[j].Position })
+	return sorted
+}
+
+// CreateArgOfRelations binds the arguments of a call to the parameters of
+// the function it calls and links each pair with an ARG_OF relation carrying
+// the argument's position. It returns the number of relations created.
+func (cg *CodeGraph) CreateArgOfRelations(ctx context.Context, callID, functionID ast.NodeID, fileID int32) (int, error) {
+	args, err := cg.readCallSlots(ctx, callID, "FUNCTION_CALL_ARG")
+	if err != nil || len(args) == 0 {
+		return 0, err
+	}
+	params, err := cg.readCallSlots(ctx, functionID, "FUNCTION_ARG")
+	if err != nil || len(params) == 0 {
+		return 0, err
+	}
+
+	bindings := BindArguments(args, params)
+	for _, b := range bindings {
+		err := cg.CreateRelation(ctx, b.Arg, b.Param, ArgOfRelation, map[string]any{"position": b.Position}, fileID)
+		if err != nil {
+			return 0, err
+		}
+	}
+	return len(bindings), nil
+}
+
+// readCallSlots reads the arguments or parameters linked from a call or
+// function by relationLabel
+func (cg *CodeGraph) readCallSlots(ctx context.Context, fromID ast.NodeID, relationLabel string) ([]CallSlot, error) {
+	query := fmt.Sprintf(`
+		MATCH (from {id: $fromId})-[r:%s]->(to)
+		RETURN to.id AS id, to.name AS name, r.md_position AS position,
+		       r.md_keyword AS keyword, coalesce(r.md_variadic, to.md_variadic) AS variadic
+	`, relationLabel)
+
+	records, err := cg.db.ExecuteRead(ctx, query, map[string]any{"fromId": int64(fromID)})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s relations: %w", relationLabel, err)
+	}
+
+	slots := make([]CallSlot, 0, len(records))
+	for _, record := range records {
+		slot := CallSlot{
+			ID:       ast.NodeID(cg.convertToInt64(record["id"])),
+			Position: int(cg.convertToInt64(record["position"])),
+		}
+		slot.Name, _ = record["name"].(string)
+		slot.Keyword, _ = record["keyword"].(string)
+		slot.Variadic, _ = record["variadic"].(string)
+		slots = append(slots, slot)
+	}
+	return slots, nil
+}
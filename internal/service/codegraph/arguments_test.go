@@ -0,0 +1,92 @@
+package codegraph
+
+import (
+	"testing"
+
+	"github.com/armchr/codeapi/internal/model/ast"
+)
+
+func TestBindArguments(t *testing.T) {
+	arg := func(id ast.NodeID, position int) CallSlot {
+		return CallSlot{ID: id, Position: position}
+	}
+	param := func(id ast.NodeID, name string, position int) CallSlot {
+		return CallSlot{ID: id, Name: name, Position: position}
+	}
+	withKeyword := func(s CallSlot, keyword string) CallSlot { s.Keyword = keyword; return s }
+	withVariadic := func(s CallSlot, variadic string) CallSlot { s.Variadic = variadic; return s }
+
+	tests := []struct {
+		name   string
+		args   []CallSlot
+		params []CallSlot
+		want   map[ast.NodeID]ast.NodeID // argument to parameter
+	}{
+		{
+			name:   "positional",
+			args:   []CallSlot{arg(2, 1), arg(1, 0)},
+			params: []CallSlot{param(10, "userId", 0), param(11, "name", 1)},
+			want:   map[ast.NodeID]ast.NodeID{1: 10, 2: 11},
+		},
+		{
+			name:   "more arguments than parameters",
+			args:   []CallSlot{arg(1, 0), arg(2, 1)},
+			params: []CallSlot{param(10, "userId", 0)},
+			want:   map[ast.NodeID]ast.NodeID{1: 10},
+		},
+		{
+			name:   "varargs take the surplus",
+			args:   []CallSlot{arg(1, 0), arg(2, 1), arg(3, 2)},
+			params: []CallSlot{param(10, "format", 0), withVariadic(param(11, "values", 1), "positional")},
+			want:   map[ast.NodeID]ast.NodeID{1: 10, 2: 11, 3: 11},
+		},
+		{
+			name:   "python self",
+			args:   []CallSlot{arg(1, 0)},
+			params: []CallSlot{param(10, "self", 0), param(11, "userId", 1)},
+			want:   map[ast.NodeID]ast.NodeID{1: 11},
+		},
+		{
+			name: "python keywords",
+			args: []CallSlot{arg(1, 0), withKeyword(arg(2, 1), "limit"), withKeyword(arg(3, 2), "verbose")},
+			params: []CallSlot{
+				param(10, "userId", 0), param(11, "limit", 1), withVariadic(param(12, "kwargs", 2), "keyword"),
+			},
+			want: map[ast.NodeID]ast.NodeID{1: 10, 2: 11, 3: 12},
+		},
+		{
+			name:   "unknown keyword",
+			args:   []CallSlot{withKeyword(arg(1, 0), "missing")},
+			params: []CallSlot{param(10, "userId", 0)},
+			want:   map[ast.NodeID]ast.NodeID{},
+		},
+		{
+			name:   "spread argument",
+			args:   []CallSlot{arg(1, 0), withVariadic(arg(2, 1), "positional"), arg(3, 2)},
+			params: []CallSlot{param(10, "a", 0), param(11, "b", 1), param(12, "c", 2)},
+			want:   map[ast.NodeID]ast.NodeID{1: 10},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := make(map[ast.NodeID]ast.NodeID)
+			for _, b := range BindArguments(tt.args, tt.params) {
+				got[b.Arg] = b.Param
+				for _, a := range tt.args {
+					if a.ID == b.Arg && a.Position != b.Position {
+						t.Errorf("binding of %d has position %d, want %d", b.Arg, b.Position, a.Position)
+					}
+				}
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("BindArguments() = %v, want %v", got, tt.want)
+			}
+			for a, p := range tt.want {
+				if got[a] != p {
+					t.Errorf("BindArguments() = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
@@ -4,10 +4,13 @@ import (
 	"bufio"
 	"context"
 	"fmt"
+	"io"
+	"maps"
 	"os"
 	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/armchr/codeapi/internal/config"
@@ -33,10 +36,18 @@ type CodeGraph struct {
 	batchSize         int
 	buffers           map[int32]*Buffer // Map: fileID -> buffer
 	bufferMutex       sync.Mutex        // Protects buffer maps
+	nodesWritten      atomic.Int64      // Nodes written or buffered since creation
+	// Oversized metadata values are moved to the overflow store
+	maxNodeProperty int
+	maxEdgeProperty int
+	overflow        OverflowStore
+	overflowMu      sync.RWMutex // Protects overflow, which may be set after creation
 }
 
+// NewCodeGraph connects to Neo4j, retrying transient failures and pausing
+// graph writes during outages as configured under neo4j.retry
 func NewCodeGraph(uri, username, password string, config *config.Config, logger *zap.Logger) (*CodeGraph, error) {
-	db, err := NewNeo4jDatabase(uri, username, password, logger)
+	db, err := NewNeo4jDatabase(uri, username, password, config.Neo4j.Pool, logger)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create Neo4j database: %w", err)
 	}
@@ -47,12 +58,25 @@ func NewCodeGraph(uri, username, password string, config *config.Config, logger
 		return nil, fmt.Errorf("failed to verify database connectivity: %w", err)
 	}
 
+	resilient, err := NewResilientDatabase(db, config.Neo4j.Retry, config.GraphWALPath(), logger)
+	if err != nil {
+		db.Close(context.Background())
+		return nil, fmt.Errorf("failed to open graph write-ahead log: %w", err)
+	}
+
+	return NewCodeGraphWithDatabase(resilient, config, logger), nil
+}
+
+// NewCodeGraphWithDatabase creates a CodeGraph on an already connected
+// database, such as a stand-in used by benchmarks
+func NewCodeGraphWithDatabase(db GraphDatabase, config *config.Config, logger *zap.Logger) *CodeGraph {
 	// Initialize batch writing configuration
 	enableBatch := config.CodeGraph.EnableBatchWrites
 	batchSize := config.CodeGraph.BatchSize
 	if batchSize == 0 {
 		batchSize = 100 // default
 	}
+	graphConfig := config.CodeGraph.GetDefaults()
 
 	return &CodeGraph{
 		db:                db,
@@ -62,7 +86,27 @@ func NewCodeGraph(uri, username, password string, config *config.Config, logger
 		enableBatchWrites: enableBatch,
 		batchSize:         batchSize,
 		buffers:           make(map[int32]*Buffer),
-	}, nil
+		maxNodeProperty:   graphConfig.MaxNodePropertyBytes,
+		maxEdgeProperty:   graphConfig.MaxEdgePropertyBytes,
+	}
+}
+
+// NodesWritten returns the number of nodes created through this CodeGraph
+func (cg *CodeGraph) NodesWritten() int64 {
+	return cg.nodesWritten.Load()
+}
+
+// CacheSizes reports the entries held in the file path cache and the batch
+// write buffers of files still being processed
+func (cg *CodeGraph) CacheSizes() (filePaths, bufferedFiles int) {
+	cg.fileIDCacheMu.RLock()
+	filePaths = len(cg.fileIDCache)
+	cg.fileIDCacheMu.RUnlock()
+
+	cg.bufferMutex.Lock()
+	bufferedFiles = len(cg.buffers)
+	cg.bufferMutex.Unlock()
+	return filePaths, bufferedFiles
 }
 
 func (cg *CodeGraph) Close(ctx context.Context) error {
@@ -266,11 +310,12 @@ func (cg *CodeGraph) FlushRelations(ctx context.Context, fileID *int32) error {
 
 // Flush writes buffered nodes and relations to the database
 // If fileID is provided, only flushes buffers for that file
-// If fileID is nil, flushes all buffers
+// If fileID is nil, flushes all buffers and waits for writes paused during a
+// database outage to be replayed, so that the queries after it see them
 // IMPORTANT: Nodes are flushed BEFORE relations to ensure they exist in the database
 func (cg *CodeGraph) Flush(ctx context.Context, fileID *int32) error {
 	if !cg.enableBatchWrites {
-		return nil // No-op if batch writes not enabled
+		return cg.waitForPausedWrites(ctx, fileID) // Nothing buffered if batch writes not enabled
 	}
 
 	// Flush nodes first (required for relations to reference them)
@@ -283,9 +328,30 @@ func (cg *CodeGraph) Flush(ctx context.Context, fileID *int32) error {
 		return err
 	}
 
+	return cg.waitForPausedWrites(ctx, fileID)
+}
+
+// waitForPausedWrites waits for the deferred writes of a full flush
+func (cg *CodeGraph) waitForPausedWrites(ctx context.Context, fileID *int32) error {
+	if fileID != nil {
+		return nil
+	}
+	if r, ok := cg.db.(*ResilientDatabase); ok {
+		return r.WaitForPendingWrites(ctx)
+	}
 	return nil
 }
 
+// writeDeferred runs a write whose records are not needed, letting a
+// database that supports it hold the write back while it is unavailable
+func (cg *CodeGraph) writeDeferred(ctx context.Context, query string, params map[string]any) error {
+	if w, ok := cg.db.(DeferredWriter); ok {
+		return w.DeferWrite(ctx, query, params)
+	}
+	_, err := cg.db.ExecuteWrite(ctx, query, params)
+	return err
+}
+
 func (cg *CodeGraph) dbRecordToNode(record GraphNode) (*ast.Node, error) {
 	recordMap := make(map[string]any)
 	for key, value := range record.GetProperties() {
@@ -312,6 +378,7 @@ func (cg *CodeGraph) recordToNode(record map[string]any) (*ast.Node, error) {
 			newMetadata[key[3:]] = value
 		}
 	}
+	markOverflow(record, newMetadata)
 
 	node := &ast.Node{
 		ID:       ast.NodeID(cg.convertToInt64(id)),
@@ -387,6 +454,16 @@ func (cg *CodeGraph) getNodeLabel(nodeType ast.NodeType) string {
 		return "Loop"
 	case ast.NodeTypeImport:
 		return "Import"
+	case ast.NodeTypeTryCatch:
+		return "TryCatch"
+	case ast.NodeTypeConstant:
+		return "Constant"
+	case ast.NodeTypeTable:
+		return "Table"
+	case ast.NodeTypeConfig:
+		return "Config"
+	case ast.NodeTypeFeatureFlag:
+		return "FeatureFlag"
 	default:
 		return "Node"
 	}
@@ -568,6 +645,105 @@ func (cg *CodeGraph) CreateLoop(ctx context.Context, node *ast.Node) error {
 	return cg.writeNode(ctx, node)
 }
 
+func (cg *CodeGraph) CreateTryCatch(ctx context.Context, node *ast.Node) error {
+	if node.NodeType != ast.NodeTypeTryCatch {
+		return fmt.Errorf("invalid node type: expected %d, got %d", ast.NodeTypeTryCatch, node.NodeType)
+	}
+	return cg.writeNode(ctx, node)
+}
+
+func (cg *CodeGraph) CreateConstant(ctx context.Context, node *ast.Node) error {
+	if node.NodeType != ast.NodeTypeConstant {
+		return fmt.Errorf("invalid node type: expected %d, got %d", ast.NodeTypeConstant, node.NodeType)
+	}
+	return cg.writeNode(ctx, node)
+}
+
+// FindConstantsByValue returns the constants of a repository declared with
+// value as their source text, e.g. 42 or "ACTIVE" with the quotes
+func (cg *CodeGraph) FindConstantsByValue(ctx context.Context, repoName string, value string) ([]*ast.Node, error) {
+	query := `
+		MATCH (f:FileScope {repo: $repo})
+		MATCH (c:Constant {fileId: f.fileId, md_value: $value})
+		RETURN c
+	`
+	return cg.readNodesByQuery(ctx, "c", query, map[string]any{"repo": repoName, "value": value})
+}
+
+// CreateTable writes a database table referenced by the SQL of a file
+func (cg *CodeGraph) CreateTable(ctx context.Context, node *ast.Node) error {
+	if node.NodeType != ast.NodeTypeTable {
+		return fmt.Errorf("invalid node type: expected %d, got %d", ast.NodeTypeTable, node.NodeType)
+	}
+	return cg.writeNode(ctx, node)
+}
+
+// CreateReadsTableRelation records that the function or constant fromID
+// holds SQL reading the table
+func (cg *CodeGraph) CreateReadsTableRelation(ctx context.Context, fromID, tableID ast.NodeID, fileID int32) error {
+	return cg.CreateRelation(ctx, fromID, tableID, "READS_TABLE", nil, fileID)
+}
+
+// CreateWritesTableRelation records that the function or constant fromID
+// holds SQL inserting into, updating or deleting from the table
+func (cg *CodeGraph) CreateWritesTableRelation(ctx context.Context, fromID, tableID ast.NodeID, fileID int32) error {
+	return cg.CreateRelation(ctx, fromID, tableID, "WRITES_TABLE", nil, fileID)
+}
+
+// CreateConfig writes a key defined by a configuration file
+func (cg *CodeGraph) CreateConfig(ctx context.Context, node *ast.Node) error {
+	if node.NodeType != ast.NodeTypeConfig {
+		return fmt.Errorf("invalid node type: expected %d, got %d", ast.NodeTypeConfig, node.NodeType)
+	}
+	return cg.writeNode(ctx, node)
+}
+
+// FindConfigReadsInFile returns the variables recording the configuration
+// keys the code of a file reads
+func (cg *CodeGraph) FindConfigReadsInFile(ctx context.Context, fileID int32) ([]*ast.Node, error) {
+	query := `
+		MATCH (v:Variable {fileId: $fileId})
+		WHERE v.md_config_key IS NOT NULL
+		RETURN v
+	`
+	return cg.readNodesByQuery(ctx, "v", query, map[string]any{"fileId": int64(fileID)})
+}
+
+// FindConfigsByLookup returns the Config nodes of a repository defining a
+// key, given in its normalized lookup form
+func (cg *CodeGraph) FindConfigsByLookup(ctx context.Context, repoName string, lookup string) ([]*ast.Node, error) {
+	query := `
+		MATCH (f:FileScope {repo: $repo})
+		MATCH (c:Config {fileId: f.fileId, md_lookup: $lookup})
+		RETURN c
+	`
+	return cg.readNodesByQuery(ctx, "c", query, map[string]any{"repo": repoName, "lookup": lookup})
+}
+
+// CreateReadsConfigRelation records that the function or class fromID reads
+// the configuration key defined by configID
+func (cg *CodeGraph) CreateReadsConfigRelation(ctx context.Context, fromID, configID ast.NodeID, key string, fileID int32) error {
+	return cg.CreateRelation(ctx, fromID, configID, "READS_CONFIG", map[string]any{"key": key}, fileID)
+}
+
+// CreateFeatureFlag writes a feature flag checked by the code of a file
+func (cg *CodeGraph) CreateFeatureFlag(ctx context.Context, node *ast.Node) error {
+	if node.NodeType != ast.NodeTypeFeatureFlag {
+		return fmt.Errorf("invalid node type: expected %d, got %d", ast.NodeTypeFeatureFlag, node.NodeType)
+	}
+	return cg.writeNode(ctx, node)
+}
+
+// CreateUsesFlagRelation records that a call checks a feature flag, with the
+// function making the call when there is one
+func (cg *CodeGraph) CreateUsesFlagRelation(ctx context.Context, callID, flagID, functionID ast.NodeID, fileID int32) error {
+	var props map[string]any
+	if functionID != ast.InvalidNodeID {
+		props = map[string]any{"function": int64(functionID)}
+	}
+	return cg.CreateRelation(ctx, callID, flagID, "USES_FLAG", props, fileID)
+}
+
 func (cg *CodeGraph) CreateField(ctx context.Context, node *ast.Node) error {
 	if node.NodeType != ast.NodeTypeField {
 		return fmt.Errorf("invalid node type: expected %d, got %d", ast.NodeTypeField, node.NodeType)
@@ -696,6 +872,14 @@ func (cg *CodeGraph) writeNodeReal(ctx context.Context, node *ast.Node) error {
 		}
 	}
 
+	spilled, err := cg.spillNode(parameters, int64(node.ID))
+	if err != nil {
+		return err
+	}
+	if err := cg.saveOverflow(ctx, spilled); err != nil {
+		return err
+	}
+
 	// cg.logger.Debug("Writing node", zap.Int64("nodeId", int64(node.ID)), zap.Any("parameters", parameters))
 
 	setQ := cg.mapToSetParamString(parameters, "n")
@@ -705,7 +889,7 @@ func (cg *CodeGraph) writeNodeReal(ctx context.Context, node *ast.Node) error {
 		RETURN n
 	`, nodeLabel, setQ)
 
-	_, err := cg.db.ExecuteWrite(ctx, query, parameters)
+	err = cg.writeDeferred(ctx, query, parameters)
 	if err != nil {
 		cg.logger.Error("Failed to write node", zap.Int64("nodeId", int64(node.ID)), zap.Error(err))
 		return fmt.Errorf("failed to write node: %w", err)
@@ -715,6 +899,8 @@ func (cg *CodeGraph) writeNodeReal(ctx context.Context, node *ast.Node) error {
 }
 
 func (cg *CodeGraph) writeNode(ctx context.Context, node *ast.Node) error {
+	cg.nodesWritten.Add(1)
+
 	// If batch writes are enabled, buffer the node instead of writing immediately
 	if cg.enableBatchWrites {
 		fileID := node.FileID
@@ -757,6 +943,7 @@ func (cg *CodeGraph) BatchWriteNodes(ctx context.Context, nodes []*ast.Node) err
 	// Group nodes by label for efficient batch operations
 	nodesByLabel := make(map[string][]map[string]any)
 	astNodesByLabel := make(map[string][]*ast.Node)
+	var spilled []OverflowValue
 	for _, node := range nodes {
 		label := cg.getNodeLabel(node.NodeType)
 		astNodesByLabel[label] = append(astNodesByLabel[label], node)
@@ -780,8 +967,17 @@ func (cg *CodeGraph) BatchWriteNodes(ctx context.Context, nodes []*ast.Node) err
 			}
 		}
 
+		nodeSpilled, err := cg.spillNode(parameters, int64(node.ID))
+		if err != nil {
+			return err
+		}
+		spilled = append(spilled, nodeSpilled...)
+
 		nodesByLabel[label] = append(nodesByLabel[label], parameters)
 	}
+	if err := cg.saveOverflow(ctx, spilled); err != nil {
+		return err
+	}
 
 	// Write each label group in batch
 	for label, nodeParams := range nodesByLabel {
@@ -816,7 +1012,7 @@ func (cg *CodeGraph) BatchWriteNodes(ctx context.Context, nodes []*ast.Node) err
 			RETURN count(n) as created
 		`, label, setClause)
 
-		_, err := cg.db.ExecuteWrite(ctx, query, map[string]any{"nodes": nodeParams})
+		err := cg.writeDeferred(ctx, query, map[string]any{"nodes": nodeParams})
 		if err != nil {
 			cg.logger.Error("Failed to batch write nodes",
 				zap.String("label", label),
@@ -853,6 +1049,7 @@ func (cg *CodeGraph) BatchCreateRelations(ctx context.Context, relations []Relat
 
 	// Group relations by label for efficient processing
 	relationsByLabel := make(map[string][]map[string]any)
+	var spilled []OverflowValue
 	for _, rel := range relations {
 		relData := map[string]any{
 			"parentId": int64(rel.ParentID),
@@ -863,6 +1060,11 @@ func (cg *CodeGraph) BatchCreateRelations(ctx context.Context, relations []Relat
 		if rel.Metadata != nil {
 			newMetadata := make(map[string]any)
 			cg.flattenMetadata(rel.Metadata, newMetadata)
+			relSpilled, err := cg.spillRelation(newMetadata, rel.Label, int64(rel.ParentID), int64(rel.ChildID))
+			if err != nil {
+				return err
+			}
+			spilled = append(spilled, relSpilled...)
 			for key, value := range newMetadata {
 				relData[key] = value
 			}
@@ -870,6 +1072,9 @@ func (cg *CodeGraph) BatchCreateRelations(ctx context.Context, relations []Relat
 
 		relationsByLabel[rel.Label] = append(relationsByLabel[rel.Label], relData)
 	}
+	if err := cg.saveOverflow(ctx, spilled); err != nil {
+		return err
+	}
 
 	// Write each label group in batch
 	for label, relParams := range relationsByLabel {
@@ -900,7 +1105,7 @@ func (cg *CodeGraph) BatchCreateRelations(ctx context.Context, relations []Relat
 			RETURN count(r) as created
 		`, label, setClause)
 
-		_, err := cg.db.ExecuteWrite(ctx, query, map[string]any{"relations": relParams})
+		err := cg.writeDeferred(ctx, query, map[string]any{"relations": relParams})
 		if err != nil {
 			cg.logger.Error("Failed to batch create relations",
 				zap.String("label", label),
@@ -952,6 +1157,9 @@ func (cg *CodeGraph) readNodesByQuery(ctx context.Context, nodeVarName string, q
 		results = append(results, node)
 	}
 
+	if err := cg.rehydrate(ctx, results); err != nil {
+		return nil, err
+	}
 	return results, nil
 }
 
@@ -1041,6 +1249,22 @@ func (cg *CodeGraph) GetNodesByTypeAndFileID(ctx context.Context, nodeType ast.N
 	})
 }
 
+// GetUnsharedNodesOfFiles returns the nodes of a type that DeleteFiles would
+// delete with the given files of a repository: those of the files that no
+// other version of the file shares
+func (cg *CodeGraph) GetUnsharedNodesOfFiles(ctx context.Context, repoName string, nodeType ast.NodeType, fileIDs []int32) ([]*ast.Node, error) {
+	ids := make([]int64, len(fileIDs))
+	for i, id := range fileIDs {
+		ids[i] = int64(id)
+	}
+	query := fmt.Sprintf(`
+		MATCH (n:%s)
+		WHERE n.fileId IN $fileIds AND NOT %s
+		RETURN n
+	`, cg.getNodeLabel(nodeType), containedByOtherFile("n"))
+	return cg.readNodesByQuery(ctx, "n", query, map[string]any{"repo": repoName, "fileIds": ids})
+}
+
 func (cg *CodeGraph) CreateRelationReal(ctx context.Context, parentNodeID, childNodeID ast.NodeID,
 	relationLabel string, metaData map[string]any, fileID int32) error {
 	parameters := map[string]any{
@@ -1054,6 +1278,13 @@ func (cg *CodeGraph) CreateRelationReal(ctx context.Context, parentNodeID, child
 		//setMetaDataQ = "SET r.metaData = $metaData"
 		newMetadata := make(map[string]any)
 		cg.flattenMetadata(metaData, newMetadata)
+		spilled, err := cg.spillRelation(newMetadata, relationLabel, int64(parentNodeID), int64(childNodeID))
+		if err != nil {
+			return err
+		}
+		if err := cg.saveOverflow(ctx, spilled); err != nil {
+			return err
+		}
 		setMetaDataQ = cg.mapToSetParamString(newMetadata, "r")
 		if setMetaDataQ != "" {
 			setMetaDataQ = "SET " + setMetaDataQ
@@ -1071,7 +1302,7 @@ func (cg *CodeGraph) CreateRelationReal(ctx context.Context, parentNodeID, child
 		RETURN parent, child
 	`, relationLabel, setMetaDataQ)
 
-	_, err := cg.db.ExecuteWrite(ctx, query, parameters)
+	err := cg.writeDeferred(ctx, query, parameters)
 	if err != nil {
 		cg.logger.Error("Failed to create relation",
 			zap.Int64("parentId", int64(parentNodeID)),
@@ -1140,8 +1371,17 @@ func (cg *CodeGraph) CreateContainedByRelation(ctx context.Context, parentNodeID
 }
 */
 
-func (cg *CodeGraph) CreateInheritsRelation(ctx context.Context, parentNodeID, childNodeID ast.NodeID, fileID int32) error {
-	return cg.CreateRelation(ctx, parentNodeID, childNodeID, "INHERITS", nil, fileID)
+// CreateInheritsRelation records that childClassID extends or implements
+// parentClassID, as (child)-[:INHERITS]->(parent)
+func (cg *CodeGraph) CreateInheritsRelation(ctx context.Context, childClassID, parentClassID ast.NodeID, fileID int32) error {
+	return cg.CreateRelation(ctx, childClassID, parentClassID, "INHERITS", nil, fileID)
+}
+
+// CreateInjectsRelation records that a DI container injects dependencyID
+// into classID, as (class)-[:INJECTS {via, name}]->(dependency) where via is
+// field, constructor or method and name is the receiving field or parameter
+func (cg *CodeGraph) CreateInjectsRelation(ctx context.Context, classID, dependencyID ast.NodeID, via, name string, fileID int32) error {
+	return cg.CreateRelation(ctx, classID, dependencyID, "INJECTS", map[string]any{"via": via, "name": name}, fileID)
 }
 
 func (cg *CodeGraph) CreateCallsFunctionRelation(ctx context.Context, callerNodeID, calleeNodeID ast.NodeID, fileID int32) error {
@@ -1221,6 +1461,9 @@ func (cg *CodeGraph) GetChildNodes(ctx context.Context, parentID ast.NodeID, rel
 		results = append(results, node)
 	}
 
+	if err := cg.rehydrate(ctx, results); err != nil {
+		return nil, err
+	}
 	return results, nil
 }
 
@@ -1315,11 +1558,16 @@ func (cg *CodeGraph) CreateDataFlowRelation(ctx context.Context, sourceNodeID, t
 	return cg.CreateRelation(ctx, sourceNodeID, targetNodeID, "DATA_FLOW", nil, fileID)
 }
 
+// CreateFunctionCallArgRelation links a call to its argument at position.
+// metadata tells how the argument is passed if not by position alone, such
+// as the keyword of a Python keyword argument.
 func (cg *CodeGraph) CreateFunctionCallArgRelation(ctx context.Context, callNodeID, argNodeID ast.NodeID,
-	position int, fileID int32) error {
-	return cg.CreateRelation(ctx, callNodeID, argNodeID, "FUNCTION_CALL_ARG", map[string]any{
+	position int, metadata map[string]any, fileID int32) error {
+	props := map[string]any{
 		"position": position,
-	}, fileID)
+	}
+	maps.Copy(props, metadata)
+	return cg.CreateRelation(ctx, callNodeID, argNodeID, "FUNCTION_CALL_ARG", props, fileID)
 }
 
 func (cg *CodeGraph) CreateReturnsRelation(ctx context.Context, functionNodeID, returnNodeID ast.NodeID, fileID int32) error {
@@ -1338,6 +1586,20 @@ func (cg *CodeGraph) CreateConditionalRelation(ctx context.Context, condNodeID,
 	}, fileID)
 }
 
+// CreateCatchRelation links a TryCatch node to the body of its handler at
+// position, with the exception types the handler catches
+func (cg *CodeGraph) CreateCatchRelation(ctx context.Context, tryNodeID,
+	handlerNodeID ast.NodeID, position int, handles []string, fileID int32) error {
+	return cg.CreateRelation(ctx, tryNodeID, handlerNodeID, "CATCH", map[string]any{
+		"position": position,
+		"handles":  handles,
+	}, fileID)
+}
+
+func (cg *CodeGraph) CreateFinallyRelation(ctx context.Context, tryNodeID, finallyNodeID ast.NodeID, fileID int32) error {
+	return cg.CreateRelation(ctx, tryNodeID, finallyNodeID, "FINALLY", nil, fileID)
+}
+
 /*func (cg *CodeGraph) GetOrCreateNextFileID(ctx context.Context) (int32, error) {
 	query := `
 		MERGE (fn:FileNumber {id: -1})
@@ -1388,6 +1650,7 @@ func (cg *CodeGraph) FindFunctionCalls(ctx context.Context, fileID ast.NodeID) (
 	}
 
 	functionCalls := make(map[ast.NodeID][]*ast.Node)
+	var nodes []*ast.Node
 	for _, record := range records {
 		fcData, ok := record["fc"]
 		if !ok || fcData == nil {
@@ -1411,8 +1674,12 @@ func (cg *CodeGraph) FindFunctionCalls(ctx context.Context, fileID ast.NodeID) (
 
 		functionCalls[ast.NodeID(functionId.(int64))] =
 			append(functionCalls[ast.NodeID(functionId.(int64))], node)
+		nodes = append(nodes, node)
 	}
 
+	if err := cg.rehydrate(ctx, nodes); err != nil {
+		return nil, err
+	}
 	return functionCalls, nil
 }
 
@@ -1523,8 +1790,23 @@ func (cg *CodeGraph) updateNodeMetaDataReal(ctx context.Context, nodeID ast.Node
 		return fmt.Errorf("no valid metadata to update")
 	}
 
+	// Keys spilled by an update are added to those the node already lists
+	spilled, err := cg.spillNode(parameters, int64(nodeID))
+	if err != nil {
+		return err
+	}
+	overflowKeys := parameters[overflowProperty]
+	delete(parameters, overflowProperty)
+	if err := cg.saveOverflow(ctx, spilled); err != nil {
+		return err
+	}
+
 	// Build SET clause
 	setQ := cg.mapToSetParamString(parameters, "n")
+	if len(spilled) > 0 {
+		parameters["overflowKeys"] = overflowKeys
+		setQ += ",\nn.mdOverflow = [k IN coalesce(n.mdOverflow, []) WHERE NOT k IN $overflowKeys] + $overflowKeys"
+	}
 
 	// Add node ID to parameters
 	parameters["id"] = int64(nodeID)
@@ -1623,6 +1905,7 @@ func (cg *CodeGraph) BatchUpdateNodeMetaData(ctx context.Context, updates map[as
 	// Perform batch update in database
 	// Build a single query with UNWIND for all updates
 	var updateItems []map[string]any
+	oversized := 0
 	for nodeID, metadata := range updates {
 		parameters := make(map[string]any)
 		newMetadata := make(map[string]any)
@@ -1632,6 +1915,16 @@ func (cg *CodeGraph) BatchUpdateNodeMetaData(ctx context.Context, updates map[as
 			cg.flattenMetadata(newMetadata, parameters)
 		}
 
+		// SET n += update would replace the keys the node lists as moved to
+		// the overflow store, so nodes with oversized values are updated alone
+		if cg.hasOversizedProperty(parameters) {
+			if err := cg.updateNodeMetaDataReal(ctx, nodeID, metadata); err != nil {
+				return err
+			}
+			oversized++
+			continue
+		}
+
 		if len(parameters) > 0 {
 			parameters["id"] = int64(nodeID)
 			updateItems = append(updateItems, parameters)
@@ -1639,6 +1932,9 @@ func (cg *CodeGraph) BatchUpdateNodeMetaData(ctx context.Context, updates map[as
 	}
 
 	if len(updateItems) == 0 {
+		if oversized > 0 {
+			return nil
+		}
 		return fmt.Errorf("no valid metadata to update")
 	}
 
@@ -1696,6 +1992,28 @@ func (cg *CodeGraph) FindClassesByNameInRepo(ctx context.Context, name string, r
 	})
 }
 
+// CreatePossibleCallsRelations adds a POSSIBLE_CALLS edge from every call
+// resolved to a method of an interface of the repository to the same-named
+// methods of the classes implementing it, directly or through other
+// interfaces and superclasses. It needs the CALLS_FUNCTION and INHERITS
+// edges of all files, so it runs after they are flushed. It returns the
+// number of edges created or already present.
+func (cg *CodeGraph) CreatePossibleCallsRelations(ctx context.Context, repoName string) (int64, error) {
+	query := `
+		MATCH (:FileScope {repo: $repo})-[:CONTAINS]->(:ModuleScope)-[:CONTAINS]->(i:Class {md_is_interface: true})
+		MATCH (i)-[:CONTAINS]->(m:Function)<-[:CALLS_FUNCTION]-(fc:FunctionCall)
+		MATCH (impl:Class)-[:INHERITS*1..]->(i)
+		MATCH (impl)-[:CONTAINS]->(target:Function {name: m.name})
+		MERGE (fc)-[r:POSSIBLE_CALLS]->(target)
+		RETURN count(DISTINCT r) AS edges
+	`
+	record, err := cg.db.ExecuteWriteSingle(ctx, query, map[string]any{"repo": repoName})
+	if err != nil {
+		return 0, fmt.Errorf("failed to create possible calls: %w", err)
+	}
+	return cg.convertToInt64(record["edges"]), nil
+}
+
 // FindAllClassesWithInheritance returns all classes in a repository that have extends or implements metadata.
 func (cg *CodeGraph) FindAllClassesWithInheritance(ctx context.Context, repoName string) ([]*ast.Node, error) {
 	q := `MATCH (f:FileScope {repo: $repo})-[:CONTAINS]->(m:ModuleScope)-[:CONTAINS]->(c:Class)
@@ -1893,9 +2211,10 @@ func (cg *CodeGraph) UpdateFakeClasses(ctx context.Context, fileID int32) error
 
 	moduleNode := moduleRecords[0]
 
-	// find all fake classes in the given file scope
+	// find all fake classes in the given file scope; is_fake is not first
+	// class metadata, so it is stored flattened
 	query := `
-		MATCH (c:Class {fileId: $fileID, is_fake: true})
+		MATCH (c:Class {fileId: $fileID, md_is_fake: true})
 		RETURN c
 	`
 
@@ -2001,7 +2320,12 @@ func (cg *CodeGraph) DumpToFile(ctx context.Context, filePath string, repoNames
 	}
 	defer file.Close()
 
-	writer := bufio.NewWriter(file)
+	return cg.Dump(ctx, file, repoNames)
+}
+
+// Dump writes the dump DumpToFile produces to w
+func (cg *CodeGraph) Dump(ctx context.Context, w io.Writer, repoNames []string) error {
+	writer := bufio.NewWriter(w)
 	defer writer.Flush()
 
 	// Write header
@@ -2195,71 +2519,348 @@ func (cg *CodeGraph) getAllRelationsInFile(ctx context.Context, fileID int32) ([
 	return relations, nil
 }
 
+// CountRepositoryNodes returns the number of FileScope nodes of a repository
+// and of all nodes belonging to its files, i.e. what CleanRepository deletes
+func (cg *CodeGraph) CountRepositoryNodes(ctx context.Context, repoName string) (fileScopes, nodes int64, err error) {
+	query := `
+		MATCH (fs:FileScope {repo: $repo})
+		WITH collect(fs.id) AS fileIds
+		OPTIONAL MATCH (n)
+		WHERE n.fileId IN fileIds
+		RETURN size(fileIds) AS fileScopes, count(n) AS nodes
+	`
+	record, err := cg.db.ExecuteReadSingle(ctx, query, map[string]any{"repo": repoName})
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to count repository nodes: %w", err)
+	}
+	return cg.convertToInt64(record["fileScopes"]), cg.convertToInt64(record["nodes"]), nil
+}
+
+// LanguageCounts is the number of files, functions and classes of a
+// repository written in one language
+type LanguageCounts struct {
+	Files     int64 `json:"files"`
+	Functions int64 `json:"functions"`
+	Classes   int64 `json:"classes"`
+}
+
+// GraphStats counts what the code graph holds for a repository: files,
+// functions and classes by language, and relationships by type
+type GraphStats struct {
+	Languages map[string]*LanguageCounts `json:"languages"`
+	Edges     map[string]int64           `json:"edges"`
+}
+
+// RepositoryStats counts the nodes and relationships of a repository. Every
+// file version still in the graph is counted.
+func (cg *CodeGraph) RepositoryStats(ctx context.Context, repoName string) (*GraphStats, error) {
+	stats := &GraphStats{Languages: map[string]*LanguageCounts{}, Edges: map[string]int64{}}
+	params := map[string]any{"repo": repoName}
+
+	nodeQuery := `
+		MATCH (f:FileScope {repo: $repo})
+		OPTIONAL MATCH (n {fileId: f.fileId})
+		WHERE n:Function OR n:Class
+		RETURN f.language AS language, count(DISTINCT f) AS files,
+		       count(CASE WHEN n:Function THEN 1 END) AS functions,
+		       count(CASE WHEN n:Class THEN 1 END) AS classes
+	`
+	records, err := cg.db.ExecuteRead(ctx, nodeQuery, params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count nodes: %w", err)
+	}
+	for _, record := range records {
+		language, _ := record["language"].(string)
+		stats.Languages[language] = &LanguageCounts{
+			Files:     cg.convertToInt64(record["files"]),
+			Functions: cg.convertToInt64(record["functions"]),
+			Classes:   cg.convertToInt64(record["classes"]),
+		}
+	}
+
+	edgeQuery := `
+		MATCH (f:FileScope {repo: $repo})
+		MATCH (n {fileId: f.fileId})-[r]->()
+		RETURN type(r) AS type, count(r) AS count
+	`
+	records, err = cg.db.ExecuteRead(ctx, edgeQuery, params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count relationships: %w", err)
+	}
+	for _, record := range records {
+		relType, _ := record["type"].(string)
+		stats.Edges[relType] = cg.convertToInt64(record["count"])
+	}
+	return stats, nil
+}
+
+// CallResolution counts the function calls of a repository by how
+// post-processing resolved them: to a function of the repository, to a
+// library function, or not at all
+type CallResolution struct {
+	Calls      int64 `json:"calls"`
+	External   int64 `json:"external"`
+	Unresolved int64 `json:"unresolved"`
+}
+
+// CountCallResolution counts the calls of the newest version of each file
+// of a repository by how they were resolved
+func (cg *CodeGraph) CountCallResolution(ctx context.Context, repoName string) (*CallResolution, error) {
+	query := `
+		MATCH (f:FileScope {repo: $repo})
+		WITH f.path AS path, max(f.fileId) AS fileId
+		MATCH (c:FunctionCall {fileId: fileId})
+		WITH coalesce(c.md_external, false) AS external,
+		     EXISTS { (c)-[:CALLS_FUNCTION]->() } AS resolved
+		RETURN count(*) AS calls,
+		       count(CASE WHEN external THEN 1 END) AS external,
+		       count(CASE WHEN NOT external AND NOT resolved THEN 1 END) AS unresolved
+	`
+	records, err := cg.db.ExecuteRead(ctx, query, map[string]any{"repo": repoName})
+	if err != nil {
+		return nil, fmt.Errorf("failed to count calls: %w", err)
+	}
+	counts := &CallResolution{}
+	if len(records) > 0 {
+		counts.Calls = cg.convertToInt64(records[0]["calls"])
+		counts.External = cg.convertToInt64(records[0]["external"])
+		counts.Unresolved = cg.convertToInt64(records[0]["unresolved"])
+	}
+	return counts, nil
+}
+
+// CleanProgress is called after every batch deleted by
+// CleanRepositoryWithProgress with the number of nodes deleted so far and the
+// number counted before the deletion started
+type CleanProgress func(deleted, total int64)
+
+// cleanFileChunk is the number of files whose nodes are deleted together.
+// It bounds the size of the $fileIds parameter and of the CONTAINS subgraph
+// each batch query has to match.
+const cleanFileChunk = 500
+
 // CleanRepository deletes all nodes and relationships for a specific repository from Neo4j.
 // This includes all FileScopes and their descendant nodes (functions, classes, variables, etc.)
 func (cg *CodeGraph) CleanRepository(ctx context.Context, repoName string) error {
+	return cg.CleanRepositoryWithProgress(ctx, repoName, nil)
+}
+
+// CleanRepositoryWithProgress is CleanRepository reporting its progress.
+// Repositories can hold millions of nodes, too many to delete in a single
+// transaction, so files are processed in chunks and each chunk's nodes are
+// deleted code_graph.delete_batch_size at a time until none are left. A
+// failed or cancelled clean leaves a partially deleted repository that can
+// be cleaned again.
+func (cg *CodeGraph) CleanRepositoryWithProgress(ctx context.Context, repoName string, progress CleanProgress) error {
 	cg.logger.Info("Starting Neo4j cleanup for repository", zap.String("repo", repoName))
 
-	// First, get count of nodes to be deleted for logging
-	countQuery := `
-		MATCH (fs:FileScope {repo: $repo})
-		OPTIONAL MATCH (fs)-[:CONTAINS*]->(descendant)
-		RETURN count(DISTINCT fs) as fileScopeCount, count(DISTINCT descendant) as descendantCount
-	`
-	countResult, err := cg.db.ExecuteReadSingle(ctx, countQuery, map[string]any{"repo": repoName})
+	var total int64
+	fileScopes, nodes, err := cg.CountRepositoryNodes(ctx, repoName)
 	if err != nil {
 		cg.logger.Warn("Failed to count nodes for deletion", zap.Error(err))
 	} else {
-		fsCount := cg.convertToInt64(countResult["fileScopeCount"])
-		descCount := cg.convertToInt64(countResult["descendantCount"])
+		total = fileScopes + nodes
 		cg.logger.Info("Nodes to be deleted",
 			zap.String("repo", repoName),
-			zap.Int64("file_scopes", fsCount),
-			zap.Int64("descendants", descCount))
+			zap.Int64("file_scopes", fileScopes),
+			zap.Int64("nodes", nodes))
 	}
 
-	// Phase 1: Delete all descendant nodes (nodes connected via CONTAINS relationships)
-	// Using DETACH DELETE to also remove all relationships
-	deleteDescendantsQuery := `
-		MATCH (fs:FileScope {repo: $repo})-[:CONTAINS*]->(descendant)
-		DETACH DELETE descendant
-	`
-	_, err = cg.db.ExecuteWrite(ctx, deleteDescendantsQuery, map[string]any{"repo": repoName})
+	fileIDs, err := cg.GetFileIDs(ctx, repoName)
 	if err != nil {
-		return fmt.Errorf("failed to delete descendant nodes: %w", err)
+		return err
 	}
-	cg.logger.Debug("Phase 1: Deleted descendant nodes via CONTAINS", zap.String("repo", repoName))
 
-	// Phase 1.5: Delete any orphaned nodes that have the same fileId as FileScopes in this repo
-	// This catches nodes that may not be connected via CONTAINS but still belong to repo files
+	limit := cg.config.CodeGraph.GetDefaults().DeleteBatchSize
+	var deleted int64
+	report := func(n int64) {
+		deleted += n
+		if progress != nil {
+			progress(deleted, total)
+		}
+	}
+
+	// Descendants are deleted leaves first: removing an inner node first would
+	// cut its children off from the FileScope they are matched through
+	deleteLeavesQuery := `
+		MATCH (fs:FileScope {repo: $repo})-[:CONTAINS*]->(descendant)
+		WHERE fs.id IN $fileIds AND NOT (descendant)-[:CONTAINS]->()
+		WITH DISTINCT descendant LIMIT $limit
+		DETACH DELETE descendant
+		RETURN count(*) AS deleted
+	`
+	// Catches nodes that belong to the files but are not connected via CONTAINS
 	deleteByFileIdQuery := `
-		MATCH (fs:FileScope {repo: $repo})
-		WITH collect(fs.id) AS fileIds
 		MATCH (n)
-		WHERE n.fileId IN fileIds AND NOT n:FileScope
+		WHERE n.fileId IN $fileIds AND NOT n:FileScope
+		WITH n LIMIT $limit
 		DETACH DELETE n
+		RETURN count(*) AS deleted
 	`
-	_, err = cg.db.ExecuteWrite(ctx, deleteByFileIdQuery, map[string]any{"repo": repoName})
+	deleteFileScopesQuery := `
+		MATCH (fs:FileScope {repo: $repo})
+		WHERE fs.id IN $fileIds
+		DETACH DELETE fs
+		RETURN count(*) AS deleted
+	`
+
+	for start := 0; start < len(fileIDs); start += cleanFileChunk {
+		chunk := fileIDs[start:min(start+cleanFileChunk, len(fileIDs))]
+		ids := make([]int64, len(chunk))
+		for i, id := range chunk {
+			ids[i] = int64(id)
+		}
+		params := map[string]any{"repo": repoName, "fileIds": ids, "limit": limit}
+		if err := cg.deleteFileOverflow(ctx, params, false); err != nil {
+			return err
+		}
+
+		for _, phase := range []struct{ name, query string }{
+			{"descendant", deleteLeavesQuery},
+			{"file", deleteByFileIdQuery},
+		} {
+			for {
+				if err := ctx.Err(); err != nil {
+					return err
+				}
+				n, err := cg.deleteBatch(ctx, phase.query, params)
+				if err != nil {
+					return fmt.Errorf("failed to delete %s nodes: %w", phase.name, err)
+				}
+				if n == 0 {
+					break
+				}
+				report(n)
+			}
+		}
+
+		n, err := cg.deleteBatch(ctx, deleteFileScopesQuery, params)
+		if err != nil {
+			return fmt.Errorf("failed to delete FileScope nodes: %w", err)
+		}
+		report(n)
+
+		cg.fileIDCacheMu.Lock()
+		for _, id := range chunk {
+			delete(cg.fileIDCache, id)
+		}
+		cg.fileIDCacheMu.Unlock()
+
+		cg.logger.Debug("Deleted repository nodes",
+			zap.String("repo", repoName),
+			zap.Int("files", start+len(chunk)),
+			zap.Int64("deleted", deleted),
+			zap.Int64("total", total))
+	}
+
+	cg.logger.Info("Neo4j cleanup completed for repository",
+		zap.String("repo", repoName),
+		zap.Int64("deleted", deleted))
+	return nil
+}
+
+// deleteBatch runs a delete query returning the number of deleted nodes as
+// "deleted" in its own transaction
+func (cg *CodeGraph) deleteBatch(ctx context.Context, query string, params map[string]any) (int64, error) {
+	record, err := cg.db.ExecuteWriteSingle(ctx, query, params)
 	if err != nil {
-		return fmt.Errorf("failed to delete nodes by fileId: %w", err)
+		return 0, err
+	}
+	return cg.convertToInt64(record["deleted"]), nil
+}
+
+// containedByOtherFile is the condition that a FileScope of the repository
+// other than those in $fileIds contains the node bound to variable. Versions
+// of a file with the same content share their nodes, as node IDs derive from
+// the content, so such a node must outlive the deletion of one version.
+func containedByOtherFile(variable string) string {
+	return fmt.Sprintf(`EXISTS {
+			MATCH (other:FileScope {repo: $repo})-[:CONTAINS*]->(%s)
+			WHERE NOT other.id IN $fileIds
+		}`, variable)
+}
+
+// DeleteFiles deletes the FileScopes with the given file IDs in a repository,
+// together with every node they contain. File IDs are only unique within a
+// repository, so deletion is anchored on the repository's FileScopes rather
+// than on the fileId property alone. Nodes another version of the file
+// shares are kept, and move to that version if their fileId named a
+// deleted one.
+func (cg *CodeGraph) DeleteFiles(ctx context.Context, repoName string, fileIDs []int32) error {
+	if len(fileIDs) == 0 {
+		return nil
+	}
+
+	ids := make([]int64, len(fileIDs))
+	for i, id := range fileIDs {
+		ids[i] = int64(id)
+	}
+	params := map[string]any{"repo": repoName, "fileIds": ids}
+	if err := cg.deleteFileOverflow(ctx, params, true); err != nil {
+		return err
+	}
+
+	reassignSharedQuery := `
+		MATCH (fs:FileScope {repo: $repo})-[:CONTAINS*]->(shared)
+		WHERE fs.id IN $fileIds AND shared.fileId IN $fileIds
+		WITH DISTINCT shared
+		MATCH (other:FileScope {repo: $repo})-[:CONTAINS*]->(shared)
+		WHERE NOT other.id IN $fileIds
+		WITH shared, max(other.id) AS owner
+		SET shared.fileId = owner
+	`
+	if _, err := cg.db.ExecuteWrite(ctx, reassignSharedQuery, params); err != nil {
+		return fmt.Errorf("failed to reassign shared nodes: %w", err)
+	}
+
+	deleteDescendantsQuery := fmt.Sprintf(`
+		MATCH (fs:FileScope {repo: $repo})-[:CONTAINS*]->(descendant)
+		WHERE fs.id IN $fileIds AND NOT %s
+		DETACH DELETE descendant
+	`, containedByOtherFile("descendant"))
+	if _, err := cg.db.ExecuteWrite(ctx, deleteDescendantsQuery, params); err != nil {
+		return fmt.Errorf("failed to delete descendant nodes: %w", err)
 	}
-	cg.logger.Debug("Phase 1.5: Deleted orphaned nodes by fileId", zap.String("repo", repoName))
 
-	// Phase 2: Delete the FileScope nodes themselves
 	deleteFileScopesQuery := `
 		MATCH (fs:FileScope {repo: $repo})
+		WHERE fs.id IN $fileIds
 		DETACH DELETE fs
 	`
-	_, err = cg.db.ExecuteWrite(ctx, deleteFileScopesQuery, map[string]any{"repo": repoName})
-	if err != nil {
+	if _, err := cg.db.ExecuteWrite(ctx, deleteFileScopesQuery, params); err != nil {
 		return fmt.Errorf("failed to delete FileScope nodes: %w", err)
 	}
-	cg.logger.Debug("Phase 2: Deleted FileScope nodes", zap.String("repo", repoName))
 
-	cg.logger.Info("Neo4j cleanup completed for repository", zap.String("repo", repoName))
+	cg.fileIDCacheMu.Lock()
+	for _, id := range fileIDs {
+		delete(cg.fileIDCache, id)
+	}
+	cg.fileIDCacheMu.Unlock()
+
+	cg.logger.Debug("Deleted file nodes", zap.String("repo", repoName), zap.Int("files", len(fileIDs)))
 	return nil
 }
 
+// GetFileIDs returns the IDs of all FileScope nodes stored for a repository
+func (cg *CodeGraph) GetFileIDs(ctx context.Context, repoName string) ([]int32, error) {
+	query := `
+		MATCH (fs:FileScope {repo: $repo})
+		RETURN fs.id AS id
+	`
+	records, err := cg.db.ExecuteRead(ctx, query, map[string]any{"repo": repoName})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query file IDs: %w", err)
+	}
+
+	fileIDs := make([]int32, 0, len(records))
+	for _, record := range records {
+		if id, ok := record["id"].(int64); ok {
+			fileIDs = append(fileIDs, int32(id))
+		}
+	}
+	return fileIDs, nil
+}
+
 // ExecuteRead executes a read-only Cypher query and returns the raw records.
 // This is exposed for use by higher-level query APIs (e.g., codeapi package).
 func (cg *CodeGraph) ExecuteRead(ctx context.Context, query string, params map[string]any) ([]map[string]any, error) {
@@ -2322,6 +2923,41 @@ func (cg *CodeGraph) FindClassByName(ctx context.Context, filePath string, class
 	return nodes[0], nil
 }
 
+// EntityRef names a node and the file it is declared in
+type EntityRef struct {
+	ID       ast.NodeID
+	Name     string
+	FilePath string
+}
+
+// GetLatestEntities returns the functions or classes declared in the newest
+// indexed version of each file of a repository. With ast.NodeTypeFileScope
+// it returns those file versions themselves.
+func (cg *CodeGraph) GetLatestEntities(ctx context.Context, repoName string, nodeType ast.NodeType) ([]EntityRef, error) {
+	if nodeType != ast.NodeTypeFunction && nodeType != ast.NodeTypeClass && nodeType != ast.NodeTypeFileScope {
+		return nil, fmt.Errorf("unsupported node type %d: expected a function, class or file scope", nodeType)
+	}
+	query := fmt.Sprintf(`
+		MATCH (f:FileScope {repo: $repo})
+		WITH f.path AS path, max(f.fileId) AS fileId
+		MATCH (n:%s {fileId: fileId})
+		RETURN n.id AS id, n.name AS name, path
+	`, cg.getNodeLabel(nodeType))
+	records, err := cg.db.ExecuteRead(ctx, query, map[string]any{"repo": repoName})
+	if err != nil {
+		return nil, err
+	}
+
+	entities := make([]EntityRef, 0, len(records))
+	for _, record := range records {
+		id, _ := record["id"].(int64)
+		name, _ := record["name"].(string)
+		path, _ := record["path"].(string)
+		entities = append(entities, EntityRef{ID: ast.NodeID(id), Name: name, FilePath: path})
+	}
+	return entities, nil
+}
+
 // FindFileByPath finds a file node by its path in a repository
 func (cg *CodeGraph) FindFileByPath(ctx context.Context, repoName string, filePath string) (*ast.Node, error) {
 	query := `
@@ -2347,6 +2983,68 @@ func (cg *CodeGraph) GetClassMethods(ctx context.Context, classID ast.NodeID) ([
 	return cg.GetMethodsOfClass(ctx, classID)
 }
 
+// FunctionRelations are the functions related to each function of a file,
+// keyed by the function's ID
+type FunctionRelations struct {
+	Callees   map[ast.NodeID][]*ast.Node // called directly
+	Callers   map[ast.NodeID][]*ast.Node // calling it directly
+	SameClass map[ast.NodeID][]*ast.Node // the other methods of its class
+}
+
+// GetFunctionRelationsOfFile returns the callees, callers and sibling
+// methods of all functions of a file in one query
+func (cg *CodeGraph) GetFunctionRelationsOfFile(ctx context.Context, fileID int32) (*FunctionRelations, error) {
+	query := `
+		MATCH (f:Function {fileId: $fileId})-[:CONTAINS*]->(:FunctionCall)-[:CALLS_FUNCTION]->(r:Function)
+		RETURN DISTINCT f.id AS functionId, 'callee' AS relation, r
+		UNION
+		MATCH (r:Function)-[:CONTAINS*]->(:FunctionCall)-[:CALLS_FUNCTION]->(f:Function {fileId: $fileId})
+		RETURN DISTINCT f.id AS functionId, 'caller' AS relation, r
+		UNION
+		MATCH (r:Function)<-[:CONTAINS]-(:Class)-[:CONTAINS]->(f:Function {fileId: $fileId})
+		WHERE r.id <> f.id
+		RETURN DISTINCT f.id AS functionId, 'same_class' AS relation, r
+	`
+	records, err := cg.db.ExecuteRead(ctx, query, map[string]any{"fileId": int64(fileID)})
+	if err != nil {
+		return nil, fmt.Errorf("failed to find function relations: %w", err)
+	}
+
+	relations := &FunctionRelations{
+		Callees:   make(map[ast.NodeID][]*ast.Node),
+		Callers:   make(map[ast.NodeID][]*ast.Node),
+		SameClass: make(map[ast.NodeID][]*ast.Node),
+	}
+	byRelation := map[string]map[ast.NodeID][]*ast.Node{
+		"callee":     relations.Callees,
+		"caller":     relations.Callers,
+		"same_class": relations.SameClass,
+	}
+	var nodes []*ast.Node
+	for _, record := range records {
+		nodeMap, ok := record["r"].(map[string]any)
+		if !ok {
+			continue
+		}
+		related, ok := byRelation[fmt.Sprint(record["relation"])]
+		if !ok {
+			continue
+		}
+		node, err := cg.recordToNode(nodeMap)
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert record to node: %w", err)
+		}
+		functionID := ast.NodeID(cg.convertToInt64(record["functionId"]))
+		related[functionID] = append(related[functionID], node)
+		nodes = append(nodes, node)
+	}
+
+	if err := cg.rehydrate(ctx, nodes); err != nil {
+		return nil, err
+	}
+	return relations, nil
+}
+
 // -----------------------------------------------------------------------------
 // Git Churn Support Methods
 // -----------------------------------------------------------------------------
@@ -0,0 +1,139 @@
+package codegraph
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/armchr/codeapi/internal/config"
+
+	"go.uber.org/zap"
+)
+
+// cleanDB simulates a repository whose files each hold the same number of
+// contained and loose nodes, and deletes at most $limit of them per query
+type cleanDB struct {
+	GraphDatabase
+	files            int
+	containedPerFile int64
+	loosePerFile     int64
+
+	remaining map[string]int64 // by phase, for the current file chunk
+	writes    int
+	maxLimit  int
+	maxChunk  int
+}
+
+func (db *cleanDB) ExecuteRead(ctx context.Context, query string, params map[string]any) ([]map[string]any, error) {
+	records := make([]map[string]any, db.files)
+	for i := range records {
+		records[i] = map[string]any{"id": int64(i + 1)}
+	}
+	return records, nil
+}
+
+func (db *cleanDB) ExecuteReadSingle(ctx context.Context, query string, params map[string]any) (map[string]any, error) {
+	files := int64(db.files)
+	return map[string]any{"fileScopes": files, "nodes": files * (db.containedPerFile + db.loosePerFile)}, nil
+}
+
+func (db *cleanDB) ExecuteWriteSingle(ctx context.Context, query string, params map[string]any) (map[string]any, error) {
+	db.writes++
+	chunk := len(params["fileIds"].([]int64))
+	db.maxChunk = max(db.maxChunk, chunk)
+
+	var phase string
+	switch {
+	case strings.Contains(query, "NOT (descendant)-[:CONTAINS]->()"):
+		phase = "contained"
+	case strings.Contains(query, "n.fileId IN"):
+		phase = "loose"
+	default:
+		// FileScopes go last; the next chunk starts afresh
+		db.remaining = nil
+		return map[string]any{"deleted": int64(chunk)}, nil
+	}
+
+	if db.remaining == nil {
+		db.remaining = map[string]int64{
+			"contained": int64(chunk) * db.containedPerFile,
+			"loose":     int64(chunk) * db.loosePerFile,
+		}
+	}
+	limit := params["limit"].(int)
+	db.maxLimit = max(db.maxLimit, limit)
+	n := min(int64(limit), db.remaining[phase])
+	db.remaining[phase] -= n
+	return map[string]any{"deleted": n}, nil
+}
+
+func TestCleanRepositoryDeletesInBatches(t *testing.T) {
+	tests := []struct {
+		name             string
+		files            int
+		containedPerFile int64
+		loosePerFile     int64
+		batchSize        int
+		wantWrites       int
+	}{
+		// Per chunk: ceil(n/limit) full batches, one empty batch per phase, one FileScope delete
+		{"empty repository", 0, 0, 0, 10, 0},
+		{"single chunk", 4, 25, 0, 10, 10 + 1 + 1 + 1},
+		{"several chunks", cleanFileChunk*2 + 1, 3, 1, 1000, (2+1+1+1+1)*2 + (1 + 1 + 1 + 1 + 1)},
+		{"default batch size", 1, 25000, 0, 0, 3 + 1 + 1 + 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{}
+			cfg.CodeGraph.DeleteBatchSize = tt.batchSize
+			db := &cleanDB{files: tt.files, containedPerFile: tt.containedPerFile, loosePerFile: tt.loosePerFile}
+			cg := NewCodeGraphWithDatabase(db, cfg, zap.NewNop())
+
+			var last, total int64
+			err := cg.CleanRepositoryWithProgress(context.Background(), "repo", func(deleted, n int64) {
+				if deleted < last {
+					t.Errorf("progress went from %d back to %d", last, deleted)
+				}
+				last, total = deleted, n
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			wantTotal := int64(tt.files) * (1 + tt.containedPerFile + tt.loosePerFile)
+			if last != wantTotal || total != wantTotal {
+				t.Errorf("progress ended at %d/%d, want %d/%d", last, total, wantTotal, wantTotal)
+			}
+			if db.writes != tt.wantWrites {
+				t.Errorf("ran %d delete queries, want %d", db.writes, tt.wantWrites)
+			}
+			if db.maxChunk > cleanFileChunk {
+				t.Errorf("deleted %d files in one query, want at most %d", db.maxChunk, cleanFileChunk)
+			}
+			wantLimit := cfg.CodeGraph.GetDefaults().DeleteBatchSize
+			if tt.files > 0 && db.maxLimit != wantLimit {
+				t.Errorf("batch limit = %d, want %d", db.maxLimit, wantLimit)
+			}
+		})
+	}
+}
+
+func TestCleanRepositoryStopsWhenCancelled(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.CodeGraph.DeleteBatchSize = 1
+	db := &cleanDB{files: 1, containedPerFile: 100}
+	cg := NewCodeGraphWithDatabase(db, cfg, zap.NewNop())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	err := cg.CleanRepositoryWithProgress(ctx, "repo", func(deleted, total int64) {
+		if deleted == 5 {
+			cancel()
+		}
+	})
+	if err != context.Canceled {
+		t.Fatalf("err = %v, want context.Canceled", err)
+	}
+	if db.writes != 5 {
+		t.Errorf("ran %d delete queries after cancelling at 5, want 5", db.writes)
+	}
+}
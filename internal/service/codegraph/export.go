@@ -0,0 +1,185 @@
+package codegraph
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// ExportedNode is a graph node with its labels and raw properties, as
+// written to and read from repository backups
+type ExportedNode struct {
+	Labels     []string       `json:"labels"`
+	Properties map[string]any `json:"properties"`
+}
+
+// ExportedRelation is a relationship between two nodes identified by their
+// id property
+type ExportedRelation struct {
+	Type       string         `json:"type"`
+	From       int64          `json:"from"`
+	To         int64          `json:"to"`
+	Properties map[string]any `json:"properties,omitempty"`
+}
+
+// identifierPattern matches labels and relationship types that can be
+// spliced into a query. Backups are external input, so anything else is
+// rejected rather than escaped.
+var identifierPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// ExportFiles returns the nodes belonging to the given files of a repository
+// and the relationships leaving them. Like CleanRepository, nodes are matched
+// on their fileId property so nodes outside the CONTAINS tree are included.
+// Metadata held by the overflow store is put back into the properties.
+func (cg *CodeGraph) ExportFiles(ctx context.Context, fileIDs []int32) ([]ExportedNode, []ExportedRelation, error) {
+	if len(fileIDs) == 0 {
+		return nil, nil, nil
+	}
+	ids := make([]int64, len(fileIDs))
+	for i, id := range fileIDs {
+		ids[i] = int64(id)
+	}
+	params := map[string]any{"fileIds": ids}
+
+	nodeQuery := `
+		MATCH (n)
+		WHERE n.fileId IN $fileIds
+		RETURN labels(n) AS labels, properties(n) AS props
+	`
+	records, err := cg.db.ExecuteRead(ctx, nodeQuery, params)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to export nodes: %w", err)
+	}
+	nodes := make([]ExportedNode, 0, len(records))
+	for _, record := range records {
+		node := ExportedNode{Properties: toPropertyMap(record["props"])}
+		if labels, ok := record["labels"].([]any); ok {
+			for _, label := range labels {
+				if s, ok := label.(string); ok {
+					node.Labels = append(node.Labels, s)
+				}
+			}
+		}
+		nodes = append(nodes, node)
+	}
+
+	relQuery := `
+		MATCH (n)-[r]->(m)
+		WHERE n.fileId IN $fileIds
+		RETURN type(r) AS type, n.id AS from, m.id AS to, properties(r) AS props
+	`
+	records, err = cg.db.ExecuteRead(ctx, relQuery, params)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to export relationships: %w", err)
+	}
+	relations := make([]ExportedRelation, 0, len(records))
+	for _, record := range records {
+		relType, _ := record["type"].(string)
+		relations = append(relations, ExportedRelation{
+			Type:       relType,
+			From:       cg.convertToInt64(record["from"]),
+			To:         cg.convertToInt64(record["to"]),
+			Properties: toPropertyMap(record["props"]),
+		})
+	}
+
+	if err := cg.inlineOverflow(ctx, nodes, relations); err != nil {
+		return nil, nil, err
+	}
+	return nodes, relations, nil
+}
+
+// ImportNodes writes exported nodes, replacing the properties of nodes that
+// already exist with the same labels and id. Oversized metadata is moved to
+// the overflow store as when indexing.
+func (cg *CodeGraph) ImportNodes(ctx context.Context, nodes []ExportedNode) error {
+	byLabels := make(map[string][]map[string]any)
+	var spilled []OverflowValue
+	for _, node := range nodes {
+		if len(node.Labels) == 0 {
+			return fmt.Errorf("node without labels")
+		}
+		for _, label := range node.Labels {
+			if !identifierPattern.MatchString(label) {
+				return fmt.Errorf("invalid node label %q", label)
+			}
+		}
+		id, ok := node.Properties["id"]
+		if !ok {
+			return fmt.Errorf("node without id property")
+		}
+		props := maps.Clone(node.Properties)
+		nodeSpilled, err := cg.spillNode(props, cg.convertToInt64(id))
+		if err != nil {
+			return err
+		}
+		spilled = append(spilled, nodeSpilled...)
+		labels := append([]string(nil), node.Labels...)
+		sort.Strings(labels)
+		key := strings.Join(labels, ":")
+		byLabels[key] = append(byLabels[key], map[string]any{"id": id, "props": props})
+	}
+	if err := cg.saveOverflow(ctx, spilled); err != nil {
+		return err
+	}
+
+	for labels, rows := range byLabels {
+		query := fmt.Sprintf(`
+			UNWIND $rows AS row
+			MERGE (n:%s {id: row.id})
+			SET n = row.props
+		`, labels)
+		if _, err := cg.db.ExecuteWrite(ctx, query, map[string]any{"rows": rows}); err != nil {
+			return fmt.Errorf("failed to import %s nodes: %w", labels, err)
+		}
+	}
+	return nil
+}
+
+// ImportRelations recreates exported relationships. Relationships whose end
+// node is missing (for example a call into another repository) are skipped.
+func (cg *CodeGraph) ImportRelations(ctx context.Context, relations []ExportedRelation) error {
+	byType := make(map[string][]map[string]any)
+	var spilled []OverflowValue
+	for _, rel := range relations {
+		if !identifierPattern.MatchString(rel.Type) {
+			return fmt.Errorf("invalid relationship type %q", rel.Type)
+		}
+		props := maps.Clone(rel.Properties)
+		if props == nil {
+			props = map[string]any{}
+		}
+		relSpilled, err := cg.spillRelation(props, rel.Type, rel.From, rel.To)
+		if err != nil {
+			return err
+		}
+		spilled = append(spilled, relSpilled...)
+		byType[rel.Type] = append(byType[rel.Type], map[string]any{"from": rel.From, "to": rel.To, "props": props})
+	}
+	if err := cg.saveOverflow(ctx, spilled); err != nil {
+		return err
+	}
+
+	for relType, rows := range byType {
+		query := fmt.Sprintf(`
+			UNWIND $rows AS row
+			MATCH (parent {id: row.from}), (child {id: row.to})
+			MERGE (parent)-[r:%s]->(child)
+			SET r = row.props
+		`, relType)
+		if _, err := cg.db.ExecuteWrite(ctx, query, map[string]any{"rows": rows}); err != nil {
+			return fmt.Errorf("failed to import %s relationships: %w", relType, err)
+		}
+	}
+	return nil
+}
+
+func toPropertyMap(v any) map[string]any {
+	if props, ok := v.(map[string]any); ok {
+		return props
+	}
+	return map[string]any{}
+}
@@ -25,6 +25,14 @@ type GraphDatabase interface {
 	VerifyConnectivity(ctx context.Context) error
 }
 
+// DeferredWriter is implemented by databases that can hold back a write
+// whose records are not needed, such as ResilientDatabase while Neo4j is
+// unavailable. The write is applied later, in order with the other
+// deferred writes.
+type DeferredWriter interface {
+	DeferWrite(ctx context.Context, query string, params map[string]any) error
+}
+
 // GraphNode represents a node returned from the graph database
 type GraphNode interface {
 	GetProperties() map[string]any
@@ -0,0 +1,214 @@
+package codegraph
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+func init() {
+	// Query parameters nest these under interface values; basic types and
+	// their slices are known to gob already
+	gob.Register(map[string]any{})
+	gob.Register([]any{})
+	gob.Register([]map[string]any{})
+	gob.Register(time.Time{})
+}
+
+// walEntry is a graph write paused while Neo4j was unavailable
+type walEntry struct {
+	Query  string
+	Params map[string]any
+}
+
+// writeAheadLog keeps paused graph writes on disk, in order, until they are
+// replayed. Each entry is a length-prefixed gob record encoded on its own,
+// so a log left by an earlier process can be appended to. The file is only
+// created by the first append.
+type writeAheadLog struct {
+	mu      sync.Mutex
+	path    string
+	pending int
+}
+
+// openWriteAheadLog opens the log at path, keeping the entries left by an
+// earlier process. A record that process did not finish writing is dropped,
+// so new entries are not appended behind it.
+func openWriteAheadLog(path string) (*writeAheadLog, error) {
+	w := &writeAheadLog{path: path}
+	entries, err := w.read()
+	if err != nil {
+		return nil, err
+	}
+	if err := w.rewrite(entries); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// Pending returns the number of entries waiting to be replayed
+func (w *writeAheadLog) Pending() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.pending
+}
+
+// Append adds an entry at the end of the log
+func (w *writeAheadLog) Append(entry walEntry) error {
+	record, err := encodeWALEntry(entry)
+	if err != nil {
+		return err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err := os.MkdirAll(filepath.Dir(w.path), 0o755); err != nil {
+		return fmt.Errorf("failed to create write-ahead log directory: %w", err)
+	}
+	f, err := os.OpenFile(w.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open write-ahead log: %w", err)
+	}
+	if _, err := f.Write(record); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to append to write-ahead log: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to append to write-ahead log: %w", err)
+	}
+	w.pending++
+	return nil
+}
+
+// Replay applies the entries to fn in order, including entries appended
+// while it runs, and removes the applied ones from the log. It stops at the
+// first error, leaving that entry and the following ones in the log.
+func (w *writeAheadLog) Replay(fn func(walEntry) error) (int, error) {
+	replayed := 0
+	for {
+		w.mu.Lock()
+		entries, err := w.read()
+		w.mu.Unlock()
+		if err != nil {
+			return replayed, err
+		}
+		if len(entries) == 0 {
+			return replayed, nil
+		}
+
+		applied := 0
+		var applyErr error
+		for _, entry := range entries {
+			if applyErr = fn(entry); applyErr != nil {
+				break
+			}
+			applied++
+		}
+		replayed += applied
+		if err := w.discard(applied); err != nil {
+			return replayed, err
+		}
+		if applyErr != nil {
+			return replayed, applyErr
+		}
+	}
+}
+
+// discard removes the first n entries of the log
+func (w *writeAheadLog) discard(n int) error {
+	if n == 0 {
+		return nil
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	entries, err := w.read()
+	if err != nil {
+		return err
+	}
+	return w.rewrite(entries[min(n, len(entries)):])
+}
+
+// rewrite replaces the content of the log with entries, removing the file
+// when there are none. Callers hold mu.
+func (w *writeAheadLog) rewrite(entries []walEntry) error {
+	w.pending = len(entries)
+	if len(entries) == 0 {
+		if err := os.Remove(w.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to remove write-ahead log: %w", err)
+		}
+		return nil
+	}
+
+	var buf bytes.Buffer
+	for _, entry := range entries {
+		record, err := encodeWALEntry(entry)
+		if err != nil {
+			return err
+		}
+		buf.Write(record)
+	}
+	tmp := w.path + ".tmp"
+	if err := os.WriteFile(tmp, buf.Bytes(), 0o600); err != nil {
+		return fmt.Errorf("failed to rewrite write-ahead log: %w", err)
+	}
+	if err := os.Rename(tmp, w.path); err != nil {
+		return fmt.Errorf("failed to rewrite write-ahead log: %w", err)
+	}
+	return nil
+}
+
+// read decodes every entry of the log. A record cut short by a crash while
+// it was appended ends the log. Callers hold mu.
+func (w *writeAheadLog) read() ([]walEntry, error) {
+	f, err := os.Open(w.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open write-ahead log: %w", err)
+	}
+	defer f.Close()
+
+	var entries []walEntry
+	r := bufio.NewReader(f)
+	for {
+		var size uint32
+		if err := binary.Read(r, binary.BigEndian, &size); err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				return entries, nil
+			}
+			return nil, fmt.Errorf("failed to read write-ahead log: %w", err)
+		}
+		record := make([]byte, size)
+		if _, err := io.ReadFull(r, record); err != nil {
+			if errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF) {
+				return entries, nil
+			}
+			return nil, fmt.Errorf("failed to read write-ahead log: %w", err)
+		}
+		var entry walEntry
+		if err := gob.NewDecoder(bytes.NewReader(record)).Decode(&entry); err != nil {
+			return nil, fmt.Errorf("failed to decode write-ahead log entry %d: %w", len(entries), err)
+		}
+		entries = append(entries, entry)
+	}
+}
+
+func encodeWALEntry(entry walEntry) ([]byte, error) {
+	var body bytes.Buffer
+	if err := gob.NewEncoder(&body).Encode(entry); err != nil {
+		return nil, fmt.Errorf("failed to encode write-ahead log entry: %w", err)
+	}
+	record := make([]byte, 4, 4+body.Len())
+	binary.BigEndian.PutUint32(record, uint32(body.Len()))
+	return append(record, body.Bytes()...), nil
+}
@@ -3,32 +3,62 @@ package codegraph
 import (
 	"context"
 	"fmt"
+	"time"
+
+	"github.com/armchr/codeapi/internal/config"
+	"github.com/armchr/codeapi/internal/metrics"
+	"github.com/armchr/codeapi/internal/tracing"
 
 	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+	"go.opentelemetry.io/otel/attribute"
+	neo4jconfig "github.com/neo4j/neo4j-go-driver/v5/neo4j/config"
 	"go.uber.org/zap"
 )
 
 // Neo4jDatabase implements the GraphDatabase interface using Neo4j
 type Neo4jDatabase struct {
-	driver neo4j.DriverWithContext
-	logger *zap.Logger
+	driver       neo4j.DriverWithContext
+	queryTimeout time.Duration
+	logger       *zap.Logger
 }
 
-// NewNeo4jDatabase creates a new Neo4j database instance
-func NewNeo4jDatabase(uri, username, password string, logger *zap.Logger) (*Neo4jDatabase, error) {
-	driver, err := neo4j.NewDriverWithContext(uri, neo4j.BasicAuth(username, password, ""))
+// NewNeo4jDatabase creates a new Neo4j database instance. Unset pool fields
+// keep the driver defaults.
+func NewNeo4jDatabase(uri, username, password string, pool config.PoolConfig, logger *zap.Logger) (*Neo4jDatabase, error) {
+	driver, err := neo4j.NewDriverWithContext(uri, neo4j.BasicAuth(username, password, ""), func(c *neo4jconfig.Config) {
+		if pool.MaxOpenConns > 0 {
+			c.MaxConnectionPoolSize = pool.MaxOpenConns
+		}
+		if pool.ConnMaxLifetimeSeconds > 0 {
+			c.MaxConnectionLifetime = pool.ConnMaxLifetime()
+		}
+		if pool.AcquireTimeoutSeconds > 0 {
+			c.ConnectionAcquisitionTimeout = pool.AcquireTimeout()
+		}
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to create Neo4j driver: %w", err)
 	}
 
 	db := &Neo4jDatabase{
-		driver: driver,
-		logger: logger,
+		driver:       driver,
+		queryTimeout: pool.QueryTimeout(),
+		logger:       logger,
 	}
 
 	return db, nil
 }
 
+// txOptions returns the transaction settings for managed transactions. The
+// query timeout is enforced by the server, which also releases locks held by
+// a transaction that ran past it.
+func (db *Neo4jDatabase) txOptions() []func(*neo4j.TransactionConfig) {
+	if db.queryTimeout <= 0 {
+		return nil
+	}
+	return []func(*neo4j.TransactionConfig){neo4j.WithTxTimeout(db.queryTimeout)}
+}
+
 // VerifyConnectivity checks if the database connection is working
 func (db *Neo4jDatabase) VerifyConnectivity(ctx context.Context) error {
 	return db.driver.VerifyConnectivity(ctx)
@@ -44,6 +74,8 @@ func (db *Neo4jDatabase) ExecuteRead(ctx context.Context, query string, params m
 	session := db.driver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
 	defer session.Close(ctx)
 
+	ctx, span := tracing.Start(ctx, "neo4j.read", attribute.String("repo", queryRepo(params)))
+	done := metrics.TimeStoreQuery(metrics.StoreNeo4j, "read", queryRepo(params))
 	result, err := session.ExecuteRead(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
 		result, err := tx.Run(ctx, query, params)
 		if err != nil {
@@ -72,7 +104,9 @@ func (db *Neo4jDatabase) ExecuteRead(ctx context.Context, query string, params m
 		}
 
 		return records, nil
-	})
+	}, db.txOptions()...)
+	done(err)
+	tracing.End(span, err)
 
 	if err != nil {
 		db.logger.Error("Failed to execute read query", zap.String("query", query), zap.Error(err))
@@ -87,6 +121,8 @@ func (db *Neo4jDatabase) ExecuteWrite(ctx context.Context, query string, params
 	session := db.driver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
 	defer session.Close(ctx)
 
+	ctx, span := tracing.Start(ctx, "neo4j.write", attribute.String("repo", queryRepo(params)))
+	done := metrics.TimeStoreQuery(metrics.StoreNeo4j, "write", queryRepo(params))
 	result, err := session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
 		result, err := tx.Run(ctx, query, params)
 		if err != nil {
@@ -115,7 +151,9 @@ func (db *Neo4jDatabase) ExecuteWrite(ctx context.Context, query string, params
 		}
 
 		return records, nil
-	})
+	}, db.txOptions()...)
+	done(err)
+	tracing.End(span, err)
 
 	if err != nil {
 		db.logger.Error("Failed to execute write query", zap.String("query", query), zap.Error(err))
@@ -125,27 +163,24 @@ func (db *Neo4jDatabase) ExecuteWrite(ctx context.Context, query string, params
 	return result.([]map[string]any), nil
 }
 
+// queryRepo returns the repository a query is scoped to, for metric labels
+func queryRepo(params map[string]any) string {
+	repo, _ := params["repo"].(string)
+	return repo
+}
+
 // ExecuteReadSingle executes a read-only Cypher query expecting a single record
 func (db *Neo4jDatabase) ExecuteReadSingle(ctx context.Context, query string, params map[string]any) (map[string]any, error) {
-	records, err := db.ExecuteRead(ctx, query, params)
-	if err != nil {
-		return nil, err
-	}
-
-	if len(records) == 0 {
-		return nil, fmt.Errorf("no records returned")
-	}
-
-	if len(records) > 1 {
-		return nil, fmt.Errorf("expected single record, got %d", len(records))
-	}
-
-	return records[0], nil
+	return singleRecord(db.ExecuteRead(ctx, query, params))
 }
 
 // ExecuteWriteSingle executes a write Cypher query expecting a single record
 func (db *Neo4jDatabase) ExecuteWriteSingle(ctx context.Context, query string, params map[string]any) (map[string]any, error) {
-	records, err := db.ExecuteWrite(ctx, query, params)
+	return singleRecord(db.ExecuteWrite(ctx, query, params))
+}
+
+// singleRecord returns the only record of a query result
+func singleRecord(records []map[string]any, err error) (map[string]any, error) {
 	if err != nil {
 		return nil, err
 	}
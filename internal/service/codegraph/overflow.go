@@ -0,0 +1,328 @@
+package codegraph
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/armchr/codeapi/internal/model/ast"
+	"go.uber.org/zap"
+)
+
+// overflowProperty lists, on a node or relationship, the metadata keys whose
+// values were moved to the overflow store
+const overflowProperty = "mdOverflow"
+
+// OverflowValue is a metadata value too large to be kept as a graph property
+type OverflowValue struct {
+	OwnerID int64  // ID of the node, or of the start node of a relationship
+	Edge    string // "<label>-><end node ID>" for a relationship value, empty for a node value
+	Key     string // Metadata key, without the md_ prefix
+	Value   string // JSON encoding of the value
+}
+
+// OverflowStore keeps oversized metadata values outside the graph, by the
+// ID of the node they belong to
+type OverflowStore interface {
+	SaveOverflow(ctx context.Context, values []OverflowValue) error
+	// LoadOverflow returns the values of the given nodes and of the
+	// relationships leaving them
+	LoadOverflow(ctx context.Context, ownerIDs []int64) ([]OverflowValue, error)
+	// DeleteOverflow removes the values of the given nodes and of the
+	// relationships leaving them
+	DeleteOverflow(ctx context.Context, ownerIDs []int64) error
+}
+
+// overflowRef stands in the metadata of a node read from the graph for a
+// value held by the overflow store until the node is rehydrated
+type overflowRef struct{}
+
+// SetOverflowStore sets the store metadata values larger than
+// code_graph.max_node_property_bytes and max_edge_property_bytes are moved
+// to. Without one, values of any size are written to the graph.
+func (cg *CodeGraph) SetOverflowStore(store OverflowStore) {
+	cg.overflowMu.Lock()
+	defer cg.overflowMu.Unlock()
+	cg.overflow = store
+}
+
+func (cg *CodeGraph) overflowStore() OverflowStore {
+	cg.overflowMu.RLock()
+	defer cg.overflowMu.RUnlock()
+	return cg.overflow
+}
+
+// edgeOverflowKey identifies a relationship among those leaving a node
+func edgeOverflowKey(label string, endID int64) string {
+	return fmt.Sprintf("%s->%d", label, endID)
+}
+
+// propertySize is the number of bytes a metadata value takes as a property
+func propertySize(value any) int {
+	switch v := value.(type) {
+	case nil:
+		return 0
+	case string:
+		return len(v)
+	default:
+		encoded, err := json.Marshal(v)
+		if err != nil {
+			return 0
+		}
+		return len(encoded)
+	}
+}
+
+// spillProperties moves the md_ properties larger than limit out of props,
+// which are the flattened properties of one node or relationship. A moved
+// property is set to null, so a value written earlier is removed from the
+// graph, and its key is listed under mdOverflow. mdOverflow is always set
+// so that properties of nodes written together in a batch have the same
+// keys. Nothing is moved when there is no overflow store or no limit.
+func spillProperties(props map[string]any, limit int, ownerID int64, edge string) ([]OverflowValue, error) {
+	var spilled []OverflowValue
+	var keys []string
+	for key, value := range props {
+		if !strings.HasPrefix(key, "md_") || propertySize(value) <= limit {
+			continue
+		}
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode metadata %s: %w", key, err)
+		}
+		spilled = append(spilled, OverflowValue{OwnerID: ownerID, Edge: edge, Key: key[3:], Value: string(encoded)})
+		keys = append(keys, key[3:])
+		props[key] = nil
+	}
+	if len(keys) > 0 {
+		sort.Strings(keys)
+		props[overflowProperty] = keys
+	} else {
+		props[overflowProperty] = nil
+	}
+	return spilled, nil
+}
+
+// spillNode applies the node property limit to the flattened properties of
+// a node
+func (cg *CodeGraph) spillNode(props map[string]any, nodeID int64) ([]OverflowValue, error) {
+	if cg.overflowStore() == nil || cg.maxNodeProperty <= 0 {
+		return nil, nil
+	}
+	return spillProperties(props, cg.maxNodeProperty, nodeID, "")
+}
+
+// spillRelation applies the relationship property limit to the flattened
+// metadata of a relationship
+func (cg *CodeGraph) spillRelation(props map[string]any, label string, parentID, childID int64) ([]OverflowValue, error) {
+	if cg.overflowStore() == nil || cg.maxEdgeProperty <= 0 {
+		return nil, nil
+	}
+	return spillProperties(props, cg.maxEdgeProperty, parentID, edgeOverflowKey(label, childID))
+}
+
+// hasOversizedProperty reports whether spillNode would move any of the
+// flattened properties of a node
+func (cg *CodeGraph) hasOversizedProperty(props map[string]any) bool {
+	if cg.overflowStore() == nil || cg.maxNodeProperty <= 0 {
+		return false
+	}
+	for key, value := range props {
+		if strings.HasPrefix(key, "md_") && propertySize(value) > cg.maxNodeProperty {
+			return true
+		}
+	}
+	return false
+}
+
+// saveOverflow stores spilled values. It runs before the graph write that
+// references them, so a node never lists a value that was not stored.
+func (cg *CodeGraph) saveOverflow(ctx context.Context, values []OverflowValue) error {
+	if len(values) == 0 {
+		return nil
+	}
+	if err := cg.overflowStore().SaveOverflow(ctx, values); err != nil {
+		return fmt.Errorf("failed to store oversized metadata: %w", err)
+	}
+	cg.logger.Debug("Moved oversized metadata to overflow store", zap.Int("values", len(values)))
+	return nil
+}
+
+// markOverflow puts overflowRef in node metadata for the keys the record
+// lists under mdOverflow. A key whose property is set again was rewritten
+// with a smaller value after it overflowed, and the property wins.
+func markOverflow(record map[string]any, metadata map[string]any) {
+	var keys []string
+	switch listed := record[overflowProperty].(type) {
+	case []string:
+		keys = listed
+	case []any:
+		for _, k := range listed {
+			if key, ok := k.(string); ok {
+				keys = append(keys, key)
+			}
+		}
+	}
+	for _, key := range keys {
+		if record["md_"+key] == nil {
+			metadata[key] = overflowRef{}
+		}
+	}
+}
+
+// rehydrate replaces the overflowRef values of nodes read from the graph
+// with the values held by the overflow store
+func (cg *CodeGraph) rehydrate(ctx context.Context, nodes []*ast.Node) error {
+	pending := make(map[int64]*ast.Node)
+	for _, node := range nodes {
+		for _, value := range node.MetaData {
+			if _, ok := value.(overflowRef); ok {
+				pending[int64(node.ID)] = node
+				break
+			}
+		}
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+
+	if store := cg.overflowStore(); store != nil {
+		ids := make([]int64, 0, len(pending))
+		for id := range pending {
+			ids = append(ids, id)
+		}
+		values, err := store.LoadOverflow(ctx, ids)
+		if err != nil {
+			return fmt.Errorf("failed to load oversized metadata: %w", err)
+		}
+		for _, v := range values {
+			node := pending[v.OwnerID]
+			if node == nil || v.Edge != "" {
+				continue
+			}
+			if _, ok := node.MetaData[v.Key].(overflowRef); !ok {
+				continue
+			}
+			var value any
+			if err := json.Unmarshal([]byte(v.Value), &value); err != nil {
+				return fmt.Errorf("failed to decode metadata %s of node %d: %w", v.Key, v.OwnerID, err)
+			}
+			node.MetaData[v.Key] = value
+		}
+	}
+
+	// Values the store does not have are left out rather than handed to
+	// callers as placeholders
+	for _, node := range pending {
+		for key, value := range node.MetaData {
+			if _, ok := value.(overflowRef); ok {
+				cg.logger.Warn("Oversized metadata value missing from overflow store",
+					zap.Int64("nodeId", int64(node.ID)), zap.String("key", key))
+				delete(node.MetaData, key)
+			}
+		}
+	}
+	return nil
+}
+
+// deleteFileOverflow removes the overflow values of the nodes of the given
+// files, and of the relationships leaving them, before the nodes are deleted.
+// With keepShared it skips the nodes another file still contains.
+func (cg *CodeGraph) deleteFileOverflow(ctx context.Context, params map[string]any, keepShared bool) error {
+	store := cg.overflowStore()
+	if store == nil {
+		return nil
+	}
+	shared := ""
+	if keepShared {
+		shared = "AND NOT " + containedByOtherFile("n")
+	}
+	query := fmt.Sprintf(`
+		MATCH (fs:FileScope {repo: $repo})-[:CONTAINS*0..]->(n)
+		WHERE fs.id IN $fileIds
+			AND (n.mdOverflow IS NOT NULL OR size([(n)-[r]->() WHERE r.mdOverflow IS NOT NULL | r]) > 0)
+			%s
+		RETURN DISTINCT n.id AS id
+	`, shared)
+	records, err := cg.db.ExecuteRead(ctx, query, params)
+	if err != nil {
+		return fmt.Errorf("failed to find nodes with oversized metadata: %w", err)
+	}
+	if len(records) == 0 {
+		return nil
+	}
+	ids := make([]int64, 0, len(records))
+	for _, record := range records {
+		ids = append(ids, cg.convertToInt64(record["id"]))
+	}
+	if err := store.DeleteOverflow(ctx, ids); err != nil {
+		return fmt.Errorf("failed to delete oversized metadata: %w", err)
+	}
+	return nil
+}
+
+// inlineOverflow puts the values of exported nodes and relationships held
+// by the overflow store back into their properties, so backups do not
+// depend on the store
+func (cg *CodeGraph) inlineOverflow(ctx context.Context, nodes []ExportedNode, relations []ExportedRelation) error {
+	store := cg.overflowStore()
+	if store == nil {
+		return nil
+	}
+	owners := make(map[int64]bool)
+	for _, node := range nodes {
+		if node.Properties[overflowProperty] != nil {
+			owners[cg.convertToInt64(node.Properties["id"])] = true
+		}
+	}
+	for _, rel := range relations {
+		if rel.Properties[overflowProperty] != nil {
+			owners[rel.From] = true
+		}
+	}
+	if len(owners) == 0 {
+		return nil
+	}
+
+	ids := make([]int64, 0, len(owners))
+	for id := range owners {
+		ids = append(ids, id)
+	}
+	values, err := store.LoadOverflow(ctx, ids)
+	if err != nil {
+		return fmt.Errorf("failed to load oversized metadata: %w", err)
+	}
+	byOwner := make(map[string]map[string]any)
+	for _, v := range values {
+		var value any
+		if err := json.Unmarshal([]byte(v.Value), &value); err != nil {
+			return fmt.Errorf("failed to decode metadata %s of node %d: %w", v.Key, v.OwnerID, err)
+		}
+		owner := fmt.Sprintf("%d/%s", v.OwnerID, v.Edge)
+		if byOwner[owner] == nil {
+			byOwner[owner] = make(map[string]any)
+		}
+		byOwner[owner]["md_"+v.Key] = value
+	}
+
+	inline := func(props map[string]any, owner string) {
+		if props[overflowProperty] == nil {
+			return
+		}
+		for key, value := range byOwner[owner] {
+			if props[key] == nil {
+				props[key] = value
+			}
+		}
+		delete(props, overflowProperty)
+	}
+	for _, node := range nodes {
+		inline(node.Properties, fmt.Sprintf("%d/", cg.convertToInt64(node.Properties["id"])))
+	}
+	for _, rel := range relations {
+		inline(rel.Properties, fmt.Sprintf("%d/%s", rel.From, edgeOverflowKey(rel.Type, rel.To)))
+	}
+	return nil
+}
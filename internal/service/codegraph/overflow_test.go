@@ -0,0 +1,209 @@
+package codegraph
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/armchr/codeapi/internal/config"
+	"github.com/armchr/codeapi/internal/model/ast"
+
+	"go.uber.org/zap"
+)
+
+// memoryOverflow is an OverflowStore kept in a map
+type memoryOverflow struct {
+	values map[OverflowValue]bool
+}
+
+func (s *memoryOverflow) SaveOverflow(ctx context.Context, values []OverflowValue) error {
+	for _, v := range values {
+		for old := range s.values {
+			if old.OwnerID == v.OwnerID && old.Edge == v.Edge && old.Key == v.Key {
+				delete(s.values, old)
+			}
+		}
+		s.values[v] = true
+	}
+	return nil
+}
+
+func (s *memoryOverflow) LoadOverflow(ctx context.Context, ownerIDs []int64) ([]OverflowValue, error) {
+	var values []OverflowValue
+	for v := range s.values {
+		for _, id := range ownerIDs {
+			if v.OwnerID == id {
+				values = append(values, v)
+			}
+		}
+	}
+	return values, nil
+}
+
+func (s *memoryOverflow) DeleteOverflow(ctx context.Context, ownerIDs []int64) error {
+	for v := range s.values {
+		for _, id := range ownerIDs {
+			if v.OwnerID == id {
+				delete(s.values, v)
+			}
+		}
+	}
+	return nil
+}
+
+// propertyDB keeps the properties last written for each node and returns
+// them from reads the way Neo4j does, with lists as []any
+type propertyDB struct {
+	GraphDatabase
+	nodes map[int64]map[string]any
+}
+
+func (db *propertyDB) ExecuteWrite(ctx context.Context, query string, params map[string]any) ([]map[string]any, error) {
+	props := make(map[string]any)
+	for key, value := range params {
+		if value == nil {
+			continue
+		}
+		if list, ok := value.([]string); ok {
+			items := make([]any, len(list))
+			for i, item := range list {
+				items[i] = item
+			}
+			value = items
+		}
+		props[key] = value
+	}
+	db.nodes[params["id"].(int64)] = props
+	return nil, nil
+}
+
+func (db *propertyDB) ExecuteRead(ctx context.Context, query string, params map[string]any) ([]map[string]any, error) {
+	var records []map[string]any
+	for _, props := range db.nodes {
+		records = append(records, map[string]any{"n": props})
+	}
+	return records, nil
+}
+
+func TestSpillProperties(t *testing.T) {
+	long := strings.Repeat("x", 20)
+	tests := []struct {
+		name      string
+		props     map[string]any
+		wantKeys  []string
+		wantProps map[string]any
+	}{
+		{
+			name:      "nothing oversized",
+			props:     map[string]any{"name": long, "md_doc": "short"},
+			wantProps: map[string]any{"name": long, "md_doc": "short", overflowProperty: nil},
+		},
+		{
+			name:      "first-class properties stay",
+			props:     map[string]any{"path": long},
+			wantProps: map[string]any{"path": long, overflowProperty: nil},
+		},
+		{
+			name:      "oversized metadata moves",
+			props:     map[string]any{"md_doc": long, "md_tags": []string{long}, "md_kind": "func"},
+			wantKeys:  []string{"doc", "tags"},
+			wantProps: map[string]any{"md_doc": nil, "md_tags": nil, "md_kind": "func", overflowProperty: []string{"doc", "tags"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spilled, err := spillProperties(tt.props, 10, 7, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			var keys []string
+			for _, v := range spilled {
+				if v.OwnerID != 7 {
+					t.Errorf("value %s owned by %d, want 7", v.Key, v.OwnerID)
+				}
+				keys = append(keys, v.Key)
+			}
+			if len(keys) != len(tt.wantKeys) {
+				t.Errorf("spilled %v, want %v", keys, tt.wantKeys)
+			}
+			if !reflect.DeepEqual(tt.props, tt.wantProps) {
+				t.Errorf("props = %v, want %v", tt.props, tt.wantProps)
+			}
+		})
+	}
+}
+
+func TestOverflowRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	doc := strings.Repeat("Documentation. ", 10)
+
+	tests := []struct {
+		name    string
+		store   bool
+		before  func(db *propertyDB, store *memoryOverflow)
+		wantDoc any
+	}{
+		{name: "without store the value stays in the graph", wantDoc: doc},
+		{name: "value rehydrated from store", store: true, wantDoc: doc},
+		{
+			name:  "value missing from store is left out",
+			store: true,
+			before: func(db *propertyDB, store *memoryOverflow) {
+				clear(store.values)
+			},
+		},
+		{
+			name:  "property written after the overflow wins",
+			store: true,
+			before: func(db *propertyDB, store *memoryOverflow) {
+				db.nodes[1]["md_doc"] = "short"
+			},
+			wantDoc: "short",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{}
+			cfg.CodeGraph.MaxNodePropertyBytes = 64
+			db := &propertyDB{nodes: make(map[int64]map[string]any)}
+			cg := NewCodeGraphWithDatabase(db, cfg, zap.NewNop())
+			store := &memoryOverflow{values: make(map[OverflowValue]bool)}
+			if tt.store {
+				cg.SetOverflowStore(store)
+			}
+
+			node := &ast.Node{ID: 1, NodeType: ast.NodeTypeFunction, Name: "f",
+				MetaData: map[string]any{"doc": doc, "kind": "func"}}
+			if err := cg.writeNodeReal(ctx, node); err != nil {
+				t.Fatal(err)
+			}
+			if stored := db.nodes[1]["md_doc"]; tt.store != (stored == nil) {
+				t.Errorf("graph holds md_doc = %v with store %v", stored, tt.store)
+			}
+			if tt.before != nil {
+				tt.before(db, store)
+			}
+
+			nodes, err := cg.readNodesByQuery(ctx, "n", "MATCH (n) RETURN n", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(nodes) != 1 {
+				t.Fatalf("read %d nodes, want 1", len(nodes))
+			}
+			got := nodes[0].MetaData
+			if got["doc"] != tt.wantDoc {
+				t.Errorf("doc = %v, want %v", got["doc"], tt.wantDoc)
+			}
+			if got["kind"] != "func" {
+				t.Errorf("kind = %v, want func", got["kind"])
+			}
+			for key, value := range got {
+				if _, ok := value.(overflowRef); ok {
+					t.Errorf("placeholder left for %s", key)
+				}
+			}
+		})
+	}
+}
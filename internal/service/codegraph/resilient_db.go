@@ -0,0 +1,425 @@
+package codegraph
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/armchr/codeapi/internal/config"
+	"github.com/armchr/codeapi/internal/metrics"
+
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+	"go.uber.org/zap"
+)
+
+// ErrGraphUnavailable is returned without querying the database while the
+// circuit breaker is open
+var ErrGraphUnavailable = errors.New("graph database unavailable: circuit breaker open")
+
+// BreakerState is the state of the circuit breaker of a ResilientDatabase
+type BreakerState int
+
+const (
+	BreakerClosed   BreakerState = iota // Queries reach the database
+	BreakerOpen                         // Queries are refused and deferrable writes logged
+	BreakerHalfOpen                     // The database answered a probe; logged writes are replayed
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half_open"
+	}
+	return "unknown"
+}
+
+// IsRetryableError reports whether err is a transient database failure that
+// the same query may get past when tried again: a lost or refused
+// connection, a transient Neo4j error, or a cluster that is electing a new
+// leader. Cancelled and timed out contexts are not retryable.
+func IsRetryableError(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	// The driver ran out of its own retries; look at what it gave up on
+	var limit *neo4j.TransactionExecutionLimit
+	if errors.As(err, &limit) {
+		return len(limit.Errors) > 0 && IsRetryableError(limit.Errors[len(limit.Errors)-1])
+	}
+	var connErr *neo4j.ConnectivityError
+	if errors.As(err, &connErr) {
+		return neo4j.IsRetryable(connErr)
+	}
+	var dbErr *neo4j.Neo4jError
+	if errors.As(err, &dbErr) {
+		return dbErr.IsRetriable()
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+	return errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ECONNRESET)
+}
+
+// backoff returns the wait before retry number attempt (1 for the first
+// retry): the initial backoff doubled per retry and capped, of which a random
+// half is jitter so that workers failing together do not retry together
+func backoff(cfg config.Neo4jRetryConfig, attempt int) time.Duration {
+	limit := cfg.InitialBackoff()
+	for i := 1; i < attempt && limit < cfg.MaxBackoff(); i++ {
+		limit *= 2
+	}
+	limit = min(limit, cfg.MaxBackoff())
+	if limit <= 0 {
+		return 0
+	}
+	return limit/2 + rand.N(limit/2+1)
+}
+
+// ResilientDatabase wraps a GraphDatabase with bounded retries of transient
+// failures and a circuit breaker. After BreakerThreshold consecutive queries
+// failed every attempt, the breaker opens: queries fail fast with
+// ErrGraphUnavailable, and writes made through DeferWrite are appended to a
+// write-ahead log instead. A background probe closes the breaker once the
+// database answers again and the log has been replayed in order.
+type ResilientDatabase struct {
+	db     GraphDatabase
+	cfg    config.Neo4jRetryConfig
+	wal    *writeAheadLog
+	logger *zap.Logger
+	sleep  func(ctx context.Context, d time.Duration) error
+
+	mu        sync.Mutex
+	state     BreakerState
+	failures  int           // Consecutive queries that failed every attempt
+	recovered chan struct{} // Closed when an open breaker closes again
+
+	ctx    context.Context // Ends the recovery probe on Close
+	cancel context.CancelFunc
+	probes sync.WaitGroup
+}
+
+// NewResilientDatabase wraps db with the retry policy and circuit breaker of
+// cfg, logging paused writes to walPath. Writes left in the log by an
+// earlier process are replayed as soon as the database answers.
+func NewResilientDatabase(db GraphDatabase, cfg config.Neo4jRetryConfig, walPath string, logger *zap.Logger) (*ResilientDatabase, error) {
+	wal, err := openWriteAheadLog(walPath)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	r := &ResilientDatabase{
+		db:     db,
+		cfg:    cfg.GetDefaults(),
+		wal:    wal,
+		logger: logger,
+		sleep:  sleepContext,
+		ctx:    ctx,
+		cancel: cancel,
+	}
+	if pending := wal.Pending(); pending > 0 {
+		logger.Warn("Replaying graph writes left in the write-ahead log",
+			zap.String("path", walPath), zap.Int("pending", pending))
+		r.mu.Lock()
+		r.open(0)
+		r.mu.Unlock()
+	}
+	metrics.SetGraphWALPending(wal.Pending())
+	return r, nil
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// State returns the state of the circuit breaker
+func (r *ResilientDatabase) State() BreakerState {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.state
+}
+
+// PendingWrites returns the writes waiting in the write-ahead log
+func (r *ResilientDatabase) PendingWrites() int {
+	return r.wal.Pending()
+}
+
+// ExecuteRead executes a read-only Cypher query, retrying transient failures
+func (r *ResilientDatabase) ExecuteRead(ctx context.Context, query string, params map[string]any) ([]map[string]any, error) {
+	var records []map[string]any
+	err := r.run(ctx, "read", func() error {
+		var err error
+		records, err = r.db.ExecuteRead(ctx, query, params)
+		return err
+	})
+	return records, err
+}
+
+// ExecuteWrite executes a write Cypher query, retrying transient failures.
+// The driver already retries within its managed transactions; these retries
+// cover failures that outlast the driver's own retry window.
+func (r *ResilientDatabase) ExecuteWrite(ctx context.Context, query string, params map[string]any) ([]map[string]any, error) {
+	var records []map[string]any
+	err := r.run(ctx, "write", func() error {
+		var err error
+		records, err = r.db.ExecuteWrite(ctx, query, params)
+		return err
+	})
+	return records, err
+}
+
+// ExecuteReadSingle executes a read-only Cypher query expecting a single record
+func (r *ResilientDatabase) ExecuteReadSingle(ctx context.Context, query string, params map[string]any) (map[string]any, error) {
+	return singleRecord(r.ExecuteRead(ctx, query, params))
+}
+
+// ExecuteWriteSingle executes a write Cypher query expecting a single record
+func (r *ResilientDatabase) ExecuteWriteSingle(ctx context.Context, query string, params map[string]any) (map[string]any, error) {
+	return singleRecord(r.ExecuteWrite(ctx, query, params))
+}
+
+// DeferWrite executes a write whose records are not needed. While the
+// breaker is open, or writes logged earlier are still waiting, the write is
+// appended to the write-ahead log instead and replayed after them, so
+// indexing carries on through a short outage. A write failing every
+// attempt is logged as well. Writes that cannot be logged fail.
+func (r *ResilientDatabase) DeferWrite(ctx context.Context, query string, params map[string]any) error {
+	logged, err := r.logIfPaused(query, params)
+	if logged || err != nil {
+		return err
+	}
+
+	_, err = r.ExecuteWrite(ctx, query, params)
+	if err == nil || !(errors.Is(err, ErrGraphUnavailable) || IsRetryableError(err)) {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.state == BreakerClosed {
+		// The writes after this one have to wait for its replay
+		r.logger.Error("Graph write failed every attempt, pausing graph writes", zap.Error(err))
+		r.open(r.cfg.BreakerCooldown())
+	}
+	if walErr := r.appendWAL(query, params); walErr != nil {
+		return errors.Join(err, walErr)
+	}
+	return nil
+}
+
+// logIfPaused appends the write to the log when writes are paused. The
+// decision is taken under mu, so a write is never logged after the recovery
+// probe found the log empty and closed the breaker.
+func (r *ResilientDatabase) logIfPaused(query string, params map[string]any) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.state == BreakerClosed && r.wal.Pending() == 0 {
+		return false, nil
+	}
+	return true, r.appendWAL(query, params)
+}
+
+func (r *ResilientDatabase) appendWAL(query string, params map[string]any) error {
+	if err := r.wal.Append(walEntry{Query: query, Params: params}); err != nil {
+		r.logger.Error("Failed to log paused graph write", zap.Error(err))
+		return err
+	}
+	metrics.SetGraphWALPending(r.wal.Pending())
+	return nil
+}
+
+// WaitForPendingWrites blocks until the writes in the write-ahead log have
+// been replayed, or ctx is done
+func (r *ResilientDatabase) WaitForPendingWrites(ctx context.Context) error {
+	r.mu.Lock()
+	if r.state == BreakerClosed && r.wal.Pending() == 0 {
+		r.mu.Unlock()
+		return nil
+	}
+	recovered := r.recovered
+	r.mu.Unlock()
+
+	r.logger.Info("Waiting for paused graph writes to be replayed", zap.Int("pending", r.wal.Pending()))
+	select {
+	case <-recovered:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("graph writes still paused: %w", ctx.Err())
+	}
+}
+
+// run calls fn until it succeeds, fails with an error that is not
+// retryable, or runs out of attempts, and feeds the outcome to the breaker
+func (r *ResilientDatabase) run(ctx context.Context, operation string, fn func() error) error {
+	if state := r.State(); state != BreakerClosed {
+		return ErrGraphUnavailable
+	}
+
+	var err error
+	for attempt := 1; ; attempt++ {
+		err = fn()
+		if err == nil || !IsRetryableError(err) {
+			break
+		}
+		if attempt >= r.cfg.MaxAttempts {
+			break
+		}
+		wait := backoff(r.cfg, attempt)
+		r.logger.Warn("Retrying graph query after transient failure",
+			zap.String("operation", operation),
+			zap.Int("attempt", attempt),
+			zap.Duration("backoff", wait),
+			zap.Error(err))
+		if r.sleep(ctx, wait) != nil {
+			break
+		}
+		metrics.StoreQueryRetried(metrics.StoreNeo4j, operation)
+	}
+
+	switch {
+	case ctx.Err() != nil:
+		// Says nothing about the database
+	case err != nil && IsRetryableError(err):
+		r.recordFailure(err)
+	default:
+		// Answered, even if with an error of the query itself
+		r.recordSuccess()
+	}
+	return err
+}
+
+func (r *ResilientDatabase) recordSuccess() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.state == BreakerClosed {
+		r.failures = 0
+	}
+}
+
+func (r *ResilientDatabase) recordFailure(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.state != BreakerClosed {
+		return
+	}
+	r.failures++
+	if r.failures >= r.cfg.BreakerThreshold {
+		r.logger.Error("Graph database unavailable, pausing graph writes",
+			zap.Int("consecutive_failures", r.failures),
+			zap.Duration("probe_interval", r.cfg.BreakerCooldown()),
+			zap.Error(err))
+		r.open(r.cfg.BreakerCooldown())
+	}
+}
+
+// open opens the breaker and starts probing the database after delay.
+// Callers hold mu.
+func (r *ResilientDatabase) open(delay time.Duration) {
+	r.state = BreakerOpen
+	r.recovered = make(chan struct{})
+	metrics.SetGraphBreakerOpen(true)
+
+	r.probes.Add(1)
+	go func() {
+		defer r.probes.Done()
+		r.recover(delay)
+	}()
+}
+
+// recover probes the database every cooldown until it answers, then
+// replays the write-ahead log and closes the breaker
+func (r *ResilientDatabase) recover(delay time.Duration) {
+	for {
+		if r.sleep(r.ctx, delay) != nil {
+			return
+		}
+		delay = r.cfg.BreakerCooldown()
+
+		if err := r.db.VerifyConnectivity(r.ctx); err != nil {
+			r.logger.Warn("Graph database still unavailable", zap.Error(err))
+			continue
+		}
+		r.setState(BreakerHalfOpen)
+
+		replayed, err := r.wal.Replay(r.replayEntry)
+		metrics.SetGraphWALPending(r.wal.Pending())
+		if err != nil {
+			r.logger.Warn("Failed to replay paused graph writes",
+				zap.Int("replayed", replayed),
+				zap.Int("pending", r.wal.Pending()),
+				zap.Error(err))
+			r.setState(BreakerOpen)
+			continue
+		}
+
+		r.mu.Lock()
+		if r.wal.Pending() > 0 {
+			// Logged after the replay read the log
+			r.mu.Unlock()
+			delay = 0
+			continue
+		}
+		r.state = BreakerClosed
+		r.failures = 0
+		close(r.recovered)
+		r.mu.Unlock()
+		metrics.SetGraphBreakerOpen(false)
+
+		r.logger.Info("Graph database recovered, resuming graph writes", zap.Int("replayed", replayed))
+		return
+	}
+}
+
+// replayEntry runs a logged write. A write the database rejects for a
+// reason other than its availability would be rejected forever, so it is
+// dropped rather than holding back the writes after it.
+func (r *ResilientDatabase) replayEntry(entry walEntry) error {
+	_, err := r.db.ExecuteWrite(r.ctx, entry.Query, entry.Params)
+	if err != nil && !IsRetryableError(err) && r.ctx.Err() == nil {
+		r.logger.Error("Dropping paused graph write rejected by the database",
+			zap.String("query", entry.Query), zap.Error(err))
+		return nil
+	}
+	return err
+}
+
+func (r *ResilientDatabase) setState(state BreakerState) {
+	r.mu.Lock()
+	r.state = state
+	r.mu.Unlock()
+}
+
+// VerifyConnectivity checks if the database connection is working
+func (r *ResilientDatabase) VerifyConnectivity(ctx context.Context) error {
+	return r.db.VerifyConnectivity(ctx)
+}
+
+// Close stops probing and closes the database. Writes still in the
+// write-ahead log stay there for the next process to replay.
+func (r *ResilientDatabase) Close(ctx context.Context) error {
+	r.cancel()
+	r.probes.Wait()
+	if pending := r.wal.Pending(); pending > 0 {
+		r.logger.Warn("Closing with graph writes still paused in the write-ahead log", zap.Int("pending", pending))
+	}
+	return r.db.Close(ctx)
+}
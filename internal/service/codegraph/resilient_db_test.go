@@ -0,0 +1,264 @@
+package codegraph
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/armchr/codeapi/internal/config"
+
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+	"go.uber.org/zap"
+)
+
+func TestIsRetryableError(t *testing.T) {
+	leader := &neo4j.Neo4jError{Code: "Neo.ClientError.Cluster.NotALeader"}
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"transient", fmt.Errorf("failed to execute write query: %w", &neo4j.Neo4jError{Code: "Neo.TransientError.General.DatabaseUnavailable"}), true},
+		{"not a leader", leader, true},
+		{"syntax error", &neo4j.Neo4jError{Code: "Neo.ClientError.Statement.SyntaxError"}, false},
+		{"terminated transaction", &neo4j.Neo4jError{Code: "Neo.TransientError.Transaction.Terminated"}, false},
+		{"driver retries exhausted", &neo4j.TransactionExecutionLimit{Cause: "timeout", Errors: []error{leader}}, true},
+		{"connection refused", fmt.Errorf("dial: %w", syscall.ECONNREFUSED), true},
+		{"cancelled", fmt.Errorf("failed: %w", context.Canceled), false},
+		{"plain error", errors.New("no records returned"), false},
+	}
+	for _, tt := range tests {
+		if got := IsRetryableError(tt.err); got != tt.want {
+			t.Errorf("%s: IsRetryableError = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	cfg := config.Neo4jRetryConfig{InitialBackoffMs: 100, MaxBackoffMs: 1000}
+	tests := []struct {
+		attempt  int
+		min, max time.Duration
+	}{
+		{1, 50 * time.Millisecond, 100 * time.Millisecond},
+		{3, 200 * time.Millisecond, 400 * time.Millisecond},
+		{10, 500 * time.Millisecond, 1000 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		for range 20 {
+			if got := backoff(cfg, tt.attempt); got < tt.min || got > tt.max {
+				t.Fatalf("backoff(%d) = %v, want within [%v, %v]", tt.attempt, got, tt.min, tt.max)
+			}
+		}
+	}
+}
+
+// flakyDB fails queries with a transient error while it is down or has
+// failures left, and records the writes it applies
+type flakyDB struct {
+	GraphDatabase
+	mu       sync.Mutex
+	down     bool
+	failures int
+	attempts int
+	writes   []string
+}
+
+var errUnavailable = &neo4j.Neo4jError{Code: "Neo.TransientError.General.DatabaseUnavailable"}
+
+func (db *flakyDB) query(query string, write bool) ([]map[string]any, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	db.attempts++
+	if db.down {
+		return nil, fmt.Errorf("failed to execute query: %w", errUnavailable)
+	}
+	if db.failures > 0 {
+		db.failures--
+		return nil, fmt.Errorf("failed to execute query: %w", errUnavailable)
+	}
+	if write {
+		db.writes = append(db.writes, query)
+	}
+	return []map[string]any{{"ok": true}}, nil
+}
+
+func (db *flakyDB) ExecuteRead(ctx context.Context, query string, params map[string]any) ([]map[string]any, error) {
+	return db.query(query, false)
+}
+
+func (db *flakyDB) ExecuteWrite(ctx context.Context, query string, params map[string]any) ([]map[string]any, error) {
+	return db.query(query, true)
+}
+
+func (db *flakyDB) VerifyConnectivity(ctx context.Context) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if db.down {
+		return errUnavailable
+	}
+	return nil
+}
+
+func (db *flakyDB) Close(ctx context.Context) error { return nil }
+
+func (db *flakyDB) setDown(down bool) {
+	db.mu.Lock()
+	db.down = down
+	db.mu.Unlock()
+}
+
+func (db *flakyDB) applied() []string {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	return append([]string(nil), db.writes...)
+}
+
+func newTestResilientDB(t *testing.T, db GraphDatabase, cfg config.Neo4jRetryConfig) *ResilientDatabase {
+	t.Helper()
+	r, err := NewResilientDatabase(db, cfg, filepath.Join(t.TempDir(), "graph.wal"), zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Keep retries and recovery probes fast, still honouring Close
+	r.sleep = func(ctx context.Context, d time.Duration) error {
+		return sleepContext(ctx, time.Millisecond)
+	}
+	t.Cleanup(func() { r.Close(context.Background()) })
+	return r
+}
+
+func TestResilientDatabaseRetries(t *testing.T) {
+	tests := []struct {
+		name         string
+		failures     int
+		wantAttempts int
+		wantErr      bool
+	}{
+		{"succeeds at once", 0, 1, false},
+		{"recovers within the attempts", 2, 3, false},
+		{"runs out of attempts", 5, 3, true},
+	}
+	for _, tt := range tests {
+		db := &flakyDB{failures: tt.failures}
+		r := newTestResilientDB(t, db, config.Neo4jRetryConfig{MaxAttempts: 3, BreakerThreshold: 2})
+
+		_, err := r.ExecuteWrite(context.Background(), "CREATE (n)", nil)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: ExecuteWrite error = %v, want error %v", tt.name, err, tt.wantErr)
+		}
+		if db.attempts != tt.wantAttempts {
+			t.Errorf("%s: %d attempts, want %d", tt.name, db.attempts, tt.wantAttempts)
+		}
+		if got := r.State(); got != BreakerClosed {
+			t.Errorf("%s: breaker %s after one failed query, want closed", tt.name, got)
+		}
+	}
+}
+
+func TestResilientDatabasePausesWritesUntilRecovery(t *testing.T) {
+	ctx := context.Background()
+	db := &flakyDB{down: true}
+	r := newTestResilientDB(t, db, config.Neo4jRetryConfig{MaxAttempts: 2, BreakerThreshold: 1})
+
+	// Failing every attempt opens the breaker and logs the write
+	if err := r.DeferWrite(ctx, "write 1", map[string]any{"id": int64(1)}); err != nil {
+		t.Fatalf("DeferWrite while down: %v", err)
+	}
+	if err := r.DeferWrite(ctx, "write 2", map[string]any{"ids": []int64{2, 3}}); err != nil {
+		t.Fatalf("DeferWrite while open: %v", err)
+	}
+	if _, err := r.ExecuteRead(ctx, "MATCH (n) RETURN n", nil); !errors.Is(err, ErrGraphUnavailable) {
+		t.Errorf("ExecuteRead while open = %v, want ErrGraphUnavailable", err)
+	}
+	if got := db.applied(); len(got) != 0 {
+		t.Fatalf("writes applied while down: %v", got)
+	}
+
+	db.setDown(false)
+	waitCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	if err := r.WaitForPendingWrites(waitCtx); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := db.applied(), []string{"write 1", "write 2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("replayed writes = %v, want %v", got, want)
+	}
+	if r.State() != BreakerClosed || r.PendingWrites() != 0 {
+		t.Errorf("after recovery: breaker %s with %d pending writes", r.State(), r.PendingWrites())
+	}
+
+	// Writes go straight to the database again
+	if err := r.DeferWrite(ctx, "write 3", nil); err != nil {
+		t.Fatal(err)
+	}
+	if got := db.applied(); len(got) != 3 || got[2] != "write 3" {
+		t.Errorf("writes after recovery = %v", got)
+	}
+}
+
+func TestWriteAheadLogReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "graph.wal")
+	wal, err := openWriteAheadLog(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range 3 {
+		entry := walEntry{Query: fmt.Sprintf("q%d", i), Params: map[string]any{
+			"id":    int64(i),
+			"nodes": []map[string]any{{"name": "n", "line": int32(i)}},
+		}}
+		if err := wal.Append(entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// A record cut short by a crash is dropped when the log is reopened
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write([]byte{0, 0, 1})
+	f.Close()
+	if wal, err = openWriteAheadLog(path); err != nil {
+		t.Fatal(err)
+	}
+	if wal.Pending() != 3 {
+		t.Fatalf("pending after reopen = %d, want 3", wal.Pending())
+	}
+
+	// Replay stops at the first failure and keeps the rest
+	var got []walEntry
+	replayed, err := wal.Replay(func(e walEntry) error {
+		if e.Query == "q2" {
+			return errUnavailable
+		}
+		got = append(got, e)
+		return nil
+	})
+	if replayed != 2 || !errors.Is(err, errUnavailable) {
+		t.Fatalf("Replay = %d, %v; want 2 and the failure", replayed, err)
+	}
+	want := map[string]any{"id": int64(1), "nodes": []map[string]any{{"name": "n", "line": int32(1)}}}
+	if !reflect.DeepEqual(got[1].Params, want) {
+		t.Errorf("replayed params = %#v, want %#v", got[1].Params, want)
+	}
+	if wal.Pending() != 1 {
+		t.Errorf("pending after partial replay = %d, want 1", wal.Pending())
+	}
+
+	if _, err := wal.Replay(func(walEntry) error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("log file left after full replay: %v", err)
+	}
+}
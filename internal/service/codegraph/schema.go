@@ -0,0 +1,217 @@
+package codegraph
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// schemaPrefix starts the names of the indexes and constraints codeapi
+// manages, so that they can be told from those added by hand
+const schemaPrefix = "codeapi_"
+
+// GraphIndex is a range index on properties of the nodes of one label or,
+// with Unique, a uniqueness constraint, which Neo4j backs with an index of
+// its own. A composite index serves lookups on a prefix of its properties.
+type GraphIndex struct {
+	Name       string   `json:"name"`
+	Label      string   `json:"label"`
+	Properties []string `json:"properties"`
+	Unique     bool     `json:"unique,omitempty"`
+}
+
+func (i GraphIndex) String() string {
+	s := fmt.Sprintf(":%s(%s)", i.Label, strings.Join(i.Properties, ", "))
+	if i.Unique {
+		s += " unique"
+	}
+	return s
+}
+
+// sameSchema reports whether two indexes cover the same label and properties
+func (i GraphIndex) sameSchema(o GraphIndex) bool {
+	return i.Label == o.Label && slices.Equal(i.Properties, o.Properties)
+}
+
+// satisfies reports whether i serves the lookups of required. A uniqueness
+// constraint serves a plain index requirement, but not the other way round.
+func (i GraphIndex) satisfies(required GraphIndex) bool {
+	return i.sameSchema(required) && (i.Unique || !required.Unique)
+}
+
+func nodeIndex(label string, properties ...string) GraphIndex {
+	name := schemaPrefix + strings.ToLower(label+"_"+strings.Join(properties, "_"))
+	return GraphIndex{Name: name, Label: label, Properties: properties}
+}
+
+func uniqueNode(label, property string) GraphIndex {
+	index := nodeIndex(label, property)
+	index.Name += "_unique"
+	index.Unique = true
+	return index
+}
+
+// nodeLabels are the labels of getNodeLabel. Every node is written with
+// MERGE on its ID, so each label needs an index on id.
+var nodeLabels = []string{
+	"ModuleScope", "FileScope", "Block", "Variable", "Expression", "Conditional",
+	"Function", "Class", "Field", "FunctionCall", "FileNumber", "Loop", "Import",
+	"TryCatch", "Constant", "Table", "Config", "FeatureFlag",
+}
+
+// RequiredSchema declares the indexes graph queries rely on: node IDs are
+// unique, and files are looked up by repository and path, and nodes by file
+// version and name
+var RequiredSchema = requiredSchema()
+
+func requiredSchema() []GraphIndex {
+	schema := make([]GraphIndex, 0, len(nodeLabels)+9)
+	for _, label := range nodeLabels {
+		schema = append(schema, uniqueNode(label, "id"))
+	}
+	return append(schema,
+		nodeIndex("FileScope", "repo", "path"),
+		nodeIndex("FileScope", "fileId"),
+		nodeIndex("ModuleScope", "fileId"),
+		nodeIndex("ModuleScope", "name"),
+		nodeIndex("Class", "fileId"),
+		nodeIndex("Class", "name"),
+		nodeIndex("Function", "fileId"),
+		nodeIndex("Function", "name"),
+		nodeIndex("FunctionCall", "fileId"),
+	)
+}
+
+// SchemaReport compares the indexes of the graph database with the required
+// schema. Extra indexes are reported only; they may serve queries of other
+// applications.
+type SchemaReport struct {
+	Missing []GraphIndex `json:"missing"`
+	Extra   []GraphIndex `json:"extra"`
+	Created []GraphIndex `json:"created,omitempty"` // set by EnsureSchema
+}
+
+// DiffSchema returns the required indexes no existing index satisfies, and
+// the existing indexes that satisfy none of them
+func DiffSchema(required, existing []GraphIndex) *SchemaReport {
+	report := &SchemaReport{}
+	for _, r := range required {
+		if !slices.ContainsFunc(existing, func(e GraphIndex) bool { return e.satisfies(r) }) {
+			report.Missing = append(report.Missing, r)
+		}
+	}
+	for _, e := range existing {
+		if !slices.ContainsFunc(required, e.satisfies) {
+			report.Extra = append(report.Extra, e)
+		}
+	}
+	return report
+}
+
+// NodeIndexes returns the range indexes on node properties of the graph
+// database, including those backing uniqueness constraints
+func (cg *CodeGraph) NodeIndexes(ctx context.Context) ([]GraphIndex, error) {
+	// YIELD * because the columns differ between Neo4j 4.4 and 5
+	records, err := cg.db.ExecuteRead(ctx, `SHOW INDEXES YIELD * WHERE entityType = 'NODE' RETURN *`, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list indexes: %w", err)
+	}
+
+	var indexes []GraphIndex
+	for _, record := range records {
+		if kind, _ := record["type"].(string); kind != "RANGE" && kind != "BTREE" {
+			continue
+		}
+		labels := toStrings(record["labelsOrTypes"])
+		if len(labels) != 1 {
+			continue
+		}
+		name, _ := record["name"].(string)
+		uniqueness, _ := record["uniqueness"].(string)
+		indexes = append(indexes, GraphIndex{
+			Name:       name,
+			Label:      labels[0],
+			Properties: toStrings(record["properties"]),
+			Unique:     record["owningConstraint"] != nil || uniqueness == "UNIQUE",
+		})
+	}
+	return indexes, nil
+}
+
+// CheckSchema compares the graph database with RequiredSchema
+func (cg *CodeGraph) CheckSchema(ctx context.Context) (*SchemaReport, error) {
+	existing, err := cg.NodeIndexes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return DiffSchema(RequiredSchema, existing), nil
+}
+
+// EnsureSchema creates the indexes and constraints of RequiredSchema that
+// are missing, and can be run any number of times. A plain index codeapi
+// created where a constraint is now required is dropped first, as Neo4j
+// allows only one index per schema. Neo4j builds new indexes in the
+// background. The report lists what was missing and what was created; the
+// error joins the failures.
+func (cg *CodeGraph) EnsureSchema(ctx context.Context) (*SchemaReport, error) {
+	existing, err := cg.NodeIndexes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	report := DiffSchema(RequiredSchema, existing)
+
+	var errs []error
+	for _, index := range report.Missing {
+		for _, e := range existing {
+			if index.Unique && !e.Unique && e.sameSchema(index) && strings.HasPrefix(e.Name, schemaPrefix) {
+				if err := cg.dropIndex(ctx, e); err != nil {
+					errs = append(errs, err)
+				}
+			}
+		}
+		if err := cg.createIndex(ctx, index); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		report.Created = append(report.Created, index)
+	}
+	return report, errors.Join(errs...)
+}
+
+// createIndex creates index unless one of its name exists
+func (cg *CodeGraph) createIndex(ctx context.Context, index GraphIndex) error {
+	properties := make([]string, len(index.Properties))
+	for i, p := range index.Properties {
+		properties[i] = "n." + p
+	}
+	query := fmt.Sprintf("CREATE INDEX %s IF NOT EXISTS FOR (n:%s) ON (%s)", index.Name, index.Label, strings.Join(properties, ", "))
+	if index.Unique {
+		query = fmt.Sprintf("CREATE CONSTRAINT %s IF NOT EXISTS FOR (n:%s) REQUIRE (%s) IS UNIQUE", index.Name, index.Label, strings.Join(properties, ", "))
+	}
+	if _, err := cg.db.ExecuteWrite(ctx, query, nil); err != nil {
+		return fmt.Errorf("failed to create index %s: %w", index, err)
+	}
+	return nil
+}
+
+// dropIndex drops a plain index
+func (cg *CodeGraph) dropIndex(ctx context.Context, index GraphIndex) error {
+	if _, err := cg.db.ExecuteWrite(ctx, fmt.Sprintf("DROP INDEX %s IF EXISTS", index.Name), nil); err != nil {
+		return fmt.Errorf("failed to drop index %s: %w", index.Name, err)
+	}
+	return nil
+}
+
+// toStrings converts a list returned by the driver to strings
+func toStrings(value any) []string {
+	values, _ := value.([]any)
+	out := make([]string, 0, len(values))
+	for _, v := range values {
+		if s, ok := v.(string); ok {
+			out = append(out, s)
+		}
+	}
+	return out
+}
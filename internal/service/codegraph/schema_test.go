@@ -0,0 +1,102 @@
+package codegraph
+
+import (
+	"context"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/armchr/codeapi/internal/config"
+
+	"go.uber.org/zap"
+)
+
+func TestDiffSchema(t *testing.T) {
+	required := []GraphIndex{
+		nodeIndex("FileScope", "repo", "path"),
+		nodeIndex("Class", "name"),
+		uniqueNode("Function", "id"),
+		uniqueNode("Class", "id"),
+	}
+	existing := []GraphIndex{
+		{Name: "by_path", Label: "FileScope", Properties: []string{"path", "repo"}},
+		{Name: "class_name", Label: "Class", Properties: []string{"name"}, Unique: true},
+		{Name: "fn_id", Label: "Function", Properties: []string{"id"}, Unique: true},
+		{Name: "codeapi_class_id", Label: "Class", Properties: []string{"id"}},
+	}
+	want := &SchemaReport{
+		Missing: []GraphIndex{required[0], required[3]},
+		Extra:   []GraphIndex{existing[0], existing[3]},
+	}
+	if got := DiffSchema(required, existing); !reflect.DeepEqual(got, want) {
+		t.Errorf("DiffSchema() = %+v, want %+v", got, want)
+	}
+	if got := DiffSchema(required, required); got.Missing != nil || got.Extra != nil {
+		t.Errorf("DiffSchema() = %+v, want an empty report", got)
+	}
+}
+
+func TestRequiredSchemaNames(t *testing.T) {
+	seen := make(map[string]bool)
+	for _, index := range RequiredSchema {
+		if !strings.HasPrefix(index.Name, schemaPrefix) || seen[index.Name] {
+			t.Errorf("index %s has name %q, want a unique name starting with %q", index, index.Name, schemaPrefix)
+		}
+		seen[index.Name] = true
+	}
+	if got := nodeIndex("FileScope", "repo", "path").Name; got != "codeapi_filescope_repo_path" {
+		t.Errorf("nodeIndex().Name = %q", got)
+	}
+}
+
+// schemaDB lists indexes the way SHOW INDEXES does and records schema writes
+type schemaDB struct {
+	GraphDatabase
+	indexes []map[string]any
+	writes  []string
+}
+
+func (d *schemaDB) ExecuteRead(ctx context.Context, query string, params map[string]any) ([]map[string]any, error) {
+	return d.indexes, nil
+}
+
+func (d *schemaDB) ExecuteWrite(ctx context.Context, query string, params map[string]any) ([]map[string]any, error) {
+	d.writes = append(d.writes, query)
+	return nil, nil
+}
+
+func TestEnsureSchema(t *testing.T) {
+	db := &schemaDB{indexes: []map[string]any{
+		// created by an earlier release, superseded by the constraint
+		{"name": "codeapi_function_id", "type": "RANGE", "labelsOrTypes": []any{"Function"}, "properties": []any{"id"}},
+		{"name": "class_id", "type": "RANGE", "labelsOrTypes": []any{"Class"}, "properties": []any{"id"}, "owningConstraint": "class_id"},
+		{"name": "text", "type": "TEXT", "labelsOrTypes": []any{"Function"}, "properties": []any{"name"}},
+	}}
+	cg := NewCodeGraphWithDatabase(db, &config.Config{}, zap.NewNop())
+
+	report, err := cg.EnsureSchema(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Missing) != len(RequiredSchema)-1 || len(report.Created) != len(report.Missing) {
+		t.Errorf("missing %d and created %d indexes, want %d", len(report.Missing), len(report.Created), len(RequiredSchema)-1)
+	}
+	if len(report.Extra) != 1 || report.Extra[0].Name != "codeapi_function_id" {
+		t.Errorf("Extra = %+v, want the superseded Function(id) index", report.Extra)
+	}
+
+	drop := slices.Index(db.writes, "DROP INDEX codeapi_function_id IF EXISTS")
+	create := slices.Index(db.writes, "CREATE CONSTRAINT codeapi_function_id_unique IF NOT EXISTS FOR (n:Function) REQUIRE (n.id) IS UNIQUE")
+	if drop < 0 || create < drop {
+		t.Errorf("writes = %v, want the old Function(id) index dropped before the constraint is created", db.writes)
+	}
+	if !slices.Contains(db.writes, "CREATE INDEX codeapi_filescope_repo_path IF NOT EXISTS FOR (n:FileScope) ON (n.repo, n.path)") {
+		t.Errorf("writes = %v, want the FileScope(repo, path) index created", db.writes)
+	}
+	for _, query := range db.writes {
+		if strings.Contains(query, "(n:Class) REQUIRE") {
+			t.Errorf("recreated the existing Class(id) constraint: %s", query)
+		}
+	}
+}
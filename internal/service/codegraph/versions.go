@@ -0,0 +1,155 @@
+package codegraph
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/armchr/codeapi/internal/model/ast"
+)
+
+// PreviousVersionRelation links a function of a file version to the same
+// function in the previous version of the file, even when it was renamed
+const PreviousVersionRelation = "PREVIOUS_VERSION"
+
+// bodyHashKey is the metadata the parser records the hash of a function body
+// under (parse.MetaBodyHash)
+const bodyHashKey = "body_hash"
+
+// VersionMatch pairs a function with the same function in the previous
+// version of its file
+type VersionMatch struct {
+	Previous *ast.Node
+	Current  *ast.Node
+}
+
+// Renamed reports whether the function changed its name between the versions
+func (m VersionMatch) Renamed() bool {
+	return m.Previous.Name != m.Current.Name
+}
+
+// MatchVersions pairs the functions of two versions of a file. A function
+// keeps its identity if it has the name and body of a previous one, or
+// otherwise the name, or else the body of one that no longer exists, as long
+// as the name or body picks out a single function on each side.
+func MatchVersions(previous, current []*ast.Node) []VersionMatch {
+	var matches []VersionMatch
+	matchedPrev := make(map[ast.NodeID]bool)
+	matchedCur := make(map[ast.NodeID]bool)
+
+	pair := func(prev, cur *ast.Node) {
+		matches = append(matches, VersionMatch{Previous: prev, Current: cur})
+		matchedPrev[prev.ID] = true
+		matchedCur[cur.ID] = true
+	}
+
+	// Unchanged functions, in order for names that repeat
+	for _, cur := range current {
+		for _, prev := range previous {
+			if !matchedPrev[prev.ID] && prev.Name == cur.Name && bodyHash(prev) != "" && bodyHash(prev) == bodyHash(cur) {
+				pair(prev, cur)
+				break
+			}
+		}
+	}
+
+	// Edited functions, then renamed ones
+	for _, key := range []func(*ast.Node) string{
+		func(n *ast.Node) string { return n.Name },
+		bodyHash,
+	} {
+		prevByKey := uniqueUnmatched(previous, matchedPrev, key)
+		curByKey := uniqueUnmatched(current, matchedCur, key)
+		for _, cur := range current {
+			k := key(cur)
+			if prev := prevByKey[k]; prev != nil && curByKey[k] == cur {
+				pair(prev, cur)
+			}
+		}
+	}
+	return matches
+}
+
+// uniqueUnmatched indexes the unmatched nodes by key, leaving out empty keys
+// and keys shared by several nodes
+func uniqueUnmatched(nodes []*ast.Node, matched map[ast.NodeID]bool, key func(*ast.Node) string) map[string]*ast.Node {
+	byKey := make(map[string]*ast.Node)
+	seen := make(map[string]int)
+	for _, n := range nodes {
+		if matched[n.ID] {
+			continue
+		}
+		if k := key(n); k != "" {
+			byKey[k] = n
+			seen[k]++
+		}
+	}
+	for k, count := range seen {
+		if count > 1 {
+			delete(byKey, k)
+		}
+	}
+	return byKey
+}
+
+func bodyHash(n *ast.Node) string {
+	hash, _ := n.MetaData[bodyHashKey].(string)
+	return hash
+}
+
+// PreviousFileVersion returns the FileID of the latest version of filePath
+// indexed before fileID, or 0 if there is none
+func (cg *CodeGraph) PreviousFileVersion(ctx context.Context, repoName, filePath string, fileID int32) (int32, error) {
+	scopes, err := cg.FindFileScopes(ctx, repoName, filePath)
+	if err != nil {
+		return 0, err
+	}
+	var previous int32
+	for _, scope := range scopes {
+		if scope.FileID < fileID && scope.FileID > previous {
+			previous = scope.FileID
+		}
+	}
+	return previous, nil
+}
+
+// LinkPreviousVersions matches the functions of file version fileID with
+// those of the previous version of the file and links each match with a
+// PREVIOUS_VERSION relation, recording whether the function was renamed
+func (cg *CodeGraph) LinkPreviousVersions(ctx context.Context, repoName string, fileID int32) ([]VersionMatch, error) {
+	filePath := cg.GetFilePath(ctx, fileID)
+	if filePath == "" {
+		return nil, nil
+	}
+	previousID, err := cg.PreviousFileVersion(ctx, repoName, filePath, fileID)
+	if err != nil || previousID == 0 {
+		return nil, err
+	}
+
+	previous, err := cg.GetNodesByTypeAndFileID(ctx, ast.NodeTypeFunction, previousID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get functions of file %d: %w", previousID, err)
+	}
+	current, err := cg.GetNodesByTypeAndFileID(ctx, ast.NodeTypeFunction, fileID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get functions of file %d: %w", fileID, err)
+	}
+
+	matches := MatchVersions(previous, current)
+	for _, m := range matches {
+		props := map[string]any{"renamed": m.Renamed()}
+		if err := cg.CreateRelation(ctx, m.Current.ID, m.Previous.ID, PreviousVersionRelation, props, fileID); err != nil {
+			return nil, err
+		}
+	}
+	return matches, nil
+}
+
+// GetPreviousVersion returns the node a function was linked to by
+// LinkPreviousVersions, or InvalidNodeID
+func (cg *CodeGraph) GetPreviousVersion(ctx context.Context, nodeID ast.NodeID) (ast.NodeID, error) {
+	relations, err := cg.GetOutgoingRelations(ctx, nodeID, PreviousVersionRelation)
+	if err != nil || len(relations) == 0 {
+		return ast.InvalidNodeID, err
+	}
+	return relations[0].ToNodeID, nil
+}
@@ -0,0 +1,93 @@
+package codegraph
+
+import (
+	"testing"
+
+	"github.com/armchr/codeapi/internal/model/ast"
+)
+
+func TestMatchVersions(t *testing.T) {
+	fn := func(id ast.NodeID, name, hash string) *ast.Node {
+		n := &ast.Node{ID: id, NodeType: ast.NodeTypeFunction, Name: name}
+		if hash != "" {
+			n.MetaData = map[string]any{bodyHashKey: hash}
+		}
+		return n
+	}
+
+	tests := []struct {
+		name     string
+		previous []*ast.Node
+		current  []*ast.Node
+		want     map[ast.NodeID]ast.NodeID // current to previous
+		renamed  []ast.NodeID
+	}{
+		{
+			name:     "unchanged and edited",
+			previous: []*ast.Node{fn(1, "add", "h1"), fn(2, "sub", "h2")},
+			current:  []*ast.Node{fn(11, "add", "h1"), fn(12, "sub", "h2b")},
+			want:     map[ast.NodeID]ast.NodeID{11: 1, 12: 2},
+		},
+		{
+			name:     "renamed",
+			previous: []*ast.Node{fn(1, "add", "h1"), fn(2, "sub", "h2")},
+			current:  []*ast.Node{fn(11, "plus", "h1"), fn(12, "sub", "h2")},
+			want:     map[ast.NodeID]ast.NodeID{11: 1, 12: 2},
+			renamed:  []ast.NodeID{11},
+		},
+		{
+			name:     "renamed and edited is an addition",
+			previous: []*ast.Node{fn(1, "add", "h1")},
+			current:  []*ast.Node{fn(11, "plus", "h1b")},
+			want:     map[ast.NodeID]ast.NodeID{},
+		},
+		{
+			name:     "repeated names pair by body, then in order",
+			previous: []*ast.Node{fn(1, "__init__", "a"), fn(2, "__init__", "b")},
+			current:  []*ast.Node{fn(11, "__init__", "b"), fn(12, "__init__", "a")},
+			want:     map[ast.NodeID]ast.NodeID{11: 2, 12: 1},
+		},
+		{
+			name:     "ambiguous names are not paired",
+			previous: []*ast.Node{fn(1, "run", "a"), fn(2, "run", "b")},
+			current:  []*ast.Node{fn(11, "run", "c"), fn(12, "run", "d")},
+			want:     map[ast.NodeID]ast.NodeID{},
+		},
+		{
+			name:     "ambiguous bodies are not paired",
+			previous: []*ast.Node{fn(1, "getA", "same"), fn(2, "getB", "same")},
+			current:  []*ast.Node{fn(11, "fetchA", "same"), fn(12, "fetchB", "same")},
+			want:     map[ast.NodeID]ast.NodeID{},
+		},
+		{
+			name:     "no body hash",
+			previous: []*ast.Node{fn(1, "decl", "")},
+			current:  []*ast.Node{fn(11, "other", "")},
+			want:     map[ast.NodeID]ast.NodeID{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := make(map[ast.NodeID]ast.NodeID)
+			var renamed []ast.NodeID
+			for _, m := range MatchVersions(tt.previous, tt.current) {
+				got[m.Current.ID] = m.Previous.ID
+				if m.Renamed() {
+					renamed = append(renamed, m.Current.ID)
+				}
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("MatchVersions() = %v, want %v", got, tt.want)
+			}
+			for cur, prev := range tt.want {
+				if got[cur] != prev {
+					t.Errorf("MatchVersions() = %v, want %v", got, tt.want)
+				}
+			}
+			if len(renamed) != len(tt.renamed) || (len(renamed) > 0 && renamed[0] != tt.renamed[0]) {
+				t.Errorf("renamed = %v, want %v", renamed, tt.renamed)
+			}
+		})
+	}
+}
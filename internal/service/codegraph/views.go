@@ -0,0 +1,274 @@
+package codegraph
+
+import (
+	"context"
+	"fmt"
+	"path"
+
+	"github.com/armchr/codeapi/internal/model/ast"
+	"go.uber.org/zap"
+)
+
+// Metadata keys of the materialized views. Call counts are stored on
+// Function nodes; the coupling of a file's package is stored on each of its
+// FileScope nodes, since packages have no node of their own.
+const (
+	ViewCallees           = "view_callees"
+	ViewTransitiveCallees = "view_transitive_callees"
+	ViewPackage           = "view_package"
+	ViewPackageFanIn      = "view_package_fan_in"
+	ViewPackageFanOut     = "view_package_fan_out"
+)
+
+// viewUpdateBatch bounds the nodes updated by a single statement
+const viewUpdateBatch = 1000
+
+// CallCounts are the distinct functions a function calls, directly and
+// through the functions it calls
+type CallCounts struct {
+	Direct     int
+	Transitive int
+}
+
+// PackageCoupling counts the other packages calling into a package and the
+// packages it calls into
+type PackageCoupling struct {
+	FanIn  int
+	FanOut int
+}
+
+// ViewRefresh reports the work done by RefreshViews
+type ViewRefresh struct {
+	Functions  int // functions of the newest version of each file
+	Recomputed int // functions whose call counts were recomputed
+	Packages   int
+	Updated    int // nodes whose stored views changed
+}
+
+// PackageOf returns the package a file belongs to: its directory, or "."
+// for files at the repository root
+func PackageOf(filePath string) string {
+	return path.Dir(filePath)
+}
+
+// ComputeCallCounts returns the call counts of the given functions, walking
+// calls, the callees of each function. A function is not its own callee,
+// even when it is recursive.
+func ComputeCallCounts(calls map[ast.NodeID][]ast.NodeID, functions []ast.NodeID) map[ast.NodeID]CallCounts {
+	counts := make(map[ast.NodeID]CallCounts, len(functions))
+	for _, fn := range functions {
+		seen := map[ast.NodeID]bool{fn: true}
+		direct := 0
+		for _, callee := range calls[fn] {
+			if !seen[callee] {
+				seen[callee] = true
+				direct++
+			}
+		}
+		queue := append([]ast.NodeID(nil), calls[fn]...)
+		for len(queue) > 0 {
+			next := queue[0]
+			queue = queue[1:]
+			for _, callee := range calls[next] {
+				if !seen[callee] {
+					seen[callee] = true
+					queue = append(queue, callee)
+				}
+			}
+		}
+		counts[fn] = CallCounts{Direct: direct, Transitive: len(seen) - 1}
+	}
+	return counts
+}
+
+// CallersClosure returns the given functions and every function calling one
+// of them, directly or not: those whose transitive call counts may change
+// when the given functions do
+func CallersClosure(calls map[ast.NodeID][]ast.NodeID, functions []ast.NodeID) []ast.NodeID {
+	callers := make(map[ast.NodeID][]ast.NodeID)
+	for caller, callees := range calls {
+		for _, callee := range callees {
+			callers[callee] = append(callers[callee], caller)
+		}
+	}
+	seen := make(map[ast.NodeID]bool, len(functions))
+	var closure []ast.NodeID
+	queue := functions
+	for len(queue) > 0 {
+		fn := queue[0]
+		queue = queue[1:]
+		if seen[fn] {
+			continue
+		}
+		seen[fn] = true
+		closure = append(closure, fn)
+		queue = append(queue, callers[fn]...)
+	}
+	return closure
+}
+
+// ComputePackageCoupling returns the coupling of every package named by
+// packageOf, which maps functions to their packages. Calls within a package
+// and calls to functions without a package are not counted.
+func ComputePackageCoupling(calls map[ast.NodeID][]ast.NodeID, packageOf map[ast.NodeID]string) map[string]PackageCoupling {
+	callsInto := make(map[string]map[string]bool)
+	calledFrom := make(map[string]map[string]bool)
+	for _, pkg := range packageOf {
+		callsInto[pkg] = make(map[string]bool)
+		calledFrom[pkg] = make(map[string]bool)
+	}
+	for caller, callees := range calls {
+		from, ok := packageOf[caller]
+		if !ok {
+			continue
+		}
+		for _, callee := range callees {
+			if to, ok := packageOf[callee]; ok && to != from {
+				callsInto[from][to] = true
+				calledFrom[to][from] = true
+			}
+		}
+	}
+
+	coupling := make(map[string]PackageCoupling, len(callsInto))
+	for pkg := range callsInto {
+		coupling[pkg] = PackageCoupling{FanIn: len(calledFrom[pkg]), FanOut: len(callsInto[pkg])}
+	}
+	return coupling
+}
+
+// RefreshViews recomputes the materialized views of a repository over the
+// newest version of each file. Call counts are recomputed for the functions
+// of changedFiles, functions without stored counts and every function
+// calling one of those; package coupling is cheap and recomputed in full.
+// Only nodes whose values changed are written.
+func (cg *CodeGraph) RefreshViews(ctx context.Context, repoName string, changedFiles []int32) (*ViewRefresh, error) {
+	changed := make(map[int32]bool, len(changedFiles))
+	for _, fileID := range changedFiles {
+		changed[fileID] = true
+	}
+
+	functionQuery := `
+		MATCH (f:FileScope {repo: $repo})
+		WITH f.path AS path, max(f.fileId) AS fileId
+		MATCH (fn:Function {fileId: fileId})
+		RETURN fn.id AS id, fileId, path,
+		       fn.md_view_callees AS callees, fn.md_view_transitive_callees AS transitive
+	`
+	records, err := cg.db.ExecuteRead(ctx, functionQuery, map[string]any{"repo": repoName})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read functions: %w", err)
+	}
+	stored := make(map[ast.NodeID]CallCounts, len(records))
+	packageOf := make(map[ast.NodeID]string, len(records))
+	var stale []ast.NodeID
+	for _, record := range records {
+		id := ast.NodeID(cg.convertToInt64(record["id"]))
+		filePath, _ := record["path"].(string)
+		packageOf[id] = PackageOf(filePath)
+		if record["transitive"] == nil || changed[int32(cg.convertToInt64(record["fileId"]))] {
+			stale = append(stale, id)
+			continue
+		}
+		stored[id] = CallCounts{
+			Direct:     int(cg.convertToInt64(record["callees"])),
+			Transitive: int(cg.convertToInt64(record["transitive"])),
+		}
+	}
+
+	callQuery := `
+		MATCH (f:FileScope {repo: $repo})
+		WITH f.path AS path, max(f.fileId) AS fileId
+		MATCH (caller:Function {fileId: fileId})-[:CONTAINS*]->(:FunctionCall)-[:CALLS_FUNCTION]->(callee:Function)
+		RETURN DISTINCT caller.id AS caller, callee.id AS callee
+	`
+	records, err = cg.db.ExecuteRead(ctx, callQuery, map[string]any{"repo": repoName})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read calls: %w", err)
+	}
+	calls := make(map[ast.NodeID][]ast.NodeID)
+	for _, record := range records {
+		caller := ast.NodeID(cg.convertToInt64(record["caller"]))
+		callee := ast.NodeID(cg.convertToInt64(record["callee"]))
+		// Calls into older file versions are left out with them
+		if _, ok := packageOf[callee]; ok {
+			calls[caller] = append(calls[caller], callee)
+		}
+	}
+
+	recompute := CallersClosure(calls, stale)
+	updates := make(map[ast.NodeID]map[string]any)
+	for id, counts := range ComputeCallCounts(calls, recompute) {
+		if prev, ok := stored[id]; ok && prev == counts {
+			continue
+		}
+		updates[id] = map[string]any{ViewCallees: counts.Direct, ViewTransitiveCallees: counts.Transitive}
+	}
+
+	coupling := ComputePackageCoupling(calls, packageOf)
+	if err := cg.addPackageViewUpdates(ctx, repoName, coupling, updates); err != nil {
+		return nil, err
+	}
+
+	if err := cg.writeViewUpdates(ctx, updates); err != nil {
+		return nil, err
+	}
+	refresh := &ViewRefresh{Functions: len(packageOf), Recomputed: len(recompute), Packages: len(coupling), Updated: len(updates)}
+	cg.logger.Info("Refreshed materialized views",
+		zap.String("repo_name", repoName),
+		zap.Int("functions", refresh.Functions),
+		zap.Int("recomputed", refresh.Recomputed),
+		zap.Int("packages", refresh.Packages),
+		zap.Int("updated", refresh.Updated))
+	return refresh, nil
+}
+
+// addPackageViewUpdates adds to updates the newest file versions whose
+// stored package coupling differs from coupling. Files of packages without
+// functions have no fan-in or fan-out.
+func (cg *CodeGraph) addPackageViewUpdates(ctx context.Context, repoName string, coupling map[string]PackageCoupling, updates map[ast.NodeID]map[string]any) error {
+	query := `
+		MATCH (f:FileScope {repo: $repo})
+		WITH f.path AS path, max(f.fileId) AS fileId
+		MATCH (fs:FileScope {repo: $repo, fileId: fileId})
+		RETURN fs.id AS id, path, fs.md_view_package AS package,
+		       fs.md_view_package_fan_in AS fanIn, fs.md_view_package_fan_out AS fanOut
+	`
+	records, err := cg.db.ExecuteRead(ctx, query, map[string]any{"repo": repoName})
+	if err != nil {
+		return fmt.Errorf("failed to read file scopes: %w", err)
+	}
+	for _, record := range records {
+		filePath, _ := record["path"].(string)
+		pkg := PackageOf(filePath)
+		c := coupling[pkg]
+		if record["package"] == pkg && record["fanIn"] != nil &&
+			int(cg.convertToInt64(record["fanIn"])) == c.FanIn && int(cg.convertToInt64(record["fanOut"])) == c.FanOut {
+			continue
+		}
+		updates[ast.NodeID(cg.convertToInt64(record["id"]))] = map[string]any{
+			ViewPackage:       pkg,
+			ViewPackageFanIn:  c.FanIn,
+			ViewPackageFanOut: c.FanOut,
+		}
+	}
+	return nil
+}
+
+// writeViewUpdates stores the view values of each node, a batch at a time
+func (cg *CodeGraph) writeViewUpdates(ctx context.Context, updates map[ast.NodeID]map[string]any) error {
+	batch := make(map[ast.NodeID]map[string]any, viewUpdateBatch)
+	for id, values := range updates {
+		batch[id] = values
+		if len(batch) == viewUpdateBatch {
+			if err := cg.BatchUpdateNodeMetaData(ctx, batch); err != nil {
+				return fmt.Errorf("failed to store views: %w", err)
+			}
+			batch = make(map[ast.NodeID]map[string]any, viewUpdateBatch)
+		}
+	}
+	if err := cg.BatchUpdateNodeMetaData(ctx, batch); err != nil {
+		return fmt.Errorf("failed to store views: %w", err)
+	}
+	return nil
+}
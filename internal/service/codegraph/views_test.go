@@ -0,0 +1,76 @@
+package codegraph
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/armchr/codeapi/internal/model/ast"
+)
+
+// viewCalls is a call graph with a cycle (3 -> 4 -> 3), a recursive
+// function (5) and a function called twice (6)
+var viewCalls = map[ast.NodeID][]ast.NodeID{
+	1: {2, 3},
+	2: {6},
+	3: {4, 6},
+	4: {3},
+	5: {5, 6},
+}
+
+func TestComputeCallCounts(t *testing.T) {
+	got := ComputeCallCounts(viewCalls, []ast.NodeID{1, 2, 3, 5, 6})
+	want := map[ast.NodeID]CallCounts{
+		1: {Direct: 2, Transitive: 4},
+		2: {Direct: 1, Transitive: 1},
+		3: {Direct: 2, Transitive: 2},
+		5: {Direct: 1, Transitive: 1},
+		6: {},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ComputeCallCounts = %v, want %v", got, want)
+	}
+}
+
+func TestCallersClosure(t *testing.T) {
+	tests := []struct {
+		name      string
+		functions []ast.NodeID
+		want      []ast.NodeID
+	}{
+		{"leaf", []ast.NodeID{6}, []ast.NodeID{1, 2, 3, 4, 5, 6}},
+		{"cycle", []ast.NodeID{4}, []ast.NodeID{1, 3, 4}},
+		{"root", []ast.NodeID{1}, []ast.NodeID{1}},
+		{"none", nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := CallersClosure(viewCalls, tt.functions)
+			slices.Sort(got)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("CallersClosure(%v) = %v, want %v", tt.functions, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestComputePackageCoupling(t *testing.T) {
+	packageOf := map[ast.NodeID]string{
+		1: PackageOf("api/handler.go"),
+		2: PackageOf("api/middleware.go"),
+		3: PackageOf("store/orders.go"),
+		4: PackageOf("store/orders.go"),
+		5: PackageOf("main.go"),
+		6: PackageOf("util/strings.go"),
+	}
+	got := ComputePackageCoupling(viewCalls, packageOf)
+	want := map[string]PackageCoupling{
+		"api":   {FanIn: 0, FanOut: 2},
+		"store": {FanIn: 1, FanOut: 1},
+		".":     {FanIn: 0, FanOut: 1},
+		"util":  {FanIn: 3, FanOut: 0},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ComputePackageCoupling = %v, want %v", got, want)
+	}
+}
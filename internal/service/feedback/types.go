@@ -0,0 +1,57 @@
+package feedback
+
+import "time"
+
+// Kinds of entities clients give feedback on
+const (
+	EntitySearchResult = "search_result" // a chunk returned by a search, by chunk ID
+	EntitySummary      = "summary"       // a generated summary, by the entity it summarizes
+)
+
+// EntityTypes are the accepted entity types
+var EntityTypes = map[string]bool{EntitySearchResult: true, EntitySummary: true}
+
+// Votes on an entity
+const (
+	Upvote   = 1
+	Downvote = -1
+)
+
+// Feedback is one vote on a search result or summary. Query is the search
+// that returned the result, so a chunk can be good for one query and bad for
+// another; it is empty for summaries.
+type Feedback struct {
+	ID         int64     `json:"id" db:"id"`
+	EntityType string    `json:"entity_type" db:"entity_type"`
+	EntityID   string    `json:"entity_id" db:"entity_id"`
+	Query      string    `json:"query,omitempty" db:"query"`
+	Vote       int       `json:"vote" db:"vote"`
+	Source     string    `json:"source,omitempty" db:"source"` // endpoint or client that showed the entity
+	Comment    string    `json:"comment,omitempty" db:"comment"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+}
+
+// Aggregate totals the votes on an entity, for one query when grouped by
+// query. Score is upvotes minus downvotes.
+type Aggregate struct {
+	EntityType string `json:"entity_type"`
+	EntityID   string `json:"entity_id"`
+	Query      string `json:"query,omitempty"`
+	Upvotes    int    `json:"upvotes"`
+	Downvotes  int    `json:"downvotes"`
+	Score      int    `json:"score"`
+	Comments   int    `json:"comments"` // votes that came with a comment
+}
+
+// Filter selects the feedback to aggregate. Zero fields match everything.
+type Filter struct {
+	EntityType   string
+	EntityID     string
+	Query        string // exact
+	Source       string
+	Since        *time.Time
+	GroupByQuery bool // one aggregate per entity and query instead of per entity
+	BestFirst    bool // highest score first; the lowest comes first otherwise
+	Limit        int
+	Offset       int
+}
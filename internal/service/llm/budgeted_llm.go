@@ -0,0 +1,31 @@
+package llm
+
+import (
+	"context"
+
+	"github.com/armchr/codeapi/internal/budget"
+)
+
+// budgetedLLM holds an LLM slot of the resource budget for every call, so
+// index builds and on-demand summaries share one limit on requests in flight
+type budgetedLLM struct {
+	LLMService
+}
+
+func (b *budgetedLLM) Generate(ctx context.Context, prompt string, opts GenerateOptions) (*GenerateResponse, error) {
+	release, err := budget.Acquire(ctx, budget.LLM)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return b.LLMService.Generate(ctx, prompt, opts)
+}
+
+func (b *budgetedLLM) GenerateWithSystem(ctx context.Context, systemPrompt, userPrompt string, opts GenerateOptions) (*GenerateResponse, error) {
+	release, err := budget.Acquire(ctx, budget.LLM)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return b.LLMService.GenerateWithSystem(ctx, systemPrompt, userPrompt, opts)
+}
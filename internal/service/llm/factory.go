@@ -7,8 +7,20 @@ import (
 	"go.uber.org/zap"
 )
 
-// NewLLMService creates an LLM service based on the provided configuration
+// NewLLMService creates an LLM service based on the provided configuration.
+// Calls are traced when tracing is enabled and count against the LLM
+// resource budget.
 func NewLLMService(config Config, logger *zap.Logger) (LLMService, error) {
+	service, err := newProviderService(config, logger)
+	if err != nil {
+		return nil, err
+	}
+	// Budget outside the tracing wrapper, so spans time the call itself
+	return &budgetedLLM{LLMService: &tracedLLM{LLMService: service}}, nil
+}
+
+// newProviderService creates the client for the configured provider
+func newProviderService(config Config, logger *zap.Logger) (LLMService, error) {
 	switch config.Provider {
 	case ProviderOllama:
 		return NewOllamaLLM(OllamaConfig{
@@ -0,0 +1,49 @@
+package llm
+
+import (
+	"context"
+
+	"github.com/armchr/codeapi/internal/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// tracedLLM wraps a provider so each generation shows up as a span with its
+// token usage
+type tracedLLM struct {
+	LLMService
+}
+
+func (t *tracedLLM) Generate(ctx context.Context, prompt string, opts GenerateOptions) (*GenerateResponse, error) {
+	ctx, span := t.start(ctx, opts)
+	resp, err := t.LLMService.Generate(ctx, prompt, opts)
+	t.end(span, resp, err)
+	return resp, err
+}
+
+func (t *tracedLLM) GenerateWithSystem(ctx context.Context, systemPrompt, userPrompt string, opts GenerateOptions) (*GenerateResponse, error) {
+	ctx, span := t.start(ctx, opts)
+	resp, err := t.LLMService.GenerateWithSystem(ctx, systemPrompt, userPrompt, opts)
+	t.end(span, resp, err)
+	return resp, err
+}
+
+func (t *tracedLLM) start(ctx context.Context, opts GenerateOptions) (context.Context, tracing.Span) {
+	model := opts.Model
+	if model == "" {
+		model = t.ModelName()
+	}
+	return tracing.Start(ctx, "llm.generate",
+		attribute.String("provider", t.Name()),
+		attribute.String("model", model),
+		attribute.Int("max_tokens", opts.MaxTokens))
+}
+
+func (t *tracedLLM) end(span tracing.Span, resp *GenerateResponse, err error) {
+	if resp != nil {
+		span.SetAttributes(
+			attribute.Int("prompt_tokens", resp.PromptTokens),
+			attribute.Int("output_tokens", resp.OutputTokens))
+	}
+	tracing.End(span, err)
+}
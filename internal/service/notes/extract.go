@@ -0,0 +1,162 @@
+package notes
+
+import (
+	"regexp"
+	"strings"
+)
+
+// maxLicenseText caps the header text kept for license notes without an
+// SPDX identifier
+const maxLicenseText = 1000
+
+// commentStarts open a comment in the languages codeapi indexes and the
+// configuration and markup files next to them
+var commentStarts = []string{"//", "#", "/*", "<!--", "--"}
+
+// Extractor finds notes in file content
+type Extractor struct {
+	word     *regexp.Regexp // a marker anywhere on a line
+	marker   *regexp.Regexp // a note starting with a marker
+	licenses bool
+}
+
+// NewExtractor creates an extractor for the given comment markers, matched
+// case-sensitively as whole words. With licenses set the license header at
+// the top of each file is recorded too.
+func NewExtractor(markers []string, licenses bool) *Extractor {
+	if len(markers) == 0 {
+		markers = DefaultMarkers
+	}
+	quoted := make([]string, len(markers))
+	for i, m := range markers {
+		quoted[i] = regexp.QuoteMeta(m)
+	}
+	alternatives := strings.Join(quoted, "|")
+	return &Extractor{
+		word: regexp.MustCompile(`\b(?:` + alternatives + `)\b`),
+		// marker, optional (assignee), optional colon or dash, then the text
+		marker:   regexp.MustCompile(`^(` + alternatives + `)(?:\(([^)]*)\))?\s*[:\-]?\s*(.*)$`),
+		licenses: licenses,
+	}
+}
+
+// Extract returns the notes of a file. Only markers inside comments count,
+// so identifiers such as TODOList or a string mentioning TODO are skipped
+// unless a comment opens before them on the line.
+func (e *Extractor) Extract(filePath string, content []byte) []CodeNote {
+	lines := strings.Split(string(content), "\n")
+	var notes []CodeNote
+	if e.licenses {
+		if note, ok := licenseHeader(lines); ok {
+			note.FilePath = filePath
+			notes = append(notes, note)
+		}
+	}
+
+	for i, line := range lines {
+		for _, w := range e.word.FindAllStringIndex(line, -1) {
+			if !inComment(line, w[0]) {
+				continue
+			}
+			m := e.marker.FindStringSubmatch(line[w[0]:])
+			notes = append(notes, CodeNote{
+				FilePath: filePath,
+				Line:     i + 1,
+				Kind:     strings.ToLower(m[1]),
+				Text:     cleanCommentText(m[3]),
+				Assignee: strings.TrimSpace(m[2]),
+			})
+			break
+		}
+	}
+	return notes
+}
+
+// inComment reports whether a comment is open at pos on line: a comment
+// token before it, or a line continuing a block comment
+func inComment(line string, pos int) bool {
+	before := line[:pos]
+	for _, start := range commentStarts {
+		if strings.Contains(before, start) {
+			return true
+		}
+	}
+	trimmed := strings.TrimSpace(before)
+	return trimmed == "*" || trimmed == ";" || trimmed == ";;"
+}
+
+// licenseHeader finds the comment block at the top of a file, after any
+// shebang, and returns it as a license note if it mentions a copyright or
+// license. An SPDX identifier is kept on its own as the note text.
+func licenseHeader(lines []string) (CodeNote, bool) {
+	first, inBlock := -1, false
+	var text []string
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if first < 0 && (trimmed == "" || i == 0 && strings.HasPrefix(trimmed, "#!")) {
+			continue
+		}
+		if !inBlock && !isCommentLine(trimmed) {
+			break
+		}
+		if first < 0 {
+			first = i
+		}
+		if strings.HasPrefix(trimmed, "/*") || strings.HasPrefix(trimmed, "<!--") {
+			inBlock = true
+		}
+		if strings.Contains(trimmed, "*/") || strings.Contains(trimmed, "-->") {
+			inBlock = false
+		}
+		if cleaned := cleanCommentText(stripCommentStart(trimmed)); cleaned != "" {
+			text = append(text, cleaned)
+		}
+	}
+
+	header := strings.Join(text, "\n")
+	lower := strings.ToLower(header)
+	if !strings.Contains(lower, "copyright") && !strings.Contains(lower, "license") {
+		return CodeNote{}, false
+	}
+	for _, line := range text {
+		if _, id, ok := strings.Cut(line, "SPDX-License-Identifier:"); ok {
+			header = strings.TrimSpace(id)
+			break
+		}
+	}
+	if len(header) > maxLicenseText {
+		header = header[:maxLicenseText]
+	}
+	return CodeNote{Line: first + 1, Kind: KindLicense, Text: header}, true
+}
+
+func isCommentLine(trimmed string) bool {
+	if strings.HasPrefix(trimmed, "*") {
+		return true
+	}
+	for _, start := range commentStarts {
+		if strings.HasPrefix(trimmed, start) {
+			return true
+		}
+	}
+	return false
+}
+
+// stripCommentStart removes the comment token a line starts with
+func stripCommentStart(trimmed string) string {
+	for _, start := range []string{"<!--", "/**", "/*", "//", "--", "#", "*"} {
+		if rest, ok := strings.CutPrefix(trimmed, start); ok {
+			return strings.TrimLeft(rest, "/#*")
+		}
+	}
+	return trimmed
+}
+
+// cleanCommentText drops block comment terminators and surrounding space
+func cleanCommentText(text string) string {
+	text = strings.TrimSpace(text)
+	for _, end := range []string{"*/", "-->"} {
+		text = strings.TrimSpace(strings.TrimSuffix(text, end))
+	}
+	return text
+}
@@ -0,0 +1,59 @@
+package notes
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestExtract(t *testing.T) {
+	tests := []struct {
+		name     string
+		licenses bool
+		content  string
+		want     []string // kind:line:assignee:text
+	}{
+		{
+			name: "line comments",
+			content: "package p\n\n" +
+				"// TODO: handle tabs\n" +
+				"x := 1 // FIXME(ada) overflow\n" +
+				"var TODOList = \"TODO: not a note\"\n" +
+				"s := \"TODO\" // TODO: after a string\n",
+			want: []string{"todo:3::handle tabs", "fixme:4:ada:overflow", "todo:6::after a string"},
+		},
+		{
+			name:    "block and hash comments",
+			content: "/*\n * HACK - works around a driver bug\n */\n# XXX: revisit */\n",
+			want:    []string{"hack:2::works around a driver bug", "xxx:4::revisit"},
+		},
+		{
+			name:     "spdx license",
+			licenses: true,
+			content:  "#!/usr/bin/env python\n# Copyright 2024 Example\n# SPDX-License-Identifier: Apache-2.0\n\nimport os\n",
+			want:     []string{"license:2::Apache-2.0"},
+		},
+		{
+			name:     "license block",
+			licenses: true,
+			content:  "/*\n * Licensed under the MIT License.\n */\npackage p\n",
+			want:     []string{"license:1::Licensed under the MIT License."},
+		},
+		{
+			name:     "doc comment is not a license",
+			licenses: true,
+			content:  "// Package p parses things.\npackage p\n",
+			want:     nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, n := range NewExtractor(nil, tt.licenses).Extract("f", []byte(tt.content)) {
+				got = append(got, fmt.Sprintf("%s:%d:%s:%s", n.Kind, n.Line, n.Assignee, n.Text))
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("notes = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,36 @@
+package notes
+
+import "time"
+
+// KindLicense is the kind of notes recording a file's license header. Other
+// notes have the lowercased comment marker as kind, such as "todo".
+const KindLicense = "license"
+
+// DefaultMarkers are the comment markers recorded when none are configured
+var DefaultMarkers = []string{"TODO", "FIXME", "HACK", "XXX"}
+
+// CodeNote is a TODO-style comment or license header found in a file
+type CodeNote struct {
+	ID          int64      `json:"id" db:"id"`
+	FileID      int32      `json:"file_id" db:"file_id"`
+	FilePath    string     `json:"file_path" db:"file_path"`
+	Line        int        `json:"line" db:"line"` // 1-based, as in editors and git blame
+	Kind        string     `json:"kind" db:"kind"`
+	Text        string     `json:"text" db:"text"`
+	Assignee    string     `json:"assignee,omitempty" db:"assignee"` // the name in TODO(name)
+	Author      string     `json:"author,omitempty" db:"author"`     // from git blame
+	AuthorEmail string     `json:"author_email,omitempty" db:"author_email"`
+	CommitID    string     `json:"commit_id,omitempty" db:"commit_id"`
+	AuthoredAt  *time.Time `json:"authored_at,omitempty" db:"authored_at"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+}
+
+// Filter selects notes. Zero fields match everything.
+type Filter struct {
+	Kinds      []string // any of these kinds
+	PathPrefix string   // files under this path
+	Author     string   // blamed author or assignee, exact
+	Contains   string   // substring of the note text
+	Limit      int
+	Offset     int
+}
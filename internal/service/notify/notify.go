@@ -0,0 +1,81 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/armchr/codeapi/internal/config"
+	"go.uber.org/zap"
+)
+
+// Events notified
+const (
+	EventQualityRegression = "quality_regression"
+)
+
+// Notification is the JSON document POSTed to the webhook
+type Notification struct {
+	Event    string         `json:"event"`
+	RepoName string         `json:"repo_name"`
+	Title    string         `json:"title"`
+	Text     string         `json:"text"` // one line per finding, for chat webhooks
+	Details  map[string]any `json:"details,omitempty"`
+	Time     time.Time      `json:"time"`
+}
+
+// Notifier logs notifications and sends them to the configured webhook
+type Notifier struct {
+	cfg    config.NotificationsConfig
+	client *http.Client
+	logger *zap.Logger
+}
+
+// New creates a notifier for the notifications section of app.yaml
+func New(cfg config.NotificationsConfig, logger *zap.Logger) *Notifier {
+	return &Notifier{
+		cfg:    cfg,
+		client: &http.Client{Timeout: cfg.Timeout()},
+		logger: logger,
+	}
+}
+
+// Notify logs n as a warning and POSTs it to the webhook, if one is
+// configured. The returned error only concerns the webhook.
+func (n *Notifier) Notify(ctx context.Context, notification *Notification) error {
+	if notification.Time.IsZero() {
+		notification.Time = time.Now().UTC()
+	}
+	n.logger.Warn(notification.Title,
+		zap.String("event", notification.Event),
+		zap.String("repo_name", notification.RepoName),
+		zap.String("text", notification.Text))
+	if n.cfg.WebhookURL == "" {
+		return nil
+	}
+
+	body, err := json.Marshal(notification)
+	if err != nil {
+		return fmt.Errorf("failed to encode notification: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.cfg.WebhookURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range n.cfg.Headers {
+		req.Header.Set(name, value)
+	}
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send notification: %w", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("notification webhook returned %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/armchr/codeapi/internal/config"
+	"go.uber.org/zap"
+)
+
+func TestNotify(t *testing.T) {
+	var got Notification
+	var auth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Error(err)
+		}
+	}))
+	defer server.Close()
+
+	n := New(config.NotificationsConfig{WebhookURL: server.URL, Headers: map[string]string{"Authorization": "Bearer t"}}, zap.NewNop())
+	err := n.Notify(context.Background(), &Notification{Event: EventQualityRegression, RepoName: "shop", Title: "Index quality regressed"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Event != EventQualityRegression || got.RepoName != "shop" || got.Time.IsZero() {
+		t.Errorf("webhook received %+v", got)
+	}
+	if auth != "Bearer t" {
+		t.Errorf("Authorization = %q, want the configured header", auth)
+	}
+}
+
+func TestNotifyErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name    string
+		url     string
+		wantErr bool
+	}{
+		{"no webhook", "", false},
+		{"webhook fails", server.URL, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n := New(config.NotificationsConfig{WebhookURL: tt.url}, zap.NewNop())
+			err := n.Notify(context.Background(), &Notification{Event: EventQualityRegression})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Notify() error = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -2,10 +2,12 @@ package service
 
 import (
 	"context"
+	"time"
 
 	"github.com/armchr/codeapi/internal/config"
 	"github.com/armchr/codeapi/internal/model"
 	"github.com/armchr/codeapi/pkg/lsp"
+	"github.com/armchr/codeapi/pkg/lsp/base"
 
 	"go.uber.org/zap"
 )
@@ -40,6 +42,18 @@ func (rs *RepoService) GetFunctionDependencies(ctx context.Context, repoName, re
 	return rs.lspService.GetFunctionDependencies(ctx, repoName, relativePath, functionName, depth)
 }
 
+// GetFunctionDependenciesUntil builds the call graph of a function until
+// deadline, returning the functions left to expand
+func (rs *RepoService) GetFunctionDependenciesUntil(ctx context.Context, repoName, relativePath, functionName string, depth int, deadline time.Time) (*model.CallGraph, []model.PendingFunction, error) {
+	return rs.lspService.GetFunctionDependenciesUntil(ctx, repoName, relativePath, functionName, depth, deadline)
+}
+
+// ContinueFunctionDependencies resumes a call graph traversal from the
+// functions an earlier one left
+func (rs *RepoService) ContinueFunctionDependencies(ctx context.Context, repoName, relativePath string, pending []model.PendingFunction, deadline time.Time) (*model.CallGraph, []model.PendingFunction, error) {
+	return rs.lspService.ContinueFunctionDependencies(ctx, repoName, relativePath, pending, deadline)
+}
+
 func (rs *RepoService) GetFunctionHovers(ctx context.Context, repoName string, functions []model.FunctionDefinition) ([]string, error) {
 	return rs.lspService.GetFunctionHovers(ctx, repoName, functions)
 }
@@ -48,8 +62,35 @@ func (rs *RepoService) GetFunctionCallers(ctx context.Context, repoName, relativ
 	return rs.lspService.GetFunctionCallers(ctx, repoName, relativePath, functionName, depth)
 }
 
+// GetDefinition returns where the symbol at position in a repository file is defined
+func (rs *RepoService) GetDefinition(ctx context.Context, repoName, relativePath string, position base.Position) ([]base.Location, error) {
+	return rs.lspService.GetDefinition(ctx, repoName, relativePath, position)
+}
+
+// GetReferences returns where the symbol at position in a repository file is used
+func (rs *RepoService) GetReferences(ctx context.Context, repoName, relativePath string, position base.Position, includeDeclaration bool) ([]base.Location, error) {
+	return rs.lspService.GetReferences(ctx, repoName, relativePath, position, includeDeclaration)
+}
+
+// GetHover returns the hover text of the symbol at position in a repository file
+func (rs *RepoService) GetHover(ctx context.Context, repoName, relativePath string, position base.Position) (string, *base.Range, error) {
+	return rs.lspService.GetHover(ctx, repoName, relativePath, position)
+}
+
 // PrepareLanguageServer initializes the language server for a repository upfront.
 // This is useful for index building to ensure LSP is ready before post-processing.
 func (rs *RepoService) PrepareLanguageServer(repoName string) error {
 	return rs.lspService.PrepareLanguageServer(repoName)
 }
+
+// WarmFiles opens repository files in their language server ahead of the
+// first request
+func (rs *RepoService) WarmFiles(ctx context.Context, repoName string, relativePaths []string) (int, error) {
+	return rs.lspService.WarmFiles(ctx, repoName, relativePaths)
+}
+
+// ReleaseRepository stops the language servers of a repository that is no
+// longer served
+func (rs *RepoService) ReleaseRepository(ctx context.Context, repoName string) {
+	rs.lspService.CloseRepository(ctx, repoName)
+}
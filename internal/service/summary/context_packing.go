@@ -0,0 +1,97 @@
+package summary
+
+import (
+	"sort"
+)
+
+// ContextRelation is how a candidate piece of LLM context relates to the
+// entities a question is about
+type ContextRelation string
+
+const (
+	RelationSeed      ContextRelation = "seed"       // an entity the question is about, e.g. the function being summarized
+	RelationCallee    ContextRelation = "callee"     // called by a seed
+	RelationCaller    ContextRelation = "caller"     // calls a seed
+	RelationSameClass ContextRelation = "same_class" // another method of a seed's class
+)
+
+// relationWeights rank the entities a seed is connected to in the graph: a
+// callee explains what a seed does, a caller how it is used. Unknown
+// relations rank last.
+var relationWeights = map[ContextRelation]float64{
+	RelationSeed:      1.0,
+	RelationCallee:    0.8,
+	RelationCaller:    0.7,
+	RelationSameClass: 0.6,
+}
+
+// minTruncatedPiece is the smallest remainder of the budget worth filling
+// with a truncated piece
+const minTruncatedPiece = 200
+
+// ContextPiece is a candidate for the LLM context of a question: the
+// summary or code of an entity, with how it was found
+type ContextPiece struct {
+	EntityID  string          `json:"entity_id"`
+	Name      string          `json:"name"`
+	FilePath  string          `json:"file_path,omitempty"`
+	Relation  ContextRelation `json:"relation"`
+	Hops      int             `json:"hops"` // graph distance from the nearest seed
+	Text      string          `json:"text"`
+	Score     float64         `json:"score"` // set by PackContext
+	Truncated bool            `json:"truncated,omitempty"`
+}
+
+// ScoreContextPiece scores a candidate: its relation's weight, halved with
+// every hop away from the seeds
+func ScoreContextPiece(p ContextPiece) float64 {
+	weight := relationWeights[p.Relation]
+	for i := 0; i < p.Hops; i++ {
+		weight /= 2
+	}
+	return weight
+}
+
+// PackContext chooses the pieces of LLM context that fit the builder's
+// character budget, best scored first. An entity found several ways is
+// kept once, under its best score. When the next piece does not fit, the
+// rest of the budget is filled with it truncated, if enough is left, and
+// smaller pieces are still tried. Returns the chosen pieces in score order
+// and the number left out.
+func (cb *ContextBuilder) PackContext(candidates []ContextPiece) ([]ContextPiece, int) {
+	byEntity := make(map[string]int, len(candidates))
+	pieces := make([]ContextPiece, 0, len(candidates))
+	for _, p := range candidates {
+		p.Score = ScoreContextPiece(p)
+		if i, ok := byEntity[p.EntityID]; ok && p.EntityID != "" {
+			if p.Score > pieces[i].Score {
+				pieces[i] = p
+			}
+			continue
+		}
+		byEntity[p.EntityID] = len(pieces)
+		pieces = append(pieces, p)
+	}
+
+	sort.SliceStable(pieces, func(i, j int) bool {
+		return pieces[i].Score > pieces[j].Score
+	})
+
+	remaining := cb.maxContextChars
+	chosen := make([]ContextPiece, 0, len(pieces))
+	for _, p := range pieces {
+		size := len(p.Name) + len(p.Text)
+		if size > remaining {
+			if remaining-len(p.Name) < minTruncatedPiece {
+				continue
+			}
+			// Leave room for the marker truncateText appends
+			p.Text = cb.truncateText(p.Text, remaining-len(p.Name)-len("\n... (truncated)"))
+			p.Truncated = true
+			size = len(p.Name) + len(p.Text)
+		}
+		chosen = append(chosen, p)
+		remaining -= size
+	}
+	return chosen, len(pieces) - len(chosen)
+}
@@ -0,0 +1,147 @@
+package summary
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestScoreContextPiece(t *testing.T) {
+	tests := []struct {
+		name  string
+		piece ContextPiece
+		want  float64
+	}{
+		{
+			name:  "seed",
+			piece: ContextPiece{Relation: RelationSeed},
+			want:  1.0,
+		},
+		{
+			name:  "callee one hop away",
+			piece: ContextPiece{Relation: RelationCallee, Hops: 1},
+			want:  0.4,
+		},
+		{
+			name:  "caller two hops away",
+			piece: ContextPiece{Relation: RelationCaller, Hops: 2},
+			want:  0.175,
+		},
+		{
+			name:  "same class",
+			piece: ContextPiece{Relation: RelationSameClass},
+			want:  0.6,
+		},
+		{
+			name:  "unknown relation ranks last",
+			piece: ContextPiece{Relation: "imported"},
+			want:  0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ScoreContextPiece(tt.piece); math.Abs(got-tt.want) > 1e-6 {
+				t.Errorf("ScoreContextPiece() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPackContext(t *testing.T) {
+	piece := func(entityID string, relation ContextRelation, hops int, textLen int) ContextPiece {
+		return ContextPiece{
+			EntityID: entityID,
+			Name:     "fn" + entityID,
+			Relation: relation,
+			Hops:     hops,
+			Text:     strings.Repeat("x", textLen),
+		}
+	}
+
+	tests := []struct {
+		name          string
+		budget        int
+		candidates    []ContextPiece
+		wantIDs       []string
+		wantRelations []ContextRelation
+		wantTruncated []bool
+		wantLeftOut   int
+	}{
+		{
+			name:          "callees rank above callers and sibling methods",
+			budget:        1000,
+			candidates:    []ContextPiece{piece("1", RelationSameClass, 0, 10), piece("2", RelationCaller, 0, 10), piece("3", RelationCallee, 0, 10)},
+			wantIDs:       []string{"3", "2", "1"},
+			wantRelations: []ContextRelation{RelationCallee, RelationCaller, RelationSameClass},
+			wantTruncated: []bool{false, false, false},
+		},
+		{
+			name:          "entity found twice keeps its best score",
+			budget:        1000,
+			candidates:    []ContextPiece{piece("1", RelationCaller, 2, 10), piece("2", RelationSameClass, 1, 10), piece("1", RelationCallee, 0, 10)},
+			wantIDs:       []string{"1", "2"},
+			wantRelations: []ContextRelation{RelationCallee, RelationSameClass},
+			wantTruncated: []bool{false, false},
+		},
+		{
+			name:          "pieces without an entity are not merged",
+			budget:        1000,
+			candidates:    []ContextPiece{piece("", RelationCallee, 1, 10), piece("", RelationCaller, 1, 10)},
+			wantIDs:       []string{"", ""},
+			wantRelations: []ContextRelation{RelationCallee, RelationCaller},
+			wantTruncated: []bool{false, false},
+		},
+		{
+			name:          "piece that overflows is truncated into the rest of the budget",
+			budget:        600,
+			candidates:    []ContextPiece{piece("1", RelationSeed, 0, 300), piece("2", RelationCallee, 1, 1000)},
+			wantIDs:       []string{"1", "2"},
+			wantRelations: []ContextRelation{RelationSeed, RelationCallee},
+			wantTruncated: []bool{false, true},
+		},
+		{
+			name:          "too little left to truncate, smaller pieces still fit",
+			budget:        300,
+			candidates:    []ContextPiece{piece("1", RelationSeed, 0, 200), piece("2", RelationCallee, 1, 500), piece("3", RelationCaller, 1, 50)},
+			wantIDs:       []string{"1", "3"},
+			wantRelations: []ContextRelation{RelationSeed, RelationCaller},
+			wantTruncated: []bool{false, false},
+			wantLeftOut:   1,
+		},
+		{
+			name:        "nothing fits",
+			budget:      100,
+			candidates:  []ContextPiece{piece("1", RelationSeed, 0, 500)},
+			wantLeftOut: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chosen, leftOut := NewContextBuilder(tt.budget).PackContext(tt.candidates)
+			if leftOut != tt.wantLeftOut {
+				t.Errorf("left out %d, want %d", leftOut, tt.wantLeftOut)
+			}
+			if len(chosen) != len(tt.wantIDs) {
+				t.Fatalf("chose %d pieces, want %d", len(chosen), len(tt.wantIDs))
+			}
+			size := 0
+			for i, p := range chosen {
+				if p.EntityID != tt.wantIDs[i] || p.Relation != tt.wantRelations[i] {
+					t.Errorf("piece %d = %q (%s), want %q (%s)", i, p.EntityID, p.Relation, tt.wantIDs[i], tt.wantRelations[i])
+				}
+				if p.Truncated != tt.wantTruncated[i] {
+					t.Errorf("piece %d truncated = %v, want %v", i, p.Truncated, tt.wantTruncated[i])
+				}
+				if p.Score != ScoreContextPiece(p) {
+					t.Errorf("piece %d score = %v, want %v", i, p.Score, ScoreContextPiece(p))
+				}
+				size += len(p.Name) + len(p.Text)
+			}
+			if size > tt.budget {
+				t.Errorf("chosen pieces take %d characters, budget is %d", size, tt.budget)
+			}
+		})
+	}
+}
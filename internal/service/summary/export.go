@@ -0,0 +1,420 @@
+package summary
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Formats Export can write
+const (
+	ExportMarkdown = "markdown"
+	ExportHTML     = "html"
+)
+
+// blurbLength caps the one-line description shown next to links
+const blurbLength = 160
+
+// ExportOptions controls how Export renders a repository's summaries
+type ExportOptions struct {
+	Format string // ExportMarkdown (default) or ExportHTML
+
+	// MethodClasses maps the entity ID of a function summary to the entity
+	// ID of the class containing it, to list methods under their class.
+	// Functions missing from it are listed on their file's page.
+	MethodClasses map[string]string
+}
+
+// Export writes the summaries of a repository under dir as pages linked
+// project → folder → file: index at the root with the project summary, an
+// index in each folder and one page per file with its classes, methods and
+// functions. Summaries of entities from older file versions are dropped in
+// favour of the latest one with the same file, class and name. It returns
+// the number of pages written.
+func Export(summaries []*CodeSummary, repoName, dir string, opts ExportOptions) (int, error) {
+	var render func(*exportPage) ([]byte, error)
+	var ext string
+	switch opts.Format {
+	case "", ExportMarkdown:
+		render, ext = renderMarkdown, ".md"
+	case ExportHTML:
+		render, ext = renderHTML, ".html"
+	default:
+		return 0, fmt.Errorf("unknown export format %q: must be %s or %s", opts.Format, ExportMarkdown, ExportHTML)
+	}
+
+	tree := buildExportTree(summaries, opts.MethodClasses)
+	pages := tree.pages(repoName, ext)
+	for _, p := range pages {
+		content, err := render(p)
+		if err != nil {
+			return 0, fmt.Errorf("failed to render %s: %w", p.path, err)
+		}
+		target := filepath.Join(dir, filepath.FromSlash(p.path))
+		if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+			return 0, fmt.Errorf("failed to create directory for %s: %w", p.path, err)
+		}
+		if err := os.WriteFile(target, content, 0o644); err != nil {
+			return 0, fmt.Errorf("failed to write %s: %w", p.path, err)
+		}
+	}
+	return len(pages), nil
+}
+
+type exportClass struct {
+	summary *CodeSummary
+	methods map[string]*CodeSummary // by name
+}
+
+type exportFile struct {
+	summary   *CodeSummary
+	classes   map[string]*exportClass // by name
+	functions map[string]*CodeSummary // by name
+}
+
+type exportFolder struct {
+	summary *CodeSummary
+	folders map[string]bool
+	files   map[string]bool
+}
+
+// exportTree is the summaries of a repository arranged by path, keyed by
+// slash-separated paths relative to the repository root ("." for the root)
+type exportTree struct {
+	project *CodeSummary
+	folders map[string]*exportFolder
+	files   map[string]*exportFile
+}
+
+// newer reports whether s should replace current
+func newer(s, current *CodeSummary) bool {
+	return current == nil || s.UpdatedAt.After(current.UpdatedAt)
+}
+
+func buildExportTree(summaries []*CodeSummary, methodClasses map[string]string) *exportTree {
+	t := &exportTree{
+		folders: map[string]*exportFolder{".": {folders: map[string]bool{}, files: map[string]bool{}}},
+		files:   map[string]*exportFile{},
+	}
+	file := func(p string) *exportFile {
+		p = path.Clean(filepath.ToSlash(p))
+		f, ok := t.files[p]
+		if !ok {
+			f = &exportFile{classes: map[string]*exportClass{}, functions: map[string]*CodeSummary{}}
+			t.files[p] = f
+		}
+		return f
+	}
+
+	// Classes first, so methods can find them by entity ID
+	classesByID := map[string]*exportClass{}
+	var functions []*CodeSummary
+	for _, s := range summaries {
+		switch s.EntityType {
+		case LevelProject:
+			if newer(s, t.project) {
+				t.project = s
+			}
+		case LevelFolder:
+			f := t.folder(path.Clean(filepath.ToSlash(s.FilePath)))
+			if newer(s, f.summary) {
+				f.summary = s
+			}
+		case LevelFile:
+			f := file(s.FilePath)
+			if newer(s, f.summary) {
+				f.summary = s
+			}
+		case LevelClass:
+			f := file(s.FilePath)
+			c, ok := f.classes[s.EntityName]
+			if !ok {
+				c = &exportClass{methods: map[string]*CodeSummary{}}
+				f.classes[s.EntityName] = c
+			}
+			if newer(s, c.summary) {
+				c.summary = s
+			}
+			classesByID[s.EntityID] = c
+		case LevelFunction:
+			functions = append(functions, s)
+		}
+	}
+	for _, s := range functions {
+		byName := file(s.FilePath).functions
+		if c, ok := classesByID[methodClasses[s.EntityID]]; ok {
+			byName = c.methods
+		}
+		if newer(s, byName[s.EntityName]) {
+			byName[s.EntityName] = s
+		}
+	}
+
+	// Link every file into its folder, and each folder into its parent
+	for p := range t.files {
+		dir := path.Dir(p)
+		t.folder(dir).files[p] = true
+	}
+	return t
+}
+
+// folder returns the folder at p, adding it and its ancestors to the tree
+func (t *exportTree) folder(p string) *exportFolder {
+	f, ok := t.folders[p]
+	if !ok {
+		f = &exportFolder{folders: map[string]bool{}, files: map[string]bool{}}
+		t.folders[p] = f
+		t.folder(path.Dir(p)).folders[p] = true
+	}
+	return f
+}
+
+// exportLink is a link to another page, with the start of its summary
+type exportLink struct {
+	Text  string
+	Href  string
+	Blurb string
+}
+
+// exportEntry is a summarized entity on a file page; methods are entries
+// nested under their class
+type exportEntry struct {
+	Name    string
+	Summary []string // paragraphs
+	Methods []exportEntry
+}
+
+type exportSection struct {
+	Heading string
+	Links   []exportLink
+	Entries []exportEntry
+}
+
+// exportPage is one page of the export, independent of its format
+type exportPage struct {
+	path     string // relative to the export directory
+	Crumbs   []exportLink
+	Title    string
+	Summary  []string
+	Sections []exportSection
+}
+
+func (t *exportTree) pages(repoName, ext string) []*exportPage {
+	folderPage := func(p string) string { return path.Join(p, "index"+ext) }
+	filePage := func(p string) string { return p + ext }
+	link := func(from, to, text string, s *CodeSummary) exportLink {
+		href, _ := filepath.Rel(path.Dir(from), to)
+		l := exportLink{Text: text, Href: filepath.ToSlash(href)}
+		if s != nil {
+			l.Blurb = blurb(s.Summary)
+		}
+		return l
+	}
+	crumbs := func(from, p string) []exportLink {
+		var dirs []string
+		for d := path.Dir(p); d != "."; d = path.Dir(d) {
+			dirs = append(dirs, d)
+		}
+		links := []exportLink{link(from, folderPage("."), repoName, nil)}
+		for i := len(dirs) - 1; i >= 0; i-- {
+			links = append(links, link(from, folderPage(dirs[i]), path.Base(dirs[i]), nil))
+		}
+		return links
+	}
+
+	var pages []*exportPage
+	for _, p := range sortedKeys(t.folders) {
+		f := t.folders[p]
+		page := &exportPage{path: folderPage(p), Title: path.Base(p)}
+		if p == "." {
+			page.Title = repoName
+			if t.project != nil {
+				page.Summary = paragraphs(t.project.Summary)
+			}
+		} else {
+			page.Crumbs = crumbs(page.path, p)
+			if f.summary != nil {
+				page.Summary = paragraphs(f.summary.Summary)
+			}
+		}
+
+		folders := exportSection{Heading: "Folders"}
+		for _, sub := range sortedKeys(f.folders) {
+			folders.Links = append(folders.Links, link(page.path, folderPage(sub), path.Base(sub)+"/", t.folders[sub].summary))
+		}
+		files := exportSection{Heading: "Files"}
+		for _, fp := range sortedKeys(f.files) {
+			files.Links = append(files.Links, link(page.path, filePage(fp), path.Base(fp), t.files[fp].summary))
+		}
+		for _, s := range []exportSection{folders, files} {
+			if len(s.Links) > 0 {
+				page.Sections = append(page.Sections, s)
+			}
+		}
+		pages = append(pages, page)
+	}
+
+	for _, p := range sortedKeys(t.files) {
+		f := t.files[p]
+		page := &exportPage{path: filePage(p), Title: path.Base(p)}
+		page.Crumbs = crumbs(page.path, p)
+		if f.summary != nil {
+			page.Summary = paragraphs(f.summary.Summary)
+		}
+
+		classes := exportSection{Heading: "Classes"}
+		for _, name := range sortedKeys(f.classes) {
+			c := f.classes[name]
+			entry := exportEntry{Name: name}
+			if c.summary != nil {
+				entry.Summary = paragraphs(c.summary.Summary)
+			}
+			for _, m := range sortedKeys(c.methods) {
+				entry.Methods = append(entry.Methods, exportEntry{Name: m, Summary: paragraphs(c.methods[m].Summary)})
+			}
+			classes.Entries = append(classes.Entries, entry)
+		}
+		functions := exportSection{Heading: "Functions"}
+		for _, name := range sortedKeys(f.functions) {
+			functions.Entries = append(functions.Entries, exportEntry{Name: name, Summary: paragraphs(f.functions[name].Summary)})
+		}
+		for _, s := range []exportSection{classes, functions} {
+			if len(s.Entries) > 0 {
+				page.Sections = append(page.Sections, s)
+			}
+		}
+		pages = append(pages, page)
+	}
+	return pages
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// paragraphs splits a summary on blank lines
+func paragraphs(s string) []string {
+	var result []string
+	for _, p := range strings.Split(strings.ReplaceAll(s, "\r\n", "\n"), "\n\n") {
+		if p = strings.TrimSpace(p); p != "" {
+			result = append(result, p)
+		}
+	}
+	return result
+}
+
+// blurb is the first line of a summary, shortened for a link list
+func blurb(s string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(s), "\n")
+	line = strings.TrimSpace(line)
+	if utf8.RuneCountInString(line) <= blurbLength {
+		return line
+	}
+	runes := []rune(line)
+	return strings.TrimSpace(string(runes[:blurbLength])) + "…"
+}
+
+func renderMarkdown(p *exportPage) ([]byte, error) {
+	var b bytes.Buffer
+	if len(p.Crumbs) > 0 {
+		for _, c := range p.Crumbs {
+			fmt.Fprintf(&b, "[%s](%s) / ", c.Text, c.Href)
+		}
+		fmt.Fprintf(&b, "%s\n\n", p.Title)
+	}
+	fmt.Fprintf(&b, "# %s\n\n", p.Title)
+	writeParagraphs(&b, p.Summary)
+	for _, s := range p.Sections {
+		fmt.Fprintf(&b, "## %s\n\n", s.Heading)
+		for _, l := range s.Links {
+			fmt.Fprintf(&b, "- [%s](%s)", l.Text, l.Href)
+			if l.Blurb != "" {
+				fmt.Fprintf(&b, " — %s", l.Blurb)
+			}
+			b.WriteString("\n")
+		}
+		if len(s.Links) > 0 {
+			b.WriteString("\n")
+		}
+		for _, e := range s.Entries {
+			fmt.Fprintf(&b, "### %s\n\n", e.Name)
+			writeParagraphs(&b, e.Summary)
+			for _, m := range e.Methods {
+				fmt.Fprintf(&b, "#### %s.%s\n\n", e.Name, m.Name)
+				writeParagraphs(&b, m.Summary)
+			}
+		}
+	}
+	return append(bytes.TrimRight(b.Bytes(), "\n"), '\n'), nil
+}
+
+func writeParagraphs(b *bytes.Buffer, paras []string) {
+	if len(paras) == 0 {
+		b.WriteString("_No summary._\n\n")
+		return
+	}
+	for _, p := range paras {
+		b.WriteString(p)
+		b.WriteString("\n\n")
+	}
+}
+
+var exportHTMLTemplate = template.Must(template.New("page").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: sans-serif; max-width: 60em; margin: 2em auto; padding: 0 1em; line-height: 1.5; }
+p { white-space: pre-wrap; }
+nav, .blurb, .none { color: #666; }
+</style>
+</head>
+<body>
+{{- if .Crumbs}}
+<nav>{{range .Crumbs}}<a href="{{.Href}}">{{.Text}}</a> / {{end}}{{.Title}}</nav>
+{{- end}}
+<h1>{{.Title}}</h1>
+{{template "summary" .Summary}}
+{{- range .Sections}}
+<h2>{{.Heading}}</h2>
+{{- if .Links}}
+<ul>
+{{- range .Links}}
+<li><a href="{{.Href}}">{{.Text}}</a>{{if .Blurb}} <span class="blurb">— {{.Blurb}}</span>{{end}}</li>
+{{- end}}
+</ul>
+{{- end}}
+{{- range $entry := .Entries}}
+<h3>{{.Name}}</h3>
+{{template "summary" .Summary}}
+{{- range .Methods}}
+<h4>{{$entry.Name}}.{{.Name}}</h4>
+{{template "summary" .Summary}}
+{{- end}}
+{{- end}}
+{{- end}}
+</body>
+</html>
+{{define "summary"}}{{if .}}{{range .}}<p>{{.}}</p>
+{{end}}{{else}}<p class="none"><em>No summary.</em></p>
+{{end}}{{end}}`))
+
+func renderHTML(p *exportPage) ([]byte, error) {
+	var b bytes.Buffer
+	if err := exportHTMLTemplate.Execute(&b, p); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
@@ -175,12 +175,17 @@ levels:
       {{if .Signature}}Signature: {{.Signature}}{{end}}
       {{if .Docstring}}Existing Docstring: {{.Docstring}}{{end}}
       {{if .Annotations}}Annotations: {{range .Annotations}}@{{.}} {{end}}{{end}}
+      {{if .Metrics}}Metrics: cyclomatic complexity {{.Metrics.Complexity}}, {{.Metrics.LOC}} lines of code, nesting depth {{.Metrics.Nesting}}{{end}}
 
       Code:
       ` + "```{{.Language}}" + `
       {{.SourceCode}}
       ` + "```" + `
-    context_fields: [name, signature, docstring, source_code, parameters, return_type, annotations]
+      {{if .RelatedContext}}
+      Related functions (callees, callers and methods of the same class):
+      {{range .RelatedContext}}- {{.Name}} ({{.Relation}}): {{.Text}}
+      {{end}}{{end}}
+    context_fields: [name, signature, docstring, source_code, parameters, return_type, annotations, metrics, related_context]
     max_context_chars: 4000
 
   class:
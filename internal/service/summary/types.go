@@ -51,19 +51,25 @@ func ParseSummaryLevel(s string) SummaryLevel {
 
 // CodeSummary represents a generated summary for a code entity
 type CodeSummary struct {
-	ID           int64        `json:"id" db:"id"`
-	EntityID     string       `json:"entity_id" db:"entity_id"`         // AST NodeID or path
-	EntityType   SummaryLevel `json:"entity_type" db:"entity_type"`     // function, class, file, folder, project
-	EntityName   string       `json:"entity_name" db:"entity_name"`
-	FilePath     string       `json:"file_path" db:"file_path"`
-	Summary      string       `json:"summary" db:"summary"`
-	ContextHash  string       `json:"context_hash" db:"context_hash"`   // Hash of input context
-	LLMProvider  string       `json:"llm_provider" db:"llm_provider"`
-	LLMModel     string       `json:"llm_model" db:"llm_model"`
-	PromptTokens int          `json:"prompt_tokens" db:"prompt_tokens"`
-	OutputTokens int          `json:"output_tokens" db:"output_tokens"`
-	CreatedAt    time.Time    `json:"created_at" db:"created_at"`
-	UpdatedAt    time.Time    `json:"updated_at" db:"updated_at"`
+	ID               int64        `json:"id" db:"id"`
+	EntityID         string       `json:"entity_id" db:"entity_id"`                             // AST NodeID or path
+	PreviousEntityID string       `json:"previous_entity_id,omitempty" db:"previous_entity_id"` // Entity of the previous file version the summary was carried from
+	EntityType       SummaryLevel `json:"entity_type" db:"entity_type"`                         // function, class, file, folder, project
+	EntityName       string       `json:"entity_name" db:"entity_name"`
+	FilePath         string       `json:"file_path" db:"file_path"`
+	Summary          string       `json:"summary" db:"summary"`
+	ContextHash      string       `json:"context_hash" db:"context_hash"` // Hash of input context
+	Encryption       string       `json:"-" db:"encryption"`              // encryption.Version of the stored summary text, empty for plaintext
+	LLMProvider      string       `json:"llm_provider" db:"llm_provider"`
+	LLMModel         string       `json:"llm_model" db:"llm_model"`
+	PromptTokens     int          `json:"prompt_tokens" db:"prompt_tokens"`
+	OutputTokens     int          `json:"output_tokens" db:"output_tokens"`
+	CreatedAt        time.Time    `json:"created_at" db:"created_at"`
+	UpdatedAt        time.Time    `json:"updated_at" db:"updated_at"`
+	EntityRef        string       `json:"entity_ref,omitempty" db:"-"` // Public reference, set on API responses
+	// ContextPieces are the related entities packed into the prompt, set on
+	// summaries generated by the request
+	ContextPieces []ContextPiece `json:"context_pieces,omitempty" db:"-"`
 }
 
 // FunctionContext holds context for function-level summarization
@@ -79,6 +85,19 @@ type FunctionContext struct {
 	ClassName   string            `json:"class_name"` // If it's a method
 	Annotations []string          `json:"annotations"`
 	Modifiers   []string          `json:"modifiers"` // public, private, static, etc.
+	Metrics     *FunctionMetrics  `json:"metrics,omitempty"`
+	// RelatedContext holds the summaries of callees, callers and sibling
+	// methods chosen by PackContext. It is left out of the context hash so a
+	// neighbour's new summary does not regenerate this one.
+	RelatedContext []ContextPiece `json:"related_context,omitempty"`
+}
+
+// FunctionMetrics holds the size and complexity metrics of a function
+type FunctionMetrics struct {
+	Complexity int `json:"complexity"` // cyclomatic complexity
+	LOC        int `json:"loc"`        // lines holding code
+	Params     int `json:"params"`
+	Nesting    int `json:"nesting"` // deepest nesting of control structures
 }
 
 // ParameterInfo holds information about a function parameter
@@ -4,16 +4,19 @@ import (
 	"context"
 	"crypto/sha256"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 	"runtime"
+	"slices"
 	"strings"
-	"sync"
 
+	"github.com/armchr/codeapi/internal/budget"
 	"github.com/armchr/codeapi/internal/chunk"
 	"github.com/armchr/codeapi/internal/config"
 	"github.com/armchr/codeapi/internal/model"
+	"github.com/armchr/codeapi/internal/service/notes"
 	"github.com/armchr/codeapi/internal/util"
 
 	tree_sitter "github.com/tree-sitter/go-tree-sitter"
@@ -27,34 +30,57 @@ import (
 
 // CodeChunkService orchestrates code chunking, embedding, and vector storage
 type CodeChunkService struct {
-	vectorDB            VectorDatabase
-	embedding           EmbeddingModel
-	logger              *zap.Logger
-	parser              *tree_sitter.Parser
-	parserMutex         sync.Mutex // Protects parser access (tree-sitter is not thread-safe)
-	minConditionalLines int
-	minLoopLines        int
-	gcThreshold         int64
-	numFileThreads      int
+	vectorDB       VectorDatabase
+	embedding      EmbeddingModel
+	logger         *zap.Logger
+	chunking       config.ChunkingConfig
+	gcThreshold    int64
+	numFileThreads int
+}
+
+// ChunkOptions are the settings of the repository a file belongs to. The
+// zero value uses the service defaults.
+type ChunkOptions struct {
+	RepoName string
+	Chunking *config.ChunkingConfig // overrides of the service settings, see SetChunking
+}
+
+// RepoChunkOptions returns the chunk options of a repository
+func RepoChunkOptions(repo *config.Repository) ChunkOptions {
+	if repo == nil {
+		return ChunkOptions{}
+	}
+	return ChunkOptions{RepoName: repo.Name, Chunking: repo.Chunking}
 }
 
 // NewCodeChunkService creates a new code chunk service
 func NewCodeChunkService(vectorDB VectorDatabase, embedding EmbeddingModel, minConditionalLines, minLoopLines int, gcThreshold int64, numFileThreads int, logger *zap.Logger) *CodeChunkService {
 	return &CodeChunkService{
-		vectorDB:            vectorDB,
-		embedding:           embedding,
-		logger:              logger,
-		parser:              tree_sitter.NewParser(),
-		minConditionalLines: minConditionalLines,
-		minLoopLines:        minLoopLines,
-		gcThreshold:         gcThreshold,
-		numFileThreads:      numFileThreads,
+		vectorDB:  vectorDB,
+		embedding: embedding,
+		logger:    logger,
+		chunking: config.ChunkingConfig{
+			MinConditionalLines: minConditionalLines,
+			MinLoopLines:        minLoopLines,
+			MaxChunkTokens:      -1,
+			ContextHeader:       new(bool),
+		},
+		gcThreshold:    gcThreshold,
+		numFileThreads: numFileThreads,
 	}
 }
 
+// SetChunking sets the chunking settings used for repositories that do not
+// override them: thresholds, emitted chunk types, window splitting of
+// oversized chunks (see chunk.SplitOversized) and context headers. Until it
+// is called only the thresholds given to NewCodeChunkService apply.
+func (ccs *CodeChunkService) SetChunking(chunking config.ChunkingConfig) {
+	ccs.chunking = chunking
+}
+
 // ProcessFile processes a single source file and stores chunks in vector DB
 // Returns (chunks, error) - if error is non-nil, processing failed but can be retried
-func (ccs *CodeChunkService) ProcessFile(ctx context.Context, filePath, language, collectionName string) ([]*model.CodeChunk, error) {
+func (ccs *CodeChunkService) ProcessFile(ctx context.Context, filePath, language, collectionName string, opts ChunkOptions) ([]*model.CodeChunk, error) {
 	// Read file content
 	sourceCode, err := ccs.readFile(filePath)
 	if err != nil {
@@ -65,12 +91,12 @@ func (ccs *CodeChunkService) ProcessFile(ctx context.Context, filePath, language
 		return nil, nil // Return nil error to continue processing other files
 	}
 
-	return ccs.processFileWithContent(ctx, filePath, language, collectionName, sourceCode)
+	return ccs.processFileWithContent(ctx, filePath, language, collectionName, sourceCode, opts)
 }
 
 // ProcessFileWithContent processes a single source file with provided content and stores chunks in vector DB
 // Returns (chunks, error) - if error is non-nil, processing failed but can be retried
-func (ccs *CodeChunkService) processFileWithContent(ctx context.Context, filePath, language, collectionName string, sourceCode []byte) ([]*model.CodeChunk, error) {
+func (ccs *CodeChunkService) processFileWithContent(ctx context.Context, filePath, language, collectionName string, sourceCode []byte, opts ChunkOptions) ([]*model.CodeChunk, error) {
 	// Check for existing chunks in the database
 	existingChunks, err := ccs.vectorDB.GetChunksByFilePath(ctx, collectionName, filePath)
 	if err != nil {
@@ -81,7 +107,7 @@ func (ccs *CodeChunkService) processFileWithContent(ctx context.Context, filePat
 	}
 
 	// Parse file and generate chunks
-	chunks, err := ccs.parseAndChunk(ctx, filePath, language, sourceCode)
+	chunks, err := ccs.parseAndChunk(ctx, filePath, language, sourceCode, opts)
 	if err != nil {
 		// Parse errors might indicate corrupted files or unsupported syntax - log and skip
 		ccs.logger.Warn("Failed to parse file, skipping",
@@ -137,6 +163,9 @@ func (ccs *CodeChunkService) processFileWithContent(ctx context.Context, filePat
 			return nil, nil // Return nil error to continue processing other files
 		}
 		chunksToStore = newChunksWithEmbeddings
+		if ccs.SignatureVectors(ctx, collectionName) {
+			ccs.embedSignatures(ctx, language, chunksToStore)
+		}
 	}
 
 	// Store only new chunks in vector database
@@ -162,7 +191,7 @@ func (ccs *CodeChunkService) processFileWithContent(ctx context.Context, filePat
 // ProcessFileWithContentAndFileID processes a single source file with provided content and FileID
 // This version is used by the IndexBuilder which provides centralized FileID from MySQL
 // Returns (chunks, error) - if error is non-nil, processing failed but can be retried
-func (ccs *CodeChunkService) ProcessFileWithContentAndFileID(ctx context.Context, filePath, language, collectionName string, sourceCode []byte, fileID int32) ([]*model.CodeChunk, error) {
+func (ccs *CodeChunkService) ProcessFileWithContentAndFileID(ctx context.Context, filePath, language, collectionName string, sourceCode []byte, fileID int32, opts ChunkOptions) ([]*model.CodeChunk, error) {
 	// Check for existing chunks in the database
 	existingChunks, err := ccs.vectorDB.GetChunksByFilePath(ctx, collectionName, filePath)
 	if err != nil {
@@ -174,7 +203,7 @@ func (ccs *CodeChunkService) ProcessFileWithContentAndFileID(ctx context.Context
 	}
 
 	// Parse file and generate chunks
-	chunks, err := ccs.parseAndChunk(ctx, filePath, language, sourceCode)
+	chunks, err := ccs.parseAndChunk(ctx, filePath, language, sourceCode, opts)
 	if err != nil {
 		// Parse errors might indicate corrupted files or unsupported syntax - log and skip
 		ccs.logger.Warn("Failed to parse file, skipping",
@@ -266,6 +295,9 @@ func (ccs *CodeChunkService) ProcessFileWithContentAndFileID(ctx context.Context
 			return nil, nil // Return nil error to continue processing other files
 		}
 		chunksToStore = newChunksWithEmbeddings
+		if ccs.SignatureVectors(ctx, collectionName) {
+			ccs.embedSignatures(ctx, language, chunksToStore)
+		}
 	}
 
 	// Store only new chunks in vector database
@@ -299,9 +331,15 @@ func (ccs *CodeChunkService) ProcessDirectory(ctx context.Context, dirPath, coll
 	// Extract repository configuration if provided
 	var skipOtherLanguages bool
 	var repoLanguage string
+	languageAllowed := func(lang string) bool { return lang == repoLanguage }
+	opts := ChunkOptions{RepoName: collectionName}
 	if repo, ok := repoConfig.(*config.Repository); ok && repo != nil {
+		opts = RepoChunkOptions(repo)
 		skipOtherLanguages = repo.SkipOtherLanguages
 		repoLanguage = repo.Language
+		if repo.IsMultiLanguage() {
+			languageAllowed = repo.IncludesLanguage
+		}
 		if skipOtherLanguages {
 			ccs.logger.Info("Skip other languages enabled",
 				zap.String("repo_language", repoLanguage),
@@ -309,18 +347,21 @@ func (ccs *CodeChunkService) ProcessDirectory(ctx context.Context, dirPath, coll
 		}
 	}
 
-	err := util.WalkDirTree(dirPath, func(path string, err error) error {
+	err := util.WalkDirTree(ctx, dirPath, func(path string, err error) error {
 		if err != nil {
 			return err
 		}
 
 		language := ccs.detectLanguage(path)
-		if language == "" {
-			ccs.logger.Info("WalkDirTree - Skipping unsupported file", zap.String("path", path))
-			return nil
+
+		release, err := budget.Acquire(ctx, budget.CPU)
+		if err != nil {
+			return err
 		}
+		defer release()
+
 		// Process file
-		chunks, err := ccs.ProcessFile(ctx, path, language, collectionName)
+		chunks, err := ccs.ProcessFile(ctx, path, language, collectionName, opts)
 		if err != nil {
 			// This shouldn't happen as ProcessFile now handles errors internally
 			// But keep this as a safeguard
@@ -349,14 +390,16 @@ func (ccs *CodeChunkService) ProcessDirectory(ctx context.Context, dirPath, coll
 				return false
 			}
 
-			language := ccs.detectLanguage(path)
-			if language == "" {
+			// Files of other languages are chunked in line windows, unless
+			// they are binaries, lock files and the like
+			if util.ShouldSkipFile(path, nil) {
 				ccs.logger.Info("WalkDirTree - Skipping unsupported file", zap.String("path", path))
 				return true
 			}
+			language := ccs.detectLanguage(path)
 
 			// Skip files of other languages if skip_other_languages is enabled
-			if skipOtherLanguages && language != repoLanguage {
+			if skipOtherLanguages && !languageAllowed(language) {
 				ccs.logger.Error("WalkDirTree - Skipping file due to language mismatch",
 					zap.String("path", path),
 					zap.String("file_language", language),
@@ -405,7 +448,8 @@ func (ccs *CodeChunkService) SearchSimilarCode(ctx context.Context, collectionNa
 // SearchSimilarCodeBySnippet chunks a code snippet and searches for similar code in the database
 func (ccs *CodeChunkService) SearchSimilarCodeBySnippet(ctx context.Context, collectionName, codeSnippet, language string, limit int, filter map[string]interface{}) ([]*model.CodeChunk, []*model.CodeChunk, []float32, []int, error) {
 	// Parse and chunk the code snippet
-	queryChunks, err := ccs.parseAndChunk(ctx, "query.snippet", language, []byte(codeSnippet))
+	// A snippet has no repository or path worth putting into its embedding
+	queryChunks, err := ccs.parseAndChunk(ctx, "query.snippet", language, []byte(codeSnippet), ChunkOptions{Chunking: &config.ChunkingConfig{ContextHeader: util.Ptr(false)}})
 	if err != nil {
 		return nil, nil, nil, nil, fmt.Errorf("failed to parse code snippet: %w", err)
 	}
@@ -525,39 +569,306 @@ func (ccs *CodeChunkService) DeleteCollection(ctx context.Context, collectionNam
 	return nil
 }
 
+// ChunkContent parses source code into chunks without embedding or storing
+// them, for estimates such as dry runs. The service needs neither a vector
+// database nor an embedding model for this.
+func (ccs *CodeChunkService) ChunkContent(ctx context.Context, filePath, language string, sourceCode []byte, opts ChunkOptions) ([]*model.CodeChunk, error) {
+	return ccs.parseAndChunk(ctx, filePath, language, sourceCode, opts)
+}
+
+// GetChunkHierarchy returns a stored chunk with its ancestors and siblings,
+// see chunk.Hierarchy. The error wraps ErrChunkNotFound when no chunk has
+// the ID.
+func (ccs *CodeChunkService) GetChunkHierarchy(ctx context.Context, collectionName, chunkID string) (target *model.CodeChunk, ancestors, siblings []*model.CodeChunk, err error) {
+	target, err = ccs.vectorDB.GetChunkByID(ctx, collectionName, chunkID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if target.ParentID == "" {
+		return target, nil, nil, nil
+	}
+
+	fileChunks, err := ccs.vectorDB.GetChunksByFilePath(ctx, collectionName, target.FilePath)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to fetch chunks of %s: %w", target.FilePath, err)
+	}
+	ancestors, siblings = chunk.Hierarchy(target, fileChunks)
+	return target, ancestors, siblings, nil
+}
+
+// RemapChunks maps the line ranges of stored chunks to the current version
+// of their files under repoPath. Each chunk's lines are aligned with the
+// current file using the line hashes stored with it; chunks whose lines were
+// edited or are gone are flagged as ContentChanged. A chunk that cannot be
+// looked up gets an Error instead of failing the whole call.
+func (ccs *CodeChunkService) RemapChunks(ctx context.Context, collectionName, repoPath string, chunkIDs []string) []model.ChunkRemap {
+	type currentFile struct {
+		text string
+		sha  string
+		err  error
+	}
+	files := make(map[string]*currentFile)
+
+	results := make([]model.ChunkRemap, len(chunkIDs))
+	for i, id := range chunkIDs {
+		result := &results[i]
+		result.ChunkID = id
+
+		c, err := ccs.vectorDB.GetChunkByID(ctx, collectionName, id)
+		if err != nil {
+			result.Error = err.Error()
+			continue
+		}
+		result.FilePath, result.FileID, result.FileSHA = c.FilePath, c.FileID, c.FileSHA
+		result.OldStartLine, result.OldEndLine = c.StartLine, c.EndLine
+
+		file, ok := files[c.FilePath]
+		if !ok {
+			path := c.FilePath
+			if !filepath.IsAbs(path) {
+				path = filepath.Join(repoPath, path)
+			}
+			file = &currentFile{}
+			content, err := os.ReadFile(path)
+			if err == nil {
+				file.text, file.sha = string(content), util.CalculateFileSHA256(content)
+			} else {
+				file.err = err
+			}
+			files[c.FilePath] = file
+		}
+
+		var status chunk.RemapStatus
+		switch {
+		case errors.Is(file.err, os.ErrNotExist):
+			result.StartLine, result.EndLine, status = c.StartLine, c.EndLine, chunk.RemapDeleted
+		case file.err != nil:
+			result.Error = fmt.Sprintf("failed to read %s: %v", c.FilePath, file.err)
+			continue
+		case file.sha == c.FileSHA:
+			result.StartLine, result.EndLine, status = c.StartLine, c.EndLine, chunk.RemapUnchanged
+		case c.ChunkType == model.ChunkTypeFile:
+			result.StartLine, result.EndLine, status = 0, strings.Count(file.text, "\n"), chunk.RemapChanged
+		default:
+			result.StartLine, result.EndLine, status = chunk.RemapRange(c.LineHashes, c.StartLine, file.text)
+			if status == chunk.RemapUnknown {
+				result.EndLine = c.EndLine
+			}
+		}
+		result.CurrentSHA = file.sha
+		result.Status = string(status)
+		result.ContentChanged = status == chunk.RemapChanged || status == chunk.RemapDeleted
+	}
+	return results
+}
+
+// duplicateScrollBatch is the number of chunks read per page while loading
+// function embeddings for duplicate detection
+const duplicateScrollBatch = 512
+
+// FindDuplicates loads the function chunks stored in each collection, named
+// after its repository, and reports the clusters of near-identical ones, see
+// chunk.FindDuplicates. It returns the number of functions compared and at
+// most limit clusters, all of them if limit is not positive.
+func (ccs *CodeChunkService) FindDuplicates(ctx context.Context, collectionNames []string, threshold float64, minLines, limit int) (int, []model.DuplicateCluster, error) {
+	var functions []*model.CodeChunk
+	var repos []string
+	for _, collectionName := range collectionNames {
+		chunks, err := ccs.scrollAll(ctx, collectionName, func(c *model.CodeChunk) bool {
+			return c.ChunkType == model.ChunkTypeFunction
+		})
+		if err != nil {
+			return 0, nil, err
+		}
+		for _, c := range chunks {
+			functions = append(functions, c)
+			repos = append(repos, collectionName)
+		}
+	}
+
+	clusters := chunk.FindDuplicates(functions, threshold, minLines)
+	if limit > 0 && len(clusters) > limit {
+		clusters = clusters[:limit]
+	}
+
+	results := make([]model.DuplicateCluster, len(clusters))
+	for n, cluster := range clusters {
+		position := make(map[int]int, len(cluster.Members))
+		members := make([]model.DuplicateLocation, len(cluster.Members))
+		for i, index := range cluster.Members {
+			c := functions[index]
+			position[index] = i
+			members[i] = model.DuplicateLocation{
+				RepoName:  repos[index],
+				ChunkID:   c.ID,
+				Name:      c.Name,
+				ClassName: c.ClassName,
+				FilePath:  c.FilePath,
+				FileID:    c.FileID,
+				StartLine: c.StartLine,
+				EndLine:   c.EndLine,
+			}
+		}
+		pairs := make([]model.DuplicatePair, len(cluster.Pairs))
+		for i, p := range cluster.Pairs {
+			pairs[i] = model.DuplicatePair{A: position[p.A], B: position[p.B], Similarity: p.Similarity}
+		}
+		results[n] = model.DuplicateCluster{Similarity: cluster.Similarity(), Members: members, Pairs: pairs}
+	}
+	return len(functions), results, nil
+}
+
+// FindSimilarEntities returns the classes, or with className empty the
+// files, of the given collections most similar to a class or file of
+// collectionName, comparing the means of their chunk embeddings. It also
+// returns how many entities were compared. A class or file without embedded
+// chunks is ErrEntityNotFound.
+func (ccs *CodeChunkService) FindSimilarEntities(ctx context.Context, collectionName, filePath, className string, collectionNames []string, limit int, minSimilarity float64) (int, []chunk.SimilarEntity, error) {
+	aggregate := chunk.AggregateFiles
+	if className != "" {
+		aggregate = chunk.AggregateClasses
+	}
+	keep := func(c *model.CodeChunk) bool {
+		return c.ChunkType != model.ChunkTypeMethodSignature && c.ChunkType != model.ChunkTypeNote &&
+			c.ChunkType != model.ChunkTypeFolderSummary && c.ChunkType != model.ChunkTypeProjectSummary
+	}
+
+	var query *chunk.Entity
+	var candidates []*chunk.Entity
+	scanned := make(map[string]bool, len(collectionNames)+1)
+	for _, name := range append([]string{collectionName}, collectionNames...) {
+		if scanned[name] {
+			continue
+		}
+		scanned[name] = true
+		chunks, err := ccs.scrollAll(ctx, name, keep)
+		if err != nil {
+			return 0, nil, err
+		}
+		entities := aggregate(chunks)
+		for _, e := range entities {
+			e.Repo = name
+			if name == collectionName && e.FilePath == filePath && e.ClassName == className {
+				query = e
+			}
+		}
+		if name == collectionName && !slices.Contains(collectionNames, name) {
+			continue
+		}
+		candidates = append(candidates, entities...)
+	}
+
+	if query == nil {
+		if className != "" {
+			return 0, nil, fmt.Errorf("%w for class %s in %s", ErrEntityNotFound, className, filePath)
+		}
+		return 0, nil, fmt.Errorf("%w for file %s", ErrEntityNotFound, filePath)
+	}
+	return len(candidates), chunk.RankSimilar(query, candidates, limit, minSimilarity), nil
+}
+
+// scrollAll reads the chunks of a collection, with their embeddings, that
+// keep accepts
+func (ccs *CodeChunkService) scrollAll(ctx context.Context, collectionName string, keep func(*model.CodeChunk) bool) ([]*model.CodeChunk, error) {
+	var kept []*model.CodeChunk
+	offset := ""
+	for {
+		chunks, next, err := ccs.vectorDB.ScrollChunks(ctx, collectionName, offset, duplicateScrollBatch)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read chunks of %s: %w", collectionName, err)
+		}
+		for _, c := range chunks {
+			if keep(c) {
+				kept = append(kept, c)
+			}
+		}
+		if next == "" {
+			return kept, nil
+		}
+		offset = next
+	}
+}
+
 // Helper methods
 
-func (ccs *CodeChunkService) parseAndChunk(ctx context.Context, filePath, language string, sourceCode []byte) ([]*model.CodeChunk, error) {
+func (ccs *CodeChunkService) parseAndChunk(ctx context.Context, filePath, language string, sourceCode []byte, opts ChunkOptions) ([]*model.CodeChunk, error) {
+	settings := ccs.chunking.Merge(opts.Chunking)
+	chunks, imports, err := ccs.chunkFile(ctx, filePath, language, sourceCode, settings)
+	if err != nil {
+		return nil, err
+	}
+
+	fileSHA := util.CalculateFileSHA256(sourceCode)
+	fileHashes := chunk.LineHashes(string(sourceCode))
+	for _, c := range chunks {
+		c.FileSHA = fileSHA
+		// The file chunk always spans the whole file, it needs no hashes
+		if c.ChunkType != model.ChunkTypeFile {
+			c.LineHashes = chunk.LineRange(fileHashes, c.StartLine, c.EndLine)
+		}
+	}
+
+	if settings.ContextHeader != nil && *settings.ContextHeader {
+		chunk.AddContextHeaders(chunks, opts.RepoName, imports)
+	}
+	return chunks, nil
+}
+
+// chunkFile splits a file into chunks and returns them with its imports
+func (ccs *CodeChunkService) chunkFile(ctx context.Context, filePath, language string, sourceCode []byte, settings config.ChunkingConfig) ([]*model.CodeChunk, []chunk.Import, error) {
+	// Components and markdown hold code in other languages; each region is
+	// chunked on its own, as if it were a file in its language
+	if chunk.EmbedsLanguages(language) && util.IsText(sourceCode) {
+		return chunk.EmbeddedChunks(filePath, language, sourceCode, settings.MaxChunkTokens, settings.OverlapTokens,
+			func(region chunk.Region) ([]*model.CodeChunk, []chunk.Import, error) {
+				return ccs.chunkFile(ctx, filePath, region.Language, region.Content, settings)
+			})
+	}
+
 	// Get tree-sitter language
 	tsLanguage, err := ccs.getTreeSitterLanguage(language)
 	if err != nil {
-		return nil, err
+		// Without a visitor, text files still get line windows so they can
+		// be found by semantic search; binary files get nothing
+		if !util.IsText(sourceCode) {
+			return nil, nil, nil
+		}
+		return chunk.WindowChunks(filePath, language, sourceCode, settings.MaxChunkTokens, settings.OverlapTokens), nil, nil
 	}
 
-	// Lock parser access (tree-sitter is not thread-safe)
-	ccs.parserMutex.Lock()
-	defer ccs.parserMutex.Unlock()
+	// Tree-sitter parsers are not thread-safe; each call takes its own
+	// from the pool so files can be chunked concurrently
+	parser := util.AcquireParser()
+	defer util.ReleaseParser(parser)
 
 	// Set parser language
-	if err := ccs.parser.SetLanguage(tsLanguage); err != nil {
-		return nil, fmt.Errorf("failed to set parser language: %w", err)
+	if err := parser.SetLanguage(tsLanguage); err != nil {
+		return nil, nil, fmt.Errorf("failed to set parser language: %w", err)
 	}
 
 	// Parse source code
-	tree := ccs.parser.Parse(sourceCode, nil)
+	tree := parser.Parse(sourceCode, nil)
 	if tree == nil {
-		return nil, fmt.Errorf("failed to parse file")
+		return nil, nil, fmt.Errorf("failed to parse file")
 	}
 	defer tree.Close()
 
 	// Create chunk visitor
-	visitor := chunk.NewChunkVisitor(ccs.logger, language, filePath, sourceCode, ccs.minConditionalLines, ccs.minLoopLines)
+	visitor := chunk.NewChunkVisitor(ccs.logger, language, filePath, sourceCode, settings.MinConditionalLines, settings.MinLoopLines)
+	if len(settings.ChunkTypes) > 0 {
+		types := make([]model.ChunkType, len(settings.ChunkTypes))
+		for i, name := range settings.ChunkTypes {
+			types[i] = model.ChunkType(strings.ToLower(name))
+		}
+		visitor.SetChunkTypes(types)
+	}
 
 	// Traverse syntax tree
-	rootNode := tree.RootNode()
-	visitor.TraverseNode(ctx, rootNode, nil)
+	if err := visitor.Traverse(ctx, tree.RootNode()); err != nil {
+		return nil, nil, err
+	}
 
-	return visitor.GetChunks(), nil
+	return chunk.SplitOversized(visitor.GetChunks(), settings.MaxChunkTokens, settings.OverlapTokens), visitor.GetImports(), nil
 }
 
 func (ccs *CodeChunkService) generateAndPrepareEmbeddings(ctx context.Context, chunks []*model.CodeChunk) ([]*model.CodeChunk, error) {
@@ -763,7 +1074,8 @@ func (ccs *CodeChunkService) detectLanguage(filePath string) string {
 	case ".ts", ".tsx":
 		return "typescript"
 	default:
-		return ""
+		// Chunked in line windows; the name is only recorded on the chunks
+		return util.DetectLanguage(filePath, nil)
 	}
 }
 
@@ -988,6 +1300,63 @@ func (ccs *CodeChunkService) IndexMethodSignatures(ctx context.Context, collecti
 	return nil
 }
 
+// SignatureVectors reports whether the function chunks of a collection carry
+// their signature embedding as a named vector, in which case no separate
+// method_signature chunks are stored
+func (ccs *CodeChunkService) SignatureVectors(ctx context.Context, collectionName string) bool {
+	store, ok := ccs.vectorDB.(SignatureVectorStore)
+	if !ok {
+		return false
+	}
+	named, err := store.HasSignatureVectors(ctx, collectionName)
+	if err != nil {
+		ccs.logger.Debug("Failed to check for signature vectors",
+			zap.String("collection", collectionName), zap.Error(err))
+		return false
+	}
+	return named
+}
+
+// embedSignatures sets the signature embedding of the function chunks that
+// have a signature. Without it the chunks are still stored, just not found
+// by signature search, so a failure is only logged.
+func (ccs *CodeChunkService) embedSignatures(ctx context.Context, language string, chunks []*model.CodeChunk) {
+	var withSignature []*model.CodeChunk
+	var textsToEmbed []string
+	for _, chunk := range chunks {
+		if chunk.ChunkType != model.ChunkTypeFunction || chunk.Signature == "" {
+			continue
+		}
+		sigInfo := util.ParseSignatureByLanguage(chunk.Signature, chunk.Name, chunk.ClassName, language)
+		normalizedText := util.NormalizeSignatureForEmbedding(sigInfo)
+		if normalizedText == "" {
+			continue
+		}
+		if chunk.Metadata == nil {
+			chunk.Metadata = make(map[string]interface{})
+		}
+		chunk.Metadata["return_type"] = sigInfo.ReturnType
+		chunk.Metadata["parameter_types"] = strings.Join(sigInfo.ParameterTypes, ",")
+		chunk.Metadata["parameter_names"] = strings.Join(sigInfo.ParameterNames, ",")
+		chunk.Metadata["normalized_text"] = normalizedText
+		withSignature = append(withSignature, chunk)
+		textsToEmbed = append(textsToEmbed, normalizedText)
+	}
+	if len(withSignature) == 0 {
+		return
+	}
+
+	embeddings, err := ccs.embedding.GenerateEmbeddings(ctx, textsToEmbed)
+	if err != nil {
+		ccs.logger.Warn("Failed to generate signature embeddings, storing chunks without them",
+			zap.Int("signatures", len(withSignature)), zap.Error(err))
+		return
+	}
+	for i, embedding := range embeddings {
+		withSignature[i].SignatureEmbedding = embedding
+	}
+}
+
 // SearchMethodSignatures searches for methods by natural language query on their signatures
 func (ccs *CodeChunkService) SearchMethodSignatures(ctx context.Context, collectionName, query string, limit int) ([]*model.CodeChunk, []float32, error) {
 	// Normalize the query text similarly to how signatures are normalized
@@ -1000,9 +1369,13 @@ func (ccs *CodeChunkService) SearchMethodSignatures(ctx context.Context, collect
 		return nil, nil, fmt.Errorf("failed to generate query embedding: %w", err)
 	}
 
-	// Create filter to only search method_signature chunks
-	filter := map[string]interface{}{
-		"chunk_type": string(model.ChunkTypeMethodSignature),
+	filter := ccs.SignatureSearchFilter(ctx, collectionName)
+	if store, ok := ccs.vectorDB.(SignatureVectorStore); ok && filter["chunk_type"] == string(model.ChunkTypeFunction) {
+		chunks, scores, err := store.SearchSignatures(ctx, collectionName, queryVector, limit, filter)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to search signatures: %w", err)
+		}
+		return chunks, scores, nil
 	}
 
 	// Search in vector database
@@ -1014,6 +1387,16 @@ func (ccs *CodeChunkService) SearchMethodSignatures(ctx context.Context, collect
 	return chunks, scores, nil
 }
 
+// SignatureSearchFilter returns the payload filter SearchMethodSignatures
+// applies in a collection. Signatures are either named vectors of the
+// function chunks or method_signature chunks of their own.
+func (ccs *CodeChunkService) SignatureSearchFilter(ctx context.Context, collectionName string) map[string]interface{} {
+	if ccs.SignatureVectors(ctx, collectionName) {
+		return map[string]interface{}{"chunk_type": string(model.ChunkTypeFunction)}
+	}
+	return map[string]interface{}{"chunk_type": string(model.ChunkTypeMethodSignature)}
+}
+
 // generateSignatureChunkID generates a unique ID for a method signature chunk
 func (ccs *CodeChunkService) generateSignatureChunkID(filePath, className, methodName string, line uint) string {
 	input := fmt.Sprintf("%s:%s:%s:%d:signature", filePath, className, methodName, line)
@@ -1029,3 +1412,145 @@ func (ccs *CodeChunkService) generateSignatureChunkID(filePath, className, metho
 		hashStr[20:32],
 	)
 }
+
+// IndexNotes embeds TODO-style notes of a file version so they can be found
+// by meaning. License headers are left out; they are the same everywhere.
+// Note text is kept in the metadata since the vector store drops content.
+func (ccs *CodeChunkService) IndexNotes(ctx context.Context, collectionName string, fileID int32, fileNotes []notes.CodeNote) error {
+	var chunks []*model.CodeChunk
+	var textsToEmbed []string
+	for _, n := range fileNotes {
+		if n.Kind == notes.KindLicense || n.Text == "" {
+			continue
+		}
+		input := fmt.Sprintf("%d:%s:%d:note", fileID, n.FilePath, n.Line)
+		hash := sha256.Sum256([]byte(input))
+		hashStr := hex.EncodeToString(hash[:])
+
+		chunks = append(chunks, &model.CodeChunk{
+			ID:        fmt.Sprintf("%s-%s-%s-%s-%s", hashStr[0:8], hashStr[8:12], hashStr[12:16], hashStr[16:20], hashStr[20:32]),
+			FileID:    fileID,
+			ChunkType: model.ChunkTypeNote,
+			Level:     4,
+			FilePath:  n.FilePath,
+			StartLine: n.Line - 1, // chunks count lines from 0
+			EndLine:   n.Line - 1,
+			Name:      n.Kind,
+			Metadata: map[string]interface{}{
+				"kind":     n.Kind,
+				"text":     n.Text,
+				"assignee": n.Assignee,
+				"author":   n.Author,
+			},
+		})
+		textsToEmbed = append(textsToEmbed, n.Kind+": "+n.Text)
+	}
+	if len(chunks) == 0 {
+		return nil
+	}
+
+	embeddings, err := ccs.embedding.GenerateEmbeddings(ctx, textsToEmbed)
+	if err != nil {
+		return fmt.Errorf("failed to generate note embeddings: %w", err)
+	}
+	for i, embedding := range embeddings {
+		chunks[i].Embedding = embedding
+	}
+
+	if err := ccs.vectorDB.UpsertChunks(ctx, collectionName, chunks); err != nil {
+		return fmt.Errorf("failed to store note chunks: %w", err)
+	}
+	return nil
+}
+
+// NoteSearchFilter returns the payload filter SearchNotes applies
+func NoteSearchFilter() map[string]interface{} {
+	return map[string]interface{}{"chunk_type": string(model.ChunkTypeNote)}
+}
+
+// SearchNotes finds the notes closest in meaning to query
+func (ccs *CodeChunkService) SearchNotes(ctx context.Context, collectionName, query string, limit int) ([]*model.CodeChunk, []float32, error) {
+	queryVector, err := ccs.embedding.GenerateEmbedding(ctx, query)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to generate query embedding: %w", err)
+	}
+
+	chunks, scores, err := ccs.vectorDB.SearchSimilar(ctx, collectionName, queryVector, limit, NoteSearchFilter())
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to search notes: %w", err)
+	}
+	return chunks, scores, nil
+}
+
+// SummaryData holds a folder or project summary to embed
+type SummaryData struct {
+	Path    string // Folder path in the repository; the repository name for the project
+	Summary string
+}
+
+// IndexSummaries embeds folder or project summaries, chunkType telling
+// which, so searches can first pick the folders a query is about. A
+// summary replaces the one embedded before for the same path. The summary
+// text is kept in the metadata since the vector store drops content.
+func (ccs *CodeChunkService) IndexSummaries(ctx context.Context, collectionName string, chunkType model.ChunkType, summaries []SummaryData) error {
+	var chunks []*model.CodeChunk
+	var textsToEmbed []string
+	for _, s := range summaries {
+		if strings.TrimSpace(s.Summary) == "" {
+			continue
+		}
+		hash := sha256.Sum256([]byte(fmt.Sprintf("%s:%s:summary", s.Path, chunkType)))
+		hashStr := hex.EncodeToString(hash[:])
+
+		chunks = append(chunks, &model.CodeChunk{
+			ID:        fmt.Sprintf("%s-%s-%s-%s-%s", hashStr[0:8], hashStr[8:12], hashStr[12:16], hashStr[16:20], hashStr[20:32]),
+			ChunkType: chunkType,
+			Level:     0, // Above the file chunks
+			FilePath:  s.Path,
+			Name:      filepath.Base(s.Path),
+			Metadata: map[string]interface{}{
+				"summary": s.Summary,
+			},
+		})
+		textsToEmbed = append(textsToEmbed, s.Path+"\n"+s.Summary)
+	}
+	if len(chunks) == 0 {
+		return nil
+	}
+
+	embeddings, err := ccs.embedding.GenerateEmbeddings(ctx, textsToEmbed)
+	if err != nil {
+		return fmt.Errorf("failed to generate summary embeddings: %w", err)
+	}
+	for i, embedding := range embeddings {
+		chunks[i].Embedding = embedding
+	}
+
+	if err := ccs.vectorDB.UpsertChunks(ctx, collectionName, chunks); err != nil {
+		return fmt.Errorf("failed to store summary chunks: %w", err)
+	}
+	return nil
+}
+
+// SelectFolders returns the limit folders whose summaries are closest to
+// text, best first, with their scores. It returns none when no folder
+// summaries are embedded.
+func (ccs *CodeChunkService) SelectFolders(ctx context.Context, collectionName, text string, limit int) ([]string, []float32, error) {
+	queryVector, err := ccs.embedding.GenerateEmbedding(ctx, text)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to generate query embedding: %w", err)
+	}
+
+	filter := map[string]interface{}{
+		"chunk_type": string(model.ChunkTypeFolderSummary),
+	}
+	chunks, scores, err := ccs.vectorDB.SearchSimilar(ctx, collectionName, queryVector, limit, filter)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to search folder summaries: %w", err)
+	}
+	folders := make([]string, len(chunks))
+	for i, c := range chunks {
+		folders[i] = c.FilePath
+	}
+	return folders, scores, nil
+}
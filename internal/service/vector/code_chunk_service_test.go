@@ -0,0 +1,287 @@
+package vector
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/armchr/codeapi/internal/config"
+	"github.com/armchr/codeapi/internal/model"
+	"github.com/armchr/codeapi/internal/util"
+
+	"go.uber.org/zap"
+)
+
+func TestChunkContentConcurrentAndCapped(t *testing.T) {
+	var src strings.Builder
+	src.WriteString("package big\n\n")
+	for i := 0; src.Len() <= 2*model.MaxChunkContentBytes; i++ {
+		fmt.Fprintf(&src, "func f%d() int {\n\treturn %d\n}\n\n", i, i)
+	}
+	source := []byte(src.String())
+
+	ccs := NewCodeChunkService(nil, nil, 5, 5, 0, 1, zap.NewNop())
+	var wg sync.WaitGroup
+	errs := make(chan error, 8)
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			chunks, err := ccs.ChunkContent(context.Background(), "big.go", "go", source, ChunkOptions{})
+			if err != nil {
+				errs <- err
+				return
+			}
+			for _, c := range chunks {
+				if c.ChunkType != model.ChunkTypeFile {
+					continue
+				}
+				if len(c.Content) > model.MaxChunkContentBytes+len("\n// ... (truncated)") {
+					errs <- fmt.Errorf("file chunk holds %d bytes", len(c.Content))
+				} else if !strings.HasSuffix(c.Content, "// ... (truncated)") {
+					errs <- fmt.Errorf("file chunk is not marked as truncated")
+				}
+				return
+			}
+			errs <- fmt.Errorf("no file chunk among %d chunks", len(chunks))
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+}
+
+func TestChunkContentFallback(t *testing.T) {
+	ccs := NewCodeChunkService(nil, nil, 5, 5, 0, 1, zap.NewNop())
+
+	tests := []struct {
+		name     string
+		path     string
+		language string
+		content  string
+		want     []model.ChunkType
+	}{
+		{"unsupported language", "lib.rs", "rust", "fn main() {\n    println!(\"hi\");\n}\n",
+			[]model.ChunkType{model.ChunkTypeFile, model.ChunkTypeWindow}},
+		{"undetected language", "notes", "", "plain text\n",
+			[]model.ChunkType{model.ChunkTypeFile, model.ChunkTypeWindow}},
+		{"binary", "blob.dat", "", "\x7fELF\x00\x00", nil},
+		{"blank", "empty.rs", "rust", "\n\n", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chunks, err := ccs.ChunkContent(context.Background(), tt.path, tt.language, []byte(tt.content), ChunkOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []model.ChunkType
+			for _, c := range chunks {
+				got = append(got, c.ChunkType)
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("chunk types = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChunkContentRepoSettings(t *testing.T) {
+	source := []byte(`import os
+
+class Store:
+    def path(self):
+        return os.getcwd()
+`)
+	ccs := NewCodeChunkService(nil, nil, 5, 5, 0, 1, zap.NewNop())
+	opts := ChunkOptions{RepoName: "shop", Chunking: &config.ChunkingConfig{
+		ChunkTypes:    []string{"file", "Function"},
+		ContextHeader: util.Ptr(true),
+	}}
+
+	chunks, err := ccs.ChunkContent(context.Background(), "store.py", "python", source, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(chunks) != 2 || chunks[0].ChunkType != model.ChunkTypeFile || chunks[1].ChunkType != model.ChunkTypeFunction {
+		t.Fatalf("got %d chunks, want the file and the method", len(chunks))
+	}
+	if chunks[1].ParentID != chunks[0].ID {
+		t.Errorf("method parent = %q, want the file chunk %q", chunks[1].ParentID, chunks[0].ID)
+	}
+	if !strings.HasPrefix(chunks[1].EmbeddingHeader, "Repository: shop\n") {
+		t.Errorf("header = %q, want the repository header", chunks[1].EmbeddingHeader)
+	}
+
+	// Other repositories keep the service settings
+	chunks, err = ccs.ChunkContent(context.Background(), "store.py", "python", source, ChunkOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(chunks) != 3 || chunks[1].ChunkType != model.ChunkTypeClass || chunks[2].EmbeddingHeader != "" {
+		t.Errorf("default settings not applied: %d chunks", len(chunks))
+	}
+}
+
+// chunkStore serves GetChunkByID from a map; other methods are not used
+type chunkStore struct {
+	VectorDatabase
+	chunks map[string]*model.CodeChunk
+}
+
+func (s chunkStore) GetChunkByID(_ context.Context, _ string, id string) (*model.CodeChunk, error) {
+	if c, ok := s.chunks[id]; ok {
+		return c, nil
+	}
+	return nil, fmt.Errorf("%w: %s", ErrChunkNotFound, id)
+}
+
+func TestRemapChunks(t *testing.T) {
+	dir := t.TempDir()
+	old := "package p\n\nfunc A() int {\n\treturn 1\n}\n\nfunc B() {}\n"
+	ccs := NewCodeChunkService(nil, nil, 5, 5, 0, 1, zap.NewNop())
+	chunks, err := ccs.ChunkContent(context.Background(), "p.go", "go", []byte(old), ChunkOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := chunkStore{chunks: map[string]*model.CodeChunk{}}
+	for _, c := range chunks {
+		if c.FileSHA != util.CalculateFileSHA256([]byte(old)) {
+			t.Fatalf("%s chunk has file SHA %q", c.ChunkType, c.FileSHA)
+		}
+		store.chunks[c.Name] = c
+	}
+	ccs.vectorDB = store
+
+	current := "package p\n\nimport \"fmt\"\n\nfunc A() int {\n\treturn 2\n}\n\nfunc B() {}\n"
+	if err := os.WriteFile(filepath.Join(dir, "p.go"), []byte(current), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	results := ccs.RemapChunks(context.Background(), "repo", dir, []string{"A", "B", "missing"})
+	want := []struct {
+		status     string
+		start, end int
+		changed    bool
+	}{
+		{"changed", 4, 6, true},
+		{"moved", 8, 8, false},
+	}
+	for i, w := range want {
+		r := results[i]
+		if r.Status != w.status || r.StartLine != w.start || r.EndLine != w.end || r.ContentChanged != w.changed {
+			t.Errorf("%s: got %s %d-%d changed=%v, want %s %d-%d changed=%v",
+				r.ChunkID, r.Status, r.StartLine, r.EndLine, r.ContentChanged, w.status, w.start, w.end, w.changed)
+		}
+	}
+	if results[2].Error == "" {
+		t.Errorf("missing chunk: no error")
+	}
+}
+
+func TestLineHashesPayload(t *testing.T) {
+	hashes := []uint32{0, 1, 0xdeadbeef}
+	if got := decodeLineHashes(encodeLineHashes(hashes)); fmt.Sprint(got) != fmt.Sprint(hashes) {
+		t.Errorf("line hashes round trip to %v, want %v", got, hashes)
+	}
+	if got := decodeLineHashes("xyz"); got != nil {
+		t.Errorf("malformed line hashes decode to %v", got)
+	}
+}
+
+// keywordEmbedding embeds a text as 1 when it mentions the keyword, else 0
+type keywordEmbedding struct {
+	EmbeddingModel
+	keyword string
+}
+
+func (e keywordEmbedding) GenerateEmbedding(_ context.Context, text string) ([]float32, error) {
+	if strings.Contains(text, e.keyword) {
+		return []float32{1, 0}, nil
+	}
+	return []float32{0, 1}, nil
+}
+
+func (e keywordEmbedding) GenerateEmbeddings(ctx context.Context, texts []string) ([][]float32, error) {
+	embeddings := make([][]float32, len(texts))
+	for i, text := range texts {
+		embeddings[i], _ = e.GenerateEmbedding(ctx, text)
+	}
+	return embeddings, nil
+}
+
+func TestSelectFolders(t *testing.T) {
+	ctx := context.Background()
+	db := newMemoryDB()
+	db.CreateCollection(ctx, "repo", 2, DistanceMetricCosine)
+	ccs := NewCodeChunkService(db, keywordEmbedding{keyword: "billing"}, 5, 5, 0, 1, zap.NewNop())
+
+	err := ccs.IndexSummaries(ctx, "repo", model.ChunkTypeFolderSummary, []SummaryData{
+		{Path: "api/auth", Summary: "Login and sessions"},
+		{Path: "api/billing", Summary: "Invoices and payments"},
+		{Path: "docs", Summary: "   "},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Embedding a summary again replaces it
+	err = ccs.IndexSummaries(ctx, "repo", model.ChunkTypeFolderSummary, []SummaryData{{Path: "api/auth", Summary: "Login"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ccs.IndexSummaries(ctx, "repo", model.ChunkTypeProjectSummary, []SummaryData{{Path: "repo", Summary: "billing everywhere"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	ids := map[string]bool{}
+	for _, c := range db.collections["repo"] {
+		ids[c.ID] = true
+	}
+	if len(db.collections["repo"]) != 4 || len(ids) != 3 {
+		t.Fatalf("stored %d summaries with %d IDs, want 3 distinct", len(db.collections["repo"]), len(ids))
+	}
+
+	folders, scores, err := ccs.SelectFolders(ctx, "repo", "charge the billing account", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(folders) != "[api/billing]" || len(scores) != 1 {
+		t.Errorf("SelectFolders = %v %v, want [api/billing] alone", folders, scores)
+	}
+}
+
+func TestChunkFolders(t *testing.T) {
+	tests := []struct {
+		chunk *model.CodeChunk
+		want  string
+	}{
+		{&model.CodeChunk{FilePath: "a/b/c.go"}, "[a a/b]"},
+		{&model.CodeChunk{FilePath: "main.go"}, "[]"},
+		{&model.CodeChunk{FilePath: "/abs/x.go"}, "[/abs]"},
+		{&model.CodeChunk{FilePath: "a/b", ChunkType: model.ChunkTypeFolderSummary}, "[]"},
+	}
+	for _, tt := range tests {
+		if got := fmt.Sprint(tt.chunk.Folders()); got != tt.want {
+			t.Errorf("Folders(%q) = %s, want %s", tt.chunk.FilePath, got, tt.want)
+		}
+	}
+
+	filter := buildFilter(map[string]interface{}{"folders": []string{"a", "b"}, "chunk_type": "function"})
+	if len(filter.GetMust()) != 2 {
+		t.Fatalf("filter has %d conditions, want 2", len(filter.GetMust()))
+	}
+	for _, cond := range filter.GetMust() {
+		field := cond.GetField()
+		if field.GetKey() == "folders" && fmt.Sprint(field.GetMatch().GetKeywords().GetStrings()) != "[a b]" {
+			t.Errorf("folders condition = %v, want any of [a b]", field.GetMatch())
+		}
+	}
+	if buildFilter(nil) != nil {
+		t.Error("empty filter builds a condition")
+	}
+}
@@ -2,6 +2,8 @@ package vector
 
 import (
 	"context"
+
+	"github.com/armchr/codeapi/internal/budget"
 )
 
 // EmbeddingModel represents a generic embedding model interface
@@ -19,3 +21,24 @@ type EmbeddingModel interface {
 	// GetModelName returns the name of the embedding model being used
 	GetModelName() string
 }
+
+// BudgetedEmbedding wraps model so that batch embedding calls, which come
+// from indexing, hold an embedding slot of the resource budget. Single-text
+// calls embed search queries and are left out, so searches never queue
+// behind an index build.
+func BudgetedEmbedding(model EmbeddingModel) EmbeddingModel {
+	return &budgetedEmbedding{EmbeddingModel: model}
+}
+
+type budgetedEmbedding struct {
+	EmbeddingModel
+}
+
+func (b *budgetedEmbedding) GenerateEmbeddings(ctx context.Context, texts []string) ([][]float32, error) {
+	release, err := budget.Acquire(ctx, budget.Embedding)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return b.EmbeddingModel.GenerateEmbeddings(ctx, texts)
+}
@@ -0,0 +1,194 @@
+package vector
+
+import (
+	"context"
+	"fmt"
+	"maps"
+
+	"github.com/armchr/codeapi/internal/encryption"
+	"github.com/armchr/codeapi/internal/model"
+)
+
+// encryptedMetadata are the metadata keys holding text rather than
+// identifiers: the summary of folder and project chunks and the text of notes
+var encryptedMetadata = []string{"summary", "text"}
+
+// encryptionMetadata is the metadata key holding the encryption.Version the
+// text of a chunk was encrypted with. Chunks without it are plaintext.
+const encryptionMetadata = "encryption"
+
+// EncryptedDatabase encrypts the text of the chunks of repositories that set
+// encrypt before they reach the vector database, and decrypts it on the way
+// back. Embeddings, paths, names and the other payload fields searches filter
+// on are stored as they are.
+type EncryptedDatabase struct {
+	VectorDatabase
+	ciphers func(collectionName string) (*encryption.Cipher, error)
+}
+
+// NewEncryptedDatabase wraps db, asking ciphers for the cipher of a
+// collection, which is named after its repository. Collections without a
+// cipher are passed through unchanged. Chunks must only be written to the
+// collection of their own repository, see RepoController.ProcessDirectory.
+func NewEncryptedDatabase(db VectorDatabase, ciphers func(collectionName string) (*encryption.Cipher, error)) *EncryptedDatabase {
+	return &EncryptedDatabase{VectorDatabase: db, ciphers: ciphers}
+}
+
+// UpsertChunks stores encrypted copies of the chunks, leaving the caller's
+// chunks as they were
+func (e *EncryptedDatabase) UpsertChunks(ctx context.Context, collectionName string, chunks []*model.CodeChunk) error {
+	c, err := e.ciphers(collectionName)
+	if err != nil {
+		return err
+	}
+	version := c.Version()
+	if version == "" {
+		return e.VectorDatabase.UpsertChunks(ctx, collectionName, chunks)
+	}
+	encrypted := make([]*model.CodeChunk, len(chunks))
+	for i, chunk := range chunks {
+		copied := *chunk
+		copied.Metadata = maps.Clone(chunk.Metadata)
+		if err := transformChunk(&copied, c.Encrypt); err != nil {
+			return fmt.Errorf("failed to encrypt chunk %s: %w", chunk.ID, err)
+		}
+		if copied.Metadata == nil {
+			copied.Metadata = make(map[string]interface{})
+		}
+		copied.Metadata[encryptionMetadata] = version
+		encrypted[i] = &copied
+	}
+	return e.VectorDatabase.UpsertChunks(ctx, collectionName, encrypted)
+}
+
+// SearchSimilar decrypts the matching chunks
+func (e *EncryptedDatabase) SearchSimilar(ctx context.Context, collectionName string, queryVector []float32, limit int, filter map[string]interface{}) ([]*model.CodeChunk, []float32, error) {
+	chunks, scores, err := e.VectorDatabase.SearchSimilar(ctx, collectionName, queryVector, limit, filter)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := e.decrypt(collectionName, chunks); err != nil {
+		return nil, nil, err
+	}
+	return chunks, scores, nil
+}
+
+// HasSignatureVectors asks the wrapped database, which may not store
+// signature vectors
+func (e *EncryptedDatabase) HasSignatureVectors(ctx context.Context, collectionName string) (bool, error) {
+	store, ok := e.VectorDatabase.(SignatureVectorStore)
+	if !ok {
+		return false, nil
+	}
+	return store.HasSignatureVectors(ctx, collectionName)
+}
+
+// VectorSize asks the wrapped database; encryption leaves vectors as they are
+func (e *EncryptedDatabase) VectorSize(ctx context.Context, collectionName string) (int, error) {
+	reader, ok := e.VectorDatabase.(VectorSizeReader)
+	if !ok {
+		return 0, fmt.Errorf("vector database does not report vector sizes")
+	}
+	return reader.VectorSize(ctx, collectionName)
+}
+
+// SearchSignatures decrypts the chunks whose signatures match
+func (e *EncryptedDatabase) SearchSignatures(ctx context.Context, collectionName string, queryVector []float32, limit int, filter map[string]interface{}) ([]*model.CodeChunk, []float32, error) {
+	store, ok := e.VectorDatabase.(SignatureVectorStore)
+	if !ok {
+		return nil, nil, fmt.Errorf("vector database does not support signature vectors")
+	}
+	chunks, scores, err := store.SearchSignatures(ctx, collectionName, queryVector, limit, filter)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := e.decrypt(collectionName, chunks); err != nil {
+		return nil, nil, err
+	}
+	return chunks, scores, nil
+}
+
+// GetChunkByID decrypts the chunk
+func (e *EncryptedDatabase) GetChunkByID(ctx context.Context, collectionName string, chunkID string) (*model.CodeChunk, error) {
+	chunk, err := e.VectorDatabase.GetChunkByID(ctx, collectionName, chunkID)
+	if err != nil {
+		return nil, err
+	}
+	if err := e.decrypt(collectionName, []*model.CodeChunk{chunk}); err != nil {
+		return nil, err
+	}
+	return chunk, nil
+}
+
+// GetChunksByFilePath decrypts the chunks of the file
+func (e *EncryptedDatabase) GetChunksByFilePath(ctx context.Context, collectionName string, filePath string) ([]*model.CodeChunk, error) {
+	chunks, err := e.VectorDatabase.GetChunksByFilePath(ctx, collectionName, filePath)
+	if err != nil {
+		return nil, err
+	}
+	if err := e.decrypt(collectionName, chunks); err != nil {
+		return nil, err
+	}
+	return chunks, nil
+}
+
+// ScrollChunks decrypts each page
+func (e *EncryptedDatabase) ScrollChunks(ctx context.Context, collectionName string, offset string, limit int) ([]*model.CodeChunk, string, error) {
+	chunks, next, err := e.VectorDatabase.ScrollChunks(ctx, collectionName, offset, limit)
+	if err != nil {
+		return nil, "", err
+	}
+	if err := e.decrypt(collectionName, chunks); err != nil {
+		return nil, "", err
+	}
+	return chunks, next, nil
+}
+
+// decrypt decrypts the chunks read from a collection that were stored
+// encrypted, in place, and drops their encryption version
+func (e *EncryptedDatabase) decrypt(collectionName string, chunks []*model.CodeChunk) error {
+	if len(chunks) == 0 {
+		return nil
+	}
+	c, err := e.ciphers(collectionName)
+	if err != nil {
+		return err
+	}
+	for _, chunk := range chunks {
+		version, _ := chunk.Metadata[encryptionMetadata].(string)
+		if version == "" {
+			continue
+		}
+		err := transformChunk(chunk, func(value string) (string, error) {
+			return c.Decrypt(value, version)
+		})
+		if err != nil {
+			return fmt.Errorf("failed to decrypt chunk %s: %w", chunk.ID, err)
+		}
+		delete(chunk.Metadata, encryptionMetadata)
+	}
+	return nil
+}
+
+// transformChunk applies fn to the text fields of a chunk
+func transformChunk(chunk *model.CodeChunk, fn func(string) (string, error)) error {
+	for _, field := range []*string{&chunk.Content, &chunk.Signature, &chunk.Docstring} {
+		value, err := fn(*field)
+		if err != nil {
+			return err
+		}
+		*field = value
+	}
+	for _, key := range encryptedMetadata {
+		text, ok := chunk.Metadata[key].(string)
+		if !ok {
+			continue
+		}
+		value, err := fn(text)
+		if err != nil {
+			return err
+		}
+		chunk.Metadata[key] = value
+	}
+	return nil
+}
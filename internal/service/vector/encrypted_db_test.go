@@ -0,0 +1,116 @@
+package vector
+
+import (
+	"bytes"
+	"context"
+	"maps"
+	"strings"
+	"testing"
+
+	"github.com/armchr/codeapi/internal/encryption"
+	"github.com/armchr/codeapi/internal/model"
+)
+
+func TestEncryptedDatabase(t *testing.T) {
+	ctx := context.Background()
+	inner := newMemoryDB()
+	db := NewEncryptedDatabase(inner, func(name string) (*encryption.Cipher, error) {
+		if name != "secret" {
+			return nil, nil
+		}
+		return encryption.NewCipher(bytes.Repeat([]byte{3}, encryption.KeySize), name)
+	})
+
+	newChunk := func() *model.CodeChunk {
+		return &model.CodeChunk{
+			ID:        "c1",
+			Name:      "Parse",
+			FilePath:  "parse.go",
+			Content:   "func Parse() {}",
+			Signature: "func Parse()",
+			Docstring: "Parse parses.",
+			Embedding: []float32{1, 0},
+			Metadata:  map[string]interface{}{"summary": "Parsing helpers", "kind": "func"},
+		}
+	}
+
+	for _, name := range []string{"secret", "plain"} {
+		t.Run(name, func(t *testing.T) {
+			if err := db.CreateCollection(ctx, name, 2, DistanceMetricCosine); err != nil {
+				t.Fatal(err)
+			}
+			chunk := newChunk()
+			if err := db.UpsertChunks(ctx, name, []*model.CodeChunk{chunk}); err != nil {
+				t.Fatal(err)
+			}
+			if chunk.Content != "func Parse() {}" || chunk.Metadata["summary"] != "Parsing helpers" {
+				t.Error("UpsertChunks changed the caller's chunk")
+			}
+
+			stored := inner.collections[name][0]
+			wantEncrypted := name == "secret"
+			if version, _ := stored.Metadata[encryptionMetadata].(string); (version == encryption.Version) != wantEncrypted {
+				t.Errorf("stored encryption version %q, encrypted = %v", version, wantEncrypted)
+			}
+			for _, text := range []string{stored.Content, stored.Signature, stored.Docstring, stored.Metadata["summary"].(string)} {
+				if strings.Contains(text, "Pars") == wantEncrypted {
+					t.Errorf("stored %q, encrypted = %v", text, wantEncrypted)
+				}
+			}
+			if stored.Name != "Parse" || stored.FilePath != "parse.go" || stored.Metadata["kind"] != "func" {
+				t.Errorf("filterable fields changed: %+v", stored)
+			}
+
+			// The memory database returns its own chunks, so decrypt
+			// copies to keep the stored ones encrypted between reads
+			inner.collections[name][0] = cloneChunk(stored)
+			chunks, _, err := db.SearchSimilar(ctx, name, []float32{1, 0}, 5, nil)
+			if err != nil || len(chunks) != 1 {
+				t.Fatalf("SearchSimilar() = %v, %v", chunks, err)
+			}
+			want := newChunk()
+			got := chunks[0]
+			if got.Content != want.Content || got.Signature != want.Signature || got.Docstring != want.Docstring || got.Metadata["summary"] != want.Metadata["summary"] {
+				t.Errorf("SearchSimilar() = %+v", got)
+			}
+			if _, ok := got.Metadata[encryptionMetadata]; ok {
+				t.Error("SearchSimilar() kept the encryption version")
+			}
+
+			inner.collections[name][0] = cloneChunk(stored)
+			byPath, err := db.GetChunksByFilePath(ctx, name, "parse.go")
+			if err != nil || len(byPath) != 1 || byPath[0].Content != want.Content {
+				t.Errorf("GetChunksByFilePath() = %v, %v", byPath, err)
+			}
+		})
+	}
+}
+
+func TestEncryptedDatabasePlaintextLookalike(t *testing.T) {
+	ctx := context.Background()
+	inner := newMemoryDB()
+	db := NewEncryptedDatabase(inner, func(name string) (*encryption.Cipher, error) {
+		c, err := encryption.NewCipher(bytes.Repeat([]byte{3}, encryption.KeySize), name)
+		return c.DecryptOnly(), err
+	})
+
+	// A repository that turned encryption off writes plaintext, which may
+	// look like ciphertext
+	content := "enc:v1:" + strings.Repeat("A", 40)
+	if err := db.CreateCollection(ctx, "api", 2, DistanceMetricCosine); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.UpsertChunks(ctx, "api", []*model.CodeChunk{{ID: "c1", FilePath: "a.go", Content: content}}); err != nil {
+		t.Fatal(err)
+	}
+	chunk, err := db.GetChunkByID(ctx, "api", "c1")
+	if err != nil || chunk.Content != content {
+		t.Errorf("GetChunkByID() = %+v, %v, want the plaintext", chunk, err)
+	}
+}
+
+func cloneChunk(chunk *model.CodeChunk) *model.CodeChunk {
+	copied := *chunk
+	copied.Metadata = maps.Clone(chunk.Metadata)
+	return &copied
+}
@@ -3,28 +3,48 @@ package vector
 import (
 	"context"
 	"fmt"
+	"path"
+	"strconv"
+	"sync"
+	"time"
 
+	"github.com/armchr/codeapi/internal/config"
+	"github.com/armchr/codeapi/internal/metrics"
 	"github.com/armchr/codeapi/internal/model"
+	"github.com/armchr/codeapi/internal/tracing"
 	"github.com/armchr/codeapi/pkg/lsp/base"
 
 	"github.com/google/uuid"
 	"github.com/qdrant/go-client/qdrant"
+	"go.opentelemetry.io/otel/attribute"
 	"go.uber.org/zap"
+	"google.golang.org/grpc"
 )
 
 // QdrantDatabase implements VectorDatabase interface using Qdrant
 type QdrantDatabase struct {
-	client *qdrant.Client
-	logger *zap.Logger
+	client       *qdrant.Client
+	logger       *zap.Logger
+	namedVectors bool     // Layout of new collections
+	layouts      sync.Map // Collection name -> bool, whether it has named vectors
 }
 
-// NewQdrantDatabase creates a new Qdrant database connection
-func NewQdrantDatabase(host string, port int, apiKey string, logger *zap.Logger) (*QdrantDatabase, error) {
+// NewQdrantDatabase creates a new Qdrant database connection. pool.MaxOpenConns
+// sets the number of gRPC connections requests are spread over.
+func NewQdrantDatabase(host string, port int, apiKey string, pool config.PoolConfig, logger *zap.Logger) (*QdrantDatabase, error) {
+	interceptors := []grpc.UnaryClientInterceptor{metricsInterceptor}
+	if timeout := pool.QueryTimeout(); timeout > 0 {
+		interceptors = append(interceptors, timeoutInterceptor(timeout))
+	}
+	grpcOptions := []grpc.DialOption{grpc.WithChainUnaryInterceptor(interceptors...)}
+
 	client, err := qdrant.NewClient(&qdrant.Config{
-		Host:   host,
-		Port:   port,
-		APIKey: apiKey,
-		UseTLS: false,
+		Host:        host,
+		Port:        port,
+		APIKey:      apiKey,
+		UseTLS:      false,
+		PoolSize:    uint(max(pool.MaxOpenConns, 0)),
+		GrpcOptions: grpcOptions,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to create Qdrant client: %w", err)
@@ -36,6 +56,71 @@ func NewQdrantDatabase(host string, port int, apiKey string, logger *zap.Logger)
 	}, nil
 }
 
+// SetNamedVectors makes new collections keep content and signature
+// embeddings as named vectors of one point, see SignatureVectorStore.
+// Collections created before keep their layout.
+func (q *QdrantDatabase) SetNamedVectors(enabled bool) {
+	q.namedVectors = enabled
+}
+
+// hasNamedVectors reports whether a collection was created with named
+// vectors, asking Qdrant once per collection
+func (q *QdrantDatabase) hasNamedVectors(ctx context.Context, collectionName string) (bool, error) {
+	if named, ok := q.layouts.Load(collectionName); ok {
+		return named.(bool), nil
+	}
+	info, err := q.client.GetCollectionInfo(ctx, collectionName)
+	if err != nil {
+		return false, fmt.Errorf("failed to get collection info: %w", err)
+	}
+	named := info.GetConfig().GetParams().GetVectorsConfig().GetParamsMap() != nil
+	q.layouts.Store(collectionName, named)
+	return named, nil
+}
+
+// HasSignatureVectors reports whether the points of a collection carry
+// signature vectors
+func (q *QdrantDatabase) HasSignatureVectors(ctx context.Context, collectionName string) (bool, error) {
+	return q.hasNamedVectors(ctx, collectionName)
+}
+
+// VectorSize returns the size of the content vectors of a collection, which
+// is the only vector of collections created without named vectors
+func (q *QdrantDatabase) VectorSize(ctx context.Context, collectionName string) (int, error) {
+	info, err := q.client.GetCollectionInfo(ctx, collectionName)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get collection info: %w", err)
+	}
+	vectors := info.GetConfig().GetParams().GetVectorsConfig()
+	if named := vectors.GetParamsMap(); named != nil {
+		return int(named.GetMap()[ContentVectorName].GetSize()), nil
+	}
+	return int(vectors.GetParams().GetSize()), nil
+}
+
+// timeoutInterceptor bounds every Qdrant call by timeout, on top of any
+// deadline already carried by the caller's context
+func timeoutInterceptor(timeout time.Duration) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+// metricsInterceptor records the latency of every Qdrant call, labelled by
+// the gRPC method and the collection (the repository) it targets
+func metricsInterceptor(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	collection := ""
+	if r, ok := req.(interface{ GetCollectionName() string }); ok {
+		collection = r.GetCollectionName()
+	}
+	done := metrics.TimeStoreQuery(metrics.StoreQdrant, path.Base(method), collection)
+	err := invoker(ctx, method, req, reply, cc, opts...)
+	done(err)
+	return err
+}
+
 // CreateCollection creates a new collection with the specified dimension and distance metric
 func (q *QdrantDatabase) CreateCollection(ctx context.Context, collectionName string, vectorDim int, distance DistanceMetric) error {
 	// Map our distance metric to Qdrant's distance type
@@ -51,24 +136,36 @@ func (q *QdrantDatabase) CreateCollection(ctx context.Context, collectionName st
 		qdrantDistance = qdrant.Distance_Cosine
 	}
 
+	params := &qdrant.VectorParams{
+		Size:     uint64(vectorDim),
+		Distance: qdrantDistance,
+	}
+	vectorsConfig := qdrant.NewVectorsConfig(params)
+	if q.namedVectors {
+		vectorsConfig = qdrant.NewVectorsConfigMap(map[string]*qdrant.VectorParams{
+			ContentVectorName:   params,
+			SignatureVectorName: params,
+		})
+	}
+
 	err := q.client.CreateCollection(ctx, &qdrant.CreateCollection{
 		CollectionName: collectionName,
-		VectorsConfig: qdrant.NewVectorsConfig(&qdrant.VectorParams{
-			Size:     uint64(vectorDim),
-			Distance: qdrantDistance,
-		}),
+		VectorsConfig:  vectorsConfig,
 	})
 	if err != nil {
 		return fmt.Errorf("failed to create collection: %w", err)
 	}
+	q.layouts.Store(collectionName, q.namedVectors)
 
-	q.logger.Info("Created Qdrant collection", zap.String("collection", collectionName), zap.Int("dim", vectorDim))
+	q.logger.Info("Created Qdrant collection", zap.String("collection", collectionName),
+		zap.Int("dim", vectorDim), zap.Bool("named_vectors", q.namedVectors))
 	return nil
 }
 
 // DeleteCollection deletes a collection
 func (q *QdrantDatabase) DeleteCollection(ctx context.Context, collectionName string) error {
 	err := q.client.DeleteCollection(ctx, collectionName)
+	q.layouts.Delete(collectionName)
 	if err != nil {
 		return fmt.Errorf("failed to delete collection: %w", err)
 	}
@@ -90,6 +187,10 @@ func (q *QdrantDatabase) UpsertChunks(ctx context.Context, collectionName string
 		return nil
 	}
 
+	named, err := q.hasNamedVectors(ctx, collectionName)
+	if err != nil {
+		return err
+	}
 	points := make([]*qdrant.PointStruct, 0, len(chunks))
 
 	for _, chunk := range chunks {
@@ -113,17 +214,26 @@ func (q *QdrantDatabase) UpsertChunks(ctx context.Context, collectionName string
 
 		// Convert CodeChunk to Qdrant point
 		// Note: content is excluded to save storage space - use file_path and line numbers to retrieve content
+		vectors := map[string]*qdrant.Vector{"": qdrant.NewVector(chunk.Embedding...)}
+		if named {
+			vectors = map[string]*qdrant.Vector{ContentVectorName: qdrant.NewVector(chunk.Embedding...)}
+			if len(chunk.SignatureEmbedding) > 0 {
+				vectors[SignatureVectorName] = qdrant.NewVector(chunk.SignatureEmbedding...)
+			}
+		}
 		point := &qdrant.PointStruct{
-			Id: qdrant.NewIDUUID(chunk.ID),
-			Vectors: qdrant.NewVectorsMap(map[string]*qdrant.Vector{
-				"": qdrant.NewVector(chunk.Embedding...),
-			}),
+			Id:      qdrant.NewIDUUID(chunk.ID),
+			Vectors: qdrant.NewVectorsMap(vectors),
 			Payload: qdrant.NewValueMap(map[string]any{
 				"chunk_type":  string(chunk.ChunkType),
+				"file_id":     int64(chunk.FileID),
+				"file_sha":    chunk.FileSHA,
+				"line_hashes": encodeLineHashes(chunk.LineHashes),
 				"level":       chunk.Level,
 				"parent_id":   chunk.ParentID,
 				"language":    chunk.Language,
 				"file_path":   chunk.FilePath,
+				"folders":     stringsToAny(chunk.Folders()),
 				"start_line":  chunk.StartLine,
 				"end_line":    chunk.EndLine,
 				"range":       rangeToMap(chunk.Range),
@@ -148,10 +258,13 @@ func (q *QdrantDatabase) UpsertChunks(ctx context.Context, collectionName string
 		zap.String("collection", collectionName),
 		zap.Int("points_count", len(points)))
 
-	_, err := q.client.Upsert(ctx, &qdrant.UpsertPoints{
+	ctx, span := tracing.Start(ctx, "qdrant.upsert",
+		attribute.String("collection", collectionName), attribute.Int("points", len(points)))
+	_, err = q.client.Upsert(ctx, &qdrant.UpsertPoints{
 		CollectionName: collectionName,
 		Points:         points,
 	})
+	tracing.End(span, err)
 	if err != nil {
 		q.logger.Error("Upsert failed",
 			zap.String("collection", collectionName),
@@ -167,32 +280,41 @@ func (q *QdrantDatabase) UpsertChunks(ctx context.Context, collectionName string
 
 // SearchSimilar finds similar code chunks using vector similarity search
 func (q *QdrantDatabase) SearchSimilar(ctx context.Context, collectionName string, queryVector []float32, limit int, filter map[string]interface{}) ([]*model.CodeChunk, []float32, error) {
-	// Build Qdrant filter if provided
-	var qdrantFilter *qdrant.Filter
-	if len(filter) > 0 {
-		conditions := make([]*qdrant.Condition, 0, len(filter))
-		for key, value := range filter {
-			conditions = append(conditions, &qdrant.Condition{
-				ConditionOneOf: &qdrant.Condition_Field{
-					Field: &qdrant.FieldCondition{
-						Key:   key,
-						Match: &qdrant.Match{MatchValue: &qdrant.Match_Keyword{Keyword: fmt.Sprint(value)}},
-					},
-				},
-			})
-		}
-		qdrantFilter = &qdrant.Filter{
-			Must: conditions,
-		}
+	named, err := q.hasNamedVectors(ctx, collectionName)
+	if err != nil {
+		return nil, nil, err
 	}
+	using := ""
+	if named {
+		using = ContentVectorName
+	}
+	return q.search(ctx, collectionName, using, queryVector, limit, filter)
+}
 
-	searchResult, err := q.client.Query(ctx, &qdrant.QueryPoints{
+// SearchSignatures finds the function chunks whose signature vector is
+// closest to queryVector. The collection must have named vectors.
+func (q *QdrantDatabase) SearchSignatures(ctx context.Context, collectionName string, queryVector []float32, limit int, filter map[string]interface{}) ([]*model.CodeChunk, []float32, error) {
+	return q.search(ctx, collectionName, SignatureVectorName, queryVector, limit, filter)
+}
+
+// search queries the named vector using, or the only vector if using is empty
+func (q *QdrantDatabase) search(ctx context.Context, collectionName, using string, queryVector []float32, limit int, filter map[string]interface{}) ([]*model.CodeChunk, []float32, error) {
+	qdrantFilter := buildFilter(filter)
+
+	ctx, span := tracing.Start(ctx, "qdrant.search",
+		attribute.String("collection", collectionName), attribute.Int("limit", limit))
+	request := &qdrant.QueryPoints{
 		CollectionName: collectionName,
 		Query:          qdrant.NewQuery(queryVector...),
 		Limit:          qdrant.PtrOf(uint64(limit)),
 		Filter:         qdrantFilter,
 		WithPayload:    qdrant.NewWithPayload(true),
-	})
+	}
+	if using != "" {
+		request.Using = qdrant.PtrOf(using)
+	}
+	searchResult, err := q.client.Query(ctx, request)
+	tracing.End(span, err)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to search: %w", err)
 	}
@@ -223,7 +345,7 @@ func (q *QdrantDatabase) GetChunkByID(ctx context.Context, collectionName string
 	}
 
 	if len(points) == 0 {
-		return nil, fmt.Errorf("chunk not found: %s", chunkID)
+		return nil, fmt.Errorf("%w: %s", ErrChunkNotFound, chunkID)
 	}
 
 	return retrievedPointToCodeChunk(points[0]), nil
@@ -247,6 +369,44 @@ func (q *QdrantDatabase) DeleteChunk(ctx context.Context, collectionName string,
 	return nil
 }
 
+// DeleteChunksByFileIDs deletes all chunks whose file_id payload is one of fileIDs
+func (q *QdrantDatabase) DeleteChunksByFileIDs(ctx context.Context, collectionName string, fileIDs []int32) error {
+	if len(fileIDs) == 0 {
+		return nil
+	}
+
+	ids := make([]int64, len(fileIDs))
+	for i, id := range fileIDs {
+		ids[i] = int64(id)
+	}
+
+	filter := &qdrant.Filter{
+		Must: []*qdrant.Condition{
+			{
+				ConditionOneOf: &qdrant.Condition_Field{
+					Field: &qdrant.FieldCondition{
+						Key:   "file_id",
+						Match: &qdrant.Match{MatchValue: &qdrant.Match_Integers{Integers: &qdrant.RepeatedIntegers{Integers: ids}}},
+					},
+				},
+			},
+		},
+	}
+
+	_, err := q.client.Delete(ctx, &qdrant.DeletePoints{
+		CollectionName: collectionName,
+		Points: &qdrant.PointsSelector{
+			PointsSelectorOneOf: &qdrant.PointsSelector_Filter{
+				Filter: filter,
+			},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete chunks by file id: %w", err)
+	}
+	return nil
+}
+
 // GetChunksByFilePath retrieves all chunks for a specific file path
 func (q *QdrantDatabase) GetChunksByFilePath(ctx context.Context, collectionName string, filePath string) ([]*model.CodeChunk, error) {
 	q.logger.Info("GetChunksByFilePath ", zap.String("filePath", filePath), zap.String("collectionName", collectionName))
@@ -292,6 +452,54 @@ func (q *QdrantDatabase) GetChunksByFilePath(ctx context.Context, collectionName
 	return chunks, nil
 }
 
+// CountChunks returns the exact number of points in a collection
+func (q *QdrantDatabase) CountChunks(ctx context.Context, collectionName string) (uint64, error) {
+	count, err := q.client.Count(ctx, &qdrant.CountPoints{
+		CollectionName: collectionName,
+		Exact:          qdrant.PtrOf(true),
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to count points: %w", err)
+	}
+	return count, nil
+}
+
+// ScrollChunks pages through every point of a collection in ID order,
+// including the vectors, so a collection can be copied point by point
+func (q *QdrantDatabase) ScrollChunks(ctx context.Context, collectionName string, offset string, limit int) ([]*model.CodeChunk, string, error) {
+	request := &qdrant.ScrollPoints{
+		CollectionName: collectionName,
+		Limit:          qdrant.PtrOf(uint32(limit)),
+		WithPayload:    qdrant.NewWithPayload(true),
+		WithVectors:    qdrant.NewWithVectors(true),
+	}
+	if offset != "" {
+		request.Offset = qdrant.NewIDUUID(offset)
+	}
+
+	points, next, err := q.client.ScrollAndOffset(ctx, request)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to scroll points: %w", err)
+	}
+
+	chunks := make([]*model.CodeChunk, 0, len(points))
+	for _, point := range points {
+		chunk := retrievedPointToCodeChunk(point)
+		if chunk == nil {
+			continue
+		}
+		if named := point.GetVectors().GetVectors(); named != nil {
+			chunk.Embedding = vectorData(named.GetVectors()[ContentVectorName])
+			chunk.SignatureEmbedding = vectorData(named.GetVectors()[SignatureVectorName])
+		} else {
+			chunk.Embedding = vectorData(point.GetVectors().GetVector())
+		}
+		chunks = append(chunks, chunk)
+	}
+
+	return chunks, next.GetUuid(), nil
+}
+
 // Close closes the database connection
 func (q *QdrantDatabase) Close() error {
 	if q.client != nil {
@@ -311,6 +519,50 @@ func (q *QdrantDatabase) Health(ctx context.Context) error {
 
 // Helper functions
 
+// buildFilter requires every key of filter to match its value. A []string
+// value matches any of its strings; on an array field, such as folders, a
+// point matches when one of its elements does.
+func buildFilter(filter map[string]interface{}) *qdrant.Filter {
+	if len(filter) == 0 {
+		return nil
+	}
+	conditions := make([]*qdrant.Condition, 0, len(filter))
+	for key, value := range filter {
+		if values, ok := value.([]string); ok {
+			conditions = append(conditions, qdrant.NewMatchKeywords(key, values...))
+			continue
+		}
+		conditions = append(conditions, &qdrant.Condition{
+			ConditionOneOf: &qdrant.Condition_Field{
+				Field: &qdrant.FieldCondition{
+					Key:   key,
+					Match: &qdrant.Match{MatchValue: &qdrant.Match_Keyword{Keyword: fmt.Sprint(value)}},
+				},
+			},
+		})
+	}
+	return &qdrant.Filter{Must: conditions}
+}
+
+// stringsToAny converts values for a payload, which takes []any for lists
+func stringsToAny(values []string) []any {
+	list := make([]any, len(values))
+	for i, v := range values {
+		list[i] = v
+	}
+	return list
+}
+
+func vectorData(vec *qdrant.VectorOutput) []float32 {
+	if vec == nil {
+		return nil
+	}
+	if dense := vec.GetDense(); dense != nil {
+		return dense.GetData()
+	}
+	return vec.GetData()
+}
+
 func rangeToMap(r base.Range) map[string]interface{} {
 	return map[string]interface{}{
 		"start": map[string]interface{}{
@@ -363,6 +615,9 @@ func payloadToCodeChunk(id string, payload map[string]*qdrant.Value) *model.Code
 
 	chunk := &model.CodeChunk{
 		ID:         chunkID,
+		FileID:     int32(getIntValue(payload, "file_id")),
+		FileSHA:    getStringValue(payload, "file_sha"),
+		LineHashes: decodeLineHashes(getStringValue(payload, "line_hashes")),
 		ChunkType:  model.ChunkType(getStringValue(payload, "chunk_type")),
 		Level:      int(getIntValue(payload, "level")),
 		ParentID:   getStringValue(payload, "parent_id"),
@@ -396,6 +651,31 @@ func payloadToCodeChunk(id string, payload map[string]*qdrant.Value) *model.Code
 	return chunk
 }
 
+// encodeLineHashes packs line hashes into a string of 8 hex digits each,
+// far smaller in the payload than a list of numbers
+func encodeLineHashes(hashes []uint32) string {
+	buf := make([]byte, 0, 8*len(hashes))
+	for _, h := range hashes {
+		buf = fmt.Appendf(buf, "%08x", h)
+	}
+	return string(buf)
+}
+
+func decodeLineHashes(s string) []uint32 {
+	if s == "" || len(s)%8 != 0 {
+		return nil
+	}
+	hashes := make([]uint32, len(s)/8)
+	for i := range hashes {
+		h, err := strconv.ParseUint(s[8*i:8*i+8], 16, 32)
+		if err != nil {
+			return nil
+		}
+		hashes[i] = uint32(h)
+	}
+	return hashes
+}
+
 func getStringValue(payload map[string]*qdrant.Value, key string) string {
 	if val, ok := payload[key]; ok {
 		return val.GetStringValue()
@@ -0,0 +1,298 @@
+package vector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/armchr/codeapi/internal/model"
+)
+
+// ShardedDatabase spreads the chunks of a collection over several physical
+// collections, picked by a hash of the chunk's file path, so a very large
+// repository does not end up in one oversized collection. Collections with
+// a single shard are passed through unchanged.
+type ShardedDatabase struct {
+	VectorDatabase
+	shards func(collectionName string) int
+}
+
+// NewShardedDatabase wraps db, asking shards for the number of shards of a
+// collection. Changing the number of a collection that holds chunks leaves
+// them where a lookup by file path no longer finds them, so the collection
+// has to be rebuilt.
+func NewShardedDatabase(db VectorDatabase, shards func(collectionName string) int) *ShardedDatabase {
+	return &ShardedDatabase{VectorDatabase: db, shards: shards}
+}
+
+// ShardName returns the physical collection holding shard i of a collection
+func ShardName(collectionName string, shard int) string {
+	return fmt.Sprintf("%s__shard%d", collectionName, shard)
+}
+
+// shardNames returns the physical collections of a collection
+func (s *ShardedDatabase) shardNames(collectionName string) []string {
+	n := s.shards(collectionName)
+	if n <= 1 {
+		return []string{collectionName}
+	}
+	names := make([]string, n)
+	for i := range names {
+		names[i] = ShardName(collectionName, i)
+	}
+	return names
+}
+
+// shardFor returns the physical collection holding the chunks of a file
+func (s *ShardedDatabase) shardFor(collectionName, filePath string) string {
+	n := s.shards(collectionName)
+	if n <= 1 {
+		return collectionName
+	}
+	h := fnv.New32a()
+	h.Write([]byte(filePath))
+	return ShardName(collectionName, int(h.Sum32()%uint32(n)))
+}
+
+// CreateCollection creates the shards that do not exist yet
+func (s *ShardedDatabase) CreateCollection(ctx context.Context, collectionName string, vectorDim int, distance DistanceMetric) error {
+	names := s.shardNames(collectionName)
+	if len(names) == 1 {
+		return s.VectorDatabase.CreateCollection(ctx, collectionName, vectorDim, distance)
+	}
+	for _, name := range names {
+		exists, err := s.VectorDatabase.CollectionExists(ctx, name)
+		if err != nil {
+			return err
+		}
+		if exists {
+			continue
+		}
+		if err := s.VectorDatabase.CreateCollection(ctx, name, vectorDim, distance); err != nil {
+			return fmt.Errorf("shard %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// DeleteCollection deletes every shard
+func (s *ShardedDatabase) DeleteCollection(ctx context.Context, collectionName string) error {
+	return s.each(collectionName, func(name string) error {
+		return s.VectorDatabase.DeleteCollection(ctx, name)
+	})
+}
+
+// CollectionExists reports whether every shard exists, so a collection
+// whose shard count was raised gets its new shards created
+func (s *ShardedDatabase) CollectionExists(ctx context.Context, collectionName string) (bool, error) {
+	for _, name := range s.shardNames(collectionName) {
+		exists, err := s.VectorDatabase.CollectionExists(ctx, name)
+		if err != nil || !exists {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// UpsertChunks stores every chunk in the shard of its file
+func (s *ShardedDatabase) UpsertChunks(ctx context.Context, collectionName string, chunks []*model.CodeChunk) error {
+	if s.shards(collectionName) <= 1 {
+		return s.VectorDatabase.UpsertChunks(ctx, collectionName, chunks)
+	}
+	byShard := make(map[string][]*model.CodeChunk)
+	for _, chunk := range chunks {
+		name := s.shardFor(collectionName, chunk.FilePath)
+		byShard[name] = append(byShard[name], chunk)
+	}
+	for name, shardChunks := range byShard {
+		if err := s.VectorDatabase.UpsertChunks(ctx, name, shardChunks); err != nil {
+			return fmt.Errorf("shard %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// SearchSimilar searches every shard and keeps the limit best matches
+func (s *ShardedDatabase) SearchSimilar(ctx context.Context, collectionName string, queryVector []float32, limit int, filter map[string]interface{}) ([]*model.CodeChunk, []float32, error) {
+	return s.search(collectionName, limit, func(name string) ([]*model.CodeChunk, []float32, error) {
+		return s.VectorDatabase.SearchSimilar(ctx, name, queryVector, limit, filter)
+	})
+}
+
+// HasSignatureVectors reports whether the shards carry signature vectors.
+// The first shard stands for all of them, they are created together.
+func (s *ShardedDatabase) HasSignatureVectors(ctx context.Context, collectionName string) (bool, error) {
+	store, ok := s.VectorDatabase.(SignatureVectorStore)
+	if !ok {
+		return false, nil
+	}
+	return store.HasSignatureVectors(ctx, s.shardNames(collectionName)[0])
+}
+
+// VectorSize returns the vector size of the first shard, as for
+// HasSignatureVectors
+func (s *ShardedDatabase) VectorSize(ctx context.Context, collectionName string) (int, error) {
+	reader, ok := s.VectorDatabase.(VectorSizeReader)
+	if !ok {
+		return 0, fmt.Errorf("vector database does not report vector sizes")
+	}
+	return reader.VectorSize(ctx, s.shardNames(collectionName)[0])
+}
+
+// SearchSignatures searches the signature vectors of every shard
+func (s *ShardedDatabase) SearchSignatures(ctx context.Context, collectionName string, queryVector []float32, limit int, filter map[string]interface{}) ([]*model.CodeChunk, []float32, error) {
+	store, ok := s.VectorDatabase.(SignatureVectorStore)
+	if !ok {
+		return nil, nil, fmt.Errorf("vector database does not support signature vectors")
+	}
+	return s.search(collectionName, limit, func(name string) ([]*model.CodeChunk, []float32, error) {
+		return store.SearchSignatures(ctx, name, queryVector, limit, filter)
+	})
+}
+
+// search runs query on the shards concurrently and merges the results by
+// descending score
+func (s *ShardedDatabase) search(collectionName string, limit int, query func(name string) ([]*model.CodeChunk, []float32, error)) ([]*model.CodeChunk, []float32, error) {
+	names := s.shardNames(collectionName)
+	if len(names) == 1 {
+		return query(names[0])
+	}
+
+	type shardResult struct {
+		chunks []*model.CodeChunk
+		scores []float32
+		err    error
+	}
+	results := make([]shardResult, len(names))
+	var wg sync.WaitGroup
+	for i, name := range names {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			chunks, scores, err := query(name)
+			results[i] = shardResult{chunks, scores, err}
+		}()
+	}
+	wg.Wait()
+
+	type match struct {
+		chunk *model.CodeChunk
+		score float32
+	}
+	var matches []match
+	for i, r := range results {
+		if r.err != nil {
+			return nil, nil, fmt.Errorf("shard %s: %w", names[i], r.err)
+		}
+		for j, chunk := range r.chunks {
+			matches = append(matches, match{chunk, r.scores[j]})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].score > matches[j].score })
+	if limit > 0 && len(matches) > limit {
+		matches = matches[:limit]
+	}
+
+	chunks := make([]*model.CodeChunk, len(matches))
+	scores := make([]float32, len(matches))
+	for i, m := range matches {
+		chunks[i], scores[i] = m.chunk, m.score
+	}
+	return chunks, scores, nil
+}
+
+// GetChunkByID looks for the chunk in every shard, a chunk ID does not
+// tell its file
+func (s *ShardedDatabase) GetChunkByID(ctx context.Context, collectionName string, chunkID string) (*model.CodeChunk, error) {
+	for _, name := range s.shardNames(collectionName) {
+		chunk, err := s.VectorDatabase.GetChunkByID(ctx, name, chunkID)
+		if err == nil {
+			return chunk, nil
+		}
+		if !errors.Is(err, ErrChunkNotFound) {
+			return nil, err
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrChunkNotFound, chunkID)
+}
+
+// DeleteChunk deletes the chunk from every shard
+func (s *ShardedDatabase) DeleteChunk(ctx context.Context, collectionName string, chunkID string) error {
+	return s.each(collectionName, func(name string) error {
+		return s.VectorDatabase.DeleteChunk(ctx, name, chunkID)
+	})
+}
+
+// DeleteChunksByFileIDs deletes the chunks of the file versions from every shard
+func (s *ShardedDatabase) DeleteChunksByFileIDs(ctx context.Context, collectionName string, fileIDs []int32) error {
+	return s.each(collectionName, func(name string) error {
+		return s.VectorDatabase.DeleteChunksByFileIDs(ctx, name, fileIDs)
+	})
+}
+
+// GetChunksByFilePath reads the shard of the file only
+func (s *ShardedDatabase) GetChunksByFilePath(ctx context.Context, collectionName string, filePath string) ([]*model.CodeChunk, error) {
+	return s.VectorDatabase.GetChunksByFilePath(ctx, s.shardFor(collectionName, filePath), filePath)
+}
+
+// ScrollChunks pages through the shards one after the other. The offset
+// of a sharded collection is "<shard>/<offset in the shard>".
+func (s *ShardedDatabase) ScrollChunks(ctx context.Context, collectionName string, offset string, limit int) ([]*model.CodeChunk, string, error) {
+	names := s.shardNames(collectionName)
+	if len(names) == 1 {
+		return s.VectorDatabase.ScrollChunks(ctx, collectionName, offset, limit)
+	}
+
+	shard, inner := 0, ""
+	if offset != "" {
+		prefix, rest, ok := strings.Cut(offset, "/")
+		n, err := strconv.Atoi(prefix)
+		if !ok || err != nil || n < 0 || n >= len(names) {
+			return nil, "", fmt.Errorf("invalid scroll offset %q", offset)
+		}
+		shard, inner = n, rest
+	}
+
+	chunks, next, err := s.VectorDatabase.ScrollChunks(ctx, names[shard], inner, limit)
+	if err != nil {
+		return nil, "", fmt.Errorf("shard %s: %w", names[shard], err)
+	}
+	switch {
+	case next != "":
+		return chunks, fmt.Sprintf("%d/%s", shard, next), nil
+	case shard+1 < len(names):
+		return chunks, fmt.Sprintf("%d/", shard+1), nil
+	default:
+		return chunks, "", nil
+	}
+}
+
+// CountChunks sums the points of the shards
+func (s *ShardedDatabase) CountChunks(ctx context.Context, collectionName string) (uint64, error) {
+	var total uint64
+	err := s.each(collectionName, func(name string) error {
+		count, err := s.VectorDatabase.CountChunks(ctx, name)
+		total += count
+		return err
+	})
+	return total, err
+}
+
+// each runs fn on the shards in order, stopping at the first error
+func (s *ShardedDatabase) each(collectionName string, fn func(name string) error) error {
+	names := s.shardNames(collectionName)
+	if len(names) == 1 {
+		return fn(names[0])
+	}
+	for _, name := range names {
+		if err := fn(name); err != nil {
+			return fmt.Errorf("shard %s: %w", name, err)
+		}
+	}
+	return nil
+}
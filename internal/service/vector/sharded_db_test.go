@@ -0,0 +1,216 @@
+package vector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"testing"
+
+	"github.com/armchr/codeapi/internal/model"
+)
+
+// memoryDB keeps chunks per collection in memory, in insertion order, and
+// scores a search by the first embedding component. Searches only honour a
+// chunk_type filter.
+type memoryDB struct {
+	VectorDatabase
+	collections map[string][]*model.CodeChunk
+}
+
+func newMemoryDB() *memoryDB {
+	return &memoryDB{collections: make(map[string][]*model.CodeChunk)}
+}
+
+func (m *memoryDB) CreateCollection(ctx context.Context, name string, vectorDim int, distance DistanceMetric) error {
+	if _, ok := m.collections[name]; ok {
+		return fmt.Errorf("collection %s exists", name)
+	}
+	m.collections[name] = nil
+	return nil
+}
+
+func (m *memoryDB) CollectionExists(ctx context.Context, name string) (bool, error) {
+	_, ok := m.collections[name]
+	return ok, nil
+}
+
+func (m *memoryDB) DeleteCollection(ctx context.Context, name string) error {
+	delete(m.collections, name)
+	return nil
+}
+
+func (m *memoryDB) UpsertChunks(ctx context.Context, name string, chunks []*model.CodeChunk) error {
+	if _, ok := m.collections[name]; !ok {
+		return fmt.Errorf("no collection %s", name)
+	}
+	m.collections[name] = append(m.collections[name], chunks...)
+	return nil
+}
+
+func (m *memoryDB) SearchSimilar(ctx context.Context, name string, queryVector []float32, limit int, filter map[string]interface{}) ([]*model.CodeChunk, []float32, error) {
+	var chunks []*model.CodeChunk
+	for _, c := range m.collections[name] {
+		if chunkType, ok := filter["chunk_type"]; !ok || string(c.ChunkType) == chunkType {
+			chunks = append(chunks, c)
+		}
+	}
+	sort.Slice(chunks, func(i, j int) bool { return chunks[i].Embedding[0] > chunks[j].Embedding[0] })
+	if len(chunks) > limit {
+		chunks = chunks[:limit]
+	}
+	scores := make([]float32, len(chunks))
+	for i, c := range chunks {
+		scores[i] = c.Embedding[0]
+	}
+	return chunks, scores, nil
+}
+
+func (m *memoryDB) GetChunkByID(ctx context.Context, name, chunkID string) (*model.CodeChunk, error) {
+	for _, c := range m.collections[name] {
+		if c.ID == chunkID {
+			return c, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrChunkNotFound, chunkID)
+}
+
+func (m *memoryDB) GetChunksByFilePath(ctx context.Context, name, filePath string) ([]*model.CodeChunk, error) {
+	var chunks []*model.CodeChunk
+	for _, c := range m.collections[name] {
+		if c.FilePath == filePath {
+			chunks = append(chunks, c)
+		}
+	}
+	return chunks, nil
+}
+
+func (m *memoryDB) ScrollChunks(ctx context.Context, name, offset string, limit int) ([]*model.CodeChunk, string, error) {
+	start := 0
+	if offset != "" {
+		fmt.Sscanf(offset, "%d", &start)
+	}
+	chunks := m.collections[name][start:]
+	if len(chunks) <= limit {
+		return chunks, "", nil
+	}
+	return chunks[:limit], fmt.Sprint(start + limit), nil
+}
+
+func (m *memoryDB) CountChunks(ctx context.Context, name string) (uint64, error) {
+	return uint64(len(m.collections[name])), nil
+}
+
+func TestShardedDatabase(t *testing.T) {
+	ctx := context.Background()
+	inner := newMemoryDB()
+	db := NewShardedDatabase(inner, func(name string) int {
+		if name == "big" {
+			return 4
+		}
+		return 1
+	})
+
+	for _, name := range []string{"big", "small"} {
+		if err := db.CreateCollection(ctx, name, 2, DistanceMetricCosine); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(inner.collections) != 5 {
+		t.Fatalf("physical collections = %d, want 4 shards and 1 plain", len(inner.collections))
+	}
+	if exists, _ := db.CollectionExists(ctx, "big"); !exists {
+		t.Fatal("sharded collection does not exist")
+	}
+
+	var chunks []*model.CodeChunk
+	for i := range 20 {
+		chunks = append(chunks, &model.CodeChunk{
+			ID:        fmt.Sprintf("c%d", i),
+			FilePath:  fmt.Sprintf("pkg/file%d.go", i%10),
+			Embedding: []float32{float32(i) / 20, 0},
+		})
+	}
+	if err := db.UpsertChunks(ctx, "big", chunks); err != nil {
+		t.Fatal(err)
+	}
+
+	used := 0
+	for i := range 4 {
+		if n := len(inner.collections[ShardName("big", i)]); n > 0 {
+			used++
+		}
+	}
+	if used < 2 {
+		t.Errorf("chunks went to %d shard(s), want them spread", used)
+	}
+	if count, _ := db.CountChunks(ctx, "big"); count != 20 {
+		t.Errorf("CountChunks = %d, want 20", count)
+	}
+
+	// The two chunks of a file are found through its shard alone
+	got, err := db.GetChunksByFilePath(ctx, "big", "pkg/file3.go")
+	if err != nil || len(got) != 2 {
+		t.Errorf("GetChunksByFilePath = %d chunks, %v; want 2", len(got), err)
+	}
+	if c, err := db.GetChunkByID(ctx, "big", "c17"); err != nil || c.ID != "c17" {
+		t.Errorf("GetChunkByID = %v, %v", c, err)
+	}
+	if _, err := db.GetChunkByID(ctx, "big", "missing"); !errors.Is(err, ErrChunkNotFound) {
+		t.Errorf("GetChunkByID of a missing chunk = %v, want ErrChunkNotFound", err)
+	}
+
+	// Search merges the shards by score
+	found, scores, err := db.SearchSimilar(ctx, "big", []float32{1, 0}, 3, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, c := range found {
+		ids = append(ids, c.ID)
+	}
+	if fmt.Sprint(ids) != "[c19 c18 c17]" || len(scores) != 3 || scores[0] < scores[2] {
+		t.Errorf("SearchSimilar = %v %v, want the 3 best of all shards", ids, scores)
+	}
+
+	// Scrolling walks every shard exactly once
+	seen := make(map[string]bool)
+	offset := ""
+	for {
+		page, next, err := db.ScrollChunks(ctx, "big", offset, 3)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, c := range page {
+			if seen[c.ID] {
+				t.Fatalf("chunk %s scrolled twice", c.ID)
+			}
+			seen[c.ID] = true
+		}
+		if next == "" {
+			break
+		}
+		offset = next
+	}
+	if len(seen) != 20 {
+		t.Errorf("scrolled %d chunks, want 20", len(seen))
+	}
+	if _, _, err := db.ScrollChunks(ctx, "big", "9/x", 3); err == nil {
+		t.Error("ScrollChunks accepted an offset past the last shard")
+	}
+
+	// A collection with one shard keeps its name
+	if err := db.UpsertChunks(ctx, "small", chunks[:2]); err != nil {
+		t.Fatal(err)
+	}
+	if len(inner.collections["small"]) != 2 {
+		t.Errorf("unsharded collection holds %d chunks, want 2", len(inner.collections["small"]))
+	}
+
+	if err := db.DeleteCollection(ctx, "big"); err != nil {
+		t.Fatal(err)
+	}
+	if len(inner.collections) != 1 {
+		t.Errorf("collections left after delete: %d, want 1", len(inner.collections))
+	}
+}
@@ -3,6 +3,7 @@ package vector
 import (
 	"github.com/armchr/codeapi/internal/model"
 	"context"
+	"errors"
 )
 
 // VectorDatabase represents a generic vector database interface
@@ -29,9 +30,19 @@ type VectorDatabase interface {
 	// DeleteChunk deletes a chunk by its ID
 	DeleteChunk(ctx context.Context, collectionName string, chunkID string) error
 
+	// DeleteChunksByFileIDs deletes all chunks belonging to the given file versions
+	DeleteChunksByFileIDs(ctx context.Context, collectionName string, fileIDs []int32) error
+
 	// GetChunksByFilePath retrieves all chunks for a specific file path
 	GetChunksByFilePath(ctx context.Context, collectionName string, filePath string) ([]*model.CodeChunk, error)
 
+	// ScrollChunks returns up to limit chunks, with embeddings, starting at
+	// offset ("" for the first page), and the offset of the next page ("" after the last)
+	ScrollChunks(ctx context.Context, collectionName string, offset string, limit int) ([]*model.CodeChunk, string, error)
+
+	// CountChunks returns the number of points stored in a collection
+	CountChunks(ctx context.Context, collectionName string) (uint64, error)
+
 	// Close closes the database connection
 	Close() error
 
@@ -39,6 +50,38 @@ type VectorDatabase interface {
 	Health(ctx context.Context) error
 }
 
+// SignatureVectorStore is implemented by vector databases that can keep the
+// signature embedding of a function chunk in the chunk's own point, as a
+// second named vector next to the content embedding
+type SignatureVectorStore interface {
+	// HasSignatureVectors reports whether the points of a collection carry
+	// signature vectors
+	HasSignatureVectors(ctx context.Context, collectionName string) (bool, error)
+
+	// SearchSignatures finds the chunks whose signature vector is closest to queryVector
+	SearchSignatures(ctx context.Context, collectionName string, queryVector []float32, limit int, filter map[string]interface{}) ([]*model.CodeChunk, []float32, error)
+}
+
+// VectorSizeReader is implemented by vector databases that can report the
+// dimension a collection was created with, so a collection left over from
+// another embedding model can be told apart
+type VectorSizeReader interface {
+	// VectorSize returns the dimension of the content vectors of a collection
+	VectorSize(ctx context.Context, collectionName string) (int, error)
+}
+
+// Named vectors of collections created with named vectors
+const (
+	ContentVectorName   = "content"
+	SignatureVectorName = "signature"
+)
+
+// ErrChunkNotFound is returned by GetChunkByID when no chunk has the ID
+var ErrChunkNotFound = errors.New("chunk not found")
+
+// ErrEntityNotFound is returned when a class or file has no embedded chunks
+var ErrEntityNotFound = errors.New("no embedded chunks")
+
 // DistanceMetric represents the distance metric used for vector similarity
 type DistanceMetric string
 
@@ -0,0 +1,200 @@
+package telemetry
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"runtime"
+	"runtime/debug"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/armchr/codeapi/internal/config"
+	"go.uber.org/zap"
+)
+
+// Report is the JSON document sent to the telemetry endpoint
+type Report struct {
+	// InstallID is random and changes on every restart; it only tells
+	// reports of one process apart
+	InstallID   string      `json:"install_id"`
+	Version     string      `json:"version"`
+	OS          string      `json:"os"`
+	Arch        string      `json:"arch"`
+	PeriodStart time.Time   `json:"period_start"`
+	PeriodEnd   time.Time   `json:"period_end"`
+	Deployment  Deployment  `json:"deployment"`
+	IndexBuilds IndexBuilds `json:"index_builds"`
+	// FilesByLanguage counts the files index builds visited per language
+	FilesByLanguage map[string]int64 `json:"files_by_language,omitempty"`
+	// Errors counts errors per class
+	Errors map[string]int64 `json:"errors,omitempty"`
+	// Features counts requests per API route pattern
+	Features map[string]int64 `json:"features,omitempty"`
+}
+
+// Deployment describes the configuration, without names or addresses
+type Deployment struct {
+	DBDriver string `json:"db_driver"`
+	// Repositories counts the enabled repositories per configured language
+	Repositories map[string]int `json:"repositories"`
+	// Features lists the optional features turned on in app.yaml
+	Features []string `json:"features"`
+}
+
+// IndexBuilds summarizes the repository builds of a period
+type IndexBuilds struct {
+	Count        int64   `json:"count"`
+	Failed       int64   `json:"failed"`
+	Cancelled    int64   `json:"cancelled"`
+	TotalSeconds float64 `json:"total_seconds"`
+	// DurationBuckets counts builds by duration range
+	DurationBuckets map[string]int64 `json:"duration_buckets,omitempty"`
+}
+
+// Reporter sends the recorded counts to the configured endpoint
+type Reporter struct {
+	cfg         *config.Config
+	client      *http.Client
+	installID   string
+	periodStart time.Time
+	logger      *zap.Logger
+}
+
+// NewReporter creates a reporter for telemetry.endpoint and turns recording
+// on. Call it only when telemetry.enabled is true.
+func NewReporter(cfg *config.Config, logger *zap.Logger) *Reporter {
+	id := make([]byte, 16)
+	rand.Read(id)
+	enabled.Store(true)
+	return &Reporter{
+		cfg:         cfg,
+		client:      &http.Client{Timeout: 30 * time.Second},
+		installID:   hex.EncodeToString(id),
+		periodStart: time.Now(),
+		logger:      logger,
+	}
+}
+
+// Run sends a report every telemetry.interval_minutes until ctx is done
+func (r *Reporter) Run(ctx context.Context) {
+	interval := time.Duration(r.cfg.Telemetry.GetDefaults().IntervalMinutes) * time.Minute
+	r.logger.Info("Telemetry reporting enabled",
+		zap.String("endpoint", r.cfg.Telemetry.Endpoint), zap.Duration("interval", interval))
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := r.Send(ctx); err != nil {
+				r.logger.Debug("Failed to send telemetry report", zap.Error(err))
+			}
+		}
+	}
+}
+
+// Send reports what was recorded since the last report. Counts that fail to
+// send are kept for the next one.
+func (r *Reporter) Send(ctx context.Context) error {
+	taken := take()
+	now := time.Now()
+	report := r.buildReport(taken, now)
+
+	body, err := json.Marshal(report)
+	if err != nil {
+		restore(taken)
+		return fmt.Errorf("failed to encode report: %w", err)
+	}
+	if err := r.post(ctx, body); err != nil {
+		restore(taken)
+		return err
+	}
+	r.periodStart = now
+	return nil
+}
+
+func (r *Reporter) post(ctx context.Context, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.cfg.Telemetry.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("telemetry endpoint returned %s", resp.Status)
+	}
+	return nil
+}
+
+func (r *Reporter) buildReport(c *counts, now time.Time) *Report {
+	return &Report{
+		InstallID:       r.installID,
+		Version:         version(),
+		OS:              runtime.GOOS,
+		Arch:            runtime.GOARCH,
+		PeriodStart:     r.periodStart.UTC(),
+		PeriodEnd:       now.UTC(),
+		Deployment:      describeDeployment(r.cfg),
+		IndexBuilds:     c.indexBuilds,
+		FilesByLanguage: c.filesByLanguage,
+		Errors:          c.errors,
+		Features:        c.features,
+	}
+}
+
+// describeDeployment summarizes the configuration without anything that
+// identifies the installation
+func describeDeployment(cfg *config.Config) Deployment {
+	d := Deployment{
+		DBDriver:     cfg.DB.GetDefaults().Driver,
+		Repositories: make(map[string]int),
+		Features:     []string{},
+	}
+	for _, repo := range cfg.Repositories() {
+		if repo.Disabled {
+			continue
+		}
+		d.Repositories[strings.ToLower(repo.Language)]++
+		if repo.Encrypt && !slices.Contains(d.Features, "encryption") {
+			d.Features = append(d.Features, "encryption")
+		}
+	}
+
+	enabledFeatures := map[string]bool{
+		"code_graph":   cfg.App.CodeGraph || cfg.IndexBuilding.EnableCodeGraph,
+		"embeddings":   cfg.IndexBuilding.EnableEmbeddings,
+		"summary":      cfg.IndexBuilding.EnableSummary,
+		"tenancy":      cfg.Tenancy.Enabled,
+		"secrets":      cfg.Secrets.Provider != "",
+		"tracing":      cfg.Tracing.Enabled,
+		"source_watch": !cfg.App.DisableSourceReload,
+	}
+	for name, on := range enabledFeatures {
+		if on {
+			d.Features = append(d.Features, name)
+		}
+	}
+	sort.Strings(d.Features)
+	return d
+}
+
+// version returns the module version the binary was built from
+func version() string {
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" {
+		return info.Main.Version
+	}
+	return "unknown"
+}
@@ -0,0 +1,109 @@
+package telemetry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/armchr/codeapi/internal/config"
+	"go.uber.org/zap"
+)
+
+func TestRecordDisabled(t *testing.T) {
+	enabled.Store(false)
+	take()
+	RecordIndexBuild(time.Second, map[string]int{"go": 1}, nil)
+	RecordError("neo4j_query")
+	RecordFeature("GET /health")
+	if c := take(); c.indexBuilds.Count != 0 || len(c.errors) != 0 || len(c.features) != 0 || len(c.filesByLanguage) != 0 {
+		t.Errorf("recorded while disabled: %+v", c)
+	}
+}
+
+func TestReporterSend(t *testing.T) {
+	var bodies []string
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{
+		Telemetry: config.TelemetryConfig{Enabled: true, Endpoint: server.URL},
+		Source: config.SourceConfig{Repositories: []config.Repository{
+			{Name: "secret-project", Path: "/src/secret-project", Language: "go", Encrypt: true},
+			{Name: "other", Path: "/src/other", Language: "python"},
+		}},
+	}
+	take()
+	reporter := NewReporter(cfg, zap.NewNop())
+	defer enabled.Store(false)
+
+	RecordIndexBuild(30*time.Second, map[string]int{"go": 10, "python": 2}, nil)
+	RecordIndexBuild(2*time.Hour, map[string]int{"go": 5}, errors.New("neo4j down"))
+	RecordIndexBuild(time.Second, nil, context.Canceled)
+	RecordError("qdrant_query")
+	RecordFeature("GET /codeapi/v1/repos")
+	RecordFeature("GET /codeapi/v1/repos")
+
+	// A failed send keeps the counts for the next report
+	status = http.StatusServiceUnavailable
+	if err := reporter.Send(context.Background()); err == nil {
+		t.Fatal("Send() to a failing endpoint succeeded")
+	}
+	status = http.StatusOK
+	if err := reporter.Send(context.Background()); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if len(bodies) != 2 {
+		t.Fatalf("endpoint received %d reports, want 2", len(bodies))
+	}
+	if strings.Contains(bodies[1], "secret-project") || strings.Contains(bodies[1], "/src/") {
+		t.Errorf("report identifies a repository: %s", bodies[1])
+	}
+
+	var report Report
+	if err := json.Unmarshal([]byte(bodies[1]), &report); err != nil {
+		t.Fatal(err)
+	}
+	builds := report.IndexBuilds
+	if builds.Count != 3 || builds.Failed != 1 || builds.Cancelled != 1 {
+		t.Errorf("index builds = %+v", builds)
+	}
+	if builds.DurationBuckets["under_1m"] != 2 || builds.DurationBuckets["over_1h"] != 1 {
+		t.Errorf("duration buckets = %v", builds.DurationBuckets)
+	}
+	if report.FilesByLanguage["go"] != 15 || report.FilesByLanguage["python"] != 2 {
+		t.Errorf("files by language = %v", report.FilesByLanguage)
+	}
+	if report.Errors["qdrant_query"] != 1 || report.Errors[ErrorIndexFailed] != 1 || report.Errors[ErrorIndexCancelled] != 1 {
+		t.Errorf("errors = %v", report.Errors)
+	}
+	if report.Features["GET /codeapi/v1/repos"] != 2 {
+		t.Errorf("features = %v", report.Features)
+	}
+	if report.Deployment.Repositories["go"] != 1 || report.Deployment.Repositories["python"] != 1 {
+		t.Errorf("deployment repositories = %v", report.Deployment.Repositories)
+	}
+	if !strings.Contains(strings.Join(report.Deployment.Features, ","), "encryption") {
+		t.Errorf("deployment features = %v", report.Deployment.Features)
+	}
+
+	// Sent counts are not reported again
+	if err := reporter.Send(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	var next Report
+	json.Unmarshal([]byte(bodies[2]), &next)
+	if next.IndexBuilds.Count != 0 || len(next.Features) != 0 {
+		t.Errorf("counts reported twice: %+v", next)
+	}
+}
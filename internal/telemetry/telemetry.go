@@ -0,0 +1,146 @@
+// Package telemetry collects the opt-in usage report: aggregate counts of
+// index builds, indexed languages, error classes and the API routes in use.
+// It never records repository names, paths, code or request contents. The
+// Record helpers do nothing until a Reporter is started, so an instance with
+// telemetry off keeps no counts.
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Error classes recorded for index builds
+const (
+	ErrorIndexFailed    = "index_failed"
+	ErrorIndexCancelled = "index_cancelled"
+)
+
+// enabled turns recording on for the process
+var enabled atomic.Bool
+
+// counts accumulates what is recorded between two reports
+type counts struct {
+	mu              sync.Mutex
+	indexBuilds     IndexBuilds
+	filesByLanguage map[string]int64
+	errors          map[string]int64
+	features        map[string]int64
+}
+
+var recorded = newCounts()
+
+func newCounts() *counts {
+	return &counts{
+		indexBuilds:     IndexBuilds{DurationBuckets: make(map[string]int64)},
+		filesByLanguage: make(map[string]int64),
+		errors:          make(map[string]int64),
+		features:        make(map[string]int64),
+	}
+}
+
+// durationBucket groups a build duration into a coarse range
+func durationBucket(d time.Duration) string {
+	switch {
+	case d < time.Minute:
+		return "under_1m"
+	case d < 10*time.Minute:
+		return "1m_10m"
+	case d < time.Hour:
+		return "10m_1h"
+	default:
+		return "over_1h"
+	}
+}
+
+// RecordIndexBuild counts one repository build, the files it visited per
+// language and, for a failed build, its error class
+func RecordIndexBuild(d time.Duration, filesByLanguage map[string]int, err error) {
+	if !enabled.Load() {
+		return
+	}
+	recorded.mu.Lock()
+	defer recorded.mu.Unlock()
+
+	builds := &recorded.indexBuilds
+	builds.Count++
+	builds.TotalSeconds += d.Seconds()
+	builds.DurationBuckets[durationBucket(d)]++
+	for language, n := range filesByLanguage {
+		recorded.filesByLanguage[language] += int64(n)
+	}
+	switch {
+	case err == nil:
+	case errors.Is(err, context.Canceled):
+		builds.Cancelled++
+		recorded.errors[ErrorIndexCancelled]++
+	default:
+		builds.Failed++
+		recorded.errors[ErrorIndexFailed]++
+	}
+}
+
+// RecordError counts an error of a class such as "neo4j_query" or
+// "lsp_timeout"
+func RecordError(class string) {
+	if !enabled.Load() {
+		return
+	}
+	recorded.mu.Lock()
+	recorded.errors[class]++
+	recorded.mu.Unlock()
+}
+
+// RecordFeature counts a use of a feature, such as a request to an API
+// route pattern
+func RecordFeature(name string) {
+	if !enabled.Load() {
+		return
+	}
+	recorded.mu.Lock()
+	recorded.features[name]++
+	recorded.mu.Unlock()
+}
+
+// take returns the counts recorded so far and starts new ones
+func take() *counts {
+	recorded.mu.Lock()
+	defer recorded.mu.Unlock()
+	taken := &counts{
+		indexBuilds:     recorded.indexBuilds,
+		filesByLanguage: recorded.filesByLanguage,
+		errors:          recorded.errors,
+		features:        recorded.features,
+	}
+	fresh := newCounts()
+	recorded.indexBuilds = fresh.indexBuilds
+	recorded.filesByLanguage = fresh.filesByLanguage
+	recorded.errors = fresh.errors
+	recorded.features = fresh.features
+	return taken
+}
+
+// restore adds counts that could not be sent back, so the next report
+// includes them
+func restore(c *counts) {
+	recorded.mu.Lock()
+	defer recorded.mu.Unlock()
+	builds := &recorded.indexBuilds
+	builds.Count += c.indexBuilds.Count
+	builds.Failed += c.indexBuilds.Failed
+	builds.Cancelled += c.indexBuilds.Cancelled
+	builds.TotalSeconds += c.indexBuilds.TotalSeconds
+	addCounts(builds.DurationBuckets, c.indexBuilds.DurationBuckets)
+	addCounts(recorded.filesByLanguage, c.filesByLanguage)
+	addCounts(recorded.errors, c.errors)
+	addCounts(recorded.features, c.features)
+}
+
+func addCounts(dst, src map[string]int64) {
+	for key, n := range src {
+		dst[key] += n
+	}
+}
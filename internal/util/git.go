@@ -7,7 +7,9 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 )
 
 // GitInfo contains git repository information
@@ -194,3 +196,105 @@ func GetRelativePath(repoPath, filePath string) (string, error) {
 	}
 	return relPath, nil
 }
+
+// GetGitDiff returns the unified diff between two refs with no context lines.
+// Paths are reported relative to repoPath, which may be a subdirectory of the
+// git root. Refs are passed after "--end-of-options" so they cannot be mistaken
+// for flags.
+func GetGitDiff(repoPath, baseRef, headRef string) (string, error) {
+	cmd := exec.Command("git", "diff", "--no-color", "--no-ext-diff", "--relative", "-U0", "--end-of-options", baseRef, headRef, "--")
+	cmd.Dir = repoPath
+	output, err := cmd.Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			return "", fmt.Errorf("git diff failed: %s", strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return "", fmt.Errorf("failed to run git diff: %w", err)
+	}
+	return string(output), nil
+}
+
+// BlameLine is the commit that last changed a line, as reported by git blame
+type BlameLine struct {
+	CommitID    string
+	Author      string
+	AuthorEmail string
+	AuthorTime  time.Time
+}
+
+// notCommitted is the commit git blame reports for lines of the working tree
+const notCommitted = "0000000000000000000000000000000000000000"
+
+// BlameLines runs git blame for the given 1-based lines of a file, relative
+// to repoPath, and returns what it reports by line. Lines not yet committed
+// are left out.
+func BlameLines(repoPath, relPath string, lines []int) (map[int]BlameLine, error) {
+	if len(lines) == 0 {
+		return nil, nil
+	}
+	args := []string{"blame", "--porcelain"}
+	for _, line := range lines {
+		args = append(args, "-L", fmt.Sprintf("%d,%d", line, line))
+	}
+	args = append(args, "--", relPath)
+
+	cmd := exec.Command("git", args...)
+	cmd.Dir = repoPath
+	output, err := cmd.Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			return nil, fmt.Errorf("git blame failed: %s", strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return nil, fmt.Errorf("failed to run git blame: %w", err)
+	}
+	return parseBlamePorcelain(string(output)), nil
+}
+
+// parseBlamePorcelain reads git blame --porcelain output. Commit details are
+// only printed the first time a commit appears, so they are kept by commit.
+func parseBlamePorcelain(output string) map[int]BlameLine {
+	result := make(map[int]BlameLine)
+	commits := make(map[string]*BlameLine)
+	var current *BlameLine
+	var line int
+
+	for _, text := range strings.Split(output, "\n") {
+		switch {
+		case strings.HasPrefix(text, "\t"):
+			// The line content closes each entry
+			if current != nil && current.CommitID != notCommitted {
+				result[line] = *current
+			}
+			current = nil
+		case current == nil:
+			// Header: <commit> <original line> <final line> [<lines in group>]
+			fields := strings.Fields(text)
+			if len(fields) < 3 {
+				continue
+			}
+			n, err := strconv.Atoi(fields[2])
+			if err != nil {
+				continue
+			}
+			line = n
+			current = commits[fields[0]]
+			if current == nil {
+				current = &BlameLine{CommitID: fields[0]}
+				commits[fields[0]] = current
+			}
+		default:
+			key, value, _ := strings.Cut(text, " ")
+			switch key {
+			case "author":
+				current.Author = value
+			case "author-mail":
+				current.AuthorEmail = strings.Trim(value, "<>")
+			case "author-time":
+				if secs, err := strconv.ParseInt(value, 10, 64); err == nil {
+					current.AuthorTime = time.Unix(secs, 0).UTC()
+				}
+			}
+		}
+	}
+	return result
+}
@@ -0,0 +1,35 @@
+package util
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseBlamePorcelain(t *testing.T) {
+	output := "aaaa1111 3 3 1\n" +
+		"author Ada Lovelace\n" +
+		"author-mail <ada@example.com>\n" +
+		"author-time 1700000000\n" +
+		"author-tz +0000\n" +
+		"summary Add parser\n" +
+		"filename a.go\n" +
+		"\t// TODO: handle tabs\n" +
+		"0000000000000000000000000000000000000000 9 9 1\n" +
+		"author Not Committed Yet\n" +
+		"filename a.go\n" +
+		"\t// FIXME: new\n" +
+		"aaaa1111 12 14 1\n" +
+		"filename a.go\n" +
+		"\t// HACK: again\n"
+
+	got := parseBlamePorcelain(output)
+	if len(got) != 2 {
+		t.Fatalf("got %d lines, want 2 (uncommitted left out): %+v", len(got), got)
+	}
+	want := BlameLine{CommitID: "aaaa1111", Author: "Ada Lovelace", AuthorEmail: "ada@example.com", AuthorTime: time.Unix(1700000000, 0).UTC()}
+	for _, line := range []int{3, 14} {
+		if got[line] != want {
+			t.Errorf("line %d = %+v, want %+v", line, got[line], want)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package util
+
+import (
+	"bufio"
+	"bytes"
+	"path/filepath"
+	"strings"
+)
+
+// languageExtensions maps language names to the file extensions (without the
+// leading dot) that belong to them.
+var languageExtensions = map[string][]string{
+	"go":         {"go"},
+	"python":     {"py", "pyw", "pyi", "pyx", "pyd"},
+	"javascript": {"js", "jsx", "mjs", "cjs"},
+	"typescript": {"ts", "tsx", "mts", "cts"},
+	"java":       {"java"},
+	"rust":       {"rs"},
+	"c":          {"c", "h"},
+	"cpp":        {"cpp", "cc", "cxx", "hpp", "hxx", "c++", "h++"},
+	"csharp":     {"cs"},
+	"ruby":       {"rb"},
+	"php":        {"php"},
+	"swift":      {"swift"},
+	"kotlin":     {"kt", "kts"},
+	"scala":      {"scala", "sc"},
+	"r":          {"r", "rmd"},
+	"shell":      {"sh", "bash", "zsh"},
+	"yaml":       {"yaml", "yml"},
+	"json":       {"json"},
+	"xml":        {"xml"},
+	"html":       {"html", "htm"},
+	"css":        {"css", "scss", "sass", "less"},
+	"sql":        {"sql"},
+	"markdown":   {"md", "markdown"},
+	"vue":        {"vue"},
+	"svelte":     {"svelte"},
+}
+
+// extensionLanguages is the reverse of languageExtensions
+var extensionLanguages = func() map[string]string {
+	m := make(map[string]string)
+	for lang, exts := range languageExtensions {
+		for _, ext := range exts {
+			m[ext] = lang
+		}
+	}
+	return m
+}()
+
+// shebangInterpreters maps interpreter names found in a "#!" line to languages
+var shebangInterpreters = map[string]string{
+	"python":  "python",
+	"python2": "python",
+	"python3": "python",
+	"node":    "javascript",
+	"nodejs":  "javascript",
+	"deno":    "typescript",
+	"ts-node": "typescript",
+	"sh":      "shell",
+	"bash":    "shell",
+	"zsh":     "shell",
+	"ruby":    "ruby",
+	"php":     "php",
+}
+
+// DetectLanguage returns the language of a single file, or "" if it cannot be
+// determined. The extension is consulted first; when it is missing or unknown
+// and content is provided, the shebang line is used instead. This is what lets
+// mixed-language repositories route each file independently of the
+// repository-level language setting.
+func DetectLanguage(filePath string, content []byte) string {
+	ext := strings.TrimPrefix(strings.ToLower(filepath.Ext(filePath)), ".")
+	if lang, ok := extensionLanguages[ext]; ok {
+		return lang
+	}
+	return languageFromShebang(content)
+}
+
+// tagAliases are language names seen in fenced code blocks and lang
+// attributes that are neither a language nor one of its extensions
+var tagAliases = map[string]string{
+	"golang":  "go",
+	"python3": "python",
+	"node":    "javascript",
+	"console": "shell",
+	"c#":      "csharp",
+	"cs":      "csharp",
+	"c++":     "cpp",
+}
+
+// LanguageForTag returns the language named by the info string of a fenced
+// code block or the lang attribute of a <script> or <style> section: a
+// language name like "python", an extension like "ts", or a common alias.
+// It returns "" for tags it does not know.
+func LanguageForTag(tag string) string {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	if _, ok := languageExtensions[tag]; ok {
+		return tag
+	}
+	if lang, ok := tagAliases[tag]; ok {
+		return lang
+	}
+	return extensionLanguages[strings.TrimPrefix(tag, ".")]
+}
+
+// languageFromShebang inspects the first line of content for an interpreter
+// directive such as "#!/usr/bin/env python3"
+func languageFromShebang(content []byte) string {
+	if !bytes.HasPrefix(content, []byte("#!")) {
+		return ""
+	}
+
+	line, _, _ := bufio.NewReader(bytes.NewReader(content)).ReadLine()
+	fields := strings.Fields(strings.TrimPrefix(string(line), "#!"))
+	if len(fields) == 0 {
+		return ""
+	}
+
+	interpreter := filepath.Base(fields[0])
+	if interpreter == "env" {
+		// Skip env flags like "-S" to get to the actual interpreter
+		interpreter = ""
+		for _, f := range fields[1:] {
+			if !strings.HasPrefix(f, "-") {
+				interpreter = filepath.Base(f)
+				break
+			}
+		}
+	}
+
+	return shebangInterpreters[interpreter]
+}
+
+// testDirectories are path segments that conventionally hold only test code
+var testDirectories = []string{"test", "tests", "__tests__", "spec", "testdata"}
+
+// IsTestFile reports whether a path looks like a test source file by the
+// naming conventions of the supported languages (foo_test.go, test_foo.py,
+// FooTest.java, foo.spec.ts, files under tests/, ...).
+func IsTestFile(filePath string) bool {
+	normalized := filepath.ToSlash(filePath)
+	for _, segment := range strings.Split(filepath.Dir(normalized), "/") {
+		for _, dir := range testDirectories {
+			if segment == dir {
+				return true
+			}
+		}
+	}
+
+	base := filepath.Base(normalized)
+	ext := filepath.Ext(base)
+	stem := strings.TrimSuffix(base, ext)
+
+	switch {
+	case strings.HasSuffix(stem, "_test"), strings.HasPrefix(stem, "test_"):
+		return true
+	case strings.HasSuffix(stem, ".test"), strings.HasSuffix(stem, ".spec"):
+		return true
+	case strings.HasPrefix(stem, "Test") && len(stem) > 4 && stem[4] >= 'A' && stem[4] <= 'Z':
+		return true
+	case strings.HasSuffix(stem, "Test"), strings.HasSuffix(stem, "Tests"):
+		return true
+	}
+	return false
+}
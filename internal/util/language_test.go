@@ -0,0 +1,91 @@
+package util
+
+import "testing"
+
+func TestDetectLanguage(t *testing.T) {
+	tests := []struct {
+		name     string
+		filePath string
+		content  string
+		expected string
+	}{
+		{"Go file", "/repo/main.go", "", "go"},
+		{"Uppercase extension", "/repo/Main.JAVA", "", "java"},
+		{"TSX file", "/repo/ui/App.tsx", "", "typescript"},
+		{"CommonJS file", "/repo/lib/index.cjs", "", "javascript"},
+		{"C# file", "/repo/src/Program.cs", "", "csharp"},
+		{"Extension wins over shebang", "/repo/run.py", "#!/bin/bash\n", "python"},
+		{"Python shebang via env", "/repo/bin/tool", "#!/usr/bin/env python3\nprint('hi')\n", "python"},
+		{"Node shebang", "/repo/scripts/build", "#!/usr/local/bin/node\n", "javascript"},
+		{"Env with flags", "/repo/scripts/run", "#!/usr/bin/env -S deno run\n", "typescript"},
+		{"Shell shebang", "/repo/install", "#!/bin/sh\nset -e\n", "shell"},
+		{"Unknown interpreter", "/repo/script", "#!/usr/bin/perl\n", ""},
+		{"No extension, no shebang", "/repo/LICENSE", "MIT License\n", ""},
+		{"Unknown extension", "/repo/data.bin", "", ""},
+		{"Vue single-file component", "/repo/src/App.vue", "", "vue"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := DetectLanguage(tt.filePath, []byte(tt.content))
+			if result != tt.expected {
+				t.Errorf("DetectLanguage(%q) = %q, want %q", tt.filePath, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestLanguageForTag(t *testing.T) {
+	tests := []struct {
+		tag      string
+		expected string
+	}{
+		{"python", "python"},
+		{"ts", "typescript"},
+		{"TSX", "typescript"},
+		{"golang", "go"},
+		{"bash", "shell"},
+		{"scss", "css"},
+		{"c#", "csharp"},
+		{" yml ", "yaml"},
+		{"mermaid", ""},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		if result := LanguageForTag(tt.tag); result != tt.expected {
+			t.Errorf("LanguageForTag(%q) = %q, want %q", tt.tag, result, tt.expected)
+		}
+	}
+}
+
+func TestIsTestFile(t *testing.T) {
+	tests := []struct {
+		name     string
+		filePath string
+		expected bool
+	}{
+		{"Go test", "pkg/server/handler_test.go", true},
+		{"Go source", "pkg/server/handler.go", false},
+		{"Pytest prefix", "app/test_models.py", true},
+		{"Pytest suffix", "app/models_test.py", true},
+		{"Java test class", "src/main/java/com/acme/OrderServiceTest.java", true},
+		{"Java tests class", "src/com/acme/OrderServiceTests.java", true},
+		{"JUnit prefix", "src/com/acme/TestOrderService.java", true},
+		{"Word starting with Test", "src/com/acme/Testimonial.java", false},
+		{"Jest spec", "web/src/cart.spec.ts", true},
+		{"Jest test", "web/src/cart.test.jsx", true},
+		{"Under tests directory", "tests/integration/helpers.py", true},
+		{"Under __tests__", "web/__tests__/utils.js", true},
+		{"Maven test tree", "src/test/java/com/acme/Fixtures.java", true},
+		{"Name containing test", "src/latest/contest.go", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := IsTestFile(tt.filePath); result != tt.expected {
+				t.Errorf("IsTestFile(%q) = %v, want %v", tt.filePath, result, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,33 @@
+package util
+
+import (
+	"runtime"
+	"sync"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+// parserPool holds idle tree-sitter parsers. A parser owns C memory that the
+// Go collector cannot see, so each one gets a finalizer that frees it when
+// the pool drops it during a collection.
+var parserPool = sync.Pool{
+	New: func() any {
+		parser := tree_sitter.NewParser()
+		runtime.SetFinalizer(parser, (*tree_sitter.Parser).Close)
+		return parser
+	},
+}
+
+// AcquireParser returns a parser for exclusive use by the caller, who must
+// set its language before parsing and hand it back with ReleaseParser.
+// Parsers are not safe for concurrent use.
+func AcquireParser() *tree_sitter.Parser {
+	return parserPool.Get().(*tree_sitter.Parser)
+}
+
+// ReleaseParser resets a parser and returns it to the pool. Trees it produced
+// remain valid.
+func ReleaseParser(parser *tree_sitter.Parser) {
+	parser.Reset()
+	parserPool.Put(parser)
+}
@@ -27,3 +27,22 @@ func (sm *SafeMap[V]) Get(key string) (V, bool) {
 	val, ok := sm.data[key]
 	return val, ok
 }
+
+func (sm *SafeMap[V]) Delete(key string) (V, bool) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	val, ok := sm.data[key]
+	delete(sm.data, key)
+	return val, ok
+}
+
+// Keys returns a snapshot of the keys in unspecified order
+func (sm *SafeMap[V]) Keys() []string {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+	keys := make([]string, 0, len(sm.data))
+	for k := range sm.data {
+		keys = append(keys, k)
+	}
+	return keys
+}
@@ -201,3 +201,25 @@ func TestSafeMap_EmptyKey(t *testing.T) {
 		t.Errorf("Get('') = %q, want 'empty key value'", got)
 	}
 }
+
+func TestSafeMap_Delete(t *testing.T) {
+	m := NewSafeMap[int]()
+	m.Set("a", 1)
+	m.Set("b", 2)
+
+	got, ok := m.Delete("a")
+	if !ok || got != 1 {
+		t.Errorf("Delete('a') = %d, %v, want 1, true", got, ok)
+	}
+	if _, ok := m.Get("a"); ok {
+		t.Error("Get('a') should not find deleted key")
+	}
+	if _, ok := m.Delete("missing"); ok {
+		t.Error("Delete('missing') should report false")
+	}
+
+	keys := m.Keys()
+	if len(keys) != 1 || keys[0] != "b" {
+		t.Errorf("Keys() = %v, want [b]", keys)
+	}
+}
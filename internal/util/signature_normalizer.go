@@ -95,8 +95,9 @@ func normalizeTypeName(typeName string) string {
 		return ""
 	}
 
-	// Handle arrays - just remove the brackets
+	// Handle arrays and varargs (String...) - just remove the brackets
 	typeName = strings.ReplaceAll(typeName, "[]", "")
+	typeName = strings.TrimSuffix(typeName, "...")
 
 	// Handle generics: List<User> -> List User
 	// Map<String, User> -> Map String User
@@ -39,6 +39,7 @@ func TestNormalizeTypeName(t *testing.T) {
 		{"Map<String, User>", "Map String User"},
 		{"com.example.User", "User"},
 		{"String[]", "String"},  // array suffix removed but array word not added (simplified)
+		{"String...", "String"},
 		{"void", "void"},
 		{"ResponseEntity<List<UserDto>>", "Response Entity List User Dto"},
 	}
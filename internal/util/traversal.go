@@ -0,0 +1,36 @@
+package util
+
+import (
+	"fmt"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+// TraversalPanic is the error a panic raised while visiting a syntax tree
+// is turned into. It names the innermost node known to be visited when the
+// panic happened, so a malformed file can be reported and skipped.
+type TraversalPanic struct {
+	Path   string
+	Kind   string // Empty when no node was being visited
+	Line   uint   // 1-based
+	Column uint   // 1-based, in bytes
+	Value  any    // The value passed to panic
+}
+
+// NewTraversalPanic describes a panic recovered while visiting node of the
+// file at path. node may be nil.
+func NewTraversalPanic(path string, node *tree_sitter.Node, value any) *TraversalPanic {
+	p := &TraversalPanic{Path: path, Value: value}
+	if node != nil {
+		pos := node.StartPosition()
+		p.Kind, p.Line, p.Column = node.Kind(), pos.Row+1, pos.Column+1
+	}
+	return p
+}
+
+func (p *TraversalPanic) Error() string {
+	if p.Kind == "" {
+		return fmt.Sprintf("panic while traversing %s: %v", p.Path, p.Value)
+	}
+	return fmt.Sprintf("panic while traversing %s at %s %d:%d: %v", p.Path, p.Kind, p.Line, p.Column, p.Value)
+}
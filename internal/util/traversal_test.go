@@ -0,0 +1,22 @@
+package util
+
+import "testing"
+
+func TestTraversalPanicError(t *testing.T) {
+	tests := []struct {
+		name string
+		p    *TraversalPanic
+		want string
+	}{
+		{"without node", NewTraversalPanic("a/b.go", nil, "boom"), "panic while traversing a/b.go: boom"},
+		{"with node", &TraversalPanic{Path: "a/b.go", Kind: "call_expression", Line: 3, Column: 7, Value: "boom"},
+			"panic while traversing a/b.go at call_expression 3:7: boom"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.p.Error(); got != tt.want {
+				t.Errorf("Error() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,10 +1,13 @@
 package util
 
 import (
+	"bytes"
 	"github.com/armchr/codeapi/internal/config"
 	"net/url"
+	"os"
 	"path/filepath"
 	"strings"
+	"unicode/utf8"
 )
 
 func ToUri(path, rootPath string) (string, error) {
@@ -27,6 +30,68 @@ func ToRelativePath(rootPath, fullPath string) string {
 	return relPath
 }
 
+// WithinRoot reports whether path is inside the directory root once both are
+// made absolute and their symlinks resolved. A path that does not exist is
+// judged by its deepest existing ancestor, so a symlinked parent directory
+// pointing elsewhere does not pass.
+func WithinRoot(root, path string) bool {
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return false
+	}
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return false
+	}
+	realPath, err := resolveExisting(absPath)
+	if err != nil {
+		return false
+	}
+	if realRoot, err := filepath.EvalSymlinks(absRoot); err == nil {
+		absRoot = realRoot
+	}
+	return strings.HasPrefix(realPath, absRoot+string(filepath.Separator))
+}
+
+// resolveExisting resolves the symlinks of the deepest existing ancestor of
+// the absolute path and joins the missing parts back onto it
+func resolveExisting(absPath string) (string, error) {
+	var missing []string
+	dir := absPath
+	for {
+		real, err := filepath.EvalSymlinks(dir)
+		if err == nil {
+			for i := len(missing) - 1; i >= 0; i-- {
+				real = filepath.Join(real, missing[i])
+			}
+			return real, nil
+		}
+		if !os.IsNotExist(err) {
+			return "", err
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", err
+		}
+		missing = append(missing, filepath.Base(dir))
+		dir = parent
+	}
+}
+
+// URIWithinRoot reports whether uri is a file URI naming a path inside the
+// directory root. The path is percent-decoded before it is checked, so
+// encoded ".." segments cannot leave the root.
+func URIWithinRoot(root, uri string) bool {
+	if !strings.HasPrefix(uri, "file://") {
+		return false
+	}
+	path, err := url.PathUnescape(ExtractPathFromURI(uri))
+	if err != nil {
+		return false
+	}
+	return WithinRoot(root, filepath.Clean(path))
+}
+
 func ExtractPathFromURI(uri string) string {
 	if len(uri) > 7 && uri[:7] == "file://" {
 		return uri[7:]
@@ -36,6 +101,39 @@ func ExtractPathFromURI(uri string) string {
 
 func Ptr[T any](v T) *T { return &v }
 
+// TruncateAtLine returns the longest prefix of b no longer than limit bytes
+// that ends at a line break. Without a line break in range it cuts at the
+// last whole UTF-8 character instead.
+func TruncateAtLine(b []byte, limit int) []byte {
+	if len(b) <= limit {
+		return b
+	}
+	if i := bytes.LastIndexByte(b[:limit], '\n'); i >= 0 {
+		return b[:i+1]
+	}
+	for limit > 0 && !utf8.RuneStart(b[limit]) {
+		limit--
+	}
+	return b[:limit]
+}
+
+// textSniffBytes is how much of a file IsText looks at, as git does
+const textSniffBytes = 8000
+
+// IsText reports whether content looks like text rather than binary data:
+// its first few kilobytes hold no NUL byte and are valid UTF-8, allowing for
+// a character cut off at the end of the sample.
+func IsText(content []byte) bool {
+	sample := content[:min(len(content), textSniffBytes)]
+	if bytes.IndexByte(sample, 0) >= 0 {
+		return false
+	}
+	if len(sample) < len(content) {
+		sample = TruncateAtLine(sample, len(sample)-utf8.UTFMax)
+	}
+	return utf8.Valid(sample)
+}
+
 // ShouldSkipDirectory checks if a directory should be skipped during traversal
 func ShouldSkipDirectory(path string) bool {
 	skipDirs := []string{
@@ -67,11 +165,20 @@ func ShouldSkipFile(filePath string, repo *config.Repository) bool {
 
 	// Language filtering if repo config is provided and SkipOtherLanguages is enabled
 	if repo != nil && repo.SkipOtherLanguages && repo.Language != "" {
-		if !isLanguageMatch(filePath, repo.Language) {
+		if repo.IsMultiLanguage() {
+			if !repo.IncludesLanguage(DetectLanguage(filePath, nil)) {
+				return true
+			}
+		} else if !isLanguageMatch(filePath, repo.Language) {
 			return true
 		}
 	}
 
+	// Vendored and generated code the repository marks as external
+	if repo != nil && config.IsExternalPath(filePath, repo.Path, repo.ExternalPaths) {
+		return true
+	}
+
 	// Skip specific file names (case-insensitive)
 	skipFileNames := []string{
 		"dockerfile",
@@ -229,33 +336,6 @@ func isLanguageMatch(filePath, language string) bool {
 	// Remove the leading dot
 	ext = strings.TrimPrefix(ext, ".")
 
-	// Define language extension mappings with variants
-	languageExtensions := map[string][]string{
-		"go": {"go"},
-		"python": {"py", "pyw", "pyi", "pyx", "pyd"},
-		"javascript": {"js", "jsx", "mjs", "cjs"},
-		"typescript": {"ts", "tsx", "mts", "cts"},
-		"java": {"java"},
-		"rust": {"rs"},
-		"c": {"c", "h"},
-		"cpp": {"cpp", "cc", "cxx", "hpp", "hxx", "c++", "h++"},
-		"csharp": {"cs"},
-		"ruby": {"rb"},
-		"php": {"php"},
-		"swift": {"swift"},
-		"kotlin": {"kt", "kts"},
-		"scala": {"scala", "sc"},
-		"r": {"r", "rmd"},
-		"shell": {"sh", "bash", "zsh"},
-		"yaml": {"yaml", "yml"},
-		"json": {"json"},
-		"xml": {"xml"},
-		"html": {"html", "htm"},
-		"css": {"css", "scss", "sass", "less"},
-		"sql": {"sql"},
-		"markdown": {"md", "markdown"},
-	}
-
 	// Normalize language name to lowercase
 	normalizedLang := strings.ToLower(language)
 
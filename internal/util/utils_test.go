@@ -2,6 +2,9 @@ package util
 
 import (
 	"github.com/armchr/codeapi/internal/config"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -55,6 +58,54 @@ func TestIsLanguageMatch(t *testing.T) {
 	}
 }
 
+func TestTruncateAtLine(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		limit int
+		want  string
+	}{
+		{"fits", "a\nb\n", 10, "a\nb\n"},
+		{"cut at last newline", "one\ntwo\nthree\n", 10, "one\ntwo\n"},
+		{"newline at limit", "one\ntwo\n", 4, "one\n"},
+		{"no newline", "abcdef", 4, "abcd"},
+		{"no newline inside rune", "ab\u00e9cd", 3, "ab"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(TruncateAtLine([]byte(tt.input), tt.limit)); got != tt.want {
+				t.Errorf("TruncateAtLine(%q, %d) = %q, want %q", tt.input, tt.limit, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsText(t *testing.T) {
+	long := strings.Repeat("x", textSniffBytes-1) + "\u00e9"
+	tests := []struct {
+		name    string
+		content string
+		want    bool
+	}{
+		{"empty", "", true},
+		{"source", "package main\n\nfunc main() {}\n", true},
+		{"utf-8", "# caf\u00e9\n", true},
+		{"nul byte", "ELF\x00\x01", false},
+		{"invalid utf-8", "\xff\xfe\x00h", false},
+		{"latin-1", "caf\xe9\n", false},
+		{"rune cut by the sample", long + "\x00", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsText([]byte(tt.content)); got != tt.want {
+				t.Errorf("IsText(%q) = %v, want %v", tt.content[:min(len(tt.content), 20)], got, tt.want)
+			}
+		})
+	}
+}
+
 func TestShouldSkipFile_WithLanguageFilter(t *testing.T) {
 	tests := []struct {
 		name         string
@@ -113,6 +164,28 @@ func TestShouldSkipFile_WithLanguageFilter(t *testing.T) {
 			shouldSkip:  false,
 			description: "Should process TSX files in TypeScript repo (variant)",
 		},
+		{
+			name:     "Auto repo, Python file, languages list includes python",
+			filePath: "/repo/tools/gen.py",
+			repo: &config.Repository{
+				Language:           "auto",
+				Languages:          []string{"go", "python"},
+				SkipOtherLanguages: true,
+			},
+			shouldSkip:  false,
+			description: "Should process files whose detected language is listed",
+		},
+		{
+			name:     "Auto repo, Java file, languages list excludes java",
+			filePath: "/repo/legacy/Main.java",
+			repo: &config.Repository{
+				Language:           "auto",
+				Languages:          []string{"go", "python"},
+				SkipOtherLanguages: true,
+			},
+			shouldSkip:  true,
+			description: "Should skip files whose detected language is not listed",
+		},
 		{
 			name:        "No repo config",
 			filePath:    "/repo/main.go",
@@ -140,6 +213,28 @@ func TestShouldSkipFile_WithLanguageFilter(t *testing.T) {
 			shouldSkip:  true,
 			description: "Should skip files in bin directory",
 		},
+		{
+			name:     "Generated protobuf output skipped",
+			filePath: "/repo/api/order.pb.go",
+			repo: &config.Repository{
+				Path:          "/repo",
+				Language:      "go",
+				ExternalPaths: []string{"third_party", "*.pb.go"},
+			},
+			shouldSkip:  true,
+			description: "Should skip files matching the repository's external paths",
+		},
+		{
+			name:     "External pattern matched inside the repository only",
+			filePath: "/src/third_party/repo/main.go",
+			repo: &config.Repository{
+				Path:          "/src/third_party/repo",
+				Language:      "go",
+				ExternalPaths: []string{"third_party"},
+			},
+			shouldSkip:  false,
+			description: "Should not match external paths against the directories above the repository",
+		},
 	}
 
 	for _, tt := range tests {
@@ -151,3 +246,66 @@ func TestShouldSkipFile_WithLanguageFilter(t *testing.T) {
 		})
 	}
 }
+
+func TestWithinRoot(t *testing.T) {
+	root := t.TempDir()
+	outside := t.TempDir()
+	for _, dir := range []string{root, outside} {
+		if err := os.WriteFile(filepath.Join(dir, "a.go"), []byte("package a"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink(outside, filepath.Join(root, "link")); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		path string
+		want bool
+	}{
+		{"file in root", filepath.Join(root, "a.go"), true},
+		{"missing file in root", filepath.Join(root, "b.go"), true},
+		{"root itself", root, false},
+		{"other directory", filepath.Join(outside, "a.go"), false},
+		{"dot dot", filepath.Join(root, "..", filepath.Base(outside), "a.go"), false},
+		{"symlink out of root", filepath.Join(root, "link", "a.go"), false},
+		{"missing file under symlink out of root", filepath.Join(root, "link", "b.go"), false},
+		{"missing directory under symlink out of root", filepath.Join(root, "link", "new", "b.go"), false},
+		{"missing directory in root", filepath.Join(root, "new", "b.go"), true},
+		{"system file", "/etc/passwd", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := WithinRoot(root, tt.path); got != tt.want {
+				t.Errorf("WithinRoot(%q) = %v, want %v", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestURIWithinRoot(t *testing.T) {
+	root := t.TempDir()
+
+	tests := []struct {
+		name string
+		uri  string
+		want bool
+	}{
+		{"file in root", "file://" + root + "/a.go", true},
+		{"encoded name in root", "file://" + root + "/my%20file.go", true},
+		{"dot dot", "file://" + root + "/../a.go", false},
+		{"encoded dot dot", "file://" + root + "/%2e%2e/a.go", false},
+		{"upper case encoded dot dot", "file://" + root + "/sub/%2E%2E/%2E%2E/a.go", false},
+		{"encoded separator", "file://" + root + "/..%2f..%2fetc/passwd", false},
+		{"invalid escape", "file://" + root + "/%zz.go", false},
+		{"not a file URI", "https://example.com" + root + "/a.go", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := URIWithinRoot(root, tt.uri); got != tt.want {
+				t.Errorf("URIWithinRoot(%q) = %v, want %v", tt.uri, got, tt.want)
+			}
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package util
 
 import (
+	"context"
 	"os"
 	"path/filepath"
 	"runtime"
@@ -23,8 +24,10 @@ type walkItem struct {
 // and directory. Unlike filepath.Walk, this implementation does not guarantee
 // any particular ordering of children directories and files.
 // gcThreshold controls how often to trigger GC (every N files). Set to 0 to disable.
-// Uses 2 worker goroutines to process files concurrently.
-func WalkDirTree(root string, walkFn WalkFunc, skipPath SkipFunc, logger *zap.Logger, gcThreshold int64, numThreads int) error {
+// Uses numThreads worker goroutines to process files concurrently. Once ctx is
+// done no more files are handed out, files already handed out finish, and
+// ctx.Err() is returned.
+func WalkDirTree(ctx context.Context, root string, walkFn WalkFunc, skipPath SkipFunc, logger *zap.Logger, gcThreshold int64, numThreads int) error {
 	processedCount := int64(0)
 	// Create channels for work distribution
 	workQueue := make(chan walkItem, 2)
@@ -37,6 +40,10 @@ func WalkDirTree(root string, walkFn WalkFunc, skipPath SkipFunc, logger *zap.Lo
 		go func() {
 			defer wg.Done()
 			for item := range workQueue {
+				if ctx.Err() != nil {
+					continue // Drain the queue so the walk can finish
+				}
+
 				// Increment processed count
 				mu.Lock()
 				processedCount++
@@ -75,7 +82,7 @@ func WalkDirTree(root string, walkFn WalkFunc, skipPath SkipFunc, logger *zap.Lo
 
 	processedCount = 0
 
-	err = walk(root, workQueue, skipPath, &processedCount, gcThreshold, logger)
+	err = walk(ctx, root, workQueue, skipPath, &processedCount, gcThreshold, logger)
 	close(workQueue)
 
 	// Wait for all workers to finish
@@ -86,11 +93,11 @@ func WalkDirTree(root string, walkFn WalkFunc, skipPath SkipFunc, logger *zap.Lo
 		return err
 	}
 
-	return nil
+	return ctx.Err()
 }
 
 // walk recursively traverses the directory tree and sends items to the work queue
-func walk(path string, fileQueue chan<- walkItem, skipPath SkipFunc, processedCount *int64, gcThreshold int64, logger *zap.Logger) error {
+func walk(ctx context.Context, path string, fileQueue chan<- walkItem, skipPath SkipFunc, processedCount *int64, gcThreshold int64, logger *zap.Logger) error {
 	// This must be a directory. Don't call for files
 	if skipPath(path, true) {
 		logger.Info("WalkDirTree - Skipping path", zap.String("path", path))
@@ -106,6 +113,9 @@ func walk(path string, fileQueue chan<- walkItem, skipPath SkipFunc, processedCo
 
 	// Recursively walk child entries
 	for _, entry := range entries {
+		if ctx.Err() != nil {
+			return nil
+		}
 		childPath := filepath.Join(path, entry.Name())
 		*processedCount++
 
@@ -122,7 +132,7 @@ func walk(path string, fileQueue chan<- walkItem, skipPath SkipFunc, processedCo
 			}
 			fileQueue <- walkItem{path: childPath}
 		} else {
-			walk(childPath, fileQueue, skipPath, processedCount, gcThreshold, logger)
+			walk(ctx, childPath, fileQueue, skipPath, processedCount, gcThreshold, logger)
 		}
 	}
 
@@ -0,0 +1,274 @@
+package client
+
+import (
+	"context"
+	"net/url"
+	"strings"
+)
+
+// BuildIndex indexes a repository. The server answers once the build is
+// done, so use a context or HTTP client timeout that allows for it.
+func (c *Client) BuildIndex(ctx context.Context, req *BuildIndexRequest) (*BuildIndexResponse, error) {
+	var resp BuildIndexResponse
+	if err := c.post(ctx, "/api/v1/buildIndex", req, &resp, retryWrite); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// IndexFile reindexes one file, or indexes it into a sandbox
+func (c *Client) IndexFile(ctx context.Context, req *IndexFileRequest) (*IndexFileResponse, error) {
+	var resp IndexFileResponse
+	if err := c.post(ctx, "/api/v1/indexFile", req, &resp, retryWrite); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// PurgeSandbox removes what IndexFile stored in a sandbox
+func (c *Client) PurgeSandbox(ctx context.Context, req *PurgeSandboxRequest) (*PurgeSandboxResponse, error) {
+	var resp PurgeSandboxResponse
+	if err := c.post(ctx, "/api/v1/purgeSandbox", req, &resp, retryWrite); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// ProcessDirectory chunks and embeds a directory
+func (c *Client) ProcessDirectory(ctx context.Context, req *ProcessDirectoryRequest) (*ProcessDirectoryResponse, error) {
+	var resp ProcessDirectoryResponse
+	if err := c.post(ctx, "/api/v1/processDirectory", req, &resp, retryWrite); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// GetFunctionDependencies returns the functions a function calls
+func (c *Client) GetFunctionDependencies(ctx context.Context, req *GetFunctionDependenciesRequest) (*FunctionDependencies, error) {
+	var resp FunctionDependencies
+	if err := c.post(ctx, "/api/v1/functionDependencies", req, &resp, retryRead); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// GetFunctionHistory returns a function's signature and summary in each
+// indexed version of its file, newest first
+func (c *Client) GetFunctionHistory(ctx context.Context, req *FunctionHistoryRequest) (*FunctionHistoryResponse, error) {
+	var resp FunctionHistoryResponse
+	if err := c.post(ctx, "/api/v1/functionHistory", req, &resp, retryRead); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// CompareRepos compares two indexed repositories, such as a fork and its
+// upstream, by files, functions and summaries
+func (c *Client) CompareRepos(ctx context.Context, req *CompareReposRequest) (*RepoComparison, error) {
+	var resp RepoComparison
+	if err := c.post(ctx, "/api/v1/compareRepos", req, &resp, retryRead); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// SearchSimilarCode finds the chunks most similar to a code snippet
+func (c *Client) SearchSimilarCode(ctx context.Context, req *SearchSimilarCodeRequest) (*SearchSimilarCodeResponse, error) {
+	var resp SearchSimilarCodeResponse
+	if err := c.post(ctx, "/api/v1/searchSimilarCode", req, &resp, retryRead); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// SearchMethodsBySignature finds methods whose signature matches a query
+func (c *Client) SearchMethodsBySignature(ctx context.Context, req *SearchMethodsBySignatureRequest) (*SearchMethodsBySignatureResponse, error) {
+	var resp SearchMethodsBySignatureResponse
+	if err := c.post(ctx, "/api/v1/searchMethodsBySignature", req, &resp, retryRead); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// GetChunkHierarchy returns a chunk with its parents and children
+func (c *Client) GetChunkHierarchy(ctx context.Context, req *ChunkHierarchyRequest) (*ChunkHierarchyResponse, error) {
+	var resp ChunkHierarchyResponse
+	if err := c.post(ctx, "/api/v1/chunkHierarchy", req, &resp, retryRead); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// RemapChunks maps chunk IDs of an older index to the current chunks
+func (c *Client) RemapChunks(ctx context.Context, req *RemapChunksRequest) (*RemapChunksResponse, error) {
+	var resp RemapChunksResponse
+	if err := c.post(ctx, "/api/v1/remapChunks", req, &resp, retryRead); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// FindDuplicates returns groups of near-duplicate chunks
+func (c *Client) FindDuplicates(ctx context.Context, req *DuplicatesRequest) (*DuplicatesResponse, error) {
+	var resp DuplicatesResponse
+	if err := c.post(ctx, "/api/v1/duplicates", req, &resp, retryRead); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// ListNotes returns one page of TODO-style comments and license headers.
+// Notes iterates over all of them.
+func (c *Client) ListNotes(ctx context.Context, req *ListNotesRequest) (*ListNotesResponse, error) {
+	var resp ListNotesResponse
+	if err := c.post(ctx, "/api/v1/notes", req, &resp, retryRead); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// SearchNotes finds notes semantically similar to a query
+func (c *Client) SearchNotes(ctx context.Context, req *SearchNotesRequest) (*SearchNotesResponse, error) {
+	var resp SearchNotesResponse
+	if err := c.post(ctx, "/api/v1/searchNotes", req, &resp, retryRead); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// RecordFeedback stores votes on search results and summaries and returns
+// how many were recorded
+func (c *Client) RecordFeedback(ctx context.Context, req *RecordFeedbackRequest) (int, error) {
+	var resp struct {
+		Recorded int `json:"recorded"`
+	}
+	if err := c.post(ctx, "/api/v1/feedback", req, &resp, retryWrite); err != nil {
+		return 0, err
+	}
+	return resp.Recorded, nil
+}
+
+// AggregateFeedback returns vote totals per entity or query
+func (c *Client) AggregateFeedback(ctx context.Context, req *AggregateFeedbackRequest) (*AggregateFeedbackResponse, error) {
+	var resp AggregateFeedbackResponse
+	if err := c.post(ctx, "/api/v1/feedback/aggregate", req, &resp, retryRead); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// ListAuditLog returns one page of the audit entries of a repository,
+// newest first. AuditEntries iterates over all of them.
+func (c *Client) ListAuditLog(ctx context.Context, req *AuditLogRequest) (*AuditLogResponse, error) {
+	var resp AuditLogResponse
+	if err := c.post(ctx, "/api/v1/audit", req, &resp, retryRead); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// LspDefinition returns where the symbol at a position is defined
+func (c *Client) LspDefinition(ctx context.Context, req *LspPositionRequest) ([]LspLocation, error) {
+	return c.lspLocations(ctx, "/api/v1/lsp/definition", req)
+}
+
+// LspReferences returns the references to the symbol at a position
+func (c *Client) LspReferences(ctx context.Context, req *LspPositionRequest) ([]LspLocation, error) {
+	return c.lspLocations(ctx, "/api/v1/lsp/references", req)
+}
+
+func (c *Client) lspLocations(ctx context.Context, path string, req *LspPositionRequest) ([]LspLocation, error) {
+	var resp struct {
+		Locations []LspLocation `json:"locations"`
+	}
+	if err := c.post(ctx, path, req, &resp, retryRead); err != nil {
+		return nil, err
+	}
+	return resp.Locations, nil
+}
+
+// LspHover returns the hover text of a position
+func (c *Client) LspHover(ctx context.Context, req *LspPositionRequest) (*LspHoverResponse, error) {
+	var resp LspHoverResponse
+	if err := c.post(ctx, "/api/v1/lsp/hover", req, &resp, retryRead); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// ListJobs reports for each repository whether an operation is running on
+// it. A non-empty state ("running" or "idle") filters the repositories.
+func (c *Client) ListJobs(ctx context.Context, state string) (*ListJobsResponse, error) {
+	path := "/api/v1/jobs"
+	if state != "" {
+		path += "?state=" + url.QueryEscape(state)
+	}
+	var resp ListJobsResponse
+	if err := c.get(ctx, path, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// GetRepoJob reports whether an operation is running on a repository
+func (c *Client) GetRepoJob(ctx context.Context, repoName string) (*RepoJob, error) {
+	var resp RepoJob
+	if err := c.get(ctx, "/api/v1/jobs/"+url.PathEscape(repoName), &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// GetRepoStats returns the size of a repository's index in each store
+func (c *Client) GetRepoStats(ctx context.Context, repoName string) (*RepoStats, error) {
+	var resp RepoStats
+	if err := c.get(ctx, "/api/v1/repos/"+url.PathEscape(repoName)+"/stats", &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// GetFileOutline returns the classes and functions of a file, filePath
+// being relative to the repository root
+func (c *Client) GetFileOutline(ctx context.Context, repoName, filePath string) (*FileOutline, error) {
+	segments := strings.Split(strings.TrimPrefix(filePath, "/"), "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	path := "/api/v1/repos/" + url.PathEscape(repoName) + "/files/" + strings.Join(segments, "/") + "/outline"
+
+	var resp FileOutline
+	if err := c.get(ctx, path, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// ResolveEntity maps an entity reference, as returned in the entity_ref
+// fields of responses, to its graph node, summary and chunks
+func (c *Client) ResolveEntity(ctx context.Context, entityRef string) (*ResolvedEntity, error) {
+	var resp ResolvedEntity
+	if err := c.post(ctx, "/api/v1/entities/resolve", &ResolveEntityRequest{EntityRef: entityRef}, &resp, retryRead); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// Health reports whether the server's dependencies are available
+func (c *Client) Health(ctx context.Context) (*Health, error) {
+	var resp Health
+	if err := c.get(ctx, "/api/v1/health", &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// Diagnostics returns the processors and language servers of the server.
+// It needs an admin tenant's key when tenancy is enabled.
+func (c *Client) Diagnostics(ctx context.Context) (*Diagnostics, error) {
+	var resp Diagnostics
+	if err := c.get(ctx, "/api/v1/admin/diagnostics", &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
@@ -0,0 +1,279 @@
+// Package client is a typed Go client for the codeapi HTTP API. Requests and
+// responses use the same types as the server, so a field added to an endpoint
+// is available to callers without touching the client.
+//
+//	c := client.New("http://localhost:8181", client.WithAPIKey(key))
+//	graph, err := c.GetCallers(ctx, &client.GetCallGraphRequest{RepoName: "api", FunctionName: "Save"})
+//
+// Reads are retried on network errors and on 429, 502, 503 and 504
+// responses. Requests that change state are only retried on 429 and 503,
+// which the server sends before it starts the work.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Default retry and paging settings
+const (
+	DefaultMaxAttempts = 3
+	DefaultBackoff     = 500 * time.Millisecond
+	DefaultPageSize    = 100
+
+	// maxBackoff caps the wait between two attempts, including waits asked
+	// for by Retry-After
+	maxBackoff = 30 * time.Second
+)
+
+// Client calls one codeapi server. It is safe for concurrent use.
+type Client struct {
+	baseURL     string
+	httpClient  *http.Client
+	headers     http.Header
+	maxAttempts int
+	backoff     time.Duration
+	pageSize    int
+}
+
+// Option configures a Client
+type Option func(*Client)
+
+// WithAPIKey authenticates requests with "Authorization: Bearer <key>",
+// which the server accepts whatever tenancy.header is set to
+func WithAPIKey(key string) Option {
+	return func(c *Client) {
+		c.headers.Set("Authorization", "Bearer "+key)
+	}
+}
+
+// WithHeader sets a header on every request, such as the API key header of a
+// server that sets tenancy.header
+func WithHeader(name, value string) Option {
+	return func(c *Client) {
+		c.headers.Set(name, value)
+	}
+}
+
+// WithHTTPClient sends requests through httpClient instead of a client with
+// a 60 second timeout
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithRetry sets how many times a request is attempted and the backoff
+// before the first retry, which doubles for every further one. One attempt
+// turns retries off.
+func WithRetry(maxAttempts int, backoff time.Duration) Option {
+	return func(c *Client) {
+		c.maxAttempts = max(maxAttempts, 1)
+		c.backoff = backoff
+	}
+}
+
+// WithPageSize sets the page size the iterators request when the request
+// has no limit of its own
+func WithPageSize(n int) Option {
+	return func(c *Client) {
+		if n > 0 {
+			c.pageSize = n
+		}
+	}
+}
+
+// New returns a client for the server at baseURL, e.g.
+// "http://localhost:8181"
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		baseURL:     strings.TrimRight(baseURL, "/"),
+		httpClient:  &http.Client{Timeout: 60 * time.Second},
+		headers:     make(http.Header),
+		maxAttempts: DefaultMaxAttempts,
+		backoff:     DefaultBackoff,
+		pageSize:    DefaultPageSize,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// APIError is an error response of the server
+type APIError struct {
+	StatusCode int
+	// Message is the "error" field of the response, or "message" for the
+	// endpoints answering with that
+	Message string
+	Details string
+	// RequestID identifies the request in the server logs; quote it when
+	// reporting a failure
+	RequestID string
+}
+
+func (e *APIError) Error() string {
+	msg := e.Message
+	if msg == "" {
+		msg = http.StatusText(e.StatusCode)
+	}
+	if e.Details != "" {
+		msg += ": " + e.Details
+	}
+	if e.RequestID != "" {
+		msg += " (request " + e.RequestID + ")"
+	}
+	return fmt.Sprintf("codeapi: %d %s", e.StatusCode, msg)
+}
+
+// IsNotFound reports whether err is a 404 response
+func IsNotFound(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound
+}
+
+// IsConflict reports whether err is a 409 response, which the server sends
+// while a repository is locked by another index, clean or restore run
+func IsConflict(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusConflict
+}
+
+// retry says which failures of a request may be retried
+type retry int
+
+const (
+	// retryRead retries network errors and all retryable statuses
+	retryRead retry = iota
+	// retryWrite retries only statuses sent before the request was handled
+	retryWrite
+)
+
+func (r retry) status(code int) bool {
+	switch code {
+	case http.StatusTooManyRequests, http.StatusServiceUnavailable:
+		return true
+	case http.StatusBadGateway, http.StatusGatewayTimeout:
+		return r == retryRead
+	}
+	return false
+}
+
+// post sends body as JSON and decodes the response into out
+func (c *Client) post(ctx context.Context, path string, body, out any, r retry) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to encode request: %w", err)
+	}
+	return c.do(ctx, http.MethodPost, path, payload, out, r)
+}
+
+// get fetches path and decodes the response into out
+func (c *Client) get(ctx context.Context, path string, out any) error {
+	return c.do(ctx, http.MethodGet, path, nil, out, retryRead)
+}
+
+func (c *Client) do(ctx context.Context, method, path string, payload []byte, out any, r retry) error {
+	backoff := c.backoff
+	for attempt := 1; ; attempt++ {
+		wait, err := c.attempt(ctx, method, path, payload, out, r)
+		if err == nil || wait < 0 || attempt >= c.maxAttempts {
+			return err
+		}
+		if wait == 0 {
+			wait = backoff + rand.N(backoff/2+1)
+			backoff *= 2
+		}
+		timer := time.NewTimer(min(wait, maxBackoff))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// attempt sends the request once. On failure it returns how long to wait
+// before retrying: 0 for the regular backoff, negative when the failure
+// must not be retried.
+func (c *Client) attempt(ctx context.Context, method, path string, payload []byte, out any, r retry) (time.Duration, error) {
+	var body io.Reader
+	if payload != nil {
+		body = bytes.NewReader(payload)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, body)
+	if err != nil {
+		return -1, err
+	}
+	for name, values := range c.headers {
+		req.Header[name] = values
+	}
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		if ctx.Err() != nil || r != retryRead {
+			return -1, err
+		}
+		return 0, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return -1, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if resp.StatusCode >= 300 {
+		apiErr := &APIError{StatusCode: resp.StatusCode, RequestID: resp.Header.Get("X-Request-ID")}
+		var fields struct {
+			Error     string `json:"error"`
+			Message   string `json:"message"`
+			Details   string `json:"details"`
+			RequestID string `json:"request_id"`
+		}
+		if json.Unmarshal(data, &fields) == nil {
+			apiErr.Message = fields.Error
+			if apiErr.Message == "" {
+				apiErr.Message = fields.Message
+			}
+			apiErr.Details = fields.Details
+			if fields.RequestID != "" {
+				apiErr.RequestID = fields.RequestID
+			}
+		}
+		if !r.status(resp.StatusCode) {
+			return -1, apiErr
+		}
+		return retryAfter(resp.Header.Get("Retry-After")), apiErr
+	}
+
+	if out == nil {
+		return -1, nil
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return -1, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return -1, nil
+}
+
+// retryAfter parses a Retry-After header given in seconds, 0 when absent
+func retryAfter(header string) time.Duration {
+	seconds, err := strconv.Atoi(header)
+	if err != nil || seconds <= 0 {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
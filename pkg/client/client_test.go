@@ -0,0 +1,204 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTestClient serves handler and returns a client for it that retries
+// without waiting
+func newTestClient(t *testing.T, handler http.HandlerFunc, opts ...Option) *Client {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return New(server.URL, append([]Option{WithRetry(3, time.Millisecond)}, opts...)...)
+}
+
+func TestClientSendsRequest(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/codeapi/v1/callers" || r.Method != http.MethodPost {
+			t.Errorf("request = %s %s", r.Method, r.URL.Path)
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer key-1" {
+			t.Errorf("Authorization = %q", got)
+		}
+		var req GetCallGraphRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatal(err)
+		}
+		if req.RepoName != "api" || req.FunctionID == nil || req.FunctionID.ClassName != "Store" || req.FunctionID.FunctionName != "Save" {
+			t.Errorf("request body = %+v, function %+v", req, req.FunctionID)
+		}
+		w.Write([]byte(`{"call_graph":{"root_id":7,"truncated":true}}`))
+	}, WithAPIKey("key-1"))
+
+	graph, err := c.GetCallers(context.Background(), &GetCallGraphRequest{
+		RepoName:   "api",
+		FunctionID: &FlexibleFunctionID{ClassName: "Store", FunctionName: "Save"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if graph == nil || !graph.Truncated {
+		t.Errorf("GetCallers() = %+v", graph)
+	}
+}
+
+func TestClientErrors(t *testing.T) {
+	tests := []struct {
+		name         string
+		status       int
+		body         string
+		write        bool
+		wantAttempts int32
+		wantMessage  string
+		wantRequest  string
+	}{
+		{name: "error field", status: http.StatusBadRequest, body: `{"error":"repo_name is required"}`, wantAttempts: 1, wantMessage: "repo_name is required"},
+		{name: "message field", status: http.StatusNotFound, body: `{"success":false,"message":"no such chunk"}`, wantAttempts: 1, wantMessage: "no such chunk"},
+		{name: "read retried on 502", status: http.StatusBadGateway, wantAttempts: 3},
+		{name: "write not retried on 502", status: http.StatusBadGateway, write: true, wantAttempts: 1},
+		{name: "write retried on 503", status: http.StatusServiceUnavailable, body: `{"error":"Database not available"}`, write: true, wantAttempts: 3, wantMessage: "Database not available"},
+		{name: "request id", status: http.StatusNotFound, body: `{"error":"Repository not found","request_id":"abc-123"}`, wantAttempts: 1, wantMessage: "Repository not found", wantRequest: "abc-123"},
+		{name: "conflict not retried", status: http.StatusConflict, body: `{"error":"repository is locked"}`, write: true, wantAttempts: 1, wantMessage: "repository is locked"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var attempts atomic.Int32
+			c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				attempts.Add(1)
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			})
+
+			var err error
+			if tt.write {
+				_, err = c.BuildIndex(context.Background(), &BuildIndexRequest{RepoName: "api"})
+			} else {
+				_, err = c.ListRepos(context.Background())
+			}
+			var apiErr *APIError
+			if !errors.As(err, &apiErr) {
+				t.Fatalf("error = %v, want *APIError", err)
+			}
+			if apiErr.StatusCode != tt.status || apiErr.Message != tt.wantMessage || apiErr.RequestID != tt.wantRequest {
+				t.Errorf("APIError = %+v", apiErr)
+			}
+			if got := attempts.Load(); got != tt.wantAttempts {
+				t.Errorf("attempts = %d, want %d", got, tt.wantAttempts)
+			}
+		})
+	}
+}
+
+func TestClientRetrySucceeds(t *testing.T) {
+	var attempts atomic.Int32
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if attempts.Add(1) == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Write([]byte(`{"repos":["api","web"]}`))
+	})
+
+	repos, err := c.ListRepos(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(repos) != 2 || attempts.Load() != 2 {
+		t.Errorf("ListRepos() = %v after %d attempts", repos, attempts.Load())
+	}
+}
+
+func TestFilesIterator(t *testing.T) {
+	var offsets []int
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		var req ListFilesRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		offsets = append(offsets, req.Offset)
+		if req.Limit != 2 {
+			t.Errorf("limit = %d, want 2", req.Limit)
+		}
+		switch req.Offset {
+		case 0:
+			w.Write([]byte(`{"files":[{"path":"a.go"},{"path":"b.go"}]}`))
+		case 2:
+			w.Write([]byte(`{"files":[{"path":"c.go"}]}`))
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}, WithPageSize(2))
+
+	var paths []string
+	for file, err := range c.Files(context.Background(), ListFilesRequest{RepoName: "api"}) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, file.Path)
+	}
+	if len(paths) != 3 || paths[2] != "c.go" {
+		t.Errorf("files = %v", paths)
+	}
+	if len(offsets) != 2 {
+		t.Errorf("requested offsets %v, want [0 2]", offsets)
+	}
+}
+
+func TestIteratorStopsOnError(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error":"bad request"}`))
+	})
+
+	var errs int
+	for note, err := range c.Notes(context.Background(), ListNotesRequest{RepoName: "api"}) {
+		if err == nil {
+			t.Errorf("unexpected note %+v", note)
+			continue
+		}
+		errs++
+	}
+	if errs != 1 {
+		t.Errorf("yielded %d errors, want 1", errs)
+	}
+}
+
+func TestFunctionDependencyPages(t *testing.T) {
+	var cursors []string
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		var req GetFunctionDependenciesRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		cursors = append(cursors, req.Cursor)
+		switch req.Cursor {
+		case "":
+			w.Write([]byte(`{"roots":[{"name":"main"}],"functions":[{"name":"run"}],"edges":[],"partial":true,"cursor":"c1"}`))
+		case "c1":
+			w.Write([]byte(`{"roots":[],"functions":[{"name":"serve"}],"edges":[]}`))
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	})
+
+	var functions []string
+	for page, err := range c.FunctionDependencyPages(context.Background(), GetFunctionDependenciesRequest{RepoName: "api", TimeBudgetMs: 500}) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, fn := range page.Functions {
+			functions = append(functions, fn.Name)
+		}
+	}
+	if len(functions) != 2 || functions[1] != "serve" {
+		t.Errorf("functions = %v, want [run serve]", functions)
+	}
+	if len(cursors) != 2 || cursors[1] != "c1" {
+		t.Errorf("requested cursors %q, want [\"\" c1]", cursors)
+	}
+}
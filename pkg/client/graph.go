@@ -0,0 +1,315 @@
+package client
+
+import (
+	"context"
+	"net/url"
+)
+
+// ListRepos returns the repositories in the code graph the caller may see
+func (c *Client) ListRepos(ctx context.Context) ([]string, error) {
+	var resp ListReposResponse
+	if err := c.get(ctx, "/codeapi/v1/repos", &resp); err != nil {
+		return nil, err
+	}
+	return resp.Repos, nil
+}
+
+// ListFiles returns one page of the files of a repository. Files iterates
+// over all of them.
+func (c *Client) ListFiles(ctx context.Context, req *ListFilesRequest) ([]*FileInfo, error) {
+	var resp struct {
+		Files []*FileInfo `json:"files"`
+	}
+	err := c.post(ctx, "/codeapi/v1/files", req, &resp, retryRead)
+	return resp.Files, err
+}
+
+// ListClasses returns one page of the classes of a repository
+func (c *Client) ListClasses(ctx context.Context, req *ListClassesRequest) ([]*ClassInfo, error) {
+	return c.classes(ctx, "/codeapi/v1/classes", req)
+}
+
+// FindClasses returns one page of the classes matching a filter
+func (c *Client) FindClasses(ctx context.Context, req *FindClassesRequest) ([]*ClassInfo, error) {
+	return c.classes(ctx, "/codeapi/v1/classes/find", req)
+}
+
+func (c *Client) classes(ctx context.Context, path string, req any) ([]*ClassInfo, error) {
+	var resp struct {
+		Classes []*ClassInfo `json:"classes"`
+	}
+	err := c.post(ctx, path, req, &resp, retryRead)
+	return resp.Classes, err
+}
+
+// ListMethods returns one page of the methods of a repository
+func (c *Client) ListMethods(ctx context.Context, req *ListMethodsRequest) ([]*MethodInfo, error) {
+	return c.methods(ctx, "/codeapi/v1/methods", "methods", req)
+}
+
+// ListFunctions returns one page of the free functions of a repository.
+// The request type is shared with ListMethods.
+func (c *Client) ListFunctions(ctx context.Context, req *ListMethodsRequest) ([]*MethodInfo, error) {
+	return c.methods(ctx, "/codeapi/v1/functions", "functions", req)
+}
+
+// FindMethods returns one page of the methods matching a filter
+func (c *Client) FindMethods(ctx context.Context, req *FindMethodsRequest) ([]*MethodInfo, error) {
+	return c.methods(ctx, "/codeapi/v1/methods/find", "methods", req)
+}
+
+// GetClassMethods returns the methods of a class
+func (c *Client) GetClassMethods(ctx context.Context, req *GetClassRequest) ([]*MethodInfo, error) {
+	return c.methods(ctx, "/codeapi/v1/class/methods", "methods", req)
+}
+
+// methods posts req and returns the methods under key of the response
+func (c *Client) methods(ctx context.Context, path, key string, req any) ([]*MethodInfo, error) {
+	var resp map[string][]*MethodInfo
+	err := c.post(ctx, path, req, &resp, retryRead)
+	return resp[key], err
+}
+
+// GetClass returns a class, with its methods and fields when requested
+func (c *Client) GetClass(ctx context.Context, req *GetClassRequest) (*ClassInfo, error) {
+	var resp struct {
+		Class *ClassInfo `json:"class"`
+	}
+	err := c.post(ctx, "/codeapi/v1/class", req, &resp, retryRead)
+	return resp.Class, err
+}
+
+// GetMethod returns a method
+func (c *Client) GetMethod(ctx context.Context, req *GetMethodRequest) (*MethodInfo, error) {
+	var resp struct {
+		Method *MethodInfo `json:"method"`
+	}
+	err := c.post(ctx, "/codeapi/v1/method", req, &resp, retryRead)
+	return resp.Method, err
+}
+
+// GetClassFields returns the fields of a class
+func (c *Client) GetClassFields(ctx context.Context, req *GetClassRequest) ([]*FieldInfo, error) {
+	var resp struct {
+		Fields []*FieldInfo `json:"fields"`
+	}
+	err := c.post(ctx, "/codeapi/v1/class/fields", req, &resp, retryRead)
+	return resp.Fields, err
+}
+
+// GetCallGraph returns the call graph around a function in the requested
+// direction
+func (c *Client) GetCallGraph(ctx context.Context, req *GetCallGraphRequest) (*CallGraph, error) {
+	return c.callGraph(ctx, "/codeapi/v1/callgraph", req)
+}
+
+// GetCallers returns the functions calling a function
+func (c *Client) GetCallers(ctx context.Context, req *GetCallGraphRequest) (*CallGraph, error) {
+	return c.callGraph(ctx, "/codeapi/v1/callers", req)
+}
+
+// GetCallees returns the functions a function calls
+func (c *Client) GetCallees(ctx context.Context, req *GetCallGraphRequest) (*CallGraph, error) {
+	return c.callGraph(ctx, "/codeapi/v1/callees", req)
+}
+
+func (c *Client) callGraph(ctx context.Context, path string, req *GetCallGraphRequest) (*CallGraph, error) {
+	var resp struct {
+		CallGraph *CallGraph `json:"call_graph"`
+	}
+	err := c.post(ctx, path, req, &resp, retryRead)
+	return resp.CallGraph, err
+}
+
+// GetDataDependents returns what depends on the value of a variable
+func (c *Client) GetDataDependents(ctx context.Context, req *GetDataDependentsRequest) (*DependencyGraph, error) {
+	return c.dependencyGraph(ctx, "/codeapi/v1/data/dependents", req)
+}
+
+// GetDataSources returns where the value of a variable comes from
+func (c *Client) GetDataSources(ctx context.Context, req *GetDataDependentsRequest) (*DependencyGraph, error) {
+	return c.dependencyGraph(ctx, "/codeapi/v1/data/sources", req)
+}
+
+func (c *Client) dependencyGraph(ctx context.Context, path string, req *GetDataDependentsRequest) (*DependencyGraph, error) {
+	var resp struct {
+		DependencyGraph *DependencyGraph `json:"dependency_graph"`
+	}
+	err := c.post(ctx, path, req, &resp, retryRead)
+	return resp.DependencyGraph, err
+}
+
+// GetImpact returns what a change to a function, class, field or variable
+// affects
+func (c *Client) GetImpact(ctx context.Context, req *GetImpactRequest) (*ImpactResult, error) {
+	var resp struct {
+		Impact *ImpactResult `json:"impact"`
+	}
+	err := c.post(ctx, "/codeapi/v1/impact", req, &resp, retryRead)
+	return resp.Impact, err
+}
+
+// GetInheritanceTree returns the parents and children of a class
+func (c *Client) GetInheritanceTree(ctx context.Context, req *GetClassRequest) (*InheritanceTree, error) {
+	var resp struct {
+		InheritanceTree *InheritanceTree `json:"inheritance_tree"`
+	}
+	err := c.post(ctx, "/codeapi/v1/inheritance", req, &resp, retryRead)
+	return resp.InheritanceTree, err
+}
+
+// GetClassHierarchy returns the classes of a repository as inheritance
+// trees, optionally limited to a package or path prefix
+func (c *Client) GetClassHierarchy(ctx context.Context, repoName, packagePrefix, pathPrefix string) (*ClassHierarchy, error) {
+	query := url.Values{}
+	if packagePrefix != "" {
+		query.Set("package", packagePrefix)
+	}
+	if pathPrefix != "" {
+		query.Set("path", pathPrefix)
+	}
+	path := "/codeapi/v1/repos/" + url.PathEscape(repoName) + "/classes"
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+
+	var resp struct {
+		Hierarchy *ClassHierarchy `json:"hierarchy"`
+	}
+	err := c.get(ctx, path, &resp)
+	return resp.Hierarchy, err
+}
+
+// GetInjectionWiring returns the dependencies injected into a class and
+// where it is injected
+func (c *Client) GetInjectionWiring(ctx context.Context, req *GetClassRequest) (*InjectionWiring, error) {
+	var resp struct {
+		InjectionWiring *InjectionWiring `json:"injection_wiring"`
+	}
+	err := c.post(ctx, "/codeapi/v1/injection", req, &resp, retryRead)
+	return resp.InjectionWiring, err
+}
+
+// GetFieldAccessors returns the methods reading or writing a field
+func (c *Client) GetFieldAccessors(ctx context.Context, req *FieldAccessorsRequest) (*FieldAccessResult, error) {
+	var resp struct {
+		FieldAccessors *FieldAccessResult `json:"field_accessors"`
+	}
+	err := c.post(ctx, "/codeapi/v1/field/accessors", req, &resp, retryRead)
+	return resp.FieldAccessors, err
+}
+
+// GetTableAccessors returns the code reading or writing a database table
+func (c *Client) GetTableAccessors(ctx context.Context, req *TableAccessorsRequest) (*TableAccessResult, error) {
+	var resp struct {
+		TableAccessors *TableAccessResult `json:"table_accessors"`
+	}
+	err := c.post(ctx, "/codeapi/v1/table/accessors", req, &resp, retryRead)
+	return resp.TableAccessors, err
+}
+
+// GetConfigUsages returns where a configuration key is defined and read
+func (c *Client) GetConfigUsages(ctx context.Context, req *ConfigUsagesRequest) (*ConfigUsageResult, error) {
+	var resp struct {
+		ConfigUsages *ConfigUsageResult `json:"config_usages"`
+	}
+	err := c.post(ctx, "/codeapi/v1/config/usages", req, &resp, retryRead)
+	return resp.ConfigUsages, err
+}
+
+// ListFeatureFlags returns the feature flags of a repository and the calls
+// checking them
+func (c *Client) ListFeatureFlags(ctx context.Context, req *FeatureFlagsRequest) ([]*FeatureFlagInfo, error) {
+	var resp struct {
+		FeatureFlags []*FeatureFlagInfo `json:"feature_flags"`
+	}
+	err := c.post(ctx, "/codeapi/v1/flags", req, &resp, retryRead)
+	return resp.FeatureFlags, err
+}
+
+// GetSinkPaths returns the call paths from HTTP handlers to
+// security-sensitive calls
+func (c *Client) GetSinkPaths(ctx context.Context, req *SinkPathsRequest) ([]*SinkPath, error) {
+	var resp struct {
+		SinkPaths []*SinkPath `json:"sink_paths"`
+	}
+	err := c.post(ctx, "/codeapi/v1/security/sink-paths", req, &resp, retryRead)
+	return resp.SinkPaths, err
+}
+
+// GetEndpointLineage returns the tables, services and external APIs each
+// HTTP endpoint of a repository reaches
+func (c *Client) GetEndpointLineage(ctx context.Context, req *EndpointLineageRequest) ([]*EndpointLineage, error) {
+	var resp struct {
+		Endpoints []*EndpointLineage `json:"endpoints"`
+	}
+	err := c.post(ctx, "/codeapi/v1/lineage/endpoints", req, &resp, retryRead)
+	return resp.Endpoints, err
+}
+
+// ListComplexFunctions returns functions ranked by a complexity or size
+// metric
+func (c *Client) ListComplexFunctions(ctx context.Context, req *ComplexFunctionsRequest) ([]*FunctionMetricsInfo, error) {
+	var resp struct {
+		Functions []*FunctionMetricsInfo `json:"functions"`
+	}
+	err := c.post(ctx, "/codeapi/v1/metrics/complex-functions", req, &resp, retryRead)
+	return resp.Functions, err
+}
+
+// ListHotspots returns functions that are both complex and often changed
+func (c *Client) ListHotspots(ctx context.Context, req *HotspotsRequest) ([]*FunctionHotspot, error) {
+	var resp struct {
+		Hotspots []*FunctionHotspot `json:"hotspots"`
+	}
+	err := c.post(ctx, "/codeapi/v1/metrics/hotspots", req, &resp, retryRead)
+	return resp.Hotspots, err
+}
+
+// ListPackageCoupling returns packages ranked by their fan-in or fan-out
+func (c *Client) ListPackageCoupling(ctx context.Context, req *PackageCouplingRequest) ([]*PackageMetricsInfo, error) {
+	var resp struct {
+		Packages []*PackageMetricsInfo `json:"packages"`
+	}
+	err := c.post(ctx, "/codeapi/v1/metrics/packages", req, &resp, retryRead)
+	return resp.Packages, err
+}
+
+// ExecuteCypher runs a read-only Cypher query. It needs an admin tenant's
+// key when tenancy is enabled.
+func (c *Client) ExecuteCypher(ctx context.Context, req *ExecuteCypherRequest) ([]map[string]any, error) {
+	return c.cypher(ctx, "/codeapi/v1/cypher", req, retryRead)
+}
+
+// ExecuteCypherWrite runs a Cypher query that may change the graph. It is
+// not retried on network errors, the query may have run.
+func (c *Client) ExecuteCypherWrite(ctx context.Context, req *ExecuteCypherRequest) ([]map[string]any, error) {
+	return c.cypher(ctx, "/codeapi/v1/cypher/write", req, retryWrite)
+}
+
+func (c *Client) cypher(ctx context.Context, path string, req *ExecuteCypherRequest, r retry) ([]map[string]any, error) {
+	var resp struct {
+		Results []map[string]any `json:"results"`
+	}
+	err := c.post(ctx, path, req, &resp, r)
+	return resp.Results, err
+}
+
+// GetCodeSnippet returns lines of a file
+func (c *Client) GetCodeSnippet(ctx context.Context, req *GetCodeSnippetRequest) (*GetCodeSnippetResponse, error) {
+	var resp GetCodeSnippetResponse
+	if err := c.post(ctx, "/codeapi/v1/snippet", req, &resp, retryRead); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// AnalyzeDiff returns the functions a diff changes and what calls them
+func (c *Client) AnalyzeDiff(ctx context.Context, repoName string, req *AnalyzeDiffRequest) (*AnalyzeDiffResponse, error) {
+	var resp AnalyzeDiffResponse
+	path := "/codeapi/v1/repos/" + url.PathEscape(repoName) + "/analyze-diff"
+	if err := c.post(ctx, path, req, &resp, retryRead); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
@@ -0,0 +1,136 @@
+package client
+
+import (
+	"context"
+	"iter"
+)
+
+// The iterators below walk offset-paginated endpoints page by page, so a
+// caller can range over every file, class or note of a repository without
+// keeping track of offsets. They request the limit of the request, or the
+// client's page size when it has none, starting at its offset. Iteration
+// stops at the first error, which is yielded with a zero value.
+
+// paginate yields the items of the pages fetch returns until a page comes
+// back short
+func paginate[T any](limit int, fetch func(limit, offset int) ([]T, error), offset int) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		for {
+			page, err := fetch(limit, offset)
+			if err != nil {
+				var zero T
+				yield(zero, err)
+				return
+			}
+			for _, item := range page {
+				if !yield(item, nil) {
+					return
+				}
+			}
+			if len(page) < limit {
+				return
+			}
+			offset += len(page)
+		}
+	}
+}
+
+func (c *Client) limit(n int) int {
+	if n > 0 {
+		return n
+	}
+	return c.pageSize
+}
+
+// Files iterates over the files of a repository
+func (c *Client) Files(ctx context.Context, req ListFilesRequest) iter.Seq2[*FileInfo, error] {
+	return paginate(c.limit(req.Limit), func(limit, offset int) ([]*FileInfo, error) {
+		req.Limit, req.Offset = limit, offset
+		return c.ListFiles(ctx, &req)
+	}, req.Offset)
+}
+
+// Classes iterates over the classes of a repository
+func (c *Client) Classes(ctx context.Context, req ListClassesRequest) iter.Seq2[*ClassInfo, error] {
+	return paginate(c.limit(req.Limit), func(limit, offset int) ([]*ClassInfo, error) {
+		req.Limit, req.Offset = limit, offset
+		return c.ListClasses(ctx, &req)
+	}, req.Offset)
+}
+
+// Methods iterates over the methods of a repository
+func (c *Client) Methods(ctx context.Context, req ListMethodsRequest) iter.Seq2[*MethodInfo, error] {
+	return paginate(c.limit(req.Limit), func(limit, offset int) ([]*MethodInfo, error) {
+		req.Limit, req.Offset = limit, offset
+		return c.ListMethods(ctx, &req)
+	}, req.Offset)
+}
+
+// Functions iterates over the free functions of a repository
+func (c *Client) Functions(ctx context.Context, req ListMethodsRequest) iter.Seq2[*MethodInfo, error] {
+	return paginate(c.limit(req.Limit), func(limit, offset int) ([]*MethodInfo, error) {
+		req.Limit, req.Offset = limit, offset
+		return c.ListFunctions(ctx, &req)
+	}, req.Offset)
+}
+
+// FindAllClasses iterates over the classes matching a filter
+func (c *Client) FindAllClasses(ctx context.Context, req FindClassesRequest) iter.Seq2[*ClassInfo, error] {
+	return paginate(c.limit(req.Limit), func(limit, offset int) ([]*ClassInfo, error) {
+		req.Limit, req.Offset = limit, offset
+		return c.FindClasses(ctx, &req)
+	}, req.Offset)
+}
+
+// FindAllMethods iterates over the methods matching a filter
+func (c *Client) FindAllMethods(ctx context.Context, req FindMethodsRequest) iter.Seq2[*MethodInfo, error] {
+	return paginate(c.limit(req.Limit), func(limit, offset int) ([]*MethodInfo, error) {
+		req.Limit, req.Offset = limit, offset
+		return c.FindMethods(ctx, &req)
+	}, req.Offset)
+}
+
+// Notes iterates over the notes of a repository in file and line order
+func (c *Client) Notes(ctx context.Context, req ListNotesRequest) iter.Seq2[Note, error] {
+	return paginate(c.limit(req.Limit), func(limit, offset int) ([]Note, error) {
+		req.Limit, req.Offset = limit, offset
+		resp, err := c.ListNotes(ctx, &req)
+		if err != nil {
+			return nil, err
+		}
+		return resp.Notes, nil
+	}, req.Offset)
+}
+
+// AuditEntries iterates over the audit entries of a repository, newest
+// first
+func (c *Client) AuditEntries(ctx context.Context, req AuditLogRequest) iter.Seq2[AuditEntry, error] {
+	return paginate(c.limit(req.Limit), func(limit, offset int) ([]AuditEntry, error) {
+		req.Limit, req.Offset = limit, offset
+		resp, err := c.ListAuditLog(ctx, &req)
+		if err != nil {
+			return nil, err
+		}
+		return resp.Entries, nil
+	}, req.Offset)
+}
+
+// FunctionDependencyPages yields the pages of a time-boxed function
+// dependency traversal, following the cursor of each partial page. The first
+// page holds the roots; later ones only the calls found since. Set
+// req.TimeBudgetMs to bound each page.
+func (c *Client) FunctionDependencyPages(ctx context.Context, req GetFunctionDependenciesRequest) iter.Seq2[*FunctionDependencies, error] {
+	return func(yield func(*FunctionDependencies, error) bool) {
+		for {
+			page, err := c.GetFunctionDependencies(ctx, &req)
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+			if !yield(page, nil) || page.Cursor == "" {
+				return
+			}
+			req.Cursor = page.Cursor
+		}
+	}
+}
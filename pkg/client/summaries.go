@@ -0,0 +1,54 @@
+package client
+
+import (
+	"context"
+	"net/url"
+)
+
+// GetFileSummaries returns the stored summaries of the entities of a file
+func (c *Client) GetFileSummaries(ctx context.Context, req *GetFileSummariesRequest) (*GetFileSummariesResponse, error) {
+	var resp GetFileSummariesResponse
+	if err := c.post(ctx, "/codeapi/v1/summaries/file", req, &resp, retryRead); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// GetFileSummary returns the summary of a file itself
+func (c *Client) GetFileSummary(ctx context.Context, req *GetFileSummaryRequest) (*CodeSummary, error) {
+	var resp CodeSummary
+	if err := c.post(ctx, "/codeapi/v1/summaries/file/summary", req, &resp, retryRead); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// GetEntitySummary returns the summary of a function or class
+func (c *Client) GetEntitySummary(ctx context.Context, req *GetEntitySummaryRequest) (*CodeSummary, error) {
+	var resp CodeSummary
+	if err := c.post(ctx, "/codeapi/v1/summaries/entity", req, &resp, retryRead); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// GetSummaryStats returns how many summaries a repository has per level
+func (c *Client) GetSummaryStats(ctx context.Context, req *GetSummaryStatsRequest) (*GetSummaryStatsResponse, error) {
+	var resp GetSummaryStatsResponse
+	if err := c.post(ctx, "/codeapi/v1/summaries/stats", req, &resp, retryRead); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// GetSummariesBatch returns the stored summaries of up to 500 entities and
+// the entities without one. With GenerateMissing the server generates the
+// missing ones in the background.
+func (c *Client) GetSummariesBatch(ctx context.Context, repoName string, req *BatchSummariesRequest) (*BatchSummariesResponse, error) {
+	var resp BatchSummariesResponse
+	path := "/codeapi/v1/repos/" + url.PathEscape(repoName) + "/summaries/batch"
+	if err := c.post(ctx, path, req, &resp, retryRead); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
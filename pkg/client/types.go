@@ -0,0 +1,152 @@
+package client
+
+import (
+	"github.com/armchr/codeapi/internal/codeapi"
+	"github.com/armchr/codeapi/internal/controller"
+	init_services "github.com/armchr/codeapi/internal/init"
+	"github.com/armchr/codeapi/internal/model"
+	"github.com/armchr/codeapi/internal/service/audit"
+	"github.com/armchr/codeapi/internal/service/notes"
+	"github.com/armchr/codeapi/internal/service/summary"
+)
+
+// Index jobs
+type (
+	BuildIndexRequest    = controller.BuildIndexRequest
+	BuildIndexResponse   = controller.BuildIndexResponse
+	IndexFileRequest     = controller.IndexFileRequest
+	IndexFileResponse    = controller.IndexFileResponse
+	PurgeSandboxRequest  = controller.PurgeSandboxRequest
+	PurgeSandboxResponse = controller.PurgeSandboxResponse
+	RepoJob              = controller.RepoJob
+	ListJobsResponse     = controller.ListJobsResponse
+
+	ProcessDirectoryRequest  = model.ProcessDirectoryRequest
+	ProcessDirectoryResponse = model.ProcessDirectoryResponse
+)
+
+// Search and chunks
+type (
+	SearchSimilarCodeRequest         = model.SearchSimilarCodeRequest
+	SearchSimilarCodeResponse        = model.SearchSimilarCodeResponse
+	SearchMethodsBySignatureRequest  = controller.SearchMethodsBySignatureRequest
+	SearchMethodsBySignatureResponse = controller.SearchMethodsBySignatureResponse
+	ChunkHierarchyRequest            = model.ChunkHierarchyRequest
+	ChunkHierarchyResponse           = model.ChunkHierarchyResponse
+	RemapChunksRequest               = model.RemapChunksRequest
+	RemapChunksResponse              = model.RemapChunksResponse
+	DuplicatesRequest                = model.DuplicatesRequest
+	DuplicatesResponse               = model.DuplicatesResponse
+
+	GetFunctionDependenciesRequest = model.GetFunctionDependenciesRequest
+	FunctionDependencies           = model.CallGraph
+	FunctionHistoryRequest         = controller.FunctionHistoryRequest
+	FunctionHistoryResponse        = controller.FunctionHistoryResponse
+	CompareReposRequest            = controller.CompareReposRequest
+	RepoComparison                 = controller.RepoComparison
+)
+
+// Notes, feedback and audit
+type (
+	ListNotesRequest          = controller.ListNotesRequest
+	ListNotesResponse         = controller.ListNotesResponse
+	SearchNotesRequest        = controller.SearchNotesRequest
+	SearchNotesResponse       = controller.SearchNotesResponse
+	RecordFeedbackRequest     = controller.RecordFeedbackRequest
+	FeedbackVote              = controller.FeedbackVote
+	AggregateFeedbackRequest  = controller.AggregateFeedbackRequest
+	AggregateFeedbackResponse = controller.AggregateFeedbackResponse
+	AuditLogRequest           = controller.AuditLogRequest
+	AuditLogResponse          = controller.AuditLogResponse
+
+	Note       = notes.CodeNote
+	AuditEntry = audit.Entry
+)
+
+// LSP proxy, repository stats and server state
+type (
+	LspPositionRequest   = controller.LspPositionRequest
+	LspLocation          = controller.LspLocation
+	LspHoverResponse     = controller.LspHoverResponse
+	RepoStats            = controller.RepoStats
+	FileOutline          = controller.FileOutline
+	ResolveEntityRequest = controller.ResolveEntityRequest
+	ResolvedEntity       = controller.ResolvedEntity
+	Diagnostics          = controller.Diagnostics
+	DependencyStatus     = init_services.DependencyStatus
+)
+
+// Code graph reads
+type (
+	ListReposResponse  = controller.ListReposResponse
+	ListFilesRequest   = controller.ListFilesRequest
+	ListClassesRequest = controller.ListClassesRequest
+	ListMethodsRequest = controller.ListMethodsRequest
+	FindClassesRequest = controller.FindClassesRequest
+	FindMethodsRequest = controller.FindMethodsRequest
+	GetClassRequest    = controller.GetClassRequest
+	GetMethodRequest   = controller.GetMethodRequest
+
+	FileInfo   = codeapi.FileInfo
+	ClassInfo  = codeapi.ClassInfo
+	MethodInfo = codeapi.MethodInfo
+	FieldInfo  = codeapi.FieldInfo
+)
+
+// Graph queries and analysis
+type (
+	GetCallGraphRequest      = controller.GetCallGraphRequest
+	FlexibleFunctionID       = controller.FlexibleFunctionID
+	GetDataDependentsRequest = controller.GetDataDependentsRequest
+	GetImpactRequest         = controller.GetImpactRequest
+	FieldAccessorsRequest    = controller.FieldAccessorsRequest
+	TableAccessorsRequest    = controller.TableAccessorsRequest
+	ConfigUsagesRequest      = controller.ConfigUsagesRequest
+	FeatureFlagsRequest      = controller.FeatureFlagsRequest
+	SinkPathsRequest         = controller.SinkPathsRequest
+	EndpointLineageRequest   = controller.EndpointLineageRequest
+	ComplexFunctionsRequest  = controller.ComplexFunctionsRequest
+	HotspotsRequest          = controller.HotspotsRequest
+	PackageCouplingRequest   = controller.PackageCouplingRequest
+	ExecuteCypherRequest     = controller.ExecuteCypherRequest
+	GetCodeSnippetRequest    = controller.GetCodeSnippetRequest
+	GetCodeSnippetResponse   = controller.GetCodeSnippetResponse
+	AnalyzeDiffRequest       = controller.AnalyzeDiffRequest
+	AnalyzeDiffResponse      = controller.AnalyzeDiffResponse
+
+	CallGraph           = codeapi.CallGraph
+	DependencyGraph     = codeapi.DependencyGraph
+	ImpactResult        = codeapi.ImpactResult
+	InheritanceTree     = codeapi.InheritanceTree
+	ClassHierarchy      = codeapi.ClassHierarchy
+	InjectionWiring     = codeapi.InjectionWiring
+	FieldAccessResult   = codeapi.FieldAccessResult
+	TableAccessResult   = codeapi.TableAccessResult
+	ConfigUsageResult   = codeapi.ConfigUsageResult
+	FeatureFlagInfo     = codeapi.FeatureFlagInfo
+	SinkPath            = codeapi.SinkPath
+	EndpointLineage     = codeapi.EndpointLineage
+	FunctionMetricsInfo = codeapi.FunctionMetricsInfo
+	FunctionHotspot     = codeapi.FunctionHotspot
+	PackageMetricsInfo  = codeapi.PackageMetricsInfo
+)
+
+// Summaries
+type (
+	GetFileSummariesRequest  = controller.GetFileSummariesRequest
+	GetFileSummariesResponse = controller.GetFileSummariesResponse
+	GetFileSummaryRequest    = controller.GetFileSummaryRequest
+	GetEntitySummaryRequest  = controller.GetEntitySummaryRequest
+	GetSummaryStatsRequest   = controller.GetSummaryStatsRequest
+	GetSummaryStatsResponse  = controller.GetSummaryStatsResponse
+	BatchSummariesRequest    = controller.BatchSummariesRequest
+	BatchSummariesResponse   = controller.BatchSummariesResponse
+	SummaryRef               = controller.SummaryRef
+	CodeSummary              = summary.CodeSummary
+)
+
+// Health is the state of the server and the stores it depends on
+type Health struct {
+	Status       string             `json:"status"` // "healthy" or "degraded"
+	Dependencies []DependencyStatus `json:"dependencies"`
+}
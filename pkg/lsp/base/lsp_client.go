@@ -20,12 +20,11 @@ type LSPClient interface {
 	GetDocumentSymbols(ctx context.Context, uri string) ([]interface{}, error)
 	GetCallHierarchy(ctx context.Context, uri string, fnName string, position Position, inbound bool) (*CallHierarchyIncomingOrgoingCalls, error)
 	GetHover(ctx context.Context, uri string, position Position) (*Hover, error)
+	GetDefinition(ctx context.Context, uri string, position Position) ([]Location, error)
+	GetReferences(ctx context.Context, uri string, position Position, includeDeclaration bool) ([]Location, error)
 	//GetFunctionsInFile(ctx context.Context, uri string) ([]model.Function, error)
 
 	/*
-		GetDefinition(ctx context.Context, uri, text string, position model.Position) (*model.Location, error)
-		GetReferences(ctx context.Context, uri, text string, position model.Position) ([]model.Location, error)
-
 		GetFunctionsInFile(ctx context.Context, request model.GetFunctionsInFileRequest) (*model.GetFunctionsInFileResponse, error)
 	*/
 }
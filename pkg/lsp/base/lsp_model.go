@@ -26,8 +26,19 @@ type InitializeParams struct {
 type ClientCapabilities struct {
 	TextDocument TextDocumentClientCapabilities `json:"textDocument"`
 	Workspace    WorkspaceClientCapabilities    `json:"workspace"`
+	General      GeneralClientCapabilities      `json:"general"`
 }
 
+// GeneralClientCapabilities announces the position encodings the client
+// understands. Only UTF-16 is offered, so servers supporting several never
+// pick one the client does not convert from.
+type GeneralClientCapabilities struct {
+	PositionEncodings []string `json:"positionEncodings,omitempty"`
+}
+
+// PositionEncodingUTF16 counts characters in UTF-16 code units, the LSP default
+const PositionEncodingUTF16 = "utf-16"
+
 type ServerCapabilities struct {
 	Capabilities map[string]interface{} `json:"capabilities"`
 	// TextDocumentSync        TextDocumentSyncOptions `json:"textDocumentSync,omitempty"`
@@ -652,3 +663,60 @@ func MapToCallHierarchyOutgoingCall(data map[string]interface{}) (*CallHierarchy
 func LocationToKey(loc *Location) string {
 	return fmt.Sprintf("%s:%d:%d-%d:%d", loc.URI, loc.Range.Start.Line, loc.Range.Start.Character, loc.Range.End.Line, loc.Range.End.Character)
 }
+
+// MapToLocations decodes the result of a definition or references request:
+// null, a Location, or an array of Locations or LocationLinks. A link is
+// reduced to its target, selecting the name rather than the whole
+// declaration when the server gives both. Malformed entries are skipped.
+func MapToLocations(result interface{}) []Location {
+	var items []interface{}
+	switch r := result.(type) {
+	case []interface{}:
+		items = r
+	case map[string]interface{}:
+		items = []interface{}{r}
+	}
+
+	locations := make([]Location, 0, len(items))
+	for _, item := range items {
+		data, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		uri, _ := data["uri"].(string)
+		rangeData := data["range"]
+		if target, ok := data["targetUri"].(string); ok {
+			uri = target
+			rangeData = data["targetSelectionRange"]
+			if rangeData == nil {
+				rangeData = data["targetRange"]
+			}
+		}
+		r, ok := mapToRange(rangeData)
+		if uri == "" || !ok {
+			continue
+		}
+		locations = append(locations, Location{URI: uri, Range: r})
+	}
+	return locations
+}
+
+func mapToRange(data interface{}) (Range, bool) {
+	rangeData, ok := data.(map[string]interface{})
+	if !ok {
+		return Range{}, false
+	}
+	start, ok1 := mapToPosition(rangeData["start"])
+	end, ok2 := mapToPosition(rangeData["end"])
+	return Range{Start: start, End: end}, ok1 && ok2
+}
+
+func mapToPosition(data interface{}) (Position, bool) {
+	positionData, ok := data.(map[string]interface{})
+	if !ok {
+		return Position{}, false
+	}
+	line, ok1 := positionData["line"].(float64)
+	character, ok2 := positionData["character"].(float64)
+	return Position{Line: int(line), Character: int(character)}, ok1 && ok2
+}
@@ -0,0 +1,44 @@
+package base
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestMapToLocations(t *testing.T) {
+	at := func(uri string, l1, c1, l2, c2 int) Location {
+		return Location{URI: uri, Range: Range{Start: Position{Line: l1, Character: c1}, End: Position{Line: l2, Character: c2}}}
+	}
+	tests := []struct {
+		name   string
+		result string
+		want   []Location
+	}{
+		{"null", `null`, []Location{}},
+		{"single location", `{"uri": "file:///r/a.go", "range": {"start": {"line": 3, "character": 5}, "end": {"line": 3, "character": 9}}}`,
+			[]Location{at("file:///r/a.go", 3, 5, 3, 9)}},
+		{"location array", `[
+			{"uri": "file:///r/a.go", "range": {"start": {"line": 1, "character": 0}, "end": {"line": 1, "character": 4}}},
+			{"uri": "file:///r/b.go", "range": {"start": {"line": 7, "character": 2}, "end": {"line": 7, "character": 6}}}]`,
+			[]Location{at("file:///r/a.go", 1, 0, 1, 4), at("file:///r/b.go", 7, 2, 7, 6)}},
+		{"location link selects the name", `[{"originSelectionRange": {"start": {"line": 0, "character": 0}, "end": {"line": 0, "character": 1}},
+			"targetUri": "file:///r/c.go",
+			"targetRange": {"start": {"line": 10, "character": 0}, "end": {"line": 20, "character": 1}},
+			"targetSelectionRange": {"start": {"line": 10, "character": 5}, "end": {"line": 10, "character": 12}}}]`,
+			[]Location{at("file:///r/c.go", 10, 5, 10, 12)}},
+		{"malformed entry skipped", `[{"uri": "file:///r/a.go"}, {"uri": "file:///r/b.go", "range": {"start": {"line": 2, "character": 0}, "end": {"line": 2, "character": 3}}}]`,
+			[]Location{at("file:///r/b.go", 2, 0, 2, 3)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var result interface{}
+			if err := json.Unmarshal([]byte(tt.result), &result); err != nil {
+				t.Fatal(err)
+			}
+			if got := MapToLocations(result); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("MapToLocations() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
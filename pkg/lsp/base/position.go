@@ -0,0 +1,93 @@
+package base
+
+import (
+	"bytes"
+	"unicode/utf8"
+)
+
+// Positions exchanged with language servers count characters in UTF-16 code
+// units, the LSP default, while tree-sitter and Go strings count bytes. The
+// helpers below convert between the two so that ranges from the parsers and
+// from language servers compare equal on lines with non-ASCII text.
+
+// UTF16Len returns the length of s in UTF-16 code units. Invalid UTF-8 bytes
+// count as one unit each, like the replacement character they decode to.
+func UTF16Len(s []byte) int {
+	n := 0
+	for i := 0; i < len(s); {
+		if s[i] < utf8.RuneSelf {
+			n++
+			i++
+			continue
+		}
+		r, size := utf8.DecodeRune(s[i:])
+		if r >= 0x10000 {
+			n += 2
+		} else {
+			n++
+		}
+		i += size
+	}
+	return n
+}
+
+// UTF16ByteOffset returns the byte offset within line of UTF-16 column col.
+// A column past the end of the line, or inside a surrogate pair, is clamped
+// to the next character boundary.
+func UTF16ByteOffset(line []byte, col int) int {
+	i, units := 0, 0
+	for i < len(line) && units < col {
+		if line[i] < utf8.RuneSelf {
+			units++
+			i++
+			continue
+		}
+		r, size := utf8.DecodeRune(line[i:])
+		if r >= 0x10000 {
+			units += 2
+		} else {
+			units++
+		}
+		i += size
+	}
+	return i
+}
+
+// PositionAt returns the LSP position of the byte at offset in content,
+// given the row and byte column a parser reports for it. A carriage return
+// ending the line is not a character of the line for language servers, so
+// a position right after one is moved before it.
+func PositionAt(content []byte, offset, row, column int) Position {
+	lineStart := offset - column
+	if lineStart < 0 || offset > len(content) {
+		return Position{Line: row, Character: column}
+	}
+	prefix := content[lineStart:offset]
+	if n := len(prefix); n > 0 && prefix[n-1] == '\r' && (offset == len(content) || content[offset] == '\n') {
+		prefix = prefix[:n-1]
+	}
+	return Position{Line: row, Character: UTF16Len(prefix)}
+}
+
+// ByteOffsetAt returns the byte offset in content of LSP position pos. Lines
+// are separated by '\n', with a preceding '\r' belonging to the line break.
+// Positions past the end of a line or of content are clamped to it.
+func ByteOffsetAt(content []byte, pos Position) int {
+	lineStart := 0
+	for line := 0; line < pos.Line; line++ {
+		next := bytes.IndexByte(content[lineStart:], '\n')
+		if next < 0 {
+			return len(content)
+		}
+		lineStart += next + 1
+	}
+
+	lineEnd := len(content)
+	if next := bytes.IndexByte(content[lineStart:], '\n'); next >= 0 {
+		lineEnd = lineStart + next
+	}
+	if lineEnd > lineStart && content[lineEnd-1] == '\r' {
+		lineEnd--
+	}
+	return lineStart + UTF16ByteOffset(content[lineStart:lineEnd], pos.Character)
+}
@@ -0,0 +1,95 @@
+package base
+
+import "testing"
+
+func TestUTF16Len(t *testing.T) {
+	tests := []struct {
+		s    string
+		want int
+	}{
+		{"", 0},
+		{"name", 4},
+		{"café", 4},  // é is 2 bytes, 1 unit
+		{"日本", 2},    // 3 bytes each, 1 unit each
+		{"a😀b", 4},   // outside the BMP: 4 bytes, 2 units
+		{"\xffx", 2}, // invalid byte counts as one unit
+	}
+	for _, tt := range tests {
+		if got := UTF16Len([]byte(tt.s)); got != tt.want {
+			t.Errorf("UTF16Len(%q) = %d, want %d", tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestUTF16ByteOffset(t *testing.T) {
+	line := []byte("a😀é日z")
+	tests := []struct {
+		col  int
+		want int
+	}{
+		{0, 0},
+		{1, 1},
+		{2, 5}, // inside the surrogate pair: moves past the emoji
+		{3, 5},
+		{4, 7},
+		{5, 10},
+		{6, 11},
+		{99, 11},
+	}
+	for _, tt := range tests {
+		if got := UTF16ByteOffset(line, tt.col); got != tt.want {
+			t.Errorf("UTF16ByteOffset(col %d) = %d, want %d", tt.col, got, tt.want)
+		}
+	}
+}
+
+func TestPositionAt(t *testing.T) {
+	content := []byte("x := \"é\"; f()\r\n// 😀 note\r\nend")
+	tests := []struct {
+		name   string
+		offset int
+		row    int
+		column int
+		want   Position
+	}{
+		{"ascii", 0, 0, 0, Position{0, 0}},
+		{"after two-byte character", 9, 0, 9, Position{0, 8}},
+		{"line end before carriage return", 14, 0, 14, Position{0, 13}},
+		{"after carriage return", 15, 0, 15, Position{0, 13}},
+		{"after emoji", 23, 1, 7, Position{1, 5}},
+		{"last line", 33, 2, 3, Position{2, 3}},
+		{"without content", 4, 0, 4, Position{0, 4}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := content
+			if tt.name == "without content" {
+				src = nil
+			}
+			if got := PositionAt(src, tt.offset, tt.row, tt.column); got != tt.want {
+				t.Errorf("PositionAt(%d) = %+v, want %+v", tt.offset, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestByteOffsetAt(t *testing.T) {
+	content := []byte("x := \"é\"; f()\r\n// 😀 note\r\nend")
+	tests := []struct {
+		pos  Position
+		want int
+	}{
+		{Position{0, 0}, 0},
+		{Position{0, 8}, 9},
+		{Position{0, 13}, 14},
+		{Position{0, 40}, 14}, // clamped before the line break
+		{Position{1, 5}, 23},
+		{Position{2, 3}, 33},
+		{Position{9, 0}, 33},
+	}
+	for _, tt := range tests {
+		if got := ByteOffsetAt(content, tt.pos); got != tt.want {
+			t.Errorf("ByteOffsetAt(%+v) = %d, want %d", tt.pos, got, tt.want)
+		}
+	}
+}
@@ -49,7 +49,8 @@ func (fh *FileHolder) FindNameInLine(lspClient LSPClient, name string, line int)
 	namePart := lspClient.SymbolPartToMatch(name)
 	for i := 0; i <= len(lineStr)-len(namePart); i++ {
 		if lineStr[i:i+len(namePart)] == namePart {
-			return i
+			// The language server expects the column in UTF-16 units
+			return UTF16Len([]byte(lineStr[:i]))
 		}
 	}
 	return -1
@@ -4,17 +4,24 @@ import (
 	"bufio"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"os/exec"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"sync"
 	"sync/atomic"
+	"time"
 
+	"github.com/armchr/codeapi/internal/metrics"
+	"github.com/armchr/codeapi/internal/tracing"
 	"github.com/armchr/codeapi/internal/util"
 	"github.com/armchr/codeapi/pkg/lsp/base"
 
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
 	"go.uber.org/zap"
 )
 
@@ -29,6 +36,7 @@ type BaseClient struct {
 	pendingReqs map[int]chan *base.JSONRPCMessage
 	mu          *sync.Mutex
 	initialized bool
+	server      string // Language server binary, for metric labels
 	logger      *zap.Logger
 }
 
@@ -76,6 +84,7 @@ func NewBaseClient(command string, logger *zap.Logger, args ...string) (*BaseCli
 		mu:          &sync.Mutex{},
 		pendingReqs: make(map[int]chan *base.JSONRPCMessage),
 		fileHolders: make(map[string]*base.FileHolder),
+		server:      filepath.Base(command),
 		logger:      logger,
 	}
 
@@ -167,6 +176,18 @@ func (t *BaseClient) Shutdown(ctx context.Context) error {
 func (c *BaseClient) sendRequest(ctx context.Context, method string, params interface{}) (*base.JSONRPCMessage, error) {
 	id := int(atomic.AddInt64(&c.nextID, 1))
 	c.logger.Info("Sending LSP request", zap.String("method", method), zap.Int("id", id))
+	ctx, span := tracing.Start(ctx, "lsp.request",
+		attribute.String("server", c.server), attribute.String("method", method))
+	start := time.Now()
+	outcome := metrics.LSPOutcomeError
+	defer func() {
+		metrics.ObserveLSPRequest(c.server, method, outcome, time.Since(start))
+		span.SetAttributes(attribute.String("outcome", outcome))
+		if outcome != metrics.LSPOutcomeOK {
+			span.SetStatus(codes.Error, outcome)
+		}
+		span.End()
+	}()
 
 	req := base.JSONRPCMessage{
 		JSONRPC: "2.0",
@@ -206,9 +227,18 @@ func (c *BaseClient) sendRequest(ctx context.Context, method string, params inte
 			return nil, fmt.Errorf("RPC error %d: %s", resp.Error.Code, resp.Error.Message)
 		}
 		c.logger.Info("LSP request completed successfully", zap.String("method", method), zap.Int("id", id))
+		outcome = metrics.LSPOutcomeOK
 		return resp, nil
 	case <-ctx.Done():
 		c.logger.Warn("LSP request cancelled due to context timeout", zap.String("method", method), zap.Int("id", id), zap.Error(ctx.Err()))
+		outcome = metrics.LSPOutcomeTimeout
+		if errors.Is(ctx.Err(), context.Canceled) {
+			outcome = metrics.LSPOutcomeCancel
+		}
+		// Tell the server to drop the request, its answer is not waited for
+		if err := c.SendNotification("$/cancelRequest", map[string]int{"id": id}); err != nil {
+			c.logger.Debug("Failed to cancel LSP request", zap.String("method", method), zap.Int("id", id), zap.Error(err))
+		}
 		return nil, ctx.Err()
 	}
 }
@@ -32,14 +32,14 @@ func (t *BaseClient) Initialize(ctx context.Context) (*base.InitializeResult, er
 				},
 				/*SignatureHelp: SignatureHelpClientCapabilities{
 					DynamicRegistration: false,
-				},
-				Definition: DefinitionClientCapabilities{
+				},*/
+				Definition: base.DefinitionClientCapabilities{
 					DynamicRegistration: false,
 					LinkSupport:         true,
 				},
-				References: ReferenceClientCapabilities{
+				References: base.ReferenceClientCapabilities{
 					DynamicRegistration: false,
-				},*/
+				},
 				CallHierarchy: base.CallHierarchyClientCapabilities{
 					DynamicRegistration: false,
 				},
@@ -61,6 +61,9 @@ func (t *BaseClient) Initialize(ctx context.Context) (*base.InitializeResult, er
 				},
 				Configuration: false,
 			},
+			General: base.GeneralClientCapabilities{
+				PositionEncodings: []string{base.PositionEncodingUTF16},
+			},
 		},
 	}
 
@@ -107,7 +110,10 @@ func (t *BaseClient) DidOpenFile(ctx context.Context, uri string) error {
 	}
 
 	fileHolder := base.NewFileHolder(uri, string(content))
+	// Proxied editor requests open files concurrently
+	t.mu.Lock()
 	t.fileHolders[uri] = fileHolder
+	t.mu.Unlock()
 
 	params := base.DidOpenTextDocumentParams{
 		TextDocument: base.TextDocumentItem{
@@ -391,3 +397,56 @@ func (t *BaseClient) GetHover(ctx context.Context, uri string, position base.Pos
 	t.logger.Debug("Hover information retrieved successfully", zap.String("uri", uri))
 	return hover, nil
 }
+
+func (t *BaseClient) GetDefinition(ctx context.Context, uri string, position base.Position) ([]base.Location, error) {
+	t.logger.Info("Getting definition from language server", zap.String("uri", uri))
+
+	if !t.initialized {
+		t.logger.Error("language server client not initialized", zap.String("uri", uri))
+		return nil, fmt.Errorf("client not initialized")
+	}
+
+	params := base.DefinitionParams{
+		TextDocumentPositionParams: base.TextDocumentPositionParams{
+			TextDocument: base.TextDocumentIdentifier{
+				URI: uri,
+			},
+			Position: position,
+		},
+	}
+
+	resp, err := t.sendRequest(ctx, "textDocument/definition", params)
+	if err != nil {
+		t.logger.Error("Failed to get definition from language server", zap.String("uri", uri), zap.Error(err))
+		return nil, fmt.Errorf("failed to get definition: %w", err)
+	}
+	return base.MapToLocations(resp.Result), nil
+}
+
+func (t *BaseClient) GetReferences(ctx context.Context, uri string, position base.Position, includeDeclaration bool) ([]base.Location, error) {
+	t.logger.Info("Getting references from language server", zap.String("uri", uri))
+
+	if !t.initialized {
+		t.logger.Error("language server client not initialized", zap.String("uri", uri))
+		return nil, fmt.Errorf("client not initialized")
+	}
+
+	params := base.ReferenceParams{
+		TextDocumentPositionParams: base.TextDocumentPositionParams{
+			TextDocument: base.TextDocumentIdentifier{
+				URI: uri,
+			},
+			Position: position,
+		},
+		Context: base.ReferenceContext{
+			IncludeDeclaration: includeDeclaration,
+		},
+	}
+
+	resp, err := t.sendRequest(ctx, "textDocument/references", params)
+	if err != nil {
+		t.logger.Error("Failed to get references from language server", zap.String("uri", uri), zap.Error(err))
+		return nil, fmt.Errorf("failed to get references: %w", err)
+	}
+	return base.MapToLocations(resp.Result), nil
+}
@@ -13,12 +13,13 @@ import (
 // CSharpLanguageServerClient wraps the base LSP client for C# specific functionality
 type CSharpLanguageServerClient struct {
 	*BaseClient
-	rootPath string
-	logger   *zap.Logger
+	rootPath      string
+	externalPaths []string
+	logger        *zap.Logger
 }
 
 // NewCSharpLanguageServerClient creates a new C# language server client
-func NewCSharpLanguageServerClient(config *config.Config, rootPath string, logger *zap.Logger) (*CSharpLanguageServerClient, error) {
+func NewCSharpLanguageServerClient(config *config.Config, rootPath string, externalPaths []string, logger *zap.Logger) (*CSharpLanguageServerClient, error) {
 	logger.Info("Creating new C# language server client")
 	lspPath := config.LanguageServers.GetLSPPath("csharp")
 	if lspPath == "" {
@@ -29,7 +30,7 @@ func NewCSharpLanguageServerClient(config *config.Config, rootPath string, logge
 		return nil, err
 	}
 
-	t := &CSharpLanguageServerClient{BaseClient: baseClient, rootPath: rootPath, logger: logger}
+	t := &CSharpLanguageServerClient{BaseClient: baseClient, rootPath: rootPath, externalPaths: externalPaths, logger: logger}
 	t.client = t
 	return t, nil
 }
@@ -50,17 +51,8 @@ func (t *CSharpLanguageServerClient) LanguageID(uri string) string {
 // IsExternalModule checks if the given URI points to an external module
 // For C#, this includes NuGet packages and .NET SDK assemblies
 func (t *CSharpLanguageServerClient) IsExternalModule(uri string) bool {
-	// NuGet packages cache locations
-	if strings.Contains(uri, ".nuget/packages/") ||
-		strings.Contains(uri, "/.nuget/") ||
-		strings.Contains(uri, "/packages/") {
-		return true
-	}
-
-	// .NET SDK and runtime locations
-	if strings.Contains(uri, "/dotnet/") ||
-		strings.Contains(uri, "/Microsoft.NETCore.App/") ||
-		strings.Contains(uri, "/Microsoft.AspNetCore.App/") {
+	// NuGet packages and .NET SDK and runtime assemblies
+	if isExternalURI(uri, t.rootPath, t.externalPaths) {
 		return true
 	}
 
@@ -0,0 +1,109 @@
+package lsp
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/armchr/codeapi/internal/model"
+	"github.com/armchr/codeapi/internal/util"
+	"github.com/armchr/codeapi/pkg/lsp/base"
+)
+
+// GetFunctionDependenciesUntil builds the call graph of GetFunctionDependencies
+// breadth first, and stops expanding functions once deadline has passed. The
+// functions left to expand are returned for ContinueFunctionDependencies. The
+// deadline is checked between functions, so a slow language server call can
+// overrun it; a zero deadline never passes.
+func (rs *LspService) GetFunctionDependenciesUntil(ctx context.Context, repoName, relativePath, functionName string, depth int, deadline time.Time) (*model.CallGraph, []model.PendingFunction, error) {
+	lspClient, err := rs.getLanguageServerClient(repoName, relativePath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get language server client: %w", err)
+	}
+
+	uri, _ := util.ToUri(relativePath, lspClient.GetRootPath())
+	roots, err := rs.getFunctionDefinitions(ctx, lspClient, uri, functionName)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get functions in file: %w", err)
+	}
+
+	callGraph := model.NewCallGraph()
+	callGraph.Roots = roots
+	pending := make([]model.PendingFunction, 0, len(roots))
+	for _, fn := range roots {
+		pending = append(pending, model.PendingFunction{Function: fn, Depth: depth})
+	}
+	pending, err = rs.expandPending(ctx, lspClient, callGraph, pending, deadline)
+	if err != nil {
+		return nil, nil, err
+	}
+	return callGraph, pending, nil
+}
+
+// ContinueFunctionDependencies expands the functions a time-boxed traversal
+// left pending. The returned graph holds the calls found by this round only,
+// without roots. Functions reached again through another path are expanded
+// again, as the functions of earlier rounds are not known. Pending functions
+// must lie in files under the root of the language server, which opens them.
+func (rs *LspService) ContinueFunctionDependencies(ctx context.Context, repoName, relativePath string, pending []model.PendingFunction, deadline time.Time) (*model.CallGraph, []model.PendingFunction, error) {
+	lspClient, err := rs.getLanguageServerClient(repoName, relativePath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get language server client: %w", err)
+	}
+
+	for _, p := range pending {
+		if !util.URIWithinRoot(lspClient.GetRootPath(), p.Function.Location.URI) {
+			return nil, nil, fmt.Errorf("pending function %s is outside the repository", p.Function.Name)
+		}
+	}
+
+	callGraph := model.NewCallGraph()
+	for _, p := range pending {
+		lspClient.DidOpenFile(ctx, p.Function.Location.URI)
+	}
+	pending, err = rs.expandPending(ctx, lspClient, callGraph, pending, deadline)
+	if err != nil {
+		return nil, nil, err
+	}
+	return callGraph, pending, nil
+}
+
+// expandPending adds the calls of pending functions to callGraph in
+// breadth-first order until none are left or deadline passes, and returns
+// those left. At least one function is expanded per call so that a
+// traversal always makes progress.
+func (rs *LspService) expandPending(ctx context.Context, lspClient base.LSPClient, callGraph *model.CallGraph, pending []model.PendingFunction, deadline time.Time) ([]model.PendingFunction, error) {
+	seen := make(map[string]bool, len(pending))
+	for _, p := range pending {
+		seen[p.Function.ToKey()] = true
+	}
+
+	for expanded := 0; len(pending) > 0; expanded++ {
+		if expanded > 0 && !deadline.IsZero() && time.Now().After(deadline) {
+			return pending, nil
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		p := pending[0]
+		pending = pending[1:]
+
+		deps, err := rs.getFunctionCallsAndDefinitions(ctx, lspClient, &p.Function)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get function dependencies: %w", err)
+		}
+		for _, dep := range deps {
+			callGraph.AddFunctionDependency(&p.Function, &dep)
+			key := dep.Definition.ToKey()
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			if p.Depth > 1 && !lspClient.IsExternalModule(dep.Definition.Location.URI) {
+				lspClient.DidOpenFile(ctx, dep.Definition.Location.URI)
+				pending = append(pending, model.PendingFunction{Function: dep.Definition, Depth: p.Depth - 1})
+			}
+		}
+	}
+	return pending, nil
+}
@@ -12,11 +12,12 @@ import (
 
 type GoLanguageServerClient struct {
 	*BaseClient
-	rootPath string
-	logger   *zap.Logger
+	rootPath      string
+	externalPaths []string
+	logger        *zap.Logger
 }
 
-func NewGoLanguageServerClient(config *config.Config, rootPath string, logger *zap.Logger) (*GoLanguageServerClient, error) {
+func NewGoLanguageServerClient(config *config.Config, rootPath string, externalPaths []string, logger *zap.Logger) (*GoLanguageServerClient, error) {
 	logger.Info("Creating new Go language server client")
 	lspPath := config.LanguageServers.GetLSPPath("go")
 	if lspPath == "" {
@@ -27,7 +28,7 @@ func NewGoLanguageServerClient(config *config.Config, rootPath string, logger *z
 		return nil, err
 	}
 
-	t := &GoLanguageServerClient{BaseClient: base, rootPath: rootPath, logger: logger}
+	t := &GoLanguageServerClient{BaseClient: base, rootPath: rootPath, externalPaths: externalPaths, logger: logger}
 	t.client = t
 	return t, nil
 }
@@ -53,7 +54,7 @@ func (t *GoLanguageServerClient) LanguageID(uri string) string {
 }
 
 func (t *GoLanguageServerClient) IsExternalModule(uri string) bool {
-	if strings.Contains(uri, "vendor/") {
+	if isExternalURI(uri, t.rootPath, t.externalPaths) {
 		return true
 	}
 
@@ -13,12 +13,13 @@ import (
 // JavaLanguageServerClient wraps the base LSP client for Java specific functionality
 type JavaLanguageServerClient struct {
 	*BaseClient
-	rootPath string
-	logger   *zap.Logger
+	rootPath      string
+	externalPaths []string
+	logger        *zap.Logger
 }
 
 // NewJavaLanguageServerClient creates a new Java language server client (Eclipse JDT.LS)
-func NewJavaLanguageServerClient(config *config.Config, rootPath string, logger *zap.Logger) (*JavaLanguageServerClient, error) {
+func NewJavaLanguageServerClient(config *config.Config, rootPath string, externalPaths []string, logger *zap.Logger) (*JavaLanguageServerClient, error) {
 	logger.Info("Creating new Java language server client (Eclipse JDT.LS)")
 	lspPath := config.LanguageServers.GetLSPPath("java")
 	if lspPath == "" {
@@ -29,7 +30,7 @@ func NewJavaLanguageServerClient(config *config.Config, rootPath string, logger
 		return nil, err
 	}
 
-	t := &JavaLanguageServerClient{BaseClient: baseClient, rootPath: rootPath, logger: logger}
+	t := &JavaLanguageServerClient{BaseClient: baseClient, rootPath: rootPath, externalPaths: externalPaths, logger: logger}
 	t.client = t
 	return t, nil
 }
@@ -53,37 +54,8 @@ func (t *JavaLanguageServerClient) LanguageID(uri string) string {
 // IsExternalModule checks if the given URI points to an external module
 // For Java, this includes Maven/Gradle dependencies and JDK classes
 func (t *JavaLanguageServerClient) IsExternalModule(uri string) bool {
-	// Maven local repository
-	if strings.Contains(uri, ".m2/repository/") ||
-		strings.Contains(uri, "/.m2/") {
-		return true
-	}
-
-	// Gradle cache locations
-	if strings.Contains(uri, ".gradle/caches/") ||
-		strings.Contains(uri, "/.gradle/") {
-		return true
-	}
-
-	// Build output directories (compiled dependencies)
-	if strings.Contains(uri, "/target/") ||
-		strings.Contains(uri, "/build/") ||
-		strings.Contains(uri, "/out/") {
-		return true
-	}
-
-	// JDK/JRE locations - be careful not to match src/main/java/ which is standard Maven structure
-	if strings.Contains(uri, "/jdk") ||
-		strings.Contains(uri, "/jre") ||
-		strings.Contains(uri, "/Library/Java/") || // macOS JDK location
-		strings.Contains(uri, "/usr/lib/jvm/") ||  // Linux JDK location
-		strings.Contains(uri, "rt.jar") {
-		return true
-	}
-
-	// Eclipse JDT.LS workspace/cache
-	if strings.Contains(uri, "jdt.ls") ||
-		strings.Contains(uri, "jdt-language-server") {
+	// Maven and Gradle caches, build output, JDKs and the JDT.LS workspace
+	if isExternalURI(uri, t.rootPath, t.externalPaths) {
 		return true
 	}
 
@@ -2,6 +2,7 @@ package lsp
 
 import (
 	"github.com/armchr/codeapi/internal/config"
+	"github.com/armchr/codeapi/internal/util"
 	"github.com/armchr/codeapi/pkg/lsp/base"
 	"fmt"
 	"strings"
@@ -9,14 +10,19 @@ import (
 	"go.uber.org/zap"
 )
 
-func NewLSPLanguageServer(config *config.Config, language, rootPath string, logger *zap.Logger) (base.LSPClient, error) {
+// NewLSPLanguageServer creates the language server client for the files of
+// repo in language. The client treats the external paths of the language
+// and the repository as outside the repository.
+func NewLSPLanguageServer(config *config.Config, language string, repo *config.Repository, logger *zap.Logger) (base.LSPClient, error) {
+	rootPath := repo.Path
+	externalPaths := config.ExternalPathPatterns(language, repo)
 	switch strings.ToLower(language) {
 	case "go", "golang":
-		return NewGoLanguageServerClient(config, rootPath, logger)
+		return NewGoLanguageServerClient(config, rootPath, externalPaths, logger)
 	case "java", "kotlin":
-		return NewJavaLanguageServerClient(config, rootPath, logger)
+		return NewJavaLanguageServerClient(config, rootPath, externalPaths, logger)
 	case "csharp", "c#":
-		return NewCSharpLanguageServerClient(config, rootPath, logger)
+		return NewCSharpLanguageServerClient(config, rootPath, externalPaths, logger)
 	case "ruby":
 		return nil, fmt.Errorf("Ruby language server not implemented yet")
 	case "php":
@@ -28,10 +34,16 @@ func NewLSPLanguageServer(config *config.Config, language, rootPath string, logg
 	case "swift":
 		return nil, fmt.Errorf("Swift language server not implemented yet")
 	case "python", "py":
-		return NewPythonLanguageServerClient(config, rootPath, logger)
+		return NewPythonLanguageServerClient(config, rootPath, externalPaths, logger)
 	case "javascript", "js", "typescript", "ts":
-		return NewTypeScriptLanguageServerClient(rootPath, logger)
+		return NewTypeScriptLanguageServerClient(rootPath, externalPaths, logger)
 	default:
 		return nil, fmt.Errorf("unsupported language: %s", language)
 	}
 }
+
+// isExternalURI reports whether the file of uri lies under one of the
+// external path patterns, matched relative to rootPath for files inside it
+func isExternalURI(uri, rootPath string, externalPaths []string) bool {
+	return config.IsExternalPath(util.ExtractPathFromURI(uri), rootPath, externalPaths)
+}
@@ -7,6 +7,8 @@ import (
 	"github.com/armchr/codeapi/pkg/lsp/base"
 	"context"
 	"fmt"
+	"sort"
+	"strings"
 
 	"go.uber.org/zap"
 )
@@ -25,46 +27,66 @@ func NewLspService(config *config.Config, logger *zap.Logger) *LspService {
 	}
 }
 
-func (rs *LspService) prepareLanguageServer(repoName string) (base.LSPClient, error) {
-	rs.logger.Info("Preparing language server", zap.String("repo_name", repoName))
+func (rs *LspService) prepareLanguageServer(repo *config.Repository, language string) (base.LSPClient, error) {
+	rs.logger.Info("Preparing language server", zap.String("repo_name", repo.Name), zap.String("language", language))
 
-	repo, err := rs.config.GetRepository(repoName)
+	languageServer, err := NewLSPLanguageServer(rs.config, language, repo, rs.logger)
 	if err != nil {
-		rs.logger.Error("Failed to get repository config", zap.String("repo_name", repoName), zap.Error(err))
-		return nil, fmt.Errorf("failed to get repository config: %w", err)
-	}
-
-	languageServer, err := NewLSPLanguageServer(rs.config, repo.Language, repo.Path, rs.logger)
-	if err != nil {
-		rs.logger.Error("Failed to create language server", zap.String("language", repo.Language), zap.Error(err))
+		rs.logger.Error("Failed to create language server", zap.String("language", language), zap.Error(err))
 		return nil, fmt.Errorf("failed to create language server: %w", err)
 	}
 
 	_, err = languageServer.Initialize(context.Background())
 	if err != nil {
-		rs.logger.Error("Failed to initialize language server", zap.String("repo_name", repoName), zap.Error(err))
+		rs.logger.Error("Failed to initialize language server", zap.String("repo_name", repo.Name), zap.Error(err))
 		return nil, fmt.Errorf("failed to initialize language server: %w", err)
 	}
 
 	return languageServer, nil
 }
 
-func (rs *LspService) getLanguageServerClient(repoName string) (base.LSPClient, error) {
+// clientKey returns the cache key for a repository's language server.
+// Single-language repositories keep one client keyed by repo name; mixed-language
+// repositories get one client per language.
+func clientKey(repo *config.Repository, language string) string {
+	if repo.IsMultiLanguage() {
+		return repo.Name + ":" + language
+	}
+	return repo.Name
+}
+
+// getLanguageServerClient returns the language server for the file identified by
+// fileHint (a path or URI). The hint is only consulted for mixed-language repositories.
+func (rs *LspService) getLanguageServerClient(repoName, fileHint string) (base.LSPClient, error) {
 	rs.logger.Info("Getting language server client", zap.String("repo_name", repoName))
 
-	//var client lsp.LSPClient
-	client, exists := rs.lspClients.Get(repoName)
+	repo, err := rs.config.GetRepository(repoName)
+	if err != nil {
+		rs.logger.Error("Failed to get repository config", zap.String("repo_name", repoName), zap.Error(err))
+		return nil, fmt.Errorf("failed to get repository config: %w", err)
+	}
+
+	language := repo.Language
+	if repo.IsMultiLanguage() {
+		language = util.DetectLanguage(fileHint, nil)
+		if language == "" {
+			return nil, fmt.Errorf("cannot determine language for %s", fileHint)
+		}
+	}
+
+	key := clientKey(repo, language)
+	client, exists := rs.lspClients.Get(key)
 	if exists {
 		return client, nil
 	}
 
-	client, err := rs.prepareLanguageServer(repoName)
+	client, err = rs.prepareLanguageServer(repo, language)
 
 	if err != nil {
 		rs.logger.Error("Failed to prepare language server", zap.String("repo_name", repoName), zap.Error(err))
 		return nil, fmt.Errorf("failed to prepare language server: %w", err)
 	}
-	rs.lspClients.Set(repoName, client)
+	rs.lspClients.Set(key, client)
 	return client, nil
 }
 
@@ -72,25 +94,75 @@ func (rs *LspService) getLanguageServerClient(repoName string) (base.LSPClient,
 // This is useful for index building where we want to ensure the LSP is ready
 // before processing begins, avoiding initialization delays during post-processing.
 // If the language server is already initialized, this is a no-op.
+// For mixed-language repositories a server is started for each configured
+// language; without an explicit languages list they are started lazily per file.
 func (rs *LspService) PrepareLanguageServer(repoName string) error {
-	// Check if already initialized
-	if _, exists := rs.lspClients.Get(repoName); exists {
-		rs.logger.Debug("Language server already initialized", zap.String("repo_name", repoName))
-		return nil
+	repo, err := rs.config.GetRepository(repoName)
+	if err != nil {
+		return fmt.Errorf("failed to get repository config: %w", err)
 	}
 
-	rs.logger.Info("Pre-initializing language server for repository", zap.String("repo_name", repoName))
-
-	client, err := rs.prepareLanguageServer(repoName)
-	if err != nil {
-		return fmt.Errorf("failed to prepare language server for %s: %w", repoName, err)
+	languages := []string{repo.Language}
+	if repo.IsMultiLanguage() {
+		languages = repo.Languages
+		if len(languages) == 0 {
+			rs.logger.Info("Mixed-language repository without languages list, language servers will start on demand",
+				zap.String("repo_name", repoName))
+		}
 	}
 
-	rs.lspClients.Set(repoName, client)
-	rs.logger.Info("Language server initialized successfully", zap.String("repo_name", repoName))
+	for _, language := range languages {
+		key := clientKey(repo, language)
+		// Check if already initialized
+		if _, exists := rs.lspClients.Get(key); exists {
+			rs.logger.Debug("Language server already initialized", zap.String("repo_name", repoName), zap.String("language", language))
+			continue
+		}
+
+		rs.logger.Info("Pre-initializing language server for repository", zap.String("repo_name", repoName), zap.String("language", language))
+
+		client, err := rs.prepareLanguageServer(repo, language)
+		if err != nil {
+			return fmt.Errorf("failed to prepare language server for %s: %w", repoName, err)
+		}
+
+		rs.lspClients.Set(key, client)
+		rs.logger.Info("Language server initialized successfully", zap.String("repo_name", repoName), zap.String("language", language))
+	}
 	return nil
 }
 
+// OpenServers lists the running language servers by their repository key:
+// the repository name, or name:language for mixed-language repositories
+func (rs *LspService) OpenServers() []string {
+	keys := rs.lspClients.Keys()
+	sort.Strings(keys)
+	return keys
+}
+
+// CloseRepository shuts down every language server started for repoName so
+// a removed or relocated repository stops holding server processes. Servers
+// are started again on demand if the repository is served later.
+func (rs *LspService) CloseRepository(ctx context.Context, repoName string) {
+	for _, key := range rs.lspClients.Keys() {
+		if key != repoName && !strings.HasPrefix(key, repoName+":") {
+			continue
+		}
+		client, ok := rs.lspClients.Delete(key)
+		if !ok {
+			continue
+		}
+
+		rs.logger.Info("Stopping language server", zap.String("repo_name", repoName), zap.String("key", key))
+		if err := client.Shutdown(ctx); err != nil {
+			rs.logger.Warn("Language server shutdown failed", zap.String("key", key), zap.Error(err))
+		}
+		if err := client.Close(); err != nil {
+			rs.logger.Warn("Failed to close language server", zap.String("key", key), zap.Error(err))
+		}
+	}
+}
+
 func (rs *LspService) getSymbolsOfType(ctx context.Context, lspClient base.LSPClient, fileUri string, symType int) ([]interface{}, error) {
 	lspClient.DidOpenFile(ctx, fileUri)
 
@@ -179,7 +251,7 @@ func (rs *LspService) extractSignature(sig map[string]interface{}) string {
 func (rs *LspService) GetFunctionCallsAndDefinitions(ctx context.Context,
 	repoName string,
 	targetFunction *model.FunctionDefinition) ([]model.FunctionDependency, error) {
-	lspClient, err := rs.getLanguageServerClient(repoName)
+	lspClient, err := rs.getLanguageServerClient(repoName, targetFunction.Location.URI)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get language server client: %w", err)
 	}
@@ -282,7 +354,7 @@ func (rs *LspService) PopulateCallGraphForFunction(
 	repoName string,
 	fn *model.FunctionDefinition,
 	depth int) (*model.CallGraph, error) {
-	lspClient, err := rs.getLanguageServerClient(repoName)
+	lspClient, err := rs.getLanguageServerClient(repoName, fn.Location.URI)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get language server client: %w", err)
 	}
@@ -297,7 +369,7 @@ func (rs *LspService) PopulateCallGraphForFunction(
 }
 
 func (rs *LspService) GetFunctionDependencies(ctx context.Context, repoName, relativePath, functionName string, depth int) (*model.CallGraph, error) {
-	lspClient, err := rs.getLanguageServerClient(repoName)
+	lspClient, err := rs.getLanguageServerClient(repoName, relativePath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get language server client: %w", err)
 	}
@@ -316,16 +388,17 @@ func (rs *LspService) GetFunctionDependencies(ctx context.Context, repoName, rel
 }
 
 func (rs *LspService) GetFunctionHovers(ctx context.Context, repoName string, functions []model.FunctionDefinition) ([]string, error) {
-	lspClient, err := rs.getLanguageServerClient(repoName)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get language server client: %w", err)
-	}
-
 	hovers := make([]string, len(functions))
 
 	for i, fn := range functions {
+		// Resolved per function since mixed-language repositories use one server per language
+		lspClient, err := rs.getLanguageServerClient(repoName, fn.Location.URI)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get language server client: %w", err)
+		}
+
 		// Ensure the file is open in the LSP client
-		err := lspClient.DidOpenFile(ctx, fn.Location.URI)
+		err = lspClient.DidOpenFile(ctx, fn.Location.URI)
 		if err != nil {
 			rs.logger.Warn("Failed to open file for hover",
 				zap.String("uri", fn.Location.URI),
@@ -534,7 +607,7 @@ func (rs *LspService) populateCallerGraph(
 }
 
 func (rs *LspService) GetFunctionCallers(ctx context.Context, repoName, relativePath, functionName string, depth int) (*model.CallGraph, error) {
-	lspClient, err := rs.getLanguageServerClient(repoName)
+	lspClient, err := rs.getLanguageServerClient(repoName, relativePath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get language server client: %w", err)
 	}
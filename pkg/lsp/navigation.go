@@ -0,0 +1,88 @@
+package lsp
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/armchr/codeapi/internal/util"
+	"github.com/armchr/codeapi/pkg/lsp/base"
+)
+
+// openDocument returns the language server for a file of a repository with
+// the file opened in it, and the file's URI. The file is read again from
+// disk so the server sees its current content.
+func (rs *LspService) openDocument(ctx context.Context, repoName, relativePath string) (base.LSPClient, string, error) {
+	repo, err := rs.config.GetRepository(repoName)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get repository config: %w", err)
+	}
+	client, err := rs.getLanguageServerClient(repoName, relativePath)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get language server client: %w", err)
+	}
+	uri, err := util.ToUri(relativePath, repo.Path)
+	if err != nil {
+		return nil, "", err
+	}
+	if err := client.DidOpenFile(ctx, uri); err != nil {
+		return nil, "", fmt.Errorf("failed to open %s: %w", relativePath, err)
+	}
+	return client, uri, nil
+}
+
+// GetDefinition returns where the symbol at position in a file of a
+// repository is defined
+func (rs *LspService) GetDefinition(ctx context.Context, repoName, relativePath string, position base.Position) ([]base.Location, error) {
+	client, uri, err := rs.openDocument(ctx, repoName, relativePath)
+	if err != nil {
+		return nil, err
+	}
+	return client.GetDefinition(ctx, uri, position)
+}
+
+// GetReferences returns where the symbol at position in a file of a
+// repository is used, and declared if includeDeclaration is set
+func (rs *LspService) GetReferences(ctx context.Context, repoName, relativePath string, position base.Position, includeDeclaration bool) ([]base.Location, error) {
+	client, uri, err := rs.openDocument(ctx, repoName, relativePath)
+	if err != nil {
+		return nil, err
+	}
+	return client.GetReferences(ctx, uri, position, includeDeclaration)
+}
+
+// GetHover returns the hover text of the symbol at position in a file of a
+// repository, flattened to a string, and the range it applies to. Both are
+// empty when the server has nothing to show.
+func (rs *LspService) GetHover(ctx context.Context, repoName, relativePath string, position base.Position) (string, *base.Range, error) {
+	client, uri, err := rs.openDocument(ctx, repoName, relativePath)
+	if err != nil {
+		return "", nil, err
+	}
+	hover, err := client.GetHover(ctx, uri, position)
+	if err != nil || hover == nil {
+		return "", nil, err
+	}
+	return rs.extractHoverContent(hover.Contents), hover.Range, nil
+}
+
+// WarmFiles opens files of a repository in its language server so the
+// server has parsed them before the first request asks about them. Files
+// that fail to open are skipped; the number opened is returned with the
+// first failure.
+func (rs *LspService) WarmFiles(ctx context.Context, repoName string, relativePaths []string) (int, error) {
+	opened := 0
+	var firstErr error
+	for _, relativePath := range relativePaths {
+		if ctx.Err() != nil {
+			return opened, ctx.Err()
+		}
+		if _, _, err := rs.openDocument(ctx, repoName, relativePath); err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		opened++
+	}
+	return opened, firstErr
+}
@@ -12,11 +12,12 @@ import (
 
 type PythonLanguageServerClient struct {
 	*BaseClient
-	rootPath string
-	logger   *zap.Logger
+	rootPath      string
+	externalPaths []string
+	logger        *zap.Logger
 }
 
-func NewPythonLanguageServerClient(config *config.Config, rootPath string, logger *zap.Logger) (*PythonLanguageServerClient, error) {
+func NewPythonLanguageServerClient(config *config.Config, rootPath string, externalPaths []string, logger *zap.Logger) (*PythonLanguageServerClient, error) {
 	logger.Info("Creating new Python language server client")
 	lspPath := config.LanguageServers.GetLSPPath("python")
 	if lspPath == "" {
@@ -27,7 +28,7 @@ func NewPythonLanguageServerClient(config *config.Config, rootPath string, logge
 		return nil, err
 	}
 
-	t := &PythonLanguageServerClient{BaseClient: base, rootPath: rootPath, logger: logger}
+	t := &PythonLanguageServerClient{BaseClient: base, rootPath: rootPath, externalPaths: externalPaths, logger: logger}
 	t.client = t
 	return t, nil
 }
@@ -45,11 +46,7 @@ func (t *PythonLanguageServerClient) LanguageID(uri string) string {
 }
 
 func (t *PythonLanguageServerClient) IsExternalModule(uri string) bool {
-	if strings.Contains(uri, "site-packages/") || strings.Contains(uri, "dist-packages/") ||
-		strings.Contains(uri, ".venv/") || strings.Contains(uri, "node_modules/") {
-		return true
-	}
-	return false
+	return isExternalURI(uri, t.rootPath, t.externalPaths)
 }
 
 func (t *PythonLanguageServerClient) MatchSymbolByName(name, nameInFile string) bool {
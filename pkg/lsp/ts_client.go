@@ -9,18 +9,19 @@ import (
 
 type TypeScriptLanguageServerClient struct {
 	*BaseClient
-	rootPath string
-	logger   *zap.Logger
+	rootPath      string
+	externalPaths []string
+	logger        *zap.Logger
 }
 
-func NewTypeScriptLanguageServerClient(rootPath string, logger *zap.Logger) (*TypeScriptLanguageServerClient, error) {
+func NewTypeScriptLanguageServerClient(rootPath string, externalPaths []string, logger *zap.Logger) (*TypeScriptLanguageServerClient, error) {
 	logger.Info("Creating new TypeScript language server client")
 	base, err := NewBaseClient("typescript-language-server", logger, "--stdio")
 	if err != nil {
 		return nil, err
 	}
 
-	t := &TypeScriptLanguageServerClient{BaseClient: base, rootPath: rootPath, logger: logger}
+	t := &TypeScriptLanguageServerClient{BaseClient: base, rootPath: rootPath, externalPaths: externalPaths, logger: logger}
 	t.client = t
 	return t, nil
 }
@@ -40,7 +41,7 @@ func (t *TypeScriptLanguageServerClient) LanguageID(uri string) string {
 }
 
 func (t *TypeScriptLanguageServerClient) IsExternalModule(uri string) bool {
-	return strings.Contains(uri, "node_modules/")
+	return isExternalURI(uri, t.rootPath, t.externalPaths)
 }
 
 func (t *TypeScriptLanguageServerClient) MatchSymbolByName(name, nameInFile string) bool {
@@ -0,0 +1,22 @@
+#!/bin/bash
+# Runs the benchmarks of a git ref in a temporary worktree, so bench-compare
+# can measure a branch against its base without switching checkouts.
+# Usage: scripts/bench_baseline.sh <ref> <output file> [go test flags...]
+
+set -euo pipefail
+
+if [ $# -lt 2 ]; then
+    echo "Usage: $0 <ref> <output file> [go test flags...]" >&2
+    exit 2
+fi
+ref="$1"
+out="$2"
+shift 2
+
+worktree="$(mktemp -d)"
+trap 'git worktree remove --force "$worktree"' EXIT
+git worktree add --detach --quiet "$worktree" "$ref"
+
+mkdir -p "$(dirname "$out")"
+echo "Running benchmarks at $ref..."
+(cd "$worktree" && go test "$@") > "$out"
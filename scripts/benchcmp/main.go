@@ -0,0 +1,75 @@
+// Command benchcmp compares two go test -bench outputs and exits with status
+// 1 when a benchmark got slower or allocates more by more than a threshold.
+//
+//	benchcmp [-threshold 10] [-units ns/op,B/op] base.txt head.txt
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/armchr/codeapi/internal/bench"
+)
+
+func main() {
+	threshold := flag.Float64("threshold", 10, "largest allowed increase, in percent")
+	units := flag.String("units", "ns/op,B/op", "comma-separated units to compare; lower must be better")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: benchcmp [flags] base.txt head.txt\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 2 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	base, err := readResults(flag.Arg(0))
+	if err != nil {
+		fatal(err)
+	}
+	head, err := readResults(flag.Arg(1))
+	if err != nil {
+		fatal(err)
+	}
+
+	deltas := bench.Compare(base, head, strings.Split(*units, ","), *threshold)
+	if len(deltas) == 0 {
+		fatal(fmt.Errorf("no benchmarks in common between %s and %s", flag.Arg(0), flag.Arg(1)))
+	}
+
+	regressions := 0
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "BENCHMARK\tUNIT\tBASE\tHEAD\tCHANGE\t")
+	for _, d := range deltas {
+		mark := ""
+		if d.Regressed {
+			mark = "  REGRESSION"
+			regressions++
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%.0f\t%.0f\t%+.1f%%%s\t\n", d.Benchmark, d.Unit, d.Base, d.Head, d.Change, mark)
+	}
+	tw.Flush()
+
+	if regressions > 0 {
+		fmt.Fprintf(os.Stderr, "\n%d metric(s) regressed by more than %.0f%%\n", regressions, *threshold)
+		os.Exit(1)
+	}
+}
+
+func readResults(path string) (bench.Results, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return bench.ParseResults(f)
+}
+
+func fatal(err error) {
+	fmt.Fprintln(os.Stderr, "benchcmp:", err)
+	os.Exit(2)
+}
@@ -184,16 +184,19 @@ run_index_build() {
 
     # Run codeapi with build-index, capturing output
     local dump_file="${LOG_DIR}/${repo_name}.dump.txt"
-    echo -e "${YELLOW}Running: ${BINARY} -build-index ${repo_name} -app ${APP_CONFIG} -source ${SOURCE_CONFIG} -test-dump ${dump_file}${NC}"
+    echo -e "${YELLOW}Running: ${BINARY} --app ${APP_CONFIG} --source ${SOURCE_CONFIG} index build ${repo_name}${NC}"
     echo -e "${YELLOW}Log file: ${log_file}${NC}"
     echo -e "${YELLOW}Dump file: ${dump_file}${NC}"
 
-    # Run the command and capture both stdout and stderr to log file only
+    # Run the commands and capture both stdout and stderr to log file only
     "${BINARY}" \
-        -build-index "${repo_name}" \
-        -app "${APP_CONFIG}" \
-        -source "${SOURCE_CONFIG}" \
-        -test-dump "${dump_file}" > "${log_file}" 2>&1
+        --app "${APP_CONFIG}" \
+        --source "${SOURCE_CONFIG}" \
+        index build "${repo_name}" > "${log_file}" 2>&1 &&
+    "${BINARY}" \
+        --app "${APP_CONFIG}" \
+        --source "${SOURCE_CONFIG}" \
+        graph dump "${repo_name}" --out "${dump_file}" >> "${log_file}" 2>&1
     exit_code=$?
 
     # Check exit code